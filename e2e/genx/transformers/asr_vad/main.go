@@ -94,10 +94,9 @@ func runEoSPipelineTest(ctx context.Context, doubaoClient *doubaospeech.Client,
 		transformers.WithMinimaxTTSFormat("mp3"),
 		transformers.WithMinimaxTTSSampleRate(24000),
 	)
-	codec := transformers.NewMP3ToOgg()
-	asr := transformers.NewDoubaoASRSAUC(doubaoClient,
-		transformers.WithDoubaoASRSAUCSampleRate(24000),
-	)
+	// The codec also resamples to the ASR's default 16000 Hz
+	codec := transformers.NewAudioConvert("audio/ogg", 16000, 1)
+	asr := transformers.NewDoubaoASRSAUC(doubaoClient)
 
 	// Create text streams for each sentence
 	fmt.Println("[1] Creating text streams with CompositeSeq...")
@@ -99,7 +99,7 @@ func runMinimaxTest(ctx context.Context) {
 		transformers.WithMinimaxTTSFormat("mp3"),
 		transformers.WithMinimaxTTSSampleRate(24000),
 	)
-	codec := transformers.NewMP3ToOgg()
+	codec := transformers.NewAudioConvert("audio/ogg", 24000, 1)
 
 	// Round 1: TTS -> Codec -> ASR
 	fmt.Println("[1] TTS (MiniMax)...")
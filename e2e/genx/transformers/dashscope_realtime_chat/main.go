@@ -229,7 +229,7 @@ func runChat(ctx context.Context, tts *transformers.MinimaxTTS, aiA, aiB *transf
 			track.HandleChunk(chunk)
 
 			// Filter and pipe audio to AI_B (including EOS)
-			if isAudioChunk(chunk) || isAudioEOS(chunk) {
+			if isAudio(chunk) {
 				// Play AI_A audio (24kHz)
 				if blob, ok := chunk.Part.(*genx.Blob); ok && len(blob.Data) > 0 {
 					playAudio(blob.Data, 24000)
@@ -240,7 +240,7 @@ func runChat(ctx context.Context, tts *transformers.MinimaxTTS, aiA, aiB *transf
 					currentStreamID = genx.NewStreamID()
 				}
 
-				isEOS := isAudioEOS(chunk)
+				isEOS := chunk.IsEndOfStream()
 
 				// Resample audio from 24kHz (DashScope output) to 16kHz (DashScope input)
 				var audioPart genx.Part = chunk.Part
@@ -311,7 +311,7 @@ func runChat(ctx context.Context, tts *transformers.MinimaxTTS, aiA, aiB *transf
 			track.HandleChunk(chunk)
 
 			// Filter and pipe audio to AI_A (including EOS)
-			if isAudioChunk(chunk) || isAudioEOS(chunk) {
+			if isAudio(chunk) {
 				// Play AI_B audio (24kHz)
 				if blob, ok := chunk.Part.(*genx.Blob); ok && len(blob.Data) > 0 {
 					playAudio(blob.Data, 24000)
@@ -322,7 +322,7 @@ func runChat(ctx context.Context, tts *transformers.MinimaxTTS, aiA, aiB *transf
 					currentStreamID = genx.NewStreamID()
 				}
 
-				isEOS := isAudioEOS(chunk)
+				isEOS := chunk.IsEndOfStream()
 
 				// Resample audio from 24kHz (DashScope output) to 16kHz (DashScope input)
 				var audioPart genx.Part = chunk.Part
@@ -432,29 +432,8 @@ func (s *bufferStream) Write(chunk *genx.MessageChunk) error {
 	return s.buf.Add(chunk)
 }
 
-// isAudioChunk checks if chunk contains audio data
-func isAudioChunk(chunk *genx.MessageChunk) bool {
-	if chunk == nil {
-		return false
-	}
-	blob, ok := chunk.Part.(*genx.Blob)
-	if !ok {
-		return false
-	}
-	return internal.IsAudioMIME(blob.MIMEType) && len(blob.Data) > 0
-}
-
-// isAudioEOS checks if chunk is an audio end-of-stream marker
-func isAudioEOS(chunk *genx.MessageChunk) bool {
-	if chunk == nil || chunk.Ctrl == nil || !chunk.Ctrl.EndOfStream {
-		return false
-	}
-	blob, ok := chunk.Part.(*genx.Blob)
-	if !ok {
-		return false
-	}
-	return internal.IsAudioMIME(blob.MIMEType)
-}
+// isAudio matches audio chunks, including audio EoS markers.
+var isAudio = genx.MIMETypeMatcher("audio/")
 
 // resamplePCM converts PCM from one sample rate to another
 func resamplePCM(data []byte, fromRate, toRate int) ([]byte, error) {
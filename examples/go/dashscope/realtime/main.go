@@ -325,7 +325,7 @@ func collectResponseText(eventCh <-chan *dashscope.RealtimeEvent, errCh <-chan e
 
 			default:
 				if *verbose {
-					log.Printf("[Event] %s", event.Type)
+					log.Printf("[Event] %s: %s", event.Type, event.Raw)
 				}
 			}
 
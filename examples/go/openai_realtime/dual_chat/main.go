@@ -34,7 +34,6 @@ import (
 	"log/slog"
 	"os"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/haivivi/giztoy/go/pkg/audio/pcm"
@@ -282,8 +281,9 @@ func createAgent(ctx context.Context, client *openairealtime.Client, name, trans
 	var session openairealtime.Session
 	var err error
 
-	// Connect based on transport type
-	config := &openairealtime.ConnectConfig{Model: *model}
+	// Connect based on transport type. The session paces the audio we
+	// append, so it reaches the server VAD like a live microphone.
+	config := &openairealtime.ConnectConfig{Model: *model, PaceAudio: true}
 
 	switch transport {
 	case "ws", "websocket":
@@ -398,19 +398,10 @@ func sendAndReceive(agent *Agent, textInput string, audioInput []byte) (string,
 			// VAD mode: send audio in real-time with silence padding
 			return sendWithVAD(agent, audioInput)
 		}
-		// Manual mode: send audio in chunks then commit
-		const chunkSize = 4800 // 100ms at 24kHz
-		for i := 0; i < len(audioInput); i += chunkSize {
-			end := i + chunkSize
-			if end > len(audioInput) {
-				end = len(audioInput)
-			}
-			if err := agent.Session.AppendAudio(audioInput[i:end]); err != nil {
-				return "", nil, fmt.Errorf("append audio failed: %w", err)
-			}
-			time.Sleep(20 * time.Millisecond)
+		// Manual mode: send audio then commit
+		if err := agent.Session.AppendAudio(audioInput); err != nil {
+			return "", nil, fmt.Errorf("append audio failed: %w", err)
 		}
-		time.Sleep(100 * time.Millisecond)
 		if err := agent.Session.CommitInput(); err != nil {
 			return "", nil, fmt.Errorf("commit failed: %w", err)
 		}
@@ -430,142 +421,28 @@ func sendAndReceive(agent *Agent, textInput string, audioInput []byte) (string,
 	return collectResponse(agent, 60*time.Second)
 }
 
-// sendWithVAD sends audio using real-time streaming with VAD via pcm.Mixer.
-// The Mixer automatically outputs silence when no track data is available.
-// Flow: [silence] → [speech audio written to track in real-time] → [silence after track closes]
-// VAD will automatically detect speech end and trigger response.
+// sendWithVAD sends audio with silence padding for VAD. The session paces
+// it in real time, so VAD detects the speech end and triggers the response.
+// Flow: [silence] → [speech audio] → [silence]
 func sendWithVAD(agent *Agent, audioInput []byte) (string, []byte, error) {
 	const (
-		chunkDuration   = 20 * time.Millisecond // 20ms per chunk
 		leadingSilence  = 500 * time.Millisecond
 		trailingSilence = 1000 * time.Millisecond // Must be > VAD silence_duration_ms (800ms)
 	)
 
-	// Create mixer with silence gap - it will output silence when no tracks have data
-	mixer := pcm.NewMixer(pcm.L16Mono24K, pcm.WithSilenceGap(time.Hour))
-	defer mixer.Close()
-
-	// Calculate chunk size in bytes (20ms at 24kHz 16-bit mono = 960 bytes)
-	chunkBytes := int(pcm.L16Mono24K.BytesInDuration(chunkDuration))
-	readBuf := make([]byte, chunkBytes)
-
-	// Context for cancellation
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Error channel for goroutine communication
-	errCh := make(chan error, 2)
-	var wg sync.WaitGroup
+	leading := make([]byte, pcm.L16Mono24K.BytesInDuration(leadingSilence))
+	trailing := make([]byte, pcm.L16Mono24K.BytesInDuration(trailingSilence))
 
-	// Goroutine 1: Read from mixer and send to OpenAI at real-time rate
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		ticker := time.NewTicker(chunkDuration)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				n, err := mixer.Read(readBuf)
-				if err != nil {
-					if err != io.EOF {
-						errCh <- fmt.Errorf("mixer read failed: %w", err)
-					}
-					return
-				}
-				if n > 0 {
-					if err := agent.Session.AppendAudio(readBuf[:n]); err != nil {
-						errCh <- fmt.Errorf("append audio failed: %w", err)
-						return
-					}
-				}
-			}
-		}
-	}()
-
-	// Calculate timing
-	speechDuration := pcm.L16Mono24K.Duration(int64(len(audioInput)))
-	totalDuration := leadingSilence + speechDuration + trailingSilence
-
-	log.Printf("  [VAD] Using pcm.Mixer for real-time audio streaming")
 	log.Printf("  [VAD] Leading silence: %v, Speech: %v, Trailing silence: %v",
-		leadingSilence, speechDuration, trailingSilence)
-
-	// Goroutine 2: Write speech audio to track at REAL-TIME rate
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-
-		// Wait for leading silence (mixer outputs silence automatically)
-		time.Sleep(leadingSilence)
+		leadingSilence, pcm.L16Mono24K.Duration(int64(len(audioInput))), trailingSilence)
 
-		// Create track for writing speech audio
-		track, trackCtrl, err := mixer.CreateTrack()
-		if err != nil {
-			errCh <- fmt.Errorf("create track failed: %w", err)
-			return
+	for _, audio := range [][]byte{leading, audioInput, trailing} {
+		if err := agent.Session.AppendAudio(audio); err != nil {
+			return "", nil, fmt.Errorf("append audio failed: %w", err)
 		}
-
-		log.Printf("  [VAD] Writing speech audio to track in real-time...")
-
-		// Write audio in chunks at real-time rate
-		// This ensures the mixer doesn't get all data at once
-		ticker := time.NewTicker(chunkDuration)
-		defer ticker.Stop()
-
-		offset := 0
-		for offset < len(audioInput) {
-			select {
-			case <-ctx.Done():
-				trackCtrl.CloseWrite()
-				return
-			case <-ticker.C:
-				end := offset + chunkBytes
-				if end > len(audioInput) {
-					end = len(audioInput)
-				}
-				chunk := pcm.L16Mono24K.DataChunk(audioInput[offset:end])
-				if err := track.Write(chunk); err != nil {
-					errCh <- fmt.Errorf("write to track failed: %w", err)
-					return
-				}
-				offset = end
-			}
-		}
-
-		// Close track to signal end of speech (mixer will return to silence)
-		trackCtrl.CloseWrite()
-		log.Printf("  [VAD] Speech written, waiting for trailing silence...")
-
-		// Wait for trailing silence to be sent
-		time.Sleep(trailingSilence)
-
-		// Stop the read goroutine
-		cancel()
-	}()
-
-	// Wait for audio streaming to complete or error
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(done)
-	}()
-
-	select {
-	case err := <-errCh:
-		cancel()
-		return "", nil, err
-	case <-done:
-		// Audio streaming complete
-	case <-time.After(totalDuration + 10*time.Second):
-		cancel()
-		return "", nil, fmt.Errorf("timeout sending audio")
 	}
 
-	log.Printf("  [VAD] Audio stream complete, waiting for VAD response...")
+	log.Printf("  [VAD] Audio queued, waiting for VAD response...")
 
 	// Collect response (VAD should auto-trigger response.create)
 	return collectResponse(agent, 60*time.Second)
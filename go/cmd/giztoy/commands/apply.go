@@ -5,6 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/haivivi/giztoy/go/pkg/cli"
 	"github.com/haivivi/giztoy/go/pkg/cortex"
 )
 
@@ -18,7 +19,10 @@ Use '-' to read from stdin. Multi-document YAML (--- separated) is supported.
 
 Supported kinds:
   creds/openai, creds/genai, creds/minimax, creds/doubaospeech, creds/dashscope
-  genx/generator, genx/tts, genx/asr, genx/realtime, genx/segmentor, genx/profiler
+  genx/generator, genx/tts, genx/asr, genx/realtime, genx/segmentor, genx/profiler,
+  genx/match
+  chatgear/config, chatgear/speaker
+  memory/prompt
 
 Examples:
   giztoy apply -f setup.yaml
@@ -26,7 +30,7 @@ Examples:
   cat config.yaml | giztoy apply -f -`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if applyFile == "" {
-			return fmt.Errorf("flag -f is required")
+			return cli.Errorf(cli.CodeUsage, "flag -f is required")
 		}
 
 		docs, err := cortex.ParseDocumentsFromFile(applyFile)
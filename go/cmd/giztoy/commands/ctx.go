@@ -24,7 +24,8 @@ var ctxCmd = &cobra.Command{
 	Long: `Manage contexts and storage backend configurations.
 
 A context is a named set of storage backend connections (KV, Storage,
-VecStore, Embed). Switching contexts switches the entire backend stack.
+VecStore, Embed) and the address of the serve daemon (Server). Switching
+contexts switches the entire backend stack.
 
 Examples:
   giztoy ctx add dev
@@ -168,6 +169,7 @@ var ctxShowCmd = &cobra.Command{
 				"storage":  cfg.Storage,
 				"vecstore": cfg.VecStore,
 				"embed":    cfg.Embed,
+				"server":   cfg.Server,
 			})
 		}
 		fmt.Printf("Context: %s\n", ctxName)
@@ -175,6 +177,7 @@ var ctxShowCmd = &cobra.Command{
 		fmt.Printf("  storage:  %s\n", valueOrEmpty(cfg.Storage))
 		fmt.Printf("  vecstore: %s\n", valueOrEmpty(cfg.VecStore))
 		fmt.Printf("  embed:    %s\n", valueOrEmpty(cfg.Embed))
+		fmt.Printf("  server:   %s\n", valueOrEmpty(cfg.Server))
 		return nil
 	},
 }
@@ -67,7 +67,8 @@ func runCmd(t *testing.T, args ...string) (stdout, stderr string, exitCode int)
 	outputFile = ""
 
 	rootCmd.SetArgs(args)
-	err := rootCmd.Execute()
+	err := Execute()
+	exitCode = ReportError(err)
 
 	wOut.Close()
 	wErr.Close()
@@ -81,7 +82,6 @@ func runCmd(t *testing.T, args ...string) (stdout, stderr string, exitCode int)
 	stdout = outBuf.String()
 	stderr = errBuf.String()
 	if err != nil {
-		exitCode = 1
 		if stderr == "" {
 			stderr = err.Error()
 		}
@@ -99,8 +99,25 @@ func resetAllFlags() {
 	listLimit = 10
 	listFrom = ""
 	listAll = false
+	listTable = tableFlags{}
 	applyFile = ""
 	runFile = ""
+	runAsync = false
+	recordPipeline = pipelineFlags{}
+	recordInput = ""
+	recordSave = ""
+	replayPipeline = pipelineFlags{}
+	replayRealtime = false
+	replaySave = ""
+	replayCompare = ""
+	selfUpdateChannel = ""
+	selfUpdateEndpoint = ""
+	selfUpdateCheck = false
+	selfUpdateForce = false
+	serveDashboard = ""
+	serveAPI = ""
+	tasksServer = ""
+	tasksListTable = tableFlags{}
 }
 
 // writeTestYAML writes a YAML file to a temp dir and returns its path.
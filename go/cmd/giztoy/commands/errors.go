@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/haivivi/giztoy/go/pkg/cli"
+	"github.com/haivivi/giztoy/go/pkg/cortex"
+)
+
+// ReportError prints err and returns the exit status for it (0 if err is
+// nil). With --format json the error goes to stdout as
+// {"error": {"code": ..., "exit_code": ..., "message": ...}}, otherwise to
+// stderr as "Error: <message>". See cli.ErrorCode for the codes.
+func ReportError(err error) int {
+	if err == nil {
+		return 0
+	}
+	err = withErrorCode(err)
+	if formatOutput == "json" {
+		return cli.ReportError(os.Stdout, err, cli.FormatJSON)
+	}
+	return cli.ReportError(os.Stderr, err, cli.FormatTable)
+}
+
+// withErrorCode attaches the error code of cortex errors; other errors are
+// classified by cli.Classify.
+func withErrorCode(err error) error {
+	var coded *cli.Error
+	if errors.As(err, &coded) {
+		return err
+	}
+	switch {
+	case errors.Is(err, cortex.ErrForbidden):
+		return cli.WithCode(cli.CodeAuth, err)
+	case errors.Is(err, cortex.ErrInvalid):
+		return cli.WithCode(cli.CodeValidation, err)
+	case errors.Is(err, cortex.ErrNotFound):
+		return cli.WithCode(cli.CodeNotFound, err)
+	}
+	return err
+}
+
+var usageErrorsOnce sync.Once
+
+// markUsageErrors makes flag and argument errors of cmd and its
+// subcommands usage errors.
+func markUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return cli.WithCode(cli.CodeUsage, err)
+	})
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(cmd *cobra.Command, a []string) error {
+			return cli.WithCode(cli.CodeUsage, args(cmd, a))
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExitCodes(t *testing.T) {
+	cleanup := setupTestEnvWithKV(t)
+	defer cleanup()
+
+	badKind := writeTestYAML(t, "bad.yaml", `kind: foo/bar
+name: x
+`)
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"unknown command", []string{"bogus"}, 2},
+		{"unknown flag", []string{"list", "creds:*", "--bogus"}, 2},
+		{"missing argument", []string{"get"}, 2},
+		{"missing -f", []string{"apply"}, 2},
+		{"unknown kind", []string{"apply", "-f", badKind}, 3},
+		{"missing file", []string{"apply", "-f", "/nonexistent.yaml"}, 8},
+		{"missing document", []string{"get", "creds:openai:nonexistent"}, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, code := runCmd(t, tt.args...)
+			if code != tt.want {
+				t.Fatalf("exit %d, want %d (stderr: %s)", code, tt.want, stderr)
+			}
+		})
+	}
+}
+
+func TestErrorJSON(t *testing.T) {
+	cleanup := setupTestEnvWithKV(t)
+	defer cleanup()
+
+	stdout, _, code := runCmd(t, "get", "creds:openai:nonexistent", "--format", "json")
+	if code != 8 {
+		t.Fatalf("exit %d, want 8", code)
+	}
+	var out struct {
+		Error struct {
+			Code     string `json:"code"`
+			ExitCode int    `json:"exit_code"`
+			Message  string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout, err)
+	}
+	if out.Error.Code != "not_found" || out.Error.ExitCode != 8 {
+		t.Fatalf("unexpected error: %+v", out.Error)
+	}
+	if out.Error.Message != "not found: creds:openai:nonexistent" {
+		t.Fatalf("unexpected message: %q", out.Error.Message)
+	}
+}
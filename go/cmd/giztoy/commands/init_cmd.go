@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+
+	"github.com/haivivi/giztoy/go/pkg/cli"
+)
+
+// scaffoldFS holds the files of a new project. Each file is a text/template
+// executed with a scaffoldData.
+//
+//go:embed scaffold
+var scaffoldFS embed.FS
+
+// scaffoldData is the data of the scaffold templates.
+type scaffoldData struct {
+	Name string // project name, also the name of its context and documents
+}
+
+// projectNamePattern matches names that are valid in a context name, a KV
+// key segment and a shell word alike.
+var projectNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// scaffoldedFile is a file written (or kept) by init.
+type scaffoldedFile struct {
+	Path   string `json:"path"`
+	Status string `json:"status"` // "created", "overwritten", "skipped"
+}
+
+var (
+	initName  string
+	initForce bool
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init [dir]",
+	Short: "Create a new voice assistant project",
+	Long: `Create a new voice assistant project in dir (default: the current
+directory):
+
+  setup.sh        creates the context and applies credentials and documents
+  models.yaml     chat model, voice (TTS) and speech recognizer (ASR)
+  gear.yaml       device config (chatgear/config)
+  playground/     the assistant agent (agent_v1/) and its tools (tool_v1/)
+  gear-test.sh    simulates a gear for one round: listen, think, speak
+  README.md       how to run it
+
+The project name defaults to the name of dir; it names the context and
+the documents. Existing files are kept unless --force is given.
+
+Examples:
+  giztoy init my-bot
+  cd my-bot && ./setup.sh && ./gear-test.sh "你好"
+  giztoy init --name toybox --force .`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		name := initName
+		if name == "" {
+			abs, err := filepath.Abs(dir)
+			if err != nil {
+				return err
+			}
+			name = strings.ToLower(filepath.Base(abs))
+		}
+		if !projectNamePattern.MatchString(name) {
+			return cli.Errorf(cli.CodeUsage, "invalid project name %q: use lowercase letters, digits, '-' and '_' (or set --name)", name)
+		}
+
+		files, err := scaffoldProject(dir, scaffoldData{Name: name}, initForce)
+		if err != nil {
+			return err
+		}
+
+		if formatOutput == "json" {
+			return printJSON(files)
+		}
+		for _, f := range files {
+			fmt.Printf("%s %s\n", f.Path, f.Status)
+		}
+		fmt.Printf("\nProject %q is ready. Next:\n", name)
+		if dir != "." {
+			fmt.Printf("  cd %s\n", dir)
+		}
+		fmt.Println("  export DASHSCOPE_API_KEY=... DOUBAO_APP_ID=... DOUBAO_TOKEN=...")
+		fmt.Println("  ./setup.sh")
+		fmt.Println(`  ./gear-test.sh "你好"`)
+		return nil
+	},
+}
+
+// scaffoldProject writes the scaffold files to dir, rendered with data.
+// Existing files are skipped unless force is set.
+func scaffoldProject(dir string, data scaffoldData, force bool) ([]scaffoldedFile, error) {
+	root, err := fs.Sub(scaffoldFS, "scaffold")
+	if err != nil {
+		return nil, err
+	}
+	var files []scaffoldedFile
+	err = fs.WalkDir(root, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		status := "created"
+		if _, err := os.Stat(dst); err == nil {
+			if !force {
+				files = append(files, scaffoldedFile{Path: dst, Status: "skipped"})
+				return nil
+			}
+			status = "overwritten"
+		}
+
+		content, err := renderScaffold(root, name, data)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return fmt.Errorf("init: %w", err)
+		}
+		perm := os.FileMode(0644)
+		if path.Ext(name) == ".sh" {
+			perm = 0755
+		}
+		if err := os.WriteFile(dst, content, perm); err != nil {
+			return fmt.Errorf("init: %w", err)
+		}
+		// WriteFile keeps the mode of an existing file
+		if err := os.Chmod(dst, perm); err != nil {
+			return fmt.Errorf("init: %w", err)
+		}
+		files = append(files, scaffoldedFile{Path: dst, Status: status})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// renderScaffold executes the scaffold template at name.
+func renderScaffold(root fs.FS, name string, data scaffoldData) ([]byte, error) {
+	text, err := fs.ReadFile(root, name)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("init: template %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("init: template %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func init() {
+	initCmd.Flags().StringVar(&initName, "name", "", "project name (default: name of dir)")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "overwrite existing files")
+	rootCmd.AddCommand(initCmd)
+}
@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runInit runs init with flags that are not carried over from other runs.
+func runInit(t *testing.T, args ...string) (stdout, stderr string, exitCode int) {
+	t.Helper()
+	initName, initForce = "", false
+	return runCmd(t, append([]string{"init"}, args...)...)
+}
+
+func TestInitCreatesProject(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "my-bot")
+	stdout, stderr, code := runInit(t, dir)
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+
+	for _, name := range []string{
+		"README.md",
+		"setup.sh",
+		"models.yaml",
+		"gear.yaml",
+		"gear-test.sh",
+		"playground/agent_v1/assistant.yaml",
+		"playground/tool_v1/get_weather.yaml",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s not created: %v", name, err)
+		}
+		if strings.Contains(string(data), "{{") {
+			t.Errorf("%s not rendered:\n%s", name, data)
+		}
+		if !strings.Contains(stdout, path+" created") {
+			t.Errorf("%s not reported, got: %s", name, stdout)
+		}
+	}
+
+	models, _ := os.ReadFile(filepath.Join(dir, "models.yaml"))
+	if !strings.Contains(string(models), "name: my-bot/chat") {
+		t.Errorf("models.yaml not named after the project:\n%s", models)
+	}
+	for _, script := range []string{"setup.sh", "gear-test.sh"} {
+		info, err := os.Stat(filepath.Join(dir, script))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm()&0100 == 0 {
+			t.Errorf("%s is not executable: %v", script, info.Mode())
+		}
+	}
+}
+
+func TestInitDocumentsApply(t *testing.T) {
+	cleanup := setupTestEnvWithKV(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	if _, stderr, code := runInit(t, "--name", "toybox", dir); code != 0 {
+		t.Fatalf("init exit %d: %s", code, stderr)
+	}
+	for _, name := range []string{"models.yaml", "gear.yaml"} {
+		stdout, stderr, code := runCmd(t, "apply", "-f", filepath.Join(dir, name))
+		if code != 0 {
+			t.Fatalf("apply %s: exit %d: %s", name, code, stderr)
+		}
+		if !strings.Contains(stdout, "created") {
+			t.Errorf("apply %s: expected 'created', got: %s", name, stdout)
+		}
+	}
+	stdout, _, code := runCmd(t, "get", "chatgear:config:toybox")
+	if code != 0 || !strings.Contains(stdout, "toybox/voice") {
+		t.Errorf("get gear config: exit %d, got: %s", code, stdout)
+	}
+}
+
+func TestInitScriptsParse(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+	dir := t.TempDir()
+	if _, stderr, code := runInit(t, "--name", "toybox", dir); code != 0 {
+		t.Fatalf("init exit %d: %s", code, stderr)
+	}
+	for _, script := range []string{"setup.sh", "gear-test.sh"} {
+		if out, err := exec.Command(sh, "-n", filepath.Join(dir, script)).CombinedOutput(); err != nil {
+			t.Errorf("%s: %v\n%s", script, err, out)
+		}
+	}
+}
+
+func TestInitKeepsExistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	gear := filepath.Join(dir, "gear.yaml")
+	if err := os.WriteFile(gear, []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, code := runInit(t, "--name", "toybox", dir)
+	if code != 0 {
+		t.Fatalf("exit %d", code)
+	}
+	if !strings.Contains(stdout, gear+" skipped") {
+		t.Errorf("expected gear.yaml skipped, got: %s", stdout)
+	}
+	if data, _ := os.ReadFile(gear); string(data) != "mine\n" {
+		t.Errorf("gear.yaml overwritten without --force:\n%s", data)
+	}
+
+	stdout, _, code = runInit(t, "--name", "toybox", "--force", dir)
+	if code != 0 {
+		t.Fatalf("exit %d", code)
+	}
+	if !strings.Contains(stdout, gear+" overwritten") {
+		t.Errorf("expected gear.yaml overwritten, got: %s", stdout)
+	}
+	if data, _ := os.ReadFile(gear); !strings.Contains(string(data), "kind: chatgear/config") {
+		t.Errorf("gear.yaml not overwritten with --force:\n%s", data)
+	}
+}
+
+func TestInitInvalidName(t *testing.T) {
+	_, stderr, code := runInit(t, "--name", "My Bot", t.TempDir())
+	if code != 2 {
+		t.Fatalf("expected exit 2, got %d", code)
+	}
+	if !strings.Contains(stderr, "invalid project name") {
+		t.Errorf("expected 'invalid project name', got: %s", stderr)
+	}
+}
@@ -12,8 +12,16 @@ var (
 	listLimit int
 	listFrom  string
 	listAll   bool
+	listTable tableFlags
 )
 
+// listRow is a row of the list table.
+type listRow struct {
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Details string `json:"details"`
+}
+
 var listCmd = &cobra.Command{
 	Use:   "list <prefix*>",
 	Short: "List resources by prefix",
@@ -24,7 +32,9 @@ Examples:
   giztoy list creds:openai:*
   giztoy list genx:generator:*
   giztoy list genx:* --limit=20
-  giztoy list creds:* --all`,
+  giztoy list creds:* --all
+  giztoy list genx:* --columns name,kind --sort -name
+  giztoy list genx:* --format tsv`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		pattern := args[0]
@@ -60,15 +70,16 @@ Examples:
 			return nil
 		}
 
-		// table format
-		w := newTabWriter()
-		fmt.Fprintln(w, "KIND\tNAME\tDETAILS")
-		for _, doc := range docs {
-			details := summarizeDoc(doc)
-			fmt.Fprintf(w, "%s\t%s\t%s\n", doc.Kind, doc.Name(), details)
+		rows := make([]listRow, len(docs))
+		for i, doc := range docs {
+			rows[i] = listRow{Kind: doc.Kind, Name: doc.Name(), Details: summarizeDoc(doc)}
+		}
+		if err := listTable.print(rows); err != nil {
+			return err
+		}
+		if formatOutput != "tsv" {
+			fmt.Printf("(%d items)\n", len(docs))
 		}
-		w.Flush()
-		fmt.Printf("(%d items)\n", len(docs))
 		return nil
 	},
 }
@@ -98,6 +109,7 @@ func init() {
 	listCmd.Flags().IntVar(&listLimit, "limit", 10, "max items to return")
 	listCmd.Flags().StringVar(&listFrom, "from", "", "start listing after this key")
 	listCmd.Flags().BoolVar(&listAll, "all", false, "list all items (ignore limit)")
+	listTable.register(listCmd)
 
 	rootCmd.AddCommand(listCmd)
 }
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"slices"
 	"strings"
 	"testing"
 )
@@ -159,6 +160,55 @@ func TestListName(t *testing.T) {
 	}
 }
 
+func TestListColumnsSort(t *testing.T) {
+	cleanup := setupTestEnvWithKV(t)
+	defer cleanup()
+	applyTestCreds(t)
+
+	stdout, stderr, code := runCmd(t, "list", "creds:*", "--all", "--columns", "name,kind", "--sort", "-name")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	lines := strings.Split(stdout, "\n")
+	if !strings.HasPrefix(lines[0], "NAME") || strings.Contains(lines[0], "DETAILS") {
+		t.Fatalf("expected NAME and KIND columns, got: %s", stdout)
+	}
+	var names []string
+	for _, line := range lines[1:] {
+		if f := strings.Fields(line); len(f) == 2 {
+			names = append(names, f[0])
+		}
+	}
+	if len(names) < 2 || !slices.IsSortedFunc(names, func(a, b string) int { return strings.Compare(b, a) }) {
+		t.Fatalf("expected names sorted descending, got: %q", names)
+	}
+}
+
+func TestListTSV(t *testing.T) {
+	cleanup := setupTestEnvWithKV(t)
+	defer cleanup()
+	applyTestCreds(t)
+
+	stdout, _, code := runCmd(t, "list", "creds:openai:*", "--all", "--format", "tsv", "--columns", "kind,name")
+	if code != 0 {
+		t.Fatalf("exit %d", code)
+	}
+	if !strings.HasPrefix(stdout, "kind\tname\n") || !strings.Contains(stdout, "creds/openai\tqwen\n") || strings.Contains(stdout, "items") {
+		t.Fatalf("expected TSV, got: %q", stdout)
+	}
+}
+
+func TestListUnknownColumn(t *testing.T) {
+	cleanup := setupTestEnvWithKV(t)
+	defer cleanup()
+	applyTestCreds(t)
+
+	_, stderr, code := runCmd(t, "list", "creds:*", "--all", "--columns", "nope")
+	if code != 2 || !strings.Contains(stderr, `unknown column "nope"`) {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // get tests
 // ---------------------------------------------------------------------------
@@ -0,0 +1,239 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/haivivi/giztoy/go/pkg/cli"
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/modelloader"
+	"github.com/haivivi/giztoy/go/pkg/genx/record"
+	"github.com/haivivi/giztoy/go/pkg/genx/transformers"
+)
+
+// pipelineAudioChunk is the size of the chunks an audio input file is read
+// in: 100ms of 16kHz 16-bit mono PCM.
+const pipelineAudioChunk = 3200
+
+// pipelineAudioTypes maps audio input file extensions to MIME types.
+var pipelineAudioTypes = map[string]string{
+	".pcm":  "audio/pcm",
+	".wav":  "audio/wav",
+	".mp3":  "audio/mp3",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+}
+
+// pipelineFlags are the flags of record and replay that select the
+// pipeline.
+type pipelineFlags struct {
+	stages string // comma-separated transformer patterns
+	models string // model config directory
+}
+
+func (f *pipelineFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.stages, "pipeline", "", "transformer patterns to chain, comma-separated (e.g. asr/sauc,tts/doubao/cancan)")
+	cmd.Flags().StringVar(&f.models, "models", "", "directory of model configs to register the transformers from")
+}
+
+// loadedModels records the model directories already registered, as the
+// transformers mux rejects registering a pattern twice.
+var loadedModels = make(map[string]bool)
+
+// build registers the model configs and returns the chained transformers.
+func (f *pipelineFlags) build() (genx.Transformer, error) {
+	if f.stages == "" {
+		return nil, cli.Errorf(cli.CodeUsage, "flag --pipeline is required")
+	}
+	if f.models != "" && !loadedModels[f.models] {
+		names, err := modelloader.LoadFromDir(f.models)
+		if err != nil {
+			return nil, fmt.Errorf("load models: %w", err)
+		}
+		loadedModels[f.models] = true
+		printVerbose("Registered %d models from %s", len(names), f.models)
+	}
+	var stages []genx.Transformer
+	for _, pattern := range strings.Split(f.stages, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		stages = append(stages, genx.WithPattern(transformers.DefaultMux, pattern))
+	}
+	return genx.Chain(stages...), nil
+}
+
+// readPipelineInput returns the chunks of an input file as a stream of
+// RoleUser chunks. A text file ("-" for stdin) gives one sub-stream per
+// non-empty line; an audio file gives one sub-stream of its audio.
+func readPipelineInput(path string) (genx.Stream, error) {
+	var chunks []*genx.MessageChunk
+	ext := strings.ToLower(filepath.Ext(path))
+	if mimeType, ok := pipelineAudioTypes[ext]; ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read input: %w", err)
+		}
+		for len(data) > 0 {
+			n := min(len(data), pipelineAudioChunk)
+			chunks = append(chunks, &genx.MessageChunk{
+				Role: genx.RoleUser,
+				Part: &genx.Blob{MIMEType: mimeType, Data: data[:n]},
+			})
+			data = data[n:]
+		}
+		eos := genx.NewEndOfStream(mimeType)
+		eos.Role = genx.RoleUser
+		chunks = append(chunks, eos)
+	} else if path == "-" || ext == ".txt" {
+		var r io.Reader = os.Stdin
+		if path != "-" {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, fmt.Errorf("read input: %w", err)
+			}
+			defer f.Close()
+			r = f
+		}
+		sc := bufio.NewScanner(r)
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" {
+				continue
+			}
+			eos := genx.NewTextEndOfStream()
+			eos.Role = genx.RoleUser
+			chunks = append(chunks, &genx.MessageChunk{Role: genx.RoleUser, Part: genx.Text(line)}, eos)
+		}
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("read input: %w", err)
+		}
+	} else {
+		return nil, cli.Errorf(cli.CodeUsage, "unsupported input %s: use a .txt file, '-' or audio (.pcm, .wav, .mp3, .ogg, .opus)", path)
+	}
+
+	s := genx.NewBufferedStream(genx.BufferedStreamConfig{Size: len(chunks) + 1})
+	for _, chunk := range chunks {
+		s.Write(context.Background(), chunk)
+	}
+	s.Close()
+	return s, nil
+}
+
+// pipelineText is the text of one sub-stream of a pipeline output.
+type pipelineText struct {
+	Role string `json:"role"`
+	Text string `json:"text"`
+}
+
+// pipelineResult summarizes the output of a pipeline run.
+type pipelineResult struct {
+	Chunks int            `json:"chunks"`
+	Text   []pipelineText `json:"text,omitempty"`
+	Audio  map[string]int `json:"audio,omitempty"` // MIME type → bytes
+	Error  string         `json:"error,omitempty"`
+}
+
+// collectPipeline reads s to the end and summarizes it. Blob data is
+// written to w if it is not nil. The error that ended s, if any, is
+// recorded in the result and returned.
+func collectPipeline(s genx.Stream, w io.Writer) (*pipelineResult, error) {
+	res := &pipelineResult{}
+	open := make(map[genx.Role]int) // role → index of its open text in res.Text
+	for {
+		chunk, err := s.Next()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			res.Error = err.Error()
+			return res, err
+		}
+		res.Chunks++
+		switch p := chunk.Part.(type) {
+		case genx.Text:
+			if p != "" {
+				i, ok := open[chunk.Role]
+				if !ok {
+					i = len(res.Text)
+					open[chunk.Role] = i
+					res.Text = append(res.Text, pipelineText{Role: string(chunk.Role)})
+				}
+				res.Text[i].Text += string(p)
+			}
+		case *genx.Blob:
+			if len(p.Data) > 0 {
+				if res.Audio == nil {
+					res.Audio = make(map[string]int)
+				}
+				res.Audio[p.MIMEType] += len(p.Data)
+				if w != nil {
+					if _, err := w.Write(p.Data); err != nil {
+						return res, fmt.Errorf("write output: %w", err)
+					}
+				}
+			}
+		}
+		if chunk.IsEndOfStream() {
+			delete(open, chunk.Role)
+		}
+		chunk.Release()
+	}
+}
+
+// runPipeline runs input through pipeline and summarizes the output, which
+// is also recorded to save if it is set. Blob output goes to the -o file.
+func runPipeline(cmd *cobra.Command, pipeline genx.Transformer, input genx.Stream, save string) (*pipelineResult, error) {
+	if save != "" {
+		rec, err := record.Create(save)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err := rec.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}()
+		pipeline = genx.Chain(pipeline, rec)
+	}
+
+	var w io.Writer
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return nil, fmt.Errorf("create output: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	out, err := pipeline.Transform(cmd.Context(), "", input)
+	if err != nil {
+		input.Close()
+		return &pipelineResult{Error: err.Error()}, err
+	}
+	defer out.Close()
+	return collectPipeline(out, w)
+}
+
+func printPipelineResult(res *pipelineResult) {
+	for _, t := range res.Text {
+		fmt.Printf("%s: %s\n", t.Role, t.Text)
+	}
+	for _, mimeType := range slices.Sorted(maps.Keys(res.Audio)) {
+		fmt.Printf("%s: %d bytes\n", mimeType, res.Audio[mimeType])
+	}
+	if res.Error != "" {
+		fmt.Printf("Error: %s\n", res.Error)
+	}
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/haivivi/giztoy/go/pkg/cli"
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/record"
+)
+
+var (
+	recordPipeline pipelineFlags
+	recordInput    string
+	recordSave     string
+)
+
+var recordCmd = &cobra.Command{
+	Use:   "record --pipeline <patterns> --input <file> <artifact>",
+	Short: "Run a pipeline and record its input for replay",
+	Long: `Run a genx pipeline on an input and record the input stream, with the
+timing of its chunks, to a genx record file (see package genx/record). Use
+'giztoy replay' to feed the same input to a changed pipeline.
+
+The pipeline chains the transformers registered for the given patterns,
+e.g. from the model configs in --models. The input is a text file (one
+user turn per line, '-' for stdin) or an audio file (.pcm, .wav, .mp3,
+.ogg, .opus). The artifact is JSONL if its name ends with ".jsonl" and
+binary otherwise.
+
+The output text is printed and audio is written to the -o file. Use
+--save to also record the output, for 'giztoy replay --compare'.
+
+Examples:
+  giztoy record --models ./models --pipeline asr/sauc,tts/doubao/cancan --input question.pcm q.genxr
+  giztoy record --models ./models --pipeline tts/minimax/shaonv --input lines.txt --save out.genxr lines.genxr`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if recordInput == "" {
+			return cli.Errorf(cli.CodeUsage, "flag --input is required")
+		}
+		pipeline, err := recordPipeline.build()
+		if err != nil {
+			return err
+		}
+		input, err := readPipelineInput(recordInput)
+		if err != nil {
+			return err
+		}
+
+		rec, err := record.Create(args[0])
+		if err != nil {
+			input.Close()
+			return err
+		}
+		res, runErr := runPipeline(cmd, genx.Chain(rec, pipeline), input, recordSave)
+		if err := rec.Close(); err != nil {
+			return err
+		}
+		printVerbose("Recorded the input to %s", args[0])
+		if res == nil {
+			return runErr
+		}
+
+		if formatOutput == "json" {
+			if err := printJSON(res); err != nil {
+				return err
+			}
+			return runErr
+		}
+		printPipelineResult(res)
+		if recordSave != "" {
+			fmt.Printf("Output recorded to %s\n", recordSave)
+		}
+		return runErr
+	},
+}
+
+func init() {
+	recordPipeline.register(recordCmd)
+	recordCmd.Flags().StringVar(&recordInput, "input", "", "input file: text (one turn per line, '-' for stdin) or audio")
+	recordCmd.Flags().StringVar(&recordSave, "save", "", "also record the pipeline output to this file")
+	rootCmd.AddCommand(recordCmd)
+}
@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/record"
+	"github.com/haivivi/giztoy/go/pkg/genx/transformers"
+)
+
+func init() {
+	// test/upper answers user text in upper case; test/reverse reversed;
+	// test/fail fails on any text
+	answer := func(fn func(string) string) genx.TransformFunc {
+		return func(chunk *genx.MessageChunk) (*genx.MessageChunk, error) {
+			text, ok := chunk.Part.(genx.Text)
+			if !ok || chunk.Role != genx.RoleUser {
+				return chunk, nil
+			}
+			return &genx.MessageChunk{Role: genx.RoleModel, Part: genx.Text(fn(string(text)))}, nil
+		}
+	}
+	transformers.Handle("test/upper", answer(strings.ToUpper))
+	transformers.Handle("test/reverse", answer(func(s string) string {
+		r := []rune(s)
+		for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+			r[i], r[j] = r[j], r[i]
+		}
+		return string(r)
+	}))
+	transformers.Handle("test/fail", genx.TransformFunc(func(chunk *genx.MessageChunk) (*genx.MessageChunk, error) {
+		if _, ok := chunk.Part.(genx.Text); ok && !chunk.IsEndOfStream() {
+			return nil, errors.New("model unavailable")
+		}
+		return chunk, nil
+	}))
+}
+
+// readRecord returns the chunks of a genx record file.
+func readRecord(t *testing.T, path string) []*genx.MessageChunk {
+	t.Helper()
+	s, err := record.Replay(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	var chunks []*genx.MessageChunk
+	for {
+		chunk, err := s.Next()
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			t.Fatalf("replay %s: %v", path, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+}
+
+func TestRecordMissingFlags(t *testing.T) {
+	artifact := filepath.Join(t.TempDir(), "in.genxr")
+	input := writeTestYAML(t, "in.txt", "hi\n")
+	if _, _, code := runCmd(t, "record", "--pipeline", "test/upper", "--input", "", artifact); code != 2 {
+		t.Errorf("without --input: exit %d, want 2", code)
+	}
+	if _, _, code := runCmd(t, "record", "--pipeline", "", "--input", input, artifact); code != 2 {
+		t.Errorf("without --pipeline: exit %d, want 2", code)
+	}
+	if _, _, code := runCmd(t, "record", "--pipeline", "test/upper", "--input", "in.doc", artifact); code != 2 {
+		t.Errorf("unsupported input: exit %d, want 2", code)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	input := writeTestYAML(t, "in.txt", "hello there\n\nhow are you\n")
+	artifact := filepath.Join(dir, "in.jsonl")
+	output := filepath.Join(dir, "out.genxr")
+
+	stdout, stderr, code := runCmd(t, "record", "--pipeline", "test/upper", "--input", input, "--save", output, artifact)
+	if code != 0 {
+		t.Fatalf("record: exit %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "model: HELLO THERE\nmodel: HOW ARE YOU\n") {
+		t.Errorf("record output: %s", stdout)
+	}
+
+	// The artifact holds the input: two user turns, each ended by EoS
+	var turns []string
+	for _, chunk := range readRecord(t, artifact) {
+		if chunk.Role != genx.RoleUser {
+			t.Errorf("recorded chunk of role %q", chunk.Role)
+		}
+		if text := chunk.Part.(genx.Text); !chunk.IsEndOfStream() {
+			turns = append(turns, string(text))
+		}
+	}
+	if strings.Join(turns, "|") != "hello there|how are you" {
+		t.Errorf("recorded turns = %q", turns)
+	}
+	if got := len(readRecord(t, output)); got != 4 {
+		t.Errorf("recorded output has %d chunks, want 4", got)
+	}
+
+	// Replay the same input to another pipeline and compare
+	stdout, stderr, code = runCmd(t, "replay", artifact, "--pipeline", "test/reverse", "--compare", output)
+	if code != 0 {
+		t.Fatalf("replay: exit %d: %s", code, stderr)
+	}
+	want := "--- recorded\nmodel: HELLO THERE\nmodel: HOW ARE YOU\n--- replayed\nmodel: ereht olleh\nmodel: uoy era woh\n"
+	if stdout != want {
+		t.Errorf("replay output:\n%s\nwant:\n%s", stdout, want)
+	}
+}
+
+func TestRecordAudio(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.pcm")
+	pcm := bytes.Repeat([]byte{1, 2}, 4000)
+	if err := os.WriteFile(input, pcm, 0644); err != nil {
+		t.Fatal(err)
+	}
+	artifact := filepath.Join(dir, "in.genxr")
+	audio := filepath.Join(dir, "out.pcm")
+
+	stdout, stderr, code := runCmd(t, "record", "--pipeline", "test/upper", "--input", input, "-o", audio, artifact)
+	if code != 0 {
+		t.Fatalf("record: exit %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "audio/pcm: 8000 bytes") {
+		t.Errorf("record output: %s", stdout)
+	}
+	if got, _ := os.ReadFile(audio); !bytes.Equal(got, pcm) {
+		t.Errorf("-o file has %d bytes, want the %d input bytes passed through", len(got), len(pcm))
+	}
+
+	// 100ms chunks and the EoS marker
+	chunks := readRecord(t, artifact)
+	if len(chunks) != 4 || !chunks[3].IsEndOfStream() {
+		t.Fatalf("recorded %d chunks, want 3 and EoS", len(chunks))
+	}
+	var got []byte
+	for _, chunk := range chunks {
+		got = append(got, chunk.Part.(*genx.Blob).Data...)
+	}
+	if !bytes.Equal(got, pcm) {
+		t.Error("recorded audio differs from the input")
+	}
+}
+
+func TestReplayPipelineError(t *testing.T) {
+	dir := t.TempDir()
+	artifact := filepath.Join(dir, "in.genxr")
+	input := writeTestYAML(t, "in.txt", "hi\n")
+	if _, stderr, code := runCmd(t, "record", "--pipeline", "test/upper", "--input", input, artifact); code != 0 {
+		t.Fatalf("record: exit %d: %s", code, stderr)
+	}
+
+	stdout, stderr, code := runCmd(t, "replay", artifact, "--pipeline", "test/fail", "--format", "json")
+	if code == 0 {
+		t.Fatal("expected the pipeline error")
+	}
+	if !strings.Contains(stdout, `"error": "model unavailable"`) || !strings.Contains(stderr, "model unavailable") {
+		t.Errorf("stdout: %s\nstderr: %s", stdout, stderr)
+	}
+
+	if _, _, code := runCmd(t, "replay", artifact, "--pipeline", "test/missing"); code == 0 {
+		t.Error("expected an error for an unregistered pattern")
+	}
+	if _, _, code := runCmd(t, "replay", filepath.Join(dir, "missing.genxr"), "--pipeline", "test/upper"); code == 0 {
+		t.Error("expected an error for a missing artifact")
+	}
+}
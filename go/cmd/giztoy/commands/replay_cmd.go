@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/haivivi/giztoy/go/pkg/genx/record"
+)
+
+var (
+	replayPipeline pipelineFlags
+	replayRealtime bool
+	replaySave     string
+	replayCompare  string
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <artifact> --pipeline <patterns>",
+	Short: "Feed a recorded input to a pipeline",
+	Long: `Replay the input recorded by 'giztoy record' (or any genx record file)
+into a pipeline, so a changed prompt, model or voice can be compared on
+identical input.
+
+With --realtime the chunks are fed at their recorded pace, as in the live
+session; otherwise as fast as the pipeline reads them. Use --compare with
+an output recorded by 'record --save' or 'replay --save' to print the
+recorded and the new output one after the other.
+
+Examples:
+  giztoy replay q.genxr --models ./models --pipeline asr/sauc,tts/doubao/cancan
+  giztoy replay q.genxr --models ./models-v2 --pipeline asr/sauc,tts/doubao/cancan --compare out.genxr
+  giztoy replay q.genxr --pipeline asr/sauc --realtime --save out-v2.genxr --format json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pipeline, err := replayPipeline.build()
+		if err != nil {
+			return err
+		}
+
+		var recorded *pipelineResult
+		if replayCompare != "" {
+			s, err := record.Replay(replayCompare, false)
+			if err != nil {
+				return err
+			}
+			recorded, _ = collectPipeline(s, nil)
+			s.Close()
+		}
+
+		input, err := record.Replay(args[0], replayRealtime)
+		if err != nil {
+			return err
+		}
+		replayed, runErr := runPipeline(cmd, pipeline, input, replaySave)
+		if replayed == nil {
+			return runErr
+		}
+		if replaySave != "" {
+			printVerbose("Saved the output to %s", replaySave)
+		}
+
+		if formatOutput == "json" {
+			v := any(replayed)
+			if recorded != nil {
+				v = map[string]any{"recorded": recorded, "replayed": replayed}
+			}
+			if err := printJSON(v); err != nil {
+				return err
+			}
+			return runErr
+		}
+
+		if recorded != nil {
+			fmt.Println("--- recorded")
+			printPipelineResult(recorded)
+			fmt.Println("--- replayed")
+		}
+		printPipelineResult(replayed)
+		return runErr
+	},
+}
+
+func init() {
+	replayPipeline.register(replayCmd)
+	replayCmd.Flags().BoolVar(&replayRealtime, "realtime", false, "feed the chunks at their recorded pace")
+	replayCmd.Flags().StringVar(&replaySave, "save", "", "record the pipeline output to this file")
+	replayCmd.Flags().StringVar(&replayCompare, "compare", "", "a recorded output to print before the new one")
+	rootCmd.AddCommand(replayCmd)
+}
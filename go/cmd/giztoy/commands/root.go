@@ -5,10 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"text/tabwriter"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/haivivi/giztoy/go/pkg/cli"
 	"github.com/haivivi/giztoy/go/pkg/cortex"
 	"github.com/haivivi/giztoy/go/pkg/kv"
 )
@@ -25,36 +26,61 @@ var rootCmd = &cobra.Command{
 	Long: `giztoy — A unified command line interface for AI services.
 
 Commands:
+  init      Create a new voice assistant project
   ctx       Context configuration management (file-based bootstrap)
   apply     Declare and write resources (creds, genx configs)
   list      List resources by prefix
   get       Get a resource by full name
   delete    Delete a resource by full name
   run       Execute a task (TTS, chat, ASR, etc.)
+  record    Run a pipeline and record its input for replay
+  replay    Feed a recorded input to a changed pipeline
+  serve     Run the server (web dashboard, task API)
+  tasks     Inspect and cancel long-running tasks of the server
+  self-update  Update the binary to the latest release (stable or beta)
   version   Version information
 
 Resource kinds:
   creds/openai, creds/genai, creds/minimax, creds/doubaospeech, creds/dashscope
-  genx/generator, genx/tts, genx/asr, genx/realtime, genx/segmentor, genx/profiler
+  genx/generator, genx/tts, genx/asr, genx/realtime, genx/segmentor, genx/profiler,
+  genx/match
+  chatgear/config, chatgear/speaker
+  memory/prompt
 
 Examples:
+  giztoy init my-bot
   giztoy ctx add dev && giztoy ctx use dev
   giztoy ctx config set kv badger:///tmp/dev
   giztoy apply -f setup.yaml
   giztoy list creds:*
   giztoy get creds:openai:qwen
-  giztoy run -f task.yaml`,
+  giztoy run -f task.yaml
+
+Exit status:
+  0 success, 1 unknown, 2 usage, 3 validation, 4 auth, 5 quota,
+  6 network, 7 backend, 8 not_found
+  With --format json, errors are printed as
+  {"error": {"code": "...", "exit_code": N, "message": "..."}}`,
 	SilenceUsage:  true,
 	SilenceErrors: true,
 }
 
+// Execute runs the command line. Pass its error to ReportError for the
+// message and exit status.
 func Execute() error {
-	return rootCmd.Execute()
+	usageErrorsOnce.Do(func() { markUsageErrors(rootCmd) })
+	cmd, err := rootCmd.ExecuteC()
+	if err != nil && cmd == rootCmd {
+		// The root command runs nothing itself: its errors are unknown
+		// commands
+		return cli.WithCode(cli.CodeUsage, err)
+	}
+	return err
 }
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
-	rootCmd.PersistentFlags().StringVar(&formatOutput, "format", "table", "output format: table, json, yaml, name")
+	rootCmd.PersistentFlags().StringVar(&formatOutput, "format", "table", "output format: table, tsv, json, yaml, name")
 	rootCmd.PersistentFlags().StringVarP(&outputFile, "o", "o", "", "output file path")
 }
 
@@ -86,6 +112,32 @@ func printVerbose(format string, args ...any) {
 	}
 }
 
-func newTabWriter() *tabwriter.Writer {
-	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+// tableFlags are the --columns and --sort flags of list commands.
+type tableFlags struct {
+	columns string // comma-separated column names
+	sort    string // column to sort by, "-" prefix for descending
+}
+
+func (f *tableFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.columns, "columns", "", "columns to show, comma-separated (default: all)")
+	cmd.Flags().StringVar(&f.sort, "sort", "", "column to sort by, prefix with - for descending")
+}
+
+// print writes rows as a table, or as TSV with --format tsv.
+func (f *tableFlags) print(rows any) error {
+	format := cli.FormatTable
+	if formatOutput == string(cli.FormatTSV) {
+		format = cli.FormatTSV
+	}
+	var columns []string
+	for _, col := range strings.Split(f.columns, ",") {
+		if col = strings.TrimSpace(col); col != "" {
+			columns = append(columns, col)
+		}
+	}
+	err := cli.Output(rows, cli.OutputOptions{Format: format, Columns: columns, Sort: f.sort})
+	if err != nil {
+		return cli.WithCode(cli.CodeUsage, err)
+	}
+	return nil
 }
@@ -5,10 +5,14 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/haivivi/giztoy/go/pkg/cli"
 	"github.com/haivivi/giztoy/go/pkg/cortex"
 )
 
-var runFile string
+var (
+	runFile  string
+	runAsync bool
+)
 
 var runTaskCmd = &cobra.Command{
 	Use:   "run -f <file>",
@@ -21,6 +25,7 @@ Run kinds (genx):
   genx/generator    LLM text generation
   genx/tts          Text-to-speech synthesis
   genx/asr          Automatic speech recognition
+  genx/match        Check a match rule set: diagnostics and test matrix
 
 Run kinds (direct SDK):
   minimax/text/chat, minimax/speech/synthesize, ...
@@ -32,13 +37,23 @@ Run kinds (direct SDK):
 Run kinds (memory):
   memory/create, memory/recall, memory/search, memory/add, ...
 
+Run kinds (knowledge base):
+  kb/ingest, kb/search, kb/list, kb/delete
+
+Long-running jobs (video generation, voice clone training) can be
+submitted with --async to a server running "giztoy serve --api <addr>":
+the task ID is printed at once and the job is followed with "giztoy
+tasks". The minimax video kinds wait for the video when the task sets
+"wait: true".
+
 Examples:
   giztoy run -f testdata/run/genx/generator-chat.yaml
   giztoy run -f testdata/run/minimax/text-chat.yaml --format json
-  giztoy run -f testdata/run/minimax/speech-synthesize.yaml -o output.mp3`,
+  giztoy run -f testdata/run/minimax/speech-synthesize.yaml -o output.mp3
+  giztoy run -f video.yaml --async`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if runFile == "" {
-			return fmt.Errorf("flag -f is required")
+			return cli.Errorf(cli.CodeUsage, "flag -f is required")
 		}
 
 		docs, err := cortex.ParseDocumentsFromFile(runFile)
@@ -56,6 +71,18 @@ Examples:
 			task.Fields["output"] = outputFile
 		}
 
+		if runAsync {
+			info, err := newTaskClient().Start(cmd.Context(), task)
+			if err != nil {
+				return err
+			}
+			if formatOutput == "json" {
+				return printJSON(info)
+			}
+			fmt.Printf("Task ID: %s\n", info.ID)
+			return nil
+		}
+
 		c, err := openCortex(cmd.Context())
 		if err != nil {
 			return err
@@ -72,23 +99,30 @@ Examples:
 		}
 
 		// Default: human-readable
-		if result.Text != "" {
-			fmt.Println(result.Text)
-		}
-		if result.AudioFile != "" {
-			fmt.Printf("Audio saved to: %s (%d bytes)\n", result.AudioFile, result.AudioSize)
-		}
-		if result.TaskID != "" {
-			fmt.Printf("Task ID: %s\n", result.TaskID)
-		}
-		if result.Status != "" && result.Text == "" && result.AudioFile == "" && result.TaskID == "" {
-			fmt.Printf("Status: %s\n", result.Status)
-		}
+		printRunResult(result)
 		return nil
 	},
 }
 
+// printRunResult prints a run result in human-readable form.
+func printRunResult(result *cortex.RunResult) {
+	if result.Text != "" {
+		fmt.Println(result.Text)
+	}
+	if result.AudioFile != "" {
+		fmt.Printf("Audio saved to: %s (%d bytes)\n", result.AudioFile, result.AudioSize)
+	}
+	if result.TaskID != "" {
+		fmt.Printf("Task ID: %s\n", result.TaskID)
+	}
+	if result.Status != "" && result.Text == "" && result.AudioFile == "" && result.TaskID == "" {
+		fmt.Printf("Status: %s\n", result.Status)
+	}
+}
+
 func init() {
 	runTaskCmd.Flags().StringVarP(&runFile, "file", "f", "", "task YAML file (use '-' for stdin)")
+	runTaskCmd.Flags().BoolVar(&runAsync, "async", false, "start the task on the serve daemon and return its ID")
+	runTaskCmd.Flags().StringVar(&tasksServer, "server", "", "task API address of the serve daemon (with --async)")
 	rootCmd.AddCommand(runTaskCmd)
 }
@@ -0,0 +1,48 @@
+#!/bin/sh
+# Simulates a gear of {{.Name}} for one round: recognizes the question (text,
+# or speech with --audio), asks the chat model as the assistant and speaks
+# the reply into out/reply.mp3.
+#
+#   ./gear-test.sh "你好，你叫什么名字？"
+#   ./gear-test.sh --audio question.mp3
+set -eu
+out="$(dirname "$0")/out"
+
+PERSONA='你是{{.Name}}，一个陪伴孩子的语音助手。回答简短、友好，适合朗读，不使用表情符号和列表。'
+
+# indent prints stdin indented for a YAML block scalar
+indent() { sed 's/^/      /'; }
+
+if [ "${1:-}" = "--audio" ]; then
+	question=$(giztoy run -f - <<TASK
+kind: genx/asr
+name: {{.Name}}/asr
+audio: $2
+TASK
+)
+	echo "heard: $question"
+else
+	question=${1:-你好！}
+fi
+
+reply=$(giztoy run -f - <<TASK
+kind: genx/generator
+name: {{.Name}}/chat
+messages:
+  - role: system
+    content: |
+$(printf '%s\n' "$PERSONA" | indent)
+  - role: user
+    content: |
+$(printf '%s\n' "$question" | indent)
+TASK
+)
+echo "reply: $reply"
+
+mkdir -p "$out"
+giztoy run -o "$out/reply.mp3" -f - <<TASK
+kind: genx/tts
+name: {{.Name}}/voice
+text: |
+$(printf '%s\n' "$reply" | sed 's/^/  /')
+TASK
@@ -0,0 +1,29 @@
+#!/bin/sh
+# Creates the {{.Name}} context, with its data in ./data, and applies the
+# credentials and documents of the project. Safe to run again.
+set -eu
+cd "$(dirname "$0")"
+
+: "${DASHSCOPE_API_KEY:?set DASHSCOPE_API_KEY}"
+: "${DOUBAO_APP_ID:?set DOUBAO_APP_ID}"
+: "${DOUBAO_TOKEN:?set DOUBAO_TOKEN}"
+
+giztoy ctx show {{.Name}} >/dev/null 2>&1 || giztoy ctx add {{.Name}}
+giztoy ctx use {{.Name}}
+giztoy ctx config set kv "badger://$PWD/data/kv"
+
+# Credentials come from the environment and are never written to a file
+giztoy apply -f - <<CREDS
+kind: creds/openai
+name: {{.Name}}
+api_key: "$DASHSCOPE_API_KEY"
+base_url: https://dashscope.aliyuncs.com/compatible-mode/v1
+---
+kind: creds/doubaospeech
+name: {{.Name}}
+app_id: "$DOUBAO_APP_ID"
+token: "$DOUBAO_TOKEN"
+CREDS
+
+giztoy apply -f models.yaml
+giztoy apply -f gear.yaml
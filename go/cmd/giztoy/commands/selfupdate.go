@@ -0,0 +1,481 @@
+package commands
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/cobra"
+)
+
+// Build-time settings, set with -ldflags "-X <pkg>.Name=value".
+var (
+	// UpdateEndpoint is the default release endpoint for self-update.
+	UpdateEndpoint = ""
+
+	// UpdatePublicKey is the base64 Ed25519 public key release binaries
+	// are signed with. self-update refuses to run without it.
+	UpdatePublicKey = ""
+)
+
+// Release channels.
+const (
+	channelStable = "stable"
+	channelBeta   = "beta"
+)
+
+// maxReleaseSize bounds the size of a downloaded binary.
+const maxReleaseSize = 512 << 20
+
+// executablePath returns the path of the running binary. Tests replace it.
+var executablePath = func() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
+
+// releaseManifest is served at <endpoint>/<channel>/latest.json.
+type releaseManifest struct {
+	Version string                  `json:"version"`
+	Notes   string                  `json:"notes,omitempty"`
+	Assets  map[string]releaseAsset `json:"assets"` // "<os>/<arch>" → asset
+}
+
+// releaseAsset is a binary for one platform. Signature is the base64
+// Ed25519 signature of releaseMessage for the binary, which binds it to the
+// version, channel and platform it is served for.
+type releaseAsset struct {
+	URL       string `json:"url"`
+	SHA256    string `json:"sha256"`
+	Signature string `json:"signature"`
+}
+
+// updateSettings is persisted in update.yaml in the config directory.
+type updateSettings struct {
+	Channel string `yaml:"channel,omitempty"`
+}
+
+func updateSettingsPath() (string, error) {
+	s, err := openStore()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Dir(), "update.yaml"), nil
+}
+
+func loadUpdateSettings() (*updateSettings, error) {
+	path, err := updateSettingsPath()
+	if err != nil {
+		return nil, err
+	}
+	var st updateSettings
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &st, nil
+}
+
+func saveUpdateSettings(st *updateSettings) error {
+	path, err := updateSettingsPath()
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func validateChannel(ch string) error {
+	switch ch {
+	case channelStable, channelBeta:
+		return nil
+	}
+	return fmt.Errorf("unknown channel %q (want %s or %s)", ch, channelStable, channelBeta)
+}
+
+var (
+	selfUpdateChannel  string
+	selfUpdateEndpoint string
+	selfUpdateCheck    bool
+	selfUpdateForce    bool
+)
+
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Update giztoy to the latest release",
+	Long: `Check the release endpoint for the latest release on a channel, verify
+its checksum and signature, and replace the running binary.
+
+The channel defaults to the one saved with 'giztoy self-update channel',
+or stable. The endpoint comes from --endpoint, the GIZTOY_UPDATE_ENDPOINT
+environment variable or the build, in that order. Releases are served as
+<endpoint>/<channel>/latest.json.
+
+Examples:
+  giztoy self-update --check
+  giztoy self-update --channel beta
+  giztoy self-update channel beta`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := loadUpdateSettings()
+		if err != nil {
+			return err
+		}
+		channel := firstNonEmpty(selfUpdateChannel, st.Channel, channelStable)
+		if err := validateChannel(channel); err != nil {
+			return err
+		}
+		endpoint := firstNonEmpty(selfUpdateEndpoint, os.Getenv("GIZTOY_UPDATE_ENDPOINT"), UpdateEndpoint)
+		if endpoint == "" {
+			return errors.New("no release endpoint configured; pass --endpoint or set GIZTOY_UPDATE_ENDPOINT")
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), 10*time.Minute)
+		defer cancel()
+		manifestURL, m, err := fetchManifest(ctx, endpoint, channel)
+		if err != nil {
+			return err
+		}
+		newer := compareVersions(m.Version, Version) > 0
+		result := map[string]any{
+			"channel": channel,
+			"current": Version,
+			"latest":  m.Version,
+			"newer":   newer,
+		}
+
+		if selfUpdateCheck || (!newer && !selfUpdateForce) {
+			if formatOutput == "json" {
+				return printJSON(result)
+			}
+			if newer {
+				fmt.Printf("giztoy %s is available on %s (current %s).\n", m.Version, channel, Version)
+				if m.Notes != "" {
+					fmt.Println(m.Notes)
+				}
+			} else {
+				fmt.Printf("giztoy %s is up to date (%s: %s).\n", Version, channel, m.Version)
+			}
+			return nil
+		}
+
+		asset, ok := m.Assets[runtime.GOOS+"/"+runtime.GOARCH]
+		if !ok {
+			return fmt.Errorf("release %s has no binary for %s/%s", m.Version, runtime.GOOS, runtime.GOARCH)
+		}
+		printVerbose("downloading %s", asset.URL)
+		bin, err := downloadAsset(ctx, manifestURL, asset)
+		if err != nil {
+			return err
+		}
+		if err := verifyAsset(bin, channel, m.Version, asset); err != nil {
+			return err
+		}
+		exe, err := executablePath()
+		if err != nil {
+			return fmt.Errorf("locate executable: %w", err)
+		}
+		if err := replaceExecutable(exe, bin); err != nil {
+			return err
+		}
+
+		result["updated"] = true
+		if formatOutput == "json" {
+			return printJSON(result)
+		}
+		fmt.Printf("Updated giztoy %s -> %s (%s).\n", Version, m.Version, channel)
+		return nil
+	},
+}
+
+var selfUpdateChannelCmd = &cobra.Command{
+	Use:   "channel [stable|beta]",
+	Short: "Show or set the release channel",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := loadUpdateSettings()
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			channel := firstNonEmpty(st.Channel, channelStable)
+			if formatOutput == "json" {
+				return printJSON(map[string]any{"channel": channel})
+			}
+			fmt.Println(channel)
+			return nil
+		}
+		if err := validateChannel(args[0]); err != nil {
+			return err
+		}
+		st.Channel = args[0]
+		if err := saveUpdateSettings(st); err != nil {
+			return err
+		}
+		if formatOutput == "json" {
+			return printJSON(map[string]any{"channel": st.Channel, "status": "set"})
+		}
+		fmt.Printf("Release channel set to %q.\n", st.Channel)
+		return nil
+	},
+}
+
+func init() {
+	selfUpdateCmd.Flags().StringVar(&selfUpdateChannel, "channel", "", "release channel: stable, beta (default: saved channel or stable)")
+	selfUpdateCmd.Flags().StringVar(&selfUpdateEndpoint, "endpoint", "", "release endpoint URL")
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateCheck, "check", false, "only check for a newer release")
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateForce, "force", false, "install the latest release even if it is not newer")
+	selfUpdateCmd.AddCommand(selfUpdateChannelCmd)
+	rootCmd.AddCommand(selfUpdateCmd)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func fetchManifest(ctx context.Context, endpoint, channel string) (*url.URL, *releaseManifest, error) {
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/") + "/" + channel + "/latest.json")
+	if err != nil {
+		return nil, nil, fmt.Errorf("release endpoint: %w", err)
+	}
+	data, err := httpGet(ctx, u.String(), 1<<20)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch release manifest: %w", err)
+	}
+	var m releaseManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, nil, fmt.Errorf("parse release manifest: %w", err)
+	}
+	if m.Version == "" {
+		return nil, nil, errors.New("release manifest has no version")
+	}
+	return u, &m, nil
+}
+
+// downloadAsset downloads the asset, resolving its URL against the
+// manifest URL.
+func downloadAsset(ctx context.Context, base *url.URL, asset releaseAsset) ([]byte, error) {
+	ref, err := url.Parse(asset.URL)
+	if err != nil {
+		return nil, fmt.Errorf("asset url: %w", err)
+	}
+	data, err := httpGet(ctx, base.ResolveReference(ref).String(), maxReleaseSize)
+	if err != nil {
+		return nil, fmt.Errorf("download release: %w", err)
+	}
+	return data, nil
+}
+
+func httpGet(ctx context.Context, rawURL string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "giztoy/"+Version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("GET %s: response exceeds %d bytes", rawURL, limit)
+	}
+	return data, nil
+}
+
+// releaseMessage returns the message signed for a release binary:
+//
+//	giztoy-release
+//	<channel>
+//	<version>
+//	<os>/<arch>
+//	<hex sha256 of the binary>
+//
+// Signing the binary alone would let whoever serves the manifest pair a
+// signed binary with another version (e.g. an older one, rolling back) or
+// another channel.
+func releaseMessage(channel, version, platform string, sum [sha256.Size]byte) []byte {
+	return fmt.Appendf(nil, "giztoy-release\n%s\n%s\n%s\n%x\n", channel, version, platform, sum)
+}
+
+// verifyAsset checks the checksum and the signature of a binary downloaded
+// for the release version on channel, for this platform, against the
+// build's public key.
+func verifyAsset(bin []byte, channel, version string, asset releaseAsset) error {
+	if UpdatePublicKey == "" {
+		return errors.New("this build has no update public key; cannot verify releases")
+	}
+	pub, err := base64.StdEncoding.DecodeString(UpdatePublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return errors.New("invalid update public key in this build")
+	}
+	sum := sha256.Sum256(bin)
+	if want, err := hex.DecodeString(asset.SHA256); err != nil || !bytes.Equal(sum[:], want) {
+		return errors.New("release checksum mismatch")
+	}
+	sig, err := base64.StdEncoding.DecodeString(asset.Signature)
+	msg := releaseMessage(channel, version, runtime.GOOS+"/"+runtime.GOARCH, sum)
+	if err != nil || !ed25519.Verify(pub, msg, sig) {
+		return errors.New("release signature verification failed")
+	}
+	return nil
+}
+
+// replaceExecutable atomically replaces the binary at exe with bin, keeping
+// its permissions. The new binary is written next to exe so the final
+// rename stays on one file system.
+func replaceExecutable(exe string, bin []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return fmt.Errorf("stat executable: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".giztoy-update-*")
+	if err != nil {
+		return fmt.Errorf("write update: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(bin); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write update: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write update: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0o111); err != nil {
+		return fmt.Errorf("write update: %w", err)
+	}
+
+	// Move the old binary aside first: Windows cannot overwrite a running
+	// executable, but it can rename it.
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		return fmt.Errorf("replace executable: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		if rerr := os.Rename(old, exe); rerr != nil {
+			return fmt.Errorf("replace executable: %w (restore failed: %v)", err, rerr)
+		}
+		return fmt.Errorf("replace executable: %w", err)
+	}
+	os.Remove(old)
+	return nil
+}
+
+// compareVersions compares versions of the form v1.2.3 or 1.2.3-beta.4,
+// returning -1, 0 or +1. A release is newer than its pre-releases, and any
+// version is newer than "dev" or an unparsable one.
+func compareVersions(a, b string) int {
+	pa, okA := parseVersion(a)
+	pb, okB := parseVersion(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	for i := range 3 {
+		if c := cmp.Compare(pa.nums[i], pb.nums[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case pa.pre == pb.pre:
+		return 0
+	case pa.pre == "":
+		return 1
+	case pb.pre == "":
+		return -1
+	}
+	return comparePrerelease(pa.pre, pb.pre)
+}
+
+type version struct {
+	nums [3]int
+	pre  string
+}
+
+func parseVersion(s string) (version, bool) {
+	var v version
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		s, v.pre = s[:i], s[i+1:]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return v, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v.nums[i] = n
+	}
+	return v, true
+}
+
+// comparePrerelease compares dot-separated pre-release identifiers:
+// numeric ones numerically, others lexically.
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		na, errA := strconv.Atoi(as[i])
+		nb, errB := strconv.Atoi(bs[i])
+		var c int
+		if errA == nil && errB == nil {
+			c = cmp.Compare(na, nb)
+		} else {
+			c = strings.Compare(as[i], bs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
@@ -0,0 +1,222 @@
+package commands
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// releaseServer serves a signed release of bin for the current platform on
+// the given channel.
+func releaseServer(t *testing.T, channel, version string, bin []byte, priv ed25519.PrivateKey) *httptest.Server {
+	t.Helper()
+	return serveRelease(t, channel, version, bin, signRelease(priv, channel, version, bin))
+}
+
+// signRelease signs bin as the release version on channel for the current
+// platform.
+func signRelease(priv ed25519.PrivateKey, channel, version string, bin []byte) string {
+	msg := releaseMessage(channel, version, runtime.GOOS+"/"+runtime.GOARCH, sha256.Sum256(bin))
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(priv, msg))
+}
+
+// serveRelease serves bin with signature as the release version on
+// channel for the current platform.
+func serveRelease(t *testing.T, channel, version string, bin []byte, signature string) *httptest.Server {
+	t.Helper()
+	sum := sha256.Sum256(bin)
+	m := releaseManifest{
+		Version: version,
+		Assets: map[string]releaseAsset{
+			runtime.GOOS + "/" + runtime.GOARCH: {
+				URL:       "giztoy.bin",
+				SHA256:    hex.EncodeToString(sum[:]),
+				Signature: signature,
+			},
+		},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+channel+"/latest.json", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(m)
+	})
+	mux.HandleFunc("/"+channel+"/giztoy.bin", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bin)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// setupSelfUpdate installs a fake executable and a fresh signing key.
+func setupSelfUpdate(t *testing.T, current string) (exe string, priv ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe = filepath.Join(t.TempDir(), "giztoy")
+	if err := os.WriteFile(exe, []byte("old binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	oldKey, oldVersion, oldExe := UpdatePublicKey, Version, executablePath
+	UpdatePublicKey = base64.StdEncoding.EncodeToString(pub)
+	Version = current
+	executablePath = func() (string, error) { return exe, nil }
+	t.Cleanup(func() {
+		UpdatePublicKey, Version, executablePath = oldKey, oldVersion, oldExe
+	})
+	return exe, priv
+}
+
+func TestSelfUpdate(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	exe, priv := setupSelfUpdate(t, "v1.0.0")
+	srv := releaseServer(t, "stable", "v1.1.0", []byte("new binary"), priv)
+
+	stdout, stderr, code := runCmd(t, "self-update", "--endpoint", srv.URL)
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "v1.0.0 -> v1.1.0") {
+		t.Errorf("stdout = %q", stdout)
+	}
+	data, _ := os.ReadFile(exe)
+	if string(data) != "new binary" {
+		t.Errorf("executable = %q, want replaced", data)
+	}
+	if info, _ := os.Stat(exe); info.Mode().Perm()&0o100 == 0 {
+		t.Errorf("executable mode = %v", info.Mode())
+	}
+	if _, err := os.Stat(exe + ".old"); !os.IsNotExist(err) {
+		t.Error("old binary left behind")
+	}
+}
+
+func TestSelfUpdate_UpToDateAndCheck(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	exe, priv := setupSelfUpdate(t, "v1.1.0")
+	srv := releaseServer(t, "stable", "v1.1.0", []byte("new binary"), priv)
+
+	stdout, stderr, code := runCmd(t, "self-update", "--endpoint", srv.URL)
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "up to date") {
+		t.Errorf("stdout = %q", stdout)
+	}
+
+	Version = "v1.0.0"
+	stdout, _, code = runCmd(t, "self-update", "--endpoint", srv.URL, "--check", "--format", "json")
+	if code != 0 || !strings.Contains(stdout, `"newer": true`) {
+		t.Errorf("check: exit %d, stdout = %q", code, stdout)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old binary" {
+		t.Error("--check replaced the executable")
+	}
+}
+
+func TestSelfUpdate_BadSignature(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	exe, _ := setupSelfUpdate(t, "v1.0.0")
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	srv := releaseServer(t, "stable", "v1.1.0", []byte("evil binary"), otherKey)
+
+	_, stderr, code := runCmd(t, "self-update", "--endpoint", srv.URL)
+	if code == 0 || !strings.Contains(stderr, "signature") {
+		t.Fatalf("exit %d, stderr = %q, want signature failure", code, stderr)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old binary" {
+		t.Error("executable replaced despite bad signature")
+	}
+}
+
+func TestSelfUpdate_SignatureBindsRelease(t *testing.T) {
+	tests := []struct {
+		name                         string
+		channel, version             string
+		signedChannel, signedVersion string
+	}{
+		// An old signed binary served as a newer version rolls back.
+		{"rollback", "stable", "v1.2.0", "stable", "v0.9.0"},
+		{"other channel", "stable", "v1.2.0", "beta", "v1.2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, cleanup := setupTestEnv(t)
+			defer cleanup()
+			exe, priv := setupSelfUpdate(t, "v1.0.0")
+			bin := []byte("signed binary")
+			srv := serveRelease(t, tt.channel, tt.version, bin, signRelease(priv, tt.signedChannel, tt.signedVersion, bin))
+
+			_, stderr, code := runCmd(t, "self-update", "--endpoint", srv.URL)
+			if code == 0 || !strings.Contains(stderr, "signature") {
+				t.Fatalf("exit %d, stderr = %q, want signature failure", code, stderr)
+			}
+			if data, _ := os.ReadFile(exe); string(data) != "old binary" {
+				t.Error("executable replaced by a release signed for another version or channel")
+			}
+		})
+	}
+}
+
+func TestSelfUpdate_Channel(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	exe, priv := setupSelfUpdate(t, "v1.0.0")
+	srv := releaseServer(t, "beta", "v1.1.0-beta.2", []byte("beta binary"), priv)
+
+	if _, stderr, code := runCmd(t, "self-update", "channel", "nightly"); code == 0 {
+		t.Error("unknown channel accepted")
+	} else if !strings.Contains(stderr, "unknown channel") {
+		t.Errorf("stderr = %q", stderr)
+	}
+	if _, stderr, code := runCmd(t, "self-update", "channel", "beta"); code != 0 {
+		t.Fatalf("set channel: %s", stderr)
+	}
+	if stdout, _, _ := runCmd(t, "self-update", "channel"); strings.TrimSpace(stdout) != "beta" {
+		t.Errorf("channel = %q, want beta", stdout)
+	}
+
+	if _, stderr, code := runCmd(t, "self-update", "--endpoint", srv.URL); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "beta binary" {
+		t.Errorf("executable = %q, want beta release", data)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"v1.2.4", "v1.2.3", 1},
+		{"1.10.0", "v1.9.9", 1},
+		{"v2", "v1.9", 1},
+		{"v1.2.3", "v1.2.3-beta.1", 1},
+		{"v1.2.3-beta.2", "v1.2.3-beta.10", -1},
+		{"v1.2.3-alpha", "v1.2.3-beta", -1},
+		{"v0.0.1", "dev", 1},
+		{"dev", "dev", 0},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/haivivi/giztoy/go/pkg/cli"
+	"github.com/haivivi/giztoy/go/pkg/cortex"
+)
+
+var (
+	serveDashboard string
+	serveAPI       string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [--dashboard <addr>] [--api <addr>]",
+	Short: "Run the giztoy server",
+	Long: `Run the giztoy server until interrupted.
+
+With --dashboard, serve a read-only web dashboard of connected devices,
+active sessions, pipeline stage latencies, recent errors and the config
+documents in KV (secrets redacted).
+
+With --api, serve the task API: long-running jobs submitted with
+"giztoy run --async" run in this process and are inspected with
+"giztoy tasks". Interrupting the server cancels the running tasks.
+
+Neither has authentication: bind them to a local address.
+
+Examples:
+  giztoy serve --dashboard 127.0.0.1:7070
+  giztoy serve --dashboard 127.0.0.1:7070 --api 127.0.0.1:7071`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if serveDashboard == "" && serveAPI == "" {
+			return cli.Errorf(cli.CodeUsage, "nothing to serve: flag --dashboard or --api is required")
+		}
+
+		c, err := openCortex(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		// Run the servers until interrupted; the first to fail stops the
+		// others.
+		errc := make(chan error, 2)
+		n := 0
+		if serveDashboard != "" {
+			n++
+			go func() { errc <- serveHTTP(ctx, "dashboard", serveDashboard, cortex.NewDashboard(c)) }()
+		}
+		if serveAPI != "" {
+			n++
+			go func() { errc <- serveHTTP(ctx, "api", serveAPI, cortex.NewTaskServer(c)) }()
+		}
+		var firstErr error
+		for range n {
+			if err := <-errc; err != nil && firstErr == nil {
+				firstErr = err
+				cancel()
+			}
+		}
+		return firstErr
+	},
+}
+
+// serveHTTP serves h on addr until ctx is done. name labels the address
+// printed to stderr.
+func serveHTTP(ctx context.Context, name, addr string, h http.Handler) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s: http://%s/\n", name, ln.Addr())
+
+	srv := &http.Server{Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveDashboard, "dashboard", "", "listen address of the web dashboard")
+	serveCmd.Flags().StringVar(&serveAPI, "api", "", "listen address of the task API")
+
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/haivivi/giztoy/go/pkg/cortex"
+)
+
+// defaultTaskServer is the task API address used when neither --server
+// nor the ctx config "server" is set.
+const defaultTaskServer = "127.0.0.1:7071"
+
+var (
+	tasksServer    string
+	tasksListTable tableFlags
+)
+
+// taskRow is a row of the tasks list table.
+type taskRow struct {
+	ID      string `json:"id"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Started string `json:"started"`
+}
+
+var tasksCmd = &cobra.Command{
+	Use:   "tasks",
+	Short: "Inspect long-running tasks of the serve daemon",
+	Long: `Inspect and cancel the long-running tasks (video generation, voice
+clone training, ...) started with "giztoy run --async" on a server
+running "giztoy serve --api <addr>".
+
+The server address is --server, else the "server" key of the current
+context, else ` + defaultTaskServer + `.
+
+Examples:
+  giztoy ctx config set server 127.0.0.1:7071
+  giztoy tasks list
+  giztoy tasks list --columns id,status --sort -started
+  giztoy tasks status 3f2a9c0d1e7b4a56
+  giztoy tasks logs 3f2a9c0d1e7b4a56
+  giztoy tasks cancel 3f2a9c0d1e7b4a56`,
+}
+
+var tasksListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List running and recently finished tasks",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		infos, err := newTaskClient().List(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if formatOutput == "json" {
+			return printJSON(infos)
+		}
+		if len(infos) == 0 {
+			fmt.Println("No tasks found.")
+			return nil
+		}
+		rows := make([]taskRow, len(infos))
+		for i, info := range infos {
+			rows[i] = taskRow{
+				ID:      info.ID,
+				Kind:    info.Kind,
+				Name:    info.Name,
+				Status:  string(info.Status),
+				Started: info.StartedAt.Format(time.DateTime),
+			}
+		}
+		if err := tasksListTable.print(rows); err != nil {
+			return err
+		}
+		if formatOutput != "tsv" {
+			fmt.Printf("(%d items)\n", len(infos))
+		}
+		return nil
+	},
+}
+
+var tasksStatusCmd = &cobra.Command{
+	Use:   "status <id>",
+	Short: "Show the state and result of a task",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info, err := newTaskClient().Status(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		if formatOutput == "json" {
+			return printJSON(info)
+		}
+		printTaskInfo(info)
+		return nil
+	},
+}
+
+var tasksLogsCmd = &cobra.Command{
+	Use:   "logs <id>",
+	Short: "Print the log of a task",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logs, err := newTaskClient().Logs(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		if formatOutput == "json" {
+			return printJSON(logs)
+		}
+		for _, entry := range logs {
+			fmt.Printf("%s  %s\n", entry.Time.Format(time.TimeOnly), entry.Message)
+		}
+		return nil
+	},
+}
+
+var tasksCancelCmd = &cobra.Command{
+	Use:   "cancel <id>",
+	Short: "Cancel a running task",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info, err := newTaskClient().Cancel(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		if formatOutput == "json" {
+			return printJSON(info)
+		}
+		fmt.Printf("Task %s cancel requested (status: %s)\n", info.ID, info.Status)
+		return nil
+	},
+}
+
+// newTaskClient creates a client for the task API of the serve daemon.
+func newTaskClient() *cortex.TaskClient {
+	addr := tasksServer
+	if addr == "" {
+		if s, err := openStore(); err == nil {
+			if _, cfg, err := s.CtxShow(""); err == nil {
+				addr = cfg.Server
+			}
+		}
+	}
+	if addr == "" {
+		addr = defaultTaskServer
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	printVerbose("task server: %s", addr)
+	return &cortex.TaskClient{BaseURL: addr}
+}
+
+// printTaskInfo prints the state of a task in human-readable form.
+func printTaskInfo(info *cortex.TaskInfo) {
+	fmt.Printf("ID:      %s\n", info.ID)
+	fmt.Printf("Kind:    %s\n", info.Kind)
+	if info.Name != "" {
+		fmt.Printf("Name:    %s\n", info.Name)
+	}
+	fmt.Printf("Status:  %s\n", info.Status)
+	fmt.Printf("Started: %s\n", info.StartedAt.Format(time.DateTime))
+	if !info.EndedAt.IsZero() {
+		fmt.Printf("Ended:   %s (%s)\n", info.EndedAt.Format(time.DateTime), info.EndedAt.Sub(info.StartedAt).Round(time.Second))
+	}
+	if info.Error != "" {
+		fmt.Printf("Error:   %s\n", info.Error)
+	}
+	if info.Result != nil {
+		printRunResult(info.Result)
+		for _, k := range slices.Sorted(maps.Keys(info.Result.Data)) {
+			fmt.Printf("%s: %v\n", k, info.Result.Data[k])
+		}
+	}
+}
+
+func init() {
+	tasksCmd.PersistentFlags().StringVar(&tasksServer, "server", "", "task API address of the serve daemon")
+
+	tasksListTable.register(tasksListCmd)
+	tasksCmd.AddCommand(tasksListCmd)
+	tasksCmd.AddCommand(tasksStatusCmd)
+	tasksCmd.AddCommand(tasksLogsCmd)
+	tasksCmd.AddCommand(tasksCancelCmd)
+	rootCmd.AddCommand(tasksCmd)
+}
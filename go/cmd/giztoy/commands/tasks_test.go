@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/cortex"
+	"github.com/haivivi/giztoy/go/pkg/kv"
+)
+
+func init() {
+	cortex.RegisterRunHandler("test/tasks-echo", func(ctx context.Context, c *cortex.Cortex, task cortex.Document) (*cortex.RunResult, error) {
+		cortex.TaskLogf(ctx, "echo %s", task.GetString("text"))
+		return &cortex.RunResult{Kind: task.Kind, Status: "ok", Text: task.GetString("text")}, nil
+	})
+}
+
+// startTaskServer serves the task API of a separate Cortex, as a serve
+// daemon would, and points the current ctx at it.
+func startTaskServer(t *testing.T) *cortex.Cortex {
+	t.Helper()
+	store, err := openStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := cortex.New(context.Background(), store, cortex.WithKV(kv.NewMemory(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	srv := httptest.NewServer(cortex.NewTaskServer(c))
+	t.Cleanup(srv.Close)
+	if err := store.CtxConfigSet("server", srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRunAsyncAndTasks(t *testing.T) {
+	cleanup := setupTestEnvWithKV(t)
+	defer cleanup()
+	c := startTaskServer(t)
+
+	path := writeTestYAML(t, "task.yaml", `kind: test/tasks-echo
+name: hello
+text: hi there
+`)
+	stdout, stderr, code := runCmd(t, "run", "-f", path, "--async")
+	if code != 0 {
+		t.Fatalf("run --async: exit %d: %s", code, stderr)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(stdout, "Task ID:"))
+	task, err := c.Task(id)
+	if err != nil {
+		t.Fatalf("task %q not started on the server: %v", id, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := task.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, code = runCmd(t, "tasks", "list")
+	if code != 0 {
+		t.Fatalf("tasks list: exit %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, id) || !strings.Contains(stdout, "succeeded") {
+		t.Errorf("tasks list output:\n%s", stdout)
+	}
+	stdout, _, code = runCmd(t, "tasks", "list", "--format", "tsv", "--columns", "id,status")
+	if want := "id\tstatus\n" + id + "\tsucceeded\n"; code != 0 || stdout != want {
+		t.Errorf("tasks list --columns: exit %d: %q, want %q", code, stdout, want)
+	}
+
+	stdout, _, code = runCmd(t, "tasks", "status", id)
+	if code != 0 || !strings.Contains(stdout, "Status:  succeeded") || !strings.Contains(stdout, "hi there") {
+		t.Errorf("tasks status: exit %d:\n%s", code, stdout)
+	}
+
+	stdout, _, code = runCmd(t, "tasks", "logs", id)
+	if code != 0 || !strings.Contains(stdout, "echo hi there") {
+		t.Errorf("tasks logs: exit %d:\n%s", code, stdout)
+	}
+}
+
+func TestTasksNotFound(t *testing.T) {
+	cleanup := setupTestEnvWithKV(t)
+	defer cleanup()
+	startTaskServer(t)
+
+	_, stderr, code := runCmd(t, "tasks", "status", "nope")
+	if code != 8 {
+		t.Fatalf("exit %d, want 8 (stderr: %s)", code, stderr)
+	}
+}
+
+func TestServeWithAPIOnly(t *testing.T) {
+	cleanup := setupTestEnvWithKV(t)
+	defer cleanup()
+
+	// A bad address fails to listen rather than being a usage error.
+	_, stderr, code := runCmd(t, "serve", "--api", "bad address")
+	if code == 0 || code == 2 {
+		t.Fatalf("exit %d, want a listen error (stderr: %s)", code, stderr)
+	}
+}
@@ -7,12 +7,16 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// Version is the release version of the binary, set at build time with
+// -ldflags "-X github.com/haivivi/giztoy/go/cmd/giztoy/commands.Version=v1.2.3".
+var Version = "dev"
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show version information",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		info := map[string]any{
-			"version": "dev",
+			"version": Version,
 			"go":      runtime.Version(),
 			"os":      runtime.GOOS,
 			"arch":    runtime.GOARCH,
@@ -20,7 +24,7 @@ var versionCmd = &cobra.Command{
 		if formatOutput == "json" {
 			return printJSON(info)
 		}
-		fmt.Printf("giztoy dev (%s %s/%s)\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+		fmt.Printf("giztoy %s (%s %s/%s)\n", Version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
 		return nil
 	},
 }
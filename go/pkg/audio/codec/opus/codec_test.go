@@ -194,3 +194,112 @@ func TestFrameDurationCalculation(t *testing.T) {
 			frameSize, expectedDuration, actualDuration, len(frame))
 	}
 }
+
+func TestEncoderComplexity(t *testing.T) {
+	enc, err := NewVoIPEncoder(16000, 1)
+	if err != nil {
+		t.Fatalf("failed to create encoder: %v", err)
+	}
+	defer enc.Close()
+
+	if err := enc.SetComplexity(3); err != nil {
+		t.Fatalf("SetComplexity failed: %v", err)
+	}
+	complexity, err := enc.Complexity()
+	if err != nil {
+		t.Fatalf("Complexity failed: %v", err)
+	}
+	if complexity != 3 {
+		t.Errorf("Complexity() = %d, want 3", complexity)
+	}
+}
+
+func TestEncoderDTX(t *testing.T) {
+	sampleRate := 16000
+	enc, err := NewVoIPEncoder(sampleRate, 1)
+	if err != nil {
+		t.Fatalf("failed to create encoder: %v", err)
+	}
+	defer enc.Close()
+
+	if err := enc.SetDTX(true); err != nil {
+		t.Fatalf("SetDTX failed: %v", err)
+	}
+
+	// DTX starts after a short run of silence
+	frameSize := enc.FrameSize20ms()
+	silence := make([]int16, frameSize)
+	var dtxFrames int
+	for range 50 {
+		frame, err := enc.Encode(silence, frameSize)
+		if err != nil {
+			t.Fatalf("encode failed: %v", err)
+		}
+		if frame.IsDTX() {
+			dtxFrames++
+		}
+	}
+	if dtxFrames == 0 {
+		t.Error("no DTX frames for 1s of silence")
+	}
+	inDTX, err := enc.InDTX()
+	if err != nil {
+		t.Fatalf("InDTX failed: %v", err)
+	}
+	if !inDTX {
+		t.Error("InDTX() = false after silence")
+	}
+}
+
+func TestDecoderFEC(t *testing.T) {
+	sampleRate := 16000
+	channels := 1
+
+	enc, err := NewVoIPEncoder(sampleRate, channels)
+	if err != nil {
+		t.Fatalf("failed to create encoder: %v", err)
+	}
+	defer enc.Close()
+	if err := enc.SetInbandFEC(true); err != nil {
+		t.Fatalf("SetInbandFEC failed: %v", err)
+	}
+	if err := enc.SetPacketLossPerc(20); err != nil {
+		t.Fatalf("SetPacketLossPerc failed: %v", err)
+	}
+
+	dec, err := NewDecoder(sampleRate, channels)
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+	defer dec.Close()
+
+	frameSize := enc.FrameSize20ms()
+	var frames []Frame
+	for n := range 3 {
+		pcm := make([]int16, frameSize)
+		for i := range pcm {
+			ti := float64(n*frameSize+i) / float64(sampleRate)
+			pcm[i] = int16(math.Sin(2*math.Pi*440*ti) * 16000)
+		}
+		frame, err := enc.Encode(pcm, frameSize)
+		if err != nil {
+			t.Fatalf("encode failed: %v", err)
+		}
+		frames = append(frames, frame.Clone())
+	}
+
+	// Frame 1 is lost: recover it from frame 2
+	if _, err := dec.Decode(frames[0]); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	recovered, err := dec.DecodeFEC(frames[2], frameSize)
+	if err != nil {
+		t.Fatalf("FEC decode failed: %v", err)
+	}
+	if got := len(recovered) / 2 / channels; got != frameSize {
+		t.Errorf("FEC recovered %d samples, want %d", got, frameSize)
+	}
+	if _, err := dec.Decode(frames[2]); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+}
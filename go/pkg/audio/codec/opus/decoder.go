@@ -113,6 +113,27 @@ func (d *Decoder) DecodePLC(samples int) ([]byte, error) {
 	return unsafe.Slice((*byte)(unsafe.Pointer(&buf[0])), 2*int(n)*d.channels), nil
 }
 
+// DecodeFEC recovers a lost frame from the forward error correction data in
+// f, the frame that follows it. samples is the duration of the lost frame in
+// samples per channel. If f carries no FEC data, the lost frame is concealed
+// as with DecodePLC. See Encoder.SetInbandFEC.
+func (d *Decoder) DecodeFEC(f Frame, samples int) ([]byte, error) {
+	if d.cDec == nil {
+		return nil, fmt.Errorf("opus: decoder is closed")
+	}
+	if len(f) == 0 {
+		return d.DecodePLC(samples)
+	}
+
+	buf := make([]int16, samples*d.channels)
+	n := C.opus_decode(d.cDec, (*C.uchar)(unsafe.Pointer(&f[0])), C.opus_int32(len(f)),
+		(*C.opus_int16)(unsafe.Pointer(&buf[0])), C.int(samples), 1)
+	if n < 0 {
+		return nil, fmt.Errorf("opus: FEC decode failed: %s", C.GoString(C.opus_strerror(n)))
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&buf[0])), 2*int(n)*d.channels), nil
+}
+
 // SampleRate returns the sample rate of this decoder.
 func (d *Decoder) SampleRate() int {
 	return d.sampleRate
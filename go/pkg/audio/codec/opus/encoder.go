@@ -16,6 +16,26 @@ static int opus_encoder_set_bitrate(OpusEncoder *enc, opus_int32 bitrate) {
 static int opus_encoder_set_complexity(OpusEncoder *enc, opus_int32 complexity) {
     return opus_encoder_ctl(enc, OPUS_SET_COMPLEXITY(complexity));
 }
+
+static int opus_encoder_get_complexity(OpusEncoder *enc, opus_int32 *complexity) {
+    return opus_encoder_ctl(enc, OPUS_GET_COMPLEXITY(complexity));
+}
+
+static int opus_encoder_set_dtx(OpusEncoder *enc, opus_int32 dtx) {
+    return opus_encoder_ctl(enc, OPUS_SET_DTX(dtx));
+}
+
+static int opus_encoder_get_in_dtx(OpusEncoder *enc, opus_int32 *in_dtx) {
+    return opus_encoder_ctl(enc, OPUS_GET_IN_DTX(in_dtx));
+}
+
+static int opus_encoder_set_inband_fec(OpusEncoder *enc, opus_int32 fec) {
+    return opus_encoder_ctl(enc, OPUS_SET_INBAND_FEC(fec));
+}
+
+static int opus_encoder_set_packet_loss_perc(OpusEncoder *enc, opus_int32 perc) {
+    return opus_encoder_ctl(enc, OPUS_SET_PACKET_LOSS_PERC(perc));
+}
 */
 import "C"
 import (
@@ -159,6 +179,74 @@ func (e *Encoder) SetComplexity(complexity int) error {
 	return nil
 }
 
+// Complexity returns the encoder's computational complexity (0-10).
+func (e *Encoder) Complexity() (int, error) {
+	if e.cEnc == nil {
+		return 0, fmt.Errorf("opus: encoder is closed")
+	}
+	var complexity C.opus_int32
+	ret := C.opus_encoder_get_complexity(e.cEnc, &complexity)
+	if ret != C.OPUS_OK {
+		return 0, fmt.Errorf("opus: get complexity failed: %s", C.GoString(C.opus_strerror(ret)))
+	}
+	return int(complexity), nil
+}
+
+// SetDTX enables or disables discontinuous transmission. With DTX enabled,
+// the encoder emits frames of at most 2 bytes during silence (see
+// Frame.IsDTX), which need not be transmitted.
+func (e *Encoder) SetDTX(enabled bool) error {
+	if e.cEnc == nil {
+		return fmt.Errorf("opus: encoder is closed")
+	}
+	ret := C.opus_encoder_set_dtx(e.cEnc, cBool(enabled))
+	if ret != C.OPUS_OK {
+		return fmt.Errorf("opus: set DTX failed: %s", C.GoString(C.opus_strerror(ret)))
+	}
+	return nil
+}
+
+// InDTX reports whether the last encoded frame was silence under DTX.
+func (e *Encoder) InDTX() (bool, error) {
+	if e.cEnc == nil {
+		return false, fmt.Errorf("opus: encoder is closed")
+	}
+	var inDTX C.opus_int32
+	ret := C.opus_encoder_get_in_dtx(e.cEnc, &inDTX)
+	if ret != C.OPUS_OK {
+		return false, fmt.Errorf("opus: get in DTX failed: %s", C.GoString(C.opus_strerror(ret)))
+	}
+	return inDTX != 0, nil
+}
+
+// SetInbandFEC enables or disables inband forward error correction. FEC
+// data lets the decoder recover a lost frame from the next one (see
+// Decoder.DecodeFEC). It only applies to the SILK (voice) mode and is
+// only used when the expected packet loss is above zero.
+func (e *Encoder) SetInbandFEC(enabled bool) error {
+	if e.cEnc == nil {
+		return fmt.Errorf("opus: encoder is closed")
+	}
+	ret := C.opus_encoder_set_inband_fec(e.cEnc, cBool(enabled))
+	if ret != C.OPUS_OK {
+		return fmt.Errorf("opus: set inband FEC failed: %s", C.GoString(C.opus_strerror(ret)))
+	}
+	return nil
+}
+
+// SetPacketLossPerc sets the expected packet loss in percent (0-100). A
+// higher value makes the encoder more robust to loss at the cost of quality.
+func (e *Encoder) SetPacketLossPerc(perc int) error {
+	if e.cEnc == nil {
+		return fmt.Errorf("opus: encoder is closed")
+	}
+	ret := C.opus_encoder_set_packet_loss_perc(e.cEnc, C.opus_int32(perc))
+	if ret != C.OPUS_OK {
+		return fmt.Errorf("opus: set packet loss failed: %s", C.GoString(C.opus_strerror(ret)))
+	}
+	return nil
+}
+
 // FrameSizeForDuration returns the frame size (samples per channel) for a given duration.
 func (e *Encoder) FrameSizeForDuration(fd FrameDuration) int {
 	return e.sampleRate * int(fd.Millis()) / 1000
@@ -168,3 +256,11 @@ func (e *Encoder) FrameSizeForDuration(fd FrameDuration) int {
 func (e *Encoder) FrameSize20ms() int {
 	return e.sampleRate * 20 / 1000
 }
+
+// cBool converts a bool to an opus_int32 ctl argument.
+func cBool(b bool) C.opus_int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -45,6 +45,13 @@ func (f Frame) Clone() Frame {
 	return slices.Clone(f)
 }
 
+// IsDTX reports whether this frame is a silence frame of discontinuous
+// transmission: an encoder with DTX enabled emits frames of at most 2 bytes
+// during silence, which need not be transmitted.
+func (f Frame) IsDTX() bool {
+	return len(f) <= 2
+}
+
 // IsStereo returns true if this frame contains stereo audio.
 func (f Frame) IsStereo() bool {
 	return f.TOC().IsStereo()
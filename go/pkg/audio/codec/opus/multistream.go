@@ -0,0 +1,349 @@
+package opus
+
+// For go build: use pkg-config to find system libopus
+// For bazel build: cdeps provides opus headers and library
+
+/*
+#cgo pkg-config: opus
+#include <opus.h>
+#include <opus_multistream.h>
+#include <stdlib.h>
+
+// Wrapper functions for variadic opus_multistream_encoder_ctl
+static int opus_ms_encoder_set_bitrate(OpusMSEncoder *enc, opus_int32 bitrate) {
+    return opus_multistream_encoder_ctl(enc, OPUS_SET_BITRATE(bitrate));
+}
+
+static int opus_ms_encoder_set_complexity(OpusMSEncoder *enc, opus_int32 complexity) {
+    return opus_multistream_encoder_ctl(enc, OPUS_SET_COMPLEXITY(complexity));
+}
+
+static int opus_ms_encoder_set_dtx(OpusMSEncoder *enc, opus_int32 dtx) {
+    return opus_multistream_encoder_ctl(enc, OPUS_SET_DTX(dtx));
+}
+
+static int opus_ms_encoder_set_inband_fec(OpusMSEncoder *enc, opus_int32 fec) {
+    return opus_multistream_encoder_ctl(enc, OPUS_SET_INBAND_FEC(fec));
+}
+
+static int opus_ms_encoder_set_packet_loss_perc(OpusMSEncoder *enc, opus_int32 perc) {
+    return opus_multistream_encoder_ctl(enc, OPUS_SET_PACKET_LOSS_PERC(perc));
+}
+*/
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Mapping families for NewSurroundEncoder.
+const (
+	// MappingFamilyRTP is for mono and stereo (1 or 2 channels).
+	MappingFamilyRTP = 0
+
+	// MappingFamilyVorbis is for 1 to 8 channels in Vorbis channel order.
+	MappingFamilyVorbis = 1
+
+	// MappingFamilyIndependent codes every channel as its own mono stream,
+	// for up to 255 channels, e.g. a microphone array.
+	MappingFamilyIndependent = 255
+)
+
+// MultistreamEncoder wraps an Opus multistream encoder, which codes more
+// than two channels, such as the signals of a multi-microphone array, as
+// several mono and stereo streams in one packet.
+//
+// The TOC helpers of Frame describe only the first stream of a multistream
+// packet.
+type MultistreamEncoder struct {
+	sampleRate     int
+	channels       int
+	streams        int
+	coupledStreams int
+	mapping        []byte
+	cEnc           *C.OpusMSEncoder
+}
+
+// NewMultistreamEncoder creates a new Opus multistream encoder.
+//
+// Parameters:
+//   - sampleRate: Sample rate of input signal (8000, 12000, 16000, 24000, or 48000)
+//   - channels: Number of input channels (1-255)
+//   - streams: Total number of streams to code
+//   - coupledStreams: Number of streams that are stereo; the first
+//     coupledStreams streams are stereo, the rest mono
+//   - mapping: For each input channel, the index of the decoded channel it
+//     feeds: 2*i and 2*i+1 are the left and right of coupled stream i, then
+//     one per mono stream; 255 drops the channel
+//   - application: Intended application type (ApplicationVoIP, ApplicationAudio, etc.)
+func NewMultistreamEncoder(sampleRate, channels, streams, coupledStreams int, mapping []byte, application int) (*MultistreamEncoder, error) {
+	if channels < 1 || channels > 255 {
+		return nil, fmt.Errorf("opus: invalid channel count %d", channels)
+	}
+	if len(mapping) != channels {
+		return nil, fmt.Errorf("opus: mapping has %d entries, want %d", len(mapping), channels)
+	}
+	var err C.int
+	cEnc := C.opus_multistream_encoder_create(C.opus_int32(sampleRate), C.int(channels),
+		C.int(streams), C.int(coupledStreams), (*C.uchar)(unsafe.Pointer(&mapping[0])),
+		C.int(application), &err)
+	if err != C.OPUS_OK {
+		return nil, fmt.Errorf("opus: multistream encoder create failed: %s", C.GoString(C.opus_strerror(err)))
+	}
+	return &MultistreamEncoder{
+		sampleRate:     sampleRate,
+		channels:       channels,
+		streams:        streams,
+		coupledStreams: coupledStreams,
+		mapping:        append([]byte(nil), mapping...),
+		cEnc:           cEnc,
+	}, nil
+}
+
+// NewSurroundEncoder creates a new Opus multistream encoder that chooses the
+// streams and mapping for channels from a mapping family
+// (MappingFamilyRTP, MappingFamilyVorbis or MappingFamilyIndependent). Use
+// Streams, CoupledStreams and Mapping to set up the matching decoder.
+func NewSurroundEncoder(sampleRate, channels, mappingFamily, application int) (*MultistreamEncoder, error) {
+	if channels < 1 || channels > 255 {
+		return nil, fmt.Errorf("opus: invalid channel count %d", channels)
+	}
+	var (
+		err            C.int
+		streams        C.int
+		coupledStreams C.int
+	)
+	mapping := make([]byte, channels)
+	cEnc := C.opus_multistream_surround_encoder_create(C.opus_int32(sampleRate), C.int(channels),
+		C.int(mappingFamily), &streams, &coupledStreams, (*C.uchar)(unsafe.Pointer(&mapping[0])),
+		C.int(application), &err)
+	if err != C.OPUS_OK {
+		return nil, fmt.Errorf("opus: surround encoder create failed: %s", C.GoString(C.opus_strerror(err)))
+	}
+	return &MultistreamEncoder{
+		sampleRate:     sampleRate,
+		channels:       channels,
+		streams:        int(streams),
+		coupledStreams: int(coupledStreams),
+		mapping:        mapping,
+		cEnc:           cEnc,
+	}, nil
+}
+
+// Close releases the encoder resources.
+func (e *MultistreamEncoder) Close() {
+	if e.cEnc != nil {
+		C.opus_multistream_encoder_destroy(e.cEnc)
+		e.cEnc = nil
+	}
+}
+
+// Encode encodes interleaved PCM samples to an Opus multistream packet.
+//
+// Parameters:
+//   - pcm: Input PCM samples as int16 slice. Must contain frameSize*channels samples.
+//   - frameSize: Number of samples per channel in the input signal.
+func (e *MultistreamEncoder) Encode(pcm []int16, frameSize int) (Frame, error) {
+	// Max Opus frame size per stream
+	buf := make([]byte, 4000*e.streams)
+	n, err := e.EncodeTo(pcm, frameSize, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// EncodeTo encodes interleaved PCM samples to the provided buffer.
+// Returns the number of bytes written.
+func (e *MultistreamEncoder) EncodeTo(pcm []int16, frameSize int, buf []byte) (int, error) {
+	if e.cEnc == nil {
+		return 0, fmt.Errorf("opus: encoder is closed")
+	}
+
+	n := C.opus_multistream_encode(e.cEnc,
+		(*C.opus_int16)(unsafe.Pointer(&pcm[0])), C.int(frameSize),
+		(*C.uchar)(unsafe.Pointer(&buf[0])), C.opus_int32(len(buf)))
+	if n < 0 {
+		return 0, fmt.Errorf("opus: encode failed: %s", C.GoString(C.opus_strerror(n)))
+	}
+	return int(n), nil
+}
+
+// SampleRate returns the sample rate of this encoder.
+func (e *MultistreamEncoder) SampleRate() int {
+	return e.sampleRate
+}
+
+// Channels returns the number of input channels of this encoder.
+func (e *MultistreamEncoder) Channels() int {
+	return e.channels
+}
+
+// Streams returns the total number of streams.
+func (e *MultistreamEncoder) Streams() int {
+	return e.streams
+}
+
+// CoupledStreams returns the number of stereo streams.
+func (e *MultistreamEncoder) CoupledStreams() int {
+	return e.coupledStreams
+}
+
+// Mapping returns the channel mapping, for NewMultistreamDecoder.
+func (e *MultistreamEncoder) Mapping() []byte {
+	return append([]byte(nil), e.mapping...)
+}
+
+// SetBitrate sets the target bitrate of all streams in bits per second.
+func (e *MultistreamEncoder) SetBitrate(bitrate int) error {
+	if e.cEnc == nil {
+		return fmt.Errorf("opus: encoder is closed")
+	}
+	ret := C.opus_ms_encoder_set_bitrate(e.cEnc, C.opus_int32(bitrate))
+	if ret != C.OPUS_OK {
+		return fmt.Errorf("opus: set bitrate failed: %s", C.GoString(C.opus_strerror(ret)))
+	}
+	return nil
+}
+
+// SetComplexity sets the encoder's computational complexity (0-10).
+func (e *MultistreamEncoder) SetComplexity(complexity int) error {
+	if e.cEnc == nil {
+		return fmt.Errorf("opus: encoder is closed")
+	}
+	ret := C.opus_ms_encoder_set_complexity(e.cEnc, C.opus_int32(complexity))
+	if ret != C.OPUS_OK {
+		return fmt.Errorf("opus: set complexity failed: %s", C.GoString(C.opus_strerror(ret)))
+	}
+	return nil
+}
+
+// SetDTX enables or disables discontinuous transmission. See
+// Encoder.SetDTX.
+func (e *MultistreamEncoder) SetDTX(enabled bool) error {
+	if e.cEnc == nil {
+		return fmt.Errorf("opus: encoder is closed")
+	}
+	ret := C.opus_ms_encoder_set_dtx(e.cEnc, cBool(enabled))
+	if ret != C.OPUS_OK {
+		return fmt.Errorf("opus: set DTX failed: %s", C.GoString(C.opus_strerror(ret)))
+	}
+	return nil
+}
+
+// SetInbandFEC enables or disables inband forward error correction. See
+// Encoder.SetInbandFEC.
+func (e *MultistreamEncoder) SetInbandFEC(enabled bool) error {
+	if e.cEnc == nil {
+		return fmt.Errorf("opus: encoder is closed")
+	}
+	ret := C.opus_ms_encoder_set_inband_fec(e.cEnc, cBool(enabled))
+	if ret != C.OPUS_OK {
+		return fmt.Errorf("opus: set inband FEC failed: %s", C.GoString(C.opus_strerror(ret)))
+	}
+	return nil
+}
+
+// SetPacketLossPerc sets the expected packet loss in percent (0-100).
+func (e *MultistreamEncoder) SetPacketLossPerc(perc int) error {
+	if e.cEnc == nil {
+		return fmt.Errorf("opus: encoder is closed")
+	}
+	ret := C.opus_ms_encoder_set_packet_loss_perc(e.cEnc, C.opus_int32(perc))
+	if ret != C.OPUS_OK {
+		return fmt.Errorf("opus: set packet loss failed: %s", C.GoString(C.opus_strerror(ret)))
+	}
+	return nil
+}
+
+// FrameSize20ms returns the frame size for 20ms frames (recommended default).
+func (e *MultistreamEncoder) FrameSize20ms() int {
+	return e.sampleRate * 20 / 1000
+}
+
+// MultistreamDecoder wraps an Opus multistream decoder.
+type MultistreamDecoder struct {
+	sampleRate int
+	channels   int
+	cDec       *C.OpusMSDecoder
+}
+
+// NewMultistreamDecoder creates a new Opus multistream decoder. streams,
+// coupledStreams and mapping must match the encoder; see
+// NewMultistreamEncoder.
+func NewMultistreamDecoder(sampleRate, channels, streams, coupledStreams int, mapping []byte) (*MultistreamDecoder, error) {
+	if channels < 1 || channels > 255 {
+		return nil, fmt.Errorf("opus: invalid channel count %d", channels)
+	}
+	if len(mapping) != channels {
+		return nil, fmt.Errorf("opus: mapping has %d entries, want %d", len(mapping), channels)
+	}
+	var err C.int
+	cDec := C.opus_multistream_decoder_create(C.opus_int32(sampleRate), C.int(channels),
+		C.int(streams), C.int(coupledStreams), (*C.uchar)(unsafe.Pointer(&mapping[0])), &err)
+	if err != C.OPUS_OK {
+		return nil, fmt.Errorf("opus: multistream decoder create failed: %s", C.GoString(C.opus_strerror(err)))
+	}
+	return &MultistreamDecoder{
+		sampleRate: sampleRate,
+		channels:   channels,
+		cDec:       cDec,
+	}, nil
+}
+
+// Close releases the decoder resources.
+func (d *MultistreamDecoder) Close() {
+	if d.cDec != nil {
+		C.opus_multistream_decoder_destroy(d.cDec)
+		d.cDec = nil
+	}
+}
+
+// Decode decodes an Opus multistream packet to interleaved PCM samples. An
+// empty frame performs packet loss concealment for 20ms.
+// Returns the decoded PCM data as bytes (int16 samples, little-endian).
+func (d *MultistreamDecoder) Decode(f Frame) ([]byte, error) {
+	// Max frame size: 120ms at 48kHz = 5760 samples per channel
+	buf := make([]int16, 5760*d.channels)
+	if len(f) == 0 {
+		buf = buf[:d.sampleRate*20/1000*d.channels]
+	}
+	n, err := d.DecodeTo(f, buf)
+	if err != nil {
+		return nil, err
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&buf[0])), 2*n*d.channels), nil
+}
+
+// DecodeTo decodes an Opus multistream packet to the provided PCM buffer.
+// The buffer should be large enough to hold the decoded samples.
+// Returns the number of samples per channel decoded.
+func (d *MultistreamDecoder) DecodeTo(f Frame, buf []int16) (int, error) {
+	if d.cDec == nil {
+		return 0, fmt.Errorf("opus: decoder is closed")
+	}
+
+	var dataPtr *C.uchar
+	var dataLen C.opus_int32
+	if len(f) > 0 {
+		dataPtr = (*C.uchar)(unsafe.Pointer(&f[0]))
+		dataLen = C.opus_int32(len(f))
+	}
+
+	n := C.opus_multistream_decode(d.cDec, dataPtr, dataLen,
+		(*C.opus_int16)(unsafe.Pointer(&buf[0])), C.int(len(buf)/d.channels), 0)
+	if n < 0 {
+		return 0, fmt.Errorf("opus: decode failed: %s", C.GoString(C.opus_strerror(n)))
+	}
+	return int(n), nil
+}
+
+// SampleRate returns the sample rate of this decoder.
+func (d *MultistreamDecoder) SampleRate() int {
+	return d.sampleRate
+}
+
+// Channels returns the number of output channels of this decoder.
+func (d *MultistreamDecoder) Channels() int {
+	return d.channels
+}
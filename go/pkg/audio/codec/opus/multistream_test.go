@@ -0,0 +1,112 @@
+package opus
+
+import (
+	"math"
+	"testing"
+)
+
+// sinePCM returns a frame of interleaved samples with a different tone on
+// each channel.
+func sinePCM(sampleRate, channels, frameSize int) []int16 {
+	pcm := make([]int16, frameSize*channels)
+	for i := range frameSize {
+		ti := float64(i) / float64(sampleRate)
+		for ch := range channels {
+			pcm[i*channels+ch] = int16(math.Sin(2*math.Pi*float64(220*(ch+1))*ti) * 8000)
+		}
+	}
+	return pcm
+}
+
+func TestMultistreamEncoderDecoder(t *testing.T) {
+	sampleRate := 16000
+	channels := 4
+
+	// Four microphones as four mono streams
+	enc, err := NewSurroundEncoder(sampleRate, channels, MappingFamilyIndependent, ApplicationVoIP)
+	if err != nil {
+		t.Fatalf("failed to create encoder: %v", err)
+	}
+	defer enc.Close()
+	if enc.Streams() != 4 || enc.CoupledStreams() != 0 {
+		t.Errorf("streams = %d (%d coupled), want 4 (0 coupled)", enc.Streams(), enc.CoupledStreams())
+	}
+	if err := enc.SetDTX(true); err != nil {
+		t.Fatalf("SetDTX failed: %v", err)
+	}
+	if err := enc.SetComplexity(5); err != nil {
+		t.Fatalf("SetComplexity failed: %v", err)
+	}
+
+	dec, err := NewMultistreamDecoder(sampleRate, channels, enc.Streams(), enc.CoupledStreams(), enc.Mapping())
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+	defer dec.Close()
+
+	frameSize := enc.FrameSize20ms()
+	frame, err := enc.Encode(sinePCM(sampleRate, channels, frameSize), frameSize)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	decoded, err := dec.Decode(frame)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if got := len(decoded) / 2 / channels; got != frameSize {
+		t.Errorf("decoded %d samples, want %d", got, frameSize)
+	}
+
+	// Packet loss concealment
+	plc, err := dec.Decode(nil)
+	if err != nil {
+		t.Fatalf("PLC decode failed: %v", err)
+	}
+	if got := len(plc) / 2 / channels; got != frameSize {
+		t.Errorf("PLC generated %d samples, want %d", got, frameSize)
+	}
+}
+
+func TestMultistreamEncoderCoupled(t *testing.T) {
+	sampleRate := 48000
+	channels := 3
+
+	// One stereo stream (channels 0, 1) and one mono stream (channel 2)
+	mapping := []byte{0, 1, 2}
+	enc, err := NewMultistreamEncoder(sampleRate, channels, 2, 1, mapping, ApplicationAudio)
+	if err != nil {
+		t.Fatalf("failed to create encoder: %v", err)
+	}
+	defer enc.Close()
+	dec, err := NewMultistreamDecoder(sampleRate, channels, 2, 1, mapping)
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+	defer dec.Close()
+
+	frameSize := enc.FrameSize20ms()
+	frame, err := enc.Encode(sinePCM(sampleRate, channels, frameSize), frameSize)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	if !frame.IsStereo() {
+		t.Error("expected the first stream to be stereo")
+	}
+	buf := make([]int16, frameSize*channels)
+	n, err := dec.DecodeTo(frame, buf)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if n != frameSize {
+		t.Errorf("decoded %d samples, want %d", n, frameSize)
+	}
+}
+
+func TestNewMultistreamEncoder_InvalidMapping(t *testing.T) {
+	if _, err := NewMultistreamEncoder(16000, 2, 2, 0, []byte{0}, ApplicationVoIP); err == nil {
+		t.Error("expected error for short mapping")
+	}
+	if _, err := NewMultistreamDecoder(16000, 0, 1, 0, nil); err == nil {
+		t.Error("expected error for zero channels")
+	}
+}
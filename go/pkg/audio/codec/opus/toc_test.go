@@ -155,3 +155,20 @@ func TestFrameDuration_Frame(t *testing.T) {
 		t.Errorf("Frame.Duration() = %v, want %v", got, expected)
 	}
 }
+
+func TestFrameIsDTX(t *testing.T) {
+	tests := []struct {
+		frame Frame
+		want  bool
+	}{
+		{Frame{}, true},
+		{Frame{1 << 3}, true},
+		{Frame{1 << 3, 0x00}, true},
+		{Frame{1 << 3, 0x00, 0x00}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.frame.IsDTX(); got != tt.want {
+			t.Errorf("Frame(%v).IsDTX() = %v, want %v", []byte(tt.frame), got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,9 @@
 // This package serves as an umbrella for audio-related sub-packages:
 //
 //   - pcm: PCM (Pulse Code Modulation) audio format handling
+//   - quality: packet loss, gap and MOS metrics of received audio streams
+//   - spectrum: FFT spectra, mel spectrograms, and pitch estimation
+//   - watermark: inaudible spread-spectrum marking of synthetic speech
 //
 // For buffer utilities, use the separate github.com/haivivi/giztoy/go/pkg/buffer package.
 //
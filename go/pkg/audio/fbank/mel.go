@@ -2,6 +2,27 @@ package fbank
 
 import "math"
 
+// HammingWindow returns a Hamming window of length n.
+func HammingWindow(n int) []float64 {
+	return hammingWindow(n)
+}
+
+// HzToMel converts frequency in Hz to the HTK mel scale.
+func HzToMel(hz float64) float64 {
+	return hzToMel(hz)
+}
+
+// MelToHz converts an HTK mel scale value back to Hz.
+func MelToHz(mel float64) float64 {
+	return melToHz(mel)
+}
+
+// MelFilterBank returns triangular mel filters as a [numMels][fftSize/2+1]
+// matrix, using the same layout as the Extractor.
+func MelFilterBank(numMels, fftSize, sampleRate int, lowFreq, highFreq float64) [][]float64 {
+	return melFilterBank(numMels, fftSize, sampleRate, lowFreq, highFreq)
+}
+
 // hammingWindow generates a Hamming window of the given length.
 func hammingWindow(n int) []float64 {
 	w := make([]float64, n)
@@ -0,0 +1,131 @@
+package pcm
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+// Crossfade returns a reader that transitions from a to b. The first
+// duration of the output mixes a fading out with b fading in, using
+// equal-power curves so the loudness stays even across the transition;
+// after that the output is the rest of b, and a is no longer read.
+//
+// Both readers carry 16-bit PCM audio in the given format. If a ends during
+// the transition, it is treated as silence. The reader returns io.EOF when
+// b ends.
+//
+// A non-positive duration switches to b immediately.
+func Crossfade(a, b io.Reader, duration time.Duration, format Format) io.Reader {
+	return &crossfader{
+		a:    a,
+		b:    b,
+		ramp: gainRamp{total: format.SamplesInDuration(duration) * int64(format.Channels())},
+	}
+}
+
+type crossfader struct {
+	a, b  io.Reader
+	ramp  gainRamp
+	aDone bool
+	bDone bool
+	abuf  []byte
+}
+
+func (c *crossfader) Read(p []byte) (int, error) {
+	if c.bDone {
+		return 0, io.EOF
+	}
+	if c.ramp.done() {
+		return c.b.Read(p)
+	}
+
+	// Read a whole number of samples, up to the end of the transition.
+	n := min(len(p), int(c.ramp.total-c.ramp.pos)*2) &^ 1
+	if n == 0 {
+		return 0, nil
+	}
+	nb, err := readSamples(c.b, p[:n])
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		c.bDone = true
+	}
+	if nb == 0 {
+		return 0, io.EOF
+	}
+
+	if len(c.abuf) < nb {
+		c.abuf = make([]byte, nb)
+	}
+	abuf := c.abuf[:nb]
+	na := 0
+	if !c.aDone {
+		na, err = readSamples(c.a, abuf)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return 0, err
+			}
+			c.aDone = true
+		}
+	}
+
+	for i := 0; i < nb/2; i++ {
+		out, in := c.ramp.gains(i)
+		s := float32(int16(binary.LittleEndian.Uint16(p[i*2:]))) * in
+		if i*2 < na {
+			s += float32(int16(binary.LittleEndian.Uint16(abuf[i*2:]))) * out
+		}
+		binary.LittleEndian.PutUint16(p[i*2:], uint16(clipInt16(s)))
+	}
+	c.ramp.pos += int64(nb / 2)
+	return nb, nil
+}
+
+// readSamples reads up to len(p) bytes from r, stopping early only at the
+// end of r, and returns a whole number of 16-bit samples. At the end of r
+// it returns io.EOF along with the last samples.
+func readSamples(r io.Reader, p []byte) (int, error) {
+	n, err := io.ReadFull(r, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n &^ 1, err
+}
+
+func clipInt16(s float32) int16 {
+	switch {
+	case s > math.MaxInt16:
+		return math.MaxInt16
+	case s < math.MinInt16:
+		return math.MinInt16
+	}
+	return int16(s)
+}
+
+// gainRamp is a sample-accurate equal-power fade over total samples, of
+// which pos have been played.
+type gainRamp struct {
+	pos, total int64
+}
+
+func (r *gainRamp) done() bool {
+	return r.pos >= r.total
+}
+
+// gains returns the gains of the fading out and fading in signals at the
+// i-th sample from the current position. Their squares sum to 1.
+func (r *gainRamp) gains(i int) (out, in float32) {
+	if r.total <= 0 {
+		return 0, 1
+	}
+	t := float64(r.pos+int64(i)) / float64(r.total)
+	if t >= 1 {
+		return 0, 1
+	}
+	sin, cos := math.Sincos(t * math.Pi / 2)
+	return float32(cos), float32(sin)
+}
@@ -0,0 +1,168 @@
+package pcm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+)
+
+// constant returns duration of 16kHz mono audio with every sample set to v.
+func constant(v int16, duration time.Duration) []byte {
+	data := make([]byte, L16Mono16K.BytesInDuration(duration))
+	for i := 0; i < len(data); i += 2 {
+		binary.LittleEndian.PutUint16(data[i:], uint16(v))
+	}
+	return data
+}
+
+func samplesOf(data []byte) []int16 {
+	s := make([]int16, len(data)/2)
+	for i := range s {
+		s[i] = int16(binary.LittleEndian.Uint16(data[i*2:]))
+	}
+	return s
+}
+
+// maxStep returns the largest difference between adjacent samples.
+func maxStep(s []int16) int {
+	var step int
+	for i := 1; i < len(s); i++ {
+		d := int(s[i]) - int(s[i-1])
+		step = max(step, d, -d)
+	}
+	return step
+}
+
+func TestCrossfade(t *testing.T) {
+	a := bytes.NewReader(constant(10000, 200*time.Millisecond))
+	b := bytes.NewReader(constant(20000, 300*time.Millisecond))
+
+	out, err := io.ReadAll(Crossfade(a, b, 100*time.Millisecond, L16Mono16K))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := samplesOf(out)
+	if len(s) != 4800 {
+		t.Fatalf("samples = %d, want 4800 (the length of b)", len(s))
+	}
+	if s[0] != 10000 {
+		t.Errorf("first sample = %d, want a's 10000", s[0])
+	}
+	// Equal-power at the midpoint: (10000 + 20000) * cos(pi/4).
+	if mid := s[800]; mid < 21100 || mid > 21300 {
+		t.Errorf("midpoint sample = %d, want about 21213", mid)
+	}
+	for i, v := range s[1600:] {
+		if v != 20000 {
+			t.Fatalf("sample %d after the transition = %d, want b's 20000", 1600+i, v)
+		}
+	}
+	if step := maxStep(s); step > 50 {
+		t.Errorf("max step = %d, want a smooth transition", step)
+	}
+	if a.Len() != 200*32-100*32 {
+		t.Errorf("a has %d bytes left, want it read only during the transition", a.Len())
+	}
+}
+
+func TestCrossfade_ShortInputs(t *testing.T) {
+	// a ends halfway through the transition.
+	a := bytes.NewReader(constant(10000, 50*time.Millisecond))
+	b := bytes.NewReader(constant(0, 200*time.Millisecond))
+	out, err := io.ReadAll(Crossfade(a, b, 100*time.Millisecond, L16Mono16K))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := samplesOf(out)
+	if len(s) != 3200 {
+		t.Fatalf("samples = %d, want 3200", len(s))
+	}
+	if s[0] != 10000 || s[799] == 0 || s[800] != 0 {
+		t.Errorf("samples around the end of a: %d, %d, %d", s[0], s[799], s[800])
+	}
+
+	// b ends during the transition.
+	a = bytes.NewReader(constant(10000, 200*time.Millisecond))
+	b = bytes.NewReader(constant(10000, 30*time.Millisecond))
+	out, err = io.ReadAll(Crossfade(a, b, 100*time.Millisecond, L16Mono16K))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != len(constant(0, 30*time.Millisecond)) {
+		t.Errorf("len = %d, want the length of b", len(out))
+	}
+
+	// No transition.
+	out, err = io.ReadAll(Crossfade(bytes.NewReader(constant(1, time.Second)), bytes.NewReader(constant(2, 10*time.Millisecond)), 0, L16Mono16K))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, constant(2, 10*time.Millisecond)) {
+		t.Error("zero duration should switch to b immediately")
+	}
+}
+
+func TestMixerCrossfade(t *testing.T) {
+	format := L16Mono16K
+	mixer := NewMixer(format)
+
+	trackA, ctrlA, err := mixer.CreateTrack(WithTrackLabel("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := trackA.Write(format.DataChunk(constant(10000, time.Second))); err != nil {
+		t.Fatal(err)
+	}
+
+	var out []byte
+	buf := make([]byte, format.BytesInDuration(20*time.Millisecond))
+	read := func(d time.Duration) {
+		for range d / (20 * time.Millisecond) {
+			n, err := mixer.Read(buf)
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			out = append(out, buf[:n]...)
+		}
+	}
+	read(100 * time.Millisecond)
+
+	trackB, ctrlB, err := mixer.CreateTrack(WithTrackLabel("b"), WithCrossfade(ctrlA, 100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := trackA.Write(format.DataChunk(constant(10000, 10*time.Millisecond))); err == nil {
+		t.Error("write to the replaced track should fail")
+	}
+	if err := trackB.Write(format.DataChunk(constant(-10000, 300*time.Millisecond))); err != nil {
+		t.Fatal(err)
+	}
+	ctrlB.CloseWrite()
+	mixer.CloseWrite()
+
+	rest, err := io.ReadAll(mixer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = append(out, rest...)
+
+	s := samplesOf(out)
+	// The mixer pads its last read with silence.
+	if len(s) < 6400 {
+		t.Fatalf("samples = %d, want at least 6400 (100ms of a, then b)", len(s))
+	}
+	// A hard cut from a to b would be a step of 20000.
+	if step := maxStep(s[:6400]); step > 100 {
+		t.Errorf("max step = %d, want a smooth transition", step)
+	}
+	if s[1599] != 9999 && s[1599] != 10000 {
+		t.Errorf("last sample before the transition = %d", s[1599])
+	}
+	for i, v := range s[3200:6400] {
+		if v < -10001 || v > -9999 {
+			t.Fatalf("sample %d after the transition = %d, want b only", 3200+i, v)
+		}
+	}
+}
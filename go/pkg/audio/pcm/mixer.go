@@ -139,6 +139,28 @@ func WithTrackLabel(label string) TrackOption {
 	return trackLabelOption{label: label}
 }
 
+type crossfadeOption struct {
+	prev     *TrackCtrl
+	duration time.Duration
+}
+
+func (o crossfadeOption) apply(tc *TrackCtrl) {
+	tc.replaces = o.prev
+	tc.crossfade = o.duration
+}
+
+// WithCrossfade makes the new track replace prev: prev fades out while the
+// new track fades in over duration, then prev is closed. prev stops
+// accepting writes immediately. The fades are equal-power and applied per
+// sample as the mixer is read, so the transition has no audible cut, and
+// the fade-in starts with the first audio written to the new track.
+//
+// prev may be nil, for the first track of a sequence. A non-positive
+// duration closes prev immediately.
+func WithCrossfade(prev *TrackCtrl, duration time.Duration) TrackOption {
+	return crossfadeOption{prev: prev, duration: duration}
+}
+
 // CreateTrack creates a new writable track in the mixer. It returns the Track
 // for writing audio chunks, a TrackCtrl for controlling the track, and an error
 // if the mixer is closed or CloseWrite has been called.
@@ -166,6 +188,10 @@ func (mx *Mixer) CreateTrack(opts ...TrackOption) (Track, *TrackCtrl, error) {
 	for _, opt := range opts {
 		opt.apply(mx.head)
 	}
+	if prev := mx.head.replaces; prev != nil {
+		mx.head.replaces = nil
+		mx.crossfadeLocked(prev, mx.head)
+	}
 	select {
 	case mx.trackNotify <- struct{}{}:
 	default:
@@ -176,6 +202,26 @@ func (mx *Mixer) CreateTrack(opts ...TrackOption) (Track, *TrackCtrl, error) {
 	return tr, mx.head, nil
 }
 
+// crossfadeLocked starts the transition from prev to next.
+func (mx *Mixer) crossfadeLocked(prev, next *TrackCtrl) {
+	total := mx.output.SamplesInDuration(next.crossfade) * int64(mx.output.Channels())
+	if total <= 0 || prev.track.mx != mx {
+		prev.track.Close()
+		return
+	}
+	next.ramp = &trackRamp{gainRamp: gainRamp{total: total}, in: true}
+	out := &trackRamp{gainRamp: gainRamp{total: total}}
+	if r := prev.ramp; r != nil {
+		if !r.in {
+			return // already fading out
+		}
+		// prev is still fading in: fade out from its current gain.
+		out.pos = total - total*r.pos/r.total
+	}
+	prev.ramp = out
+	prev.track.CloseWrite()
+}
+
 // Read reads mixed audio data from the mixer into p. It mixes all active tracks
 // and returns the mixed audio in the mixer's output format. The method
 // implements io.Reader.
@@ -407,8 +453,11 @@ func (mx *Mixer) readFullLocked(p []byte) (peak float32, read, silence bool, err
 					} else {
 						s /= 32768
 					}
-					// Apply track gain
+					// Apply track gain, and the crossfade if any
 					s *= gain
+					if it.ramp != nil {
+						s *= it.ramp.gain(i)
+					}
 					// Track peak amplitude (absolute value)
 					if s > peak {
 						peak = s
@@ -420,6 +469,17 @@ func (mx *Mixer) readFullLocked(p []byte) (peak float32, read, silence bool, err
 				}
 			}
 		}
+		if it.ramp != nil && (ok || !it.ramp.in) {
+			// A fade-in follows the track's audio; a fade-out follows the
+			// mixer, so the replaced track goes away on time.
+			it.ramp.pos += int64(len(trackI16))
+			if it.ramp.done() {
+				if !it.ramp.in {
+					it.track.Close()
+				}
+				it.ramp = nil
+			}
+		}
 		prev = it
 		it = it.next
 	}
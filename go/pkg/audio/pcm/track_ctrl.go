@@ -15,6 +15,27 @@ type TrackCtrl struct {
 	gain            AtomicFloat32
 	readn           atomic.Int64
 	fadeOutDuration atomic.Int32
+
+	// Crossfade state, guarded by the mixer's mutex.
+	replaces  *TrackCtrl
+	crossfade time.Duration
+	ramp      *trackRamp
+}
+
+// trackRamp is a crossfade in progress on a track.
+type trackRamp struct {
+	gainRamp
+	in bool // fading in rather than out
+}
+
+// gain returns the crossfade gain at the i-th sample from the current
+// position.
+func (r *trackRamp) gain(i int) float32 {
+	out, in := r.gains(i)
+	if r.in {
+		return in
+	}
+	return out
 }
 
 // Label returns the label of the track.
@@ -0,0 +1,222 @@
+// Package quality scores the continuity of received audio streams, so that
+// complaints about garbled audio can be told apart from complaints about
+// what was said.
+//
+// A Meter is fed the capture timestamp and duration of each received frame.
+// A frame starting later than the end of the previous one means frames were
+// lost on the way and the receiver had to conceal the gap; a frame starting
+// earlier arrived late or twice. Gaps longer than Config.MaxGap are pauses
+// between utterances (e.g. the device stopped recording) and start a new
+// segment instead of counting as loss.
+//
+// The Report has the concealed ratio, a histogram of gap lengths and a MOS
+// (mean opinion score, 1 to 4.5) estimated with the ITU-T G.107 E-model from
+// the loss rate and burstiness. The MOS only accounts for packet loss: codec
+// and network delay impairments are left out, so a clean stream scores the
+// E-model maximum of about 4.4.
+//
+// Example:
+//
+//	m := quality.NewMeter(quality.Config{})
+//	for frame := range frames {
+//	    m.Frame(frame.Timestamp, frame.Duration())
+//	}
+//	r := m.Report()
+//	fmt.Printf("lost %.1f%%, MOS %.2f\n", r.ConcealedRatio*100, r.MOS)
+package quality
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// GapBounds are the upper bounds of the gap histogram buckets of a Report.
+var GapBounds = [...]time.Duration{
+	20 * time.Millisecond,
+	40 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+}
+
+// Config configures a Meter.
+type Config struct {
+	// FrameDuration is the duration of frames whose duration is unknown
+	// (passed as 0). Defaults to 20ms.
+	FrameDuration time.Duration
+
+	// Tolerance is the timestamp jitter below which consecutive frames are
+	// contiguous. Defaults to half of FrameDuration.
+	Tolerance time.Duration
+
+	// MaxGap is the longest gap counted as loss. Longer gaps are pauses
+	// and start a new segment. Defaults to 1s.
+	MaxGap time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.FrameDuration <= 0 {
+		c.FrameDuration = 20 * time.Millisecond
+	}
+	if c.Tolerance <= 0 {
+		c.Tolerance = c.FrameDuration / 2
+	}
+	if c.MaxGap <= 0 {
+		c.MaxGap = time.Second
+	}
+	return c
+}
+
+// Report is a snapshot of the measurements of a Meter.
+type Report struct {
+	// Segments is the number of contiguous runs of audio, separated by
+	// pauses longer than Config.MaxGap.
+	Segments int64 `json:"segments"`
+
+	// Received is the number of received frames, Lost the estimated number
+	// of lost frames.
+	Received int64 `json:"received"`
+	Lost     int64 `json:"lost"`
+
+	// Late is the number of frames that started before the end of the
+	// previous frame, i.e. arrived out of order or twice.
+	Late int64 `json:"late,omitzero"`
+
+	// Bursts is the number of gaps, each a run of one or more lost frames.
+	Bursts int64 `json:"bursts"`
+
+	// Duration is the received audio, LostDuration the total length of the
+	// gaps.
+	Duration     time.Duration `json:"duration"`
+	LostDuration time.Duration `json:"lost_duration"`
+
+	// ConcealedRatio is Lost / (Received + Lost), the fraction of frames
+	// the receiver had to conceal.
+	ConcealedRatio float64 `json:"concealed_ratio"`
+
+	// Gaps counts the gaps by length: Gaps[i] those up to GapBounds[i],
+	// the last entry those longer than the last bound.
+	Gaps [len(GapBounds) + 1]int64 `json:"gaps"`
+
+	// MOS is the estimated mean opinion score, from 1 (bad) to 4.5.
+	MOS float64 `json:"mos"`
+}
+
+// Meter measures the continuity of a stream of frames. It is safe for
+// concurrent use.
+type Meter struct {
+	cfg Config
+
+	mu     sync.Mutex
+	next   time.Time     // expected start of the next frame
+	frame  time.Duration // duration of the last frame
+	report Report
+}
+
+// NewMeter creates a Meter.
+func NewMeter(cfg Config) *Meter {
+	return &Meter{cfg: cfg.withDefaults()}
+}
+
+// Frame records a received frame captured at ts with duration d. A d of 0
+// means Config.FrameDuration.
+func (m *Meter) Frame(ts time.Time, d time.Duration) {
+	if d <= 0 {
+		d = m.cfg.FrameDuration
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := &m.report
+	r.Received++
+	r.Duration += d
+
+	if m.next.IsZero() {
+		r.Segments++
+		m.next, m.frame = ts.Add(d), d
+		return
+	}
+
+	gap := ts.Sub(m.next)
+	switch {
+	case gap < -m.cfg.Tolerance:
+		// Out of order or duplicated: the receiver has already played
+		// or concealed this span, so the frame does not move the stream.
+		r.Late++
+		return
+	case gap <= m.cfg.Tolerance:
+	case gap > m.cfg.MaxGap:
+		r.Segments++
+	default:
+		lost := int64(math.Round(float64(gap) / float64(m.frame)))
+		r.Lost += max(lost, 1)
+		r.LostDuration += gap
+		r.Bursts++
+		i, _ := slices.BinarySearch(GapBounds[:], gap)
+		r.Gaps[i]++
+	}
+	m.next, m.frame = ts.Add(d), d
+}
+
+// Report returns the measurements so far.
+func (m *Meter) Report() Report {
+	m.mu.Lock()
+	r := m.report
+	m.mu.Unlock()
+
+	if total := r.Received + r.Lost; total > 0 {
+		r.ConcealedRatio = float64(r.Lost) / float64(total)
+	}
+	r.MOS = estimateMOS(r.Received, r.Lost, r.Bursts)
+	return r
+}
+
+// Reset discards the measurements, e.g. when a new session starts on the
+// same stream.
+func (m *Meter) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next, m.frame = time.Time{}, 0
+	m.report = Report{}
+}
+
+// E-model constants (ITU-T G.107, G.113 Appendix I): the maximum rating
+// factor with default parameters, and the packet-loss robustness of a codec
+// with concealment. Ie is 0, as for a wideband codec at a high bitrate.
+const (
+	emodelR0  = 93.2
+	emodelBpl = 25.1
+)
+
+// estimateMOS estimates the MOS of a stream from its packet loss with the
+// E-model. Burstiness follows the two-state Markov model of G.107: p is the
+// probability of losing a frame after a received one, q of receiving a frame
+// after a lost one, and BurstR = 1/(p+q) is 1 for random loss and grows as
+// losses cluster.
+func estimateMOS(received, lost, bursts int64) float64 {
+	if received+lost == 0 {
+		return 0
+	}
+	ppl := 100 * float64(lost) / float64(received+lost)
+	burstR := 1.0
+	if lost > 0 && received > 0 {
+		p := float64(bursts) / float64(received)
+		q := float64(bursts) / float64(lost)
+		burstR = max(1/(p+q), 1)
+	}
+	ie := 95 * ppl / (ppl/burstR + emodelBpl)
+	return mosFromR(emodelR0 - ie)
+}
+
+// mosFromR converts an E-model rating factor to a MOS (G.107 Annex B).
+func mosFromR(r float64) float64 {
+	switch {
+	case r <= 0:
+		return 1
+	case r >= 100:
+		return 4.5
+	}
+	return 1 + 0.035*r + 7e-6*r*(r-60)*(100-r)
+}
@@ -0,0 +1,148 @@
+package quality
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+const frame = 20 * time.Millisecond
+
+var t0 = time.UnixMilli(1_700_000_000_000)
+
+// feed records n contiguous frames starting at ts and returns the end.
+func feed(m *Meter, ts time.Time, n int) time.Time {
+	for range n {
+		m.Frame(ts, frame)
+		ts = ts.Add(frame)
+	}
+	return ts
+}
+
+func TestMeterClean(t *testing.T) {
+	m := NewMeter(Config{})
+	feed(m, t0, 100)
+
+	r := m.Report()
+	if r.Received != 100 || r.Lost != 0 || r.Bursts != 0 || r.Segments != 1 {
+		t.Errorf("report = %+v", r)
+	}
+	if r.Duration != 2*time.Second {
+		t.Errorf("Duration = %v, want 2s", r.Duration)
+	}
+	if r.ConcealedRatio != 0 {
+		t.Errorf("ConcealedRatio = %v, want 0", r.ConcealedRatio)
+	}
+	if math.Abs(r.MOS-4.41) > 0.01 {
+		t.Errorf("MOS = %.3f, want 4.41", r.MOS)
+	}
+}
+
+func TestMeterJitter(t *testing.T) {
+	m := NewMeter(Config{})
+	ts := t0
+	for i := range 50 {
+		jitter := time.Duration(i%3-1) * 5 * time.Millisecond
+		m.Frame(ts.Add(jitter), frame)
+		ts = ts.Add(frame)
+	}
+	if r := m.Report(); r.Lost != 0 || r.Late != 0 {
+		t.Errorf("jitter within tolerance: report = %+v", r)
+	}
+}
+
+func TestMeterLoss(t *testing.T) {
+	m := NewMeter(Config{})
+	ts := feed(m, t0, 50)
+	ts = feed(m, ts.Add(frame), 50)           // 1 lost
+	ts = feed(m, ts.Add(3*frame), 50)         // 3 lost
+	feed(m, ts.Add(300*time.Millisecond), 48) // 15 lost
+
+	r := m.Report()
+	if r.Received != 198 || r.Lost != 19 || r.Bursts != 3 || r.Segments != 1 {
+		t.Fatalf("report = %+v", r)
+	}
+	if r.LostDuration != 380*time.Millisecond {
+		t.Errorf("LostDuration = %v, want 380ms", r.LostDuration)
+	}
+	if want := 19.0 / 217; math.Abs(r.ConcealedRatio-want) > 1e-9 {
+		t.Errorf("ConcealedRatio = %v, want %v", r.ConcealedRatio, want)
+	}
+	want := [len(GapBounds) + 1]int64{1, 0, 1, 0, 1, 0}
+	if r.Gaps != want {
+		t.Errorf("Gaps = %v, want %v", r.Gaps, want)
+	}
+	if r.MOS >= 4 || r.MOS <= 1 {
+		t.Errorf("MOS = %.2f, want degraded", r.MOS)
+	}
+}
+
+func TestMeterBurstiness(t *testing.T) {
+	// Same loss rate: 10 random single losses score higher than one burst
+	// of 10 frames.
+	random := NewMeter(Config{})
+	ts := t0
+	for range 10 {
+		ts = feed(random, ts, 19).Add(frame)
+	}
+	feed(random, ts, 10)
+	burst := NewMeter(Config{})
+	feed(burst, feed(burst, t0, 100).Add(10*frame), 100)
+
+	rr, br := random.Report(), burst.Report()
+	if rr.Lost != 10 || br.Lost != 10 {
+		t.Fatalf("lost = %d, %d, want 10", rr.Lost, br.Lost)
+	}
+	if rr.MOS <= br.MOS {
+		t.Errorf("MOS random = %.2f, burst = %.2f, want random higher", rr.MOS, br.MOS)
+	}
+}
+
+func TestMeterPause(t *testing.T) {
+	m := NewMeter(Config{})
+	ts := feed(m, t0, 10)
+	feed(m, ts.Add(5*time.Second), 10)
+
+	r := m.Report()
+	if r.Segments != 2 || r.Lost != 0 || r.Bursts != 0 {
+		t.Errorf("report = %+v", r)
+	}
+}
+
+func TestMeterLate(t *testing.T) {
+	m := NewMeter(Config{})
+	m.Frame(t0, frame)
+	m.Frame(t0.Add(2*frame), frame) // frame 1 lost...
+	m.Frame(t0.Add(frame), frame)   // ...then arrives late
+	m.Frame(t0.Add(3*frame), frame)
+
+	r := m.Report()
+	if r.Received != 4 || r.Late != 1 || r.Lost != 1 {
+		t.Errorf("report = %+v", r)
+	}
+}
+
+func TestMeterDefaultDuration(t *testing.T) {
+	m := NewMeter(Config{FrameDuration: 60 * time.Millisecond})
+	m.Frame(t0, 0)
+	m.Frame(t0.Add(60*time.Millisecond), 0)
+	m.Frame(t0.Add(180*time.Millisecond), 0)
+
+	r := m.Report()
+	if r.Duration != 180*time.Millisecond || r.Lost != 1 {
+		t.Errorf("report = %+v", r)
+	}
+}
+
+func TestMeterReset(t *testing.T) {
+	m := NewMeter(Config{})
+	feed(m, t0, 10)
+	m.Reset()
+	if r := m.Report(); r.Received != 0 || r.MOS != 0 {
+		t.Errorf("after Reset: report = %+v", r)
+	}
+	feed(m, t0.Add(time.Hour), 10)
+	if r := m.Report(); r.Received != 10 || r.Segments != 1 {
+		t.Errorf("report = %+v", r)
+	}
+}
@@ -3,11 +3,12 @@
 //
 // It supports:
 //   - Sample rate conversion (e.g., 44100Hz to 48000Hz)
-//   - Channel conversion (mono to stereo or stereo to mono)
+//   - Channel conversion between any channel counts (e.g., a microphone
+//     array to mono)
+//   - Sample conversion between 16-bit signed integers and 32-bit floats
 //   - Streaming interface via io.Reader
 //
-// The package uses high-quality resampling by default and handles 16-bit signed
-// integer audio samples.
+// The package uses high-quality resampling by default.
 //
 // Example usage:
 //
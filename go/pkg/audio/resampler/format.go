@@ -1,25 +1,79 @@
 package resampler
 
-// Format describes the audio format for resampling. Currently only supports
-// 16-bit signed integer samples.
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Format describes the audio format for resampling. Samples are interleaved
+// and little-endian, either 16-bit signed integers or 32-bit floats.
 type Format struct {
 	// SampleRate is the sample rate in Hz (e.g., 44100, 48000).
 	SampleRate int
 
 	// Stereo indicates stereo (2 channels) if true, mono (1 channel) if false.
+	// Ignored if Channels is set.
 	Stereo bool
+
+	// Channels is the number of channels, for layouts beyond stereo such as
+	// microphone arrays. If zero, Stereo decides.
+	Channels int
+
+	// Float32 selects 32-bit float samples in [-1, 1] instead of 16-bit
+	// signed integers.
+	Float32 bool
 }
 
 func (f Format) channels() int {
+	if f.Channels > 0 {
+		return f.Channels
+	}
 	if f.Stereo {
 		return 2
 	}
 	return 1
 }
 
-func (f Format) sampleBytes() int {
-	if f.Stereo {
+// bytesPerSample returns the size of one sample of one channel.
+func (f Format) bytesPerSample() int {
+	if f.Float32 {
 		return 4
 	}
 	return 2
 }
+
+// sampleBytes returns the size of one frame (one sample of every channel).
+func (f Format) sampleBytes() int {
+	return f.channels() * f.bytesPerSample()
+}
+
+// decode converts samples in b to float64 in [-1, 1], appending to dst.
+func (f Format) decode(dst []float64, b []byte) []float64 {
+	if f.Float32 {
+		for i := 0; i+4 <= len(b); i += 4 {
+			dst = append(dst, float64(math.Float32frombits(binary.LittleEndian.Uint32(b[i:]))))
+		}
+		return dst
+	}
+	for i := 0; i+2 <= len(b); i += 2 {
+		dst = append(dst, float64(int16(binary.LittleEndian.Uint16(b[i:])))/32768.0)
+	}
+	return dst
+}
+
+// encode converts float64 samples to the format, appending to dst. Integer
+// samples are clipped to the int16 range.
+func (f Format) encode(dst []byte, samples []float64) []byte {
+	if f.Float32 {
+		for _, s := range samples {
+			dst = binary.LittleEndian.AppendUint32(dst, math.Float32bits(float32(s)))
+		}
+		return dst
+	}
+	for _, s := range samples {
+		v := math.Round(s * 32768.0)
+		v = max(min(v, 32767), -32768)
+		dst = binary.LittleEndian.AppendUint16(dst, uint16(int16(v)))
+	}
+	return dst
+}
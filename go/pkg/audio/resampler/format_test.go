@@ -18,6 +18,11 @@ func TestFormat_channels(t *testing.T) {
 			format: Format{SampleRate: 48000, Stereo: true},
 			want:   2,
 		},
+		{
+			name:   "channels overrides stereo",
+			format: Format{SampleRate: 16000, Stereo: true, Channels: 6},
+			want:   6,
+		},
 	}
 
 	for _, tt := range tests {
@@ -45,6 +50,11 @@ func TestFormat_sampleBytes(t *testing.T) {
 			format: Format{SampleRate: 48000, Stereo: true},
 			want:   4, // 2 channels * 2 bytes (16-bit)
 		},
+		{
+			name:   "4 channels float32",
+			format: Format{SampleRate: 16000, Channels: 4, Float32: true},
+			want:   16, // 4 channels * 4 bytes (float32)
+		},
 	}
 
 	for _, tt := range tests {
@@ -56,3 +66,26 @@ func TestFormat_sampleBytes(t *testing.T) {
 	}
 }
 
+
+func TestFormat_encodeDecode(t *testing.T) {
+	samples := []float64{0, 0.5, -0.5, -1}
+
+	for _, f := range []Format{{}, {Float32: true}} {
+		b := f.encode(nil, samples)
+		if len(b) != len(samples)*f.bytesPerSample() {
+			t.Fatalf("Float32=%v: encoded %d bytes, want %d", f.Float32, len(b), len(samples)*f.bytesPerSample())
+		}
+		got := f.decode(nil, b)
+		for i := range samples {
+			if got[i] != samples[i] {
+				t.Errorf("Float32=%v: sample %d = %v, want %v", f.Float32, i, got[i], samples[i])
+			}
+		}
+	}
+
+	// Integer samples clip.
+	got := Format{}.decode(nil, Format{}.encode(nil, []float64{1.5, -1.5}))
+	if got[0] != 32767.0/32768.0 || got[1] != -1 {
+		t.Errorf("clipped samples = %v", got)
+	}
+}
@@ -12,7 +12,7 @@ import (
 )
 
 // Resampler wraps an io.Reader and resamples audio from srcFmt to dstFmt.
-// It supports sample rate conversion and channel conversion (mono↔stereo).
+// It supports sample rate, channel and sample type conversion.
 // The resampler must be closed with Close() to release resources.
 type Resampler interface {
 	io.ReadCloser
@@ -30,29 +30,43 @@ type Soxr struct {
 
 	mu            sync.Mutex
 	closeErr      error
-	resampler     resampling.Resampler
+	resamplers    []resampling.Resampler // one per destination channel
+	flushed       bool                   // the resamplers were flushed at EOF
 	leftover      []byte
 	needsResample bool
+	passthrough   bool
 }
 
 // New creates a new Resampler that resamples audio from srcFmt to dstFmt. It
-// supports sample rate conversion and channel conversion (mono↔stereo). The
-// formats must use 16-bit signed integer samples.
+// supports sample rate conversion, any channel counts and conversion between
+// 16-bit integer and 32-bit float samples.
+//
+// Channels are converted as follows: downmixing to mono averages all
+// channels, upmixing from mono duplicates the channel, and otherwise the
+// first source channels are kept, repeated cyclically if the destination
+// has more.
 func New(src io.Reader, srcFmt, dstFmt Format) (Resampler, error) {
+	if srcFmt.Channels < 0 || dstFmt.Channels < 0 {
+		return nil, fmt.Errorf("resampler: invalid channel count %d -> %d", srcFmt.Channels, dstFmt.Channels)
+	}
 	needsResample := srcFmt.SampleRate != dstFmt.SampleRate
 
-	var resampler resampling.Resampler
+	// One mono resampler per channel: the resampler filters a single
+	// channel of samples and flushes only the first of several.
+	var resamplers []resampling.Resampler
 	if needsResample {
 		config := &resampling.Config{
 			InputRate:  float64(srcFmt.SampleRate),
 			OutputRate: float64(dstFmt.SampleRate),
-			Channels:   dstFmt.channels(),
+			Channels:   1,
 			Quality:    resampling.QualitySpec{Preset: resampling.QualityHigh},
 		}
-		var err error
-		resampler, err = resampling.New(config)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create resampler: %w", err)
+		for range dstFmt.channels() {
+			resampler, err := resampling.New(config)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create resampler: %w", err)
+			}
+			resamplers = append(resamplers, resampler)
 		}
 	}
 
@@ -62,8 +76,11 @@ func New(src io.Reader, srcFmt, dstFmt Format) (Resampler, error) {
 
 		dstFmt: dstFmt,
 
-		resampler:     resampler,
+		resamplers:    resamplers,
 		needsResample: needsResample,
+		passthrough: !needsResample &&
+			srcFmt.channels() == dstFmt.channels() &&
+			srcFmt.Float32 == dstFmt.Float32,
 	}
 
 	return rs, nil
@@ -102,66 +119,51 @@ func (r *Soxr) Read(p []byte) (int, error) {
 	return r.readAndProcess(p)
 }
 
-// readAndProcess reads from source and processes through resampler.
+// readAndProcess reads from source and converts channels, sample type and
+// sample rate as needed.
 func (r *Soxr) readAndProcess(p []byte) (int, error) {
-	if !r.needsResample {
-		// No sample rate conversion, just channel conversion
-		return r.readPassthrough(p)
+	if r.passthrough {
+		return r.src.Read(p)
 	}
 
-	// Read source data
 	// Estimate how much source data we need based on ratio
-	ratio := float64(r.srcFmt.SampleRate) / float64(r.dstFmt.SampleRate)
-	srcBytesNeeded := int(float64(len(p))*ratio) + r.srcFmt.sampleBytes()*4 // Extra buffer
-
-	if cap(r.readBuf) < srcBytesNeeded {
-		r.readBuf = make([]byte, srcBytesNeeded)
+	frames := len(p) / r.dstFmt.sampleBytes()
+	if r.needsResample {
+		ratio := float64(r.srcFmt.SampleRate) / float64(r.dstFmt.SampleRate)
+		frames = int(float64(frames)*ratio) + 4 // Extra buffer
 	}
-
-	bytesRead, readErr := r.readSourceWithChannelConv(srcBytesNeeded)
-	if bytesRead == 0 {
-		if readErr != nil {
-			return 0, readErr
-		}
-		return 0, io.EOF
+	srcLen := frames * r.srcFmt.sampleBytes()
+	if cap(r.readBuf) < srcLen {
+		r.readBuf = make([]byte, srcLen)
 	}
 
-	// Convert bytes to float64 samples (normalized to -1.0 to 1.0)
-	numChannels := r.dstFmt.channels()
-	numFrames := bytesRead / (2 * numChannels) // 2 bytes per int16, per channel
-	input := make([]float64, numFrames*numChannels)
-
-	for i := 0; i < numFrames*numChannels; i++ {
-		sample := int16(r.readBuf[i*2]) | int16(r.readBuf[i*2+1])<<8
-		input[i] = float64(sample) / 32768.0
+	bytesRead, readErr := r.src.Read(r.readBuf[:srcLen])
+	// At EOF the resamplers still hold the samples of their filter delay
+	flush := r.needsResample && !r.flushed && readErr == io.EOF
+	if bytesRead == 0 && !flush {
+		return 0, readErr
 	}
 
-	// Resample
-	output, err := r.resampler.Process(input)
-	if err != nil {
-		return 0, fmt.Errorf("resample error: %w", err)
-	}
+	// Convert to float64 samples (normalized to -1.0 to 1.0) in the
+	// destination channel layout
+	input := r.srcFmt.decode(nil, r.readBuf[:bytesRead])
+	input = convertChannels(input, r.srcFmt.channels(), r.dstFmt.channels())
 
-	if len(output) == 0 {
-		if readErr != nil {
-			return 0, readErr
+	output := input
+	if r.needsResample {
+		var err error
+		output, err = r.resample(input, flush)
+		if err != nil {
+			return 0, fmt.Errorf("resample error: %w", err)
 		}
-		return 0, nil
 	}
 
-	// Convert back to bytes (int16)
-	outputBytes := make([]byte, len(output)*2)
-	for i, s := range output {
-		sample := int16(s * 32767.0)
-		if s > 1.0 {
-			sample = 32767
-		} else if s < -1.0 {
-			sample = -32768
-		}
-		outputBytes[i*2] = byte(sample)
-		outputBytes[i*2+1] = byte(sample >> 8)
+	if len(output) == 0 {
+		return 0, readErr
 	}
 
+	outputBytes := r.dstFmt.encode(make([]byte, 0, len(output)*r.dstFmt.bytesPerSample()), output)
+
 	// Ensure output is aligned to sample bytes
 	outputLen := (len(outputBytes) / r.dstFmt.sampleBytes()) * r.dstFmt.sampleBytes()
 	outputBytes = outputBytes[:outputLen]
@@ -175,46 +177,45 @@ func (r *Soxr) readAndProcess(p []byte) (int, error) {
 	return n, readErr
 }
 
-// readPassthrough reads without sample rate conversion.
-func (r *Soxr) readPassthrough(p []byte) (int, error) {
-	n, err := r.readSourceWithChannelConv(len(p))
-	if n == 0 {
-		return 0, err
-	}
-	copy(p, r.readBuf[:n])
-	return n, err
-}
-
-// readSourceWithChannelConv reads from source and handles channel conversion.
-func (r *Soxr) readSourceWithChannelConv(dstLen int) (int, error) {
-	if cap(r.readBuf) < dstLen {
-		r.readBuf = make([]byte, dstLen)
-	}
-
-	if r.srcFmt.Stereo && !r.dstFmt.Stereo {
-		// Downmixing stereo to mono: need double the source data
-		srcLen := dstLen * 2
-		if cap(r.readBuf) < srcLen {
-			r.readBuf = make([]byte, srcLen)
+// resample resamples interleaved samples channel by channel, flushing the
+// resamplers if flush is true.
+func (r *Soxr) resample(input []float64, flush bool) ([]float64, error) {
+	channels := len(r.resamplers)
+	frames := len(input) / channels
+	outs := make([][]float64, channels)
+	mono := make([]float64, frames)
+	for c, rs := range r.resamplers {
+		for f := range frames {
+			mono[f] = input[f*channels+c]
+		}
+		out, err := rs.Process(mono)
+		if err != nil {
+			return nil, err
 		}
-		rn, err := r.src.Read(r.readBuf[:srcLen])
-		if rn == 0 {
-			return 0, err
+		if flush {
+			rest, err := rs.Flush()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, rest...)
 		}
-		return stereoToMono(r.readBuf[:rn]), err
+		outs[c] = out
 	}
-
-	if r.srcFmt.Stereo == r.dstFmt.Stereo {
-		// Same channel count
-		return r.src.Read(r.readBuf[:dstLen])
+	if flush {
+		r.flushed = true
 	}
 
-	// Upmixing mono to stereo
-	rn, err := r.src.Read(r.readBuf[:dstLen/2])
-	if rn == 0 {
-		return 0, err
+	n := len(outs[0])
+	for _, out := range outs[1:] {
+		n = min(n, len(out))
+	}
+	output := make([]float64, n*channels)
+	for c, out := range outs {
+		for f := range n {
+			output[f*channels+c] = out[f]
+		}
 	}
-	return monoToStereo(r.readBuf[:rn*2]), err
+	return output, nil
 }
 
 // Close releases resources and marks the resampler as closed.
@@ -231,36 +232,33 @@ func (r *Soxr) CloseWithError(err error) error {
 	if r.closeErr == nil {
 		r.closeErr = err
 	}
-	r.resampler = nil
+	r.resamplers = nil
 	return nil
 }
 
-// stereoToMono converts stereo 16-bit samples to mono in-place by averaging L
-// and R channels.
-func stereoToMono(b []byte) int {
-	numFrames := len(b) / 4
-	for i := range numFrames {
-		j := i * 4
-		k := i * 2
-		l := int16(b[j]) | int16(b[j+1])<<8
-		r := int16(b[j+2]) | int16(b[j+3])<<8
-		m := int16((int32(l) + int32(r)) / 2)
-		b[k] = byte(m)
-		b[k+1] = byte(m >> 8)
+// convertChannels converts interleaved samples from one channel count to
+// another. Mono output averages all channels; otherwise each output channel
+// c takes input channel c modulo the input channel count.
+func convertChannels(in []float64, from, to int) []float64 {
+	if from == to {
+		return in
 	}
-	return numFrames * 2
-}
-
-// monoToStereo converts mono 16-bit samples to stereo in-place by duplicating
-// each sample.
-func monoToStereo(b []byte) int {
-	stereoLen := len(b)
-	numSamples := stereoLen / 4
-	for i := numSamples - 1; i >= 0; i-- {
-		s0, s1 := b[i*2], b[i*2+1]
-		j := i * 4
-		b[j], b[j+1] = s0, s1
-		b[j+2], b[j+3] = s0, s1
+	frames := len(in) / from
+	out := make([]float64, frames*to)
+	for f := range frames {
+		src := in[f*from : (f+1)*from]
+		dst := out[f*to : (f+1)*to]
+		if to == 1 {
+			var sum float64
+			for _, s := range src {
+				sum += s
+			}
+			dst[0] = sum / float64(from)
+			continue
+		}
+		for c := range dst {
+			dst[c] = src[c%from]
+		}
 	}
-	return stereoLen
+	return out
 }
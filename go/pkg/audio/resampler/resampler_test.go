@@ -0,0 +1,108 @@
+//go:build !js
+
+package resampler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestConvertChannels(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       []float64
+		from, to int
+		want     []float64
+	}{
+		{"mono to stereo", []float64{0.1, 0.2}, 1, 2, []float64{0.1, 0.1, 0.2, 0.2}},
+		{"4 to mono", []float64{0.1, 0.2, 0.3, 0.4}, 4, 1, []float64{0.25}},
+		{"4 to stereo", []float64{0.1, 0.2, 0.3, 0.4}, 4, 2, []float64{0.1, 0.2}},
+		{"stereo to 4", []float64{0.1, 0.2}, 2, 4, []float64{0.1, 0.2, 0.1, 0.2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := convertChannels(tt.in, tt.from, tt.to)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNew_MultiChannelInt16ToFloat32(t *testing.T) {
+	// 4-channel int16 frames, downmixed to mono float32 at the same rate.
+	var src bytes.Buffer
+	for range 10 {
+		for _, s := range []int16{8192, 8192, -8192, 16384} {
+			binary.Write(&src, binary.LittleEndian, s)
+		}
+	}
+
+	r, err := New(&src,
+		Format{SampleRate: 16000, Channels: 4},
+		Format{SampleRate: 16000, Float32: true},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 10*4 {
+		t.Fatalf("got %d bytes, want 40", len(out))
+	}
+	for i := 0; i < len(out); i += 4 {
+		got := math.Float32frombits(binary.LittleEndian.Uint32(out[i:]))
+		if got != 0.1875 {
+			t.Fatalf("frame %d = %v, want 0.1875", i/4, got)
+		}
+	}
+}
+
+func TestNew_StereoResample(t *testing.T) {
+	// 1s of stereo: a sine on the left channel, silence on the right.
+	var src bytes.Buffer
+	for i := range 16000 {
+		s := int16(16384 * math.Sin(2*math.Pi*440*float64(i)/16000))
+		binary.Write(&src, binary.LittleEndian, [2]int16{s, 0})
+	}
+
+	r, err := New(&src,
+		Format{SampleRate: 16000, Channels: 2},
+		Format{SampleRate: 8000, Channels: 2},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flushed at EOF, the output is as long as the input
+	if frames := len(out) / 4; frames < 7950 || frames > 8050 {
+		t.Fatalf("got %d frames, want about 8000", frames)
+	}
+	var left, right float64
+	for i := 0; i+4 <= len(out); i += 4 {
+		l := float64(int16(binary.LittleEndian.Uint16(out[i:])))
+		r := float64(int16(binary.LittleEndian.Uint16(out[i+2:])))
+		left += l * l
+		right += r * r
+	}
+	if left == 0 || right > left*1e-6 {
+		t.Errorf("channels mixed: left energy %g, right energy %g", left, right)
+	}
+}
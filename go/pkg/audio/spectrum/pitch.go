@@ -0,0 +1,117 @@
+package spectrum
+
+import "math"
+
+// VoicedThreshold is the minimum confidence at which EstimatePitch considers
+// a frame voiced. PitchTrack reports 0 Hz for frames below it.
+const VoicedThreshold = 0.5
+
+// PitchFrame is a single pitch estimate within a PitchTrack.
+type PitchFrame struct {
+	Offset     int     // start sample of the frame
+	Hz         float64 // estimated fundamental frequency, 0 if unvoiced
+	Confidence float64 // normalized autocorrelation peak in [0, 1]
+}
+
+// EstimatePitch estimates the fundamental frequency of frame using normalized
+// autocorrelation, searching between minHz and maxHz.
+//
+// It returns the frequency in Hz and a confidence in [0, 1]. Silent frames,
+// frames shorter than two periods of minHz, and invalid ranges return (0, 0).
+// Callers typically treat confidence below VoicedThreshold as unvoiced.
+func EstimatePitch(frame []float32, sampleRate int, minHz, maxHz float64) (float64, float64) {
+	if sampleRate <= 0 || minHz <= 0 || maxHz <= minHz {
+		return 0, 0
+	}
+	minLag := int(float64(sampleRate) / maxHz)
+	maxLag := int(math.Ceil(float64(sampleRate) / minHz))
+	if minLag < 1 {
+		minLag = 1
+	}
+	n := len(frame)
+	if maxLag*2 > n {
+		maxLag = n / 2
+	}
+	if maxLag <= minLag {
+		return 0, 0
+	}
+
+	// Remove DC so low-frequency offsets don't dominate the correlation.
+	var mean float64
+	for _, s := range frame {
+		mean += float64(s)
+	}
+	mean /= float64(n)
+	x := make([]float64, n)
+	for i, s := range frame {
+		x[i] = float64(s) - mean
+	}
+
+	nsdf := make([]float64, maxLag+2)
+	for lag := minLag; lag <= maxLag+1 && lag < n; lag++ {
+		var acf, e0, e1 float64
+		for i := 0; i+lag < n; i++ {
+			acf += x[i] * x[i+lag]
+			e0 += x[i] * x[i]
+			e1 += x[i+lag] * x[i+lag]
+		}
+		if e0 == 0 || e1 == 0 {
+			continue
+		}
+		nsdf[lag] = acf / math.Sqrt(e0*e1)
+	}
+
+	// Pick the first local maximum that is close to the global maximum; this
+	// avoids octave errors where a multiple of the period scores marginally
+	// higher.
+	globalMax := 0.0
+	for lag := minLag; lag <= maxLag; lag++ {
+		globalMax = max(globalMax, nsdf[lag])
+	}
+	if globalMax <= 0 {
+		return 0, 0
+	}
+	bestLag := 0
+	for lag := minLag; lag <= maxLag; lag++ {
+		v := nsdf[lag]
+		if v < 0.9*globalMax {
+			continue
+		}
+		if (lag == minLag || v >= nsdf[lag-1]) && v >= nsdf[lag+1] {
+			bestLag = lag
+			break
+		}
+	}
+	if bestLag == 0 {
+		return 0, 0
+	}
+
+	// Parabolic interpolation around the peak for sub-sample accuracy.
+	period := float64(bestLag)
+	if bestLag > minLag && bestLag < maxLag {
+		a, b, c := nsdf[bestLag-1], nsdf[bestLag], nsdf[bestLag+1]
+		if d := a - 2*b + c; d != 0 {
+			period += 0.5 * (a - c) / d
+		}
+	}
+	return float64(sampleRate) / period, min(nsdf[bestLag], 1)
+}
+
+// PitchTrack estimates pitch over consecutive frames of frameSize samples
+// advanced by hopSize. Unvoiced frames have Hz set to 0.
+func PitchTrack(pcm []float32, sampleRate, frameSize, hopSize int, minHz, maxHz float64) []PitchFrame {
+	if frameSize <= 0 || hopSize <= 0 || len(pcm) < frameSize {
+		return nil
+	}
+	numFrames := (len(pcm)-frameSize)/hopSize + 1
+	out := make([]PitchFrame, numFrames)
+	for t := range out {
+		start := t * hopSize
+		hz, conf := EstimatePitch(pcm[start:start+frameSize], sampleRate, minHz, maxHz)
+		if conf < VoicedThreshold {
+			hz = 0
+		}
+		out[t] = PitchFrame{Offset: start, Hz: hz, Confidence: conf}
+	}
+	return out
+}
@@ -0,0 +1,233 @@
+// Package spectrum provides spectral analysis of PCM audio for tooling.
+//
+// It exposes windowed FFT magnitude/power spectra, linear and mel
+// spectrograms, and a simple autocorrelation-based pitch estimator. The FFT
+// and mel filterbank come from the fbank package, so mel output here lines
+// up with the features used for speaker recognition.
+//
+// Default parameters are tuned for 16 kHz speech:
+//
+//	SampleRate: 16000
+//	FFTSize:    512
+//	HopSize:    160 (10 ms)
+//	Window:     Hann
+//
+// Example:
+//
+//	a := spectrum.New(spectrum.DefaultConfig())
+//	spec := a.Spectrogram(samples)          // [T][FFTSize/2+1] power
+//	mel := a.MelSpectrogram(samples, 80)    // [T][80] log mel energy
+//	hz, conf := spectrum.EstimatePitch(samples[:640], 16000, 60, 800)
+package spectrum
+
+import (
+	"math"
+
+	"github.com/haivivi/giztoy/go/pkg/audio/fbank"
+)
+
+// Config controls spectral analysis parameters.
+type Config struct {
+	SampleRate int    // audio sample rate in Hz (default 16000)
+	FFTSize    int    // FFT size, must be a power of 2 (default 512)
+	WindowSize int    // analysis window length in samples (default FFTSize)
+	HopSize    int    // hop length in samples (default 160 = 10ms)
+	Window     Window // window function (default Hann)
+}
+
+// DefaultConfig returns a config suitable for 16 kHz speech.
+func DefaultConfig() Config {
+	return Config{
+		SampleRate: 16000,
+		FFTSize:    512,
+		WindowSize: 512,
+		HopSize:    160,
+		Window:     Hann,
+	}
+}
+
+// Analyzer computes spectra and spectrograms with a fixed configuration.
+// An Analyzer is safe for concurrent use; all working buffers are allocated
+// per call.
+type Analyzer struct {
+	cfg    Config
+	window []float64
+}
+
+// New creates an Analyzer. Zero fields in cfg are filled from DefaultConfig,
+// and FFTSize is rounded up to the next power of 2.
+func New(cfg Config) *Analyzer {
+	def := DefaultConfig()
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = def.SampleRate
+	}
+	if cfg.FFTSize <= 0 {
+		cfg.FFTSize = def.FFTSize
+	}
+	cfg.FFTSize = nextPow2(cfg.FFTSize)
+	if cfg.WindowSize <= 0 || cfg.WindowSize > cfg.FFTSize {
+		cfg.WindowSize = cfg.FFTSize
+	}
+	if cfg.HopSize <= 0 {
+		cfg.HopSize = def.HopSize
+	}
+	return &Analyzer{cfg: cfg, window: cfg.Window.coefficients(cfg.WindowSize)}
+}
+
+// Config returns the effective configuration.
+func (a *Analyzer) Config() Config {
+	return a.cfg
+}
+
+// NumBins returns the number of frequency bins per spectrum (FFTSize/2 + 1).
+func (a *Analyzer) NumBins() int {
+	return a.cfg.FFTSize/2 + 1
+}
+
+// BinFrequency returns the center frequency in Hz of FFT bin k.
+func (a *Analyzer) BinFrequency(k int) float64 {
+	return float64(k) * float64(a.cfg.SampleRate) / float64(a.cfg.FFTSize)
+}
+
+// NumFrames returns how many frames Spectrogram produces for n samples.
+func (a *Analyzer) NumFrames(n int) int {
+	if n < a.cfg.WindowSize {
+		return 0
+	}
+	return (n-a.cfg.WindowSize)/a.cfg.HopSize + 1
+}
+
+// Power computes the power spectrum |X(k)|² of a single frame.
+// Only the first WindowSize samples are used; shorter frames are zero-padded.
+func (a *Analyzer) Power(frame []float32) []float64 {
+	n := a.cfg.FFTSize
+	real := make([]float64, n)
+	imag := make([]float64, n)
+	return a.power(frame, real, imag)
+}
+
+// Magnitude computes the magnitude spectrum |X(k)| of a single frame.
+func (a *Analyzer) Magnitude(frame []float32) []float64 {
+	p := a.Power(frame)
+	for i, v := range p {
+		p[i] = math.Sqrt(v)
+	}
+	return p
+}
+
+// Spectrogram computes the power spectrogram of pcm.
+// Output: [T][NumBins()] where T = NumFrames(len(pcm)).
+func (a *Analyzer) Spectrogram(pcm []float32) [][]float64 {
+	numFrames := a.NumFrames(len(pcm))
+	if numFrames == 0 {
+		return nil
+	}
+	n := a.cfg.FFTSize
+	real := make([]float64, n)
+	imag := make([]float64, n)
+
+	out := make([][]float64, numFrames)
+	for t := range out {
+		start := t * a.cfg.HopSize
+		out[t] = a.power(pcm[start:start+a.cfg.WindowSize], real, imag)
+	}
+	return out
+}
+
+// MelSpectrogram computes the log mel spectrogram of pcm with numMels bands
+// spanning 0 Hz to the Nyquist frequency.
+// Output: [T][numMels] natural-log energies floored at 1e-10.
+func (a *Analyzer) MelSpectrogram(pcm []float32, numMels int) [][]float32 {
+	return a.MelSpectrogramRange(pcm, numMels, 0, float64(a.cfg.SampleRate)/2)
+}
+
+// MelSpectrogramRange is like MelSpectrogram but restricts the filterbank to
+// [lowFreq, highFreq] Hz.
+func (a *Analyzer) MelSpectrogramRange(pcm []float32, numMels int, lowFreq, highFreq float64) [][]float32 {
+	spec := a.Spectrogram(pcm)
+	if spec == nil || numMels <= 0 {
+		return nil
+	}
+	bank := fbank.MelFilterBank(numMels, a.cfg.FFTSize, a.cfg.SampleRate, lowFreq, highFreq)
+
+	out := make([][]float32, len(spec))
+	for t, power := range spec {
+		mel := make([]float32, numMels)
+		for m, filter := range bank {
+			sum := 0.0
+			for k, w := range filter {
+				sum += w * power[k]
+			}
+			if sum < 1e-10 {
+				sum = 1e-10
+			}
+			mel[m] = float32(math.Log(sum))
+		}
+		out[t] = mel
+	}
+	return out
+}
+
+// Centroid returns the spectral centroid in Hz of a power spectrum produced
+// by this Analyzer. It returns 0 for a silent spectrum.
+func (a *Analyzer) Centroid(power []float64) float64 {
+	var num, den float64
+	for k, p := range power {
+		num += a.BinFrequency(k) * p
+		den += p
+	}
+	if den == 0 {
+		return 0
+	}
+	return num / den
+}
+
+// PeakFrequency returns the frequency in Hz of the strongest bin in a power
+// spectrum, ignoring the DC bin.
+func (a *Analyzer) PeakFrequency(power []float64) float64 {
+	best := 0
+	for k := 1; k < len(power); k++ {
+		if best == 0 || power[k] > power[best] {
+			best = k
+		}
+	}
+	return a.BinFrequency(best)
+}
+
+func (a *Analyzer) power(frame []float32, real, imag []float64) []float64 {
+	ws := a.cfg.WindowSize
+	for i := range real {
+		real[i] = 0
+		imag[i] = 0
+	}
+	for i := 0; i < ws && i < len(frame); i++ {
+		real[i] = float64(frame[i]) * a.window[i]
+	}
+	fbank.FFT(real, imag)
+
+	out := make([]float64, a.NumBins())
+	for k := range out {
+		out[k] = real[k]*real[k] + imag[k]*imag[k]
+	}
+	return out
+}
+
+// Int16ToFloat32 converts little-endian int16 PCM bytes to normalized
+// float32 samples in [-1, 1).
+func Int16ToFloat32(pcm []byte) []float32 {
+	n := len(pcm) / 2
+	samples := make([]float32, n)
+	for i := 0; i < n; i++ {
+		s := int16(pcm[i*2]) | int16(pcm[i*2+1])<<8
+		samples[i] = float32(s) / 32768.0
+	}
+	return samples
+}
+
+func nextPow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
@@ -0,0 +1,126 @@
+package spectrum
+
+import (
+	"math"
+	"testing"
+)
+
+func sine(freq float64, sampleRate, n int) []float32 {
+	out := make([]float32, n)
+	for i := range out {
+		out[i] = float32(0.5 * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate)))
+	}
+	return out
+}
+
+func TestNewDefaults(t *testing.T) {
+	a := New(Config{FFTSize: 400})
+	cfg := a.Config()
+	if cfg.FFTSize != 512 {
+		t.Errorf("FFTSize = %d, want 512", cfg.FFTSize)
+	}
+	if cfg.SampleRate != 16000 || cfg.HopSize != 160 || cfg.WindowSize != 512 {
+		t.Errorf("unexpected defaults: %+v", cfg)
+	}
+	if a.NumBins() != 257 {
+		t.Errorf("NumBins = %d, want 257", a.NumBins())
+	}
+}
+
+func TestPeakFrequency(t *testing.T) {
+	a := New(DefaultConfig())
+	pcm := sine(1000, 16000, 512)
+	power := a.Power(pcm)
+	peak := a.PeakFrequency(power)
+	// Bin width is 31.25 Hz.
+	if math.Abs(peak-1000) > 31.25 {
+		t.Errorf("PeakFrequency = %f, want ~1000", peak)
+	}
+	c := a.Centroid(power)
+	if math.Abs(c-1000) > 200 {
+		t.Errorf("Centroid = %f, want ~1000", c)
+	}
+}
+
+func TestSpectrogramShape(t *testing.T) {
+	a := New(DefaultConfig())
+	pcm := sine(440, 16000, 16000)
+	spec := a.Spectrogram(pcm)
+	want := (16000-512)/160 + 1
+	if len(spec) != want {
+		t.Fatalf("frames = %d, want %d", len(spec), want)
+	}
+	if len(spec[0]) != 257 {
+		t.Errorf("bins = %d, want 257", len(spec[0]))
+	}
+	if a.Spectrogram(pcm[:100]) != nil {
+		t.Error("expected nil for input shorter than window")
+	}
+}
+
+func TestMelSpectrogram(t *testing.T) {
+	a := New(DefaultConfig())
+	pcm := sine(440, 16000, 8000)
+	mel := a.MelSpectrogram(pcm, 40)
+	if len(mel) == 0 || len(mel[0]) != 40 {
+		t.Fatalf("unexpected mel shape: %d frames", len(mel))
+	}
+	// The band covering 440 Hz must dominate the top bands.
+	frame := mel[len(mel)/2]
+	if frame[39] >= frame[5] {
+		t.Errorf("expected low band energy > high band: low=%f high=%f", frame[5], frame[39])
+	}
+}
+
+func TestWindowString(t *testing.T) {
+	for w, name := range map[Window]string{Hann: "hann", Hamming: "hamming", Rectangular: "rectangular"} {
+		if w.String() != name {
+			t.Errorf("%d.String() = %q, want %q", w, w.String(), name)
+		}
+	}
+	c := Hann.coefficients(5)
+	if c[0] != 0 || c[2] != 1 {
+		t.Errorf("hann coefficients = %v", c)
+	}
+}
+
+func TestEstimatePitch(t *testing.T) {
+	for _, freq := range []float64{110, 220, 330, 440} {
+		pcm := sine(freq, 16000, 1024)
+		hz, conf := EstimatePitch(pcm, 16000, 60, 800)
+		if math.Abs(hz-freq) > freq*0.02 {
+			t.Errorf("EstimatePitch(%v) = %f", freq, hz)
+		}
+		if conf < VoicedThreshold {
+			t.Errorf("EstimatePitch(%v) confidence = %f", freq, conf)
+		}
+	}
+}
+
+func TestEstimatePitchSilence(t *testing.T) {
+	hz, conf := EstimatePitch(make([]float32, 1024), 16000, 60, 800)
+	if hz != 0 || conf != 0 {
+		t.Errorf("silence: hz=%f conf=%f", hz, conf)
+	}
+}
+
+func TestPitchTrack(t *testing.T) {
+	pcm := append(sine(220, 16000, 4000), make([]float32, 4000)...)
+	track := PitchTrack(pcm, 16000, 640, 320, 60, 800)
+	if len(track) == 0 {
+		t.Fatal("empty track")
+	}
+	if first := track[0]; math.Abs(first.Hz-220) > 5 {
+		t.Errorf("first frame Hz = %f, want ~220", first.Hz)
+	}
+	if last := track[len(track)-1]; last.Hz != 0 {
+		t.Errorf("last frame Hz = %f, want 0 (unvoiced)", last.Hz)
+	}
+}
+
+func TestInt16ToFloat32(t *testing.T) {
+	got := Int16ToFloat32([]byte{0x00, 0x40, 0x00, 0xC0})
+	if got[0] != 0.5 || got[1] != -0.5 {
+		t.Errorf("got %v", got)
+	}
+}
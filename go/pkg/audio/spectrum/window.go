@@ -0,0 +1,57 @@
+package spectrum
+
+import (
+	"math"
+
+	"github.com/haivivi/giztoy/go/pkg/audio/fbank"
+)
+
+// Window selects the analysis window function.
+type Window int
+
+const (
+	// Hann is the raised-cosine window; good general-purpose leakage control.
+	Hann Window = iota
+	// Hamming matches the fbank front-end.
+	Hamming
+	// Rectangular applies no tapering.
+	Rectangular
+)
+
+// String returns the window name.
+func (w Window) String() string {
+	switch w {
+	case Hann:
+		return "hann"
+	case Hamming:
+		return "hamming"
+	case Rectangular:
+		return "rectangular"
+	default:
+		return "unknown"
+	}
+}
+
+// coefficients generates n window coefficients.
+func (w Window) coefficients(n int) []float64 {
+	switch w {
+	case Hamming:
+		return fbank.HammingWindow(n)
+	case Rectangular:
+		c := make([]float64, n)
+		for i := range c {
+			c[i] = 1
+		}
+		return c
+	default:
+		c := make([]float64, n)
+		if n == 1 {
+			c[0] = 1
+			return c
+		}
+		for i := range c {
+			c[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+		}
+		return c
+	}
+}
@@ -0,0 +1,240 @@
+// Package watermark embeds and detects an inaudible spread-spectrum mark in
+// PCM audio, so synthetic speech can be identified after it leaves the
+// device.
+//
+// The mark is a key-derived pseudo-noise (PN) sequence of Period samples,
+// repeated for the length of the audio and added at a fixed fraction
+// (Strength) of the local signal level. Silence stays silent and loud
+// passages mask the added noise.
+//
+// Detection folds the audio modulo Period and correlates it with the PN
+// sequence at every alignment, so it needs neither the start of the stream
+// nor the sample offset. The score is a z-score: unmarked audio stays
+// around 3-4 at the best alignment, marked audio grows with the square root
+// of its length. With default settings a few seconds of speech are enough.
+//
+// The mark survives gain changes, clipping and mixing, but not resampling
+// or lossy codecs that discard low-level noise; use metadata sidecars (see
+// genx/transformers.Watermark) where audio is transcoded.
+//
+// Audio is PCM16 signed little-endian, mono, at any fixed sample rate.
+//
+// Example:
+//
+//	e := watermark.NewEmbedder(watermark.Config{Key: "my-product"})
+//	e.Embed(pcm) // in place, chunk by chunk
+//
+//	res := watermark.Detect(pcm, watermark.Config{Key: "my-product"})
+//	if res.Detected { ... }
+package watermark
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"math/rand/v2"
+)
+
+// Config configures embedding and detection. The embedder and detector must
+// use the same Key and Period.
+type Config struct {
+	// Key seeds the PN sequence. Audio marked with one key is not detected
+	// with another. Default "giztoy".
+	Key string
+
+	// Period is the PN sequence length in samples (default 2048).
+	Period int
+
+	// Strength is the mark amplitude relative to the local RMS level
+	// (default 0.03, about -30 dB).
+	Strength float64
+
+	// Threshold is the detection score above which audio is considered
+	// marked (default 6).
+	Threshold float64
+}
+
+func (c *Config) setDefaults() {
+	if c.Key == "" {
+		c.Key = "giztoy"
+	}
+	if c.Period <= 0 {
+		c.Period = 2048
+	}
+	if c.Strength <= 0 {
+		c.Strength = 0.03
+	}
+	if c.Threshold <= 0 {
+		c.Threshold = 6
+	}
+}
+
+// blockSize is the number of samples over which the embedder measures the
+// signal level.
+const blockSize = 256
+
+// preEmphasis whitens speech before correlation. Speech energy is
+// concentrated at low frequencies while the mark is white, so this raises
+// the detection score considerably.
+const preEmphasis = 0.97
+
+// pnSequence returns the ±1 sequence for cfg.
+func pnSequence(cfg Config) []float64 {
+	sum := sha256.Sum256([]byte(cfg.Key))
+	r := rand.New(rand.NewPCG(binary.LittleEndian.Uint64(sum[:8]), binary.LittleEndian.Uint64(sum[8:16])))
+	pn := make([]float64, cfg.Period)
+	for i := range pn {
+		if r.Uint64()&1 == 0 {
+			pn[i] = -1
+		} else {
+			pn[i] = 1
+		}
+	}
+	return pn
+}
+
+// Embedder adds the mark to a stream of PCM chunks. It keeps the PN phase
+// across calls, so chunks must be passed in order. An Embedder is not safe
+// for concurrent use.
+type Embedder struct {
+	cfg Config
+	pn  []float64
+	pos int     // position in pn
+	rms float64 // level of the previous block
+}
+
+// NewEmbedder creates an Embedder. Zero fields in cfg take defaults.
+func NewEmbedder(cfg Config) *Embedder {
+	cfg.setDefaults()
+	return &Embedder{cfg: cfg, pn: pnSequence(cfg)}
+}
+
+// Embed marks pcm in place. A trailing odd byte is left unchanged.
+func (e *Embedder) Embed(pcm []byte) {
+	n := len(pcm) / 2
+	for start := 0; start < n; start += blockSize {
+		end := min(start+blockSize, n)
+
+		var sum float64
+		for i := start; i < end; i++ {
+			v := float64(int16(binary.LittleEndian.Uint16(pcm[i*2:])))
+			sum += v * v
+		}
+		level := math.Sqrt(sum / float64(end-start))
+
+		// Ramp from the previous level to avoid steps at block edges.
+		for i := start; i < end; i++ {
+			t := float64(i-start+1) / float64(end-start)
+			amp := e.cfg.Strength * (e.rms + (level-e.rms)*t)
+			v := float64(int16(binary.LittleEndian.Uint16(pcm[i*2:])))
+			v = math.Round(v + amp*e.pn[e.pos])
+			v = max(math.MinInt16, min(math.MaxInt16, v))
+			binary.LittleEndian.PutUint16(pcm[i*2:], uint16(int16(v)))
+			e.pos = (e.pos + 1) % len(e.pn)
+		}
+		e.rms = level
+	}
+}
+
+// Reset restarts the embedder as for a new stream.
+func (e *Embedder) Reset() {
+	e.pos = 0
+	e.rms = 0
+}
+
+// Result is the outcome of a detection.
+type Result struct {
+	// Detected reports whether Score reached the configured threshold.
+	Detected bool
+
+	// Score is the correlation z-score at the best alignment.
+	Score float64
+
+	// Offset is the position of the best alignment within the PN period.
+	Offset int
+
+	// Samples is the number of samples analyzed.
+	Samples int
+}
+
+// Detector accumulates audio and checks it for the mark. Audio may be
+// written in chunks of any size. A Detector is not safe for concurrent use.
+type Detector struct {
+	cfg  Config
+	pn   []float64 // pre-emphasized PN sequence
+	fold []float64
+	pos  int
+	prev float64
+	n    int
+}
+
+// NewDetector creates a Detector. Zero fields in cfg take defaults.
+func NewDetector(cfg Config) *Detector {
+	cfg.setDefaults()
+	pn := pnSequence(cfg)
+	q := make([]float64, len(pn))
+	for i := range pn {
+		q[i] = pn[i] - preEmphasis*pn[(i+len(pn)-1)%len(pn)]
+	}
+	return &Detector{cfg: cfg, pn: q, fold: make([]float64, len(pn))}
+}
+
+// Write adds PCM audio to the analysis.
+func (d *Detector) Write(pcm []byte) {
+	for i := 0; i+1 < len(pcm); i += 2 {
+		v := float64(int16(binary.LittleEndian.Uint16(pcm[i:])))
+		d.fold[d.pos] += v - preEmphasis*d.prev
+		d.prev = v
+		d.pos = (d.pos + 1) % len(d.fold)
+		d.n++
+	}
+}
+
+// Result checks the audio written so far.
+func (d *Detector) Result() Result {
+	res := Result{Samples: d.n}
+
+	var energy, pnEnergy float64
+	for i := range d.fold {
+		energy += d.fold[i] * d.fold[i]
+		pnEnergy += d.pn[i] * d.pn[i]
+	}
+	if energy == 0 {
+		return res
+	}
+	norm := math.Sqrt(energy * pnEnergy / float64(len(d.pn)))
+
+	best := math.Inf(-1)
+	period := len(d.pn)
+	for lag := range period {
+		var c float64
+		for i, f := range d.fold {
+			j := i + lag
+			if j >= period {
+				j -= period
+			}
+			c += f * d.pn[j]
+		}
+		if c > best {
+			best, res.Offset = c, lag
+		}
+	}
+	res.Score = best / norm
+	res.Detected = res.Score >= d.cfg.Threshold
+	return res
+}
+
+// Reset discards all audio written so far.
+func (d *Detector) Reset() {
+	clear(d.fold)
+	d.pos = 0
+	d.prev = 0
+	d.n = 0
+}
+
+// Detect checks pcm for the mark.
+func Detect(pcm []byte, cfg Config) Result {
+	d := NewDetector(cfg)
+	d.Write(pcm)
+	return d.Result()
+}
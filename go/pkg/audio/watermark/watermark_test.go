@@ -0,0 +1,104 @@
+package watermark
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// speechLike generates PCM16 (16kHz) of harmonics with a syllable-rate
+// envelope plus noise, loosely resembling voiced speech.
+func speechLike(seconds float64, seed uint64) []byte {
+	r := rand.New(rand.NewPCG(seed, seed))
+	n := int(16000 * seconds)
+	out := make([]byte, n*2)
+	for i := range n {
+		t := float64(i) / 16000
+		env := 0.5 + 0.5*math.Sin(2*math.Pi*4*t)
+		v := 0.3*math.Sin(2*math.Pi*180*t) + 0.2*math.Sin(2*math.Pi*360*t) + 0.1*math.Sin(2*math.Pi*720*t)
+		v = env*v + 0.02*r.NormFloat64()
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(int16(v*20000)))
+	}
+	return out
+}
+
+func TestEmbedDetect(t *testing.T) {
+	pcm := speechLike(3, 1)
+	clean := Detect(pcm, Config{})
+
+	e := NewEmbedder(Config{})
+	// Embed in uneven chunks, as a stream would.
+	for off := 0; off < len(pcm); off += 1234 {
+		e.Embed(pcm[off:min(off+1234, len(pcm))])
+	}
+
+	marked := Detect(pcm, Config{})
+	if !marked.Detected {
+		t.Errorf("marked audio not detected: %+v", marked)
+	}
+	if clean.Detected {
+		t.Errorf("clean audio detected: %+v", clean)
+	}
+	t.Logf("clean score %.2f, marked score %.2f", clean.Score, marked.Score)
+}
+
+func TestDetect_UnalignedAndScaled(t *testing.T) {
+	pcm := speechLike(3, 2)
+	NewEmbedder(Config{}).Embed(pcm)
+
+	// Start mid-stream at an odd sample offset and halve the volume.
+	part := append([]byte(nil), pcm[2*777:]...)
+	for i := 0; i+1 < len(part); i += 2 {
+		v := int16(binary.LittleEndian.Uint16(part[i:]))
+		binary.LittleEndian.PutUint16(part[i:], uint16(v/2))
+	}
+
+	if res := Detect(part, Config{}); !res.Detected {
+		t.Errorf("unaligned, scaled audio not detected: %+v", res)
+	}
+}
+
+func TestDetect_WrongKey(t *testing.T) {
+	pcm := speechLike(3, 3)
+	NewEmbedder(Config{Key: "a"}).Embed(pcm)
+
+	if res := Detect(pcm, Config{Key: "b"}); res.Detected {
+		t.Errorf("detected with wrong key: %+v", res)
+	}
+	if res := Detect(pcm, Config{Key: "a"}); !res.Detected {
+		t.Errorf("not detected with right key: %+v", res)
+	}
+}
+
+func TestEmbed_Inaudible(t *testing.T) {
+	pcm := speechLike(1, 4)
+	orig := append([]byte(nil), pcm...)
+	NewEmbedder(Config{}).Embed(pcm)
+
+	var sig, diff float64
+	for i := 0; i+1 < len(pcm); i += 2 {
+		a := float64(int16(binary.LittleEndian.Uint16(orig[i:])))
+		b := float64(int16(binary.LittleEndian.Uint16(pcm[i:])))
+		sig += a * a
+		diff += (b - a) * (b - a)
+	}
+	if snr := 10 * math.Log10(sig/diff); snr < 25 {
+		t.Errorf("mark SNR = %.1f dB, want >= 25 dB", snr)
+	}
+
+	silence := make([]byte, 3200)
+	NewEmbedder(Config{}).Embed(silence)
+	for _, b := range silence {
+		if b != 0 {
+			t.Fatal("silence was modified")
+		}
+	}
+}
+
+func TestDetector_Empty(t *testing.T) {
+	d := NewDetector(Config{})
+	if res := d.Result(); res.Detected || res.Samples != 0 {
+		t.Errorf("empty detector result = %+v", res)
+	}
+}
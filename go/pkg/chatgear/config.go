@@ -0,0 +1,108 @@
+package chatgear
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/jsontime"
+)
+
+// DeviceConfig is the server-managed configuration of a device, such as the
+// persona it talks as and the volume range it may play at.
+//
+// The server pushes a DeviceConfig on the config downlink topic and the
+// device acknowledges it with a ConfigAck. Version increases with every
+// change, so that the device can tell a new config from a stale or repeated
+// one and the server can tell whether the device is up to date.
+type DeviceConfig struct {
+	// Version is the config version, increasing with every change.
+	Version int64 `json:"version"`
+
+	// Time is when the config was pushed.
+	Time jsontime.Milli `json:"time,omitzero"`
+
+	// Persona is the name of the persona (agent) the device talks as.
+	Persona string `json:"persona,omitempty"`
+
+	// Voice is the TTS voice of the device.
+	Voice string `json:"voice,omitempty"`
+
+	// VolumeMin and VolumeMax limit the volume percentage. Nil means no
+	// limit.
+	VolumeMin *int `json:"volume_min,omitempty"`
+	VolumeMax *int `json:"volume_max,omitempty"`
+
+	// DownlinkPrebufferMs is the audio, in milliseconds, the device queues
+	// before starting playback and after an underrun, trading latency for
+	// click-free playback on congested networks. Zero uses the default of
+	// the device, see ClientPort.SetDownlinkPrebuffer.
+	DownlinkPrebufferMs int `json:"downlink_prebuffer_ms,omitempty"`
+}
+
+// DeviceConfigFromFields decodes a DeviceConfig from document fields, such
+// as those of a cortex "chatgear/config" document.
+func DeviceConfigFromFields(fields map[string]any) (*DeviceConfig, error) {
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("chatgear: device config: %w", err)
+	}
+	var cfg DeviceConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("chatgear: device config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// ClampVolume limits a volume percentage to [VolumeMin, VolumeMax].
+func (c *DeviceConfig) ClampVolume(volume int) int {
+	if c == nil {
+		return volume
+	}
+	if c.VolumeMax != nil && volume > *c.VolumeMax {
+		volume = *c.VolumeMax
+	}
+	if c.VolumeMin != nil && volume < *c.VolumeMin {
+		volume = *c.VolumeMin
+	}
+	return volume
+}
+
+// Clone returns a deep copy of the config.
+func (c *DeviceConfig) Clone() *DeviceConfig {
+	if c == nil {
+		return nil
+	}
+	cp := *c
+	if c.VolumeMin != nil {
+		v := *c.VolumeMin
+		cp.VolumeMin = &v
+	}
+	if c.VolumeMax != nil {
+		v := *c.VolumeMax
+		cp.VolumeMax = &v
+	}
+	return &cp
+}
+
+// ConfigAck is sent by the device on the config ack uplink topic.
+//
+// A device sends a ConfigAck when it applies or rejects a pushed config, and
+// when it connects, to report the version it has. The server pushes its
+// config again if that version is older.
+type ConfigAck struct {
+	// Version is the config version the device has applied.
+	Version int64 `json:"version"`
+
+	// Time is when the ack was sent.
+	Time jsontime.Milli `json:"time"`
+
+	// Error is set if the device rejected a pushed config. Version is then
+	// the version it kept.
+	Error string `json:"error,omitempty"`
+}
+
+// NewConfigAck creates a ConfigAck for the given version.
+func NewConfigAck(version int64, t time.Time) *ConfigAck {
+	return &ConfigAck{Version: version, Time: jsontime.Milli(t)}
+}
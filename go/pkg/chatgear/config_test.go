@@ -0,0 +1,176 @@
+package chatgear
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestDeviceConfig_JSON(t *testing.T) {
+	cfg := &DeviceConfig{
+		Version:   3,
+		Persona:   "lele",
+		Voice:     "zh_female_cancan",
+		VolumeMax: intPtr(60),
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	want := `{"version":3,"persona":"lele","voice":"zh_female_cancan","volume_max":60}`
+	if string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+
+	var got DeviceConfig
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got.Version != 3 || got.Persona != "lele" || got.VolumeMin != nil || *got.VolumeMax != 60 {
+		t.Errorf("Unmarshal = %+v", got)
+	}
+}
+
+func TestDeviceConfigFromFields(t *testing.T) {
+	// Fields as decoded from a YAML document.
+	cfg, err := DeviceConfigFromFields(map[string]any{
+		"name":       "gear-001",
+		"version":    uint64(2),
+		"persona":    "lele",
+		"volume_min": uint64(10),
+		"volume_max": uint64(80),
+	})
+	if err != nil {
+		t.Fatalf("DeviceConfigFromFields: %v", err)
+	}
+	if cfg.Version != 2 || cfg.Persona != "lele" || *cfg.VolumeMin != 10 || *cfg.VolumeMax != 80 {
+		t.Errorf("DeviceConfigFromFields = %+v", cfg)
+	}
+
+	if _, err := DeviceConfigFromFields(map[string]any{"volume_max": "loud"}); err == nil {
+		t.Error("expected error for non-numeric volume_max")
+	}
+}
+
+func TestDeviceConfig_ClampVolume(t *testing.T) {
+	cfg := &DeviceConfig{VolumeMin: intPtr(10), VolumeMax: intPtr(60)}
+	tests := []struct{ in, want int }{{5, 10}, {30, 30}, {90, 60}}
+	for _, tt := range tests {
+		if got := cfg.ClampVolume(tt.in); got != tt.want {
+			t.Errorf("ClampVolume(%d) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	var none *DeviceConfig
+	if got := none.ClampVolume(90); got != 90 {
+		t.Errorf("nil ClampVolume(90) = %d, want 90", got)
+	}
+}
+
+func TestDeviceConfig_Clone(t *testing.T) {
+	cfg := &DeviceConfig{Version: 1, VolumeMax: intPtr(60)}
+	cp := cfg.Clone()
+	*cp.VolumeMax = 90
+	if *cfg.VolumeMax != 60 {
+		t.Error("Clone shares VolumeMax")
+	}
+}
+
+func TestServerPort_SetVolume_Clamped(t *testing.T) {
+	port := NewServerPort()
+	defer port.Close()
+
+	port.SetConfig(&DeviceConfig{VolumeMax: intPtr(60)})
+	port.SetVolume(100)
+
+	evt, err := port.commandQueue.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if v, ok := evt.Payload.(*SetVolume); !ok || int(*v) != 60 {
+		t.Errorf("command = %+v, want set_volume 60", evt.Payload)
+	}
+}
+
+func TestServerPort_SetConfig_Version(t *testing.T) {
+	port := NewServerPort()
+	defer port.Close()
+
+	port.SetConfig(&DeviceConfig{Persona: "a"})
+	port.SetConfig(&DeviceConfig{Persona: "b"})
+	cfg, ok := port.Config()
+	if !ok || cfg.Version != 2 || cfg.Persona != "b" {
+		t.Errorf("Config = %+v, want version 2 persona b", cfg)
+	}
+
+	port.SetConfig(&DeviceConfig{Version: 10})
+	if cfg, _ := port.Config(); cfg.Version != 10 {
+		t.Errorf("Version = %d, want 10", cfg.Version)
+	}
+}
+
+func TestConfigSync_ThroughPipe(t *testing.T) {
+	server, client := NewPipe()
+
+	serverPort := NewServerPort()
+	clientPort := NewClientPort()
+
+	// The device restored version 1 from flash; the server has version 2.
+	clientPort.SetConfig(&DeviceConfig{Version: 1, Persona: "old"})
+	serverPort.SetConfig(&DeviceConfig{Version: 2, Persona: "new", VolumeMax: intPtr(70)})
+
+	go serverPort.ReadFrom(server)
+	serverDone := make(chan error, 1)
+	go func() { serverDone <- serverPort.WriteTo(server) }()
+	go clientPort.ReadFrom(client)
+	clientDone := make(chan error, 1)
+	go func() { clientDone <- clientPort.WriteTo(client) }()
+
+	received := make(chan *DeviceConfig, 1)
+	go func() {
+		for cfg, err := range clientPort.Configs() {
+			if err != nil {
+				return
+			}
+			received <- cfg
+		}
+	}()
+
+	select {
+	case cfg := <-received:
+		if cfg.Version != 2 || cfg.Persona != "new" || *cfg.VolumeMax != 70 {
+			t.Errorf("received config = %+v", cfg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for config")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !serverPort.ConfigSynced() {
+		if time.Now().After(deadline) {
+			ack, _ := serverPort.LastConfigAck()
+			t.Fatalf("config not synced, last ack = %+v", ack)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if cfg, ok := clientPort.Config(); !ok || cfg.Version != 2 {
+		t.Errorf("client Config = %+v, want version 2", cfg)
+	}
+
+	// A repeated push is acknowledged but not returned again.
+	serverPort.SetConfig(&DeviceConfig{Version: 2, Persona: "new"})
+	select {
+	case cfg := <-received:
+		t.Errorf("stale config returned: %+v", cfg)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Closing the ports stops WriteTo, which closes the pipe.
+	clientPort.Close()
+	<-clientDone
+	serverPort.Close()
+	<-serverDone
+}
@@ -28,6 +28,9 @@ type UplinkTx interface {
 	// SendStats sends a stats event to the server.
 	SendStats(stats *StatsEvent) error
 
+	// SendConfigAck acknowledges a device config to the server.
+	SendConfigAck(ack *ConfigAck) error
+
 	// Close closes the uplink.
 	Close() error
 }
@@ -46,6 +49,9 @@ type UplinkRx interface {
 	// LatestStats returns the latest stats from the client.
 	LatestStats() *StatsEvent
 
+	// ConfigAcks returns an iterator for config acks from the client.
+	ConfigAcks() iter.Seq2[*ConfigAck, error]
+
 	// Close closes the receiver.
 	Close() error
 }
@@ -62,6 +68,9 @@ type DownlinkTx interface {
 	// IssueCommand issues a command to the client.
 	IssueCommand(cmd Command, t time.Time) error
 
+	// PushConfig pushes a device config to the client.
+	PushConfig(cfg *DeviceConfig) error
+
 	// Close closes the downlink.
 	Close() error
 }
@@ -74,6 +83,9 @@ type DownlinkRx interface {
 	// Commands returns an iterator for commands from the server.
 	Commands() iter.Seq2[*CommandEvent, error]
 
+	// Configs returns an iterator for device configs from the server.
+	Configs() iter.Seq2[*DeviceConfig, error]
+
 	// Close closes the receiver.
 	Close() error
 }
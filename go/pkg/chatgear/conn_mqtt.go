@@ -153,19 +153,21 @@ func DialMQTT(ctx context.Context, cfg MQTTClientConfig) (*MQTTClientConn, error
 		logger:     logger,
 		opusFrames: make(chan StampedOpusFrame, 1024),
 		commands:   make(chan *CommandEvent, 32),
+		configs:    make(chan *DeviceConfig, 8),
 	}
 
 	// Subscribe to downlink topics
 	audioTopic := fmt.Sprintf("%sdevice/%s/output_audio_stream", scope, cfg.GearID)
 	cmdTopic := fmt.Sprintf("%sdevice/%s/command", scope, cfg.GearID)
+	configTopic := fmt.Sprintf("%sdevice/%s/config", scope, cfg.GearID)
 
-	if err := client.Subscribe(ctx, audioTopic, cmdTopic); err != nil {
+	if err := client.Subscribe(ctx, audioTopic, cmdTopic, configTopic); err != nil {
 		client.Close()
 		cancel()
 		return nil, fmt.Errorf("chatgear/mqtt: subscribe: %w", err)
 	}
 
-	logger.InfoPrintf("subscribed to MQTT topics: audio=%s, command=%s", audioTopic, cmdTopic)
+	logger.InfoPrintf("subscribed to MQTT topics: audio=%s, command=%s, config=%s", audioTopic, cmdTopic, configTopic)
 
 	// Start receive loop
 	go conn.receiveLoop()
@@ -186,6 +188,7 @@ type MQTTClientConn struct {
 	// Downlink channels
 	opusFrames chan StampedOpusFrame
 	commands   chan *CommandEvent
+	configs    chan *DeviceConfig
 
 	mu     sync.Mutex
 	closed bool
@@ -195,6 +198,7 @@ func (c *MQTTClientConn) receiveLoop() {
 	c.logger.InfoPrintf("receiveLoop started")
 	audioTopic := fmt.Sprintf("%sdevice/%s/output_audio_stream", c.scope, c.gearID)
 	cmdTopic := fmt.Sprintf("%sdevice/%s/command", c.scope, c.gearID)
+	configTopic := fmt.Sprintf("%sdevice/%s/config", c.scope, c.gearID)
 
 	for {
 		select {
@@ -242,6 +246,18 @@ func (c *MQTTClientConn) receiveLoop() {
 			default:
 				c.logger.WarnPrintf("commands channel full, dropping command")
 			}
+		case configTopic:
+			c.logger.InfoPrintf("MQTT RX config: %s", string(msg.Payload))
+			var cfg DeviceConfig
+			if err := json.Unmarshal(msg.Payload, &cfg); err != nil {
+				c.logger.WarnPrintf("failed to unmarshal config: %v", err)
+				continue
+			}
+			select {
+			case c.configs <- &cfg:
+			default:
+				c.logger.WarnPrintf("configs channel full, dropping config")
+			}
 		}
 	}
 }
@@ -275,6 +291,16 @@ func (c *MQTTClientConn) SendStats(stats *StatsEvent) error {
 	return c.client.Publish(c.ctx, topic, data)
 }
 
+func (c *MQTTClientConn) SendConfigAck(ack *ConfigAck) error {
+	topic := fmt.Sprintf("%sdevice/%s/config_ack", c.scope, c.gearID)
+	data, err := json.Marshal(ack)
+	if err != nil {
+		return err
+	}
+	c.logger.InfoPrintf("MQTT TX config ack: %s", string(data))
+	return c.client.Publish(c.ctx, topic, data)
+}
+
 // --- DownlinkRx implementation ---
 
 func (c *MQTTClientConn) OpusFrames() iter.Seq2[StampedOpusFrame, error] {
@@ -313,6 +339,24 @@ func (c *MQTTClientConn) Commands() iter.Seq2[*CommandEvent, error] {
 	}
 }
 
+func (c *MQTTClientConn) Configs() iter.Seq2[*DeviceConfig, error] {
+	return func(yield func(*DeviceConfig, error) bool) {
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case cfg, ok := <-c.configs:
+				if !ok {
+					return
+				}
+				if !yield(cfg, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
 // --- Lifecycle ---
 
 func (c *MQTTClientConn) Close() error {
@@ -29,6 +29,7 @@ type serverMux struct {
 	opusFrames chan StampedOpusFrame
 	states     chan *StateEvent
 	stats      chan *StatsEvent
+	configAcks chan *ConfigAck
 
 	mu          sync.Mutex
 	latestStats *StatsEvent
@@ -43,27 +44,30 @@ func newServerMux(scope, gearID string, logger Logger) *serverMux {
 		opusFrames: make(chan StampedOpusFrame, 1024),
 		states:     make(chan *StateEvent, 32),
 		stats:      make(chan *StatsEvent, 32),
+		configAcks: make(chan *ConfigAck, 32),
 	}
 }
 
 // topics returns the uplink topics for this gear.
-func (m *serverMux) topics() (audio, state, stats string) {
+func (m *serverMux) topics() (audio, state, stats, configAck string) {
 	audio = fmt.Sprintf("%sdevice/%s/input_audio_stream", m.scope, m.gearID)
 	state = fmt.Sprintf("%sdevice/%s/state", m.scope, m.gearID)
 	stats = fmt.Sprintf("%sdevice/%s/stats", m.scope, m.gearID)
+	configAck = fmt.Sprintf("%sdevice/%s/config_ack", m.scope, m.gearID)
 	return
 }
 
 // downlinkTopics returns the downlink topics for this gear.
-func (m *serverMux) downlinkTopics() (audio, command string) {
+func (m *serverMux) downlinkTopics() (audio, command, config string) {
 	audio = fmt.Sprintf("%sdevice/%s/output_audio_stream", m.scope, m.gearID)
 	command = fmt.Sprintf("%sdevice/%s/command", m.scope, m.gearID)
+	config = fmt.Sprintf("%sdevice/%s/config", m.scope, m.gearID)
 	return
 }
 
 // handleMessage routes incoming MQTT messages to appropriate channels.
 func (m *serverMux) handleMessage(topic string, payload []byte) {
-	audioTopic, stateTopic, statsTopic := m.topics()
+	audioTopic, stateTopic, statsTopic, configAckTopic := m.topics()
 
 	switch topic {
 	case audioTopic:
@@ -107,6 +111,19 @@ func (m *serverMux) handleMessage(topic string, payload []byte) {
 		default:
 			m.logger.WarnPrintf("stats channel full, dropping stats")
 		}
+
+	case configAckTopic:
+		m.logger.InfoPrintf("MQTT RX config ack: %s", string(payload))
+		var ack ConfigAck
+		if err := json.Unmarshal(payload, &ack); err != nil {
+			m.logger.WarnPrintf("failed to unmarshal config ack: %v", err)
+			return
+		}
+		select {
+		case m.configAcks <- &ack:
+		default:
+			m.logger.WarnPrintf("config acks channel full, dropping config ack")
+		}
 	}
 }
 
@@ -115,6 +132,7 @@ func (m *serverMux) close() {
 	close(m.opusFrames)
 	close(m.states)
 	close(m.stats)
+	close(m.configAcks)
 }
 
 // =============================================================================
@@ -169,8 +187,8 @@ type MQTTServerConn struct {
 }
 
 // DialMQTTServer connects to an MQTT broker and returns a server connection.
-// The server connection receives uplink data (audio, state, stats) from the client
-// and sends downlink data (audio, commands) to the client.
+// The server connection receives uplink data (audio, state, stats, config acks)
+// from the client and sends downlink data (audio, commands, configs) to the client.
 func DialMQTTServer(ctx context.Context, cfg MQTTServerConfig) (*MQTTServerConn, error) {
 	// Normalize scope
 	scope := cfg.Scope
@@ -235,14 +253,14 @@ func DialMQTTServer(ctx context.Context, cfg MQTTServerConfig) (*MQTTServerConn,
 	}
 
 	// Subscribe to uplink topics (from client)
-	audioTopic, stateTopic, statsTopic := mux.topics()
-	if err := client.Subscribe(ctx, audioTopic, stateTopic, statsTopic); err != nil {
+	audioTopic, stateTopic, statsTopic, configAckTopic := mux.topics()
+	if err := client.Subscribe(ctx, audioTopic, stateTopic, statsTopic, configAckTopic); err != nil {
 		client.Close()
 		cancel()
 		return nil, fmt.Errorf("chatgear/mqtt-server: subscribe: %w", err)
 	}
 
-	logger.InfoPrintf("subscribed to MQTT topics: audio=%s, state=%s, stats=%s", audioTopic, stateTopic, statsTopic)
+	logger.InfoPrintf("subscribed to MQTT topics: audio=%s, state=%s, stats=%s, config_ack=%s", audioTopic, stateTopic, statsTopic, configAckTopic)
 
 	// Start receive loop for client mode
 	go conn.clientReceiveLoop()
@@ -407,17 +425,35 @@ func (c *MQTTServerConn) LatestStats() *StatsEvent {
 	return c.mux.latestStats
 }
 
+func (c *MQTTServerConn) ConfigAcks() iter.Seq2[*ConfigAck, error] {
+	return func(yield func(*ConfigAck, error) bool) {
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case ack, ok := <-c.mux.configAcks:
+				if !ok {
+					return
+				}
+				if !yield(ack, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
 // --- DownlinkTx implementation (send to client) ---
 
 func (c *MQTTServerConn) SendOpusFrame(timestamp time.Time, frame opus.Frame) error {
-	audioTopic, _ := c.mux.downlinkTopics()
+	audioTopic, _, _ := c.mux.downlinkTopics()
 	stamped := stampFrame(frame, timestamp)
 	c.mux.logger.DebugPrintf("MQTT TX audio: len=%d ts=%v", len(frame), timestamp.Format("15:04:05.000"))
 	return c.publish(audioTopic, stamped)
 }
 
 func (c *MQTTServerConn) IssueCommand(cmd Command, t time.Time) error {
-	_, cmdTopic := c.mux.downlinkTopics()
+	_, cmdTopic, _ := c.mux.downlinkTopics()
 	evt := NewCommandEvent(cmd, t)
 	data, err := json.Marshal(evt)
 	if err != nil {
@@ -427,6 +463,16 @@ func (c *MQTTServerConn) IssueCommand(cmd Command, t time.Time) error {
 	return c.publish(cmdTopic, data)
 }
 
+func (c *MQTTServerConn) PushConfig(cfg *DeviceConfig) error {
+	_, _, configTopic := c.mux.downlinkTopics()
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	c.mux.logger.InfoPrintf("MQTT TX config: %s", string(data))
+	return c.publish(configTopic, data)
+}
+
 // publish sends a message using either client or broker depending on mode.
 func (c *MQTTServerConn) publish(topic string, payload []byte) error {
 	if c.client != nil {
@@ -19,30 +19,36 @@ func NewPipe() (*PipeServerConn, *PipeClientConn) {
 	uplinkOpus := make(chan StampedOpusFrame, 1024)
 	uplinkStates := make(chan *StateEvent, 32)
 	uplinkStats := make(chan *StatsEvent, 32)
+	uplinkAcks := make(chan *ConfigAck, 32)
 
 	// Downlink channels (server -> client)
 	downlinkOpus := make(chan StampedOpusFrame, 1024)
 	downlinkCmds := make(chan *CommandEvent, 32)
+	downlinkConfigs := make(chan *DeviceConfig, 8)
 
 	// Shared error state for cross-connection error propagation
 	shared := &pipeSharedState{}
 
 	server := &PipeServerConn{
-		uplinkOpus:   uplinkOpus,
-		uplinkStates: uplinkStates,
-		uplinkStats:  uplinkStats,
-		downlinkOpus: downlinkOpus,
-		downlinkCmds: downlinkCmds,
-		shared:       shared,
+		uplinkOpus:      uplinkOpus,
+		uplinkStates:    uplinkStates,
+		uplinkStats:     uplinkStats,
+		uplinkAcks:      uplinkAcks,
+		downlinkOpus:    downlinkOpus,
+		downlinkCmds:    downlinkCmds,
+		downlinkConfigs: downlinkConfigs,
+		shared:          shared,
 	}
 
 	client := &PipeClientConn{
-		uplinkOpus:   uplinkOpus,
-		uplinkStates: uplinkStates,
-		uplinkStats:  uplinkStats,
-		downlinkOpus: downlinkOpus,
-		downlinkCmds: downlinkCmds,
-		shared:       shared,
+		uplinkOpus:      uplinkOpus,
+		uplinkStates:    uplinkStates,
+		uplinkStats:     uplinkStats,
+		uplinkAcks:      uplinkAcks,
+		downlinkOpus:    downlinkOpus,
+		downlinkCmds:    downlinkCmds,
+		downlinkConfigs: downlinkConfigs,
+		shared:          shared,
 	}
 
 	return server, client
@@ -65,10 +71,12 @@ type PipeServerConn struct {
 	uplinkOpus   chan StampedOpusFrame
 	uplinkStates chan *StateEvent
 	uplinkStats  chan *StatsEvent
+	uplinkAcks   chan *ConfigAck
 
 	// Downlink channels (send to client)
-	downlinkOpus chan StampedOpusFrame
-	downlinkCmds chan *CommandEvent
+	downlinkOpus    chan StampedOpusFrame
+	downlinkCmds    chan *CommandEvent
+	downlinkConfigs chan *DeviceConfig
 
 	shared *pipeSharedState
 
@@ -137,6 +145,22 @@ func (c *PipeServerConn) LatestStats() *StatsEvent {
 	return c.latestStats
 }
 
+func (c *PipeServerConn) ConfigAcks() iter.Seq2[*ConfigAck, error] {
+	return func(yield func(*ConfigAck, error) bool) {
+		for ack := range c.uplinkAcks {
+			if !yield(ack, nil) {
+				return
+			}
+		}
+		c.shared.mu.Lock()
+		err := c.shared.clientErr
+		c.shared.mu.Unlock()
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
 // --- DownlinkTx implementation (send to client) ---
 
 func (c *PipeServerConn) SendOpusFrame(timestamp time.Time, frame opus.Frame) error {
@@ -172,6 +196,22 @@ func (c *PipeServerConn) IssueCommand(cmd Command, t time.Time) error {
 	}
 }
 
+func (c *PipeServerConn) PushConfig(cfg *DeviceConfig) error {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return nil
+	}
+
+	select {
+	case c.downlinkConfigs <- cfg:
+		return nil
+	default:
+		return ErrPipeBufferFull
+	}
+}
+
 // --- Lifecycle ---
 
 func (c *PipeServerConn) Close() error {
@@ -194,6 +234,7 @@ func (c *PipeServerConn) CloseWithError(err error) error {
 	// Close downlink channels (server owns these)
 	close(c.downlinkOpus)
 	close(c.downlinkCmds)
+	close(c.downlinkConfigs)
 	return nil
 }
 
@@ -207,10 +248,12 @@ type PipeClientConn struct {
 	uplinkOpus   chan StampedOpusFrame
 	uplinkStates chan *StateEvent
 	uplinkStats  chan *StatsEvent
+	uplinkAcks   chan *ConfigAck
 
 	// Downlink channels (receive from server)
-	downlinkOpus chan StampedOpusFrame
-	downlinkCmds chan *CommandEvent
+	downlinkOpus    chan StampedOpusFrame
+	downlinkCmds    chan *CommandEvent
+	downlinkConfigs chan *DeviceConfig
 
 	shared *pipeSharedState
 
@@ -268,6 +311,22 @@ func (c *PipeClientConn) SendStats(stats *StatsEvent) error {
 	}
 }
 
+func (c *PipeClientConn) SendConfigAck(ack *ConfigAck) error {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return nil
+	}
+
+	select {
+	case c.uplinkAcks <- ack:
+		return nil
+	default:
+		return ErrPipeBufferFull
+	}
+}
+
 // --- DownlinkRx implementation (receive from server) ---
 
 func (c *PipeClientConn) OpusFrames() iter.Seq2[StampedOpusFrame, error] {
@@ -303,6 +362,22 @@ func (c *PipeClientConn) Commands() iter.Seq2[*CommandEvent, error] {
 	}
 }
 
+func (c *PipeClientConn) Configs() iter.Seq2[*DeviceConfig, error] {
+	return func(yield func(*DeviceConfig, error) bool) {
+		for cfg := range c.downlinkConfigs {
+			if !yield(cfg, nil) {
+				return
+			}
+		}
+		c.shared.mu.Lock()
+		err := c.shared.serverErr
+		c.shared.mu.Unlock()
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
 // --- Lifecycle ---
 
 func (c *PipeClientConn) Close() error {
@@ -326,6 +401,7 @@ func (c *PipeClientConn) CloseWithError(err error) error {
 	close(c.uplinkOpus)
 	close(c.uplinkStates)
 	close(c.uplinkStats)
+	close(c.uplinkAcks)
 	return nil
 }
 
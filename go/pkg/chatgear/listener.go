@@ -29,9 +29,10 @@ type Listener struct {
 	listener net.Listener
 	acceptCh chan *AcceptedPort
 
-	scope   string
-	logger  Logger
-	timeout time.Duration
+	scope      string
+	logger     Logger
+	timeout    time.Duration
+	loadConfig func(gearID string) (*DeviceConfig, error)
 
 	mu     sync.RWMutex
 	ports  map[string]*managedPort
@@ -76,6 +77,11 @@ type ListenerConfig struct {
 
 	// Logger is used for logging. If nil, DefaultLogger() is used.
 	Logger Logger
+
+	// LoadConfig, if set, loads the device config of a newly connected
+	// device, which is then pushed to it with ServerPort.SetConfig. A nil
+	// config means the device has none.
+	LoadConfig func(gearID string) (*DeviceConfig, error)
 }
 
 // ListenMQTT0 creates a new Listener that accepts device connections via MQTT.
@@ -121,13 +127,14 @@ func ListenMQTT0(ctx context.Context, cfg ListenerConfig) (*Listener, error) {
 	childCtx, cancel := context.WithCancel(ctx)
 
 	l := &Listener{
-		acceptCh: make(chan *AcceptedPort, 32),
-		scope:    scope,
-		logger:   logger,
-		timeout:  timeout,
-		ports:    make(map[string]*managedPort),
-		ctx:      childCtx,
-		cancel:   cancel,
+		acceptCh:   make(chan *AcceptedPort, 32),
+		scope:      scope,
+		logger:     logger,
+		timeout:    timeout,
+		loadConfig: cfg.LoadConfig,
+		ports:      make(map[string]*managedPort),
+		ctx:        childCtx,
+		cancel:     cancel,
 	}
 
 	// Create broker with wildcard handler
@@ -194,6 +201,16 @@ func (l *Listener) Close() error {
 	return nil
 }
 
+// Port returns the ServerPort of a connected device.
+func (l *Listener) Port(gearID string) (*ServerPort, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if mp, ok := l.ports[gearID]; ok {
+		return mp.port, true
+	}
+	return nil, false
+}
+
 // Addr returns the listener address.
 func (l *Listener) Addr() string {
 	if l.listener != nil {
@@ -208,9 +225,10 @@ func (l *Listener) Addr() string {
 
 // Topic patterns for parsing gearID
 var (
-	topicAudioPattern = regexp.MustCompile(`^(.*)device/([^/]+)/input_audio_stream$`)
-	topicStatePattern = regexp.MustCompile(`^(.*)device/([^/]+)/state$`)
-	topicStatsPattern = regexp.MustCompile(`^(.*)device/([^/]+)/stats$`)
+	topicAudioPattern     = regexp.MustCompile(`^(.*)device/([^/]+)/input_audio_stream$`)
+	topicStatePattern     = regexp.MustCompile(`^(.*)device/([^/]+)/state$`)
+	topicStatsPattern     = regexp.MustCompile(`^(.*)device/([^/]+)/stats$`)
+	topicConfigAckPattern = regexp.MustCompile(`^(.*)device/([^/]+)/config_ack$`)
 )
 
 // handleMessage routes incoming MQTT messages to appropriate ServerPorts.
@@ -230,6 +248,9 @@ func (l *Listener) handleMessage(topic string, payload []byte) {
 	} else if matches := topicStatsPattern.FindStringSubmatch(topic); matches != nil {
 		gearID = matches[2]
 		msgType = "stats"
+	} else if matches := topicConfigAckPattern.FindStringSubmatch(topic); matches != nil {
+		gearID = matches[2]
+		msgType = "config_ack"
 	} else {
 		// Unknown topic - log for debugging
 		l.logger.DebugPrintf("unknown topic: %s", topic)
@@ -280,6 +301,15 @@ func (l *Listener) handleMessage(topic string, payload []byte) {
 			return
 		}
 		mp.port.HandleStats(&evt)
+
+	case "config_ack":
+		l.logger.InfoPrintf("RX config ack from %s: %s", gearID, string(payload))
+		var ack ConfigAck
+		if err := json.Unmarshal(payload, &ack); err != nil {
+			l.logger.WarnPrintf("failed to unmarshal config ack from %s: %v", gearID, err)
+			return
+		}
+		mp.port.HandleConfigAck(&ack)
 	}
 }
 
@@ -310,6 +340,11 @@ func (l *Listener) getOrCreatePort(gearID string) *managedPort {
 	// Start WriteTo goroutine
 	go port.WriteTo(downlink)
 
+	// Load and push the device config
+	if l.loadConfig != nil {
+		go l.pushConfig(port, gearID)
+	}
+
 	mp := &managedPort{
 		port:       port,
 		gearID:     gearID,
@@ -330,6 +365,18 @@ func (l *Listener) getOrCreatePort(gearID string) *managedPort {
 	return mp
 }
 
+// pushConfig loads the device config of a new port and pushes it.
+func (l *Listener) pushConfig(port *ServerPort, gearID string) {
+	cfg, err := l.loadConfig(gearID)
+	if err != nil {
+		l.logger.WarnPrintf("failed to load config for %s: %v", gearID, err)
+		return
+	}
+	if cfg != nil {
+		port.SetConfig(cfg)
+	}
+}
+
 // releasePort closes and removes a port.
 func (l *Listener) releasePort(gearID string) {
 	l.mu.Lock()
@@ -429,6 +476,16 @@ func (d *gearDownlink) IssueCommand(cmd Command, t time.Time) error {
 	return d.listener.broker.Publish(d.listener.ctx, topic, data)
 }
 
+func (d *gearDownlink) PushConfig(cfg *DeviceConfig) error {
+	topic := fmt.Sprintf("%sdevice/%s/config", d.scope, d.gearID)
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	d.listener.logger.InfoPrintf("TX config to %s: %s", d.gearID, string(data))
+	return d.listener.broker.Publish(d.listener.ctx, topic, data)
+}
+
 func (d *gearDownlink) Close() error {
 	// No-op: the listener manages the lifecycle
 	return nil
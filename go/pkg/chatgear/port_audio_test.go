@@ -918,3 +918,55 @@ func TestMixer_ConcurrentReadWrite(t *testing.T) {
 		t.Error("No data read from mixer")
 	}
 }
+
+// TestServerPort_UplinkProcessing tests that uplink frames pass through the
+// processing chain before Poll.
+func TestServerPort_UplinkProcessing(t *testing.T) {
+	format := pcm.L16Mono16K
+	frameSize := int(format.SamplesInDuration(20 * time.Millisecond))
+
+	encoder, err := opus.NewVoIPEncoder(format.SampleRate(), format.Channels())
+	if err != nil {
+		t.Fatalf("NewVoIPEncoder: %v", err)
+	}
+	defer encoder.Close()
+	frame, err := encoder.Encode(generateSineWave(frameSize, 440, format.SampleRate()), frameSize)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	port := NewServerPort()
+	defer port.Close()
+
+	var calls int
+	err = port.SetUplinkProcessing(&UplinkProcessing{
+		Format: format,
+		Processors: []UplinkProcessor{
+			UplinkProcessorFunc(func(samples []int16) error {
+				calls++
+				clear(samples) // mute
+				return nil
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatalf("SetUplinkProcessing: %v", err)
+	}
+
+	port.HandleAudio(&StampedOpusFrame{Timestamp: time.Now(), Frame: frame})
+	data, err := port.Poll()
+	if err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("processor called %d times, want 1", calls)
+	}
+	if data.Audio == nil || len(data.PCM) != frameSize*2 {
+		t.Fatalf("uplink data: audio=%v pcm=%d bytes", data.Audio != nil, len(data.PCM))
+	}
+	for _, b := range data.PCM {
+		if b != 0 {
+			t.Fatal("PCM was not processed")
+		}
+	}
+}
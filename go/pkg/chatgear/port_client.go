@@ -43,15 +43,24 @@ type Speaker interface {
 //	for cmd, err := range port.Commands() {
 //	    // handle commands from server
 //	}
+//
+// Device configs pushed by the server are acknowledged automatically and
+// returned by Configs().
+//
+// WriteToSpeaker can queue some audio before playback starts (see
+// SetDownlinkPrebuffer and DeviceConfig.DownlinkPrebufferMs), and reports
+// underruns in the DownlinkAudio stats.
 type ClientPort struct {
 	// Downlink - from server
 	downlinkAudio *buffer.Buffer[StampedOpusFrame]
 	commandQueue  *buffer.Buffer[*CommandEvent]
+	configQueue   *buffer.Buffer[*DeviceConfig]
 
 	// Uplink - to server
 	uplinkAudio *buffer.Buffer[StampedOpusFrame]
 	uplinkState *buffer.Buffer[*StateEvent]
 	uplinkStats *buffer.Buffer[*StatsEvent]
+	uplinkAcks  *buffer.Buffer[*ConfigAck]
 
 	// Internal state
 	mu           sync.RWMutex
@@ -59,8 +68,13 @@ type ClientPort struct {
 	stats        *StatsEvent // Full stats storage
 	statsPending *StatsEvent // Only changed fields (for diff upload)
 	batchMode    bool        // When true, Set* methods don't queue updates
+	config       *DeviceConfig
 	closed       bool
 
+	// Downlink playback
+	prebuffer             time.Duration  // see SetDownlinkPrebuffer
+	downlinkAudioReported jsontime.Milli // UpdateAt of the last DownlinkAudio reported
+
 	logger Logger
 }
 
@@ -69,9 +83,11 @@ func NewClientPort() *ClientPort {
 	return &ClientPort{
 		downlinkAudio: buffer.N[StampedOpusFrame](256),
 		commandQueue:  buffer.N[*CommandEvent](32),
+		configQueue:   buffer.N[*DeviceConfig](4),
 		uplinkAudio:   buffer.N[StampedOpusFrame](256),
 		uplinkState:   buffer.N[*StateEvent](32),
 		uplinkStats:   buffer.N[*StatsEvent](32),
+		uplinkAcks:    buffer.N[*ConfigAck](4),
 		stats:         &StatsEvent{},
 		logger:        DefaultLogger(),
 	}
@@ -151,8 +167,9 @@ func (p *ClientPort) WriteToSpeaker(speaker Speaker) error {
 	}
 	defer decoder.Close()
 
+	var pb downlinkPlayback
 	for {
-		frame, err := p.downlinkAudio.Next()
+		frame, err := p.nextDownlinkFrame(&pb)
 		if err != nil {
 			if err == buffer.ErrIteratorDone {
 				return nil
@@ -174,6 +191,87 @@ func (p *ClientPort) WriteToSpeaker(speaker Speaker) error {
 	}
 }
 
+// downlinkPrebufferPoll is how often the downlink queue is checked while
+// prebuffering.
+const downlinkPrebufferPoll = 5 * time.Millisecond
+
+// downlinkPlayback is the playback state of WriteToSpeaker.
+type downlinkPlayback struct {
+	playing bool
+	last    StampedOpusFrame
+}
+
+// SetDownlinkPrebuffer sets how much audio is queued before playback starts
+// and after an underrun. It applies unless the device config sets
+// DownlinkPrebufferMs. Zero, the default, plays frames as they arrive.
+func (p *ClientPort) SetDownlinkPrebuffer(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prebuffer = d
+}
+
+// DownlinkPrebuffer returns the prebuffer depth in effect.
+func (p *ClientPort) DownlinkPrebuffer() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config != nil && p.config.DownlinkPrebufferMs > 0 {
+		return time.Duration(p.config.DownlinkPrebufferMs) * time.Millisecond
+	}
+	return p.prebuffer
+}
+
+// nextDownlinkFrame returns the next frame to play. The queue running dry
+// for longer than a frame stops playback; it is an underrun if the next
+// frame continues the same stream (timestamps contiguous), the start of a
+// new response otherwise. Playback starts again once the prebuffer is
+// queued.
+func (p *ClientPort) nextDownlinkFrame(pb *downlinkPlayback) (StampedOpusFrame, error) {
+	start := time.Now()
+	frame, err := p.downlinkAudio.Next()
+	if err != nil {
+		return frame, err
+	}
+	waited := time.Since(start)
+
+	if pb.playing && waited > frameDuration(pb.last.Frame) {
+		pb.playing = false
+		gap := frame.Timestamp.Sub(pb.last.Timestamp)
+		if gap <= frameDuration(pb.last.Frame)*3/2 {
+			p.recordUnderrun(waited)
+		}
+	}
+	if !pb.playing {
+		prebuffer := p.DownlinkPrebuffer()
+		p.waitPrebuffer(prebuffer, frameDuration(frame.Frame))
+		p.recordPlayback(prebuffer)
+		pb.playing = true
+	}
+	pb.last = frame
+	return frame, nil
+}
+
+// waitPrebuffer waits until d of audio, including a frame of frameDur just
+// taken, is queued. It waits at most d, so that a response shorter than
+// the prebuffer still plays.
+func (p *ClientPort) waitPrebuffer(d, frameDur time.Duration) {
+	if d <= frameDur {
+		return
+	}
+	want := int((d - 1) / frameDur) // frames queued behind the one taken
+	deadline := time.Now().Add(d)
+	for p.downlinkAudio.Len() < want && time.Now().Before(deadline) {
+		time.Sleep(downlinkPrebufferPoll)
+	}
+}
+
+// frameDuration returns the duration of f, or 20ms if its TOC is invalid.
+func frameDuration(f opus.Frame) time.Duration {
+	if d := f.Duration(); d > 0 {
+		return d
+	}
+	return 20 * time.Millisecond
+}
+
 // bytesToInt16 converts a byte slice to an int16 slice without copying.
 func bytesToInt16(b []byte) []int16 {
 	return unsafe.Slice((*int16)(unsafe.Pointer(&b[0])), len(b)/2)
@@ -204,7 +302,7 @@ func (p *ClientPort) ReadFrom(rx DownlinkRx) error {
 		mu.Unlock()
 	}
 
-	wg.Add(2)
+	wg.Add(3)
 
 	// Read opus frames
 	go func() {
@@ -238,10 +336,44 @@ func (p *ClientPort) ReadFrom(rx DownlinkRx) error {
 		}
 	}()
 
+	// Read device configs
+	go func() {
+		defer wg.Done()
+		for cfg, err := range rx.Configs() {
+			if err != nil {
+				setErr(err)
+				return
+			}
+			if err := p.handleConfig(cfg); err != nil {
+				setErr(err)
+				return
+			}
+		}
+	}()
+
 	wg.Wait()
 	return firstErr
 }
 
+// handleConfig applies a device config from the server if it is newer than
+// the current one, and acknowledges the version the device has.
+func (p *ClientPort) handleConfig(cfg *DeviceConfig) error {
+	p.mu.Lock()
+	if p.config != nil && cfg.Version <= p.config.Version {
+		// Stale or repeated push: report the version we have.
+		version := p.config.Version
+		p.mu.Unlock()
+		return p.uplinkAcks.Add(NewConfigAck(version, time.Now()))
+	}
+	p.config = cfg.Clone()
+	p.mu.Unlock()
+
+	if err := p.configQueue.Add(cfg); err != nil {
+		return err
+	}
+	return p.uplinkAcks.Add(NewConfigAck(cfg.Version, time.Now()))
+}
+
 // Commands returns an iterator for commands from the server.
 func (p *ClientPort) Commands() iter.Seq2[*CommandEvent, error] {
 	return func(yield func(*CommandEvent, error) bool) {
@@ -261,6 +393,42 @@ func (p *ClientPort) Commands() iter.Seq2[*CommandEvent, error] {
 	}
 }
 
+// Configs returns an iterator for device configs from the server. Only
+// configs newer than the current one are returned.
+func (p *ClientPort) Configs() iter.Seq2[*DeviceConfig, error] {
+	return func(yield func(*DeviceConfig, error) bool) {
+		for {
+			cfg, err := p.configQueue.Next()
+			if err != nil {
+				if err == buffer.ErrIteratorDone {
+					return
+				}
+				yield(nil, err)
+				return
+			}
+			if !yield(cfg, nil) {
+				return
+			}
+		}
+	}
+}
+
+// SetConfig sets the current device config without acknowledging it, e.g.
+// to restore the config the device persisted before a reboot. The server
+// then pushes only newer configs.
+func (p *ClientPort) SetConfig(cfg *DeviceConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = cfg.Clone()
+}
+
+// Config returns the current device config.
+func (p *ClientPort) Config() (*DeviceConfig, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.config, p.config != nil
+}
+
 // =============================================================================
 // Network Layer: Uplink (Client -> Server)
 // =============================================================================
@@ -268,9 +436,20 @@ func (p *ClientPort) Commands() iter.Seq2[*CommandEvent, error] {
 // WriteTo writes data to the given UplinkTx from the uplink queues.
 // This method blocks until all queues are closed or an error occurs.
 // Use `go port.WriteTo(tx)` for non-blocking operation.
+//
+// WriteTo first reports the version of the current device config, so that
+// the server pushes its config if it is newer.
 func (p *ClientPort) WriteTo(tx UplinkTx) error {
 	defer tx.Close()
 
+	p.mu.RLock()
+	var version int64
+	if p.config != nil {
+		version = p.config.Version
+	}
+	p.mu.RUnlock()
+	p.uplinkAcks.Add(NewConfigAck(version, time.Now()))
+
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	var firstErr error
@@ -286,7 +465,7 @@ func (p *ClientPort) WriteTo(tx UplinkTx) error {
 		mu.Unlock()
 	}
 
-	wg.Add(3)
+	wg.Add(4)
 
 	// Write audio frames
 	go func() {
@@ -348,6 +527,25 @@ func (p *ClientPort) WriteTo(tx UplinkTx) error {
 		}
 	}()
 
+	// Write config acks
+	go func() {
+		defer wg.Done()
+		for {
+			ack, err := p.uplinkAcks.Next()
+			if err != nil {
+				if err == buffer.ErrIteratorDone {
+					return
+				}
+				setErr(err)
+				return
+			}
+			if err := tx.SendConfigAck(ack); err != nil {
+				setErr(err)
+				return
+			}
+		}
+	}()
+
 	wg.Wait()
 	return firstErr
 }
@@ -694,6 +892,36 @@ func (p *ClientPort) SetShaking(level float64) {
 	p.notifyStatsSend()
 }
 
+// recordPlayback counts a playback start in the downlink audio stats. The
+// stats are reported periodically rather than queued per update.
+func (p *ClientPort) recordPlayback(prebuffer time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	da := p.downlinkAudioStats()
+	da.PrebufferMs = prebuffer.Milliseconds()
+	da.Playbacks++
+}
+
+// recordUnderrun counts an underrun that stalled playback for stall in the
+// downlink audio stats.
+func (p *ClientPort) recordUnderrun(stall time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	da := p.downlinkAudioStats()
+	da.Underruns++
+	da.UnderrunMs += stall.Milliseconds()
+}
+
+// downlinkAudioStats returns the downlink audio stats for update.
+// Must be called with lock held.
+func (p *ClientPort) downlinkAudioStats() *DownlinkAudio {
+	if p.stats.DownlinkAudio == nil {
+		p.stats.DownlinkAudio = &DownlinkAudio{}
+	}
+	p.stats.DownlinkAudio.UpdateAt = jsontime.NowEpochMilli()
+	return p.stats.DownlinkAudio
+}
+
 // =============================================================================
 // Periodic Reporting (Protocol)
 // =============================================================================
@@ -734,7 +962,7 @@ func (p *ClientPort) stateSendLoop(ctx context.Context) {
 }
 
 // statsReportLoop implements tiered stats reporting:
-// - Every 1 minute (20s * 3): battery, volume, brightness, light_mode, sys_ver, wifi
+// - Every 1 minute (20s * 3): battery, volume, brightness, light_mode, sys_ver, wifi, downlink_audio
 // - Every 2 minutes (20s * 6): shaking, cellular
 // - Every 10 minutes (20s * 30): wifi_store
 // Note: Initial stats should be sent via BeginBatch/EndBatch before calling StartPeriodicReporting.
@@ -772,7 +1000,7 @@ func (p *ClientPort) sendPeriodicStats(rounds int) {
 	switch rounds % 3 {
 	case 0:
 		// Every 1 minute (20s * 3 = 60s)
-		// Report: battery, volume, brightness, light_mode, sys_ver, wifi, pair_status
+		// Report: battery, volume, brightness, light_mode, sys_ver, wifi, pair_status, downlink_audio
 		if p.stats.Battery != nil {
 			p.statsPending.Battery = &Battery{
 				Percentage: p.stats.Battery.Percentage,
@@ -824,6 +1052,12 @@ func (p *ClientPort) sendPeriodicStats(rounds int) {
 			}
 			hasFields = true
 		}
+		// Downlink audio counters, when they changed since the last report
+		if da := p.stats.DownlinkAudio; da != nil && da.UpdateAt.After(p.downlinkAudioReported) {
+			p.statsPending.DownlinkAudio = clonePtr(da)
+			p.downlinkAudioReported = da.UpdateAt
+			hasFields = true
+		}
 
 	case 1:
 		// Every 2 minutes (rounds % 6 == 1)
@@ -879,8 +1113,10 @@ func (p *ClientPort) Close() error {
 
 	p.downlinkAudio.Close()
 	p.commandQueue.Close()
+	p.configQueue.Close()
 	p.uplinkAudio.Close()
 	p.uplinkState.Close()
 	p.uplinkStats.Close()
+	p.uplinkAcks.Close()
 	return nil
 }
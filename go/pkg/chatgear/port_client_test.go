@@ -6,6 +6,7 @@ import (
 	"testing/synctest"
 	"time"
 
+	"github.com/haivivi/giztoy/go/pkg/audio/codec/opus"
 	"github.com/haivivi/giztoy/go/pkg/audio/pcm"
 )
 
@@ -137,6 +138,90 @@ func TestClientPort_SetShaking(t *testing.T) {
 	port.SetShaking(1.0)
 }
 
+func TestClientPort_DownlinkPrebuffer(t *testing.T) {
+	port := NewClientPort()
+	defer port.Close()
+
+	if got := port.DownlinkPrebuffer(); got != 0 {
+		t.Errorf("default prebuffer = %v, want 0", got)
+	}
+	port.SetDownlinkPrebuffer(60 * time.Millisecond)
+	if got := port.DownlinkPrebuffer(); got != 60*time.Millisecond {
+		t.Errorf("prebuffer = %v, want 60ms", got)
+	}
+	port.SetConfig(&DeviceConfig{Version: 1, DownlinkPrebufferMs: 200})
+	if got := port.DownlinkPrebuffer(); got != 200*time.Millisecond {
+		t.Errorf("prebuffer with config = %v, want 200ms from config", got)
+	}
+}
+
+func TestClientPort_DownlinkUnderrun(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		port := NewClientPort()
+		defer port.Close()
+		port.SetDownlinkPrebuffer(60 * time.Millisecond)
+
+		// A 20ms SILK frame (TOC config 1, one frame).
+		frame := opus.Frame{1 << 3, 0}
+		base := time.Now()
+		send := func(i int) {
+			port.downlinkAudio.Add(StampedOpusFrame{
+				Timestamp: base.Add(time.Duration(i) * 20 * time.Millisecond),
+				Frame:     frame,
+			})
+		}
+
+		var pb downlinkPlayback
+		next := func() time.Duration {
+			start := time.Now()
+			if _, err := port.nextDownlinkFrame(&pb); err != nil {
+				t.Fatalf("nextDownlinkFrame: %v", err)
+			}
+			return time.Since(start)
+		}
+
+		// Playback starts once 60ms are queued.
+		send(0)
+		go func() {
+			time.Sleep(40 * time.Millisecond)
+			send(1)
+			send(2)
+		}()
+		if d := next(); d < 40*time.Millisecond {
+			t.Errorf("first frame after %v, want prebuffered", d)
+		}
+		next()
+		next()
+
+		// The stream continues 100ms late: an underrun.
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			for i := 3; i < 6; i++ {
+				send(i)
+			}
+		}()
+		next()
+		next()
+		next()
+
+		// A new response after a pause is not an underrun.
+		go func() {
+			time.Sleep(time.Second)
+			send(100)
+			send(101)
+			send(102)
+		}()
+		next()
+
+		port.mu.RLock()
+		da := *port.stats.DownlinkAudio
+		port.mu.RUnlock()
+		if da.Playbacks != 3 || da.Underruns != 1 || da.UnderrunMs != 100 || da.PrebufferMs != 60 {
+			t.Errorf("DownlinkAudio = %+v, want 3 playbacks, 1 underrun of 100ms, prebuffer 60", da)
+		}
+	})
+}
+
 func TestClientPort_ReadFromMic_Nil(t *testing.T) {
 	port := NewClientPort()
 	defer port.Close()
@@ -7,7 +7,9 @@ import (
 
 	"github.com/haivivi/giztoy/go/pkg/audio/codec/opus"
 	"github.com/haivivi/giztoy/go/pkg/audio/pcm"
+	"github.com/haivivi/giztoy/go/pkg/audio/quality"
 	"github.com/haivivi/giztoy/go/pkg/buffer"
+	"github.com/haivivi/giztoy/go/pkg/jsontime"
 )
 
 // UplinkData represents data received from the device.
@@ -18,6 +20,11 @@ type UplinkData struct {
 	State *StateEvent
 	// StatsChanges is set when there are stats changes.
 	StatsChanges *StatsChanges
+	// ConfigAck is set when the device acknowledges a device config.
+	ConfigAck *ConfigAck
+	// PCM is the decoded audio of Audio after uplink processing, in the
+	// UplinkProcessing format. Set only when uplink processing is enabled.
+	PCM []byte
 }
 
 // ServerPort is a bidirectional audio port for server-side communication.
@@ -33,6 +40,9 @@ type UplinkData struct {
 //	    // ...
 //	    port.SetVolume(50)         // issue command
 //	}
+//
+// SetConfig pushes a device config, which the port pushes again whenever the
+// device reports an older version, e.g. after reconnecting.
 type ServerPort struct {
 	// Uplink - from device
 	uplinkQueue *buffer.Buffer[UplinkData]
@@ -43,12 +53,20 @@ type ServerPort struct {
 	foreground   *pcm.TrackCtrl
 	overlay      *pcm.TrackCtrl
 	commandQueue *buffer.Buffer[*CommandEvent]
+	configQueue  *buffer.Buffer[*DeviceConfig]
 
 	// State
-	mu     sync.RWMutex
-	stats  *StatsEvent
-	state  *StateEvent
-	closed bool
+	mu        sync.RWMutex
+	stats     *StatsEvent
+	state     *StateEvent
+	config    *DeviceConfig
+	configAck *ConfigAck
+	closed    bool
+
+	uplinkChain *uplinkChain
+
+	// uplinkQuality measures the loss of uplink audio frames.
+	uplinkQuality *quality.Meter
 
 	logger Logger
 }
@@ -58,7 +76,10 @@ func NewServerPort() *ServerPort {
 	p := &ServerPort{
 		uplinkQueue:  buffer.N[UplinkData](256),
 		commandQueue: buffer.N[*CommandEvent](32),
+		configQueue:  buffer.N[*DeviceConfig](4),
 		logger:       DefaultLogger(),
+
+		uplinkQuality: quality.NewMeter(quality.Config{}),
 	}
 
 	// Track count for streaming state management
@@ -87,7 +108,10 @@ func NewServerPortWithMixer(mixer *pcm.Mixer) *ServerPort {
 		uplinkQueue:  buffer.N[UplinkData](256),
 		mixer:        mixer,
 		commandQueue: buffer.N[*CommandEvent](32),
+		configQueue:  buffer.N[*DeviceConfig](4),
 		logger:       DefaultLogger(),
+
+		uplinkQuality: quality.NewMeter(quality.Config{}),
 	}
 }
 
@@ -116,7 +140,7 @@ func (p *ServerPort) ReadFrom(rx UplinkRx) error {
 		mu.Unlock()
 	}
 
-	wg.Add(3)
+	wg.Add(4)
 
 	// Read opus frames
 	go func() {
@@ -127,7 +151,7 @@ func (p *ServerPort) ReadFrom(rx UplinkRx) error {
 				return
 			}
 			frameCopy := frame // copy to avoid closure capture issues
-			data := UplinkData{Audio: &frameCopy}
+			data := p.uplinkAudio(&frameCopy)
 			if err := p.uplinkQueue.Add(data); err != nil {
 				setErr(err)
 				return
@@ -172,6 +196,23 @@ func (p *ServerPort) ReadFrom(rx UplinkRx) error {
 		}
 	}()
 
+	// Read config acks
+	go func() {
+		defer wg.Done()
+		for ack, err := range rx.ConfigAcks() {
+			if err != nil {
+				setErr(err)
+				return
+			}
+			p.handleConfigAck(ack)
+			data := UplinkData{ConfigAck: ack}
+			if err := p.uplinkQueue.Add(data); err != nil {
+				setErr(err)
+				return
+			}
+		}
+	}()
+
 	wg.Wait()
 	return firstErr
 }
@@ -192,7 +233,7 @@ func (p *ServerPort) HandleAudio(frame *StampedOpusFrame) {
 	if frame == nil {
 		return
 	}
-	data := UplinkData{Audio: frame}
+	data := p.uplinkAudio(frame)
 	p.uplinkQueue.Add(data)
 }
 
@@ -221,6 +262,17 @@ func (p *ServerPort) HandleStats(stats *StatsEvent) {
 	p.uplinkQueue.Add(data)
 }
 
+// HandleConfigAck handles an incoming config ack from the device.
+// This method is called by the Listener when a new config ack is received.
+func (p *ServerPort) HandleConfigAck(ack *ConfigAck) {
+	if ack == nil {
+		return
+	}
+	p.handleConfigAck(ack)
+	data := UplinkData{ConfigAck: ack}
+	p.uplinkQueue.Add(data)
+}
+
 // handleStateEvent updates internal state from a state event.
 func (p *ServerPort) handleStateEvent(e *StateEvent) {
 	p.mu.Lock()
@@ -248,6 +300,22 @@ func (p *ServerPort) handleStatsEvent(e *StatsEvent) *StatsChanges {
 	return changes
 }
 
+// handleConfigAck records a config ack, and pushes the config again if the
+// device has an older version.
+func (p *ServerPort) handleConfigAck(ack *ConfigAck) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.configAck = ack
+	if ack.Error != "" {
+		p.logger.WarnPrintf("device rejected config version %d: %s", ack.Version, ack.Error)
+		return
+	}
+	if p.config != nil && ack.Version < p.config.Version {
+		p.pushConfigLocked()
+	}
+}
+
 // =============================================================================
 // Downlink: Server -> Device
 // =============================================================================
@@ -273,7 +341,7 @@ func (p *ServerPort) WriteTo(tx DownlinkTx) error {
 		mu.Unlock()
 	}
 
-	wg.Add(2)
+	wg.Add(3)
 
 	// Write audio frames from mixer
 	go func() {
@@ -300,6 +368,25 @@ func (p *ServerPort) WriteTo(tx DownlinkTx) error {
 		}
 	}()
 
+	// Write device configs
+	go func() {
+		defer wg.Done()
+		for {
+			cfg, err := p.configQueue.Next()
+			if err != nil {
+				if err == buffer.ErrIteratorDone {
+					return
+				}
+				setErr(err)
+				return
+			}
+			if err := tx.PushConfig(cfg); err != nil {
+				setErr(err)
+				return
+			}
+		}
+	}()
+
 	wg.Wait()
 	return firstErr
 }
@@ -331,6 +418,8 @@ func (p *ServerPort) streamAudioTo(tx DownlinkTx, setErr func(error)) {
 			return
 		}
 
+		p.observePlayback(pcmBytes[:n])
+
 		// Convert bytes to int16 samples
 		pcmSamples := bytesToInt16(pcmBytes[:n])
 
@@ -390,17 +479,15 @@ func (p *ServerPort) NewBackgroundTrack() (pcm.Track, *pcm.TrackCtrl, error) {
 	return w, ctrl, nil
 }
 
-// NewForegroundTrack creates a new foreground audio track.
+// NewForegroundTrack creates a new foreground audio track. It crossfades
+// from the current foreground track, if any.
 func (p *ServerPort) NewForegroundTrack() (pcm.Track, *pcm.TrackCtrl, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	w, ctrl, err := p.mixer.CreateTrack()
+	w, ctrl, err := p.mixer.CreateTrack(pcm.WithCrossfade(p.foreground, 200*time.Millisecond))
 	if err != nil {
 		return nil, nil, err
 	}
-	if p.foreground != nil {
-		p.foreground.CloseWithError(nil)
-	}
 	p.foreground = ctrl
 	ctrl.SetFadeOutDuration(200 * time.Millisecond)
 	return w, ctrl, nil
@@ -465,11 +552,28 @@ func (p *ServerPort) Interrupt() {
 // State Getters
 // =============================================================================
 
-// Stats returns the current stats.
+// Stats returns the current stats, with UplinkAudio set once the device
+// has sent audio. The returned event is a copy.
 func (p *ServerPort) Stats() (*StatsEvent, bool) {
 	p.mu.RLock()
-	defer p.mu.RUnlock()
-	return p.stats, p.stats != nil
+	stats := p.stats.Clone()
+	p.mu.RUnlock()
+
+	if r, ok := p.AudioQuality(); ok {
+		if stats == nil {
+			stats = &StatsEvent{Time: jsontime.NowEpochMilli()}
+		}
+		stats.UplinkAudio = &r
+	}
+	return stats, stats != nil
+}
+
+// AudioQuality returns the loss metrics of the audio received from the
+// device: the concealed ratio, gap distribution and estimated MOS. It
+// tells garbled uplink audio apart from a bad model answer.
+func (p *ServerPort) AudioQuality() (quality.Report, bool) {
+	r := p.uplinkQuality.Report()
+	return r, r.Received > 0
 }
 
 // State returns the current state.
@@ -579,6 +683,29 @@ func (p *ServerPort) ReadNFCTag() (*ReadNFCTag, bool) {
 	return nil, false
 }
 
+// Config returns the device config set by SetConfig.
+func (p *ServerPort) Config() (*DeviceConfig, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.config, p.config != nil
+}
+
+// LastConfigAck returns the last config ack from the device.
+func (p *ServerPort) LastConfigAck() (*ConfigAck, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.configAck, p.configAck != nil
+}
+
+// ConfigSynced reports whether the device has acknowledged the version of
+// the device config set by SetConfig.
+func (p *ServerPort) ConfigSynced() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.config != nil && p.configAck != nil &&
+		p.configAck.Error == "" && p.configAck.Version >= p.config.Version
+}
+
 // Shaking returns the current shaking level.
 func (p *ServerPort) Shaking() (float64, bool) {
 	p.mu.RLock()
@@ -589,6 +716,17 @@ func (p *ServerPort) Shaking() (float64, bool) {
 	return 0, false
 }
 
+// DownlinkAudio returns the playback statistics reported by the device:
+// prebuffer depth and underruns of the audio sent to it.
+func (p *ServerPort) DownlinkAudio() (DownlinkAudio, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stats != nil && p.stats.DownlinkAudio != nil {
+		return *p.stats.DownlinkAudio, true
+	}
+	return DownlinkAudio{}, false
+}
+
 // =============================================================================
 // Device Commands
 // =============================================================================
@@ -599,12 +737,41 @@ func (p *ServerPort) IssueCommand(cmd Command) {
 	p.commandQueue.Add(evt)
 }
 
-// SetVolume sets the volume of the device.
+// SetVolume sets the volume of the device, limited to the volume range of
+// the device config.
 func (p *ServerPort) SetVolume(volume int) {
+	p.mu.RLock()
+	volume = p.config.ClampVolume(volume)
+	p.mu.RUnlock()
 	cmd := SetVolume(volume)
 	p.IssueCommand(&cmd)
 }
 
+// SetConfig sets the device config and pushes it to the device. If
+// cfg.Version is 0, it is set to one more than the current version.
+func (p *ServerPort) SetConfig(cfg *DeviceConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cfg = cfg.Clone()
+	if cfg.Version == 0 {
+		cfg.Version = 1
+		if p.config != nil {
+			cfg.Version = p.config.Version + 1
+		}
+	}
+	p.config = cfg
+	p.pushConfigLocked()
+}
+
+// pushConfigLocked queues the current device config for the device.
+// Note: caller must hold p.mu lock.
+func (p *ServerPort) pushConfigLocked() {
+	cfg := p.config.Clone()
+	cfg.Time = jsontime.NowEpochMilli()
+	p.configQueue.Add(cfg)
+}
+
 // SetLightMode sets the light mode of the device.
 func (p *ServerPort) SetLightMode(mode string) {
 	cmd := SetLightMode(mode)
@@ -673,6 +840,7 @@ func (p *ServerPort) Close() error {
 
 	p.uplinkQueue.Close()
 	p.commandQueue.Close()
+	p.configQueue.Close()
 	p.mixer.Close()
-	return nil
+	return p.SetUplinkProcessing(nil)
 }
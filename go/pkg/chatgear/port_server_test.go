@@ -491,6 +491,46 @@ func TestServerPort_ReadFrom_Stats(t *testing.T) {
 	}
 }
 
+func TestServerPort_AudioQuality(t *testing.T) {
+	port := NewServerPort()
+	defer port.Close()
+
+	if _, ok := port.AudioQuality(); ok {
+		t.Error("AudioQuality should return not ok before audio")
+	}
+
+	// TOC config 1 (SILK NB, 20ms), one frame; frame 3 of 6 is lost.
+	t0 := time.UnixMilli(1_700_000_000_000)
+	for _, i := range []int{0, 1, 3, 4, 5} {
+		port.HandleAudio(&StampedOpusFrame{
+			Timestamp: t0.Add(time.Duration(i) * 20 * time.Millisecond),
+			Frame:     []byte{1 << 3, 0xAA},
+		})
+	}
+
+	r, ok := port.AudioQuality()
+	if !ok {
+		t.Fatal("AudioQuality should return ok after audio")
+	}
+	if r.Received != 5 || r.Lost != 1 || r.Bursts != 1 {
+		t.Errorf("AudioQuality = %+v", r)
+	}
+	if r.ConcealedRatio < 0.16 || r.ConcealedRatio > 0.17 {
+		t.Errorf("ConcealedRatio = %v, want 1/6", r.ConcealedRatio)
+	}
+
+	// Stats carries the report even before the device sent stats.
+	stats, ok := port.Stats()
+	if !ok || stats.UplinkAudio == nil || stats.UplinkAudio.Lost != 1 {
+		t.Fatalf("Stats = %+v, %v", stats, ok)
+	}
+	port.HandleStats(&StatsEvent{Time: jsontime.NowEpochMilli(), Volume: &Volume{Percentage: 30}})
+	stats, _ = port.Stats()
+	if stats.Volume == nil || stats.UplinkAudio == nil || stats.UplinkAudio.Received != 5 {
+		t.Errorf("Stats = %+v", stats)
+	}
+}
+
 func TestServerPort_WriteTo_Commands(t *testing.T) {
 	port := NewServerPort()
 	defer port.Close()
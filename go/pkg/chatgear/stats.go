@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"slices"
 
+	"github.com/haivivi/giztoy/go/pkg/audio/quality"
 	"github.com/haivivi/giztoy/go/pkg/encoding"
 	"github.com/haivivi/giztoy/go/pkg/jsontime"
 )
@@ -25,6 +26,7 @@ type StatsChanges struct {
 	ReadNFCTag    *ReadNFCTag
 	PairStatus    *PairStatus
 	Shaking       *Shaking
+	DownlinkAudio *DownlinkAudio
 }
 
 // StatsEvent converts changes to a full stats event.
@@ -44,6 +46,7 @@ func (c *StatsChanges) StatsEvent() *StatsEvent {
 		ReadNFCTag:    c.ReadNFCTag,
 		PairStatus:    c.PairStatus,
 		Shaking:       c.Shaking,
+		DownlinkAudio: c.DownlinkAudio,
 	}
 	if c.LastResetAt != nil {
 		e.LastResetAt = *c.LastResetAt
@@ -66,6 +69,11 @@ type StatsEvent struct {
 	ReadNFCTag    *ReadNFCTag        `json:"read_nfc_tag,omitzero"`
 	PairStatus    *PairStatus        `json:"pair_status,omitzero"`
 	Shaking       *Shaking           `json:"shaking,omitzero"`
+	DownlinkAudio *DownlinkAudio     `json:"downlink_audio,omitzero"`
+
+	// UplinkAudio is measured by the server from the received audio
+	// frames, not reported by the device; see ServerPort.AudioQuality.
+	UplinkAudio *quality.Report `json:"uplink_audio,omitzero"`
 }
 
 // SystemVersion contains system version information.
@@ -183,6 +191,21 @@ type Shaking struct {
 	Level float64 `json:"level"`
 }
 
+// DownlinkAudio contains playback statistics of the audio received from
+// the server. Counters accumulate since the device started.
+type DownlinkAudio struct {
+	// PrebufferMs is the prebuffer depth in effect, in milliseconds.
+	PrebufferMs int64 `json:"prebuffer_ms,omitzero"`
+	// Playbacks is the number of times playback started after
+	// prebuffering, at the start of a response or after an underrun.
+	Playbacks int64 `json:"playbacks,omitzero"`
+	// Underruns is the number of times the queue ran dry in the middle of
+	// a response, and UnderrunMs the total time playback stalled.
+	Underruns  int64          `json:"underruns,omitzero"`
+	UnderrunMs int64          `json:"underrun_ms,omitzero"`
+	UpdateAt   jsontime.Milli `json:"update_at"`
+}
+
 // Clone returns a deep copy of the stats event.
 func (e *StatsEvent) Clone() *StatsEvent {
 	if e == nil {
@@ -199,6 +222,8 @@ func (e *StatsEvent) Clone() *StatsEvent {
 	v.ReadNFCTag = e.ReadNFCTag.clone()
 	v.PairStatus = clonePtr(e.PairStatus)
 	v.Shaking = clonePtr(e.Shaking)
+	v.DownlinkAudio = clonePtr(e.DownlinkAudio)
+	v.UplinkAudio = clonePtr(e.UplinkAudio)
 	return &v
 }
 
@@ -378,6 +403,14 @@ func (e *StatsEvent) MergeWith(other *StatsEvent) *StatsChanges {
 		diff.Shaking = clonePtr(other.Shaking)
 	}
 
+	switch {
+	case other.DownlinkAudio == nil:
+	case e.DownlinkAudio == nil,
+		other.DownlinkAudio.UpdateAt.After(e.DownlinkAudio.UpdateAt):
+		e.DownlinkAudio = other.DownlinkAudio
+		diff.DownlinkAudio = clonePtr(other.DownlinkAudio)
+	}
+
 	if diff == (StatsChanges{}) {
 		return nil
 	}
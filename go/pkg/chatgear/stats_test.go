@@ -615,6 +615,34 @@ func TestStatsEvent_MergeWith_Brightness(t *testing.T) {
 	}
 }
 
+func TestStatsEvent_MergeWith_DownlinkAudio(t *testing.T) {
+	t1 := jsontime.Milli(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	t2 := jsontime.Milli(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	event := &StatsEvent{
+		Time:          t1,
+		DownlinkAudio: &DownlinkAudio{Playbacks: 3, UpdateAt: t1},
+	}
+	other := &StatsEvent{
+		Time:          t2,
+		DownlinkAudio: &DownlinkAudio{Playbacks: 5, Underruns: 1, UnderrunMs: 120, UpdateAt: t2},
+	}
+
+	changes := event.MergeWith(other)
+	if changes == nil || changes.DownlinkAudio == nil {
+		t.Fatal("DownlinkAudio change should be detected")
+	}
+	if changes.DownlinkAudio.Underruns != 1 || event.DownlinkAudio.Playbacks != 5 {
+		t.Errorf("DownlinkAudio = %+v, want the newer counters", event.DownlinkAudio)
+	}
+
+	// Repeated report of the same counters.
+	other.Time = jsontime.Milli(time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC))
+	if changes := event.MergeWith(other.Clone()); changes != nil {
+		t.Errorf("repeated DownlinkAudio should not be a change, got %+v", changes)
+	}
+}
+
 func TestStatsEvent_MergeWith_LightMode(t *testing.T) {
 	t1 := jsontime.Milli(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
 	t2 := jsontime.Milli(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
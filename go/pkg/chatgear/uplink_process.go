@@ -0,0 +1,181 @@
+package chatgear
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/haivivi/giztoy/go/pkg/audio/codec/opus"
+	"github.com/haivivi/giztoy/go/pkg/audio/pcm"
+)
+
+// UplinkProcessor processes decoded uplink audio (denoise, AGC, AEC, ...).
+// ProcessUplink receives interleaved 16-bit samples of one frame in the
+// UplinkProcessing format and modifies them in place.
+//
+// Processors are called from the uplink goroutine, one frame at a time and
+// in frame order.
+type UplinkProcessor interface {
+	ProcessUplink(samples []int16) error
+}
+
+// UplinkProcessorFunc adapts a function to the UplinkProcessor interface.
+type UplinkProcessorFunc func(samples []int16) error
+
+// ProcessUplink calls f.
+func (f UplinkProcessorFunc) ProcessUplink(samples []int16) error {
+	return f(samples)
+}
+
+// PlaybackObserver is implemented by uplink processors that need the audio
+// played on the device as a reference, such as echo cancellers. The port
+// passes every downlink frame, in the mixer output format, before it is
+// sent to the device. ObservePlayback is called from the downlink
+// goroutine, concurrently with ProcessUplink.
+type PlaybackObserver interface {
+	ObservePlayback(samples []int16)
+}
+
+// UplinkProcessing configures the uplink processing chain of a ServerPort.
+type UplinkProcessing struct {
+	// Format is the format the uplink audio is decoded to and processed in.
+	// The zero value is pcm.L16Mono16K.
+	Format pcm.Format
+
+	// Processors run in order on every uplink audio frame.
+	Processors []UplinkProcessor
+}
+
+// uplinkChain decodes, processes and re-encodes uplink opus frames.
+type uplinkChain struct {
+	mu      sync.Mutex
+	format  pcm.Format
+	procs   []UplinkProcessor
+	decoder *opus.Decoder
+	encoder *opus.Encoder
+	closed  bool
+}
+
+func newUplinkChain(cfg *UplinkProcessing) (*uplinkChain, error) {
+	format := cfg.Format
+	decoder, err := opus.NewDecoder(format.SampleRate(), format.Channels())
+	if err != nil {
+		return nil, fmt.Errorf("chatgear: uplink decoder: %w", err)
+	}
+	encoder, err := opus.NewVoIPEncoder(format.SampleRate(), format.Channels())
+	if err != nil {
+		decoder.Close()
+		return nil, fmt.Errorf("chatgear: uplink encoder: %w", err)
+	}
+	return &uplinkChain{
+		format:  format,
+		procs:   cfg.Processors,
+		decoder: decoder,
+		encoder: encoder,
+	}, nil
+}
+
+// process runs the chain on frame. It returns the re-encoded frame and the
+// processed PCM.
+func (c *uplinkChain) process(frame *StampedOpusFrame) (*StampedOpusFrame, []byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return frame, nil, nil
+	}
+
+	data, err := c.decoder.Decode(frame.Frame)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode: %w", err)
+	}
+	if len(data) < 2 {
+		return frame, data, nil
+	}
+	samples := bytesToInt16(data)
+	for _, proc := range c.procs {
+		if err := proc.ProcessUplink(samples); err != nil {
+			return nil, nil, err
+		}
+	}
+	encoded, err := c.encoder.Encode(samples, len(samples)/c.format.Channels())
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode: %w", err)
+	}
+	return &StampedOpusFrame{Timestamp: frame.Timestamp, Frame: encoded}, data, nil
+}
+
+// observePlayback feeds a downlink frame to processors that need it.
+func (c *uplinkChain) observePlayback(samples []int16) {
+	for _, proc := range c.procs {
+		if o, ok := proc.(PlaybackObserver); ok {
+			o.ObservePlayback(samples)
+		}
+	}
+}
+
+func (c *uplinkChain) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	c.decoder.Close()
+	c.encoder.Close()
+}
+
+// SetUplinkProcessing installs a processing chain for the device's uplink
+// audio. Every audio frame received by ReadFrom or HandleAudio is decoded,
+// passed through the processors and re-encoded before it is queued for
+// Poll, so all handlers get cleaned-up audio. The processed PCM is also
+// available in UplinkData.PCM.
+//
+// Call it once per port, e.g. with processors chosen from the device's
+// configuration; a nil cfg removes the chain. If a processor fails, the
+// frame is passed on unprocessed and the error is logged.
+func (p *ServerPort) SetUplinkProcessing(cfg *UplinkProcessing) error {
+	var chain *uplinkChain
+	if cfg != nil {
+		c, err := newUplinkChain(cfg)
+		if err != nil {
+			return err
+		}
+		chain = c
+	}
+
+	p.mu.Lock()
+	old := p.uplinkChain
+	p.uplinkChain = chain
+	p.mu.Unlock()
+
+	if old != nil {
+		old.close()
+	}
+	return nil
+}
+
+// uplinkAudio records an audio frame in the uplink quality meter and builds
+// its UplinkData, running the uplink processing chain if configured.
+func (p *ServerPort) uplinkAudio(frame *StampedOpusFrame) UplinkData {
+	p.uplinkQuality.Frame(frame.Timestamp, frame.Frame.Duration())
+
+	p.mu.RLock()
+	chain := p.uplinkChain
+	p.mu.RUnlock()
+	if chain == nil {
+		return UplinkData{Audio: frame}
+	}
+
+	processed, data, err := chain.process(frame)
+	if err != nil {
+		p.logger.ErrorPrintf("uplink processing: %v", err)
+		return UplinkData{Audio: frame}
+	}
+	return UplinkData{Audio: processed, PCM: data}
+}
+
+// observePlayback passes downlink audio to the uplink chain.
+func (p *ServerPort) observePlayback(data []byte) {
+	p.mu.RLock()
+	chain := p.uplinkChain
+	p.mu.RUnlock()
+	if chain != nil && len(data) >= 2 {
+		chain.observePlayback(bytesToInt16(data))
+	}
+}
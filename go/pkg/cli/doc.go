@@ -2,8 +2,9 @@
 //
 // This package includes:
 //   - Configuration management (contexts, profiles)
-//   - Output formatting (JSON, YAML, table)
+//   - Output formatting (JSON, YAML, table, TSV)
 //   - Request file loading (YAML/JSON)
+//   - Error codes and exit statuses (see ErrorCode)
 //   - Common flags and options
 //
 // Configuration is stored in ~/.giztoy/<app>/ directory, supporting
@@ -22,4 +23,12 @@
 //	    Format: cli.FormatJSON,
 //	    File:   outputPath,
 //	})
+//
+//	// List-style results render as a table; Columns and Sort back the
+//	// --columns and --sort flags
+//	cli.Output(voices, cli.OutputOptions{
+//	    Format:  cli.FormatTable,
+//	    Columns: []string{"voice_id", "name"},
+//	    Sort:    "name",
+//	})
 package cli
@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+)
+
+// ErrorCode is the machine-readable class of a CLI failure. Codes are
+// stable: scripts and supervisors may branch on them (or on the matching
+// exit status) instead of on error messages.
+type ErrorCode string
+
+const (
+	// CodeUnknown is a failure that fits no other class.
+	CodeUnknown ErrorCode = "unknown"
+	// CodeUsage is a bad command line: unknown flags, missing arguments.
+	CodeUsage ErrorCode = "usage"
+	// CodeValidation is invalid input, such as a document missing a
+	// required field.
+	CodeValidation ErrorCode = "validation"
+	// CodeAuth is a rejected credential or a denied permission.
+	CodeAuth ErrorCode = "auth"
+	// CodeQuota is an exhausted quota or a rate limit.
+	CodeQuota ErrorCode = "quota"
+	// CodeNetwork is a failure to reach a service: connection errors and
+	// timeouts.
+	CodeNetwork ErrorCode = "network"
+	// CodeBackend is a server-side failure of a service.
+	CodeBackend ErrorCode = "backend"
+	// CodeNotFound is a missing resource or file.
+	CodeNotFound ErrorCode = "not_found"
+)
+
+// exitCodes maps error codes to process exit statuses.
+var exitCodes = map[ErrorCode]int{
+	CodeUnknown:    1,
+	CodeUsage:      2,
+	CodeValidation: 3,
+	CodeAuth:       4,
+	CodeQuota:      5,
+	CodeNetwork:    6,
+	CodeBackend:    7,
+	CodeNotFound:   8,
+}
+
+// ExitCode returns the process exit status of the code:
+//
+//	unknown    1
+//	usage      2
+//	validation 3
+//	auth       4
+//	quota      5
+//	network    6
+//	backend    7
+//	not_found  8
+func (c ErrorCode) ExitCode() int {
+	if code, ok := exitCodes[c]; ok {
+		return code
+	}
+	return 1
+}
+
+// Error is an error with an ErrorCode.
+type Error struct {
+	Code ErrorCode
+	Err  error
+}
+
+// Error returns the message of the wrapped error.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithCode attaches code to err. It returns nil if err is nil.
+func WithCode(code ErrorCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Errorf formats an error with code.
+func Errorf(code ErrorCode, format string, args ...any) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// Classify returns the ErrorCode of err:
+//   - the code of an *Error in the chain, if any
+//   - the class reported by an SDK error (IsAuth, IsRateLimit,
+//     IsServerError, ...)
+//   - CodeNotFound for missing files
+//   - CodeNetwork for network errors and timeouts
+//   - CodeUnknown otherwise
+func Classify(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	// SDK errors tell their class by methods (see e.g. minimax.Error,
+	// doubaospeech.Error and dashscope.Error)
+	switch {
+	case is(err, func(e interface{ IsAuthError() bool }) bool { return e.IsAuthError() }),
+		is(err, func(e interface{ IsAuth() bool }) bool { return e.IsAuth() }),
+		is(err, func(e interface{ IsInvalidAPIKey() bool }) bool { return e.IsInvalidAPIKey() }):
+		return CodeAuth
+	case is(err, func(e interface{ IsQuotaExceeded() bool }) bool { return e.IsQuotaExceeded() }),
+		is(err, func(e interface{ IsInsufficientQuota() bool }) bool { return e.IsInsufficientQuota() }),
+		is(err, func(e interface{ IsRateLimit() bool }) bool { return e.IsRateLimit() }):
+		return CodeQuota
+	case is(err, func(e interface{ IsInvalidParam() bool }) bool { return e.IsInvalidParam() }),
+		is(err, func(e interface{ IsInvalidRequest() bool }) bool { return e.IsInvalidRequest() }):
+		return CodeValidation
+	case is(err, func(e interface{ IsServerError() bool }) bool { return e.IsServerError() }):
+		return CodeBackend
+	}
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return CodeNotFound
+	}
+	if isNetworkError(err) {
+		return CodeNetwork
+	}
+	return CodeUnknown
+}
+
+// is reports whether an error in the chain of err is a T for which check
+// returns true.
+func is[T any](err error, check func(T) bool) bool {
+	var t T
+	return errors.As(err, &t) && check(t)
+}
+
+// isNetworkError reports whether err is a failed dial, lookup or I/O on a
+// connection, or a timeout.
+func isNetworkError(err error) bool {
+	var (
+		opErr  *net.OpError
+		dnsErr *net.DNSError
+		netErr net.Error
+	)
+	switch {
+	case errors.As(err, &opErr), errors.As(err, &dnsErr):
+		return true
+	case errors.As(err, &netErr) && netErr.Timeout():
+		// syscall.Errno is a net.Error too; only its timeouts count
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// ExitCode returns the process exit status for err: 0 if err is nil, the
+// exit status of its ErrorCode otherwise.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return Classify(err).ExitCode()
+}
+
+// ErrorReport is the JSON form of a failure.
+type ErrorReport struct {
+	Code     ErrorCode `json:"code"`
+	ExitCode int       `json:"exit_code"`
+	Message  string    `json:"message"`
+}
+
+// NewErrorReport creates the ErrorReport of err.
+func NewErrorReport(err error) ErrorReport {
+	code := Classify(err)
+	return ErrorReport{Code: code, ExitCode: code.ExitCode(), Message: err.Error()}
+}
+
+// ReportError writes err to w and returns its exit status. With FormatJSON
+// it writes {"error": {"code": ..., "exit_code": ..., "message": ...}};
+// otherwise it writes "Error: <message>".
+func ReportError(w io.Writer, err error, format OutputFormat) int {
+	if err == nil {
+		return 0
+	}
+	report := NewErrorReport(err)
+	if format == FormatJSON {
+		outputJSON(w, map[string]ErrorReport{"error": report}, "")
+	} else {
+		fmt.Fprintf(w, "Error: %s\n", report.Message)
+	}
+	return report.ExitCode
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"syscall"
+	"testing"
+)
+
+// sdkError mimics the class methods of SDK errors.
+type sdkError struct {
+	auth, rateLimit, server bool
+}
+
+func (e *sdkError) Error() string       { return "sdk error" }
+func (e *sdkError) IsAuth() bool        { return e.auth }
+func (e *sdkError) IsRateLimit() bool   { return e.rateLimit }
+func (e *sdkError) IsServerError() bool { return e.server }
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCode
+	}{
+		{"nil", nil, ""},
+		{"plain", errors.New("boom"), CodeUnknown},
+		{"coded", Errorf(CodeUsage, "flag -f is required"), CodeUsage},
+		{"wrapped coded", fmt.Errorf("apply: %w", WithCode(CodeValidation, errors.New("bad"))), CodeValidation},
+		{"sdk auth", &sdkError{auth: true}, CodeAuth},
+		{"sdk rate limit", fmt.Errorf("call: %w", &sdkError{rateLimit: true}), CodeQuota},
+		{"sdk server", &sdkError{server: true}, CodeBackend},
+		{"sdk other", &sdkError{}, CodeUnknown},
+		{"network", &net.OpError{Op: "dial", Err: errors.New("refused")}, CodeNetwork},
+		{"deadline", fmt.Errorf("request: %w", context.DeadlineExceeded), CodeNetwork},
+		{"missing file", fmt.Errorf("open: %w", fs.ErrNotExist), CodeNotFound},
+		{"file error", &fs.PathError{Op: "open", Path: "x", Err: syscall.EACCES}, CodeUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if got := ExitCode(nil); got != 0 {
+		t.Errorf("ExitCode(nil) = %d, want 0", got)
+	}
+	if got := ExitCode(errors.New("boom")); got != 1 {
+		t.Errorf("ExitCode(unknown) = %d, want 1", got)
+	}
+
+	// Every code has its own exit status
+	seen := make(map[int]ErrorCode)
+	for code := range exitCodes {
+		exit := code.ExitCode()
+		if other, ok := seen[exit]; ok {
+			t.Errorf("%q and %q share exit status %d", code, other, exit)
+		}
+		seen[exit] = code
+	}
+	if got := ErrorCode("bogus").ExitCode(); got != 1 {
+		t.Errorf("ExitCode(bogus) = %d, want 1", got)
+	}
+}
+
+func TestWithCode_Nil(t *testing.T) {
+	if err := WithCode(CodeAuth, nil); err != nil {
+		t.Errorf("WithCode(nil) = %v, want nil", err)
+	}
+}
+
+func TestReportError(t *testing.T) {
+	err := WithCode(CodeAuth, errors.New("invalid api key"))
+
+	var buf bytes.Buffer
+	if exit := ReportError(&buf, err, FormatJSON); exit != 4 {
+		t.Errorf("exit = %d, want 4", exit)
+	}
+	var out struct {
+		Error ErrorReport `json:"error"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	want := ErrorReport{Code: CodeAuth, ExitCode: 4, Message: "invalid api key"}
+	if out.Error != want {
+		t.Errorf("report = %+v, want %+v", out.Error, want)
+	}
+
+	buf.Reset()
+	if exit := ReportError(&buf, err, FormatTable); exit != 4 {
+		t.Errorf("exit = %d, want 4", exit)
+	}
+	if buf.String() != "Error: invalid api key\n" {
+		t.Errorf("text output = %q", buf.String())
+	}
+}
@@ -17,15 +17,17 @@ const (
 	FormatYAML OutputFormat = "yaml"
 	// FormatJSON outputs as JSON
 	FormatJSON OutputFormat = "json"
-	// FormatTable outputs as formatted table
+	// FormatTable outputs as an aligned table
 	FormatTable OutputFormat = "table"
+	// FormatTSV outputs as tab-separated values
+	FormatTSV OutputFormat = "tsv"
 	// FormatRaw outputs raw data
 	FormatRaw OutputFormat = "raw"
 )
 
 // OutputOptions configures output behavior
 type OutputOptions struct {
-	// Format is the output format (yaml, json, table, raw)
+	// Format is the output format (yaml, json, table, tsv, raw)
 	Format OutputFormat
 
 	// File is the output file path (empty for stdout)
@@ -36,6 +38,14 @@ type OutputOptions struct {
 
 	// Writer is an optional custom writer (overrides File)
 	Writer io.Writer
+
+	// Columns selects and orders the columns of table and TSV output
+	// (e.g. from a --columns flag). Empty means all columns.
+	Columns []string
+
+	// Sort is the column to sort table and TSV rows by (e.g. from a
+	// --sort flag). A "-" prefix sorts descending.
+	Sort string
 }
 
 // Output writes the result to the configured destination
@@ -58,6 +68,8 @@ func Output(result any, opts OutputOptions) error {
 		return outputJSON(w, result, opts.Indent)
 	case FormatYAML, "":
 		return outputYAML(w, result)
+	case FormatTable, FormatTSV:
+		return outputTable(w, result, opts)
 	case FormatRaw:
 		return outputRaw(w, result)
 	default:
@@ -0,0 +1,310 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// table is a result flattened into rows of cells.
+type table struct {
+	columns []string
+	rows    [][]string
+}
+
+// buildTable flattens result into a table. The result is converted through
+// JSON, so columns are the JSON field names:
+//
+//   - A slice of structs or maps gives one row per element.
+//   - A struct or map with a single list field (e.g. {"voices": [...]})
+//     gives one row per list element.
+//   - Any other struct or map gives a single row.
+//
+// Columns appear in field order; nested values are rendered as compact JSON.
+func buildTable(result any) (*table, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format output: %w", err)
+	}
+	data = bytes.TrimSpace(data)
+
+	var items []json.RawMessage
+	switch {
+	case len(data) > 0 && data[0] == '[':
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("failed to format output: %w", err)
+		}
+	case len(data) > 0 && data[0] == '{':
+		items = []json.RawMessage{data}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("failed to format output: %w", err)
+		}
+		if len(fields) == 1 {
+			for _, v := range fields {
+				var list []json.RawMessage
+				if json.Unmarshal(v, &list) == nil && len(list) > 0 && isObject(list[0]) {
+					items = list
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("table output requires a list or an object, got %s", data)
+	}
+
+	t := &table{}
+	for _, item := range items {
+		if !isObject(item) {
+			// A list of scalars is a single-column table.
+			if !slices.Contains(t.columns, "value") {
+				t.columns = append(t.columns, "value")
+			}
+			t.rows = append(t.rows, []string{formatCell(item)})
+			continue
+		}
+		keys, fields, err := objectFields(item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to format output: %w", err)
+		}
+		for _, k := range keys {
+			if !slices.Contains(t.columns, k) {
+				t.columns = append(t.columns, k)
+			}
+		}
+		row := make([]string, len(t.columns))
+		for i, col := range t.columns {
+			if v, ok := fields[col]; ok {
+				row[i] = formatCell(v)
+			}
+		}
+		t.rows = append(t.rows, row)
+	}
+	// Pad rows created before later columns were discovered.
+	for i, row := range t.rows {
+		if len(row) < len(t.columns) {
+			t.rows[i] = append(row, make([]string, len(t.columns)-len(row))...)
+		}
+	}
+	return t, nil
+}
+
+func isObject(raw json.RawMessage) bool {
+	raw = bytes.TrimSpace(raw)
+	return len(raw) > 0 && raw[0] == '{'
+}
+
+// objectFields decodes a JSON object, returning its keys in document order.
+func objectFields(raw json.RawMessage) ([]string, map[string]json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil { // {
+		return nil, nil, err
+	}
+	var keys []string
+	fields := make(map[string]json.RawMessage)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key, _ := tok.(string)
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := fields[key]; !ok {
+			keys = append(keys, key)
+		}
+		fields[key] = v
+	}
+	return keys, fields, nil
+}
+
+// formatCell renders a JSON value as a table cell.
+func formatCell(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	if raw[0] == '"' {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			return s
+		}
+	}
+	return string(raw)
+}
+
+// selectColumns keeps the given columns, in the given order. Names match
+// case-insensitively.
+func (t *table) selectColumns(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	idx := make([]int, 0, len(names))
+	for _, name := range names {
+		i := t.columnIndex(name)
+		if i < 0 {
+			return fmt.Errorf("unknown column %q (available: %s)", name, strings.Join(t.columns, ", "))
+		}
+		idx = append(idx, i)
+	}
+
+	columns := make([]string, len(idx))
+	for j, i := range idx {
+		columns[j] = t.columns[i]
+	}
+	for r, row := range t.rows {
+		sel := make([]string, len(idx))
+		for j, i := range idx {
+			sel[j] = row[i]
+		}
+		t.rows[r] = sel
+	}
+	t.columns = columns
+	return nil
+}
+
+// sortBy sorts the rows by a column, descending if prefixed with "-".
+// Columns whose values are all numbers sort numerically.
+func (t *table) sortBy(spec string) error {
+	if spec == "" {
+		return nil
+	}
+	name, desc := strings.CutPrefix(spec, "-")
+	i := t.columnIndex(name)
+	if i < 0 {
+		return fmt.Errorf("unknown sort column %q (available: %s)", name, strings.Join(t.columns, ", "))
+	}
+
+	numeric := true
+	for _, row := range t.rows {
+		if _, err := strconv.ParseFloat(row[i], 64); err != nil {
+			numeric = false
+			break
+		}
+	}
+	slices.SortStableFunc(t.rows, func(a, b []string) int {
+		var c int
+		if numeric {
+			x, _ := strconv.ParseFloat(a[i], 64)
+			y, _ := strconv.ParseFloat(b[i], 64)
+			c = compareFloat(x, y)
+		} else {
+			c = strings.Compare(a[i], b[i])
+		}
+		if desc {
+			return -c
+		}
+		return c
+	})
+	return nil
+}
+
+func compareFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func (t *table) columnIndex(name string) int {
+	for i, col := range t.columns {
+		if strings.EqualFold(col, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeTable writes t as aligned columns with an upper-case header.
+func (t *table) writeTable(w io.Writer) error {
+	widths := make([]int, len(t.columns))
+	for i, col := range t.columns {
+		widths[i] = lipgloss.Width(col)
+	}
+	for _, row := range t.rows {
+		for i, cell := range row {
+			cell = tableCell(cell)
+			widths[i] = max(widths[i], lipgloss.Width(cell))
+		}
+	}
+
+	var sb strings.Builder
+	writeRow := func(cells []string) {
+		var line strings.Builder
+		for i, cell := range cells {
+			line.WriteString(cell)
+			if i < len(cells)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-lipgloss.Width(cell)+2))
+			}
+		}
+		sb.WriteString(strings.TrimRight(line.String(), " "))
+		sb.WriteByte('\n')
+	}
+
+	header := make([]string, len(t.columns))
+	for i, col := range t.columns {
+		header[i] = strings.ToUpper(col)
+	}
+	writeRow(header)
+	for _, row := range t.rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = tableCell(cell)
+		}
+		writeRow(cells)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// tableCell keeps a cell on one line.
+func tableCell(s string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\t", " ").Replace(s)
+}
+
+// writeTSV writes t as tab-separated values with a header line, for
+// scripting (cut, awk, spreadsheets).
+func (t *table) writeTSV(w io.Writer) error {
+	escape := strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+	var sb strings.Builder
+	sb.WriteString(strings.Join(t.columns, "\t"))
+	sb.WriteByte('\n')
+	for _, row := range t.rows {
+		for i, cell := range row {
+			if i > 0 {
+				sb.WriteByte('\t')
+			}
+			sb.WriteString(escape.Replace(cell))
+		}
+		sb.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func outputTable(w io.Writer, result any, opts OutputOptions) error {
+	t, err := buildTable(result)
+	if err != nil {
+		return err
+	}
+	if err := t.selectColumns(opts.Columns); err != nil {
+		return err
+	}
+	if err := t.sortBy(opts.Sort); err != nil {
+		return err
+	}
+	if opts.Format == FormatTSV {
+		return t.writeTSV(w)
+	}
+	return t.writeTable(w)
+}
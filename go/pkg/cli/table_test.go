@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type testVoice struct {
+	ID       string  `json:"voice_id"`
+	Name     string  `json:"name"`
+	Speed    float64 `json:"speed"`
+	Language string  `json:"language,omitempty"`
+}
+
+var testVoices = []testVoice{
+	{ID: "v2", Name: "Bella", Speed: 1.5, Language: "en"},
+	{ID: "v1", Name: "Alice", Speed: 10},
+	{ID: "v3", Name: "小明", Speed: 2, Language: "zh"},
+}
+
+func TestOutput_Table(t *testing.T) {
+	var buf bytes.Buffer
+	err := Output(testVoices, OutputOptions{Format: FormatTable, Writer: &buf})
+	if err != nil {
+		t.Fatalf("Output error: %v", err)
+	}
+
+	want := "" +
+		"VOICE_ID  NAME   SPEED  LANGUAGE\n" +
+		"v2        Bella  1.5    en\n" +
+		"v1        Alice  10\n" +
+		"v3        小明   2      zh\n"
+	if buf.String() != want {
+		t.Errorf("table output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestOutput_TableColumnsAndSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		sort    string
+		want    string
+	}{
+		{
+			name:    "select and reorder",
+			columns: []string{"name", "VOICE_ID"},
+			want:    "NAME   VOICE_ID\nBella  v2\nAlice  v1\n小明   v3\n",
+		},
+		{
+			name:    "sort by string",
+			columns: []string{"voice_id"},
+			sort:    "voice_id",
+			want:    "VOICE_ID\nv1\nv2\nv3\n",
+		},
+		{
+			name:    "sort numeric descending",
+			columns: []string{"voice_id", "speed"},
+			sort:    "-speed",
+			want:    "VOICE_ID  SPEED\nv1        10\nv3        2\nv2        1.5\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Output(testVoices, OutputOptions{
+				Format:  FormatTable,
+				Writer:  &buf,
+				Columns: tt.columns,
+				Sort:    tt.sort,
+			})
+			if err != nil {
+				t.Fatalf("Output error: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestOutput_TableUnknownColumn(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Output(testVoices, OutputOptions{Format: FormatTable, Writer: &buf, Columns: []string{"gender"}}); err == nil {
+		t.Error("Output should fail for unknown column")
+	}
+	if err := Output(testVoices, OutputOptions{Format: FormatTable, Writer: &buf, Sort: "-gender"}); err == nil {
+		t.Error("Output should fail for unknown sort column")
+	}
+}
+
+func TestOutput_TableWrappedList(t *testing.T) {
+	var buf bytes.Buffer
+	result := map[string]any{"voices": testVoices[:1]}
+	if err := Output(result, OutputOptions{Format: FormatTable, Writer: &buf}); err != nil {
+		t.Fatalf("Output error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "VOICE_ID") || !strings.Contains(buf.String(), "Bella") {
+		t.Errorf("wrapped list should render its elements, got:\n%s", buf.String())
+	}
+}
+
+func TestOutput_TableSingleObject(t *testing.T) {
+	var buf bytes.Buffer
+	result := map[string]any{"name": "dev", "tags": []string{"a", "b"}}
+	if err := Output(result, OutputOptions{Format: FormatTable, Writer: &buf}); err != nil {
+		t.Fatalf("Output error: %v", err)
+	}
+	want := "NAME  TAGS\ndev   [\"a\",\"b\"]\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestOutput_TSV(t *testing.T) {
+	var buf bytes.Buffer
+	data := []map[string]string{
+		{"id": "1", "text": "hello\tworld"},
+		{"id": "2", "text": "line1\nline2"},
+	}
+	if err := Output(data, OutputOptions{Format: FormatTSV, Writer: &buf}); err != nil {
+		t.Fatalf("Output error: %v", err)
+	}
+	want := "id\ttext\n1\thello\\tworld\n2\tline1\\nline2\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
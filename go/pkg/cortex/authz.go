@@ -0,0 +1,210 @@
+package cortex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/haivivi/giztoy/go/pkg/kv"
+)
+
+// ErrForbidden is returned (wrapped) when the Authorizer denies an operation.
+var ErrForbidden = errors.New("forbidden")
+
+// Action is an operation checked by the Authorizer.
+type Action string
+
+const (
+	ActionApply  Action = "apply"
+	ActionRun    Action = "run"
+	ActionGet    Action = "get"
+	ActionList   Action = "list"
+	ActionDelete Action = "delete"
+)
+
+// Subject identifies the caller of a Cortex operation. A multi-user server
+// attaches it to the request context with WithSubject after authenticating
+// the request.
+type Subject struct {
+	Name   string   `yaml:"name" json:"name"`
+	Groups []string `yaml:"groups,omitempty" json:"groups,omitempty"`
+}
+
+type subjectKey struct{}
+
+// WithSubject returns a context carrying the subject.
+func WithSubject(ctx context.Context, s Subject) context.Context {
+	return context.WithValue(ctx, subjectKey{}, s)
+}
+
+// SubjectFrom returns the subject of ctx. ok is false when none was set.
+func SubjectFrom(ctx context.Context) (s Subject, ok bool) {
+	s, ok = ctx.Value(subjectKey{}).(Subject)
+	return s, ok
+}
+
+// Access describes an operation to authorize.
+type Access struct {
+	Subject Subject
+	Action  Action
+	Kind    string // e.g. "creds/openai", "genx/generator"; for List also a category, e.g. "genx"
+	Name    string // document name; empty for List
+}
+
+// Authorizer decides whether an operation is allowed. It returns nil to
+// allow, or an error (typically wrapping ErrForbidden) to deny.
+type Authorizer interface {
+	Authorize(ctx context.Context, access Access) error
+}
+
+// AuthorizerFunc adapts a function to the Authorizer interface.
+type AuthorizerFunc func(ctx context.Context, access Access) error
+
+// Authorize calls f.
+func (f AuthorizerFunc) Authorize(ctx context.Context, access Access) error {
+	return f(ctx, access)
+}
+
+// AccessRule allows matching subjects to perform matching actions on
+// matching documents. Empty lists match everything.
+type AccessRule struct {
+	// Subjects lists subject names and "group:<name>" entries; "*" matches
+	// any subject, including an anonymous one.
+	Subjects []string `yaml:"subjects,omitempty" json:"subjects,omitempty"`
+
+	Actions []Action `yaml:"actions,omitempty" json:"actions,omitempty"`
+
+	// Kinds and Names are path.Match patterns, e.g. "genx/*", "creds/*",
+	// "qwen/*".
+	Kinds []string `yaml:"kinds,omitempty" json:"kinds,omitempty"`
+	Names []string `yaml:"names,omitempty" json:"names,omitempty"`
+}
+
+// AccessRules is an allow-list Authorizer: an operation is allowed if any
+// rule matches it and denied otherwise.
+//
+// Example — team A manages agents, only ops touches creds:
+//
+//	cortex.AccessRules{
+//	    {Subjects: []string{"group:team-a"}, Kinds: []string{"genx/*"}},
+//	    {Subjects: []string{"group:ops"}},
+//	    {Subjects: []string{"*"}, Actions: []cortex.Action{cortex.ActionRun}},
+//	}
+type AccessRules []AccessRule
+
+// Authorize implements Authorizer.
+func (rs AccessRules) Authorize(_ context.Context, a Access) error {
+	for _, r := range rs {
+		if r.matches(a) {
+			return nil
+		}
+	}
+	who := a.Subject.Name
+	if who == "" {
+		who = "anonymous"
+	}
+	target := a.Kind
+	if a.Name != "" {
+		target += " " + a.Name
+	}
+	return fmt.Errorf("%s may not %s %s: %w", who, a.Action, target, ErrForbidden)
+}
+
+func (r *AccessRule) matches(a Access) bool {
+	if len(r.Actions) > 0 && !slices.Contains(r.Actions, a.Action) {
+		return false
+	}
+	if len(r.Subjects) > 0 && !slices.ContainsFunc(r.Subjects, func(s string) bool {
+		return matchSubject(s, a.Subject)
+	}) {
+		return false
+	}
+	if a.Action == ActionList {
+		// Listing a category (e.g. "genx:*") is allowed if the rule could
+		// match a kind inside it; the listed documents are filtered one
+		// by one.
+		if len(r.Kinds) > 0 && !slices.ContainsFunc(r.Kinds, func(p string) bool { return matchKindPrefix(p, a.Kind) }) {
+			return false
+		}
+	} else if !matchAny(r.Kinds, a.Kind) {
+		return false
+	}
+	// List has no name; a name-restricted rule still lets the caller list,
+	// and the listed documents are filtered one by one.
+	if a.Name != "" && !matchAny(r.Names, a.Name) {
+		return false
+	}
+	return true
+}
+
+func matchSubject(pattern string, s Subject) bool {
+	if pattern == "*" {
+		return true
+	}
+	if group, ok := strings.CutPrefix(pattern, "group:"); ok {
+		return slices.Contains(s.Groups, group)
+	}
+	return s.Name != "" && pattern == s.Name
+}
+
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchKindPrefix reports whether the kind pattern matches kind, or a kind
+// inside the category kind: "genx/*" matches "genx" and "genx/generator",
+// and every pattern matches "" (all kinds).
+func matchKindPrefix(pattern, kind string) bool {
+	if kind == "" {
+		return true
+	}
+	n := len(strings.Split(kind, "/"))
+	segs := strings.Split(pattern, "/")
+	if len(segs) > n {
+		segs = segs[:n]
+	}
+	ok, _ := path.Match(strings.Join(segs, "/"), kind)
+	return ok
+}
+
+// WithAuthorizer checks every Apply, Run, Get, List and Delete against a.
+// The subject is taken from the operation's context (see WithSubject).
+// Without an Authorizer all operations are allowed.
+func WithAuthorizer(a Authorizer) Option {
+	return func(o *options) { o.authz = a }
+}
+
+// authorize checks an operation against the configured Authorizer.
+func (c *Cortex) authorize(ctx context.Context, action Action, kind, name string) error {
+	if c.authz == nil {
+		return nil
+	}
+	subject, _ := SubjectFrom(ctx)
+	return c.authz.Authorize(ctx, Access{
+		Subject: subject,
+		Action:  action,
+		Kind:    kind,
+		Name:    name,
+	})
+}
+
+// authorizeKey checks an operation on the document stored at key.
+func (c *Cortex) authorizeKey(ctx context.Context, action Action, key kv.Key) error {
+	if c.authz == nil {
+		return nil
+	}
+	kind := inferKind(key)
+	n := min(len(strings.Split(kind, "/")), len(key))
+	return c.authorize(ctx, action, kind, strings.Join(key[n:], ":"))
+}
@@ -0,0 +1,155 @@
+package cortex
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/haivivi/giztoy/go/pkg/kv"
+)
+
+func newAuthzCortex(t *testing.T, authz Authorizer) *Cortex {
+	t.Helper()
+	store := newTestStore(t)
+	store.CtxAdd("test")
+	store.CtxUse("test")
+	c, err := New(context.Background(), store, WithKV(kv.NewMemory(nil)), WithAuthorizer(authz))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+var testRules = AccessRules{
+	{Subjects: []string{"group:team-a"}, Kinds: []string{"genx/*"}},
+	{Subjects: []string{"group:ops"}},
+}
+
+var (
+	teamA = Subject{Name: "alice", Groups: []string{"team-a"}}
+	ops   = Subject{Name: "olga", Groups: []string{"ops"}}
+)
+
+func credDoc() Document {
+	return Document{
+		Kind:   "creds/openai",
+		Fields: map[string]any{"name": "qwen", "api_key": "sk-test"},
+	}
+}
+
+func TestAuthzApplyPerKind(t *testing.T) {
+	c := newAuthzCortex(t, testRules)
+	ctxA := WithSubject(context.Background(), teamA)
+	ctxOps := WithSubject(context.Background(), ops)
+
+	_, err := c.Apply(ctxA, []Document{credDoc()})
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("team-a apply creds: err = %v, want ErrForbidden", err)
+	}
+	if _, err := c.Apply(ctxOps, []Document{credDoc()}); err != nil {
+		t.Fatalf("ops apply creds: %v", err)
+	}
+	if _, err := c.Apply(context.Background(), []Document{credDoc()}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("anonymous apply: err = %v, want ErrForbidden", err)
+	}
+}
+
+func TestAuthzGetDeleteList(t *testing.T) {
+	c := newAuthzCortex(t, testRules)
+	ctxA := WithSubject(context.Background(), teamA)
+	ctxOps := WithSubject(context.Background(), ops)
+
+	if _, err := c.Apply(ctxOps, []Document{credDoc()}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Get(ctxA, "creds:openai:qwen"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("team-a get creds: err = %v, want ErrForbidden", err)
+	}
+	if err := c.Delete(ctxA, "creds:openai:qwen"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("team-a delete creds: err = %v, want ErrForbidden", err)
+	}
+	if _, err := c.List(ctxA, "creds:*", ListOpts{}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("team-a list creds: err = %v, want ErrForbidden", err)
+	}
+	if _, err := c.Get(ctxOps, "creds:openai:qwen"); err != nil {
+		t.Errorf("ops get creds: %v", err)
+	}
+}
+
+func TestAuthzListCategory(t *testing.T) {
+	c := newAuthzCortex(t, testRules)
+	ctxA := WithSubject(context.Background(), teamA)
+	ctxOps := WithSubject(context.Background(), ops)
+
+	gen := Document{Kind: "genx/generator", Fields: map[string]any{"name": "qwen/turbo", "cred": "openai:qwen", "model": "m"}}
+	if _, err := c.Apply(ctxOps, []Document{credDoc(), gen}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pattern := range []string{"genx:*", "genx:generator:*"} {
+		docs, err := c.List(ctxA, pattern, ListOpts{})
+		if err != nil {
+			t.Errorf("team-a list %s: %v", pattern, err)
+			continue
+		}
+		if len(docs) != 1 || docs[0].Kind != "genx/generator" {
+			t.Errorf("team-a list %s = %+v, want the generator", pattern, docs)
+		}
+	}
+	// Listing everything is filtered to the documents team-a may get
+	docs, err := c.List(ctxA, "*", ListOpts{All: true})
+	if err != nil {
+		t.Fatalf("team-a list *: %v", err)
+	}
+	if len(docs) != 1 || docs[0].Kind != "genx/generator" {
+		t.Errorf("team-a list * = %+v, want only the generator", docs)
+	}
+}
+
+func TestAuthzRunAndNames(t *testing.T) {
+	var got Access
+	c := newAuthzCortex(t, AuthorizerFunc(func(_ context.Context, a Access) error {
+		got = a
+		return AccessRules{{Actions: []Action{ActionRun}, Names: []string{"qwen/*"}}}.Authorize(context.Background(), a)
+	}))
+	RegisterRunHandler("test/authz", func(context.Context, *Cortex, Document) (*RunResult, error) {
+		return &RunResult{Status: "ok"}, nil
+	})
+	t.Cleanup(func() { delete(runHandlers, "test/authz") })
+
+	ctx := WithSubject(context.Background(), teamA)
+	task := Document{Kind: "test/authz", Fields: map[string]any{"name": "qwen/turbo"}}
+	if _, err := c.Run(ctx, task); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got.Subject.Name != "alice" || got.Action != ActionRun || got.Kind != "test/authz" || got.Name != "qwen/turbo" {
+		t.Errorf("access = %+v", got)
+	}
+
+	task.Fields["name"] = "gpt/4o"
+	if _, err := c.Run(ctx, task); !errors.Is(err, ErrForbidden) {
+		t.Errorf("run other name: err = %v, want ErrForbidden", err)
+	}
+}
+
+func TestAccessRuleSubjects(t *testing.T) {
+	tests := []struct {
+		pattern string
+		subject Subject
+		want    bool
+	}{
+		{"*", Subject{}, true},
+		{"alice", teamA, true},
+		{"bob", teamA, false},
+		{"group:team-a", teamA, true},
+		{"group:ops", teamA, false},
+		{"", Subject{}, false},
+	}
+	for _, tt := range tests {
+		if got := matchSubject(tt.pattern, tt.subject); got != tt.want {
+			t.Errorf("matchSubject(%q, %+v) = %v, want %v", tt.pattern, tt.subject, got, tt.want)
+		}
+	}
+}
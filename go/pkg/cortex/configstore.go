@@ -54,6 +54,7 @@ type CtxConfig struct {
 	Storage  string `yaml:"storage,omitempty" json:"storage,omitempty"`
 	VecStore string `yaml:"vecstore,omitempty" json:"vecstore,omitempty"`
 	Embed    string `yaml:"embed,omitempty" json:"embed,omitempty"`
+	Server   string `yaml:"server,omitempty" json:"server,omitempty"`
 }
 
 // CtxInfo describes a context in list output.
@@ -67,6 +68,7 @@ var validCtxConfigKeys = map[string]string{
 	"storage":  "File storage (local/s3/oss)",
 	"vecstore": "Vector index (hnsw/milvus/qdrant)",
 	"embed":    "Embedding service (dashscope/openai)",
+	"server":   "Task API address of the serve daemon (host:port)",
 }
 
 // ConfigKeyInfo describes a supported config key.
@@ -219,6 +221,8 @@ func (s *ConfigStore) CtxConfigSet(key, value string) error {
 		cfg.VecStore = value
 	case "embed":
 		cfg.Embed = value
+	case "server":
+		cfg.Server = value
 	}
 	data, err := yaml.Marshal(cfg)
 	if err != nil {
@@ -216,7 +216,7 @@ func TestCtxConfigSetUnknownKey(t *testing.T) {
 func TestCtxConfigList(t *testing.T) {
 	s := newTestStore(t)
 	keys := s.CtxConfigList()
-	if len(keys) != 4 {
-		t.Fatalf("expected 4 config keys, got %d", len(keys))
+	if len(keys) != 5 {
+		t.Fatalf("expected 5 config keys, got %d", len(keys))
 	}
 }
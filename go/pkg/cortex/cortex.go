@@ -3,8 +3,11 @@ package cortex
 import (
 	"context"
 	"fmt"
+	neturl "net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/goccy/go-yaml"
 
@@ -15,20 +18,25 @@ import (
 // Cortex is the unified runtime for giztoy. It opens KV from ctx config
 // and provides Apply/Get/List/Delete for all resources with schema validation.
 type Cortex struct {
-	config   *ConfigStore
-	kv       kv.Store
-	schemas  *SchemaRegistry
-	ownsKV   bool // true if Cortex opened the KV (should close it)
+	config  *ConfigStore
+	kv      kv.Store
+	schemas *SchemaRegistry
+	ownsKV  bool // true if Cortex opened the KV (should close it)
+	authz   Authorizer
 
 	memMu   sync.Mutex
 	memHost *memory.Host
+
+	tasksMu sync.Mutex
+	tasks   map[string]*Task
 }
 
 // Option configures Cortex creation.
 type Option func(*options)
 
 type options struct {
-	kv kv.Store
+	kv    kv.Store
+	authz Authorizer
 }
 
 // WithKV injects a KV store (for testing with kv.Memory).
@@ -60,6 +68,7 @@ func New(ctx context.Context, cfg *ConfigStore, opts ...Option) (*Cortex, error)
 		kv:      kvStore,
 		schemas: NewSchemaRegistry(),
 		ownsKV:  ownsKV,
+		authz:   o.authz,
 	}, nil
 }
 
@@ -69,9 +78,10 @@ func (c *Cortex) Config() *ConfigStore { return c.config }
 // KV returns the underlying KV store.
 func (c *Cortex) KV() kv.Store { return c.kv }
 
-// Close releases all resources. If the KV was injected via WithKV,
-// it is NOT closed (the caller owns it).
+// Close cancels the running tasks and releases all resources. If the KV
+// was injected via WithKV, it is NOT closed (the caller owns it).
 func (c *Cortex) Close() error {
+	c.cancelTasks()
 	if c.ownsKV && c.kv != nil {
 		return c.kv.Close()
 	}
@@ -95,23 +105,44 @@ func (c *Cortex) Apply(ctx context.Context, docs []Document) ([]ApplyResult, err
 func (c *Cortex) applyOne(ctx context.Context, doc Document) (ApplyResult, error) {
 	schema := c.schemas.Get(doc.Kind)
 	if schema == nil {
-		return ApplyResult{}, fmt.Errorf("unknown kind %q", doc.Kind)
+		return ApplyResult{}, invalidf("unknown kind %q", doc.Kind)
 	}
 
-	if err := schema.Validate(doc.Fields); err != nil {
+	if err := c.authorize(ctx, ActionApply, doc.Kind, doc.Name()); err != nil {
 		return ApplyResult{}, err
 	}
 
+	if err := schema.Validate(doc.Fields); err != nil {
+		return ApplyResult{}, invalid(err)
+	}
+
 	key := schema.Key(doc.Fields)
 
 	// Check if already exists (for status reporting).
-	_, existErr := c.kv.Get(ctx, key)
+	existing, existErr := c.kv.Get(ctx, key)
 	status := "created"
 	if existErr == nil {
 		status = "updated"
 	}
 
-	data, err := yaml.Marshal(doc.Fields)
+	fields := doc.Fields
+	if schema.Versioned {
+		var prev int64
+		if existErr == nil {
+			var old map[string]any
+			if err := yaml.Unmarshal(existing, &old); err != nil {
+				return ApplyResult{}, fmt.Errorf("parse existing: %w", err)
+			}
+			prev = versionOf(old)
+		}
+		fields = make(map[string]any, len(doc.Fields)+1)
+		for k, v := range doc.Fields {
+			fields[k] = v
+		}
+		fields["version"] = prev + 1
+	}
+
+	data, err := yaml.Marshal(fields)
 	if err != nil {
 		return ApplyResult{}, fmt.Errorf("marshal: %w", err)
 	}
@@ -131,11 +162,14 @@ func (c *Cortex) applyOne(ctx context.Context, doc Document) (ApplyResult, error
 // Get retrieves a single document by its full KV name (e.g. "creds:openai:qwen").
 func (c *Cortex) Get(ctx context.Context, fullName string) (*Document, error) {
 	key := parseFullName(fullName)
+	if err := c.authorizeKey(ctx, ActionGet, key); err != nil {
+		return nil, err
+	}
 
 	data, err := c.kv.Get(ctx, key)
 	if err != nil {
 		if err == kv.ErrNotFound {
-			return nil, fmt.Errorf("not found: %s", fullName)
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, fullName)
 		}
 		return nil, fmt.Errorf("get %s: %w", fullName, err)
 	}
@@ -153,12 +187,15 @@ func (c *Cortex) Get(ctx context.Context, fullName string) (*Document, error) {
 // The pattern must end with "*".
 func (c *Cortex) List(ctx context.Context, pattern string, opts ListOpts) ([]Document, error) {
 	if !strings.HasSuffix(pattern, "*") {
-		return nil, fmt.Errorf("list pattern must end with '*', got %q", pattern)
+		return nil, invalidf("list pattern must end with '*', got %q", pattern)
 	}
 	prefix := strings.TrimSuffix(pattern, "*")
 	prefix = strings.TrimSuffix(prefix, ":") // "creds:*" → prefix="creds"
 
 	key := parseFullName(prefix)
+	if err := c.authorizeKey(ctx, ActionList, key); err != nil {
+		return nil, err
+	}
 
 	limit := opts.Limit
 	if limit <= 0 {
@@ -186,6 +223,10 @@ func (c *Cortex) List(ctx context.Context, pattern string, opts ListOpts) ([]Doc
 			continue
 		}
 
+		if c.authorizeKey(ctx, ActionGet, entry.Key) != nil {
+			continue
+		}
+
 		var fields map[string]any
 		if err := yaml.Unmarshal(entry.Value, &fields); err != nil {
 			continue
@@ -206,11 +247,14 @@ func (c *Cortex) List(ctx context.Context, pattern string, opts ListOpts) ([]Doc
 // Delete removes a single document by its full KV name.
 func (c *Cortex) Delete(ctx context.Context, fullName string) error {
 	key := parseFullName(fullName)
+	if err := c.authorizeKey(ctx, ActionDelete, key); err != nil {
+		return err
+	}
 
 	_, err := c.kv.Get(ctx, key)
 	if err != nil {
 		if err == kv.ErrNotFound {
-			return fmt.Errorf("not found: %s", fullName)
+			return fmt.Errorf("%w: %s", ErrNotFound, fullName)
 		}
 		return fmt.Errorf("delete %s: %w", fullName, err)
 	}
@@ -234,9 +278,25 @@ func parseFullName(name string) kv.Key {
 	return kv.Key(parts)
 }
 
+// versionOf returns the "version" field of a stored document, or 0.
+func versionOf(fields map[string]any) int64 {
+	switch v := fields["version"].(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case uint64:
+		return int64(v)
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
 // inferKind reconstructs the kind from a KV key.
 // "creds:openai:qwen" → "creds/openai"
 // "genx:generator:qwen/turbo" → "genx/generator"
+// "chatgear:config:gear-001" → "chatgear/config"
 func inferKind(key kv.Key) string {
 	if len(key) < 2 {
 		return strings.Join(key, "/")
@@ -246,6 +306,8 @@ func inferKind(key kv.Key) string {
 		return "creds/" + key[1]
 	case "genx":
 		return "genx/" + key[1]
+	case "chatgear":
+		return "chatgear/" + key[1]
 	default:
 		return key[0]
 	}
@@ -264,15 +326,27 @@ func openKVFromConfig(cfg *ConfigStore) (kv.Store, error) {
 }
 
 // openKVByURL opens a KV store from a URL like "badger:///path" or "memory://".
+//
+// A badger URL may cache hot keys in memory (kv.Tiered) with the query
+// parameters cache (max cached keys) and cache_ttl (e.g. "5m"), as in
+// "badger:///path?cache=10000&cache_ttl=5m".
 func openKVByURL(url string) (kv.Store, error) {
 	switch {
 	case strings.HasPrefix(url, "badger://"):
-		path := strings.TrimPrefix(url, "badger://")
-		return kv.NewBadger(kv.BadgerOptions{
+		path, query, _ := strings.Cut(strings.TrimPrefix(url, "badger://"), "?")
+		cache, err := parseKVCache(query)
+		if err != nil {
+			return nil, fmt.Errorf("open KV %s: %w", url, err)
+		}
+		store, err := kv.NewBadger(kv.BadgerOptions{
 			Dir:     path,
 			Options: &kv.Options{},
 			Logger:  silentBadgerLogger{},
 		})
+		if err != nil || cache == nil {
+			return store, err
+		}
+		return kv.NewTiered(kv.NewMemory(&kv.Options{}), store, cache), nil
 	case url == "memory://":
 		return kv.NewMemory(nil), nil
 	default:
@@ -280,6 +354,31 @@ func openKVByURL(url string) (kv.Store, error) {
 	}
 }
 
+// parseKVCache parses the cache parameters of a KV URL query, returning
+// nil if there are none.
+func parseKVCache(query string) (*kv.TieredOptions, error) {
+	values, err := neturl.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	size, ttl := values.Get("cache"), values.Get("cache_ttl")
+	if size == "" && ttl == "" {
+		return nil, nil
+	}
+	var opts kv.TieredOptions
+	if size != "" {
+		if opts.MaxEntries, err = strconv.Atoi(size); err != nil || opts.MaxEntries < 0 {
+			return nil, fmt.Errorf("invalid cache %q", size)
+		}
+	}
+	if ttl != "" {
+		if opts.TTL, err = time.ParseDuration(ttl); err != nil || opts.TTL < 0 {
+			return nil, fmt.Errorf("invalid cache_ttl %q", ttl)
+		}
+	}
+	return &opts, nil
+}
+
 type silentBadgerLogger struct{}
 
 func (silentBadgerLogger) Errorf(string, ...any)   {}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/haivivi/giztoy/go/pkg/genx/labelers"
@@ -158,6 +161,59 @@ func TestApplyUpdate(t *testing.T) {
 	}
 }
 
+func TestApplyChatgearConfigVersion(t *testing.T) {
+	c := newTestCortex(t)
+	ctx := context.Background()
+
+	for i, persona := range []string{"lele", "xiaoxi"} {
+		_, err := c.Apply(ctx, []Document{{
+			Kind: "chatgear/config",
+			Fields: map[string]any{
+				"name":       "gear-001",
+				"persona":    persona,
+				"volume_max": 80,
+				"version":    100, // ignored
+			},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := c.Get(ctx, "chatgear:config:gear-001")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Kind != "chatgear/config" {
+			t.Fatalf("expected kind=chatgear/config, got %q", doc.Kind)
+		}
+		if got := versionOf(doc.Fields); got != int64(i+1) {
+			t.Fatalf("apply %d: expected version=%d, got %d", i, i+1, got)
+		}
+		if doc.GetString("persona") != persona {
+			t.Fatalf("expected persona=%s, got %q", persona, doc.GetString("persona"))
+		}
+	}
+}
+
+func TestApplyChatgearConfigBadVolume(t *testing.T) {
+	c := newTestCortex(t)
+	_, err := c.Apply(context.Background(), []Document{{
+		Kind: "chatgear/config", Fields: map[string]any{"name": "gear-001", "volume_max": 150},
+	}})
+	if err == nil {
+		t.Fatal("expected error for volume_max > 100")
+	}
+}
+
+func TestApplyChatgearConfigBadPrebuffer(t *testing.T) {
+	c := newTestCortex(t)
+	_, err := c.Apply(context.Background(), []Document{{
+		Kind: "chatgear/config", Fields: map[string]any{"name": "gear-001", "downlink_prebuffer_ms": -20},
+	}})
+	if err == nil {
+		t.Fatal("expected error for negative downlink_prebuffer_ms")
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Validation error tests
 // ---------------------------------------------------------------------------
@@ -170,6 +226,9 @@ func TestApplyUnknownKind(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error for unknown kind")
 	}
+	if !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid, got %v", err)
+	}
 }
 
 func TestApplyMissingRequiredField(t *testing.T) {
@@ -181,6 +240,12 @@ func TestApplyMissingRequiredField(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error for missing api_key")
 	}
+	if !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `missing required field "api_key"`) {
+		t.Fatalf("unexpected message: %v", err)
+	}
 }
 
 func TestApplyEmptyRequiredField(t *testing.T) {
@@ -297,6 +362,12 @@ func TestGetNotFound(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected not found error")
 	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err.Error() != "not found: creds:openai:nonexistent" {
+		t.Fatalf("unexpected message: %v", err)
+	}
 }
 
 func TestGetGenx(t *testing.T) {
@@ -544,11 +615,11 @@ func TestParseDocumentsInvalidYAML(t *testing.T) {
 // Schema tests
 // ---------------------------------------------------------------------------
 
-func TestSchemaRegistryHas12Kinds(t *testing.T) {
+func TestSchemaRegistryHas16Kinds(t *testing.T) {
 	r := NewSchemaRegistry()
 	kinds := r.Kinds()
-	if len(kinds) != 12 {
-		t.Fatalf("expected 12 kinds, got %d: %v", len(kinds), kinds)
+	if len(kinds) != 16 {
+		t.Fatalf("expected 16 kinds, got %d: %v", len(kinds), kinds)
 	}
 }
 
@@ -747,6 +818,40 @@ func TestCortexRecallWithoutLabeler(t *testing.T) {
 	}
 }
 
+func TestCortexRecallPeriod(t *testing.T) {
+	c := newTestCortex(t)
+	ctx := context.Background()
+	_, _ = c.Run(ctx, Document{Kind: "memory/create", Fields: map[string]any{"name": "p6"}})
+	if _, err := c.Run(ctx, Document{Kind: "memory/add", Fields: map[string]any{"persona": "p6", "text": "聊恐龙"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.Run(ctx, Document{Kind: "memory/recall", Fields: map[string]any{"persona": "p6", "period": "today"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Data["segments_count"] != 1 {
+		t.Errorf("segments today = %v, want 1", res.Data["segments_count"])
+	}
+	res, err = c.Run(ctx, Document{Kind: "memory/recall", Fields: map[string]any{"persona": "p6", "until": "2020-01-01T00:00:00Z"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Data["segments_count"] != 0 {
+		t.Errorf("segments until 2020 = %v, want 0", res.Data["segments_count"])
+	}
+
+	for _, fields := range []map[string]any{
+		{"persona": "p6", "period": "someday"},
+		{"persona": "p6", "since": "yesterday"},
+		{"persona": "p6"},
+	} {
+		if _, err := c.Run(ctx, Document{Kind: "memory/recall", Fields: fields}); !errors.Is(err, ErrInvalid) {
+			t.Errorf("recall %v: error = %v, want ErrInvalid", fields, err)
+		}
+	}
+}
+
 func TestCortexLabelerErrorHandling(t *testing.T) {
 	labelers.DefaultMux = labelers.NewMux()
 	expected := errors.New("mock labeler failure")
@@ -763,3 +868,87 @@ func TestCortexLabelerErrorHandling(t *testing.T) {
 		t.Fatalf("error = %v, want %v", err, expected)
 	}
 }
+
+func TestCortexKB(t *testing.T) {
+	c := newTestCortex(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	manual := filepath.Join(dir, "giztoy-manual.md")
+	content := "# Giztoy Manual\n\n## Charging\n\nA full charge takes two hours.\n\n## Wi-Fi\n\nHold the power button for five seconds.\n"
+	if err := os.WriteFile(manual, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.Run(ctx, Document{Kind: "kb/ingest", Fields: map[string]any{"collection": "manuals", "files": []any{manual}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunks := res.Data["chunks"]; chunks != 2 {
+		t.Fatalf("chunks = %v, want 2", chunks)
+	}
+
+	res, err = c.Run(ctx, Document{Kind: "kb/search", Fields: map[string]any{"collection": "manuals", "text": "how long to charge", "limit": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, ok := res.Data["results"].([]map[string]any)
+	if !ok || len(items) != 1 {
+		t.Fatalf("results = %v, want 1 result", res.Data["results"])
+	}
+	if items[0]["doc_id"] != "giztoy-manual" || items[0]["title"] != "Giztoy Manual" {
+		t.Fatalf("result = %v", items[0])
+	}
+	if !strings.Contains(items[0]["text"].(string), "two hours") {
+		t.Fatalf("text = %q", items[0]["text"])
+	}
+
+	res, err = c.Run(ctx, Document{Kind: "kb/list", Fields: map[string]any{"collection": "manuals"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Data["count"] != 1 {
+		t.Fatalf("count = %v, want 1", res.Data["count"])
+	}
+
+	if _, err := c.Run(ctx, Document{Kind: "kb/delete", Fields: map[string]any{"collection": "manuals", "id": "giztoy-manual"}}); err != nil {
+		t.Fatal(err)
+	}
+	res, err = c.Run(ctx, Document{Kind: "kb/search", Fields: map[string]any{"collection": "manuals", "text": "charge"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Data["count"] != 0 {
+		t.Fatalf("count after delete = %v, want 0", res.Data["count"])
+	}
+
+	if _, err := c.Run(ctx, Document{Kind: "kb/ingest", Fields: map[string]any{"collection": "manuals"}}); err == nil {
+		t.Fatal("expected error for ingest without documents")
+	}
+}
+
+func TestOpenKVByURLCache(t *testing.T) {
+	dir := t.TempDir()
+	store, err := openKVByURL("badger://" + dir + "?cache=100&cache_ttl=5m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if _, ok := store.(*kv.Tiered); !ok {
+		t.Fatalf("store = %T, want *kv.Tiered", store)
+	}
+
+	ctx := context.Background()
+	if err := store.Set(ctx, kv.Key{"a"}, []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Get(ctx, kv.Key{"a"}); err != nil || string(got) != "1" {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+
+	for _, url := range []string{"badger://" + dir + "?cache=x", "badger://" + dir + "?cache_ttl=-1s"} {
+		if _, err := openKVByURL(url); err == nil {
+			t.Errorf("openKVByURL(%q) should fail", url)
+		}
+	}
+}
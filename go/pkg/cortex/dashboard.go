@@ -0,0 +1,451 @@
+package cortex
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx/trace"
+)
+
+// Dashboard is a read-only web view of a running server: connected devices,
+// active sessions, pipeline stage latencies, recent errors and the config
+// documents in KV. It is an http.Handler:
+//
+//	dash := cortex.NewDashboard(c)
+//	rec := trace.NewRecorder(trace.WithOnSpan(dash.ObserveSpan))
+//	go http.ListenAndServe("127.0.0.1:7070", dash)
+//
+// The server reports devices, sessions and errors as they happen; stage
+// latencies come from the spans of a trace.Recorder. The page at "/" polls
+// the JSON endpoints:
+//
+//	GET /api/devices     []DeviceStatus
+//	GET /api/sessions    []SessionStatus
+//	GET /api/latency     []StageLatency
+//	GET /api/errors      []ErrorEntry, newest first
+//	GET /api/documents   documents with their kind, secrets redacted;
+//	                     ?kind= filters
+//
+// Documents are listed with the Cortex's authorizer, as the subject of the
+// request context if any. A Dashboard is safe for concurrent use.
+type Dashboard struct {
+	cortex    *Cortex
+	now       func() time.Time
+	maxErrors int
+	mux       *http.ServeMux
+
+	mu       sync.Mutex
+	devices  map[string]*DeviceStatus
+	sessions map[string]*SessionStatus
+	stages   map[string]*stageStats
+	errors   []ErrorEntry // ring buffer of the last maxErrors
+	errNext  int
+}
+
+// DashboardOption configures a Dashboard.
+type DashboardOption func(*Dashboard)
+
+// WithDashboardClock sets the clock of the Dashboard. Defaults to time.Now.
+func WithDashboardClock(now func() time.Time) DashboardOption {
+	return func(d *Dashboard) { d.now = now }
+}
+
+// WithDashboardMaxErrors sets the number of recent errors kept. Defaults
+// to 100.
+func WithDashboardMaxErrors(n int) DashboardOption {
+	return func(d *Dashboard) { d.maxErrors = n }
+}
+
+// DeviceStatus is a connected device.
+type DeviceStatus struct {
+	ID          string    `json:"id"`
+	Addr        string    `json:"addr,omitempty"`
+	ConnectedAt time.Time `json:"connected_at"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// SessionStatus is an active session, e.g. a conversation of an agent with
+// a device.
+type SessionStatus struct {
+	ID        string    `json:"id"`
+	DeviceID  string    `json:"device_id,omitempty"`
+	Agent     string    `json:"agent,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// StageLatency summarizes the spans of a pipeline stage. Latencies are the
+// time to first output (see trace.Span.FirstOutputLatency) of the turns
+// that produced output, in milliseconds.
+type StageLatency struct {
+	Stage    string  `json:"stage"`
+	Turns    int     `json:"turns"`
+	NoOutput int     `json:"no_output"`
+	LastMs   float64 `json:"last_ms"`
+	MeanMs   float64 `json:"mean_ms"`
+	MaxMs    float64 `json:"max_ms"`
+}
+
+// ErrorEntry is a reported error.
+type ErrorEntry struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	Message string    `json:"message"`
+}
+
+type stageStats struct {
+	turns    int
+	noOutput int
+	last     time.Duration
+	total    time.Duration
+	max      time.Duration
+}
+
+// NewDashboard creates a Dashboard that lists the documents of c.
+func NewDashboard(c *Cortex, opts ...DashboardOption) *Dashboard {
+	d := &Dashboard{
+		cortex:    c,
+		now:       time.Now,
+		maxErrors: 100,
+		devices:   make(map[string]*DeviceStatus),
+		sessions:  make(map[string]*SessionStatus),
+		stages:    make(map[string]*stageStats),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.maxErrors = max(d.maxErrors, 1)
+
+	d.mux = http.NewServeMux()
+	d.mux.HandleFunc("GET /{$}", d.serveIndex)
+	d.mux.HandleFunc("GET /api/devices", d.serveDevices)
+	d.mux.HandleFunc("GET /api/sessions", d.serveSessions)
+	d.mux.HandleFunc("GET /api/latency", d.serveLatency)
+	d.mux.HandleFunc("GET /api/errors", d.serveErrors)
+	d.mux.HandleFunc("GET /api/documents", d.serveDocuments)
+	return d
+}
+
+// ServeHTTP implements http.Handler.
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mux.ServeHTTP(w, r)
+}
+
+// DeviceConnected records that a device connected from addr. A device that
+// reconnects replaces its previous connection.
+func (d *Dashboard) DeviceConnected(id, addr string) {
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.devices[id] = &DeviceStatus{ID: id, Addr: addr, ConnectedAt: now, LastSeen: now}
+}
+
+// DeviceSeen records activity of a connected device.
+func (d *Dashboard) DeviceSeen(id string) {
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if dev, ok := d.devices[id]; ok {
+		dev.LastSeen = now
+	}
+}
+
+// DeviceDisconnected removes a device.
+func (d *Dashboard) DeviceDisconnected(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.devices, id)
+}
+
+// SessionStarted records an active session. StartedAt defaults to now.
+func (d *Dashboard) SessionStarted(s SessionStatus) {
+	if s.StartedAt.IsZero() {
+		s.StartedAt = d.now()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sessions[s.ID] = &s
+}
+
+// SessionEnded removes a session.
+func (d *Dashboard) SessionEnded(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.sessions, id)
+}
+
+// ObserveSpan adds a span to the latency of its stage. Pass it to
+// trace.WithOnSpan.
+func (d *Dashboard) ObserveSpan(s trace.Span) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	st, ok := d.stages[s.Stage]
+	if !ok {
+		st = &stageStats{}
+		d.stages[s.Stage] = st
+	}
+	if s.FirstOutput.IsZero() {
+		st.noOutput++
+		return
+	}
+	lat := s.FirstOutputLatency()
+	st.turns++
+	st.last = lat
+	st.total += lat
+	st.max = max(st.max, lat)
+}
+
+// ReportError records an error of source, e.g. a device ID or a stage
+// name. Nil errors are ignored.
+func (d *Dashboard) ReportError(source string, err error) {
+	if err == nil {
+		return
+	}
+	e := ErrorEntry{Time: d.now(), Source: source, Message: err.Error()}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.errors) < d.maxErrors {
+		d.errors = append(d.errors, e)
+		return
+	}
+	d.errors[d.errNext] = e
+	d.errNext = (d.errNext + 1) % d.maxErrors
+}
+
+// Devices returns the connected devices ordered by ID.
+func (d *Dashboard) Devices() []DeviceStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	devices := make([]DeviceStatus, 0, len(d.devices))
+	for _, dev := range d.devices {
+		devices = append(devices, *dev)
+	}
+	slices.SortFunc(devices, func(a, b DeviceStatus) int { return cmp.Compare(a.ID, b.ID) })
+	return devices
+}
+
+// Sessions returns the active sessions ordered by start.
+func (d *Dashboard) Sessions() []SessionStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sessions := make([]SessionStatus, 0, len(d.sessions))
+	for _, s := range d.sessions {
+		sessions = append(sessions, *s)
+	}
+	slices.SortFunc(sessions, func(a, b SessionStatus) int {
+		return cmp.Or(a.StartedAt.Compare(b.StartedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return sessions
+}
+
+// Latency returns the latency of every observed stage ordered by name.
+func (d *Dashboard) Latency() []StageLatency {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	latency := make([]StageLatency, 0, len(d.stages))
+	for _, name := range slices.Sorted(maps.Keys(d.stages)) {
+		st := d.stages[name]
+		l := StageLatency{Stage: name, Turns: st.turns, NoOutput: st.noOutput}
+		if st.turns > 0 {
+			l.LastMs = ms(st.last)
+			l.MeanMs = ms(st.total / time.Duration(st.turns))
+			l.MaxMs = ms(st.max)
+		}
+		latency = append(latency, l)
+	}
+	return latency
+}
+
+// Errors returns the recent errors, newest first.
+func (d *Dashboard) Errors() []ErrorEntry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	errs := make([]ErrorEntry, 0, len(d.errors))
+	for i := range d.errors {
+		// errNext is the oldest entry once the ring is full
+		errs = append(errs, d.errors[(d.errNext+len(d.errors)-1-i)%len(d.errors)])
+	}
+	return errs
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// secretFields are the document fields the dashboard never shows.
+var secretFields = map[string]bool{
+	"api_key":    true,
+	"app_key":    true,
+	"token":      true,
+	"console_ak": true,
+	"console_sk": true,
+	"password":   true,
+	"secret":     true,
+}
+
+// redact returns a copy of doc with the values of secret fields masked.
+func redact(doc Document) Document {
+	fields := make(map[string]any, len(doc.Fields))
+	for k, v := range doc.Fields {
+		if secretFields[k] {
+			v = "***"
+		}
+		fields[k] = v
+	}
+	return Document{Kind: doc.Kind, Fields: fields}
+}
+
+// documents lists the documents of every registered kind, or of kind if it
+// is not empty. Kinds the caller may not list are skipped.
+func (d *Dashboard) documents(r *http.Request, kind string) ([]Document, error) {
+	kinds := d.cortex.Schemas().Kinds()
+	if kind != "" {
+		if !slices.Contains(kinds, kind) {
+			return nil, invalidf("unknown kind %q", kind)
+		}
+		kinds = []string{kind}
+	}
+	slices.Sort(kinds)
+
+	docs := []Document{}
+	for _, k := range kinds {
+		pattern := strings.ReplaceAll(k, "/", ":") + ":*"
+		list, err := d.cortex.List(r.Context(), pattern, ListOpts{All: true})
+		if errors.Is(err, ErrForbidden) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range list {
+			docs = append(docs, redact(doc))
+		}
+	}
+	return docs, nil
+}
+
+func (d *Dashboard) serveDevices(w http.ResponseWriter, r *http.Request) {
+	writeDashboardJSON(w, d.Devices())
+}
+
+func (d *Dashboard) serveSessions(w http.ResponseWriter, r *http.Request) {
+	writeDashboardJSON(w, d.Sessions())
+}
+
+func (d *Dashboard) serveLatency(w http.ResponseWriter, r *http.Request) {
+	writeDashboardJSON(w, d.Latency())
+}
+
+func (d *Dashboard) serveErrors(w http.ResponseWriter, r *http.Request) {
+	writeDashboardJSON(w, d.Errors())
+}
+
+func (d *Dashboard) serveDocuments(w http.ResponseWriter, r *http.Request) {
+	docs, err := d.documents(r, r.URL.Query().Get("kind"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalid) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	// Document inlines its fields in YAML only; flatten them for JSON
+	out := make([]map[string]any, len(docs))
+	for i, doc := range docs {
+		m := maps.Clone(doc.Fields)
+		m["kind"] = doc.Kind
+		out[i] = m
+	}
+	writeDashboardJSON(w, out)
+}
+
+func (d *Dashboard) serveIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(dashboardHTML))
+}
+
+func writeDashboardJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(v)
+}
+
+// dashboardHTML is the dashboard page. It renders the JSON endpoints and
+// refreshes every few seconds.
+const dashboardHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>giztoy</title>
+<style>
+body { font: 14px/1.4 system-ui, sans-serif; margin: 2em; color: #222; }
+h2 { font-size: 1.1em; margin: 1.5em 0 0.5em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #ddd; vertical-align: top; }
+th { background: #f5f5f5; }
+td.empty { color: #999; }
+pre { margin: 0; font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>giztoy</h1>
+<h2>Devices</h2><table id="devices"></table>
+<h2>Sessions</h2><table id="sessions"></table>
+<h2>Stage latency (time to first output)</h2><table id="latency"></table>
+<h2>Recent errors</h2><table id="errors"></table>
+<h2>Documents</h2><table id="documents"></table>
+<script>
+function esc(s) {
+  return String(s).replace(/[&<>"]/g, c => ({"&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;"}[c]));
+}
+function render(id, cols, rows) {
+  let html = "<tr>" + cols.map(c => "<th>" + esc(c[0]) + "</th>").join("") + "</tr>";
+  if (!rows.length) {
+    html += '<tr><td class="empty" colspan="' + cols.length + '">none</td></tr>';
+  }
+  for (const row of rows) {
+    html += "<tr>" + cols.map(c => "<td>" + c[1](row) + "</td>").join("") + "</tr>";
+  }
+  document.getElementById(id).innerHTML = html;
+}
+function time(t) { return esc(new Date(t).toLocaleString()); }
+async function load(path) {
+  const resp = await fetch(path);
+  if (!resp.ok) throw new Error(path + ": " + resp.status);
+  return resp.json();
+}
+async function refresh() {
+  try {
+    render("devices", [["ID", r => esc(r.id)], ["Address", r => esc(r.addr || "")],
+      ["Connected", r => time(r.connected_at)], ["Last seen", r => time(r.last_seen)]],
+      await load("api/devices"));
+    render("sessions", [["ID", r => esc(r.id)], ["Device", r => esc(r.device_id || "")],
+      ["Agent", r => esc(r.agent || "")], ["Started", r => time(r.started_at)]],
+      await load("api/sessions"));
+    render("latency", [["Stage", r => esc(r.stage)], ["Turns", r => r.turns],
+      ["No output", r => r.no_output], ["Last ms", r => r.last_ms.toFixed(1)],
+      ["Mean ms", r => r.mean_ms.toFixed(1)], ["Max ms", r => r.max_ms.toFixed(1)]],
+      await load("api/latency"));
+    render("errors", [["Time", r => time(r.time)], ["Source", r => esc(r.source)],
+      ["Message", r => esc(r.message)]],
+      await load("api/errors"));
+    render("documents", [["Kind", r => esc(r.kind)], ["Name", r => esc(r.name || "")],
+      ["Fields", r => "<pre>" + esc(JSON.stringify(r, null, 2)) + "</pre>"]],
+      await load("api/documents"));
+  } catch (e) {
+    console.error(e);
+  }
+}
+refresh();
+setInterval(refresh, 3000);
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,173 @@
+package cortex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx/trace"
+)
+
+func getDashboardJSON(t *testing.T, d *Dashboard, path string, v any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s: status %d: %s", path, rec.Code, rec.Body)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+}
+
+func TestDashboardDevicesAndSessions(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	d := NewDashboard(newTestCortex(t), WithDashboardClock(func() time.Time { return now }))
+
+	d.DeviceConnected("gear-002", "10.0.0.2:4000")
+	d.DeviceConnected("gear-001", "10.0.0.1:4000")
+	now = now.Add(time.Minute)
+	d.DeviceSeen("gear-001")
+	d.DeviceConnected("gear-003", "")
+	d.DeviceDisconnected("gear-003")
+	d.SessionStarted(SessionStatus{ID: "s2", DeviceID: "gear-002", Agent: "tutor"})
+	d.SessionStarted(SessionStatus{ID: "s1", DeviceID: "gear-001", StartedAt: now.Add(-time.Hour)})
+	d.SessionStarted(SessionStatus{ID: "s3"})
+	d.SessionEnded("s3")
+
+	var devices []DeviceStatus
+	getDashboardJSON(t, d, "/api/devices", &devices)
+	if len(devices) != 2 || devices[0].ID != "gear-001" || devices[1].ID != "gear-002" {
+		t.Fatalf("devices = %+v", devices)
+	}
+	if !devices[0].LastSeen.Equal(now) || !devices[0].ConnectedAt.Equal(now.Add(-time.Minute)) {
+		t.Errorf("gear-001 = %+v, want seen a minute after connecting", devices[0])
+	}
+
+	var sessions []SessionStatus
+	getDashboardJSON(t, d, "/api/sessions", &sessions)
+	if len(sessions) != 2 || sessions[0].ID != "s1" || sessions[1].ID != "s2" {
+		t.Fatalf("sessions = %+v, want s1 and s2 by start", sessions)
+	}
+	if sessions[1].Agent != "tutor" || !sessions[1].StartedAt.Equal(now) {
+		t.Errorf("s2 = %+v", sessions[1])
+	}
+}
+
+func TestDashboardLatency(t *testing.T) {
+	d := NewDashboard(newTestCortex(t))
+	start := time.Now()
+	span := func(stage string, firstOutput time.Duration) trace.Span {
+		s := trace.Span{Stage: stage, Start: start, End: start.Add(time.Second)}
+		if firstOutput > 0 {
+			s.FirstOutput = start.Add(firstOutput)
+		}
+		return s
+	}
+	d.ObserveSpan(span("tts", 100*time.Millisecond))
+	d.ObserveSpan(span("asr", 300*time.Millisecond))
+	d.ObserveSpan(span("asr", 100*time.Millisecond))
+	d.ObserveSpan(span("asr", 0))
+
+	var latency []StageLatency
+	getDashboardJSON(t, d, "/api/latency", &latency)
+	want := []StageLatency{
+		{Stage: "asr", Turns: 2, NoOutput: 1, LastMs: 100, MeanMs: 200, MaxMs: 300},
+		{Stage: "tts", Turns: 1, LastMs: 100, MeanMs: 100, MaxMs: 100},
+	}
+	if fmt.Sprint(latency) != fmt.Sprint(want) {
+		t.Errorf("latency = %+v, want %+v", latency, want)
+	}
+}
+
+func TestDashboardErrorsRing(t *testing.T) {
+	d := NewDashboard(newTestCortex(t), WithDashboardMaxErrors(3))
+	d.ReportError("asr", nil)
+	for i := range 5 {
+		d.ReportError("gear-001", fmt.Errorf("error %d", i))
+	}
+
+	var errs []ErrorEntry
+	getDashboardJSON(t, d, "/api/errors", &errs)
+	var msgs []string
+	for _, e := range errs {
+		msgs = append(msgs, e.Message)
+	}
+	if got := strings.Join(msgs, ","); got != "error 4,error 3,error 2" {
+		t.Errorf("errors = %s, want the last 3, newest first", got)
+	}
+}
+
+func TestDashboardDocuments(t *testing.T) {
+	c := newTestCortex(t)
+	ctx := context.Background()
+	if _, err := c.Apply(ctx, []Document{
+		{Kind: "creds/openai", Fields: map[string]any{"name": "qwen", "api_key": "sk-secret"}},
+		{Kind: "genx/generator", Fields: map[string]any{"name": "chat", "cred": "openai:qwen", "model": "qwen-turbo"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	d := NewDashboard(c)
+
+	var docs []map[string]any
+	getDashboardJSON(t, d, "/api/documents", &docs)
+	if len(docs) != 2 {
+		t.Fatalf("documents = %v, want 2", docs)
+	}
+	if docs[0]["kind"] != "creds/openai" || docs[0]["api_key"] != "***" {
+		t.Errorf("creds = %v, want the api_key redacted", docs[0])
+	}
+	if docs[1]["kind"] != "genx/generator" || docs[1]["model"] != "qwen-turbo" {
+		t.Errorf("generator = %v", docs[1])
+	}
+
+	getDashboardJSON(t, d, "/api/documents?kind=genx/generator", &docs)
+	if len(docs) != 1 || docs[0]["name"] != "chat" {
+		t.Errorf("documents of genx/generator = %v", docs)
+	}
+
+	rec := httptest.NewRecorder()
+	d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/documents?kind=bogus", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown kind: status %d, want 400", rec.Code)
+	}
+}
+
+func TestDashboardDocumentsAuthz(t *testing.T) {
+	c := newAuthzCortex(t, AccessRules{{Subjects: []string{"*"}, Kinds: []string{"genx/*"}}})
+	// Apply as an admin, then list anonymously
+	authz := c.authz
+	c.authz = nil
+	if _, err := c.Apply(context.Background(), []Document{
+		credDoc(),
+		{Kind: "genx/generator", Fields: map[string]any{"name": "chat", "cred": "openai:qwen", "model": "m"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	c.authz = authz
+
+	var docs []map[string]any
+	getDashboardJSON(t, NewDashboard(c), "/api/documents", &docs)
+	if len(docs) != 1 || docs[0]["kind"] != "genx/generator" {
+		t.Errorf("documents = %v, want only the allowed genx/generator", docs)
+	}
+}
+
+func TestDashboardIndex(t *testing.T) {
+	d := NewDashboard(newTestCortex(t))
+	rec := httptest.NewRecorder()
+	d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "api/devices") {
+		t.Errorf("GET /: status %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	d.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/devices", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /api/devices: status %d, want 405", rec.Code)
+	}
+}
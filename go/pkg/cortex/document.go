@@ -111,7 +111,7 @@ func ParseDocuments(data []byte) ([]Document, error) {
 		}
 		kind, _ := raw["kind"].(string)
 		if kind == "" {
-			return nil, fmt.Errorf("document missing 'kind' field")
+			return nil, invalidf("document missing 'kind' field")
 		}
 		delete(raw, "kind")
 		docs = append(docs, Document{Kind: kind, Fields: raw})
@@ -0,0 +1,33 @@
+package cortex
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalid is returned (wrapped) when a document or task is invalid:
+	// an unknown kind, a missing or malformed field.
+	ErrInvalid = errors.New("invalid")
+
+	// ErrNotFound is returned (wrapped) when a document does not exist.
+	ErrNotFound = errors.New("not found")
+)
+
+// invalidError marks an error as ErrInvalid without changing its message.
+type invalidError struct {
+	err error
+}
+
+func (e *invalidError) Error() string   { return e.err.Error() }
+func (e *invalidError) Unwrap() []error { return []error{e.err, ErrInvalid} }
+
+// invalid marks err as ErrInvalid.
+func invalid(err error) error {
+	return &invalidError{err: err}
+}
+
+// invalidf formats an error marked as ErrInvalid.
+func invalidf(format string, args ...any) error {
+	return invalid(fmt.Errorf(format, args...))
+}
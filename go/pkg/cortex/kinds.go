@@ -108,6 +108,54 @@ func registerBuiltinSchemas(r *SchemaRegistry) {
 		ValidateFn: validateCredFormat,
 	})
 
+	r.Register(&Schema{
+		Kind:     "genx/match",
+		Required: []string{"name", "rules"},
+		KeyFunc: func(f map[string]any) kv.Key {
+			return kv.Key{"genx", "match", f["name"].(string)}
+		},
+		ValidateFn: validateMatchRules,
+	})
+
+	// --- chatgear ---
+
+	r.Register(&Schema{
+		Kind:     "chatgear/config",
+		Required: []string{"name"},
+		Optional: []string{"persona", "voice", "volume_min", "volume_max", "downlink_prebuffer_ms"},
+		KeyFunc: func(f map[string]any) kv.Key {
+			return kv.Key{"chatgear", "config", f["name"].(string)}
+		},
+		ValidateFn: chainValidators(
+			validatePercent("volume_min"),
+			validatePercent("volume_max"),
+			validateRange("downlink_prebuffer_ms", 0, 2000),
+		),
+		Versioned: true,
+	})
+
+	r.Register(&Schema{
+		Kind:     "chatgear/speaker",
+		Required: []string{"name"},
+		Optional: []string{"member", "voice", "persona", "instructions"},
+		KeyFunc: func(f map[string]any) kv.Key {
+			return kv.Key{"chatgear", "speaker", f["name"].(string)}
+		},
+		ValidateFn: validateVoiceHash,
+	})
+
+	// --- memory ---
+
+	r.Register(&Schema{
+		Kind:     "memory/prompt",
+		Required: []string{"name"},
+		Optional: []string{"segmentor", "profiler"},
+		KeyFunc: func(f map[string]any) kv.Key {
+			return kv.Key{"memory", "prompt", f["name"].(string)}
+		},
+		ValidateFn: validateMemoryPrompt,
+	})
+
 	// --- ctx ---
 
 	r.Register(&Schema{
@@ -0,0 +1,101 @@
+package cortex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/haivivi/giztoy/go/pkg/genx/match"
+)
+
+func init() {
+	RegisterRunHandler("genx/match", runGenxMatch)
+}
+
+// MatchRules returns the rule set of a "genx/match" document, in order:
+//
+//	kind: genx/match
+//	name: player
+//	rules:
+//	  - name: play_song
+//	    vars:
+//	      title: {label: song title}
+//	    patterns:
+//	      - play [title]
+//	  - name: stop
+//	    patterns:
+//	      - stop
+//
+// Apply rejects rule sets with errors reported by match.Check, such as two
+// rules matching the same utterance. It returns an error wrapping
+// ErrNotFound if there is no such rule set.
+func (c *Cortex) MatchRules(ctx context.Context, name string) ([]*match.Rule, error) {
+	doc, err := c.Get(ctx, "genx:match:"+name)
+	if err != nil {
+		return nil, fmt.Errorf("match rules: %w", err)
+	}
+	rules, err := decodeMatchRules(doc.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("match rules: %w", err)
+	}
+	return rules, nil
+}
+
+// decodeMatchRules decodes the rules field of a rule set document.
+func decodeMatchRules(fields map[string]any) ([]*match.Rule, error) {
+	if _, ok := fields["rules"].([]any); !ok {
+		return nil, fmt.Errorf("field 'rules' must be a list")
+	}
+	b, err := yaml.Marshal(fields["rules"])
+	if err != nil {
+		return nil, fmt.Errorf("field 'rules': %w", err)
+	}
+	var rules []*match.Rule
+	if err := yaml.UnmarshalWithOptions(b, &rules, yaml.DisallowUnknownField()); err != nil {
+		return nil, fmt.Errorf("field 'rules': %w", err)
+	}
+	return rules, nil
+}
+
+// validateMatchRules checks a rule set document with match.Check.
+func validateMatchRules(fields map[string]any) error {
+	rules, err := decodeMatchRules(fields)
+	if err != nil {
+		return err
+	}
+	if err := match.Check(rules).Err(); err != nil {
+		return fmt.Errorf("field 'rules':\n%w", err)
+	}
+	return nil
+}
+
+// runGenxMatch checks a stored rule set and returns its diagnostics and
+// test matrix.
+func runGenxMatch(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
+	name := task.GetString("name")
+	if name == "" {
+		return nil, invalidf("genx/match: missing 'name'")
+	}
+	rules, err := c.MatchRules(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("genx/match: %w", err)
+	}
+	report := match.Check(rules)
+
+	text := fmt.Sprintf("%d rules, %d test cases, %d errors, %d warnings",
+		len(rules), len(report.Matrix), len(report.Errors()), len(report.Diagnostics)-len(report.Errors()))
+	for _, d := range report.Diagnostics {
+		text += "\n" + d.String()
+	}
+	return &RunResult{
+		Kind:   task.Kind,
+		Name:   name,
+		Status: "ok",
+		Text:   text,
+		Data: map[string]any{
+			"diagnostics": report.Diagnostics,
+			"matrix":      report.Matrix,
+		},
+	}, nil
+}
@@ -0,0 +1,70 @@
+package cortex
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func matchRulesDoc(rules ...any) Document {
+	return Document{
+		Kind:   "genx/match",
+		Fields: map[string]any{"name": "player", "rules": rules},
+	}
+}
+
+var (
+	playSongRule = map[string]any{
+		"name":     "play_song",
+		"vars":     map[string]any{"title": map[string]any{"label": "song title"}},
+		"patterns": []any{"play [title]"},
+	}
+	playMusicRule = map[string]any{
+		"name":     "play_music",
+		"patterns": []any{"play music"},
+	}
+)
+
+func TestMatchRules(t *testing.T) {
+	c := newTestCortex(t)
+	ctx := context.Background()
+
+	if _, err := c.Apply(ctx, []Document{matchRulesDoc(playMusicRule, playSongRule)}); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := c.MatchRules(ctx, "player")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[1].Name != "play_song" || rules[1].Vars["title"].Label != "song title" || rules[1].Patterns[0].Input != "play [title]" {
+		t.Fatalf("rules = %+v", rules)
+	}
+
+	result, err := c.Run(ctx, Document{Kind: "genx/match", Fields: map[string]any{"name": "player"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(result.Text, "2 rules, 2 test cases, 0 errors, 1 warnings") {
+		t.Errorf("Text = %q", result.Text)
+	}
+
+	if _, err := c.MatchRules(ctx, "other"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestApplyMatchRulesInvalid(t *testing.T) {
+	c := newTestCortex(t)
+	for name, doc := range map[string]Document{
+		"shadowed":  matchRulesDoc(playSongRule, playMusicRule),
+		"undefined": matchRulesDoc(map[string]any{"name": "go", "patterns": []any{"go [where]"}}),
+		"typo":      matchRulesDoc(map[string]any{"name": "go", "pattrens": []any{"go"}}),
+		"not list":  {Kind: "genx/match", Fields: map[string]any{"name": "player", "rules": "play"}},
+	} {
+		_, err := c.Apply(context.Background(), []Document{doc})
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected ErrInvalid, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package cortex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/haivivi/giztoy/go/pkg/genx/segmentors"
+)
+
+// MemoryPrompt customizes how the memory of a persona is extracted from its
+// conversations, e.g. the granularity of segment summaries or the entity
+// types to track. It is stored as a "memory/prompt" document named after
+// the persona:
+//
+//	kind: memory/prompt
+//	name: lele
+//	segmentor:
+//	  rules:
+//	    - Only extract people and pets, not topics.
+//	  examples:
+//	    - conversation: |
+//	        user: 我的狗叫旺财
+//	      output: |
+//	        {"segment": {"summary": "用户的狗叫旺财", ...}, ...}
+//	profiler:
+//	  instructions: ...
+//
+// Apply it to a memory.LLMCompressor with WithPrompts.
+type MemoryPrompt struct {
+	// Segmentor customizes the segmentor prompt, or is nil.
+	Segmentor *segmentors.Prompt
+
+	// Profiler customizes the profiler prompt, or is nil.
+	Profiler *segmentors.Prompt
+}
+
+// MemoryPrompt returns the memory prompt of persona. It returns an error
+// wrapping ErrNotFound if there is none.
+func (c *Cortex) MemoryPrompt(ctx context.Context, persona string) (*MemoryPrompt, error) {
+	doc, err := c.Get(ctx, "memory:prompt:"+persona)
+	if err != nil {
+		return nil, fmt.Errorf("memory prompt: %w", err)
+	}
+	var mp MemoryPrompt
+	if mp.Segmentor, err = decodePrompt(doc.Fields, "segmentor"); err != nil {
+		return nil, fmt.Errorf("memory prompt: %w", err)
+	}
+	if mp.Profiler, err = decodePrompt(doc.Fields, "profiler"); err != nil {
+		return nil, fmt.Errorf("memory prompt: %w", err)
+	}
+	return &mp, nil
+}
+
+// decodePrompt decodes the prompt in field, or returns nil if unset.
+func decodePrompt(fields map[string]any, field string) (*segmentors.Prompt, error) {
+	v, ok := fields[field]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	if _, ok := v.(map[string]any); !ok {
+		return nil, fmt.Errorf("field '%s' must be a mapping", field)
+	}
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("field '%s': %w", field, err)
+	}
+	var p segmentors.Prompt
+	if err := yaml.UnmarshalWithOptions(b, &p, yaml.DisallowUnknownField()); err != nil {
+		return nil, fmt.Errorf("field '%s': %w", field, err)
+	}
+	return &p, nil
+}
+
+// validateMemoryPrompt checks that the segmentor and profiler fields of a
+// memory prompt document are prompts.
+func validateMemoryPrompt(fields map[string]any) error {
+	for _, field := range []string{"segmentor", "profiler"} {
+		if _, err := decodePrompt(fields, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
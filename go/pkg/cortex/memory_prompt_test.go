@@ -0,0 +1,60 @@
+package cortex
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemoryPrompt(t *testing.T) {
+	c := newTestCortex(t)
+	ctx := context.Background()
+
+	if _, err := c.Apply(ctx, []Document{{
+		Kind: "memory/prompt",
+		Fields: map[string]any{
+			"name": "lele",
+			"segmentor": map[string]any{
+				"rules": []any{"Only extract people and pets."},
+				"examples": []any{map[string]any{
+					"conversation": "user: 我的狗叫旺财",
+					"output":       `{"segment": {"summary": "用户的狗叫旺财"}}`,
+				}},
+			},
+		},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	mp, err := c.MemoryPrompt(ctx, "lele")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mp.Profiler != nil {
+		t.Errorf("unexpected profiler prompt: %+v", mp.Profiler)
+	}
+	seg := mp.Segmentor
+	if seg == nil || len(seg.Rules) != 1 || len(seg.Examples) != 1 || seg.Examples[0].Conversation != "user: 我的狗叫旺财" {
+		t.Fatalf("unexpected segmentor prompt: %+v", seg)
+	}
+
+	if _, err := c.MemoryPrompt(ctx, "other"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestApplyMemoryPromptInvalid(t *testing.T) {
+	c := newTestCortex(t)
+	for _, prompt := range []any{
+		"not a mapping",
+		map[string]any{"instructoins": "typo"},
+	} {
+		_, err := c.Apply(context.Background(), []Document{{
+			Kind:   "memory/prompt",
+			Fields: map[string]any{"name": "lele", "segmentor": prompt},
+		}})
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("%v: expected ErrInvalid, got %v", prompt, err)
+		}
+	}
+}
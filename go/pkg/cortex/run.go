@@ -36,7 +36,10 @@ func RegisterRunHandler(kind string, handler RunHandler) {
 func (c *Cortex) Run(ctx context.Context, task Document) (*RunResult, error) {
 	handler, ok := runHandlers[task.Kind]
 	if !ok {
-		return nil, fmt.Errorf("unknown run kind %q; no handler registered", task.Kind)
+		return nil, invalidf("unknown run kind %q; no handler registered", task.Kind)
+	}
+	if err := c.authorize(ctx, ActionRun, task.Kind, task.Name()); err != nil {
+		return nil, err
 	}
 	return handler(ctx, c, task)
 }
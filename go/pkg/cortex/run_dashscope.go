@@ -15,7 +15,7 @@ func init() {
 func runDashscopeOmniChat(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
 	credRef := task.GetString("cred")
 	if credRef == "" {
-		return nil, fmt.Errorf("dashscope/omni/chat: missing 'cred' field")
+		return nil, invalidf("dashscope/omni/chat: missing 'cred' field")
 	}
 	cred, err := c.ResolveCred(ctx, credRef)
 	if err != nil {
@@ -24,7 +24,7 @@ func runDashscopeOmniChat(ctx context.Context, c *Cortex, task Document) (*RunRe
 
 	apiKey, _ := cred["api_key"].(string)
 	if apiKey == "" {
-		return nil, fmt.Errorf("dashscope cred missing api_key")
+		return nil, invalidf("dashscope cred missing api_key")
 	}
 
 	var opts []dashscope.Option
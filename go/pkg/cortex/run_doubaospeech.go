@@ -32,7 +32,7 @@ func init() {
 func newDoubaoClient(cred map[string]any) (*ds.Client, error) {
 	appID, _ := cred["app_id"].(string)
 	if appID == "" {
-		return nil, fmt.Errorf("doubaospeech cred missing app_id")
+		return nil, invalidf("doubaospeech cred missing app_id")
 	}
 	var opts []ds.Option
 	token, _ := cred["token"].(string)
@@ -271,7 +271,7 @@ func runDoubaoASRV1Recognize(ctx context.Context, c *Cortex, task Document) (*Ru
 
 	audioPath := task.GetString("audio")
 	if audioPath == "" {
-		return nil, fmt.Errorf("doubao asr v1: missing 'audio' field")
+		return nil, invalidf("doubao asr v1: missing 'audio' field")
 	}
 	audioData, err := os.ReadFile(audioPath)
 	if err != nil {
@@ -303,7 +303,7 @@ func runDoubaoVoiceList(ctx context.Context, c *Cortex, task Document) (*RunResu
 	consoleAK, _ := cred["console_ak"].(string)
 	consoleSK, _ := cred["console_sk"].(string)
 	if consoleAK == "" || consoleSK == "" {
-		return nil, fmt.Errorf("doubao voice list requires console_ak and console_sk in cred")
+		return nil, invalidf("doubao voice list requires console_ak and console_sk in cred")
 	}
 
 	appID, _ := cred["app_id"].(string)
@@ -334,7 +334,7 @@ func runDoubaoASRV1Stream(ctx context.Context, c *Cortex, task Document) (*RunRe
 	}
 	audioPath := task.GetString("audio")
 	if audioPath == "" {
-		return nil, fmt.Errorf("doubaospeech/asr/v1/stream: missing 'audio'")
+		return nil, invalidf("doubaospeech/asr/v1/stream: missing 'audio'")
 	}
 	audioData, err := os.ReadFile(audioPath)
 	if err != nil {
@@ -389,7 +389,7 @@ func runDoubaoASRV2Stream(ctx context.Context, c *Cortex, task Document) (*RunRe
 	}
 	audioPath := task.GetString("audio")
 	if audioPath == "" {
-		return nil, fmt.Errorf("doubaospeech/asr/v2/stream: missing 'audio'")
+		return nil, invalidf("doubaospeech/asr/v2/stream: missing 'audio'")
 	}
 	audioData, err := os.ReadFile(audioPath)
 	if err != nil {
@@ -520,7 +520,7 @@ func runDoubaoTranslationStream(ctx context.Context, c *Cortex, task Document) (
 	}
 	audioPath := task.GetString("audio")
 	if audioPath == "" {
-		return nil, fmt.Errorf("doubaospeech/translation/stream: missing 'audio'")
+		return nil, invalidf("doubaospeech/translation/stream: missing 'audio'")
 	}
 	audioData, err := os.ReadFile(audioPath)
 	if err != nil {
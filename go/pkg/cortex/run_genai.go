@@ -15,7 +15,7 @@ func init() {
 func runGenaiTextGenerate(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
 	credRef := task.GetString("cred")
 	if credRef == "" {
-		return nil, fmt.Errorf("genai/text/generate: missing 'cred' field")
+		return nil, invalidf("genai/text/generate: missing 'cred' field")
 	}
 	cred, err := c.ResolveCred(ctx, credRef)
 	if err != nil {
@@ -24,7 +24,7 @@ func runGenaiTextGenerate(ctx context.Context, c *Cortex, task Document) (*RunRe
 
 	apiKey, _ := cred["api_key"].(string)
 	if apiKey == "" {
-		return nil, fmt.Errorf("genai cred missing api_key")
+		return nil, invalidf("genai cred missing api_key")
 	}
 
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: apiKey})
@@ -34,12 +34,12 @@ func runGenaiTextGenerate(ctx context.Context, c *Cortex, task Document) (*RunRe
 
 	model := task.GetString("model")
 	if model == "" {
-		return nil, fmt.Errorf("genai/text/generate: missing 'model' field")
+		return nil, invalidf("genai/text/generate: missing 'model' field")
 	}
 
 	msgs, _ := task.Fields["messages"].([]any)
 	if len(msgs) == 0 {
-		return nil, fmt.Errorf("genai/text/generate: missing 'messages' field")
+		return nil, invalidf("genai/text/generate: missing 'messages' field")
 	}
 
 	var textParts []*genai.Part
@@ -14,11 +14,11 @@ func init() {
 func runGenxGenerator(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
 	name := task.GetString("name")
 	if name == "" {
-		return nil, fmt.Errorf("genx/generator: missing 'name'")
+		return nil, invalidf("genx/generator: missing 'name'")
 	}
 	msgs := task.Fields["messages"]
 	if msgs == nil {
-		return nil, fmt.Errorf("genx/generator: missing 'messages'")
+		return nil, invalidf("genx/generator: missing 'messages'")
 	}
 
 	genxDoc, err := c.Get(ctx, "genx:generator:"+name)
@@ -61,18 +61,18 @@ func runGenxGenerator(ctx context.Context, c *Cortex, task Document) (*RunResult
 		})
 	default:
 		_ = cred
-		return nil, fmt.Errorf("genx/generator: unsupported cred service %q for generator", credService)
+		return nil, invalidf("genx/generator: unsupported cred service %q for generator", credService)
 	}
 }
 
 func runGenxTTS(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
 	name := task.GetString("name")
 	if name == "" {
-		return nil, fmt.Errorf("genx/tts: missing 'name'")
+		return nil, invalidf("genx/tts: missing 'name'")
 	}
 	text := task.GetString("text")
 	if text == "" {
-		return nil, fmt.Errorf("genx/tts: missing 'text'")
+		return nil, invalidf("genx/tts: missing 'text'")
 	}
 
 	genxDoc, err := c.Get(ctx, "genx:tts:"+name)
@@ -114,18 +114,18 @@ func runGenxTTS(ctx context.Context, c *Cortex, task Document) (*RunResult, erro
 			},
 		})
 	default:
-		return nil, fmt.Errorf("genx/tts: unsupported cred service %q for tts", credService)
+		return nil, invalidf("genx/tts: unsupported cred service %q for tts", credService)
 	}
 }
 
 func runGenxASR(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
 	name := task.GetString("name")
 	if name == "" {
-		return nil, fmt.Errorf("genx/asr: missing 'name'")
+		return nil, invalidf("genx/asr: missing 'name'")
 	}
 	audio := task.GetString("audio")
 	if audio == "" {
-		return nil, fmt.Errorf("genx/asr: missing 'audio'")
+		return nil, invalidf("genx/asr: missing 'audio'")
 	}
 
 	genxDoc, err := c.Get(ctx, "genx:asr:"+name)
@@ -0,0 +1,219 @@
+package cortex
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/haivivi/giztoy/go/pkg/embed"
+	"github.com/haivivi/giztoy/go/pkg/genx/agent"
+	"github.com/haivivi/giztoy/go/pkg/kv"
+)
+
+func init() {
+	RegisterRunHandler("kb/ingest", runKBIngest)
+	RegisterRunHandler("kb/search", runKBSearch)
+	RegisterRunHandler("kb/list", runKBList)
+	RegisterRunHandler("kb/delete", runKBDelete)
+}
+
+// openDocumentStore opens the knowledge-base collection stored under
+// kb:{collection} in the Cortex KV. Chunks are embedded with the ctx
+// embed service if one is configured.
+func (c *Cortex) openDocumentStore(collection string) (*agent.DocumentStore, error) {
+	embedder, err := c.openEmbedder()
+	if err != nil {
+		return nil, err
+	}
+	return agent.NewDocumentStore(agent.DocumentStoreConfig{
+		Store:    c.kv,
+		Prefix:   kv.Key{"kb", collection},
+		Embedder: embedder,
+	})
+}
+
+// openEmbedder opens the embed service of the current ctx. It returns nil
+// if none is configured.
+func (c *Cortex) openEmbedder() (embed.Embedder, error) {
+	if c.config == nil {
+		return nil, nil
+	}
+	_, ctxCfg, err := c.config.CtxShow("")
+	if err != nil || ctxCfg.Embed == "" {
+		return nil, nil
+	}
+	return openEmbedByURL(ctxCfg.Embed)
+}
+
+// openEmbedByURL opens an embedder from a URL like "dashscope://<api-key>"
+// or "openai://<api-key>".
+func openEmbedByURL(url string) (embed.Embedder, error) {
+	switch {
+	case strings.HasPrefix(url, "dashscope://"):
+		return embed.NewDashScope(strings.TrimPrefix(url, "dashscope://")), nil
+	case strings.HasPrefix(url, "openai://"):
+		return embed.NewOpenAI(strings.TrimPrefix(url, "openai://")), nil
+	default:
+		return nil, invalidf("unsupported embed URL scheme: %s", url)
+	}
+}
+
+// kbCollection returns the 'collection' field of a kb task.
+func kbCollection(task Document) (string, error) {
+	collection := task.GetString("collection")
+	if collection == "" {
+		return "", invalidf("%s: missing 'collection' field", task.Kind)
+	}
+	return collection, nil
+}
+
+func runKBIngest(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
+	collection, err := kbCollection(task)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []agent.Document
+	if text := task.GetString("text"); text != "" {
+		id := task.GetString("id")
+		if id == "" {
+			return nil, invalidf("kb/ingest: 'text' requires an 'id' field")
+		}
+		docs = append(docs, agent.Document{ID: id, Title: task.GetString("title"), Content: text})
+	}
+	var files []string
+	if file := task.GetString("file"); file != "" {
+		files = append(files, file)
+	}
+	if list, ok := task.Fields["files"].([]any); ok {
+		for _, f := range list {
+			if s, ok := f.(string); ok && s != "" {
+				files = append(files, s)
+			}
+		}
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("kb/ingest: read %s: %w", file, err)
+		}
+		docs = append(docs, fileDocument(file, string(data)))
+	}
+	if len(docs) == 0 {
+		return nil, invalidf("kb/ingest: missing 'file', 'files' or 'text' field")
+	}
+
+	store, err := c.openDocumentStore(collection)
+	if err != nil {
+		return nil, fmt.Errorf("kb/ingest: %w", err)
+	}
+	var items []map[string]any
+	total := 0
+	for _, doc := range docs {
+		n, err := store.Ingest(ctx, doc)
+		if err != nil {
+			return nil, fmt.Errorf("kb/ingest: %w", err)
+		}
+		total += n
+		items = append(items, map[string]any{"id": doc.ID, "title": doc.Title, "chunks": n})
+	}
+	return &RunResult{Kind: task.Kind, Status: "ok", Data: map[string]any{
+		"collection": collection,
+		"documents":  items,
+		"chunks":     total,
+	}}, nil
+}
+
+// fileDocument makes a document of a file. The ID is the file name without
+// extension, and the title the first top-level markdown heading.
+func fileDocument(path, content string) agent.Document {
+	id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	id = strings.ReplaceAll(id, string(kv.DefaultSeparator), "_")
+	doc := agent.Document{ID: id, Title: id, Content: content}
+	for _, line := range strings.SplitN(content, "\n", 20) {
+		if title, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok {
+			doc.Title = strings.TrimSpace(title)
+			break
+		}
+	}
+	return doc
+}
+
+func runKBSearch(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
+	collection, err := kbCollection(task)
+	if err != nil {
+		return nil, err
+	}
+	text := task.GetString("text")
+	if text == "" {
+		return nil, invalidf("kb/search: missing 'text' field")
+	}
+
+	store, err := c.openDocumentStore(collection)
+	if err != nil {
+		return nil, fmt.Errorf("kb/search: %w", err)
+	}
+	hits, err := store.Search(ctx, text, task.GetInt("limit"))
+	if err != nil {
+		return nil, fmt.Errorf("kb/search: %w", err)
+	}
+
+	var items []map[string]any
+	for _, h := range hits {
+		items = append(items, map[string]any{
+			"score":   h.Score,
+			"doc_id":  h.DocID,
+			"title":   h.Title,
+			"heading": h.Heading,
+			"text":    h.Text,
+		})
+	}
+	return &RunResult{Kind: task.Kind, Status: "ok", Data: map[string]any{"results": items, "count": len(items)}}, nil
+}
+
+func runKBList(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
+	collection, err := kbCollection(task)
+	if err != nil {
+		return nil, err
+	}
+	store, err := c.openDocumentStore(collection)
+	if err != nil {
+		return nil, fmt.Errorf("kb/list: %w", err)
+	}
+	docs, err := store.Documents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("kb/list: %w", err)
+	}
+
+	var items []map[string]any
+	for _, d := range docs {
+		items = append(items, map[string]any{
+			"id":         d.ID,
+			"title":      d.Title,
+			"chunks":     d.Chunks,
+			"updated_at": d.UpdatedAt,
+		})
+	}
+	return &RunResult{Kind: task.Kind, Status: "ok", Data: map[string]any{"documents": items, "count": len(items)}}, nil
+}
+
+func runKBDelete(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
+	collection, err := kbCollection(task)
+	if err != nil {
+		return nil, err
+	}
+	id := task.GetString("id")
+	if id == "" {
+		return nil, invalidf("kb/delete: missing 'id' field")
+	}
+	store, err := c.openDocumentStore(collection)
+	if err != nil {
+		return nil, fmt.Errorf("kb/delete: %w", err)
+	}
+	if err := store.Delete(ctx, id); err != nil {
+		return nil, fmt.Errorf("kb/delete: %w", err)
+	}
+	return &RunResult{Kind: task.Kind, Status: "ok", Data: map[string]any{"collection": collection, "id": id}}, nil
+}
@@ -3,6 +3,7 @@ package cortex
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/haivivi/giztoy/go/pkg/genx/labelers"
 	"github.com/haivivi/giztoy/go/pkg/graph"
@@ -56,7 +57,7 @@ func (c *Cortex) openMemory(ctx context.Context, persona string) (*memory.Memory
 func runMemoryCreate(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
 	name := task.GetString("name")
 	if name == "" {
-		return nil, fmt.Errorf("memory/create: missing 'name' field")
+		return nil, invalidf("memory/create: missing 'name' field")
 	}
 	// "create" for memory just opens it (creates on first use)
 	_, err := c.openMemory(ctx, name)
@@ -69,11 +70,11 @@ func runMemoryCreate(ctx context.Context, c *Cortex, task Document) (*RunResult,
 func runMemoryAdd(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
 	persona := task.GetString("persona")
 	if persona == "" {
-		return nil, fmt.Errorf("memory/add: missing 'persona' field")
+		return nil, invalidf("memory/add: missing 'persona' field")
 	}
 	text := task.GetString("text")
 	if text == "" {
-		return nil, fmt.Errorf("memory/add: missing 'text' field")
+		return nil, invalidf("memory/add: missing 'text' field")
 	}
 
 	mem, err := c.openMemory(ctx, persona)
@@ -108,7 +109,7 @@ func runMemorySearch(ctx context.Context, c *Cortex, task Document) (*RunResult,
 	persona := task.GetString("persona")
 	text := task.GetString("text")
 	if persona == "" || text == "" {
-		return nil, fmt.Errorf("memory/search: missing 'persona' or 'text'")
+		return nil, invalidf("memory/search: missing 'persona' or 'text'")
 	}
 
 	mem, err := c.openMemory(ctx, persona)
@@ -140,8 +141,29 @@ func runMemorySearch(ctx context.Context, c *Cortex, task Document) (*RunResult,
 func runMemoryRecall(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
 	persona := task.GetString("persona")
 	text := task.GetString("text")
-	if persona == "" || text == "" {
-		return nil, fmt.Errorf("memory/recall: missing 'persona' or 'text'")
+	q := memory.RecallQuery{
+		Text:   text,
+		Limit:  task.GetInt("limit"),
+		Hops:   task.GetInt("hops"),
+		Period: memory.RecallPeriod(task.GetString("period")),
+	}
+	for key, t := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		if s := task.GetString(key); s != "" {
+			v, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return nil, invalidf("memory/recall: invalid '%s': %v", key, err)
+			}
+			*t = v
+		}
+	}
+	if q.Period != "" {
+		if _, err := q.Period.Ranges(time.Now()); err != nil {
+			return nil, invalid(err)
+		}
+	}
+	timed := q.Period != "" || !q.Since.IsZero() || !q.Until.IsZero()
+	if persona == "" || (text == "" && !timed) {
+		return nil, invalidf("memory/recall: missing 'persona' or 'text'")
 	}
 
 	mem, err := c.openMemory(ctx, persona)
@@ -149,7 +171,6 @@ func runMemoryRecall(ctx context.Context, c *Cortex, task Document) (*RunResult,
 		return nil, err
 	}
 
-	q := memory.RecallQuery{Text: text, Limit: task.GetInt("limit"), Hops: task.GetInt("hops")}
 	if q.Limit <= 0 {
 		q.Limit = 10
 	}
@@ -165,7 +186,7 @@ func runMemoryRecall(ctx context.Context, c *Cortex, task Document) (*RunResult,
 	}
 
 	labelerPattern := task.GetString("labeler")
-	if labelerPattern != "" {
+	if labelerPattern != "" && text != "" {
 		candidates, err := collectCandidateLabels(ctx, mem.Graph())
 		if err != nil {
 			return nil, fmt.Errorf("memory recall: collect label candidates: %w", err)
@@ -238,7 +259,7 @@ func runMemoryEntitySet(ctx context.Context, c *Cortex, task Document) (*RunResu
 	persona := task.GetString("persona")
 	label := task.GetString("label")
 	if persona == "" || label == "" {
-		return nil, fmt.Errorf("memory/entity/set: missing 'persona' or 'label'")
+		return nil, invalidf("memory/entity/set: missing 'persona' or 'label'")
 	}
 
 	mem, err := c.openMemory(ctx, persona)
@@ -258,7 +279,7 @@ func runMemoryEntityGet(ctx context.Context, c *Cortex, task Document) (*RunResu
 	persona := task.GetString("persona")
 	label := task.GetString("label")
 	if persona == "" || label == "" {
-		return nil, fmt.Errorf("memory/entity/get: missing 'persona' or 'label'")
+		return nil, invalidf("memory/entity/get: missing 'persona' or 'label'")
 	}
 
 	mem, err := c.openMemory(ctx, persona)
@@ -280,7 +301,7 @@ func runMemoryEntityGet(ctx context.Context, c *Cortex, task Document) (*RunResu
 func runMemoryEntityList(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
 	persona := task.GetString("persona")
 	if persona == "" {
-		return nil, fmt.Errorf("memory/entity/list: missing 'persona'")
+		return nil, invalidf("memory/entity/list: missing 'persona'")
 	}
 
 	mem, err := c.openMemory(ctx, persona)
@@ -304,7 +325,7 @@ func runMemoryEntityDelete(ctx context.Context, c *Cortex, task Document) (*RunR
 	persona := task.GetString("persona")
 	label := task.GetString("label")
 	if persona == "" || label == "" {
-		return nil, fmt.Errorf("memory/entity/delete: missing 'persona' or 'label'")
+		return nil, invalidf("memory/entity/delete: missing 'persona' or 'label'")
 	}
 
 	mem, err := c.openMemory(ctx, persona)
@@ -325,7 +346,7 @@ func runMemoryRelationAdd(ctx context.Context, c *Cortex, task Document) (*RunRe
 	to := task.GetString("to")
 	relType := task.GetString("rel_type")
 	if persona == "" || from == "" || to == "" || relType == "" {
-		return nil, fmt.Errorf("memory/relation/add: missing required fields")
+		return nil, invalidf("memory/relation/add: missing required fields")
 	}
 
 	mem, err := c.openMemory(ctx, persona)
@@ -344,7 +365,7 @@ func runMemoryRelationList(ctx context.Context, c *Cortex, task Document) (*RunR
 	persona := task.GetString("persona")
 	label := task.GetString("label")
 	if persona == "" || label == "" {
-		return nil, fmt.Errorf("memory/relation/list: missing 'persona' or 'label'")
+		return nil, invalidf("memory/relation/list: missing 'persona' or 'label'")
 	}
 
 	mem, err := c.openMemory(ctx, persona)
@@ -52,7 +52,7 @@ func newMinimaxClient(cred map[string]any) (*minimax.Client, error) {
 		}
 	}
 	if apiKey == "" {
-		return nil, fmt.Errorf("minimax cred missing api_key (keys: %v)", mapKeys(cred))
+		return nil, invalidf("minimax cred missing api_key (keys: %v)", mapKeys(cred))
 	}
 	var opts []minimax.Option
 	if baseURL, _ := cred["base_url"].(string); baseURL != "" {
@@ -64,7 +64,7 @@ func newMinimaxClient(cred map[string]any) (*minimax.Client, error) {
 func runMinimaxTextChat(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
 	credRef := task.GetString("cred")
 	if credRef == "" {
-		return nil, fmt.Errorf("minimax/text/chat: missing 'cred' field")
+		return nil, invalidf("minimax/text/chat: missing 'cred' field")
 	}
 	cred, err := c.ResolveCred(ctx, credRef)
 	if err != nil {
@@ -306,7 +306,29 @@ func runMinimaxVideoT2V(ctx context.Context, c *Cortex, task Document) (*RunResu
 		return nil, fmt.Errorf("minimax video t2v: %w", err)
 	}
 
-	return &RunResult{Kind: task.Kind, Status: "ok", TaskID: taskResp.ID}, nil
+	return videoTaskResult(ctx, task, taskResp)
+}
+
+// videoTaskResult returns the result of a submitted video task. With the
+// task field "wait: true" it waits for the video, which takes minutes,
+// logging progress to the cortex task; start such tasks in the background
+// with "giztoy run --async".
+func videoTaskResult(ctx context.Context, task Document, video *minimax.Task[minimax.VideoResult]) (*RunResult, error) {
+	if wait, _ := task.Fields["wait"].(bool); !wait {
+		return &RunResult{Kind: task.Kind, Status: "ok", TaskID: video.ID}, nil
+	}
+	TaskLogf(ctx, "video task %s submitted, waiting for the video", video.ID)
+	result, err := video.Wait(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("minimax video task %s: %w", video.ID, err)
+	}
+	TaskLogf(ctx, "video ready: file %s", result.FileID)
+	return &RunResult{Kind: task.Kind, Status: "ok", TaskID: video.ID, Data: map[string]any{
+		"file_id":      result.FileID,
+		"download_url": result.DownloadURL,
+		"width":        result.VideoWidth,
+		"height":       result.VideoHeight,
+	}}, nil
 }
 
 func runMinimaxImageGenerate(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
@@ -449,7 +471,7 @@ func runMinimaxVideoI2V(ctx context.Context, c *Cortex, task Document) (*RunResu
 	if err != nil {
 		return nil, fmt.Errorf("minimax video i2v: %w", err)
 	}
-	return &RunResult{Kind: task.Kind, Status: "ok", TaskID: taskResp.ID}, nil
+	return videoTaskResult(ctx, task, taskResp)
 }
 
 func runMinimaxVideoFrame(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
@@ -473,7 +495,7 @@ func runMinimaxVideoFrame(ctx context.Context, c *Cortex, task Document) (*RunRe
 	if err != nil {
 		return nil, fmt.Errorf("minimax video frame: %w", err)
 	}
-	return &RunResult{Kind: task.Kind, Status: "ok", TaskID: taskResp.ID}, nil
+	return videoTaskResult(ctx, task, taskResp)
 }
 
 func runMinimaxImageReference(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
@@ -583,7 +605,7 @@ func runMinimaxFileUpload(ctx context.Context, c *Cortex, task Document) (*RunRe
 	}
 	filePath := task.GetString("file_path")
 	if filePath == "" {
-		return nil, fmt.Errorf("minimax/file/upload: missing 'file_path'")
+		return nil, invalidf("minimax/file/upload: missing 'file_path'")
 	}
 	file, err := os.Open(filePath)
 	if err != nil {
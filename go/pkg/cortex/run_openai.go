@@ -18,7 +18,7 @@ func init() {
 func newOpenAIClient(cred map[string]any) (*openai.Client, error) {
 	apiKey, _ := cred["api_key"].(string)
 	if apiKey == "" {
-		return nil, fmt.Errorf("openai cred missing api_key")
+		return nil, invalidf("openai cred missing api_key")
 	}
 	opts := []option.RequestOption{option.WithAPIKey(apiKey)}
 	if baseURL, _ := cred["base_url"].(string); baseURL != "" {
@@ -50,7 +50,7 @@ func buildOpenAIMessages(task Document) []openai.ChatCompletionMessageParamUnion
 func runOpenAITextChat(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
 	credRef := task.GetString("cred")
 	if credRef == "" {
-		return nil, fmt.Errorf("openai/text/chat: missing 'cred' field")
+		return nil, invalidf("openai/text/chat: missing 'cred' field")
 	}
 	cred, err := c.ResolveCred(ctx, credRef)
 	if err != nil {
@@ -63,12 +63,12 @@ func runOpenAITextChat(ctx context.Context, c *Cortex, task Document) (*RunResul
 
 	model := task.GetString("model")
 	if model == "" {
-		return nil, fmt.Errorf("openai/text/chat: missing 'model' field")
+		return nil, invalidf("openai/text/chat: missing 'model' field")
 	}
 
 	messages := buildOpenAIMessages(task)
 	if len(messages) == 0 {
-		return nil, fmt.Errorf("openai/text/chat: missing 'messages' field")
+		return nil, invalidf("openai/text/chat: missing 'messages' field")
 	}
 
 	params := openai.ChatCompletionNewParams{
@@ -105,7 +105,7 @@ func runOpenAITextChat(ctx context.Context, c *Cortex, task Document) (*RunResul
 func runOpenAITextChatStream(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
 	credRef := task.GetString("cred")
 	if credRef == "" {
-		return nil, fmt.Errorf("openai/text/chat-stream: missing 'cred' field")
+		return nil, invalidf("openai/text/chat-stream: missing 'cred' field")
 	}
 	cred, err := c.ResolveCred(ctx, credRef)
 	if err != nil {
@@ -118,12 +118,12 @@ func runOpenAITextChatStream(ctx context.Context, c *Cortex, task Document) (*Ru
 
 	model := task.GetString("model")
 	if model == "" {
-		return nil, fmt.Errorf("openai/text/chat-stream: missing 'model' field")
+		return nil, invalidf("openai/text/chat-stream: missing 'model' field")
 	}
 
 	messages := buildOpenAIMessages(task)
 	if len(messages) == 0 {
-		return nil, fmt.Errorf("openai/text/chat-stream: missing 'messages' field")
+		return nil, invalidf("openai/text/chat-stream: missing 'messages' field")
 	}
 
 	params := openai.ChatCompletionNewParams{
@@ -14,6 +14,11 @@ type Schema struct {
 	Optional []string
 	KeyFunc  func(fields map[string]any) kv.Key
 	ValidateFn func(fields map[string]any) error // additional validation beyond required fields
+
+	// Versioned makes apply maintain a "version" field, starting at 1 and
+	// increasing every time the document is applied, so that consumers
+	// (e.g. devices) can tell whether their copy is up to date.
+	Versioned bool
 }
 
 // Validate checks that all required fields are present and non-empty,
@@ -134,6 +139,40 @@ func validateMaxTokens(fields map[string]any) error {
 	return nil
 }
 
+// validatePercent returns a validator that checks an optional field is a
+// number in 0-100.
+func validatePercent(field string) func(map[string]any) error {
+	return validateRange(field, 0, 100)
+}
+
+// validateRange returns a validator that checks an optional field is a
+// number in [lo, hi].
+func validateRange(field string, lo, hi float64) func(map[string]any) error {
+	return func(fields map[string]any) error {
+		v, ok := fields[field]
+		if !ok {
+			return nil
+		}
+		var n float64
+		switch t := v.(type) {
+		case int:
+			n = float64(t)
+		case int64:
+			n = float64(t)
+		case uint64:
+			n = float64(t)
+		case float64:
+			n = t
+		default:
+			return fmt.Errorf("field '%s' must be a number", field)
+		}
+		if n < lo || n > hi {
+			return fmt.Errorf("field '%s' must be between %v and %v, got %v", field, lo, hi, v)
+		}
+		return nil
+	}
+}
+
 // chainValidators runs multiple validators in sequence.
 func chainValidators(validators ...func(map[string]any) error) func(map[string]any) error {
 	return func(fields map[string]any) error {
@@ -0,0 +1,211 @@
+package cortex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// SpeakerVoice is the assistant voice and persona a family member prefers,
+// keyed by the voice hash voiceprint identifies them by. It is stored as a
+// "chatgear/speaker" document named after the hash:
+//
+//	kind: chatgear/speaker
+//	name: A3F8                 # voice hash, as in the label "voice:A3F8"
+//	member: grandma
+//	voice: toybox/slow-voice   # genx/tts name
+//	persona: storyteller
+//	instructions: Speak slowly and call her Nana.
+type SpeakerVoice struct {
+	// Speaker is the voice label of the speaker (e.g., "voice:A3F8").
+	Speaker string `json:"speaker"`
+
+	// Member names the speaker (e.g., "grandma").
+	Member string `json:"member,omitempty"`
+
+	// Voice is the name of the genx/tts to answer the speaker with.
+	Voice string `json:"voice,omitempty"`
+
+	// Persona is the name of the persona (agent) to talk as.
+	Persona string `json:"persona,omitempty"`
+
+	// Instructions are added to the model's instructions while the speaker
+	// talks.
+	Instructions string `json:"instructions,omitempty"`
+}
+
+// voiceLabelPrefix is the prefix of voiceprint labels ("voice:A3F8").
+const voiceLabelPrefix = "voice:"
+
+// minSpeakerHash is the shortest hash prefix SpeakerVoice falls back to: 8
+// bits, the voiceprint group level. Shorter prefixes match strangers.
+const minSpeakerHash = 2
+
+// speakerHash returns the voice hash of a voice label or hash.
+func speakerHash(speaker string) string {
+	return strings.TrimPrefix(speaker, voiceLabelPrefix)
+}
+
+// SetSpeakerVoice stores the preferred voice of v.Speaker (a voice label or
+// hash). Empty fields fall back to the gear's config.
+func (c *Cortex) SetSpeakerVoice(ctx context.Context, v *SpeakerVoice) (ApplyResult, error) {
+	fields := map[string]any{"name": speakerHash(v.Speaker)}
+	for k, s := range map[string]string{
+		"member":       v.Member,
+		"voice":        v.Voice,
+		"persona":      v.Persona,
+		"instructions": v.Instructions,
+	} {
+		if s != "" {
+			fields[k] = s
+		}
+	}
+	results, err := c.Apply(ctx, []Document{{Kind: "chatgear/speaker", Fields: fields}})
+	if err != nil {
+		return ApplyResult{}, err
+	}
+	return results[0], nil
+}
+
+// SpeakerVoice returns the preferred voice of speaker, a voice label
+// ("voice:A3F8") or hash. Like voiceprint hashes, preferences match by
+// prefix: without a preference for A3F8, those of A3F and then A3 apply. It
+// returns an error wrapping ErrNotFound if there is none.
+func (c *Cortex) SpeakerVoice(ctx context.Context, speaker string) (*SpeakerVoice, error) {
+	hash := speakerHash(speaker)
+	if !isVoiceHash(hash) {
+		return nil, invalidf("speaker voice: invalid speaker %q", speaker)
+	}
+	for n := len(hash); n >= min(minSpeakerHash, len(hash)); n-- {
+		doc, err := c.Get(ctx, "chatgear:speaker:"+hash[:n])
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("speaker voice: %w", err)
+		}
+		return &SpeakerVoice{
+			Speaker:      voiceLabelPrefix + hash,
+			Member:       doc.GetString("member"),
+			Voice:        doc.GetString("voice"),
+			Persona:      doc.GetString("persona"),
+			Instructions: doc.GetString("instructions"),
+		}, nil
+	}
+	return nil, fmt.Errorf("speaker voice: %w: %s", ErrNotFound, speaker)
+}
+
+// SpeakerHook switches the assistant's voice and persona when the active
+// speaker changes, so that each family member is answered the way they
+// prefer.
+//
+// Feed it the speaker labels the voiceprint transformer sets on audio
+// chunks (Ctrl.Label). When the speaker changes, it resolves their
+// SpeakerVoice, filling what the preference leaves empty from the gear's
+// chatgear/config, and calls the switch function if the voice, persona or
+// instructions differ from the active ones.
+type SpeakerHook struct {
+	c        *Cortex
+	gear     string
+	onSwitch func(context.Context, *SpeakerVoice) error
+
+	mu      sync.Mutex
+	speaker string
+	active  *SpeakerVoice
+}
+
+// NewSpeakerHook creates a SpeakerHook for the gear (the name of its
+// chatgear/config, or empty for none). onSwitch applies a new voice, e.g.
+// by changing the TTS voice of the pipeline; it is called from Observe.
+func (c *Cortex) NewSpeakerHook(gear string, onSwitch func(context.Context, *SpeakerVoice) error) *SpeakerHook {
+	return &SpeakerHook{c: c, gear: gear, onSwitch: onSwitch}
+}
+
+// Observe reports the current speaker label. Empty labels (unknown
+// speakers) keep the active voice.
+func (h *SpeakerHook) Observe(ctx context.Context, speaker string) error {
+	if speaker == "" {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if speaker == h.speaker {
+		return nil
+	}
+
+	v, err := h.c.SpeakerVoice(ctx, speaker)
+	if errors.Is(err, ErrNotFound) {
+		v = &SpeakerVoice{Speaker: voiceLabelPrefix + speakerHash(speaker)}
+	} else if err != nil {
+		return err
+	}
+	if err := h.fillFromGear(ctx, v); err != nil {
+		return err
+	}
+	h.speaker = speaker
+
+	if a := h.active; a != nil && a.Voice == v.Voice && a.Persona == v.Persona && a.Instructions == v.Instructions {
+		h.active = v
+		return nil
+	}
+	h.active = v
+	return h.onSwitch(ctx, v)
+}
+
+// fillFromGear fills the empty voice and persona of v from the gear config.
+func (h *SpeakerHook) fillFromGear(ctx context.Context, v *SpeakerVoice) error {
+	if h.gear == "" || (v.Voice != "" && v.Persona != "") {
+		return nil
+	}
+	doc, err := h.c.Get(ctx, "chatgear:config:"+h.gear)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("speaker hook: %w", err)
+	}
+	if v.Voice == "" {
+		v.Voice = doc.GetString("voice")
+	}
+	if v.Persona == "" {
+		v.Persona = doc.GetString("persona")
+	}
+	return nil
+}
+
+// Active returns the voice of the active speaker, or nil before the first
+// speaker is observed.
+func (h *SpeakerHook) Active() *SpeakerVoice {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.active == nil {
+		return nil
+	}
+	v := *h.active
+	return &v
+}
+
+// isVoiceHash reports whether s is a voiceprint hash: uppercase hex.
+func isVoiceHash(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'A' || r > 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// validateVoiceHash checks that the name of a speaker document is a voice
+// hash.
+func validateVoiceHash(fields map[string]any) error {
+	name, _ := fields["name"].(string)
+	if !isVoiceHash(name) {
+		return fmt.Errorf("field 'name' must be a voice hash in uppercase hex (e.g. A3F8), got %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package cortex
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSpeakerVoicePrefixMatch(t *testing.T) {
+	c := newTestCortex(t)
+	ctx := context.Background()
+
+	for _, v := range []*SpeakerVoice{
+		{Speaker: "voice:A3F8", Member: "grandma", Voice: "toybox/slow"},
+		{Speaker: "B7", Member: "kids", Persona: "storyteller"},
+	} {
+		if _, err := c.SetSpeakerVoice(ctx, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v, err := c.SpeakerVoice(ctx, "voice:A3F8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Member != "grandma" || v.Voice != "toybox/slow" || v.Speaker != "voice:A3F8" {
+		t.Fatalf("unexpected voice: %+v", v)
+	}
+
+	// B7C1 falls back to the 8-bit group B7
+	v, err = c.SpeakerVoice(ctx, "voice:B7C1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Member != "kids" || v.Speaker != "voice:B7C1" {
+		t.Fatalf("unexpected voice: %+v", v)
+	}
+
+	// A prefix shorter than 8 bits does not match
+	if _, err := c.SpeakerVoice(ctx, "voice:A000"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestApplySpeakerBadName(t *testing.T) {
+	c := newTestCortex(t)
+	_, err := c.Apply(context.Background(), []Document{{
+		Kind: "chatgear/speaker", Fields: map[string]any{"name": "voice:a3f8"},
+	}})
+	if !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid, got %v", err)
+	}
+}
+
+func TestSpeakerHookSwitches(t *testing.T) {
+	c := newTestCortex(t)
+	ctx := context.Background()
+
+	if _, err := c.Apply(ctx, []Document{{
+		Kind:   "chatgear/config",
+		Fields: map[string]any{"name": "gear-001", "persona": "lele", "voice": "toybox/voice"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SetSpeakerVoice(ctx, &SpeakerVoice{
+		Speaker:      "voice:A3F8",
+		Voice:        "toybox/slow",
+		Instructions: "Speak slowly.",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var switched []*SpeakerVoice
+	h := c.NewSpeakerHook("gear-001", func(_ context.Context, v *SpeakerVoice) error {
+		switched = append(switched, v)
+		return nil
+	})
+
+	for _, speaker := range []string{
+		"voice:A3F8",
+		"voice:A3F8", // same speaker
+		"",           // unknown speaker keeps the voice
+		"voice:1234", // no preference: the gear's voice
+		"voice:5678", // no preference either: nothing changes
+		"voice:A3F8",
+	} {
+		if err := h.Observe(ctx, speaker); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(switched) != 3 {
+		t.Fatalf("expected 3 switches, got %d: %+v", len(switched), switched)
+	}
+	if v := switched[0]; v.Voice != "toybox/slow" || v.Persona != "lele" || v.Instructions != "Speak slowly." {
+		t.Errorf("switch 0: %+v", v)
+	}
+	if v := switched[1]; v.Voice != "toybox/voice" || v.Persona != "lele" || v.Instructions != "" {
+		t.Errorf("switch 1: %+v", v)
+	}
+	if v := h.Active(); v == nil || v.Speaker != "voice:A3F8" {
+		t.Errorf("active: %+v", v)
+	}
+}
@@ -0,0 +1,236 @@
+package cortex
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// TaskStatus is the state of a task started with Cortex.Start.
+type TaskStatus string
+
+const (
+	TaskRunning   TaskStatus = "running"
+	TaskSucceeded TaskStatus = "succeeded"
+	TaskFailed    TaskStatus = "failed"
+	TaskCanceled  TaskStatus = "canceled"
+)
+
+// maxFinishedTasks is the number of finished tasks a Cortex keeps for
+// inspection; older ones are forgotten.
+const maxFinishedTasks = 100
+
+// TaskInfo is the state of a task.
+type TaskInfo struct {
+	ID        string     `json:"id"`
+	Kind      string     `json:"kind"`
+	Name      string     `json:"name,omitempty"`
+	Status    TaskStatus `json:"status"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   time.Time  `json:"ended_at,omitzero"`
+	Error     string     `json:"error,omitempty"`
+	Result    *RunResult `json:"result,omitempty"`
+}
+
+// TaskLogEntry is a line of a task's log.
+type TaskLogEntry struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// Task is the handle of a task running in the background, e.g. a video
+// generation or voice clone training that takes minutes. Run handlers
+// report progress with TaskLogf.
+type Task struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu   sync.Mutex
+	info TaskInfo
+	logs []TaskLogEntry
+	err  error
+}
+
+// ID returns the task ID.
+func (t *Task) ID() string { return t.info.ID }
+
+// Info returns the current state of the task.
+func (t *Task) Info() TaskInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.info
+}
+
+// Logs returns the log of the task.
+func (t *Task) Logs() []TaskLogEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.logs)
+}
+
+// Cancel cancels the task. It returns at once; the task ends when its
+// handler returns.
+func (t *Task) Cancel() { t.cancel() }
+
+// Done returns a channel that is closed when the task ends.
+func (t *Task) Done() <-chan struct{} { return t.done }
+
+// Wait waits for the task to end and returns its result.
+func (t *Task) Wait(ctx context.Context) (*RunResult, error) {
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.info.Result, t.err
+}
+
+func (t *Task) logf(format string, args ...any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.logs = append(t.logs, TaskLogEntry{Time: time.Now(), Message: fmt.Sprintf(format, args...)})
+}
+
+type taskCtxKey struct{}
+
+// TaskLogf appends a line to the log of the task running with ctx, if
+// any. Run handlers call it to report progress.
+func TaskLogf(ctx context.Context, format string, args ...any) {
+	if t, ok := ctx.Value(taskCtxKey{}).(*Task); ok {
+		t.logf(format, args...)
+	}
+}
+
+// Start runs a task document in the background, like Run, and returns its
+// handle at once. The task keeps running after ctx is done, with its
+// values (e.g., the subject), until it ends or is cancelled. The kind and
+// authorization are checked before Start returns.
+func (c *Cortex) Start(ctx context.Context, task Document) (*Task, error) {
+	handler, ok := runHandlers[task.Kind]
+	if !ok {
+		return nil, invalidf("unknown run kind %q; no handler registered", task.Kind)
+	}
+	if err := c.authorize(ctx, ActionRun, task.Kind, task.Name()); err != nil {
+		return nil, err
+	}
+
+	var id [8]byte
+	rand.Read(id[:])
+	t := &Task{
+		done: make(chan struct{}),
+		info: TaskInfo{
+			ID:        hex.EncodeToString(id[:]),
+			Kind:      task.Kind,
+			Name:      task.Name(),
+			Status:    TaskRunning,
+			StartedAt: time.Now(),
+		},
+	}
+	runCtx, cancel := context.WithCancel(context.WithValue(context.WithoutCancel(ctx), taskCtxKey{}, t))
+	t.cancel = cancel
+
+	c.tasksMu.Lock()
+	if c.tasks == nil {
+		c.tasks = make(map[string]*Task)
+	}
+	c.tasks[t.info.ID] = t
+	c.tasksMu.Unlock()
+
+	t.logf("started %s", task.Kind)
+	go func() {
+		defer cancel()
+		result, err := handler(runCtx, c, task)
+		c.finishTask(runCtx, t, result, err)
+	}()
+	return t, nil
+}
+
+// finishTask records the outcome of t.
+func (c *Cortex) finishTask(ctx context.Context, t *Task, result *RunResult, err error) {
+	t.mu.Lock()
+	t.info.EndedAt = time.Now()
+	switch {
+	case err != nil && ctx.Err() != nil:
+		t.info.Status = TaskCanceled
+	case err != nil:
+		t.info.Status = TaskFailed
+	default:
+		t.info.Status = TaskSucceeded
+	}
+	if err != nil {
+		t.info.Error = err.Error()
+	}
+	t.info.Result = result
+	t.err = err
+	status := t.info.Status
+	t.mu.Unlock()
+
+	if err != nil {
+		t.logf("%s: %v", status, err)
+	} else {
+		t.logf("%s", status)
+	}
+	close(t.done)
+	c.pruneTasks()
+}
+
+// cancelTasks cancels the running tasks.
+func (c *Cortex) cancelTasks() {
+	c.tasksMu.Lock()
+	defer c.tasksMu.Unlock()
+	for _, t := range c.tasks {
+		t.Cancel()
+	}
+}
+
+// pruneTasks forgets the oldest finished tasks beyond maxFinishedTasks.
+func (c *Cortex) pruneTasks() {
+	c.tasksMu.Lock()
+	defer c.tasksMu.Unlock()
+	var finished []TaskInfo
+	for _, t := range c.tasks {
+		if info := t.Info(); info.Status != TaskRunning {
+			finished = append(finished, info)
+		}
+	}
+	if len(finished) <= maxFinishedTasks {
+		return
+	}
+	slices.SortFunc(finished, func(a, b TaskInfo) int { return a.EndedAt.Compare(b.EndedAt) })
+	for _, info := range finished[:len(finished)-maxFinishedTasks] {
+		delete(c.tasks, info.ID)
+	}
+}
+
+// Task returns the task with the given ID, or an error wrapping
+// ErrNotFound.
+func (c *Cortex) Task(id string) (*Task, error) {
+	c.tasksMu.Lock()
+	defer c.tasksMu.Unlock()
+	t, ok := c.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("task %q: %w", id, ErrNotFound)
+	}
+	return t, nil
+}
+
+// Tasks returns the running and recently finished tasks, newest first.
+func (c *Cortex) Tasks() []TaskInfo {
+	c.tasksMu.Lock()
+	infos := make([]TaskInfo, 0, len(c.tasks))
+	for _, t := range c.tasks {
+		infos = append(infos, t.Info())
+	}
+	c.tasksMu.Unlock()
+	slices.SortFunc(infos, func(a, b TaskInfo) int {
+		return cmp.Or(b.StartedAt.Compare(a.StartedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return infos
+}
@@ -0,0 +1,240 @@
+package cortex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// maxTaskBody limits the size of a task document posted to a TaskServer.
+const maxTaskBody = 1 << 20
+
+// TaskServer is an http.Handler exposing the tasks of a Cortex, so that
+// tasks started by one CLI invocation can be inspected by the next while
+// the serve daemon runs them:
+//
+//	POST /api/tasks              start the task document (YAML or JSON) in
+//	                             the body; TaskInfo
+//	GET  /api/tasks              []TaskInfo, newest first
+//	GET  /api/tasks/{id}         TaskInfo
+//	GET  /api/tasks/{id}/logs    []TaskLogEntry
+//	POST /api/tasks/{id}/cancel  TaskInfo
+//
+// Errors are {"error": "..."} with status 400 for ErrInvalid, 403 for
+// ErrForbidden and 404 for ErrNotFound. Tasks run with the subject of the
+// request context, if any. TaskClient is the client.
+type TaskServer struct {
+	cortex *Cortex
+	mux    *http.ServeMux
+}
+
+// NewTaskServer creates a TaskServer for the tasks of c.
+func NewTaskServer(c *Cortex) *TaskServer {
+	s := &TaskServer{cortex: c, mux: http.NewServeMux()}
+	s.mux.HandleFunc("POST /api/tasks", s.serveStart)
+	s.mux.HandleFunc("GET /api/tasks", s.serveList)
+	s.mux.HandleFunc("GET /api/tasks/{id}", s.serveStatus)
+	s.mux.HandleFunc("GET /api/tasks/{id}/logs", s.serveLogs)
+	s.mux.HandleFunc("POST /api/tasks/{id}/cancel", s.serveCancel)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *TaskServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *TaskServer) serveStart(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxTaskBody))
+	if err != nil {
+		writeTaskError(w, err)
+		return
+	}
+	docs, err := ParseDocuments(data)
+	if err != nil {
+		writeTaskError(w, invalid(err))
+		return
+	}
+	if len(docs) != 1 {
+		writeTaskError(w, invalidf("expected exactly 1 task document, got %d", len(docs)))
+		return
+	}
+	t, err := s.cortex.Start(r.Context(), docs[0])
+	if err != nil {
+		writeTaskError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	writeDashboardJSON(w, t.Info())
+}
+
+func (s *TaskServer) serveList(w http.ResponseWriter, r *http.Request) {
+	writeDashboardJSON(w, s.cortex.Tasks())
+}
+
+func (s *TaskServer) serveStatus(w http.ResponseWriter, r *http.Request) {
+	t, err := s.cortex.Task(r.PathValue("id"))
+	if err != nil {
+		writeTaskError(w, err)
+		return
+	}
+	writeDashboardJSON(w, t.Info())
+}
+
+func (s *TaskServer) serveLogs(w http.ResponseWriter, r *http.Request) {
+	t, err := s.cortex.Task(r.PathValue("id"))
+	if err != nil {
+		writeTaskError(w, err)
+		return
+	}
+	writeDashboardJSON(w, t.Logs())
+}
+
+func (s *TaskServer) serveCancel(w http.ResponseWriter, r *http.Request) {
+	t, err := s.cortex.Task(r.PathValue("id"))
+	if err != nil {
+		writeTaskError(w, err)
+		return
+	}
+	info := t.Info()
+	if err := s.cortex.authorize(r.Context(), ActionRun, info.Kind, info.Name); err != nil {
+		writeTaskError(w, err)
+		return
+	}
+	t.Cancel()
+	writeDashboardJSON(w, t.Info())
+}
+
+func writeTaskError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// TaskClient calls the TaskServer of a serve daemon.
+type TaskClient struct {
+	// BaseURL is the URL of the server, e.g. "http://127.0.0.1:7071".
+	BaseURL string
+
+	// HTTPClient is used for requests. If nil, http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Start starts a task on the server.
+func (c *TaskClient) Start(ctx context.Context, task Document) (*TaskInfo, error) {
+	fields := make(map[string]any, len(task.Fields)+1)
+	for k, v := range task.Fields {
+		fields[k] = v
+	}
+	fields["kind"] = task.Kind
+	body, err := yaml.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("marshal task: %w", err)
+	}
+	var info TaskInfo
+	if err := c.do(ctx, http.MethodPost, "/api/tasks", body, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// List returns the running and recently finished tasks, newest first.
+func (c *TaskClient) List(ctx context.Context) ([]TaskInfo, error) {
+	var infos []TaskInfo
+	if err := c.do(ctx, http.MethodGet, "/api/tasks", nil, &infos); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+// Status returns the state of a task.
+func (c *TaskClient) Status(ctx context.Context, id string) (*TaskInfo, error) {
+	var info TaskInfo
+	if err := c.do(ctx, http.MethodGet, "/api/tasks/"+url.PathEscape(id), nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Logs returns the log of a task.
+func (c *TaskClient) Logs(ctx context.Context, id string) ([]TaskLogEntry, error) {
+	var logs []TaskLogEntry
+	if err := c.do(ctx, http.MethodGet, "/api/tasks/"+url.PathEscape(id)+"/logs", nil, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// Cancel cancels a task and returns its state.
+func (c *TaskClient) Cancel(ctx context.Context, id string) (*TaskInfo, error) {
+	var info TaskInfo
+	if err := c.do(ctx, http.MethodPost, "/api/tasks/"+url.PathEscape(id)+"/cancel", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// do sends a request and decodes the JSON response into out. Error
+// responses are mapped back to ErrInvalid, ErrForbidden and ErrNotFound.
+func (c *TaskClient) do(ctx context.Context, method, path string, body []byte, out any) error {
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, rd)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/yaml")
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("task server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&e)
+		if e.Error == "" {
+			e.Error = resp.Status
+		}
+		switch resp.StatusCode {
+		case http.StatusBadRequest:
+			return invalid(errors.New(e.Error))
+		case http.StatusForbidden:
+			return fmt.Errorf("%s: %w", strings.TrimSuffix(e.Error, ": "+ErrForbidden.Error()), ErrForbidden)
+		case http.StatusNotFound:
+			return fmt.Errorf("%s: %w", strings.TrimSuffix(e.Error, ": "+ErrNotFound.Error()), ErrNotFound)
+		}
+		return fmt.Errorf("task server: %s", e.Error)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("task server: decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package cortex
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func init() {
+	RegisterRunHandler("test/echo", func(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
+		TaskLogf(ctx, "echo %s", task.GetString("text"))
+		return &RunResult{Kind: task.Kind, Status: "ok", Text: task.GetString("text")}, nil
+	})
+	RegisterRunHandler("test/block", func(ctx context.Context, c *Cortex, task Document) (*RunResult, error) {
+		TaskLogf(ctx, "waiting")
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+}
+
+func echoTask(text string) Document {
+	return Document{Kind: "test/echo", Fields: map[string]any{"name": "e", "text": text}}
+}
+
+func waitTask(t *testing.T, task *Task) (*RunResult, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := task.Wait(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("task did not end")
+	}
+	return result, err
+}
+
+func TestStartTask(t *testing.T) {
+	c := newTestCortex(t)
+
+	task, err := c.Start(context.Background(), echoTask("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := waitTask(t, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Text != "hi" {
+		t.Errorf("Text = %q, want hi", result.Text)
+	}
+
+	info := task.Info()
+	if info.Status != TaskSucceeded || info.Kind != "test/echo" || info.Name != "e" {
+		t.Errorf("info = %+v", info)
+	}
+	if info.EndedAt.IsZero() {
+		t.Error("EndedAt not set")
+	}
+
+	var msgs []string
+	for _, e := range task.Logs() {
+		msgs = append(msgs, e.Message)
+	}
+	want := []string{"started test/echo", "echo hi", "succeeded"}
+	if len(msgs) != len(want) {
+		t.Fatalf("logs = %q, want %q", msgs, want)
+	}
+	for i := range want {
+		if msgs[i] != want[i] {
+			t.Errorf("logs[%d] = %q, want %q", i, msgs[i], want[i])
+		}
+	}
+
+	got, err := c.Task(task.ID())
+	if err != nil || got != task {
+		t.Errorf("Task(%q) = %v, %v", task.ID(), got, err)
+	}
+	if infos := c.Tasks(); len(infos) != 1 || infos[0].ID != task.ID() {
+		t.Errorf("Tasks() = %+v", infos)
+	}
+}
+
+func TestStartTaskCancel(t *testing.T) {
+	c := newTestCortex(t)
+
+	// The task outlives the context it was started with.
+	ctx, cancel := context.WithCancel(context.Background())
+	task, err := c.Start(ctx, Document{Kind: "test/block", Fields: map[string]any{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	select {
+	case <-task.Done():
+		t.Fatal("task ended with its start context")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	task.Cancel()
+	if _, err := waitTask(t, task); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if info := task.Info(); info.Status != TaskCanceled {
+		t.Errorf("Status = %s, want canceled", info.Status)
+	}
+}
+
+func TestStartTaskCloseCancels(t *testing.T) {
+	c := newTestCortex(t)
+	task, err := c.Start(context.Background(), Document{Kind: "test/block", Fields: map[string]any{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	waitTask(t, task)
+	if info := task.Info(); info.Status != TaskCanceled {
+		t.Errorf("Status = %s, want canceled", info.Status)
+	}
+}
+
+func TestStartTaskErrors(t *testing.T) {
+	c := newTestCortex(t)
+	if _, err := c.Start(context.Background(), Document{Kind: "test/nope"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("unknown kind: err = %v, want ErrInvalid", err)
+	}
+	if _, err := c.Task("nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Task: err = %v, want ErrNotFound", err)
+	}
+
+	c = newAuthzCortex(t, testRules)
+	ctxA := WithSubject(context.Background(), teamA)
+	if _, err := c.Start(ctxA, echoTask("hi")); !errors.Is(err, ErrForbidden) {
+		t.Errorf("team-a start test/echo: err = %v, want ErrForbidden", err)
+	}
+}
+
+func TestTaskServer(t *testing.T) {
+	c := newTestCortex(t)
+	srv := httptest.NewServer(NewTaskServer(c))
+	defer srv.Close()
+	client := &TaskClient{BaseURL: srv.URL}
+	ctx := context.Background()
+
+	info, err := client.Start(ctx, echoTask("over http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, err := c.Task(info.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitTask(t, task)
+
+	got, err := client.Status(ctx, info.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != TaskSucceeded || got.Result == nil || got.Result.Text != "over http" {
+		t.Errorf("Status = %+v", got)
+	}
+	logs, err := client.Logs(ctx, info.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 3 || logs[1].Message != "echo over http" {
+		t.Errorf("Logs = %+v", logs)
+	}
+	infos, err := client.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].ID != info.ID {
+		t.Errorf("List = %+v", infos)
+	}
+
+	block, err := client.Start(ctx, Document{Kind: "test/block", Fields: map[string]any{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Cancel(ctx, block.ID); err != nil {
+		t.Fatal(err)
+	}
+	task, _ = c.Task(block.ID)
+	waitTask(t, task)
+	if got, _ := client.Status(ctx, block.ID); got.Status != TaskCanceled {
+		t.Errorf("Status after cancel = %s, want canceled", got.Status)
+	}
+
+	if _, err := client.Status(ctx, "nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Status(nope): err = %v, want ErrNotFound", err)
+	}
+	if _, err := client.Start(ctx, Document{Kind: "test/nope"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Start(test/nope): err = %v, want ErrInvalid", err)
+	}
+}
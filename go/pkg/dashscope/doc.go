@@ -25,6 +25,61 @@
 //	    // Handle event
 //	}
 //
+// # Events
+//
+// RealtimeEvent carries the fields of all server events. Typed returns the
+// event as a type holding only the fields of its Type, for a type switch:
+//
+//	switch ev := event.Typed().(type) {
+//	case *dashscope.ResponseAudioDeltaEvent:
+//	    play(ev.Audio)
+//	case *dashscope.ResponseOutputItemEvent:
+//	    log.Printf("item %s: %s", ev.Item.ID, ev.Item.Type)
+//	case *dashscope.RealtimeEvent:
+//	    // Not known to this package: decode ev.Raw
+//	}
+//
+// Raw holds the JSON message of every event, so event types and fields the
+// package does not parse yet remain accessible.
+//
+// # Function Calling
+//
+// Declare tools in the session config. When the model calls one, a
+// response.function_call_arguments.done event carries the complete call;
+// run the function and send its output back with SubmitToolResult, which
+// also asks the model to respond:
+//
+//	session.UpdateSession(&dashscope.SessionConfig{
+//	    Tools: []dashscope.Tool{{
+//	        Name:        "get_weather",
+//	        Description: "Get the current weather for a city",
+//	        Parameters: map[string]interface{}{
+//	            "type": "object",
+//	            "properties": map[string]interface{}{
+//	                "city": map[string]interface{}{"type": "string"},
+//	            },
+//	        },
+//	    }},
+//	})
+//
+//	for event, err := range session.Events() {
+//	    // ...
+//	    if event.Type == dashscope.EventTypeResponseFunctionCallArgumentsDone {
+//	        result := runTool(event.FunctionCall.Name, event.FunctionCall.Arguments)
+//	        session.SubmitToolResult(event.FunctionCall.CallID, result)
+//	    }
+//	}
+//
+// # Reconnecting
+//
+// A session records its configuration and conversation (transcripts,
+// replies, function calls and outputs). When the connection drops, Resume
+// connects a new session and replays them, so the conversation continues:
+//
+//	if dashscope.IsAbnormalClosure(err) {
+//	    session, err = client.Realtime.Resume(ctx, session.State())
+//	}
+//
 // # Authentication
 //
 // DashScope supports API Key authentication:
@@ -3,35 +3,44 @@ package dashscope
 // Event types for realtime communication.
 const (
 	// Client events
-	EventTypeSessionUpdate       = "session.update"
-	EventTypeInputAudioAppend    = "input_audio_buffer.append"
-	EventTypeInputAudioCommit    = "input_audio_buffer.commit"
-	EventTypeInputAudioClear     = "input_audio_buffer.clear"
-	EventTypeResponseCreate      = "response.create"
-	EventTypeResponseCancel      = "response.cancel"
-	EventTypeTranscriptionUpdate = "transcription.update"
+	EventTypeSessionUpdate          = "session.update"
+	EventTypeInputAudioAppend       = "input_audio_buffer.append"
+	EventTypeInputAudioCommit       = "input_audio_buffer.commit"
+	EventTypeInputAudioClear        = "input_audio_buffer.clear"
+	EventTypeResponseCreate         = "response.create"
+	EventTypeResponseCancel         = "response.cancel"
+	EventTypeTranscriptionUpdate    = "transcription.update"
+	EventTypeConversationItemCreate = "conversation.item.create"
+	EventTypeInputImageAppend       = "input_image_buffer.append"
+	EventTypeSessionFinish          = "session.finish"
 
 	// Server events
-	EventTypeSessionCreated          = "session.created"
-	EventTypeSessionUpdated          = "session.updated"
-	EventTypeInputAudioCommitted     = "input_audio_buffer.committed"
-	EventTypeInputAudioCleared       = "input_audio_buffer.cleared"
-	EventTypeInputSpeechStarted      = "input_audio_buffer.speech_started"
-	EventTypeInputSpeechStopped      = "input_audio_buffer.speech_stopped"
-	EventTypeResponseCreated         = "response.created"
-	EventTypeResponseDone            = "response.done"
-	EventTypeResponseOutputAdded     = "response.output_item.added"
-	EventTypeResponseOutputDone      = "response.output_item.done"
-	EventTypeResponseContentAdded    = "response.content_part.added"
-	EventTypeResponseContentDone     = "response.content_part.done"
-	EventTypeResponseTextDelta       = "response.text.delta"
-	EventTypeResponseTextDone        = "response.text.done"
-	EventTypeResponseAudioDelta      = "response.audio.delta"
-	EventTypeResponseAudioDone       = "response.audio.done"
-	EventTypeResponseTranscriptDelta          = "response.audio_transcript.delta"
-	EventTypeResponseTranscriptDone           = "response.audio_transcript.done"
-	EventTypeInputAudioTranscriptionCompleted = "conversation.item.input_audio_transcription.completed"
-	EventTypeError                            = "error"
+	EventTypeSessionCreated                     = "session.created"
+	EventTypeSessionUpdated                     = "session.updated"
+	EventTypeSessionFinished                    = "session.finished"
+	EventTypeInputAudioCommitted                = "input_audio_buffer.committed"
+	EventTypeInputAudioCleared                  = "input_audio_buffer.cleared"
+	EventTypeInputSpeechStarted                 = "input_audio_buffer.speech_started"
+	EventTypeInputSpeechStopped                 = "input_audio_buffer.speech_stopped"
+	EventTypeResponseCreated                    = "response.created"
+	EventTypeResponseDone                       = "response.done"
+	EventTypeResponseOutputAdded                = "response.output_item.added"
+	EventTypeResponseOutputDone                 = "response.output_item.done"
+	EventTypeResponseContentAdded               = "response.content_part.added"
+	EventTypeResponseContentDone                = "response.content_part.done"
+	EventTypeResponseTextDelta                  = "response.text.delta"
+	EventTypeResponseTextDone                   = "response.text.done"
+	EventTypeResponseAudioDelta                 = "response.audio.delta"
+	EventTypeResponseAudioDone                  = "response.audio.done"
+	EventTypeResponseTranscriptDelta            = "response.audio_transcript.delta"
+	EventTypeResponseTranscriptDone             = "response.audio_transcript.done"
+	EventTypeConversationItemCreated            = "conversation.item.created"
+	EventTypeInputAudioTranscriptionCompleted   = "conversation.item.input_audio_transcription.completed"
+	EventTypeInputAudioTranscriptionFailed      = "conversation.item.input_audio_transcription.failed"
+	EventTypeResponseFunctionCallArgumentsDelta = "response.function_call_arguments.delta"
+	EventTypeResponseFunctionCallArgumentsDone  = "response.function_call_arguments.done"
+	EventTypeRateLimitsUpdated                  = "rate_limits.updated"
+	EventTypeError                              = "error"
 
 	// DashScope-specific: "choices" format response (different from OpenAI Realtime)
 	EventTypeChoicesResponse = "choices"
@@ -64,6 +73,9 @@ type RealtimeEvent struct {
 	// AudioBase64 is the raw base64 audio from JSON.
 	AudioBase64 string `json:"audio,omitempty"`
 
+	// Text contains the complete text (for response.text.done).
+	Text string `json:"text,omitempty"`
+
 	// Transcript contains transcript text (for transcript completion events).
 	Transcript string `json:"transcript,omitempty"`
 
@@ -74,17 +86,48 @@ type RealtimeEvent struct {
 	// ItemID is the item identifier (for item events).
 	ItemID string `json:"item_id,omitempty"`
 
+	// PreviousItemID is the ID of the preceding item (for
+	// input_audio_buffer.committed and conversation.item.created).
+	PreviousItemID string `json:"previous_item_id,omitempty"`
+
+	// Item contains the item (for conversation.item.created and
+	// response.output_item.* events).
+	Item *OutputItem `json:"item,omitempty"`
+
+	// Part contains the content part (for response.content_part.* events).
+	Part *ContentPart `json:"part,omitempty"`
+
+	// AudioStartMs is where speech started in the input audio (for
+	// input_audio_buffer.speech_started).
+	AudioStartMs int `json:"audio_start_ms,omitempty"`
+
+	// AudioEndMs is where speech stopped in the input audio (for
+	// input_audio_buffer.speech_stopped).
+	AudioEndMs int `json:"audio_end_ms,omitempty"`
+
 	// OutputIndex is the output index (for content events).
 	OutputIndex int `json:"output_index,omitempty"`
 
 	// ContentIndex is the content index (for content events).
 	ContentIndex int `json:"content_index,omitempty"`
 
-	// Error contains error information (for error events).
+	// FunctionCall contains the function call (for response.function_call_arguments.*
+	// events and function_call items in response.output_item.* events).
+	FunctionCall *FunctionCall `json:"function_call,omitempty"`
+
+	// Error contains error information (for error and
+	// conversation.item.input_audio_transcription.failed events).
 	Error *EventError `json:"error,omitempty"`
 
 	// Usage contains usage statistics (for response.done).
 	Usage *UsageStats `json:"usage,omitempty"`
+
+	// RateLimits contains the current rate limits (for rate_limits.updated).
+	RateLimits []RateLimit `json:"rate_limits,omitempty"`
+
+	// Raw is the original JSON message. It is set for every event, so the
+	// data of event types this package does not know is still accessible.
+	Raw []byte `json:"-"`
 }
 
 // ResponseInfo contains response state information.
@@ -105,11 +148,14 @@ type StatusDetail struct {
 
 // OutputItem represents an output item in a response.
 type OutputItem struct {
-	ID      string        `json:"id,omitempty"`
-	Type    string        `json:"type,omitempty"`
-	Role    string        `json:"role,omitempty"`
-	Status  string        `json:"status,omitempty"`
-	Content []ContentPart `json:"content,omitempty"`
+	ID        string        `json:"id,omitempty"`
+	Type      string        `json:"type,omitempty"`
+	Role      string        `json:"role,omitempty"`
+	Status    string        `json:"status,omitempty"`
+	Content   []ContentPart `json:"content,omitempty"`
+	CallID    string        `json:"call_id,omitempty"`   // for function_call
+	Name      string        `json:"name,omitempty"`      // for function_call
+	Arguments string        `json:"arguments,omitempty"` // for function_call
 }
 
 // ContentPart represents a part of content.
@@ -120,6 +166,14 @@ type ContentPart struct {
 	Transcript string `json:"transcript,omitempty"`
 }
 
+// RateLimit is the state of one rate limit.
+type RateLimit struct {
+	Name         string  `json:"name,omitempty"` // "requests" or "tokens"
+	Limit        int     `json:"limit,omitempty"`
+	Remaining    int     `json:"remaining,omitempty"`
+	ResetSeconds float64 `json:"reset_seconds,omitempty"`
+}
+
 // EventError contains error information from error events.
 type EventError struct {
 	Type    string `json:"type,omitempty"`
@@ -83,6 +83,7 @@ type RealtimeSession struct {
 	eventsCh  chan eventOrError
 	closeOnce sync.Once
 	mu        sync.Mutex
+	recorder  sessionRecorder
 }
 
 type eventOrError struct {
@@ -138,12 +139,30 @@ func (s *RealtimeSession) UpdateSession(config *SessionConfig) error {
 		}
 		sessionConfig["turn_detection"] = turnDetection
 	}
+	if len(config.Tools) > 0 {
+		tools := make([]Tool, len(config.Tools))
+		for i, t := range config.Tools {
+			if t.Type == "" {
+				t.Type = "function"
+			}
+			tools[i] = t
+		}
+		sessionConfig["tools"] = tools
+	}
+	if config.ToolChoice != "" {
+		sessionConfig["tool_choice"] = config.ToolChoice
+	}
 
-	return s.sendEvent(map[string]interface{}{
+	err := s.sendEvent(map[string]interface{}{
 		"event_id": generateEventID(),
 		"type":     "session.update",
 		"session":  sessionConfig,
 	})
+	if err != nil {
+		return err
+	}
+	s.recorder.setSession(config)
+	return nil
 }
 
 // AppendAudio sends audio data to the input audio buffer.
@@ -242,6 +261,27 @@ func (s *RealtimeSession) CancelResponse() error {
 	})
 }
 
+// SubmitToolResult returns the output of a function call to the model and
+// requests a new response so the model can continue with the result.
+// callID is FunctionCall.CallID from the function call event; output is
+// usually a JSON string.
+func (s *RealtimeSession) SubmitToolResult(callID, output string) error {
+	err := s.sendEvent(map[string]interface{}{
+		"event_id": generateEventID(),
+		"type":     EventTypeConversationItemCreate,
+		"item": map[string]interface{}{
+			"type":    ItemTypeFunctionCallOutput,
+			"call_id": callID,
+			"output":  output,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	s.recorder.add(ConversationItem{Type: ItemTypeFunctionCallOutput, CallID: callID, Output: output})
+	return s.CreateResponse(nil)
+}
+
 // FinishSession sends a session.finish event to gracefully end the session.
 func (s *RealtimeSession) FinishSession() error {
 	return s.sendEvent(map[string]interface{}{
@@ -380,6 +420,7 @@ func (s *RealtimeSession) readLoop() {
 			if err := json.Unmarshal(message, &errorData); err == nil {
 				event := &RealtimeEvent{
 					Type: EventTypeError,
+					Raw:  message,
 					Error: &EventError{
 						Type:    errorData.Error.Type,
 						Code:    errorData.Error.Code,
@@ -403,6 +444,7 @@ func (s *RealtimeSession) readLoop() {
 			if eventType == "session.created" && event.Session != nil {
 				s.sessionID = event.Session.ID
 			}
+			s.recorder.observe(event)
 
 			select {
 			case <-s.closeCh:
@@ -417,6 +459,7 @@ func (s *RealtimeSession) readLoop() {
 func (s *RealtimeSession) parseEvent(eventType string, message []byte) *RealtimeEvent {
 	event := &RealtimeEvent{
 		Type: eventType,
+		Raw:  message,
 	}
 
 	// Check for DashScope "choices" format (different from OpenAI Realtime events)
@@ -458,79 +501,96 @@ func (s *RealtimeSession) parseEvent(eventType string, message []byte) *Realtime
 		return event
 	}
 
-	// Standard event format
-	switch eventType {
-	case EventTypeSessionCreated, EventTypeSessionUpdated:
-		var data struct {
-			Session struct {
-				ID string `json:"id"`
-			} `json:"session"`
-		}
-		if err := json.Unmarshal(message, &data); err == nil && data.Session.ID != "" {
-			event.Session = &SessionInfo{ID: data.Session.ID}
-		}
+	// Standard event format. The fields an event type does not have stay
+	// zero; a field of an unexpected type is skipped.
+	var data struct {
+		EventID        string        `json:"event_id"`
+		ResponseID     string        `json:"response_id"`
+		ItemID         string        `json:"item_id"`
+		PreviousItemID string        `json:"previous_item_id"`
+		OutputIndex    int           `json:"output_index"`
+		ContentIndex   int           `json:"content_index"`
+		AudioStartMs   int           `json:"audio_start_ms"`
+		AudioEndMs     int           `json:"audio_end_ms"`
+		Delta          string        `json:"delta"`
+		Text           string        `json:"text"`
+		Transcript     string        `json:"transcript"`
+		CallID         string        `json:"call_id"`
+		Name           string        `json:"name"`
+		Arguments      string        `json:"arguments"`
+		Session        *SessionInfo  `json:"session"`
+		Response       *ResponseInfo `json:"response"`
+		Item           *OutputItem   `json:"item"`
+		Part           *ContentPart  `json:"part"`
+		Error          *EventError   `json:"error"`
+		RateLimits     []RateLimit   `json:"rate_limits"`
+	}
+	if err := json.Unmarshal(message, &data); err != nil {
+		slog.Debug("failed to unmarshal event", "type", eventType, "error", err)
+	}
+	event.EventID = data.EventID
+	event.ResponseID = data.ResponseID
+	event.ItemID = data.ItemID
+	event.PreviousItemID = data.PreviousItemID
+	event.OutputIndex = data.OutputIndex
+	event.ContentIndex = data.ContentIndex
+	event.AudioStartMs = data.AudioStartMs
+	event.AudioEndMs = data.AudioEndMs
+	event.Delta = data.Delta
+	event.Text = data.Text
+	event.Transcript = data.Transcript
+	event.Session = data.Session
+	event.Response = data.Response
+	event.Item = data.Item
+	event.Part = data.Part
+	event.Error = data.Error
+	event.RateLimits = data.RateLimits
 
+	switch eventType {
 	case EventTypeResponseCreated:
-		var data struct {
-			Response struct {
-				ID string `json:"id"`
-			} `json:"response"`
-		}
-		if err := json.Unmarshal(message, &data); err == nil {
+		if event.ResponseID == "" && data.Response != nil {
 			event.ResponseID = data.Response.ID
 		}
 
 	case EventTypeResponseAudioDelta:
-		var data struct {
-			Delta string `json:"delta"`
-		}
-		if err := json.Unmarshal(message, &data); err == nil && data.Delta != "" {
+		// The delta of audio events is base64 audio, not text
+		event.Delta = ""
+		if data.Delta != "" {
 			event.AudioBase64 = data.Delta
 			if decoded, err := base64.StdEncoding.DecodeString(data.Delta); err == nil {
 				event.Audio = decoded
 			}
 		}
 
-	case EventTypeResponseTranscriptDelta:
-		var data struct {
-			Delta string `json:"delta"`
-		}
-		if err := json.Unmarshal(message, &data); err == nil {
-			event.Delta = data.Delta
-		}
+	case EventTypeResponseFunctionCallArgumentsDelta:
+		event.FunctionCall = &FunctionCall{CallID: data.CallID, Arguments: data.Delta}
 
-	case EventTypeResponseTextDelta:
-		var data struct {
-			Delta string `json:"delta"`
-		}
-		if err := json.Unmarshal(message, &data); err == nil {
-			event.Delta = data.Delta
+	case EventTypeResponseFunctionCallArgumentsDone:
+		event.FunctionCall = &FunctionCall{
+			CallID:    data.CallID,
+			Name:      data.Name,
+			Arguments: data.Arguments,
 		}
 
-	case EventTypeInputAudioTranscriptionCompleted:
-		var data struct {
-			Transcript string `json:"transcript"`
-		}
-		if err := json.Unmarshal(message, &data); err == nil {
-			event.Transcript = data.Transcript
+	case EventTypeResponseOutputAdded, EventTypeResponseOutputDone, EventTypeConversationItemCreated:
+		if item := data.Item; item != nil {
+			event.ItemID = item.ID
+			if item.Type == ItemTypeFunctionCall {
+				event.FunctionCall = &FunctionCall{
+					CallID:    item.CallID,
+					Name:      item.Name,
+					Arguments: item.Arguments,
+				}
+			}
 		}
 
 	case EventTypeResponseDone:
-		var data struct {
-			Response struct {
-				Usage struct {
-					TotalTokens  int `json:"total_tokens"`
-					InputTokens  int `json:"input_tokens"`
-					OutputTokens int `json:"output_tokens"`
-				} `json:"usage"`
-			} `json:"response"`
+		if data.Response != nil {
+			event.ResponseID = data.Response.ID
+			event.Usage = data.Response.Usage
 		}
-		if err := json.Unmarshal(message, &data); err == nil {
-			event.Usage = &UsageStats{
-				TotalTokens:  data.Response.Usage.TotalTokens,
-				InputTokens:  data.Response.Usage.InputTokens,
-				OutputTokens: data.Response.Usage.OutputTokens,
-			}
+		if event.Usage == nil {
+			event.Usage = &UsageStats{}
 		}
 	}
 
@@ -0,0 +1,251 @@
+package dashscope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// ConversationItem is a turn of a realtime conversation, recorded so the
+// conversation can be replayed into a new session by Resume.
+type ConversationItem struct {
+	// Type is ItemTypeMessage, ItemTypeFunctionCall or
+	// ItemTypeFunctionCallOutput.
+	Type string `json:"type"`
+
+	// Role is "user" or "assistant" (messages only).
+	Role string `json:"role,omitempty"`
+
+	// Text is the message text: the input transcript for user messages and
+	// the response text or audio transcript for assistant messages.
+	Text string `json:"text,omitempty"`
+
+	// CallID links a function call and its output.
+	CallID string `json:"call_id,omitempty"`
+
+	// Name and Arguments describe a function call.
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+
+	// Output is the output of a function call.
+	Output string `json:"output,omitempty"`
+}
+
+// SessionState is the state of a realtime session needed to continue its
+// conversation in a new session: the connection and session configuration
+// and the conversation so far. It is JSON-serializable, so it can be kept
+// across processes.
+type SessionState struct {
+	// Config is the connection config of the session.
+	Config RealtimeConfig `json:"config"`
+
+	// Session is the last configuration sent with UpdateSession, if any.
+	Session *SessionConfig `json:"session,omitempty"`
+
+	// Items is the conversation, oldest first. Callers may trim it before
+	// Resume to bound the replayed history.
+	Items []ConversationItem `json:"items,omitempty"`
+}
+
+// sessionRecorder records the state of a session from the events it sends
+// and receives.
+type sessionRecorder struct {
+	mu      sync.Mutex
+	session *SessionConfig
+	items   []ConversationItem
+	reply   strings.Builder // assistant text of the response in progress
+}
+
+func (r *sessionRecorder) setSession(config *SessionConfig) {
+	c := *config
+	c.Modalities = slices.Clone(config.Modalities)
+	c.Tools = slices.Clone(config.Tools)
+	r.mu.Lock()
+	r.session = &c
+	r.mu.Unlock()
+}
+
+func (r *sessionRecorder) add(item ConversationItem) {
+	r.mu.Lock()
+	r.items = append(r.items, item)
+	r.mu.Unlock()
+}
+
+// observe records the conversation turns of a received event.
+func (r *sessionRecorder) observe(event *RealtimeEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch event.Type {
+	case EventTypeInputAudioTranscriptionCompleted:
+		if event.Transcript != "" {
+			r.items = append(r.items, ConversationItem{Type: ItemTypeMessage, Role: "user", Text: event.Transcript})
+		}
+	case EventTypeResponseTextDelta, EventTypeResponseTranscriptDelta:
+		r.reply.WriteString(event.Delta)
+	case EventTypeChoicesResponse:
+		r.reply.WriteString(event.Delta)
+		if event.FinishReason != "" {
+			r.flushReplyLocked()
+		}
+	case EventTypeResponseFunctionCallArgumentsDone:
+		if fc := event.FunctionCall; fc != nil {
+			r.items = append(r.items, ConversationItem{
+				Type:      ItemTypeFunctionCall,
+				CallID:    fc.CallID,
+				Name:      fc.Name,
+				Arguments: fc.Arguments,
+			})
+		}
+	case EventTypeResponseDone:
+		r.flushReplyLocked()
+	}
+}
+
+func (r *sessionRecorder) flushReplyLocked() {
+	if r.reply.Len() > 0 {
+		r.items = append(r.items, ConversationItem{Type: ItemTypeMessage, Role: "assistant", Text: r.reply.String()})
+		r.reply.Reset()
+	}
+}
+
+// State returns a snapshot of the session state, for Resume. An assistant
+// reply still being generated is not included.
+func (s *RealtimeSession) State() *SessionState {
+	st := &SessionState{Config: *s.config}
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	if s.recorder.session != nil {
+		c := *s.recorder.session
+		st.Session = &c
+	}
+	st.Items = slices.Clone(s.recorder.items)
+	return st
+}
+
+// Resume connects a new session that continues the conversation of prior,
+// typically after the previous connection dropped (see IsAbnormalClosure).
+//
+// Resume waits for session.created, re-sends prior.Session with
+// UpdateSession and replays prior.Items with conversation.item.create, so
+// the model keeps the instructions, tools and history of the conversation.
+// The session.created event is consumed; events after it are delivered by
+// Events as usual. The new session records its state on top of prior's.
+//
+//	for event, err := range session.Events() {
+//	    if dashscope.IsAbnormalClosure(err) {
+//	        session, err = client.Realtime.Resume(ctx, session.State())
+//	        ...
+//	    }
+//	}
+func (s *RealtimeService) Resume(ctx context.Context, prior *SessionState) (*RealtimeSession, error) {
+	if prior == nil {
+		prior = &SessionState{}
+	}
+	config := prior.Config
+	session, err := s.Connect(ctx, &config)
+	if err != nil {
+		return nil, err
+	}
+	if err := session.resume(ctx, prior); err != nil {
+		session.Close()
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *RealtimeSession) resume(ctx context.Context, prior *SessionState) error {
+	if err := s.waitCreated(ctx); err != nil {
+		return err
+	}
+	if prior.Session != nil {
+		if err := s.UpdateSession(prior.Session); err != nil {
+			return fmt.Errorf("dashscope: resume: update session: %w", err)
+		}
+	}
+	for _, item := range prior.Items {
+		if err := s.sendEvent(map[string]interface{}{
+			"event_id": generateEventID(),
+			"type":     EventTypeConversationItemCreate,
+			"item":     item.event(),
+		}); err != nil {
+			return fmt.Errorf("dashscope: resume: replay conversation: %w", err)
+		}
+	}
+	s.recorder.mu.Lock()
+	s.recorder.items = append(slices.Clone(prior.Items), s.recorder.items...)
+	s.recorder.mu.Unlock()
+	return nil
+}
+
+// waitCreated waits for the session.created event.
+func (s *RealtimeSession) waitCreated(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case item, ok := <-s.eventsCh:
+			if !ok {
+				return errors.New("dashscope: resume: session closed before session.created")
+			}
+			if item.err != nil {
+				return fmt.Errorf("dashscope: resume: %w", item.err)
+			}
+			if item.event.Type == EventTypeSessionCreated {
+				return nil
+			}
+		}
+	}
+}
+
+// event returns the conversation.item.create item of a ConversationItem.
+func (item ConversationItem) event() map[string]interface{} {
+	switch item.Type {
+	case ItemTypeFunctionCall:
+		return map[string]interface{}{
+			"type":      ItemTypeFunctionCall,
+			"call_id":   item.CallID,
+			"name":      item.Name,
+			"arguments": item.Arguments,
+		}
+	case ItemTypeFunctionCallOutput:
+		return map[string]interface{}{
+			"type":    ItemTypeFunctionCallOutput,
+			"call_id": item.CallID,
+			"output":  item.Output,
+		}
+	default:
+		contentType := "input_text"
+		if item.Role == "assistant" {
+			contentType = "text"
+		}
+		return map[string]interface{}{
+			"type": ItemTypeMessage,
+			"role": item.Role,
+			"content": []map[string]interface{}{
+				{"type": contentType, "text": item.Text},
+			},
+		}
+	}
+}
+
+// IsAbnormalClosure reports whether err is a connection lost without a
+// close frame (WebSocket close code 1006, a reset or an unexpected EOF),
+// after which a session can be continued with Resume.
+func IsAbnormalClosure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		return closeErr.Code == websocket.CloseAbnormalClosure
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
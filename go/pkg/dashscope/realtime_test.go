@@ -0,0 +1,162 @@
+package dashscope
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// parse parses a server message as the read loop does.
+func parse(t *testing.T, message string) *RealtimeEvent {
+	t.Helper()
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal([]byte(message), &head); err != nil {
+		t.Fatalf("invalid test message %s: %v", message, err)
+	}
+	return (&RealtimeSession{}).parseEvent(head.Type, []byte(message))
+}
+
+func TestParseEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		check   func(t *testing.T, e *RealtimeEvent)
+	}{
+		{
+			name:    "audio delta",
+			message: `{"event_id":"event_7","type":"response.audio.delta","response_id":"resp_1","item_id":"item_2","output_index":0,"content_index":0,"delta":"AAECAw=="}`,
+			check: func(t *testing.T, e *RealtimeEvent) {
+				if e.Delta != "" {
+					t.Errorf("Delta = %q, want empty: the delta of audio events is audio", e.Delta)
+				}
+				if e.AudioBase64 != "AAECAw==" || !bytes.Equal(e.Audio, []byte{0, 1, 2, 3}) {
+					t.Errorf("AudioBase64 = %q, Audio = %v", e.AudioBase64, e.Audio)
+				}
+				if e.ResponseID != "resp_1" || e.ItemID != "item_2" {
+					t.Errorf("ResponseID = %q, ItemID = %q", e.ResponseID, e.ItemID)
+				}
+			},
+		},
+		{
+			name:    "transcript delta",
+			message: `{"event_id":"event_8","type":"response.audio_transcript.delta","response_id":"resp_1","item_id":"item_2","output_index":0,"content_index":0,"delta":"你好"}`,
+			check: func(t *testing.T, e *RealtimeEvent) {
+				if e.Delta != "你好" || e.Audio != nil {
+					t.Errorf("Delta = %q, Audio = %v", e.Delta, e.Audio)
+				}
+			},
+		},
+		{
+			name:    "response created",
+			message: `{"event_id":"event_5","type":"response.created","response":{"id":"resp_1","status":"in_progress","output":[]}}`,
+			check: func(t *testing.T, e *RealtimeEvent) {
+				if e.ResponseID != "resp_1" || e.Response == nil || e.Response.Status != "in_progress" {
+					t.Errorf("ResponseID = %q, Response = %+v", e.ResponseID, e.Response)
+				}
+			},
+		},
+		{
+			name:    "response done",
+			message: `{"event_id":"event_20","type":"response.done","response":{"id":"resp_1","status":"completed","usage":{"total_tokens":120,"input_tokens":100,"output_tokens":20,"output_token_details":{"text_tokens":5,"audio_tokens":15}}}}`,
+			check: func(t *testing.T, e *RealtimeEvent) {
+				want := &UsageStats{TotalTokens: 120, InputTokens: 100, OutputTokens: 20,
+					OutputTokenDetails: &TokenDetails{TextTokens: 5, AudioTokens: 15}}
+				if e.ResponseID != "resp_1" || !reflect.DeepEqual(e.Usage, want) {
+					t.Errorf("ResponseID = %q, Usage = %+v", e.ResponseID, e.Usage)
+				}
+			},
+		},
+		{
+			name:    "response done without usage",
+			message: `{"event_id":"event_20","type":"response.done","response":{"id":"resp_1","status":"cancelled"}}`,
+			check: func(t *testing.T, e *RealtimeEvent) {
+				if e.Usage == nil || *e.Usage != (UsageStats{}) {
+					t.Errorf("Usage = %+v, want zero usage", e.Usage)
+				}
+			},
+		},
+		{
+			name:    "function call arguments delta",
+			message: `{"event_id":"event_9","type":"response.function_call_arguments.delta","response_id":"resp_1","item_id":"item_3","call_id":"call_1","delta":"{\"city\":"}`,
+			check: func(t *testing.T, e *RealtimeEvent) {
+				want := &FunctionCall{CallID: "call_1", Arguments: `{"city":`}
+				if !reflect.DeepEqual(e.FunctionCall, want) {
+					t.Errorf("FunctionCall = %+v, want %+v", e.FunctionCall, want)
+				}
+			},
+		},
+		{
+			name:    "function call arguments done",
+			message: `{"event_id":"event_10","type":"response.function_call_arguments.done","response_id":"resp_1","item_id":"item_3","call_id":"call_1","name":"get_weather","arguments":"{\"city\":\"杭州\"}"}`,
+			check: func(t *testing.T, e *RealtimeEvent) {
+				want := &FunctionCall{CallID: "call_1", Name: "get_weather", Arguments: `{"city":"杭州"}`}
+				if !reflect.DeepEqual(e.FunctionCall, want) {
+					t.Errorf("FunctionCall = %+v, want %+v", e.FunctionCall, want)
+				}
+			},
+		},
+		{
+			name:    "function call output item",
+			message: `{"event_id":"event_11","type":"response.output_item.done","response_id":"resp_1","output_index":0,"item":{"id":"item_3","type":"function_call","status":"completed","call_id":"call_1","name":"get_weather","arguments":"{}"}}`,
+			check: func(t *testing.T, e *RealtimeEvent) {
+				want := &FunctionCall{CallID: "call_1", Name: "get_weather", Arguments: "{}"}
+				if e.ItemID != "item_3" || !reflect.DeepEqual(e.FunctionCall, want) {
+					t.Errorf("ItemID = %q, FunctionCall = %+v", e.ItemID, e.FunctionCall)
+				}
+			},
+		},
+		{
+			name:    "message output item",
+			message: `{"event_id":"event_4","type":"response.output_item.added","response_id":"resp_1","output_index":0,"item":{"id":"item_2","type":"message","role":"assistant","status":"in_progress","content":[]}}`,
+			check: func(t *testing.T, e *RealtimeEvent) {
+				if e.ItemID != "item_2" || e.FunctionCall != nil {
+					t.Errorf("ItemID = %q, FunctionCall = %+v", e.ItemID, e.FunctionCall)
+				}
+			},
+		},
+		{
+			name:    "choices",
+			message: `{"choices":[{"finish_reason":"null","message":{"role":"assistant","content":[{"text":"好的"},{"audio":{"data":"AAE="}}]}}]}`,
+			check: func(t *testing.T, e *RealtimeEvent) {
+				if e.Type != EventTypeChoicesResponse || e.Delta != "好的" || !bytes.Equal(e.Audio, []byte{0, 1}) || e.FinishReason != "" {
+					t.Errorf("event = %+v", e)
+				}
+			},
+		},
+		{
+			name:    "choices finished",
+			message: `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":[]}}]}`,
+			check: func(t *testing.T, e *RealtimeEvent) {
+				if e.Type != EventTypeChoicesResponse || e.FinishReason != "stop" {
+					t.Errorf("Type = %q, FinishReason = %q", e.Type, e.FinishReason)
+				}
+			},
+		},
+		{
+			name:    "field of unexpected type",
+			message: `{"event_id":"event_3","type":"input_audio_buffer.speech_started","audio_start_ms":"soon","item_id":"item_1"}`,
+			check: func(t *testing.T, e *RealtimeEvent) {
+				if e.Type != EventTypeInputSpeechStarted || e.EventID != "event_3" || e.AudioStartMs != 0 {
+					t.Errorf("event = %+v", e)
+				}
+			},
+		},
+		{
+			name:    "unknown event",
+			message: `{"event_id":"event_30","type":"response.audio_subtitle.delta","subtitle":"hi"}`,
+			check: func(t *testing.T, e *RealtimeEvent) {
+				if e.Type != "response.audio_subtitle.delta" || e.EventID != "event_30" || string(e.Raw) != `{"event_id":"event_30","type":"response.audio_subtitle.delta","subtitle":"hi"}` {
+					t.Errorf("event = %+v", e)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, parse(t, tt.message))
+		})
+	}
+}
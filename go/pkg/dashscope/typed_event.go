@@ -0,0 +1,264 @@
+package dashscope
+
+// Typed server events. RealtimeEvent carries the fields of every event
+// type; the types below hold only the fields the server sends for one type,
+// so a type switch replaces switching on Type and guessing which fields are
+// set:
+//
+//	switch ev := event.Typed().(type) {
+//	case *dashscope.ResponseAudioDeltaEvent:
+//	    play(ev.Audio)
+//	case *dashscope.InputSpeechStartedEvent:
+//	    stopPlayback()
+//	case *dashscope.RealtimeEvent:
+//	    // An event type this package does not know; its JSON is in ev.Raw
+//	}
+//
+// or, for a single type:
+//
+//	if ev, ok := dashscope.As[*dashscope.ResponseTextDeltaEvent](event); ok {
+//	    fmt.Print(ev.Delta)
+//	}
+//
+// The RealtimeEvent fields remain available.
+
+// ContentRef identifies the content part a response event belongs to.
+type ContentRef struct {
+	ResponseID   string
+	ItemID       string
+	OutputIndex  int
+	ContentIndex int
+}
+
+// ErrorEvent is an "error" event. The session stays open.
+type ErrorEvent struct {
+	Error *EventError
+}
+
+// SessionCreatedEvent is a "session.created" event.
+type SessionCreatedEvent struct {
+	Session *SessionInfo
+}
+
+// SessionUpdatedEvent is a "session.updated" event.
+type SessionUpdatedEvent struct {
+	Session *SessionInfo
+}
+
+// SessionFinishedEvent is a "session.finished" event, the server's answer
+// to FinishSession.
+type SessionFinishedEvent struct{}
+
+// ConversationItemCreatedEvent is a "conversation.item.created" event.
+type ConversationItemCreatedEvent struct {
+	PreviousItemID string
+	Item           *OutputItem
+}
+
+// InputTranscriptionCompletedEvent is a
+// "conversation.item.input_audio_transcription.completed" event.
+type InputTranscriptionCompletedEvent struct {
+	ItemID       string
+	ContentIndex int
+	Transcript   string
+}
+
+// InputTranscriptionFailedEvent is a
+// "conversation.item.input_audio_transcription.failed" event.
+type InputTranscriptionFailedEvent struct {
+	ItemID       string
+	ContentIndex int
+	Error        *EventError
+}
+
+// InputAudioCommittedEvent is an "input_audio_buffer.committed" event.
+type InputAudioCommittedEvent struct {
+	PreviousItemID string
+	ItemID         string
+}
+
+// InputAudioClearedEvent is an "input_audio_buffer.cleared" event.
+type InputAudioClearedEvent struct{}
+
+// InputSpeechStartedEvent is an "input_audio_buffer.speech_started" event.
+type InputSpeechStartedEvent struct {
+	ItemID       string
+	AudioStartMs int
+}
+
+// InputSpeechStoppedEvent is an "input_audio_buffer.speech_stopped" event.
+type InputSpeechStoppedEvent struct {
+	ItemID     string
+	AudioEndMs int
+}
+
+// ResponseCreatedEvent is a "response.created" event.
+type ResponseCreatedEvent struct {
+	Response *ResponseInfo
+}
+
+// ResponseDoneEvent is a "response.done" event.
+type ResponseDoneEvent struct {
+	Response *ResponseInfo
+	Usage    *UsageStats
+}
+
+// ResponseOutputItemEvent is a "response.output_item.added" or
+// "response.output_item.done" event.
+type ResponseOutputItemEvent struct {
+	ResponseID  string
+	OutputIndex int
+	Item        *OutputItem
+	Done        bool
+}
+
+// ResponseContentPartEvent is a "response.content_part.added" or
+// "response.content_part.done" event.
+type ResponseContentPartEvent struct {
+	ContentRef
+	Part *ContentPart
+	Done bool
+}
+
+// ResponseTextDeltaEvent is a "response.text.delta" event.
+type ResponseTextDeltaEvent struct {
+	ContentRef
+	Delta string
+}
+
+// ResponseTextDoneEvent is a "response.text.done" event.
+type ResponseTextDoneEvent struct {
+	ContentRef
+	Text string
+}
+
+// ResponseAudioDeltaEvent is a "response.audio.delta" event.
+type ResponseAudioDeltaEvent struct {
+	ContentRef
+	// Audio is the decoded audio in the session's output format.
+	Audio []byte
+}
+
+// ResponseAudioDoneEvent is a "response.audio.done" event.
+type ResponseAudioDoneEvent struct {
+	ContentRef
+}
+
+// ResponseTranscriptDeltaEvent is a "response.audio_transcript.delta" event.
+type ResponseTranscriptDeltaEvent struct {
+	ContentRef
+	Delta string
+}
+
+// ResponseTranscriptDoneEvent is a "response.audio_transcript.done" event.
+type ResponseTranscriptDoneEvent struct {
+	ContentRef
+	Transcript string
+}
+
+// ResponseFunctionCallArgumentsEvent is a
+// "response.function_call_arguments.delta" or
+// "response.function_call_arguments.done" event.
+type ResponseFunctionCallArgumentsEvent struct {
+	ResponseID string
+	ItemID     string
+	// FunctionCall holds only the new fragment of the arguments for delta
+	// events, and the complete call for the done event.
+	FunctionCall *FunctionCall
+	Done         bool
+}
+
+// RateLimitsUpdatedEvent is a "rate_limits.updated" event.
+type RateLimitsUpdatedEvent struct {
+	RateLimits []RateLimit
+}
+
+// ChoicesEvent is a DashScope "choices" format response (Type
+// EventTypeChoicesResponse).
+type ChoicesEvent struct {
+	// Delta is the new text.
+	Delta string
+	// Audio is the decoded new audio.
+	Audio []byte
+	// FinishReason is set on the last choices response, e.g. "stop".
+	FinishReason string
+}
+
+// Typed returns the typed event for e's Type, as a pointer to one of the
+// event types above. For event types without one, it returns e itself; the
+// JSON of the event is in e.Raw.
+func (e *RealtimeEvent) Typed() any {
+	ref := ContentRef{
+		ResponseID:   e.ResponseID,
+		ItemID:       e.ItemID,
+		OutputIndex:  e.OutputIndex,
+		ContentIndex: e.ContentIndex,
+	}
+	switch e.Type {
+	case EventTypeError:
+		return &ErrorEvent{Error: e.Error}
+	case EventTypeSessionCreated:
+		return &SessionCreatedEvent{Session: e.Session}
+	case EventTypeSessionUpdated:
+		return &SessionUpdatedEvent{Session: e.Session}
+	case EventTypeSessionFinished:
+		return &SessionFinishedEvent{}
+	case EventTypeConversationItemCreated:
+		return &ConversationItemCreatedEvent{PreviousItemID: e.PreviousItemID, Item: e.Item}
+	case EventTypeInputAudioTranscriptionCompleted:
+		return &InputTranscriptionCompletedEvent{ItemID: e.ItemID, ContentIndex: e.ContentIndex, Transcript: e.Transcript}
+	case EventTypeInputAudioTranscriptionFailed:
+		return &InputTranscriptionFailedEvent{ItemID: e.ItemID, ContentIndex: e.ContentIndex, Error: e.Error}
+	case EventTypeInputAudioCommitted:
+		return &InputAudioCommittedEvent{PreviousItemID: e.PreviousItemID, ItemID: e.ItemID}
+	case EventTypeInputAudioCleared:
+		return &InputAudioClearedEvent{}
+	case EventTypeInputSpeechStarted:
+		return &InputSpeechStartedEvent{ItemID: e.ItemID, AudioStartMs: e.AudioStartMs}
+	case EventTypeInputSpeechStopped:
+		return &InputSpeechStoppedEvent{ItemID: e.ItemID, AudioEndMs: e.AudioEndMs}
+	case EventTypeResponseCreated:
+		return &ResponseCreatedEvent{Response: e.Response}
+	case EventTypeResponseDone:
+		return &ResponseDoneEvent{Response: e.Response, Usage: e.Usage}
+	case EventTypeResponseOutputAdded, EventTypeResponseOutputDone:
+		return &ResponseOutputItemEvent{
+			ResponseID:  e.ResponseID,
+			OutputIndex: e.OutputIndex,
+			Item:        e.Item,
+			Done:        e.Type == EventTypeResponseOutputDone,
+		}
+	case EventTypeResponseContentAdded, EventTypeResponseContentDone:
+		return &ResponseContentPartEvent{ContentRef: ref, Part: e.Part, Done: e.Type == EventTypeResponseContentDone}
+	case EventTypeResponseTextDelta:
+		return &ResponseTextDeltaEvent{ContentRef: ref, Delta: e.Delta}
+	case EventTypeResponseTextDone:
+		return &ResponseTextDoneEvent{ContentRef: ref, Text: e.Text}
+	case EventTypeResponseAudioDelta:
+		return &ResponseAudioDeltaEvent{ContentRef: ref, Audio: e.Audio}
+	case EventTypeResponseAudioDone:
+		return &ResponseAudioDoneEvent{ContentRef: ref}
+	case EventTypeResponseTranscriptDelta:
+		return &ResponseTranscriptDeltaEvent{ContentRef: ref, Delta: e.Delta}
+	case EventTypeResponseTranscriptDone:
+		return &ResponseTranscriptDoneEvent{ContentRef: ref, Transcript: e.Transcript}
+	case EventTypeResponseFunctionCallArgumentsDelta, EventTypeResponseFunctionCallArgumentsDone:
+		return &ResponseFunctionCallArgumentsEvent{
+			ResponseID:   e.ResponseID,
+			ItemID:       e.ItemID,
+			FunctionCall: e.FunctionCall,
+			Done:         e.Type == EventTypeResponseFunctionCallArgumentsDone,
+		}
+	case EventTypeRateLimitsUpdated:
+		return &RateLimitsUpdatedEvent{RateLimits: e.RateLimits}
+	case EventTypeChoicesResponse:
+		return &ChoicesEvent{Delta: e.Delta, Audio: e.Audio, FinishReason: e.FinishReason}
+	}
+	return e
+}
+
+// As returns the typed event of event if it is a T.
+func As[T any](event *RealtimeEvent) (T, bool) {
+	t, ok := event.Typed().(T)
+	return t, ok
+}
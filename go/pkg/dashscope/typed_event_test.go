@@ -0,0 +1,163 @@
+package dashscope
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTyped(t *testing.T) {
+	ref := ContentRef{ResponseID: "resp_1", ItemID: "item_2", OutputIndex: 0, ContentIndex: 1}
+	tests := []struct {
+		name    string
+		message string
+		want    any
+	}{
+		{
+			name:    "error",
+			message: `{"event_id":"event_1","type":"error","error":{"type":"invalid_request_error","code":"invalid_value","message":"Invalid voice","param":"session.voice"}}`,
+			want: &ErrorEvent{Error: &EventError{
+				Type: "invalid_request_error", Code: "invalid_value", Message: "Invalid voice", Param: "session.voice",
+			}},
+		},
+		{
+			name:    "session created",
+			message: `{"event_id":"event_1","type":"session.created","session":{"id":"sess_1","object":"realtime.session","model":"qwen-omni-turbo-realtime","modalities":["text","audio"],"voice":"Chelsie","input_audio_format":"pcm16","output_audio_format":"pcm24","turn_detection":{"type":"server_vad","threshold":0.5,"prefix_padding_ms":300,"silence_duration_ms":800}}}`,
+			want: &SessionCreatedEvent{Session: &SessionInfo{
+				ID:                "sess_1",
+				Model:             "qwen-omni-turbo-realtime",
+				Modalities:        []string{"text", "audio"},
+				Voice:             "Chelsie",
+				InputAudioFormat:  "pcm16",
+				OutputAudioFormat: "pcm24",
+				TurnDetection:     &TurnDetection{Type: "server_vad", Threshold: 0.5, PrefixPaddingMs: 300, SilenceDurationMs: 800},
+			}},
+		},
+		{
+			name:    "session finished",
+			message: `{"event_id":"event_40","type":"session.finished"}`,
+			want:    &SessionFinishedEvent{},
+		},
+		{
+			name:    "speech started",
+			message: `{"event_id":"event_2","type":"input_audio_buffer.speech_started","audio_start_ms":1540,"item_id":"item_1"}`,
+			want:    &InputSpeechStartedEvent{ItemID: "item_1", AudioStartMs: 1540},
+		},
+		{
+			name:    "speech stopped",
+			message: `{"event_id":"event_3","type":"input_audio_buffer.speech_stopped","audio_end_ms":3260,"item_id":"item_1"}`,
+			want:    &InputSpeechStoppedEvent{ItemID: "item_1", AudioEndMs: 3260},
+		},
+		{
+			name:    "committed",
+			message: `{"event_id":"event_4","type":"input_audio_buffer.committed","previous_item_id":"item_0","item_id":"item_1"}`,
+			want:    &InputAudioCommittedEvent{PreviousItemID: "item_0", ItemID: "item_1"},
+		},
+		{
+			name:    "transcription completed",
+			message: `{"event_id":"event_6","type":"conversation.item.input_audio_transcription.completed","item_id":"item_1","content_index":0,"transcript":"今天天气怎么样"}`,
+			want:    &InputTranscriptionCompletedEvent{ItemID: "item_1", Transcript: "今天天气怎么样"},
+		},
+		{
+			name:    "transcription failed",
+			message: `{"event_id":"event_6","type":"conversation.item.input_audio_transcription.failed","item_id":"item_1","content_index":0,"error":{"code":"asr_failed","message":"no speech"}}`,
+			want:    &InputTranscriptionFailedEvent{ItemID: "item_1", Error: &EventError{Code: "asr_failed", Message: "no speech"}},
+		},
+		{
+			name:    "conversation item created",
+			message: `{"event_id":"event_5","type":"conversation.item.created","previous_item_id":"item_0","item":{"id":"item_1","type":"message","role":"user","status":"completed","content":[{"type":"input_audio","transcript":""}]}}`,
+			want: &ConversationItemCreatedEvent{PreviousItemID: "item_0", Item: &OutputItem{
+				ID: "item_1", Type: "message", Role: "user", Status: "completed", Content: []ContentPart{{Type: "input_audio"}},
+			}},
+		},
+		{
+			name:    "output item done",
+			message: `{"event_id":"event_19","type":"response.output_item.done","response_id":"resp_1","output_index":0,"item":{"id":"item_2","type":"message","role":"assistant","status":"completed","content":[{"type":"audio","transcript":"晴"}]}}`,
+			want: &ResponseOutputItemEvent{ResponseID: "resp_1", Done: true, Item: &OutputItem{
+				ID: "item_2", Type: "message", Role: "assistant", Status: "completed", Content: []ContentPart{{Type: "audio", Transcript: "晴"}},
+			}},
+		},
+		{
+			name:    "content part added",
+			message: `{"event_id":"event_8","type":"response.content_part.added","response_id":"resp_1","item_id":"item_2","output_index":0,"content_index":1,"part":{"type":"audio","transcript":""}}`,
+			want:    &ResponseContentPartEvent{ContentRef: ref, Part: &ContentPart{Type: "audio"}},
+		},
+		{
+			name:    "text delta",
+			message: `{"event_id":"event_9","type":"response.text.delta","response_id":"resp_1","item_id":"item_2","output_index":0,"content_index":1,"delta":"晴"}`,
+			want:    &ResponseTextDeltaEvent{ContentRef: ref, Delta: "晴"},
+		},
+		{
+			name:    "text done",
+			message: `{"event_id":"event_10","type":"response.text.done","response_id":"resp_1","item_id":"item_2","output_index":0,"content_index":1,"text":"晴天"}`,
+			want:    &ResponseTextDoneEvent{ContentRef: ref, Text: "晴天"},
+		},
+		{
+			name:    "audio delta",
+			message: `{"event_id":"event_11","type":"response.audio.delta","response_id":"resp_1","item_id":"item_2","output_index":0,"content_index":1,"delta":"AAECAw=="}`,
+			want:    &ResponseAudioDeltaEvent{ContentRef: ref, Audio: []byte{0, 1, 2, 3}},
+		},
+		{
+			name:    "audio done",
+			message: `{"event_id":"event_12","type":"response.audio.done","response_id":"resp_1","item_id":"item_2","output_index":0,"content_index":1}`,
+			want:    &ResponseAudioDoneEvent{ContentRef: ref},
+		},
+		{
+			name:    "transcript delta",
+			message: `{"event_id":"event_13","type":"response.audio_transcript.delta","response_id":"resp_1","item_id":"item_2","output_index":0,"content_index":1,"delta":"晴"}`,
+			want:    &ResponseTranscriptDeltaEvent{ContentRef: ref, Delta: "晴"},
+		},
+		{
+			name:    "transcript done",
+			message: `{"event_id":"event_14","type":"response.audio_transcript.done","response_id":"resp_1","item_id":"item_2","output_index":0,"content_index":1,"transcript":"晴天"}`,
+			want:    &ResponseTranscriptDoneEvent{ContentRef: ref, Transcript: "晴天"},
+		},
+		{
+			name:    "function call arguments done",
+			message: `{"event_id":"event_15","type":"response.function_call_arguments.done","response_id":"resp_1","item_id":"item_3","call_id":"call_1","name":"get_weather","arguments":"{}"}`,
+			want: &ResponseFunctionCallArgumentsEvent{ResponseID: "resp_1", ItemID: "item_3", Done: true,
+				FunctionCall: &FunctionCall{CallID: "call_1", Name: "get_weather", Arguments: "{}"}},
+		},
+		{
+			name:    "response done",
+			message: `{"event_id":"event_20","type":"response.done","response":{"id":"resp_1","status":"completed","usage":{"total_tokens":30,"input_tokens":20,"output_tokens":10}}}`,
+			want: &ResponseDoneEvent{
+				Response: &ResponseInfo{ID: "resp_1", Status: "completed", Usage: &UsageStats{TotalTokens: 30, InputTokens: 20, OutputTokens: 10}},
+				Usage:    &UsageStats{TotalTokens: 30, InputTokens: 20, OutputTokens: 10},
+			},
+		},
+		{
+			name:    "rate limits",
+			message: `{"event_id":"event_21","type":"rate_limits.updated","rate_limits":[{"name":"requests","limit":60,"remaining":59,"reset_seconds":1.5}]}`,
+			want:    &RateLimitsUpdatedEvent{RateLimits: []RateLimit{{Name: "requests", Limit: 60, Remaining: 59, ResetSeconds: 1.5}}},
+		},
+		{
+			name:    "choices",
+			message: `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":[{"text":"好"}]}}]}`,
+			want:    &ChoicesEvent{Delta: "好", FinishReason: "stop"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parse(t, tt.message).Typed(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Typed() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTyped_Unknown(t *testing.T) {
+	event := parse(t, `{"event_id":"event_30","type":"response.audio_subtitle.delta","subtitle":"hi"}`)
+	if got := event.Typed(); got != event {
+		t.Errorf("Typed() = %#v, want the event itself", got)
+	}
+}
+
+func TestAs(t *testing.T) {
+	event := parse(t, `{"event_id":"event_9","type":"response.text.delta","response_id":"resp_1","item_id":"item_2","delta":"hi"}`)
+	if ev, ok := As[*ResponseTextDeltaEvent](event); !ok || ev.Delta != "hi" {
+		t.Errorf("As text delta = %+v, %v", ev, ok)
+	}
+	if _, ok := As[*ResponseAudioDeltaEvent](event); ok {
+		t.Error("As audio delta of a text delta")
+	}
+}
@@ -39,6 +39,20 @@ const (
 	ModalityAudio = "audio"
 )
 
+// Tool choice options.
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceNone     = "none"
+	ToolChoiceRequired = "required"
+)
+
+// Output item types.
+const (
+	ItemTypeMessage            = "message"
+	ItemTypeFunctionCall       = "function_call"
+	ItemTypeFunctionCallOutput = "function_call_output"
+)
+
 // RealtimeConfig is the configuration for establishing a realtime session.
 type RealtimeConfig struct {
 	// Model is the model ID to use.
@@ -81,6 +95,42 @@ type SessionConfig struct {
 
 	// InputAudioTranscriptionModel specifies the model for input transcription.
 	InputAudioTranscriptionModel string `json:"input_audio_transcription_model,omitempty"`
+
+	// Tools defines the functions the model may call.
+	Tools []Tool `json:"tools,omitempty"`
+
+	// ToolChoice specifies how the model uses tools:
+	// "auto" (default), "none" or "required".
+	ToolChoice string `json:"tool_choice,omitempty"`
+}
+
+// Tool defines a function tool available to the model.
+type Tool struct {
+	// Type is always "function". Empty defaults to "function".
+	Type string `json:"type"`
+
+	// Name is the function name.
+	Name string `json:"name"`
+
+	// Description describes what the function does.
+	Description string `json:"description,omitempty"`
+
+	// Parameters is the JSON Schema for the function parameters.
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// FunctionCall is a function call requested by the model.
+type FunctionCall struct {
+	// CallID identifies the call. Pass it to SubmitToolResult.
+	CallID string `json:"call_id,omitempty"`
+
+	// Name is the function name. It may be empty on argument deltas.
+	Name string `json:"name,omitempty"`
+
+	// Arguments is the JSON-encoded arguments. On
+	// response.function_call_arguments.delta events it holds only the
+	// new fragment (also in RealtimeEvent.Delta).
+	Arguments string `json:"arguments,omitempty"`
 }
 
 // TurnDetection configures voice activity detection.
@@ -21,6 +21,7 @@ import (
 	"context"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"iter"
@@ -63,7 +64,8 @@ type ASRV2Config struct {
 	// Enable punctuation
 	EnablePunc bool `json:"enable_punc,omitempty" yaml:"enable_punc,omitempty"`
 
-	// Enable speaker diarization
+	// Enable speaker diarization. Utterances then carry the SpeakerID of
+	// the speaker turn they belong to.
 	EnableDiarization bool `json:"enable_diarization,omitempty" yaml:"enable_diarization,omitempty"`
 
 	// Number of speakers (for diarization)
@@ -250,6 +252,21 @@ func (s *ASRV2Session) Recv() iter.Seq2[*ASRV2Result, error] {
 	}
 }
 
+// Ping sends a WebSocket ping to check that an idle session is still
+// usable, e.g. before a pooled session is handed out. It fails if the
+// session has already received an error.
+func (s *ASRV2Session) Ping() error {
+	select {
+	case <-s.closeChan:
+		return errors.New("session closed")
+	default:
+	}
+	if len(s.errChan) > 0 {
+		return errors.New("session failed")
+	}
+	return s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second))
+}
+
 // Close closes the ASR session
 func (s *ASRV2Session) Close() error {
 	s.closeOnce.Do(func() {
@@ -307,7 +324,10 @@ func (s *ASRV2Session) sendSessionStart() error {
 		request["enable_punc"] = true
 	}
 	if s.config.EnableDiarization {
+		// The bigmodel API calls the option enable_speaker_info; older
+		// resources use enable_diarization.
 		request["enable_diarization"] = true
+		request["enable_speaker_info"] = true
 	}
 	if len(s.config.Hotwords) > 0 {
 		request["hotwords"] = s.config.Hotwords
@@ -438,7 +458,11 @@ func (s *ASRV2Session) receiveLoop() {
 						StartTime int    `json:"start_time"`
 						EndTime   int    `json:"end_time"`
 						Definite  bool   `json:"definite"`
-						Words     []struct {
+						SpeakerID string `json:"speaker_id"`
+						Additions struct {
+							Speaker string `json:"speaker"`
+						} `json:"additions"`
+						Words []struct {
 							Text      string `json:"text"`
 							StartTime int    `json:"start_time"`
 							EndTime   int    `json:"end_time"`
@@ -467,6 +491,10 @@ func (s *ASRV2Session) receiveLoop() {
 					StartTime: u.StartTime,
 					EndTime:   u.EndTime,
 					Definite:  u.Definite,
+					SpeakerID: u.SpeakerID,
+				}
+				if utt.SpeakerID == "" {
+					utt.SpeakerID = u.Additions.Speaker
 				}
 				for _, w := range u.Words {
 					utt.Words = append(utt.Words, ASRV2Word{
@@ -70,6 +70,37 @@
 //	    fmt.Println(chunk.Text)
 //	}
 //
+// # 情感与风格
+//
+// TTS V2 支持情感（happy/sad/storytelling 等）、情感强度 EmotionScale
+// 以及语速、音量、音调。双向流式会话中可用行内标签逐句切换风格，
+// 无需为每句话单独发起请求：
+//
+//	session, _ := client.TTSV2.OpenSession(ctx, &doubaospeech.TTSV2SessionConfig{
+//	    Speaker: "zh_female_xiaohe_uranus_bigtts",
+//	})
+//	session.SendStyledText(ctx, "[storytelling]从前有座山。[sad]小熊迷路了。", false)
+//	session.SendStyledText(ctx, "[emotion=happy energy=5]它终于回家了！", true)
+//
+// 标签语法见 ParseStyleTags。
+//
+// # 发音词典
+//
+// 人名、地名等容易读错的词可以用 Lexicon 指定拼音（带声调数字）或替换读法。
+// TTSRequest、TTSV2Request 和 TTSV2SessionConfig 设置 Lexicon 后，
+// 纯文本会自动转换为带 phoneme 标注的 SSML：
+//
+//	lexicon := doubaospeech.Lexicon{
+//	    {Word: "乐乐", Pinyin: "le4 le4"},
+//	    {Word: "Giztoy", Alias: "吉兹托伊"},
+//	}
+//	session, _ := client.TTSV2.OpenSession(ctx, &doubaospeech.TTSV2SessionConfig{
+//	    Speaker: "zh_female_xiaohe_uranus_bigtts",
+//	    Lexicon: lexicon,
+//	})
+//
+// 手写 SSML 时可用 Phoneme 生成单个词的拼音标注。
+//
 // # 认证方式
 //
 // Client (语音 API) 支持三种认证方式：
@@ -0,0 +1,101 @@
+package doubaospeech
+
+import (
+	"html"
+	"sort"
+	"strings"
+)
+
+// Pronunciation fixes how a word is spoken: as the given pinyin, or as
+// another text.
+type Pronunciation struct {
+	// Word is the text to match, e.g. a name: "重庆", "乐乐".
+	Word string `json:"word" yaml:"word"`
+
+	// Pinyin is the pronunciation in pinyin with tone numbers, one syllable
+	// per character, e.g. "chong2 qing4". Tone 5 is the neutral tone.
+	Pinyin string `json:"pinyin,omitempty" yaml:"pinyin,omitempty"`
+
+	// Alias is spoken instead of Word if Pinyin is empty, e.g. "gizz toy"
+	// for "Giztoy".
+	Alias string `json:"alias,omitempty" yaml:"alias,omitempty"`
+}
+
+// Lexicon is a pronunciation dictionary. Set it on a TTS request or session
+// to fix repeated mispronunciations of names; plain text is then sent as
+// SSML with each lexicon word marked up (see Lexicon.SSML).
+type Lexicon []Pronunciation
+
+// Phoneme returns the SSML markup that speaks word as pinyin, for hints in
+// hand-written SSML (TTSTextTypeSSML):
+//
+//	"<speak>欢迎来到" + doubaospeech.Phoneme("重庆", "chong2 qing4") + "</speak>"
+func Phoneme(word, pinyin string) string {
+	return `<phoneme alphabet="py" ph="` + html.EscapeString(pinyin) + `">` + html.EscapeString(word) + `</phoneme>`
+}
+
+// SSML converts plain text to an SSML document in which each occurrence of
+// a lexicon word is marked up with its pronunciation. Longer words win over
+// words they contain, and matching is case-sensitive. ok is false if no
+// word occurs in text, in which case text can be sent as is.
+func (l Lexicon) SSML(text string) (ssml string, ok bool) {
+	entries := make([]Pronunciation, 0, len(l))
+	for _, p := range l {
+		if p.Word != "" && (p.Pinyin != "" || p.Alias != "") {
+			entries = append(entries, p)
+		}
+	}
+	if len(entries) == 0 {
+		return text, false
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return len(entries[i].Word) > len(entries[j].Word)
+	})
+
+	var b strings.Builder
+	b.WriteString("<speak>")
+	plain := 0
+	for i := 0; i < len(text); {
+		p, found := matchPronunciation(entries, text[i:])
+		if !found {
+			i++
+			continue
+		}
+		b.WriteString(html.EscapeString(text[plain:i]))
+		if p.Pinyin != "" {
+			b.WriteString(Phoneme(p.Word, p.Pinyin))
+		} else {
+			b.WriteString(`<sub alias="` + html.EscapeString(p.Alias) + `">` + html.EscapeString(p.Word) + `</sub>`)
+		}
+		ok = true
+		i += len(p.Word)
+		plain = i
+	}
+	if !ok {
+		return text, false
+	}
+	b.WriteString(html.EscapeString(text[plain:]))
+	b.WriteString("</speak>")
+	return b.String(), true
+}
+
+// matchPronunciation returns the first entry whose word prefixes s; entries
+// are sorted longest first.
+func matchPronunciation(entries []Pronunciation, s string) (Pronunciation, bool) {
+	for _, p := range entries {
+		if strings.HasPrefix(s, p.Word) {
+			return p, true
+		}
+	}
+	return Pronunciation{}, false
+}
+
+// setTextParam sets the text of V2 request params: as "ssml" if the lexicon
+// applies to text, otherwise as "text".
+func setTextParam(params map[string]any, text string, lexicon Lexicon) {
+	if ssml, ok := lexicon.SSML(text); ok {
+		params["ssml"] = ssml
+		return
+	}
+	params["text"] = text
+}
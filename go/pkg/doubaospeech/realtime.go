@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"iter"
 	"sync"
@@ -377,6 +378,17 @@ func (c *RealtimeConnection) StartSession(ctx context.Context, config *RealtimeC
 	return session, nil
 }
 
+// Ping sends a WebSocket ping to check that an idle connection is still
+// usable, e.g. before a pooled connection is handed a session.
+func (c *RealtimeConnection) Ping() error {
+	select {
+	case <-c.closeChan:
+		return errors.New("connection closed")
+	default:
+	}
+	return c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second))
+}
+
 // Close closes the connection
 func (c *RealtimeConnection) Close() error {
 	c.closeOnce.Do(func() {
@@ -398,6 +398,12 @@ func (s *TTSService) buildRequest(req *TTSRequest) *ttsRequest {
 	if req.TextType != "" {
 		ttsReq.Request.TextType = string(req.TextType)
 	}
+	if req.TextType != TTSTextTypeSSML {
+		if ssml, ok := req.Lexicon.SSML(req.Text); ok {
+			ttsReq.Request.Text = ssml
+			ttsReq.Request.TextType = string(TTSTextTypeSSML)
+		}
+	}
 	if req.Encoding != "" {
 		ttsReq.Audio.Encoding = string(req.Encoding)
 	}
@@ -78,14 +78,26 @@ type TTSV2Request struct {
 	SpeedRatio  float64 `json:"speed_ratio,omitempty" yaml:"speed_ratio,omitempty"`   // 0.2-3.0, default 1.0
 	VolumeRatio float64 `json:"volume_ratio,omitempty" yaml:"volume_ratio,omitempty"` // 0.1-3.0, default 1.0
 	PitchRatio  float64 `json:"pitch_ratio,omitempty" yaml:"pitch_ratio,omitempty"`   // 0.1-3.0, default 1.0
-	Emotion     string  `json:"emotion,omitempty" yaml:"emotion,omitempty"`           // happy, sad, angry, fear, hate, surprise
+	Emotion     string  `json:"emotion,omitempty" yaml:"emotion,omitempty"`           // happy, sad, angry, fear, hate, surprise, storytelling
 	Language    string  `json:"language,omitempty" yaml:"language,omitempty"`         // zh, en, ja, etc.
 
+	// EmotionScale is the emotion intensity (energy), 1-5 (default 4).
+	// Only applies when Emotion is set.
+	EmotionScale float64 `json:"emotion_scale,omitempty" yaml:"emotion_scale,omitempty"`
+
 	// Resource ID (default: seed-tts-2.0)
 	ResourceID string `json:"resource_id,omitempty" yaml:"resource_id,omitempty"`
 
 	// Mixed speaker for voice mixing
 	MixSpeaker *MixSpeakerConfig `json:"mix_speaker,omitempty" yaml:"mix_speaker,omitempty"`
+
+	// Lexicon fixes the pronunciation of words in Text, which is then sent
+	// as SSML.
+	Lexicon Lexicon `json:"lexicon,omitempty" yaml:"lexicon,omitempty"`
+
+	// EnableTimestamp requests the timing of each synthesized sentence and
+	// its words, returned in TTSV2Chunk.Sentence.
+	EnableTimestamp bool `json:"enable_timestamp,omitempty" yaml:"enable_timestamp,omitempty"`
 }
 
 // MixSpeakerConfig represents mixed speaker configuration
@@ -108,6 +120,25 @@ type TTSV2Chunk struct {
 	IsLast  bool   `json:"is_last"`
 	ReqID   string `json:"reqid"`
 	Payload []byte `json:"-"` // Raw payload for debugging
+
+	// Sentence is the timing of a synthesized sentence, sent with
+	// EnableTimestamp after the audio of the sentence.
+	Sentence *TTSV2Sentence `json:"sentence,omitempty"`
+}
+
+// TTSV2Sentence is the timing of a synthesized sentence.
+type TTSV2Sentence struct {
+	Text  string      `json:"text"`
+	Words []TTSV2Word `json:"words,omitempty"`
+}
+
+// TTSV2Word is the timing of a word (a character in Chinese) in the audio
+// of a request.
+type TTSV2Word struct {
+	Word       string  `json:"word"`
+	StartTime  float64 `json:"startTime"` // seconds
+	EndTime    float64 `json:"endTime"`   // seconds
+	Confidence float64 `json:"confidence"`
 }
 
 // Stream synthesizes speech using streaming HTTP API
@@ -175,11 +206,12 @@ func (s *TTSServiceV2) Stream(ctx context.Context, req *TTSV2Request) iter.Seq2[
 			}
 
 			var chunkResp struct {
-				ReqID   string `json:"reqid"`
-				Data    string `json:"data"`    // base64 encoded audio
-				Done    bool   `json:"done"`    // last chunk
-				Code    int    `json:"code"`    // error code
-				Message string `json:"message"` // error message
+				ReqID    string         `json:"reqid"`
+				Data     string         `json:"data"`     // base64 encoded audio
+				Done     bool           `json:"done"`     // last chunk
+				Code     int            `json:"code"`     // error code
+				Message  string         `json:"message"`  // error message
+				Sentence *TTSV2Sentence `json:"sentence"` // with enable_timestamp
 			}
 
 			if err := json.Unmarshal(line, &chunkResp); err != nil {
@@ -197,8 +229,9 @@ func (s *TTSServiceV2) Stream(ctx context.Context, req *TTSV2Request) iter.Seq2[
 			}
 
 			chunk := &TTSV2Chunk{
-				ReqID:  chunkResp.ReqID,
-				IsLast: chunkResp.Done,
+				ReqID:    chunkResp.ReqID,
+				IsLast:   chunkResp.Done,
+				Sentence: chunkResp.Sentence,
 			}
 
 			if chunkResp.Data != "" {
@@ -250,19 +283,27 @@ func (s *TTSServiceV2) buildRequestBody(req *TTSV2Request) map[string]any {
 	if req.Emotion != "" {
 		audioParams["emotion"] = req.Emotion
 	}
+	if req.EmotionScale > 0 {
+		audioParams["emotion_scale"] = req.EmotionScale
+	}
 	if req.Language != "" {
 		audioParams["language"] = req.Language
 	}
+	if req.EnableTimestamp {
+		audioParams["enable_timestamp"] = true
+	}
+
+	reqParams := map[string]any{
+		"speaker":      req.Speaker,
+		"audio_params": audioParams,
+	}
+	setTextParam(reqParams, req.Text, req.Lexicon)
 
 	body := map[string]any{
 		"user": map[string]any{
 			"uid": s.client.config.userID,
 		},
-		"req_params": map[string]any{
-			"text":         req.Text,
-			"speaker":      req.Speaker,
-			"audio_params": audioParams,
-		},
+		"req_params": reqParams,
 	}
 
 	if req.MixSpeaker != nil {
@@ -298,8 +339,16 @@ type TTSV2SessionConfig struct {
 	Emotion     string  `json:"emotion,omitempty" yaml:"emotion,omitempty"`
 	Language    string  `json:"language,omitempty" yaml:"language,omitempty"`
 
+	// EmotionScale is the emotion intensity (energy), 1-5 (default 4).
+	EmotionScale float64 `json:"emotion_scale,omitempty" yaml:"emotion_scale,omitempty"`
+
 	// Resource ID (default: seed-tts-2.0)
 	ResourceID string `json:"resource_id,omitempty" yaml:"resource_id,omitempty"`
+
+	// Lexicon fixes the pronunciation of words in the text of each
+	// SendText call, which is then sent as SSML. A word split across two
+	// calls is not matched, so send whole sentences.
+	Lexicon Lexicon `json:"lexicon,omitempty" yaml:"lexicon,omitempty"`
 }
 
 // TTSV2Session represents a bidirectional WebSocket TTS session
@@ -316,6 +365,11 @@ type TTSV2Session struct {
 	closeOnce sync.Once
 	sequence  int32
 	started   bool
+
+	// styled holds an unterminated style tag from the last SendStyledText.
+	styled string
+	// style is the style set by the last style tag, nil for session defaults.
+	style *TTSV2Style
 }
 
 // OpenSession opens a bidirectional WebSocket TTS session
@@ -559,6 +613,9 @@ func (s *TTSV2Session) sendSessionStart() error {
 	if s.config.Emotion != "" {
 		audioParams["emotion"] = s.config.Emotion
 	}
+	if s.config.EmotionScale > 0 {
+		audioParams["emotion_scale"] = s.config.EmotionScale
+	}
 	if s.config.Language != "" {
 		audioParams["language"] = s.config.Language
 	}
@@ -580,20 +637,30 @@ func (s *TTSV2Session) sendSessionStart() error {
 }
 
 func (s *TTSV2Session) sendTaskRequest(text string, isLast bool) error {
+	return s.sendStyledTaskRequest(text, nil)
+}
+
+// sendStyledTaskRequest sends a TaskRequest. A non-nil style is sent as
+// audio_params and applies to this text only.
+func (s *TTSV2Session) sendStyledTaskRequest(text string, style *TTSV2Style) error {
 	// TaskRequest payload format:
 	// {
 	//   "user": {"uid": "xxx"},
 	//   "event": 200,
-	//   "req_params": {"text": "xxx"}
+	//   "req_params": {"text": "xxx", "audio_params": {...}}
 	// }
+	reqParams := map[string]any{}
+	setTextParam(reqParams, text, s.config.Lexicon)
+	if style != nil {
+		reqParams["speaker"] = s.config.Speaker
+		reqParams["audio_params"] = style.audioParams(s.config)
+	}
 	payload := map[string]any{
 		"user": map[string]any{
 			"uid": s.client.config.userID,
 		},
-		"event": ttsV2EventTaskRequest,
-		"req_params": map[string]any{
-			"text": text,
-		},
+		"event":      ttsV2EventTaskRequest,
+		"req_params": reqParams,
 	}
 	return s.sendV2BinaryMessage(ttsV2EventTaskRequest, payload)
 }
@@ -0,0 +1,241 @@
+package doubaospeech
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// TTS V2 emotions. Which emotions a speaker supports depends on the voice;
+// multi-emotion voices list theirs in Console.ListTimbres (TimbreEmotion).
+const (
+	TTSEmotionHappy        = "happy"
+	TTSEmotionSad          = "sad"
+	TTSEmotionAngry        = "angry"
+	TTSEmotionFear         = "fear"
+	TTSEmotionHate         = "hate"
+	TTSEmotionSurprise     = "surprise"
+	TTSEmotionStorytelling = "storytelling"
+	TTSEmotionNeutral      = "neutral"
+)
+
+var ttsV2Emotions = map[string]struct{}{
+	TTSEmotionHappy:        {},
+	TTSEmotionSad:          {},
+	TTSEmotionAngry:        {},
+	TTSEmotionFear:         {},
+	TTSEmotionHate:         {},
+	TTSEmotionSurprise:     {},
+	TTSEmotionStorytelling: {},
+	TTSEmotionNeutral:      {},
+}
+
+// TTSV2Style is a set of voice style parameters for part of a bidirectional
+// TTS session. Zero fields fall back to the session config.
+type TTSV2Style struct {
+	Emotion      string  `json:"emotion,omitempty" yaml:"emotion,omitempty"`             // happy, sad, storytelling, ...
+	EmotionScale float64 `json:"emotion_scale,omitempty" yaml:"emotion_scale,omitempty"` // emotion intensity (energy), 1-5
+	SpeedRatio   float64 `json:"speed_ratio,omitempty" yaml:"speed_ratio,omitempty"`     // 0.2-3.0
+	VolumeRatio  float64 `json:"volume_ratio,omitempty" yaml:"volume_ratio,omitempty"`   // 0.1-3.0
+	PitchRatio   float64 `json:"pitch_ratio,omitempty" yaml:"pitch_ratio,omitempty"`     // 0.1-3.0
+}
+
+// audioParams merges the style over the session config.
+func (st *TTSV2Style) audioParams(config *TTSV2SessionConfig) map[string]any {
+	params := map[string]any{}
+	if config.Format != "" {
+		params["format"] = config.Format
+	}
+	if config.SampleRate > 0 {
+		params["sample_rate"] = config.SampleRate
+	}
+	if config.Language != "" {
+		params["language"] = config.Language
+	}
+	set := func(key string, v, fallback float64) {
+		if v > 0 {
+			params[key] = v
+		} else if fallback > 0 {
+			params[key] = fallback
+		}
+	}
+	set("speed_ratio", st.SpeedRatio, config.SpeedRatio)
+	set("volume_ratio", st.VolumeRatio, config.VolumeRatio)
+	set("pitch_ratio", st.PitchRatio, config.PitchRatio)
+	set("emotion_scale", st.EmotionScale, config.EmotionScale)
+	if st.Emotion != "" {
+		params["emotion"] = st.Emotion
+	} else if config.Emotion != "" {
+		params["emotion"] = config.Emotion
+	}
+	return params
+}
+
+// TTSV2StyledText is a piece of text with the style it is spoken in.
+// Style is nil for the session's default style.
+type TTSV2StyledText struct {
+	Text  string
+	Style *TTSV2Style
+}
+
+// SendTextWithStyle sends text to synthesize in the given style, without
+// changing the style of later text. A nil style uses the session config.
+func (s *TTSV2Session) SendTextWithStyle(ctx context.Context, text string, style *TTSV2Style, isLast bool) error {
+	s.sequence++
+	if err := s.sendStyledTaskRequest(text, style); err != nil {
+		return err
+	}
+	if isLast {
+		return s.sendFinishSession()
+	}
+	return nil
+}
+
+// SendStyledText sends text containing inline style tags (see
+// ParseStyleTags). A tag switches the style of all following text, also
+// across calls, until the next tag; [default] returns to the session
+// config. This lets one session narrate a story with per-sentence
+// emotions, e.g. text streamed from an LLM prompted to emit tags.
+//
+// A tag split across two calls is held back until it is complete.
+func (s *TTSV2Session) SendStyledText(ctx context.Context, text string, isLast bool) error {
+	text = s.styled + text
+	s.styled = ""
+	if !isLast {
+		if i := strings.LastIndexByte(text, '['); i >= 0 && !strings.ContainsRune(text[i:], ']') {
+			text, s.styled = text[:i], text[i:]
+		}
+	}
+
+	var segments []TTSV2StyledText
+	segments, s.style = parseStyleTags(text, s.style)
+	for _, seg := range segments {
+		if err := s.SendTextWithStyle(ctx, seg.Text, seg.Style, false); err != nil {
+			return err
+		}
+	}
+	if isLast {
+		return s.sendFinishSession()
+	}
+	return nil
+}
+
+// ParseStyleTags splits text at inline style tags.
+//
+// A tag is a bracketed emotion name (one of the TTSEmotion constants) or a
+// list of key=value settings:
+//
+//	[storytelling] Once upon a time... [sad] The king was ill.
+//	[emotion=happy energy=5 pitch=1.2 speed=0.9] Hooray!
+//	[default] Back to the normal voice.
+//
+// Keys are emotion, energy (EmotionScale), pitch, speed and volume. Each
+// tag fully replaces the previous style. Brackets that do not form a valid
+// tag are kept as text. Empty segments are omitted.
+func ParseStyleTags(text string) []TTSV2StyledText {
+	segments, _ := parseStyleTags(text, nil)
+	return segments
+}
+
+// parseStyleTags splits text starting in style and also returns the style
+// in effect at the end of text.
+func parseStyleTags(text string, style *TTSV2Style) ([]TTSV2StyledText, *TTSV2Style) {
+	var segments []TTSV2StyledText
+	var buf strings.Builder
+	flush := func() {
+		if buf.Len() > 0 {
+			segments = append(segments, TTSV2StyledText{Text: buf.String(), Style: style})
+		}
+		buf.Reset()
+	}
+
+	for {
+		start := strings.IndexByte(text, '[')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start:], ']')
+		if end < 0 {
+			break
+		}
+		end += start
+		next, ok := parseStyleTag(text[start+1 : end])
+		if !ok {
+			buf.WriteString(text[:end+1])
+			text = text[end+1:]
+			continue
+		}
+		buf.WriteString(text[:start])
+		flush()
+		style = next
+		text = text[end+1:]
+	}
+	buf.WriteString(text)
+	flush()
+	return segments, style
+}
+
+// parseStyleTag parses the content of a style tag. It returns a nil style
+// for [default].
+func parseStyleTag(tag string) (*TTSV2Style, bool) {
+	fields := strings.Fields(tag)
+	if len(fields) == 0 {
+		return nil, false
+	}
+	if len(fields) == 1 && !strings.Contains(fields[0], "=") {
+		name := strings.ToLower(fields[0])
+		if name == "default" {
+			return nil, true
+		}
+		if _, ok := ttsV2Emotions[name]; !ok {
+			return nil, false
+		}
+		return &TTSV2Style{Emotion: name}, true
+	}
+
+	style := &TTSV2Style{}
+	for _, f := range fields {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok {
+			return nil, false
+		}
+		if strings.ToLower(key) == "emotion" {
+			if !isStyleWord(value) {
+				return nil, false
+			}
+			style.Emotion = strings.ToLower(value)
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || v <= 0 {
+			return nil, false
+		}
+		switch strings.ToLower(key) {
+		case "energy":
+			style.EmotionScale = v
+		case "pitch":
+			style.PitchRatio = v
+		case "speed":
+			style.SpeedRatio = v
+		case "volume":
+			style.VolumeRatio = v
+		default:
+			return nil, false
+		}
+	}
+	return style, true
+}
+
+// isStyleWord reports whether s looks like an emotion name (ASCII letters
+// and underscores).
+func isStyleWord(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
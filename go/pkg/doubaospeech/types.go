@@ -147,6 +147,11 @@ type TTSRequest struct {
 	Language        Language      `json:"language,omitempty" yaml:"language,omitempty"`
 	EnableSubtitle  bool          `json:"enable_subtitle,omitempty" yaml:"enable_subtitle,omitempty"`
 	SilenceDuration int           `json:"silence_duration,omitempty" yaml:"silence_duration,omitempty"`
+
+	// Lexicon fixes the pronunciation of words in plain text; the text is
+	// then sent as SSML. Ignored if TextType is TTSTextTypeSSML, which can
+	// use Phoneme markup directly.
+	Lexicon Lexicon `json:"lexicon,omitempty" yaml:"lexicon,omitempty"`
 }
 
 // TTSResponse represents TTS synthesis response
@@ -36,6 +36,29 @@ const (
 
 	// EventInterrupted indicates the agent was interrupted via Interrupt().
 	EventInterrupted
+
+	// EventToolChunk carries a chunk of output from a running streaming tool.
+	EventToolChunk
+
+	// EventBlocked indicates a guardrail blocked the input or the output
+	// (see Guardrail).
+	EventBlocked
+
+	// EventLimited indicates the round reached one of its limits (see
+	// agentcfg.RoundLimits). The final answer, if any, follows.
+	EventLimited
+
+	// EventProactive indicates a trigger started or poked the agent rather
+	// than user input (see Trigger). The agent's output follows.
+	EventProactive
+
+	// EventToolApprovalRequired indicates a tool call waits for approval
+	// (see ToolApprover). The next event follows Approve or Reject.
+	EventToolApprovalRequired
+
+	// EventMatched indicates a MatchAgent matched the input to rules and
+	// routes it to their agents, whose events follow.
+	EventMatched
 )
 
 // String returns the string representation of the event type.
@@ -55,6 +78,18 @@ func (t EventType) String() string {
 		return "tool_error"
 	case EventInterrupted:
 		return "interrupted"
+	case EventToolChunk:
+		return "tool_chunk"
+	case EventBlocked:
+		return "blocked"
+	case EventLimited:
+		return "limited"
+	case EventProactive:
+		return "proactive"
+	case EventToolApprovalRequired:
+		return "tool_approval_required"
+	case EventMatched:
+		return "matched"
 	default:
 		return "unknown"
 	}
@@ -78,10 +113,11 @@ type AgentEvent struct {
 	// Combined with AgentDef, this uniquely identifies the source agent.
 	AgentStateID string
 
-	// Chunk contains the message chunk (for EventChunk).
+	// Chunk contains the message chunk (for EventChunk and EventToolChunk).
 	Chunk *genx.MessageChunk
 
-	// ToolCall contains the tool call info (for EventToolStart).
+	// ToolCall contains the tool call info (for EventToolStart, EventToolChunk,
+	// EventToolDone, EventToolError and EventToolApprovalRequired).
 	ToolCall *genx.ToolCall
 
 	// ToolResult contains the tool result (for EventToolDone).
@@ -89,6 +125,22 @@ type AgentEvent struct {
 
 	// ToolError contains the tool execution error (for EventToolError).
 	ToolError error
+
+	// Moderation contains the guardrail verdict (for EventBlocked, for an
+	// EventChunk whose text a guardrail rewrote or labeled, and for the
+	// first event after an input a guardrail rewrote or labeled).
+	Moderation *GuardrailVerdict
+
+	// Limit is the limit the round reached, wrapping ErrRoundLimit (for
+	// EventLimited).
+	Limit error
+
+	// Trigger contains the trigger firing (for EventProactive).
+	Trigger *TriggerFiring
+
+	// Matches contains the matched intents, with their source and score
+	// (for EventMatched).
+	Matches []MatchedIntent
 }
 
 // IsTerminal returns true if this event indicates the agent should stop.
@@ -120,9 +172,14 @@ type Agent interface {
 	//   - EventEOF: Current round ended, call Input() to provide new input.
 	//   - EventClosed: Agent completed (quit tool) or closed, stop reading.
 	//   - EventToolStart: Tool execution started.
+	//   - EventToolChunk: Output chunk of a running streaming tool.
 	//   - EventToolDone: Tool execution completed successfully.
 	//   - EventToolError: Tool execution failed.
 	//   - EventInterrupted: Agent was interrupted via Interrupt().
+	//   - EventBlocked: A guardrail blocked the input or the output.
+	//   - EventLimited: The round reached one of its limits.
+	//   - EventToolApprovalRequired: A tool call waits for approval (see ToolApprover).
+	//   - EventMatched: A MatchAgent matched the input; its sub-agent's events follow.
 	//
 	// After EventEOF, Next() will block until Input() is called.
 	// After EventClosed or EventInterrupted, subsequent Next() calls return the same event.
@@ -135,6 +192,11 @@ type Agent interface {
 	// This is useful for converting a sub-agent's conversation into a tool result.
 	FormatHistory(ctx context.Context) string
 
+	// Snapshot serializes the agent's conversation state, pending tool calls
+	// and scratchpad, so that ResumeAgent can continue it mid-conversation
+	// (e.g. after a server restart or a device reconnect).
+	Snapshot() ([]byte, error)
+
 	// Close closes the Agent.
 	Close() error
 
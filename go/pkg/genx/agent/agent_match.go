@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/haivivi/giztoy/go/pkg/genx"
@@ -34,12 +35,27 @@ type roundtripEvent struct {
 	err   error
 }
 
+// MatchSource is how a MatchAgent matched an intent.
+type MatchSource string
+
+const (
+	MatchSourcePattern MatchSource = "pattern" // The patterns and examples of the rule, without a model
+	MatchSourceLLM     MatchSource = "llm"     // The LLM classifier
+)
+
 // MatchedIntent represents a single matched intent with its routing info.
 type MatchedIntent struct {
 	Rule     string         `json:"rule" msgpack:"rule"`
 	Args     map[string]any `json:"args,omitzero" msgpack:"args,omitempty"`
 	AgentRef string         `json:"agent_ref,omitzero" msgpack:"agent_ref,omitempty"`
 	AgentDef agentcfg.Agent `json:"-" msgpack:"-"` // Inline agent def (not serialized)
+
+	// Source is how the intent was matched.
+	Source MatchSource `json:"source,omitzero" msgpack:"source,omitempty"`
+	// Score is the confidence of the pattern match of the rule, from 0 to
+	// 1 (see match.Matcher.Score). It is 0 unless the agent sets a
+	// confidence threshold; LLM matches keep the score that fell short.
+	Score float64 `json:"score,omitzero" msgpack:"score,omitempty"`
 }
 
 // MatchAgent is an Agent that matches user input against rules and routes to sub-agents.
@@ -74,9 +90,13 @@ type MatchedIntent struct {
 // # Execution Flow
 //
 //  1. Input: User provides text input via Input()
-//  2. Match: LLM matches input against rules to identify intent(s)
+//  2. Match: LLM matches input against rules to identify intent(s); with a
+//     confidence threshold, the rules' patterns and examples are scored
+//     first and the LLM is only asked below the threshold
+//     (see agentcfg.MatchConfidence)
 //  3. Route: Matched intents are looked up in route map to find target agent
-//  4. Execute: Sub-agent is created and receives the input
+//  4. Execute: Sub-agent is created and receives the input; EventMatched
+//     reports the matched intents with their source and score
 //  5. Stream: Events from sub-agent are forwarded to caller
 //  6. Complete: When sub-agent finishes, next matched intent is processed (if any)
 //  7. No Match: If no rules match, returns EOF immediately (caller handles fallback)
@@ -108,7 +128,7 @@ type MatchAgent struct {
 	//   - inputReady channel operations
 	//
 	// Note: 'state' is thread-safe (see MatchState interface) and does NOT require mu.
-	// Note: 'matcher' and 'routeMap' are read-only after initialization and do NOT require mu.
+	// Note: 'matcher', 'routeMap' and 'tracer' are read-only after initialization and do NOT require mu.
 	mu     sync.Mutex
 	ctx    context.Context    // protected by mu
 	cancel context.CancelFunc // protected by mu
@@ -119,6 +139,7 @@ type MatchAgent struct {
 	// Runtime components (read-only after init, no mu needed)
 	matcher  *match.Matcher
 	routeMap map[string]*agentcfg.MatchRoute // rule name -> route config
+	tracer   *decisionTracer                 // nil unless the definition enables tracing
 
 	// calling is the currently executing sub-agent; protected by mu
 	calling Agent
@@ -184,6 +205,13 @@ func NewMatchAgentWithState(ctx context.Context, def *agentcfg.MatchAgent, rt Ru
 		}
 	}
 
+	tracer := newDecisionTracer(ctx, &def.AgentBase, rt, state.ID())
+	if tracer != nil {
+		for _, rule := range rules {
+			tracer.candidates = append(tracer.candidates, rule.Name)
+		}
+	}
+
 	return &MatchAgent{
 		def:        def,
 		rt:         rt,
@@ -192,6 +220,7 @@ func NewMatchAgentWithState(ctx context.Context, def *agentcfg.MatchAgent, rt Ru
 		state:      state,
 		matcher:    matcher,
 		routeMap:   routeMap,
+		tracer:     tracer,
 		inputReady: make(chan struct{}, 1),
 	}, nil
 }
@@ -258,6 +287,9 @@ func (a *MatchAgent) Input(contents genx.Contents) error {
 		a.state.SetInput(text)
 	}
 
+	a.tracer.startRound()
+	a.tracer.record(&Decision{Kind: DecisionInput, Input: text})
+
 	// Create new roundtrip
 	ctx, cancel := context.WithCancel(a.ctx)
 	a.currentRound = &roundtrip{
@@ -403,7 +435,7 @@ func (a *MatchAgent) runRoundtripWithCalling(round *roundtrip, inputText string,
 			}
 			if result.switched {
 				// Intent switched, continue with new agent if exists
-				if a.hasCalling() {
+				if a.hasCalling() && send(a.matchedEvent(), nil) {
 					a.runCallingLoop(round)
 				}
 				return
@@ -432,7 +464,9 @@ func (a *MatchAgent) runRoundtripWithCalling(round *roundtrip, inputText string,
 				return
 			}
 			if result.switched {
-				a.runCallingLoop(round)
+				if send(a.matchedEvent(), nil) {
+					a.runCallingLoop(round)
+				}
 				return
 			}
 
@@ -451,7 +485,9 @@ func (a *MatchAgent) runRoundtripWithCalling(round *roundtrip, inputText string,
 				return
 			}
 			if result.switched {
-				a.runCallingLoop(round)
+				if send(a.matchedEvent(), nil) {
+					a.runCallingLoop(round)
+				}
 				return
 			}
 
@@ -518,11 +554,23 @@ func (a *MatchAgent) runRoundtripFreshMatch(round *roundtrip, send func(*AgentEv
 	if !started {
 		return
 	}
+	if !send(a.matchedEvent(), nil) {
+		return
+	}
 
 	// Run calling loop
 	a.runCallingLoop(round)
 }
 
+// matchedEvent returns the EventMatched of the current matches.
+func (a *MatchAgent) matchedEvent() *AgentEvent {
+	return a.tagEvent(&AgentEvent{
+		Type:    EventMatched,
+		Phase:   string(MatchPhaseExecuting),
+		Matches: a.state.Matches(),
+	})
+}
+
 // performFreshMatch sets phase to matching and performs the match.
 func (a *MatchAgent) performFreshMatch(ctx context.Context) error {
 	a.mu.Lock()
@@ -680,9 +728,32 @@ func (a *MatchAgent) forwardInputToCalling(contents genx.Contents) {
 	}
 }
 
-// doMatch performs the matching using the LLM.
+// doMatch matches the input against the rules. With a confidence
+// threshold, the patterns and examples of the rules are tried first and the
+// LLM only classifies inputs none of them matches confidently.
 // Note: caller must hold a.mu.
 func (a *MatchAgent) doMatch(ctx context.Context) error {
+	input := a.state.Input()
+
+	// Scores of the local match by rule, also reported for LLM matches
+	scores := make(map[string]float64)
+	if conf := a.def.Confidence; conf != nil {
+		local := a.matcher.Score(input)
+		for _, r := range local {
+			scores[r.Rule] = r.Score
+		}
+		if len(local) > 0 && local[0].Score >= conf.EffectiveThreshold() {
+			a.traceMatch(input, local[:1], nil)
+			a.setMatches(local[:1], MatchSourcePattern, scores)
+			return nil
+		}
+		if conf.Fallback == agentcfg.MatchFallbackNone {
+			a.traceMatch(input, nil, nil)
+			a.setMatches(nil, MatchSourcePattern, scores)
+			return nil
+		}
+	}
+
 	// Get model name
 	if a.def.Generator.IsEmpty() {
 		return fmt.Errorf("generator.model is required")
@@ -699,7 +770,7 @@ func (a *MatchAgent) doMatch(ctx context.Context) error {
 
 	// Build model context with user input
 	mcb := &genx.ModelContextBuilder{}
-	mcb.UserText("", a.state.Input())
+	mcb.UserText("", input)
 	mc := mcb.Build()
 
 	// Create generator adapter
@@ -714,10 +785,38 @@ func (a *MatchAgent) doMatch(ctx context.Context) error {
 	// Re-acquire lock
 	a.mu.Lock()
 
+	a.traceMatch(input, results, err)
 	if err != nil {
 		return fmt.Errorf("match: %w", err)
 	}
+	a.setMatches(results, MatchSourceLLM, scores)
+	return nil
+}
+
+// traceMatch records a match decision.
+func (a *MatchAgent) traceMatch(input string, results []match.Result, err error) {
+	if a.tracer == nil {
+		return
+	}
+	d := &Decision{Kind: DecisionMatch, Input: input}
+	var unmatched []string
+	for _, r := range results {
+		if r.Rule != "" {
+			d.Matches = append(d.Matches, r.Rule)
+		} else if r.RawText != "" {
+			unmatched = append(unmatched, r.RawText)
+		}
+	}
+	d.Text = strings.Join(unmatched, "\n")
+	if err != nil {
+		d.Error = err.Error()
+	}
+	a.tracer.record(d)
+}
 
+// setMatches stores the routed results as the matched intents, with their
+// source and their local scores.
+func (a *MatchAgent) setMatches(results []match.Result, source MatchSource, scores map[string]float64) {
 	// Clear previous matches
 	a.state.SetMatches(nil)
 
@@ -743,8 +842,10 @@ func (a *MatchAgent) doMatch(ctx context.Context) error {
 		}
 
 		intent := MatchedIntent{
-			Rule: r.Rule,
-			Args: args,
+			Rule:   r.Rule,
+			Args:   args,
+			Source: source,
+			Score:  scores[r.Rule],
 		}
 
 		// Store agent reference or inline def
@@ -757,8 +858,6 @@ func (a *MatchAgent) doMatch(ctx context.Context) error {
 		matches = append(matches, intent)
 	}
 	a.state.SetMatches(matches)
-
-	return nil
 }
 
 // startNextAgent starts the next sub-agent in the execution queue.
@@ -1139,3 +1139,101 @@ func TestMatchAgent_SubAgent_ParentStateID(t *testing.T) {
 	t.Logf("Sub-agent ParentStateID: %s", subAgentParentID)
 	t.Log("Call stack verified: sub-agent correctly references parent MatchAgent")
 }
+
+// newConfidentMatchAgent creates the intent_router agent with conf. The LLM
+// classifier of the runtime answers matchResult.
+func newConfidentMatchAgent(t *testing.T, matchResult string, conf *agentcfg.MatchConfidence) *agent.MatchAgent {
+	t.Helper()
+	ctx := context.Background()
+	rt := setupMatchAgentTestRuntimeWithMatchResult(t, matchResult)
+	agentDef, err := rt.GetAgentDef(ctx, "intent_router")
+	if err != nil {
+		t.Fatalf("GetAgentDef error: %v", err)
+	}
+	def := *agentcfg.AsMatchAgent(agentDef)
+	def.Confidence = conf
+	a, err := agent.NewMatchAgent(ctx, &def, rt, "")
+	if err != nil {
+		t.Fatalf("NewMatchAgent error: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+// firstMatchEvent inputs text and returns the first event that follows.
+func firstMatchEvent(t *testing.T, a agent.Agent, text string) *agent.AgentEvent {
+	t.Helper()
+	if err := a.Input(genx.Contents{genx.Text(text)}); err != nil {
+		t.Fatalf("Input error: %v", err)
+	}
+	evt, err := a.Next()
+	if err != nil {
+		t.Fatalf("Next error: %v", err)
+	}
+	return evt
+}
+
+func TestMatchAgent_Confidence_PatternMatch(t *testing.T) {
+	// The LLM would pick weather_query; the examples of greeting match first
+	a := newConfidentMatchAgent(t, "weather_query", &agentcfg.MatchConfidence{})
+
+	evt := firstMatchEvent(t, a, "Hello!")
+	if evt.Type != agent.EventMatched {
+		t.Fatalf("event type = %v, want EventMatched", evt.Type)
+	}
+	if len(evt.Matches) != 1 {
+		t.Fatalf("Matches = %+v, want 1", evt.Matches)
+	}
+	m := evt.Matches[0]
+	if m.Rule != "greeting" || m.Source != agent.MatchSourcePattern || m.Score != 1 {
+		t.Errorf("match = %s from %s scoring %v, want greeting from pattern scoring 1", m.Rule, m.Source, m.Score)
+	}
+	if evt.AgentDef != "intent_router" {
+		t.Errorf("AgentDef = %q, want intent_router", evt.AgentDef)
+	}
+}
+
+func TestMatchAgent_Confidence_LLMFallback(t *testing.T) {
+	a := newConfidentMatchAgent(t, "greeting", &agentcfg.MatchConfidence{Threshold: 0.95})
+
+	evt := firstMatchEvent(t, a, "good evening, friend")
+	if evt.Type != agent.EventMatched {
+		t.Fatalf("event type = %v, want EventMatched", evt.Type)
+	}
+	if len(evt.Matches) != 1 {
+		t.Fatalf("Matches = %+v, want 1", evt.Matches)
+	}
+	m := evt.Matches[0]
+	if m.Rule != "greeting" || m.Source != agent.MatchSourceLLM {
+		t.Errorf("match = %s from %s, want greeting from llm", m.Rule, m.Source)
+	}
+	if m.Score <= 0 || m.Score >= 0.95 {
+		t.Errorf("Score = %v, want the pattern score below the threshold", m.Score)
+	}
+}
+
+func TestMatchAgent_Confidence_NoFallback(t *testing.T) {
+	a := newConfidentMatchAgent(t, "weather_query", &agentcfg.MatchConfidence{
+		Fallback: agentcfg.MatchFallbackNone,
+	})
+
+	evt := firstMatchEvent(t, a, "tell me a joke")
+	if evt.Type != agent.EventEOF {
+		t.Fatalf("event type = %v, want EventEOF", evt.Type)
+	}
+	if evt.Phase != "" {
+		t.Errorf("Phase = %q, want empty string (idle)", evt.Phase)
+	}
+}
+
+func TestMatchAgent_NoConfidence_MatchedEvent(t *testing.T) {
+	a := newConfidentMatchAgent(t, "greeting", nil)
+
+	evt := firstMatchEvent(t, a, "hello")
+	if evt.Type != agent.EventMatched {
+		t.Fatalf("event type = %v, want EventMatched", evt.Type)
+	}
+	if len(evt.Matches) != 1 || evt.Matches[0].Source != agent.MatchSourceLLM || evt.Matches[0].Score != 0 {
+		t.Errorf("Matches = %+v, want greeting from llm without score", evt.Matches)
+	}
+}
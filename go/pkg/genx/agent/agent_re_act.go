@@ -2,10 +2,14 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"iter"
 	"os"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/haivivi/giztoy/go/pkg/genx"
 	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
@@ -36,6 +40,7 @@ const (
 // The agent continues the think-act-observe cycle until either:
 //   - A quit/finish tool is called (configured via ToolRef.Quit)
 //   - The LLM generates a final response without tool calls
+//   - The round reaches one of its limits (see Round Limits)
 //   - The agent is explicitly closed
 //
 // # Definition
@@ -75,14 +80,45 @@ const (
 //  3. Generate: Call LLM to generate response (may include tool calls)
 //  4. Stream: Emit EventChunk for each text chunk
 //  5. Tool Call: If LLM requests tool call:
+//     - If the tool requires approval, emit EventToolApprovalRequired and wait
 //     - Emit EventToolStart
-//     - Execute tool
+//     - Execute tool, emitting EventToolChunk for the output of a streaming tool
 //     - Emit EventToolDone or EventToolError
 //     - Store tool result in state
 //     - Continue generation (go to step 3)
 //  6. EOF: When generation ends without tool call, emit EventEOF
 //  7. Quit: If quit tool is called, set finished=true and emit EventClosed
 //
+// # Round Limits
+//
+// "limits" caps the tool calls, generated tokens and wall-clock time of a
+// round (see agentcfg.RoundLimits):
+//
+//	"limits": {"max_tool_calls": 5, "max_tokens": 4000, "timeout": 30}
+//
+// Limits are checked before each tool call and each generation of the
+// round. When one is reached, the agent emits EventLimited and, by default,
+// asks the model once more, without running tools, for a final answer with
+// what it has so far. "on_limit": "stop" ends the round after EventLimited
+// instead, and "on_limit": "error" makes Next return an error wrapping
+// ErrRoundLimit. MaxTokens counts the tokens reported in the usage of the
+// round's generations.
+//
+// # Tool Approval
+//
+// Calls of a tool whose definition has "approval": "required" (see
+// agentcfg.ToolApproval) wait for a human, e.g. a parent app gating volume,
+// OTA or purchases:
+//
+//	{"type": "http", "name": "start_ota", "approval": "required", ...}
+//
+// Instead of EventToolStart, the agent emits EventToolApprovalRequired and
+// the next Next() blocks until Approve or Reject is called with the ID of
+// the call, usually from the goroutine handling the parent app. An approved
+// call runs as usual; a rejected call is not run, the model sees the reason
+// as the tool's error and the agent emits EventToolError wrapping
+// ErrToolRejected. Interrupt, Revert and Close abandon a waiting call.
+//
 // # Tool Types
 //
 // ReActAgent supports various tool types:
@@ -118,10 +154,13 @@ type ReActAgent struct {
 	//   - pendingText (accumulated response)
 	//   - closed, interrupted, finished
 	//   - inputReady channel operations
-	//   - pendingToolEvent
+	//   - toolEvents, toolCancel, toolCall, approval
+	//   - resumeCalls
+	//   - outputBuf, pending, inputVerdict
+	//   - roundToolCalls, roundTokens, roundDeadline, summarizing, roundErr
 	//
 	// Note: 'state' is thread-safe (see ReActState interface) and does NOT require mu.
-	// Note: 'mcb', 'quitTools', 'memOpts' are read-only after initialization and do NOT require mu.
+	// Note: 'mcb', 'quitTools', 'approvalTools', 'delegates', 'guardrails', 'memOpts', 'tracer' are read-only after initialization and do NOT require mu.
 	mu     sync.Mutex
 	ctx    context.Context    // protected by mu
 	cancel context.CancelFunc // protected by mu
@@ -141,9 +180,52 @@ type ReActAgent struct {
 	// quitTools contains tool names that trigger agent completion; read-only after init
 	quitTools map[string]struct{}
 
+	// approvalTools contains tool names whose calls wait for approval;
+	// read-only after init
+	approvalTools map[string]struct{}
+
+	// delegates contains the tools of `$ref: agent:<name>` references by
+	// name; read-only after init
+	delegates map[string]*genx.FuncTool
+
+	// guardrails moderate input and output (see Guardrail); read-only after init
+	guardrails []Guardrail
+
+	// tracer records the decision trace if the definition enables it (see
+	// TraceSink); nil otherwise, read-only after init
+	tracer *decisionTracer
+
 	// pendingText is the accumulated model response in current round; protected by mu
 	pendingText string
 
+	// outputBuf is model text held back until guardrails check it; protected by mu
+	outputBuf string
+
+	// traceText is the model text since the last traced decision, only
+	// kept when tracing; protected by mu
+	traceText string
+
+	// pending are events Next() returns before anything else, e.g. the
+	// EventBlocked of a blocked input; protected by mu
+	pending []*AgentEvent
+
+	// inputVerdict is the guardrail verdict of the latest input, attached to
+	// the next event; protected by mu
+	inputVerdict *GuardrailVerdict
+
+	// --- Round limits (see agentcfg.RoundLimits; protected by mu) ---
+
+	roundToolCalls int
+	roundTokens    int64
+	roundDeadline  time.Time
+
+	// summarizing indicates the final answer of a round that reached a
+	// limit is being generated
+	summarizing bool
+
+	// roundErr is returned by Next() when a limit fails the round
+	roundErr error
+
 	// --- Lifecycle state (protected by mu) ---
 
 	closed      bool
@@ -155,8 +237,19 @@ type ReActAgent struct {
 	// inputReady signals that Input() has been called after EOF
 	inputReady chan struct{}
 
-	// pendingToolEvent holds a tool event to be returned on next Next() call
-	pendingToolEvent *AgentEvent
+	// toolEvents delivers the chunk and result events of the running tool
+	// call, and is closed once the call is done; toolCancel cancels it.
+	toolEvents chan *AgentEvent
+	toolCancel context.CancelFunc
+	toolCall   *genx.ToolCall
+
+	// approval receives the decision on the running tool call while it
+	// waits for approval (see ToolApprover); nil otherwise.
+	approval chan toolApproval
+
+	// resumeCalls are the tool calls that were pending when the agent was
+	// snapshotted; Next() runs them before anything else (see ResumeAgent).
+	resumeCalls []*genx.ToolCall
 }
 
 // NewReActAgent creates a new ReActAgent with a fresh state.
@@ -174,6 +267,8 @@ func NewReActAgent(ctx context.Context, def *agentcfg.ReActAgent, rt Runtime, pa
 // This is used for restoring agents from saved state.
 func NewReActAgentWithState(ctx context.Context, def *agentcfg.ReActAgent, rt Runtime, state ReActState) (*ReActAgent, error) {
 	ctx, cancel := context.WithCancel(ctx)
+	// Agent tools run by this agent create sub-agents under its state
+	ctx = withAgentCaller(ctx, agentCaller{stateID: state.ID(), depth: agentCallerFrom(ctx).depth})
 	mcb := &genx.ModelContextBuilder{}
 
 	// Extract $mem config from context_layers (if any)
@@ -207,12 +302,21 @@ func NewReActAgentWithState(ctx context.Context, def *agentcfg.ReActAgent, rt Ru
 
 	// Load tools from def.Tools to mcb and track quit tools
 	quitTools := make(map[string]struct{})
+	approvalTools := make(map[string]struct{})
+	delegates := make(map[string]*genx.FuncTool)
 	for _, toolRef := range def.Tools {
 		var tool *genx.FuncTool
 		var err error
 		var toolName string
 
-		if toolRef.IsRef() {
+		if strings.HasPrefix(toolRef.Ref, agentRefPrefix) {
+			// $ref: agent:<name> - delegate to another agent
+			tool, err = NewAgentTool(rt).createRefTool(ctx, toolRef.Ref)
+			if tool != nil {
+				toolName = tool.Name
+				delegates[toolName] = tool
+			}
+		} else if toolRef.IsRef() {
 			// $ref - load tool by reference
 			toolName = toolRef.Ref
 			tool, err = rt.GetTool(ctx, toolRef.Ref)
@@ -237,18 +341,39 @@ func NewReActAgentWithState(ctx context.Context, def *agentcfg.ReActAgent, rt Ru
 		if toolRef.Quit {
 			quitTools[toolName] = struct{}{}
 		}
+		if !strings.HasPrefix(toolRef.Ref, agentRefPrefix) && requiresApproval(ctx, rt, toolRef) {
+			approvalTools[tool.Name] = struct{}{}
+		}
+	}
+
+	var guardrails []Guardrail
+	if gr, ok := rt.(GuardrailRuntime); ok {
+		guardrails = gr.Guardrails()
+	}
+	if def.Style != nil {
+		// After the runtime's guardrails, without appending to their slice
+		guardrails = append(slices.Clip(guardrails), NewStyleRewriter(*def.Style, rt))
+	}
+
+	tracer := newDecisionTracer(ctx, &def.AgentBase, rt, state.ID())
+	if tracer != nil {
+		tracer.candidates = toolNames(mcb.Tools)
 	}
 
 	return &ReActAgent{
-		def:        def,
-		rt:         rt,
-		ctx:        ctx,
-		cancel:     cancel,
-		state:      state,
-		memOpts:    memOpts,
-		mcb:        mcb,
-		quitTools:  quitTools,
-		inputReady: make(chan struct{}, 1),
+		def:           def,
+		rt:            rt,
+		ctx:           ctx,
+		cancel:        cancel,
+		state:         state,
+		memOpts:       memOpts,
+		mcb:           mcb,
+		quitTools:     quitTools,
+		approvalTools: approvalTools,
+		delegates:     delegates,
+		guardrails:    guardrails,
+		tracer:        tracer,
+		inputReady:    make(chan struct{}, 1),
 	}, nil
 }
 
@@ -397,9 +522,26 @@ func (a *ReActAgent) buildModelContext() (genx.ModelContext, error) {
 		memPrompts = append(memPrompts, p)
 	}
 
-	// Build combined context: base (prompts, tools) + memory prompts + messages
+	// Assemble the per-turn prompt, if the runtime has an assembler
+	var assembled *genx.Prompt
+	if pr, ok := a.rt.(PromptAssemblerRuntime); ok {
+		if assembler := pr.PromptAssembler(); assembler != nil {
+			assembled, err = assembler.AssemblePrompt(a.ctx, &PromptRequest{
+				AgentDef: a.def.AgentName(),
+				StateID:  a.state.ID(),
+				Input:    lastUserInput(messages),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("assemble prompt: %w", err)
+			}
+		}
+	}
+
+	// Build combined context: base (prompts, tools) + assembled prompt +
+	// memory prompts + messages
 	return &modelContextWithMemory{
 		base:       a.mcb.Build(),
+		assembled:  assembled,
 		memPrompts: memPrompts,
 		messages:   messages,
 	}, nil
@@ -408,6 +550,7 @@ func (a *ReActAgent) buildModelContext() (genx.ModelContext, error) {
 // modelContextWithMemory wraps a base ModelContext with memory-based prompts and messages.
 type modelContextWithMemory struct {
 	base       genx.ModelContext
+	assembled  *genx.Prompt
 	memPrompts []*genx.Prompt
 	messages   []*genx.Message
 }
@@ -420,6 +563,10 @@ func (m *modelContextWithMemory) Prompts() iter.Seq[*genx.Prompt] {
 				return
 			}
 		}
+		// Then the assembled prompt (see PromptAssembler)
+		if m.assembled != nil && !yield(m.assembled) {
+			return
+		}
 		// Then yield memory prompts (summary, query results)
 		for _, p := range m.memPrompts {
 			if !yield(p) {
@@ -517,6 +664,29 @@ func (a *ReActAgent) Input(contents genx.Contents) error {
 		return ErrClosed
 	}
 
+	// Moderate the input before the model or the history sees it
+	contents, verdict, err := a.moderateInput(contents)
+	if err != nil {
+		return err
+	}
+	a.pending = nil
+	a.inputVerdict = nil
+	if verdict.blocked() {
+		a.pending = append(a.pending, a.tagEvent(&AgentEvent{Type: EventBlocked, Moderation: verdict}))
+		if verdict.Text != "" {
+			a.pending = append(a.pending, a.tagEvent(&AgentEvent{
+				Type:  EventChunk,
+				Chunk: &genx.MessageChunk{Role: genx.RoleModel, Part: genx.Text(verdict.Text)},
+			}))
+		}
+		select {
+		case a.inputReady <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+	a.inputVerdict = verdict
+
 	// Store user message to state
 	if err := a.storeUserMessage(contents); err != nil {
 		return fmt.Errorf("store user message: %w", err)
@@ -534,6 +704,12 @@ func (a *ReActAgent) Input(contents genx.Contents) error {
 	}
 	a.stream = stream
 	a.pendingText = "" // reset accumulated text
+	a.outputBuf = ""
+	a.traceText = ""
+	a.resumeCalls = nil
+	a.resetRoundLocked()
+	a.tracer.startRound()
+	a.tracer.record(&Decision{Kind: DecisionInput, Input: contentsText(contents)})
 
 	// Signal that input is ready (unblock Next() if waiting)
 	select {
@@ -559,6 +735,7 @@ func (a *ReActAgent) Interrupt() error {
 	defer a.mu.Unlock()
 
 	a.interrupted = true
+	a.cancelToolLocked()
 
 	if a.stream != nil {
 		return a.stream.Close()
@@ -582,14 +759,21 @@ func (a *ReActAgent) Revert() error {
 		return ErrClosed
 	}
 
-	// Close current stream
+	// Cancel running tool and close current stream
+	a.cancelToolLocked()
 	if a.stream != nil {
 		a.stream.Close()
 		a.stream = nil
 	}
 
-	// Clear pending text
+	// Clear pending text and events and abandon resumed tool calls
+	a.traceText = ""
 	a.pendingText = ""
+	a.outputBuf = ""
+	a.pending = nil
+	a.inputVerdict = nil
+	a.resumeCalls = nil
+	a.resetRoundLocked()
 
 	// Delegate to state
 	return a.state.Revert(a.ctx)
@@ -597,10 +781,40 @@ func (a *ReActAgent) Revert() error {
 
 // Next returns the next output chunk.
 func (a *ReActAgent) Next() (*AgentEvent, error) {
+	evt, err := a.next()
+	if evt != nil {
+		// Report the verdict of a moderated input with the first event
+		a.mu.Lock()
+		if a.inputVerdict != nil && evt.Moderation == nil {
+			evt.Moderation = a.inputVerdict
+			a.inputVerdict = nil
+		}
+		a.mu.Unlock()
+	}
+	return evt, err
+}
+
+func (a *ReActAgent) next() (*AgentEvent, error) {
 	// Check terminal states and get pending event
 	if evt := a.checkNextState(); evt != nil {
 		return evt, nil
 	}
+	if evt := a.popPending(); evt != nil {
+		return evt, nil
+	}
+	if err := a.popRoundErr(); err != nil {
+		return nil, err
+	}
+
+	// Return the events of the running tool call until it is done
+	if evt, ok := a.nextToolEvent(); ok {
+		return evt, nil
+	}
+
+	// Run the tool calls that were pending when the agent was snapshotted
+	if tc := a.popResumeCall(); tc != nil {
+		return a.handleToolCallEvent(tc)
+	}
 
 	// Get or wait for stream
 	stream, err := a.waitForStream()
@@ -608,6 +822,10 @@ func (a *ReActAgent) Next() (*AgentEvent, error) {
 		return nil, err
 	}
 	if stream == nil {
+		// Input may have been blocked while waiting
+		if evt := a.popPending(); evt != nil {
+			return evt, nil
+		}
 		return a.tagEvent(&AgentEvent{Type: EventEOF}), nil
 	}
 
@@ -630,15 +848,65 @@ func (a *ReActAgent) checkNextState() *AgentEvent {
 	if a.closed {
 		return a.tagEvent(&AgentEvent{Type: EventClosed})
 	}
+	return nil
+}
+
+// nextToolEvent blocks for the next event of the running tool call. It
+// returns false if no tool call is running or the call is done.
+func (a *ReActAgent) nextToolEvent() (*AgentEvent, bool) {
+	a.mu.Lock()
+	events := a.toolEvents
+	a.mu.Unlock()
+	if events == nil {
+		return nil, false
+	}
 
-	// Check for pending tool event (already tagged)
-	if a.pendingToolEvent != nil {
-		evt := a.pendingToolEvent
-		a.pendingToolEvent = nil
-		return evt
+	if evt, ok := <-events; ok {
+		return evt, true
 	}
 
-	return nil
+	a.mu.Lock()
+	if a.toolEvents == events {
+		a.cancelToolLocked()
+	}
+	a.mu.Unlock()
+	return nil, false
+}
+
+// cancelToolLocked cancels the running tool call, if any.
+// Note: caller must hold a.mu lock.
+func (a *ReActAgent) cancelToolLocked() {
+	if a.toolCancel != nil {
+		a.toolCancel()
+	}
+	a.toolEvents = nil
+	a.toolCancel = nil
+	a.toolCall = nil
+	a.approval = nil
+}
+
+// popPending returns the next pending event, or nil.
+func (a *ReActAgent) popPending() *AgentEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.pending) == 0 {
+		return nil
+	}
+	evt := a.pending[0]
+	a.pending = a.pending[1:]
+	return evt
+}
+
+// popResumeCall returns the next resumed tool call to run, or nil.
+func (a *ReActAgent) popResumeCall() *genx.ToolCall {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.resumeCalls) == 0 {
+		return nil
+	}
+	tc := a.resumeCalls[0]
+	a.resumeCalls = a.resumeCalls[1:]
+	return tc
 }
 
 // waitForStream gets the current stream or blocks waiting for input.
@@ -666,17 +934,28 @@ func (a *ReActAgent) waitForStream() (genx.Stream, error) {
 
 // nextFromStream processes the next chunk from the generation stream.
 func (a *ReActAgent) nextFromStream(stream genx.Stream) (*AgentEvent, error) {
+	if len(a.guardrails) > 0 {
+		return a.nextModerated(stream)
+	}
+
 	chunk, err := stream.Next()
 	if err != nil {
 		// Check if it's normal end (Done status)
 		if state, ok := err.(*genx.State); ok && state.Status() == genx.StatusDone {
-			return a.handleStreamEnd()
+			a.countUsage(state.Usage())
+			return a.handleStreamEnd(state.Usage())
 		}
 		return nil, err
 	}
 
-	// Handle tool call
+	// Handle tool call, unless a round limit ends the round
 	if chunk.ToolCall != nil {
+		if limited, err := a.limitToolCall(stream); err != nil || limited {
+			if err != nil {
+				return nil, err
+			}
+			return a.next()
+		}
 		return a.handleToolCallEvent(chunk.ToolCall)
 	}
 
@@ -685,6 +964,7 @@ func (a *ReActAgent) nextFromStream(stream genx.Stream) (*AgentEvent, error) {
 		if t, ok := chunk.Part.(genx.Text); ok {
 			a.mu.Lock()
 			a.pendingText += string(t)
+			a.traceTextLocked(t)
 			a.mu.Unlock()
 		}
 	}
@@ -692,11 +972,195 @@ func (a *ReActAgent) nextFromStream(stream genx.Stream) (*AgentEvent, error) {
 	return a.tagEvent(&AgentEvent{Type: EventChunk, Chunk: chunk}), nil
 }
 
+// nextModerated is nextFromStream with guardrails: model text is held back
+// and emitted a sentence at a time once the guardrails allow it.
+func (a *ReActAgent) nextModerated(stream genx.Stream) (*AgentEvent, error) {
+	for {
+		chunk, err := stream.Next()
+		if err != nil {
+			state, ok := err.(*genx.State)
+			if !ok || state.Status() != genx.StatusDone {
+				return nil, err
+			}
+			a.countUsage(state.Usage())
+			// Check the rest of the text before ending the round
+			evt, err := a.moderateOutput(nil, true)
+			if err != nil || (evt != nil && evt.Type == EventBlocked) {
+				return evt, err
+			}
+			end, err := a.handleStreamEnd(state.Usage())
+			if err != nil || evt == nil {
+				return end, err
+			}
+			a.pushPending(end)
+			return evt, nil
+		}
+
+		if chunk.ToolCall != nil {
+			// Check the text before the tool call; a blocked text drops it
+			evt, err := a.moderateOutput(nil, true)
+			if err != nil || (evt != nil && evt.Type == EventBlocked) {
+				return evt, err
+			}
+			if limited, err := a.limitToolCall(stream); err != nil {
+				return nil, err
+			} else if limited {
+				if evt != nil {
+					return evt, nil
+				}
+				return a.next()
+			}
+			start, err := a.handleToolCallEvent(chunk.ToolCall)
+			if err != nil || evt == nil {
+				return start, err
+			}
+			a.pushPending(start)
+			return evt, nil
+		}
+
+		if _, ok := chunk.Part.(genx.Text); !ok || chunk.Ctrl != nil {
+			return a.tagEvent(&AgentEvent{Type: EventChunk, Chunk: chunk}), nil
+		}
+		evt, err := a.moderateOutput(chunk, false)
+		if err != nil || evt != nil {
+			return evt, err
+		}
+	}
+}
+
+// moderateOutput adds the text of chunk to the held back output and checks
+// its complete sentences, or all of it if flush is set. It returns the
+// checked text as an EventChunk, EventBlocked if a guardrail blocked it, or
+// nil if no text is ready.
+func (a *ReActAgent) moderateOutput(chunk *genx.MessageChunk, flush bool) (*AgentEvent, error) {
+	out := &genx.MessageChunk{Role: genx.RoleModel}
+	a.mu.Lock()
+	if chunk != nil {
+		out.Role, out.Name = chunk.Role, chunk.Name
+		a.outputBuf += string(chunk.Part.(genx.Text))
+		a.traceTextLocked(chunk.Part.(genx.Text))
+	}
+	end := len(a.outputBuf)
+	if !flush {
+		end = genx.LastSentenceEnd(a.outputBuf)
+	}
+	text := a.outputBuf[:end]
+	a.outputBuf = a.outputBuf[end:]
+	a.mu.Unlock()
+	if text == "" {
+		return nil, nil
+	}
+
+	verdict, err := checkGuardrails(a.ctx, a.guardrails, GuardrailOutput, a.guardrailRequest(text))
+	if err != nil {
+		return nil, fmt.Errorf("output guardrail: %w", err)
+	}
+	if verdict.blocked() {
+		return a.blockOutput(verdict)
+	}
+	if verdict != nil && verdict.Action == GuardrailRewrite {
+		text = verdict.Text
+	}
+
+	a.mu.Lock()
+	a.pendingText += text
+	a.mu.Unlock()
+	out.Part = genx.Text(text)
+	return a.tagEvent(&AgentEvent{Type: EventChunk, Chunk: out, Moderation: verdict}), nil
+}
+
+// blockOutput ends the round after a guardrail blocked the output: the
+// generation stops, the text emitted so far and the verdict's reply are
+// stored, and the reply and the end of the round follow EventBlocked.
+func (a *ReActAgent) blockOutput(verdict *GuardrailVerdict) (*AgentEvent, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.stream != nil {
+		a.stream.Close()
+		a.stream = nil
+	}
+	a.outputBuf = ""
+	if text := a.pendingText + verdict.Text; text != "" {
+		if err := a.storeModelText(text); err != nil {
+			return nil, fmt.Errorf("store model text: %w", err)
+		}
+	}
+	a.pendingText = ""
+
+	if verdict.Text != "" {
+		a.pending = append(a.pending, a.tagEvent(&AgentEvent{
+			Type:  EventChunk,
+			Chunk: &genx.MessageChunk{Role: genx.RoleModel, Part: genx.Text(verdict.Text)},
+		}))
+	}
+	end := EventEOF
+	if a.finished {
+		end = EventClosed
+	}
+	a.pending = append(a.pending, a.tagEvent(&AgentEvent{Type: end}))
+	return a.tagEvent(&AgentEvent{Type: EventBlocked, Moderation: verdict}), nil
+}
+
+// moderateInput checks the text of contents with the guardrails and
+// returns the contents to use, rewritten if a guardrail says so.
+// Note: caller must hold a.mu lock.
+func (a *ReActAgent) moderateInput(contents genx.Contents) (genx.Contents, *GuardrailVerdict, error) {
+	if len(a.guardrails) == 0 {
+		return contents, nil, nil
+	}
+	var sb strings.Builder
+	for _, c := range contents {
+		if t, ok := c.(genx.Text); ok {
+			sb.WriteString(string(t))
+		}
+	}
+	if sb.Len() == 0 {
+		return contents, nil, nil
+	}
+
+	verdict, err := checkGuardrails(a.ctx, a.guardrails, GuardrailInput, a.guardrailRequest(sb.String()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("input guardrail: %w", err)
+	}
+	if verdict != nil && verdict.Action == GuardrailRewrite {
+		rewritten := genx.Contents{genx.Text(verdict.Text)}
+		for _, c := range contents {
+			if _, ok := c.(genx.Text); !ok {
+				rewritten = append(rewritten, c)
+			}
+		}
+		contents = rewritten
+	}
+	return contents, verdict, nil
+}
+
+func (a *ReActAgent) guardrailRequest(text string) GuardrailRequest {
+	return GuardrailRequest{AgentDef: a.def.AgentName(), StateID: a.StateID(), Text: text}
+}
+
+// pushPending queues an event for Next().
+func (a *ReActAgent) pushPending(evt *AgentEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending = append(a.pending, evt)
+}
+
 // handleStreamEnd handles stream completion.
-func (a *ReActAgent) handleStreamEnd() (*AgentEvent, error) {
+func (a *ReActAgent) handleStreamEnd(usage genx.Usage) (*AgentEvent, error) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	if a.tracer != nil {
+		a.tracer.record(&Decision{
+			Kind:            DecisionResponse,
+			Text:            a.traceText,
+			PromptTokens:    usage.PromptTokenCount,
+			GeneratedTokens: usage.GeneratedTokenCount,
+		})
+		a.traceText = ""
+	}
+
 	// Store accumulated text to state
 	if a.pendingText != "" {
 		if err := a.storeModelText(a.pendingText); err != nil {
@@ -705,6 +1169,7 @@ func (a *ReActAgent) handleStreamEnd() (*AgentEvent, error) {
 		a.pendingText = ""
 	}
 	a.stream = nil
+	a.summarizing = false
 
 	// Check if agent is finished (quit tool was called)
 	if a.finished {
@@ -713,41 +1178,99 @@ func (a *ReActAgent) handleStreamEnd() (*AgentEvent, error) {
 	return a.tagEvent(&AgentEvent{Type: EventEOF}), nil
 }
 
-// handleToolCallEvent handles a tool call from the stream.
+// handleToolCallEvent handles a tool call from the stream. The tool runs in
+// the background; its chunk and result events are returned by the following
+// Next() calls. A call of a tool that requires approval first waits for
+// Approve or Reject.
 func (a *ReActAgent) handleToolCallEvent(tc *genx.ToolCall) (*AgentEvent, error) {
-	// Return tool start event first
+	// Return tool start event first, or the approval request
 	startEvt := a.tagEvent(&AgentEvent{
 		Type:     EventToolStart,
 		ToolCall: tc,
 	})
+	var approval chan toolApproval
+	if a.needsApproval(tc) {
+		startEvt.Type = EventToolApprovalRequired
+		approval = make(chan toolApproval, 1)
+	}
+
+	a.mu.Lock()
+	ctx, cancel := context.WithCancel(a.ctx)
+	events := make(chan *AgentEvent)
+	a.toolEvents = events
+	a.toolCancel = cancel
+	a.toolCall = tc
+	a.approval = approval
+	if a.tracer != nil && tc.FuncCall != nil {
+		a.tracer.record(&Decision{
+			Kind:       DecisionToolCall,
+			Text:       a.traceText,
+			Tool:       tc.FuncCall.Name,
+			ToolCallID: tc.ID,
+			Arguments:  tc.FuncCall.Arguments,
+		})
+		a.traceText = ""
+	}
+	a.mu.Unlock()
+
+	go a.runToolCall(ctx, tc, approval, events)
+
+	return startEvt, nil
+}
+
+// runToolCall executes a tool call, sending its chunk events and then its
+// result event to events, which is closed on return. If approval is not
+// nil, the call first waits for the decision on it.
+func (a *ReActAgent) runToolCall(ctx context.Context, tc *genx.ToolCall, approval <-chan toolApproval, events chan<- *AgentEvent) {
+	defer close(events)
+
+	send := func(evt *AgentEvent) error {
+		select {
+		case events <- a.tagEvent(evt):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var rejectErr error
+	if approval != nil {
+		select {
+		case d := <-approval:
+			rejectErr = d.err(tc)
+		case <-ctx.Done():
+			return
+		}
+		if rejectErr == nil && send(&AgentEvent{Type: EventToolStart, ToolCall: tc}) != nil {
+			return
+		}
+	}
 
-	// Execute tool and prepare result event
-	toolErr := a.handleToolCall(tc)
+	ctx = withToolChunkSink(ctx, func(chunk string) error {
+		return send(&AgentEvent{
+			Type:     EventToolChunk,
+			ToolCall: tc,
+			Chunk:    &genx.MessageChunk{Role: genx.RoleTool, Part: genx.Text(chunk)},
+		})
+	})
 
-	var resultEvt *AgentEvent
-	if toolErr != nil {
-		resultEvt = a.tagEvent(&AgentEvent{
+	if toolErr := a.handleToolCall(ctx, tc, rejectErr); toolErr != nil {
+		send(&AgentEvent{
 			Type:      EventToolError,
 			ToolCall:  tc,
 			ToolError: toolErr,
 		})
 	} else {
-		resultEvt = a.tagEvent(&AgentEvent{
+		send(&AgentEvent{
 			Type:     EventToolDone,
 			ToolCall: tc,
 		})
 	}
-
-	// Store result event to return on next Next() call
-	a.mu.Lock()
-	a.pendingToolEvent = resultEvt
-	a.mu.Unlock()
-
-	return startEvt, nil
 }
 
-// handleToolCall handles tool call.
-func (a *ReActAgent) handleToolCall(tc *genx.ToolCall) error {
+// handleToolCall handles tool call. A call rejected by its approver is not
+// invoked; rejectErr is its result.
+func (a *ReActAgent) handleToolCall(ctx context.Context, tc *genx.ToolCall, rejectErr error) error {
 	if tc.FuncCall == nil {
 		return ErrInvalidToolCall
 	}
@@ -760,32 +1283,99 @@ func (a *ReActAgent) handleToolCall(tc *genx.ToolCall) error {
 		return err
 	}
 
-	// Get and invoke tool (no lock needed - can be long-running)
-	tool, err := a.rt.GetTool(a.ctx, toolName)
-	if err != nil {
+	// A call rejected by the tool's limits or its approver is reported as
+	// EventToolError, but the model still sees the error as the tool result
+	// and continues.
+	var limitErr error
+
+	// Get and invoke tool unless rejected (no lock needed - can be long-running)
+	if rejectErr != nil {
+		// Rejected by its approver: store the rejection, don't invoke
+		a.traceToolResult(tc, "", rejectErr)
+		if storeErr := a.storeToolResultSafe(toolID, "tool error: "+rejectErr.Error()); storeErr != nil {
+			return fmt.Errorf("store tool error: %w", storeErr)
+		}
+		limitErr = rejectErr
+	} else if tool, err := a.getTool(ctx, toolName); err != nil {
+		a.traceToolResult(tc, "", err)
 		// Failed to get tool, store error result
 		if storeErr := a.storeToolResultSafe(toolID, "tool error: "+err.Error()); storeErr != nil {
 			return fmt.Errorf("store tool error: %w", storeErr)
 		}
 	} else {
 		// Call tool (no lock held - can be long-running)
-		result, err := tool.Invoke(a.ctx, tc.FuncCall, tc.FuncCall.Arguments)
+		result, err := tool.Invoke(ctx, tc.FuncCall, tc.FuncCall.Arguments)
+
+		// Interrupted, reverted or closed: the call is abandoned
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 
 		if err != nil {
+			a.traceToolResult(tc, "", err)
 			if storeErr := a.storeToolResultSafe(toolID, "invoke error: "+err.Error()); storeErr != nil {
 				return fmt.Errorf("store invoke error: %w", storeErr)
 			}
+			if errors.Is(err, ErrToolLimited) {
+				limitErr = err
+			}
 		} else {
 			// Store tool result
-			if storeErr := a.storeToolResultSafe(toolID, formatOutput(result)); storeErr != nil {
+			output := formatOutput(result)
+			a.traceToolResult(tc, output, nil)
+			if storeErr := a.storeToolResultSafe(toolID, output); storeErr != nil {
 				return fmt.Errorf("store tool result: %w", storeErr)
 			}
 		}
 	}
 
-	// Check quit and continue generation
-	a.checkQuitTool(toolName)
-	return a.continueGenerationSafe()
+	// Check quit and continue generation, once no resumed calls remain,
+	// unless a round limit ends the round
+	if rejectErr == nil {
+		a.checkQuitTool(toolName)
+	}
+	if a.hasResumeCalls() {
+		return limitErr
+	}
+	if limited, err := a.limitGeneration(); err != nil || limited {
+		if err != nil {
+			return err
+		}
+		return limitErr
+	}
+	if err := a.continueGenerationSafe(); err != nil {
+		return err
+	}
+	return limitErr
+}
+
+// traceTextLocked keeps model text for the next traced decision.
+// Note: caller must hold a.mu lock.
+func (a *ReActAgent) traceTextLocked(text genx.Text) {
+	if a.tracer != nil {
+		a.traceText += string(text)
+	}
+}
+
+// traceToolResult traces the result or the error of a tool call.
+func (a *ReActAgent) traceToolResult(tc *genx.ToolCall, result string, err error) {
+	if a.tracer == nil {
+		return
+	}
+	d := &Decision{Kind: DecisionToolResult, Tool: tc.FuncCall.Name, ToolCallID: tc.ID, Result: result}
+	if err != nil {
+		d.Error = err.Error()
+	}
+	a.tracer.record(d)
+}
+
+// getTool returns the tool of a call. Tools that delegate to agents are
+// created with the agent; other tools are looked up in the runtime.
+func (a *ReActAgent) getTool(ctx context.Context, name string) (*genx.FuncTool, error) {
+	if tool, ok := a.delegates[name]; ok {
+		return tool, nil
+	}
+	return a.rt.GetTool(ctx, name)
 }
 
 // storePendingTextAndToolCall stores any pending text and the tool call.
@@ -821,6 +1411,13 @@ func (a *ReActAgent) checkQuitTool(toolName string) {
 	}
 }
 
+// hasResumeCalls reports whether resumed tool calls remain to be run.
+func (a *ReActAgent) hasResumeCalls() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.resumeCalls) > 0
+}
+
 // continueGenerationSafe continues the generation after a tool call with proper locking.
 func (a *ReActAgent) continueGenerationSafe() error {
 	a.mu.Lock()
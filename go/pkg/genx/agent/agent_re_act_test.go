@@ -2,7 +2,9 @@ package agent_test
 
 import (
 	"context"
+	"errors"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 
@@ -377,6 +379,177 @@ func TestReActAgent_ToolCall(t *testing.T) {
 	}
 }
 
+// limitedToolRuntime serves tools wrapped by a shared ToolLimiter.
+type limitedToolRuntime struct {
+	*playground.Runtime
+	limiter *agent.ToolLimiter
+	limits  *agentcfg.ToolLimits
+	tools   map[string]*genx.FuncTool
+}
+
+func (r *limitedToolRuntime) GetTool(ctx context.Context, name string) (*genx.FuncTool, error) {
+	if tool, ok := r.tools[name]; ok {
+		return r.limiter.Wrap(tool, r.limits), nil
+	}
+	return r.Runtime.GetTool(ctx, name)
+}
+
+func TestReActAgent_ToolLimited(t *testing.T) {
+	ctx := context.Background()
+	mockGen := newMockReActGenerator().
+		WithToolCall("test-model", "call-1", "calculator", `{"expression":"2+2"}`).
+		WithTextResponse("test-model", "The calculator is busy, please try again.")
+
+	tools := make(map[string]*genx.FuncTool)
+	for _, tool := range createReActBuiltinTools() {
+		tools[tool.Name] = tool
+	}
+	rt := &limitedToolRuntime{
+		Runtime: setupReActAgentTestRuntime(t, mockGen),
+		limiter: agent.NewToolLimiter(),
+		limits:  &agentcfg.ToolLimits{QPS: 0.001, Burst: 1, OnLimit: agentcfg.ToolLimitReject},
+		tools:   tools,
+	}
+
+	// Use up the only token, as another device's agent would.
+	calc, _ := rt.GetTool(ctx, "calculator")
+	if _, err := calc.Invoke(ctx, nil, `{"expression":"1+1"}`); err != nil {
+		t.Fatalf("first Invoke: %v", err)
+	}
+
+	agentDef, err := rt.GetAgentDef(ctx, "assistant")
+	if err != nil {
+		t.Fatalf("GetAgentDef error: %v", err)
+	}
+	reactAgent, err := agent.NewReActAgent(ctx, agentcfg.AsReActAgent(agentDef), rt, "")
+	if err != nil {
+		t.Fatalf("NewReActAgent error: %v", err)
+	}
+	defer reactAgent.Close()
+
+	if err := reactAgent.Input(genx.Contents{genx.Text("What is 2+2?")}); err != nil {
+		t.Fatalf("Input error: %v", err)
+	}
+
+	var toolErr error
+	var text string
+	for {
+		evt, err := reactAgent.Next()
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		switch evt.Type {
+		case agent.EventToolDone:
+			t.Error("rate-limited call reported EventToolDone")
+		case agent.EventToolError:
+			toolErr = evt.ToolError
+		case agent.EventChunk:
+			if txt, ok := evt.Chunk.Part.(genx.Text); ok {
+				text += string(txt)
+			}
+		}
+		if evt.Type == agent.EventEOF || evt.Type == agent.EventClosed {
+			break
+		}
+	}
+
+	if !errors.Is(toolErr, agent.ErrToolLimited) {
+		t.Errorf("ToolError = %v, want ErrToolLimited", toolErr)
+	}
+	if text == "" {
+		t.Error("generation did not continue after the limited call")
+	}
+}
+
+// streamingToolRuntime serves extra tools by name.
+type streamingToolRuntime struct {
+	*playground.Runtime
+	tools map[string]*genx.FuncTool
+}
+
+func (r *streamingToolRuntime) GetTool(ctx context.Context, name string) (*genx.FuncTool, error) {
+	if tool, ok := r.tools[name]; ok {
+		return tool, nil
+	}
+	return r.Runtime.GetTool(ctx, name)
+}
+
+func TestReActAgent_StreamingTool(t *testing.T) {
+	ctx := context.Background()
+	mockGen := newMockReActGenerator().
+		WithToolCall("test-model", "call-1", "calculator", `{"expression":"2+2"}`).
+		WithTextResponse("test-model", "The answer is 4.")
+
+	type calcArgs struct {
+		Expression string `json:"expression"`
+	}
+	calc, err := agent.NewStreamingFuncTool[calcArgs]("calculator", "Perform basic math calculations",
+		agent.StreamingToolFunc(func(ctx context.Context, call *genx.FuncCall, args string, emit func(string) error) (any, error) {
+			for _, step := range []string{"parsing; ", "adding; "} {
+				if err := emit(step); err != nil {
+					return nil, err
+				}
+			}
+			return "4", nil
+		}),
+		agent.WithForwardChunks(),
+	)
+	if err != nil {
+		t.Fatalf("NewStreamingFuncTool error: %v", err)
+	}
+	rt := &streamingToolRuntime{
+		Runtime: setupReActAgentTestRuntime(t, mockGen),
+		tools:   map[string]*genx.FuncTool{"calculator": calc},
+	}
+
+	agentDef, err := rt.GetAgentDef(ctx, "assistant")
+	if err != nil {
+		t.Fatalf("GetAgentDef error: %v", err)
+	}
+	reactAgent, err := agent.NewReActAgent(ctx, agentcfg.AsReActAgent(agentDef), rt, "")
+	if err != nil {
+		t.Fatalf("NewReActAgent error: %v", err)
+	}
+	defer reactAgent.Close()
+
+	if err := reactAgent.Input(genx.Contents{genx.Text("What is 2+2?")}); err != nil {
+		t.Fatalf("Input error: %v", err)
+	}
+
+	var types []agent.EventType
+	var chunks string
+	for {
+		evt, err := reactAgent.Next()
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		if evt.Type == agent.EventEOF || evt.Type == agent.EventClosed {
+			break
+		}
+		if evt.Type == agent.EventChunk {
+			continue
+		}
+		types = append(types, evt.Type)
+		if evt.Type == agent.EventToolChunk {
+			if evt.ToolCall == nil || evt.ToolCall.ID != "call-1" {
+				t.Errorf("EventToolChunk ToolCall = %v, want call-1", evt.ToolCall)
+			}
+			chunks += string(evt.Chunk.Part.(genx.Text))
+		}
+	}
+
+	want := []agent.EventType{agent.EventToolStart, agent.EventToolChunk, agent.EventToolChunk, agent.EventToolDone}
+	if !slices.Equal(types, want) {
+		t.Errorf("events = %v, want %v", types, want)
+	}
+	if chunks != "parsing; adding; " {
+		t.Errorf("chunks = %q", chunks)
+	}
+	if history := reactAgent.FormatHistory(ctx); !strings.Contains(history, "parsing; adding; 4") {
+		t.Errorf("tool result not forwarded to history:\n%s", history)
+	}
+}
+
 func TestReActAgent_QuitTool(t *testing.T) {
 	ctx := context.Background()
 	mockGen := newMockReActGenerator().
@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+)
+
+// DecisionKind is the kind of step a Decision records.
+type DecisionKind string
+
+const (
+	DecisionInput      DecisionKind = "input"       // An input started a round
+	DecisionToolCall   DecisionKind = "tool_call"   // The model selected a tool
+	DecisionToolResult DecisionKind = "tool_result" // A tool call returned
+	DecisionResponse   DecisionKind = "response"    // The model answered without a tool call
+	DecisionLimit      DecisionKind = "limit"       // A round limit was reached
+	DecisionMatch      DecisionKind = "match"       // A MatchAgent matched the input to rules
+)
+
+// Decision is one step of an agent's decision trace, keyed by the round
+// (from an input to its final answer) and the step within the round.
+//
+// Text is what the model generated before the step, as it came from the
+// model: for a tool call, the reasoning that led to it; for a response, the
+// answer before guardrails saw it.
+type Decision struct {
+	Time    time.Time    `json:"time"`
+	Agent   string       `json:"agent"`
+	StateID string       `json:"state_id,omitzero"`
+	Round   int          `json:"round"`
+	Step    int          `json:"step"`
+	Kind    DecisionKind `json:"kind"`
+	Model   string       `json:"model,omitzero"`
+
+	Input string `json:"input,omitzero"`
+	Text  string `json:"text,omitzero"`
+
+	// Tool, ToolCallID and Arguments describe the selected tool; Candidates
+	// are the tools the model could choose from, or the rules a MatchAgent
+	// could match.
+	Tool       string   `json:"tool,omitzero"`
+	ToolCallID string   `json:"tool_call_id,omitzero"`
+	Arguments  string   `json:"arguments,omitzero"`
+	Candidates []string `json:"candidates,omitzero"`
+
+	// Matches are the rules a MatchAgent matched, in order.
+	Matches []string `json:"matches,omitzero"`
+
+	Result string `json:"result,omitzero"`
+	Error  string `json:"error,omitzero"`
+
+	PromptTokens    int64 `json:"prompt_tokens,omitzero"`
+	GeneratedTokens int64 `json:"generated_tokens,omitzero"`
+}
+
+// TraceSink records the decision traces of agents, to find out why an agent
+// chose a tool. Agents trace their decisions if their definition sets
+// "trace" and their Runtime implements TraceRuntime.
+//
+// Record is called synchronously as the agent runs and may be called
+// concurrently. Its errors are ignored: tracing never fails an agent.
+type TraceSink interface {
+	Record(ctx context.Context, d *Decision) error
+}
+
+// TraceRuntime is implemented by Runtimes that record decision traces.
+type TraceRuntime interface {
+	// TraceSink returns the sink of the decision traces, or nil.
+	TraceSink() TraceSink
+}
+
+// JSONLTraceSink writes decisions to a writer as JSON lines.
+type JSONLTraceSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONLTraceSink returns a sink that writes a JSON line per decision to w.
+func NewJSONLTraceSink(w io.Writer) *JSONLTraceSink {
+	return &JSONLTraceSink{enc: json.NewEncoder(w)}
+}
+
+// Record implements TraceSink.
+func (s *JSONLTraceSink) Record(_ context.Context, d *Decision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(d)
+}
+
+// decisionTracer numbers and records the decisions of one agent. A nil
+// tracer records nothing.
+type decisionTracer struct {
+	ctx     context.Context
+	sink    TraceSink
+	agent   string
+	stateID string
+	model   string
+
+	// candidates are the tools, or rules, the agent selects from
+	candidates []string
+
+	mu    sync.Mutex
+	round int
+	step  int
+}
+
+// newDecisionTracer returns the tracer of an agent, or nil if def does not
+// enable tracing or rt has no sink.
+func newDecisionTracer(ctx context.Context, def *agentcfg.AgentBase, rt Runtime, stateID string) *decisionTracer {
+	if !def.Trace {
+		return nil
+	}
+	tr, ok := rt.(TraceRuntime)
+	if !ok {
+		return nil
+	}
+	sink := tr.TraceSink()
+	if sink == nil {
+		return nil
+	}
+	t := &decisionTracer{ctx: ctx, sink: sink, agent: def.Name, stateID: stateID}
+	if !def.Generator.IsEmpty() && def.Generator.Generator != nil {
+		t.model = def.Generator.Generator.Model
+	}
+	return t
+}
+
+// startRound starts the next round.
+func (t *decisionTracer) startRound() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.round++
+	t.step = 0
+}
+
+// record records d as the next step of the round.
+func (t *decisionTracer) record(d *Decision) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.step++
+	d.Time = time.Now()
+	d.Agent, d.StateID, d.Model = t.agent, t.stateID, t.model
+	d.Round, d.Step = t.round, t.step
+	if d.Kind == DecisionToolCall || d.Kind == DecisionMatch {
+		d.Candidates = t.candidates
+	}
+	t.mu.Unlock()
+	t.sink.Record(t.ctx, d)
+}
+
+// toolNames returns the names of the function tools of tools.
+func toolNames(tools []genx.Tool) []string {
+	var names []string
+	for _, tool := range tools {
+		if ft, ok := tool.(*genx.FuncTool); ok {
+			names = append(names, ft.Name)
+		}
+	}
+	return names
+}
+
+// contentsText returns the text parts of contents.
+func contentsText(contents genx.Contents) string {
+	var text string
+	for _, part := range contents {
+		if t, ok := part.(genx.Text); ok {
+			text += string(t)
+		}
+	}
+	return text
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// OTelTraceSink exports decisions as OpenTelemetry spans, e.g. to an OTLP
+// collector. Each decision is a span named "genx.agent.<kind>" under the
+// span of the agent's context, with the fields of the decision as
+// "genx.agent.*" attributes. A decision with an error sets the span status.
+type OTelTraceSink struct {
+	tracer oteltrace.Tracer
+}
+
+// NewOTelTraceSink returns a sink that exports decisions with tracer.
+func NewOTelTraceSink(tracer oteltrace.Tracer) *OTelTraceSink {
+	return &OTelTraceSink{tracer: tracer}
+}
+
+// Record implements TraceSink.
+func (s *OTelTraceSink) Record(ctx context.Context, d *Decision) error {
+	attrs := []attribute.KeyValue{
+		attribute.String("genx.agent.name", d.Agent),
+		attribute.String("genx.agent.state_id", d.StateID),
+		attribute.Int("genx.agent.round", d.Round),
+		attribute.Int("genx.agent.step", d.Step),
+		attribute.String("genx.agent.kind", string(d.Kind)),
+	}
+	for _, a := range []struct{ key, value string }{
+		{"genx.agent.model", d.Model},
+		{"genx.agent.input", d.Input},
+		{"genx.agent.text", d.Text},
+		{"genx.agent.tool", d.Tool},
+		{"genx.agent.tool_call_id", d.ToolCallID},
+		{"genx.agent.arguments", d.Arguments},
+		{"genx.agent.result", d.Result},
+	} {
+		if a.value != "" {
+			attrs = append(attrs, attribute.String(a.key, a.value))
+		}
+	}
+	if len(d.Candidates) > 0 {
+		attrs = append(attrs, attribute.StringSlice("genx.agent.candidates", d.Candidates))
+	}
+	if len(d.Matches) > 0 {
+		attrs = append(attrs, attribute.StringSlice("genx.agent.matches", d.Matches))
+	}
+	if d.PromptTokens > 0 || d.GeneratedTokens > 0 {
+		attrs = append(attrs,
+			attribute.Int64("genx.agent.prompt_tokens", d.PromptTokens),
+			attribute.Int64("genx.agent.generated_tokens", d.GeneratedTokens),
+		)
+	}
+
+	_, span := s.tracer.Start(ctx, "genx.agent."+string(d.Kind),
+		oteltrace.WithTimestamp(d.Time),
+		oteltrace.WithSpanKind(oteltrace.SpanKindInternal),
+		oteltrace.WithAttributes(attrs...),
+	)
+	if d.Error != "" {
+		span.SetStatus(codes.Error, d.Error)
+	}
+	span.End(oteltrace.WithTimestamp(d.Time))
+	return nil
+}
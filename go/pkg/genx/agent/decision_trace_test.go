@@ -0,0 +1,239 @@
+package agent_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agent"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+	"github.com/haivivi/giztoy/go/pkg/genx/playground"
+)
+
+// newTracedAgent creates the assistant agent on a runtime with gen whose
+// decision traces are written to buf as JSON lines.
+func newTracedAgent(t *testing.T, gen genx.Generator, trace bool, buf *bytes.Buffer) *agent.ReActAgent {
+	t.Helper()
+	ctx := context.Background()
+	store := playground.NewStore(nil)
+	if err := store.LoadReadonlyLayer("testdata", os.DirFS("testdata/agent_react_test")); err != nil {
+		t.Fatalf("load testdata: %v", err)
+	}
+	rt := playground.NewRuntime(
+		playground.WithStore(store),
+		playground.WithGenerator(gen),
+		playground.WithBuiltinTools(createReActBuiltinTools()...),
+		playground.WithTraceSink(agent.NewJSONLTraceSink(buf)),
+	)
+	agentDef, err := rt.GetAgentDef(ctx, "assistant")
+	if err != nil {
+		t.Fatalf("GetAgentDef error: %v", err)
+	}
+	def := *agentcfg.AsReActAgent(agentDef)
+	def.Trace = trace
+	a, err := agent.NewReActAgent(ctx, &def, rt, "")
+	if err != nil {
+		t.Fatalf("NewReActAgent error: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+// readDecisions decodes the JSON lines of buf.
+func readDecisions(t *testing.T, buf *bytes.Buffer) []agent.Decision {
+	t.Helper()
+	var decisions []agent.Decision
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var d agent.Decision
+		if err := dec.Decode(&d); err != nil {
+			t.Fatalf("decode decision: %v", err)
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions
+}
+
+func TestReActAgent_DecisionTrace(t *testing.T) {
+	gen := newMockReActGenerator().
+		WithTextAndToolCall("test-model", "Let me look it up.", "call-1", "search", `{"query":"dinosaurs"}`).
+		WithTextResponse("test-model", "Dinosaurs were big.")
+	var buf bytes.Buffer
+	a := newTracedAgent(t, gen, true, &buf)
+
+	guardedRound(t, a, "Tell me about dinosaurs")
+	decisions := readDecisions(t, &buf)
+
+	var kinds []agent.DecisionKind
+	for i, d := range decisions {
+		kinds = append(kinds, d.Kind)
+		if d.Agent != "assistant" || d.StateID != a.StateID() || d.Model != "test-model" {
+			t.Errorf("decision %d = %+v, want keyed by the agent", i, d)
+		}
+		if d.Round != 1 || d.Step != i+1 {
+			t.Errorf("decision %d: round %d step %d, want round 1 step %d", i, d.Round, d.Step, i+1)
+		}
+	}
+	want := []agent.DecisionKind{agent.DecisionInput, agent.DecisionToolCall, agent.DecisionToolResult, agent.DecisionResponse}
+	if !slices.Equal(kinds, want) {
+		t.Fatalf("kinds = %v, want %v", kinds, want)
+	}
+
+	if d := decisions[0]; d.Input != "Tell me about dinosaurs" {
+		t.Errorf("input = %+v", d)
+	}
+	call := decisions[1]
+	if call.Text != "Let me look it up." || call.Tool != "search" || call.ToolCallID != "call-1" || call.Arguments != `{"query":"dinosaurs"}` {
+		t.Errorf("tool call = %+v", call)
+	}
+	if !slices.Equal(call.Candidates, []string{"calculator", "search", "finish"}) {
+		t.Errorf("candidates = %v", call.Candidates)
+	}
+	if d := decisions[2]; d.Tool != "search" || d.Result != "Search result for: dinosaurs" || d.Error != "" {
+		t.Errorf("tool result = %+v", d)
+	}
+	if d := decisions[3]; d.Text != "Dinosaurs were big." {
+		t.Errorf("response = %+v", d)
+	}
+
+	// The next round starts over at step 1
+	gen.WithTextResponse("test-model", "Birds are dinosaurs.")
+	guardedRound(t, a, "And birds?")
+	decisions = readDecisions(t, &buf)
+	if len(decisions) != 2 || decisions[0].Round != 2 || decisions[0].Step != 1 || decisions[1].Step != 2 {
+		t.Errorf("second round = %+v", decisions)
+	}
+}
+
+func TestReActAgent_DecisionTrace_ToolError(t *testing.T) {
+	gen := newMockReActGenerator().
+		WithToolCall("test-model", "call-1", "missing", `{}`).
+		WithTextResponse("test-model", "Sorry.")
+	var buf bytes.Buffer
+	a := newTracedAgent(t, gen, true, &buf)
+
+	guardedRound(t, a, "Do it")
+	for _, d := range readDecisions(t, &buf) {
+		if d.Kind == agent.DecisionToolResult {
+			if d.Tool != "missing" || d.Error == "" || d.Result != "" {
+				t.Errorf("tool result = %+v, want an error", d)
+			}
+			return
+		}
+	}
+	t.Error("no tool result traced")
+}
+
+func TestReActAgent_DecisionTrace_Disabled(t *testing.T) {
+	gen := newMockReActGenerator().
+		WithToolCall("test-model", "call-1", "search", `{"query":"a"}`).
+		WithTextResponse("test-model", "Done.")
+	var buf bytes.Buffer
+	a := newTracedAgent(t, gen, false, &buf)
+
+	guardedRound(t, a, "Search")
+	if buf.Len() != 0 {
+		t.Errorf("trace = %s, want none without trace in the definition", buf.String())
+	}
+}
+
+func TestMatchAgent_DecisionTrace(t *testing.T) {
+	ctx := context.Background()
+	store := playground.NewStore(nil)
+	if err := store.LoadReadonlyLayer("testdata", os.DirFS("testdata/agent_match_test")); err != nil {
+		t.Fatalf("load testdata: %v", err)
+	}
+	var buf bytes.Buffer
+	rt := playground.NewRuntime(
+		playground.WithStore(store),
+		playground.WithGenerator(&mockMatchGenerator{matchResults: map[string]string{"test-model": "greeting"}}),
+		playground.WithTraceSink(agent.NewJSONLTraceSink(&buf)),
+	)
+	agentDef, err := rt.GetAgentDef(ctx, "intent_router")
+	if err != nil {
+		t.Fatalf("GetAgentDef error: %v", err)
+	}
+	def := *agentcfg.AsMatchAgent(agentDef)
+	def.Trace = true
+	a, err := agent.NewMatchAgent(ctx, &def, rt, "")
+	if err != nil {
+		t.Fatalf("NewMatchAgent error: %v", err)
+	}
+	defer a.Close()
+
+	guardedRound(t, a, "hello")
+	decisions := readDecisions(t, &buf)
+	if len(decisions) != 2 {
+		t.Fatalf("decisions = %+v, want input and match", decisions)
+	}
+	m := decisions[1]
+	if m.Kind != agent.DecisionMatch || m.Round != 1 || m.Step != 2 || m.Input != "hello" {
+		t.Errorf("match = %+v", m)
+	}
+	if !slices.Equal(m.Matches, []string{"greeting"}) || !slices.Contains(m.Candidates, "greeting") {
+		t.Errorf("matches = %v of %v, want greeting", m.Matches, m.Candidates)
+	}
+}
+
+type spanRecorder struct {
+	noop.Span
+	name   string
+	attrs  []attribute.KeyValue
+	status string
+}
+
+func (s *spanRecorder) SetStatus(_ codes.Code, desc string) { s.status = desc }
+
+type tracerRecorder struct {
+	noop.Tracer
+	spans []*spanRecorder
+}
+
+func (t *tracerRecorder) Start(ctx context.Context, name string, opts ...oteltrace.SpanStartOption) (context.Context, oteltrace.Span) {
+	cfg := oteltrace.NewSpanStartConfig(opts...)
+	s := &spanRecorder{name: name, attrs: cfg.Attributes()}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func TestOTelTraceSink(t *testing.T) {
+	tracer := &tracerRecorder{}
+	sink := agent.NewOTelTraceSink(tracer)
+	sink.Record(context.Background(), &agent.Decision{
+		Time: time.Now(), Agent: "assistant", Round: 2, Step: 3,
+		Kind: agent.DecisionToolCall, Tool: "search", Candidates: []string{"search", "finish"},
+	})
+	sink.Record(context.Background(), &agent.Decision{
+		Time: time.Now(), Agent: "assistant", Round: 2, Step: 4,
+		Kind: agent.DecisionToolResult, Tool: "search", Error: "timeout",
+	})
+
+	if len(tracer.spans) != 2 || tracer.spans[0].name != "genx.agent.tool_call" {
+		t.Fatalf("spans = %+v", tracer.spans)
+	}
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range tracer.spans[0].attrs {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["genx.agent.round"].AsInt64() != 2 || attrs["genx.agent.step"].AsInt64() != 3 ||
+		attrs["genx.agent.tool"].AsString() != "search" ||
+		!slices.Equal(attrs["genx.agent.candidates"].AsStringSlice(), []string{"search", "finish"}) {
+		t.Errorf("attributes = %v", tracer.spans[0].attrs)
+	}
+	if _, ok := attrs["genx.agent.result"]; ok {
+		t.Error("empty result exported")
+	}
+	if tracer.spans[1].status != "timeout" {
+		t.Errorf("status = %q, want the error", tracer.spans[1].status)
+	}
+}
@@ -39,6 +39,8 @@
 //	        return nil
 //	    case EventToolStart:
 //	        // Tool execution started
+//	    case EventToolChunk:
+//	        // Output of a running streaming tool
 //	    case EventToolDone:
 //	        // Tool completed successfully
 //	    case EventToolError:
@@ -46,6 +48,15 @@
 //	    case EventInterrupted:
 //	        // Agent was interrupted
 //	        return nil
+//	    case EventBlocked:
+//	        // A guardrail blocked the input or the output
+//	    case EventLimited:
+//	        // The round reached a limit; the final answer follows
+//	    case EventToolApprovalRequired:
+//	        // Ask the parent app, then agent.Approve or agent.Reject
+//	        // (from another goroutine) with evt.ToolCall.ID
+//	    case EventMatched:
+//	        // A MatchAgent routed the input to evt.Matches
 //	    }
 //	}
 //
@@ -67,6 +78,84 @@
 //   - FinalizerTool: Structured output with validation
 //   - HTTPTool: HTTP requests with jq-based response extraction
 //   - CompositeTool: Sequential tool orchestration
+//   - DocumentsTool: Knowledge-base search over documents ingested into kv
+//   - RetrievalTool: Recall of the persona's memory (keywords, graph
+//     labels and periods), with citation IDs
+//   - AgentTool: Delegation of a task to another agent
+//   - MCPTool: Tools of an MCP (Model Context Protocol) server, over stdio
+//     or SSE; MCPToolset exposes all tools of a server
+//
+// # Tool Limits
+//
+// A tool definition can cap concurrency and call rate, protecting a
+// downstream API shared by many device agents:
+//
+//	name: search_music
+//	type: http
+//	limits:
+//	  max_concurrent: 4   # in-flight calls
+//	  qps: 10             # sustained calls per second
+//	  burst: 20
+//	  on_limit: reject    # or queue (default)
+//
+// Runtimes enforce limits with a shared ToolLimiter. Queued calls wait for
+// capacity; rejected calls fail with ErrToolLimited, which ReActAgent
+// reports as EventToolError while passing the error to the model as the
+// tool result.
+//
+// # Tool Approval
+//
+// A tool definition can require a human to approve each call, so that a
+// parent app gates device control such as volume, OTA or purchases:
+//
+//	name: start_ota
+//	type: http
+//	approval: required
+//
+// A ReActAgent emits EventToolApprovalRequired instead of EventToolStart
+// and waits until Approve or Reject (see ToolApprover) is called with the
+// ID of the call. A rejected call is not run; the model sees the reason as
+// the tool's error. Calls of sub-agents run by agent tools and of agents
+// run by triggers are rejected, as nobody is there to approve them.
+//
+// # Match Confidence
+//
+// By default a MatchAgent asks its LLM to classify every input. With a
+// confidence threshold, it first scores the input against the patterns and
+// examples of its rules and only asks the LLM when no rule reaches the
+// threshold:
+//
+//	type: match
+//	name: intent_router
+//	confidence:
+//	  threshold: 0.85   # default 0.8
+//	  fallback: llm     # or none: no match below the threshold
+//
+// EventMatched reports the matched intents with their Source ("pattern" or
+// "llm") and Score, to tune the threshold.
+//
+// # Streaming Tools
+//
+// A long-running tool can report its output as it goes instead of leaving
+// the agent silent until it returns. NewStreamingFuncTool wraps a
+// StreamingTool; ReActAgent runs it in the background and returns each
+// chunk as EventToolChunk before EventToolDone:
+//
+//	crawl, _ := agent.NewStreamingFuncTool[CrawlArgs]("crawl", "Crawl a site",
+//	    agent.StreamingToolFunc(func(ctx context.Context, call *genx.FuncCall, args string, emit func(string) error) (any, error) {
+//	        for page := range crawlPages(ctx, args) {
+//	            if err := emit(page.Title + "\n"); err != nil {
+//	                return nil, err
+//	            }
+//	        }
+//	        return "done", nil
+//	    }),
+//	    agent.WithForwardChunks(),
+//	)
+//
+// With WithForwardChunks the model sees the chunks as part of the tool
+// result; otherwise only the final result. Interrupt and Revert cancel the
+// running tool.
 //
 // # Definition System
 //
@@ -85,6 +174,108 @@
 //   - Agent definition loading
 //   - State management with memory capabilities
 //
+// # Prompt Assembly
+//
+// A Runtime that implements PromptAssemblerRuntime adds a prompt to every
+// generation of its ReAct agents. TemplateAssembler renders it from a
+// template and sources such as recalled memories, the device state and the
+// persona profile:
+//
+//	pa, err := agent.NewTemplateAssembler(
+//	    "You are {{.Persona.name}}. It is {{.TimeOfDay}}.{{range .Memories}}\n- {{.}}{{end}}",
+//	    agent.PersonaSource(profile),
+//	    agent.MemorySource(recall),
+//	)
+//	rt := playground.NewRuntime(playground.WithPromptAssembler(pa), ...)
+//
+// # Guardrails
+//
+// A Runtime that implements GuardrailRuntime moderates its ReAct agents.
+// Each Guardrail checks user input before it is stored and sent to the
+// model, and model output before it is emitted; output is held back and
+// checked a sentence at a time. A guardrail can allow, rewrite or block the
+// content, and label it. Blocking emits EventBlocked, followed by the
+// verdict's reply if it has one:
+//
+//	words, _ := agent.NewPatternGuardrail(agent.PatternRule{
+//	    Label:    "weapons",
+//	    Keywords: []string{"gun", "knife"},
+//	    Reply:    "Let's talk about something else!",
+//	})
+//	moderator := agent.NewModeratorGuardrail(agent.ModeratorConfig{
+//	    Generator: gen,
+//	    Model:     "moderation-model",
+//	})
+//	rt := playground.NewRuntime(playground.WithGuardrails(words, moderator), ...)
+//
+// # Output Style
+//
+// A ReAct agent definition can set the output style of its persona, which
+// a StyleRewriter enforces on output after the runtime's guardrails, before
+// it reaches TTS. Rules are applied in code; a small model rewrites only
+// what the rules cannot fix:
+//
+//	style:
+//	  max_sentence_length: 40
+//	  banned_phrases: ["as an AI"]
+//	  replacements: {approximately: about}
+//	  vocabulary: words a 5-year-old knows
+//	  rewrite_model: small-model
+//
+// # Round Limits
+//
+// A ReAct agent definition can cap each round, so that a model stuck in a
+// tool loop cannot run up cost or keep a child waiting:
+//
+//	limits:
+//	  max_tool_calls: 5
+//	  max_tokens: 4000
+//	  timeout: 30        # seconds
+//	  on_limit: summarize
+//
+// When a limit is reached, the agent emits EventLimited and asks the model
+// for a final answer without tools ("summarize", the default), ends the
+// round ("stop"), or makes Next return an error wrapping ErrRoundLimit
+// ("error").
+//
+// # Checkpoints
+//
+// Agent.Snapshot serializes an agent's conversation, its running tool call
+// and its partial response (MessagePack). ResumeAgent recreates the agent
+// from the snapshot on any runtime, so that a restarted server or a
+// reconnecting device continues mid-conversation:
+//
+//	data, _ := a.Snapshot()
+//	// ... later, possibly in another process
+//	a, err := agent.ResumeAgent(ctx, rt, data)
+//
+// # Triggers
+//
+// Triggers start or poke agents without user input. A TriggerBinding ties
+// a Trigger to an agent definition (a new agent per firing) or to a saved
+// agent state; the TriggerRegistry of a TriggerRuntime runs the bindings and
+// passes an EventProactive, followed by the agent's output, to a sink:
+//
+//	cron, _ := agent.NewCronTrigger("0 8 * * *", loc)
+//	rt := playground.NewRuntime(playground.WithTriggers(agent.TriggerBinding{
+//	    Name: "morning", Trigger: cron, Agent: "reminder", Target: "gear-001",
+//	}), ...)
+//	rt.Triggers().Start(ctx, func(evt *agent.AgentEvent) { ... })
+//
+// Built in are CronTrigger (cron schedules), MQTTTrigger (MQTT topics, from
+// an mqtt0 client or broker) and EventBus triggers (events the host emits,
+// e.g. on memory or graph updates).
+//
+// # Decision Traces
+//
+// An agent whose definition sets "trace" records its decisions, keyed by
+// round and step, to the TraceSink of a TraceRuntime: its inputs, the tools
+// the model selected with the text that led to them, tool results, final
+// answers, reached limits and MatchAgent matches. JSONLTraceSink writes JSON
+// lines and OTelTraceSink exports OpenTelemetry spans:
+//
+//	rt := playground.NewRuntime(playground.WithTraceSink(agent.NewJSONLTraceSink(f)), ...)
+//
 // # Example: Multi-Skill Assistant
 //
 // This example demonstrates a router agent that delegates to specialized sub-agents:
@@ -169,4 +360,18 @@
 //	User: 稻香
 //	Music Agent: [calls play_song]
 //	Music Agent: 正在播放：周杰伦 - 稻香
+//
+// The router can also be a ReAct agent that references the other agents as
+// tools. The model then picks the agent, and each call runs the sub-agent
+// until it answers (see AgentTool):
+//
+//	type: react
+//	name: router
+//	prompt: |
+//	  你是助手。算命的问题交给 fortune，听歌的请求交给 music。
+//	generator:
+//	  model: gpt-4
+//	tools:
+//	  - $ref: agent:fortune
+//	  - $ref: agent:music
 package agent
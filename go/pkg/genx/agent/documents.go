@@ -0,0 +1,498 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/haivivi/giztoy/go/pkg/embed"
+	"github.com/haivivi/giztoy/go/pkg/kv"
+	"github.com/haivivi/giztoy/go/pkg/vecstore"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// DocumentStoreConfig configures a DocumentStore.
+type DocumentStoreConfig struct {
+	// Store is the KV store holding documents and chunks. Required.
+	Store kv.Store
+
+	// Prefix is the key prefix of the collection, e.g. {"kb", "manuals"}.
+	// Each collection needs its own prefix.
+	// Default: {"kb"}
+	Prefix kv.Key
+
+	// Embedder, if set, embeds chunks on ingest and queries on search,
+	// so search ranks by meaning as well as by keywords.
+	Embedder embed.Embedder
+
+	// ChunkSize is the target size of a chunk, in runes.
+	// Default: 800
+	ChunkSize int
+
+	// ChunkOverlap is the number of runes of a chunk repeated at the start
+	// of the next chunk of the same section, so a sentence cut at a
+	// boundary is still found whole. It is capped at a quarter of
+	// ChunkSize.
+	// Default: 100
+	ChunkOverlap int
+}
+
+func (c *DocumentStoreConfig) setDefaults() {
+	if len(c.Prefix) == 0 {
+		c.Prefix = kv.Key{"kb"}
+	}
+	if c.ChunkSize <= 0 {
+		c.ChunkSize = 800
+	}
+	if c.ChunkOverlap <= 0 {
+		c.ChunkOverlap = 100
+	}
+	if c.ChunkOverlap > c.ChunkSize/4 {
+		c.ChunkOverlap = c.ChunkSize / 4
+	}
+}
+
+// Document is a document to ingest.
+type Document struct {
+	// ID identifies the document in its collection. Ingesting a document
+	// with the same ID replaces it. It must not contain the KV separator.
+	ID string
+
+	// Title is the document title, returned with search hits.
+	Title string
+
+	// Content is the document text. Markdown headings split it into
+	// sections, and a chunk never spans two sections.
+	Content string
+}
+
+// DocumentInfo describes an ingested document.
+type DocumentInfo struct {
+	ID        string    `msgpack:"id"`
+	Title     string    `msgpack:"title,omitempty"`
+	Chunks    int       `msgpack:"chunks"`
+	UpdatedAt time.Time `msgpack:"updated_at"`
+}
+
+// DocumentHit is a chunk returned by DocumentStore.Search.
+type DocumentHit struct {
+	DocID   string  `json:"doc_id"`
+	Title   string  `json:"title,omitempty"`
+	Heading string  `json:"heading,omitempty"` // section path, e.g. "Setup > Wi-Fi"
+	Text    string  `json:"text"`
+	Score   float64 `json:"score"`
+}
+
+// documentChunk is the stored form of a chunk.
+type documentChunk struct {
+	DocID   string    `msgpack:"doc_id"`
+	Title   string    `msgpack:"title,omitempty"`
+	Heading string    `msgpack:"heading,omitempty"`
+	Text    string    `msgpack:"text"`
+	Vector  []float32 `msgpack:"vector,omitempty"`
+}
+
+// DocumentStore is a knowledge base of documents kept in a kv.Store. It
+// splits documents into chunks on ingest and searches the chunks by
+// keywords and, with an Embedder, by vector similarity.
+//
+// Search scans every chunk of the collection, which suits product manuals
+// and FAQs of up to some thousands of chunks without a vector database.
+//
+// Keys under Prefix:
+//
+//	{prefix}:doc:{id}          → DocumentInfo (msgpack)
+//	{prefix}:chunk:{id}:{seq}  → chunk (msgpack)
+//
+// DocumentStore is safe for concurrent use if the Store is.
+type DocumentStore struct {
+	config DocumentStoreConfig
+}
+
+// NewDocumentStore creates a DocumentStore.
+func NewDocumentStore(config DocumentStoreConfig) (*DocumentStore, error) {
+	if config.Store == nil {
+		return nil, errors.New("document store: Store is required")
+	}
+	config.Prefix = slices.Clone(config.Prefix)
+	config.setDefaults()
+	return &DocumentStore{config: config}, nil
+}
+
+func (s *DocumentStore) key(parts ...string) kv.Key {
+	return append(slices.Clone(s.config.Prefix), parts...)
+}
+
+// Ingest splits doc into chunks and stores them, replacing any earlier
+// version of the document. It returns the number of chunks stored.
+func (s *DocumentStore) Ingest(ctx context.Context, doc Document) (int, error) {
+	if doc.ID == "" {
+		return 0, errors.New("ingest document: empty ID")
+	}
+	sections := chunkDocument(doc.Content, s.config.ChunkSize, s.config.ChunkOverlap)
+
+	var vectors [][]float32
+	if s.config.Embedder != nil && len(sections) > 0 {
+		texts := make([]string, len(sections))
+		for i, c := range sections {
+			texts[i] = embedText(doc.Title, c.heading, c.text)
+		}
+		var err error
+		vectors, err = s.config.Embedder.EmbedBatch(ctx, texts)
+		if err != nil {
+			return 0, fmt.Errorf("ingest document %s: embed: %w", doc.ID, err)
+		}
+		if len(vectors) != len(texts) {
+			return 0, fmt.Errorf("ingest document %s: embed: got %d vectors for %d chunks", doc.ID, len(vectors), len(texts))
+		}
+	}
+
+	if err := s.Delete(ctx, doc.ID); err != nil {
+		return 0, err
+	}
+
+	entries := make([]kv.Entry, 0, len(sections)+1)
+	for i, c := range sections {
+		chunk := documentChunk{DocID: doc.ID, Title: doc.Title, Heading: c.heading, Text: c.text}
+		if vectors != nil {
+			chunk.Vector = vectors[i]
+		}
+		data, err := msgpack.Marshal(&chunk)
+		if err != nil {
+			return 0, fmt.Errorf("ingest document %s: %w", doc.ID, err)
+		}
+		entries = append(entries, kv.Entry{Key: s.key("chunk", doc.ID, fmt.Sprintf("%06d", i)), Value: data})
+	}
+	info := DocumentInfo{ID: doc.ID, Title: doc.Title, Chunks: len(sections), UpdatedAt: time.Now()}
+	data, err := msgpack.Marshal(&info)
+	if err != nil {
+		return 0, fmt.Errorf("ingest document %s: %w", doc.ID, err)
+	}
+	entries = append(entries, kv.Entry{Key: s.key("doc", doc.ID), Value: data})
+
+	if err := s.config.Store.BatchSet(ctx, entries); err != nil {
+		return 0, fmt.Errorf("ingest document %s: %w", doc.ID, err)
+	}
+	return len(sections), nil
+}
+
+// Delete removes a document and its chunks. Deleting a missing document
+// is not an error.
+func (s *DocumentStore) Delete(ctx context.Context, id string) error {
+	keys := []kv.Key{s.key("doc", id)}
+	for e, err := range s.config.Store.List(ctx, s.key("chunk", id)) {
+		if err != nil {
+			return fmt.Errorf("delete document %s: %w", id, err)
+		}
+		keys = append(keys, e.Key)
+	}
+	if err := s.config.Store.BatchDelete(ctx, keys); err != nil {
+		return fmt.Errorf("delete document %s: %w", id, err)
+	}
+	return nil
+}
+
+// Documents returns the ingested documents, ordered by ID.
+func (s *DocumentStore) Documents(ctx context.Context) ([]DocumentInfo, error) {
+	var docs []DocumentInfo
+	for e, err := range s.config.Store.List(ctx, s.key("doc")) {
+		if err != nil {
+			return nil, fmt.Errorf("list documents: %w", err)
+		}
+		var info DocumentInfo
+		if err := msgpack.Unmarshal(e.Value, &info); err != nil {
+			return nil, fmt.Errorf("list documents: %w", err)
+		}
+		docs = append(docs, info)
+	}
+	return docs, nil
+}
+
+// Search returns up to limit chunks relevant to query, best first.
+//
+// A chunk scores the fraction of the query terms it contains. With an
+// Embedder, the score is 0.7 × cosine similarity + 0.3 × keyword score.
+// Chunks matching no term are only returned when ranked by vectors.
+func (s *DocumentStore) Search(ctx context.Context, query string, limit int) ([]DocumentHit, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = 5
+	}
+
+	var qvec []float32
+	if s.config.Embedder != nil {
+		var err error
+		qvec, err = s.config.Embedder.Embed(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("search documents: embed: %w", err)
+		}
+	}
+
+	var hits []DocumentHit
+	for e, err := range s.config.Store.List(ctx, s.key("chunk")) {
+		if err != nil {
+			return nil, fmt.Errorf("search documents: %w", err)
+		}
+		var chunk documentChunk
+		if err := msgpack.Unmarshal(e.Value, &chunk); err != nil {
+			return nil, fmt.Errorf("search documents: %w", err)
+		}
+
+		score := keywordScore(terms, chunk)
+		if qvec != nil && len(chunk.Vector) == len(qvec) {
+			sim := 1 - float64(vecstore.CosineDistance(qvec, chunk.Vector))
+			score = 0.7*sim + 0.3*score
+		}
+		if score <= 0 {
+			continue
+		}
+		hits = append(hits, DocumentHit{
+			DocID:   chunk.DocID,
+			Title:   chunk.Title,
+			Heading: chunk.Heading,
+			Text:    chunk.Text,
+			Score:   score,
+		})
+	}
+
+	slices.SortStableFunc(hits, func(a, b DocumentHit) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// embedText is the text embedded for a chunk; the title and heading give
+// short chunks their context.
+func embedText(title, heading, text string) string {
+	var b strings.Builder
+	for _, s := range []string{title, heading} {
+		if s != "" {
+			b.WriteString(s)
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString(text)
+	return b.String()
+}
+
+// keywordScore returns the fraction of terms found in the chunk.
+func keywordScore(terms []string, chunk documentChunk) float64 {
+	text := strings.ToLower(chunk.Title + "\n" + chunk.Heading + "\n" + chunk.Text)
+	n := 0
+	for _, t := range terms {
+		if strings.Contains(text, t) {
+			n++
+		}
+	}
+	return float64(n) / float64(len(terms))
+}
+
+// searchTerms splits a query into distinct lowercase terms: words of
+// letters and digits, and single Han characters, which are not separated
+// by spaces.
+func searchTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	add := func(t string) {
+		if t != "" && !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	var word strings.Builder
+	for _, r := range strings.ToLower(query) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			add(word.String())
+			word.Reset()
+			add(string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			add(word.String())
+			word.Reset()
+		}
+	}
+	add(word.String())
+	return terms
+}
+
+// textChunk is a chunk produced by chunkDocument.
+type textChunk struct {
+	heading string
+	text    string
+}
+
+// chunkDocument splits markdown or plain text into chunks of about size
+// runes. Headings start new sections; within a section, paragraphs are
+// packed into chunks, each starting with the last overlap runes of the
+// previous one. Paragraphs longer than size are cut.
+func chunkDocument(content string, size, overlap int) []textChunk {
+	var (
+		chunks   []textChunk
+		headings []string // heading path, by level
+		para     []string // lines of the current paragraph
+		paras    []string // paragraphs of the current section
+	)
+
+	flushPara := func() {
+		if p := strings.TrimSpace(strings.Join(para, "\n")); p != "" {
+			paras = append(paras, p)
+		}
+		para = para[:0]
+	}
+	flushSection := func() {
+		flushPara()
+		heading := strings.Join(slices.DeleteFunc(slices.Clone(headings), func(h string) bool { return h == "" }), " > ")
+		for _, text := range packParagraphs(paras, size, overlap) {
+			chunks = append(chunks, textChunk{heading: heading, text: text})
+		}
+		paras = paras[:0]
+	}
+
+	inFence := false
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if !inFence {
+			if level, title, ok := markdownHeading(trimmed); ok {
+				flushSection()
+				if len(headings) >= level {
+					headings = headings[:level-1]
+				}
+				for len(headings) < level-1 {
+					headings = append(headings, "")
+				}
+				headings = append(headings, title)
+				continue
+			}
+			if trimmed == "" {
+				flushPara()
+				continue
+			}
+		}
+		para = append(para, line)
+	}
+	flushSection()
+	return chunks
+}
+
+// markdownHeading parses an ATX heading such as "## Setup".
+func markdownHeading(line string) (level int, title string, ok bool) {
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ' && line[level] != '\t') {
+		return 0, "", false
+	}
+	title = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(line[level:]), "#"))
+	return level, title, true
+}
+
+// packParagraphs packs paragraphs into chunks of at most size runes.
+func packParagraphs(paras []string, size, overlap int) []string {
+	var (
+		chunks []string
+		cur    strings.Builder
+		n      int // runes in cur
+	)
+	emit := func() {
+		if n == 0 {
+			return
+		}
+		text := cur.String()
+		chunks = append(chunks, text)
+		cur.Reset()
+		n = 0
+		if tail := tailRunes(text, overlap); tail != "" {
+			cur.WriteString(tail)
+			n = utf8.RuneCountInString(tail)
+		}
+	}
+	// fresh reports whether cur only holds the overlap of the last chunk.
+	fresh := true
+
+	for _, p := range paras {
+		for _, piece := range splitRunes(p, size-overlap) {
+			pn := utf8.RuneCountInString(piece)
+			if !fresh && n+2+pn > size {
+				emit()
+				fresh = true
+			}
+			if n > 0 {
+				cur.WriteString("\n\n")
+				n += 2
+			}
+			cur.WriteString(piece)
+			n += pn
+			fresh = false
+		}
+	}
+	if !fresh {
+		chunks = append(chunks, cur.String())
+	}
+	return chunks
+}
+
+// splitRunes cuts s into pieces of at most n runes, preferring to cut
+// after a sentence end or a space.
+func splitRunes(s string, n int) []string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return []string{s}
+	}
+	var pieces []string
+	runes := []rune(s)
+	for len(runes) > n {
+		cut := n
+		for i := n; i > n/2; i-- {
+			if r := runes[i-1]; strings.ContainsRune(".!?。！？；;\n", r) || unicode.IsSpace(r) {
+				cut = i
+				break
+			}
+		}
+		pieces = append(pieces, strings.TrimSpace(string(runes[:cut])))
+		runes = runes[cut:]
+	}
+	if rest := strings.TrimSpace(string(runes)); rest != "" {
+		pieces = append(pieces, rest)
+	}
+	return pieces
+}
+
+// tailRunes returns the last n runes of s, starting at a word boundary if
+// there is one.
+func tailRunes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	if len(runes) <= n {
+		return ""
+	}
+	tail := runes[len(runes)-n:]
+	for i, r := range tail[:len(tail)/2] {
+		if unicode.IsSpace(r) {
+			return strings.TrimSpace(string(tail[i:]))
+		}
+	}
+	return strings.TrimSpace(string(tail))
+}
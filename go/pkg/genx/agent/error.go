@@ -8,4 +8,31 @@ var (
 
 	// ErrInvalidToolCall indicates an invalid tool call.
 	ErrInvalidToolCall = errors.New("agent: invalid tool call")
+
+	// ErrToolLimited indicates a tool call was rejected because the tool's
+	// concurrency or rate limit was reached (see agentcfg.ToolLimits).
+	ErrToolLimited = errors.New("agent: tool limit exceeded")
+
+	// ErrToolRejected indicates a tool call was rejected by its approver
+	// (see ToolApprover).
+	ErrToolRejected = errors.New("agent: tool call rejected")
+
+	// ErrNoPendingApproval indicates a tool call to approve or reject is
+	// not waiting for approval.
+	ErrNoPendingApproval = errors.New("agent: no tool call waiting for approval")
+
+	// ErrMaxDepth indicates an agent tool call was rejected because agent
+	// tools were nested deeper than allowed (see agentcfg.AgentTool).
+	ErrMaxDepth = errors.New("agent: max agent depth exceeded")
+
+	// ErrRoundLimit indicates a round reached one of its limits (see
+	// agentcfg.RoundLimits).
+	ErrRoundLimit = errors.New("agent: round limit exceeded")
+
+	// ErrMCPClosed indicates the connection to an MCP server is closed.
+	ErrMCPClosed = errors.New("agent: mcp connection closed")
+
+	// ErrTriggerBusy indicates a trigger firing was dropped because the
+	// firings of its binding queued up (see TriggerRegistry).
+	ErrTriggerBusy = errors.New("agent: trigger busy")
 )
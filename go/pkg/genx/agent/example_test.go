@@ -216,6 +216,7 @@ func Example_eventTypes() {
 	fmt.Println("EventToolDone:", agent.EventToolDone)
 	fmt.Println("EventToolError:", agent.EventToolError)
 	fmt.Println("EventInterrupted:", agent.EventInterrupted)
+	fmt.Println("EventToolChunk:", agent.EventToolChunk)
 	// Output:
 	// EventChunk: chunk
 	// EventEOF: eof
@@ -224,4 +225,5 @@ func Example_eventTypes() {
 	// EventToolDone: tool_done
 	// EventToolError: tool_error
 	// EventInterrupted: interrupted
+	// EventToolChunk: tool_chunk
 }
@@ -0,0 +1,350 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// Guardrail checks the content that enters and leaves a ReActAgent, for
+// deployments such as child-facing toys where moderation cannot be left to
+// the prompt. A guardrail can allow content, rewrite it, block it, or label
+// it for the caller.
+//
+// A Runtime that also implements GuardrailRuntime provides the guardrails of
+// its agents. They run in order: a rewrite is passed on to the next
+// guardrail, and the first block stops the chain.
+type Guardrail interface {
+	// CheckInput checks user input before the agent stores it and sends it
+	// to the model. A nil verdict allows the input.
+	CheckInput(ctx context.Context, req *GuardrailRequest) (*GuardrailVerdict, error)
+
+	// CheckOutput checks model output before the agent emits it. Output is
+	// checked a sentence at a time. A nil verdict allows the output.
+	CheckOutput(ctx context.Context, req *GuardrailRequest) (*GuardrailVerdict, error)
+}
+
+// GuardrailRuntime is implemented by Runtimes that moderate agent content.
+type GuardrailRuntime interface {
+	// Guardrails returns the guardrails, in the order they run.
+	Guardrails() []Guardrail
+}
+
+// GuardrailStage is the point at which a guardrail checks content.
+type GuardrailStage string
+
+const (
+	GuardrailInput  GuardrailStage = "input"  // User input
+	GuardrailOutput GuardrailStage = "output" // Model output
+)
+
+// GuardrailAction is the decision of a guardrail.
+type GuardrailAction string
+
+const (
+	GuardrailAllow   GuardrailAction = "allow"   // Pass the content unchanged
+	GuardrailRewrite GuardrailAction = "rewrite" // Replace the content with Text
+	GuardrailBlock   GuardrailAction = "block"   // Drop the content
+)
+
+// GuardrailRequest is the content a guardrail checks.
+type GuardrailRequest struct {
+	// AgentDef is the name of the agent definition.
+	AgentDef string
+
+	// StateID is the state ID of the agent.
+	StateID string
+
+	// Text is the content: the user input, or a sentence of the model
+	// output.
+	Text string
+}
+
+// GuardrailVerdict is the decision of a guardrail.
+type GuardrailVerdict struct {
+	// Action is the decision. Empty means GuardrailAllow.
+	Action GuardrailAction
+
+	// Text is the replacement content for GuardrailRewrite. For
+	// GuardrailBlock it is an optional reply the agent gives instead, e.g.
+	// "Let's talk about something else!".
+	Text string
+
+	// Reason explains the decision.
+	Reason string
+
+	// Labels annotate the content, e.g. "profanity" or "personal_info".
+	// Labels are reported with any action.
+	Labels []string
+}
+
+// blocked reports whether the verdict blocks the content.
+func (v *GuardrailVerdict) blocked() bool {
+	return v != nil && v.Action == GuardrailBlock
+}
+
+// checkGuardrails runs guardrails on text at stage. It returns nil if every
+// guardrail allows the text without labels; otherwise the combined verdict,
+// whose Text is the final text for a rewrite.
+func checkGuardrails(ctx context.Context, guardrails []Guardrail, stage GuardrailStage, req GuardrailRequest) (*GuardrailVerdict, error) {
+	var result *GuardrailVerdict
+	for _, g := range guardrails {
+		var (
+			v   *GuardrailVerdict
+			err error
+		)
+		if stage == GuardrailInput {
+			v, err = g.CheckInput(ctx, &req)
+		} else {
+			v, err = g.CheckOutput(ctx, &req)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			continue
+		}
+		if result == nil {
+			result = &GuardrailVerdict{Action: GuardrailAllow}
+		}
+		result.Labels = append(result.Labels, v.Labels...)
+		if v.Reason != "" {
+			result.Reason = v.Reason
+		}
+		switch v.Action {
+		case GuardrailBlock:
+			result.Action = GuardrailBlock
+			result.Text = v.Text
+			return result, nil
+		case GuardrailRewrite:
+			result.Action = GuardrailRewrite
+			result.Text = v.Text
+			req.Text = v.Text
+		}
+	}
+	return result, nil
+}
+
+// PatternRule is a rule of a PatternGuardrail.
+type PatternRule struct {
+	// Label annotates content that matches the rule.
+	Label string
+
+	// Pattern is a regular expression to match.
+	Pattern string
+
+	// Keywords are words to match, ignoring case.
+	Keywords []string
+
+	// Stage limits the rule to input or output. Empty means both.
+	Stage GuardrailStage
+
+	// Action is taken on a match. Empty means GuardrailBlock;
+	// GuardrailRewrite replaces the matches with Replacement;
+	// GuardrailAllow only labels the content.
+	Action GuardrailAction
+
+	// Replacement replaces matches for GuardrailRewrite.
+	Replacement string
+
+	// Reply is the reply given instead of blocked content.
+	Reply string
+}
+
+// PatternGuardrail is a Guardrail that matches regular expressions and
+// keywords. For example, to block an unsafe topic and mask phone numbers:
+//
+//	g, err := agent.NewPatternGuardrail(
+//	    agent.PatternRule{Label: "violence", Keywords: []string{"gun", "knife"},
+//	        Reply: "Let's talk about something else!"},
+//	    agent.PatternRule{Label: "phone", Pattern: `1\d{10}`,
+//	        Action: agent.GuardrailRewrite, Replacement: "***"},
+//	)
+type PatternGuardrail struct {
+	rules []patternRule
+}
+
+type patternRule struct {
+	PatternRule
+	re *regexp.Regexp
+}
+
+// NewPatternGuardrail compiles rules and creates a PatternGuardrail.
+func NewPatternGuardrail(rules ...PatternRule) (*PatternGuardrail, error) {
+	g := &PatternGuardrail{rules: make([]patternRule, 0, len(rules))}
+	for i, rule := range rules {
+		var alts []string
+		if rule.Pattern != "" {
+			alts = append(alts, "(?:"+rule.Pattern+")")
+		}
+		if len(rule.Keywords) > 0 {
+			quoted := make([]string, len(rule.Keywords))
+			for j, kw := range rule.Keywords {
+				quoted[j] = regexp.QuoteMeta(kw)
+			}
+			alts = append(alts, "(?i:"+strings.Join(quoted, "|")+")")
+		}
+		if len(alts) == 0 {
+			return nil, fmt.Errorf("agent: guardrail rule %d: pattern or keywords required", i)
+		}
+		re, err := regexp.Compile(strings.Join(alts, "|"))
+		if err != nil {
+			return nil, fmt.Errorf("agent: guardrail rule %d: %w", i, err)
+		}
+		g.rules = append(g.rules, patternRule{PatternRule: rule, re: re})
+	}
+	return g, nil
+}
+
+// CheckInput implements Guardrail.
+func (g *PatternGuardrail) CheckInput(ctx context.Context, req *GuardrailRequest) (*GuardrailVerdict, error) {
+	return g.check(GuardrailInput, req.Text), nil
+}
+
+// CheckOutput implements Guardrail.
+func (g *PatternGuardrail) CheckOutput(ctx context.Context, req *GuardrailRequest) (*GuardrailVerdict, error) {
+	return g.check(GuardrailOutput, req.Text), nil
+}
+
+func (g *PatternGuardrail) check(stage GuardrailStage, text string) *GuardrailVerdict {
+	var (
+		labels    []string
+		rewritten bool
+	)
+	for _, rule := range g.rules {
+		if rule.Stage != "" && rule.Stage != stage {
+			continue
+		}
+		if !rule.re.MatchString(text) {
+			continue
+		}
+		if rule.Label != "" {
+			labels = append(labels, rule.Label)
+		}
+		switch rule.Action {
+		case GuardrailAllow:
+		case GuardrailRewrite:
+			text = rule.re.ReplaceAllLiteralString(text, rule.Replacement)
+			rewritten = true
+		default:
+			return &GuardrailVerdict{
+				Action: GuardrailBlock,
+				Text:   rule.Reply,
+				Reason: "matched " + ruleName(rule.PatternRule),
+				Labels: labels,
+			}
+		}
+	}
+	if rewritten {
+		return &GuardrailVerdict{Action: GuardrailRewrite, Text: text, Labels: labels}
+	}
+	if len(labels) > 0 {
+		return &GuardrailVerdict{Action: GuardrailAllow, Labels: labels}
+	}
+	return nil
+}
+
+func ruleName(rule PatternRule) string {
+	if rule.Label != "" {
+		return rule.Label
+	}
+	return "pattern"
+}
+
+// defaultModerationPolicy is the policy of a ModeratorGuardrail when
+// ModeratorConfig.Policy is not set.
+const defaultModerationPolicy = `Flag content that is not suitable for a conversation with a young child:
+violence, sexual content, self-harm, drugs, hate, profanity, or requests for
+personal information such as addresses and phone numbers.`
+
+// ModeratorConfig configures a ModeratorGuardrail.
+type ModeratorConfig struct {
+	// Generator runs the moderation model, e.g. the agent Runtime.
+	Generator genx.Generator
+
+	// Model is the moderation model.
+	Model string
+
+	// Policy describes the content to flag. Empty means a policy for
+	// conversations with young children.
+	Policy string
+
+	// Stage limits moderation to input or output. Empty means both.
+	Stage GuardrailStage
+
+	// Reply is the reply given instead of flagged content.
+	Reply string
+}
+
+// ModeratorGuardrail is a Guardrail that asks a model whether content
+// violates a policy, and blocks flagged content.
+type ModeratorGuardrail struct {
+	cfg ModeratorConfig
+}
+
+// NewModeratorGuardrail creates a ModeratorGuardrail.
+func NewModeratorGuardrail(cfg ModeratorConfig) *ModeratorGuardrail {
+	if cfg.Policy == "" {
+		cfg.Policy = defaultModerationPolicy
+	}
+	return &ModeratorGuardrail{cfg: cfg}
+}
+
+type moderateArgs struct {
+	Flagged bool     `json:"flagged" description:"Whether the content violates the policy"`
+	Labels  []string `json:"labels,omitempty" description:"Categories of the violation"`
+	Reason  string   `json:"reason,omitempty" description:"Short explanation"`
+}
+
+var moderateTool = genx.MustNewFuncTool[moderateArgs](
+	"moderate",
+	"Report whether the content violates the moderation policy.",
+)
+
+// CheckInput implements Guardrail.
+func (g *ModeratorGuardrail) CheckInput(ctx context.Context, req *GuardrailRequest) (*GuardrailVerdict, error) {
+	return g.check(ctx, GuardrailInput, req.Text)
+}
+
+// CheckOutput implements Guardrail.
+func (g *ModeratorGuardrail) CheckOutput(ctx context.Context, req *GuardrailRequest) (*GuardrailVerdict, error) {
+	return g.check(ctx, GuardrailOutput, req.Text)
+}
+
+func (g *ModeratorGuardrail) check(ctx context.Context, stage GuardrailStage, text string) (*GuardrailVerdict, error) {
+	if g.cfg.Stage != "" && g.cfg.Stage != stage {
+		return nil, nil
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+
+	var mcb genx.ModelContextBuilder
+	mcb.PromptText("moderator", "You are a content moderator.\n"+g.cfg.Policy+"\nCall the moderate tool with your decision.")
+	mcb.UserText("", fmt.Sprintf("Content (%s):\n%s", stage, text))
+
+	_, call, err := g.cfg.Generator.Invoke(ctx, g.cfg.Model, mcb.Build(), moderateTool)
+	if err != nil {
+		return nil, fmt.Errorf("moderate: %w", err)
+	}
+	if call == nil {
+		return nil, fmt.Errorf("moderate: no function call returned")
+	}
+	var args moderateArgs
+	if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+		return nil, fmt.Errorf("moderate: parse result: %w", err)
+	}
+	if !args.Flagged {
+		return nil, nil
+	}
+	return &GuardrailVerdict{
+		Action: GuardrailBlock,
+		Text:   g.cfg.Reply,
+		Reason: args.Reason,
+		Labels: args.Labels,
+	}, nil
+}
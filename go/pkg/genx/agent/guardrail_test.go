@@ -0,0 +1,283 @@
+package agent_test
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agent"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+	"github.com/haivivi/giztoy/go/pkg/genx/playground"
+)
+
+// chunkedGenerator streams each response as the given text chunks, and
+// answers Invoke with a call of moderate.
+type chunkedGenerator struct {
+	responses [][]string
+	calls     int
+	moderate  string
+}
+
+func (g *chunkedGenerator) GenerateStream(ctx context.Context, model string, mc genx.ModelContext) (genx.Stream, error) {
+	var chunks []string
+	if g.calls < len(g.responses) {
+		chunks = g.responses[g.calls]
+	}
+	g.calls++
+	return &chunkedStream{chunks: chunks}, nil
+}
+
+func (g *chunkedGenerator) Invoke(ctx context.Context, model string, mc genx.ModelContext, tool *genx.FuncTool) (genx.Usage, *genx.FuncCall, error) {
+	return genx.Usage{}, tool.NewFuncCall(g.moderate), nil
+}
+
+type chunkedStream struct {
+	chunks []string
+}
+
+func (s *chunkedStream) Next() (*genx.MessageChunk, error) {
+	if len(s.chunks) == 0 {
+		return nil, genx.Done(genx.Usage{})
+	}
+	text := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return &genx.MessageChunk{Role: genx.RoleModel, Part: genx.Text(text)}, nil
+}
+
+func (s *chunkedStream) Close() error               { return nil }
+func (s *chunkedStream) CloseWithError(error) error { return nil }
+
+// newGuardedAgent creates simple_agent on a runtime with guardrails.
+func newGuardedAgent(t *testing.T, gen genx.Generator, guardrails ...agent.Guardrail) *agent.ReActAgent {
+	t.Helper()
+	ctx := context.Background()
+	store := playground.NewStore(nil)
+	if err := store.LoadReadonlyLayer("testdata", os.DirFS("testdata/agent_react_test")); err != nil {
+		t.Fatalf("load testdata: %v", err)
+	}
+	rt := playground.NewRuntime(
+		playground.WithStore(store),
+		playground.WithGenerator(gen),
+		playground.WithGuardrails(guardrails...),
+	)
+	agentDef, err := rt.GetAgentDef(ctx, "simple_agent")
+	if err != nil {
+		t.Fatalf("GetAgentDef error: %v", err)
+	}
+	a, err := agent.NewReActAgent(ctx, agentcfg.AsReActAgent(agentDef), rt, "")
+	if err != nil {
+		t.Fatalf("NewReActAgent error: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+// guardedRound sends input and returns the events of the round.
+func guardedRound(t *testing.T, a agent.Agent, input string) []*agent.AgentEvent {
+	t.Helper()
+	if err := a.Input(genx.Contents{genx.Text(input)}); err != nil {
+		t.Fatalf("Input error: %v", err)
+	}
+	var events []*agent.AgentEvent
+	for {
+		evt, err := a.Next()
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		events = append(events, evt)
+		if evt.Type == agent.EventEOF || evt.Type == agent.EventClosed {
+			return events
+		}
+	}
+}
+
+// chunkTexts returns the texts of the chunk events.
+func chunkTexts(events []*agent.AgentEvent) []string {
+	var texts []string
+	for _, evt := range events {
+		if evt.Type == agent.EventChunk {
+			texts = append(texts, string(evt.Chunk.Part.(genx.Text)))
+		}
+	}
+	return texts
+}
+
+// findEvent returns the first event of type typ, or nil.
+func findEvent(events []*agent.AgentEvent, typ agent.EventType) *agent.AgentEvent {
+	for _, evt := range events {
+		if evt.Type == typ {
+			return evt
+		}
+	}
+	return nil
+}
+
+func newTestPatternGuardrail(t *testing.T) *agent.PatternGuardrail {
+	t.Helper()
+	g, err := agent.NewPatternGuardrail(
+		agent.PatternRule{Label: "weapons", Keywords: []string{"gun", "knife"}, Reply: "Let's talk about something else!"},
+		agent.PatternRule{Label: "phone", Pattern: `1\d{10}`, Action: agent.GuardrailRewrite, Replacement: "***"},
+		agent.PatternRule{Label: "greeting", Keywords: []string{"hello"}, Action: agent.GuardrailAllow},
+	)
+	if err != nil {
+		t.Fatalf("NewPatternGuardrail error: %v", err)
+	}
+	return g
+}
+
+func TestPatternGuardrail(t *testing.T) {
+	g := newTestPatternGuardrail(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		text   string
+		action agent.GuardrailAction
+		want   string
+		labels []string
+	}{
+		{"I want a GUN", agent.GuardrailBlock, "Let's talk about something else!", []string{"weapons"}},
+		{"Call 13812345678 now", agent.GuardrailRewrite, "Call *** now", []string{"phone"}},
+		{"Hello, 13812345678", agent.GuardrailRewrite, "Hello, ***", []string{"phone", "greeting"}},
+		{"Hello!", agent.GuardrailAllow, "", []string{"greeting"}},
+	}
+	for _, tt := range tests {
+		v, err := g.CheckInput(ctx, &agent.GuardrailRequest{Text: tt.text})
+		if err != nil {
+			t.Fatalf("CheckInput(%q) error: %v", tt.text, err)
+		}
+		if v == nil || v.Action != tt.action || v.Text != tt.want || !slices.Equal(v.Labels, tt.labels) {
+			t.Errorf("CheckInput(%q) = %+v, want %s %q %v", tt.text, v, tt.action, tt.want, tt.labels)
+		}
+	}
+
+	if v, _ := g.CheckOutput(ctx, &agent.GuardrailRequest{Text: "A nice story."}); v != nil {
+		t.Errorf("CheckOutput of clean text = %+v, want nil", v)
+	}
+}
+
+func TestPatternGuardrail_Stage(t *testing.T) {
+	g, err := agent.NewPatternGuardrail(agent.PatternRule{Keywords: []string{"secret"}, Stage: agent.GuardrailOutput})
+	if err != nil {
+		t.Fatalf("NewPatternGuardrail error: %v", err)
+	}
+	ctx := context.Background()
+	if v, _ := g.CheckInput(ctx, &agent.GuardrailRequest{Text: "a secret"}); v != nil {
+		t.Errorf("CheckInput = %+v, want nil for an output rule", v)
+	}
+	if v, _ := g.CheckOutput(ctx, &agent.GuardrailRequest{Text: "a secret"}); v == nil || v.Action != agent.GuardrailBlock {
+		t.Errorf("CheckOutput = %+v, want block", v)
+	}
+}
+
+func TestNewPatternGuardrail_Invalid(t *testing.T) {
+	if _, err := agent.NewPatternGuardrail(agent.PatternRule{Label: "empty"}); err == nil {
+		t.Error("expected error for rule without pattern or keywords")
+	}
+	if _, err := agent.NewPatternGuardrail(agent.PatternRule{Pattern: "("}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestModeratorGuardrail(t *testing.T) {
+	ctx := context.Background()
+	gen := &chunkedGenerator{moderate: `{"flagged":true,"labels":["self_harm"],"reason":"unsafe"}`}
+	g := agent.NewModeratorGuardrail(agent.ModeratorConfig{Generator: gen, Model: "mod", Reply: "Let's ask a grown-up."})
+
+	v, err := g.CheckInput(ctx, &agent.GuardrailRequest{Text: "something unsafe"})
+	if err != nil {
+		t.Fatalf("CheckInput error: %v", err)
+	}
+	if v == nil || v.Action != agent.GuardrailBlock || v.Text != "Let's ask a grown-up." || v.Reason != "unsafe" {
+		t.Errorf("verdict = %+v, want block with reply", v)
+	}
+
+	gen.moderate = `{"flagged":false}`
+	if v, err := g.CheckOutput(ctx, &agent.GuardrailRequest{Text: "A nice story."}); err != nil || v != nil {
+		t.Errorf("CheckOutput = %+v, %v; want nil, nil", v, err)
+	}
+
+	inputOnly := agent.NewModeratorGuardrail(agent.ModeratorConfig{Generator: gen, Stage: agent.GuardrailInput})
+	gen.moderate = `{"flagged":true}`
+	if v, _ := inputOnly.CheckOutput(ctx, &agent.GuardrailRequest{Text: "anything"}); v != nil {
+		t.Errorf("CheckOutput of input-only moderator = %+v, want nil", v)
+	}
+}
+
+func TestReActAgent_GuardrailBlocksInput(t *testing.T) {
+	gen := &chunkedGenerator{responses: [][]string{{"Sure!"}}}
+	a := newGuardedAgent(t, gen, newTestPatternGuardrail(t))
+
+	events := guardedRound(t, a, "Where can I buy a gun?")
+	blocked := findEvent(events, agent.EventBlocked)
+	if blocked == nil || blocked.Moderation == nil || !slices.Equal(blocked.Moderation.Labels, []string{"weapons"}) {
+		t.Fatalf("events = %v, want EventBlocked labeled weapons", events)
+	}
+	if got := chunkTexts(events); !slices.Equal(got, []string{"Let's talk about something else!"}) {
+		t.Errorf("chunks = %q, want the reply", got)
+	}
+	if gen.calls != 0 {
+		t.Errorf("generator called %d times, want 0", gen.calls)
+	}
+	if history := a.FormatHistory(context.Background()); strings.Contains(history, "gun") {
+		t.Errorf("blocked input stored:\n%s", history)
+	}
+
+	// The agent goes on with the next input
+	events = guardedRound(t, a, "Tell me a story")
+	if got := strings.Join(chunkTexts(events), ""); got != "Sure!" {
+		t.Errorf("chunks = %q, want %q", got, "Sure!")
+	}
+}
+
+func TestReActAgent_GuardrailRewritesInput(t *testing.T) {
+	gen := &chunkedGenerator{responses: [][]string{{"Got it."}}}
+	a := newGuardedAgent(t, gen, newTestPatternGuardrail(t))
+
+	events := guardedRound(t, a, "My number is 13812345678")
+	if events[0].Moderation == nil || events[0].Moderation.Action != agent.GuardrailRewrite {
+		t.Errorf("first event moderation = %+v, want the input rewrite", events[0].Moderation)
+	}
+	history := a.FormatHistory(context.Background())
+	if !strings.Contains(history, "My number is ***") || strings.Contains(history, "13812345678") {
+		t.Errorf("history not rewritten:\n%s", history)
+	}
+}
+
+func TestReActAgent_GuardrailOutput(t *testing.T) {
+	gen := &chunkedGenerator{responses: [][]string{{"Hi the", "re. Call 138123", "45678 now. B", "ye"}}}
+	a := newGuardedAgent(t, gen, newTestPatternGuardrail(t))
+
+	events := guardedRound(t, a, "Hi")
+	want := []string{"Hi there.", " Call *** now.", " Bye"}
+	if got := chunkTexts(events); !slices.Equal(got, want) {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+	if history := a.FormatHistory(context.Background()); !strings.Contains(history, "[model]: Hi there. Call *** now. Bye") {
+		t.Errorf("history missing rewritten response:\n%s", history)
+	}
+}
+
+func TestReActAgent_GuardrailBlocksOutput(t *testing.T) {
+	gen := &chunkedGenerator{responses: [][]string{{"Sure. Here is how to use a kni", "fe. First, ", "hold it."}}}
+	a := newGuardedAgent(t, gen, newTestPatternGuardrail(t))
+
+	events := guardedRound(t, a, "Tell me something")
+	var types []agent.EventType
+	for _, evt := range events {
+		types = append(types, evt.Type)
+	}
+	wantTypes := []agent.EventType{agent.EventChunk, agent.EventBlocked, agent.EventChunk, agent.EventEOF}
+	if !slices.Equal(types, wantTypes) {
+		t.Fatalf("event types = %v, want %v", types, wantTypes)
+	}
+	if got := chunkTexts(events); !slices.Equal(got, []string{"Sure.", "Let's talk about something else!"}) {
+		t.Errorf("chunks = %q", got)
+	}
+	history := a.FormatHistory(context.Background())
+	if !strings.Contains(history, "[model]: Sure.Let's talk about something else!") || strings.Contains(history, "knife") {
+		t.Errorf("history = \n%s", history)
+	}
+}
@@ -0,0 +1,552 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+)
+
+// mcpProtocolVersion is the MCP protocol version the client asks for.
+const mcpProtocolVersion = "2024-11-05"
+
+// mcpStopTimeout is how long a stdio server has to exit after its input is
+// closed before it is killed.
+const mcpStopTimeout = 2 * time.Second
+
+// MCPClient is a client of an MCP (Model Context Protocol) server. It
+// speaks JSON-RPC 2.0 over the transport of an agentcfg.MCPServer: the
+// stdio of a subprocess, or HTTP with Server-Sent Events.
+//
+// A client is safe for concurrent use; calls are matched to their
+// responses by request ID.
+type MCPClient struct {
+	transport mcpTransport
+	nextID    atomic.Int64
+	info      MCPServerInfo
+	closeOnce sync.Once
+	closeErr  error
+
+	mu      sync.Mutex
+	pending map[int64]chan *mcpMessage
+	err     error // why the connection ended, once it has
+
+	done chan struct{}
+}
+
+// MCPServerInfo describes an MCP server, as reported on initialization.
+type MCPServerInfo struct {
+	Name            string `json:"name"`
+	Version         string `json:"version"`
+	ProtocolVersion string `json:"-"`
+}
+
+// MCPToolInfo is a tool listed by an MCP server.
+type MCPToolInfo struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+// MCPContent is an item of the content of a tool result: text, an image,
+// audio, or an embedded resource.
+type MCPContent struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	Data     string          `json:"data,omitempty"`
+	MimeType string          `json:"mimeType,omitempty"`
+	Resource json.RawMessage `json:"resource,omitempty"`
+}
+
+// MCPToolResult is the result of a tool call.
+type MCPToolResult struct {
+	Content           []MCPContent    `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
+}
+
+// Text returns the text items of the content, one per line.
+func (r *MCPToolResult) Text() string {
+	var texts []string
+	for _, c := range r.Content {
+		if c.Type == "text" {
+			texts = append(texts, c.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// MCPError is an error returned by an MCP server.
+type MCPError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *MCPError) Error() string {
+	return fmt.Sprintf("agent: mcp error %d: %s", e.Code, e.Message)
+}
+
+// mcpMessage is a JSON-RPC 2.0 request, notification or response.
+type mcpMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  any             `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *MCPError       `json:"error,omitempty"`
+}
+
+// mcpTransport carries the JSON-RPC messages of a client.
+type mcpTransport interface {
+	// send sends a message.
+	send(ctx context.Context, msg []byte) error
+
+	// recv blocks for the next message from the server.
+	recv() ([]byte, error)
+
+	// close ends the connection; a blocked recv returns an error.
+	close() error
+}
+
+// ConnectMCP connects to server and initializes an MCP session. The
+// connection lasts until Close, independent of ctx.
+func ConnectMCP(ctx context.Context, server agentcfg.MCPServer) (*MCPClient, error) {
+	var (
+		t   mcpTransport
+		err error
+	)
+	switch {
+	case server.Command != "":
+		t, err = startMCPStdio(server)
+	case server.URL != "":
+		t, err = dialMCPSSE(ctx, server)
+	default:
+		err = errors.New("command or url is required")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("agent: mcp connect: %w", err)
+	}
+
+	c := &MCPClient{
+		transport: t,
+		pending:   make(map[int64]chan *mcpMessage),
+		done:      make(chan struct{}),
+	}
+	go c.readLoop()
+
+	if err := c.initialize(ctx); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("agent: mcp initialize: %w", err)
+	}
+	return c, nil
+}
+
+// initialize performs the MCP handshake.
+func (c *MCPClient) initialize(ctx context.Context) error {
+	params := map[string]any{
+		"protocolVersion": mcpProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "giztoy", "version": "1.0.0"},
+	}
+	var result struct {
+		ProtocolVersion string        `json:"protocolVersion"`
+		ServerInfo      MCPServerInfo `json:"serverInfo"`
+	}
+	if err := c.call(ctx, "initialize", params, &result); err != nil {
+		return err
+	}
+	c.info = result.ServerInfo
+	c.info.ProtocolVersion = result.ProtocolVersion
+	return c.notify(ctx, "notifications/initialized", nil)
+}
+
+// ServerInfo returns the server's description.
+func (c *MCPClient) ServerInfo() MCPServerInfo {
+	return c.info
+}
+
+// ListTools returns all tools of the server.
+func (c *MCPClient) ListTools(ctx context.Context) ([]MCPToolInfo, error) {
+	var (
+		tools  []MCPToolInfo
+		cursor string
+	)
+	for {
+		var params any
+		if cursor != "" {
+			params = map[string]any{"cursor": cursor}
+		}
+		var result struct {
+			Tools      []MCPToolInfo `json:"tools"`
+			NextCursor string        `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", params, &result); err != nil {
+			return nil, fmt.Errorf("list tools: %w", err)
+		}
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallTool calls the tool name with args, a JSON object. A tool that fails
+// reports it in the result with IsError rather than as an error.
+func (c *MCPClient) CallTool(ctx context.Context, name string, args json.RawMessage) (*MCPToolResult, error) {
+	if len(bytes.TrimSpace(args)) == 0 {
+		args = json.RawMessage("{}")
+	}
+	params := map[string]any{"name": name, "arguments": args}
+	var result MCPToolResult
+	if err := c.call(ctx, "tools/call", params, &result); err != nil {
+		return nil, fmt.Errorf("call tool %s: %w", name, err)
+	}
+	return &result, nil
+}
+
+// Close ends the session and, for a stdio server, stops the subprocess.
+func (c *MCPClient) Close() error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.transport.close()
+		c.closeWithError(ErrMCPClosed)
+	})
+	return c.closeErr
+}
+
+// call sends a request and waits for its response, decoding its result
+// into result.
+func (c *MCPClient) call(ctx context.Context, method string, params, result any) error {
+	id := c.nextID.Add(1)
+	ch := make(chan *mcpMessage, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(ctx, &mcpMessage{
+		ID:     json.RawMessage(strconv.FormatInt(id, 10)),
+		Method: method,
+		Params: params,
+	}); err != nil {
+		return err
+	}
+
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result == nil || len(msg.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(msg.Result, result)
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		// Tell the server to stop working on the request
+		c.notify(context.Background(), "notifications/cancelled", map[string]any{
+			"requestId": id,
+			"reason":    ctx.Err().Error(),
+		})
+		return ctx.Err()
+	}
+}
+
+// notify sends a notification.
+func (c *MCPClient) notify(ctx context.Context, method string, params any) error {
+	return c.write(ctx, &mcpMessage{Method: method, Params: params})
+}
+
+func (c *MCPClient) write(ctx context.Context, msg *mcpMessage) error {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", msg.Method, err)
+	}
+	if err := c.transport.send(ctx, data); err != nil {
+		return fmt.Errorf("send %s: %w", msg.Method, err)
+	}
+	return nil
+}
+
+// readLoop dispatches the messages of the server until the connection
+// ends.
+func (c *MCPClient) readLoop() {
+	for {
+		data, err := c.transport.recv()
+		if err != nil {
+			c.closeWithError(fmt.Errorf("%w: %v", ErrMCPClosed, err))
+			return
+		}
+		var msg mcpMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		if msg.Method != "" {
+			c.handleRequest(&msg)
+			continue
+		}
+		id, err := strconv.ParseInt(string(msg.ID), 10, 64)
+		if err != nil {
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[id]
+		c.mu.Unlock()
+		if ok {
+			select {
+			case ch <- &msg:
+			default: // a duplicate response
+			}
+		}
+	}
+}
+
+// handleRequest answers a request of the server. Notifications such as
+// log messages are ignored; the client offers no capabilities besides
+// ping.
+func (c *MCPClient) handleRequest(req *mcpMessage) {
+	if len(req.ID) == 0 {
+		return
+	}
+	resp := &mcpMessage{ID: req.ID}
+	if req.Method == "ping" {
+		resp.Result = json.RawMessage("{}")
+	} else {
+		resp.Error = &MCPError{Code: -32601, Message: "method not found: " + req.Method}
+	}
+	go c.write(context.Background(), resp)
+}
+
+// closeWithError ends the connection with err, once.
+func (c *MCPClient) closeWithError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.done)
+}
+
+// mcpStdio is the stdio transport: the server is a subprocess reading
+// messages from its stdin and writing them to its stdout, one per line.
+type mcpStdio struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+
+	wmu sync.Mutex
+}
+
+func startMCPStdio(server agentcfg.MCPServer) (*mcpStdio, error) {
+	cmd := exec.Command(server.Command, server.Args...)
+	if len(server.Env) > 0 {
+		keys := make([]string, 0, len(server.Env))
+		for k := range server.Env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		cmd.Env = os.Environ()
+		for _, k := range keys {
+			cmd.Env = append(cmd.Env, k+"="+server.Env[k])
+		}
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", server.Command, err)
+	}
+	return &mcpStdio{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}, nil
+}
+
+func (t *mcpStdio) send(_ context.Context, msg []byte) error {
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+	_, err := t.stdin.Write(append(msg, '\n'))
+	return err
+}
+
+func (t *mcpStdio) recv() ([]byte, error) {
+	for {
+		line, err := t.stdout.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *mcpStdio) close() error {
+	t.stdin.Close()
+	kill := time.AfterFunc(mcpStopTimeout, func() { t.cmd.Process.Kill() })
+	defer kill.Stop()
+	var exitErr *exec.ExitError
+	if err := t.cmd.Wait(); err != nil && !errors.As(err, &exitErr) {
+		return err
+	}
+	return nil
+}
+
+// mcpSSE is the HTTP with Server-Sent Events transport: the server sends
+// messages as events of a long-lived GET request, and receives them as
+// POST requests to the endpoint it announces first.
+type mcpSSE struct {
+	headers  map[string]string
+	endpoint string
+	body     io.ReadCloser
+	events   *bufio.Reader
+	cancel   context.CancelFunc
+}
+
+func dialMCPSSE(ctx context.Context, server agentcfg.MCPServer) (*mcpSSE, error) {
+	// The event stream outlives ctx, which only bounds the dial
+	streamCtx, cancel := context.WithCancel(context.Background())
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for k, v := range server.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("GET %s: %s", server.URL, resp.Status)
+	}
+
+	t := &mcpSSE{
+		headers: server.Headers,
+		body:    resp.Body,
+		events:  bufio.NewReader(resp.Body),
+		cancel:  cancel,
+	}
+	for {
+		event, data, err := t.readEvent()
+		if err != nil {
+			t.close()
+			return nil, fmt.Errorf("read endpoint: %w", err)
+		}
+		if event != "endpoint" {
+			continue
+		}
+		base, _ := url.Parse(server.URL)
+		ref, err := url.Parse(strings.TrimSpace(data))
+		if err != nil {
+			t.close()
+			return nil, fmt.Errorf("invalid endpoint %q: %w", data, err)
+		}
+		t.endpoint = base.ResolveReference(ref).String()
+		return t, nil
+	}
+}
+
+// readEvent reads the next event of the stream.
+func (t *mcpSSE) readEvent() (event, data string, err error) {
+	var lines []string
+	for {
+		line, err := t.events.ReadString('\n')
+		if err != nil {
+			return "", "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if event == "" && lines == nil {
+				continue
+			}
+			if event == "" {
+				event = "message"
+			}
+			return event, strings.Join(lines, "\n"), nil
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			lines = append(lines, value)
+		}
+	}
+}
+
+func (t *mcpSSE) send(ctx context.Context, msg []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("POST %s: %s", t.endpoint, resp.Status)
+	}
+	return nil
+}
+
+func (t *mcpSSE) recv() ([]byte, error) {
+	for {
+		event, data, err := t.readEvent()
+		if err != nil {
+			return nil, err
+		}
+		if event == "message" {
+			return []byte(data), nil
+		}
+	}
+}
+
+func (t *mcpSSE) close() error {
+	t.cancel()
+	return t.body.Close()
+}
@@ -0,0 +1,299 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+)
+
+// StyleLabel labels the GuardrailVerdict of output a StyleRewriter
+// rewrote.
+const StyleLabel = "style"
+
+// StyleRewriter is a Guardrail that enforces the agentcfg.OutputStyle of a
+// persona on model output, a sentence at a time, before it reaches TTS:
+//   - Banned phrases are removed and replacement words substituted
+//   - With a RewriteModel, sentences longer than MaxSentenceLength (every
+//     sentence if Vocabulary is set) are rewritten by the model
+//   - Otherwise, or if the model fails, long sentences are split at clause
+//     breaks (commas, colons); a clause longer than MaxSentenceLength
+//     is kept whole
+//
+// A ReActAgent whose definition has a style runs a StyleRewriter after the
+// guardrails of its runtime, so that it sees moderated text. Its rewrites
+// are labeled StyleLabel. It does not check input.
+type StyleRewriter struct {
+	style   agentcfg.OutputStyle
+	gen     genx.Generator
+	banned  *regexp.Regexp
+	words   *regexp.Regexp
+	replace map[string]string // by lower-case word
+}
+
+var _ Guardrail = (*StyleRewriter)(nil)
+
+// NewStyleRewriter creates a StyleRewriter for style. gen runs the
+// RewriteModel, e.g. the agent Runtime; it may be nil without one.
+func NewStyleRewriter(style agentcfg.OutputStyle, gen genx.Generator) *StyleRewriter {
+	r := &StyleRewriter{
+		style:   style,
+		gen:     gen,
+		banned:  phraseRegexp(style.BannedPhrases),
+		replace: make(map[string]string, len(style.Replacements)),
+	}
+	for k, v := range style.Replacements {
+		r.replace[strings.ToLower(k)] = v
+	}
+	r.words = phraseRegexp(slices.Collect(maps.Keys(style.Replacements)))
+	return r
+}
+
+// phraseRegexp matches phrases ignoring case, whole words only where a
+// phrase starts or ends with an ASCII letter or digit. It returns nil for
+// no phrases.
+func phraseRegexp(phrases []string) *regexp.Regexp {
+	phrases = slices.DeleteFunc(slices.Clone(phrases), func(p string) bool { return p == "" })
+	if len(phrases) == 0 {
+		return nil
+	}
+	// Longest first, so that a phrase wins over its prefix
+	slices.SortFunc(phrases, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b))
+	})
+	alts := make([]string, len(phrases))
+	for i, p := range phrases {
+		q := regexp.QuoteMeta(p)
+		if isWordByte(p[0]) {
+			q = `\b` + q
+		}
+		if isWordByte(p[len(p)-1]) {
+			q += `\b`
+		}
+		alts[i] = q
+	}
+	return regexp.MustCompile("(?i:" + strings.Join(alts, "|") + ")")
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// CheckInput implements Guardrail. Input is not checked.
+func (r *StyleRewriter) CheckInput(ctx context.Context, req *GuardrailRequest) (*GuardrailVerdict, error) {
+	return nil, nil
+}
+
+// CheckOutput implements Guardrail. A failure of the rewrite model is
+// reported in the verdict's Reason, not as an error.
+func (r *StyleRewriter) CheckOutput(ctx context.Context, req *GuardrailRequest) (*GuardrailVerdict, error) {
+	if strings.TrimSpace(req.Text) == "" {
+		return nil, nil
+	}
+
+	// Keep the space around the sentence, which separates it from the
+	// sentences before and after
+	body := strings.TrimSpace(req.Text)
+	start := strings.Index(req.Text, body)
+	lead, trail := req.Text[:start], req.Text[start+len(body):]
+
+	text := r.applyRules(body)
+	var reason string
+	if r.style.RewriteModel != "" && r.gen != nil && (r.style.Vocabulary != "" || r.tooLong(text)) {
+		rewritten, err := r.rewrite(ctx, text)
+		if err != nil {
+			reason = err.Error()
+		} else {
+			text = r.applyRules(rewritten)
+		}
+	}
+	text = splitSentence(text, r.style.MaxSentenceLength)
+
+	action := GuardrailRewrite
+	if text == body {
+		if reason == "" {
+			return nil, nil
+		}
+		action = GuardrailAllow
+	}
+	return &GuardrailVerdict{
+		Action: action,
+		Text:   lead + text + trail,
+		Reason: reason,
+		Labels: []string{StyleLabel},
+	}, nil
+}
+
+// applyRules removes banned phrases and substitutes replacement words.
+func (r *StyleRewriter) applyRules(text string) string {
+	if r.banned != nil {
+		text = tidySpace(r.banned.ReplaceAllString(text, ""))
+	}
+	if r.words != nil {
+		text = r.words.ReplaceAllStringFunc(text, func(w string) string {
+			return r.replace[strings.ToLower(w)]
+		})
+	}
+	return text
+}
+
+var (
+	spaceRun       = regexp.MustCompile(`[ \t]{2,}`)
+	spaceBefore    = regexp.MustCompile(`[ \t]+([,.!?;:])`)
+	breakBeforeEnd = regexp.MustCompile(`[,，、:：]+([.!?;。！？；])`)
+)
+
+// tidySpace cleans up after removed phrases: runs of spaces, space before
+// punctuation, and clause breaks left before a sentence end or at the
+// start of the text.
+func tidySpace(text string) string {
+	text = spaceRun.ReplaceAllString(text, " ")
+	text = spaceBefore.ReplaceAllString(text, "$1")
+	text = breakBeforeEnd.ReplaceAllString(text, "$1")
+	return strings.TrimLeft(text, " \t,，、;；:：")
+}
+
+// tooLong reports whether a sentence of text is longer than
+// MaxSentenceLength.
+func (r *StyleRewriter) tooLong(text string) bool {
+	if r.style.MaxSentenceLength <= 0 {
+		return false
+	}
+	for _, s := range sentences(text) {
+		if utf8.RuneCountInString(strings.TrimSpace(s)) > r.style.MaxSentenceLength {
+			return true
+		}
+	}
+	return false
+}
+
+type rewriteArgs struct {
+	Text string `json:"text" description:"The rewritten text"`
+}
+
+var rewriteTool = genx.MustNewFuncTool[rewriteArgs](
+	"rewrite",
+	"Return the text rewritten to fit the style.",
+)
+
+// rewrite asks the RewriteModel to rewrite text to the style.
+func (r *StyleRewriter) rewrite(ctx context.Context, text string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("You rewrite the replies of a voice assistant to fit its persona. Keep their meaning, tone and language, and do not add anything.\n")
+	if n := r.style.MaxSentenceLength; n > 0 {
+		fmt.Fprintf(&sb, "- Keep every sentence under %d characters; split longer sentences.\n", n)
+	}
+	if r.style.Vocabulary != "" {
+		fmt.Fprintf(&sb, "- Use only %s.\n", r.style.Vocabulary)
+	}
+	if len(r.style.BannedPhrases) > 0 {
+		fmt.Fprintf(&sb, "- Never say: %s.\n", strings.Join(r.style.BannedPhrases, "; "))
+	}
+	sb.WriteString("Call the rewrite tool with the rewritten text.")
+
+	var mcb genx.ModelContextBuilder
+	mcb.PromptText("rewriter", sb.String())
+	mcb.UserText("", text)
+
+	_, call, err := r.gen.Invoke(ctx, r.style.RewriteModel, mcb.Build(), rewriteTool)
+	if err != nil {
+		return "", fmt.Errorf("rewrite: %w", err)
+	}
+	if call == nil {
+		return "", fmt.Errorf("rewrite: no function call returned")
+	}
+	var args rewriteArgs
+	if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+		return "", fmt.Errorf("rewrite: parse result: %w", err)
+	}
+	if strings.TrimSpace(args.Text) == "" {
+		return "", fmt.Errorf("rewrite: empty text")
+	}
+	return strings.TrimSpace(args.Text), nil
+}
+
+// sentences splits text after each sentence end.
+func sentences(text string) []string {
+	var out []string
+	for text != "" {
+		i := strings.IndexAny(text, ".!?;\n。！？；")
+		if i < 0 {
+			return append(out, text)
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		out = append(out, text[:i+size])
+		text = text[i+size:]
+	}
+	return out
+}
+
+// splitSentence splits the sentences of text longer than limit characters
+// at clause breaks, ending each part with a full stop. A limit of 0 leaves
+// text as it is.
+func splitSentence(text string, limit int) string {
+	if limit <= 0 {
+		return text
+	}
+	var out strings.Builder
+	for _, s := range sentences(text) {
+		if utf8.RuneCountInString(strings.TrimSpace(s)) <= limit {
+			out.WriteString(s)
+			continue
+		}
+		var part strings.Builder
+		for _, clause := range clauses(s) {
+			if part.Len() > 0 && utf8.RuneCountInString(strings.TrimSpace(part.String()+clause)) > limit {
+				out.WriteString(endClause(part.String()))
+				part.Reset()
+				clause = capitalize(clause)
+			}
+			part.WriteString(clause)
+		}
+		out.WriteString(part.String())
+	}
+	return out.String()
+}
+
+// clauses splits a sentence after each clause break.
+func clauses(s string) []string {
+	var out []string
+	for s != "" {
+		i := strings.IndexAny(s, ",:，：")
+		if i < 0 {
+			return append(out, s)
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		out = append(out, s[:i+size])
+		s = s[i+size:]
+	}
+	return out
+}
+
+// endClause replaces the clause break ending part with a full stop.
+func endClause(part string) string {
+	r, size := utf8.DecodeLastRuneInString(part)
+	if r >= 0x3000 {
+		return part[:len(part)-size] + "。"
+	}
+	return part[:len(part)-size] + "."
+}
+
+// capitalize upper-cases the first letter of a clause, after its leading
+// space.
+func capitalize(clause string) string {
+	i := strings.IndexFunc(clause, func(r rune) bool { return !unicode.IsSpace(r) })
+	if i < 0 {
+		return clause
+	}
+	r, size := utf8.DecodeRuneInString(clause[i:])
+	return clause[:i] + string(unicode.ToUpper(r)) + clause[i+size:]
+}
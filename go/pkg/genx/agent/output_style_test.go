@@ -0,0 +1,111 @@
+package agent_test
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/haivivi/giztoy/go/pkg/genx/agent"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+	"github.com/haivivi/giztoy/go/pkg/genx/playground"
+)
+
+func TestStyleRewriter_Rules(t *testing.T) {
+	ctx := context.Background()
+	r := agent.NewStyleRewriter(agentcfg.OutputStyle{
+		MaxSentenceLength: 20,
+		BannedPhrases:     []string{"as an AI"},
+		Replacements:      map[string]string{"approximately": "about", "hi": "hello"},
+	}, nil)
+
+	tests := []struct {
+		text string
+		want string // "" for no rewrite
+	}{
+		{" As an AI, I think it is approximately ten miles, over the hill. ", " I think it is about ten miles. Over the hill. "},
+		{"我们今天早上去公园玩，然后去动物园看大熊猫，最后回家吃饭。", "我们今天早上去公园玩。然后去动物园看大熊猫，最后回家吃饭。"},
+		{"Hi, this is fine.", "hello, this is fine."},
+		{"This is fine.", ""},
+		{"A clause far longer than twenty characters.", ""},
+	}
+	for _, tt := range tests {
+		v, err := r.CheckOutput(ctx, &agent.GuardrailRequest{Text: tt.text})
+		if err != nil {
+			t.Fatalf("CheckOutput(%q) error: %v", tt.text, err)
+		}
+		if tt.want == "" {
+			if v != nil {
+				t.Errorf("CheckOutput(%q) = %+v, want nil", tt.text, v)
+			}
+			continue
+		}
+		if v == nil || v.Action != agent.GuardrailRewrite || v.Text != tt.want {
+			t.Errorf("CheckOutput(%q) = %+v, want rewrite to %q", tt.text, v, tt.want)
+			continue
+		}
+		if !slices.Equal(v.Labels, []string{agent.StyleLabel}) {
+			t.Errorf("labels = %v, want %v", v.Labels, []string{agent.StyleLabel})
+		}
+	}
+
+	if v, _ := r.CheckInput(ctx, &agent.GuardrailRequest{Text: "As an AI"}); v != nil {
+		t.Errorf("CheckInput = %+v, want nil", v)
+	}
+}
+
+func TestStyleRewriter_Model(t *testing.T) {
+	ctx := context.Background()
+	gen := &chunkedGenerator{moderate: `{"text":"The sun is a big hot star, as an AI knows."}`}
+	r := agent.NewStyleRewriter(agentcfg.OutputStyle{
+		BannedPhrases: []string{"as an AI knows"},
+		Vocabulary:    "words a 5-year-old knows",
+		RewriteModel:  "small",
+	}, gen)
+
+	v, err := r.CheckOutput(ctx, &agent.GuardrailRequest{Text: "The sun is a main-sequence star. "})
+	if err != nil {
+		t.Fatalf("CheckOutput error: %v", err)
+	}
+	if v == nil || v.Text != "The sun is a big hot star. " {
+		t.Errorf("verdict = %+v, want the model rewrite with banned phrases removed", v)
+	}
+
+	// A failed rewrite keeps the text and reports the failure
+	gen.moderate = `{"text":""}`
+	v, err = r.CheckOutput(ctx, &agent.GuardrailRequest{Text: "The sun is a star."})
+	if err != nil {
+		t.Fatalf("CheckOutput error: %v", err)
+	}
+	if v == nil || v.Action != agent.GuardrailAllow || !strings.Contains(v.Reason, "empty text") {
+		t.Errorf("verdict = %+v, want allow with the failure as reason", v)
+	}
+}
+
+func TestReActAgent_OutputStyle(t *testing.T) {
+	ctx := context.Background()
+	store := playground.NewStore(nil)
+	if err := store.LoadReadonlyLayer("testdata", os.DirFS("testdata/agent_react_test")); err != nil {
+		t.Fatalf("load testdata: %v", err)
+	}
+	gen := &chunkedGenerator{responses: [][]string{{"As an AI, I love stories. Once upon a time, in a land far away, ", "lived a fox."}}}
+	rt := playground.NewRuntime(playground.WithStore(store), playground.WithGenerator(gen))
+	agentDef, err := rt.GetAgentDef(ctx, "simple_agent")
+	if err != nil {
+		t.Fatalf("GetAgentDef error: %v", err)
+	}
+	def := *agentcfg.AsReActAgent(agentDef)
+	def.Style = &agentcfg.OutputStyle{MaxSentenceLength: 35, BannedPhrases: []string{"as an AI"}}
+	a, err := agent.NewReActAgent(ctx, &def, rt, "")
+	if err != nil {
+		t.Fatalf("NewReActAgent error: %v", err)
+	}
+	defer a.Close()
+
+	events := guardedRound(t, a, "Tell me a story")
+	want := []string{"I love stories.", " Once upon a time. In a land far away, lived a fox."}
+	if got := chunkTexts(events); !slices.Equal(got, want) {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+}
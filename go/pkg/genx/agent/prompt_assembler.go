@@ -0,0 +1,195 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// PromptAssembler builds a system prompt before each generation of a
+// ReActAgent, for context that changes between turns: memories recalled
+// for the latest input, the device state, the persona profile.
+//
+// A Runtime that also implements PromptAssemblerRuntime provides the
+// assembler of its agents. The assembled prompt follows the prompts of the
+// agent definition.
+type PromptAssembler interface {
+	// AssemblePrompt returns the prompt for a generation, or nil to add
+	// none.
+	AssemblePrompt(ctx context.Context, req *PromptRequest) (*genx.Prompt, error)
+}
+
+// PromptAssemblerRuntime is implemented by Runtimes that assemble system
+// prompts.
+type PromptAssemblerRuntime interface {
+	// PromptAssembler returns the assembler, or nil if there is none.
+	PromptAssembler() PromptAssembler
+}
+
+// PromptRequest describes the generation a prompt is assembled for.
+type PromptRequest struct {
+	// AgentDef is the name of the agent definition.
+	AgentDef string
+
+	// StateID is the state ID of the agent.
+	StateID string
+
+	// Input is the text of the latest user message.
+	Input string
+}
+
+// PromptData is the data of a TemplateAssembler template.
+type PromptData struct {
+	// AgentDef, StateID and Input come from the PromptRequest.
+	AgentDef string
+	StateID  string
+	Input    string
+
+	// Now is the time of the generation.
+	Now time.Time
+
+	// Memories are memory segments recalled for Input.
+	Memories []string
+
+	// Device is the device state, e.g. "battery" or "charging".
+	Device map[string]any
+
+	// Persona is the persona profile, e.g. "name" or "personality".
+	Persona map[string]any
+
+	// Vars holds any other template values.
+	Vars map[string]any
+}
+
+// TimeOfDay returns "morning", "afternoon", "evening" or "night" for Now.
+func (d *PromptData) TimeOfDay() string {
+	switch h := d.Now.Hour(); {
+	case h >= 5 && h < 12:
+		return "morning"
+	case h >= 12 && h < 18:
+		return "afternoon"
+	case h >= 18 && h < 22:
+		return "evening"
+	default:
+		return "night"
+	}
+}
+
+// PromptSource fills in PromptData for a TemplateAssembler, e.g. by
+// recalling memories for data.Input or reading the device state.
+type PromptSource func(ctx context.Context, data *PromptData) error
+
+// MemorySource returns a PromptSource that sets Memories from recall.
+func MemorySource(recall func(ctx context.Context, query string) ([]string, error)) PromptSource {
+	return func(ctx context.Context, data *PromptData) error {
+		if data.Input == "" {
+			return nil
+		}
+		memories, err := recall(ctx, data.Input)
+		if err != nil {
+			return fmt.Errorf("recall memories: %w", err)
+		}
+		data.Memories = memories
+		return nil
+	}
+}
+
+// DeviceSource returns a PromptSource that sets Device from state.
+func DeviceSource(state func(ctx context.Context) (map[string]any, error)) PromptSource {
+	return func(ctx context.Context, data *PromptData) error {
+		device, err := state(ctx)
+		if err != nil {
+			return fmt.Errorf("device state: %w", err)
+		}
+		data.Device = device
+		return nil
+	}
+}
+
+// PersonaSource returns a PromptSource that sets Persona to profile.
+func PersonaSource(profile map[string]any) PromptSource {
+	return func(ctx context.Context, data *PromptData) error {
+		data.Persona = profile
+		return nil
+	}
+}
+
+// TemplateAssembler is a PromptAssembler that renders a text/template with
+// PromptData filled in by its sources. For example:
+//
+//	You are {{.Persona.name}}. It is {{.TimeOfDay}}.
+//	{{- with .Device.battery}}
+//	Battery: {{.}}%
+//	{{- end}}
+//	{{- range .Memories}}
+//	- {{.}}
+//	{{- end}}
+//
+// Missing map keys render as empty text. If the template renders only
+// whitespace, no prompt is added.
+type TemplateAssembler struct {
+	tmpl    *template.Template
+	sources []PromptSource
+	now     func() time.Time
+}
+
+// NewTemplateAssembler parses text and creates a TemplateAssembler. The
+// sources run in order before each rendering.
+func NewTemplateAssembler(text string, sources ...PromptSource) (*TemplateAssembler, error) {
+	tmpl, err := template.New("prompt").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("agent: parse prompt template: %w", err)
+	}
+	return &TemplateAssembler{tmpl: tmpl, sources: sources, now: time.Now}, nil
+}
+
+// AssemblePrompt implements PromptAssembler.
+func (a *TemplateAssembler) AssemblePrompt(ctx context.Context, req *PromptRequest) (*genx.Prompt, error) {
+	data := &PromptData{
+		AgentDef: req.AgentDef,
+		StateID:  req.StateID,
+		Input:    req.Input,
+		Now:      a.now(),
+	}
+	for _, source := range a.sources {
+		if err := source(ctx, data); err != nil {
+			return nil, err
+		}
+	}
+
+	var sb strings.Builder
+	if err := a.tmpl.Execute(&sb, data); err != nil {
+		return nil, fmt.Errorf("render prompt template: %w", err)
+	}
+	text := strings.TrimSpace(sb.String())
+	if text == "" {
+		return nil, nil
+	}
+	return &genx.Prompt{Name: "context", Text: text}, nil
+}
+
+// lastUserInput returns the text of the last user message.
+func lastUserInput(messages []*genx.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Role != genx.RoleUser {
+			continue
+		}
+		contents, ok := msg.Payload.(genx.Contents)
+		if !ok {
+			return ""
+		}
+		var sb strings.Builder
+		for _, part := range contents {
+			if text, ok := part.(genx.Text); ok {
+				sb.WriteString(string(text))
+			}
+		}
+		return sb.String()
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+package agent_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agent"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+	"github.com/haivivi/giztoy/go/pkg/genx/playground"
+)
+
+// promptRecorder records the system prompts of each generation.
+type promptRecorder struct {
+	*mockReActGenerator
+	prompts [][]string
+}
+
+func (g *promptRecorder) GenerateStream(ctx context.Context, model string, mc genx.ModelContext) (genx.Stream, error) {
+	var prompts []string
+	for p := range mc.Prompts() {
+		prompts = append(prompts, p.Text)
+	}
+	g.prompts = append(g.prompts, prompts)
+	return g.mockReActGenerator.GenerateStream(ctx, model, mc)
+}
+
+func TestTemplateAssembler(t *testing.T) {
+	var recalled string
+	assembler, err := agent.NewTemplateAssembler(
+		`You are {{.Persona.name}}.
+{{- with .Device.battery}} Battery: {{.}}%.{{end}}
+{{- range .Memories}}
+- {{.}}
+{{- end}}`,
+		agent.PersonaSource(map[string]any{"name": "Lele"}),
+		agent.DeviceSource(func(ctx context.Context) (map[string]any, error) {
+			return map[string]any{"battery": 15}, nil
+		}),
+		agent.MemorySource(func(ctx context.Context, query string) ([]string, error) {
+			recalled = query
+			return []string{"Likes dinosaurs", "Has a cat named Mimi"}, nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewTemplateAssembler error: %v", err)
+	}
+
+	prompt, err := assembler.AssemblePrompt(context.Background(), &agent.PromptRequest{
+		AgentDef: "assistant",
+		Input:    "Tell me about my cat",
+	})
+	if err != nil {
+		t.Fatalf("AssemblePrompt error: %v", err)
+	}
+	want := "You are Lele. Battery: 15%.\n- Likes dinosaurs\n- Has a cat named Mimi"
+	if prompt == nil || prompt.Text != want {
+		t.Errorf("prompt = %+v, want text %q", prompt, want)
+	}
+	if recalled != "Tell me about my cat" {
+		t.Errorf("recall query = %q, want the input", recalled)
+	}
+}
+
+func TestTemplateAssembler_Empty(t *testing.T) {
+	assembler, err := agent.NewTemplateAssembler(`{{range .Memories}}- {{.}}{{end}}`)
+	if err != nil {
+		t.Fatalf("NewTemplateAssembler error: %v", err)
+	}
+	prompt, err := assembler.AssemblePrompt(context.Background(), &agent.PromptRequest{})
+	if err != nil || prompt != nil {
+		t.Errorf("AssemblePrompt = %+v, %v; want nil, nil", prompt, err)
+	}
+}
+
+func TestTemplateAssembler_SourceError(t *testing.T) {
+	assembler, err := agent.NewTemplateAssembler(`{{.Device.battery}}`,
+		agent.DeviceSource(func(ctx context.Context) (map[string]any, error) {
+			return nil, errors.New("device offline")
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewTemplateAssembler error: %v", err)
+	}
+	if _, err := assembler.AssemblePrompt(context.Background(), &agent.PromptRequest{}); err == nil {
+		t.Error("expected error from failing source")
+	}
+}
+
+func TestNewTemplateAssembler_Invalid(t *testing.T) {
+	if _, err := agent.NewTemplateAssembler(`{{.Persona`); err == nil {
+		t.Error("expected error for invalid template")
+	}
+}
+
+func TestPromptData_TimeOfDay(t *testing.T) {
+	tests := []struct {
+		hour int
+		want string
+	}{{3, "night"}, {8, "morning"}, {14, "afternoon"}, {20, "evening"}, {23, "night"}}
+	for _, tt := range tests {
+		data := &agent.PromptData{Now: time.Date(2026, 1, 1, tt.hour, 0, 0, 0, time.UTC)}
+		if got := data.TimeOfDay(); got != tt.want {
+			t.Errorf("TimeOfDay at %d:00 = %q, want %q", tt.hour, got, tt.want)
+		}
+	}
+}
+
+func TestReActAgent_PromptAssembler(t *testing.T) {
+	ctx := context.Background()
+	gen := &promptRecorder{mockReActGenerator: newMockReActGenerator().
+		WithTextResponse("test-model", "Hello!").
+		WithTextResponse("test-model", "Mimi is fine.")}
+
+	var inputs []string
+	assembler, err := agent.NewTemplateAssembler(`Memories of {{.AgentDef}}:{{range .Memories}} {{.}}{{end}}`,
+		agent.MemorySource(func(ctx context.Context, query string) ([]string, error) {
+			inputs = append(inputs, query)
+			return []string{"memory of " + query}, nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewTemplateAssembler error: %v", err)
+	}
+
+	store := playground.NewStore(nil)
+	if err := store.LoadReadonlyLayer("testdata", os.DirFS("testdata/agent_react_test")); err != nil {
+		t.Fatalf("load testdata: %v", err)
+	}
+	rt := playground.NewRuntime(
+		playground.WithStore(store),
+		playground.WithGenerator(gen),
+		playground.WithBuiltinTools(createReActBuiltinTools()...),
+		playground.WithPromptAssembler(assembler),
+	)
+
+	agentDef, err := rt.GetAgentDef(ctx, "assistant")
+	if err != nil {
+		t.Fatalf("GetAgentDef error: %v", err)
+	}
+	a, err := agent.NewReActAgent(ctx, agentcfg.AsReActAgent(agentDef), rt, "")
+	if err != nil {
+		t.Fatalf("NewReActAgent error: %v", err)
+	}
+	defer a.Close()
+
+	for _, input := range []string{"Hi", "How is my cat?"} {
+		if err := a.Input(genx.Contents{genx.Text(input)}); err != nil {
+			t.Fatalf("Input error: %v", err)
+		}
+		runRound(t, a)
+	}
+
+	if len(gen.prompts) != 2 {
+		t.Fatalf("got %d generations, want 2", len(gen.prompts))
+	}
+	// The assembled prompt follows the agent prompt and changes each turn
+	want := []string{"Memories of assistant: memory of Hi", "Memories of assistant: memory of How is my cat?"}
+	for i, prompts := range gen.prompts {
+		if len(prompts) < 2 || prompts[0] != "You are a helpful assistant." || prompts[1] != want[i] {
+			t.Errorf("generation %d prompts = %q, want agent prompt then %q", i, prompts, want[i])
+		}
+	}
+	if strings.Join(inputs, "|") != "Hi|How is my cat?" {
+		t.Errorf("recall queries = %q", inputs)
+	}
+}
@@ -0,0 +1,209 @@
+package agent
+
+import (
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+)
+
+// defaultSummaryPrompt asks for the final answer of a round that reached a
+// limit, when agentcfg.RoundLimits.SummaryPrompt is not set.
+const defaultSummaryPrompt = `You have used up the time and steps for this turn. Do not call any tools.
+Answer the user now with what you have found so far, and briefly say what is
+left undone.`
+
+// resetRoundLocked starts the limits of a new round.
+// Note: caller must hold a.mu lock.
+func (a *ReActAgent) resetRoundLocked() {
+	a.roundToolCalls = 0
+	a.roundTokens = 0
+	a.roundDeadline = time.Time{}
+	a.summarizing = false
+	a.roundErr = nil
+	if l := a.def.Limits; l != nil && l.Timeout > 0 {
+		a.roundDeadline = time.Now().Add(time.Duration(l.Timeout * float64(time.Second)))
+	}
+}
+
+// countUsage counts the tokens of a finished generation toward the round.
+func (a *ReActAgent) countUsage(usage genx.Usage) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.roundTokens += usage.GeneratedTokenCount
+}
+
+// roundLimitLocked checks the round limits before the next step of the
+// round: a tool call if toolCall is set, a generation otherwise. It returns
+// an error wrapping ErrRoundLimit if a limit is reached.
+// Note: caller must hold a.mu lock.
+func (a *ReActAgent) roundLimitLocked(toolCall bool) error {
+	l := a.def.Limits
+	if l == nil || a.summarizing {
+		return nil
+	}
+	switch {
+	case toolCall && l.MaxToolCalls > 0 && a.roundToolCalls >= l.MaxToolCalls:
+		return fmt.Errorf("%w: max_tool_calls %d", ErrRoundLimit, l.MaxToolCalls)
+	case l.MaxTokens > 0 && a.roundTokens >= l.MaxTokens:
+		return fmt.Errorf("%w: max_tokens %d", ErrRoundLimit, l.MaxTokens)
+	case !a.roundDeadline.IsZero() && !time.Now().Before(a.roundDeadline):
+		return fmt.Errorf("%w: timeout %gs", ErrRoundLimit, l.Timeout)
+	}
+	return nil
+}
+
+// limitToolCall checks the round limits before a tool call of stream runs.
+// It returns true if the call must not run; the round then ends the way
+// the limits say, with its events pending for Next().
+func (a *ReActAgent) limitToolCall(stream genx.Stream) (bool, error) {
+	if a.def.Limits == nil {
+		return false, nil
+	}
+	if a.def.Limits.MaxTokens > 0 {
+		// The usage of a generation comes with its end
+		a.drainUsage(stream)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.summarizing {
+		// The final answer must not call tools: drop the call
+		return true, a.endRoundLocked()
+	}
+	if err := a.roundLimitLocked(true); err != nil {
+		return true, a.limitRoundLocked(err)
+	}
+	a.roundToolCalls++
+	return false, nil
+}
+
+// limitGeneration checks the round limits before the generation that
+// follows a tool call. It returns true if the generation must not start;
+// the round then ends the way the limits say.
+func (a *ReActAgent) limitGeneration() (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.roundLimitLocked(false); err != nil {
+		return true, a.limitRoundLocked(err)
+	}
+	return false, nil
+}
+
+// drainUsage reads the rest of stream and counts its usage.
+func (a *ReActAgent) drainUsage(stream genx.Stream) {
+	for {
+		_, err := stream.Next()
+		if err == nil {
+			continue
+		}
+		if state, ok := err.(*genx.State); ok && state.Status() == genx.StatusDone {
+			a.countUsage(state.Usage())
+		}
+		return
+	}
+}
+
+// limitRoundLocked ends a round that reached a limit: the generation stops
+// and the text generated so far is stored. By default EventLimited is
+// queued and a final answer is generated without running tools.
+// Note: caller must hold a.mu lock.
+func (a *ReActAgent) limitRoundLocked(limitErr error) error {
+	if a.tracer != nil {
+		a.tracer.record(&Decision{Kind: DecisionLimit, Text: a.traceText, Error: limitErr.Error()})
+		a.traceText = ""
+	}
+	if a.stream != nil {
+		a.stream.Close()
+		a.stream = nil
+	}
+	if a.pendingText != "" {
+		if err := a.storeModelText(a.pendingText); err != nil {
+			return fmt.Errorf("store model text: %w", err)
+		}
+		a.pendingText = ""
+	}
+
+	limited := a.tagEvent(&AgentEvent{Type: EventLimited, Limit: limitErr})
+	switch a.def.Limits.OnLimit {
+	case agentcfg.RoundLimitError:
+		a.roundErr = limitErr
+		return nil
+	case agentcfg.RoundLimitStop:
+		a.pending = append(a.pending, limited)
+		return a.endRoundLocked()
+	}
+
+	a.pending = append(a.pending, limited)
+	mctx, err := a.buildModelContext()
+	if err != nil {
+		return err
+	}
+	prompt := a.def.Limits.SummaryPrompt
+	if prompt == "" {
+		prompt = defaultSummaryPrompt
+	}
+	stream, err := a.rt.GenerateStream(a.ctx, a.getModel(), &summaryContext{
+		ModelContext: mctx,
+		prompt:       &genx.Prompt{Name: "round_limit", Text: prompt},
+	})
+	if err != nil {
+		return err
+	}
+	a.stream = stream
+	a.summarizing = true
+	return nil
+}
+
+// endRoundLocked ends the round without further generation and queues its
+// end event.
+// Note: caller must hold a.mu lock.
+func (a *ReActAgent) endRoundLocked() error {
+	if a.stream != nil {
+		a.stream.Close()
+		a.stream = nil
+	}
+	if a.pendingText != "" {
+		if err := a.storeModelText(a.pendingText); err != nil {
+			return fmt.Errorf("store model text: %w", err)
+		}
+		a.pendingText = ""
+	}
+	a.summarizing = false
+	end := EventEOF
+	if a.finished {
+		end = EventClosed
+	}
+	a.pending = append(a.pending, a.tagEvent(&AgentEvent{Type: end}))
+	return nil
+}
+
+// popRoundErr returns the error of a round a limit failed, once.
+func (a *ReActAgent) popRoundErr() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	err := a.roundErr
+	a.roundErr = nil
+	return err
+}
+
+// summaryContext is the model context of the final answer of a limited
+// round: the round's context with an instruction to answer now. The tools
+// stay declared so that the tool calls in the history remain valid.
+type summaryContext struct {
+	genx.ModelContext
+	prompt *genx.Prompt
+}
+
+func (c *summaryContext) Prompts() iter.Seq[*genx.Prompt] {
+	return func(yield func(*genx.Prompt) bool) {
+		for p := range c.ModelContext.Prompts() {
+			if !yield(p) {
+				return
+			}
+		}
+		yield(c.prompt)
+	}
+}
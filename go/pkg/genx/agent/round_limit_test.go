@@ -0,0 +1,240 @@
+package agent_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agent"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+	"github.com/haivivi/giztoy/go/pkg/genx/playground"
+)
+
+// usageGenerator reports tokens generated tokens for each generation.
+type usageGenerator struct {
+	*promptRecorder
+	tokens int64
+}
+
+func (g *usageGenerator) GenerateStream(ctx context.Context, model string, mc genx.ModelContext) (genx.Stream, error) {
+	stream, err := g.promptRecorder.GenerateStream(ctx, model, mc)
+	if err != nil {
+		return nil, err
+	}
+	return &usageStream{Stream: stream, tokens: g.tokens}, nil
+}
+
+type usageStream struct {
+	genx.Stream
+	tokens int64
+}
+
+func (s *usageStream) Next() (*genx.MessageChunk, error) {
+	chunk, err := s.Stream.Next()
+	if state, ok := err.(*genx.State); ok && state.Status() == genx.StatusDone {
+		return nil, genx.Done(genx.Usage{GeneratedTokenCount: s.tokens})
+	}
+	return chunk, err
+}
+
+// newLimitedAgent creates the assistant agent with limits on a runtime
+// with gen and tools.
+func newLimitedAgent(t *testing.T, gen genx.Generator, limits *agentcfg.RoundLimits, tools ...*genx.FuncTool) *agent.ReActAgent {
+	t.Helper()
+	ctx := context.Background()
+	if len(tools) == 0 {
+		tools = createReActBuiltinTools()
+	}
+	store := playground.NewStore(nil)
+	if err := store.LoadReadonlyLayer("testdata", os.DirFS("testdata/agent_react_test")); err != nil {
+		t.Fatalf("load testdata: %v", err)
+	}
+	rt := playground.NewRuntime(
+		playground.WithStore(store),
+		playground.WithGenerator(gen),
+		playground.WithBuiltinTools(tools...),
+	)
+	agentDef, err := rt.GetAgentDef(ctx, "assistant")
+	if err != nil {
+		t.Fatalf("GetAgentDef error: %v", err)
+	}
+	def := *agentcfg.AsReActAgent(agentDef)
+	def.Limits = limits
+	a, err := agent.NewReActAgent(ctx, &def, rt, "")
+	if err != nil {
+		t.Fatalf("NewReActAgent error: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+// eventTypes returns the types of events, leaving out chunks.
+func eventTypes(events []*agent.AgentEvent) []agent.EventType {
+	var types []agent.EventType
+	for _, evt := range events {
+		if evt.Type != agent.EventChunk {
+			types = append(types, evt.Type)
+		}
+	}
+	return types
+}
+
+func TestReActAgent_RoundLimit_Summarize(t *testing.T) {
+	gen := &promptRecorder{mockReActGenerator: newMockReActGenerator().
+		WithToolCall("test-model", "call-1", "search", `{"query":"dinosaurs"}`).
+		WithToolCall("test-model", "call-2", "search", `{"query":"t-rex"}`).
+		WithToolCall("test-model", "call-3", "search", `{"query":"raptors"}`).
+		WithTextResponse("test-model", "Dinosaurs were big.")}
+	a := newLimitedAgent(t, gen, &agentcfg.RoundLimits{MaxToolCalls: 2})
+
+	events := guardedRound(t, a, "Tell me about dinosaurs")
+	want := []agent.EventType{
+		agent.EventToolStart, agent.EventToolDone,
+		agent.EventToolStart, agent.EventToolDone,
+		agent.EventLimited, agent.EventEOF,
+	}
+	if got := eventTypes(events); !slices.Equal(got, want) {
+		t.Fatalf("event types = %v, want %v", got, want)
+	}
+	limited := findEvent(events, agent.EventLimited)
+	if !errors.Is(limited.Limit, agent.ErrRoundLimit) || !strings.Contains(limited.Limit.Error(), "max_tool_calls 2") {
+		t.Errorf("Limit = %v, want max_tool_calls", limited.Limit)
+	}
+	if got := strings.Join(chunkTexts(events), ""); got != "Dinosaurs were big." {
+		t.Errorf("answer = %q", got)
+	}
+
+	// The final answer is asked for with the summary prompt
+	last := gen.prompts[len(gen.prompts)-1]
+	if !strings.Contains(last[len(last)-1], "Do not call any tools") {
+		t.Errorf("final prompts = %q, want the summary prompt last", last)
+	}
+	history := a.FormatHistory(context.Background())
+	if strings.Contains(history, "raptors") || !strings.Contains(history, "Dinosaurs were big.") {
+		t.Errorf("history = \n%s", history)
+	}
+
+	// The next round has its own budget
+	gen.WithToolCall("test-model", "call-4", "search", `{"query":"birds"}`).
+		WithTextResponse("test-model", "Birds are dinosaurs.")
+	events = guardedRound(t, a, "And birds?")
+	if got := eventTypes(events); slices.Contains(got, agent.EventLimited) {
+		t.Errorf("second round event types = %v, want no limit", got)
+	}
+}
+
+func TestReActAgent_RoundLimit_DropsToolCallOfAnswer(t *testing.T) {
+	gen := newMockReActGenerator().
+		WithToolCall("test-model", "call-1", "search", `{"query":"a"}`).
+		WithToolCall("test-model", "call-2", "search", `{"query":"b"}`).
+		WithTextAndToolCall("test-model", "Here is what I know.", "call-3", "search", `{"query":"c"}`)
+	a := newLimitedAgent(t, gen, &agentcfg.RoundLimits{MaxToolCalls: 1})
+
+	events := guardedRound(t, a, "Search")
+	want := []agent.EventType{agent.EventToolStart, agent.EventToolDone, agent.EventLimited, agent.EventEOF}
+	if got := eventTypes(events); !slices.Equal(got, want) {
+		t.Fatalf("event types = %v, want %v", got, want)
+	}
+	if got := strings.Join(chunkTexts(events), ""); got != "Here is what I know." {
+		t.Errorf("answer = %q", got)
+	}
+}
+
+func TestReActAgent_RoundLimit_Stop(t *testing.T) {
+	gen := newMockReActGenerator().
+		WithToolCall("test-model", "call-1", "search", `{"query":"a"}`).
+		WithTextAndToolCall("test-model", "Let me look again.", "call-2", "search", `{"query":"b"}`)
+	a := newLimitedAgent(t, gen, &agentcfg.RoundLimits{MaxToolCalls: 1, OnLimit: agentcfg.RoundLimitStop})
+
+	events := guardedRound(t, a, "Search")
+	want := []agent.EventType{agent.EventToolStart, agent.EventToolDone, agent.EventLimited, agent.EventEOF}
+	if got := eventTypes(events); !slices.Equal(got, want) {
+		t.Fatalf("event types = %v, want %v", got, want)
+	}
+	if gen.callCount["test-model"] != 2 {
+		t.Errorf("generations = %d, want 2", gen.callCount["test-model"])
+	}
+	if history := a.FormatHistory(context.Background()); !strings.Contains(history, "Let me look again.") {
+		t.Errorf("text before the limit not stored:\n%s", history)
+	}
+}
+
+func TestReActAgent_RoundLimit_Error(t *testing.T) {
+	gen := newMockReActGenerator().
+		WithToolCall("test-model", "call-1", "search", `{"query":"a"}`).
+		WithToolCall("test-model", "call-2", "search", `{"query":"b"}`).
+		WithTextResponse("test-model", "Hello again.")
+	a := newLimitedAgent(t, gen, &agentcfg.RoundLimits{MaxToolCalls: 1, OnLimit: agentcfg.RoundLimitError})
+
+	if err := a.Input(genx.Contents{genx.Text("Search")}); err != nil {
+		t.Fatalf("Input error: %v", err)
+	}
+	var err error
+	for {
+		var evt *agent.AgentEvent
+		evt, err = a.Next()
+		if err != nil || evt.Type == agent.EventEOF {
+			break
+		}
+	}
+	if !errors.Is(err, agent.ErrRoundLimit) {
+		t.Fatalf("Next error = %v, want ErrRoundLimit", err)
+	}
+
+	// The agent takes the next input
+	events := guardedRound(t, a, "Hi")
+	if got := strings.Join(chunkTexts(events), ""); got != "Hello again." {
+		t.Errorf("answer = %q", got)
+	}
+}
+
+func TestReActAgent_RoundLimit_Tokens(t *testing.T) {
+	gen := &usageGenerator{
+		promptRecorder: &promptRecorder{mockReActGenerator: newMockReActGenerator().
+			WithToolCall("test-model", "call-1", "search", `{"query":"a"}`).
+			WithToolCall("test-model", "call-2", "search", `{"query":"b"}`).
+			WithTextResponse("test-model", "Done.")},
+		tokens: 60,
+	}
+	a := newLimitedAgent(t, gen, &agentcfg.RoundLimits{MaxTokens: 100})
+
+	events := guardedRound(t, a, "Search")
+	want := []agent.EventType{agent.EventToolStart, agent.EventToolDone, agent.EventLimited, agent.EventEOF}
+	if got := eventTypes(events); !slices.Equal(got, want) {
+		t.Fatalf("event types = %v, want %v", got, want)
+	}
+	if limit := findEvent(events, agent.EventLimited).Limit; !strings.Contains(limit.Error(), "max_tokens") {
+		t.Errorf("Limit = %v, want max_tokens", limit)
+	}
+}
+
+func TestReActAgent_RoundLimit_Timeout(t *testing.T) {
+	type searchArgs struct {
+		Query string `json:"query"`
+	}
+	slowSearch := genx.MustNewFuncTool[searchArgs]("search", "Search for information",
+		genx.InvokeFunc[searchArgs](func(ctx context.Context, call *genx.FuncCall, args searchArgs) (any, error) {
+			time.Sleep(30 * time.Millisecond)
+			return "nothing", nil
+		}),
+	)
+	gen := newMockReActGenerator().
+		WithToolCall("test-model", "call-1", "search", `{"query":"a"}`).
+		WithTextResponse("test-model", "Sorry, I ran out of time.")
+	tools := slices.DeleteFunc(createReActBuiltinTools(), func(tool *genx.FuncTool) bool { return tool.Name == "search" })
+	a := newLimitedAgent(t, gen, &agentcfg.RoundLimits{Timeout: 0.01}, append(tools, slowSearch)...)
+
+	events := guardedRound(t, a, "Search")
+	want := []agent.EventType{agent.EventToolStart, agent.EventToolDone, agent.EventLimited, agent.EventEOF}
+	if got := eventTypes(events); !slices.Equal(got, want) {
+		t.Fatalf("event types = %v, want %v", got, want)
+	}
+	if limit := findEvent(events, agent.EventLimited).Limit; !strings.Contains(limit.Error(), "timeout") {
+		t.Errorf("Limit = %v, want timeout", limit)
+	}
+}
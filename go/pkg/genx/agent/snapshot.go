@@ -0,0 +1,346 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// snapshotVersion is the version of the snapshot encoding.
+const snapshotVersion = 1
+
+// agentSnapshot is the MessagePack encoding of an agent checkpoint.
+//
+// Exactly one of ReAct and Match is set, matching the agent definition.
+type agentSnapshot struct {
+	Version       int                `msgpack:"version"`
+	Def           agentcfg.AgentRef  `msgpack:"def"`
+	ParentStateID string             `msgpack:"parent_state_id,omitempty"`
+	Messages      []agentcfg.Message `msgpack:"messages,omitempty"`
+	Summary       string             `msgpack:"summary,omitempty"`
+	ReAct         *reactSnapshot     `msgpack:"react,omitempty"`
+	Match         *matchSnapshot     `msgpack:"match,omitempty"`
+}
+
+// reactSnapshot is the ReActAgent part of a snapshot.
+type reactSnapshot struct {
+	// Scratchpad is the model text generated so far in the current round.
+	Scratchpad string `msgpack:"scratchpad,omitempty"`
+
+	// PendingToolCalls are the tool calls that were running without a
+	// stored result. They are not part of Messages.
+	PendingToolCalls []snapshotToolCall `msgpack:"pending_tool_calls,omitempty"`
+
+	Finished bool `msgpack:"finished,omitempty"`
+}
+
+// snapshotToolCall is a tool call in a snapshot.
+type snapshotToolCall struct {
+	ID        string `msgpack:"id"`
+	Name      string `msgpack:"name"`
+	Arguments string `msgpack:"arguments,omitempty"`
+}
+
+// matchSnapshot is the MatchAgent part of a snapshot.
+type matchSnapshot struct {
+	Phase        MatchAgentPhase `msgpack:"phase,omitempty"`
+	Input        string          `msgpack:"input,omitempty"`
+	Matches      []MatchedIntent `msgpack:"matches,omitempty"`
+	CurrentIndex int             `msgpack:"current_index,omitempty"`
+	Matched      bool            `msgpack:"matched,omitempty"`
+
+	// Calling is the snapshot of the calling sub-agent, if any.
+	Calling []byte `msgpack:"calling,omitempty"`
+}
+
+// ResumeAgent recreates an agent from a snapshot taken by Agent.Snapshot.
+//
+// Unlike Runtime.RestoreAgent, which reattaches to a state the runtime
+// still holds, ResumeAgent needs nothing but the snapshot and the tools it
+// refers to: it creates a fresh state (with a new ID) and replays the
+// conversation into it. This lets a restarted server or a reconnecting
+// device continue mid-conversation.
+//
+// A resumed ReActAgent:
+//   - re-runs the tool calls that were pending, on the first Next();
+//     calling Input() first abandons them
+//   - stores the scratchpad (the partial model response) as a model message
+//     and waits for input
+//   - continues generation if the history ends with a tool result
+func ResumeAgent(ctx context.Context, rt Runtime, snapshot []byte) (Agent, error) {
+	return resumeAgent(ctx, rt, snapshot, "")
+}
+
+// resumeAgent resumes an agent; parentStateID overrides the snapshot's
+// parent if set (for sub-agents, whose parent state is recreated too).
+func resumeAgent(ctx context.Context, rt Runtime, snapshot []byte, parentStateID string) (Agent, error) {
+	var snap agentSnapshot
+	if err := msgpack.Unmarshal(snapshot, &snap); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	if parentStateID == "" {
+		parentStateID = snap.ParentStateID
+	}
+
+	switch def := snap.Def.Agent.(type) {
+	case *agentcfg.ReActAgent:
+		if snap.ReAct == nil {
+			return nil, fmt.Errorf("snapshot of react agent %s has no react state", def.Name)
+		}
+		return resumeReActAgent(ctx, rt, def, parentStateID, &snap)
+	case *agentcfg.MatchAgent:
+		if snap.Match == nil {
+			return nil, fmt.Errorf("snapshot of match agent %s has no match state", def.Name)
+		}
+		return resumeMatchAgent(ctx, rt, def, parentStateID, &snap)
+	default:
+		return nil, fmt.Errorf("snapshot has unknown agent def type: %T", snap.Def.Agent)
+	}
+}
+
+// restoreMemory replays the messages and summary of a snapshot into state.
+func restoreMemory(ctx context.Context, state AgentState, snap *agentSnapshot) error {
+	for _, msg := range snap.Messages {
+		if err := state.StoreMessage(ctx, msg); err != nil {
+			return fmt.Errorf("store message: %w", err)
+		}
+	}
+	if snap.Summary != "" {
+		if err := state.SetSummary(ctx, snap.Summary); err != nil {
+			return fmt.Errorf("set summary: %w", err)
+		}
+	}
+	return nil
+}
+
+// snapshotMemory fills the messages and summary of a snapshot from state.
+func snapshotMemory(ctx context.Context, state AgentState, snap *agentSnapshot) error {
+	messages, err := state.LoadRecent(ctx)
+	if err != nil {
+		return fmt.Errorf("load messages: %w", err)
+	}
+	summary, err := state.Summary(ctx)
+	if err != nil {
+		return fmt.Errorf("load summary: %w", err)
+	}
+	snap.ParentStateID = state.ParentStateID()
+	snap.Messages = messages
+	snap.Summary = summary
+	return nil
+}
+
+// Snapshot serializes the agent's conversation, the running tool call and
+// the partial model response (MessagePack), to be resumed by ResumeAgent.
+func (a *ReActAgent) Snapshot() ([]byte, error) {
+	// Hold mu so that the running tool call does not store its result or
+	// start the next generation halfway through the snapshot.
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	snap := agentSnapshot{
+		Version: snapshotVersion,
+		Def:     agentcfg.AgentRef{Agent: a.def},
+		ReAct: &reactSnapshot{
+			Scratchpad: a.pendingText,
+			Finished:   a.finished,
+		},
+	}
+	if err := snapshotMemory(a.ctx, a.state, &snap); err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", a.def.Name, err)
+	}
+
+	// The resumed agent runs the call again, which stores it again.
+	if tc := a.toolCall; tc != nil && tc.FuncCall != nil && !hasToolResult(snap.Messages, tc.ID) {
+		snap.Messages = withoutToolCall(snap.Messages, tc.ID)
+		snap.ReAct.PendingToolCalls = append(snap.ReAct.PendingToolCalls, toSnapshotToolCall(tc))
+	}
+	for _, tc := range a.resumeCalls {
+		snap.ReAct.PendingToolCalls = append(snap.ReAct.PendingToolCalls, toSnapshotToolCall(tc))
+	}
+
+	data, err := msgpack.Marshal(&snap)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", a.def.Name, err)
+	}
+	return data, nil
+}
+
+func resumeReActAgent(ctx context.Context, rt Runtime, def *agentcfg.ReActAgent, parentStateID string, snap *agentSnapshot) (*ReActAgent, error) {
+	state, err := rt.CreateReActState(ctx, def.Name, parentStateID)
+	if err != nil {
+		return nil, fmt.Errorf("create react state: %w", err)
+	}
+	if err := restoreMemory(ctx, state, snap); err != nil {
+		return nil, fmt.Errorf("resume %s: %w", def.Name, err)
+	}
+	a, err := NewReActAgentWithState(ctx, def, rt, state)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.finished = snap.ReAct.Finished
+	if snap.ReAct.Scratchpad != "" {
+		if err := a.storeModelText(snap.ReAct.Scratchpad); err != nil {
+			a.cancel()
+			return nil, fmt.Errorf("resume %s: store model text: %w", def.Name, err)
+		}
+	}
+	for _, tc := range snap.ReAct.PendingToolCalls {
+		a.resumeCalls = append(a.resumeCalls, &genx.ToolCall{
+			ID:       tc.ID,
+			FuncCall: &genx.FuncCall{Name: tc.Name, Arguments: tc.Arguments},
+		})
+	}
+	if len(a.resumeCalls) == 0 && snap.ReAct.Scratchpad == "" && endsWithToolResult(snap.Messages) {
+		if err := a.continueGeneration(); err != nil {
+			a.cancel()
+			return nil, fmt.Errorf("resume %s: %w", def.Name, err)
+		}
+	}
+	return a, nil
+}
+
+// hasPendingWork reports whether a resumed agent produces events without
+// waiting for input.
+func (a *ReActAgent) hasPendingWork() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.resumeCalls) > 0 || a.stream != nil
+}
+
+func toSnapshotToolCall(tc *genx.ToolCall) snapshotToolCall {
+	return snapshotToolCall{ID: tc.ID, Name: tc.FuncCall.Name, Arguments: tc.FuncCall.Arguments}
+}
+
+// hasToolResult reports whether messages contain the result of call id.
+func hasToolResult(messages []agentcfg.Message, id string) bool {
+	for _, msg := range messages {
+		if msg.Role == agentcfg.RoleTool && msg.ToolResultID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutToolCall returns messages without the model message of call id.
+func withoutToolCall(messages []agentcfg.Message, id string) []agentcfg.Message {
+	out := messages[:0:0]
+	for _, msg := range messages {
+		if msg.Role == agentcfg.RoleModel && msg.ToolCallID == id {
+			continue
+		}
+		out = append(out, msg)
+	}
+	return out
+}
+
+// endsWithToolResult reports whether the model has yet to respond to the
+// last tool result.
+func endsWithToolResult(messages []agentcfg.Message) bool {
+	return len(messages) > 0 && messages[len(messages)-1].Role == agentcfg.RoleTool
+}
+
+// Snapshot serializes the agent's matching state and, if a sub-agent is
+// running, the sub-agent's snapshot (MessagePack), to be resumed by
+// ResumeAgent.
+func (a *MatchAgent) Snapshot() ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	snap := agentSnapshot{
+		Version: snapshotVersion,
+		Def:     agentcfg.AgentRef{Agent: a.def},
+		Match: &matchSnapshot{
+			Phase:        a.state.Phase(),
+			Input:        a.state.Input(),
+			Matches:      a.state.Matches(),
+			CurrentIndex: a.state.CurrentIndex(),
+			Matched:      a.state.Matched(),
+		},
+	}
+	if err := snapshotMemory(a.ctx, a.state, &snap); err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", a.def.Name, err)
+	}
+	if a.calling != nil {
+		calling, err := a.calling.Snapshot()
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s: %w", a.def.Name, err)
+		}
+		snap.Match.Calling = calling
+	}
+
+	data, err := msgpack.Marshal(&snap)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", a.def.Name, err)
+	}
+	return data, nil
+}
+
+func resumeMatchAgent(ctx context.Context, rt Runtime, def *agentcfg.MatchAgent, parentStateID string, snap *agentSnapshot) (*MatchAgent, error) {
+	state, err := rt.CreateMatchState(ctx, def.Name, parentStateID)
+	if err != nil {
+		return nil, fmt.Errorf("create match state: %w", err)
+	}
+	if err := restoreMemory(ctx, state, snap); err != nil {
+		return nil, fmt.Errorf("resume %s: %w", def.Name, err)
+	}
+	a, err := NewMatchAgentWithState(ctx, def, rt, state)
+	if err != nil {
+		return nil, err
+	}
+
+	// Inline agent defs of the matches are not serialized; take them from
+	// the routes again.
+	matches := snap.Match.Matches
+	for i := range matches {
+		if route := a.routeMap[matches[i].Rule]; route != nil && matches[i].AgentRef == "" {
+			matches[i].AgentDef = route.Agent.Agent
+		}
+	}
+	state.SetPhase(snap.Match.Phase)
+	state.SetInput(snap.Match.Input)
+	state.SetMatches(matches)
+	state.SetCurrentIndex(snap.Match.CurrentIndex)
+	state.SetMatched(snap.Match.Matched)
+
+	if len(snap.Match.Calling) == 0 {
+		return a, nil
+	}
+	calling, err := resumeAgent(a.ctx, rt, snap.Match.Calling, a.StateID())
+	if err != nil {
+		a.cancel()
+		return nil, fmt.Errorf("resume %s: calling: %w", def.Name, err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calling = calling
+
+	// Drive the sub-agent if it continues without input, like a round
+	// whose input was already given.
+	if r, ok := calling.(*ReActAgent); ok && r.hasPendingWork() {
+		ctx, cancel := context.WithCancel(a.ctx)
+		round := &roundtrip{
+			ctx:    ctx,
+			cancel: cancel,
+			result: make(chan roundtripEvent),
+			done:   make(chan struct{}),
+		}
+		a.currentRound = round
+		go func() {
+			defer close(round.done)
+			defer close(round.result)
+			a.runCallingLoop(round)
+		}()
+	}
+	return a, nil
+}
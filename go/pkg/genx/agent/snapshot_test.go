@@ -0,0 +1,232 @@
+package agent_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agent"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+)
+
+// runRound reads events until the end of the round and returns their types,
+// leaving out chunks.
+func runRound(t *testing.T, a agent.Agent) []agent.EventType {
+	t.Helper()
+	var types []agent.EventType
+	for {
+		evt, err := a.Next()
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		if evt.Type == agent.EventChunk {
+			continue
+		}
+		types = append(types, evt.Type)
+		if evt.Type == agent.EventEOF || evt.Type == agent.EventClosed {
+			return types
+		}
+	}
+}
+
+func TestReActAgent_SnapshotResume(t *testing.T) {
+	ctx := context.Background()
+	rt := setupReActAgentTestRuntime(t, newMockReActGenerator().
+		WithTextResponse("test-model", "Hello!"))
+
+	agentDef, err := rt.GetAgentDef(ctx, "assistant")
+	if err != nil {
+		t.Fatalf("GetAgentDef error: %v", err)
+	}
+	reactAgent, err := agent.NewReActAgent(ctx, agentcfg.AsReActAgent(agentDef), rt, "")
+	if err != nil {
+		t.Fatalf("NewReActAgent error: %v", err)
+	}
+	if err := reactAgent.Input(genx.Contents{genx.Text("Hi")}); err != nil {
+		t.Fatalf("Input error: %v", err)
+	}
+	runRound(t, reactAgent)
+
+	snapshot, err := reactAgent.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	reactAgent.Close()
+
+	// Resume on a fresh runtime, as after a server restart.
+	rt2 := setupReActAgentTestRuntime(t, newMockReActGenerator().
+		WithTextResponse("test-model", "Welcome back!"))
+	resumed, err := agent.ResumeAgent(ctx, rt2, snapshot)
+	if err != nil {
+		t.Fatalf("ResumeAgent error: %v", err)
+	}
+	defer resumed.Close()
+
+	if resumed.Def().AgentName() != "assistant" {
+		t.Errorf("AgentName = %q, want assistant", resumed.Def().AgentName())
+	}
+	if err := resumed.Input(genx.Contents{genx.Text("I'm back")}); err != nil {
+		t.Fatalf("Input error: %v", err)
+	}
+	runRound(t, resumed)
+
+	history := resumed.FormatHistory(ctx)
+	for _, want := range []string{"[user]: Hi", "[model]: Hello!", "[user]: I'm back", "[model]: Welcome back!"} {
+		if !strings.Contains(history, want) {
+			t.Errorf("history missing %q:\n%s", want, history)
+		}
+	}
+}
+
+func TestReActAgent_SnapshotPendingToolCall(t *testing.T) {
+	ctx := context.Background()
+	mockGen := newMockReActGenerator().
+		WithToolCall("test-model", "call-1", "calculator", `{"expression":"2+2"}`)
+
+	type calcArgs struct {
+		Expression string `json:"expression"`
+	}
+	started := make(chan struct{})
+	blocked, err := agent.NewStreamingFuncTool[calcArgs]("calculator", "Perform basic math calculations",
+		agent.StreamingToolFunc(func(ctx context.Context, call *genx.FuncCall, args string, emit func(string) error) (any, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewStreamingFuncTool error: %v", err)
+	}
+	rt := &streamingToolRuntime{
+		Runtime: setupReActAgentTestRuntime(t, mockGen),
+		tools:   map[string]*genx.FuncTool{"calculator": blocked},
+	}
+
+	agentDef, err := rt.GetAgentDef(ctx, "assistant")
+	if err != nil {
+		t.Fatalf("GetAgentDef error: %v", err)
+	}
+	reactAgent, err := agent.NewReActAgent(ctx, agentcfg.AsReActAgent(agentDef), rt, "")
+	if err != nil {
+		t.Fatalf("NewReActAgent error: %v", err)
+	}
+	if err := reactAgent.Input(genx.Contents{genx.Text("What is 2+2?")}); err != nil {
+		t.Fatalf("Input error: %v", err)
+	}
+	evt, err := reactAgent.Next()
+	if err != nil || evt.Type != agent.EventToolStart {
+		t.Fatalf("Next = %v, %v; want tool_start", evt, err)
+	}
+	<-started
+
+	// Snapshot while the tool is running, then crash.
+	snapshot, err := reactAgent.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	reactAgent.Close()
+
+	rt2 := setupReActAgentTestRuntime(t, newMockReActGenerator().
+		WithTextResponse("test-model", "The answer is 42."))
+	resumed, err := agent.ResumeAgent(ctx, rt2, snapshot)
+	if err != nil {
+		t.Fatalf("ResumeAgent error: %v", err)
+	}
+	defer resumed.Close()
+
+	// The pending call runs again without new input.
+	got := runRound(t, resumed)
+	want := []agent.EventType{agent.EventToolStart, agent.EventToolDone, agent.EventEOF}
+	if !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+
+	messages, err := resumed.State().LoadRecent(ctx)
+	if err != nil {
+		t.Fatalf("LoadRecent error: %v", err)
+	}
+	var calls, results int
+	for _, msg := range messages {
+		if msg.ToolCallID == "call-1" {
+			calls++
+			if msg.ToolCallArgs != `{"expression":"2+2"}` {
+				t.Errorf("ToolCallArgs = %q", msg.ToolCallArgs)
+			}
+		}
+		if msg.ToolResultID == "call-1" {
+			results++
+		}
+	}
+	if calls != 1 || results != 1 {
+		t.Errorf("history has %d calls and %d results of call-1, want 1 and 1", calls, results)
+	}
+	if history := resumed.FormatHistory(ctx); !strings.Contains(history, "The answer is 42.") {
+		t.Errorf("history missing answer:\n%s", history)
+	}
+}
+
+func TestMatchAgent_SnapshotResume(t *testing.T) {
+	ctx := context.Background()
+	rt := setupMatchAgentTestRuntimeWithMatchResult(t, "greeting")
+
+	agentDef, err := rt.GetAgentDef(ctx, "intent_router")
+	if err != nil {
+		t.Fatalf("GetAgentDef error: %v", err)
+	}
+	matchAgent, err := agent.NewMatchAgent(ctx, agentcfg.AsMatchAgent(agentDef), rt, "")
+	if err != nil {
+		t.Fatalf("NewMatchAgent error: %v", err)
+	}
+	if err := matchAgent.Input(genx.Contents{genx.Text("hello")}); err != nil {
+		t.Fatalf("Input error: %v", err)
+	}
+	runRound(t, matchAgent)
+	if matchAgent.GetCalling() == nil {
+		t.Fatal("expected calling sub-agent to be active")
+	}
+
+	snapshot, err := matchAgent.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	matchAgent.Close()
+
+	resumed, err := agent.ResumeAgent(ctx, setupMatchAgentTestRuntimeWithMatchResult(t, "greeting"), snapshot)
+	if err != nil {
+		t.Fatalf("ResumeAgent error: %v", err)
+	}
+	defer resumed.Close()
+
+	resumedMatch, ok := resumed.(*agent.MatchAgent)
+	if !ok {
+		t.Fatalf("ResumeAgent = %T, want *agent.MatchAgent", resumed)
+	}
+	state := resumed.State().(agent.MatchState)
+	if state.Phase() != agent.MatchPhaseExecuting || !state.Matched() {
+		t.Errorf("phase = %q, matched = %v", state.Phase(), state.Matched())
+	}
+	matches := state.Matches()
+	if len(matches) != 1 || matches[0].Rule != "greeting" || matches[0].AgentDef == nil {
+		t.Errorf("matches = %+v, want greeting with inline agent def", matches)
+	}
+
+	calling := resumedMatch.GetCalling()
+	if calling == nil {
+		t.Fatal("expected resumed calling sub-agent")
+	}
+	if calling.State().ParentStateID() != resumed.StateID() {
+		t.Errorf("calling ParentStateID = %q, want %q", calling.State().ParentStateID(), resumed.StateID())
+	}
+	if history := calling.FormatHistory(ctx); !strings.Contains(history, "[user]: hello") {
+		t.Errorf("calling history missing input:\n%s", history)
+	}
+}
+
+func TestResumeAgent_Invalid(t *testing.T) {
+	rt := setupReActAgentTestRuntime(t, newMockReActGenerator())
+	if _, err := agent.ResumeAgent(context.Background(), rt, []byte("not a snapshot")); err == nil {
+		t.Error("expected error for invalid snapshot")
+	}
+}
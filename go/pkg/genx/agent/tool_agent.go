@@ -0,0 +1,285 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+)
+
+// agentRefPrefix marks a tool $ref that names an agent instead of a tool.
+const agentRefPrefix = "agent:"
+
+// defaultAgentToolMaxDepth is the nesting limit of agent tools when
+// AgentTool.MaxDepth is not set.
+const defaultAgentToolMaxDepth = 3
+
+// AgentTool creates tools that delegate a task to another agent.
+//
+// # Overview
+//
+// An agent tool runs a sub-agent on the call input, in a new state whose
+// parent is the calling agent's state. The sub-agent runs until it answers
+// (EOF) or finishes (quit tool), and its answer becomes the tool result.
+// This lets a router agent hand tasks to specialist agents through
+// definitions alone, without orchestration code.
+//
+// # Definition
+//
+// Define an agent tool in YAML:
+//
+//	tools:
+//	  - type: agent
+//	    name: ask_music
+//	    description: "Let the music agent handle song requests"
+//	    agent:
+//	      $ref: agent:music
+//	    output: response     # or history
+//	    result_processor:    # optional, e.g. summarize the history
+//	      $ref: tool:summarizer
+//	    max_depth: 3
+//
+// A ReAct agent can also reference another agent directly; the tool is then
+// named after that agent and uses the defaults:
+//
+//	tools:
+//	  - $ref: agent:music
+//
+// # Input and Output
+//
+// By default the tool accepts a single input field:
+//
+//	{ "input": "task for the sub-agent" }
+//
+// With input_jq, the tool accepts any arguments and the jq expression builds
+// the sub-agent input from them.
+//
+// The output is the text the sub-agent generated, or with output: history,
+// its whole conversation. When the tool runs inside a ReActAgent, the text
+// is also streamed as EventToolChunk events.
+//
+// # Depth
+//
+// Sub-agents may use agent tools themselves. The nesting depth is carried
+// in the context, and a call fails with ErrMaxDepth once it would exceed
+// max_depth (default 3), so agents cannot delegate to each other forever.
+type AgentTool struct {
+	rt Runtime
+}
+
+// NewAgentTool creates an AgentTool instance.
+func NewAgentTool(rt Runtime) *AgentTool {
+	return &AgentTool{rt: rt}
+}
+
+// CreateFuncTool creates a genx.FuncTool from agentcfg.AgentTool.
+func (t *AgentTool) CreateFuncTool(ctx context.Context, def *agentcfg.AgentTool) (*genx.FuncTool, error) {
+	if def.Agent.IsEmpty() {
+		return nil, fmt.Errorf("tool %s: agent is required", def.Name)
+	}
+
+	description := def.Description
+	if description == "" {
+		description = "Delegate a task to the " + agentToolTarget(def) + " agent."
+	}
+
+	type agentArgs struct {
+		Input string `json:"input" description:"Task for the agent"`
+	}
+	var (
+		tool *genx.FuncTool
+		err  error
+	)
+	if def.InputJQ != nil {
+		tool, err = genx.NewFuncTool[map[string]any](def.Name, description)
+	} else {
+		tool, err = genx.NewFuncTool[agentArgs](def.Name, description)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", def.Name, err)
+	}
+
+	tool.Invoke = func(ctx context.Context, call *genx.FuncCall, args string) (any, error) {
+		input, err := agentToolInput(def, args)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: %w", def.Name, err)
+		}
+		return t.Run(ctx, def, input)
+	}
+	return tool, nil
+}
+
+// createRefTool creates the tool of a `$ref: agent:<name>` tool reference.
+func (t *AgentTool) createRefTool(ctx context.Context, ref string) (*genx.FuncTool, error) {
+	def := &agentcfg.AgentTool{
+		ToolBase: agentcfg.ToolBase{
+			Name: strings.TrimPrefix(ref, agentRefPrefix),
+			Type: agentcfg.ToolTypeAgent,
+		},
+		Agent: agentcfg.AgentRef{Ref: ref},
+	}
+	return t.rt.CreateToolFromDef(ctx, def)
+}
+
+// Run runs the sub-agent of def on input and returns its output.
+func (t *AgentTool) Run(ctx context.Context, def *agentcfg.AgentTool, input string) (string, error) {
+	maxDepth := def.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = defaultAgentToolMaxDepth
+	}
+	caller := agentCallerFrom(ctx)
+	if caller.depth >= maxDepth {
+		return "", fmt.Errorf("tool %s: %w (%d)", def.Name, ErrMaxDepth, maxDepth)
+	}
+
+	agentDef, err := t.resolveAgentDef(ctx, def)
+	if err != nil {
+		return "", fmt.Errorf("tool %s: %w", def.Name, err)
+	}
+	reactDef := agentcfg.AsReActAgent(agentDef)
+	if reactDef == nil {
+		return "", fmt.Errorf("tool %s: agent %s: only react agents can be called as tools", def.Name, agentDef.AgentName())
+	}
+
+	// The sub-agent's own agent tools see the increased depth
+	subCtx := withAgentCaller(ctx, agentCaller{stateID: caller.stateID, depth: caller.depth + 1})
+	sub, err := NewReActAgent(subCtx, reactDef, t.rt, caller.stateID)
+	if err != nil {
+		return "", fmt.Errorf("tool %s: create agent %s: %w", def.Name, reactDef.Name, err)
+	}
+	defer sub.Close()
+	stop := context.AfterFunc(ctx, func() { sub.Close() })
+	defer stop()
+
+	if err := sub.Input(genx.Contents{genx.Text(input)}); err != nil {
+		return "", fmt.Errorf("tool %s: input to agent %s: %w", def.Name, reactDef.Name, err)
+	}
+	output, err := t.collect(ctx, sub)
+	if err != nil {
+		return "", fmt.Errorf("tool %s: agent %s: %w", def.Name, reactDef.Name, err)
+	}
+	if def.Output == agentcfg.AgentToolOutputHistory {
+		output = formatHistory(ctx, sub.State())
+	}
+
+	if def.ResultProcessor != nil {
+		output, err = NewTextProcessorTool(t.rt).Execute(ctx, def.ResultProcessor, output)
+		if err != nil {
+			return "", fmt.Errorf("tool %s: result processor: %w", def.Name, err)
+		}
+	}
+	return output, nil
+}
+
+// collect runs the sub-agent until it answers or finishes and returns the
+// text it generated, streaming it to the tool chunk sink if there is one.
+func (t *AgentTool) collect(ctx context.Context, sub Agent) (string, error) {
+	sink := toolChunkSinkFrom(ctx)
+	var sb strings.Builder
+	for {
+		evt, err := sub.Next()
+		if err != nil {
+			return "", err
+		}
+		switch evt.Type {
+		case EventChunk:
+			if evt.Chunk == nil {
+				continue
+			}
+			text, ok := evt.Chunk.Part.(genx.Text)
+			if !ok {
+				continue
+			}
+			sb.WriteString(string(text))
+			if sink != nil {
+				if err := sink(string(text)); err != nil {
+					return "", err
+				}
+			}
+		case EventToolApprovalRequired:
+			if err := rejectUnattended(sub, evt); err != nil {
+				return "", err
+			}
+		case EventEOF, EventClosed, EventInterrupted:
+			// Closed by cancellation rather than by a quit tool
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+			return sb.String(), nil
+		}
+	}
+}
+
+// resolveAgentDef resolves the sub-agent definition of def.
+func (t *AgentTool) resolveAgentDef(ctx context.Context, def *agentcfg.AgentTool) (agentcfg.Agent, error) {
+	if def.Agent.Agent != nil {
+		return def.Agent.Agent, nil
+	}
+	agentDef, err := t.rt.GetAgentDef(ctx, def.Agent.Ref)
+	if err != nil {
+		return nil, fmt.Errorf("get agent %s: %w", def.Agent.Ref, err)
+	}
+	return agentDef, nil
+}
+
+// agentToolTarget returns the name of the sub-agent of def.
+func agentToolTarget(def *agentcfg.AgentTool) string {
+	if def.Agent.Agent != nil {
+		return def.Agent.AgentName()
+	}
+	return strings.TrimPrefix(def.Agent.Ref, agentRefPrefix)
+}
+
+// agentToolInput builds the sub-agent input from the call arguments.
+func agentToolInput(def *agentcfg.AgentTool, args string) (string, error) {
+	if def.InputJQ == nil {
+		var in struct {
+			Input string `json:"input"`
+		}
+		if err := json.Unmarshal([]byte(args), &in); err != nil {
+			return "", fmt.Errorf("parse args: %w", err)
+		}
+		if in.Input == "" {
+			return "", fmt.Errorf("input is required")
+		}
+		return in.Input, nil
+	}
+
+	var v any
+	if err := json.Unmarshal([]byte(args), &v); err != nil {
+		return "", fmt.Errorf("parse args: %w", err)
+	}
+	result, err := def.InputJQ.Run(v)
+	if err != nil {
+		return "", fmt.Errorf("input_jq: %w", err)
+	}
+	// A string result is the input itself, anything else is passed as JSON
+	var s string
+	if err := json.Unmarshal([]byte(result), &s); err == nil {
+		return s, nil
+	}
+	return result, nil
+}
+
+// agentCaller is the agent whose tool is running, as seen from the tool.
+type agentCaller struct {
+	// stateID is the state ID of the calling agent.
+	stateID string
+	// depth is the number of agent tools the calling agent runs inside.
+	depth int
+}
+
+// agentCallerKey is the context key of the agentCaller.
+type agentCallerKey struct{}
+
+func withAgentCaller(ctx context.Context, caller agentCaller) context.Context {
+	return context.WithValue(ctx, agentCallerKey{}, caller)
+}
+
+func agentCallerFrom(ctx context.Context) agentCaller {
+	caller, _ := ctx.Value(agentCallerKey{}).(agentCaller)
+	return caller
+}
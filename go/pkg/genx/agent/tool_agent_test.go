@@ -0,0 +1,183 @@
+package agent_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agent"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+)
+
+// runAgentTool starts the named agent with input and returns the events of
+// its first round, leaving out model chunks.
+func runAgentTool(t *testing.T, rt agent.Runtime, name, input string) (agent.Agent, []*agent.AgentEvent) {
+	t.Helper()
+	ctx := context.Background()
+	agentDef, err := rt.GetAgentDef(ctx, name)
+	if err != nil {
+		t.Fatalf("GetAgentDef error: %v", err)
+	}
+	a, err := agent.NewReActAgent(ctx, agentcfg.AsReActAgent(agentDef), rt, "")
+	if err != nil {
+		t.Fatalf("NewReActAgent error: %v", err)
+	}
+	if err := a.Input(genx.Contents{genx.Text(input)}); err != nil {
+		t.Fatalf("Input error: %v", err)
+	}
+	var events []*agent.AgentEvent
+	for {
+		evt, err := a.Next()
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		if evt.Type != agent.EventChunk {
+			events = append(events, evt)
+		}
+		if evt.Type == agent.EventEOF || evt.Type == agent.EventClosed {
+			return a, events
+		}
+	}
+}
+
+// toolResult returns the stored result of the call with the given ID.
+func toolResult(t *testing.T, a agent.Agent, events []*agent.AgentEvent, callID string) string {
+	t.Helper()
+	for _, evt := range events {
+		if evt.Type == agent.EventToolError {
+			t.Fatalf("tool error: %v", evt.ToolError)
+		}
+	}
+	messages, err := a.State().LoadRecent(context.Background())
+	if err != nil {
+		t.Fatalf("LoadRecent error: %v", err)
+	}
+	for _, msg := range messages {
+		if msg.ToolResultID == callID {
+			return msg.Content
+		}
+	}
+	t.Fatalf("no result of %s", callID)
+	return ""
+}
+
+func TestAgentTool_Delegate(t *testing.T) {
+	mockGen := newMockReActGenerator().
+		WithToolCall("parent-model", "call-1", "call_child", `{"input":"What is 2+2?"}`).
+		WithTextResponse("parent-model", "The child says 4.").
+		WithTextAndToolCall("child-model", "2+2 is 4.", "call-2", "finish", `{}`)
+	rt := setupReActAgentTestRuntime(t, mockGen)
+
+	parent, events := runAgentTool(t, rt, "parent_agent", "Ask the child")
+	defer parent.Close()
+
+	if got := toolResult(t, parent, events, "call-1"); got != "2+2 is 4." {
+		t.Errorf("tool result = %q, want %q", got, "2+2 is 4.")
+	}
+	var chunks string
+	for _, evt := range events {
+		if evt.Type == agent.EventToolChunk {
+			chunks += string(evt.Chunk.Part.(genx.Text))
+		}
+	}
+	if chunks != "2+2 is 4." {
+		t.Errorf("tool chunks = %q, want the child's answer", chunks)
+	}
+	if history := parent.FormatHistory(context.Background()); !strings.Contains(history, "The child says 4.") {
+		t.Errorf("parent history missing answer:\n%s", history)
+	}
+}
+
+func TestAgentTool_ResultProcessor(t *testing.T) {
+	mockGen := newMockReActGenerator().
+		WithToolCall("parent-model", "call-1", "call_child_with_processor", `{"input":"What is 2+2?"}`).
+		WithTextResponse("parent-model", "Done.").
+		WithTextAndToolCall("child-model", "2+2 is 4.", "call-2", "finish", `{}`).
+		WithTextResponse("processor-model", "The child answered 4.")
+	rt := setupReActAgentTestRuntime(t, mockGen)
+
+	parent, events := runAgentTool(t, rt, "parent_with_processor", "Ask the child")
+	defer parent.Close()
+
+	if got := toolResult(t, parent, events, "call-1"); got != "The child answered 4." {
+		t.Errorf("tool result = %q, want the processed result", got)
+	}
+}
+
+func TestAgentTool_AgentRef(t *testing.T) {
+	mockGen := newMockReActGenerator().
+		WithToolCall("parent-model", "call-1", "child_agent", `{"input":"Hello"}`).
+		WithTextResponse("parent-model", "Done.").
+		WithTextResponse("child-model", "Hi from the child.")
+	rt := setupReActAgentTestRuntime(t, mockGen)
+
+	router, events := runAgentTool(t, rt, "router_agent", "Say hello")
+	defer router.Close()
+
+	if got := toolResult(t, router, events, "call-1"); got != "Hi from the child." {
+		t.Errorf("tool result = %q, want %q", got, "Hi from the child.")
+	}
+}
+
+func TestAgentTool_HistoryOutput(t *testing.T) {
+	mockGen := newMockReActGenerator().
+		WithTextResponse("child-model", "Hi from the child.")
+	rt := setupReActAgentTestRuntime(t, mockGen)
+
+	tool, err := rt.CreateToolFromDef(context.Background(), &agentcfg.AgentTool{
+		ToolBase: agentcfg.ToolBase{Name: "ask_child", Type: agentcfg.ToolTypeAgent},
+		Agent:    agentcfg.AgentRef{Ref: "agent:child_agent"},
+		Output:   agentcfg.AgentToolOutputHistory,
+	})
+	if err != nil {
+		t.Fatalf("CreateToolFromDef error: %v", err)
+	}
+	result, err := tool.Invoke(context.Background(), tool.NewFuncCall(`{"input":"Hello"}`), `{"input":"Hello"}`)
+	if err != nil {
+		t.Fatalf("Invoke error: %v", err)
+	}
+	history, _ := result.(string)
+	for _, want := range []string{"[user]: Hello", "[model]: Hi from the child."} {
+		if !strings.Contains(history, want) {
+			t.Errorf("result missing %q:\n%s", want, history)
+		}
+	}
+}
+
+func TestAgentTool_MaxDepth(t *testing.T) {
+	// Every agent delegates to itself until the depth limit stops the
+	// deepest one, which then answers.
+	mockGen := newMockReActGenerator()
+	for range 4 {
+		mockGen.WithToolCall("loop-model", "call", "recursive_agent", `{"input":"again"}`)
+	}
+	for _, text := range []string{"depth 3", "depth 2", "depth 1", "depth 0"} {
+		mockGen.WithTextResponse("loop-model", text)
+	}
+	rt := setupReActAgentTestRuntime(t, mockGen)
+
+	top, events := runAgentTool(t, rt, "recursive_agent", "Go")
+	defer top.Close()
+
+	if got := toolResult(t, top, events, "call"); got != "depth 1" {
+		t.Errorf("tool result = %q, want %q", got, "depth 1")
+	}
+	if n := mockGen.callCount["loop-model"]; n != 8 {
+		t.Errorf("loop-model called %d times, want 8", n)
+	}
+}
+
+func TestAgentTool_MissingInput(t *testing.T) {
+	rt := setupReActAgentTestRuntime(t, newMockReActGenerator())
+	tool, err := agent.NewAgentTool(rt).CreateFuncTool(context.Background(), &agentcfg.AgentTool{
+		ToolBase: agentcfg.ToolBase{Name: "ask_child", Type: agentcfg.ToolTypeAgent},
+		Agent:    agentcfg.AgentRef{Ref: "child_agent"},
+	})
+	if err != nil {
+		t.Fatalf("CreateFuncTool error: %v", err)
+	}
+	if _, err := tool.Invoke(context.Background(), tool.NewFuncCall(`{}`), `{}`); err == nil {
+		t.Error("expected error for missing input")
+	}
+}
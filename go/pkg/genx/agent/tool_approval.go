@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+)
+
+// ToolApprover is implemented by agents whose tool calls can wait for a
+// human to approve them (see agentcfg.ToolApproval). The agent emits
+// EventToolApprovalRequired with the call, and its next event waits until
+// the call is approved or rejected.
+//
+// ReActAgent implements it; MatchAgent passes the decision to the agent it
+// is calling.
+type ToolApprover interface {
+	// Approve lets the tool call with the ID callID run.
+	Approve(callID string) error
+
+	// Reject refuses the tool call with the ID callID. The call is not run
+	// and the model sees reason as the tool's error.
+	Reject(callID, reason string) error
+}
+
+var (
+	_ ToolApprover = (*ReActAgent)(nil)
+	_ ToolApprover = (*MatchAgent)(nil)
+)
+
+// toolApproval is the decision on a tool call waiting for approval.
+type toolApproval struct {
+	approved bool
+	reason   string
+}
+
+// err returns the error of a rejected call, or nil if tc is approved.
+func (d toolApproval) err(tc *genx.ToolCall) error {
+	if d.approved {
+		return nil
+	}
+	name := ""
+	if tc.FuncCall != nil {
+		name = tc.FuncCall.Name
+	}
+	if d.reason == "" {
+		return fmt.Errorf("%w: %s", ErrToolRejected, name)
+	}
+	return fmt.Errorf("%w: %s: %s", ErrToolRejected, name, d.reason)
+}
+
+// requiresApproval reports whether the calls of the tool of ref wait for
+// approval. Tools without a definition, such as Go tools registered with the
+// runtime, never do.
+func requiresApproval(ctx context.Context, rt Runtime, ref agentcfg.ToolRef) bool {
+	def := ref.Tool
+	if ref.IsRef() {
+		var err error
+		if def, err = rt.GetToolDef(ctx, ref.Ref); err != nil {
+			return false
+		}
+	}
+	return def != nil && def.ToolApproval().IsRequired()
+}
+
+// needsApproval reports whether tc waits for approval before it runs.
+func (a *ReActAgent) needsApproval(tc *genx.ToolCall) bool {
+	if tc.FuncCall == nil {
+		return false
+	}
+	_, ok := a.approvalTools[tc.FuncCall.Name]
+	return ok
+}
+
+// Approve lets the tool call with the ID callID, which is waiting for
+// approval, run.
+func (a *ReActAgent) Approve(callID string) error {
+	return a.decide(callID, toolApproval{approved: true})
+}
+
+// Reject refuses the tool call with the ID callID, which is waiting for
+// approval. The model sees reason as the tool's error.
+func (a *ReActAgent) Reject(callID, reason string) error {
+	return a.decide(callID, toolApproval{reason: reason})
+}
+
+// decide passes the decision on the tool call of callID to the goroutine
+// running it.
+func (a *ReActAgent) decide(callID string, d toolApproval) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return ErrClosed
+	}
+	if a.approval == nil || a.toolCall == nil || a.toolCall.ID != callID {
+		return fmt.Errorf("%w: %s", ErrNoPendingApproval, callID)
+	}
+	a.approval <- d
+	a.approval = nil
+	return nil
+}
+
+// Approve lets the tool call with the ID callID of the agent being called
+// run.
+func (a *MatchAgent) Approve(callID string) error {
+	approver, err := a.callingApprover(callID)
+	if err != nil {
+		return err
+	}
+	return approver.Approve(callID)
+}
+
+// Reject refuses the tool call with the ID callID of the agent being
+// called.
+func (a *MatchAgent) Reject(callID, reason string) error {
+	approver, err := a.callingApprover(callID)
+	if err != nil {
+		return err
+	}
+	return approver.Reject(callID, reason)
+}
+
+// callingApprover returns the agent being called if it can approve calls.
+func (a *MatchAgent) callingApprover(callID string) (ToolApprover, error) {
+	if approver, ok := a.getCalling().(ToolApprover); ok {
+		return approver, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNoPendingApproval, callID)
+}
+
+// rejectUnattended rejects the tool call of an EventToolApprovalRequired
+// of an agent nobody can approve calls of, such as the sub-agent of an
+// agent tool or an agent run by a trigger.
+func rejectUnattended(a Agent, evt *AgentEvent) error {
+	approver, ok := a.(ToolApprover)
+	if !ok || evt.ToolCall == nil {
+		return nil
+	}
+	return approver.Reject(evt.ToolCall.ID, "approval is not available here")
+}
@@ -0,0 +1,190 @@
+package agent_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agent"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+	"github.com/haivivi/giztoy/go/pkg/genx/playground"
+)
+
+// newApprovalAgent creates the assistant agent with the start_ota tool,
+// whose calls require approval and are counted in invoked.
+func newApprovalAgent(t *testing.T, gen genx.Generator, invoked *atomic.Int32) *agent.ReActAgent {
+	t.Helper()
+	ctx := context.Background()
+	ota, err := genx.NewFuncTool[struct{}](
+		"start_ota",
+		"Update the device firmware",
+		genx.InvokeFunc[struct{}](func(ctx context.Context, call *genx.FuncCall, args struct{}) (any, error) {
+			invoked.Add(1)
+			return "update started", nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewFuncTool error: %v", err)
+	}
+	store := playground.NewStore(nil)
+	if err := store.LoadReadonlyLayer("testdata", os.DirFS("testdata/agent_react_test")); err != nil {
+		t.Fatalf("load testdata: %v", err)
+	}
+	store.Set(playground.TypeToolV1+"/start_ota", map[string]any{
+		"type":     "built-in",
+		"name":     "start_ota",
+		"approval": "required",
+	})
+	rt := playground.NewRuntime(
+		playground.WithStore(store),
+		playground.WithGenerator(gen),
+		playground.WithBuiltinTools(append(createReActBuiltinTools(), ota)...),
+	)
+	agentDef, err := rt.GetAgentDef(ctx, "assistant")
+	if err != nil {
+		t.Fatalf("GetAgentDef error: %v", err)
+	}
+	def := *agentcfg.AsReActAgent(agentDef)
+	def.Tools = append(slices.Clone(def.Tools), agentcfg.ToolRef{Ref: "start_ota"})
+	a, err := agent.NewReActAgent(ctx, &def, rt, "")
+	if err != nil {
+		t.Fatalf("NewReActAgent error: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+// restOfRound returns the events of a up to the end of the round.
+func restOfRound(t *testing.T, a agent.Agent) []*agent.AgentEvent {
+	t.Helper()
+	var events []*agent.AgentEvent
+	for {
+		evt, err := a.Next()
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		events = append(events, evt)
+		if evt.Type == agent.EventEOF || evt.Type == agent.EventClosed {
+			return events
+		}
+	}
+}
+
+// awaitApproval inputs text and returns the approval request that follows.
+func awaitApproval(t *testing.T, a agent.Agent, text string) *agent.AgentEvent {
+	t.Helper()
+	if err := a.Input(genx.Contents{genx.Text(text)}); err != nil {
+		t.Fatalf("Input error: %v", err)
+	}
+	evt, err := a.Next()
+	if err != nil {
+		t.Fatalf("Next error: %v", err)
+	}
+	if evt.Type != agent.EventToolApprovalRequired {
+		t.Fatalf("event = %v, want %v", evt.Type, agent.EventToolApprovalRequired)
+	}
+	return evt
+}
+
+func TestReActAgent_ToolApproval_Approve(t *testing.T) {
+	gen := newMockReActGenerator().
+		WithToolCall("test-model", "call-1", "start_ota", `{}`).
+		WithTextResponse("test-model", "The update has started.")
+	var invoked atomic.Int32
+	a := newApprovalAgent(t, gen, &invoked)
+
+	req := awaitApproval(t, a, "Update yourself")
+	if req.ToolCall == nil || req.ToolCall.ID != "call-1" || req.ToolCall.FuncCall.Name != "start_ota" {
+		t.Fatalf("ToolCall = %+v", req.ToolCall)
+	}
+	if err := a.Approve("call-2"); !errors.Is(err, agent.ErrNoPendingApproval) {
+		t.Errorf("Approve of another call = %v, want ErrNoPendingApproval", err)
+	}
+
+	// Next waits for the approval
+	time.AfterFunc(50*time.Millisecond, func() {
+		if err := a.Approve("call-1"); err != nil {
+			t.Errorf("Approve error: %v", err)
+		}
+	})
+	events := restOfRound(t, a)
+	want := []agent.EventType{agent.EventToolStart, agent.EventToolDone, agent.EventEOF}
+	if got := eventTypes(events); !slices.Equal(got, want) {
+		t.Fatalf("event types = %v, want %v", got, want)
+	}
+	if n := invoked.Load(); n != 1 {
+		t.Errorf("invoked %d times, want 1", n)
+	}
+	if err := a.Approve("call-1"); !errors.Is(err, agent.ErrNoPendingApproval) {
+		t.Errorf("second Approve = %v, want ErrNoPendingApproval", err)
+	}
+}
+
+func TestReActAgent_ToolApproval_Reject(t *testing.T) {
+	gen := newMockReActGenerator().
+		WithToolCall("test-model", "call-1", "start_ota", `{}`).
+		WithTextResponse("test-model", "Your parent said no.")
+	var invoked atomic.Int32
+	a := newApprovalAgent(t, gen, &invoked)
+
+	awaitApproval(t, a, "Update yourself")
+	if err := a.Reject("call-1", "not during homework"); err != nil {
+		t.Fatalf("Reject error: %v", err)
+	}
+	events := restOfRound(t, a)
+	want := []agent.EventType{agent.EventToolError, agent.EventEOF}
+	if got := eventTypes(events); !slices.Equal(got, want) {
+		t.Fatalf("event types = %v, want %v", got, want)
+	}
+	if toolErr := findEvent(events, agent.EventToolError).ToolError; !errors.Is(toolErr, agent.ErrToolRejected) {
+		t.Errorf("ToolError = %v, want ErrToolRejected", toolErr)
+	}
+	if n := invoked.Load(); n != 0 {
+		t.Errorf("invoked %d times, want 0", n)
+	}
+	if history := a.FormatHistory(context.Background()); !strings.Contains(history, "not during homework") {
+		t.Errorf("rejection not passed to the model:\n%s", history)
+	}
+}
+
+func TestReActAgent_ToolApproval_Interrupt(t *testing.T) {
+	gen := newMockReActGenerator().
+		WithToolCall("test-model", "call-1", "start_ota", `{}`)
+	var invoked atomic.Int32
+	a := newApprovalAgent(t, gen, &invoked)
+
+	awaitApproval(t, a, "Update yourself")
+	if err := a.Interrupt(); err != nil {
+		t.Fatalf("Interrupt error: %v", err)
+	}
+	evt, err := a.Next()
+	if err != nil || evt.Type != agent.EventInterrupted {
+		t.Fatalf("Next = %v, %v; want EventInterrupted", evt, err)
+	}
+	if err := a.Approve("call-1"); !errors.Is(err, agent.ErrNoPendingApproval) {
+		t.Errorf("Approve after Interrupt = %v, want ErrNoPendingApproval", err)
+	}
+	if n := invoked.Load(); n != 0 {
+		t.Errorf("invoked %d times, want 0", n)
+	}
+}
+
+func TestReActAgent_ToolApproval_NotRequired(t *testing.T) {
+	gen := newMockReActGenerator().
+		WithToolCall("test-model", "call-1", "search", `{"query":"dinosaurs"}`).
+		WithTextResponse("test-model", "Dinosaurs were big.")
+	var invoked atomic.Int32
+	a := newApprovalAgent(t, gen, &invoked)
+
+	events := guardedRound(t, a, "Tell me about dinosaurs")
+	want := []agent.EventType{agent.EventToolStart, agent.EventToolDone, agent.EventEOF}
+	if got := eventTypes(events); !slices.Equal(got, want) {
+		t.Errorf("event types = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+)
+
+// DocumentResolver returns the DocumentStore of a collection.
+type DocumentResolver func(ctx context.Context, collection string) (*DocumentStore, error)
+
+// DocumentsTool is the runtime instance for documents (knowledge-base)
+// tools. It lets agents answer from ingested documents such as product
+// manuals.
+//
+// # Definition
+//
+// Define a documents tool in YAML:
+//
+//	tools:
+//	  - type: documents
+//	    name: kb_search
+//	    description: "Search the product manuals"
+//	    collection: manuals
+//	    limit: 3
+//
+// The model calls the tool with a query and gets the best matching
+// passages with their document title and section heading.
+type DocumentsTool struct {
+	resolve DocumentResolver
+}
+
+// NewDocumentsTool creates a DocumentsTool that finds collections with
+// resolve.
+func NewDocumentsTool(resolve DocumentResolver) *DocumentsTool {
+	return &DocumentsTool{resolve: resolve}
+}
+
+// DocumentsSearchArgs are the arguments of a documents tool call.
+type DocumentsSearchArgs struct {
+	Query string `json:"query" jsonschema:"what to look up in the documents"`
+	Limit int    `json:"limit,omitempty" jsonschema:"maximum number of passages to return"`
+}
+
+// DocumentsSearchResult is the result of a documents tool call.
+type DocumentsSearchResult struct {
+	Results []DocumentHit `json:"results"`
+}
+
+// CreateFuncTool creates a genx.FuncTool from agentcfg.DocumentsTool.
+func (t *DocumentsTool) CreateFuncTool(def *agentcfg.DocumentsTool) (*genx.FuncTool, error) {
+	description := def.Description
+	if description == "" {
+		description = "Search the " + def.Collection + " knowledge base and return relevant passages."
+	}
+	tool, err := genx.NewFuncTool[DocumentsSearchArgs](
+		def.Name,
+		description,
+		genx.InvokeFunc[DocumentsSearchArgs](func(ctx context.Context, call *genx.FuncCall, args DocumentsSearchArgs) (any, error) {
+			return t.Search(ctx, def, args)
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", def.Name, err)
+	}
+	return tool, nil
+}
+
+// Search searches the collection of def. The limit of args is capped by
+// the limit of def (default 5).
+func (t *DocumentsTool) Search(ctx context.Context, def *agentcfg.DocumentsTool, args DocumentsSearchArgs) (*DocumentsSearchResult, error) {
+	if args.Query == "" {
+		return nil, fmt.Errorf("tool %s: query is required", def.Name)
+	}
+	store, err := t.resolve(ctx, def.Collection)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: collection %s: %w", def.Name, def.Collection, err)
+	}
+
+	limit := def.Limit
+	if limit <= 0 {
+		limit = 5
+	}
+	if args.Limit > 0 && args.Limit < limit {
+		limit = args.Limit
+	}
+	hits, err := store.Search(ctx, args.Query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", def.Name, err)
+	}
+	if hits == nil {
+		hits = []DocumentHit{}
+	}
+	return &DocumentsSearchResult{Results: hits}, nil
+}
@@ -0,0 +1,255 @@
+package agent_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/haivivi/giztoy/go/pkg/genx/agent"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+	"github.com/haivivi/giztoy/go/pkg/genx/playground"
+	"github.com/haivivi/giztoy/go/pkg/kv"
+)
+
+const manual = `# Giztoy Manual
+
+Giztoy is a talking toy for kids.
+
+## Setup
+
+### Wi-Fi
+
+Hold the power button for five seconds until the light blinks blue.
+Open the app and choose your Wi-Fi network.
+
+### Charging
+
+Plug the USB-C cable in. A full charge takes two hours.
+
+## Troubleshooting
+
+If the toy does not answer, restart it by holding the power button.
+`
+
+func newDocumentStore(t *testing.T, cfg agent.DocumentStoreConfig) *agent.DocumentStore {
+	t.Helper()
+	if cfg.Store == nil {
+		cfg.Store = kv.NewMemory(nil)
+	}
+	store, err := agent.NewDocumentStore(cfg)
+	if err != nil {
+		t.Fatalf("NewDocumentStore: %v", err)
+	}
+	return store
+}
+
+func TestDocumentStore_IngestAndSearch(t *testing.T) {
+	ctx := context.Background()
+	store := newDocumentStore(t, agent.DocumentStoreConfig{})
+
+	n, err := store.Ingest(ctx, agent.Document{ID: "manual", Title: "Giztoy Manual", Content: manual})
+	if err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	if n != 4 {
+		t.Errorf("chunks = %d, want 4 (one per section)", n)
+	}
+
+	hits, err := store.Search(ctx, "how long does charging take", 2)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) == 0 {
+		t.Fatal("no hits")
+	}
+	if hits[0].Heading != "Giztoy Manual > Setup > Charging" {
+		t.Errorf("top heading = %q", hits[0].Heading)
+	}
+	if !strings.Contains(hits[0].Text, "two hours") {
+		t.Errorf("top text = %q", hits[0].Text)
+	}
+	if hits[0].DocID != "manual" || hits[0].Title != "Giztoy Manual" {
+		t.Errorf("top hit = %+v", hits[0])
+	}
+	if len(hits) > 2 {
+		t.Errorf("len(hits) = %d, want <= 2", len(hits))
+	}
+}
+
+func TestDocumentStore_ReingestReplaces(t *testing.T) {
+	ctx := context.Background()
+	store := newDocumentStore(t, agent.DocumentStoreConfig{})
+
+	if _, err := store.Ingest(ctx, agent.Document{ID: "faq", Content: "The battery lasts ten hours."}); err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	if _, err := store.Ingest(ctx, agent.Document{ID: "faq", Content: "The battery lasts twelve hours."}); err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+
+	hits, err := store.Search(ctx, "battery", 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 1 || !strings.Contains(hits[0].Text, "twelve") {
+		t.Errorf("hits = %+v, want only the new version", hits)
+	}
+
+	docs, err := store.Documents(ctx)
+	if err != nil {
+		t.Fatalf("Documents: %v", err)
+	}
+	if len(docs) != 1 || docs[0].ID != "faq" || docs[0].Chunks != 1 {
+		t.Errorf("documents = %+v", docs)
+	}
+
+	if err := store.Delete(ctx, "faq"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	hits, err = store.Search(ctx, "battery", 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 0 {
+		t.Errorf("hits after delete = %+v", hits)
+	}
+}
+
+func TestDocumentStore_ChunkSizeAndOverlap(t *testing.T) {
+	ctx := context.Background()
+	store := newDocumentStore(t, agent.DocumentStoreConfig{ChunkSize: 100, ChunkOverlap: 20})
+
+	var paras []string
+	for i := range 10 {
+		paras = append(paras, strings.Repeat(string(rune('a'+i)), 30)+" ends here.")
+	}
+	n, err := store.Ingest(ctx, agent.Document{ID: "long", Content: strings.Join(paras, "\n\n")})
+	if err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	if n < 4 {
+		t.Errorf("chunks = %d, want the text split into several chunks", n)
+	}
+
+	hits, err := store.Search(ctx, "ends", 100)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != n {
+		t.Fatalf("len(hits) = %d, want %d", len(hits), n)
+	}
+	for _, h := range hits {
+		if l := len([]rune(h.Text)); l > 100+2 {
+			t.Errorf("chunk of %d runes exceeds chunk size: %q", l, h.Text)
+		}
+	}
+}
+
+func TestDocumentStore_CJKQuery(t *testing.T) {
+	ctx := context.Background()
+	store := newDocumentStore(t, agent.DocumentStoreConfig{})
+
+	content := "## 充电\n\n使用 USB-C 线充电，充满需要两个小时。\n\n## 联网\n\n长按电源键五秒进入配网模式。"
+	if _, err := store.Ingest(ctx, agent.Document{ID: "zh", Content: content}); err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	hits, err := store.Search(ctx, "充电要多久", 1)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Heading != "充电" {
+		t.Errorf("hits = %+v", hits)
+	}
+}
+
+// letterEmbedder embeds text as letter frequencies.
+type letterEmbedder struct{}
+
+func (letterEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	v := make([]float32, 26)
+	for _, r := range strings.ToLower(text) {
+		if r >= 'a' && r <= 'z' {
+			v[r-'a']++
+		}
+	}
+	return v, nil
+}
+
+func (e letterEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vs := make([][]float32, len(texts))
+	for i, text := range texts {
+		vs[i], _ = e.Embed(ctx, text)
+	}
+	return vs, nil
+}
+
+func (letterEmbedder) Dimension() int { return 26 }
+func (letterEmbedder) Model() string  { return "letters" }
+
+func TestDocumentStore_Embedder(t *testing.T) {
+	ctx := context.Background()
+	store := newDocumentStore(t, agent.DocumentStoreConfig{Embedder: letterEmbedder{}})
+
+	if _, err := store.Ingest(ctx, agent.Document{ID: "a", Content: "aaaa aaaa"}); err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	if _, err := store.Ingest(ctx, agent.Document{ID: "z", Content: "zzzz zzzz"}); err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+
+	// No keyword matches, so the ranking comes from the vectors alone.
+	hits, err := store.Search(ctx, "zzzy", 2)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) == 0 || hits[0].DocID != "z" {
+		t.Errorf("hits = %+v, want z first", hits)
+	}
+}
+
+func TestDocumentsTool(t *testing.T) {
+	ctx := context.Background()
+	store := newDocumentStore(t, agent.DocumentStoreConfig{})
+	if _, err := store.Ingest(ctx, agent.Document{ID: "manual", Title: "Giztoy Manual", Content: manual}); err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+
+	rt := playground.NewRuntime(playground.WithDocuments("manuals", store))
+	tool, err := rt.CreateToolFromDef(ctx, &agentcfg.DocumentsTool{
+		ToolBase:   agentcfg.ToolBase{Name: "kb_search", Type: agentcfg.ToolTypeDocuments},
+		Collection: "manuals",
+		Limit:      1,
+	})
+	if err != nil {
+		t.Fatalf("CreateToolFromDef: %v", err)
+	}
+	if tool.Name != "kb_search" {
+		t.Errorf("Name = %q", tool.Name)
+	}
+
+	out, err := tool.Invoke(ctx, nil, `{"query": "wi-fi network", "limit": 3}`)
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	result, ok := out.(*agent.DocumentsSearchResult)
+	if !ok {
+		t.Fatalf("result = %T", out)
+	}
+	if len(result.Results) != 1 {
+		t.Fatalf("len(results) = %d, want 1 (capped by the tool limit)", len(result.Results))
+	}
+	if result.Results[0].Heading != "Giztoy Manual > Setup > Wi-Fi" {
+		t.Errorf("heading = %q", result.Results[0].Heading)
+	}
+
+	missing, err := rt.CreateToolFromDef(ctx, &agentcfg.DocumentsTool{
+		ToolBase:   agentcfg.ToolBase{Name: "other", Type: agentcfg.ToolTypeDocuments},
+		Collection: "missing",
+	})
+	if err != nil {
+		t.Fatalf("CreateToolFromDef: %v", err)
+	}
+	if _, err := missing.Invoke(ctx, nil, `{"query": "x"}`); err == nil {
+		t.Error("expected error for unknown collection")
+	}
+}
@@ -335,26 +335,12 @@ func (t *GeneratorTool) executeGenerate(ctx context.Context, model string, mctx
 
 // executeJSONOutput executes in json_output mode (structured output).
 func (t *GeneratorTool) executeJSONOutput(ctx context.Context, model string, mctx genx.ModelContext, outputSchema *jsonschema.Schema) (any, error) {
-	// Create a FuncTool with the output schema
-	tool := &genx.FuncTool{
-		Name:        "output",
-		Description: "Output structured result",
-		Argument:    outputSchema,
-		Invoke: func(ctx context.Context, call *genx.FuncCall, arg string) (any, error) {
-			return arg, nil
-		},
-	}
-
-	// Use Invoke to get structured output
-	_, funcCall, err := t.rt.Invoke(ctx, model, mctx, tool)
+	out, _, err := genx.GenerateJSON[json.RawMessage](ctx, t.rt, model, mctx, outputSchema,
+		genx.WithJSONOutputName("output", "Output structured result"))
 	if err != nil {
 		return nil, fmt.Errorf("invoke: %w", err)
 	}
 
-	if funcCall == nil {
-		return nil, fmt.Errorf("no function call returned")
-	}
-
 	// Return the arguments as JSON result
-	return funcCall.Arguments, nil
+	return string(out), nil
 }
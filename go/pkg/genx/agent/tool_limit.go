@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+)
+
+// ToolLimiter enforces agentcfg.ToolLimits on tool invocations.
+//
+// State is kept per tool name, so every FuncTool wrapped by the same
+// ToolLimiter shares one concurrency and rate budget per tool. A Runtime
+// should hold a single ToolLimiter and wrap each tool it creates; agents
+// for many devices then share the limits of a common tool backend.
+//
+// Calls over the limit either wait for capacity (ToolLimitQueue, the
+// default) or fail with ErrToolLimited (ToolLimitReject). ReActAgent
+// reports ErrToolLimited as EventToolError.
+type ToolLimiter struct {
+	mu    sync.Mutex
+	tools map[string]*toolLimit
+}
+
+// NewToolLimiter creates an empty ToolLimiter.
+func NewToolLimiter() *ToolLimiter {
+	return &ToolLimiter{tools: make(map[string]*toolLimit)}
+}
+
+// Wrap returns a copy of tool whose Invoke is subject to limits.
+// If limits is nil or sets no limit, tool is returned unchanged.
+func (l *ToolLimiter) Wrap(tool *genx.FuncTool, limits *agentcfg.ToolLimits) *genx.FuncTool {
+	if tool == nil || limits == nil || (limits.MaxConcurrent == 0 && limits.QPS == 0) {
+		return tool
+	}
+	tl := l.get(tool.Name, *limits)
+	invoke := tool.Invoke
+
+	wrapped := *tool
+	wrapped.Invoke = func(ctx context.Context, call *genx.FuncCall, arg string) (any, error) {
+		release, err := tl.acquire(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: %w", tool.Name, err)
+		}
+		defer release()
+		return invoke(ctx, call, arg)
+	}
+	return &wrapped
+}
+
+// get returns the limit state for name, replacing it if the limits changed.
+func (l *ToolLimiter) get(name string, limits agentcfg.ToolLimits) *toolLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if tl, ok := l.tools[name]; ok && tl.limits == limits {
+		return tl
+	}
+	tl := newToolLimit(limits)
+	l.tools[name] = tl
+	return tl
+}
+
+// toolLimit is the shared state of one tool: a semaphore for concurrency
+// and a token bucket for rate.
+type toolLimit struct {
+	limits agentcfg.ToolLimits
+	sem    chan struct{} // nil if concurrency is unlimited
+
+	mu     sync.Mutex
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newToolLimit(limits agentcfg.ToolLimits) *toolLimit {
+	tl := &toolLimit{limits: limits}
+	if limits.MaxConcurrent > 0 {
+		tl.sem = make(chan struct{}, limits.MaxConcurrent)
+	}
+	if limits.QPS > 0 {
+		tl.burst = float64(limits.Burst)
+		if tl.burst <= 0 {
+			tl.burst = max(1, math.Ceil(limits.QPS))
+		}
+		tl.tokens = tl.burst
+		tl.last = time.Now()
+	}
+	return tl
+}
+
+// acquire waits for (or, in reject mode, checks) a rate token and a
+// concurrency slot. The returned release must be called when the call ends.
+// The rate token is returned if no slot is acquired.
+func (tl *toolLimit) acquire(ctx context.Context) (release func(), err error) {
+	reject := tl.limits.OnLimit == agentcfg.ToolLimitReject
+	rated := tl.limits.QPS > 0
+
+	if rated {
+		wait, ok := tl.reserve(!reject)
+		if !ok {
+			return nil, fmt.Errorf("%w: rate limit %g/s", ErrToolLimited, tl.limits.QPS)
+		}
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				tl.cancel()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+
+	if tl.sem == nil {
+		return func() {}, nil
+	}
+	if reject {
+		select {
+		case tl.sem <- struct{}{}:
+		default:
+			if rated {
+				tl.cancel()
+			}
+			return nil, fmt.Errorf("%w: %d calls in flight", ErrToolLimited, tl.limits.MaxConcurrent)
+		}
+	} else {
+		select {
+		case tl.sem <- struct{}{}:
+		case <-ctx.Done():
+			if rated {
+				tl.cancel()
+			}
+			return nil, ctx.Err()
+		}
+	}
+	return func() { <-tl.sem }, nil
+}
+
+// reserve takes a token from the bucket. If none is available it either
+// reports failure or, when borrow is true, takes one in advance and
+// returns how long the caller must wait for it.
+func (tl *toolLimit) reserve(borrow bool) (time.Duration, bool) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	now := time.Now()
+	tl.tokens = min(tl.burst, tl.tokens+now.Sub(tl.last).Seconds()*tl.limits.QPS)
+	tl.last = now
+
+	if tl.tokens >= 1 {
+		tl.tokens--
+		return 0, true
+	}
+	if !borrow {
+		return 0, false
+	}
+	tl.tokens--
+	return time.Duration(-tl.tokens / tl.limits.QPS * float64(time.Second)), true
+}
+
+// cancel returns a token reserved by a caller that gave up or was
+// rejected.
+func (tl *toolLimit) cancel() {
+	tl.mu.Lock()
+	tl.tokens = min(tl.burst, tl.tokens+1)
+	tl.mu.Unlock()
+}
@@ -0,0 +1,207 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+)
+
+// callCounter tracks in-flight and peak concurrent calls.
+type callCounter struct {
+	inFlight, peak atomic.Int32
+}
+
+// blockingTool returns a tool that blocks each call until release is closed.
+func blockingTool(release <-chan struct{}, c *callCounter) *genx.FuncTool {
+	return &genx.FuncTool{
+		Name: "slow",
+		Invoke: func(ctx context.Context, call *genx.FuncCall, arg string) (any, error) {
+			n := c.inFlight.Add(1)
+			defer c.inFlight.Add(-1)
+			for {
+				p := c.peak.Load()
+				if n <= p || c.peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			<-release
+			return "ok", nil
+		},
+	}
+}
+
+func TestToolLimiter_NoLimits(t *testing.T) {
+	tool := &genx.FuncTool{Name: "t"}
+	l := NewToolLimiter()
+	if got := l.Wrap(tool, nil); got != tool {
+		t.Error("Wrap(nil limits) should return the tool unchanged")
+	}
+	if got := l.Wrap(tool, &agentcfg.ToolLimits{OnLimit: agentcfg.ToolLimitReject}); got != tool {
+		t.Error("Wrap(empty limits) should return the tool unchanged")
+	}
+}
+
+func TestToolLimiter_ConcurrencyReject(t *testing.T) {
+	release := make(chan struct{})
+	var calls callCounter
+	l := NewToolLimiter()
+	limits := &agentcfg.ToolLimits{MaxConcurrent: 2, OnLimit: agentcfg.ToolLimitReject}
+
+	var wg sync.WaitGroup
+	for range 2 {
+		// Wrap per call, as a runtime does; state is shared by name.
+		tool := l.Wrap(blockingTool(release, &calls), limits)
+		wg.Go(func() {
+			if _, err := tool.Invoke(context.Background(), nil, "{}"); err != nil {
+				t.Errorf("Invoke: %v", err)
+			}
+		})
+	}
+	for calls.peak.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	tool := l.Wrap(blockingTool(release, &calls), limits)
+	_, err := tool.Invoke(context.Background(), nil, "{}")
+	if !errors.Is(err, ErrToolLimited) {
+		t.Errorf("third call error = %v, want ErrToolLimited", err)
+	}
+
+	close(release)
+	wg.Wait()
+	if _, err := tool.Invoke(context.Background(), nil, "{}"); err != nil {
+		t.Errorf("call after release: %v", err)
+	}
+}
+
+func TestToolLimiter_ConcurrencyQueue(t *testing.T) {
+	release := make(chan struct{})
+	var calls callCounter
+	tool := NewToolLimiter().Wrap(blockingTool(release, &calls), &agentcfg.ToolLimits{MaxConcurrent: 2})
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			if _, err := tool.Invoke(context.Background(), nil, "{}"); err != nil {
+				t.Errorf("Invoke: %v", err)
+			}
+		})
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.peak.Load(); got != 2 {
+		t.Errorf("peak concurrency = %d, want 2", got)
+	}
+}
+
+func TestToolLimiter_QueueCanceled(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	var calls callCounter
+	tool := NewToolLimiter().Wrap(blockingTool(release, &calls), &agentcfg.ToolLimits{MaxConcurrent: 1})
+
+	go tool.Invoke(context.Background(), nil, "{}")
+	for calls.peak.Load() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := tool.Invoke(ctx, nil, "{}"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("queued call error = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestToolLimiter_RateReject(t *testing.T) {
+	tool := &genx.FuncTool{
+		Name: "fast",
+		Invoke: func(ctx context.Context, call *genx.FuncCall, arg string) (any, error) {
+			return "ok", nil
+		},
+	}
+	tool = NewToolLimiter().Wrap(tool, &agentcfg.ToolLimits{QPS: 1, Burst: 3, OnLimit: agentcfg.ToolLimitReject})
+
+	var ok, limited int
+	for range 5 {
+		_, err := tool.Invoke(context.Background(), nil, "{}")
+		switch {
+		case err == nil:
+			ok++
+		case errors.Is(err, ErrToolLimited):
+			limited++
+		default:
+			t.Fatalf("Invoke: %v", err)
+		}
+	}
+	if ok != 3 || limited != 2 {
+		t.Errorf("ok=%d limited=%d, want 3 and 2 (burst 3)", ok, limited)
+	}
+}
+
+func TestToolLimiter_RateQueue(t *testing.T) {
+	tool := &genx.FuncTool{
+		Name: "fast",
+		Invoke: func(ctx context.Context, call *genx.FuncCall, arg string) (any, error) {
+			return "ok", nil
+		},
+	}
+	tool = NewToolLimiter().Wrap(tool, &agentcfg.ToolLimits{QPS: 50, Burst: 1})
+
+	start := time.Now()
+	for range 4 {
+		if _, err := tool.Invoke(context.Background(), nil, "{}"); err != nil {
+			t.Fatalf("Invoke: %v", err)
+		}
+	}
+	// One call from the burst, then three at 20ms intervals.
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("4 calls at 50 QPS took %v, want about 60ms", elapsed)
+	}
+}
+
+func TestToolLimiter_SlotFailureReturnsToken(t *testing.T) {
+	for _, onLimit := range []agentcfg.ToolLimitAction{agentcfg.ToolLimitReject, agentcfg.ToolLimitQueue} {
+		t.Run(string(onLimit), func(t *testing.T) {
+			release := make(chan struct{})
+			var calls callCounter
+			// Burst 2 at a rate too slow to refill during the test
+			tool := NewToolLimiter().Wrap(blockingTool(release, &calls),
+				&agentcfg.ToolLimits{MaxConcurrent: 1, QPS: 0.001, Burst: 2, OnLimit: onLimit})
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				tool.Invoke(context.Background(), nil, "{}")
+			}()
+			for calls.peak.Load() < 1 {
+				time.Sleep(time.Millisecond)
+			}
+
+			// Calls failing to get the slot must not use up the last token
+			for range 3 {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+				_, err := tool.Invoke(ctx, nil, "{}")
+				cancel()
+				if err == nil {
+					t.Fatal("call without a free slot succeeded")
+				}
+			}
+			close(release)
+			<-done
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if _, err := tool.Invoke(ctx, nil, "{}"); err != nil {
+				t.Errorf("call with a token left: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+)
+
+// MCPClientResolver returns the client of an MCP server. Runtimes share one
+// client between the tools of a server.
+type MCPClientResolver func(ctx context.Context, server agentcfg.MCPServer) (*MCPClient, error)
+
+// MCPTool is the runtime instance for mcp tools, which call the tools of an
+// MCP (Model Context Protocol) server.
+//
+// # Definition
+//
+// Define an mcp tool in YAML, with a stdio server:
+//
+//	tools:
+//	  - type: mcp
+//	    name: read_file
+//	    server:
+//	      command: npx
+//	      args: ["-y", "@modelcontextprotocol/server-filesystem", "/data"]
+//
+// or an SSE server, renaming the server's tool:
+//
+//	tools:
+//	  - type: mcp
+//	    name: weather
+//	    server:
+//	      url: https://mcp.example.com/sse
+//	      headers:
+//	        Authorization: Bearer <token>
+//	    tool: get_forecast
+//
+// The description and argument schema come from the server; a description
+// in the definition takes precedence. A result the server marks as an
+// error fails the call with its text.
+type MCPTool struct {
+	resolve MCPClientResolver
+}
+
+// NewMCPTool creates an MCPTool that gets server clients with resolve.
+func NewMCPTool(resolve MCPClientResolver) *MCPTool {
+	return &MCPTool{resolve: resolve}
+}
+
+// CreateFuncTool creates a genx.FuncTool from agentcfg.MCPTool. It connects
+// to the server to discover the tool.
+func (t *MCPTool) CreateFuncTool(ctx context.Context, def *agentcfg.MCPTool) (*genx.FuncTool, error) {
+	client, err := t.resolve(ctx, def.Server)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", def.Name, err)
+	}
+	infos, err := client.ListTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", def.Name, err)
+	}
+	for _, info := range infos {
+		if info.Name != def.ServerTool() {
+			continue
+		}
+		if def.Description != "" {
+			info.Description = def.Description
+		}
+		tool, err := newMCPFuncTool(client, def.Name, info)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: %w", def.Name, err)
+		}
+		return tool, nil
+	}
+	return nil, fmt.Errorf("tool %s: %q not found on mcp server", def.Name, def.ServerTool())
+}
+
+// MCPToolset exposes all tools of an MCP server, e.g. to register them as
+// built-in tools of a runtime:
+//
+//	client, err := agent.ConnectMCP(ctx, agentcfg.MCPServer{Command: "my-mcp-server"})
+//	...
+//	tools, err := agent.NewMCPToolset(client, "fs_").FuncTools(ctx)
+//	...
+//	rt := playground.NewRuntime(playground.WithBuiltinTools(tools...))
+//
+// Agent definitions then reference the tools by name, e.g. tool:fs_read_file.
+type MCPToolset struct {
+	client *MCPClient
+	prefix string
+}
+
+// NewMCPToolset creates an MCPToolset of client. The names of the tools
+// are prefixed with prefix, which may be empty.
+func NewMCPToolset(client *MCPClient, prefix string) *MCPToolset {
+	return &MCPToolset{client: client, prefix: prefix}
+}
+
+// FuncTools discovers the tools of the server and returns them as
+// genx.FuncTools.
+func (s *MCPToolset) FuncTools(ctx context.Context) ([]*genx.FuncTool, error) {
+	infos, err := s.client.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tools := make([]*genx.FuncTool, 0, len(infos))
+	for _, info := range infos {
+		tool, err := newMCPFuncTool(s.client, s.prefix+info.Name, info)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: %w", info.Name, err)
+		}
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+// newMCPFuncTool creates a FuncTool named name that calls the server tool
+// of info.
+func newMCPFuncTool(client *MCPClient, name string, info MCPToolInfo) (*genx.FuncTool, error) {
+	schema, err := mcpSchema(info.InputSchema)
+	if err != nil {
+		return nil, fmt.Errorf("input schema: %w", err)
+	}
+	return &genx.FuncTool{
+		Name:        name,
+		Description: info.Description,
+		Argument:    schema,
+		Invoke: func(ctx context.Context, call *genx.FuncCall, args string) (any, error) {
+			result, err := client.CallTool(ctx, info.Name, json.RawMessage(args))
+			if err != nil {
+				return nil, err
+			}
+			return mcpOutput(result)
+		},
+	}, nil
+}
+
+// mcpSchema translates the input schema of an MCP tool into an argument
+// schema for models. Servers may omit the schema or declare a JSON Schema
+// dialect; model APIs expect a plain object schema.
+func mcpSchema(raw json.RawMessage) (*jsonschema.Schema, error) {
+	schema := &jsonschema.Schema{}
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, schema); err != nil {
+			return nil, err
+		}
+	}
+	schema.Schema = ""
+	schema.ID = ""
+	if schema.Type == "" && len(schema.Types) == 0 {
+		schema.Type = "object"
+	}
+	if schema.Type == "object" && schema.Properties == nil {
+		schema.Properties = map[string]*jsonschema.Schema{}
+	}
+	return schema, nil
+}
+
+// mcpOutput converts the result of an MCP tool call into the output of a
+// FuncTool: the structured content if any, otherwise the text content.
+func mcpOutput(result *MCPToolResult) (any, error) {
+	if result.IsError {
+		text := result.Text()
+		if text == "" {
+			text = "tool call failed"
+		}
+		return nil, errors.New(text)
+	}
+	if len(result.StructuredContent) > 0 {
+		return result.StructuredContent, nil
+	}
+	if text := result.Text(); text != "" || len(result.Content) == 0 {
+		return text, nil
+	}
+	// Only non-text content, e.g. an image: pass it on as JSON
+	return result.Content, nil
+}
@@ -0,0 +1,247 @@
+package agent_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/haivivi/giztoy/go/pkg/genx/agent"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+	"github.com/haivivi/giztoy/go/pkg/genx/playground"
+)
+
+// fakeMCPRequest is a JSON-RPC request to the fake MCP server.
+type fakeMCPRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params struct {
+		Cursor    string `json:"cursor"`
+		Name      string `json:"name"`
+		Arguments struct {
+			A int `json:"a"`
+			B int `json:"b"`
+		} `json:"arguments"`
+	} `json:"params"`
+}
+
+// fakeMCPRespond answers a request to the fake MCP server, which lists its
+// tools on two pages. It returns nil for notifications.
+func fakeMCPRespond(data []byte) []byte {
+	var req fakeMCPRequest
+	if err := json.Unmarshal(data, &req); err != nil || len(req.ID) == 0 {
+		return nil
+	}
+	var result string
+	switch req.Method {
+	case "initialize":
+		result = `{"protocolVersion":"2024-11-05","capabilities":{"tools":{}},"serverInfo":{"name":"fake","version":"0.1.0"}}`
+	case "tools/list":
+		if req.Params.Cursor == "" {
+			result = `{"tools":[{"name":"add","description":"Add two numbers","inputSchema":{
+				"$schema":"http://json-schema.org/draft-07/schema#","type":"object",
+				"properties":{"a":{"type":"integer"},"b":{"type":"integer"}},"required":["a","b"]}}],"nextCursor":"2"}`
+		} else {
+			result = `{"tools":[{"name":"fail","description":"Always fails"}]}`
+		}
+	case "tools/call":
+		if req.Params.Name == "add" {
+			result = fmt.Sprintf(`{"content":[{"type":"text","text":"%d"}]}`, req.Params.Arguments.A+req.Params.Arguments.B)
+		} else {
+			result = `{"content":[{"type":"text","text":"disk full"}],"isError":true}`
+		}
+	default:
+		return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%s,"error":{"code":-32601,"message":"method not found"}}`, req.ID)
+	}
+	var buf bytes.Buffer
+	json.Compact(&buf, []byte(result))
+	return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, buf.Bytes())
+}
+
+// TestMCPHelperServer is not a test: it runs the fake MCP server on stdio
+// when the test binary is started as a stdio MCP server.
+func TestMCPHelperServer(t *testing.T) {
+	if os.Getenv("GIZTOY_MCP_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if resp := fakeMCPRespond(scanner.Bytes()); resp != nil {
+			os.Stdout.Write(append(resp, '\n'))
+		}
+	}
+	os.Exit(0)
+}
+
+// stdioMCPServer runs this test binary as a stdio MCP server.
+func stdioMCPServer() agentcfg.MCPServer {
+	return agentcfg.MCPServer{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestMCPHelperServer$"},
+		Env:     map[string]string{"GIZTOY_MCP_HELPER": "1"},
+	}
+}
+
+// newSSEMCPServer starts the fake MCP server over HTTP with SSE.
+func newSSEMCPServer(t *testing.T) agentcfg.MCPServer {
+	t.Helper()
+	responses := make(chan []byte, 16)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sse", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": welcome\n\nevent: endpoint\ndata: /messages?session=1\n\n")
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case resp := <-responses:
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", resp)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	mux.HandleFunc("POST /messages", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		if resp := fakeMCPRespond(data); resp != nil {
+			responses <- resp
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return agentcfg.MCPServer{
+		URL:     srv.URL + "/sse",
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+	}
+}
+
+func TestMCPClient_Stdio(t *testing.T) {
+	ctx := context.Background()
+	client, err := agent.ConnectMCP(ctx, stdioMCPServer())
+	if err != nil {
+		t.Fatalf("ConnectMCP error: %v", err)
+	}
+	defer client.Close()
+
+	if info := client.ServerInfo(); info.Name != "fake" || info.ProtocolVersion != "2024-11-05" {
+		t.Errorf("ServerInfo() = %+v", info)
+	}
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools error: %v", err)
+	}
+	if len(tools) != 2 || tools[0].Name != "add" || tools[1].Name != "fail" {
+		t.Fatalf("tools = %+v, want add and fail from both pages", tools)
+	}
+	result, err := client.CallTool(ctx, "add", json.RawMessage(`{"a":2,"b":3}`))
+	if err != nil {
+		t.Fatalf("CallTool error: %v", err)
+	}
+	if result.IsError || result.Text() != "5" {
+		t.Errorf("result = %+v, want 5", result)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Errorf("Close error: %v", err)
+	}
+	if _, err := client.ListTools(ctx); err == nil {
+		t.Error("ListTools after Close: expected error")
+	}
+}
+
+func TestConnectMCP_MissingCommand(t *testing.T) {
+	if _, err := agent.ConnectMCP(context.Background(), agentcfg.MCPServer{Command: "/nonexistent/mcp-server"}); err == nil {
+		t.Error("ConnectMCP with a missing command: expected error")
+	}
+}
+
+func TestMCPToolset_SSE(t *testing.T) {
+	ctx := context.Background()
+	client, err := agent.ConnectMCP(ctx, newSSEMCPServer(t))
+	if err != nil {
+		t.Fatalf("ConnectMCP error: %v", err)
+	}
+	defer client.Close()
+
+	tools, err := agent.NewMCPToolset(client, "calc_").FuncTools(ctx)
+	if err != nil {
+		t.Fatalf("FuncTools error: %v", err)
+	}
+	if len(tools) != 2 || tools[0].Name != "calc_add" || tools[1].Name != "calc_fail" {
+		t.Fatalf("tools = %v, want calc_add and calc_fail", tools)
+	}
+
+	add := tools[0]
+	if add.Description != "Add two numbers" {
+		t.Errorf("Description = %q", add.Description)
+	}
+	if add.Argument.Schema != "" || add.Argument.Type != "object" || add.Argument.Properties["a"].Type != "integer" {
+		t.Errorf("add schema = %+v", add.Argument)
+	}
+	if fail := tools[1].Argument; fail.Type != "object" || fail.Properties == nil {
+		t.Errorf("schema of a tool without one = %+v, want an empty object", fail)
+	}
+
+	out, err := add.Invoke(ctx, add.NewFuncCall(`{"a":20,"b":22}`), `{"a":20,"b":22}`)
+	if err != nil {
+		t.Fatalf("Invoke error: %v", err)
+	}
+	if out != "42" {
+		t.Errorf("output = %v, want 42", out)
+	}
+	if _, err := tools[1].Invoke(ctx, tools[1].NewFuncCall(`{}`), `{}`); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Invoke of a failing tool error = %v, want disk full", err)
+	}
+}
+
+func TestMCPClient_SSEUnauthorized(t *testing.T) {
+	server := newSSEMCPServer(t)
+	server.Headers = nil
+	if _, err := agent.ConnectMCP(context.Background(), server); err == nil {
+		t.Error("ConnectMCP without credentials: expected error")
+	}
+}
+
+func TestRuntime_MCPTool(t *testing.T) {
+	ctx := context.Background()
+	server := newSSEMCPServer(t)
+	rt := playground.NewRuntime()
+	defer rt.Close()
+
+	tool, err := rt.CreateToolFromDef(ctx, &agentcfg.MCPTool{
+		ToolBase: agentcfg.ToolBase{Name: "sum", Type: agentcfg.ToolTypeMCP, Description: "Sum two numbers"},
+		Server:   server,
+		Tool:     "add",
+	})
+	if err != nil {
+		t.Fatalf("CreateToolFromDef error: %v", err)
+	}
+	if tool.Name != "sum" || tool.Description != "Sum two numbers" {
+		t.Errorf("tool = %s %q", tool.Name, tool.Description)
+	}
+	out, err := tool.Invoke(ctx, tool.NewFuncCall(`{"a":1,"b":2}`), `{"a":1,"b":2}`)
+	if err != nil || out != "3" {
+		t.Errorf("Invoke = %v, %v; want 3", out, err)
+	}
+
+	// A missing tool is reported when the tool is created
+	_, err = rt.CreateToolFromDef(ctx, &agentcfg.MCPTool{
+		ToolBase: agentcfg.ToolBase{Name: "mul", Type: agentcfg.ToolTypeMCP},
+		Server:   server,
+	})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("CreateToolFromDef of a missing tool error = %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+	"github.com/haivivi/giztoy/go/pkg/memory"
+)
+
+// MemoryResolver returns the memory searched by retrieval tools, usually
+// the memory of the persona serving the device or session of ctx.
+type MemoryResolver func(ctx context.Context) (*memory.Memory, error)
+
+// RetrievalTool is the runtime instance for retrieval tools. It lets
+// agents answer from the persona's memory of earlier conversations with
+// memory.Recall, so RAG over device memory needs no custom Go code.
+//
+// # Definition
+//
+// Define a retrieval tool in YAML and reference it from agents with
+// "$ref: tool:recall":
+//
+//	type: retrieval
+//	name: recall
+//	description: "Recall earlier conversations with the user"
+//	hops: 2
+//	limit: 5
+//
+// The model calls the tool with a query, entity labels (expanded through
+// the memory graph up to hops) and an optional period such as
+// "last_week". Each memory in the result has a short citation ID, e.g.
+// "m1", which the model cites in its answer.
+type RetrievalTool struct {
+	resolve MemoryResolver
+}
+
+// NewRetrievalTool creates a RetrievalTool that finds memories with
+// resolve.
+func NewRetrievalTool(resolve MemoryResolver) *RetrievalTool {
+	return &RetrievalTool{resolve: resolve}
+}
+
+// RetrievalArgs are the arguments of a retrieval tool call.
+type RetrievalArgs struct {
+	Query  string   `json:"query,omitempty" jsonschema:"topic or question to remember"`
+	Labels []string `json:"labels,omitempty" jsonschema:"entities the question is about, e.g. person:Xiaoming"`
+	Period string   `json:"period,omitempty" jsonschema:"only recall a period: today, yesterday, this_week, last_week, this_month, last_month, this_year, last_year or on_this_day"`
+	Limit  int      `json:"limit,omitempty" jsonschema:"maximum number of memories to return"`
+}
+
+// RetrievalResult is the result of a retrieval tool call.
+type RetrievalResult struct {
+	Memories []RetrievedMemory   `json:"memories"`
+	Entities []memory.EntityInfo `json:"entities,omitempty"`
+}
+
+// RetrievedMemory is a memory segment returned by a retrieval tool.
+type RetrievedMemory struct {
+	// Cite is the citation ID of the memory in this result, e.g. "m1".
+	Cite string `json:"cite"`
+	// ID is the ID of the memory segment.
+	ID      string   `json:"id"`
+	Summary string   `json:"summary"`
+	Time    string   `json:"time"` // RFC 3339
+	Labels  []string `json:"labels,omitempty"`
+	Score   float64  `json:"score"`
+}
+
+// CreateFuncTool creates a genx.FuncTool from agentcfg.RetrievalTool.
+func (t *RetrievalTool) CreateFuncTool(def *agentcfg.RetrievalTool) (*genx.FuncTool, error) {
+	description := def.Description
+	if description == "" {
+		description = "Recall earlier conversations with the user. Cite the memories you use by their cite ID, e.g. [m1]."
+	}
+	tool, err := genx.NewFuncTool[RetrievalArgs](
+		def.Name,
+		description,
+		genx.InvokeFunc[RetrievalArgs](func(ctx context.Context, call *genx.FuncCall, args RetrievalArgs) (any, error) {
+			return t.Retrieve(ctx, def, args)
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", def.Name, err)
+	}
+	return tool, nil
+}
+
+// Retrieve recalls the memories matching args. The limit of args is
+// capped by the limit of def (default 5).
+func (t *RetrievalTool) Retrieve(ctx context.Context, def *agentcfg.RetrievalTool, args RetrievalArgs) (*RetrievalResult, error) {
+	if args.Query == "" && len(args.Labels) == 0 && args.Period == "" {
+		return nil, fmt.Errorf("tool %s: query, labels or period is required", def.Name)
+	}
+	mem, err := t.resolve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", def.Name, err)
+	}
+
+	limit := def.Limit
+	if limit <= 0 {
+		limit = 5
+	}
+	if args.Limit > 0 && args.Limit < limit {
+		limit = args.Limit
+	}
+	res, err := mem.Recall(ctx, memory.RecallQuery{
+		Labels: args.Labels,
+		Text:   args.Query,
+		Hops:   def.Hops,
+		Limit:  limit,
+		Period: memory.RecallPeriod(args.Period),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", def.Name, err)
+	}
+
+	out := &RetrievalResult{Memories: []RetrievedMemory{}, Entities: res.Entities}
+	for i, seg := range res.Segments {
+		out.Memories = append(out.Memories, RetrievedMemory{
+			Cite:    fmt.Sprintf("m%d", i+1),
+			ID:      seg.ID,
+			Summary: seg.Summary,
+			Time:    time.Unix(0, seg.Timestamp).Format(time.RFC3339),
+			Labels:  seg.Labels,
+			Score:   seg.Score,
+		})
+	}
+	return out, nil
+}
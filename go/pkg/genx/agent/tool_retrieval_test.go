@@ -0,0 +1,133 @@
+package agent_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/haivivi/giztoy/go/pkg/genx/agent"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+	"github.com/haivivi/giztoy/go/pkg/genx/playground"
+	"github.com/haivivi/giztoy/go/pkg/kv"
+	"github.com/haivivi/giztoy/go/pkg/memory"
+	"github.com/haivivi/giztoy/go/pkg/recall"
+)
+
+// newRetrievalMemory creates a memory in which xiaoming visited the
+// dinosaur park.
+func newRetrievalMemory(t *testing.T) *memory.Memory {
+	t.Helper()
+	ctx := context.Background()
+	host, err := memory.NewHost(ctx, memory.HostConfig{Store: kv.NewMemory(nil)})
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	mem, err := host.Open("toy")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	err = mem.ApplyEntityUpdate(ctx, &memory.EntityUpdate{
+		Entities:  []memory.EntityInput{{Label: "xiaoming", Attrs: map[string]any{"age": 7}}, {Label: "dino_park"}},
+		Relations: []memory.RelationInput{{From: "xiaoming", To: "dino_park", RelType: "visited"}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyEntityUpdate: %v", err)
+	}
+	for _, seg := range []memory.SegmentInput{
+		{Summary: "Xiaoming said his favorite dinosaur is the triceratops", Keywords: []string{"dinosaur", "triceratops"}, Labels: []string{"xiaoming"}},
+		{Summary: "The dinosaur park opened a new T-rex hall", Keywords: []string{"dinosaur", "park"}, Labels: []string{"dino_park"}},
+	} {
+		if err := mem.StoreSegment(ctx, seg, recall.Bucket1H); err != nil {
+			t.Fatalf("StoreSegment: %v", err)
+		}
+	}
+	return mem
+}
+
+func TestRetrievalTool(t *testing.T) {
+	ctx := context.Background()
+	store := playground.NewStore(nil)
+	store.Set(playground.TypeToolV1+"/recall", map[string]any{
+		"type":  "retrieval",
+		"name":  "recall",
+		"hops":  1,
+		"limit": 5,
+	})
+	rt := playground.NewRuntime(
+		playground.WithStore(store),
+		playground.WithMemory(newRetrievalMemory(t)),
+	)
+
+	tool, err := rt.GetTool(ctx, "tool:recall")
+	if err != nil {
+		t.Fatalf("GetTool: %v", err)
+	}
+	out, err := tool.Invoke(ctx, nil, `{"query": "dinosaur", "labels": ["xiaoming"]}`)
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	result, ok := out.(*agent.RetrievalResult)
+	if !ok {
+		t.Fatalf("result = %T", out)
+	}
+	if len(result.Memories) != 2 {
+		t.Fatalf("memories = %+v, want both, the park one through the graph", result.Memories)
+	}
+	for i, m := range result.Memories {
+		if want := []string{"m1", "m2"}[i]; m.Cite != want {
+			t.Errorf("memory %d cite = %q, want %q", i, m.Cite, want)
+		}
+		if m.ID == "" || m.Summary == "" || m.Time == "" {
+			t.Errorf("memory %d = %+v", i, m)
+		}
+	}
+	var labels []string
+	for _, e := range result.Entities {
+		labels = append(labels, e.Label)
+	}
+	if !slices.Contains(labels, "xiaoming") || !slices.Contains(labels, "dino_park") {
+		t.Errorf("entities = %v, want the expanded labels", labels)
+	}
+
+	// Limited by the call
+	out, err = tool.Invoke(ctx, nil, `{"query": "dinosaur", "limit": 1}`)
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if n := len(out.(*agent.RetrievalResult).Memories); n != 1 {
+		t.Errorf("memories = %d, want 1", n)
+	}
+
+	// Nothing was said yesterday
+	out, err = tool.Invoke(ctx, nil, `{"query": "dinosaur", "period": "yesterday"}`)
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if m := out.(*agent.RetrievalResult).Memories; len(m) != 0 {
+		t.Errorf("memories of yesterday = %+v", m)
+	}
+}
+
+func TestRetrievalTool_Errors(t *testing.T) {
+	ctx := context.Background()
+	def := &agentcfg.RetrievalTool{ToolBase: agentcfg.ToolBase{Name: "recall", Type: agentcfg.ToolTypeRetrieval}}
+
+	tool, err := playground.NewRuntime(playground.WithMemory(newRetrievalMemory(t))).CreateToolFromDef(ctx, def)
+	if err != nil {
+		t.Fatalf("CreateToolFromDef: %v", err)
+	}
+	if _, err := tool.Invoke(ctx, nil, `{}`); err == nil {
+		t.Error("expected error without query, labels or period")
+	}
+	if _, err := tool.Invoke(ctx, nil, `{"period": "someday"}`); err == nil {
+		t.Error("expected error for an unknown period")
+	}
+
+	noMemory, err := playground.NewRuntime().CreateToolFromDef(ctx, def)
+	if err != nil {
+		t.Fatalf("CreateToolFromDef: %v", err)
+	}
+	if _, err := noMemory.Invoke(ctx, nil, `{"query": "x"}`); err == nil {
+		t.Error("expected error without memory")
+	}
+}
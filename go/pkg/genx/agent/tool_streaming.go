@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// StreamingTool is a tool whose output is produced incrementally, such as
+// a search crawl or code execution printing as it runs.
+//
+// Wrapped with NewStreamingFuncTool and called by a ReActAgent, each chunk
+// passed to emit is surfaced as an EventToolChunk while the tool runs.
+type StreamingTool interface {
+	// InvokeStream runs the tool with the raw JSON arguments, passing each
+	// output chunk to emit as it is produced, and returns the final result.
+	// emit returns an error once the call is abandoned (e.g. the agent was
+	// interrupted); the tool should then stop and return.
+	InvokeStream(ctx context.Context, call *genx.FuncCall, args string, emit func(chunk string) error) (any, error)
+}
+
+// StreamingToolFunc is an adapter to allow the use of ordinary functions as
+// StreamingTool.
+type StreamingToolFunc func(ctx context.Context, call *genx.FuncCall, args string, emit func(chunk string) error) (any, error)
+
+// InvokeStream calls f(ctx, call, args, emit).
+func (f StreamingToolFunc) InvokeStream(ctx context.Context, call *genx.FuncCall, args string, emit func(chunk string) error) (any, error) {
+	return f(ctx, call, args, emit)
+}
+
+// StreamingToolOption configures NewStreamingFuncTool.
+type StreamingToolOption func(*streamingToolConfig)
+
+type streamingToolConfig struct {
+	forward bool
+}
+
+// WithForwardChunks forwards the chunks to the model: the tool result is
+// the concatenated chunks followed by the final result. If the tool fails
+// midway, the model still sees the output produced so far. By default the
+// model sees only the final result.
+func WithForwardChunks() StreamingToolOption {
+	return func(c *streamingToolConfig) {
+		c.forward = true
+	}
+}
+
+// NewStreamingFuncTool returns a FuncTool that invokes tool. ArgType defines
+// the argument schema; tool receives the raw JSON arguments.
+//
+// Invoked outside a ReActAgent (e.g. as a step of a CompositeTool), the
+// chunks are not surfaced; with WithForwardChunks they are still part of
+// the result.
+func NewStreamingFuncTool[ArgType any](name, description string, tool StreamingTool, opts ...StreamingToolOption) (*genx.FuncTool, error) {
+	var cfg streamingToolConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	ft, err := genx.NewFuncTool[ArgType](name, description)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", name, err)
+	}
+	ft.Invoke = func(ctx context.Context, call *genx.FuncCall, args string) (any, error) {
+		sink := toolChunkSinkFrom(ctx)
+		var (
+			mu  sync.Mutex
+			out strings.Builder
+		)
+		emit := func(chunk string) error {
+			if cfg.forward {
+				mu.Lock()
+				out.WriteString(chunk)
+				mu.Unlock()
+			}
+			if sink != nil {
+				return sink(chunk)
+			}
+			return ctx.Err()
+		}
+
+		result, err := tool.InvokeStream(ctx, call, args, emit)
+		if !cfg.forward {
+			return result, err
+		}
+		mu.Lock()
+		output := out.String()
+		mu.Unlock()
+		if err != nil {
+			if output == "" {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w (output so far: %s)", err, output)
+		}
+		if result != nil {
+			output += formatOutput(result)
+		}
+		return output, nil
+	}
+	return ft, nil
+}
+
+// toolChunkSinkKey is the context key of the function that receives the
+// chunks of the running tool call.
+type toolChunkSinkKey struct{}
+
+func withToolChunkSink(ctx context.Context, sink func(chunk string) error) context.Context {
+	return context.WithValue(ctx, toolChunkSinkKey{}, sink)
+}
+
+func toolChunkSinkFrom(ctx context.Context) func(chunk string) error {
+	sink, _ := ctx.Value(toolChunkSinkKey{}).(func(chunk string) error)
+	return sink
+}
@@ -0,0 +1,564 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
+	"github.com/haivivi/giztoy/go/pkg/mqtt0"
+)
+
+// Trigger starts or pokes agents without user input: on a schedule, on an
+// MQTT message, or on an event of the host such as a memory or graph
+// update. For example, a reminder agent that speaks to a chatgear device at
+// 8am:
+//
+//	cron, err := agent.NewCronTrigger("0 8 * * *", loc)
+//	...
+//	rt := playground.NewRuntime(
+//	    playground.WithTriggers(agent.TriggerBinding{
+//	        Name:    "morning",
+//	        Trigger: cron,
+//	        Agent:   "reminder",
+//	        Target:  "gear-001",
+//	        Prompt:  "Greet the child and remind them of today's plans.",
+//	    }),
+//	    ...
+//	)
+//	rt.Triggers().Start(ctx, func(evt *agent.AgentEvent) {
+//	    // EventProactive, then the agent's output up to EventEOF
+//	})
+//
+// A Runtime that also implements TriggerRuntime holds a TriggerRegistry of
+// its bindings.
+type Trigger interface {
+	// Run calls fire for every firing until ctx is done. fire must not
+	// block for long.
+	Run(ctx context.Context, fire func(TriggerFiring)) error
+}
+
+// TriggerRuntime is implemented by Runtimes that run triggers.
+type TriggerRuntime interface {
+	// Triggers returns the trigger registry of the runtime.
+	Triggers() *TriggerRegistry
+}
+
+// Sources of trigger firings.
+const (
+	TriggerSourceCron   = "cron"
+	TriggerSourceMQTT   = "mqtt"
+	TriggerSourceEvent  = "event"
+	TriggerSourceManual = "manual" // TriggerRegistry.Fire
+)
+
+// TriggerFiring is one firing of a trigger.
+type TriggerFiring struct {
+	// Binding is the name of the TriggerBinding that fired.
+	Binding string
+
+	// Target is the Target of the binding, e.g. a device ID.
+	Target string
+
+	// Source is the kind of trigger, e.g. TriggerSourceCron.
+	Source string
+
+	// Time is when the trigger fired.
+	Time time.Time
+
+	// Topic is the cron spec, the MQTT topic or the event name.
+	Topic string
+
+	// Payload is the MQTT message or the event data, if any.
+	Payload []byte
+}
+
+// TriggerBinding binds a trigger to an agent.
+//
+// Each firing starts a new agent of Agent, or restores the agent of StateID
+// to continue its conversation, and sends it the input of the firing. The
+// firings of a binding run one at a time; while one runs, later firings
+// queue up.
+type TriggerBinding struct {
+	// Name identifies the binding in the registry.
+	Name string
+
+	// Trigger fires the binding.
+	Trigger Trigger
+
+	// Agent is the name of the agent definition started for every firing.
+	// Exactly one of Agent and StateID is required.
+	Agent string
+
+	// StateID is the saved state of an agent to poke instead (see
+	// Runtime.RestoreAgent). The state is kept after the firing.
+	StateID string
+
+	// Target is passed on in the firings, e.g. the device to speak to.
+	Target string
+
+	// Prompt is the input to the agent. The payload of the firing, if any,
+	// is appended to it.
+	Prompt string
+
+	// Input, if set, returns the input to the agent instead.
+	Input func(TriggerFiring) genx.Contents
+}
+
+func (b *TriggerBinding) validate() error {
+	switch {
+	case b.Name == "":
+		return fmt.Errorf("trigger: name is required")
+	case b.Trigger == nil:
+		return fmt.Errorf("trigger %s: trigger is required", b.Name)
+	case b.Agent == "" && b.StateID == "":
+		return fmt.Errorf("trigger %s: agent or state id is required", b.Name)
+	case b.Agent != "" && b.StateID != "":
+		return fmt.Errorf("trigger %s: agent and state id are mutually exclusive", b.Name)
+	}
+	return nil
+}
+
+// input returns the input to the agent for firing.
+func (b *TriggerBinding) input(firing TriggerFiring) genx.Contents {
+	if b.Input != nil {
+		return b.Input(firing)
+	}
+	text := b.Prompt
+	if len(firing.Payload) > 0 {
+		if text != "" {
+			text += "\n\n"
+		}
+		text += string(firing.Payload)
+	}
+	if text == "" {
+		text = fmt.Sprintf("Triggered by %s %s.", firing.Source, firing.Topic)
+	}
+	return genx.Contents{genx.Text(text)}
+}
+
+// TriggerOption configures a TriggerRegistry.
+type TriggerOption func(*TriggerRegistry)
+
+// WithTriggerErrorHandler sets a function called with the errors of
+// bindings: a trigger that fails, a firing dropped because the queue of
+// its binding is full (ErrTriggerBusy), or an agent that fails.
+func WithTriggerErrorHandler(fn func(binding string, err error)) TriggerOption {
+	return func(r *TriggerRegistry) { r.onError = fn }
+}
+
+// WithTriggerQueueSize sets the number of firings a binding queues while
+// one runs. Defaults to 8.
+func WithTriggerQueueSize(n int) TriggerOption {
+	return func(r *TriggerRegistry) { r.queueSize = n }
+}
+
+// TriggerRegistry runs the trigger bindings of a Runtime. Bindings can be
+// registered and unregistered before or after Start. It is safe for
+// concurrent use.
+type TriggerRegistry struct {
+	rt        Runtime
+	onError   func(binding string, err error)
+	queueSize int
+
+	mu      sync.Mutex
+	entries map[string]*triggerEntry
+	ctx     context.Context // set by Start
+	sink    func(*AgentEvent)
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+type triggerEntry struct {
+	binding TriggerBinding
+	queue   chan TriggerFiring
+	cancel  context.CancelFunc
+}
+
+// NewTriggerRegistry creates a TriggerRegistry that runs agents on rt.
+func NewTriggerRegistry(rt Runtime, opts ...TriggerOption) *TriggerRegistry {
+	r := &TriggerRegistry{
+		rt:        rt,
+		onError:   func(string, error) {},
+		queueSize: 8,
+		entries:   make(map[string]*triggerEntry),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.queueSize = max(r.queueSize, 1)
+	return r
+}
+
+// Register adds a binding. If the registry is started, its trigger starts
+// right away.
+func (r *TriggerRegistry) Register(b TriggerBinding) error {
+	if err := b.validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return fmt.Errorf("trigger %s: %w", b.Name, ErrClosed)
+	}
+	if _, ok := r.entries[b.Name]; ok {
+		return fmt.Errorf("trigger %s: already registered", b.Name)
+	}
+	e := &triggerEntry{binding: b, queue: make(chan TriggerFiring, r.queueSize)}
+	r.entries[b.Name] = e
+	if r.ctx != nil {
+		r.start(e)
+	}
+	return nil
+}
+
+// Unregister stops and removes a binding. It reports whether the binding
+// was registered. A firing that is running is canceled.
+func (r *TriggerRegistry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.entries[name]
+	if !ok {
+		return false
+	}
+	if e.cancel != nil {
+		e.cancel()
+	}
+	delete(r.entries, name)
+	return true
+}
+
+// Bindings returns the registered bindings ordered by name.
+func (r *TriggerRegistry) Bindings() []TriggerBinding {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	bindings := make([]TriggerBinding, 0, len(r.entries))
+	for _, e := range r.entries {
+		bindings = append(bindings, e.binding)
+	}
+	slices.SortFunc(bindings, func(a, b TriggerBinding) int { return cmp.Compare(a.Name, b.Name) })
+	return bindings
+}
+
+// Start starts the triggers of all bindings. The events of the agents they
+// run are passed to sink: an EventProactive for every firing, followed by
+// the events of the agent up to the end of its round (EventEOF) or its end
+// (EventClosed). sink is called from one goroutine per binding. Tool calls
+// waiting for approval are rejected, as nobody is there to approve them.
+//
+// The triggers run until ctx is done or Close is called.
+func (r *TriggerRegistry) Start(ctx context.Context, sink func(*AgentEvent)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return ErrClosed
+	}
+	if r.ctx != nil {
+		return fmt.Errorf("agent: triggers already started")
+	}
+	r.ctx = ctx
+	r.sink = sink
+	for _, e := range r.entries {
+		r.start(e)
+	}
+	return nil
+}
+
+// Fire fires a binding by hand, e.g. from an admin API.
+func (r *TriggerRegistry) Fire(name string, payload []byte) error {
+	r.mu.Lock()
+	e, ok := r.entries[name]
+	started := r.ctx != nil
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("trigger %s: not registered", name)
+	}
+	if !started {
+		return fmt.Errorf("trigger %s: triggers not started", name)
+	}
+	return r.enqueue(e, TriggerFiring{Source: TriggerSourceManual, Time: time.Now(), Payload: payload})
+}
+
+// Close stops all triggers and waits for running firings to end.
+func (r *TriggerRegistry) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	for _, e := range r.entries {
+		if e.cancel != nil {
+			e.cancel()
+		}
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+	return nil
+}
+
+// start runs the trigger and the worker of e. r.mu must be held.
+func (r *TriggerRegistry) start(e *triggerEntry) {
+	ctx, cancel := context.WithCancel(r.ctx)
+	e.cancel = cancel
+	name := e.binding.Name
+
+	r.wg.Add(2)
+	go func() {
+		defer r.wg.Done()
+		err := e.binding.Trigger.Run(ctx, func(firing TriggerFiring) {
+			if err := r.enqueue(e, firing); err != nil {
+				r.onError(name, err)
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			r.onError(name, err)
+		}
+	}()
+	go func() {
+		defer r.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case firing := <-e.queue:
+				if err := r.run(ctx, &e.binding, firing); err != nil && ctx.Err() == nil {
+					r.onError(name, err)
+				}
+			}
+		}
+	}()
+}
+
+func (r *TriggerRegistry) enqueue(e *triggerEntry, firing TriggerFiring) error {
+	firing.Binding = e.binding.Name
+	firing.Target = e.binding.Target
+	if firing.Time.IsZero() {
+		firing.Time = time.Now()
+	}
+	select {
+	case e.queue <- firing:
+		return nil
+	default:
+		return fmt.Errorf("trigger %s: %w", e.binding.Name, ErrTriggerBusy)
+	}
+}
+
+// run runs the agent of b for one firing.
+func (r *TriggerRegistry) run(ctx context.Context, b *TriggerBinding, firing TriggerFiring) error {
+	// A restored agent is not closed, which would destroy its state; its
+	// context is canceled instead
+	agentCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		a   Agent
+		err error
+	)
+	if b.StateID != "" {
+		a, err = r.rt.RestoreAgent(agentCtx, b.StateID)
+	} else {
+		a, err = r.newAgent(agentCtx, b.Agent)
+		if a != nil {
+			defer a.Close()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("trigger %s: %w", b.Name, err)
+	}
+
+	r.sink(&AgentEvent{
+		Type:         EventProactive,
+		AgentDef:     a.Def().AgentName(),
+		AgentStateID: a.StateID(),
+		Trigger:      &firing,
+	})
+	if err := a.Input(b.input(firing)); err != nil {
+		return fmt.Errorf("trigger %s: input: %w", b.Name, err)
+	}
+	for {
+		evt, err := a.Next()
+		if err != nil {
+			return fmt.Errorf("trigger %s: %w", b.Name, err)
+		}
+		r.sink(evt)
+		if evt.Type == EventEOF || evt.IsTerminal() {
+			return nil
+		}
+		if evt.Type == EventToolApprovalRequired {
+			if err := rejectUnattended(a, evt); err != nil {
+				return fmt.Errorf("trigger %s: %w", b.Name, err)
+			}
+		}
+	}
+}
+
+// newAgent creates an agent of the definition name.
+func (r *TriggerRegistry) newAgent(ctx context.Context, name string) (Agent, error) {
+	def, err := r.rt.GetAgentDef(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("get agent def %q: %w", name, err)
+	}
+	switch def := def.(type) {
+	case *agentcfg.ReActAgent:
+		return NewReActAgent(ctx, def, r.rt, "")
+	case *agentcfg.MatchAgent:
+		return NewMatchAgent(ctx, def, r.rt, "")
+	default:
+		return nil, fmt.Errorf("unknown agent def type: %T", def)
+	}
+}
+
+// MQTTTrigger fires on MQTT messages whose topic matches one of its topic
+// filters (with the + and # wildcards).
+//
+// With a client, Run subscribes to the filters and fires on the messages
+// the client receives; Run then owns the client's Recv. Without one, the
+// trigger is fed by a broker: set it as (or call it from) the
+// mqtt0.Broker's Handler.
+type MQTTTrigger struct {
+	client  *mqtt0.Client
+	filters []string
+	trie    *mqtt0.Trie[struct{}]
+
+	mu   sync.Mutex
+	fire func(TriggerFiring) // set while Run runs
+}
+
+var _ mqtt0.Handler = (*MQTTTrigger)(nil)
+
+// NewMQTTTrigger creates an MQTTTrigger of the topic filters. client may
+// be nil.
+func NewMQTTTrigger(client *mqtt0.Client, filters ...string) (*MQTTTrigger, error) {
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("mqtt trigger: a topic filter is required")
+	}
+	trie := mqtt0.NewTrie[struct{}]()
+	for _, f := range filters {
+		if err := trie.Insert(f, struct{}{}); err != nil {
+			return nil, fmt.Errorf("mqtt trigger: topic %q: %w", f, err)
+		}
+	}
+	return &MQTTTrigger{client: client, filters: filters, trie: trie}, nil
+}
+
+// Run fires on matching messages until ctx is done.
+func (t *MQTTTrigger) Run(ctx context.Context, fire func(TriggerFiring)) error {
+	t.mu.Lock()
+	t.fire = fire
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		t.fire = nil
+		t.mu.Unlock()
+	}()
+
+	if t.client == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if err := t.client.Subscribe(ctx, t.filters...); err != nil {
+		return fmt.Errorf("mqtt trigger: subscribe: %w", err)
+	}
+	defer t.client.Unsubscribe(context.WithoutCancel(ctx), t.filters...)
+	for {
+		// Recv only notices cancellation between reads
+		recvCtx, cancel := context.WithTimeout(ctx, time.Second)
+		msg, err := t.client.Recv(recvCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("mqtt trigger: %w", err)
+		}
+		t.HandleMessage("", msg)
+	}
+}
+
+// HandleMessage implements mqtt0.Handler. It fires if Run is running and
+// the topic of msg matches.
+func (t *MQTTTrigger) HandleMessage(clientID string, msg *mqtt0.Message) {
+	if len(t.trie.Get(msg.Topic)) == 0 {
+		return
+	}
+	t.mu.Lock()
+	fire := t.fire
+	t.mu.Unlock()
+	if fire != nil {
+		fire(TriggerFiring{Source: TriggerSourceMQTT, Time: time.Now(), Topic: msg.Topic, Payload: msg.Payload})
+	}
+}
+
+// EventBus carries events of the host, such as memory or graph updates, to
+// triggers. The host emits events under dotted names, e.g.
+// "memory.segment.stored" or "graph.relation.added", and EventTriggers
+// select them by path.Match patterns:
+//
+//	bus := agent.NewEventBus()
+//	binding := agent.TriggerBinding{Trigger: bus.Trigger("graph.relation.*"), ...}
+//	...
+//	bus.Emit("graph.relation.added", data)
+type EventBus struct {
+	mu   sync.Mutex
+	subs map[*eventSub]struct{}
+}
+
+type eventSub struct {
+	patterns []string
+	fire     func(TriggerFiring)
+}
+
+// NewEventBus creates an EventBus.
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[*eventSub]struct{})}
+}
+
+// Emit fires the running triggers that match name.
+func (b *EventBus) Emit(name string, payload []byte) {
+	firing := TriggerFiring{Source: TriggerSourceEvent, Time: time.Now(), Topic: name, Payload: payload}
+	b.mu.Lock()
+	var fires []func(TriggerFiring)
+	for sub := range b.subs {
+		if slices.ContainsFunc(sub.patterns, func(p string) bool {
+			ok, _ := path.Match(p, name)
+			return ok
+		}) {
+			fires = append(fires, sub.fire)
+		}
+	}
+	b.mu.Unlock()
+	for _, fire := range fires {
+		fire(firing)
+	}
+}
+
+// Trigger returns a trigger that fires on the events matching any of
+// patterns.
+func (b *EventBus) Trigger(patterns ...string) Trigger {
+	return &eventTrigger{bus: b, patterns: patterns}
+}
+
+type eventTrigger struct {
+	bus      *EventBus
+	patterns []string
+}
+
+func (t *eventTrigger) Run(ctx context.Context, fire func(TriggerFiring)) error {
+	sub := &eventSub{patterns: t.patterns, fire: fire}
+	t.bus.mu.Lock()
+	t.bus.subs[sub] = struct{}{}
+	t.bus.mu.Unlock()
+	defer func() {
+		t.bus.mu.Lock()
+		delete(t.bus.subs, sub)
+		t.bus.mu.Unlock()
+	}()
+	<-ctx.Done()
+	return ctx.Err()
+}
@@ -0,0 +1,341 @@
+package agent_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/agent"
+	"github.com/haivivi/giztoy/go/pkg/genx/playground"
+	"github.com/haivivi/giztoy/go/pkg/mqtt0"
+)
+
+func TestCronTrigger_Next(t *testing.T) {
+	from := time.Date(2025, 3, 14, 10, 30, 15, 0, time.UTC) // a Friday
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 3, 14, 10, 31, 0, 0, time.UTC)},
+		{"0 8 * * *", time.Date(2025, 3, 15, 8, 0, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2025, 3, 14, 10, 40, 0, 0, time.UTC)},
+		{"45 10-12 * * *", time.Date(2025, 3, 14, 10, 45, 0, 0, time.UTC)},
+		{"0 8 * * mon-fri", time.Date(2025, 3, 17, 8, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2025, 3, 16, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan,jul *", time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+		// Either day field matches when both are restricted
+		{"0 0 20 * fri", time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * fri", time.Date(2025, 3, 21, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 3, 14, 11, 0, 0, 0, time.UTC)},
+		{"@every 90s", from.Add(90 * time.Second)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			c, err := agent.NewCronTrigger(tt.spec, time.UTC)
+			if err != nil {
+				t.Fatalf("NewCronTrigger error: %v", err)
+			}
+			if got := c.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronTrigger_Location(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	c, err := agent.NewCronTrigger("0 8 * * *", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := c.Next(time.Date(2025, 3, 14, 0, 30, 0, 0, time.UTC)) // 08:30 in loc
+	if want := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+}
+
+func TestNewCronTrigger_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "* * * foo *", "@every 10ms"} {
+		if _, err := agent.NewCronTrigger(spec, time.UTC); err == nil {
+			t.Errorf("NewCronTrigger(%q): expected error", spec)
+		}
+	}
+}
+
+// inputRecorder records the user input of each generation.
+type inputRecorder struct {
+	*mockReActGenerator
+	mu     sync.Mutex
+	inputs []string
+}
+
+func (g *inputRecorder) GenerateStream(ctx context.Context, model string, mc genx.ModelContext) (genx.Stream, error) {
+	var last []string
+	for msg := range mc.Messages() {
+		if contents, ok := msg.Payload.(genx.Contents); ok && msg.Role == genx.RoleUser {
+			last = last[:0]
+			for _, p := range contents {
+				if text, ok := p.(genx.Text); ok {
+					last = append(last, string(text))
+				}
+			}
+		}
+	}
+	g.mu.Lock()
+	g.inputs = append(g.inputs, strings.Join(last, "|"))
+	g.mu.Unlock()
+	return g.mockReActGenerator.GenerateStream(ctx, model, mc)
+}
+
+// newTriggerRuntime creates a runtime with the assistant agent answering
+// with answers.
+func newTriggerRuntime(t *testing.T, answers ...string) (*playground.Runtime, *inputRecorder) {
+	t.Helper()
+	mock := newMockReActGenerator()
+	for _, answer := range answers {
+		mock.WithTextResponse("test-model", answer)
+	}
+	gen := &inputRecorder{mockReActGenerator: mock}
+	store := playground.NewStore(nil)
+	if err := store.LoadReadonlyLayer("testdata", os.DirFS("testdata/agent_react_test")); err != nil {
+		t.Fatalf("load testdata: %v", err)
+	}
+	rt := playground.NewRuntime(
+		playground.WithStore(store),
+		playground.WithGenerator(gen),
+		playground.WithBuiltinTools(createReActBuiltinTools()...),
+	)
+	t.Cleanup(func() { rt.Close() })
+	return rt, gen
+}
+
+// eventSink collects the events of a TriggerRegistry by firing.
+type eventSink chan *agent.AgentEvent
+
+func (s eventSink) sink(evt *agent.AgentEvent) { s <- evt }
+
+// round returns the events of the next firing, up to EventEOF.
+func (s eventSink) round(t *testing.T) []*agent.AgentEvent {
+	t.Helper()
+	var events []*agent.AgentEvent
+	for {
+		select {
+		case evt := <-s:
+			events = append(events, evt)
+			if evt.Type == agent.EventEOF || evt.IsTerminal() {
+				return events
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out; events so far: %v", eventTypes(events))
+		}
+	}
+}
+
+// manualTrigger fires only through TriggerRegistry.Fire.
+type manualTrigger struct{}
+
+func (manualTrigger) Run(ctx context.Context, fire func(agent.TriggerFiring)) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestTriggerRegistry_Fire(t *testing.T) {
+	rt, gen := newTriggerRuntime(t, "Good morning!")
+	triggers := rt.Triggers()
+	if err := triggers.Register(agent.TriggerBinding{
+		Name:    "morning",
+		Trigger: manualTrigger{},
+		Agent:   "assistant",
+		Target:  "gear-001",
+		Prompt:  "Greet the child.",
+	}); err != nil {
+		t.Fatalf("Register error: %v", err)
+	}
+	if err := triggers.Fire("morning", nil); err == nil {
+		t.Error("Fire before Start: expected error")
+	}
+
+	events := make(eventSink, 16)
+	if err := triggers.Start(context.Background(), events.sink); err != nil {
+		t.Fatalf("Start error: %v", err)
+	}
+	if err := triggers.Fire("morning", []byte("It is sunny.")); err != nil {
+		t.Fatalf("Fire error: %v", err)
+	}
+	round := events.round(t)
+
+	first := round[0]
+	if first.Type != agent.EventProactive || first.AgentDef != "assistant" || first.AgentStateID == "" {
+		t.Fatalf("first event = %+v, want EventProactive of assistant", first)
+	}
+	if f := first.Trigger; f.Binding != "morning" || f.Target != "gear-001" || f.Source != agent.TriggerSourceManual {
+		t.Errorf("firing = %+v", f)
+	}
+	if got := strings.Join(chunkTexts(round), ""); got != "Good morning!" {
+		t.Errorf("answer = %q", got)
+	}
+	if len(gen.inputs) != 1 || gen.inputs[0] != "Greet the child.\n\nIt is sunny." {
+		t.Errorf("inputs = %q, want the prompt followed by the payload", gen.inputs)
+	}
+	if err := triggers.Fire("evening", nil); err == nil {
+		t.Error("Fire of an unknown binding: expected error")
+	}
+}
+
+func TestTriggerRegistry_Register(t *testing.T) {
+	rt, _ := newTriggerRuntime(t)
+	triggers := rt.Triggers()
+	bad := []agent.TriggerBinding{
+		{Trigger: manualTrigger{}, Agent: "assistant"},
+		{Name: "a", Agent: "assistant"},
+		{Name: "a", Trigger: manualTrigger{}},
+		{Name: "a", Trigger: manualTrigger{}, Agent: "assistant", StateID: "s1"},
+	}
+	for _, b := range bad {
+		if err := triggers.Register(b); err == nil {
+			t.Errorf("Register(%+v): expected error", b)
+		}
+	}
+	ok := agent.TriggerBinding{Name: "b", Trigger: manualTrigger{}, Agent: "assistant"}
+	if err := triggers.Register(ok); err != nil {
+		t.Fatalf("Register error: %v", err)
+	}
+	if err := triggers.Register(ok); err == nil {
+		t.Error("Register of a duplicate name: expected error")
+	}
+	ok.Name = "a"
+	triggers.Register(ok)
+	if bindings := triggers.Bindings(); len(bindings) != 2 || bindings[0].Name != "a" || bindings[1].Name != "b" {
+		t.Errorf("Bindings() = %+v", bindings)
+	}
+	if !triggers.Unregister("a") || triggers.Unregister("a") {
+		t.Error("Unregister: want true, then false")
+	}
+	if err := triggers.Start(context.Background(), func(*agent.AgentEvent) {}); err != nil {
+		t.Fatal(err)
+	}
+	if err := triggers.Start(context.Background(), func(*agent.AgentEvent) {}); err == nil {
+		t.Error("second Start: expected error")
+	}
+	triggers.Close()
+	if err := triggers.Register(ok); !errors.Is(err, agent.ErrClosed) {
+		t.Errorf("Register after Close error = %v, want ErrClosed", err)
+	}
+}
+
+func TestTriggerRegistry_Busy(t *testing.T) {
+	rt, _ := newTriggerRuntime(t, "one", "two")
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	triggers := agent.NewTriggerRegistry(rt,
+		agent.WithTriggerQueueSize(1),
+		agent.WithTriggerErrorHandler(func(binding string, err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}),
+	)
+	defer triggers.Close()
+	triggers.Register(agent.TriggerBinding{Name: "poke", Trigger: manualTrigger{}, Agent: "assistant"})
+
+	started := make(chan struct{}, 4)
+	release := make(chan struct{})
+	events := make(eventSink, 16)
+	triggers.Start(context.Background(), func(evt *agent.AgentEvent) {
+		if evt.Type == agent.EventProactive {
+			started <- struct{}{}
+			<-release
+		}
+		events.sink(evt)
+	})
+
+	triggers.Fire("poke", nil)
+	<-started // the first firing runs
+	if err := triggers.Fire("poke", nil); err != nil {
+		t.Fatalf("second Fire error: %v", err)
+	}
+	if err := triggers.Fire("poke", nil); !errors.Is(err, agent.ErrTriggerBusy) {
+		t.Fatalf("third Fire error = %v, want ErrTriggerBusy", err)
+	}
+	close(release)
+	if got := strings.Join(chunkTexts(events.round(t)), ""); got != "one" {
+		t.Errorf("first answer = %q", got)
+	}
+	if got := strings.Join(chunkTexts(events.round(t)), ""); got != "two" {
+		t.Errorf("queued answer = %q", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 0 {
+		t.Errorf("errors = %v", errs)
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	rt, gen := newTriggerRuntime(t, "Noted.")
+	bus := agent.NewEventBus()
+	triggers := rt.Triggers()
+	triggers.Register(agent.TriggerBinding{
+		Name:    "relations",
+		Trigger: bus.Trigger("graph.relation.*"),
+		Agent:   "assistant",
+	})
+	events := make(eventSink, 16)
+	triggers.Start(context.Background(), events.sink)
+
+	// Emit until the trigger has subscribed; other events never fire
+	deadline := time.Now().Add(5 * time.Second)
+	for len(events) == 0 && time.Now().Before(deadline) {
+		bus.Emit("memory.segment.stored", []byte("ignored"))
+		bus.Emit("graph.relation.added", []byte("Mimi is a cat."))
+		time.Sleep(10 * time.Millisecond)
+	}
+	round := events.round(t)
+	if f := round[0].Trigger; f == nil || f.Source != agent.TriggerSourceEvent || f.Topic != "graph.relation.added" {
+		t.Fatalf("first event = %+v", round[0])
+	}
+	if gen.inputs[0] != "Mimi is a cat." {
+		t.Errorf("input = %q, want the payload", gen.inputs[0])
+	}
+}
+
+func TestMQTTTrigger_Handler(t *testing.T) {
+	if _, err := agent.NewMQTTTrigger(nil); err == nil {
+		t.Error("NewMQTTTrigger without topics: expected error")
+	}
+	trigger, err := agent.NewMQTTTrigger(nil, "device/+/button", "alarm/#")
+	if err != nil {
+		t.Fatalf("NewMQTTTrigger error: %v", err)
+	}
+	// Not running: messages are dropped
+	trigger.HandleMessage("gear-001", &mqtt0.Message{Topic: "alarm/fire"})
+
+	firings := make(chan agent.TriggerFiring, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- trigger.Run(ctx, func(f agent.TriggerFiring) { firings <- f }) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(firings) == 0 && time.Now().Before(deadline) {
+		trigger.HandleMessage("gear-001", &mqtt0.Message{Topic: "device/gear-001/status"})
+		trigger.HandleMessage("gear-001", &mqtt0.Message{Topic: "device/gear-001/button", Payload: []byte("pressed")})
+		time.Sleep(10 * time.Millisecond)
+	}
+	f := <-firings
+	if f.Source != agent.TriggerSourceMQTT || f.Topic != "device/gear-001/button" || string(f.Payload) != "pressed" {
+		t.Errorf("firing = %+v", f)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run error = %v, want context.Canceled", err)
+	}
+}
@@ -24,15 +24,106 @@ type AgentBase struct {
 	Prompt        string         `json:"prompt,omitzero" msgpack:"prompt,omitempty"`
 	ContextLayers []ContextLayer `json:"context_layers,omitzero" msgpack:"context_layers,omitempty"`
 	Generator     GeneratorRef   `json:"generator,omitzero" msgpack:"generator,omitempty"`
+	Trace         bool           `json:"trace,omitzero" msgpack:"trace,omitempty"` // record decision traces
 }
 
 // ReActAgent is the definition of a ReAct agent.
 //
 // Validation:
 //   - Inherits AgentBase validation (Name required)
+//   - Limits: validated via RoundLimits unmarshal
+//   - Style: validated via OutputStyle unmarshal
 type ReActAgent struct {
 	AgentBase `msgpack:",inline"`
-	Tools     []ToolRef `json:"tools,omitzero" msgpack:"tools,omitempty"`
+	Tools     []ToolRef    `json:"tools,omitzero" msgpack:"tools,omitempty"`
+	Limits    *RoundLimits `json:"limits,omitzero" msgpack:"limits,omitempty"` // per-round budget
+	Style     *OutputStyle `json:"style,omitzero" msgpack:"style,omitempty"`   // persona constraints on the output
+}
+
+// RoundLimits caps the work of a ReAct agent in one round, from an input to
+// its final answer, so a model stuck in a tool loop cannot run up cost or
+// keep a child waiting. Limits are checked between the steps of a round.
+//
+// Validation:
+//   - MaxToolCalls, MaxTokens, Timeout: must not be negative
+//   - OnLimit: validated via RoundLimitAction unmarshal
+type RoundLimits struct {
+	MaxToolCalls  int              `json:"max_tool_calls,omitzero" msgpack:"max_tool_calls,omitempty"` // tool calls per round (0 = unlimited)
+	MaxTokens     int64            `json:"max_tokens,omitzero" msgpack:"max_tokens,omitempty"`         // generated tokens per round (0 = unlimited)
+	Timeout       float64          `json:"timeout,omitzero" msgpack:"timeout,omitempty"`               // wall-clock seconds per round (0 = unlimited)
+	OnLimit       RoundLimitAction `json:"on_limit,omitzero" msgpack:"on_limit,omitempty"`             // summarize (default), stop or error
+	SummaryPrompt string           `json:"summary_prompt,omitzero" msgpack:"summary_prompt,omitempty"` // instruction for the final answer (summarize only)
+}
+
+// validate checks if the RoundLimits fields are valid.
+func (l *RoundLimits) validate() error {
+	if l.MaxToolCalls < 0 {
+		return fmt.Errorf("limits: max_tool_calls must not be negative")
+	}
+	if l.MaxTokens < 0 {
+		return fmt.Errorf("limits: max_tokens must not be negative")
+	}
+	if l.Timeout < 0 {
+		return fmt.Errorf("limits: timeout must not be negative")
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler with validation.
+func (l *RoundLimits) UnmarshalJSON(data []byte) error {
+	type Alias RoundLimits
+	var alias Alias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+	*l = RoundLimits(alias)
+	return l.validate()
+}
+
+// OutputStyle constrains the output of a ReAct agent to its persona, e.g.
+// short sentences and simple words for young children. It is enforced on
+// the output a sentence at a time, before it reaches TTS, by rules and
+// optionally a small rewrite model, which is cheaper than prompting the
+// agent's model again.
+//
+// Validation:
+//   - MaxSentenceLength: must not be negative
+//   - BannedPhrases, Replacements: keys must not be empty
+type OutputStyle struct {
+	MaxSentenceLength int               `json:"max_sentence_length,omitzero" msgpack:"max_sentence_length,omitempty"` // characters per sentence (0 = unlimited)
+	BannedPhrases     []string          `json:"banned_phrases,omitzero" msgpack:"banned_phrases,omitempty"`           // removed from the output, ignoring case
+	Replacements      map[string]string `json:"replacements,omitzero" msgpack:"replacements,omitempty"`               // words replaced, e.g. by simpler ones
+	Vocabulary        string            `json:"vocabulary,omitzero" msgpack:"vocabulary,omitempty"`                   // vocabulary level for the rewrite model, e.g. "words a 5-year-old knows"
+	RewriteModel      string            `json:"rewrite_model,omitzero" msgpack:"rewrite_model,omitempty"`             // small model rewriting sentences that break the style
+}
+
+// validate checks if the OutputStyle fields are valid.
+func (s *OutputStyle) validate() error {
+	if s.MaxSentenceLength < 0 {
+		return fmt.Errorf("style: max_sentence_length must not be negative")
+	}
+	for _, p := range s.BannedPhrases {
+		if p == "" {
+			return fmt.Errorf("style: empty banned phrase")
+		}
+	}
+	for k := range s.Replacements {
+		if k == "" {
+			return fmt.Errorf("style: empty replacement key")
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler with validation.
+func (s *OutputStyle) UnmarshalJSON(data []byte) error {
+	type Alias OutputStyle
+	var alias Alias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+	*s = OutputStyle(alias)
+	return s.validate()
 }
 
 // AgentName returns the agent name.
@@ -70,11 +161,57 @@ func (d *ReActAgent) UnmarshalJSON(data []byte) error {
 // Validation:
 //   - Inherits AgentBase validation (Name required)
 //   - Route: each MatchRoute must have non-empty Rules and a valid Agent
+//   - Confidence: validated via MatchConfidence unmarshal
 type MatchAgent struct {
-	AgentBase `msgpack:",inline"`
-	Rules     []RuleRef    `json:"rules,omitzero" msgpack:"rules,omitempty"`
-	Route     []MatchRoute `json:"route,omitzero" msgpack:"route,omitempty"`
-	Default   *AgentRef    `json:"default,omitzero" msgpack:"default,omitempty"` // Agent to use when no rules match
+	AgentBase  `msgpack:",inline"`
+	Rules      []RuleRef        `json:"rules,omitzero" msgpack:"rules,omitempty"`
+	Route      []MatchRoute     `json:"route,omitzero" msgpack:"route,omitempty"`
+	Default    *AgentRef        `json:"default,omitzero" msgpack:"default,omitempty"`       // Agent to use when no rules match
+	Confidence *MatchConfidence `json:"confidence,omitzero" msgpack:"confidence,omitempty"` // local matching before the LLM
+}
+
+// DefaultMatchThreshold is the MatchConfidence threshold used when none is
+// set.
+const DefaultMatchThreshold = 0.8
+
+// MatchConfidence makes a match agent score the input against the patterns
+// and examples of its rules itself, without a model. The best rule is taken
+// if it scores at least Threshold; otherwise the agent falls back to the
+// generator (an LLM classifier over the same rules), or to no match.
+//
+// Validation:
+//   - Threshold: between 0 and 1
+//   - Fallback: validated via MatchFallback unmarshal
+type MatchConfidence struct {
+	Threshold float64       `json:"threshold,omitzero" msgpack:"threshold,omitempty"` // minimum score, 0-1 (default 0.8)
+	Fallback  MatchFallback `json:"fallback,omitzero" msgpack:"fallback,omitempty"`   // llm (default) or none
+}
+
+// EffectiveThreshold returns Threshold, or DefaultMatchThreshold if unset.
+func (c *MatchConfidence) EffectiveThreshold() float64 {
+	if c.Threshold == 0 {
+		return DefaultMatchThreshold
+	}
+	return c.Threshold
+}
+
+// validate checks if the MatchConfidence fields are valid.
+func (c *MatchConfidence) validate() error {
+	if c.Threshold < 0 || c.Threshold > 1 {
+		return fmt.Errorf("confidence: threshold must be between 0 and 1")
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler with validation.
+func (c *MatchConfidence) UnmarshalJSON(data []byte) error {
+	type Alias MatchConfidence
+	var alias Alias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+	*c = MatchConfidence(alias)
+	return c.validate()
 }
 
 // AgentName returns the agent name.
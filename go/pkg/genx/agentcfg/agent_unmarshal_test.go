@@ -3,6 +3,7 @@ package agentcfg
 import (
 	"encoding/json"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 
@@ -201,6 +202,175 @@ func TestAgentRef_UnmarshalJSON(t *testing.T) {
 	}
 }
 
+func TestUnmarshalAgent_ReActLimits(t *testing.T) {
+	want := RoundLimits{
+		MaxToolCalls:  5,
+		MaxTokens:     4000,
+		Timeout:       30,
+		OnLimit:       RoundLimitSummarize,
+		SummaryPrompt: "Answer with what you found so far.",
+	}
+	for _, path := range []string{"testdata/agent/react_limited.json", "testdata/agent/react_limited.yaml"} {
+		t.Run(path, func(t *testing.T) {
+			var data []byte
+			if strings.HasSuffix(path, ".yaml") {
+				data = loadYAMLAgentFile(t, path)
+			} else {
+				data = loadTestFile(t, path)
+			}
+
+			agent, err := UnmarshalAgent(data)
+			if err != nil {
+				t.Fatalf("UnmarshalAgent: %v", err)
+			}
+			react := AsReActAgent(agent)
+			if react == nil || react.Limits == nil {
+				t.Fatalf("Limits not set: %+v", agent)
+			}
+			if *react.Limits != want {
+				t.Errorf("Limits = %+v, want %+v", *react.Limits, want)
+			}
+		})
+	}
+
+	data := loadTestFile(t, "testdata/agent/react_minimal.json")
+	agent, err := UnmarshalAgent(data)
+	if err != nil {
+		t.Fatalf("UnmarshalAgent: %v", err)
+	}
+	if limits := AsReActAgent(agent).Limits; limits != nil {
+		t.Errorf("Limits = %+v, want nil", limits)
+	}
+}
+
+func TestUnmarshalAgent_Error_NegativeLimits(t *testing.T) {
+	data := loadTestFile(t, "testdata/error/agent_negative_limits.json")
+
+	_, err := UnmarshalAgent(data)
+	if err == nil {
+		t.Fatal("expected error for negative max_tool_calls")
+	}
+	if !strings.Contains(err.Error(), "max_tool_calls must not be negative") {
+		t.Errorf("error = %q, want containing %q", err.Error(), "max_tool_calls must not be negative")
+	}
+}
+
+func TestUnmarshalAgent_ReActStyle(t *testing.T) {
+	for _, path := range []string{"testdata/agent/react_styled.json", "testdata/agent/react_styled.yaml"} {
+		t.Run(path, func(t *testing.T) {
+			var data []byte
+			if strings.HasSuffix(path, ".yaml") {
+				data = loadYAMLAgentFile(t, path)
+			} else {
+				data = loadTestFile(t, path)
+			}
+
+			agent, err := UnmarshalAgent(data)
+			if err != nil {
+				t.Fatalf("UnmarshalAgent: %v", err)
+			}
+			react := AsReActAgent(agent)
+			if react == nil || react.Style == nil {
+				t.Fatalf("Style not set: %+v", agent)
+			}
+			style := react.Style
+			if style.MaxSentenceLength != 40 || style.RewriteModel != "qwen-flash" || style.Vocabulary != "words a 5-year-old knows" {
+				t.Errorf("Style = %+v", *style)
+			}
+			if !slices.Equal(style.BannedPhrases, []string{"as an AI"}) {
+				t.Errorf("BannedPhrases = %v", style.BannedPhrases)
+			}
+			if style.Replacements["approximately"] != "about" {
+				t.Errorf("Replacements = %v", style.Replacements)
+			}
+		})
+	}
+}
+
+func TestUnmarshalAgent_Error_NegativeStyle(t *testing.T) {
+	data := loadTestFile(t, "testdata/error/agent_negative_style.json")
+
+	_, err := UnmarshalAgent(data)
+	if err == nil {
+		t.Fatal("expected error for negative max_sentence_length")
+	}
+	if !strings.Contains(err.Error(), "max_sentence_length must not be negative") {
+		t.Errorf("error = %q, want containing %q", err.Error(), "max_sentence_length must not be negative")
+	}
+}
+
+func TestUnmarshalAgent_MatchConfidence(t *testing.T) {
+	want := MatchConfidence{Threshold: 0.9, Fallback: MatchFallbackNone}
+	for _, path := range []string{"testdata/agent/match_confident.json", "testdata/agent/match_confident.yaml"} {
+		t.Run(path, func(t *testing.T) {
+			var data []byte
+			if strings.HasSuffix(path, ".yaml") {
+				data = loadYAMLAgentFile(t, path)
+			} else {
+				data = loadTestFile(t, path)
+			}
+
+			agent, err := UnmarshalAgent(data)
+			if err != nil {
+				t.Fatalf("UnmarshalAgent: %v", err)
+			}
+			m := AsMatchAgent(agent)
+			if m == nil || m.Confidence == nil {
+				t.Fatalf("Confidence not set: %+v", agent)
+			}
+			if *m.Confidence != want {
+				t.Errorf("Confidence = %+v, want %+v", *m.Confidence, want)
+			}
+		})
+	}
+
+	if got := (&MatchConfidence{}).EffectiveThreshold(); got != DefaultMatchThreshold {
+		t.Errorf("EffectiveThreshold() = %v, want %v", got, DefaultMatchThreshold)
+	}
+}
+
+func TestUnmarshalAgent_Error_MatchThreshold(t *testing.T) {
+	data := loadTestFile(t, "testdata/error/agent_match_threshold.json")
+
+	_, err := UnmarshalAgent(data)
+	if err == nil {
+		t.Fatal("expected error for threshold above 1")
+	}
+	if !strings.Contains(err.Error(), "threshold must be between 0 and 1") {
+		t.Errorf("error = %q, want containing %q", err.Error(), "threshold must be between 0 and 1")
+	}
+}
+
+func TestUnmarshalAgent_Trace(t *testing.T) {
+	for _, path := range []string{"testdata/agent/react_traced.json", "testdata/agent/react_traced.yaml"} {
+		t.Run(path, func(t *testing.T) {
+			var data []byte
+			if strings.HasSuffix(path, ".yaml") {
+				data = loadYAMLAgentFile(t, path)
+			} else {
+				data = loadTestFile(t, path)
+			}
+
+			agent, err := UnmarshalAgent(data)
+			if err != nil {
+				t.Fatalf("UnmarshalAgent: %v", err)
+			}
+			if react := AsReActAgent(agent); react == nil || !react.Trace {
+				t.Errorf("Trace not set: %+v", agent)
+			}
+		})
+	}
+
+	data := loadTestFile(t, "testdata/agent/react_minimal.json")
+	agent, err := UnmarshalAgent(data)
+	if err != nil {
+		t.Fatalf("UnmarshalAgent: %v", err)
+	}
+	if AsReActAgent(agent).Trace {
+		t.Error("Trace = true, want false by default")
+	}
+}
+
 // ========== YAML Tests ==========
 
 // loadYAMLAgentFile loads a YAML file and converts to JSON for UnmarshalAgent.
@@ -378,6 +548,29 @@ func TestReActAgent_MsgpackRoundtrip(t *testing.T) {
 	}
 }
 
+func TestReActAgent_MsgpackRoundtrip_Limits(t *testing.T) {
+	data := loadTestFile(t, "testdata/agent/react_limited.json")
+
+	agent, err := UnmarshalAgent(data)
+	if err != nil {
+		t.Fatalf("UnmarshalAgent: %v", err)
+	}
+	original := AsReActAgent(agent)
+
+	packed, err := msgpack.Marshal(original)
+	if err != nil {
+		t.Fatalf("MsgPack Marshal: %v", err)
+	}
+
+	var decoded ReActAgent
+	if err := msgpack.Unmarshal(packed, &decoded); err != nil {
+		t.Fatalf("MsgPack Unmarshal: %v", err)
+	}
+	if decoded.Limits == nil || *decoded.Limits != *original.Limits {
+		t.Errorf("Limits = %+v, want %+v", decoded.Limits, *original.Limits)
+	}
+}
+
 func TestMatchAgent_MsgpackRoundtrip(t *testing.T) {
 	data := loadTestFile(t, "testdata/agent/match_router.json")
 
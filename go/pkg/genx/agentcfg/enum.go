@@ -17,6 +17,10 @@ const (
 	ToolTypeGenerator     ToolType = "generator"      // single-round LLM generation tool
 	ToolTypeComposite     ToolType = "composite"      // sequential tool composition
 	ToolTypeTextProcessor ToolType = "text_processor" // text processor tool
+	ToolTypeDocuments     ToolType = "documents"      // knowledge-base search tool
+	ToolTypeAgent         ToolType = "agent"          // sub-agent delegation tool
+	ToolTypeMCP           ToolType = "mcp"            // MCP server tool
+	ToolTypeRetrieval     ToolType = "retrieval"      // memory recall tool
 )
 
 var validToolTypes = map[string]struct{}{
@@ -25,6 +29,10 @@ var validToolTypes = map[string]struct{}{
 	string(ToolTypeGenerator):     {},
 	string(ToolTypeComposite):     {},
 	string(ToolTypeTextProcessor): {},
+	string(ToolTypeDocuments):     {},
+	string(ToolTypeAgent):         {},
+	string(ToolTypeMCP):           {},
+	string(ToolTypeRetrieval):     {},
 }
 
 // IsValid returns true if the tool type is valid.
@@ -315,6 +323,57 @@ func (m *TextProcessorOutputMode) UnmarshalMsgpack(data []byte) error {
 	return nil
 }
 
+// AgentToolOutput defines what an agent tool returns.
+type AgentToolOutput string
+
+// Agent tool output constants.
+const (
+	AgentToolOutputResponse AgentToolOutput = "response" // the sub-agent's answer (default)
+	AgentToolOutputHistory  AgentToolOutput = "history"  // the sub-agent's whole conversation
+)
+
+var validAgentToolOutputs = map[string]struct{}{
+	string(AgentToolOutputResponse): {},
+	string(AgentToolOutputHistory):  {},
+}
+
+// IsValid returns true if the output is valid.
+func (o AgentToolOutput) IsValid() bool {
+	if o == "" {
+		return true // empty defaults to response
+	}
+	_, ok := validAgentToolOutputs[string(o)]
+	return ok
+}
+
+// UnmarshalJSON implements json.Unmarshaler with validation.
+func (o *AgentToolOutput) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	ao := AgentToolOutput(s)
+	if !ao.IsValid() {
+		return fmt.Errorf("invalid agent tool output: %q (must be %q or %q)", s, AgentToolOutputResponse, AgentToolOutputHistory)
+	}
+	*o = ao
+	return nil
+}
+
+// UnmarshalMsgpack implements msgpack.Unmarshaler with validation.
+func (o *AgentToolOutput) UnmarshalMsgpack(data []byte) error {
+	var s string
+	if err := msgpack.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	ao := AgentToolOutput(s)
+	if !ao.IsValid() {
+		return fmt.Errorf("invalid agent tool output: %q (must be %q or %q)", s, AgentToolOutputResponse, AgentToolOutputHistory)
+	}
+	*o = ao
+	return nil
+}
+
 // HTTPMethod defines the HTTP method for HTTP tools.
 type HTTPMethod string
 
@@ -465,3 +524,218 @@ func (m *CompositeMode) UnmarshalMsgpack(data []byte) error {
 	*m = cm
 	return nil
 }
+
+// ToolLimitAction defines what happens to a tool call that exceeds the
+// tool's limits.
+type ToolLimitAction string
+
+// Tool limit action constants.
+const (
+	ToolLimitQueue  ToolLimitAction = "queue"  // wait for capacity (default)
+	ToolLimitReject ToolLimitAction = "reject" // fail the call immediately
+)
+
+var validToolLimitActions = map[string]struct{}{
+	string(ToolLimitQueue):  {},
+	string(ToolLimitReject): {},
+}
+
+// IsValid returns true if the tool limit action is valid.
+func (a ToolLimitAction) IsValid() bool {
+	if a == "" {
+		return true // empty defaults to queue
+	}
+	_, ok := validToolLimitActions[string(a)]
+	return ok
+}
+
+// UnmarshalJSON implements json.Unmarshaler with validation.
+func (a *ToolLimitAction) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	la := ToolLimitAction(s)
+	if !la.IsValid() {
+		return fmt.Errorf("invalid tool limit action: %q (must be %q or %q)", s, ToolLimitQueue, ToolLimitReject)
+	}
+	*a = la
+	return nil
+}
+
+// UnmarshalMsgpack implements msgpack.Unmarshaler with validation.
+func (a *ToolLimitAction) UnmarshalMsgpack(data []byte) error {
+	var s string
+	if err := msgpack.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	la := ToolLimitAction(s)
+	if !la.IsValid() {
+		return fmt.Errorf("invalid tool limit action: %q (must be %q or %q)", s, ToolLimitQueue, ToolLimitReject)
+	}
+	*a = la
+	return nil
+}
+
+// ToolApproval defines whether the calls of a tool wait for a human to
+// approve them, e.g. a parent app gating device control or purchases.
+type ToolApproval string
+
+// Tool approval constants.
+const (
+	ToolApprovalNone     ToolApproval = "none"     // calls run immediately (default)
+	ToolApprovalRequired ToolApproval = "required" // calls wait for approval
+)
+
+var validToolApprovals = map[string]struct{}{
+	string(ToolApprovalNone):     {},
+	string(ToolApprovalRequired): {},
+}
+
+// IsValid returns true if the tool approval is valid.
+func (a ToolApproval) IsValid() bool {
+	if a == "" {
+		return true // empty defaults to none
+	}
+	_, ok := validToolApprovals[string(a)]
+	return ok
+}
+
+// IsRequired returns true if the calls of the tool wait for approval.
+func (a ToolApproval) IsRequired() bool {
+	return a == ToolApprovalRequired
+}
+
+// UnmarshalJSON implements json.Unmarshaler with validation.
+func (a *ToolApproval) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	ta := ToolApproval(s)
+	if !ta.IsValid() {
+		return fmt.Errorf("invalid tool approval: %q (must be %q or %q)", s, ToolApprovalNone, ToolApprovalRequired)
+	}
+	*a = ta
+	return nil
+}
+
+// UnmarshalMsgpack implements msgpack.Unmarshaler with validation.
+func (a *ToolApproval) UnmarshalMsgpack(data []byte) error {
+	var s string
+	if err := msgpack.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	ta := ToolApproval(s)
+	if !ta.IsValid() {
+		return fmt.Errorf("invalid tool approval: %q (must be %q or %q)", s, ToolApprovalNone, ToolApprovalRequired)
+	}
+	*a = ta
+	return nil
+}
+
+// MatchFallback defines what a match agent with a MatchConfidence does when
+// no rule scores at least the threshold.
+type MatchFallback string
+
+// Match fallback constants.
+const (
+	MatchFallbackLLM  MatchFallback = "llm"  // ask the generator to match the input (default)
+	MatchFallbackNone MatchFallback = "none" // treat the input as matching no rule
+)
+
+var validMatchFallbacks = map[string]struct{}{
+	string(MatchFallbackLLM):  {},
+	string(MatchFallbackNone): {},
+}
+
+// IsValid returns true if the match fallback is valid.
+func (f MatchFallback) IsValid() bool {
+	if f == "" {
+		return true // empty defaults to llm
+	}
+	_, ok := validMatchFallbacks[string(f)]
+	return ok
+}
+
+// UnmarshalJSON implements json.Unmarshaler with validation.
+func (f *MatchFallback) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	mf := MatchFallback(s)
+	if !mf.IsValid() {
+		return fmt.Errorf("invalid match fallback: %q (must be %q or %q)", s, MatchFallbackLLM, MatchFallbackNone)
+	}
+	*f = mf
+	return nil
+}
+
+// UnmarshalMsgpack implements msgpack.Unmarshaler with validation.
+func (f *MatchFallback) UnmarshalMsgpack(data []byte) error {
+	var s string
+	if err := msgpack.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	mf := MatchFallback(s)
+	if !mf.IsValid() {
+		return fmt.Errorf("invalid match fallback: %q (must be %q or %q)", s, MatchFallbackLLM, MatchFallbackNone)
+	}
+	*f = mf
+	return nil
+}
+
+// RoundLimitAction defines what a ReAct agent does when a round reaches
+// one of its RoundLimits.
+type RoundLimitAction string
+
+// Round limit action constants.
+const (
+	RoundLimitSummarize RoundLimitAction = "summarize" // answer once more without tools (default)
+	RoundLimitStop      RoundLimitAction = "stop"      // end the round without an answer
+	RoundLimitError     RoundLimitAction = "error"     // fail the round with an error
+)
+
+var validRoundLimitActions = map[string]struct{}{
+	string(RoundLimitSummarize): {},
+	string(RoundLimitStop):      {},
+	string(RoundLimitError):     {},
+}
+
+// IsValid returns true if the round limit action is valid.
+func (a RoundLimitAction) IsValid() bool {
+	if a == "" {
+		return true // empty defaults to summarize
+	}
+	_, ok := validRoundLimitActions[string(a)]
+	return ok
+}
+
+// UnmarshalJSON implements json.Unmarshaler with validation.
+func (a *RoundLimitAction) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	la := RoundLimitAction(s)
+	if !la.IsValid() {
+		return fmt.Errorf("invalid round limit action: %q (must be %q, %q or %q)", s, RoundLimitSummarize, RoundLimitStop, RoundLimitError)
+	}
+	*a = la
+	return nil
+}
+
+// UnmarshalMsgpack implements msgpack.Unmarshaler with validation.
+func (a *RoundLimitAction) UnmarshalMsgpack(data []byte) error {
+	var s string
+	if err := msgpack.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	la := RoundLimitAction(s)
+	if !la.IsValid() {
+		return fmt.Errorf("invalid round limit action: %q (must be %q, %q or %q)", s, RoundLimitSummarize, RoundLimitStop, RoundLimitError)
+	}
+	*a = la
+	return nil
+}
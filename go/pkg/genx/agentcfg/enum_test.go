@@ -11,7 +11,7 @@ import (
 // ========== ToolType Tests ==========
 
 func TestToolType_IsValid(t *testing.T) {
-	valid := []ToolType{"", ToolTypeBuiltIn, ToolTypeHTTP, ToolTypeGenerator, ToolTypeComposite, ToolTypeTextProcessor}
+	valid := []ToolType{"", ToolTypeBuiltIn, ToolTypeHTTP, ToolTypeGenerator, ToolTypeComposite, ToolTypeTextProcessor, ToolTypeDocuments, ToolTypeAgent, ToolTypeMCP, ToolTypeRetrieval}
 	for _, v := range valid {
 		if !v.IsValid() {
 			t.Errorf("ToolType(%q).IsValid() = false, want true", v)
@@ -531,3 +531,143 @@ func TestCompositeMode_UnmarshalMsgpack_InvalidData(t *testing.T) {
 		t.Fatal("expected error, got nil")
 	}
 }
+
+// ========== ToolLimitAction Tests ==========
+
+func TestToolLimitAction_IsValid(t *testing.T) {
+	valid := []ToolLimitAction{"", ToolLimitQueue, ToolLimitReject}
+	for _, v := range valid {
+		if !v.IsValid() {
+			t.Errorf("ToolLimitAction(%q).IsValid() = false, want true", v)
+		}
+	}
+
+	invalid := []ToolLimitAction{"drop", "wait", "foo"}
+	for _, v := range invalid {
+		if v.IsValid() {
+			t.Errorf("ToolLimitAction(%q).IsValid() = true, want false", v)
+		}
+	}
+}
+
+func TestToolLimitAction_UnmarshalJSON_Invalid(t *testing.T) {
+	var a ToolLimitAction
+	err := json.Unmarshal([]byte(`"drop"`), &a)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "invalid tool limit action") {
+		t.Errorf("error = %q, want contains 'invalid tool limit action'", err.Error())
+	}
+}
+
+// ========== ToolApproval Tests ==========
+
+func TestToolApproval_IsValid(t *testing.T) {
+	valid := []ToolApproval{"", ToolApprovalNone, ToolApprovalRequired}
+	for _, v := range valid {
+		if !v.IsValid() {
+			t.Errorf("ToolApproval(%q).IsValid() = false, want true", v)
+		}
+	}
+
+	invalid := []ToolApproval{"always", "optional", "foo"}
+	for _, v := range invalid {
+		if v.IsValid() {
+			t.Errorf("ToolApproval(%q).IsValid() = true, want false", v)
+		}
+	}
+}
+
+func TestToolApproval_UnmarshalJSON_Invalid(t *testing.T) {
+	var a ToolApproval
+	err := json.Unmarshal([]byte(`"always"`), &a)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "invalid tool approval") {
+		t.Errorf("error = %q, want contains 'invalid tool approval'", err.Error())
+	}
+}
+
+func TestToolApproval_UnmarshalMsgpack_Invalid(t *testing.T) {
+	data, _ := msgpack.Marshal("always")
+	var a ToolApproval
+	err := msgpack.Unmarshal(data, &a)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "invalid tool approval") {
+		t.Errorf("error = %q, want contains 'invalid tool approval'", err.Error())
+	}
+}
+
+// ========== MatchFallback Tests ==========
+
+func TestMatchFallback_IsValid(t *testing.T) {
+	valid := []MatchFallback{"", MatchFallbackLLM, MatchFallbackNone}
+	for _, v := range valid {
+		if !v.IsValid() {
+			t.Errorf("MatchFallback(%q).IsValid() = false, want true", v)
+		}
+	}
+
+	invalid := []MatchFallback{"default", "react", "foo"}
+	for _, v := range invalid {
+		if v.IsValid() {
+			t.Errorf("MatchFallback(%q).IsValid() = true, want false", v)
+		}
+	}
+}
+
+func TestMatchFallback_UnmarshalJSON_Invalid(t *testing.T) {
+	var f MatchFallback
+	err := json.Unmarshal([]byte(`"react"`), &f)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "invalid match fallback") {
+		t.Errorf("error = %q, want contains 'invalid match fallback'", err.Error())
+	}
+}
+
+func TestMatchFallback_UnmarshalMsgpack_Invalid(t *testing.T) {
+	data, _ := msgpack.Marshal("react")
+	var f MatchFallback
+	err := msgpack.Unmarshal(data, &f)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "invalid match fallback") {
+		t.Errorf("error = %q, want contains 'invalid match fallback'", err.Error())
+	}
+}
+
+// ========== RoundLimitAction Tests ==========
+
+func TestRoundLimitAction_IsValid(t *testing.T) {
+	valid := []RoundLimitAction{"", RoundLimitSummarize, RoundLimitStop, RoundLimitError}
+	for _, v := range valid {
+		if !v.IsValid() {
+			t.Errorf("RoundLimitAction(%q).IsValid() = false, want true", v)
+		}
+	}
+
+	invalid := []RoundLimitAction{"queue", "retry", "foo"}
+	for _, v := range invalid {
+		if v.IsValid() {
+			t.Errorf("RoundLimitAction(%q).IsValid() = true, want false", v)
+		}
+	}
+}
+
+func TestRoundLimitAction_UnmarshalJSON_Invalid(t *testing.T) {
+	var a RoundLimitAction
+	err := json.Unmarshal([]byte(`"retry"`), &a)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "invalid round limit action") {
+		t.Errorf("error = %q, want contains 'invalid round limit action'", err.Error())
+	}
+}
@@ -12,6 +12,8 @@ type Tool interface {
 	ToolName() string
 	ToolDescription() string
 	ToolType() ToolType
+	ToolLimits() *ToolLimits
+	ToolApproval() ToolApproval
 }
 
 // ToolBase contains common fields for all tool types.
@@ -19,14 +21,20 @@ type Tool interface {
 // Validation:
 //   - Name: required, non-empty string
 //   - Type: validated via ToolType unmarshal
+//   - Limits: validated via ToolLimits unmarshal
+//   - Approval: validated via ToolApproval unmarshal
 type ToolBase struct {
-	Name        string   `json:"name" msgpack:"name"`
-	Type        ToolType `json:"type,omitzero" msgpack:"type,omitempty"`
-	Description string   `json:"description,omitzero" msgpack:"description,omitempty"`
+	Name        string       `json:"name" msgpack:"name"`
+	Type        ToolType     `json:"type,omitzero" msgpack:"type,omitempty"`
+	Description string       `json:"description,omitzero" msgpack:"description,omitempty"`
+	Limits      *ToolLimits  `json:"limits,omitzero" msgpack:"limits,omitempty"`     // runtime concurrency/rate limits
+	Approval    ToolApproval `json:"approval,omitzero" msgpack:"approval,omitempty"` // human approval of calls
 }
 
-func (b *ToolBase) ToolName() string        { return b.Name }
-func (b *ToolBase) ToolDescription() string { return b.Description }
+func (b *ToolBase) ToolName() string           { return b.Name }
+func (b *ToolBase) ToolDescription() string    { return b.Description }
+func (b *ToolBase) ToolLimits() *ToolLimits    { return b.Limits }
+func (b *ToolBase) ToolApproval() ToolApproval { return b.Approval }
 func (b *ToolBase) ToolType() ToolType {
 	if b.Type == "" {
 		return ToolTypeBuiltIn
@@ -34,6 +42,45 @@ func (b *ToolBase) ToolType() ToolType {
 	return b.Type
 }
 
+// ToolLimits caps how often a tool may be invoked. Limits are enforced by
+// the runtime and shared by every agent invoking the tool through it, so
+// they protect a downstream API from many concurrent device sessions.
+//
+// Validation:
+//   - MaxConcurrent, QPS, Burst: must not be negative
+//   - OnLimit: validated via ToolLimitAction unmarshal
+type ToolLimits struct {
+	MaxConcurrent int             `json:"max_concurrent,omitzero" msgpack:"max_concurrent,omitempty"` // max in-flight calls (0 = unlimited)
+	QPS           float64         `json:"qps,omitzero" msgpack:"qps,omitempty"`                       // sustained calls per second (0 = unlimited)
+	Burst         int             `json:"burst,omitzero" msgpack:"burst,omitempty"`                   // calls allowed at once above QPS (default ceil(QPS))
+	OnLimit       ToolLimitAction `json:"on_limit,omitzero" msgpack:"on_limit,omitempty"`             // queue (default) or reject
+}
+
+// validate checks if the ToolLimits fields are valid.
+func (l *ToolLimits) validate() error {
+	if l.MaxConcurrent < 0 {
+		return fmt.Errorf("limits: max_concurrent must not be negative")
+	}
+	if l.QPS < 0 {
+		return fmt.Errorf("limits: qps must not be negative")
+	}
+	if l.Burst < 0 {
+		return fmt.Errorf("limits: burst must not be negative")
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler with validation.
+func (l *ToolLimits) UnmarshalJSON(data []byte) error {
+	type Alias ToolLimits
+	var alias Alias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+	*l = ToolLimits(alias)
+	return l.validate()
+}
+
 // ToolRef is a tool reference in Agent.
 // Supports $ref to external tool or inline Tool.
 //
@@ -166,6 +213,22 @@ func (t *ToolRef) DecodeMsgpack(dec *msgpack.Decoder) error {
 			var d TextProcessorTool
 			err = msgpack.Unmarshal(m.Tool, &d)
 			def = &d
+		case ToolTypeDocuments:
+			var d DocumentsTool
+			err = msgpack.Unmarshal(m.Tool, &d)
+			def = &d
+		case ToolTypeAgent:
+			var d AgentTool
+			err = msgpack.Unmarshal(m.Tool, &d)
+			def = &d
+		case ToolTypeMCP:
+			var d MCPTool
+			err = msgpack.Unmarshal(m.Tool, &d)
+			def = &d
+		case ToolTypeRetrieval:
+			var d RetrievalTool
+			err = msgpack.Unmarshal(m.Tool, &d)
+			def = &d
 		default:
 			return fmt.Errorf("unknown tool type: %s", m.Type)
 		}
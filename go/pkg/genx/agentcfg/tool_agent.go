@@ -0,0 +1,57 @@
+package agentcfg
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// AgentTool delegates a task to another agent. The sub-agent gets the call
+// input as its user message and runs until it answers or finishes; its
+// output is the tool result.
+//
+// Validation:
+//   - Inherits ToolBase validation (Name required)
+//   - Agent: required, $ref or inline definition
+//   - Output: validated via AgentToolOutput unmarshal
+//   - MaxDepth: must not be negative
+type AgentTool struct {
+	ToolBase `msgpack:",inline"`
+	// Agent is the sub-agent, e.g. {"$ref": "agent:music"}
+	Agent AgentRef `json:"agent" msgpack:"agent"`
+	// InputJQ is a jq expression to build the sub-agent input from the call
+	// arguments (default: the "input" argument)
+	InputJQ *JQExpr `json:"input_jq,omitzero" yaml:"input_jq,omitempty" msgpack:"input_jq,omitempty"`
+	// Output: "response" (the sub-agent's answer, default) or "history"
+	// (the sub-agent's whole conversation)
+	Output AgentToolOutput `json:"output,omitzero" msgpack:"output,omitempty"`
+	// ResultProcessor post-processes the output, e.g. to summarize it
+	ResultProcessor *TextProcessorToolRef `json:"result_processor,omitzero" msgpack:"result_processor,omitempty"`
+	// MaxDepth is the maximum nesting of agent tools, counting this one
+	// (default 3)
+	MaxDepth int `json:"max_depth,omitzero" msgpack:"max_depth,omitempty"`
+}
+
+// validate checks if the AgentTool fields are valid.
+func (t *AgentTool) validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("agent tool: name is required")
+	}
+	if t.Agent.IsEmpty() {
+		return fmt.Errorf("tool %s: agent is required", t.Name)
+	}
+	if t.MaxDepth < 0 {
+		return fmt.Errorf("tool %s: max_depth must not be negative", t.Name)
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler with validation.
+func (t *AgentTool) UnmarshalJSON(data []byte) error {
+	type Alias AgentTool
+	var alias Alias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+	*t = AgentTool(alias)
+	return t.validate()
+}
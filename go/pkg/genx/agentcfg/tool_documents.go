@@ -0,0 +1,46 @@
+package agentcfg
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DocumentsTool is a knowledge-base search tool. It answers queries from a
+// collection of documents ingested by the runtime.
+//
+// Validation:
+//   - Inherits ToolBase validation (Name required)
+//   - Collection: required, non-empty string
+//   - Limit: must not be negative
+type DocumentsTool struct {
+	ToolBase `msgpack:",inline"`
+	// Collection is the name of the document collection to search
+	Collection string `json:"collection" msgpack:"collection"`
+	// Limit is the maximum number of passages returned (default 5)
+	Limit int `json:"limit,omitzero" msgpack:"limit,omitempty"`
+}
+
+// validate checks if the DocumentsTool fields are valid.
+func (t *DocumentsTool) validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("documents tool: name is required")
+	}
+	if t.Collection == "" {
+		return fmt.Errorf("tool %s: collection is required", t.Name)
+	}
+	if t.Limit < 0 {
+		return fmt.Errorf("tool %s: limit must not be negative", t.Name)
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler with validation.
+func (t *DocumentsTool) UnmarshalJSON(data []byte) error {
+	type Alias DocumentsTool
+	var alias Alias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+	*t = DocumentsTool(alias)
+	return t.validate()
+}
@@ -0,0 +1,101 @@
+package agentcfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// MCPServer is the connection to an MCP (Model Context Protocol) server.
+// Set Command to run the server as a subprocess speaking over stdio, or URL
+// to connect to a server over HTTP with Server-Sent Events.
+//
+// Validation:
+//   - Exactly one of Command and URL is required
+//   - URL: must be an absolute http or https URL
+type MCPServer struct {
+	// Command is the executable of a stdio server
+	Command string `json:"command,omitzero" msgpack:"command,omitempty"`
+	// Args are the arguments of Command
+	Args []string `json:"args,omitzero" msgpack:"args,omitempty"`
+	// Env are environment variables added to the environment of Command
+	Env map[string]string `json:"env,omitzero" msgpack:"env,omitempty"`
+
+	// URL is the SSE endpoint of an HTTP server
+	URL string `json:"url,omitzero" msgpack:"url,omitempty"`
+	// Headers are sent with every request to URL, e.g. Authorization
+	Headers map[string]string `json:"headers,omitzero" msgpack:"headers,omitempty"`
+}
+
+// Key returns a string that identifies the server, for sharing one
+// connection between the tools of a server.
+func (s *MCPServer) Key() string {
+	if s.URL != "" {
+		return "sse:" + s.URL
+	}
+	data, _ := json.Marshal(s)
+	return "stdio:" + string(data)
+}
+
+// validate checks if the MCPServer fields are valid.
+func (s *MCPServer) validate() error {
+	switch {
+	case s.Command == "" && s.URL == "":
+		return fmt.Errorf("command or url is required")
+	case s.Command != "" && s.URL != "":
+		return fmt.Errorf("command and url are mutually exclusive")
+	case s.URL != "":
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			return fmt.Errorf("invalid url: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("url must be http or https, got %q", s.URL)
+		}
+	}
+	return nil
+}
+
+// MCPTool is a tool served by an MCP server. The argument schema and
+// description of the tool are discovered from the server.
+//
+// Validation:
+//   - Inherits ToolBase validation (Name required)
+//   - Server: see MCPServer
+type MCPTool struct {
+	ToolBase `msgpack:",inline"`
+	// Server is the MCP server of the tool
+	Server MCPServer `json:"server" msgpack:"server"`
+	// Tool is the name of the tool on the server (default: Name)
+	Tool string `json:"tool,omitzero" msgpack:"tool,omitempty"`
+}
+
+// ServerTool returns the name of the tool on the server.
+func (t *MCPTool) ServerTool() string {
+	if t.Tool != "" {
+		return t.Tool
+	}
+	return t.Name
+}
+
+// validate checks if the MCPTool fields are valid.
+func (t *MCPTool) validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("mcp tool: name is required")
+	}
+	if err := t.Server.validate(); err != nil {
+		return fmt.Errorf("tool %s: server: %w", t.Name, err)
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler with validation.
+func (t *MCPTool) UnmarshalJSON(data []byte) error {
+	type Alias MCPTool
+	var alias Alias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+	*t = MCPTool(alias)
+	return t.validate()
+}
@@ -0,0 +1,46 @@
+package agentcfg
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RetrievalTool is a memory retrieval tool. It recalls past conversations
+// from the memory of the persona the agent speaks as, by keywords, entity
+// labels expanded through the memory graph, and time periods.
+//
+// Validation:
+//   - Inherits ToolBase validation (Name required)
+//   - Hops, Limit: must not be negative
+type RetrievalTool struct {
+	ToolBase `msgpack:",inline"`
+	// Hops is the depth of graph expansion from the labels of a query (default 2)
+	Hops int `json:"hops,omitzero" msgpack:"hops,omitempty"`
+	// Limit is the maximum number of memories returned (default 5)
+	Limit int `json:"limit,omitzero" msgpack:"limit,omitempty"`
+}
+
+// validate checks if the RetrievalTool fields are valid.
+func (t *RetrievalTool) validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("retrieval tool: name is required")
+	}
+	if t.Hops < 0 {
+		return fmt.Errorf("tool %s: hops must not be negative", t.Name)
+	}
+	if t.Limit < 0 {
+		return fmt.Errorf("tool %s: limit must not be negative", t.Name)
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler with validation.
+func (t *RetrievalTool) UnmarshalJSON(data []byte) error {
+	type Alias RetrievalTool
+	var alias Alias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+	*t = RetrievalTool(alias)
+	return t.validate()
+}
@@ -150,6 +150,76 @@ func TestUnmarshalTool_BuiltIn(t *testing.T) {
 	}
 }
 
+func TestUnmarshalTool_Limits(t *testing.T) {
+	for _, path := range []string{"testdata/tool/http_limited.json", "testdata/tool/http_limited.yaml"} {
+		t.Run(path, func(t *testing.T) {
+			var data []byte
+			if strings.HasSuffix(path, ".yaml") {
+				data = loadYAMLTestFile(t, path)
+			} else {
+				data = loadTestFile(t, path)
+			}
+
+			tool, err := UnmarshalTool(data)
+			if err != nil {
+				t.Fatalf("UnmarshalTool: %v", err)
+			}
+
+			limits := tool.ToolLimits()
+			if limits == nil {
+				t.Fatal("ToolLimits() is nil")
+			}
+			want := ToolLimits{MaxConcurrent: 4, QPS: 10, Burst: 20, OnLimit: ToolLimitReject}
+			if *limits != want {
+				t.Errorf("ToolLimits() = %+v, want %+v", *limits, want)
+			}
+		})
+	}
+
+	data := loadTestFile(t, "testdata/tool/http_get.json")
+	tool, err := UnmarshalTool(data)
+	if err != nil {
+		t.Fatalf("UnmarshalTool: %v", err)
+	}
+	if tool.ToolLimits() != nil {
+		t.Errorf("ToolLimits() = %+v, want nil", tool.ToolLimits())
+	}
+}
+
+func TestUnmarshalTool_Approval(t *testing.T) {
+	for _, path := range []string{"testdata/tool/http_approval.json", "testdata/tool/http_approval.yaml"} {
+		t.Run(path, func(t *testing.T) {
+			var data []byte
+			if strings.HasSuffix(path, ".yaml") {
+				data = loadYAMLTestFile(t, path)
+			} else {
+				data = loadTestFile(t, path)
+			}
+
+			tool, err := UnmarshalTool(data)
+			if err != nil {
+				t.Fatalf("UnmarshalTool: %v", err)
+			}
+			if !tool.ToolApproval().IsRequired() {
+				t.Errorf("ToolApproval() = %q, want %q", tool.ToolApproval(), ToolApprovalRequired)
+			}
+		})
+	}
+
+	data := loadTestFile(t, "testdata/tool/http_get.json")
+	tool, err := UnmarshalTool(data)
+	if err != nil {
+		t.Fatalf("UnmarshalTool: %v", err)
+	}
+	if tool.ToolApproval().IsRequired() {
+		t.Errorf("ToolApproval() = %q, want none", tool.ToolApproval())
+	}
+
+	if _, err := UnmarshalTool([]byte(`{"type": "http", "name": "x", "method": "GET", "endpoint": "https://x", "approval": "always"}`)); err == nil {
+		t.Error("expected error for an invalid approval")
+	}
+}
+
 func TestToolRef_UnmarshalJSON(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -536,6 +606,366 @@ func TestUnmarshalTool_YAML_TextProcessorJSONOutput(t *testing.T) {
 	}
 }
 
+// ========== DocumentsTool Tests ==========
+
+func TestUnmarshalTool_Documents(t *testing.T) {
+	for _, path := range []string{"testdata/tool/documents.json", "testdata/tool/documents.yaml"} {
+		t.Run(path, func(t *testing.T) {
+			var data []byte
+			if strings.HasSuffix(path, ".yaml") {
+				data = loadYAMLTestFile(t, path)
+			} else {
+				data = loadTestFile(t, path)
+			}
+
+			tool, err := UnmarshalTool(data)
+			if err != nil {
+				t.Fatalf("UnmarshalTool: %v", err)
+			}
+			if tool.ToolType() != ToolTypeDocuments {
+				t.Errorf("ToolType() = %q, want %q", tool.ToolType(), ToolTypeDocuments)
+			}
+
+			dt := AsDocumentsTool(tool)
+			if dt == nil {
+				t.Fatal("AsDocumentsTool returned nil")
+			}
+			if dt.Name != "kb_search" {
+				t.Errorf("Name = %q, want %q", dt.Name, "kb_search")
+			}
+			if dt.Collection != "manuals" {
+				t.Errorf("Collection = %q, want %q", dt.Collection, "manuals")
+			}
+			if dt.Limit != 3 {
+				t.Errorf("Limit = %d, want 3", dt.Limit)
+			}
+		})
+	}
+}
+
+func TestUnmarshalTool_DocumentsMissingCollection(t *testing.T) {
+	_, err := UnmarshalTool([]byte(`{"type": "documents", "name": "kb_search"}`))
+	if err == nil {
+		t.Fatal("expected error for missing collection")
+	}
+}
+
+func TestToolRef_MsgpackRoundtrip_Documents(t *testing.T) {
+	ref := ToolRef{Tool: &DocumentsTool{
+		ToolBase:   ToolBase{Name: "kb_search", Type: ToolTypeDocuments},
+		Collection: "manuals",
+		Limit:      3,
+	}}
+
+	packed, err := msgpack.Marshal(ref)
+	if err != nil {
+		t.Fatalf("MsgPack Marshal: %v", err)
+	}
+	var decoded ToolRef
+	if err := msgpack.Unmarshal(packed, &decoded); err != nil {
+		t.Fatalf("MsgPack Unmarshal: %v", err)
+	}
+
+	dt := AsDocumentsTool(decoded.Tool)
+	if dt == nil {
+		t.Fatalf("decoded tool = %T, want *DocumentsTool", decoded.Tool)
+	}
+	if dt.Name != "kb_search" || dt.Collection != "manuals" || dt.Limit != 3 {
+		t.Errorf("decoded = %+v", dt)
+	}
+}
+
+// ========== AgentTool Tests ==========
+
+func TestUnmarshalTool_Agent(t *testing.T) {
+	for _, path := range []string{"testdata/tool/agent.json", "testdata/tool/agent.yaml"} {
+		t.Run(path, func(t *testing.T) {
+			var data []byte
+			if strings.HasSuffix(path, ".yaml") {
+				data = loadYAMLTestFile(t, path)
+			} else {
+				data = loadTestFile(t, path)
+			}
+
+			tool, err := UnmarshalTool(data)
+			if err != nil {
+				t.Fatalf("UnmarshalTool: %v", err)
+			}
+			if tool.ToolType() != ToolTypeAgent {
+				t.Errorf("ToolType() = %q, want %q", tool.ToolType(), ToolTypeAgent)
+			}
+
+			at := AsAgentTool(tool)
+			if at == nil {
+				t.Fatal("AsAgentTool returned nil")
+			}
+			if at.Name != "ask_music" {
+				t.Errorf("Name = %q, want %q", at.Name, "ask_music")
+			}
+			if at.Agent.Ref != "agent:music" {
+				t.Errorf("Agent.Ref = %q, want %q", at.Agent.Ref, "agent:music")
+			}
+			if at.InputJQ == nil || at.InputJQ.Expr != ".request" {
+				t.Errorf("InputJQ = %v, want .request", at.InputJQ)
+			}
+			if at.Output != AgentToolOutputHistory {
+				t.Errorf("Output = %q, want %q", at.Output, AgentToolOutputHistory)
+			}
+			if at.ResultProcessor == nil || at.ResultProcessor.Ref != "tool:summarizer" {
+				t.Errorf("ResultProcessor = %+v, want $ref tool:summarizer", at.ResultProcessor)
+			}
+			if at.MaxDepth != 2 {
+				t.Errorf("MaxDepth = %d, want 2", at.MaxDepth)
+			}
+		})
+	}
+}
+
+func TestUnmarshalTool_AgentInline(t *testing.T) {
+	tool, err := UnmarshalTool([]byte(`{
+		"type": "agent",
+		"name": "ask_helper",
+		"agent": {"type": "react", "name": "helper", "prompt": "Help.", "generator": {"model": "m"}}
+	}`))
+	if err != nil {
+		t.Fatalf("UnmarshalTool: %v", err)
+	}
+	at := AsAgentTool(tool)
+	if at == nil || at.Agent.IsRef() || AsReActAgent(at.Agent.Agent) == nil {
+		t.Fatalf("tool = %+v, want inline react agent", tool)
+	}
+}
+
+func TestUnmarshalTool_AgentInvalid(t *testing.T) {
+	tests := map[string]string{
+		"missing agent":  `{"type": "agent", "name": "ask"}`,
+		"bad output":     `{"type": "agent", "name": "ask", "agent": {"$ref": "music"}, "output": "all"}`,
+		"negative depth": `{"type": "agent", "name": "ask", "agent": {"$ref": "music"}, "max_depth": -1}`,
+	}
+	for name, data := range tests {
+		if _, err := UnmarshalTool([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestToolRef_MsgpackRoundtrip_Agent(t *testing.T) {
+	ref := ToolRef{Tool: &AgentTool{
+		ToolBase: ToolBase{Name: "ask_music", Type: ToolTypeAgent},
+		Agent:    AgentRef{Ref: "agent:music"},
+		Output:   AgentToolOutputHistory,
+		MaxDepth: 2,
+	}}
+
+	packed, err := msgpack.Marshal(ref)
+	if err != nil {
+		t.Fatalf("MsgPack Marshal: %v", err)
+	}
+	var decoded ToolRef
+	if err := msgpack.Unmarshal(packed, &decoded); err != nil {
+		t.Fatalf("MsgPack Unmarshal: %v", err)
+	}
+
+	at := AsAgentTool(decoded.Tool)
+	if at == nil {
+		t.Fatalf("decoded tool = %T, want *AgentTool", decoded.Tool)
+	}
+	if at.Name != "ask_music" || at.Agent.Ref != "agent:music" || at.Output != AgentToolOutputHistory || at.MaxDepth != 2 {
+		t.Errorf("decoded = %+v", at)
+	}
+}
+
+// ========== MCPTool Tests ==========
+
+func TestUnmarshalTool_MCPStdio(t *testing.T) {
+	for _, path := range []string{"testdata/tool/mcp_stdio.json", "testdata/tool/mcp_stdio.yaml"} {
+		t.Run(path, func(t *testing.T) {
+			var data []byte
+			if strings.HasSuffix(path, ".yaml") {
+				data = loadYAMLTestFile(t, path)
+			} else {
+				data = loadTestFile(t, path)
+			}
+
+			tool, err := UnmarshalTool(data)
+			if err != nil {
+				t.Fatalf("UnmarshalTool: %v", err)
+			}
+			if tool.ToolType() != ToolTypeMCP {
+				t.Errorf("ToolType() = %q, want %q", tool.ToolType(), ToolTypeMCP)
+			}
+
+			mt := AsMCPTool(tool)
+			if mt == nil {
+				t.Fatal("AsMCPTool returned nil")
+			}
+			if mt.Server.Command != "npx" || len(mt.Server.Args) != 3 || mt.Server.Args[2] != "/data" {
+				t.Errorf("Server = %+v", mt.Server)
+			}
+			if mt.Server.Env["NODE_ENV"] != "production" {
+				t.Errorf("Env = %v", mt.Server.Env)
+			}
+			if mt.ServerTool() != "read_file" {
+				t.Errorf("ServerTool() = %q, want the tool name", mt.ServerTool())
+			}
+		})
+	}
+}
+
+func TestUnmarshalTool_MCPSSE(t *testing.T) {
+	for _, path := range []string{"testdata/tool/mcp_sse.json", "testdata/tool/mcp_sse.yaml"} {
+		t.Run(path, func(t *testing.T) {
+			var data []byte
+			if strings.HasSuffix(path, ".yaml") {
+				data = loadYAMLTestFile(t, path)
+			} else {
+				data = loadTestFile(t, path)
+			}
+
+			tool, err := UnmarshalTool(data)
+			if err != nil {
+				t.Fatalf("UnmarshalTool: %v", err)
+			}
+			mt := AsMCPTool(tool)
+			if mt == nil {
+				t.Fatal("AsMCPTool returned nil")
+			}
+			if mt.Server.URL != "https://mcp.example.com/sse" {
+				t.Errorf("URL = %q", mt.Server.URL)
+			}
+			if mt.Server.Headers["Authorization"] != "Bearer token123" {
+				t.Errorf("Headers = %v", mt.Server.Headers)
+			}
+			if mt.ServerTool() != "get_forecast" {
+				t.Errorf("ServerTool() = %q, want %q", mt.ServerTool(), "get_forecast")
+			}
+		})
+	}
+}
+
+func TestUnmarshalTool_MCPInvalidServer(t *testing.T) {
+	data := loadTestFile(t, "testdata/error/tool_mcp_no_server.json")
+	if _, err := UnmarshalTool(data); err == nil {
+		t.Error("expected error for server without command or url")
+	}
+
+	tests := []string{
+		`{"type": "mcp", "name": "x", "server": {"command": "srv", "url": "http://localhost/sse"}}`,
+		`{"type": "mcp", "name": "x", "server": {"url": "ws://localhost/sse"}}`,
+		`{"type": "mcp", "server": {"command": "srv"}}`,
+	}
+	for _, data := range tests {
+		if _, err := UnmarshalTool([]byte(data)); err == nil {
+			t.Errorf("UnmarshalTool(%s): expected error", data)
+		}
+	}
+}
+
+func TestMCPServer_Key(t *testing.T) {
+	a := MCPServer{Command: "srv", Args: []string{"--root", "/a"}}
+	b := MCPServer{Command: "srv", Args: []string{"--root", "/b"}}
+	if a.Key() == b.Key() {
+		t.Errorf("Key() of servers with different args = %q", a.Key())
+	}
+	c := MCPServer{Command: "srv", Args: []string{"--root", "/a"}}
+	if a.Key() != c.Key() {
+		t.Errorf("Key() = %q and %q for the same server", a.Key(), c.Key())
+	}
+}
+
+func TestToolRef_MsgpackRoundtrip_MCP(t *testing.T) {
+	ref := ToolRef{Tool: &MCPTool{
+		ToolBase: ToolBase{Name: "weather", Type: ToolTypeMCP},
+		Server:   MCPServer{URL: "https://mcp.example.com/sse", Headers: map[string]string{"Authorization": "Bearer x"}},
+		Tool:     "get_forecast",
+	}}
+
+	packed, err := msgpack.Marshal(ref)
+	if err != nil {
+		t.Fatalf("MsgPack Marshal: %v", err)
+	}
+	var decoded ToolRef
+	if err := msgpack.Unmarshal(packed, &decoded); err != nil {
+		t.Fatalf("MsgPack Unmarshal: %v", err)
+	}
+
+	mt := AsMCPTool(decoded.Tool)
+	if mt == nil {
+		t.Fatalf("decoded tool = %T, want *MCPTool", decoded.Tool)
+	}
+	if mt.Name != "weather" || mt.Tool != "get_forecast" || mt.Server.URL != "https://mcp.example.com/sse" || mt.Server.Headers["Authorization"] != "Bearer x" {
+		t.Errorf("decoded = %+v", mt)
+	}
+}
+
+// ========== RetrievalTool Tests ==========
+
+func TestUnmarshalTool_Retrieval(t *testing.T) {
+	for _, path := range []string{"testdata/tool/retrieval.json", "testdata/tool/retrieval.yaml"} {
+		t.Run(path, func(t *testing.T) {
+			var data []byte
+			if strings.HasSuffix(path, ".yaml") {
+				data = loadYAMLTestFile(t, path)
+			} else {
+				data = loadTestFile(t, path)
+			}
+
+			tool, err := UnmarshalTool(data)
+			if err != nil {
+				t.Fatalf("UnmarshalTool: %v", err)
+			}
+			if tool.ToolType() != ToolTypeRetrieval {
+				t.Errorf("ToolType() = %q, want %q", tool.ToolType(), ToolTypeRetrieval)
+			}
+
+			rt := AsRetrievalTool(tool)
+			if rt == nil {
+				t.Fatal("AsRetrievalTool returned nil")
+			}
+			if rt.Name != "recall" || rt.Hops != 1 || rt.Limit != 3 {
+				t.Errorf("tool = %+v", rt)
+			}
+		})
+	}
+}
+
+func TestUnmarshalTool_RetrievalInvalid(t *testing.T) {
+	for _, data := range []string{
+		`{"type": "retrieval"}`,
+		`{"type": "retrieval", "name": "recall", "hops": -1}`,
+		`{"type": "retrieval", "name": "recall", "limit": -1}`,
+	} {
+		if _, err := UnmarshalTool([]byte(data)); err == nil {
+			t.Errorf("UnmarshalTool(%s): expected error", data)
+		}
+	}
+}
+
+func TestToolRef_MsgpackRoundtrip_Retrieval(t *testing.T) {
+	ref := ToolRef{Tool: &RetrievalTool{
+		ToolBase: ToolBase{Name: "recall", Type: ToolTypeRetrieval},
+		Hops:     1,
+		Limit:    3,
+	}}
+
+	packed, err := msgpack.Marshal(ref)
+	if err != nil {
+		t.Fatalf("MsgPack Marshal: %v", err)
+	}
+	var decoded ToolRef
+	if err := msgpack.Unmarshal(packed, &decoded); err != nil {
+		t.Fatalf("MsgPack Unmarshal: %v", err)
+	}
+
+	rt := AsRetrievalTool(decoded.Tool)
+	if rt == nil {
+		t.Fatalf("decoded tool = %T, want *RetrievalTool", decoded.Tool)
+	}
+	if rt.Name != "recall" || rt.Hops != 1 || rt.Limit != 3 {
+		t.Errorf("decoded = %+v", rt)
+	}
+}
+
 // ========== MsgPack Tests ==========
 
 func TestTool_MsgpackRoundtrip_BuiltIn(t *testing.T) {
@@ -712,6 +1142,32 @@ func TestTool_MsgpackRoundtrip_TextProcessor(t *testing.T) {
 	}
 }
 
+func TestTool_MsgpackRoundtrip_Limits(t *testing.T) {
+	data := loadTestFile(t, "testdata/tool/http_limited.json")
+
+	tool, err := UnmarshalTool(data)
+	if err != nil {
+		t.Fatalf("UnmarshalTool: %v", err)
+	}
+
+	packed, err := msgpack.Marshal(AsHTTPTool(tool))
+	if err != nil {
+		t.Fatalf("MsgPack Marshal: %v", err)
+	}
+
+	var decoded HTTPTool
+	if err := msgpack.Unmarshal(packed, &decoded); err != nil {
+		t.Fatalf("MsgPack Unmarshal: %v", err)
+	}
+
+	if decoded.Limits == nil {
+		t.Fatal("Limits is nil")
+	}
+	if *decoded.Limits != *tool.ToolLimits() {
+		t.Errorf("Limits = %+v, want %+v", *decoded.Limits, *tool.ToolLimits())
+	}
+}
+
 func TestToolRef_MsgpackRoundtrip(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -857,6 +1313,18 @@ func TestUnmarshalTool_Error_TextProcessorNoModel(t *testing.T) {
 
 // ========== Additional Tool Validate Error Tests ==========
 
+func TestUnmarshalTool_Error_NegativeLimits(t *testing.T) {
+	data := loadTestFile(t, "testdata/error/tool_negative_limits.json")
+
+	_, err := UnmarshalTool(data)
+	if err == nil {
+		t.Fatal("expected error for negative qps")
+	}
+	if !strings.Contains(err.Error(), "qps must not be negative") {
+		t.Errorf("error = %q, want containing %q", err.Error(), "qps must not be negative")
+	}
+}
+
 func TestHTTPTool_Validate_Error_NoName(t *testing.T) {
 	data := []byte(`{"type":"http","endpoint":"https://example.com","method":"GET"}`)
 	_, err := UnmarshalTool(data)
@@ -1339,3 +1807,25 @@ func TestTextProcessorToolRef_UnmarshalJSON_InvalidData(t *testing.T) {
 }
 
 // ========== Tool Validate Error Tests ==========
+
+func TestTool_MsgpackRoundtrip_Approval(t *testing.T) {
+	data := loadTestFile(t, "testdata/tool/http_approval.json")
+
+	tool, err := UnmarshalTool(data)
+	if err != nil {
+		t.Fatalf("UnmarshalTool: %v", err)
+	}
+
+	packed, err := msgpack.Marshal(AsHTTPTool(tool))
+	if err != nil {
+		t.Fatalf("MsgPack Marshal: %v", err)
+	}
+
+	var decoded HTTPTool
+	if err := msgpack.Unmarshal(packed, &decoded); err != nil {
+		t.Fatalf("MsgPack Unmarshal: %v", err)
+	}
+	if decoded.Approval != ToolApprovalRequired {
+		t.Errorf("Approval = %q, want %q", decoded.Approval, ToolApprovalRequired)
+	}
+}
@@ -94,6 +94,34 @@ func UnmarshalTool(data []byte) (Tool, error) {
 		}
 		return t, nil
 
+	case ToolTypeDocuments:
+		var t DocumentsTool
+		if err := json.Unmarshal(data, &t); err != nil {
+			return nil, fmt.Errorf("parse documents tool: %w", err)
+		}
+		return &t, nil
+
+	case ToolTypeAgent:
+		var t AgentTool
+		if err := json.Unmarshal(data, &t); err != nil {
+			return nil, fmt.Errorf("parse agent tool: %w", err)
+		}
+		return &t, nil
+
+	case ToolTypeMCP:
+		var t MCPTool
+		if err := json.Unmarshal(data, &t); err != nil {
+			return nil, fmt.Errorf("parse mcp tool: %w", err)
+		}
+		return &t, nil
+
+	case ToolTypeRetrieval:
+		var t RetrievalTool
+		if err := json.Unmarshal(data, &t); err != nil {
+			return nil, fmt.Errorf("parse retrieval tool: %w", err)
+		}
+		return &t, nil
+
 	case ToolTypeBuiltIn:
 		def := &BuiltInTool{
 			ToolBase: raw.ToolBase,
@@ -145,6 +173,38 @@ func AsTextProcessorTool(def Tool) *TextProcessorTool {
 	return nil
 }
 
+// AsDocumentsTool returns the Tool as *DocumentsTool if it is one, nil otherwise.
+func AsDocumentsTool(def Tool) *DocumentsTool {
+	if t, ok := def.(*DocumentsTool); ok {
+		return t
+	}
+	return nil
+}
+
+// AsAgentTool returns the Tool as *AgentTool if it is one, nil otherwise.
+func AsAgentTool(def Tool) *AgentTool {
+	if t, ok := def.(*AgentTool); ok {
+		return t
+	}
+	return nil
+}
+
+// AsMCPTool returns the Tool as *MCPTool if it is one, nil otherwise.
+func AsMCPTool(def Tool) *MCPTool {
+	if t, ok := def.(*MCPTool); ok {
+		return t
+	}
+	return nil
+}
+
+// AsRetrievalTool returns the Tool as *RetrievalTool if it is one, nil otherwise.
+func AsRetrievalTool(def Tool) *RetrievalTool {
+	if t, ok := def.(*RetrievalTool); ok {
+		return t
+	}
+	return nil
+}
+
 // AsBuiltInTool returns the Tool as *BuiltInTool if it is one, nil otherwise.
 func AsBuiltInTool(def Tool) *BuiltInTool {
 	if t, ok := def.(*BuiltInTool); ok {
@@ -0,0 +1,232 @@
+package genx
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrSlowConsumer is returned by a Broadcast consumer with the
+// SlowConsumerClose policy that fell a full buffer behind.
+var ErrSlowConsumer = errors.New("genx: slow consumer")
+
+// SlowConsumerPolicy decides what Broadcast does when a consumer's buffer is
+// full.
+type SlowConsumerPolicy int
+
+const (
+	// SlowConsumerBlock waits for the consumer, holding back the source and
+	// so every other consumer. Use it for consumers that must see every
+	// chunk, such as recording.
+	SlowConsumerBlock SlowConsumerPolicy = iota
+
+	// SlowConsumerDrop drops the chunk for that consumer only. Use it for
+	// best-effort consumers, such as metrics.
+	SlowConsumerDrop
+
+	// SlowConsumerClose closes the consumer with ErrSlowConsumer, so a
+	// stalled consumer neither stalls the others nor silently misses chunks.
+	SlowConsumerClose
+)
+
+// BroadcastConsumer configures one consumer of Broadcast.
+type BroadcastConsumer struct {
+	// Buffer is the number of chunks buffered for the consumer.
+	// Default: 100
+	Buffer int
+
+	// Policy applies when the buffer is full. Default: SlowConsumerBlock.
+	Policy SlowConsumerPolicy
+}
+
+// TeeN splits s into n streams that each receive every chunk of s. A
+// consumer more than 100 chunks behind holds back the others; use Broadcast
+// to choose per-consumer buffering and slow-consumer policies.
+func TeeN(s Stream, n int) []Stream {
+	return Broadcast(s, make([]BroadcastConsumer, n)...)
+}
+
+// Broadcast reads src in the background and returns one Stream per
+// consumer, each receiving every chunk of src (subject to its policy).
+// Chunks are shared, not copied: consumers must not modify them.
+//
+// Errors propagate both ways:
+//   - When src ends, every consumer ends after its buffered chunks; when
+//     src fails, every consumer fails with the same error.
+//   - Closing a consumer detaches it without affecting the others. When the
+//     last consumer is closed, src is closed, with the consumer's error if
+//     it was closed with one.
+func Broadcast(src Stream, consumers ...BroadcastConsumer) []Stream {
+	b := &broadcast{src: src, active: len(consumers)}
+	streams := make([]Stream, len(consumers))
+	b.consumers = make([]*broadcastStream, len(consumers))
+	for i, c := range consumers {
+		if c.Buffer <= 0 {
+			c.Buffer = 100
+		}
+		s := &broadcastStream{
+			b:      b,
+			policy: c.Policy,
+			ch:     make(chan *MessageChunk, c.Buffer),
+			done:   make(chan struct{}),
+		}
+		b.consumers[i] = s
+		streams[i] = s
+	}
+	if len(consumers) == 0 {
+		src.Close()
+		return streams
+	}
+	go b.run()
+	return streams
+}
+
+type broadcast struct {
+	src       Stream
+	consumers []*broadcastStream
+
+	mu      sync.Mutex
+	active  int   // consumers not closed
+	lastErr error // last consumer close error
+	srcDone bool  // src ended or was closed
+}
+
+func (b *broadcast) run() {
+	for {
+		chunk, err := b.src.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			b.mu.Lock()
+			b.srcDone = true
+			b.mu.Unlock()
+			for _, s := range b.consumers {
+				s.srcErr = err
+				close(s.ch)
+			}
+			return
+		}
+		// Each consumer owns a reference to a pooled chunk
+		for range len(b.consumers) - 1 {
+			chunk.Retain()
+		}
+		for _, s := range b.consumers {
+			s.send(chunk)
+		}
+	}
+}
+
+// leave records that a consumer was closed, and closes src after the last.
+func (b *broadcast) leave(err error) {
+	b.mu.Lock()
+	b.active--
+	if err != nil {
+		b.lastErr = err
+	}
+	closeSrc := b.active == 0 && !b.srcDone
+	if closeSrc {
+		b.srcDone = true
+	}
+	err = b.lastErr
+	b.mu.Unlock()
+
+	if !closeSrc {
+		return
+	}
+	if err != nil {
+		b.src.CloseWithError(err)
+	} else {
+		b.src.Close()
+	}
+}
+
+// broadcastStream is one consumer of a broadcast.
+type broadcastStream struct {
+	b      *broadcast
+	policy SlowConsumerPolicy
+	ch     chan *MessageChunk // closed by run when src ends
+	srcErr error              // set before ch is closed
+
+	once sync.Once
+	done chan struct{} // closed when the consumer is closed
+	err  error         // set before done is closed
+}
+
+// send delivers chunk according to the consumer's policy. The reference
+// of the consumer to the chunk is released if it is not delivered.
+func (s *broadcastStream) send(chunk *MessageChunk) {
+	select {
+	case <-s.done:
+		chunk.Release()
+		return
+	default:
+	}
+
+	switch s.policy {
+	case SlowConsumerDrop:
+		select {
+		case s.ch <- chunk:
+		default:
+			chunk.Release()
+		}
+	case SlowConsumerClose:
+		select {
+		case s.ch <- chunk:
+		default:
+			chunk.Release()
+			s.close(ErrSlowConsumer)
+		}
+	default:
+		select {
+		case s.ch <- chunk:
+		case <-s.done:
+			chunk.Release()
+		}
+	}
+}
+
+func (s *broadcastStream) Next() (*MessageChunk, error) {
+	select {
+	case <-s.done:
+		return nil, s.err
+	default:
+	}
+	select {
+	case <-s.done:
+		return nil, s.err
+	case chunk, ok := <-s.ch:
+		if !ok {
+			if s.srcErr != nil {
+				return nil, s.srcErr
+			}
+			return nil, io.EOF
+		}
+		return chunk, nil
+	}
+}
+
+func (s *broadcastStream) Close() error {
+	s.once.Do(func() {
+		s.err = io.EOF
+		close(s.done)
+		s.b.leave(nil)
+	})
+	return nil
+}
+
+func (s *broadcastStream) CloseWithError(err error) error {
+	if err == nil {
+		return s.Close()
+	}
+	s.close(err)
+	return nil
+}
+
+func (s *broadcastStream) close(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+		s.b.leave(err)
+	})
+}
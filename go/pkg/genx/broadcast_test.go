@@ -0,0 +1,143 @@
+package genx
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/buffer"
+)
+
+func textChunks(texts ...string) []*MessageChunk {
+	chunks := make([]*MessageChunk, len(texts))
+	for i, t := range texts {
+		chunks[i] = &MessageChunk{Part: Text(t)}
+	}
+	return chunks
+}
+
+func readTexts(s Stream) ([]string, error) {
+	var texts []string
+	for {
+		c, err := s.Next()
+		if err == io.EOF {
+			return texts, nil
+		}
+		if err != nil {
+			return texts, err
+		}
+		texts = append(texts, string(c.Part.(Text)))
+	}
+}
+
+func TestTeeN(t *testing.T) {
+	streams := TeeN(&sliceStream{chunks: textChunks("a", "b", "c")}, 3)
+	if len(streams) != 3 {
+		t.Fatalf("got %d streams, want 3", len(streams))
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]string, len(streams))
+	for i, s := range streams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			texts, err := readTexts(s)
+			if err != nil {
+				t.Errorf("stream %d: %v", i, err)
+			}
+			results[i] = texts
+		}()
+	}
+	wg.Wait()
+
+	for i, texts := range results {
+		if len(texts) != 3 || texts[0] != "a" || texts[2] != "c" {
+			t.Errorf("stream %d = %v, want [a b c]", i, texts)
+		}
+	}
+}
+
+func TestBroadcast_SourceError(t *testing.T) {
+	errSrc := errors.New("mic unplugged")
+	buf := buffer.N[*MessageChunk](10)
+	src := &bufferStream{buf: buf}
+	streams := TeeN(src, 2)
+
+	buf.Add(&MessageChunk{Part: Text("a")})
+	buf.CloseWithError(errSrc)
+
+	for i, s := range streams {
+		if _, err := readTexts(s); !errors.Is(err, errSrc) {
+			t.Errorf("stream %d: err = %v, want %v", i, err, errSrc)
+		}
+	}
+}
+
+func TestBroadcast_Drop(t *testing.T) {
+	streams := Broadcast(&sliceStream{chunks: textChunks("a", "b", "c", "d")},
+		BroadcastConsumer{},
+		BroadcastConsumer{Buffer: 1, Policy: SlowConsumerDrop},
+	)
+
+	// The fast consumer reads everything while the slow one is not reading.
+	texts, err := readTexts(streams[0])
+	if err != nil || len(texts) != 4 {
+		t.Fatalf("fast consumer = %v, %v; want 4 chunks", texts, err)
+	}
+	texts, err = readTexts(streams[1])
+	if err != nil {
+		t.Fatalf("slow consumer: %v", err)
+	}
+	if len(texts) != 1 || texts[0] != "a" {
+		t.Errorf("slow consumer = %v, want [a]", texts)
+	}
+}
+
+func TestBroadcast_Close(t *testing.T) {
+	streams := Broadcast(&sliceStream{chunks: textChunks("a", "b", "c")},
+		BroadcastConsumer{},
+		BroadcastConsumer{Buffer: 1, Policy: SlowConsumerClose},
+	)
+
+	texts, err := readTexts(streams[0])
+	if err != nil || len(texts) != 3 {
+		t.Fatalf("fast consumer = %v, %v; want 3 chunks", texts, err)
+	}
+	if _, err := readTexts(streams[1]); !errors.Is(err, ErrSlowConsumer) {
+		t.Errorf("slow consumer: err = %v, want %v", err, ErrSlowConsumer)
+	}
+}
+
+func TestBroadcast_ConsumerClose(t *testing.T) {
+	errConsumer := errors.New("speaker gone")
+	buf := buffer.N[*MessageChunk](10)
+	src := &bufferStream{buf: buf}
+	streams := TeeN(src, 2)
+
+	// Closing one consumer leaves the other running.
+	streams[0].CloseWithError(errConsumer)
+	if _, err := streams[0].Next(); !errors.Is(err, errConsumer) {
+		t.Errorf("closed consumer: err = %v, want %v", err, errConsumer)
+	}
+	buf.Add(&MessageChunk{Part: Text("a")})
+	if c, err := streams[1].Next(); err != nil || c.Part != Text("a") {
+		t.Fatalf("other consumer = %v, %v; want a", c, err)
+	}
+
+	// Closing the last consumer closes the source with the error.
+	streams[1].Close()
+	deadline := time.After(time.Second)
+	for buf.Error() == nil {
+		select {
+		case <-deadline:
+			t.Fatal("source not closed")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if !errors.Is(buf.Error(), errConsumer) {
+		t.Errorf("source closed with %v, want %v", buf.Error(), errConsumer)
+	}
+}
@@ -0,0 +1,198 @@
+package genx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Chain returns a Transformer that runs the given transformers in order,
+// each consuming the output of the previous one:
+//
+//	pipeline := genx.Chain(asr, agent, tts)
+//	out, err := pipeline.Transform(ctx, pattern, input)
+//
+// Every stage receives the chain's pattern; use WithPattern to give a stage
+// its own. Stages are initialized in order, and if one fails the streams
+// already created are closed with its error.
+//
+// The stages keep their own EoS and StreamID handling. The chain adds error
+// short-circuiting: when the output fails, or the consumer closes it with an
+// error, the error is propagated to every stage and to the input, so stages
+// that do not propagate errors backward still stop.
+//
+// A Chain of no transformers passes the input through.
+func Chain(ts ...Transformer) Transformer {
+	return chain(ts)
+}
+
+type chain []Transformer
+
+func (c chain) Transform(ctx context.Context, pattern string, input Stream) (Stream, error) {
+	streams := make([]Stream, 0, len(c)+1)
+	streams = append(streams, input)
+	for i, t := range c {
+		out, err := t.Transform(ctx, pattern, streams[len(streams)-1])
+		if err != nil {
+			err = fmt.Errorf("genx: chain stage %d: %w", i, err)
+			closeStreams(streams, err)
+			return nil, err
+		}
+		streams = append(streams, out)
+	}
+	if len(c) == 0 {
+		return input, nil
+	}
+	return &chainStream{streams: streams}, nil
+}
+
+// chainStream is the output of a chain. streams holds the chain's input
+// followed by the output of each stage.
+type chainStream struct {
+	streams []Stream
+	once    sync.Once
+}
+
+func (s *chainStream) Next() (*MessageChunk, error) {
+	chunk, err := s.streams[len(s.streams)-1].Next()
+	if err != nil && !errors.Is(err, io.EOF) {
+		s.abort(err)
+	}
+	return chunk, err
+}
+
+func (s *chainStream) Close() error {
+	return s.CloseWithError(nil)
+}
+
+func (s *chainStream) CloseWithError(err error) error {
+	s.abort(err)
+	return nil
+}
+
+// abort closes all streams of the chain, from the output back to the input.
+func (s *chainStream) abort(err error) {
+	s.once.Do(func() { closeStreams(s.streams, err) })
+}
+
+// closeStreams closes streams in reverse order, with err if it is not nil.
+func closeStreams(streams []Stream, err error) {
+	for i := len(streams) - 1; i >= 0; i-- {
+		if err != nil {
+			streams[i].CloseWithError(err)
+		} else {
+			streams[i].Close()
+		}
+	}
+}
+
+// WithPattern returns a Transformer that runs t with the given pattern,
+// ignoring the pattern it is called with. It names the resource of one
+// stage of a Chain:
+//
+//	genx.Chain(
+//	    genx.WithPattern(asr, "doubao/asr"),
+//	    agent,
+//	    genx.WithPattern(tts, "minimax/shaonv"),
+//	)
+func WithPattern(t Transformer, pattern string) Transformer {
+	return &patternTransformer{t: t, pattern: pattern}
+}
+
+type patternTransformer struct {
+	t       Transformer
+	pattern string
+}
+
+func (p *patternTransformer) Transform(ctx context.Context, _ string, input Stream) (Stream, error) {
+	return p.t.Transform(ctx, p.pattern, input)
+}
+
+// TransformFunc adapts a chunk-by-chunk function to a Transformer, for the
+// small stages between model transformers: filters, text rewriting, role
+// changes.
+//
+// The function is called for every input chunk, including EoS markers, and
+// returns the output chunk, or nil to drop the chunk. It must not modify the
+// input chunk; copy it or build a new one. To keep sub-stream boundaries
+// intact across the adapter:
+//   - An output chunk without Ctrl gets a copy of the input's Ctrl.
+//   - An output chunk without StreamID gets the input's StreamID.
+//   - If the input is an EoS marker, so is the output; a dropped EoS marker
+//     is passed through unchanged.
+//
+// An error from the function fails the output stream with it and closes
+// the input with it. The function runs in the consumer's Next call, so it
+// should not block.
+type TransformFunc func(chunk *MessageChunk) (*MessageChunk, error)
+
+// Transform implements Transformer. The pattern is ignored.
+func (f TransformFunc) Transform(_ context.Context, _ string, input Stream) (Stream, error) {
+	return &funcStream{src: input, fn: f}, nil
+}
+
+type funcStream struct {
+	src Stream
+	fn  TransformFunc
+	err error
+}
+
+func (s *funcStream) Next() (*MessageChunk, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	for {
+		in, err := s.src.Next()
+		if err != nil {
+			return nil, err
+		}
+		out, err := s.fn(in)
+		if err != nil {
+			s.err = err
+			s.src.CloseWithError(err)
+			return nil, err
+		}
+		if out == nil {
+			if in.IsEndOfStream() {
+				return in, nil
+			}
+			continue
+		}
+		return inheritCtrl(out, in.Ctrl), nil
+	}
+}
+
+func (s *funcStream) Close() error {
+	return s.src.Close()
+}
+
+func (s *funcStream) CloseWithError(err error) error {
+	return s.src.CloseWithError(err)
+}
+
+// inheritCtrl fills the Ctrl of out from the input's ctrl, copying out's
+// Ctrl rather than modifying it.
+func inheritCtrl(out *MessageChunk, ctrl *StreamCtrl) *MessageChunk {
+	if ctrl == nil {
+		return out
+	}
+	if out.Ctrl == nil {
+		c := *out
+		inherited := *ctrl
+		c.Ctrl = &inherited
+		return &c
+	}
+	if out.Ctrl.StreamID != "" && (out.Ctrl.EndOfStream || !ctrl.EndOfStream) {
+		return out
+	}
+	c := *out
+	merged := *out.Ctrl
+	if merged.StreamID == "" {
+		merged.StreamID = ctrl.StreamID
+	}
+	merged.EndOfStream = merged.EndOfStream || ctrl.EndOfStream
+	c.Ctrl = &merged
+	return &c
+}
@@ -0,0 +1,200 @@
+package genx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// opaqueTransformer wraps its input without propagating errors backward.
+type opaqueTransformer struct {
+	pattern *string
+}
+
+func (o opaqueTransformer) Transform(_ context.Context, pattern string, input Stream) (Stream, error) {
+	if o.pattern != nil {
+		*o.pattern = pattern
+	}
+	return &opaqueStream{src: input}, nil
+}
+
+type opaqueStream struct {
+	src    Stream
+	closed error
+}
+
+func (s *opaqueStream) Next() (*MessageChunk, error) { return s.src.Next() }
+func (s *opaqueStream) Close() error                 { return s.CloseWithError(io.EOF) }
+func (s *opaqueStream) CloseWithError(err error) error {
+	s.closed = err
+	return nil
+}
+
+type failingTransformer struct{ err error }
+
+func (f failingTransformer) Transform(context.Context, string, Stream) (Stream, error) {
+	return nil, f.err
+}
+
+func readAll(t *testing.T, s Stream) []*MessageChunk {
+	t.Helper()
+	var chunks []*MessageChunk
+	for {
+		c, err := s.Next()
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		chunks = append(chunks, c)
+	}
+}
+
+func TestChain(t *testing.T) {
+	upper := TransformFunc(func(c *MessageChunk) (*MessageChunk, error) {
+		text, ok := c.Part.(Text)
+		if !ok {
+			return c, nil
+		}
+		return &MessageChunk{Role: RoleModel, Part: Text(strings.ToUpper(string(text)))}, nil
+	})
+	dropEmpty := TransformFunc(func(c *MessageChunk) (*MessageChunk, error) {
+		if c.Part == Text("") {
+			return nil, nil
+		}
+		return c, nil
+	})
+
+	input := &sliceStream{chunks: []*MessageChunk{
+		{Part: Text("hello"), Ctrl: &StreamCtrl{StreamID: "s1"}},
+		{Part: Text(""), Ctrl: &StreamCtrl{StreamID: "s1"}},
+		{Part: Text(""), Ctrl: &StreamCtrl{StreamID: "s1", EndOfStream: true}},
+		{Part: Text("bye")},
+	}}
+	out, err := Chain(upper, dropEmpty).Transform(context.Background(), "", input)
+	if err != nil {
+		t.Fatalf("Transform: %v", err)
+	}
+	got := readAll(t, out)
+
+	if len(got) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(got))
+	}
+	if got[0].Part != Text("HELLO") || got[0].Role != RoleModel || got[0].Ctrl.StreamID != "s1" {
+		t.Errorf("chunk 0 = %+v, want HELLO from model in s1", got[0])
+	}
+	if !got[1].IsEndOfStream() || got[1].Ctrl.StreamID != "s1" {
+		t.Errorf("chunk 1 = %+v, want EoS of s1", got[1])
+	}
+	if got[2].Part != Text("BYE") || got[2].Ctrl != nil {
+		t.Errorf("chunk 2 = %+v, want BYE without Ctrl", got[2])
+	}
+}
+
+func TestChain_Empty(t *testing.T) {
+	input := &sliceStream{}
+	out, err := Chain().Transform(context.Background(), "", input)
+	if err != nil {
+		t.Fatalf("Transform: %v", err)
+	}
+	if out != Stream(input) {
+		t.Error("empty Chain should return the input")
+	}
+}
+
+func TestChain_InitError(t *testing.T) {
+	errInit := errors.New("dial failed")
+	input := &sliceStream{}
+	_, err := Chain(opaqueTransformer{}, failingTransformer{errInit}).Transform(context.Background(), "", input)
+	if !errors.Is(err, errInit) {
+		t.Fatalf("err = %v, want %v", err, errInit)
+	}
+	if !strings.Contains(err.Error(), "stage 1") {
+		t.Errorf("err = %q, want the failing stage", err)
+	}
+	if !errors.Is(input.err, errInit) {
+		t.Errorf("input closed with %v, want %v", input.err, errInit)
+	}
+}
+
+func TestChain_ShortCircuit(t *testing.T) {
+	errStage := errors.New("stage failed")
+	fail := TransformFunc(func(c *MessageChunk) (*MessageChunk, error) {
+		if c.Part == Text("bad") {
+			return nil, errStage
+		}
+		return c, nil
+	})
+
+	input := &sliceStream{chunks: []*MessageChunk{
+		{Part: Text("ok")},
+		{Part: Text("bad")},
+		{Part: Text("never")},
+	}}
+	out, err := Chain(opaqueTransformer{}, fail).Transform(context.Background(), "", input)
+	if err != nil {
+		t.Fatalf("Transform: %v", err)
+	}
+	if _, err := out.Next(); err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if _, err := out.Next(); !errors.Is(err, errStage) {
+		t.Fatalf("err = %v, want %v", err, errStage)
+	}
+	if !errors.Is(input.err, errStage) {
+		t.Errorf("input closed with %v, want %v", input.err, errStage)
+	}
+	if _, err := out.Next(); !errors.Is(err, errStage) {
+		t.Errorf("after error, err = %v, want %v", err, errStage)
+	}
+}
+
+func TestChain_ConsumerCloseWithError(t *testing.T) {
+	errConsumer := errors.New("speaker gone")
+	input := &sliceStream{chunks: []*MessageChunk{{Part: Text("a")}}}
+	out, err := Chain(opaqueTransformer{}, opaqueTransformer{}).Transform(context.Background(), "", input)
+	if err != nil {
+		t.Fatalf("Transform: %v", err)
+	}
+	out.CloseWithError(errConsumer)
+	if !errors.Is(input.err, errConsumer) {
+		t.Errorf("input closed with %v, want %v", input.err, errConsumer)
+	}
+}
+
+func TestWithPattern(t *testing.T) {
+	var first, second string
+	c := Chain(
+		WithPattern(opaqueTransformer{pattern: &first}, "doubao/asr"),
+		opaqueTransformer{pattern: &second},
+	)
+	if _, err := c.Transform(context.Background(), "chain", &sliceStream{}); err != nil {
+		t.Fatalf("Transform: %v", err)
+	}
+	if first != "doubao/asr" || second != "chain" {
+		t.Errorf("patterns = %q, %q; want %q, %q", first, second, "doubao/asr", "chain")
+	}
+}
+
+func TestTransformFunc_KeepsInputCtrl(t *testing.T) {
+	ctrl := &StreamCtrl{StreamID: "s1", EndOfStream: true}
+	in := &MessageChunk{Part: Text(""), Ctrl: ctrl}
+	own := &StreamCtrl{Label: "x"}
+	f := TransformFunc(func(*MessageChunk) (*MessageChunk, error) {
+		return &MessageChunk{Part: Text(""), Ctrl: own}, nil
+	})
+	out, _ := f.Transform(context.Background(), "", &sliceStream{chunks: []*MessageChunk{in}})
+	got := readAll(t, out)
+	if len(got) != 1 {
+		t.Fatalf("got %d chunks, want 1", len(got))
+	}
+	if c := got[0].Ctrl; c.StreamID != "s1" || !c.EndOfStream || c.Label != "x" {
+		t.Errorf("Ctrl = %+v, want label x, StreamID s1, EoS", c)
+	}
+	if own.StreamID != "" || own.EndOfStream {
+		t.Error("TransformFunc modified the function's Ctrl")
+	}
+}
@@ -0,0 +1,129 @@
+package genx
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+// chunkPool holds released chunks.
+var chunkPool = sync.Pool{
+	New: func() any { return &MessageChunk{refs: new(atomic.Int32)} },
+}
+
+// blobPool holds released Blob structs.
+var blobPool = sync.Pool{
+	New: func() any { return new(Blob) },
+}
+
+const (
+	minBlobShift = 9  // smallest pooled buffer, 512 bytes
+	maxBlobShift = 18 // largest pooled buffer, 256 KiB
+)
+
+// blobBufPools hold released blob buffers by size class: pool i holds
+// buffers with a capacity of 1<<(minBlobShift+i) bytes.
+var blobBufPools [maxBlobShift - minBlobShift + 1]sync.Pool
+
+// blobSizeClass returns the index of the buffer pool for n bytes, or -1 if
+// n is too large to pool.
+func blobSizeClass(n int) int {
+	if n <= 1<<minBlobShift {
+		return 0
+	}
+	shift := bits.Len(uint(n - 1))
+	if shift > maxBlobShift {
+		return -1
+	}
+	return shift - minBlobShift
+}
+
+// AcquireChunk returns an empty MessageChunk from the chunk pool, with one
+// reference held by the caller. Return it with Release.
+func AcquireChunk() *MessageChunk {
+	c := chunkPool.Get().(*MessageChunk)
+	c.refs.Store(1)
+	return c
+}
+
+// AcquireBlob returns a Blob with size bytes of Data from the blob pool.
+// Data is not zeroed. Blobs larger than 256 KiB are allocated as usual.
+// Return it with Release, or with the Release of the chunk it is the Part
+// of.
+func AcquireBlob(mimeType string, size int) *Blob {
+	b := blobPool.Get().(*Blob)
+	b.MIMEType = mimeType
+	b.pooled = true
+	class := blobSizeClass(size)
+	if class < 0 {
+		b.Data = make([]byte, size)
+		return b
+	}
+	buf, _ := blobBufPools[class].Get().(*[]byte)
+	if buf == nil {
+		s := make([]byte, 1<<(minBlobShift+class))
+		buf = &s
+	}
+	b.buf = buf
+	b.Data = (*buf)[:size]
+	return b
+}
+
+// Retain adds a reference to a chunk from AcquireChunk, for handing it to
+// one more owner; each owner calls Release. It does nothing for other
+// chunks. It returns c.
+func (c *MessageChunk) Retain() *MessageChunk {
+	if c.refs != nil {
+		c.refs.Add(1)
+	}
+	return c
+}
+
+// Release drops a reference to c. When the last reference of a chunk from
+// AcquireChunk is dropped, the chunk and its Blob part are returned to
+// their pools. It does nothing for other chunks, which may be shared
+// without references.
+func (c *MessageChunk) Release() {
+	if c == nil || c.refs == nil {
+		return
+	}
+	switch n := c.refs.Add(-1); {
+	case n > 0:
+		return
+	case n < 0:
+		panic("genx: MessageChunk released too often")
+	}
+	if b, ok := c.Part.(*Blob); ok {
+		b.Release()
+	}
+	*c = MessageChunk{refs: c.refs}
+	chunkPool.Put(c)
+}
+
+// Release returns a blob from AcquireBlob and its buffer to their pools.
+// It does nothing for other blobs.
+func (b *Blob) Release() {
+	if b == nil || !b.pooled {
+		return
+	}
+	if b.buf != nil {
+		blobBufPools[blobSizeClass(cap(*b.buf))].Put(b.buf)
+	}
+	*b = Blob{}
+	blobPool.Put(b)
+}
+
+// ClonePooled returns a deep copy of c like Clone, with the chunk and a
+// Blob part taken from the pools.
+func (c *MessageChunk) ClonePooled() *MessageChunk {
+	chk := AcquireChunk()
+	c.cloneHeaderTo(chk)
+	if b, ok := c.Part.(*Blob); ok {
+		nb := AcquireBlob(b.MIMEType, len(b.Data))
+		copy(nb.Data, b.Data)
+		chk.Part = nb
+	} else if c.Part != nil {
+		chk.Part = c.Part.clone()
+	}
+	return chk
+}
@@ -0,0 +1,282 @@
+package genx
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// frameBytes is a 20ms frame of 48kHz 16-bit stereo PCM.
+const frameBytes = 48000 / 50 * 2 * 2
+
+func pooledChunk(data []byte) *MessageChunk {
+	chunk := AcquireChunk()
+	blob := AcquireBlob("audio/pcm", len(data))
+	copy(blob.Data, data)
+	chunk.Role, chunk.Part = RoleUser, blob
+	return chunk
+}
+
+func TestBlobSizeClass(t *testing.T) {
+	tests := []struct {
+		n, want int
+	}{
+		{0, 0}, {1, 0}, {512, 0}, {513, 1}, {1024, 1},
+		{frameBytes, 3}, {1 << 18, 9}, {1<<18 + 1, -1},
+	}
+	for _, tt := range tests {
+		if got := blobSizeClass(tt.n); got != tt.want {
+			t.Errorf("blobSizeClass(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestAcquireBlob(t *testing.T) {
+	for _, size := range []int{0, 100, frameBytes, 1<<18 + 1} {
+		b := AcquireBlob("audio/pcm", size)
+		if b.MIMEType != "audio/pcm" || len(b.Data) != size {
+			t.Errorf("AcquireBlob(%d) = %q with %d bytes", size, b.MIMEType, len(b.Data))
+		}
+		b.Release()
+		if b.Data != nil || b.MIMEType != "" {
+			t.Errorf("released blob of %d bytes not reset", size)
+		}
+	}
+}
+
+func TestChunkRelease(t *testing.T) {
+	chunk := pooledChunk([]byte("frame"))
+	blob := chunk.Part.(*Blob)
+	chunk.Release()
+	if chunk.Part != nil || chunk.Role != "" {
+		t.Errorf("released chunk not reset: %+v", chunk)
+	}
+	if blob.Data != nil {
+		t.Error("blob of released chunk not released")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("second Release did not panic")
+		}
+	}()
+	chunk.Release()
+}
+
+func TestChunkRetain(t *testing.T) {
+	chunk := pooledChunk([]byte("frame"))
+	chunk.Retain()
+	chunk.Release()
+	if chunk.Part == nil {
+		t.Fatal("chunk released while still referenced")
+	}
+	chunk.Release()
+	if chunk.Part != nil {
+		t.Error("chunk not released by its last owner")
+	}
+}
+
+func TestChunkReleaseUnpooled(t *testing.T) {
+	// Unpooled chunks may be shared without references, so Release keeps
+	// them and their parts intact, even pooled blobs.
+	blob := AcquireBlob("audio/pcm", 4)
+	chunk := &MessageChunk{Role: RoleUser, Part: blob}
+	chunk.Retain()
+	chunk.Release()
+	chunk.Release()
+	if chunk.Part != blob || blob.Data == nil {
+		t.Errorf("unpooled chunk modified: %+v", chunk)
+	}
+
+	plain := &Blob{MIMEType: "audio/pcm", Data: []byte("x")}
+	plain.Release()
+	if string(plain.Data) != "x" {
+		t.Error("unpooled blob modified")
+	}
+}
+
+func TestClonePooled(t *testing.T) {
+	src := &MessageChunk{Role: RoleModel, Name: "bot", Part: &Blob{MIMEType: "audio/pcm", Data: []byte("abc")}}
+	src.SetMetadata("k", "v")
+
+	c := src.ClonePooled()
+	c.Part.(*Blob).Data[0] = 'x'
+	c.SetMetadata("k", "w")
+	if string(src.Part.(*Blob).Data) != "abc" || src.Metadata("k") != "v" {
+		t.Error("clone shares data with the source")
+	}
+	if c.Role != RoleModel || c.Name != "bot" || string(c.Part.(*Blob).Data) != "xbc" {
+		t.Errorf("clone = %+v", c)
+	}
+	c.Release()
+	if c.Part != nil {
+		t.Error("clone is not pooled")
+	}
+
+	text := (&MessageChunk{Part: Text("hi")}).ClonePooled()
+	if text.Part != Text("hi") {
+		t.Errorf("text clone = %+v", text)
+	}
+	text.Release()
+}
+
+func TestBroadcastPooled(t *testing.T) {
+	chunk := pooledChunk([]byte("frame"))
+	streams := Broadcast(&sliceStream{chunks: []*MessageChunk{chunk}},
+		BroadcastConsumer{}, BroadcastConsumer{}, BroadcastConsumer{})
+
+	var wg sync.WaitGroup
+	for i, s := range streams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := s.Next()
+			if err != nil {
+				t.Errorf("stream %d: %v", i, err)
+				return
+			}
+			if !bytes.Equal(c.Part.(*Blob).Data, []byte("frame")) {
+				t.Errorf("stream %d: data = %q", i, c.Part.(*Blob).Data)
+			}
+			c.Release()
+			if _, err := s.Next(); err != io.EOF {
+				t.Errorf("stream %d: err = %v, want EOF", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if chunk.Part != nil {
+		t.Error("chunk not released after every consumer released it")
+	}
+}
+
+func TestBroadcastDropPooled(t *testing.T) {
+	chunks := []*MessageChunk{pooledChunk([]byte("a")), pooledChunk([]byte("b"))}
+	for _, c := range chunks {
+		c.Retain() // the test's reference
+	}
+	src := &endSignalStream{Stream: &sliceStream{chunks: chunks}, end: make(chan struct{})}
+	streams := Broadcast(src, BroadcastConsumer{Buffer: 1, Policy: SlowConsumerDrop})
+	<-src.end // "a" is buffered, "b" dropped
+	for {
+		c, err := streams[0].Next()
+		if err != nil {
+			break
+		}
+		c.Release()
+	}
+	// Whether delivered or dropped, the consumer's references are released
+	for i, c := range chunks {
+		if c.Release(); c.Part != nil {
+			t.Errorf("chunk %d not released", i)
+		}
+	}
+}
+
+// endSignalStream closes end when its stream ends.
+type endSignalStream struct {
+	Stream
+	end chan struct{}
+}
+
+func (s *endSignalStream) Next() (*MessageChunk, error) {
+	c, err := s.Stream.Next()
+	if err != nil {
+		close(s.end)
+	}
+	return c, err
+}
+
+func TestBufferedStreamDropPooled(t *testing.T) {
+	s := NewBufferedStream(BufferedStreamConfig{Size: 1, Policy: SlowConsumerDrop})
+	kept, dropped := pooledChunk([]byte("a")), pooledChunk([]byte("b"))
+	dropped.Retain() // the test's reference
+	for _, c := range []*MessageChunk{kept, dropped} {
+		if err := s.Write(context.Background(), c); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if dropped.Release(); dropped.Part != nil {
+		t.Error("dropped chunk not released")
+	}
+	if c, err := s.Next(); err != nil || c != kept {
+		t.Fatalf("Next = %v, %v; want the kept chunk", c, err)
+	}
+	kept.Release()
+}
+
+func TestTeePooled(t *testing.T) {
+	chunk := pooledChunk([]byte("frame"))
+	sb := NewStreamBuilder((&ModelContextBuilder{}).Build(), 10)
+	s := Tee(&sliceStream{chunks: []*MessageChunk{chunk}}, sb)
+
+	c, err := s.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	c.Release()
+	teed, err := sb.Stream().Next()
+	if err != nil {
+		t.Fatalf("builder Next: %v", err)
+	}
+	if teed.Part == nil {
+		t.Fatal("teed chunk released by the other owner")
+	}
+	teed.Release()
+	if chunk.Part != nil {
+		t.Error("chunk not released")
+	}
+}
+
+// chunkSink keeps benchmark chunks on the heap.
+var chunkSink atomic.Pointer[MessageChunk]
+
+func BenchmarkChunkAlloc(b *testing.B) {
+	frame := make([]byte, frameBytes)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			chunkSink.Store(&MessageChunk{Role: RoleUser, Part: &Blob{MIMEType: "audio/pcm", Data: bytes.Clone(frame)}})
+		}
+	})
+}
+
+func BenchmarkChunkPool(b *testing.B) {
+	frame := make([]byte, frameBytes)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pooledChunk(frame).Release()
+		}
+	})
+}
+
+func BenchmarkBroadcastPooled(b *testing.B) {
+	frame := make([]byte, frameBytes)
+	chunks := make([]*MessageChunk, b.N)
+	for i := range chunks {
+		chunks[i] = pooledChunk(frame)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	streams := TeeN(&sliceStream{chunks: chunks}, 2)
+	var wg sync.WaitGroup
+	for _, s := range streams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				c, err := s.Next()
+				if err != nil {
+					return
+				}
+				c.Release()
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -24,8 +24,8 @@
 //   - genx/input: Convert external sources to genx.Stream
 //     (e.g., audio input with realtime pacing, jitter buffer)
 //
-//   - genx/output: Route genx.Stream to multiple outputs
-//     (demux by StreamID, Role, and MIME type)
+//   - genx/output: Sinks consuming genx.Stream
+//     (e.g., posting conversation turns to a webhook)
 //
 //   - genx/transformers: Stream transformers
 //     (TTS, ASR, realtime models - may modify any MessageChunk field)
@@ -40,6 +40,9 @@
 //
 //   - genx/luau: Luau scripting integration
 //
+//   - genx/record: Record streams to files and replay them
+//     (offline reproduction, regression inputs)
+//
 // # Data Flow Example
 //
 // A typical audio conversation pipeline:
@@ -49,4 +52,34 @@
 //
 // Notice that Role stays "user" through ASR (it's still user's words),
 // and becomes "model" after the Agent processes it.
+//
+// # Chunk Pooling
+//
+// High-throughput audio paths (hundreds of 48kHz streams through one
+// gateway) allocate a MessageChunk and a Blob buffer per 20ms frame. To cut
+// the GC pressure, producers take chunks and blobs from pools with
+// AcquireChunk and AcquireBlob, and the last consumer returns them with
+// Release:
+//
+//	chunk := genx.AcquireChunk()
+//	blob := genx.AcquireBlob("audio/pcm", len(frame))
+//	copy(blob.Data, frame)
+//	chunk.Role, chunk.Part = genx.RoleUser, blob
+//	builder.Add(chunk)
+//	...
+//	chunk, err := stream.Next()
+//	play(chunk)
+//	chunk.Release() // returns the chunk and its blob
+//
+// Ownership follows the stream: a chunk returned by Stream.Next belongs to
+// the caller, which either passes it on or releases it when done. After
+// Release, neither the chunk nor its Part may be used. Code handing one
+// chunk to several owners calls Retain once per additional owner; Broadcast
+// and Tee do so. Transformers release the chunks they consume and do not
+// pass on, e.g. the audio sent to an ASR service.
+//
+// Release is optional: chunks never released are collected by the GC as
+// usual, and Release does nothing for chunks not taken from the pool.
+// Releasing too early or twice, however, corrupts the data of other
+// streams.
 package genx
@@ -16,6 +16,8 @@ import (
 
 var _ Generator = (*GeminiGenerator)(nil)
 
+const geminiMaxStopSequences = 5
+
 // GeminiGenerator implements Generator using Google Gemini API.
 type GeminiGenerator struct {
 	Client *genai.Client `json:"-"`
@@ -74,7 +76,7 @@ func (g *GeminiGenerator) GenerateStream(ctx context.Context, _ string, mctx Mod
 			sb.Abort(err)
 		}
 	}()
-	return sb.Stream(), nil
+	return StopAt(sb.Stream(), stopSequences(g.InvokeParams, mctx.Params())...), nil
 }
 
 func geminiPull(builder *StreamBuilder, itr iter.Seq2[*genai.GenerateContentResponse, error]) error {
@@ -83,6 +85,9 @@ func geminiPull(builder *StreamBuilder, itr iter.Seq2[*genai.GenerateContentResp
 		if err != nil {
 			return err
 		}
+		if chunk.UsageMetadata != nil {
+			builder.SetUsage(geminiConvUsage(chunk.UsageMetadata))
+		}
 		if len(chunk.Candidates) == 0 {
 			continue
 		}
@@ -204,6 +209,14 @@ func geminiConvMessage(last *genai.Content, msg *Message) (new *genai.Content, e
 				parts = append(parts, genai.NewPartFromText(string(v)))
 			case *Blob:
 				parts = append(parts, genai.NewPartFromBytes(v.Data, v.MIMEType))
+			case *ImagePart:
+				if len(v.Data) == 0 {
+					parts = append(parts, genai.NewPartFromURI(v.URL, v.MIMEType))
+				} else {
+					parts = append(parts, genai.NewPartFromBytes(v.Data, v.MIMEType))
+				}
+			case *VideoFramePart:
+				parts = append(parts, genai.NewPartFromBytes(v.Data, v.MIMEType))
 			}
 		}
 	case *ToolCall:
@@ -269,6 +282,10 @@ func (g *GeminiGenerator) convModelContext(mctx ModelContext) (*genai.GenerateCo
 		cfg.TopP = &mp.TopP
 		cfg.TopK = &mp.TopK
 	}
+	if stop := stopSequences(g.InvokeParams, mctx.Params()); len(stop) > 0 {
+		// The API takes up to 5; GenerateStream enforces the rest.
+		cfg.StopSequences = stop[:min(len(stop), geminiMaxStopSequences)]
+	}
 
 	tools := []*genai.Tool{}
 	for t := range mctx.Tools() {
@@ -359,6 +376,9 @@ func geminiConvSchema(schema *jsonschema.Schema) *genai.Schema {
 }
 
 func geminiConvUsage(usage *genai.GenerateContentResponseUsageMetadata) Usage {
+	if usage == nil {
+		return Usage{}
+	}
 	return Usage{
 		PromptTokenCount:        int64(usage.PromptTokenCount),
 		CachedContentTokenCount: int64(usage.CachedContentTokenCount),
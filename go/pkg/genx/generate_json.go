@@ -0,0 +1,138 @@
+package genx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// ErrInvalidOutput is returned by GenerateJSON when the model output does
+// not match the schema after all retries.
+var ErrInvalidOutput = errors.New("genx: output does not match schema")
+
+// GenerateJSONOption configures GenerateJSON.
+type GenerateJSONOption func(*generateJSONConfig)
+
+type generateJSONConfig struct {
+	name        string
+	description string
+	maxRetries  int
+}
+
+// WithJSONOutputName sets the name and description of the output, which
+// generators send as the JSON schema or function name (default "output").
+func WithJSONOutputName(name, description string) GenerateJSONOption {
+	return func(c *generateJSONConfig) {
+		c.name = name
+		c.description = description
+	}
+}
+
+// WithJSONMaxRetries sets how many times invalid output is sent back to the
+// model with a repair prompt (default 2). Zero disables repairs.
+func WithJSONMaxRetries(n int) GenerateJSONOption {
+	return func(c *generateJSONConfig) {
+		c.maxRetries = max(n, 0)
+	}
+}
+
+// GenerateJSON generates a value of type T with model. The output is
+// requested through Invoke, so generators use JSON output (response_format)
+// where they support it and a function call otherwise.
+//
+// The output is validated against schema, or the schema of T if schema is
+// nil. Malformed JSON is repaired where possible; output that is still
+// invalid is sent back to the model with the validation error, up to the
+// retry limit, after which the error wraps ErrInvalidOutput. Usage is summed
+// over all attempts.
+//
+//	type Answer struct {
+//	    City  string `json:"city"`
+//	    Score int    `json:"score"`
+//	}
+//	answer, usage, err := genx.GenerateJSON[Answer](ctx, gen, "openai/gpt-4o", mctx, nil)
+func GenerateJSON[T any](ctx context.Context, gen Generator, model string, mctx ModelContext, schema *jsonschema.Schema, opts ...GenerateJSONOption) (T, Usage, error) {
+	var zero T
+	cfg := generateJSONConfig{
+		name:        "output",
+		description: "Output the result as JSON matching the schema.",
+		maxRetries:  2,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if schema == nil {
+		s, err := jsonschema.For[T](nil)
+		if err != nil {
+			return zero, Usage{}, fmt.Errorf("genx: schema for %T: %w", zero, err)
+		}
+		schema = s
+	}
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		return zero, Usage{}, fmt.Errorf("genx: resolve schema: %w", err)
+	}
+
+	tool := &FuncTool{
+		Name:        cfg.name,
+		Description: cfg.description,
+		Argument:    schema,
+	}
+
+	var total Usage
+	for attempt := 0; ; attempt++ {
+		usage, call, err := gen.Invoke(ctx, model, mctx, tool)
+		total = total.Add(usage)
+		if err != nil {
+			return zero, total, err
+		}
+		if call == nil {
+			return zero, total, errors.New("genx: no output returned")
+		}
+
+		v, err := decodeJSONOutput[T](call.Arguments, resolved)
+		if err == nil {
+			return v, total, nil
+		}
+		if attempt >= cfg.maxRetries {
+			return zero, total, fmt.Errorf("%w: %w", ErrInvalidOutput, err)
+		}
+
+		var repair ModelContextBuilder
+		repair.ModelText("", call.Arguments)
+		repair.UserText("", fmt.Sprintf(
+			"The output above is invalid: %v\nRespond again with only the corrected JSON, matching the schema of %s.",
+			err, cfg.name))
+		mctx = ModelContexts(mctx, repair.Build())
+	}
+}
+
+// decodeJSONOutput validates output against schema and decodes it into a T.
+func decodeJSONOutput[T any](output string, schema *jsonschema.Resolved) (T, error) {
+	var zero T
+	data := []byte(output)
+	var instance any
+	if err := json.Unmarshal(data, &instance); err != nil {
+		// Decode the repaired JSON, as validated, if it can be repaired.
+		var repaired json.RawMessage
+		if unmarshalJSON(data, &repaired) != nil {
+			return zero, fmt.Errorf("invalid JSON: %w", err)
+		}
+		data = repaired
+		if err := json.Unmarshal(data, &instance); err != nil {
+			return zero, fmt.Errorf("invalid JSON: %w", err)
+		}
+	}
+	if err := schema.Validate(instance); err != nil {
+		return zero, err
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return zero, err
+	}
+	return v, nil
+}
@@ -0,0 +1,125 @@
+package genx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// jsonGenerator answers Invoke with outputs in turn and records the last
+// message of each request.
+type jsonGenerator struct {
+	outputs  []string
+	err      error
+	requests []string
+	tools    []*FuncTool
+}
+
+func (g *jsonGenerator) GenerateStream(context.Context, string, ModelContext) (Stream, error) {
+	return nil, errors.New("not supported")
+}
+
+func (g *jsonGenerator) Invoke(_ context.Context, _ string, mctx ModelContext, tool *FuncTool) (Usage, *FuncCall, error) {
+	g.tools = append(g.tools, tool)
+	var last string
+	for msg := range mctx.Messages() {
+		if c, ok := msg.Payload.(Contents); ok {
+			for _, p := range c {
+				if t, ok := p.(Text); ok {
+					last = string(t)
+				}
+			}
+		}
+	}
+	g.requests = append(g.requests, last)
+	if g.err != nil {
+		return Usage{}, nil, g.err
+	}
+	out := g.outputs[0]
+	g.outputs = g.outputs[1:]
+	return Usage{PromptTokenCount: 10, GeneratedTokenCount: 5}, tool.NewFuncCall(out), nil
+}
+
+type weather struct {
+	City string `json:"city"`
+	Temp int    `json:"temp"`
+}
+
+func weatherContext() ModelContext {
+	var mcb ModelContextBuilder
+	mcb.UserText("", "weather in Paris?")
+	return mcb.Build()
+}
+
+func TestGenerateJSON(t *testing.T) {
+	gen := &jsonGenerator{outputs: []string{`{"city": "Paris", "temp": 21}`}}
+	w, usage, err := GenerateJSON[weather](context.Background(), gen, "m", weatherContext(), nil)
+	if err != nil {
+		t.Fatalf("GenerateJSON() error = %v", err)
+	}
+	if w != (weather{City: "Paris", Temp: 21}) {
+		t.Errorf("value = %+v", w)
+	}
+	if usage.GeneratedTokenCount != 5 {
+		t.Errorf("usage = %+v", usage)
+	}
+	if tool := gen.tools[0]; tool.Name != "output" || tool.Argument == nil {
+		t.Errorf("tool = %+v, want output with the schema of T", tool)
+	}
+}
+
+func TestGenerateJSON_Repair(t *testing.T) {
+	gen := &jsonGenerator{outputs: []string{
+		`{"city": "Paris", "temp": "warm"}`,
+		`{"city": "Paris", "temp": 21}`,
+	}}
+	w, usage, err := GenerateJSON[weather](context.Background(), gen, "m", weatherContext(), nil,
+		WithJSONOutputName("weather", "The weather."))
+	if err != nil {
+		t.Fatalf("GenerateJSON() error = %v", err)
+	}
+	if w.Temp != 21 {
+		t.Errorf("value = %+v", w)
+	}
+	if usage.PromptTokenCount != 20 {
+		t.Errorf("usage = %+v, want the sum of both attempts", usage)
+	}
+	if len(gen.requests) != 2 || !strings.Contains(gen.requests[1], "invalid") || !strings.Contains(gen.requests[1], "weather") {
+		t.Errorf("requests = %q, want a repair prompt", gen.requests)
+	}
+}
+
+func TestGenerateJSON_GivesUp(t *testing.T) {
+	gen := &jsonGenerator{outputs: []string{`{}`, `{}`}}
+	_, _, err := GenerateJSON[map[string]any](context.Background(), gen, "m", weatherContext(),
+		weatherSchema(t), WithJSONMaxRetries(1))
+	if !errors.Is(err, ErrInvalidOutput) {
+		t.Fatalf("GenerateJSON() error = %v, want ErrInvalidOutput", err)
+	}
+	if len(gen.requests) != 2 {
+		t.Errorf("attempts = %d, want 2", len(gen.requests))
+	}
+}
+
+func TestGenerateJSON_InvokeError(t *testing.T) {
+	boom := errors.New("boom")
+	gen := &jsonGenerator{err: boom}
+	if _, _, err := GenerateJSON[weather](context.Background(), gen, "m", weatherContext(), nil); !errors.Is(err, boom) {
+		t.Fatalf("GenerateJSON() error = %v, want boom", err)
+	}
+	if len(gen.requests) != 1 {
+		t.Errorf("attempts = %d, want no retry", len(gen.requests))
+	}
+}
+
+func weatherSchema(t *testing.T) *jsonschema.Schema {
+	t.Helper()
+	s, err := jsonschema.For[weather](nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
@@ -0,0 +1,173 @@
+package generators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/openai/openai-go"
+	"google.golang.org/genai"
+)
+
+var _ genx.Generator = (*FallbackGenerator)(nil)
+
+// FallbackGenerator tries a chain of models in order: when a model fails
+// with an error that another model may not have (see IsRetryable: rate
+// limits, server errors, timeouts and connection errors), the next one is
+// tried. Other errors, such as an invalid request, are returned as is.
+//
+// Models are patterns resolved through Mux at call time, so a chain can
+// mix providers and be registered as a model itself:
+//
+//	generators.Handle("chat/default", &generators.FallbackGenerator{
+//		Models: []string{"qwen/turbo", "local/llama3"},
+//	})
+//
+// The pattern the FallbackGenerator is called with is ignored.
+type FallbackGenerator struct {
+	// Models are the patterns of the models to try, in order.
+	Models []string
+
+	// Mux resolves Models. Default DefaultMux.
+	Mux *Mux
+
+	// ShouldFallback reports whether an error moves on to the next model.
+	// Default IsRetryable.
+	ShouldFallback func(error) bool
+}
+
+func (f *FallbackGenerator) mux() *Mux {
+	if f.Mux != nil {
+		return f.Mux
+	}
+	return DefaultMux
+}
+
+func (f *FallbackGenerator) shouldFallback(ctx context.Context, err error) bool {
+	// Once the caller gives up, no model will do better.
+	if ctx.Err() != nil {
+		return false
+	}
+	if f.ShouldFallback != nil {
+		return f.ShouldFallback(err)
+	}
+	return IsRetryable(err)
+}
+
+// GenerateStream implements genx.Generator. A model counts as failed if it
+// fails before producing its first chunk, so GenerateStream waits for the
+// first chunk; errors later in the stream are returned by the stream.
+func (f *FallbackGenerator) GenerateStream(ctx context.Context, _ string, mctx genx.ModelContext) (genx.Stream, error) {
+	if len(f.Models) == 0 {
+		return nil, errors.New("generators: fallback has no models")
+	}
+	var errs []error
+	for i, model := range f.Models {
+		stream, err := f.mux().GenerateStream(ctx, model, mctx)
+		var first *genx.MessageChunk
+		if err == nil {
+			first, err = stream.Next()
+			if err != nil {
+				stream.Close()
+			}
+		}
+		if err == nil || errors.Is(err, genx.ErrDone) || errors.Is(err, io.EOF) {
+			if err != nil {
+				// Done without a chunk: an empty but successful stream.
+				return &peekedStream{Stream: stream, err: err}, nil
+			}
+			return &peekedStream{Stream: stream, first: first}, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", model, err))
+		if !f.shouldFallback(ctx, err) {
+			break
+		}
+		if i+1 < len(f.Models) {
+			slog.Warn("generators: falling back", "from", model, "to", f.Models[i+1], "error", err)
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Invoke implements genx.Generator.
+func (f *FallbackGenerator) Invoke(ctx context.Context, _ string, mctx genx.ModelContext, tool *genx.FuncTool) (genx.Usage, *genx.FuncCall, error) {
+	if len(f.Models) == 0 {
+		return genx.Usage{}, nil, errors.New("generators: fallback has no models")
+	}
+	var errs []error
+	for i, model := range f.Models {
+		usage, call, err := f.mux().Invoke(ctx, model, mctx, tool)
+		if err == nil {
+			return usage, call, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", model, err))
+		if !f.shouldFallback(ctx, err) {
+			break
+		}
+		if i+1 < len(f.Models) {
+			slog.Warn("generators: falling back", "from", model, "to", f.Models[i+1], "error", err)
+		}
+	}
+	return genx.Usage{}, nil, errors.Join(errs...)
+}
+
+// IsRetryable reports whether err is likely transient or specific to the
+// model that returned it: HTTP 429 or 5xx from an OpenAI-compatible or
+// Gemini API, a timeout, or a connection error.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var oaiErr *openai.Error
+	if errors.As(err, &oaiErr) {
+		return retryableStatus(oaiErr.StatusCode)
+	}
+	var gErr genai.APIError
+	if errors.As(err, &gErr) {
+		return retryableStatus(gErr.Code)
+	}
+	var gErrPtr *genai.APIError
+	if errors.As(err, &gErrPtr) {
+		return retryableStatus(gErrPtr.Code)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func retryableStatus(code int) bool {
+	return code == 429 || code >= 500
+}
+
+// peekedStream returns a chunk read ahead of the stream, or the error the
+// stream ended with, before the rest of the stream.
+type peekedStream struct {
+	genx.Stream
+	first *genx.MessageChunk
+	err   error
+}
+
+func (s *peekedStream) Next() (*genx.MessageChunk, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.first != nil {
+		c := s.first
+		s.first = nil
+		return c, nil
+	}
+	return s.Stream.Next()
+}
+
+// Usage implements genx.UsageStream; it is zero if the stream does not
+// report usage.
+func (s *peekedStream) Usage() genx.Usage {
+	usage, _ := genx.StreamUsage(s.Stream)
+	return usage
+}
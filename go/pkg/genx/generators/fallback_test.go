@@ -0,0 +1,235 @@
+package generators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/openai/openai-go"
+	"google.golang.org/genai"
+)
+
+// sliceStream yields chunks, then err.
+type sliceStream struct {
+	chunks []*genx.MessageChunk
+	err    error
+	closed bool
+}
+
+func (s *sliceStream) Next() (*genx.MessageChunk, error) {
+	if len(s.chunks) == 0 {
+		return nil, s.err
+	}
+	c := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return c, nil
+}
+
+func (s *sliceStream) Close() error                   { s.closed = true; return nil }
+func (s *sliceStream) CloseWithError(err error) error { return s.Close() }
+
+// scriptedGenerator fails with err, or streams text and invokes call.
+type scriptedGenerator struct {
+	err    error
+	text   string
+	calls  int
+	stream *sliceStream
+}
+
+func (g *scriptedGenerator) GenerateStream(ctx context.Context, model string, mctx genx.ModelContext) (genx.Stream, error) {
+	g.calls++
+	g.stream = &sliceStream{err: genx.ErrDone}
+	if g.err != nil {
+		g.stream.err = g.err
+	} else {
+		g.stream.chunks = []*genx.MessageChunk{{Role: genx.RoleModel, Part: genx.Text(g.text)}}
+	}
+	return g.stream, nil
+}
+
+func (g *scriptedGenerator) Invoke(ctx context.Context, model string, mctx genx.ModelContext, tool *genx.FuncTool) (genx.Usage, *genx.FuncCall, error) {
+	g.calls++
+	if g.err != nil {
+		return genx.Usage{}, nil, g.err
+	}
+	return genx.Usage{}, &genx.FuncCall{Name: g.text}, nil
+}
+
+func newFallbackMux(t *testing.T, gens map[string]*scriptedGenerator) *Mux {
+	t.Helper()
+	mux := NewMux()
+	for pattern, gen := range gens {
+		if err := mux.Handle(pattern, gen); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return mux
+}
+
+func TestFallbackGenerator_Invoke(t *testing.T) {
+	primary := &scriptedGenerator{err: genai.APIError{Code: 429, Message: "quota"}}
+	secondary := &scriptedGenerator{err: genai.APIError{Code: 503}}
+	local := &scriptedGenerator{text: "local"}
+	f := &FallbackGenerator{
+		Models: []string{"a/primary", "b/secondary", "c/local"},
+		Mux:    newFallbackMux(t, map[string]*scriptedGenerator{"a/primary": primary, "b/secondary": secondary, "c/local": local}),
+	}
+
+	_, call, err := f.Invoke(context.Background(), "", nil, nil)
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if call.Name != "local" {
+		t.Errorf("call.Name = %q, want local", call.Name)
+	}
+	if primary.calls != 1 || secondary.calls != 1 || local.calls != 1 {
+		t.Errorf("calls = %d, %d, %d; want 1 each", primary.calls, secondary.calls, local.calls)
+	}
+}
+
+func TestFallbackGenerator_NonRetryableStops(t *testing.T) {
+	primary := &scriptedGenerator{err: genai.APIError{Code: 400, Message: "bad request"}}
+	secondary := &scriptedGenerator{text: "secondary"}
+	f := &FallbackGenerator{
+		Models: []string{"a/primary", "b/secondary"},
+		Mux:    newFallbackMux(t, map[string]*scriptedGenerator{"a/primary": primary, "b/secondary": secondary}),
+	}
+
+	_, _, err := f.Invoke(context.Background(), "", nil, nil)
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 400 {
+		t.Fatalf("Invoke() error = %v, want the 400", err)
+	}
+	if secondary.calls != 0 {
+		t.Error("fell back on a non-retryable error")
+	}
+}
+
+func TestFallbackGenerator_AllFail(t *testing.T) {
+	primary := &scriptedGenerator{err: genai.APIError{Code: 500}}
+	secondary := &scriptedGenerator{err: genai.APIError{Code: 502}}
+	f := &FallbackGenerator{
+		Models: []string{"a/primary", "b/secondary"},
+		Mux:    newFallbackMux(t, map[string]*scriptedGenerator{"a/primary": primary, "b/secondary": secondary}),
+	}
+
+	_, err := f.GenerateStream(context.Background(), "", nil)
+	if err == nil {
+		t.Fatal("GenerateStream() expected error")
+	}
+	for _, want := range []string{"a/primary", "b/secondary", "Error 500", "Error 502"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+	if !primary.stream.closed || !secondary.stream.closed {
+		t.Error("failed streams not closed")
+	}
+}
+
+func TestFallbackGenerator_GenerateStream(t *testing.T) {
+	primary := &scriptedGenerator{err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+	secondary := &scriptedGenerator{text: "hello"}
+	f := &FallbackGenerator{
+		Models: []string{"a/primary", "b/secondary"},
+		Mux:    newFallbackMux(t, map[string]*scriptedGenerator{"a/primary": primary, "b/secondary": secondary}),
+	}
+
+	stream, err := f.GenerateStream(context.Background(), "", nil)
+	if err != nil {
+		t.Fatalf("GenerateStream() error = %v", err)
+	}
+	defer stream.Close()
+
+	chunk, err := stream.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if text, _ := chunk.Part.(genx.Text); text != "hello" {
+		t.Errorf("chunk = %v, want hello", chunk.Part)
+	}
+	if _, err := stream.Next(); !errors.Is(err, genx.ErrDone) {
+		t.Errorf("Next() error = %v, want ErrDone", err)
+	}
+}
+
+func TestFallbackGenerator_EmptyStreamSucceeds(t *testing.T) {
+	primary := &scriptedGenerator{err: io.EOF}
+	secondary := &scriptedGenerator{text: "unused"}
+	f := &FallbackGenerator{
+		Models: []string{"a/primary", "b/secondary"},
+		Mux:    newFallbackMux(t, map[string]*scriptedGenerator{"a/primary": primary, "b/secondary": secondary}),
+	}
+
+	stream, err := f.GenerateStream(context.Background(), "", nil)
+	if err != nil {
+		t.Fatalf("GenerateStream() error = %v", err)
+	}
+	if _, err := stream.Next(); err != io.EOF {
+		t.Errorf("Next() error = %v, want io.EOF", err)
+	}
+	if secondary.calls != 0 {
+		t.Error("fell back after an empty stream")
+	}
+}
+
+func TestFallbackGenerator_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	primary := &scriptedGenerator{err: genai.APIError{Code: 503}}
+	secondary := &scriptedGenerator{text: "unused"}
+	f := &FallbackGenerator{
+		Models: []string{"a/primary", "b/secondary"},
+		Mux:    newFallbackMux(t, map[string]*scriptedGenerator{"a/primary": primary, "b/secondary": secondary}),
+	}
+
+	if _, _, err := f.Invoke(ctx, "", nil, nil); err == nil {
+		t.Fatal("Invoke() expected error")
+	}
+	if secondary.calls != 0 {
+		t.Error("fell back after the context was canceled")
+	}
+}
+
+func TestFallbackGenerator_NoModels(t *testing.T) {
+	f := &FallbackGenerator{}
+	if _, err := f.GenerateStream(context.Background(), "", nil); err == nil {
+		t.Error("GenerateStream() expected error")
+	}
+	if _, _, err := f.Invoke(context.Background(), "", nil, nil); err == nil {
+		t.Error("Invoke() expected error")
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain", errors.New("boom"), false},
+		{"openai 429", &openai.Error{StatusCode: 429}, true},
+		{"openai 500", &openai.Error{StatusCode: 500}, true},
+		{"openai 400", &openai.Error{StatusCode: 400}, false},
+		{"genai 503", genai.APIError{Code: 503}, true},
+		{"genai pointer 429", &genai.APIError{Code: 429}, true},
+		{"genai 404", genai.APIError{Code: 404}, false},
+		{"wrapped", fmt.Errorf("genx: %w", genai.APIError{Code: 502}), true},
+		{"deadline", context.DeadlineExceeded, true},
+		{"canceled", context.Canceled, false},
+		{"net", &net.OpError{Op: "dial", Err: errors.New("refused")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
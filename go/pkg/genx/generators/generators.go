@@ -1,4 +1,7 @@
-// Package generators provides a multiplexer for genx.Generator routing.
+// Package generators provides a multiplexer for genx.Generator routing,
+// a generator for OpenAI-compatible endpoints (vLLM, Ollama, llama.cpp
+// server), a FallbackGenerator that chains models, and usage accounting
+// with GeneratorStats.
 package generators
 
 import (
@@ -60,7 +63,11 @@ func (gm *Mux) GenerateStream(ctx context.Context, name string, mctx genx.ModelC
 	if err != nil {
 		return nil, err
 	}
-	return gen.GenerateStream(ctx, name, mctx)
+	stream, err := gen.GenerateStream(ctx, name, mctx)
+	if err != nil || !reportsUsage(ctx, gen) {
+		return stream, err
+	}
+	return &usageStream{Stream: stream, ctx: ctx, model: name}, nil
 }
 
 // Invoke invokes a function tool by looking up the generator for the given pattern.
@@ -69,7 +76,20 @@ func (gm *Mux) Invoke(ctx context.Context, name string, mctx genx.ModelContext,
 	if err != nil {
 		return genx.Usage{}, nil, err
 	}
-	return gen.Invoke(ctx, name, mctx, tool)
+	usage, call, err := gen.Invoke(ctx, name, mctx, tool)
+	if err == nil && reportsUsage(ctx, gen) {
+		genx.ReportUsage(ctx, name, usage)
+	}
+	return usage, call, err
+}
+
+// reportsUsage reports whether the usage of calls to gen is reported to
+// the usage collectors of ctx.
+func reportsUsage(ctx context.Context, gen genx.Generator) bool {
+	if _, ok := gen.(router); ok {
+		return false
+	}
+	return genx.HasUsageCollector(ctx)
 }
 
 func (gm *Mux) get(pattern string) (genx.Generator, error) {
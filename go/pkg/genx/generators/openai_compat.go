@@ -0,0 +1,94 @@
+package generators
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// OpenAICompatConfig configures a generator for an OpenAI-compatible chat
+// completions endpoint, such as vLLM, Ollama or the llama.cpp server.
+type OpenAICompatConfig struct {
+	// BaseURL is the API root, e.g. "http://localhost:8080/v1" for the
+	// llama.cpp server or "http://localhost:11434/v1" for Ollama. Required.
+	BaseURL string
+
+	// APIKey is sent as a bearer token. Local servers usually ignore it.
+	// OPENAI_API_KEY is never used, so that it does not leak to third-party
+	// endpoints.
+	APIKey string
+
+	// Model is the model name sent to the server. Required.
+	Model string
+
+	// Timeout limits each request attempt, including reading a streamed
+	// response. Default 60s; negative disables it.
+	Timeout time.Duration
+
+	// MaxRetries is the number of retries of requests that fail with a
+	// connection error, 408, 409, 429 or 5xx, with exponential backoff that
+	// honors Retry-After. Default 2; negative disables retries.
+	MaxRetries int
+
+	// HTTPClient is the client to use. Default http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Capabilities and parameters, see genx.OpenAIGenerator. Small local
+	// models often support neither JSON output nor tool calls.
+	GenerateParams    *genx.ModelParams
+	InvokeParams      *genx.ModelParams
+	SupportJSONOutput bool
+	SupportToolCalls  bool
+	SupportTextOnly   bool
+	UseSystemRole     bool
+	ExtraFields       map[string]any
+}
+
+func (c *OpenAICompatConfig) setDefaults() {
+	if c.Timeout == 0 {
+		c.Timeout = 60 * time.Second
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = 2
+	}
+}
+
+// NewOpenAICompat creates a generator for an OpenAI-compatible endpoint.
+func NewOpenAICompat(cfg OpenAICompatConfig) (*genx.OpenAIGenerator, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("generators: openai-compatible base url is required")
+	}
+	if cfg.Model == "" {
+		return nil, errors.New("generators: openai-compatible model is required")
+	}
+	cfg.setDefaults()
+
+	opts := []option.RequestOption{
+		option.WithBaseURL(cfg.BaseURL),
+		option.WithAPIKey(cfg.APIKey),
+		option.WithMaxRetries(max(cfg.MaxRetries, 0)),
+	}
+	if cfg.Timeout > 0 {
+		opts = append(opts, option.WithRequestTimeout(cfg.Timeout))
+	}
+	if cfg.HTTPClient != nil {
+		opts = append(opts, option.WithHTTPClient(cfg.HTTPClient))
+	}
+	client := openai.NewClient(opts...)
+
+	return &genx.OpenAIGenerator{
+		Client:            &client,
+		Model:             cfg.Model,
+		GenerateParams:    cfg.GenerateParams,
+		InvokeParams:      cfg.InvokeParams,
+		SupportJSONOutput: cfg.SupportJSONOutput,
+		SupportToolCalls:  cfg.SupportToolCalls,
+		SupportTextOnly:   cfg.SupportTextOnly,
+		UseSystemRole:     cfg.UseSystemRole,
+		ExtraFields:       cfg.ExtraFields,
+	}, nil
+}
@@ -0,0 +1,217 @@
+package generators
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/openai/openai-go"
+)
+
+// chatServer serves a streamed chat completion saying text, after failing
+// the first failures requests with status.
+func chatServer(t *testing.T, failures int32, status int, text string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		if requests.Add(1) <= failures {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After-Ms", "1")
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"error":{"message":"overloaded"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"created\":0,\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":%q}}]}\n\n", text)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func readText(t *testing.T, stream genx.Stream) string {
+	t.Helper()
+	defer stream.Close()
+	var text string
+	for {
+		chunk, err := stream.Next()
+		if err != nil {
+			if !errors.Is(err, genx.ErrDone) {
+				t.Fatalf("Next() error = %v", err)
+			}
+			return text
+		}
+		if s, ok := chunk.Part.(genx.Text); ok {
+			text += string(s)
+		}
+	}
+}
+
+func userContext() genx.ModelContext {
+	var mcb genx.ModelContextBuilder
+	mcb.UserText("user", "hi")
+	return mcb.Build()
+}
+
+func TestNewOpenAICompat_Validation(t *testing.T) {
+	if _, err := NewOpenAICompat(OpenAICompatConfig{Model: "m"}); err == nil {
+		t.Error("expected error without BaseURL")
+	}
+	if _, err := NewOpenAICompat(OpenAICompatConfig{BaseURL: "http://localhost"}); err == nil {
+		t.Error("expected error without Model")
+	}
+}
+
+func TestOpenAICompat_Retry(t *testing.T) {
+	srv, requests := chatServer(t, 1, http.StatusServiceUnavailable, "hello")
+	t.Setenv("OPENAI_API_KEY", "leaked")
+
+	gen, err := NewOpenAICompat(OpenAICompatConfig{
+		BaseURL: srv.URL + "/v1",
+		Model:   "llama3",
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := gen.GenerateStream(context.Background(), "", userContext())
+	if err != nil {
+		t.Fatalf("GenerateStream() error = %v", err)
+	}
+	if got := readText(t, stream); got != "hello" {
+		t.Errorf("text = %q, want hello", got)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("requests = %d, want 2", n)
+	}
+}
+
+func TestOpenAICompat_FallbackOn429(t *testing.T) {
+	busy, busyRequests := chatServer(t, 100, http.StatusTooManyRequests, "")
+	local, _ := chatServer(t, 0, 0, "from local")
+
+	mux := NewMux()
+	for pattern, url := range map[string]string{"remote/busy": busy.URL, "local/llama": local.URL} {
+		gen, err := NewOpenAICompat(OpenAICompatConfig{BaseURL: url + "/v1", Model: "m", MaxRetries: -1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := mux.Handle(pattern, gen); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f := &FallbackGenerator{Models: []string{"remote/busy", "local/llama"}, Mux: mux}
+
+	stream, err := f.GenerateStream(context.Background(), "", userContext())
+	if err != nil {
+		t.Fatalf("GenerateStream() error = %v", err)
+	}
+	if got := readText(t, stream); got != "from local" {
+		t.Errorf("text = %q, want from local", got)
+	}
+	if n := busyRequests.Load(); n != 1 {
+		t.Errorf("busy requests = %d, want 1 (no retries)", n)
+	}
+}
+
+func TestOpenAICompat_ErrorIsOpenAIError(t *testing.T) {
+	srv, _ := chatServer(t, 100, http.StatusBadRequest, "")
+	gen, err := NewOpenAICompat(OpenAICompatConfig{BaseURL: srv.URL + "/v1", Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := gen.GenerateStream(context.Background(), "", userContext())
+	if err == nil {
+		_, err = stream.Next()
+		stream.Close()
+	}
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("error = %v, want openai.Error 400", err)
+	}
+	if IsRetryable(err) {
+		t.Error("400 should not be retryable")
+	}
+}
+
+func TestOpenAICompat_StopAndUsage(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		// The server ignores the stop sequences, as some local servers do.
+		for _, s := range []string{"Sure.\nUs", "er: more", " text"} {
+			fmt.Fprintf(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"created\":0,\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", s)
+		}
+		fmt.Fprint(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"created\":0,\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"created\":0,\"model\":\"m\",\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":7,\"total_tokens\":19}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+
+	gen, err := NewOpenAICompat(OpenAICompatConfig{
+		BaseURL: srv.URL + "/v1",
+		Model:   "m",
+		GenerateParams: &genx.ModelParams{
+			Stop:      []string{"\nUser:"},
+			LogitBias: map[string]int{"50256": -100},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stop sequences of the generator and the model context add up.
+	mcb := genx.ModelContextBuilder{Params: &genx.ModelParams{Stop: []string{"\nUser:", "###"}}}
+	mcb.UserText("user", "hi")
+	stream, err := gen.GenerateStream(context.Background(), "", mcb.Build())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readText(t, stream); got != "Sure." {
+		t.Errorf("text = %q, want Sure.", got)
+	}
+	if stop, _ := body["stop"].([]any); len(stop) != 2 || stop[0] != "\nUser:" || stop[1] != "###" {
+		t.Errorf("stop = %v", body["stop"])
+	}
+	if bias, _ := body["logit_bias"].(map[string]any); bias["50256"] != float64(-100) {
+		t.Errorf("logit_bias = %v", body["logit_bias"])
+	}
+	if opts, _ := body["stream_options"].(map[string]any); opts["include_usage"] != true {
+		t.Errorf("stream_options = %v", body["stream_options"])
+	}
+
+	// Without stop sequences, the stream ends with the usage chunk that
+	// follows the finish reason.
+	gen.GenerateParams = nil
+	stream, err = gen.GenerateStream(context.Background(), "", userContext())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state *genx.State
+	for {
+		if _, err = stream.Next(); err != nil {
+			break
+		}
+	}
+	if !errors.As(err, &state) {
+		t.Fatalf("err = %v, want State", err)
+	}
+	if u := state.Usage(); u.PromptTokenCount != 12 || u.GeneratedTokenCount != 7 {
+		t.Errorf("usage = %+v", u)
+	}
+	if u, ok := genx.StreamUsage(stream); !ok || u.GeneratedTokenCount != 7 {
+		t.Errorf("StreamUsage() = %+v, %v", u, ok)
+	}
+}
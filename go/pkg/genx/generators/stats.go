@@ -0,0 +1,221 @@
+package generators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/trie"
+)
+
+// GeneratorStats collects the token usage of generator calls per model and
+// estimates their cost. Attach it to the calls to account for with
+// genx.WithUsageCollector; Mux reports the usage of the calls it routes.
+//
+// A GeneratorStats per persona or device gives per-persona or per-device
+// bills:
+//
+//	stats := generators.NewStats(prices)
+//	ctx = genx.WithUsageCollector(ctx, stats)
+//	...
+//	bill(deviceID, stats.Total().Cost)
+type GeneratorStats interface {
+	genx.UsageCollector
+
+	// Models returns the stats of each model called, by model pattern.
+	Models() map[string]ModelStats
+
+	// Total returns the stats of all calls.
+	Total() ModelStats
+
+	// Reset clears the stats.
+	Reset()
+}
+
+// ModelStats is the accumulated usage of a model.
+type ModelStats struct {
+	// Calls is the number of calls.
+	Calls int64
+
+	// Usage is the sum of the usage of the calls.
+	Usage genx.Usage
+
+	// Cost is the estimated cost of the priced calls, in the currency of
+	// the Pricing.
+	Cost float64
+
+	// Unpriced is the number of calls without a price.
+	Unpriced int64
+}
+
+// Pricing estimates the cost of a call.
+type Pricing interface {
+	// Cost returns the cost of a call to model with usage, or false if the
+	// model has no price.
+	Cost(model string, usage genx.Usage) (float64, bool)
+}
+
+// Price is the price of a model, per million tokens.
+type Price struct {
+	// Prompt is the price of prompt tokens that are not cached.
+	Prompt float64 `json:"prompt" yaml:"prompt"`
+
+	// CachedPrompt is the price of cached prompt tokens. Zero means the
+	// Prompt price.
+	CachedPrompt float64 `json:"cached_prompt,omitempty" yaml:"cached_prompt,omitempty"`
+
+	// Generated is the price of generated tokens.
+	Generated float64 `json:"generated" yaml:"generated"`
+}
+
+// Cost returns the cost of usage at price p.
+func (p Price) Cost(usage genx.Usage) float64 {
+	cached := p.CachedPrompt
+	if cached == 0 {
+		cached = p.Prompt
+	}
+	uncached := usage.PromptTokenCount - usage.CachedContentTokenCount
+	return (float64(uncached)*p.Prompt +
+		float64(usage.CachedContentTokenCount)*cached +
+		float64(usage.GeneratedTokenCount)*p.Generated) / 1e6
+}
+
+// PriceTable is a Pricing with prices by model pattern. Patterns are those
+// of Mux, so "openai/+" prices every model under "openai/" that has no
+// price of its own.
+type PriceTable struct {
+	prices trie.Trie[Price]
+}
+
+// NewPriceTable creates a PriceTable from prices by model pattern.
+func NewPriceTable(prices map[string]Price) (*PriceTable, error) {
+	var pt PriceTable
+	for pattern, price := range prices {
+		if err := pt.Set(pattern, price); err != nil {
+			return nil, err
+		}
+	}
+	return &pt, nil
+}
+
+// Set sets the price of the models matching pattern.
+func (pt *PriceTable) Set(pattern string, price Price) error {
+	if err := pt.prices.SetValue(pattern, price); err != nil {
+		return fmt.Errorf("generators: price %s: %w", pattern, err)
+	}
+	return nil
+}
+
+// Cost implements Pricing.
+func (pt *PriceTable) Cost(model string, usage genx.Usage) (float64, bool) {
+	price, ok := pt.prices.Get(model)
+	if !ok {
+		return 0, false
+	}
+	return price.Cost(usage), true
+}
+
+// NewStats creates a GeneratorStats that prices calls with pricing, which
+// may be nil.
+func NewStats(pricing Pricing) GeneratorStats {
+	return &stats{pricing: pricing, models: make(map[string]*ModelStats)}
+}
+
+type stats struct {
+	pricing Pricing
+
+	mu     sync.Mutex
+	models map[string]*ModelStats
+}
+
+func (s *stats) CollectUsage(_ context.Context, model string, usage genx.Usage) {
+	var (
+		cost   float64
+		priced bool
+	)
+	if s.pricing != nil {
+		cost, priced = s.pricing.Cost(model, usage)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ms, ok := s.models[model]
+	if !ok {
+		ms = &ModelStats{}
+		s.models[model] = ms
+	}
+	ms.Calls++
+	ms.Usage = ms.Usage.Add(usage)
+	if priced {
+		ms.Cost += cost
+	} else {
+		ms.Unpriced++
+	}
+}
+
+func (s *stats) Models() map[string]ModelStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]ModelStats, len(s.models))
+	for model, ms := range s.models {
+		out[model] = *ms
+	}
+	return out
+}
+
+func (s *stats) Total() ModelStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var total ModelStats
+	for _, ms := range s.models {
+		total.Calls += ms.Calls
+		total.Usage = total.Usage.Add(ms.Usage)
+		total.Cost += ms.Cost
+		total.Unpriced += ms.Unpriced
+	}
+	return total
+}
+
+func (s *stats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.models)
+}
+
+// router is implemented by generators that route calls to other models
+// through a Mux, which reports their usage; the Mux does not report it
+// again for the router.
+type router interface {
+	routes()
+}
+
+func (*Mux) routes()               {}
+func (*FallbackGenerator) routes() {}
+
+// usageStream reports the usage of the stream when it ends.
+type usageStream struct {
+	genx.Stream
+	ctx   context.Context
+	model string
+	once  sync.Once
+}
+
+func (s *usageStream) Next() (*genx.MessageChunk, error) {
+	chunk, err := s.Stream.Next()
+	if err != nil {
+		var state *genx.State
+		if errors.As(err, &state) {
+			s.once.Do(func() { genx.ReportUsage(s.ctx, s.model, state.Usage()) })
+		}
+	}
+	return chunk, err
+}
+
+// Usage implements genx.UsageStream; it is zero if the stream does not
+// report usage.
+func (s *usageStream) Usage() genx.Usage {
+	usage, _ := genx.StreamUsage(s.Stream)
+	return usage
+}
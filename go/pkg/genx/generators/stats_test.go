@@ -0,0 +1,133 @@
+package generators
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// usageGenerator streams one chunk and reports usage, or invokes with it.
+type usageGenerator struct {
+	usage genx.Usage
+}
+
+func (g *usageGenerator) GenerateStream(ctx context.Context, model string, mctx genx.ModelContext) (genx.Stream, error) {
+	sb := genx.NewStreamBuilder(mctx, 4)
+	sb.Add(&genx.MessageChunk{Role: genx.RoleModel, Part: genx.Text("hi")})
+	sb.Done(g.usage)
+	return sb.Stream(), nil
+}
+
+func (g *usageGenerator) Invoke(ctx context.Context, model string, mctx genx.ModelContext, tool *genx.FuncTool) (genx.Usage, *genx.FuncCall, error) {
+	return g.usage, &genx.FuncCall{Name: "f"}, nil
+}
+
+func drain(t *testing.T, stream genx.Stream) {
+	t.Helper()
+	for {
+		if _, err := stream.Next(); err != nil {
+			if !errors.Is(err, genx.ErrDone) {
+				t.Fatalf("Next() error = %v", err)
+			}
+			return
+		}
+	}
+}
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestPrice_Cost(t *testing.T) {
+	p := Price{Prompt: 2, CachedPrompt: 0.5, Generated: 8}
+	got := p.Cost(genx.Usage{PromptTokenCount: 1_000_000, CachedContentTokenCount: 400_000, GeneratedTokenCount: 250_000})
+	// 600k*2 + 400k*0.5 + 250k*8, per million
+	if want := 1.2 + 0.2 + 2.0; !approx(got, want) {
+		t.Errorf("Cost() = %v, want %v", got, want)
+	}
+	p.CachedPrompt = 0
+	got = p.Cost(genx.Usage{PromptTokenCount: 1_000_000, CachedContentTokenCount: 400_000})
+	if !approx(got, 2) {
+		t.Errorf("Cost() without cached price = %v, want 2", got)
+	}
+}
+
+func TestPriceTable(t *testing.T) {
+	pt, err := NewPriceTable(map[string]Price{
+		"openai/+":      {Prompt: 1, Generated: 1},
+		"openai/gpt-4o": {Prompt: 5, Generated: 15},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := genx.Usage{PromptTokenCount: 1_000_000, GeneratedTokenCount: 1_000_000}
+	if cost, ok := pt.Cost("openai/gpt-4o", u); !ok || !approx(cost, 20) {
+		t.Errorf("Cost(gpt-4o) = %v, %v; want 20", cost, ok)
+	}
+	if cost, ok := pt.Cost("openai/gpt-4o-mini", u); !ok || !approx(cost, 2) {
+		t.Errorf("Cost(gpt-4o-mini) = %v, %v; want 2 from wildcard", cost, ok)
+	}
+	if _, ok := pt.Cost("qwen/turbo", u); ok {
+		t.Error("Cost(qwen/turbo) priced without a price")
+	}
+}
+
+func TestStats_Mux(t *testing.T) {
+	mux := NewMux()
+	mux.Handle("qwen/turbo", &usageGenerator{usage: genx.Usage{PromptTokenCount: 100, GeneratedTokenCount: 20}})
+	mux.Handle("local/llama", &usageGenerator{usage: genx.Usage{PromptTokenCount: 50, GeneratedTokenCount: 10}})
+	pt, _ := NewPriceTable(map[string]Price{"qwen/turbo": {Prompt: 1e4, Generated: 1e5}})
+
+	device := NewStats(pt)
+	persona := NewStats(nil)
+	ctx := genx.WithUsageCollector(genx.WithUsageCollector(context.Background(), persona), device)
+
+	stream, err := mux.GenerateStream(ctx, "qwen/turbo", userContext())
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain(t, stream)
+	if _, _, err := mux.Invoke(ctx, "local/llama", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	models := device.Models()
+	if ms := models["qwen/turbo"]; ms.Calls != 1 || ms.Usage.GeneratedTokenCount != 20 || !approx(ms.Cost, 3) || ms.Unpriced != 0 {
+		t.Errorf("qwen/turbo stats = %+v", ms)
+	}
+	if ms := models["local/llama"]; ms.Calls != 1 || ms.Usage.PromptTokenCount != 50 || ms.Cost != 0 || ms.Unpriced != 1 {
+		t.Errorf("local/llama stats = %+v", ms)
+	}
+	total := device.Total()
+	if total.Calls != 2 || total.Usage.PromptTokenCount != 150 || total.Unpriced != 1 {
+		t.Errorf("total = %+v", total)
+	}
+	if got := persona.Total(); got.Calls != 2 || got.Usage != total.Usage {
+		t.Errorf("persona total = %+v, want the same usage", got)
+	}
+
+	device.Reset()
+	if got := device.Total(); got.Calls != 0 {
+		t.Errorf("total after Reset = %+v", got)
+	}
+}
+
+func TestStats_FallbackCountedOnce(t *testing.T) {
+	mux := NewMux()
+	mux.Handle("local/llama", &usageGenerator{usage: genx.Usage{GeneratedTokenCount: 7}})
+	mux.Handle("chat/default", &FallbackGenerator{Models: []string{"local/llama"}, Mux: mux})
+
+	stats := NewStats(nil)
+	ctx := genx.WithUsageCollector(context.Background(), stats)
+	stream, err := mux.GenerateStream(ctx, "chat/default", userContext())
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain(t, stream)
+
+	models := stats.Models()
+	if len(models) != 1 || models["local/llama"].Calls != 1 {
+		t.Errorf("models = %+v, want one call to local/llama", models)
+	}
+}
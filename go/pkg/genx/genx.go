@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"iter"
+	"maps"
+	"slices"
 	"strings"
 	"text/template"
 
@@ -40,6 +42,50 @@ type ModelParams struct {
 	TopP             float32 `json:"top_p,omitzero"`
 	PresencePenalty  float32 `json:"presence_penalty,omitzero"`
 	TopK             float32 `json:"top_k,omitzero"`
+
+	// Stop lists sequences that end the generation. The output ends before
+	// the first of them, which is not part of it. Generators pass them to
+	// the provider and cut the stream with StopAt where the provider does
+	// not stop by itself.
+	Stop []string `json:"stop,omitzero"`
+
+	// LogitBias maps token IDs of the model's tokenizer to a bias from -100
+	// (ban) to 100 (force) added to their logits. Only OpenAI-compatible
+	// generators support it; the others ignore it.
+	LogitBias map[string]int `json:"logit_bias,omitzero"`
+}
+
+// stopSequences returns the non-empty stop sequences of params, in order
+// and without duplicates. Nil params are skipped.
+func stopSequences(params ...*ModelParams) []string {
+	var out []string
+	for _, p := range params {
+		if p == nil {
+			continue
+		}
+		for _, s := range p.Stop {
+			if s != "" && !slices.Contains(out, s) {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// logitBias returns the logit bias of params, later params overriding the
+// bias of a token in earlier ones. Nil params are skipped.
+func logitBias(params ...*ModelParams) map[string]int {
+	var out map[string]int
+	for _, p := range params {
+		if p == nil || len(p.LogitBias) == 0 {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]int, len(p.LogitBias))
+		}
+		maps.Copy(out, p.LogitBias)
+	}
+	return out
 }
 
 type Prompt struct {
@@ -95,6 +141,20 @@ func InspectMessage(msg *Message) string {
 					fmt.Fprintln(&sb, pt.MIMEType)
 					fmt.Fprintf(&sb, "[%d]\n", len(pt.Data))
 				}
+			case *ImagePart:
+				if pt != nil {
+					fmt.Fprintln(&sb, PartMIMEType(pt))
+					if len(pt.Data) == 0 {
+						fmt.Fprintln(&sb, pt.URL)
+					} else {
+						fmt.Fprintf(&sb, "[%d]\n", len(pt.Data))
+					}
+				}
+			case *VideoFramePart:
+				if pt != nil {
+					fmt.Fprintln(&sb, pt.MIMEType)
+					fmt.Fprintf(&sb, "[%d @%v]\n", len(pt.Data), pt.Timestamp)
+				}
 			default:
 				fmt.Fprintf(&sb, "[%T]\n", part)
 			}
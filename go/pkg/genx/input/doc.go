@@ -9,4 +9,12 @@
 // This package provides a generic JitterBuffer that can be used to reorder
 // out-of-order packets by timestamp. It is used internally by input/opus
 // for real-time audio streaming.
+//
+// # Tone Detection
+//
+// ToneDetector recognizes DTMF keypad digits and single-frequency beeps in
+// PCM16 audio using the Goertzel algorithm. DetectTones wraps a genx.Stream
+// and inserts a text chunk (e.g., "5" or "beep") after the user audio in
+// which each tone ended, so agents can drive IVR-style menus or interpret
+// hardware self-test tones.
 package input
@@ -0,0 +1,294 @@
+package input
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// ToneKind identifies the type of a detected tone.
+type ToneKind int
+
+const (
+	// ToneDTMF is a dual-tone multi-frequency keypad digit.
+	ToneDTMF ToneKind = iota + 1
+	// ToneBeep is a single-frequency beep (e.g., a hardware self-test tone).
+	ToneBeep
+)
+
+// String returns the tone kind name.
+func (k ToneKind) String() string {
+	switch k {
+	case ToneDTMF:
+		return "dtmf"
+	case ToneBeep:
+		return "beep"
+	default:
+		return "unknown"
+	}
+}
+
+// ToneEvent is a completed tone reported by ToneDetector.
+type ToneEvent struct {
+	Kind ToneKind
+
+	// Digit is the DTMF key ('0'-'9', '*', '#', 'A'-'D'). Zero for beeps.
+	Digit rune
+
+	// Frequency is the beep frequency in Hz. Zero for DTMF.
+	Frequency float64
+
+	// Offset is the tone start relative to the first sample written.
+	Offset time.Duration
+
+	// Duration is how long the tone lasted.
+	Duration time.Duration
+}
+
+// ToneConfig configures a ToneDetector.
+type ToneConfig struct {
+	// SampleRate is the PCM sample rate in Hz (default: 16000).
+	SampleRate int
+
+	// BlockDuration is the analysis block length (default: 20ms).
+	// Shorter blocks react faster but separate adjacent DTMF rows less well.
+	BlockDuration time.Duration
+
+	// MinDuration is the minimum tone length to report (default: 40ms).
+	MinDuration time.Duration
+
+	// MinLevel is the minimum block RMS level, in [0, 1] of full scale,
+	// for a block to be considered (default: 0.01, about -40 dBFS).
+	MinLevel float64
+
+	// BeepFrequencies lists single-tone frequencies to detect.
+	// Default: 1000 Hz. Set to an empty non-nil slice to detect DTMF only.
+	BeepFrequencies []float64
+}
+
+func (c *ToneConfig) setDefaults() {
+	if c.SampleRate == 0 {
+		c.SampleRate = 16000
+	}
+	if c.BlockDuration == 0 {
+		c.BlockDuration = 20 * time.Millisecond
+	}
+	if c.MinDuration == 0 {
+		c.MinDuration = 40 * time.Millisecond
+	}
+	if c.MinLevel == 0 {
+		c.MinLevel = 0.01
+	}
+	if c.BeepFrequencies == nil {
+		c.BeepFrequencies = []float64{1000}
+	}
+}
+
+var (
+	dtmfRows = [4]float64{697, 770, 852, 941}
+	dtmfCols = [4]float64{1209, 1336, 1477, 1633}
+	dtmfKeys = [4][4]rune{
+		{'1', '2', '3', 'A'},
+		{'4', '5', '6', 'B'},
+		{'7', '8', '9', 'C'},
+		{'*', '0', '#', 'D'},
+	}
+)
+
+// Detection thresholds, as a fraction of block energy captured by a tone.
+// A pure sine scores ~1.0; each half of a balanced DTMF pair scores ~0.5.
+const (
+	dtmfMinPower  = 0.6  // row + column share of block energy
+	dtmfMaxTwist  = 6.3  // max row/column power ratio (~8 dB)
+	dtmfMinMargin = 6.3  // strongest row/column vs runner-up (~8 dB)
+	beepMinPower  = 0.75 // beep share of block energy
+)
+
+// ToneDetector detects DTMF digits and single-frequency beeps in PCM16
+// signed little-endian mono audio using the Goertzel algorithm.
+//
+// Audio is analyzed in fixed-size blocks. A tone is reported once it ends
+// (or on Flush) if it lasted at least MinDuration. ToneDetector is not safe
+// for concurrent use.
+type ToneDetector struct {
+	cfg       ToneConfig
+	blockSize int
+
+	dtmf  [8]goertzel
+	beeps []goertzel
+
+	block   []float64
+	samples int64 // samples consumed, including the partial block
+
+	cur      tone  // tone in the most recent block
+	curStart int64 // sample offset where cur started
+	curCount int   // consecutive blocks with cur
+}
+
+// tone is the per-block classification: zero, a DTMF digit, or a beep index.
+type tone struct {
+	digit rune
+	beep  int // 1-based index into BeepFrequencies
+}
+
+// NewToneDetector creates a ToneDetector.
+func NewToneDetector(cfg ToneConfig) *ToneDetector {
+	cfg.setDefaults()
+	d := &ToneDetector{
+		cfg:       cfg,
+		blockSize: max(int(int64(cfg.SampleRate)*int64(cfg.BlockDuration)/int64(time.Second)), 1),
+	}
+	for i, f := range dtmfRows {
+		d.dtmf[i] = newGoertzel(f, cfg.SampleRate)
+	}
+	for i, f := range dtmfCols {
+		d.dtmf[4+i] = newGoertzel(f, cfg.SampleRate)
+	}
+	for _, f := range cfg.BeepFrequencies {
+		d.beeps = append(d.beeps, newGoertzel(f, cfg.SampleRate))
+	}
+	d.block = make([]float64, 0, d.blockSize)
+	return d
+}
+
+// Write feeds PCM16 audio and returns tones that completed within it.
+// A trailing odd byte is ignored.
+func (d *ToneDetector) Write(pcm []byte) []ToneEvent {
+	var events []ToneEvent
+	for i := 0; i+1 < len(pcm); i += 2 {
+		s := int16(binary.LittleEndian.Uint16(pcm[i:]))
+		d.block = append(d.block, float64(s)/32768)
+		d.samples++
+		if len(d.block) == d.blockSize {
+			if ev, ok := d.step(d.classify(d.block)); ok {
+				events = append(events, ev)
+			}
+			d.block = d.block[:0]
+		}
+	}
+	return events
+}
+
+// Flush ends any tone in progress and returns it if long enough.
+// Buffered samples shorter than a block are discarded.
+func (d *ToneDetector) Flush() []ToneEvent {
+	d.block = d.block[:0]
+	if ev, ok := d.step(tone{}); ok {
+		return []ToneEvent{ev}
+	}
+	return nil
+}
+
+// Reset clears all state, including the sample offset.
+func (d *ToneDetector) Reset() {
+	d.block = d.block[:0]
+	d.samples = 0
+	d.cur, d.curStart, d.curCount = tone{}, 0, 0
+}
+
+// step advances the debounce state with the classification of the block
+// that just ended and returns the previous tone if it has finished.
+func (d *ToneDetector) step(t tone) (ToneEvent, bool) {
+	if t == d.cur {
+		if t != (tone{}) {
+			d.curCount++
+		}
+		return ToneEvent{}, false
+	}
+
+	prev, prevStart, prevCount := d.cur, d.curStart, d.curCount
+	d.cur, d.curCount = t, 1
+	d.curStart = d.samples - int64(len(d.block))
+	if t == (tone{}) {
+		d.curCount = 0
+	}
+
+	if prev == (tone{}) {
+		return ToneEvent{}, false
+	}
+	dur := d.toDuration(int64(prevCount * d.blockSize))
+	if dur < d.cfg.MinDuration {
+		return ToneEvent{}, false
+	}
+	ev := ToneEvent{
+		Offset:   d.toDuration(prevStart),
+		Duration: dur,
+	}
+	if prev.digit != 0 {
+		ev.Kind = ToneDTMF
+		ev.Digit = prev.digit
+	} else {
+		ev.Kind = ToneBeep
+		ev.Frequency = d.cfg.BeepFrequencies[prev.beep-1]
+	}
+	return ev, true
+}
+
+func (d *ToneDetector) toDuration(samples int64) time.Duration {
+	return time.Duration(samples) * time.Second / time.Duration(d.cfg.SampleRate)
+}
+
+// classify returns the tone present in a single block.
+func (d *ToneDetector) classify(block []float64) tone {
+	var energy float64
+	for _, s := range block {
+		energy += s * s
+	}
+	n := float64(len(block))
+	if energy == 0 || math.Sqrt(energy/n) < d.cfg.MinLevel {
+		return tone{}
+	}
+	// Normalize so a full-block sine at the target frequency scores ~1.
+	norm := 2 / (n * energy)
+
+	var p [8]float64
+	for i := range d.dtmf {
+		p[i] = d.dtmf[i].power(block) * norm
+	}
+	row, rowRunner := strongest(p[:4])
+	col, colRunner := strongest(p[4:])
+	rp, cp := p[row], p[4+col]
+	if rp+cp >= dtmfMinPower &&
+		rp <= cp*dtmfMaxTwist && cp <= rp*dtmfMaxTwist &&
+		rp >= rowRunner*dtmfMinMargin && cp >= colRunner*dtmfMinMargin {
+		return tone{digit: dtmfKeys[row][col]}
+	}
+
+	for i := range d.beeps {
+		if d.beeps[i].power(block)*norm >= beepMinPower {
+			return tone{beep: i + 1}
+		}
+	}
+	return tone{}
+}
+
+// strongest returns the index of the largest value and the second largest value.
+func strongest(p []float64) (int, float64) {
+	best, runner := 0, 0.0
+	for i := 1; i < len(p); i++ {
+		if p[i] > p[best] {
+			runner = p[best]
+			best = i
+		} else if p[i] > runner {
+			runner = p[i]
+		}
+	}
+	return best, runner
+}
+
+// goertzel computes the power of a single frequency over a block.
+type goertzel struct {
+	coeff float64
+}
+
+func newGoertzel(freq float64, sampleRate int) goertzel {
+	return goertzel{coeff: 2 * math.Cos(2*math.Pi*freq/float64(sampleRate))}
+}
+
+func (g goertzel) power(block []float64) float64 {
+	var s1, s2 float64
+	for _, x := range block {
+		s1, s2 = x+g.coeff*s1-s2, s1
+	}
+	return s1*s1 + s2*s2 - g.coeff*s1*s2
+}
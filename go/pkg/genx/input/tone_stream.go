@@ -0,0 +1,162 @@
+package input
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/haivivi/giztoy/go/pkg/buffer"
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// Metadata keys set on tone chunks emitted by DetectTones.
+const (
+	// MetaTone holds the tone kind ("dtmf" or "beep").
+	MetaTone = "tone"
+	// MetaToneDigit holds the DTMF key for dtmf tones.
+	MetaToneDigit = "tone.digit"
+	// MetaToneFrequency holds the beep frequency in Hz for beep tones.
+	MetaToneFrequency = "tone.frequency"
+	// MetaToneDuration holds the tone duration in milliseconds.
+	MetaToneDuration = "tone.duration_ms"
+)
+
+// DetectTones returns a stream that passes through every chunk of input and
+// additionally emits a text chunk for each DTMF digit or beep detected in
+// RoleUser audio/pcm chunks.
+//
+// A tone chunk has the same Role and Name as the audio that carried it,
+// Part set to the DTMF key (e.g., "5") or "beep", Ctrl.Label set to the tone
+// kind, and the MetaTone* metadata keys describing the tone. It is emitted
+// right after the audio chunk in which the tone ended. Agents can use these
+// chunks for IVR-style menus or to recognize hardware self-test tones.
+//
+// A user audio/pcm EoS flushes and resets the detector, so tones never span
+// sub-streams; the tones it flushes are emitted before it, as consumers
+// treat a sub-stream as closed at its EoS. Other chunks are passed through
+// unchanged.
+func DetectTones(input genx.Stream, cfg ToneConfig) genx.Stream {
+	s := &toneStream{
+		input:    input,
+		detector: NewToneDetector(cfg),
+		chunks:   buffer.N[*genx.MessageChunk](256),
+	}
+
+	go s.readLoop()
+
+	return s
+}
+
+type toneStream struct {
+	input    genx.Stream
+	detector *ToneDetector
+	chunks   *buffer.Buffer[*genx.MessageChunk]
+}
+
+// Next returns the next MessageChunk from the stream.
+func (s *toneStream) Next() (*genx.MessageChunk, error) {
+	chunk, err := s.chunks.Next()
+	if err != nil {
+		if err == buffer.ErrIteratorDone {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// Close closes the stream.
+func (s *toneStream) Close() error {
+	return s.chunks.Close()
+}
+
+// CloseWithError closes the stream with an error.
+func (s *toneStream) CloseWithError(err error) error {
+	return s.chunks.CloseWithError(err)
+}
+
+func (s *toneStream) readLoop() {
+	defer s.chunks.CloseWrite()
+
+	var last *genx.MessageChunk // most recent user audio chunk
+	for {
+		chunk, err := s.input.Next()
+		if err != nil {
+			if last != nil {
+				s.emit(last, s.detector.Flush())
+			}
+			if err != io.EOF {
+				s.chunks.CloseWithError(err)
+			}
+			return
+		}
+		if chunk == nil {
+			continue
+		}
+
+		blob, ok := chunk.Part.(*genx.Blob)
+		if !ok || chunk.Role != genx.RoleUser || !isPCMMIME(blob.MIMEType) {
+			if err := s.chunks.Add(chunk); err != nil {
+				return
+			}
+			continue
+		}
+
+		if chunk.IsEndOfStream() {
+			events := append(s.detector.Write(blob.Data), s.detector.Flush()...)
+			s.detector.Reset()
+			last = nil
+			if !s.emit(chunk, events) {
+				return
+			}
+			if err := s.chunks.Add(chunk); err != nil {
+				return
+			}
+			continue
+		}
+
+		events := s.detector.Write(blob.Data)
+		last = chunk
+
+		if err := s.chunks.Add(chunk); err != nil {
+			return
+		}
+		if !s.emit(chunk, events) {
+			return
+		}
+	}
+}
+
+// emit pushes a tone chunk per event, attributed to src.
+func (s *toneStream) emit(src *genx.MessageChunk, events []ToneEvent) bool {
+	for _, ev := range events {
+		text := "beep"
+		if ev.Kind == ToneDTMF {
+			text = string(ev.Digit)
+		}
+		c := &genx.MessageChunk{
+			Role: src.Role,
+			Name: src.Name,
+			Part: genx.Text(text),
+			Ctrl: &genx.StreamCtrl{Label: ev.Kind.String()},
+		}
+		if src.Ctrl != nil {
+			c.Ctrl.StreamID = src.Ctrl.StreamID
+		}
+		c.SetMetadata(MetaTone, ev.Kind.String())
+		if ev.Kind == ToneDTMF {
+			c.SetMetadata(MetaToneDigit, text)
+		} else {
+			c.SetMetadata(MetaToneFrequency, strconv.FormatFloat(ev.Frequency, 'f', -1, 64))
+		}
+		c.SetMetadata(MetaToneDuration, strconv.FormatInt(ev.Duration.Milliseconds(), 10))
+		if err := s.chunks.Add(c); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func isPCMMIME(mime string) bool {
+	return mime == "audio/pcm" || strings.HasPrefix(mime, "audio/pcm;")
+}
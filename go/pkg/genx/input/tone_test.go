@@ -0,0 +1,169 @@
+package input
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// tonePCM generates PCM16 audio (16kHz mono) summing sines at freqs.
+func tonePCM(d time.Duration, freqs ...float64) []byte {
+	n := int(16000 * d / time.Second)
+	out := make([]byte, n*2)
+	amp := 0.8 / float64(max(len(freqs), 1))
+	for i := range n {
+		var v float64
+		for _, f := range freqs {
+			v += amp * math.Sin(2*math.Pi*f*float64(i)/16000)
+		}
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(int16(v*32767)))
+	}
+	return out
+}
+
+func silencePCM(d time.Duration) []byte {
+	return make([]byte, int(16000*d/time.Second)*2)
+}
+
+func TestToneDetector_DTMF(t *testing.T) {
+	digits := "0123456789*#ABCD"
+	freqs := map[rune][2]float64{}
+	for r, row := range dtmfKeys {
+		for c, key := range row {
+			freqs[key] = [2]float64{dtmfRows[r], dtmfCols[c]}
+		}
+	}
+
+	var pcm []byte
+	for _, d := range digits {
+		f := freqs[d]
+		pcm = append(pcm, tonePCM(100*time.Millisecond, f[0], f[1])...)
+		pcm = append(pcm, silencePCM(60*time.Millisecond)...)
+	}
+
+	det := NewToneDetector(ToneConfig{})
+	events := append(det.Write(pcm), det.Flush()...)
+
+	var got []rune
+	for _, ev := range events {
+		if ev.Kind != ToneDTMF {
+			t.Errorf("unexpected %v event: %+v", ev.Kind, ev)
+			continue
+		}
+		got = append(got, ev.Digit)
+		if ev.Duration < 60*time.Millisecond || ev.Duration > 100*time.Millisecond {
+			t.Errorf("digit %c duration = %v, want ~100ms", ev.Digit, ev.Duration)
+		}
+	}
+	if string(got) != digits {
+		t.Errorf("digits = %q, want %q", string(got), digits)
+	}
+}
+
+func TestToneDetector_Beep(t *testing.T) {
+	pcm := append(silencePCM(100*time.Millisecond), tonePCM(300*time.Millisecond, 1000)...)
+
+	det := NewToneDetector(ToneConfig{})
+	if events := det.Write(pcm); len(events) != 0 {
+		t.Fatalf("tone in progress should not be reported yet: %+v", events)
+	}
+	events := det.Flush()
+	if len(events) != 1 {
+		t.Fatalf("events = %+v, want 1 beep", events)
+	}
+	ev := events[0]
+	if ev.Kind != ToneBeep || ev.Frequency != 1000 {
+		t.Errorf("event = %+v, want 1000Hz beep", ev)
+	}
+	if ev.Offset < 80*time.Millisecond || ev.Offset > 120*time.Millisecond {
+		t.Errorf("Offset = %v, want ~100ms", ev.Offset)
+	}
+}
+
+func TestToneDetector_Rejects(t *testing.T) {
+	tests := []struct {
+		name string
+		pcm  []byte
+	}{
+		{"silence", silencePCM(500 * time.Millisecond)},
+		{"too short", tonePCM(20*time.Millisecond, 770, 1336)},
+		{"unlisted frequency", tonePCM(300*time.Millisecond, 440)},
+		{"chord", tonePCM(300*time.Millisecond, 440, 554, 659)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			det := NewToneDetector(ToneConfig{})
+			if events := append(det.Write(tt.pcm), det.Flush()...); len(events) != 0 {
+				t.Errorf("events = %+v, want none", events)
+			}
+		})
+	}
+}
+
+type sliceStream struct {
+	chunks []*genx.MessageChunk
+}
+
+func (s *sliceStream) Next() (*genx.MessageChunk, error) {
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+	c := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return c, nil
+}
+
+func (s *sliceStream) Close() error               { return nil }
+func (s *sliceStream) CloseWithError(error) error { return nil }
+
+func TestDetectTones(t *testing.T) {
+	pcm := func(b []byte) *genx.Blob { return &genx.Blob{MIMEType: "audio/pcm", Data: b} }
+	in := &sliceStream{chunks: []*genx.MessageChunk{
+		{Role: genx.RoleUser, Name: "caller", Part: pcm(tonePCM(120*time.Millisecond, 770, 1336))},
+		{Role: genx.RoleUser, Name: "caller", Part: pcm(silencePCM(60 * time.Millisecond))},
+		// Model audio is not analyzed.
+		{Role: genx.RoleModel, Part: pcm(tonePCM(120*time.Millisecond, 1000))},
+		{Role: genx.RoleUser, Name: "caller", Part: pcm(tonePCM(120*time.Millisecond, 1000))},
+		{Role: genx.RoleUser, Name: "caller", Part: &genx.Blob{MIMEType: "audio/pcm"}, Ctrl: &genx.StreamCtrl{EndOfStream: true}},
+	}}
+
+	out := DetectTones(in, ToneConfig{})
+	var tones []*genx.MessageChunk
+	var last *genx.MessageChunk
+	total := 0
+	for {
+		c, err := out.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		total++
+		if _, ok := c.Part.(genx.Text); ok {
+			tones = append(tones, c)
+		}
+		last = c
+	}
+
+	if total != 7 {
+		t.Errorf("total chunks = %d, want 7 (5 passed through + 2 tones)", total)
+	}
+	if len(tones) != 2 {
+		t.Fatalf("tone chunks = %d, want 2", len(tones))
+	}
+	if got := tones[0]; got.Part != genx.Text("5") || got.Metadata(MetaTone) != "dtmf" || got.Name != "caller" {
+		t.Errorf("first tone = %+v, want DTMF 5 from caller", got)
+	}
+	if got := tones[1]; got.Part != genx.Text("beep") || got.Metadata(MetaToneFrequency) != "1000" {
+		t.Errorf("second tone = %+v (freq %q), want 1000Hz beep", got, got.Metadata(MetaToneFrequency))
+	}
+	// The beep flushed by the EoS comes before it.
+	if !last.IsEndOfStream() {
+		t.Errorf("last chunk = %+v, want the user EoS after its tones", last)
+	}
+}
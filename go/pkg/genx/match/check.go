@@ -0,0 +1,247 @@
+package match
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Severity is the severity of a Diagnostic.
+type Severity string
+
+const (
+	// SeverityError marks a rule set that should not be deployed.
+	SeverityError Severity = "error"
+
+	// SeverityWarning marks a likely mistake that does not break matching.
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic codes.
+const (
+	// CodeInvalid is a rule that fails to compile.
+	CodeInvalid = "invalid"
+
+	// CodeDuplicateRule is a rule with the name of an earlier rule.
+	CodeDuplicateRule = "duplicate-rule"
+
+	// CodeConflict is two patterns of different rules matching each
+	// other's utterances.
+	CodeConflict = "conflict"
+
+	// CodeShadowed is a pattern whose utterances are taken by a pattern of
+	// an earlier rule.
+	CodeShadowed = "shadowed"
+
+	// CodeOverlap is a pattern whose utterances are also matched by a
+	// pattern of a later rule. The earlier rule wins in Score, but the
+	// model may pick either.
+	CodeOverlap = "overlap"
+
+	// CodeUnreachableVar is a var that no pattern of its rule outputs.
+	CodeUnreachableVar = "unreachable-var"
+)
+
+// Diagnostic is a problem found by Check.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+
+	// Rule is the name of the rule with the problem.
+	Rule string `json:"rule"`
+
+	// Pattern is the input of the pattern with the problem, if any.
+	Pattern string `json:"pattern,omitempty"`
+
+	// Other is the rule the problem is with, for conflicts, shadowing and
+	// overlaps.
+	Other string `json:"other,omitempty"`
+
+	Message string `json:"message"`
+}
+
+// String returns the diagnostic as "severity: rule "name": message".
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: rule %q: %s", d.Severity, d.Rule, d.Message)
+}
+
+// TestCase is an utterance generated from a pattern, with the rule and args
+// it should match.
+type TestCase struct {
+	Input string            `json:"input"`
+	Rule  string            `json:"rule"`
+	Args  map[string]string `json:"args,omitempty"`
+}
+
+// Report is the result of Check.
+type Report struct {
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+
+	// Matrix has a test case per pattern of the valid rules, in rule
+	// order. Run them through Score or Match to test a deployment.
+	Matrix []TestCase `json:"matrix,omitempty"`
+}
+
+// Errors returns the diagnostics of severity SeverityError.
+func (r *Report) Errors() []Diagnostic {
+	var out []Diagnostic
+	for _, d := range r.Diagnostics {
+		if d.Severity == SeverityError {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Err returns an error listing the error diagnostics, or nil if there are
+// none.
+func (r *Report) Err() error {
+	var errs []error
+	for _, d := range r.Errors() {
+		errs = append(errs, errors.New(d.String()))
+	}
+	return errors.Join(errs...)
+}
+
+// checkPattern is a pattern of a rule, with a sample utterance.
+type checkPattern struct {
+	rule   int // index of the rule in the checked rules
+	input  string
+	re     *regexp.Regexp
+	sample string
+	args   map[string]string
+}
+
+// Check analyzes a rule set before it is compiled and deployed. It reports
+// rules that fail to compile, duplicate rule names, vars that no pattern
+// outputs, and pairs of rules matching the same utterance, and generates a
+// test matrix.
+//
+// Overlaps are found by sampling: each pattern yields an utterance, its
+// [var] placeholders filled with a value of the var's type, which is tried
+// against the patterns of the other rules. As in Score, the earlier of two
+// rules matching an utterance wins, so a pattern whose utterances an
+// earlier rule takes is shadowed and never matches.
+func Check(rules []*Rule) *Report {
+	report := &Report{}
+	add := func(d Diagnostic) { report.Diagnostics = append(report.Diagnostics, d) }
+
+	var patterns []checkPattern
+	seen := make(map[string]bool)
+	for i, r := range rules {
+		if r == nil {
+			continue
+		}
+		if seen[r.Name] {
+			add(Diagnostic{Severity: SeverityError, Code: CodeDuplicateRule, Rule: r.Name,
+				Message: "duplicate rule name; the rule is skipped"})
+			continue
+		}
+		seen[r.Name] = true
+		if err := r.compileTo(&promptData{References: make(map[string]string)}); err != nil {
+			add(Diagnostic{Severity: SeverityError, Code: CodeInvalid, Rule: r.Name,
+				Message: strings.TrimPrefix(err.Error(), fmt.Sprintf("rule %q: ", r.Name))})
+			continue
+		}
+
+		for _, name := range unreachableVars(r) {
+			add(Diagnostic{Severity: SeverityWarning, Code: CodeUnreachableVar, Rule: r.Name,
+				Message: fmt.Sprintf("var %q is never output: no pattern has a labeled [%s] placeholder or %s= in its output", name, name, name)})
+		}
+
+		for _, p := range r.Patterns {
+			if strings.TrimSpace(p.Input) == "" {
+				continue
+			}
+			re, _ := patternRegexp(p.Input)
+			sample, args := samplePattern(p.Input, r.Vars)
+			patterns = append(patterns, checkPattern{rule: i, input: p.Input, re: re, sample: sample, args: args})
+			report.Matrix = append(report.Matrix, TestCase{Input: sample, Rule: r.Name, Args: args})
+		}
+	}
+
+	for i, a := range patterns {
+		for _, b := range patterns[i+1:] {
+			if a.rule == b.rule {
+				continue
+			}
+			ra, rb := rules[a.rule].Name, rules[b.rule].Name
+			aInB := b.re.MatchString(a.sample) // b takes the utterances of a
+			bInA := a.re.MatchString(b.sample) // a takes the utterances of b
+			switch {
+			case aInB && bInA:
+				add(Diagnostic{Severity: SeverityError, Code: CodeConflict, Rule: rb, Pattern: b.input, Other: ra,
+					Message: fmt.Sprintf("pattern %q matches the same utterances as pattern %q of rule %q", b.input, a.input, ra)})
+			case bInA:
+				add(Diagnostic{Severity: SeverityError, Code: CodeShadowed, Rule: rb, Pattern: b.input, Other: ra,
+					Message: fmt.Sprintf("pattern %q is shadowed by pattern %q of earlier rule %q, e.g. %q", b.input, a.input, ra, b.sample)})
+			case aInB:
+				add(Diagnostic{Severity: SeverityWarning, Code: CodeOverlap, Rule: ra, Pattern: a.input, Other: rb,
+					Message: fmt.Sprintf("pattern %q overlaps pattern %q of later rule %q, e.g. %q", a.input, b.input, rb, a.sample)})
+			}
+		}
+	}
+	return report
+}
+
+// unreachableVars returns the sorted names of the vars of r that no
+// pattern outputs: an expanded pattern outputs the vars of its labeled
+// placeholders, a pattern with an output the keys in it.
+func unreachableVars(r *Rule) []string {
+	output := make(map[string]bool)
+	for _, p := range r.Patterns {
+		if p.Output == "" {
+			for _, m := range placeholderRe.FindAllStringSubmatch(p.Input, -1) {
+				if r.Vars[m[1]].Label != "" {
+					output[m[1]] = true
+				}
+			}
+			continue
+		}
+		_, kv, _ := strings.Cut(p.Output, ":")
+		for part := range strings.SplitSeq(kv, ",") {
+			if k, _, ok := strings.Cut(part, "="); ok {
+				output[strings.TrimSpace(k)] = true
+			}
+		}
+	}
+	var out []string
+	for _, name := range slices.Sorted(maps.Keys(r.Vars)) {
+		if !output[name] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// samplePattern returns an utterance of the pattern input, its placeholders
+// filled with a value of the var's type, and the values by var name.
+func samplePattern(input string, vars map[string]Var) (string, map[string]string) {
+	var args map[string]string
+	sample := placeholderRe.ReplaceAllStringFunc(input, func(m string) string {
+		name := m[1 : len(m)-1]
+		v := sampleValue(vars[name].Type)
+		if args == nil {
+			args = make(map[string]string)
+		}
+		args[name] = v
+		return v
+	})
+	return sample, args
+}
+
+// sampleValue returns a value of a var type.
+func sampleValue(typ string) string {
+	switch typ {
+	case "int":
+		return "42"
+	case "float":
+		return "1.5"
+	case "bool":
+		return "true"
+	}
+	return "xyzzy"
+}
@@ -39,6 +39,7 @@ func WithTpl(tpl string) Option {
 type Matcher struct {
 	systemPrompt string
 	specs        map[string]map[string]Var // rule name -> var name -> Var
+	samples      []sample                  // patterns and examples, for Score
 }
 
 // SystemPrompt returns the rendered system prompt for debugging.
@@ -68,6 +69,10 @@ type Result struct {
 
 	// RawText holds the original line when no rule matched.
 	RawText string
+
+	// Score is the confidence, from 0 to 1, of a result of Score. Results
+	// of Match, from the model, have no score.
+	Score float64
 }
 
 // MatchOption configures Match behavior.
@@ -245,26 +250,8 @@ func (m *Matcher) parseKVToArgs(kv string, vars map[string]Var) map[string]Arg {
 			continue
 		}
 
-		// Convert value based on Var.Type
-		var typedValue any = v
-		switch varDef.Type {
-		case "int":
-			if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
-				typedValue = parsed
-			}
-		case "float":
-			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
-				typedValue = parsed
-			}
-		case "bool":
-			if parsed, err := strconv.ParseBool(v); err == nil {
-				typedValue = parsed
-			}
-			// "string" or empty: keep as string
-		}
-
 		args[k] = Arg{
-			Value:    typedValue,
+			Value:    typedValue(varDef, v),
 			Var:      varDef,
 			HasValue: true,
 		}
@@ -273,6 +260,27 @@ func (m *Matcher) parseKVToArgs(kv string, vars map[string]Var) map[string]Arg {
 	return args
 }
 
+// typedValue converts v based on Var.Type. Values that do not parse are
+// kept as strings.
+func typedValue(varDef Var, v string) any {
+	switch varDef.Type {
+	case "int":
+		if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return parsed
+		}
+	case "float":
+		if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+			return parsed
+		}
+	case "bool":
+		if parsed, err := strconv.ParseBool(v); err == nil {
+			return parsed
+		}
+		// "string" or empty: keep as string
+	}
+	return v
+}
+
 // Collect consumes a streaming sequence into a slice.
 func Collect(seq iter.Seq2[Result, error]) ([]Result, error) {
 	var out []Result
@@ -310,6 +318,7 @@ func Compile(rules []*Rule, opts ...Option) (*Matcher, error) {
 	}
 
 	specs := make(map[string]map[string]Var, len(rules))
+	var samples []sample
 	for _, r := range rules {
 		if r == nil {
 			continue
@@ -319,11 +328,13 @@ func Compile(rules []*Rule, opts ...Option) (*Matcher, error) {
 			continue
 		}
 		specs[r.Name] = r.Vars
+		samples = append(samples, ruleSamples(r)...)
 	}
 
 	return &Matcher{
 		systemPrompt: buf.String(),
 		specs:        specs,
+		samples:      samples,
 	}, nil
 }
 
@@ -495,3 +495,197 @@ func TestWithTpl(t *testing.T) {
 		t.Errorf("SystemPrompt() = %q, want custom template output", matcher.SystemPrompt())
 	}
 }
+
+func TestScore(t *testing.T) {
+	rules := []*Rule{
+		{
+			Name: "music",
+			Vars: map[string]Var{
+				"artist": {Label: "歌手", Type: "string"},
+				"title":  {Label: "歌曲名", Type: "string"},
+			},
+			Patterns: []Pattern{
+				{Input: "播放歌曲"},
+				{Input: "我想听[artist]的[title]"},
+			},
+		},
+		{
+			Name: "volume",
+			Vars: map[string]Var{
+				"level": {Label: "level", Type: "int"},
+			},
+			Patterns: []Pattern{
+				{Input: "set volume to [level]"},
+			},
+			Examples: []Example{
+				{Subject: "louder", UserText: "turn it up a bit"},
+			},
+		},
+	}
+	matcher, err := Compile(rules)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		wantRule string
+		wantOne  bool // exact match, score 1
+		args     map[string]any
+	}{
+		{"我想听周杰伦的稻香。", "music", true, map[string]any{"artist": "周杰伦", "title": "稻香"}},
+		{"播放歌曲", "music", true, nil},
+		{"Set Volume to 7!", "volume", true, map[string]any{"level": int64(7)}},
+		{"turn it up a bit please", "volume", false, nil},
+		{"播放一首歌曲", "music", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			results := matcher.Score(tt.input)
+			if len(results) != 2 {
+				t.Fatalf("len(results) = %d, want 2", len(results))
+			}
+			best := results[0]
+			if best.Rule != tt.wantRule {
+				t.Fatalf("best rule = %q, want %q", best.Rule, tt.wantRule)
+			}
+			if (best.Score == 1) != tt.wantOne {
+				t.Errorf("score = %v, want exact match %v", best.Score, tt.wantOne)
+			}
+			if !tt.wantOne && (best.Score <= 0 || best.Score >= 1) {
+				t.Errorf("score = %v, want between 0 and 1", best.Score)
+			}
+			if results[1].Score > best.Score {
+				t.Errorf("results not sorted: %v > %v", results[1].Score, best.Score)
+			}
+			for k, want := range tt.args {
+				if arg := best.Args[k]; !arg.HasValue || arg.Value != want {
+					t.Errorf("arg %s = %+v, want %v", k, arg, want)
+				}
+			}
+		})
+	}
+
+	for _, r := range matcher.Score("what's the weather") {
+		if r.Score >= 0.5 {
+			t.Errorf("unrelated input: %s scored %v", r.Rule, r.Score)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "abc", 0},
+		{"a", "a", 1},
+		{"a", "b", 0},
+		{"night", "nacht", 0.25},
+		{"播放歌曲", "播放歌曲", 1},
+	}
+	for _, tt := range tests {
+		if got := similarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	rules := []*Rule{
+		{
+			Name: "play_song",
+			Vars: map[string]Var{
+				"title":  {Label: "song title"},
+				"volume": {Label: "volume", Type: "int"},
+			},
+			Patterns: []Pattern{{Input: "play [title]"}},
+		},
+		// Taken by play_song
+		{Name: "play_music", Patterns: []Pattern{{Input: "play music"}}},
+		// Same utterances as play_song
+		{
+			Name:     "start_song",
+			Vars:     map[string]Var{"name": {Label: "song"}},
+			Patterns: []Pattern{{Input: "Play  [name]"}},
+		},
+		{
+			Name:     "stop",
+			Patterns: []Pattern{{Input: "stop"}, {Input: "stop [what]", Output: "stop: what=[what]"}},
+			Vars:     map[string]Var{"what": {Type: "string"}},
+		},
+		// Taken by "stop [what]"
+		{Name: "stop_now", Patterns: []Pattern{{Input: "stop now please"}}},
+		{Name: "stop", Patterns: []Pattern{{Input: "halt"}}},
+		{Name: "bad", Patterns: []Pattern{{Input: "go [where]"}}},
+	}
+
+	report := Check(rules)
+	type key struct{ code, rule, other string }
+	got := make(map[key]Severity)
+	for _, d := range report.Diagnostics {
+		got[key{d.Code, d.Rule, d.Other}] = d.Severity
+	}
+	want := map[key]Severity{
+		{CodeUnreachableVar, "play_song", ""}:     SeverityWarning,
+		{CodeShadowed, "play_music", "play_song"}: SeverityError,
+		{CodeOverlap, "play_music", "start_song"}: SeverityWarning,
+		{CodeConflict, "start_song", "play_song"}: SeverityError,
+		{CodeShadowed, "stop_now", "stop"}:        SeverityError,
+		{CodeDuplicateRule, "stop", ""}:           SeverityError,
+		{CodeInvalid, "bad", ""}:                  SeverityError,
+	}
+	for k, sev := range want {
+		if got[k] != sev {
+			t.Errorf("missing %s diagnostic %+v", sev, k)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("diagnostics = %v", report.Diagnostics)
+	}
+	if err := report.Err(); err == nil {
+		t.Error("Err() = nil")
+	}
+
+	// One case per pattern of the valid, non-duplicate rules
+	if len(report.Matrix) != 6 {
+		t.Fatalf("matrix = %+v", report.Matrix)
+	}
+	if tc := report.Matrix[0]; tc.Rule != "play_song" || tc.Args["title"] == "" || tc.Input != "play "+tc.Args["title"] {
+		t.Errorf("matrix[0] = %+v", tc)
+	}
+}
+
+func TestCheckOverlap(t *testing.T) {
+	rules := []*Rule{
+		{Name: "play_music", Patterns: []Pattern{{Input: "play music"}}},
+		{
+			Name:     "play_song",
+			Vars:     map[string]Var{"title": {Label: "song title"}},
+			Patterns: []Pattern{{Input: "play [title]"}},
+		},
+		{Name: "stop", Patterns: []Pattern{{Input: "stop"}}},
+	}
+	report := Check(rules)
+	if err := report.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if len(report.Diagnostics) != 1 {
+		t.Fatalf("diagnostics = %v", report.Diagnostics)
+	}
+	d := report.Diagnostics[0]
+	if d.Code != CodeOverlap || d.Severity != SeverityWarning || d.Rule != "play_music" || d.Other != "play_song" {
+		t.Errorf("diagnostic = %+v", d)
+	}
+
+	// The matrix passes through Score
+	m, err := Compile(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range report.Matrix {
+		if got := m.Score(tc.Input)[0]; got.Rule != tc.Rule || got.Score != 1 {
+			t.Errorf("Score(%q) = %s %v, want %s", tc.Input, got.Rule, got.Score, tc.Rule)
+		}
+	}
+}
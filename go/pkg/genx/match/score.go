@@ -0,0 +1,173 @@
+package match
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// sample is a pattern or an example of a rule, compiled for Score.
+type sample struct {
+	rule string
+	re   *regexp.Regexp // nil for examples
+	vars []string       // var names of the groups of re
+	text string         // normalized text without placeholders
+}
+
+// ruleSamples compiles the patterns and examples of r.
+func ruleSamples(r *Rule) []sample {
+	var out []sample
+	for _, p := range r.Patterns {
+		re, vars := patternRegexp(p.Input)
+		out = append(out, sample{
+			rule: r.Name,
+			re:   re,
+			vars: vars,
+			text: normalize(placeholderRe.ReplaceAllString(p.Input, "")),
+		})
+	}
+	for _, e := range r.Examples {
+		// Examples of a single element, like ["hello"], are utterances too
+		text := e.UserText
+		if text == "" {
+			text = e.Subject
+		}
+		if text != "" {
+			out = append(out, sample{rule: r.Name, text: normalize(text)})
+		}
+	}
+	return out
+}
+
+// patternRegexp returns a regexp matching the whole of an input of the
+// pattern, with a group per [var] placeholder, and the var names in group
+// order. Case, spacing and trailing punctuation are ignored.
+func patternRegexp(input string) (*regexp.Regexp, []string) {
+	var sb strings.Builder
+	var vars []string
+	sb.WriteString(`(?i)^`)
+	last := 0
+	for _, loc := range placeholderRe.FindAllStringSubmatchIndex(input, -1) {
+		sb.WriteString(literalRegexp(input[last:loc[0]]))
+		sb.WriteString(`(.+?)`)
+		vars = append(vars, input[loc[2]:loc[3]])
+		last = loc[1]
+	}
+	sb.WriteString(literalRegexp(input[last:]))
+	sb.WriteString(`[\s\p{P}\p{S}]*$`)
+	return regexp.MustCompile(sb.String()), vars
+}
+
+// literalRegexp returns a regexp matching s with any spacing.
+func literalRegexp(s string) string {
+	fields := strings.Fields(s)
+	for i, f := range fields {
+		fields[i] = regexp.QuoteMeta(f)
+	}
+	if len(fields) == 0 {
+		return `\s*`
+	}
+	return `\s*` + strings.Join(fields, `\s*`) + `\s*`
+}
+
+// Score matches input against the patterns and examples of the rules
+// without a model. It returns a result per rule with patterns or examples,
+// best first.
+//
+// A rule scores 1 when input matches one of its patterns, any text taking
+// the place of the [var] placeholders; the result then has the vars as
+// Args. Otherwise the score is the similarity of input to the rule's
+// closest pattern (without placeholders) or example (its user text, or its
+// subject without one): the Dice coefficient of their character bigrams,
+// ignoring case, spacing and punctuation.
+func (m *Matcher) Score(input string) []Result {
+	norm := normalize(input)
+	var results []Result
+	index := make(map[string]int) // rule name -> index in results
+	for _, s := range m.samples {
+		i, ok := index[s.rule]
+		if !ok {
+			i = len(results)
+			index[s.rule] = i
+			results = append(results, Result{Rule: s.rule, Args: m.parseKVToArgs("", m.specs[s.rule])})
+		}
+		r := &results[i]
+		if r.Score == 1 {
+			continue
+		}
+		if s.re != nil {
+			if sub := s.re.FindStringSubmatch(input); sub != nil {
+				r.Score = 1
+				for j, name := range s.vars {
+					v := strings.TrimFunc(sub[j+1], func(c rune) bool {
+						return unicode.IsSpace(c) || unicode.IsPunct(c)
+					})
+					r.Args[name] = Arg{
+						Value:    typedValue(r.Args[name].Var, v),
+						Var:      r.Args[name].Var,
+						HasValue: v != "",
+					}
+				}
+				continue
+			}
+		}
+		r.Score = max(r.Score, similarity(norm, s.text))
+	}
+	slices.SortStableFunc(results, func(a, b Result) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	return results
+}
+
+// normalize lowercases s and keeps only its letters and digits.
+func normalize(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			sb.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return sb.String()
+}
+
+// similarity returns the Dice coefficient of the character bigrams of the
+// normalized strings a and b.
+func similarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	ab, bb := bigrams(a), bigrams(b)
+	if len(ab) == 0 || len(bb) == 0 {
+		return 0
+	}
+	counts := make(map[string]int, len(ab))
+	for _, g := range ab {
+		counts[g]++
+	}
+	common := 0
+	for _, g := range bb {
+		if counts[g] > 0 {
+			counts[g]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(ab)+len(bb))
+}
+
+// bigrams returns the pairs of adjacent runes of s.
+func bigrams(s string) []string {
+	runes := []rune(s)
+	if len(runes) < 2 {
+		return nil
+	}
+	out := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		out = append(out, string(runes[i:i+2]))
+	}
+	return out
+}
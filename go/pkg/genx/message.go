@@ -3,7 +3,9 @@ package genx
 import (
 	"context"
 	"fmt"
+	"maps"
 	"slices"
+	"sync/atomic"
 )
 
 // Role constants define the producer of a message.
@@ -30,7 +32,7 @@ var (
 // Fields:
 //   - Role: The producer of this message (user, model, or tool)
 //   - Name: The name of the producer (e.g., "alice", "assistant", "weather")
-//   - Part: The content payload (Text or Blob)
+//   - Part: The content payload (Text, Blob, ImagePart or VideoFramePart)
 //   - ToolCall: Tool invocation data (for model calling tools)
 //   - Ctrl: Stream control signals (optional, for routing and state)
 //
@@ -45,6 +47,8 @@ type MessageChunk struct {
 	Part     Part
 	ToolCall *ToolCall
 	Ctrl     *StreamCtrl
+
+	refs *atomic.Int32 // references of a pooled chunk, see AcquireChunk
 }
 
 // StreamCtrl controls Stream routing and state.
@@ -77,6 +81,24 @@ type StreamCtrl struct {
 	// Used for packet loss detection and timing synchronization in real-time streams.
 	// When set, receivers can detect gaps in the stream by comparing timestamps.
 	Timestamp int64 `json:"timestamp,omitempty"`
+
+	// OriginTimestamp is the Unix epoch time in milliseconds when the input
+	// this chunk derives from entered the pipeline, e.g. when the user's
+	// audio was captured. Zero means unknown. Unlike Timestamp, it is carried
+	// from input to output chunks through the stages of a pipeline, so the
+	// latency of each stage can be measured against it (see package
+	// genx/trace).
+	OriginTimestamp int64 `json:"origin_timestamp,omitempty"`
+
+	// Seq is an optional sequence number, increasing by one per chunk of
+	// the same StreamID and starting at 1. Zero means unset. See Sequence
+	// and CheckSequence.
+	Seq uint64 `json:"seq,omitempty"`
+
+	// Metadata holds free-form annotations attached by analysis transformers
+	// (e.g., "emotion" → "sad"). Like Label, it is informational only and
+	// must not be used for routing.
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 // IsBeginOfStream returns true if this chunk is a begin-of-stream marker.
@@ -89,6 +111,26 @@ func (c *MessageChunk) IsEndOfStream() bool {
 	return c != nil && c.Ctrl != nil && c.Ctrl.EndOfStream
 }
 
+// SetMetadata sets a metadata annotation on the chunk, allocating Ctrl and
+// the metadata map as needed.
+func (c *MessageChunk) SetMetadata(key, value string) {
+	if c.Ctrl == nil {
+		c.Ctrl = &StreamCtrl{}
+	}
+	if c.Ctrl.Metadata == nil {
+		c.Ctrl.Metadata = make(map[string]string)
+	}
+	c.Ctrl.Metadata[key] = value
+}
+
+// Metadata returns the metadata annotation for key, or "" if unset.
+func (c *MessageChunk) Metadata(key string) string {
+	if c == nil || c.Ctrl == nil {
+		return ""
+	}
+	return c.Ctrl.Metadata[key]
+}
+
 // NewBeginOfStream creates a BOS marker with the given StreamID.
 // This is used by transformers to signal the start of a new logical stream.
 func NewBeginOfStream(streamID string) *MessageChunk {
@@ -117,22 +159,27 @@ func NewTextEndOfStream() *MessageChunk {
 
 // Clone returns a deep copy of the MessageChunk.
 func (c *MessageChunk) Clone() *MessageChunk {
-	chk := &MessageChunk{
-		Role: c.Role,
-		Name: c.Name,
-	}
+	chk := &MessageChunk{}
+	c.cloneHeaderTo(chk)
 	if c.Part != nil {
 		chk.Part = c.Part.clone()
 	}
+	return chk
+}
+
+// cloneHeaderTo deep-copies all fields of c but Part to chk.
+func (c *MessageChunk) cloneHeaderTo(chk *MessageChunk) {
+	chk.Role = c.Role
+	chk.Name = c.Name
 	if c.ToolCall != nil {
 		t := *c.ToolCall
 		chk.ToolCall = &t
 	}
 	if c.Ctrl != nil {
 		ctrl := *c.Ctrl
+		ctrl.Metadata = maps.Clone(c.Ctrl.Metadata)
 		chk.Ctrl = &ctrl
 	}
-	return chk
 }
 
 type Message struct {
@@ -196,7 +243,8 @@ type Contents []Part
 func (Contents) isPayload() {}
 
 // Part is the content payload of a MessageChunk.
-// Implementations: Text (string content) and Blob (binary data with MIME type).
+// Implementations: Text (string content), Blob (binary data with MIME type),
+// ImagePart (a still image) and VideoFramePart (a frame of a video).
 type Part interface {
 	isPart()
 	clone() Part
@@ -205,11 +253,14 @@ type Part interface {
 // Blob represents binary data with a MIME type.
 // Common MIME types:
 //   - audio/opus, audio/pcm, audio/mp3: Audio data
-//   - image/png, image/jpeg: Image data
+//   - image/png, image/jpeg: Image data (prefer using ImagePart instead)
 //   - text/plain: Plain text (prefer using Text type instead)
 type Blob struct {
 	MIMEType string
 	Data     []byte
+
+	pooled bool    // from AcquireBlob
+	buf    *[]byte // pooled buffer backing Data
 }
 
 func (b *Blob) clone() Part {
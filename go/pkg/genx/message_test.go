@@ -115,6 +115,25 @@ func TestMessageChunk_Clone_WithBlob(t *testing.T) {
 	}
 }
 
+func TestMessageChunk_Metadata(t *testing.T) {
+	chunk := &MessageChunk{Role: RoleUser, Part: Text("hi")}
+	if got := chunk.Metadata("emotion"); got != "" {
+		t.Errorf("Metadata on empty chunk = %q, want empty", got)
+	}
+
+	chunk.SetMetadata("emotion", "happy")
+	if got := chunk.Metadata("emotion"); got != "happy" {
+		t.Errorf("Metadata = %q, want %q", got, "happy")
+	}
+
+	// Clone must deep copy the metadata map.
+	cloned := chunk.Clone()
+	chunk.SetMetadata("emotion", "sad")
+	if got := cloned.Metadata("emotion"); got != "happy" {
+		t.Errorf("cloned Metadata = %q, want %q", got, "happy")
+	}
+}
+
 func TestContents_isPayload(t *testing.T) {
 	var c Contents = []Part{Text("test")}
 	// This should compile - Contents implements Payload
@@ -133,6 +133,15 @@ func (mcb *ModelContextBuilder) UserBlob(name string, mimeType string, data []by
 	})
 }
 
+// UserImage adds a user message with an image, e.g. NewImage or NewImageURL.
+func (mcb *ModelContextBuilder) UserImage(name string, img *ImagePart) {
+	mcb.AddMessage(&Message{
+		Role:    RoleUser,
+		Name:    name,
+		Payload: Contents{img},
+	})
+}
+
 func (mcb *ModelContextBuilder) ModelText(name, text string) {
 	mcb.AddMessage(&Message{
 		Role:    RoleModel,
@@ -53,6 +53,10 @@ func registerDoubaoASR(cfg ConfigFile) ([]string, error) {
 		if language, ok := cfg.DefaultParams["language"].(string); ok {
 			opts = append(opts, transformers.WithDoubaoASRSAUCLanguage(language))
 		}
+		if diarization, ok := cfg.DefaultParams["diarization"].(bool); ok && diarization {
+			speakerNum, _ := cfg.DefaultParams["speaker_num"].(float64)
+			opts = append(opts, transformers.WithDoubaoASRSAUCDiarization(int(speakerNum)))
+		}
 	}
 
 	var names []string
@@ -26,6 +26,7 @@ const (
 	oaiFinishReasonContentFilter string = "content_filter"
 
 	oaiMaxTextContentLength = 1048576
+	oaiMaxStopSequences     = 4
 )
 
 // OpenAISchemaFormatter formats a JSON schema for OpenAI structured outputs.
@@ -67,13 +68,14 @@ func (g *OpenAIGenerator) GenerateStream(ctx context.Context, _ string, mctx Mod
 	if err != nil {
 		return nil, err
 	}
+	params.StreamOptions.IncludeUsage = param.NewOpt(true)
 	sb := NewStreamBuilder(mctx, 32)
 	go func() {
 		if err := (&oaiPuller{}).pull(sb, g.Client.Chat.Completions.NewStreaming(ctx, params)); err != nil {
 			sb.Abort(err)
 		}
 	}()
-	return sb.Stream(), nil
+	return StopAt(sb.Stream(), stopSequences(g.GenerateParams, mctx.Params())...), nil
 }
 
 func (g *OpenAIGenerator) invokeJSONOutput(ctx context.Context, mctx ModelContext, fn *FuncTool) (Usage, *FuncCall, error) {
@@ -193,6 +195,16 @@ func (g *OpenAIGenerator) chatCompletion(mctx ModelContext, mp *ModelParams) (op
 			params.PresencePenalty = param.NewOpt(float64(mp.PresencePenalty))
 		}
 	}
+	if stop := stopSequences(mp, mctx.Params()); len(stop) > 0 {
+		// The API takes up to 4; GenerateStream enforces the rest.
+		params.Stop.OfStringArray = stop[:min(len(stop), oaiMaxStopSequences)]
+	}
+	if bias := logitBias(mp, mctx.Params()); len(bias) > 0 {
+		params.LogitBias = make(map[string]int64, len(bias))
+		for token, b := range bias {
+			params.LogitBias[token] = int64(b)
+		}
+	}
 	if g.SupportToolCalls {
 		for tool := range mctx.Tools() {
 			switch tool := tool.(type) {
@@ -239,11 +251,22 @@ func (p *oaiPuller) commitTool(sb *StreamBuilder) error {
 }
 
 func (p *oaiPuller) pull(sb *StreamBuilder, stream *ssestream.Stream[openai.ChatCompletionChunk]) (re error) {
-	var index int64
+	var (
+		index  int64
+		finish string
+		usage  Usage
+	)
+	defer stream.Close()
 
 	for stream.Next() {
 		chunk := stream.Current()
-		if len(chunk.Choices) == 0 {
+		if u := chunk.Usage; u.PromptTokens > 0 || u.CompletionTokens > 0 {
+			usage = oaiConvUsage(&u)
+			sb.SetUsage(usage)
+		}
+		// With stream_options.include_usage, the usage comes in a chunk
+		// without choices after the one with the finish reason.
+		if finish != "" || len(chunk.Choices) == 0 {
 			continue
 		}
 		var sel *openai.ChatCompletionChunkChoice
@@ -293,19 +316,26 @@ func (p *oaiPuller) pull(sb *StreamBuilder, stream *ssestream.Stream[openai.Chat
 			if err := p.commitTool(sb); err != nil {
 				return err
 			}
-			return sb.Done(oaiConvUsage(&chunk.Usage))
-		case oaiFinishReasonStop:
-			return sb.Done(oaiConvUsage(&chunk.Usage))
-		case oaiFinishReasonLength:
-			return sb.Truncated(oaiConvUsage(&chunk.Usage))
+			finish = sel.FinishReason
+		case oaiFinishReasonStop,
+			oaiFinishReasonLength:
+			finish = sel.FinishReason
 		case oaiFinishReasonContentFilter:
-			return sb.Blocked(oaiConvUsage(&chunk.Usage), sel.Delta.Refusal)
+			return sb.Blocked(usage, sel.Delta.Refusal)
 		}
 		if s := sel.Delta.Refusal; s != "" {
-			return sb.Blocked(oaiConvUsage(&chunk.Usage), s)
+			return sb.Blocked(usage, s)
 		}
 	}
-	return stream.Err()
+	// An error after the finish reason only loses the usage chunk.
+	if err := stream.Err(); err != nil && finish == "" {
+		return err
+	}
+	if finish == oaiFinishReasonLength {
+		return sb.Truncated(usage)
+	}
+	// Some OpenAI-compatible servers end the stream without a finish reason.
+	return sb.Done(usage)
 }
 
 func (g *OpenAIGenerator) convModelContext(mctx ModelContext) ([]openai.ChatCompletionMessageParamUnion, error) {
@@ -410,7 +440,7 @@ func (g *OpenAIGenerator) convModelMessage(msg *Message) (openai.ChatCompletionM
 		switch v := c.(type) {
 		case Text:
 			text.WriteString(string(v))
-		case *Blob:
+		case *Blob, *ImagePart, *VideoFramePart:
 			return openai.ChatCompletionMessageParamUnion{}, fmt.Errorf("model message must contain text only")
 		}
 	}
@@ -432,23 +462,29 @@ func (g *OpenAIGenerator) convModelMessage(msg *Message) (openai.ChatCompletionM
 
 func (g *OpenAIGenerator) convUserMessage(msg *Message) (openai.ChatCompletionMessageParamUnion, error) {
 	var (
-		mp3  bytes.Buffer
-		wav  bytes.Buffer
-		text bytes.Buffer
+		mp3    bytes.Buffer
+		wav    bytes.Buffer
+		text   bytes.Buffer
+		images []string
 	)
 	for _, c := range msg.Payload.(Contents) {
 		switch v := c.(type) {
 		case Text:
 			text.WriteString(string(v))
-		case *Blob:
+		case *Blob, *ImagePart, *VideoFramePart:
 			if g.SupportTextOnly {
 				return openai.ChatCompletionMessageParamUnion{}, fmt.Errorf("model %v support text message only", g.Model)
 			}
-			switch v.MIMEType {
+			if img, ok := AsImage(v); ok {
+				images = append(images, img.DataURL())
+				continue
+			}
+			blob := v.(*Blob)
+			switch blob.MIMEType {
 			case "audio/mp3", "audio/mpeg":
-				mp3.Write(v.Data)
+				mp3.Write(blob.Data)
 			case "audio/wav":
-				wav.Write(v.Data)
+				wav.Write(blob.Data)
 			default:
 				return openai.ChatCompletionMessageParamUnion{}, fmt.Errorf("unsupported message type: %T", v)
 			}
@@ -457,7 +493,7 @@ func (g *OpenAIGenerator) convUserMessage(msg *Message) (openai.ChatCompletionMe
 
 	var contents []openai.ChatCompletionContentPartUnionParam
 	switch {
-	case g.SupportTextOnly, mp3.Len() == 0 && wav.Len() == 0:
+	case g.SupportTextOnly, mp3.Len() == 0 && wav.Len() == 0 && len(images) == 0:
 		if text.Len() == 0 {
 			return openai.ChatCompletionMessageParamUnion{}, errors.New("user message must contain text")
 		}
@@ -486,8 +522,13 @@ func (g *OpenAIGenerator) convUserMessage(msg *Message) (openai.ChatCompletionMe
 			Format: "wav",
 		}))
 	}
+	for _, url := range images {
+		contents = append(contents, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+			URL: url,
+		}))
+	}
 	if len(contents) == 0 {
-		return openai.ChatCompletionMessageParamUnion{}, errors.New("user message must contain text or audio")
+		return openai.ChatCompletionMessageParamUnion{}, errors.New("user message must contain text, audio or images")
 	}
 	mp := openai.ChatCompletionUserMessageParam{
 		Content: openai.ChatCompletionUserMessageParamContentUnion{
@@ -0,0 +1,21 @@
+// Package output provides sinks that consume a genx.Stream.
+//
+// # Webhook
+//
+// WebhookSink posts the text of a conversation to an HTTP endpoint, so
+// conversations can feed analytics systems without custom consumers. It
+// collects RoleUser transcripts and RoleModel text into turns, one per
+// EoS-delimited sub-stream, and posts them in batches:
+//
+//	sink, err := output.NewWebhookSink(output.WebhookConfig{
+//	    URL:    "https://example.com/hooks/conversation",
+//	    Secret: secret,
+//	})
+//	out = sink.Tap(out) // pass-through; or sink.Consume(out)
+//	...
+//	sink.Close(ctx) // posts the remaining turns
+//
+// Requests are signed with HMAC-SHA256 when a secret is set (see
+// WebhookSignature) and retried with exponential backoff on network errors,
+// 429 and 5xx responses.
+package output
@@ -0,0 +1,445 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// Webhook request headers.
+const (
+	// HeaderTimestamp holds the Unix time in seconds at which the request
+	// was signed.
+	HeaderTimestamp = "X-Giztoy-Timestamp"
+	// HeaderSignature holds "sha256=" followed by the hex HMAC-SHA256 of the
+	// request, see WebhookSignature.
+	HeaderSignature = "X-Giztoy-Signature"
+)
+
+// ErrQueueFull is reported to WebhookConfig.OnError for turns dropped
+// because the sink could not keep up.
+var ErrQueueFull = errors.New("output: webhook queue full")
+
+// WebhookConfig configures a WebhookSink.
+type WebhookConfig struct {
+	// URL is the endpoint the turns are posted to. Required.
+	URL string
+
+	// Secret, if set, signs each request with HMAC-SHA256 (see
+	// WebhookSignature).
+	Secret string
+
+	// Headers are added to each request.
+	Headers map[string]string
+
+	// Client sends the requests.
+	// Default: an http.Client with a 10s timeout
+	Client *http.Client
+
+	// BatchSize is the number of turns that triggers a post.
+	// Default: 20
+	BatchSize int
+
+	// FlushInterval is the longest a turn waits for its batch to fill.
+	// Default: 5s
+	FlushInterval time.Duration
+
+	// MaxRetries is the number of retries of a failed post; negative for
+	// none.
+	// Default: 3
+	MaxRetries int
+
+	// RetryBackoff is the wait before the first retry; it doubles with each
+	// retry.
+	// Default: 500ms
+	RetryBackoff time.Duration
+
+	// QueueSize is the number of turns waiting to be posted. When it is
+	// full, new turns are dropped.
+	// Default: 1000
+	QueueSize int
+
+	// OnError, if set, is called with the turns that could not be posted.
+	OnError func(err error, turns []Turn)
+}
+
+func (c *WebhookConfig) setDefaults() {
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 20
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = 5 * time.Second
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	} else if c.MaxRetries == 0 {
+		c.MaxRetries = 3
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = 500 * time.Millisecond
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 1000
+	}
+}
+
+// Turn is the text of one EoS-delimited sub-stream of one role.
+type Turn struct {
+	StreamID  string    `json:"stream_id,omitempty"`
+	Role      genx.Role `json:"role"`
+	Name      string    `json:"name,omitempty"`
+	Text      string    `json:"text"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+}
+
+// WebhookPayload is the JSON body of a webhook request.
+type WebhookPayload struct {
+	Turns  []Turn    `json:"turns"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+// WebhookSignature returns the signature of a request body signed at the
+// given Unix time: the hex HMAC-SHA256, keyed with secret, of the timestamp
+// in decimal, a dot and the body. Receivers verify a request by comparing
+// the HeaderSignature value with "sha256=" + WebhookSignature(secret,
+// HeaderTimestamp value, body), and should reject stale timestamps.
+func WebhookSignature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookSink collects the text turns of streams and posts them to a
+// webhook in batches. It is safe for concurrent use; several streams may
+// be tapped into one sink.
+type WebhookSink struct {
+	config WebhookConfig
+	queue  chan Turn
+	done   chan struct{}
+	stop   context.CancelFunc
+	ctx    context.Context
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewWebhookSink creates a WebhookSink and starts its sender. Close it to
+// post the remaining turns and stop the sender.
+func NewWebhookSink(config WebhookConfig) (*WebhookSink, error) {
+	if config.URL == "" {
+		return nil, errors.New("output: webhook URL is required")
+	}
+	config.setDefaults()
+	ctx, stop := context.WithCancel(context.Background())
+	w := &WebhookSink{
+		config: config,
+		queue:  make(chan Turn, config.QueueSize),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		stop:   stop,
+	}
+	go w.run()
+	return w, nil
+}
+
+// Tap returns a stream that passes through every chunk of s and collects
+// its text turns.
+func (w *WebhookSink) Tap(s genx.Stream) genx.Stream {
+	return &tapStream{Stream: s, sink: w, turns: newTurnCollector()}
+}
+
+// Consume reads s to the end, collecting its text turns. It returns nil at
+// the end of s, or the error of s.
+func (w *WebhookSink) Consume(s genx.Stream) error {
+	tap := w.Tap(s)
+	for {
+		if _, err := tap.Next(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Close posts the remaining turns and stops the sender. Turns collected
+// after Close are dropped. If ctx is done before the remaining turns are
+// posted, pending retries are abandoned and Close returns ctx's error.
+func (w *WebhookSink) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		w.stop()
+		<-w.done
+		return ctx.Err()
+	}
+}
+
+// add queues a turn for posting.
+func (w *WebhookSink) add(t Turn) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return
+	}
+	select {
+	case w.queue <- t:
+	default:
+		if w.config.OnError != nil {
+			w.config.OnError(ErrQueueFull, []Turn{t})
+		}
+	}
+}
+
+func (w *WebhookSink) run() {
+	defer close(w.done)
+	defer w.stop()
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []Turn
+	flush := func() {
+		if len(batch) > 0 {
+			w.post(batch)
+			batch = nil
+		}
+	}
+	for {
+		select {
+		case t, ok := <-w.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, t)
+			if len(batch) >= w.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// post posts a batch, retrying with backoff, and reports it to OnError if
+// it cannot be posted.
+func (w *WebhookSink) post(turns []Turn) {
+	body, err := json.Marshal(WebhookPayload{Turns: turns, SentAt: time.Now().UTC()})
+	if err != nil {
+		w.fail(fmt.Errorf("output: webhook: marshal: %w", err), turns)
+		return
+	}
+
+	backoff := w.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.send(body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= w.config.MaxRetries {
+			w.fail(err, turns)
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-w.ctx.Done():
+			w.fail(err, turns)
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// send sends one request. It reports whether a failure may be retried.
+func (w *WebhookSink) send(body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("output: webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.config.Headers {
+		req.Header.Set(k, v)
+	}
+	if w.config.Secret != "" {
+		ts := time.Now().Unix()
+		req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+		req.Header.Set(HeaderSignature, "sha256="+WebhookSignature(w.config.Secret, ts, body))
+	}
+
+	resp, err := w.config.Client.Do(req)
+	if err != nil {
+		return w.ctx.Err() == nil, fmt.Errorf("output: webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("output: webhook: %s", resp.Status)
+}
+
+func (w *WebhookSink) fail(err error, turns []Turn) {
+	if w.config.OnError != nil {
+		w.config.OnError(err, turns)
+	}
+}
+
+type tapStream struct {
+	genx.Stream
+	sink  *WebhookSink
+	turns *turnCollector
+	once  sync.Once
+}
+
+func (s *tapStream) Next() (*genx.MessageChunk, error) {
+	chunk, err := s.Stream.Next()
+	if err != nil {
+		s.once.Do(s.flush)
+		return nil, err
+	}
+	if chunk != nil {
+		if t, ok := s.turns.add(chunk, time.Now()); ok {
+			s.sink.add(t)
+		}
+	}
+	return chunk, nil
+}
+
+func (s *tapStream) Close() error {
+	s.once.Do(s.flush)
+	return s.Stream.Close()
+}
+
+func (s *tapStream) CloseWithError(err error) error {
+	s.once.Do(s.flush)
+	return s.Stream.CloseWithError(err)
+}
+
+// flush posts the turns still open at the end of the stream.
+func (s *tapStream) flush() {
+	for _, t := range s.turns.flush(time.Now()) {
+		s.sink.add(t)
+	}
+}
+
+// turnCollector assembles the text of user and model chunks into turns.
+type turnCollector struct {
+	open  map[turnKey]*openTurn
+	order []turnKey // keys of open turns, oldest first
+}
+
+type turnKey struct {
+	streamID string
+	role     genx.Role
+}
+
+type openTurn struct {
+	turn Turn
+	text strings.Builder
+}
+
+func newTurnCollector() *turnCollector {
+	return &turnCollector{open: make(map[turnKey]*openTurn)}
+}
+
+// add collects a chunk. It returns the turn the chunk ended, if any.
+func (c *turnCollector) add(chunk *genx.MessageChunk, now time.Time) (Turn, bool) {
+	if chunk.Role != genx.RoleUser && chunk.Role != genx.RoleModel {
+		return Turn{}, false
+	}
+	var key turnKey
+	key.role = chunk.Role
+	if chunk.Ctrl != nil {
+		key.streamID = chunk.Ctrl.StreamID
+	}
+
+	if text, ok := chunkText(chunk); ok && text != "" {
+		t := c.open[key]
+		if t == nil {
+			t = &openTurn{turn: Turn{StreamID: key.streamID, Role: chunk.Role, Name: chunk.Name, StartedAt: now}}
+			c.open[key] = t
+			c.order = append(c.order, key)
+		}
+		t.text.WriteString(text)
+	}
+
+	if !chunk.IsEndOfStream() {
+		return Turn{}, false
+	}
+	t := c.open[key]
+	if t == nil {
+		return Turn{}, false
+	}
+	c.remove(key)
+	return t.done(now), true
+}
+
+// flush returns the open turns, oldest first, and forgets them.
+func (c *turnCollector) flush(now time.Time) []Turn {
+	var turns []Turn
+	for _, key := range c.order {
+		turns = append(turns, c.open[key].done(now))
+	}
+	c.open = make(map[turnKey]*openTurn)
+	c.order = nil
+	return turns
+}
+
+func (c *turnCollector) remove(key turnKey) {
+	delete(c.open, key)
+	for i, k := range c.order {
+		if k == key {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func (t *openTurn) done(now time.Time) Turn {
+	turn := t.turn
+	turn.Text = t.text.String()
+	turn.EndedAt = now
+	return turn
+}
+
+// chunkText returns the text of a Text part or a text/plain Blob.
+func chunkText(chunk *genx.MessageChunk) (string, bool) {
+	switch p := chunk.Part.(type) {
+	case genx.Text:
+		return string(p), true
+	case *genx.Blob:
+		if strings.HasPrefix(p.MIMEType, "text/plain") {
+			return string(p.Data), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,268 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+type sliceStream struct {
+	chunks []*genx.MessageChunk
+}
+
+func (s *sliceStream) Next() (*genx.MessageChunk, error) {
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+	c := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return c, nil
+}
+
+func (s *sliceStream) Close() error                   { return nil }
+func (s *sliceStream) CloseWithError(err error) error { return nil }
+
+func text(role genx.Role, id, s string) *genx.MessageChunk {
+	return &genx.MessageChunk{Role: role, Part: genx.Text(s), Ctrl: &genx.StreamCtrl{StreamID: id}}
+}
+
+func eos(role genx.Role, id string) *genx.MessageChunk {
+	c := genx.NewTextEndOfStream()
+	c.Role = role
+	c.Ctrl.StreamID = id
+	return c
+}
+
+// webhookServer records the payloads it receives. It fails the first
+// failures requests with status.
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	payloads []WebhookPayload
+	headers  []http.Header
+	bodies   [][]byte
+	calls    atomic.Int32
+}
+
+func newWebhookServer(t *testing.T, failures int32, status int) *webhookServer {
+	ws := &webhookServer{}
+	ws.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ws.calls.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var p WebhookPayload
+		if err := json.Unmarshal(body, &p); err != nil {
+			t.Errorf("unmarshal payload: %v", err)
+		}
+		ws.mu.Lock()
+		ws.payloads = append(ws.payloads, p)
+		ws.headers = append(ws.headers, r.Header.Clone())
+		ws.bodies = append(ws.bodies, body)
+		ws.mu.Unlock()
+	}))
+	t.Cleanup(ws.Close)
+	return ws
+}
+
+func TestWebhookSink_TurnsAndSignature(t *testing.T) {
+	ws := newWebhookServer(t, 0, 0)
+	sink, err := NewWebhookSink(WebhookConfig{URL: ws.URL, Secret: "s3cret", Headers: map[string]string{"X-Device": "toy-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	audio := &genx.MessageChunk{Role: genx.RoleModel, Part: &genx.Blob{MIMEType: "audio/pcm", Data: []byte{1}}, Ctrl: &genx.StreamCtrl{StreamID: "t1"}}
+	in := &sliceStream{chunks: []*genx.MessageChunk{
+		text(genx.RoleUser, "t1", "what's the "),
+		text(genx.RoleUser, "t1", "weather?"),
+		eos(genx.RoleUser, "t1"),
+		text(genx.RoleModel, "t1", "Sunny."),
+		audio,
+		{Role: genx.RoleModel, Part: &genx.Blob{MIMEType: "audio/pcm"}, Ctrl: &genx.StreamCtrl{StreamID: "t1", EndOfStream: true}},
+		text(genx.RoleModel, "t2", "unfinished"),
+	}}
+	out := sink.Tap(in)
+	var passed int
+	for {
+		if _, err := out.Next(); err != nil {
+			break
+		}
+		passed++
+	}
+	if passed != 7 {
+		t.Errorf("passed %d chunks, want 7", passed)
+	}
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ws.payloads) != 1 {
+		t.Fatalf("payloads = %d, want 1 batch", len(ws.payloads))
+	}
+	turns := ws.payloads[0].Turns
+	want := []Turn{
+		{StreamID: "t1", Role: genx.RoleUser, Text: "what's the weather?"},
+		{StreamID: "t1", Role: genx.RoleModel, Text: "Sunny."},
+		{StreamID: "t2", Role: genx.RoleModel, Text: "unfinished"},
+	}
+	if len(turns) != len(want) {
+		t.Fatalf("turns = %+v", turns)
+	}
+	for i, w := range want {
+		got := turns[i]
+		if got.StreamID != w.StreamID || got.Role != w.Role || got.Text != w.Text {
+			t.Errorf("turn %d = %+v, want %+v", i, got, w)
+		}
+		if got.StartedAt.IsZero() || got.EndedAt.Before(got.StartedAt) {
+			t.Errorf("turn %d times = %v..%v", i, got.StartedAt, got.EndedAt)
+		}
+	}
+
+	h := ws.headers[0]
+	if h.Get("X-Device") != "toy-1" || h.Get("Content-Type") != "application/json" {
+		t.Errorf("headers = %v", h)
+	}
+	ts, err := strconv.ParseInt(h.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("timestamp header: %v", err)
+	}
+	if got, want := h.Get(HeaderSignature), "sha256="+WebhookSignature("s3cret", ts, ws.bodies[0]); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookSink_Batching(t *testing.T) {
+	ws := newWebhookServer(t, 0, 0)
+	sink, err := NewWebhookSink(WebhookConfig{URL: ws.URL, BatchSize: 2, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chunks []*genx.MessageChunk
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		chunks = append(chunks, text(genx.RoleUser, id, id), eos(genx.RoleUser, id))
+	}
+	if err := sink.Consume(&sliceStream{chunks: chunks}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var sizes []int
+	for _, p := range ws.payloads {
+		sizes = append(sizes, len(p.Turns))
+	}
+	if len(sizes) != 3 || sizes[0] != 2 || sizes[1] != 2 || sizes[2] != 1 {
+		t.Errorf("batch sizes = %v, want [2 2 1]", sizes)
+	}
+}
+
+func TestWebhookSink_FlushInterval(t *testing.T) {
+	ws := newWebhookServer(t, 0, 0)
+	sink, err := NewWebhookSink(WebhookConfig{URL: ws.URL, FlushInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close(context.Background())
+
+	if err := sink.Consume(&sliceStream{chunks: []*genx.MessageChunk{text(genx.RoleUser, "a", "hi"), eos(genx.RoleUser, "a")}}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for ws.calls.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("batch was not posted after the flush interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWebhookSink_Retry(t *testing.T) {
+	ws := newWebhookServer(t, 2, http.StatusServiceUnavailable)
+	sink, err := NewWebhookSink(WebhookConfig{URL: ws.URL, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Consume(&sliceStream{chunks: []*genx.MessageChunk{text(genx.RoleUser, "a", "hi"), eos(genx.RoleUser, "a")}})
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := ws.calls.Load(); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+	if len(ws.payloads) != 1 {
+		t.Errorf("payloads = %d, want 1", len(ws.payloads))
+	}
+}
+
+func TestWebhookSink_GiveUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantCalls int32
+	}{
+		{"client error is not retried", http.StatusBadRequest, 1},
+		{"server error exhausts retries", http.StatusInternalServerError, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := newWebhookServer(t, 100, tt.status)
+			var failed []Turn
+			var failErr error
+			sink, err := NewWebhookSink(WebhookConfig{
+				URL:          ws.URL,
+				MaxRetries:   2,
+				RetryBackoff: time.Millisecond,
+				OnError: func(err error, turns []Turn) {
+					failErr = err
+					failed = append(failed, turns...)
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sink.Consume(&sliceStream{chunks: []*genx.MessageChunk{text(genx.RoleModel, "a", "hi"), eos(genx.RoleModel, "a")}})
+			sink.Close(context.Background())
+
+			if got := ws.calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+			if len(failed) != 1 || failed[0].Text != "hi" || failErr == nil {
+				t.Errorf("OnError got %v, %+v", failErr, failed)
+			}
+		})
+	}
+}
+
+func TestWebhookSink_CloseTimeout(t *testing.T) {
+	ws := newWebhookServer(t, 100, http.StatusServiceUnavailable)
+	sink, err := NewWebhookSink(WebhookConfig{URL: ws.URL, RetryBackoff: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Consume(&sliceStream{chunks: []*genx.MessageChunk{text(genx.RoleUser, "a", "hi"), eos(genx.RoleUser, "a")}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := sink.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close = %v, want deadline exceeded", err)
+	}
+}
+
+func TestNewWebhookSink_RequiresURL(t *testing.T) {
+	if _, err := NewWebhookSink(WebhookConfig{}); err == nil {
+		t.Error("expected error without URL")
+	}
+}
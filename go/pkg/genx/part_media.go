@@ -0,0 +1,124 @@
+package genx
+
+import (
+	"encoding/base64"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	_ Part = (*ImagePart)(nil)
+	_ Part = (*VideoFramePart)(nil)
+)
+
+// ImagePart is a still image. It carries either the encoded image (Data,
+// e.g. image/jpeg or image/png) or a reference URL the model fetches
+// itself; Data takes precedence when both are set. Width and Height are
+// in pixels, zero if unknown.
+type ImagePart struct {
+	MIMEType string
+	Data     []byte
+	URL      string
+	Width    int
+	Height   int
+}
+
+// NewImage returns an ImagePart holding the encoded image data.
+func NewImage(mimeType string, data []byte) *ImagePart {
+	return &ImagePart{MIMEType: mimeType, Data: data}
+}
+
+// NewImageURL returns an ImagePart referencing the image at url.
+func NewImageURL(url string) *ImagePart {
+	return &ImagePart{URL: url}
+}
+
+// DataURL returns the image as a URL: the reference URL if the image has
+// no data, otherwise a base64 "data:" URL.
+func (p *ImagePart) DataURL() string {
+	if len(p.Data) == 0 {
+		return p.URL
+	}
+	mimeType := p.MIMEType
+	if mimeType == "" {
+		mimeType = "image/jpeg"
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(p.Data)
+}
+
+func (p *ImagePart) clone() Part {
+	c := *p
+	c.Data = slices.Clone(p.Data)
+	return &c
+}
+
+func (*ImagePart) isPart() {}
+
+// VideoFramePart is one encoded frame of a video stream, e.g. a JPEG
+// sampled from a camera. Consecutive frames of a video share the StreamID
+// of their chunks; Timestamp is the offset of the frame from the start of
+// the video. Width and Height are in pixels, zero if unknown.
+type VideoFramePart struct {
+	MIMEType  string
+	Data      []byte
+	Width     int
+	Height    int
+	Timestamp time.Duration
+}
+
+// NewVideoFrame returns a VideoFramePart with the encoded frame at offset
+// ts of its video.
+func NewVideoFrame(mimeType string, data []byte, ts time.Duration) *VideoFramePart {
+	return &VideoFramePart{MIMEType: mimeType, Data: data, Timestamp: ts}
+}
+
+// Image returns the frame as an ImagePart.
+func (p *VideoFramePart) Image() *ImagePart {
+	return &ImagePart{MIMEType: p.MIMEType, Data: p.Data, Width: p.Width, Height: p.Height}
+}
+
+func (p *VideoFramePart) clone() Part {
+	c := *p
+	c.Data = slices.Clone(p.Data)
+	return &c
+}
+
+func (*VideoFramePart) isPart() {}
+
+// PartMIMEType returns the MIME type of a Part: "text/plain" for Text, the
+// MIMEType of the others, or "" for nil. An ImagePart referenced by URL
+// without a MIME type reports "image/*".
+func PartMIMEType(p Part) string {
+	switch v := p.(type) {
+	case Text:
+		return "text/plain"
+	case *Blob:
+		return v.MIMEType
+	case *ImagePart:
+		if v.MIMEType == "" {
+			return "image/*"
+		}
+		return v.MIMEType
+	case *VideoFramePart:
+		return v.MIMEType
+	}
+	return ""
+}
+
+// AsImage returns p as an ImagePart if it is one, a VideoFramePart, or a
+// Blob with an image/* MIME type, so that consumers can accept images
+// produced before the dedicated types existed.
+func AsImage(p Part) (*ImagePart, bool) {
+	switch v := p.(type) {
+	case *ImagePart:
+		return v, true
+	case *VideoFramePart:
+		return v.Image(), true
+	case *Blob:
+		if strings.HasPrefix(v.MIMEType, "image/") {
+			return NewImage(v.MIMEType, v.Data), true
+		}
+	}
+	return nil, false
+}
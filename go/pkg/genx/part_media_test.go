@@ -0,0 +1,94 @@
+package genx
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImagePart_clone(t *testing.T) {
+	original := &ImagePart{MIMEType: "image/jpeg", Data: []byte{1, 2, 3}, Width: 640, Height: 480}
+	cloned, ok := original.clone().(*ImagePart)
+	if !ok {
+		t.Fatalf("ImagePart.clone() type = %T, want *ImagePart", original.clone())
+	}
+	if cloned.Width != 640 || cloned.Height != 480 || cloned.MIMEType != "image/jpeg" {
+		t.Errorf("clone = %+v", cloned)
+	}
+	original.Data[0] = 99
+	if cloned.Data[0] == 99 {
+		t.Error("clone should be independent of original")
+	}
+}
+
+func TestVideoFramePart_clone(t *testing.T) {
+	original := NewVideoFrame("image/jpeg", []byte{1, 2}, 40*time.Millisecond)
+	cloned := original.clone().(*VideoFramePart)
+	if cloned.Timestamp != 40*time.Millisecond {
+		t.Errorf("Timestamp = %v", cloned.Timestamp)
+	}
+	original.Data[0] = 99
+	if cloned.Data[0] == 99 {
+		t.Error("clone should be independent of original")
+	}
+}
+
+func TestImagePart_DataURL(t *testing.T) {
+	if got := NewImageURL("https://example.com/a.png").DataURL(); got != "https://example.com/a.png" {
+		t.Errorf("DataURL of reference = %q", got)
+	}
+	if got := NewImage("image/png", []byte("png")).DataURL(); got != "data:image/png;base64,cG5n" {
+		t.Errorf("DataURL of data = %q", got)
+	}
+	if got := (&ImagePart{Data: []byte("x")}).DataURL(); !strings.HasPrefix(got, "data:image/jpeg;base64,") {
+		t.Errorf("DataURL without MIME type = %q", got)
+	}
+}
+
+func TestPartMIMEType(t *testing.T) {
+	tests := []struct {
+		part Part
+		want string
+	}{
+		{nil, ""},
+		{Text("hi"), "text/plain"},
+		{&Blob{MIMEType: "audio/pcm"}, "audio/pcm"},
+		{NewImage("image/png", nil), "image/png"},
+		{NewImageURL("https://example.com/a"), "image/*"},
+		{NewVideoFrame("image/jpeg", nil, 0), "image/jpeg"},
+	}
+	for _, tt := range tests {
+		if got := PartMIMEType(tt.part); got != tt.want {
+			t.Errorf("PartMIMEType(%T) = %q, want %q", tt.part, got, tt.want)
+		}
+	}
+}
+
+func TestAsImage(t *testing.T) {
+	if img, ok := AsImage(&Blob{MIMEType: "image/png", Data: []byte{1}}); !ok || img.MIMEType != "image/png" {
+		t.Errorf("AsImage(image Blob) = %+v, %v", img, ok)
+	}
+	if _, ok := AsImage(&Blob{MIMEType: "audio/pcm"}); ok {
+		t.Error("AsImage(audio Blob) = true")
+	}
+	if _, ok := AsImage(Text("x")); ok {
+		t.Error("AsImage(Text) = true")
+	}
+	frame := &VideoFramePart{MIMEType: "image/jpeg", Data: []byte{1}, Width: 320, Height: 240}
+	if img, ok := AsImage(frame); !ok || img.Width != 320 || img.Height != 240 {
+		t.Errorf("AsImage(frame) = %+v, %v", img, ok)
+	}
+}
+
+func TestMIMETypeMatcher_Media(t *testing.T) {
+	isImage := MIMETypeMatcher("image/")
+	if !isImage(&MessageChunk{Part: NewImage("image/jpeg", nil)}) {
+		t.Error("image part not matched")
+	}
+	if !isImage(&MessageChunk{Part: NewVideoFrame("image/jpeg", nil, 0)}) {
+		t.Error("video frame not matched")
+	}
+	if MIMETypeMatcher("text/")(&MessageChunk{Part: Text("x")}) {
+		t.Error("Text matched")
+	}
+}
@@ -3,6 +3,7 @@ package playground
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -12,6 +13,7 @@ import (
 	"github.com/haivivi/giztoy/go/pkg/genx/agentcfg"
 	"github.com/haivivi/giztoy/go/pkg/genx/generators"
 	"github.com/haivivi/giztoy/go/pkg/genx/match"
+	"github.com/haivivi/giztoy/go/pkg/memory"
 )
 
 // Logger is an interface for logging runtime events.
@@ -75,6 +77,32 @@ type Runtime struct {
 	// builtinTools stores pre-registered tools that take precedence over store lookup.
 	builtinTools map[string]*genx.FuncTool
 
+	// documents stores the knowledge-base collections of documents tools.
+	documents map[string]*agent.DocumentStore
+
+	// memory is the memory searched by retrieval tools.
+	memory *memory.Memory
+
+	// limiter enforces tool limits across all agents of this runtime.
+	limiter *agent.ToolLimiter
+
+	// promptAssembler assembles the per-turn system prompt of agents.
+	promptAssembler agent.PromptAssembler
+
+	// guardrails moderate the input and output of agents.
+	guardrails []agent.Guardrail
+
+	// traceSink records the decision traces of agents that enable them.
+	traceSink agent.TraceSink
+
+	// mcpClients are the connections of mcp tools, by server key.
+	mcpMu      sync.Mutex
+	mcpClients map[string]*agent.MCPClient
+
+	// triggers run the trigger bindings of the runtime.
+	triggerBindings []agent.TriggerBinding
+	triggers        *agent.TriggerRegistry
+
 	mu     sync.RWMutex
 	states map[string]agent.AgentState
 }
@@ -108,6 +136,24 @@ func WithBuiltinTools(tools ...*genx.FuncTool) RuntimeOption {
 	}
 }
 
+// WithDocuments registers the document collection searched by documents
+// tools with the given collection name.
+func WithDocuments(collection string, store *agent.DocumentStore) RuntimeOption {
+	return func(r *Runtime) {
+		if r.documents == nil {
+			r.documents = make(map[string]*agent.DocumentStore)
+		}
+		r.documents[collection] = store
+	}
+}
+
+// WithMemory sets the memory searched by retrieval tools.
+func WithMemory(m *memory.Memory) RuntimeOption {
+	return func(r *Runtime) {
+		r.memory = m
+	}
+}
+
 // WithLogger sets the logger for the runtime.
 func WithLogger(l Logger) RuntimeOption {
 	return func(r *Runtime) {
@@ -115,15 +161,56 @@ func WithLogger(l Logger) RuntimeOption {
 	}
 }
 
+// WithPromptAssembler sets the assembler of the per-turn system prompt of
+// ReAct agents (see agent.PromptAssembler).
+func WithPromptAssembler(a agent.PromptAssembler) RuntimeOption {
+	return func(r *Runtime) {
+		r.promptAssembler = a
+	}
+}
+
+// WithGuardrails adds guardrails that moderate the input and output of
+// ReAct agents (see agent.Guardrail). They run in the order added.
+func WithGuardrails(guardrails ...agent.Guardrail) RuntimeOption {
+	return func(r *Runtime) {
+		r.guardrails = append(r.guardrails, guardrails...)
+	}
+}
+
+// WithTraceSink sets the sink of the decision traces of agents whose
+// definition sets "trace" (see agent.TraceSink).
+func WithTraceSink(sink agent.TraceSink) RuntimeOption {
+	return func(r *Runtime) {
+		r.traceSink = sink
+	}
+}
+
+// WithTriggers registers trigger bindings that start or poke agents (see
+// agent.Trigger). They run once Triggers().Start is called.
+func WithTriggers(bindings ...agent.TriggerBinding) RuntimeOption {
+	return func(r *Runtime) {
+		r.triggerBindings = append(r.triggerBindings, bindings...)
+	}
+}
+
 // NewRuntime creates a new playground Runtime.
 func NewRuntime(opts ...RuntimeOption) *Runtime {
 	r := &Runtime{
-		states: make(map[string]agent.AgentState),
-		logger: noopLogger{},
+		states:  make(map[string]agent.AgentState),
+		logger:  noopLogger{},
+		limiter: agent.NewToolLimiter(),
 	}
 	for _, opt := range opts {
 		opt(r)
 	}
+	r.triggers = agent.NewTriggerRegistry(r, agent.WithTriggerErrorHandler(func(binding string, err error) {
+		r.log().Error("trigger failed", "binding", binding, "error", err)
+	}))
+	for _, b := range r.triggerBindings {
+		if err := r.triggers.Register(b); err != nil {
+			r.log().Error("register trigger", "binding", b.Name, "error", err)
+		}
+	}
 	return r
 }
 
@@ -221,9 +308,20 @@ func (r *Runtime) GetToolDef(ctx context.Context, name string) (agentcfg.Tool, e
 	return def, nil
 }
 
+// CreateToolFromDef creates a FuncTool from def. The tool is subject to
+// def's limits, shared with every other tool of the same name created by r.
 func (r *Runtime) CreateToolFromDef(ctx context.Context, def agentcfg.Tool) (*genx.FuncTool, error) {
 	r.log().Debug("CreateToolFromDef", "type", fmt.Sprintf("%T", def), "name", def.ToolName())
 
+	tool, err := r.createToolFromDef(ctx, def)
+	if err != nil {
+		return nil, err
+	}
+	return r.limiter.Wrap(tool, def.ToolLimits()), nil
+}
+
+func (r *Runtime) createToolFromDef(ctx context.Context, def agentcfg.Tool) (*genx.FuncTool, error) {
+
 	switch d := def.(type) {
 	case *agentcfg.BuiltInTool:
 		// For built-in tools, look up in builtinTools map
@@ -254,12 +352,86 @@ func (r *Runtime) CreateToolFromDef(ctx context.Context, def agentcfg.Tool) (*ge
 		compositeTool := agent.NewCompositeTool(r)
 		return compositeTool.CreateFuncTool(ctx, d)
 
+	case *agentcfg.DocumentsTool:
+		r.log().Debug("CreateToolFromDef: creating Documents tool", "name", d.Name, "collection", d.Collection)
+		docsTool := agent.NewDocumentsTool(r.documentStore)
+		return docsTool.CreateFuncTool(d)
+
+	case *agentcfg.RetrievalTool:
+		r.log().Debug("CreateToolFromDef: creating Retrieval tool", "name", d.Name)
+		retrievalTool := agent.NewRetrievalTool(r.recallMemory)
+		return retrievalTool.CreateFuncTool(d)
+
+	case *agentcfg.AgentTool:
+		r.log().Debug("CreateToolFromDef: creating Agent tool", "name", d.Name)
+		agentTool := agent.NewAgentTool(r)
+		return agentTool.CreateFuncTool(ctx, d)
+
+	case *agentcfg.MCPTool:
+		r.log().Debug("CreateToolFromDef: creating MCP tool", "name", d.Name)
+		mcpTool := agent.NewMCPTool(r.mcpClient)
+		return mcpTool.CreateFuncTool(ctx, d)
+
 	default:
 		r.log().Error("CreateToolFromDef: unsupported type", "type", fmt.Sprintf("%T", def))
 		return nil, fmt.Errorf("unsupported tool type: %T", def)
 	}
 }
 
+// documentStore resolves the collections of documents tools.
+func (r *Runtime) documentStore(_ context.Context, collection string) (*agent.DocumentStore, error) {
+	if store, ok := r.documents[collection]; ok {
+		return store, nil
+	}
+	return nil, fmt.Errorf("document collection %q not found", collection)
+}
+
+// recallMemory resolves the memory of retrieval tools.
+func (r *Runtime) recallMemory(_ context.Context) (*memory.Memory, error) {
+	if r.memory == nil {
+		return nil, fmt.Errorf("no memory configured")
+	}
+	return r.memory, nil
+}
+
+// mcpClient returns the connection to an MCP server of mcp tools,
+// connecting on first use.
+func (r *Runtime) mcpClient(ctx context.Context, server agentcfg.MCPServer) (*agent.MCPClient, error) {
+	key := server.Key()
+	r.mcpMu.Lock()
+	defer r.mcpMu.Unlock()
+	if client, ok := r.mcpClients[key]; ok {
+		return client, nil
+	}
+	client, err := agent.ConnectMCP(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	info := client.ServerInfo()
+	r.log().Info("connected to MCP server", "name", info.Name, "version", info.Version)
+	if r.mcpClients == nil {
+		r.mcpClients = make(map[string]*agent.MCPClient)
+	}
+	r.mcpClients[key] = client
+	return client, nil
+}
+
+// Close stops the triggers and closes the connections to the MCP servers
+// of mcp tools.
+func (r *Runtime) Close() error {
+	var errs []error
+	if r.triggers != nil {
+		errs = append(errs, r.triggers.Close())
+	}
+	r.mcpMu.Lock()
+	defer r.mcpMu.Unlock()
+	for key, client := range r.mcpClients {
+		errs = append(errs, client.Close())
+		delete(r.mcpClients, key)
+	}
+	return errors.Join(errs...)
+}
+
 // --- Agent Management ---
 
 func (r *Runtime) GetAgentDef(ctx context.Context, name string) (agentcfg.Agent, error) {
@@ -320,6 +492,26 @@ func (r *Runtime) GetContextBuilder(ctx context.Context, name string) (agent.Con
 
 // --- Rule Management ---
 
+// PromptAssembler implements agent.PromptAssemblerRuntime.
+func (r *Runtime) PromptAssembler() agent.PromptAssembler {
+	return r.promptAssembler
+}
+
+// Triggers implements agent.TriggerRuntime.
+func (r *Runtime) Triggers() *agent.TriggerRegistry {
+	return r.triggers
+}
+
+// Guardrails implements agent.GuardrailRuntime.
+func (r *Runtime) Guardrails() []agent.Guardrail {
+	return r.guardrails
+}
+
+// TraceSink implements agent.TraceRuntime.
+func (r *Runtime) TraceSink() agent.TraceSink {
+	return r.traceSink
+}
+
 func (r *Runtime) GetRule(ctx context.Context, name string) (*match.Rule, error) {
 	// Handle ref format (e.g., "rule:play_music" -> "play_music")
 	name = parseRef(name)
@@ -59,6 +59,10 @@ type Input struct {
 	// Keyed by entity label (e.g., "person:小明") → attribute map.
 	// Can be nil for first-time processing.
 	Profiles map[string]map[string]any `json:"profiles,omitempty"`
+
+	// Prompt optionally customizes the instructions and adds few-shot
+	// examples, e.g. to tune the profile style for one persona.
+	Prompt *segmentors.Prompt `json:"prompt,omitempty"`
 }
 
 // Result is the output of a [Profiler].
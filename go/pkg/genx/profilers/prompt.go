@@ -11,7 +11,7 @@ import (
 // buildPrompt constructs the system prompt for entity profile analysis.
 func buildPrompt(input Input) string {
 	var sb strings.Builder
-	sb.WriteString(promptBase)
+	sb.WriteString(input.Prompt.Base(promptBase))
 
 	// Include current schema if available.
 	if input.Schema != nil && len(input.Schema.EntityTypes) > 0 {
@@ -33,6 +33,10 @@ func buildPrompt(input Input) string {
 
 	sb.WriteString("\n\n")
 	sb.WriteString(promptOutputFormat)
+	if examples := input.Prompt.ExamplesSection(); examples != "" {
+		sb.WriteString("\n\n")
+		sb.WriteString(examples)
+	}
 	return sb.String()
 }
 
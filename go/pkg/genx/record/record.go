@@ -0,0 +1,276 @@
+// Package record captures genx streams to files and replays them.
+//
+// A Recorder is a pass-through Transformer that writes every MessageChunk
+// it sees, with its Ctrl and its time offset, to a file. Replay reads the
+// file back as a Stream, optionally at the original pace, so provider bugs
+// can be reproduced offline and captured sessions can serve as regression
+// inputs:
+//
+//	rec, err := record.Create("session.genxr")
+//	pipeline := genx.Chain(rec, asr, agent, tts) // records the input
+//	...
+//	rec.Close()
+//
+//	input, err := record.Replay("session.genxr", true)
+//	out, err := pipeline.Transform(ctx, "", input)
+//
+// Two file formats are supported: JSONL, one JSON record per line, easy to
+// read and edit; and Binary, length-prefixed msgpack records after a magic
+// header, compact for audio. Create picks JSONL for ".jsonl" files and
+// Binary otherwise; Replay detects the format.
+package record
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Format is a record file format.
+type Format int
+
+const (
+	// JSONL writes one JSON record per line. Blob data is base64-encoded.
+	JSONL Format = iota
+	// Binary writes a magic header followed by records, each a 4-byte
+	// big-endian length and a msgpack-encoded record.
+	Binary
+)
+
+// magic starts Binary record files; the last byte is the format version.
+var magic = []byte("GENXR\x01")
+
+// maxRecordSize bounds the size of a binary record when replaying.
+const maxRecordSize = 64 << 20
+
+// record is the encoded form of a MessageChunk, or of the error that ended
+// a recorded stream.
+type record struct {
+	// T is the offset of the record from the start of the recording, in
+	// microseconds.
+	T int64 `json:"t"`
+
+	Role       genx.Role         `json:"role,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	Text       *string           `json:"text,omitempty"`
+	Blob       *blobRecord       `json:"blob,omitempty"`
+	Image      *imageRecord      `json:"image,omitempty"`
+	VideoFrame *videoFrameRecord `json:"video_frame,omitempty"`
+	ToolCall   *toolCallRecord   `json:"tool_call,omitempty"`
+	Ctrl       *genx.StreamCtrl  `json:"ctrl,omitempty"`
+
+	// Error is the error the stream failed with.
+	Error string `json:"error,omitempty"`
+}
+
+type blobRecord struct {
+	MIMEType string `json:"mime_type"`
+	Data     []byte `json:"data,omitempty"`
+}
+
+type imageRecord struct {
+	MIMEType string `json:"mime_type,omitempty"`
+	Data     []byte `json:"data,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+}
+
+type videoFrameRecord struct {
+	MIMEType string `json:"mime_type"`
+	Data     []byte `json:"data,omitempty"`
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+	// TS is the frame timestamp in microseconds.
+	TS int64 `json:"ts,omitempty"`
+}
+
+type toolCallRecord struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+func newRecord(chunk *genx.MessageChunk, t time.Duration) *record {
+	r := &record{T: t.Microseconds(), Role: chunk.Role, Name: chunk.Name, Ctrl: chunk.Ctrl}
+	switch p := chunk.Part.(type) {
+	case genx.Text:
+		s := string(p)
+		r.Text = &s
+	case *genx.Blob:
+		r.Blob = &blobRecord{MIMEType: p.MIMEType, Data: p.Data}
+	case *genx.ImagePart:
+		r.Image = &imageRecord{MIMEType: p.MIMEType, Data: p.Data, URL: p.URL, Width: p.Width, Height: p.Height}
+	case *genx.VideoFramePart:
+		r.VideoFrame = &videoFrameRecord{MIMEType: p.MIMEType, Data: p.Data, Width: p.Width, Height: p.Height, TS: p.Timestamp.Microseconds()}
+	}
+	if tc := chunk.ToolCall; tc != nil {
+		r.ToolCall = &toolCallRecord{ID: tc.ID}
+		if tc.FuncCall != nil {
+			r.ToolCall.Name = tc.FuncCall.Name
+			r.ToolCall.Arguments = tc.FuncCall.Arguments
+		}
+	}
+	return r
+}
+
+func (r *record) chunk() *genx.MessageChunk {
+	c := &genx.MessageChunk{Role: r.Role, Name: r.Name, Ctrl: r.Ctrl}
+	switch {
+	case r.Text != nil:
+		c.Part = genx.Text(*r.Text)
+	case r.Blob != nil:
+		c.Part = &genx.Blob{MIMEType: r.Blob.MIMEType, Data: r.Blob.Data}
+	case r.Image != nil:
+		im := r.Image
+		c.Part = &genx.ImagePart{MIMEType: im.MIMEType, Data: im.Data, URL: im.URL, Width: im.Width, Height: im.Height}
+	case r.VideoFrame != nil:
+		vf := r.VideoFrame
+		c.Part = &genx.VideoFramePart{MIMEType: vf.MIMEType, Data: vf.Data, Width: vf.Width, Height: vf.Height, Timestamp: time.Duration(vf.TS) * time.Microsecond}
+	}
+	if tc := r.ToolCall; tc != nil {
+		c.ToolCall = &genx.ToolCall{ID: tc.ID}
+		if tc.Name != "" || tc.Arguments != "" {
+			c.ToolCall.FuncCall = &genx.FuncCall{Name: tc.Name, Arguments: tc.Arguments}
+		}
+	}
+	return c
+}
+
+// Recorder is a pass-through Transformer that records every chunk of its
+// input. The output stream returns the input chunks unchanged; a failed
+// input is recorded with its error.
+//
+// A Recorder may be used for several Transform calls, which are recorded
+// into the same file in the order their chunks are read. Recording errors
+// do not affect the streams; the first one is returned by Err and Close.
+type Recorder struct {
+	w      io.Writer
+	closer io.Closer
+	format Format
+	start  time.Time
+
+	mu      sync.Mutex
+	err     error
+	started bool
+}
+
+var _ genx.Transformer = (*Recorder)(nil)
+
+// NewRecorder creates a Recorder writing to w in the given format.
+func NewRecorder(w io.Writer, format Format) *Recorder {
+	return &Recorder{w: w, format: format, start: time.Now()}
+}
+
+// Create creates the file at path and returns a Recorder writing to it,
+// in JSONL if the file name ends with ".jsonl" and Binary otherwise.
+// Close the Recorder to close the file.
+func Create(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("record: %w", err)
+	}
+	format := Binary
+	if filepath.Ext(path) == ".jsonl" {
+		format = JSONL
+	}
+	r := NewRecorder(f, format)
+	r.closer = f
+	return r, nil
+}
+
+// Transform implements genx.Transformer. The pattern is ignored.
+func (r *Recorder) Transform(_ context.Context, _ string, input genx.Stream) (genx.Stream, error) {
+	return &recordStream{Stream: input, rec: r}, nil
+}
+
+// Err returns the first error writing the recording.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close closes the file of a Recorder made by Create. It returns the first
+// error writing the recording, if any.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closer != nil {
+		if err := r.closer.Close(); err != nil && r.err == nil {
+			r.err = fmt.Errorf("record: %w", err)
+		}
+		r.closer = nil
+	}
+	return r.err
+}
+
+func (r *Recorder) write(rec *record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	var buf bytes.Buffer
+	if !r.started && r.format == Binary {
+		buf.Write(magic)
+	}
+	if err := encodeRecord(&buf, r.format, rec); err != nil {
+		r.err = fmt.Errorf("record: encode: %w", err)
+		return
+	}
+	if _, err := r.w.Write(buf.Bytes()); err != nil {
+		r.err = fmt.Errorf("record: write: %w", err)
+		return
+	}
+	r.started = true
+}
+
+func encodeRecord(buf *bytes.Buffer, format Format, rec *record) error {
+	switch format {
+	case JSONL:
+		return json.NewEncoder(buf).Encode(rec)
+	case Binary:
+		var body bytes.Buffer
+		enc := msgpack.NewEncoder(&body)
+		enc.SetCustomStructTag("json")
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(body.Len())))
+		buf.Write(body.Bytes())
+		return nil
+	}
+	return fmt.Errorf("unknown format %d", format)
+}
+
+type recordStream struct {
+	genx.Stream
+	rec *Recorder
+}
+
+func (s *recordStream) Next() (*genx.MessageChunk, error) {
+	chunk, err := s.Stream.Next()
+	t := time.Since(s.rec.start)
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			s.rec.write(&record{T: t.Microseconds(), Error: err.Error()})
+		}
+		return nil, err
+	}
+	if chunk != nil {
+		s.rec.write(newRecord(chunk, t))
+	}
+	return chunk, nil
+}
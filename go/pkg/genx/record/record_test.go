@@ -0,0 +1,184 @@
+package record
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+type sliceStream struct {
+	chunks []*genx.MessageChunk
+	err    error
+}
+
+func (s *sliceStream) Next() (*genx.MessageChunk, error) {
+	if len(s.chunks) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	c := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return c, nil
+}
+
+func (s *sliceStream) Close() error                   { return nil }
+func (s *sliceStream) CloseWithError(err error) error { return nil }
+
+func sampleChunks() []*genx.MessageChunk {
+	bos := genx.NewBeginOfStream("s1")
+	bos.Role = genx.RoleUser
+	eos := genx.NewEndOfStream("audio/pcm")
+	eos.Ctrl.StreamID = "s1"
+	return []*genx.MessageChunk{
+		bos,
+		{Role: genx.RoleUser, Part: &genx.Blob{MIMEType: "audio/pcm", Data: []byte{0, 1, 2, 255}}, Ctrl: &genx.StreamCtrl{StreamID: "s1", OriginTimestamp: 42}},
+		eos,
+		{Role: genx.RoleModel, Name: "bot", Part: genx.Text("hello\nworld")},
+		{Role: genx.RoleUser, Part: &genx.ImagePart{MIMEType: "image/jpeg", Data: []byte{0xff, 0xd8}, Width: 640, Height: 480}},
+		{Role: genx.RoleUser, Part: genx.NewImageURL("https://example.com/cat.png")},
+		{Role: genx.RoleUser, Part: genx.NewVideoFrame("image/jpeg", []byte{0xff, 0xd8}, 1500*time.Millisecond)},
+		{Role: genx.RoleModel, ToolCall: &genx.ToolCall{ID: "c1", FuncCall: &genx.FuncCall{Name: "lookup", Arguments: `{"q":"x"}`}}},
+	}
+}
+
+func drain(t *testing.T, s genx.Stream) ([]*genx.MessageChunk, error) {
+	t.Helper()
+	var chunks []*genx.MessageChunk
+	for {
+		c, err := s.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return chunks, nil
+			}
+			return chunks, err
+		}
+		chunks = append(chunks, c)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, name := range []string{"session.jsonl", "session.genxr"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			rec, err := Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := sampleChunks()
+			out, err := rec.Transform(context.Background(), "", &sliceStream{chunks: sampleChunks()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			passed, err := drain(t, out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(passed, want) {
+				t.Fatalf("pass-through changed chunks")
+			}
+			if err := rec.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			replay, err := Replay(path, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer replay.Close()
+			got, err := drain(t, replay)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("replayed %d chunks, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if !reflect.DeepEqual(got[i], want[i]) {
+					t.Errorf("chunk %d = %+v, want %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReplay_Error(t *testing.T) {
+	var buf bytes.Buffer
+	rec := NewRecorder(&buf, JSONL)
+	input := &sliceStream{chunks: sampleChunks()[:2], err: errors.New("provider failed")}
+	out, _ := rec.Transform(context.Background(), "", input)
+	if _, err := drain(t, out); err == nil {
+		t.Fatal("want input error")
+	}
+
+	replay, err := NewReplayer(&buf, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := drain(t, replay)
+	if len(got) != 2 {
+		t.Errorf("replayed %d chunks, want 2", len(got))
+	}
+	if err == nil || err.Error() != "provider failed" {
+		t.Errorf("err = %v, want the recorded error", err)
+	}
+}
+
+func TestReplay_Realtime(t *testing.T) {
+	records := `{"t":0,"text":"a"}` + "\n" + `{"t":50000,"text":"b"}` + "\n"
+	replay, err := NewReplayer(strings.NewReader(records), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	got, err := drain(t, replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("replayed %d chunks, want 2", len(got))
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("realtime replay took %v, want at least 50ms", d)
+	}
+}
+
+func TestReplay_CloseInterruptsWait(t *testing.T) {
+	records := `{"t":0,"text":"a"}` + "\n" + `{"t":60000000,"text":"b"}` + "\n"
+	replay, err := NewReplayer(strings.NewReader(records), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replay.Next(); err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(10*time.Millisecond, func() { replay.Close() })
+	if _, err := replay.Next(); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("err = %v, want io.ErrClosedPipe", err)
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestRecorder_WriteErrorKeepsStream(t *testing.T) {
+	rec := NewRecorder(failWriter{}, Binary)
+	out, _ := rec.Transform(context.Background(), "", &sliceStream{chunks: sampleChunks()})
+	got, err := drain(t, out)
+	if err != nil || len(got) != len(sampleChunks()) {
+		t.Fatalf("stream = %d chunks, %v", len(got), err)
+	}
+	if err := rec.Err(); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Err = %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Replay opens a record file and returns a Stream of its chunks. The format
+// is detected from the file content. A recorded stream error is returned by
+// Next after the chunks that preceded it.
+//
+// If realtime is true, each chunk is returned at its recorded offset from
+// the first Next call; otherwise chunks are returned as fast as they are
+// read. Close the Stream to close the file.
+func Replay(file string, realtime bool) (genx.Stream, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("record: %w", err)
+	}
+	s, err := newReplayStream(f, realtime)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.closer = f
+	return s, nil
+}
+
+// NewReplayer returns a Stream of the chunks recorded in r, like Replay.
+// Closing the Stream does not close r.
+func NewReplayer(r io.Reader, realtime bool) (genx.Stream, error) {
+	return newReplayStream(r, realtime)
+}
+
+type replayStream struct {
+	br       *bufio.Reader
+	format   Format
+	realtime bool
+	closer   io.Closer
+
+	mu    sync.Mutex
+	start time.Time
+	err   error
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newReplayStream(r io.Reader, realtime bool) (*replayStream, error) {
+	br := bufio.NewReader(r)
+	format := JSONL
+	head, err := br.Peek(len(magic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("record: read header: %w", err)
+	}
+	if bytes.Equal(head, magic) {
+		format = Binary
+		br.Discard(len(magic))
+	} else if len(head) >= len(magic)-1 && bytes.Equal(head[:len(magic)-1], magic[:len(magic)-1]) {
+		return nil, fmt.Errorf("record: unsupported binary version %d", head[len(magic)-1])
+	}
+	return &replayStream{
+		br:       br,
+		format:   format,
+		realtime: realtime,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+func (s *replayStream) Next() (*genx.MessageChunk, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.start.IsZero() {
+		s.start = time.Now()
+	}
+	rec, err := s.read()
+	if err != nil {
+		s.err = err
+		return nil, err
+	}
+	if s.realtime {
+		if d := time.Until(s.start.Add(time.Duration(rec.T) * time.Microsecond)); d > 0 {
+			timer := time.NewTimer(d)
+			select {
+			case <-timer.C:
+			case <-s.done:
+				timer.Stop()
+				return nil, io.ErrClosedPipe
+			}
+		}
+	}
+	if rec.Error != "" {
+		s.err = errors.New(rec.Error)
+		return nil, s.err
+	}
+	return rec.chunk(), nil
+}
+
+func (s *replayStream) read() (*record, error) {
+	select {
+	case <-s.done:
+		return nil, io.ErrClosedPipe
+	default:
+	}
+	var rec record
+	switch s.format {
+	case Binary:
+		var size [4]byte
+		if _, err := io.ReadFull(s.br, size[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("record: read: %w", err)
+		}
+		n := binary.BigEndian.Uint32(size[:])
+		if n > maxRecordSize {
+			return nil, fmt.Errorf("record: record of %d bytes exceeds limit", n)
+		}
+		body := make([]byte, n)
+		if _, err := io.ReadFull(s.br, body); err != nil {
+			return nil, fmt.Errorf("record: read: %w", err)
+		}
+		dec := msgpack.NewDecoder(bytes.NewReader(body))
+		dec.SetCustomStructTag("json")
+		if err := dec.Decode(&rec); err != nil {
+			return nil, fmt.Errorf("record: decode: %w", err)
+		}
+	default:
+		for {
+			line, err := s.br.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) == 0 {
+				if err != nil {
+					if errors.Is(err, io.EOF) {
+						return nil, io.EOF
+					}
+					return nil, fmt.Errorf("record: read: %w", err)
+				}
+				continue
+			}
+			if err := json.Unmarshal(line, &rec); err != nil {
+				return nil, fmt.Errorf("record: decode: %w", err)
+			}
+			break
+		}
+	}
+	return &rec, nil
+}
+
+func (s *replayStream) Close() error {
+	return s.CloseWithError(nil)
+}
+
+func (s *replayStream) CloseWithError(error) error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		if s.closer != nil {
+			err = s.closer.Close()
+		}
+	})
+	return err
+}
@@ -10,16 +10,62 @@ import (
 // segment with entity and relation extraction.
 func buildPrompt(input Input) string {
 	var sb strings.Builder
-	sb.WriteString(promptBase)
+	sb.WriteString(input.Prompt.Base(promptBase))
 	if input.Schema != nil && len(input.Schema.EntityTypes) > 0 {
 		sb.WriteString("\n\n")
 		sb.WriteString(buildSchemaHint(input.Schema))
 	}
 	sb.WriteString("\n\n")
 	sb.WriteString(promptOutputFormat)
+	if examples := input.Prompt.ExamplesSection(); examples != "" {
+		sb.WriteString("\n\n")
+		sb.WriteString(examples)
+	}
 	return sb.String()
 }
 
+// Base returns the instructions of the prompt: p.Instructions, or def if
+// empty, followed by p.Rules. It is safe to call on a nil Prompt.
+func (p *Prompt) Base(def string) string {
+	if p == nil {
+		return def
+	}
+	base := def
+	if p.Instructions != "" {
+		base = p.Instructions
+	}
+	if len(p.Rules) == 0 {
+		return base
+	}
+	var sb strings.Builder
+	sb.WriteString(base)
+	sb.WriteString("\n\n## Additional Rules\n\n")
+	for _, r := range p.Rules {
+		fmt.Fprintf(&sb, "- %s\n", r)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// ExamplesSection returns the prompt section of p.Examples, or "" if there
+// are none. It is safe to call on a nil Prompt.
+func (p *Prompt) ExamplesSection() string {
+	if p == nil || len(p.Examples) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("## Examples\n\n")
+	sb.WriteString("Follow the style of these examples.\n")
+	for i, ex := range p.Examples {
+		fmt.Fprintf(&sb, "\n### Example %d\n\n", i+1)
+		sb.WriteString("Conversation:\n")
+		sb.WriteString(strings.TrimSpace(ex.Conversation))
+		sb.WriteString("\n\nOutput:\n")
+		sb.WriteString(strings.TrimSpace(ex.Output))
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
 // buildConversationText joins input messages into a single conversation block.
 func buildConversationText(messages []string) string {
 	return strings.Join(messages, "\n")
@@ -41,6 +41,10 @@ type Input struct {
 	// When provided, the LLM is guided to extract entities matching this schema,
 	// but it can still discover entities beyond the schema.
 	Schema *Schema `json:"schema,omitempty"`
+
+	// Prompt optionally customizes the instructions and adds few-shot
+	// examples, e.g. to tune the extraction style for one persona.
+	Prompt *Prompt `json:"prompt,omitempty"`
 }
 
 // Result is the output of a [Segmentor].
@@ -118,6 +122,38 @@ type AttrDef struct {
 	Desc string `json:"desc" yaml:"desc"`
 }
 
+// ---------------------------------------------------------------------------
+// Prompt: optional customization of the extraction style
+// ---------------------------------------------------------------------------
+
+// Prompt customizes the system prompt of a segmentor or profiler, so that
+// the extraction style (granularity, entity taxonomy) can be tuned per
+// product without a new implementation. The output format section is
+// always kept, so the result still parses.
+type Prompt struct {
+	// Instructions replaces the default task instructions and rules.
+	// Optional. If empty, the default instructions are used.
+	Instructions string `json:"instructions,omitempty" yaml:"instructions,omitempty"`
+
+	// Rules are added to the instructions (e.g., "Only extract people
+	// and pets, not topics.").
+	Rules []string `json:"rules,omitempty" yaml:"rules,omitempty"`
+
+	// Examples are few-shot examples of the expected extraction.
+	Examples []Example `json:"examples,omitempty" yaml:"examples,omitempty"`
+}
+
+// Example is a few-shot example: a conversation and the expected output.
+type Example struct {
+	// Conversation is the example conversation, one message per line
+	// (e.g., "user: 我叫小明").
+	Conversation string `json:"conversation" yaml:"conversation"`
+
+	// Output is the expected function call argument, as JSON in the
+	// format of the output section.
+	Output string `json:"output" yaml:"output"`
+}
+
 // ---------------------------------------------------------------------------
 // Config: for modelloader registration
 // ---------------------------------------------------------------------------
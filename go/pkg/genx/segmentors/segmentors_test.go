@@ -424,6 +424,37 @@ func TestBuildPrompt_WithSchema(t *testing.T) {
 	}
 }
 
+func TestBuildPrompt_WithPrompt(t *testing.T) {
+	prompt := buildPrompt(Input{
+		Messages: []string{"hello"},
+		Prompt: &Prompt{
+			Instructions: "You summarize a child's day for their parents.",
+			Rules:        []string{"Only extract people and pets."},
+			Examples: []Example{{
+				Conversation: "user: 我的狗叫旺财",
+				Output:       `{"segment": {"summary": "用户的狗叫旺财"}}`,
+			}},
+		},
+	})
+	if strings.Contains(prompt, "conversation segmentor") {
+		t.Error("instructions should replace base instructions")
+	}
+	for _, want := range []string{
+		"You summarize a child's day",
+		"## Additional Rules\n\n- Only extract people and pets.",
+		"Output",
+		"### Example 1\n\nConversation:\nuser: 我的狗叫旺财\n\nOutput:\n{\"segment\"",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+	// Examples follow the output format
+	if strings.Index(prompt, "## Examples") < strings.Index(prompt, "## Output") {
+		t.Error("examples should follow the output format")
+	}
+}
+
 func TestBuildConversationText(t *testing.T) {
 	text := buildConversationText([]string{"line1", "line2", "line3"})
 	if text != "line1\nline2\nline3" {
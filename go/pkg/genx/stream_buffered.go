@@ -0,0 +1,270 @@
+package genx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/haivivi/giztoy/go/pkg/buffer"
+)
+
+// BufferedStreamConfig configures a BufferedStream.
+type BufferedStreamConfig struct {
+	// Size is the number of chunks the stream buffers.
+	// Default: 100
+	Size int
+
+	// Policy applies when a Write finds the buffer full.
+	// Default: SlowConsumerBlock
+	Policy SlowConsumerPolicy
+
+	// HighWatermark is the depth at which OnHighWatermark is called.
+	// Default: 3/4 of Size
+	HighWatermark int
+
+	// LowWatermark is the depth at which OnLowWatermark is called after
+	// the high watermark was reached.
+	// Default: 1/2 of HighWatermark
+	LowWatermark int
+
+	// OnHighWatermark, if set, is called when the depth rises to
+	// HighWatermark: the reader is falling behind. It is called again only
+	// after the depth has fallen to LowWatermark.
+	OnHighWatermark func(BufferedStreamStats)
+
+	// OnLowWatermark, if set, is called when the depth falls back to
+	// LowWatermark after the high watermark was reached.
+	OnLowWatermark func(BufferedStreamStats)
+}
+
+func (c *BufferedStreamConfig) setDefaults() {
+	if c.Size <= 0 {
+		c.Size = 100
+	}
+	if c.HighWatermark <= 0 || c.HighWatermark > c.Size {
+		c.HighWatermark = max(1, c.Size*3/4)
+	}
+	if c.LowWatermark <= 0 || c.LowWatermark >= c.HighWatermark {
+		c.LowWatermark = c.HighWatermark / 2
+	}
+}
+
+// BufferedStreamStats is a snapshot of the counters of a BufferedStream.
+type BufferedStreamStats struct {
+	// Depth is the number of buffered chunks.
+	Depth int
+	// MaxDepth is the highest depth seen.
+	MaxDepth int
+	// Written is the number of chunks accepted by Write.
+	Written uint64
+	// Read is the number of chunks returned by Next.
+	Read uint64
+	// Dropped is the number of chunks dropped by the SlowConsumerDrop
+	// policy.
+	Dropped uint64
+	// Blocked is the number of Writes that waited for room.
+	Blocked uint64
+	// HighWatermarks is the number of times the high watermark was reached.
+	HighWatermarks uint64
+}
+
+// BufferedStream is a bounded Stream fed by Write. It reports how far the
+// reader is behind through its depth, watermark callbacks and drop
+// counters, so a realtime pipeline can tell when it is falling behind.
+//
+// The producer calls Write for each chunk and Close when done; the reader
+// receives the buffered chunks and then io.EOF. CloseWithError aborts both
+// sides: pending and later Writes fail, and Next returns the error.
+//
+// BufferedStream is safe for one reader and any number of writers.
+type BufferedStream struct {
+	config BufferedStreamConfig
+	buf    *buffer.Buffer[*MessageChunk]
+
+	mu     sync.Mutex
+	room   chan struct{} // closed and replaced when a chunk is read
+	closed bool
+	err    error
+	high   bool // the high watermark was reached and not yet cleared
+	stats  BufferedStreamStats
+}
+
+var _ Stream = (*BufferedStream)(nil)
+
+// NewBufferedStream creates a BufferedStream.
+func NewBufferedStream(config BufferedStreamConfig) *BufferedStream {
+	config.setDefaults()
+	return &BufferedStream{
+		config: config,
+		buf:    buffer.N[*MessageChunk](config.Size),
+		room:   make(chan struct{}),
+	}
+}
+
+// Write adds chunk to the stream. When the buffer is full, the policy
+// applies: SlowConsumerBlock waits for room until ctx is done,
+// SlowConsumerDrop releases the chunk and returns nil, and SlowConsumerClose
+// closes the stream with ErrSlowConsumer. End-of-stream chunks are never
+// dropped; they wait for room.
+//
+// Write fails with io.ErrClosedPipe after Close, and with the close error
+// after CloseWithError.
+func (s *BufferedStream) Write(ctx context.Context, chunk *MessageChunk) error {
+	waited := false
+	for {
+		s.mu.Lock()
+		if err := s.writeErrLocked(); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		if s.buf.Len() < s.config.Size {
+			break
+		}
+		switch s.config.Policy {
+		case SlowConsumerDrop:
+			if !chunk.IsEndOfStream() {
+				s.stats.Dropped++
+				s.mu.Unlock()
+				chunk.Release()
+				return nil
+			}
+		case SlowConsumerClose:
+			s.closeLocked(ErrSlowConsumer)
+			s.mu.Unlock()
+			return ErrSlowConsumer
+		}
+		if !waited {
+			waited = true
+			s.stats.Blocked++
+		}
+		room := s.room
+		s.mu.Unlock()
+
+		select {
+		case <-room:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err := s.buf.Add(chunk); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.stats.Written++
+	depth := s.buf.Len()
+	s.stats.MaxDepth = max(s.stats.MaxDepth, depth)
+	var notify func(BufferedStreamStats)
+	if !s.high && depth >= s.config.HighWatermark {
+		s.high = true
+		s.stats.HighWatermarks++
+		notify = s.config.OnHighWatermark
+	}
+	stats := s.statsLocked()
+	s.mu.Unlock()
+
+	if notify != nil {
+		notify(stats)
+	}
+	return nil
+}
+
+func (s *BufferedStream) writeErrLocked() error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.closed {
+		return io.ErrClosedPipe
+	}
+	return nil
+}
+
+// Next returns the next chunk, waiting for one to be written. It returns
+// io.EOF after Close once the buffer is drained.
+func (s *BufferedStream) Next() (*MessageChunk, error) {
+	chunk, err := s.buf.Next()
+	if err != nil {
+		if errors.Is(err, buffer.ErrIteratorDone) {
+			return nil, io.EOF
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.stats.Read++
+	close(s.room)
+	s.room = make(chan struct{})
+	var notify func(BufferedStreamStats)
+	if s.high && s.buf.Len() <= s.config.LowWatermark {
+		s.high = false
+		notify = s.config.OnLowWatermark
+	}
+	stats := s.statsLocked()
+	s.mu.Unlock()
+
+	if notify != nil {
+		notify(stats)
+	}
+	return chunk, nil
+}
+
+// Close ends the stream for writing. The reader receives the buffered
+// chunks and then io.EOF.
+func (s *BufferedStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.room) // wake blocked writers
+	s.room = make(chan struct{})
+	return s.buf.CloseWrite()
+}
+
+// CloseWithError aborts the stream: buffered chunks are discarded, Next
+// returns err, and Writes fail with err. A nil err is io.ErrClosedPipe.
+func (s *BufferedStream) CloseWithError(err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		err = io.ErrClosedPipe
+	}
+	s.closeLocked(err)
+	return nil
+}
+
+func (s *BufferedStream) closeLocked(err error) {
+	if s.err != nil {
+		return
+	}
+	s.err = err
+	s.closed = true
+	close(s.room)
+	s.room = make(chan struct{})
+	s.buf.CloseWithError(err)
+}
+
+// Depth returns the number of buffered chunks.
+func (s *BufferedStream) Depth() int {
+	return s.buf.Len()
+}
+
+// Stats returns a snapshot of the stream's counters.
+func (s *BufferedStream) Stats() BufferedStreamStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.statsLocked()
+}
+
+func (s *BufferedStream) statsLocked() BufferedStreamStats {
+	stats := s.stats
+	stats.Depth = s.buf.Len()
+	return stats
+}
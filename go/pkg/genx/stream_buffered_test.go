@@ -0,0 +1,194 @@
+package genx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestBufferedStream(t *testing.T) {
+	s := NewBufferedStream(BufferedStreamConfig{Size: 4})
+	ctx := context.Background()
+	for _, c := range textChunks("a", "b", "c") {
+		if err := s.Write(ctx, c); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if d := s.Depth(); d != 3 {
+		t.Errorf("Depth = %d, want 3", d)
+	}
+	s.Close()
+
+	if err := s.Write(ctx, &MessageChunk{Part: Text("d")}); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Write after Close = %v, want io.ErrClosedPipe", err)
+	}
+	texts, err := readTexts(s)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(texts) != 3 || texts[0] != "a" || texts[2] != "c" {
+		t.Errorf("texts = %v", texts)
+	}
+
+	stats := s.Stats()
+	if stats.Written != 3 || stats.Read != 3 || stats.Depth != 0 || stats.MaxDepth != 3 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestBufferedStream_BlockHonoursContext(t *testing.T) {
+	s := NewBufferedStream(BufferedStreamConfig{Size: 1})
+	if err := s.Write(context.Background(), &MessageChunk{Part: Text("a")}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Write(ctx, &MessageChunk{Part: Text("b")}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Write on full buffer = %v, want deadline exceeded", err)
+	}
+
+	// A blocked Write resumes once the reader makes room.
+	done := make(chan error, 1)
+	go func() { done <- s.Write(context.Background(), &MessageChunk{Part: Text("c")}) }()
+	time.Sleep(10 * time.Millisecond)
+	if c, err := s.Next(); err != nil || string(c.Part.(Text)) != "a" {
+		t.Fatalf("Next = %v, %v", c, err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Write still blocked after Next")
+	}
+	if c, err := s.Next(); err != nil || string(c.Part.(Text)) != "c" {
+		t.Fatalf("Next = %v, %v", c, err)
+	}
+	if b := s.Stats().Blocked; b != 2 {
+		t.Errorf("Blocked = %d, want 2", b)
+	}
+}
+
+func TestBufferedStream_Drop(t *testing.T) {
+	s := NewBufferedStream(BufferedStreamConfig{Size: 2, Policy: SlowConsumerDrop})
+	ctx := context.Background()
+	for _, c := range textChunks("a", "b", "c", "d") {
+		if err := s.Write(ctx, c); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if d := s.Stats().Dropped; d != 2 {
+		t.Errorf("Dropped = %d, want 2", d)
+	}
+
+	// End-of-stream chunks wait instead of being dropped.
+	eos := &MessageChunk{Ctrl: &StreamCtrl{EndOfStream: true}}
+	done := make(chan error, 1)
+	go func() { done <- s.Write(ctx, eos) }()
+	if _, err := s.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Write EoS: %v", err)
+	}
+	s.Close()
+	var got []*MessageChunk
+	for {
+		c, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, c)
+	}
+	if len(got) != 2 || got[1] != eos {
+		t.Errorf("remaining = %v, want b and the EoS chunk", got)
+	}
+}
+
+func TestBufferedStream_CloseOnFull(t *testing.T) {
+	s := NewBufferedStream(BufferedStreamConfig{Size: 1, Policy: SlowConsumerClose})
+	ctx := context.Background()
+	if err := s.Write(ctx, &MessageChunk{Part: Text("a")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(ctx, &MessageChunk{Part: Text("b")}); !errors.Is(err, ErrSlowConsumer) {
+		t.Fatalf("Write = %v, want ErrSlowConsumer", err)
+	}
+	if _, err := s.Next(); !errors.Is(err, ErrSlowConsumer) {
+		t.Fatalf("Next = %v, want ErrSlowConsumer", err)
+	}
+}
+
+func TestBufferedStream_Watermarks(t *testing.T) {
+	var highs, lows []int
+	s := NewBufferedStream(BufferedStreamConfig{
+		Size:            8,
+		HighWatermark:   4,
+		LowWatermark:    1,
+		OnHighWatermark: func(st BufferedStreamStats) { highs = append(highs, st.Depth) },
+		OnLowWatermark:  func(st BufferedStreamStats) { lows = append(lows, st.Depth) },
+	})
+	ctx := context.Background()
+	write := func(n int) {
+		for range n {
+			if err := s.Write(ctx, &MessageChunk{Part: Text("x")}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	read := func(n int) {
+		for range n {
+			if _, err := s.Next(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	write(5) // crosses 4 once
+	read(2)  // depth 3: still above low
+	write(1) // depth 4: no second high before low
+	read(3)  // depth 1: low
+	write(3) // depth 4: high again
+
+	if len(highs) != 2 || highs[0] != 4 || highs[1] != 4 {
+		t.Errorf("high callbacks = %v, want [4 4]", highs)
+	}
+	if len(lows) != 1 || lows[0] != 1 {
+		t.Errorf("low callbacks = %v, want [1]", lows)
+	}
+	if n := s.Stats().HighWatermarks; n != 2 {
+		t.Errorf("HighWatermarks = %d, want 2", n)
+	}
+}
+
+func TestBufferedStream_CloseWithErrorUnblocksWriter(t *testing.T) {
+	s := NewBufferedStream(BufferedStreamConfig{Size: 1})
+	ctx := context.Background()
+	if err := s.Write(ctx, &MessageChunk{Part: Text("a")}); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.Write(ctx, &MessageChunk{Part: Text("b")}) }()
+	time.Sleep(10 * time.Millisecond)
+
+	boom := errors.New("boom")
+	s.CloseWithError(boom)
+	select {
+	case err := <-done:
+		if !errors.Is(err, boom) {
+			t.Errorf("blocked Write = %v, want boom", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Write still blocked after CloseWithError")
+	}
+	if _, err := s.Next(); !errors.Is(err, boom) {
+		t.Errorf("Next = %v, want boom", err)
+	}
+}
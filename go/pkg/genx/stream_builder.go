@@ -3,6 +3,7 @@ package genx
 import (
 	"fmt"
 	"log/slog"
+	"sync"
 
 	"github.com/haivivi/giztoy/go/pkg/buffer"
 )
@@ -28,6 +29,9 @@ type StreamEvent struct {
 type StreamBuilder struct {
 	rb        *buffer.BlockBuffer[*StreamEvent]
 	funcTools map[string]*FuncTool
+
+	mu    sync.Mutex
+	usage Usage
 }
 
 func NewStreamBuilder(mctx ModelContext, size int) *StreamBuilder {
@@ -44,7 +48,17 @@ func NewStreamBuilder(mctx ModelContext, size int) *StreamBuilder {
 	return sb
 }
 
+// SetUsage records the usage reported by the provider so far, for the
+// Usage of the Stream. Done, Truncated, Blocked and Unexpected record the
+// final usage.
+func (sb *StreamBuilder) SetUsage(stats Usage) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	sb.usage = stats
+}
+
 func (sb *StreamBuilder) Done(stats Usage) error {
+	sb.SetUsage(stats)
 	if err := sb.rb.Add(&StreamEvent{
 		Status: StatusDone,
 		Usage:  stats,
@@ -55,6 +69,7 @@ func (sb *StreamBuilder) Done(stats Usage) error {
 }
 
 func (sb *StreamBuilder) Truncated(stats Usage) error {
+	sb.SetUsage(stats)
 	if err := sb.rb.Add(&StreamEvent{
 		Status: StatusTruncated,
 		Usage:  stats,
@@ -65,6 +80,7 @@ func (sb *StreamBuilder) Truncated(stats Usage) error {
 }
 
 func (sb *StreamBuilder) Blocked(stats Usage, refusal string) error {
+	sb.SetUsage(stats)
 	if err := sb.rb.Add(&StreamEvent{
 		Status:  StatusBlocked,
 		Usage:   stats,
@@ -76,6 +92,7 @@ func (sb *StreamBuilder) Blocked(stats Usage, refusal string) error {
 }
 
 func (sb *StreamBuilder) Unexpected(stats Usage, err error) error {
+	sb.SetUsage(stats)
 	if err := sb.rb.Add(&StreamEvent{
 		Status: StatusError,
 		Usage:  stats,
@@ -113,6 +130,8 @@ func (sb *StreamBuilder) Stream() Stream {
 
 type streamImpl StreamBuilder
 
+var _ UsageStream = (*streamImpl)(nil)
+
 func (s *streamImpl) Next() (*MessageChunk, error) {
 	evt, err := s.rb.Next()
 	if err != nil {
@@ -136,6 +155,13 @@ func (s *streamImpl) Next() (*MessageChunk, error) {
 	return nil, err
 }
 
+// Usage implements UsageStream.
+func (s *streamImpl) Usage() Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage
+}
+
 func (s *streamImpl) Close() error {
 	return s.rb.Close()
 }
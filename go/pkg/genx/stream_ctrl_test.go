@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -81,6 +82,7 @@ func TestStreamCtrl_JSON_Roundtrip(t *testing.T) {
 		BeginOfStream: true,
 		EndOfStream:   false,
 		Timestamp:     1700000000000,
+		Metadata:      map[string]string{"emotion": "happy"},
 	}
 	data, err := json.Marshal(original)
 	if err != nil {
@@ -90,7 +92,7 @@ func TestStreamCtrl_JSON_Roundtrip(t *testing.T) {
 	if err := json.Unmarshal(data, &parsed); err != nil {
 		t.Fatalf("unmarshal: %v", err)
 	}
-	if parsed != original {
+	if !reflect.DeepEqual(parsed, original) {
 		t.Errorf("roundtrip failed: got %+v, want %+v", parsed, original)
 	}
 }
@@ -0,0 +1,270 @@
+package genx
+
+import (
+	"io"
+	"strings"
+
+	"github.com/haivivi/giztoy/go/pkg/buffer"
+)
+
+// Filter returns a Stream of the chunks of s that match pred.
+//
+// Unlike a plain filter, Filter keeps the sub-stream boundaries of the
+// chunks it drops:
+//   - The BeginOfStream of a dropped chunk moves onto the next kept chunk of
+//     the same StreamID.
+//   - The EndOfStream of a dropped chunk is emitted as an EoS marker of the
+//     type of the last kept chunk of that StreamID (an empty Blob of the same
+//     MIME type, or empty Text).
+//   - Sub-streams of which no chunk was kept produce nothing, not even their
+//     markers.
+//
+// Dropped chunks are released.
+//
+// Closing the returned Stream closes s.
+func Filter(s Stream, pred Matcher) Stream {
+	return MapChunks(s, func(chunk *MessageChunk) (*MessageChunk, error) {
+		if pred(chunk) {
+			return chunk, nil
+		}
+		return nil, nil
+	})
+}
+
+// MapChunks returns a Stream of the chunks of s mapped by fn. fn returns the
+// output chunk, or nil to drop the chunk; it must not modify its input.
+// Dropped chunks are released. A chunk mapped to a new one is left to fn,
+// which releases it once the output no longer shares its Part.
+//
+// Output chunks inherit the Ctrl of their input as with TransformFunc, and
+// the markers of dropped chunks are translated as with Filter: a dropped
+// EoS marker becomes an EoS marker of the type of the last output chunk, so
+// a mapping that changes the MIME type also changes the type of the EoS
+// markers it drops.
+//
+// An error from fn fails the returned Stream with it and closes s with it.
+func MapChunks(s Stream, fn func(*MessageChunk) (*MessageChunk, error)) Stream {
+	return &mapStream{src: s, fn: fn, markers: markerFilter{}}
+}
+
+type mapStream struct {
+	src     Stream
+	fn      func(*MessageChunk) (*MessageChunk, error)
+	markers markerFilter
+	err     error
+}
+
+func (s *mapStream) Next() (*MessageChunk, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	for {
+		in, err := s.src.Next()
+		if err != nil {
+			return nil, err
+		}
+		out, err := s.fn(in)
+		if err != nil {
+			in.Release()
+			s.err = err
+			s.src.CloseWithError(err)
+			return nil, err
+		}
+		chunk := s.markers.apply(in, out)
+		if out == nil {
+			in.Release()
+		}
+		if chunk != nil {
+			return chunk, nil
+		}
+	}
+}
+
+func (s *mapStream) Close() error {
+	return s.src.Close()
+}
+
+func (s *mapStream) CloseWithError(err error) error {
+	return s.src.CloseWithError(err)
+}
+
+// PartitionByMIME splits s by MIME type. It returns one Stream per prefix,
+// receiving the chunks whose MIME type starts with it, followed by a Stream
+// of the remaining chunks. A chunk goes to the first matching prefix; Text
+// parts have the MIME type "text/plain", and chunks without a part (pure
+// BOS/EOS markers) go to the remaining chunks.
+//
+//	parts := genx.PartitionByMIME(output, "audio/", "text/")
+//	audio, text, rest := parts[0], parts[1], parts[2]
+//
+// Each partition translates the markers of the chunks it does not receive
+// as Filter does, so an audio EoS marker ends the text sub-stream of the
+// same StreamID with a text EoS marker, and vice versa.
+//
+// A chunk whose partition was closed is released. All partitions must be
+// consumed, or closed, to avoid blocking the others.
+func PartitionByMIME(s Stream, prefixes ...string) []Stream {
+	bufs := make([]*buffer.Buffer[*MessageChunk], len(prefixes)+1)
+	markers := make([]markerFilter, len(bufs))
+	streams := make([]Stream, len(bufs))
+	for i := range bufs {
+		bufs[i] = buffer.N[*MessageChunk](100)
+		markers[i] = markerFilter{}
+		streams[i] = &bufferStream{buf: bufs[i]}
+	}
+
+	go func() {
+		defer func() {
+			for _, buf := range bufs {
+				buf.CloseWrite()
+			}
+		}()
+
+		open := len(bufs)
+		closed := make([]bool, len(bufs))
+		for open > 0 {
+			chunk, err := s.Next()
+			if err != nil {
+				if err != io.EOF {
+					for _, buf := range bufs {
+						buf.CloseWithError(err)
+					}
+				}
+				return
+			}
+
+			route := len(prefixes)
+			mime := chunkMIME(chunk)
+			for i, prefix := range prefixes {
+				if mime != "" && strings.HasPrefix(mime, prefix) {
+					route = i
+					break
+				}
+			}
+			delivered := false
+			for i, buf := range bufs {
+				if closed[i] {
+					continue
+				}
+				var out *MessageChunk
+				if i == route {
+					out = chunk
+				}
+				if c := markers[i].apply(chunk, out); c != nil {
+					if err := buf.Add(c); err != nil {
+						closed[i] = true
+						open--
+					} else if i == route {
+						delivered = true
+					}
+				}
+			}
+			if !delivered {
+				chunk.Release()
+			}
+		}
+		s.Close()
+	}()
+
+	return streams
+}
+
+// chunkMIME returns the MIME type of the chunk's part, or "" if it has none.
+func chunkMIME(chunk *MessageChunk) string {
+	if chunk == nil {
+		return ""
+	}
+	return PartMIMEType(chunk.Part)
+}
+
+// markerFilter keeps the BOS/EOS markers of a stream consistent when
+// chunks are dropped from it, tracking each StreamID separately.
+type markerFilter map[string]*markerState
+
+// markerState describes the last kept chunk of a sub-stream by value, as
+// the chunk itself belongs downstream once emitted and may be released.
+type markerState struct {
+	bos  bool // a dropped BOS waits for the next kept chunk
+	kept bool // a chunk was kept; the fields below type translated EoS markers
+	role Role
+	name string
+	part bool   // the chunk has a part
+	text bool   // the part is Text
+	mime string // MIME type of the part
+}
+
+// apply returns the chunk to emit for the input chunk in, given out, its
+// kept (and possibly mapped) form, or nil if it was dropped. It returns nil
+// if nothing is to be emitted.
+func (f markerFilter) apply(in, out *MessageChunk) *MessageChunk {
+	if in == nil {
+		return out
+	}
+	var id string
+	if in.Ctrl != nil {
+		id = in.Ctrl.StreamID
+	}
+	st := f[id]
+
+	if out != nil {
+		out = inheritCtrl(out, in.Ctrl)
+		if st != nil && st.bos && !out.IsBeginOfStream() {
+			c := *out
+			ctrl := StreamCtrl{StreamID: id}
+			if out.Ctrl != nil {
+				ctrl = *out.Ctrl
+			}
+			ctrl.BeginOfStream = true
+			c.Ctrl = &ctrl
+			out = &c
+		}
+		if out.IsEndOfStream() {
+			delete(f, id)
+			return out
+		}
+		if st == nil {
+			st = &markerState{}
+			f[id] = st
+		}
+		st.bos = false
+		st.keep(out)
+		return out
+	}
+
+	switch {
+	case in.IsEndOfStream():
+		delete(f, id)
+		if st == nil || !st.kept {
+			return nil
+		}
+		return st.endOfStream(in.Ctrl)
+	case in.IsBeginOfStream():
+		f[id] = &markerState{bos: true}
+	}
+	return nil
+}
+
+// keep records the type of chunk.
+func (st *markerState) keep(chunk *MessageChunk) {
+	st.kept = true
+	st.role, st.name = chunk.Role, chunk.Name
+	_, st.text = chunk.Part.(Text)
+	st.part = chunk.Part != nil
+	st.mime = PartMIMEType(chunk.Part)
+}
+
+// endOfStream returns an EoS marker with the role, name and part type of
+// the last kept chunk and a copy of ctrl.
+func (st *markerState) endOfStream(ctrl *StreamCtrl) *MessageChunk {
+	eos := &MessageChunk{Role: st.role, Name: st.name}
+	switch {
+	case st.text:
+		eos.Part = Text("")
+	case st.part:
+		eos.Part = &Blob{MIMEType: st.mime}
+	}
+	c := *ctrl
+	c.BeginOfStream = false
+	eos.Ctrl = &c
+	return eos
+}
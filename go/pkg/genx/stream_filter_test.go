@@ -0,0 +1,255 @@
+package genx
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func audioChunk(id string, data string) *MessageChunk {
+	return &MessageChunk{
+		Role: RoleModel,
+		Part: &Blob{MIMEType: "audio/pcm", Data: []byte(data)},
+		Ctrl: &StreamCtrl{StreamID: id},
+	}
+}
+
+func textChunk(id string, text string) *MessageChunk {
+	return &MessageChunk{Role: RoleModel, Part: Text(text), Ctrl: &StreamCtrl{StreamID: id}}
+}
+
+func withCtrl(c *MessageChunk, bos, eos bool) *MessageChunk {
+	c.Ctrl.BeginOfStream = bos
+	c.Ctrl.EndOfStream = eos
+	return c
+}
+
+func TestFilter_MovesBOSAndTranslatesEOS(t *testing.T) {
+	src := &sliceStream{chunks: []*MessageChunk{
+		withCtrl(textChunk("s1", "hello"), true, false),
+		audioChunk("s1", "a1"),
+		textChunk("s1", "world"),
+		audioChunk("s1", "a2"),
+		withCtrl(textChunk("s1", ""), false, true),
+	}}
+	got := readAll(t, Filter(src, MIMETypeMatcher("audio/")))
+
+	if len(got) != 3 {
+		t.Fatalf("len = %d, want 3: %+v", len(got), got)
+	}
+	if !got[0].IsBeginOfStream() || got[0].Ctrl.StreamID != "s1" {
+		t.Errorf("first chunk ctrl = %+v, want BOS of s1", got[0].Ctrl)
+	}
+	if got[1].IsBeginOfStream() {
+		t.Error("second chunk has BOS")
+	}
+	eos := got[2]
+	if !eos.IsEndOfStream() || eos.Ctrl.StreamID != "s1" {
+		t.Fatalf("last chunk ctrl = %+v, want EOS of s1", eos.Ctrl)
+	}
+	blob, ok := eos.Part.(*Blob)
+	if !ok || blob.MIMEType != "audio/pcm" || len(blob.Data) != 0 {
+		t.Errorf("EoS part = %#v, want empty audio/pcm blob", eos.Part)
+	}
+	if eos.Role != RoleModel {
+		t.Errorf("EoS role = %q", eos.Role)
+	}
+}
+
+func TestFilter_EmptySubStream(t *testing.T) {
+	src := &sliceStream{chunks: []*MessageChunk{
+		withCtrl(textChunk("s1", "only text"), true, false),
+		withCtrl(textChunk("s1", ""), false, true),
+		withCtrl(audioChunk("s2", "a"), true, false),
+		withCtrl(audioChunk("s2", ""), false, true),
+	}}
+	got := readAll(t, Filter(src, MIMETypeMatcher("audio/")))
+
+	if len(got) != 2 {
+		t.Fatalf("len = %d, want 2 (nothing of s1): %+v", len(got), got)
+	}
+	for _, c := range got {
+		if c.Ctrl.StreamID != "s2" {
+			t.Errorf("chunk of %q, want only s2", c.Ctrl.StreamID)
+		}
+	}
+	if !got[0].IsBeginOfStream() || !got[1].IsEndOfStream() {
+		t.Errorf("markers = %+v, %+v", got[0].Ctrl, got[1].Ctrl)
+	}
+}
+
+func TestFilter_PureMarkerChunks(t *testing.T) {
+	src := &sliceStream{chunks: []*MessageChunk{
+		NewBeginOfStream("s1"),
+		audioChunk("s1", "a"),
+		{Ctrl: &StreamCtrl{StreamID: "s1", EndOfStream: true}},
+	}}
+	got := readAll(t, Filter(src, MIMETypeMatcher("audio/")))
+
+	if len(got) != 2 {
+		t.Fatalf("len = %d, want 2: %+v", len(got), got)
+	}
+	if !got[0].IsBeginOfStream() {
+		t.Error("BOS marker was not moved onto the audio chunk")
+	}
+	if blob, ok := got[1].Part.(*Blob); !ok || !got[1].IsEndOfStream() || blob.MIMEType != "audio/pcm" {
+		t.Errorf("EoS = %+v", got[1])
+	}
+}
+
+func TestMapChunks_TranslatesEOSType(t *testing.T) {
+	src := &sliceStream{chunks: []*MessageChunk{
+		withCtrl(audioChunk("s1", "ab"), true, false),
+		audioChunk("s1", "cd"),
+		withCtrl(audioChunk("s1", ""), false, true),
+	}}
+	// Transcribe audio data into text; drop the EoS marker, which MapChunks
+	// must translate into a text EoS marker.
+	s := MapChunks(src, func(c *MessageChunk) (*MessageChunk, error) {
+		if c.IsEndOfStream() {
+			return nil, nil
+		}
+		return &MessageChunk{Role: c.Role, Part: Text(c.Part.(*Blob).Data)}, nil
+	})
+	got := readAll(t, s)
+
+	if len(got) != 3 {
+		t.Fatalf("len = %d, want 3: %+v", len(got), got)
+	}
+	if got[0].Part.(Text) != "ab" || !got[0].IsBeginOfStream() || got[0].Ctrl.StreamID != "s1" {
+		t.Errorf("first = %+v", got[0])
+	}
+	if text, ok := got[2].Part.(Text); !ok || text != "" || !got[2].IsEndOfStream() {
+		t.Errorf("EoS = %+v, want text EoS", got[2])
+	}
+}
+
+func TestMapChunks_Error(t *testing.T) {
+	errBoom := errors.New("boom")
+	s := MapChunks(&sliceStream{chunks: textChunks("a", "b")}, func(c *MessageChunk) (*MessageChunk, error) {
+		return nil, errBoom
+	})
+	if _, err := s.Next(); !errors.Is(err, errBoom) {
+		t.Fatalf("err = %v, want boom", err)
+	}
+	if _, err := s.Next(); !errors.Is(err, errBoom) {
+		t.Fatalf("second err = %v, want boom", err)
+	}
+}
+
+func TestPartitionByMIME(t *testing.T) {
+	src := &sliceStream{chunks: []*MessageChunk{
+		NewBeginOfStream("s1"),
+		textChunk("s1", "hi"),
+		audioChunk("s1", "a1"),
+		{Part: &Blob{MIMEType: "image/png", Data: []byte("img")}, Ctrl: &StreamCtrl{StreamID: "s1"}},
+		audioChunk("s1", "a2"),
+		withCtrl(audioChunk("s1", ""), false, true),
+	}}
+	parts := PartitionByMIME(src, "audio/", "text/")
+	if len(parts) != 3 {
+		t.Fatalf("len(parts) = %d, want 3", len(parts))
+	}
+
+	// The input is small enough for the partition buffers, so they can be
+	// read one after the other.
+	audio, text, rest := readAll(t, parts[0]), readAll(t, parts[1]), readAll(t, parts[2])
+	if len(audio) != 3 || !audio[0].IsBeginOfStream() || !audio[2].IsEndOfStream() {
+		t.Errorf("audio = %+v", audio)
+	}
+	if len(text) != 2 || !text[0].IsBeginOfStream() || !text[1].IsEndOfStream() {
+		t.Fatalf("text = %+v", text)
+	}
+	if _, ok := text[1].Part.(Text); !ok {
+		t.Errorf("text EoS part = %#v, want Text", text[1].Part)
+	}
+	// The rest gets the pure BOS marker and the image, and an image EoS.
+	if len(rest) != 3 || !rest[0].IsBeginOfStream() || rest[0].Part != nil {
+		t.Fatalf("rest = %+v", rest)
+	}
+	if blob, ok := rest[2].Part.(*Blob); !ok || blob.MIMEType != "image/png" || !rest[2].IsEndOfStream() {
+		t.Errorf("rest EoS = %+v", rest[2])
+	}
+}
+
+func TestPartitionByMIME_ClosedPartition(t *testing.T) {
+	var chunks []*MessageChunk
+	for range 500 {
+		chunks = append(chunks, textChunk("", "t"), audioChunk("", "a"))
+	}
+	parts := PartitionByMIME(&sliceStream{chunks: chunks}, "audio/")
+	parts[1].Close()
+
+	got := readAll(t, parts[0])
+	if len(got) != 500 {
+		t.Errorf("audio chunks = %d, want 500", len(got))
+	}
+}
+
+func TestPartitionByMIME_Error(t *testing.T) {
+	errBoom := errors.New("boom")
+	parts := PartitionByMIME(&sliceStream{err: errBoom}, "audio/")
+	for i, p := range parts {
+		if _, err := p.Next(); !errors.Is(err, errBoom) {
+			t.Errorf("part %d: err = %v, want boom", i, err)
+		}
+	}
+}
+
+func TestFilter_ReleasedKeptChunk(t *testing.T) {
+	kept := pooledChunk([]byte("frame"))
+	kept.Ctrl = &StreamCtrl{StreamID: "s1"}
+	dropped := pooledChunk([]byte("frame"))
+	dropped.Ctrl = &StreamCtrl{StreamID: "s1"}
+	src := &sliceStream{chunks: []*MessageChunk{
+		kept,
+		dropped,
+		withCtrl(textChunk("s1", ""), false, true),
+	}}
+	s := Filter(src, func(c *MessageChunk) bool { return c == kept })
+
+	c, err := s.Next()
+	if err != nil || c != kept {
+		t.Fatalf("Next = %+v, %v", c, err)
+	}
+	// The consumer owns the kept chunk and may release it at once.
+	c.Release()
+
+	eos, err := s.Next()
+	if err != nil {
+		t.Fatalf("Next EoS: %v", err)
+	}
+	if blob, ok := eos.Part.(*Blob); !ok || blob.MIMEType != "audio/pcm" || eos.Role != RoleUser || !eos.IsEndOfStream() {
+		t.Errorf("EoS = %+v, want audio/pcm EoS of the released chunk", eos)
+	}
+	if dropped.Part != nil {
+		t.Error("dropped chunk not released")
+	}
+}
+
+// chanStream returns the chunks sent on its channel until it is closed.
+type chanStream chan *MessageChunk
+
+func (s chanStream) Next() (*MessageChunk, error) {
+	if c, ok := <-s; ok {
+		return c, nil
+	}
+	return nil, io.EOF
+}
+
+func (s chanStream) Close() error                   { return nil }
+func (s chanStream) CloseWithError(err error) error { return nil }
+
+func TestPartitionByMIME_ReleasesClosedPartition(t *testing.T) {
+	src := make(chanStream)
+	parts := PartitionByMIME(src, "audio/")
+	parts[0].Close()
+
+	chunk := pooledChunk([]byte("frame"))
+	src <- chunk
+	close(src)
+	readAll(t, parts[1])
+	if chunk.Part != nil {
+		t.Error("chunk of closed partition not released")
+	}
+}
@@ -2,6 +2,8 @@ package genx
 
 import (
 	"crypto/rand"
+	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,13 +14,51 @@ const epoch2025 int64 = 1735689600
 // base62 characters for encoding
 const base62Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
 
+// streamIDSource overrides NewStreamID, see SetStreamIDSource.
+var streamIDSource atomic.Pointer[func() string]
+
+// SetStreamIDSource replaces the generator of NewStreamID, so that tests and
+// replay tooling get deterministic StreamIDs, e.g. for golden-file
+// comparisons of recorded pipelines. A nil source restores the default
+// random IDs. It returns a function that restores the previous source:
+//
+//	defer genx.SetStreamIDSource(genx.SequentialStreamIDs("s"))()
+//
+// The source applies process-wide and must be safe for concurrent use.
+func SetStreamIDSource(source func() string) (restore func()) {
+	var prev *func() string
+	if source == nil {
+		prev = streamIDSource.Swap(nil)
+	} else {
+		prev = streamIDSource.Swap(&source)
+	}
+	return func() { streamIDSource.Store(prev) }
+}
+
+// SequentialStreamIDs returns a StreamID source that yields prefix + "1",
+// prefix + "2", and so on. It is safe for concurrent use, but IDs are only
+// deterministic if streams are created in a deterministic order.
+func SequentialStreamIDs(prefix string) func() string {
+	var n atomic.Uint64
+	return func() string {
+		return prefix + strconv.FormatUint(n.Add(1), 10)
+	}
+}
+
 // NewStreamID generates a short unique stream identifier.
 // Format: base62(seconds_since_2025) + base62(random_6bytes)
 // Length: ~14 characters (6 for time + 8 for random)
 //
 // The time component ensures IDs are roughly time-ordered,
 // reducing collision probability in long-running systems.
+//
+// If a source is set with SetStreamIDSource, NewStreamID returns its IDs
+// instead.
 func NewStreamID() string {
+	if source := streamIDSource.Load(); source != nil {
+		return (*source)()
+	}
+
 	// Time component: seconds since 2025-01-01
 	secs := uint32(time.Now().Unix() - epoch2025)
 	timePart := base62EncodeUint32(secs)
@@ -0,0 +1,77 @@
+package genx
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestNewStreamID_Unique(t *testing.T) {
+	a, b := NewStreamID(), NewStreamID()
+	if a == b {
+		t.Errorf("NewStreamID() returned %q twice", a)
+	}
+	if len(a) < 8 {
+		t.Errorf("NewStreamID() = %q, too short", a)
+	}
+}
+
+func TestSetStreamIDSource(t *testing.T) {
+	restore := SetStreamIDSource(SequentialStreamIDs("s"))
+	for _, want := range []string{"s1", "s2", "s3"} {
+		if got := NewStreamID(); got != want {
+			t.Errorf("NewStreamID() = %q, want %q", got, want)
+		}
+	}
+
+	// Nested overrides restore the outer source.
+	inner := SetStreamIDSource(func() string { return "fixed" })
+	if got := NewStreamID(); got != "fixed" {
+		t.Errorf("NewStreamID() = %q, want fixed", got)
+	}
+	inner()
+	if got := NewStreamID(); got != "s4" {
+		t.Errorf("NewStreamID() after inner restore = %q, want s4", got)
+	}
+
+	restore()
+	if got := NewStreamID(); got == "s5" || got == "fixed" {
+		t.Errorf("NewStreamID() after restore = %q, want a random ID", got)
+	}
+}
+
+func TestSetStreamIDSource_Nil(t *testing.T) {
+	defer SetStreamIDSource(func() string { return "fixed" })()
+	restore := SetStreamIDSource(nil)
+	if got := NewStreamID(); got == "fixed" {
+		t.Error("nil source did not restore random IDs")
+	}
+	restore()
+	if got := NewStreamID(); got != "fixed" {
+		t.Errorf("NewStreamID() = %q, want fixed", got)
+	}
+}
+
+func TestSequentialStreamIDs_Concurrent(t *testing.T) {
+	next := SequentialStreamIDs("c")
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]bool)
+		wg   sync.WaitGroup
+	)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				id := next()
+				mu.Lock()
+				seen[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(seen) != 800 {
+		t.Errorf("got %d distinct IDs, want 800", len(seen))
+	}
+}
@@ -188,6 +188,14 @@ func (itr *StreamIter) pull(str Stream) (re error) {
 				key.MIMEType = v.MIMEType
 				data = v.Data
 				cap = 16 * 1024
+			case *ImagePart, *VideoFramePart:
+				// Frames of the same MIME type are concatenated, e.g. into
+				// an MJPEG stream.
+				key.MIMEType = PartMIMEType(v)
+				if img, ok := AsImage(v); ok {
+					data = img.Data
+				}
+				cap = 64 * 1024
 			case Text:
 				key.MIMEType = "text/plain"
 				data = []byte(v)
@@ -0,0 +1,161 @@
+package genx
+
+import "fmt"
+
+// Sequence returns a Stream that stamps StreamCtrl.Seq on the chunks of
+// input, counting from 1 per StreamID. Chunks are copied before stamping,
+// so chunks shared with other readers are not modified.
+//
+// Stamp a stream where it enters a pipeline and check it with
+// CheckSequence where it leaves, to prove that no chunk was lost,
+// duplicated or reordered on the way.
+func Sequence(input Stream) Stream {
+	return &seqStream{src: input, next: make(map[string]uint64)}
+}
+
+type seqStream struct {
+	src  Stream
+	next map[string]uint64 // StreamID → last Seq
+}
+
+func (s *seqStream) Next() (*MessageChunk, error) {
+	chunk, err := s.src.Next()
+	if err != nil || chunk == nil {
+		return chunk, err
+	}
+	id := chunk.streamID()
+	s.next[id]++
+
+	c := *chunk
+	ctrl := StreamCtrl{}
+	if chunk.Ctrl != nil {
+		ctrl = *chunk.Ctrl
+	}
+	ctrl.Seq = s.next[id]
+	c.Ctrl = &ctrl
+	return &c, nil
+}
+
+func (s *seqStream) Close() error {
+	return s.src.Close()
+}
+
+func (s *seqStream) CloseWithError(err error) error {
+	return s.src.CloseWithError(err)
+}
+
+// SeqIssueKind classifies a sequence violation.
+type SeqIssueKind string
+
+const (
+	// SeqGap means chunks are missing: Got is beyond Expected.
+	SeqGap SeqIssueKind = "gap"
+	// SeqDuplicate means a chunk was seen again: Got equals the last Seq.
+	SeqDuplicate SeqIssueKind = "duplicate"
+	// SeqReorder means a chunk arrived after a later one: Got is below the
+	// last Seq.
+	SeqReorder SeqIssueKind = "reorder"
+)
+
+// SeqIssue describes a sequence violation found by CheckSequence.
+type SeqIssue struct {
+	StreamID string
+	Kind     SeqIssueKind
+	// Expected is the Seq that should have come next.
+	Expected uint64
+	// Got is the Seq that came.
+	Got uint64
+}
+
+// Missing returns the number of chunks skipped by a gap.
+func (i SeqIssue) Missing() uint64 {
+	if i.Kind != SeqGap {
+		return 0
+	}
+	return i.Got - i.Expected
+}
+
+// SeqError is the error of a stream checked by CheckSequence without an
+// issue handler.
+type SeqError struct {
+	SeqIssue
+}
+
+func (e *SeqError) Error() string {
+	return fmt.Sprintf("genx: sequence %s in stream %q: expected seq %d, got %d",
+		e.Kind, e.StreamID, e.Expected, e.Got)
+}
+
+// CheckSequence returns a Stream that passes the chunks of input through
+// and checks their StreamCtrl.Seq per StreamID. Chunks without Seq are not
+// checked. A stream's first checked chunk may start at any Seq, so a check
+// can join a stream midway.
+//
+// Each violation is passed to onIssue. If onIssue is nil, the first
+// violation fails the stream with a *SeqError instead, and the input is
+// closed with it.
+func CheckSequence(input Stream, onIssue func(SeqIssue)) Stream {
+	return &seqCheckStream{src: input, onIssue: onIssue, last: make(map[string]uint64)}
+}
+
+type seqCheckStream struct {
+	src     Stream
+	onIssue func(SeqIssue)
+	last    map[string]uint64 // StreamID → last Seq
+}
+
+func (s *seqCheckStream) Next() (*MessageChunk, error) {
+	chunk, err := s.src.Next()
+	if err != nil || chunk == nil || chunk.Ctrl == nil || chunk.Ctrl.Seq == 0 {
+		return chunk, err
+	}
+
+	issue, ok := s.check(chunk.streamID(), chunk.Ctrl.Seq)
+	if ok {
+		return chunk, nil
+	}
+	if s.onIssue != nil {
+		s.onIssue(issue)
+		return chunk, nil
+	}
+	seqErr := &SeqError{SeqIssue: issue}
+	s.src.CloseWithError(seqErr)
+	return nil, seqErr
+}
+
+// check records seq and reports whether it follows the last one.
+func (s *seqCheckStream) check(id string, seq uint64) (SeqIssue, bool) {
+	last, seen := s.last[id]
+	if !seen || seq == last+1 {
+		s.last[id] = seq
+		return SeqIssue{}, true
+	}
+
+	issue := SeqIssue{StreamID: id, Expected: last + 1, Got: seq}
+	switch {
+	case seq > last+1:
+		issue.Kind = SeqGap
+		s.last[id] = seq
+	case seq == last:
+		issue.Kind = SeqDuplicate
+	default:
+		issue.Kind = SeqReorder
+	}
+	return issue, false
+}
+
+func (s *seqCheckStream) Close() error {
+	return s.src.Close()
+}
+
+func (s *seqCheckStream) CloseWithError(err error) error {
+	return s.src.CloseWithError(err)
+}
+
+// streamID returns the chunk's StreamID, or "" without Ctrl.
+func (c *MessageChunk) streamID() string {
+	if c.Ctrl == nil {
+		return ""
+	}
+	return c.Ctrl.StreamID
+}
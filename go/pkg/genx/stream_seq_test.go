@@ -0,0 +1,181 @@
+package genx
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+// sliceStream is a Stream over fixed chunks.
+type sliceStream struct {
+	chunks []*MessageChunk
+	err    error
+}
+
+func (s *sliceStream) Next() (*MessageChunk, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+	c := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return c, nil
+}
+
+func (s *sliceStream) Close() error { return s.CloseWithError(io.EOF) }
+
+func (s *sliceStream) CloseWithError(err error) error {
+	if s.err == nil {
+		s.err = err
+	}
+	return nil
+}
+
+func seqChunk(streamID string, seq uint64) *MessageChunk {
+	return &MessageChunk{
+		Part: Text("x"),
+		Ctrl: &StreamCtrl{StreamID: streamID, Seq: seq},
+	}
+}
+
+func collectSeqs(t *testing.T, s Stream) map[string][]uint64 {
+	t.Helper()
+	seqs := make(map[string][]uint64)
+	for {
+		c, err := s.Next()
+		if err == io.EOF {
+			return seqs
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		if c.Ctrl == nil {
+			seqs[""] = append(seqs[""], 0)
+			continue
+		}
+		seqs[c.Ctrl.StreamID] = append(seqs[c.Ctrl.StreamID], c.Ctrl.Seq)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	shared := &MessageChunk{Part: Text("b"), Ctrl: &StreamCtrl{StreamID: "b"}}
+	src := &sliceStream{chunks: []*MessageChunk{
+		{Part: Text("a")},
+		shared,
+		{Part: Text("a")},
+		{Part: Text("b"), Ctrl: &StreamCtrl{StreamID: "b", EndOfStream: true}},
+	}}
+
+	var got []*MessageChunk
+	s := Sequence(src)
+	for {
+		c, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		got = append(got, c)
+	}
+
+	want := []struct {
+		id  string
+		seq uint64
+	}{{"", 1}, {"b", 1}, {"", 2}, {"b", 2}}
+	for i, w := range want {
+		if got[i].Ctrl.StreamID != w.id || got[i].Ctrl.Seq != w.seq {
+			t.Errorf("chunk %d = %q/%d, want %q/%d", i, got[i].Ctrl.StreamID, got[i].Ctrl.Seq, w.id, w.seq)
+		}
+	}
+	if !got[3].Ctrl.EndOfStream {
+		t.Error("EndOfStream lost")
+	}
+	if shared.Ctrl.Seq != 0 {
+		t.Error("Sequence modified the input chunk")
+	}
+}
+
+func TestCheckSequence(t *testing.T) {
+	src := &sliceStream{chunks: []*MessageChunk{
+		seqChunk("a", 1),
+		seqChunk("b", 5), // joined midway
+		seqChunk("a", 2),
+		seqChunk("a", 4), // gap
+		seqChunk("b", 6),
+		seqChunk("a", 4), // duplicate
+		seqChunk("a", 3), // reorder
+		{Part: Text("no seq")},
+		seqChunk("a", 5),
+	}}
+
+	var issues []SeqIssue
+	seqs := collectSeqs(t, CheckSequence(src, func(i SeqIssue) {
+		issues = append(issues, i)
+	}))
+	if len(seqs["a"]) != 6 || len(seqs["b"]) != 2 {
+		t.Errorf("passed %v, want all chunks", seqs)
+	}
+
+	want := []SeqIssue{
+		{StreamID: "a", Kind: SeqGap, Expected: 3, Got: 4},
+		{StreamID: "a", Kind: SeqDuplicate, Expected: 5, Got: 4},
+		{StreamID: "a", Kind: SeqReorder, Expected: 5, Got: 3},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("issues = %+v, want %+v", issues, want)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Errorf("issue %d = %+v, want %+v", i, issues[i], want[i])
+		}
+	}
+	if n := issues[0].Missing(); n != 1 {
+		t.Errorf("Missing() = %d, want 1", n)
+	}
+}
+
+func TestCheckSequence_Error(t *testing.T) {
+	src := &sliceStream{chunks: []*MessageChunk{
+		seqChunk("a", 1),
+		seqChunk("a", 3),
+		seqChunk("a", 4),
+	}}
+	s := CheckSequence(src, nil)
+
+	if _, err := s.Next(); err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	_, err := s.Next()
+	var seqErr *SeqError
+	if !errors.As(err, &seqErr) {
+		t.Fatalf("err = %v, want *SeqError", err)
+	}
+	if seqErr.Kind != SeqGap || seqErr.Expected != 2 || seqErr.Got != 3 {
+		t.Errorf("issue = %+v", seqErr.SeqIssue)
+	}
+	if _, err := s.Next(); !errors.Is(err, seqErr) {
+		t.Errorf("after error, err = %v, want %v", err, seqErr)
+	}
+}
+
+func TestSequence_RoundTrip(t *testing.T) {
+	var chunks []*MessageChunk
+	for i := 0; i < 100; i++ {
+		id := "a"
+		if i%3 == 0 {
+			id = "b"
+		}
+		chunks = append(chunks, &MessageChunk{Part: Text("x"), Ctrl: &StreamCtrl{StreamID: id}})
+	}
+
+	s := CheckSequence(Sequence(&sliceStream{chunks: chunks}), func(i SeqIssue) {
+		t.Errorf("unexpected issue: %+v", i)
+	})
+	seqs := collectSeqs(t, s)
+	if len(seqs["a"])+len(seqs["b"]) != 100 {
+		t.Errorf("got %d chunks, want 100", len(seqs["a"])+len(seqs["b"]))
+	}
+}
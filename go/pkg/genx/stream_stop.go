@@ -0,0 +1,124 @@
+package genx
+
+import (
+	"slices"
+	"strings"
+)
+
+// StopAt returns a Stream that ends s at the first of the stop sequences in
+// its text, for providers that ignore stop sequences or limit their number.
+// The text before the sequence is emitted, s is closed and Next returns
+// Done with the usage s reported so far, if s is a UsageStream; the usage
+// then misses tokens the provider generated after the sequence.
+//
+// A sequence may span text chunks, so text that may start a sequence is
+// held back until the following text rules it out. Chunks other than text
+// pass through, after the held text. StopAt returns s if stop has no
+// non-empty sequences.
+func StopAt(s Stream, stop ...string) Stream {
+	stop = slices.DeleteFunc(slices.Clone(stop), func(seq string) bool { return seq == "" })
+	if len(stop) == 0 {
+		return s
+	}
+	return &stopStream{Stream: s, stop: stop}
+}
+
+type stopStream struct {
+	Stream
+	stop []string
+
+	held    strings.Builder // text that may start a stop sequence
+	role    Role            // role and name of the held text
+	name    string
+	pending []*MessageChunk // chunks to return before reading s again
+	err     error           // error to return once pending is empty
+}
+
+var _ UsageStream = (*stopStream)(nil)
+
+func (s *stopStream) Next() (*MessageChunk, error) {
+	for {
+		if len(s.pending) > 0 {
+			chunk := s.pending[0]
+			s.pending = s.pending[1:]
+			return chunk, nil
+		}
+		if s.err != nil {
+			return nil, s.err
+		}
+
+		chunk, err := s.Stream.Next()
+		if err != nil {
+			s.flush(s.held.Len())
+			s.err = err
+			continue
+		}
+		text, ok := chunk.Part.(Text)
+		if !ok || chunk.Ctrl != nil {
+			s.flush(s.held.Len())
+			s.pending = append(s.pending, chunk)
+			continue
+		}
+		if chunk.Role != s.role || chunk.Name != s.name {
+			s.flush(s.held.Len())
+			s.role, s.name = chunk.Role, chunk.Name
+		}
+		chunk.Release()
+		s.held.WriteString(string(text))
+
+		if i := s.index(); i >= 0 {
+			s.flush(i)
+			usage, _ := StreamUsage(s.Stream)
+			s.err = Done(usage)
+			s.Stream.Close()
+			continue
+		}
+		s.flush(s.held.Len() - s.partial())
+	}
+}
+
+// Usage implements UsageStream.
+func (s *stopStream) Usage() Usage {
+	usage, _ := StreamUsage(s.Stream)
+	return usage
+}
+
+// index returns the index of the first stop sequence in the held text, or
+// -1.
+func (s *stopStream) index() int {
+	held := s.held.String()
+	first := -1
+	for _, seq := range s.stop {
+		if i := strings.Index(held, seq); i >= 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+	return first
+}
+
+// partial returns the length of the longest suffix of the held text that
+// is a proper prefix of a stop sequence.
+func (s *stopStream) partial() int {
+	held := s.held.String()
+	longest := 0
+	for _, seq := range s.stop {
+		for n := min(len(held), len(seq)-1); n > longest; n-- {
+			if strings.HasPrefix(seq, held[len(held)-n:]) {
+				longest = n
+				break
+			}
+		}
+	}
+	return longest
+}
+
+// flush queues the first n bytes of the held text as a chunk, keeping the
+// rest held.
+func (s *stopStream) flush(n int) {
+	held := s.held.String()
+	if n > 0 {
+		s.pending = append(s.pending, &MessageChunk{Role: s.role, Name: s.name, Part: Text(held[:n])})
+	}
+	s.held.Reset()
+	s.held.WriteString(held[n:])
+}
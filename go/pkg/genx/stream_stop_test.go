@@ -0,0 +1,144 @@
+package genx
+
+import (
+	"errors"
+	"testing"
+)
+
+// textStream builds a stream of model text chunks that ends with Done.
+func textStream(t *testing.T, texts ...string) Stream {
+	t.Helper()
+	sb := NewStreamBuilder((&ModelContextBuilder{}).Build(), len(texts)+1)
+	for _, s := range texts {
+		if err := sb.Add(&MessageChunk{Role: RoleModel, Part: Text(s)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sb.Done(Usage{GeneratedTokenCount: 100}); err != nil {
+		t.Fatal(err)
+	}
+	return sb.Stream()
+}
+
+// drain reads s to its end and returns the text chunks and the end error.
+func drain(t *testing.T, s Stream) ([]string, error) {
+	t.Helper()
+	var texts []string
+	for {
+		chunk, err := s.Next()
+		if err != nil {
+			return texts, err
+		}
+		if text, ok := chunk.Part.(Text); ok {
+			texts = append(texts, string(text))
+		}
+	}
+}
+
+func join(texts []string) string {
+	var s string
+	for _, t := range texts {
+		s += t
+	}
+	return s
+}
+
+func TestStopAt(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		stop  []string
+		want  string
+	}{
+		{"no match", []string{"hello ", "world"}, []string{"END"}, "hello world"},
+		{"in one chunk", []string{"hello END world"}, []string{"END"}, "hello "},
+		{"across chunks", []string{"hello E", "N", "D world"}, []string{"END"}, "hello "},
+		{"false prefix", []string{"hello EN", "X world"}, []string{"END"}, "hello ENX world"},
+		{"prefix at end", []string{"hello E"}, []string{"END"}, "hello E"},
+		{"first of several", []string{"a\n\nb", "\nUser: c"}, []string{"\nUser:", "\n\n"}, "a"},
+		{"at start", []string{"END"}, []string{"END"}, ""},
+		{"unicode", []string{"你好", "。再见"}, []string{"。"}, "你好"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			texts, err := drain(t, StopAt(textStream(t, tt.texts...), tt.stop...))
+			if got := join(texts); got != tt.want {
+				t.Errorf("text = %q, want %q", got, tt.want)
+			}
+			if !errors.Is(err, ErrDone) {
+				t.Fatalf("err = %v, want Done", err)
+			}
+		})
+	}
+}
+
+func TestStopAt_Passthrough(t *testing.T) {
+	s := textStream(t, "x")
+	if got := StopAt(s, "", ""); got != s {
+		t.Error("StopAt without sequences should return the stream")
+	}
+
+	sb := NewStreamBuilder((&ModelContextBuilder{}).Build(), 8)
+	sb.Add(&MessageChunk{Role: RoleModel, Part: Text("call E")})
+	sb.Add(&MessageChunk{Role: RoleModel, ToolCall: &ToolCall{ID: "1"}})
+	sb.Add(&MessageChunk{Role: RoleModel, Part: Text("ND")})
+	sb.Done(Usage{})
+
+	stream := StopAt(sb.Stream(), "END")
+	var got []string
+	for {
+		chunk, err := stream.Next()
+		if err != nil {
+			break
+		}
+		switch {
+		case chunk.ToolCall != nil:
+			got = append(got, "<tool>")
+		default:
+			got = append(got, string(chunk.Part.(Text)))
+		}
+	}
+	// "E" is held back until the tool call rules out "END".
+	want := []string{"call ", "E", "<tool>", "ND"}
+	if len(got) != len(want) {
+		t.Fatalf("chunks = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("chunks[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStreamUsage(t *testing.T) {
+	sb := NewStreamBuilder((&ModelContextBuilder{}).Build(), 4)
+	stream := StopAt(sb.Stream(), "END")
+
+	if u, ok := StreamUsage(stream); !ok || u != (Usage{}) {
+		t.Errorf("StreamUsage() = %+v, %v, want zero, true", u, ok)
+	}
+	sb.Add(&MessageChunk{Role: RoleModel, Part: Text("hi")})
+	sb.SetUsage(Usage{PromptTokenCount: 5, GeneratedTokenCount: 1})
+	if u, _ := StreamUsage(stream); u.GeneratedTokenCount != 1 {
+		t.Errorf("usage after SetUsage = %+v", u)
+	}
+	sb.Done(Usage{PromptTokenCount: 5, GeneratedTokenCount: 3})
+	drain(t, stream)
+	if u, _ := StreamUsage(stream); u.GeneratedTokenCount != 3 {
+		t.Errorf("usage after Done = %+v", u)
+	}
+
+	// A stream cut by StopAt ends with the usage so far.
+	sb = NewStreamBuilder((&ModelContextBuilder{}).Build(), 4)
+	sb.Add(&MessageChunk{Role: RoleModel, Part: Text("hi END")})
+	sb.SetUsage(Usage{GeneratedTokenCount: 2})
+	_, err := drain(t, StopAt(sb.Stream(), "END"))
+	var state *State
+	if !errors.As(err, &state) || state.Usage().GeneratedTokenCount != 2 {
+		t.Errorf("err = %v, want Done with 2 generated tokens", err)
+	}
+
+	if _, ok := StreamUsage(&sliceStream{}); ok {
+		t.Error("StreamUsage() of a plain Stream should be false")
+	}
+}
@@ -11,16 +11,17 @@ import (
 type Matcher func(*MessageChunk) bool
 
 // MIMETypeMatcher returns a Matcher that matches chunks with the given MIME type prefix.
+// Only binary parts (Blob, ImagePart, VideoFramePart) are matched; Text never is.
 func MIMETypeMatcher(mimePrefix string) Matcher {
 	return func(chunk *MessageChunk) bool {
 		if chunk == nil || chunk.Part == nil {
 			return false
 		}
-		blob, ok := chunk.Part.(*Blob)
-		if !ok {
+		if _, ok := chunk.Part.(Text); ok {
 			return false
 		}
-		return len(blob.MIMEType) >= len(mimePrefix) && blob.MIMEType[:len(mimePrefix)] == mimePrefix
+		mimeType := PartMIMEType(chunk.Part)
+		return len(mimeType) >= len(mimePrefix) && mimeType[:len(mimePrefix)] == mimePrefix
 	}
 }
 
@@ -104,11 +105,7 @@ func CompositeSeq(streams ...Stream) Stream {
 
 				// Track the MIME type for EoS marker
 				if chunk != nil && chunk.Part != nil {
-					if blob, ok := chunk.Part.(*Blob); ok {
-						lastMIMEType = blob.MIMEType
-					} else if _, ok := chunk.Part.(Text); ok {
-						lastMIMEType = "text/plain"
-					}
+					lastMIMEType = PartMIMEType(chunk.Part)
 				}
 
 				if err := outBuf.Add(chunk); err != nil {
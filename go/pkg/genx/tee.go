@@ -6,6 +6,9 @@ import "io"
 // The original chunks pass through unchanged.
 // When src returns EOF, builder.Done() is called.
 // When src returns an error, builder.Abort() is called.
+//
+// To hand the same chunks to several independent consumers, use TeeN or
+// Broadcast.
 func Tee(src Stream, builder *StreamBuilder) Stream {
 	return &teeStream{src: src, builder: builder}
 }
@@ -27,7 +30,10 @@ func (t *teeStream) Next() (*MessageChunk, error) {
 		return nil, err
 	}
 	if chunk != nil {
-		t.builder.Add(chunk)
+		// The builder's reader owns a reference to a pooled chunk
+		if err := t.builder.Add(chunk.Retain()); err != nil {
+			chunk.Release()
+		}
 	}
 	return chunk, nil
 }
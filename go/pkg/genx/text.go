@@ -0,0 +1,16 @@
+package genx
+
+// LastSentenceEnd returns the byte offset just after the last sentence
+// terminator in s (Western or CJK punctuation, or a newline), or 0 if s has
+// no complete sentence. Streaming consumers of model text, such as
+// moderation, use it to cut the text they buffer at sentence boundaries.
+func LastSentenceEnd(s string) int {
+	end := 0
+	for i, r := range s {
+		switch r {
+		case '.', '!', '?', ';', '\n', '。', '！', '？', '；', '…':
+			end = i + len(string(r))
+		}
+	}
+	return end
+}
@@ -0,0 +1,25 @@
+package genx
+
+import "testing"
+
+func TestLastSentenceEnd(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"no end", 0},
+		{"One. Two", 4},
+		{"One! Two? Three", 9},
+		{"a;b\nc", 4},
+		{"你好。世界", len("你好。")},
+		{"真的！？还有", len("真的！？")},
+		{"等等…", len("等等…")},
+		{"Done.", 5},
+	}
+	for _, tt := range tests {
+		if got := LastSentenceEnd(tt.s); got != tt.want {
+			t.Errorf("LastSentenceEnd(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package trace
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Export records the trace as OpenTelemetry spans with tracer: a
+// "genx.turn" span from the origin to the end of the last stage, with a
+// child span per stage named after the stage. Stage spans carry the
+// latencies as attributes (in milliseconds) and the end of input and the
+// first output as events.
+func (t *Trace) Export(ctx context.Context, tracer oteltrace.Tracer) {
+	ctx, root := tracer.Start(ctx, "genx.turn",
+		oteltrace.WithTimestamp(t.Origin),
+		oteltrace.WithSpanKind(oteltrace.SpanKindInternal),
+	)
+	for _, s := range t.Spans {
+		_, span := tracer.Start(ctx, s.Stage,
+			oteltrace.WithTimestamp(s.Start),
+			oteltrace.WithAttributes(
+				attribute.String("genx.stage", s.Stage),
+				attribute.String("genx.stream_id", s.StreamID),
+			),
+		)
+		if !s.InputEnd.IsZero() {
+			span.AddEvent("input_end", oteltrace.WithTimestamp(s.InputEnd))
+		}
+		if !s.FirstOutput.IsZero() {
+			span.AddEvent("first_output", oteltrace.WithTimestamp(s.FirstOutput))
+			span.SetAttributes(
+				attribute.Int64("genx.first_output_ms", s.FirstOutputLatency().Milliseconds()),
+				attribute.Int64("genx.end_of_input_ms", s.EndOfInputLatency().Milliseconds()),
+				attribute.Int64("genx.origin_ms", s.OriginLatency().Milliseconds()),
+			)
+		}
+		span.End(oteltrace.WithTimestamp(s.End))
+	}
+	root.End(oteltrace.WithTimestamp(t.End()))
+}
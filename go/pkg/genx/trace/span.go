@@ -0,0 +1,122 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Span is one turn of one stage: the chunks of a StreamID from the stage's
+// first input to its output EoS marker.
+type Span struct {
+	// Stage is the name the stage was wrapped with.
+	Stage string
+
+	// StreamID is the StreamID of the turn's input.
+	StreamID string
+
+	// Origin is the origin of the turn's input (see
+	// genx.StreamCtrl.OriginTimestamp), at millisecond precision. Zero if
+	// unknown.
+	Origin time.Time
+
+	// Start is when the stage received the first input chunk of the turn.
+	Start time.Time
+
+	// InputEnd is when the input of the turn ended, with an EoS marker or
+	// the end of the input stream. Zero if it had not ended by End.
+	InputEnd time.Time
+
+	// FirstOutput is when the stage produced the first output chunk with
+	// content (text, audio data or a tool call). Zero if it produced none.
+	FirstOutput time.Time
+
+	// End is when the stage produced the EoS marker of the turn, or when its
+	// output stream ended.
+	End time.Time
+}
+
+// FirstOutputLatency returns the time from the first input to the first
+// output of the turn, e.g. the time to the first token of an LLM. It is
+// zero if there was no output.
+func (s Span) FirstOutputLatency() time.Duration {
+	if s.FirstOutput.IsZero() {
+		return 0
+	}
+	return s.FirstOutput.Sub(s.Start)
+}
+
+// EndOfInputLatency returns the time from the end of the input to the first
+// output, e.g. how long an ASR takes to finish after the user stopped
+// talking. It is zero if the stage answered before its input ended, or
+// there was no output.
+func (s Span) EndOfInputLatency() time.Duration {
+	if s.FirstOutput.IsZero() || s.InputEnd.IsZero() || s.FirstOutput.Before(s.InputEnd) {
+		return 0
+	}
+	return s.FirstOutput.Sub(s.InputEnd)
+}
+
+// OriginLatency returns the time from the origin to the first output, i.e.
+// the latency of the pipeline up to and including this stage. It is zero if
+// the origin is unknown or there was no output.
+func (s Span) OriginLatency() time.Duration {
+	if s.FirstOutput.IsZero() || s.Origin.IsZero() {
+		return 0
+	}
+	return s.FirstOutput.Sub(s.Origin)
+}
+
+// Duration returns the duration of the span.
+func (s Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Trace is the spans of the stages of a pipeline that share an origin,
+// ordered by start.
+type Trace struct {
+	Origin time.Time
+	Spans  []Span
+}
+
+// End returns the end of the last span.
+func (t *Trace) End() time.Time {
+	var end time.Time
+	for _, s := range t.Spans {
+		if s.End.After(end) {
+			end = s.End
+		}
+	}
+	return end
+}
+
+// String formats the trace as a latency breakdown, one line per span:
+//
+//	asr  first output +320ms    after input +120ms    at +320ms
+//	llm  first output +410ms    after input +400ms    at +730ms
+//	tts  first output +90ms     after input +0s       at +820ms
+//
+// "first output" is FirstOutputLatency, "after input" EndOfInputLatency and
+// "at" OriginLatency.
+func (t *Trace) String() string {
+	width := 0
+	for _, s := range t.Spans {
+		width = max(width, len(s.Stage))
+	}
+	var b strings.Builder
+	for _, s := range t.Spans {
+		if s.FirstOutput.IsZero() {
+			fmt.Fprintf(&b, "%-*s  no output  (%v)\n", width, s.Stage, roundMs(s.Duration()))
+			continue
+		}
+		fmt.Fprintf(&b, "%-*s  first output %-8s  after input %-8s  at %s\n", width, s.Stage,
+			"+"+roundMs(s.FirstOutputLatency()).String(),
+			"+"+roundMs(s.EndOfInputLatency()).String(),
+			"+"+roundMs(s.OriginLatency()).String())
+	}
+	return b.String()
+}
+
+func roundMs(d time.Duration) time.Duration {
+	return d.Round(time.Millisecond)
+}
@@ -0,0 +1,337 @@
+// Package trace measures where the time goes in a genx Transformer chain.
+//
+// A Recorder wraps the stages of a chain. For every turn of every stage
+// (the chunks of one StreamID, up to its EoS marker) it records a Span:
+// when the stage received its first input, when its input ended, when it
+// produced its first output and when its output ended. From these, a Span
+// gives the stage's latency to first output, e.g. the ASR's first token,
+// the LLM's first token or the TTS's first audio.
+//
+//	rec := trace.NewRecorder()
+//	pipeline := genx.Chain(
+//	    rec.Stage("asr", asr),
+//	    rec.Stage("llm", llm),
+//	    rec.Stage("tts", tts),
+//	)
+//
+// The spans of the stages are tied together by the OriginTimestamp of the
+// chunks (see genx.StreamCtrl): a stage stamps its output chunks with the
+// origin of its input, or with the time it received the input if the input
+// had none, so each turn of the chain is a Trace of spans with a common
+// origin:
+//
+//	for _, t := range rec.Traces() {
+//	    fmt.Println(t) // per-stage latency breakdown
+//	    t.Export(ctx, otel.Tracer("giztoy"))
+//	}
+package trace
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// Option configures a Recorder.
+type Option func(*Recorder)
+
+// WithClock sets the clock of the Recorder. Defaults to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(r *Recorder) { r.now = now }
+}
+
+// WithOnSpan sets a function called with every span when it ends.
+func WithOnSpan(fn func(Span)) Option {
+	return func(r *Recorder) { r.onSpan = fn }
+}
+
+// WithMaxSpans sets the number of ended spans kept for Traces; older spans
+// are discarded. Defaults to 1000.
+func WithMaxSpans(n int) Option {
+	return func(r *Recorder) { r.maxSpans = n }
+}
+
+// Recorder records the spans of traced stages. It is safe for concurrent
+// use.
+type Recorder struct {
+	now      func() time.Time
+	onSpan   func(Span)
+	maxSpans int
+
+	mu    sync.Mutex
+	spans []Span
+}
+
+// NewRecorder creates a Recorder.
+func NewRecorder(opts ...Option) *Recorder {
+	r := &Recorder{now: time.Now, maxSpans: 1000}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Stage wraps t so that each of its turns is recorded as a span of the
+// given name.
+func (r *Recorder) Stage(name string, t genx.Transformer) genx.Transformer {
+	return &stage{rec: r, name: name, t: t}
+}
+
+// Spans returns the ended spans, oldest first.
+func (r *Recorder) Spans() []Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.spans)
+}
+
+// Traces returns the ended spans grouped by origin, oldest first. Spans of
+// an unknown origin are not included.
+func (r *Recorder) Traces() []*Trace {
+	var traces []*Trace
+	byOrigin := make(map[time.Time]*Trace)
+	for _, s := range r.Spans() {
+		if s.Origin.IsZero() {
+			continue
+		}
+		t := byOrigin[s.Origin]
+		if t == nil {
+			t = &Trace{Origin: s.Origin}
+			byOrigin[s.Origin] = t
+			traces = append(traces, t)
+		}
+		t.Spans = append(t.Spans, s)
+	}
+	for _, t := range traces {
+		slices.SortStableFunc(t.Spans, func(a, b Span) int { return a.Start.Compare(b.Start) })
+	}
+	slices.SortStableFunc(traces, func(a, b *Trace) int { return a.Origin.Compare(b.Origin) })
+	return traces
+}
+
+// Reset discards the ended spans.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.spans = nil
+	r.mu.Unlock()
+}
+
+func (r *Recorder) end(s Span) {
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	if over := len(r.spans) - r.maxSpans; r.maxSpans > 0 && over > 0 {
+		r.spans = slices.Delete(r.spans, 0, over)
+	}
+	r.mu.Unlock()
+	if r.onSpan != nil {
+		r.onSpan(s)
+	}
+}
+
+type stage struct {
+	rec  *Recorder
+	name string
+	t    genx.Transformer
+}
+
+func (s *stage) Transform(ctx context.Context, pattern string, input genx.Stream) (genx.Stream, error) {
+	run := &stageRun{stage: s}
+	out, err := s.t.Transform(ctx, pattern, &inputStream{Stream: input, run: run})
+	if err != nil {
+		return nil, err
+	}
+	return &outputStream{Stream: out, run: run}, nil
+}
+
+// stageRun tracks the open turns of one Transform call of a stage.
+type stageRun struct {
+	*stage
+
+	mu    sync.Mutex
+	turns []*turn // open turns, oldest first
+}
+
+type turn struct {
+	span   Span
+	origin int64 // span.Origin in Unix milliseconds
+}
+
+// input records an input chunk.
+func (r *stageRun) input(chunk *genx.MessageChunk) {
+	now := r.rec.now()
+	id := streamID(chunk)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var t *turn
+	for i := len(r.turns) - 1; i >= 0; i-- {
+		if r.turns[i].span.StreamID == id && r.turns[i].span.InputEnd.IsZero() {
+			t = r.turns[i]
+			break
+		}
+	}
+	if t == nil {
+		t = &turn{span: Span{Stage: r.name, StreamID: id, Start: now}}
+		if chunk.Ctrl != nil && chunk.Ctrl.OriginTimestamp != 0 {
+			t.origin = chunk.Ctrl.OriginTimestamp
+		} else {
+			t.origin = now.UnixMilli()
+		}
+		t.span.Origin = time.UnixMilli(t.origin)
+		r.turns = append(r.turns, t)
+	}
+	if chunk.IsEndOfStream() {
+		t.span.InputEnd = now
+	}
+}
+
+// inputDone records the end of the input stream.
+func (r *stageRun) inputDone() {
+	now := r.rec.now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.turns {
+		if t.span.InputEnd.IsZero() {
+			t.span.InputEnd = now
+		}
+	}
+}
+
+// output records an output chunk and returns it stamped with the origin of
+// its turn.
+func (r *stageRun) output(chunk *genx.MessageChunk) *genx.MessageChunk {
+	now := r.rec.now()
+	id := streamID(chunk)
+
+	r.mu.Lock()
+	i := slices.IndexFunc(r.turns, func(t *turn) bool { return t.span.StreamID == id })
+	if i < 0 && len(r.turns) > 0 {
+		i = 0
+	}
+	if i < 0 {
+		// Output without input, e.g. from a generator.
+		t := &turn{span: Span{Stage: r.name, StreamID: id, Start: now}}
+		if chunk.Ctrl != nil && chunk.Ctrl.OriginTimestamp != 0 {
+			t.origin = chunk.Ctrl.OriginTimestamp
+			t.span.Origin = time.UnixMilli(t.origin)
+		}
+		r.turns = append(r.turns, t)
+		i = len(r.turns) - 1
+	}
+	t := r.turns[i]
+	if t.span.FirstOutput.IsZero() && hasContent(chunk) {
+		t.span.FirstOutput = now
+	}
+	var ended *Span
+	if chunk.IsEndOfStream() {
+		t.span.End = now
+		ended = &t.span
+		r.turns = slices.Delete(r.turns, i, i+1)
+	}
+	r.mu.Unlock()
+
+	if ended != nil {
+		r.rec.end(*ended)
+	}
+	return stampOrigin(chunk, t.origin)
+}
+
+// outputDone ends the open turns at the end of the output stream.
+func (r *stageRun) outputDone() {
+	now := r.rec.now()
+	r.mu.Lock()
+	turns := r.turns
+	r.turns = nil
+	r.mu.Unlock()
+	for _, t := range turns {
+		t.span.End = now
+		r.rec.end(t.span)
+	}
+}
+
+func streamID(chunk *genx.MessageChunk) string {
+	if chunk.Ctrl == nil {
+		return ""
+	}
+	return chunk.Ctrl.StreamID
+}
+
+// hasContent reports whether chunk carries content rather than only
+// control markers.
+func hasContent(chunk *genx.MessageChunk) bool {
+	switch p := chunk.Part.(type) {
+	case genx.Text:
+		return p != ""
+	case *genx.Blob:
+		return len(p.Data) > 0
+	case *genx.ImagePart:
+		return len(p.Data) > 0 || p.URL != ""
+	case *genx.VideoFramePart:
+		return len(p.Data) > 0
+	}
+	return chunk.ToolCall != nil
+}
+
+// stampOrigin returns chunk with OriginTimestamp set to origin if it has
+// none, copying rather than modifying it.
+func stampOrigin(chunk *genx.MessageChunk, origin int64) *genx.MessageChunk {
+	if origin == 0 || (chunk.Ctrl != nil && chunk.Ctrl.OriginTimestamp != 0) {
+		return chunk
+	}
+	c := *chunk
+	var ctrl genx.StreamCtrl
+	if chunk.Ctrl != nil {
+		ctrl = *chunk.Ctrl
+	}
+	ctrl.OriginTimestamp = origin
+	c.Ctrl = &ctrl
+	return &c
+}
+
+type inputStream struct {
+	genx.Stream
+	run  *stageRun
+	done sync.Once
+}
+
+func (s *inputStream) Next() (*genx.MessageChunk, error) {
+	chunk, err := s.Stream.Next()
+	if err != nil {
+		s.done.Do(s.run.inputDone)
+		return nil, err
+	}
+	if chunk != nil {
+		s.run.input(chunk)
+	}
+	return chunk, nil
+}
+
+type outputStream struct {
+	genx.Stream
+	run  *stageRun
+	done sync.Once
+}
+
+func (s *outputStream) Next() (*genx.MessageChunk, error) {
+	chunk, err := s.Stream.Next()
+	if err != nil {
+		s.done.Do(s.run.outputDone)
+		return nil, err
+	}
+	if chunk == nil {
+		return nil, nil
+	}
+	return s.run.output(chunk), nil
+}
+
+func (s *outputStream) Close() error {
+	s.done.Do(s.run.outputDone)
+	return s.Stream.Close()
+}
+
+func (s *outputStream) CloseWithError(err error) error {
+	s.done.Do(s.run.outputDone)
+	return s.Stream.CloseWithError(err)
+}
@@ -0,0 +1,299 @@
+package trace
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type sliceStream struct {
+	chunks []*genx.MessageChunk
+}
+
+func (s *sliceStream) Next() (*genx.MessageChunk, error) {
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+	c := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return c, nil
+}
+
+func (s *sliceStream) Close() error                   { return nil }
+func (s *sliceStream) CloseWithError(err error) error { return nil }
+
+// fakeClock advances by a millisecond on every reading.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(time.Millisecond)
+	return c.now
+}
+
+func readAll(t *testing.T, s genx.Stream) []*genx.MessageChunk {
+	t.Helper()
+	var chunks []*genx.MessageChunk
+	for {
+		c, err := s.Next()
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		chunks = append(chunks, c)
+	}
+}
+
+// audioTurn returns the chunks of a turn of user audio.
+func audioTurn(id string, origin int64) []*genx.MessageChunk {
+	var chunks []*genx.MessageChunk
+	for range 3 {
+		chunks = append(chunks, &genx.MessageChunk{
+			Part: &genx.Blob{MIMEType: "audio/pcm", Data: []byte{1, 2}},
+			Ctrl: &genx.StreamCtrl{StreamID: id, OriginTimestamp: origin},
+		})
+	}
+	eos := genx.NewEndOfStream("audio/pcm")
+	eos.Ctrl.StreamID = id
+	return append(chunks, eos)
+}
+
+// asr turns the audio of a turn into a single text chunk, which ends the
+// turn.
+var asr = genx.TransformFunc(func(c *genx.MessageChunk) (*genx.MessageChunk, error) {
+	if !c.IsEndOfStream() {
+		return nil, nil
+	}
+	return &genx.MessageChunk{Role: genx.RoleUser, Part: genx.Text("hello"), Ctrl: &genx.StreamCtrl{StreamID: c.Ctrl.StreamID}}, nil
+})
+
+// tts turns text into audio, dropping the origin as a real TTS might.
+var tts = genx.TransformFunc(func(c *genx.MessageChunk) (*genx.MessageChunk, error) {
+	return &genx.MessageChunk{
+		Role: genx.RoleModel,
+		Part: &genx.Blob{MIMEType: "audio/pcm", Data: []byte("audio")},
+		Ctrl: &genx.StreamCtrl{StreamID: c.Ctrl.StreamID, EndOfStream: c.IsEndOfStream()},
+	}, nil
+})
+
+func TestRecorder_Chain(t *testing.T) {
+	clock := &fakeClock{now: time.UnixMilli(1_700_000_000_000)}
+	var ended []Span
+	rec := NewRecorder(WithClock(clock.Now), WithOnSpan(func(s Span) { ended = append(ended, s) }))
+
+	pipeline := genx.Chain(rec.Stage("asr", asr), rec.Stage("tts", tts))
+	input := &sliceStream{}
+	input.chunks = append(input.chunks, audioTurn("t1", 1_000)...)
+	input.chunks = append(input.chunks, audioTurn("t2", 0)...)
+	out, err := pipeline.Transform(context.Background(), "", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := readAll(t, out)
+	if len(chunks) != 2 {
+		t.Fatalf("len(chunks) = %d, want one per turn", len(chunks))
+	}
+
+	// The origin of the input is carried to the output of the last stage,
+	// and input without an origin is stamped by the first stage.
+	if got := chunks[0].Ctrl.OriginTimestamp; got != 1_000 {
+		t.Errorf("origin of first turn = %d, want 1000", got)
+	}
+	if got := chunks[1].Ctrl.OriginTimestamp; got <= 1_700_000_000_000 {
+		t.Errorf("origin of second turn = %d, want stamped from the clock", got)
+	}
+	if chunks[0].Ctrl.StreamID != "t1" {
+		t.Errorf("stream id = %q", chunks[0].Ctrl.StreamID)
+	}
+
+	if len(ended) != 4 || len(rec.Spans()) != 4 {
+		t.Fatalf("spans = %d (%d recorded), want 4", len(ended), len(rec.Spans()))
+	}
+	traces := rec.Traces()
+	if len(traces) != 2 {
+		t.Fatalf("traces = %d, want 2", len(traces))
+	}
+	tr := traces[0]
+	if !tr.Origin.Equal(time.UnixMilli(1_000)) || len(tr.Spans) != 2 {
+		t.Fatalf("trace = %+v", tr)
+	}
+	a, b := tr.Spans[0], tr.Spans[1]
+	if a.Stage != "asr" || b.Stage != "tts" {
+		t.Fatalf("stages = %q, %q", a.Stage, b.Stage)
+	}
+	for _, s := range tr.Spans {
+		if s.Start.IsZero() || s.InputEnd.IsZero() || s.FirstOutput.IsZero() || s.End.IsZero() {
+			t.Errorf("%s: incomplete span %+v", s.Stage, s)
+		}
+		if s.FirstOutput.Before(s.Start) || s.End.Before(s.FirstOutput) {
+			t.Errorf("%s: times out of order: %+v", s.Stage, s)
+		}
+	}
+	// The ASR answers only at the end of its input.
+	if a.FirstOutput.Before(a.InputEnd) {
+		t.Errorf("asr first output %v before input end %v", a.FirstOutput, a.InputEnd)
+	}
+	if b.OriginLatency() <= a.OriginLatency() {
+		t.Errorf("tts origin latency %v, want more than asr's %v", b.OriginLatency(), a.OriginLatency())
+	}
+
+	rec.Reset()
+	if len(rec.Spans()) != 0 {
+		t.Error("Reset kept spans")
+	}
+}
+
+func TestRecorder_OutputClosedEndsSpans(t *testing.T) {
+	rec := NewRecorder()
+	input := &sliceStream{chunks: audioTurn("t1", 5)[:2]}
+	out, err := rec.Stage("asr", genx.TransformFunc(func(c *genx.MessageChunk) (*genx.MessageChunk, error) {
+		return c, nil
+	})).Transform(context.Background(), "", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := out.Next(); err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+
+	spans := rec.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d, want 1", len(spans))
+	}
+	if spans[0].End.IsZero() || !spans[0].InputEnd.IsZero() {
+		t.Errorf("span = %+v, want ended with input still open", spans[0])
+	}
+}
+
+func TestRecorder_MaxSpans(t *testing.T) {
+	rec := NewRecorder(WithMaxSpans(2))
+	for i := range 3 {
+		rec.end(Span{Stage: string(rune('a' + i))})
+	}
+	spans := rec.Spans()
+	if len(spans) != 2 || spans[0].Stage != "b" {
+		t.Errorf("spans = %+v, want the last two", spans)
+	}
+}
+
+func TestSpan_Latencies(t *testing.T) {
+	at := func(ms int) time.Time { return time.UnixMilli(int64(10_000 + ms)) }
+	s := Span{Origin: at(0), Start: at(100), InputEnd: at(900), FirstOutput: at(1_000), End: at(1_500)}
+	if got := s.FirstOutputLatency(); got != 900*time.Millisecond {
+		t.Errorf("FirstOutputLatency = %v", got)
+	}
+	if got := s.EndOfInputLatency(); got != 100*time.Millisecond {
+		t.Errorf("EndOfInputLatency = %v", got)
+	}
+	if got := s.OriginLatency(); got != time.Second {
+		t.Errorf("OriginLatency = %v", got)
+	}
+	if got := s.Duration(); got != 1_400*time.Millisecond {
+		t.Errorf("Duration = %v", got)
+	}
+
+	// Streaming: output before the end of the input.
+	s.InputEnd = at(2_000)
+	if got := s.EndOfInputLatency(); got != 0 {
+		t.Errorf("EndOfInputLatency of a streaming stage = %v, want 0", got)
+	}
+
+	tr := &Trace{Origin: at(0), Spans: []Span{
+		{Stage: "asr", Origin: at(0), Start: at(0), InputEnd: at(200), FirstOutput: at(320), End: at(330)},
+		{Stage: "llm", Origin: at(0), Start: at(320), InputEnd: at(330), FirstOutput: at(730), End: at(900)},
+		{Stage: "tts", Origin: at(0), Start: at(730), End: at(950)},
+	}}
+	want := "asr  first output +320ms    after input +120ms    at +320ms\n" +
+		"llm  first output +410ms    after input +400ms    at +730ms\n" +
+		"tts  no output  (220ms)\n"
+	if got := tr.String(); got != want {
+		t.Errorf("String =\n%s\nwant\n%s", got, want)
+	}
+	if !tr.End().Equal(at(950)) {
+		t.Errorf("End = %v", tr.End())
+	}
+}
+
+type recordedSpan struct {
+	noop.Span
+	name       string
+	start, end time.Time
+	events     []string
+	attrs      []attribute.KeyValue
+}
+
+func (s *recordedSpan) End(opts ...oteltrace.SpanEndOption) {
+	cfg := oteltrace.NewSpanEndConfig(opts...)
+	s.end = cfg.Timestamp()
+}
+
+func (s *recordedSpan) AddEvent(name string, _ ...oteltrace.EventOption) {
+	s.events = append(s.events, name)
+}
+
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.attrs = append(s.attrs, kv...)
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...oteltrace.SpanStartOption) (context.Context, oteltrace.Span) {
+	cfg := oteltrace.NewSpanStartConfig(opts...)
+	s := &recordedSpan{name: name, start: cfg.Timestamp(), attrs: cfg.Attributes()}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func TestTrace_Export(t *testing.T) {
+	at := func(ms int) time.Time { return time.UnixMilli(int64(10_000 + ms)) }
+	tr := &Trace{Origin: at(0), Spans: []Span{
+		{Stage: "asr", StreamID: "s1", Origin: at(0), Start: at(0), InputEnd: at(200), FirstOutput: at(320), End: at(330)},
+		{Stage: "tts", StreamID: "s1", Origin: at(0), Start: at(330), End: at(400)},
+	}}
+	tracer := &recordingTracer{}
+	tr.Export(context.Background(), tracer)
+
+	if len(tracer.spans) != 3 {
+		t.Fatalf("spans = %d, want 3", len(tracer.spans))
+	}
+	root, asr, tts := tracer.spans[0], tracer.spans[1], tracer.spans[2]
+	if root.name != "genx.turn" || !root.start.Equal(at(0)) || !root.end.Equal(at(400)) {
+		t.Errorf("root = %s %v..%v", root.name, root.start, root.end)
+	}
+	if asr.name != "asr" || !asr.start.Equal(at(0)) || !asr.end.Equal(at(330)) {
+		t.Errorf("asr = %s %v..%v", asr.name, asr.start, asr.end)
+	}
+	if strings.Join(asr.events, ",") != "input_end,first_output" {
+		t.Errorf("asr events = %v", asr.events)
+	}
+	var firstOutput int64
+	for _, kv := range asr.attrs {
+		if kv.Key == "genx.first_output_ms" {
+			firstOutput = kv.Value.AsInt64()
+		}
+	}
+	if firstOutput != 320 {
+		t.Errorf("genx.first_output_ms = %d, want 320", firstOutput)
+	}
+	if len(tts.events) != 0 {
+		t.Errorf("tts events = %v, want none", tts.events)
+	}
+}
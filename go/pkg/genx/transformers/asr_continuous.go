@@ -0,0 +1,251 @@
+package transformers
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// continuousBoundaryWindow is how long after a final result the speaker is
+// taken to be pausing, so that a session may be rotated without cutting an
+// utterance.
+const continuousBoundaryWindow = time.Second
+
+// ContinuousASRConfig configures continuous transcription of long input,
+// e.g. hours of a meeting, over backend sessions of limited duration.
+type ContinuousASRConfig struct {
+	// SessionLimit is the longest a backend session may run: the session
+	// is rotated when it is reached, even mid-utterance. Set it below the
+	// limit of the vendor. Default 10m.
+	SessionLimit time.Duration
+
+	// RotateAfter is when a session is rotated at the next pause after a
+	// final result, so that rotations rarely cut an utterance. Default 4/5
+	// of SessionLimit.
+	RotateAfter time.Duration
+
+	// FinalizeInterval, if positive, rotates a session that produced no
+	// final result for this long, forcing the text of long monologues
+	// out periodically.
+	FinalizeInterval time.Duration
+}
+
+func (c ContinuousASRConfig) withDefaults() ContinuousASRConfig {
+	if c.SessionLimit <= 0 {
+		c.SessionLimit = 10 * time.Minute
+	}
+	if c.RotateAfter <= 0 || c.RotateAfter > c.SessionLimit {
+		c.RotateAfter = c.SessionLimit * 4 / 5
+	}
+	return c
+}
+
+// ContinuousASRTransformer is implemented by ASR transformers that handle
+// continuous input themselves, e.g. because their backend rotates sessions
+// without the audio EoS markers ContinuousASR inserts.
+type ContinuousASRTransformer interface {
+	TransformContinuous(ctx context.Context, pattern string, input genx.Stream, cfg ContinuousASRConfig) (genx.Stream, error)
+}
+
+// ContinuousASR transcribes continuous input, e.g. hours of audio from a
+// meeting recorder, with an ASR transformer whose backend sessions have a
+// maximum duration. Sessions are measured in wall-clock time, so the input
+// is expected in real time.
+//
+// Session Rotation:
+//   - The wrapped transformer must finish its backend session at an audio
+//     EoS marker and open a new one on the next audio, as DoubaoASRSAUC
+//     does; ContinuousASR inserts audio EoS markers to rotate sessions
+//   - The text EoS markers answering them are dropped, so the output reads
+//     as one transcript; EoS markers of the input pass through
+//   - MetaASRSegment values are renumbered to stay unique until the next
+//     EoS marker of the input
+//
+// If the wrapped transformer implements ContinuousASRTransformer, Transform
+// delegates to it.
+type ContinuousASR struct {
+	t   genx.Transformer
+	cfg ContinuousASRConfig
+	now func() time.Time
+}
+
+var _ genx.Transformer = (*ContinuousASR)(nil)
+
+// NewContinuousASR creates a ContinuousASR transformer wrapping t.
+func NewContinuousASR(t genx.Transformer, cfg ContinuousASRConfig) *ContinuousASR {
+	return &ContinuousASR{t: t, cfg: cfg.withDefaults(), now: time.Now}
+}
+
+// Transform implements genx.Transformer. The ctx and pattern are passed to
+// the wrapped transformer.
+func (c *ContinuousASR) Transform(ctx context.Context, pattern string, input genx.Stream) (genx.Stream, error) {
+	if ct, ok := c.t.(ContinuousASRTransformer); ok {
+		return ct.TransformContinuous(ctx, pattern, input, c.cfg)
+	}
+
+	inner := newBufferStream(100)
+	results, err := c.t.Transform(ctx, pattern, inner)
+	if err != nil {
+		inner.Close()
+		return nil, err
+	}
+
+	s := &continuousSession{cfg: c.cfg, now: c.now}
+	output := newBufferStream(100)
+	go s.inputLoop(input, inner)
+	go s.outputLoop(results, output)
+	return output, nil
+}
+
+// continuousSession is the state of one ContinuousASR transform, shared by
+// the loop feeding the wrapped transformer and the loop reading its
+// results.
+type continuousSession struct {
+	cfg ContinuousASRConfig
+	now func() time.Time
+
+	mu           sync.Mutex
+	sessionStart time.Time // zero while no backend session runs
+	lastFinal    time.Time
+	speaking     bool   // an interim result followed the last final one
+	eos          []bool // audio EoS markers sent, true for rotations
+	segBase      int    // added to MetaASRSegment values
+	segNext      int    // one past the highest segment of the session
+}
+
+func (s *continuousSession) inputLoop(input genx.Stream, inner *bufferStream) {
+	defer inner.Close()
+
+	for {
+		chunk, err := input.Next()
+		if err != nil {
+			if err != io.EOF {
+				inner.CloseWithError(err)
+			}
+			return
+		}
+		if chunk == nil {
+			continue
+		}
+
+		if blob, ok := chunk.Part.(*genx.Blob); ok && isAudioMIME(blob.MIMEType) {
+			if chunk.IsEndOfStream() {
+				s.mu.Lock()
+				s.eos = append(s.eos, false)
+				s.sessionStart = time.Time{}
+				s.mu.Unlock()
+			} else if s.rotate(s.now()) {
+				eos := genx.NewEndOfStream(blob.MIMEType)
+				eos.Role = chunk.Role
+				eos.Name = chunk.Name
+				if err := inner.Push(eos); err != nil {
+					chunk.Release()
+					return
+				}
+			}
+		}
+		if err := inner.Push(chunk); err != nil {
+			return
+		}
+	}
+}
+
+// rotate reports whether the backend session must be rotated before the
+// audio arriving at now, recording the rotation if so.
+func (s *continuousSession) rotate(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sessionStart.IsZero() {
+		s.sessionStart = now
+		return false
+	}
+	elapsed := now.Sub(s.sessionStart)
+	pausing := !s.speaking && !s.lastFinal.IsZero() && now.Sub(s.lastFinal) < continuousBoundaryWindow
+	lastFinal := s.lastFinal
+	if lastFinal.Before(s.sessionStart) {
+		lastFinal = s.sessionStart
+	}
+	switch {
+	case elapsed >= s.cfg.SessionLimit:
+	case elapsed >= s.cfg.RotateAfter && pausing:
+	case s.cfg.FinalizeInterval > 0 && now.Sub(lastFinal) >= s.cfg.FinalizeInterval:
+	default:
+		return false
+	}
+	s.eos = append(s.eos, true)
+	s.sessionStart = now
+	s.lastFinal = time.Time{}
+	s.speaking = false
+	return true
+}
+
+func (s *continuousSession) outputLoop(results genx.Stream, output *bufferStream) {
+	defer output.Close()
+
+	for {
+		chunk, err := results.Next()
+		if err != nil {
+			if err != io.EOF {
+				output.CloseWithError(err)
+			}
+			return
+		}
+		if chunk == nil {
+			continue
+		}
+		if s.drop(chunk) {
+			chunk.Release()
+			continue
+		}
+		if err := output.Push(chunk); err != nil {
+			return
+		}
+	}
+}
+
+// drop tracks a result of the wrapped transformer and renumbers its
+// segment. It reports whether the result is the text EoS of a rotation.
+func (s *continuousSession) drop(chunk *genx.MessageChunk) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	text, ok := chunk.Part.(genx.Text)
+	if !ok {
+		return false
+	}
+	if chunk.IsEndOfStream() {
+		if len(s.eos) == 0 {
+			return false
+		}
+		rotation := s.eos[0]
+		s.eos = s.eos[1:]
+		if rotation {
+			s.segBase += s.segNext
+		} else {
+			s.segBase = 0
+		}
+		s.segNext = 0
+		return rotation
+	}
+
+	if seg := chunk.Metadata(MetaASRSegment); seg != "" {
+		if n, err := strconv.Atoi(seg); err == nil {
+			s.segNext = max(s.segNext, n+1)
+			chunk.SetMetadata(MetaASRSegment, strconv.Itoa(s.segBase+n))
+		}
+	}
+	switch {
+	case chunk.Metadata(MetaASR) == ASRInterim:
+		s.speaking = true
+	case IsUnstableASR(chunk), text == "":
+	default:
+		s.lastFinal = s.now()
+		s.speaking = false
+	}
+	return false
+}
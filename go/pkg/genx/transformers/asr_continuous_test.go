@@ -0,0 +1,205 @@
+package transformers
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+func TestContinuousSession_Rotate(t *testing.T) {
+	// Events of a session: audio must (not) rotate, or an ASR result
+	type event struct {
+		ms   int
+		kind string // "audio", "rotate", "final" or "interim"
+	}
+	limit := ContinuousASRConfig{SessionLimit: 10 * time.Second, RotateAfter: 8 * time.Second}
+	finalize := ContinuousASRConfig{SessionLimit: time.Minute, FinalizeInterval: 3 * time.Second}
+	tests := []struct {
+		name   string
+		cfg    ContinuousASRConfig
+		events []event
+	}{
+		{"session limit", limit, []event{
+			{ms: 0, kind: "audio"}, {ms: 9999, kind: "audio"},
+			{ms: 10000, kind: "rotate"}, {ms: 19999, kind: "audio"},
+			{ms: 20000, kind: "rotate"},
+		}},
+		{"pause after RotateAfter", limit, []event{
+			{ms: 0, kind: "audio"},
+			{ms: 7000, kind: "final"}, {ms: 7500, kind: "audio"}, // too early
+			{ms: 8200, kind: "final"}, {ms: 8500, kind: "rotate"},
+		}},
+		{"speaking after RotateAfter", limit, []event{
+			{ms: 0, kind: "audio"},
+			{ms: 8100, kind: "final"}, {ms: 8300, kind: "interim"},
+			{ms: 8500, kind: "audio"}, {ms: 10000, kind: "rotate"},
+		}},
+		{"pause window passed", limit, []event{
+			{ms: 0, kind: "audio"},
+			{ms: 8000, kind: "final"}, {ms: 9000, kind: "audio"},
+			{ms: 10000, kind: "rotate"},
+		}},
+		{"finalize interval", finalize, []event{
+			{ms: 0, kind: "audio"}, {ms: 2999, kind: "audio"},
+			{ms: 3000, kind: "rotate"},
+			{ms: 4000, kind: "final"}, {ms: 6500, kind: "audio"},
+			{ms: 7000, kind: "rotate"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var clock time.Duration
+			base := time.Unix(0, 0)
+			s := &continuousSession{cfg: tt.cfg.withDefaults(), now: func() time.Time { return base.Add(clock) }}
+			for _, e := range tt.events {
+				clock = time.Duration(e.ms) * time.Millisecond
+				switch e.kind {
+				case "audio", "rotate":
+					if got := s.rotate(s.now()); got != (e.kind == "rotate") {
+						t.Errorf("audio at %dms: rotate = %v, want %v", e.ms, got, !got)
+					}
+				default:
+					chunk := &genx.MessageChunk{Role: genx.RoleUser, Part: genx.Text("words")}
+					chunk.SetMetadata(MetaASR, e.kind)
+					s.drop(chunk)
+				}
+			}
+		})
+	}
+}
+
+// fakeSessionASR transcribes each audio chunk to its data, numbering
+// segments per backend session, and answers each audio EoS with a text
+// EoS. It records the chunks it receives.
+type fakeSessionASR struct {
+	mu  sync.Mutex
+	got []string
+}
+
+func (f *fakeSessionASR) Transform(_ context.Context, _ string, input genx.Stream) (genx.Stream, error) {
+	output := newBufferStream(100)
+	go func() {
+		defer output.Close()
+		seg := 0
+		for {
+			chunk, err := input.Next()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.got = append(f.got, describeChunk(chunk))
+			f.mu.Unlock()
+
+			blob := chunk.Part.(*genx.Blob)
+			if chunk.IsEndOfStream() {
+				eos := genx.NewTextEndOfStream()
+				eos.Role = genx.RoleUser
+				output.Push(eos)
+				seg = 0
+				continue
+			}
+			text := &genx.MessageChunk{Role: genx.RoleUser, Part: genx.Text(blob.Data)}
+			text.SetMetadata(MetaASR, ASRFinal)
+			text.SetMetadata(MetaASRSegment, strconv.Itoa(seg))
+			output.Push(text)
+			seg++
+		}
+	}()
+	return output, nil
+}
+
+// lockedTimedStream is a timedStream whose clock may be read from other
+// goroutines with now.
+type lockedTimedStream struct {
+	mu    sync.Mutex
+	s     *timedStream
+	clock time.Duration
+}
+
+func (s *lockedTimedStream) Next() (*genx.MessageChunk, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.s.Next()
+}
+
+func (s *lockedTimedStream) now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Unix(0, 0).Add(s.clock)
+}
+
+func (s *lockedTimedStream) Close() error                   { return nil }
+func (s *lockedTimedStream) CloseWithError(err error) error { return nil }
+
+func TestContinuousASR_EoSMatching(t *testing.T) {
+	inner := &fakeSessionASR{}
+	c := NewContinuousASR(inner, ContinuousASRConfig{SessionLimit: 10 * time.Second})
+	input := &lockedTimedStream{}
+	input.s = &timedStream{clock: &input.clock, chunks: []timedChunk{
+		at(0, userAudio("a")),
+		at(1000, userAudio("b")),
+		at(11000, userAudio("c")), // rotated
+		at(12000, userAudio("d")),
+		at(13000, userEoS()),
+		at(14000, userAudio("e")),
+		at(30000, userAudio("f")), // rotated
+	}}
+	c.now = input.now
+
+	out, err := c.Transform(context.Background(), "", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for {
+		chunk, err := out.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		desc := describeChunk(chunk)
+		if seg := chunk.Metadata(MetaASRSegment); seg != "" {
+			desc += " #" + seg
+		}
+		got = append(got, desc)
+	}
+
+	// Segments continue across rotations and restart after the input EoS
+	want := []string{
+		"user text a #0",
+		"user text b #1",
+		"user text c #2",
+		"user text d #3",
+		"user text EoS",
+		"user text e #0",
+		"user text f #1",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("output:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	wantInner := []string{
+		"user audio/pcm a",
+		"user audio/pcm b",
+		"user audio/pcm EoS",
+		"user audio/pcm c",
+		"user audio/pcm d",
+		"user audio/pcm EoS",
+		"user audio/pcm e",
+		"user audio/pcm EoS",
+		"user audio/pcm f",
+	}
+	inner.mu.Lock()
+	defer inner.mu.Unlock()
+	if strings.Join(inner.got, "\n") != strings.Join(wantInner, "\n") {
+		t.Errorf("inner input:\n%s\nwant:\n%s", strings.Join(inner.got, "\n"), strings.Join(wantInner, "\n"))
+	}
+}
@@ -0,0 +1,140 @@
+package transformers
+
+import (
+	"context"
+	"io"
+	"strconv"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// Metadata keys written by ASR transformers with interim results enabled
+// (e.g., WithDoubaoASRSAUCInterim) into StreamCtrl.Metadata.
+const (
+	// MetaASR marks the stability of a text chunk: ASRInterim, ASRFinal or
+	// ASRCorrection.
+	MetaASR = "asr"
+	// MetaASRSegment identifies the utterance a chunk belongs to, unique
+	// between EoS markers (e.g., "0", "1").
+	MetaASRSegment = "asr.segment"
+)
+
+// Values of MetaASR.
+const (
+	// ASRInterim marks an unstable hypothesis. It holds the whole text of
+	// the segment so far and replaces the previous interim of the segment.
+	ASRInterim = "interim"
+	// ASRFinal marks the stable text of a segment.
+	ASRFinal = "final"
+	// ASRCorrection marks an empty text chunk retracting the interim text
+	// of a segment. It precedes the final text of the segment, or ends a
+	// segment that was dropped (e.g., noise).
+	ASRCorrection = "correction"
+)
+
+// IsUnstableASR reports whether chunk is an interim hypothesis or a
+// correction, which live captions render but agents should not act on.
+func IsUnstableASR(chunk *genx.MessageChunk) bool {
+	switch chunk.Metadata(MetaASR) {
+	case ASRInterim, ASRCorrection:
+		return true
+	}
+	return false
+}
+
+// asrSegments tracks the interim text of the current ASR segment, and
+// creates the chunks for interim and final results.
+type asrSegments struct {
+	segment int
+	interim string // last interim text emitted, "" if none
+}
+
+// interimChunk returns the chunk of an interim result, or nil if the text
+// did not change.
+func (s *asrSegments) interimChunk(text string) *genx.MessageChunk {
+	if text == "" || text == s.interim {
+		return nil
+	}
+	s.interim = text
+	return s.chunk(text, ASRInterim)
+}
+
+// finalChunks returns the chunks of the final text of the current segment:
+// a correction if interim text was emitted, then the final text. The next
+// result starts a new segment.
+func (s *asrSegments) finalChunks(text string) []*genx.MessageChunk {
+	var chunks []*genx.MessageChunk
+	if c := s.retract(); c != nil {
+		chunks = append(chunks, c)
+	}
+	chunks = append(chunks, s.chunk(text, ASRFinal))
+	s.segment++
+	return chunks
+}
+
+// retract returns a correction for the interim text of the current
+// segment, or nil if there is none.
+func (s *asrSegments) retract() *genx.MessageChunk {
+	if s.interim == "" {
+		return nil
+	}
+	s.interim = ""
+	return s.chunk("", ASRCorrection)
+}
+
+func (s *asrSegments) chunk(text, stability string) *genx.MessageChunk {
+	c := &genx.MessageChunk{Part: genx.Text(text)}
+	c.SetMetadata(MetaASR, stability)
+	c.SetMetadata(MetaASRSegment, strconv.Itoa(s.segment))
+	return c
+}
+
+// StableASR drops interim ASR results and their corrections, passing only
+// stable text. Place it between an ASR transformer with interim results and
+// the agent, while captions read the ASR output directly:
+//
+//	asr := NewDoubaoASRSAUC(client, WithDoubaoASRSAUCInterim(true))
+//	text, _ := asr.Transform(ctx, "", audio)
+//	// fork text: all chunks to captions, and
+//	stable, _ := NewStableASR().Transform(ctx, "", text) // to the agent
+//
+// All other chunks pass through unchanged.
+type StableASR struct{}
+
+var _ genx.Transformer = (*StableASR)(nil)
+
+// NewStableASR creates a StableASR transformer.
+func NewStableASR() *StableASR {
+	return &StableASR{}
+}
+
+// Transform implements genx.Transformer. The ctx and pattern are unused.
+func (t *StableASR) Transform(_ context.Context, _ string, input genx.Stream) (genx.Stream, error) {
+	output := newBufferStream(100)
+	go t.transformLoop(input, output)
+	return output, nil
+}
+
+func (t *StableASR) transformLoop(input genx.Stream, output *bufferStream) {
+	defer output.Close()
+
+	for {
+		chunk, err := input.Next()
+		if err != nil {
+			if err != io.EOF {
+				output.CloseWithError(err)
+			}
+			return
+		}
+		if chunk == nil {
+			continue
+		}
+		if IsUnstableASR(chunk) {
+			chunk.Release()
+			continue
+		}
+		if err := output.Push(chunk); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,599 @@
+package transformers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/haivivi/giztoy/go/pkg/audio/codec/mp3"
+	"github.com/haivivi/giztoy/go/pkg/audio/codec/ogg"
+	"github.com/haivivi/giztoy/go/pkg/audio/codec/opus"
+	"github.com/haivivi/giztoy/go/pkg/audio/resampler"
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// Audio types supported by AudioConvert.
+const (
+	audioPCM  = "audio/pcm"
+	audioOpus = "audio/opus"
+	audioOgg  = "audio/ogg"
+	audioMP3  = "audio/mp3"
+)
+
+// defaultOpusBitrate is the bitrate of Opus output if none is set.
+const defaultOpusBitrate = 64000
+
+// AudioConvert is a transformer that converts audio chunks of any supported
+// type to one target type, sample rate and channel count, so that the
+// output of a TTS can feed any ASR or device without resampling code.
+//
+// Supported types, as input and target:
+//   - audio/pcm: 16-bit signed little-endian PCM. The format of input PCM
+//     is read from MIME parameters ("audio/pcm;rate=24000;channels=1", also
+//     audio/L16), or set by WithAudioConvertPCMFormat
+//   - audio/opus: raw Opus, one frame per chunk
+//   - audio/ogg: Ogg Opus
+//   - audio/mp3 or audio/mpeg
+//
+// Input type: audio/*
+// Output type: the target MIME type
+//
+// Conversion is lazy: chunks already in the target type (and, for PCM, at
+// the target rate and channels) pass through unchanged. A converter starts
+// on the first chunk of another type and streams its output in 20ms
+// frames, until the EoS marker of that type.
+//
+// EoS Handling:
+//   - When receiving an EoS marker of a converted type, flush the
+//     converter, emit an EoS marker of the target type
+//   - Non-audio chunks and audio of unsupported types are passed through
+//     unchanged
+type AudioConvert struct {
+	mime       string
+	sampleRate int
+	channels   int
+
+	pcmRate     int
+	pcmChannels int
+	bitrate     int // 0 for the default of the target codec
+}
+
+var _ genx.Transformer = (*AudioConvert)(nil)
+
+// AudioConvertOption configures the AudioConvert transformer.
+type AudioConvertOption func(*AudioConvert)
+
+// WithAudioConvertPCMFormat sets the format of input PCM without rate or
+// channels MIME parameters (default 16000Hz mono).
+func WithAudioConvertPCMFormat(sampleRate, channels int) AudioConvertOption {
+	return func(c *AudioConvert) {
+		c.pcmRate = sampleRate
+		c.pcmChannels = channels
+	}
+}
+
+// WithAudioConvertBitrate sets the bitrate in bits/second of Opus (default
+// 64000) and MP3 (default: LAME VBR) output.
+func WithAudioConvertBitrate(bitrate int) AudioConvertOption {
+	return func(c *AudioConvert) {
+		c.bitrate = bitrate
+	}
+}
+
+// NewAudioConvert creates a transformer converting audio to targetMIME
+// (audio/pcm, audio/opus, audio/ogg or audio/mp3) at targetRate Hz with
+// targetChannels channels.
+//
+// Opus and Ogg targets require a rate of 8000, 12000, 16000, 24000 or 48000
+// and 1 or 2 channels; MP3 targets require 1 or 2 channels.
+func NewAudioConvert(targetMIME string, targetRate, targetChannels int, opts ...AudioConvertOption) *AudioConvert {
+	c := &AudioConvert{
+		mime:        normalizeAudioMIME(targetMIME),
+		sampleRate:  targetRate,
+		channels:    targetChannels,
+		pcmRate:     16000,
+		pcmChannels: 1,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Transform converts audio Blob chunks to the target type.
+// AudioConvert does not require connection setup, so it returns immediately.
+// The ctx is unused; the goroutine lifetime is governed by the input Stream.
+func (c *AudioConvert) Transform(_ context.Context, _ string, input genx.Stream) (genx.Stream, error) {
+	output := newBufferStream(100)
+	go c.transformLoop(input, output)
+	return output, nil
+}
+
+func (c *AudioConvert) transformLoop(input genx.Stream, output *bufferStream) {
+	defer output.Close()
+
+	// One converter per input MIME type, so interleaved sub-streams of
+	// different types do not mix
+	convs := make(map[string]*audioConverter)
+	defer func() {
+		for _, conv := range convs {
+			conv.abort()
+		}
+	}()
+
+	for {
+		chunk, err := input.Next()
+		if err != nil {
+			if err != io.EOF {
+				output.CloseWithError(err)
+				return
+			}
+			// EOF: flush the converters, without EoS markers
+			for mimeType, conv := range convs {
+				delete(convs, mimeType)
+				if err := conv.finish(); err != nil {
+					output.CloseWithError(err)
+					return
+				}
+			}
+			return
+		}
+		if chunk == nil {
+			continue
+		}
+
+		blob, ok := chunk.Part.(*genx.Blob)
+		if !ok || !isAudioMIME(blob.MIMEType) || c.passThrough(blob.MIMEType) {
+			if err := output.Push(chunk); err != nil {
+				return
+			}
+			continue
+		}
+
+		if chunk.IsEndOfStream() {
+			// Converted EoS: flush the converter, emit target EoS
+			if conv := convs[blob.MIMEType]; conv != nil {
+				delete(convs, blob.MIMEType)
+				if err := conv.finish(); err != nil {
+					chunk.Release()
+					output.CloseWithError(err)
+					return
+				}
+			}
+			eos := genx.NewEndOfStream(c.mime)
+			eos.Role = chunk.Role
+			eos.Name = chunk.Name
+			chunk.Release()
+			if err := output.Push(eos); err != nil {
+				return
+			}
+			continue
+		}
+
+		conv := convs[blob.MIMEType]
+		if conv == nil {
+			conv, err = c.newConverter(blob.MIMEType, chunk, output)
+			if err != nil {
+				chunk.Release()
+				output.CloseWithError(err)
+				return
+			}
+			convs[blob.MIMEType] = conv
+		}
+		err = conv.write(blob.Data)
+		chunk.Release()
+		if err != nil {
+			output.CloseWithError(err)
+			return
+		}
+	}
+}
+
+// passThrough reports whether audio of mimeType is already in the target
+// format, or of a type AudioConvert does not decode.
+func (c *AudioConvert) passThrough(mimeType string) bool {
+	typ := normalizeAudioMIME(mimeType)
+	switch typ {
+	case audioPCM:
+		if c.mime != audioPCM {
+			return false
+		}
+		rate, channels := c.pcmFormat(mimeType)
+		return rate == c.sampleRate && channels == c.channels
+	case audioOpus, audioOgg, audioMP3:
+		return typ == c.mime
+	}
+	return true
+}
+
+// pcmFormat returns the sample rate and channels of PCM of mimeType.
+func (c *AudioConvert) pcmFormat(mimeType string) (rate, channels int) {
+	rate, channels = c.pcmRate, c.pcmChannels
+	_, params, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return rate, channels
+	}
+	if n, err := strconv.Atoi(params["rate"]); err == nil && n > 0 {
+		rate = n
+	}
+	if n, err := strconv.Atoi(params["channels"]); err == nil && n > 0 {
+		channels = n
+	}
+	return rate, channels
+}
+
+// normalizeAudioMIME returns the type of an audio MIME type without
+// parameters, with aliases resolved (audio/mpeg → audio/mp3, audio/L16 →
+// audio/pcm).
+func normalizeAudioMIME(mimeType string) string {
+	typ, _, _ := strings.Cut(mimeType, ";")
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	switch typ {
+	case "audio/mpeg":
+		return audioMP3
+	case "audio/l16":
+		return audioPCM
+	}
+	return typ
+}
+
+// isOpusRate reports whether Opus encodes and decodes at rate natively.
+func isOpusRate(rate int) bool {
+	switch rate {
+	case 8000, 12000, 16000, 24000, 48000:
+		return true
+	}
+	return false
+}
+
+// audioConverter converts one input sub-stream. Input bytes are written to
+// a pipe; a goroutine decodes, resamples and encodes them, and pushes the
+// output chunks.
+type audioConverter struct {
+	pw   *io.PipeWriter
+	done chan error
+
+	// opusDec decodes raw Opus input in write, since frame boundaries
+	// would be lost in the pipe.
+	opusDec *opus.Decoder
+
+	finished bool
+	err      error
+}
+
+func (c *AudioConvert) newConverter(mimeType string, first *genx.MessageChunk, output *bufferStream) (*audioConverter, error) {
+	pr, pw := io.Pipe()
+	conv := &audioConverter{pw: pw, done: make(chan error, 1)}
+
+	var decode func() (io.Reader, resampler.Format, func(), error)
+	switch normalizeAudioMIME(mimeType) {
+	case audioPCM:
+		rate, channels := c.pcmFormat(mimeType)
+		decode = func() (io.Reader, resampler.Format, func(), error) {
+			return pr, resampler.Format{SampleRate: rate, Channels: channels}, func() {}, nil
+		}
+	case audioOpus:
+		dec, srcFmt, err := c.newOpusDecoder()
+		if err != nil {
+			return nil, err
+		}
+		conv.opusDec = dec
+		decode = func() (io.Reader, resampler.Format, func(), error) {
+			return pr, srcFmt, func() {}, nil
+		}
+	case audioOgg:
+		decode = func() (io.Reader, resampler.Format, func(), error) {
+			return c.decodeOgg(pr)
+		}
+	case audioMP3:
+		decode = func() (io.Reader, resampler.Format, func(), error) {
+			return decodeMP3(pr)
+		}
+	}
+
+	go func() {
+		err := c.convert(decode, first.Role, first.Name, output)
+		// Unblock write if the conversion failed early
+		pr.CloseWithError(err)
+		conv.done <- err
+	}()
+	return conv, nil
+}
+
+// write feeds input audio to the converter.
+func (conv *audioConverter) write(data []byte) error {
+	if conv.opusDec != nil {
+		pcm, err := conv.opusDec.Decode(opus.Frame(data))
+		if err != nil {
+			return fmt.Errorf("audio convert: decode opus: %w", err)
+		}
+		data = pcm
+	}
+	if _, err := conv.pw.Write(data); err != nil {
+		// The conversion ended; report why
+		if werr := conv.wait(); werr != nil {
+			return werr
+		}
+		return fmt.Errorf("audio convert: %w", err)
+	}
+	return nil
+}
+
+// finish ends the input and waits until all output is pushed.
+func (conv *audioConverter) finish() error {
+	conv.pw.Close()
+	return conv.wait()
+}
+
+// abort stops the converter, discarding pending output.
+func (conv *audioConverter) abort() {
+	conv.pw.CloseWithError(io.ErrClosedPipe)
+	conv.wait()
+}
+
+func (conv *audioConverter) wait() error {
+	if !conv.finished {
+		conv.finished = true
+		conv.err = <-conv.done
+		if conv.opusDec != nil {
+			conv.opusDec.Close()
+		}
+	}
+	return conv.err
+}
+
+// convert runs the conversion of one sub-stream until its input ends.
+func (c *AudioConvert) convert(decode func() (io.Reader, resampler.Format, func(), error), role genx.Role, name string, output *bufferStream) error {
+	pcm, srcFmt, closeDecoder, err := decode()
+	if err == io.EOF {
+		return nil // no audio
+	}
+	if err != nil {
+		return err
+	}
+	defer closeDecoder()
+
+	dstFmt := resampler.Format{SampleRate: c.sampleRate, Channels: c.channels}
+	if srcFmt != dstFmt {
+		rs, err := resampler.New(pcm, srcFmt, dstFmt)
+		if err != nil {
+			return fmt.Errorf("audio convert: %w", err)
+		}
+		defer rs.Close()
+		pcm = rs
+	}
+
+	enc, err := c.newEncoder(func(data []byte) error {
+		return output.Push(&genx.MessageChunk{
+			Role: role,
+			Name: name,
+			Part: &genx.Blob{MIMEType: c.mime, Data: data},
+		})
+	})
+	if err != nil {
+		return err
+	}
+	defer enc.close()
+
+	frameSize := c.sampleRate * 20 / 1000 // samples per channel
+	frame := make([]byte, frameSize*c.channels*2)
+	for {
+		n, err := io.ReadFull(pcm, frame)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("audio convert: %w", err)
+		}
+		if err := enc.write(frame[:n], frameSize); err != nil {
+			return fmt.Errorf("audio convert: %w", err)
+		}
+		if err == io.ErrUnexpectedEOF {
+			break
+		}
+	}
+	if err := enc.flush(); err != nil {
+		return fmt.Errorf("audio convert: %w", err)
+	}
+	return nil
+}
+
+// newOpusDecoder creates a decoder for Opus input, decoding at the target
+// rate if Opus supports it and at 48kHz otherwise.
+func (c *AudioConvert) newOpusDecoder() (*opus.Decoder, resampler.Format, error) {
+	rate := c.sampleRate
+	if !isOpusRate(rate) {
+		rate = 48000
+	}
+	channels := min(max(c.channels, 1), 2)
+	dec, err := opus.NewDecoder(rate, channels)
+	if err != nil {
+		return nil, resampler.Format{}, fmt.Errorf("audio convert: opus decoder: %w", err)
+	}
+	return dec, resampler.Format{SampleRate: rate, Channels: channels}, nil
+}
+
+// decodeOgg decodes Ogg Opus from r.
+func (c *AudioConvert) decodeOgg(r io.Reader) (io.Reader, resampler.Format, func(), error) {
+	dec, srcFmt, err := c.newOpusDecoder()
+	if err != nil {
+		return nil, resampler.Format{}, nil, err
+	}
+	next, stop := iter.Pull2(ogg.ReadOpusPackets(r))
+	return &oggPCMReader{next: next, dec: dec}, srcFmt, func() {
+		stop()
+		dec.Close()
+	}, nil
+}
+
+// oggPCMReader reads PCM decoded from Ogg Opus packets.
+type oggPCMReader struct {
+	next func() (*ogg.OpusPacket, error, bool)
+	dec  *opus.Decoder
+	buf  []byte
+}
+
+func (r *oggPCMReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		pkt, err, ok := r.next()
+		if !ok {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, fmt.Errorf("read ogg: %w", err)
+		}
+		pcm, err := r.dec.Decode(pkt.Frame)
+		if err != nil {
+			return 0, fmt.Errorf("decode opus: %w", err)
+		}
+		r.buf = pcm
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// decodeMP3 decodes MP3 from r. The format is read from the first frame;
+// it returns io.EOF if there is none.
+func decodeMP3(r io.Reader) (io.Reader, resampler.Format, func(), error) {
+	dec := mp3.NewDecoder(r)
+	head := make([]byte, 4096)
+	n, err := dec.Read(head)
+	if n == 0 {
+		dec.Close()
+		if err == nil || err == io.EOF {
+			return nil, resampler.Format{}, nil, io.EOF
+		}
+		return nil, resampler.Format{}, nil, fmt.Errorf("audio convert: decode mp3: %w", err)
+	}
+	srcFmt := resampler.Format{SampleRate: dec.SampleRate(), Channels: dec.Channels()}
+	return io.MultiReader(bytes.NewReader(head[:n]), dec), srcFmt, func() { dec.Close() }, nil
+}
+
+// audioEncoder encodes 20ms PCM frames in the target format.
+type audioEncoder interface {
+	// write encodes a frame of frameSize samples per channel. The last
+	// frame may be shorter.
+	write(pcm []byte, frameSize int) error
+	// flush emits buffered output at the end of the sub-stream.
+	flush() error
+	close()
+}
+
+// newEncoder creates the encoder of the target type, calling emit with
+// the data of each output chunk.
+func (c *AudioConvert) newEncoder(emit func([]byte) error) (audioEncoder, error) {
+	switch c.mime {
+	case audioPCM:
+		return pcmEncoder(emit), nil
+	case audioOpus, audioOgg:
+		enc, err := opus.NewVoIPEncoder(c.sampleRate, c.channels)
+		if err != nil {
+			return nil, fmt.Errorf("audio convert: opus encoder: %w", err)
+		}
+		bitrate := c.bitrate
+		if bitrate <= 0 {
+			bitrate = defaultOpusBitrate
+		}
+		if err := enc.SetBitrate(bitrate); err != nil {
+			enc.Close()
+			return nil, fmt.Errorf("audio convert: set bitrate: %w", err)
+		}
+		oe := &opusEncoder{enc: enc, emit: emit}
+		if c.mime == audioOgg {
+			oe.ogg, err = ogg.NewOpusWriter(emitWriter(emit), c.sampleRate, c.channels)
+			if err != nil {
+				enc.Close()
+				return nil, fmt.Errorf("audio convert: ogg writer: %w", err)
+			}
+		}
+		return oe, nil
+	case audioMP3:
+		var opts []mp3.EncoderOption
+		if c.bitrate > 0 {
+			opts = append(opts, mp3.WithBitrate(c.bitrate/1000))
+		}
+		enc, err := mp3.NewEncoder(emitWriter(emit), c.sampleRate, c.channels, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("audio convert: %w", err)
+		}
+		return &mp3Encoder{enc: enc}, nil
+	}
+	return nil, fmt.Errorf("audio convert: unsupported target type %q", c.mime)
+}
+
+// emitWriter emits a copy of each write.
+type emitWriter func([]byte) error
+
+func (w emitWriter) Write(p []byte) (int, error) {
+	if err := w(bytes.Clone(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// pcmEncoder emits PCM frames as they are.
+type pcmEncoder func([]byte) error
+
+func (e pcmEncoder) write(pcm []byte, _ int) error {
+	return e(bytes.Clone(pcm))
+}
+
+func (e pcmEncoder) flush() error { return nil }
+func (e pcmEncoder) close()       {}
+
+// opusEncoder emits Opus frames, raw or in an Ogg container.
+type opusEncoder struct {
+	enc  *opus.Encoder
+	emit func([]byte) error
+	ogg  *ogg.OpusWriter
+}
+
+func (e *opusEncoder) write(pcm []byte, frameSize int) error {
+	if want := frameSize * e.enc.Channels() * 2; len(pcm) < want {
+		// Pad the last frame with silence
+		pcm = append(bytes.Clone(pcm), make([]byte, want-len(pcm))...)
+	}
+	frame, err := e.enc.EncodeBytes(pcm, frameSize)
+	if err != nil {
+		return err
+	}
+	if e.ogg != nil {
+		return e.ogg.Write(frame)
+	}
+	return e.emit(frame)
+}
+
+func (e *opusEncoder) flush() error {
+	if e.ogg != nil {
+		return e.ogg.Close()
+	}
+	return nil
+}
+
+func (e *opusEncoder) close() {
+	e.enc.Close()
+}
+
+// mp3Encoder emits MP3 data as LAME produces it.
+type mp3Encoder struct {
+	enc *mp3.Encoder
+}
+
+func (e *mp3Encoder) write(pcm []byte, _ int) error {
+	_, err := e.enc.Write(pcm)
+	return err
+}
+
+func (e *mp3Encoder) flush() error {
+	return e.enc.Flush()
+}
+
+func (e *mp3Encoder) close() {
+	e.enc.Close()
+}
@@ -0,0 +1,146 @@
+package transformers
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// sinePCM returns 16-bit mono PCM of a 440Hz sine at rate.
+func sinePCM(rate int, d float64) []byte {
+	n := int(float64(rate) * d)
+	pcm := make([]byte, n*2)
+	for i := range n {
+		v := 0.5 * math.Sin(2*math.Pi*440*float64(i)/float64(rate))
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(int16(v*math.MaxInt16)))
+	}
+	return pcm
+}
+
+// pcmChunks splits pcm into 20ms chunks of mimeType, ended by its EoS.
+func pcmChunks(pcm []byte, mimeType string, bytesPer20ms int) []*genx.MessageChunk {
+	var chunks []*genx.MessageChunk
+	for len(pcm) > 0 {
+		n := min(len(pcm), bytesPer20ms)
+		chunks = append(chunks, &genx.MessageChunk{Role: genx.RoleModel, Part: &genx.Blob{MIMEType: mimeType, Data: pcm[:n]}})
+		pcm = pcm[n:]
+	}
+	eos := genx.NewEndOfStream(mimeType)
+	eos.Role = genx.RoleModel
+	return append(chunks, eos)
+}
+
+// convertAudio runs chunks through c and returns the output chunks.
+func convertAudio(t *testing.T, c *AudioConvert, chunks ...*genx.MessageChunk) []*genx.MessageChunk {
+	t.Helper()
+	out, err := c.Transform(context.Background(), "", chunkInput(chunks...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []*genx.MessageChunk
+	for {
+		chunk, err := out.Next()
+		if err != nil {
+			return got
+		}
+		got = append(got, chunk)
+	}
+}
+
+// joinAudio returns the data of the audio chunks before the final EoS,
+// which must be of mimeType.
+func joinAudio(t *testing.T, chunks []*genx.MessageChunk, mimeType string) []byte {
+	t.Helper()
+	if len(chunks) == 0 || !chunks[len(chunks)-1].IsEndOfStream() {
+		t.Fatalf("output of %d chunks does not end with EoS", len(chunks))
+	}
+	var data []byte
+	for _, chunk := range chunks {
+		blob := chunk.Part.(*genx.Blob)
+		if blob.MIMEType != mimeType || chunk.Role != genx.RoleModel {
+			t.Fatalf("output chunk %s %q, want %s %q", chunk.Role, blob.MIMEType, genx.RoleModel, mimeType)
+		}
+		data = append(data, blob.Data...)
+	}
+	return data
+}
+
+func samples(pcm []byte) []float64 {
+	s := make([]float64, len(pcm)/2)
+	for i := range s {
+		s[i] = float64(int16(binary.LittleEndian.Uint16(pcm[i*2:])))
+	}
+	return s
+}
+
+func TestAudioConvert_PCMFormat(t *testing.T) {
+	src := sinePCM(16000, 1)
+	c := NewAudioConvert("audio/pcm", 8000, 2)
+	out := joinAudio(t, convertAudio(t, c, pcmChunks(src, "audio/pcm;rate=16000;channels=1", 640)...), "audio/pcm")
+
+	// 1s of 8kHz stereo, give or take the resampler's edges
+	if want := 8000 * 2 * 2; math.Abs(float64(len(out)-want)) > float64(want)/100 {
+		t.Errorf("output %d bytes, want about %d", len(out), want)
+	}
+	s := samples(out)
+	for i := 0; i+1 < len(s); i += 2 {
+		if s[i] != s[i+1] {
+			t.Fatalf("frame %d: channels differ: %v, %v", i/2, s[i], s[i+1])
+		}
+	}
+}
+
+func TestAudioConvert_PCMRoundTrip(t *testing.T) {
+	src := sinePCM(16000, 1)
+	up := NewAudioConvert("audio/pcm", 24000, 2)
+	down := NewAudioConvert("audio/pcm", 16000, 1, WithAudioConvertPCMFormat(24000, 2))
+	mid := convertAudio(t, up, pcmChunks(src, "audio/pcm", 640)...)
+	out := joinAudio(t, convertAudio(t, down, mid...), "audio/pcm")
+
+	if math.Abs(float64(len(out)-len(src))) > float64(len(src))/50 {
+		t.Fatalf("round trip gives %d bytes, want about %d", len(out), len(src))
+	}
+	// Compare away from the edges, allowing for the filter delay of the
+	// two resamplers (about 22ms)
+	want, got := samples(src), samples(out)
+	best := math.Inf(1)
+	for lag := 0; lag <= 800; lag++ {
+		var noise, signal float64
+		for i := 1000; i < min(len(want), len(got))-1000; i++ {
+			d := got[i+lag] - want[i]
+			noise += d * d
+			signal += want[i] * want[i]
+		}
+		best = min(best, noise/signal)
+	}
+	if snr := -10 * math.Log10(best); snr < 30 {
+		t.Errorf("round trip SNR = %.1fdB, want >= 30dB", snr)
+	}
+}
+
+func TestAudioConvert_PassThrough(t *testing.T) {
+	pcm := &genx.MessageChunk{Role: genx.RoleModel, Part: &genx.Blob{MIMEType: "audio/pcm;rate=16000", Data: []byte{1, 2}}}
+	l16 := &genx.MessageChunk{Role: genx.RoleModel, Part: &genx.Blob{MIMEType: "audio/L16", Data: []byte{3, 4}}}
+	other := &genx.MessageChunk{Role: genx.RoleModel, Part: &genx.Blob{MIMEType: "audio/x-flac", Data: []byte{5}}}
+	text := modelText("hi")
+	eos := genx.NewEndOfStream("audio/pcm")
+
+	in := []*genx.MessageChunk{pcm, l16, other, text, eos}
+	got := convertAudio(t, NewAudioConvert("audio/pcm", 16000, 1), in...)
+	if len(got) != len(in) {
+		t.Fatalf("got %d chunks, want %d", len(got), len(in))
+	}
+	for i := range in {
+		if got[i] != in[i] {
+			t.Errorf("chunk %d: got %s, want %s unchanged", i, describeChunk(got[i]), describeChunk(in[i]))
+		}
+	}
+
+	mp3 := &genx.MessageChunk{Role: genx.RoleModel, Part: &genx.Blob{MIMEType: "audio/mpeg", Data: []byte{0xff, 0xfb}}}
+	if got := convertAudio(t, NewAudioConvert("audio/mp3", 24000, 1), mp3); len(got) != 1 || got[0] != mp3 {
+		t.Errorf("mp3 to mp3: got %d chunks, want the input chunk", len(got))
+	}
+}
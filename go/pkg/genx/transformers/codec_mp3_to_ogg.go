@@ -116,6 +116,7 @@ func (c *MP3ToOgg) transformLoop(input genx.Stream, out *buffer.Buffer[*genx.Mes
 						return
 					}
 				}
+				chunk.Release()
 				// Emit OGG EoS
 				eosChunk := genx.NewEndOfStream("audio/ogg")
 				if lastChunk != nil {
@@ -139,6 +140,8 @@ func (c *MP3ToOgg) transformLoop(input genx.Stream, out *buffer.Buffer[*genx.Mes
 		if ok && (blob.MIMEType == "audio/mp3" || blob.MIMEType == "audio/mpeg") {
 			// Collect MP3 data
 			mp3Data.Write(blob.Data)
+			// Only the last chunk is kept, for its role and name
+			lastChunk.Release()
 			lastChunk = chunk
 		} else {
 			// Pass through non-MP3 chunks
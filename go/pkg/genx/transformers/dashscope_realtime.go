@@ -17,7 +17,8 @@ import (
 // Model: qwen-omni-turbo-realtime-latest (default) or qwen3-omni-flash-realtime
 //
 // This is a bidirectional transformer:
-// Input: genx.Stream with audio Blob chunks (PCM16 16kHz)
+// Input: genx.Stream with audio Blob chunks (PCM16 16kHz), and optionally
+// JPEG images (ImagePart or VideoFramePart) for visual input
 // Output: genx.Stream with audio Blob chunks (PCM16 24kHz)
 //
 // Internally uses Qwen-Omni model for speech-to-speech.
@@ -615,6 +616,17 @@ func (t *DashScopeRealtime) processLoop(input genx.Stream, output *bufferStream,
 			_ = session.CancelResponse()
 		}
 
+		// Send images (e.g. camera frames) for Qwen-Omni visual input
+		if img, ok := genx.AsImage(chunk.Part); ok {
+			if len(img.Data) > 0 {
+				if err := session.AppendImage(img.Data); err != nil {
+					output.CloseWithError(err)
+					return
+				}
+			}
+			continue
+		}
+
 		// Collect audio blob into buffer
 		if blob, ok := chunk.Part.(*genx.Blob); ok {
 			audioBuffer = append(audioBuffer, blob.Data...)
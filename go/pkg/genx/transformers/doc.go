@@ -21,6 +21,49 @@
 // MiniMax:
 //   - MinimaxTTS: MiniMax text-to-speech
 //
+// Codecs:
+//   - AudioConvert: converts between PCM, Opus, Ogg and MP3 with sample
+//     rate and channel conversion
+//   - MP3ToOgg: converts MP3 to Ogg Opus
+//
+// Style:
+//   - MetaTTSStyle: emotion or speaking style (e.g., happy, whisper) that
+//     agents set on a sub-stream of text; the TTS transformers translate it
+//     into the parameters of their backend
+//
+// Subtitles:
+//   - SubtitleCollector: assembles SRT or WebVTT (with karaoke word cues)
+//     from the sentence and word timings TTS transformers attach under
+//     MetaTTSTiming
+//
+// Analysis (pass-through, annotate chunks):
+//   - Voiceprint: speaker identification via Ctrl.Label
+//   - Emotion: user emotion via Ctrl.Metadata (ONNX prosody model)
+//   - Watermark: marks model audio as synthetic (spread-spectrum + Ctrl.Metadata)
+//
+// Safety:
+//   - Moderation: masks blocklisted words and replaces text flagged by a
+//     moderation API before it reaches TTS
+//
+// Conversation:
+//   - VAD: local voice activity detection (Silero ONNX model or energy),
+//     delimiting user audio into speech segments with BOS/EoS markers
+//   - TurnManager: arbitrates overlapping user and model speech in
+//     full-duplex pipelines (polite, assertive, hard barge-in)
+//   - StableASR: drops interim ASR results (MetaASR), so agents act only
+//     on stable text while captions render the live hypotheses
+//   - ContinuousASR: transcribes hours-long input (e.g., meeting recorders)
+//     by rotating the backend sessions of an ASR transformer at pauses,
+//     before the session limits of the vendor (ASR.CreateContinuous)
+//
+// Routing:
+//   - LanguageRouter: detects the user's language and routes each
+//     sub-stream to a transformer for it (e.g., zh or en TTS voice)
+//
+// Resilience:
+//   - Fallback: answers user turns with cached TTS responses when the
+//     wrapped transformer fails (e.g., offline)
+//
 // # Lifecycle
 //
 // All transformers in this package follow the genx.Transformer lifecycle contract:
@@ -59,6 +102,30 @@
 //
 //	output := transformers.Transform(ctx, "tts/cancan", textStream)
 //
+// Route a pattern to backup transformers, with circuit breaking and
+// Prometheus counters (Mux.WritePrometheus), so that an outage of one
+// backend falls back to the next:
+//
+//	transformers.HandleFailover("tts/primary", []transformers.FailoverTarget{
+//	    {Pattern: "tts/minimax"},
+//	    {Pattern: "tts/doubao"},
+//	})
+//
+// # Connection Pooling
+//
+// The WebSocket backends dial a connection per Transform call (per
+// utterance for ASR), which adds 200-400ms. With a PoolConfig, they keep
+// connections dialed ahead, health-checked and replaced when idle too long:
+//
+//	asr := NewDoubaoASRSAUC(client, WithDoubaoASRSAUCPool(transformers.PoolConfig{Size: 2}))
+//	defer asr.Close()
+//
+// The services allow one session per connection, so a pooled connection
+// serves one call and the pool refills in the background. PoolStats counts
+// hits and misses (ReuseRate), and WritePoolPrometheus exports them. The
+// HTTP backends (Doubao TTS, MiniMax TTS) already reuse connections
+// through the keep-alive of their http.Client.
+//
 // # Options
 //
 // Each transformer supports two types of configuration:
@@ -70,8 +137,17 @@
 //	    WithDoubaoTTSSeedV2SampleRate(24000),
 //	)
 //
-// 2. Runtime options (via context, for future use):
+// 2. Runtime options (via context), overriding construction-time options
+// for one Transform call:
 //
-//	ctx := WithDoubaoTTSSeedV2CtxOptions(ctx, DoubaoTTSSeedV2CtxOptions{})
+//	ctx := WithDoubaoTTSICLV2CtxOptions(ctx, DoubaoTTSICLV2CtxOptions{
+//	    Speaker: "S_xxxxxx",
+//	})
 //	output := tts.Transform(ctx, input)
+//
+// Some streams can also be reconfigured mid-conversation, see
+// DashScopeStream.Update and DoubaoTTSICLV2Stream.Update:
+//
+//	speaker := "S_yyyyyy"
+//	output.(*DoubaoTTSICLV2Stream).Update(&DoubaoTTSICLV2Update{Speaker: &speaker})
 package transformers
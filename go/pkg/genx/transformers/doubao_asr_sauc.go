@@ -19,6 +19,24 @@ import (
 //   - When receiving an audio/* EoS marker, finish current ASR, emit results, then emit text/plain EoS
 //   - Non-audio chunks are passed through unchanged
 //
+// Speaker Diarization:
+//   - With WithDoubaoASRSAUCDiarization, each utterance's speaker turn label
+//     is mapped to the output chunk's Name ("speaker:<id>" by default, see
+//     WithDoubaoASRSAUCSpeakerName), so one microphone yields a
+//     multi-speaker transcript
+//   - Utterances without a speaker label keep the input chunk's Name
+//
+// Interim Results:
+//   - With WithDoubaoASRSAUCInterim, unstable hypotheses are emitted as they
+//     are recognized, marked MetaASR "interim"; the definite text follows
+//     as "final", after a "correction" chunk retracting the interims
+//   - Use StableASR to pass only stable text to agents
+//
+// Connection Pooling:
+//   - Each utterance needs its own session, and opening one dials a
+//     WebSocket; with WithDoubaoASRSAUCPool, sessions are opened ahead and
+//     an utterance takes one from the pool. Call Close to close the pool
+//
 // Note: The input audio format must match the configured format.
 type DoubaoASRSAUC struct {
 	client     *doubaospeech.Client
@@ -31,6 +49,14 @@ type DoubaoASRSAUC struct {
 	enablePunc bool
 	hotwords   []string
 	resultType string // "single" (default) or "full"
+	interim    bool
+
+	diarization bool
+	speakerNum  int
+	speakerName func(speakerID string) string
+
+	poolConfig PoolConfig
+	pool       *connPool[*doubaospeech.ASRV2Session]
 }
 
 var _ genx.Transformer = (*DoubaoASRSAUC)(nil)
@@ -102,6 +128,45 @@ func WithDoubaoASRSAUCResultType(resultType string) DoubaoASRSAUCOption {
 	}
 }
 
+// WithDoubaoASRSAUCInterim enables interim results, for live captions.
+// It sets the result type to "full". See IsUnstableASR.
+func WithDoubaoASRSAUCInterim(enable bool) DoubaoASRSAUCOption {
+	return func(t *DoubaoASRSAUC) {
+		t.interim = enable
+		if enable {
+			t.resultType = "full"
+		}
+	}
+}
+
+// WithDoubaoASRSAUCDiarization enables speaker diarization. speakerNum is
+// the expected number of speakers, or 0 to let the service decide.
+func WithDoubaoASRSAUCDiarization(speakerNum int) DoubaoASRSAUCOption {
+	return func(t *DoubaoASRSAUC) {
+		t.diarization = true
+		t.speakerNum = speakerNum
+	}
+}
+
+// WithDoubaoASRSAUCSpeakerName sets how a diarization speaker ID is mapped
+// to the output chunk's Name. Default: "speaker:<id>".
+func WithDoubaoASRSAUCSpeakerName(fn func(speakerID string) string) DoubaoASRSAUCOption {
+	return func(t *DoubaoASRSAUC) {
+		if fn != nil {
+			t.speakerName = fn
+		}
+	}
+}
+
+// WithDoubaoASRSAUCPool keeps cfg.Size sessions open for the next
+// utterances. An idle session counts toward the session duration billed by
+// the service, so keep the pool small.
+func WithDoubaoASRSAUCPool(cfg PoolConfig) DoubaoASRSAUCOption {
+	return func(t *DoubaoASRSAUC) {
+		t.poolConfig = cfg
+	}
+}
+
 // NewDoubaoASRSAUC creates a new DoubaoASRSAUC transformer.
 //
 // Parameters:
@@ -118,13 +183,39 @@ func NewDoubaoASRSAUC(client *doubaospeech.Client, opts ...DoubaoASRSAUCOption)
 		enableITN:  true,
 		enablePunc: true,
 		resultType: "single", // only definite results
+		speakerName: func(speakerID string) string {
+			return "speaker:" + speakerID
+		},
 	}
 	for _, opt := range opts {
 		opt(t)
 	}
+	if t.poolConfig.Size > 0 {
+		t.pool = newConnPool(t.poolConfig, t.openSession,
+			(*doubaospeech.ASRV2Session).Ping,
+			func(s *doubaospeech.ASRV2Session) { s.Close() })
+	}
 	return t
 }
 
+// PoolStats returns the counters of the session pool, or zero stats
+// without WithDoubaoASRSAUCPool.
+func (t *DoubaoASRSAUC) PoolStats() PoolStats {
+	if t.pool == nil {
+		return PoolStats{}
+	}
+	return t.pool.Stats()
+}
+
+// Close closes the idle sessions of the pool. Running transforms are not
+// affected, but later ones open their sessions on demand.
+func (t *DoubaoASRSAUC) Close() error {
+	if t.pool != nil {
+		t.pool.Close()
+	}
+	return nil
+}
+
 // DoubaoASRSAUCCtxKey is the context key for runtime options.
 type doubaoASRSAUCCtxKey struct{}
 
@@ -167,7 +258,7 @@ func (t *DoubaoASRSAUC) transformLoop(input genx.Stream, output *bufferStream) {
 	// Helper to start a new ASR session
 	startSession := func() error {
 		var err error
-		session, err = t.openSession(ctx)
+		session, err = t.takeSession(ctx)
 		if err != nil {
 			return err
 		}
@@ -219,12 +310,17 @@ func (t *DoubaoASRSAUC) transformLoop(input genx.Stream, output *bufferStream) {
 			continue
 		}
 
-		lastChunk = chunk
+		// Keep the role and name for the results; audio chunks are
+		// released once sent
+		if lastChunk == nil || lastChunk.Role != chunk.Role || lastChunk.Name != chunk.Name {
+			lastChunk = &genx.MessageChunk{Role: chunk.Role, Name: chunk.Name}
+		}
 
 		// Check for EoS marker with audio MIME type
 		if chunk.IsEndOfStream() {
 			if blob, ok := chunk.Part.(*genx.Blob); ok && isAudioMIME(blob.MIMEType) {
 				// Audio EoS: finish current session, emit text EoS
+				chunk.Release()
 				if err := finishSession(); err != nil {
 					output.CloseWithError(err)
 					return
@@ -259,6 +355,7 @@ func (t *DoubaoASRSAUC) transformLoop(input genx.Stream, output *bufferStream) {
 				output.CloseWithError(err)
 				return
 			}
+			chunk.Release()
 		} else {
 			// Non-audio chunk: pass through
 			if err := output.Push(chunk); err != nil {
@@ -268,6 +365,15 @@ func (t *DoubaoASRSAUC) transformLoop(input genx.Stream, output *bufferStream) {
 	}
 }
 
+// takeSession returns a session from the pool, or opens one.
+func (t *DoubaoASRSAUC) takeSession(ctx context.Context) (*doubaospeech.ASRV2Session, error) {
+	if t.pool == nil {
+		return t.openSession(ctx)
+	}
+	session, _, err := t.pool.get(ctx)
+	return session, err
+}
+
 func (t *DoubaoASRSAUC) openSession(ctx context.Context) (*doubaospeech.ASRV2Session, error) {
 	config := &doubaospeech.ASRV2Config{
 		Format:     t.format,
@@ -279,6 +385,9 @@ func (t *DoubaoASRSAUC) openSession(ctx context.Context) (*doubaospeech.ASRV2Ses
 		EnablePunc: t.enablePunc,
 		Hotwords:   t.hotwords,
 		ResultType: t.resultType,
+
+		EnableDiarization: t.diarization,
+		SpeakerNum:        t.speakerNum,
 	}
 	return t.client.ASRV2.OpenStreamSession(ctx, config)
 }
@@ -286,8 +395,20 @@ func (t *DoubaoASRSAUC) openSession(ctx context.Context) (*doubaospeech.ASRV2Ses
 func (t *DoubaoASRSAUC) receiveResults(session *doubaospeech.ASRV2Session, lastChunk *genx.MessageChunk, resultsCh chan<- *genx.MessageChunk, done chan<- error) {
 	defer close(resultsCh)
 
+	emit := func(outChunk *genx.MessageChunk, speakerID string) {
+		if lastChunk != nil {
+			outChunk.Role = lastChunk.Role
+			outChunk.Name = lastChunk.Name
+		}
+		if t.diarization && speakerID != "" {
+			outChunk.Name = t.speakerName(speakerID)
+		}
+		resultsCh <- outChunk
+	}
+
 	// Track processed utterances by end time to avoid duplicates
 	lastEndTime := 0
+	var segments asrSegments
 
 	for result, err := range session.Recv() {
 		if err != nil {
@@ -297,30 +418,50 @@ func (t *DoubaoASRSAUC) receiveResults(session *doubaospeech.ASRV2Session, lastC
 
 		// Process definite utterances from the utterances array
 		if len(result.Utterances) > 0 {
+			// The text after the last definite utterance is the interim
+			// hypothesis
+			var interim, interimSpeaker string
 			for _, utt := range result.Utterances {
-				if utt.Definite && utt.EndTime > lastEndTime && utt.Text != "" {
-					outChunk := &genx.MessageChunk{
-						Part: genx.Text(utt.Text),
-					}
-					if lastChunk != nil {
-						outChunk.Role = lastChunk.Role
-						outChunk.Name = lastChunk.Name
+				if !utt.Definite {
+					interim += utt.Text
+					interimSpeaker = utt.SpeakerID
+					continue
+				}
+				if utt.EndTime > lastEndTime && utt.Text != "" {
+					if t.interim {
+						for _, c := range segments.finalChunks(utt.Text) {
+							emit(c, utt.SpeakerID)
+						}
+					} else {
+						emit(&genx.MessageChunk{Part: genx.Text(utt.Text)}, utt.SpeakerID)
 					}
-					resultsCh <- outChunk
 					lastEndTime = utt.EndTime
 				}
 			}
+			if t.interim {
+				if c := segments.interimChunk(interim); c != nil {
+					emit(c, interimSpeaker)
+				}
+			}
 		} else if result.IsFinal && result.Text != "" {
-			outChunk := &genx.MessageChunk{
-				Part: genx.Text(result.Text),
+			if t.interim {
+				for _, c := range segments.finalChunks(result.Text) {
+					emit(c, "")
+				}
+			} else {
+				emit(&genx.MessageChunk{Part: genx.Text(result.Text)}, "")
 			}
-			if lastChunk != nil {
-				outChunk.Role = lastChunk.Role
-				outChunk.Name = lastChunk.Name
+		} else if t.interim {
+			if c := segments.interimChunk(result.Text); c != nil {
+				emit(c, "")
 			}
-			resultsCh <- outChunk
 		}
 	}
+	// Interim text that never became definite is retracted, so captions
+	// do not keep it
+	if c := segments.retract(); c != nil {
+		emit(c, "")
+	}
 	done <- nil
 }
 
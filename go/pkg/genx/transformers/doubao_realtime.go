@@ -20,6 +20,9 @@ import (
 // Output: genx.Stream with audio Blob chunks (model response)
 //
 // Internally uses ASR → LLM → TTS pipeline.
+//
+// With WithDoubaoRealtimePool, connections are dialed ahead and Transform
+// only starts a session on one, saving the WebSocket handshake.
 type DoubaoRealtime struct {
 	client            *doubaospeech.Client
 	speaker           string
@@ -32,6 +35,9 @@ type DoubaoRealtime struct {
 	speakingStyle     string
 	characterManifest string
 	model             string // Model version: O, SC, 1.2.1.0 (O2.0), 2.2.0.0 (SC2.0)
+
+	poolConfig PoolConfig
+	pool       *connPool[*doubaospeech.RealtimeConnection]
 }
 
 var _ genx.Transformer = (*DoubaoRealtime)(nil)
@@ -112,6 +118,14 @@ func WithDoubaoRealtimeModel(model string) DoubaoRealtimeOption {
 	}
 }
 
+// WithDoubaoRealtimePool keeps cfg.Size connections open for the next
+// Transform calls. A connection hosts one session and is closed after it.
+func WithDoubaoRealtimePool(cfg PoolConfig) DoubaoRealtimeOption {
+	return func(t *DoubaoRealtime) {
+		t.poolConfig = cfg
+	}
+}
+
 // NewDoubaoRealtime creates a new DoubaoRealtime transformer.
 //
 // Parameters:
@@ -131,9 +145,32 @@ func NewDoubaoRealtime(client *doubaospeech.Client, opts ...DoubaoRealtimeOption
 	for _, opt := range opts {
 		opt(t)
 	}
+	if t.poolConfig.Size > 0 {
+		t.pool = newConnPool(t.poolConfig, t.client.Realtime.Dial,
+			(*doubaospeech.RealtimeConnection).Ping,
+			func(c *doubaospeech.RealtimeConnection) { c.Close() })
+	}
 	return t
 }
 
+// PoolStats returns the counters of the connection pool, or zero stats
+// without WithDoubaoRealtimePool.
+func (t *DoubaoRealtime) PoolStats() PoolStats {
+	if t.pool == nil {
+		return PoolStats{}
+	}
+	return t.pool.Stats()
+}
+
+// Close closes the idle connections of the pool. Running transforms are
+// not affected, but later ones dial their connections on demand.
+func (t *DoubaoRealtime) Close() error {
+	if t.pool != nil {
+		t.pool.Close()
+	}
+	return nil
+}
+
 // DoubaoRealtimeCtxKey is the context key for runtime options.
 type doubaoRealtimeCtxKey struct{}
 
@@ -176,7 +213,7 @@ func (t *DoubaoRealtime) Transform(ctx context.Context, _ string, input genx.Str
 	}
 
 	// Connect to realtime service (synchronous)
-	session, err := t.client.Realtime.Connect(ctx, config)
+	session, err := t.connect(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("doubao realtime connect: %w", err)
 	}
@@ -187,9 +224,31 @@ func (t *DoubaoRealtime) Transform(ctx context.Context, _ string, input genx.Str
 	return output, nil
 }
 
+// connect starts a session on a pooled connection, or on a new one.
+func (t *DoubaoRealtime) connect(ctx context.Context, config *doubaospeech.RealtimeConfig) (*doubaospeech.RealtimeSession, error) {
+	if t.pool == nil {
+		return t.client.Realtime.Connect(ctx, config)
+	}
+	conn, pooled, err := t.pool.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session, err := conn.StartSession(ctx, config)
+	if err != nil {
+		conn.Close()
+		if pooled {
+			// The server may have dropped the idle connection.
+			return t.client.Realtime.Connect(ctx, config)
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
 func (t *DoubaoRealtime) processLoop(input genx.Stream, output *bufferStream, session *doubaospeech.RealtimeSession) {
 	defer output.Close()
-	defer session.Close()
+	// Closing the connection closes the session too.
+	defer session.Connection().Close()
 
 	// StreamID management - queue for correlating input to output
 	var streamIDMu sync.Mutex
@@ -1,9 +1,12 @@
 package transformers
 
 import (
+	"cmp"
 	"context"
+	"fmt"
 	"io"
 	"strings"
+	"sync"
 
 	"github.com/haivivi/giztoy/go/pkg/doubaospeech"
 	"github.com/haivivi/giztoy/go/pkg/genx"
@@ -21,6 +24,10 @@ import (
 // EoS Handling:
 //   - When receiving a text/plain EoS marker, finish synthesis, emit audio chunks, then emit audio/* EoS
 //   - Non-text chunks are passed through unchanged
+//
+// Style:
+//   - The MetaTTSStyle of a sub-stream (e.g., happy, whisper) overrides
+//     the emotion of the stream for its synthesis
 type DoubaoTTSICLV2 struct {
 	client      *doubaospeech.Client
 	speaker     string
@@ -32,6 +39,7 @@ type DoubaoTTSICLV2 struct {
 	pitchRatio  float64
 	emotion     string
 	language    string
+	lexicon     doubaospeech.Lexicon
 }
 
 var _ genx.Transformer = (*DoubaoTTSICLV2)(nil)
@@ -95,6 +103,14 @@ func WithDoubaoTTSICLV2Language(language string) DoubaoTTSICLV2Option {
 	}
 }
 
+// WithDoubaoTTSICLV2Lexicon sets a pronunciation dictionary for names and other
+// words the voice mispronounces.
+func WithDoubaoTTSICLV2Lexicon(lexicon doubaospeech.Lexicon) DoubaoTTSICLV2Option {
+	return func(t *DoubaoTTSICLV2) {
+		t.lexicon = lexicon
+	}
+}
+
 // NewDoubaoTTSICLV2 creates a new DoubaoTTSICLV2 transformer.
 //
 // Parameters:
@@ -120,28 +136,122 @@ func NewDoubaoTTSICLV2(client *doubaospeech.Client, speaker string, opts ...Doub
 // DoubaoTTSICLV2CtxKey is the context key for runtime options.
 type doubaoTTSICLV2CtxKey struct{}
 
-// DoubaoTTSICLV2CtxOptions are runtime options passed via context.
-// TODO: Add fields as needed for runtime configuration.
-type DoubaoTTSICLV2CtxOptions struct{}
+// DoubaoTTSICLV2CtxOptions are runtime options passed via context. They
+// override the construction-time options for one Transform call; zero
+// fields keep them.
+type DoubaoTTSICLV2CtxOptions struct {
+	// Speaker is a cloned voice ID starting with "S_".
+	Speaker string
+
+	// Emotion is the emotion (happy, sad, angry, fear, hate, surprise).
+	Emotion string
+
+	// SpeedRatio is the speech speed ratio (0.2-3.0).
+	SpeedRatio float64
+}
 
 // WithDoubaoTTSICLV2CtxOptions attaches runtime options to context.
 func WithDoubaoTTSICLV2CtxOptions(ctx context.Context, opts DoubaoTTSICLV2CtxOptions) context.Context {
 	return context.WithValue(ctx, doubaoTTSICLV2CtxKey{}, opts)
 }
 
+// DoubaoTTSICLV2Stream is a Stream returned by DoubaoTTSICLV2.Transform().
+// It provides a method to switch the voice mid-conversation.
+type DoubaoTTSICLV2Stream struct {
+	*bufferStream
+
+	mu    sync.Mutex
+	voice doubaoTTSICLV2Voice
+}
+
+// doubaoTTSICLV2Voice holds the settings that can change mid-stream.
+type doubaoTTSICLV2Voice struct {
+	speaker    string
+	emotion    string
+	speedRatio float64
+}
+
+// DoubaoTTSICLV2Update contains fields that can be updated mid-stream.
+// Use pointer fields to distinguish "not set" from "set to empty".
+type DoubaoTTSICLV2Update struct {
+	// Speaker is a cloned voice ID starting with "S_".
+	Speaker *string
+
+	// Emotion is the emotion; set to "" to clear it.
+	Emotion *string
+
+	// SpeedRatio is the speech speed ratio (0.2-3.0).
+	SpeedRatio *float64
+}
+
+// Update switches the voice settings of the stream. Text already being
+// synthesized keeps the old settings; the update applies from the next
+// segment, i.e. the text after the next text/plain EoS marker.
+// Only non-nil fields are updated.
+func (s *DoubaoTTSICLV2Stream) Update(req *DoubaoTTSICLV2Update) error {
+	if req.Speaker != nil && *req.Speaker == "" {
+		return fmt.Errorf("doubao tts icl: empty speaker")
+	}
+	if req.SpeedRatio != nil && (*req.SpeedRatio < 0.2 || *req.SpeedRatio > 3.0) {
+		return fmt.Errorf("doubao tts icl: speed ratio %v out of range 0.2-3.0", *req.SpeedRatio)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.Speaker != nil {
+		s.voice.speaker = *req.Speaker
+	}
+	if req.Emotion != nil {
+		s.voice.emotion = *req.Emotion
+	}
+	if req.SpeedRatio != nil {
+		s.voice.speedRatio = *req.SpeedRatio
+	}
+	return nil
+}
+
+func (s *DoubaoTTSICLV2Stream) currentVoice() doubaoTTSICLV2Voice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.voice
+}
+
 // Transform converts Text chunks to audio Blob chunks.
 // DoubaoTTSICLV2 does not require connection setup, so it returns immediately.
-// The ctx is unused (no initialization needed); the goroutine lifetime
+// The ctx only carries DoubaoTTSICLV2CtxOptions; the goroutine lifetime
 // is governed by the input Stream.
-func (t *DoubaoTTSICLV2) Transform(_ context.Context, _ string, input genx.Stream) (genx.Stream, error) {
-	output := newBufferStream(100)
+//
+// The returned Stream is a *DoubaoTTSICLV2Stream, whose Update switches
+// the speaker, emotion or speed without rebuilding the transformer.
+func (t *DoubaoTTSICLV2) Transform(ctx context.Context, _ string, input genx.Stream) (genx.Stream, error) {
+	voice := doubaoTTSICLV2Voice{
+		speaker:    t.speaker,
+		emotion:    t.emotion,
+		speedRatio: t.speedRatio,
+	}
+	if opts, ok := ctx.Value(doubaoTTSICLV2CtxKey{}).(DoubaoTTSICLV2CtxOptions); ok {
+		if opts.Speaker != "" {
+			voice.speaker = opts.Speaker
+		}
+		if opts.Emotion != "" {
+			voice.emotion = opts.Emotion
+		}
+		if opts.SpeedRatio != 0 {
+			voice.speedRatio = opts.SpeedRatio
+		}
+	}
+
+	output := &DoubaoTTSICLV2Stream{
+		bufferStream: newBufferStream(100),
+		voice:        voice,
+	}
 
 	go t.transformLoop(input, output)
 
 	return output, nil
 }
 
-func (t *DoubaoTTSICLV2) transformLoop(input genx.Stream, output *bufferStream) {
+func (t *DoubaoTTSICLV2) transformLoop(input genx.Stream, output *DoubaoTTSICLV2Stream) {
 	defer output.Close()
 
 	// Local cancel context tied to the loop lifecycle.
@@ -152,6 +262,7 @@ func (t *DoubaoTTSICLV2) transformLoop(input genx.Stream, output *bufferStream)
 	mimeType := t.mimeType()
 	var textBuilder strings.Builder
 	var lastChunk *genx.MessageChunk
+	var style ttsStyle
 
 	for {
 		chunk, err := input.Next()
@@ -162,7 +273,7 @@ func (t *DoubaoTTSICLV2) transformLoop(input genx.Stream, output *bufferStream)
 			}
 			// EOF: synthesize any remaining text
 			if textBuilder.Len() > 0 {
-				if err := t.synthesize(ctx, textBuilder.String(), lastChunk, mimeType, output); err != nil {
+				if err := t.synthesize(ctx, textBuilder.String(), style.take(doubaoTTSStyles), lastChunk, mimeType, output); err != nil {
 					output.CloseWithError(err)
 					return
 				}
@@ -175,13 +286,17 @@ func (t *DoubaoTTSICLV2) transformLoop(input genx.Stream, output *bufferStream)
 		}
 
 		lastChunk = chunk
+		if _, ok := chunk.Part.(genx.Text); ok {
+			style.observe(chunk)
+		}
 
 		// Check for text EoS marker
 		if chunk.IsEndOfStream() {
 			if _, ok := chunk.Part.(genx.Text); ok {
 				// Text EoS: synthesize accumulated text, emit audio, then emit audio EoS
+				emotion := style.take(doubaoTTSStyles)
 			if textBuilder.Len() > 0 {
-				if err := t.synthesize(ctx, textBuilder.String(), lastChunk, mimeType, output); err != nil {
+				if err := t.synthesize(ctx, textBuilder.String(), emotion, lastChunk, mimeType, output); err != nil {
 					output.CloseWithError(err)
 					return
 				}
@@ -217,19 +332,21 @@ func (t *DoubaoTTSICLV2) transformLoop(input genx.Stream, output *bufferStream)
 	}
 }
 
-func (t *DoubaoTTSICLV2) synthesize(ctx context.Context, text string, lastChunk *genx.MessageChunk, mimeType string, output *bufferStream) error {
+func (t *DoubaoTTSICLV2) synthesize(ctx context.Context, text, emotion string, lastChunk *genx.MessageChunk, mimeType string, output *DoubaoTTSICLV2Stream) error {
+	voice := output.currentVoice()
 	req := &doubaospeech.TTSV2Request{
 		Text:        text,
-		Speaker:     t.speaker,
+		Speaker:     voice.speaker,
 		ResourceID:  doubaospeech.ResourceVoiceCloneV2,
 		Format:      t.format,
 		SampleRate:  t.sampleRate,
 		BitRate:     t.bitRate,
-		SpeedRatio:  t.speedRatio,
+		SpeedRatio:  voice.speedRatio,
 		VolumeRatio: t.volumeRatio,
 		PitchRatio:  t.pitchRatio,
-		Emotion:     t.emotion,
+		Emotion:     cmp.Or(emotion, voice.emotion),
 		Language:    t.language,
+		Lexicon:     t.lexicon,
 	}
 
 	for chunk, err := range t.client.TTSV2.Stream(ctx, req) {
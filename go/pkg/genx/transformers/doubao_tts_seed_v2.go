@@ -1,6 +1,7 @@
 package transformers
 
 import (
+	"cmp"
 	"context"
 	"io"
 	"strings"
@@ -24,6 +25,14 @@ import (
 // EoS Handling:
 //   - When receiving a text/plain EoS marker, finish synthesis, emit audio chunks, then emit audio/* EoS
 //   - Non-text chunks are passed through unchanged
+//
+// Style:
+//   - The MetaTTSStyle of a sub-stream (e.g., happy, whisper) overrides
+//     WithDoubaoTTSSeedV2Emotion for its synthesis
+//
+// Timestamps:
+//   - With WithDoubaoTTSSeedV2Timestamps, the timing of each sentence and
+//     its words is attached under MetaTTSTiming, see SubtitleCollector
 type DoubaoTTSSeedV2 struct {
 	client      *doubaospeech.Client
 	speaker     string
@@ -35,6 +44,8 @@ type DoubaoTTSSeedV2 struct {
 	pitchRatio  float64
 	emotion     string
 	language    string
+	lexicon     doubaospeech.Lexicon
+	timestamps  bool
 }
 
 var _ genx.Transformer = (*DoubaoTTSSeedV2)(nil)
@@ -98,6 +109,22 @@ func WithDoubaoTTSSeedV2Language(language string) DoubaoTTSSeedV2Option {
 	}
 }
 
+// WithDoubaoTTSSeedV2Lexicon sets a pronunciation dictionary for names and other
+// words the voice mispronounces.
+func WithDoubaoTTSSeedV2Lexicon(lexicon doubaospeech.Lexicon) DoubaoTTSSeedV2Option {
+	return func(t *DoubaoTTSSeedV2) {
+		t.lexicon = lexicon
+	}
+}
+
+// WithDoubaoTTSSeedV2Timestamps enables word and sentence timestamps, for
+// karaoke-style captions.
+func WithDoubaoTTSSeedV2Timestamps(enable bool) DoubaoTTSSeedV2Option {
+	return func(t *DoubaoTTSSeedV2) {
+		t.timestamps = enable
+	}
+}
+
 // NewDoubaoTTSSeedV2 creates a new DoubaoTTSSeedV2 transformer.
 //
 // Parameters:
@@ -155,6 +182,7 @@ func (t *DoubaoTTSSeedV2) transformLoop(input genx.Stream, output *bufferStream)
 	mimeType := t.mimeType()
 	var textBuilder strings.Builder
 	var lastChunk *genx.MessageChunk
+	var style ttsStyle
 
 	for {
 		chunk, err := input.Next()
@@ -165,7 +193,7 @@ func (t *DoubaoTTSSeedV2) transformLoop(input genx.Stream, output *bufferStream)
 			}
 			// EOF: synthesize any remaining text
 			if textBuilder.Len() > 0 {
-				if err := t.synthesize(ctx, textBuilder.String(), lastChunk, mimeType, output); err != nil {
+				if err := t.synthesize(ctx, textBuilder.String(), style.take(doubaoTTSStyles), lastChunk, mimeType, output); err != nil {
 					output.CloseWithError(err)
 					return
 				}
@@ -178,13 +206,17 @@ func (t *DoubaoTTSSeedV2) transformLoop(input genx.Stream, output *bufferStream)
 		}
 
 		lastChunk = chunk
+		if _, ok := chunk.Part.(genx.Text); ok {
+			style.observe(chunk)
+		}
 
 		// Check for text EoS marker
 		if chunk.IsEndOfStream() {
 			if _, ok := chunk.Part.(genx.Text); ok {
 				// Text EoS: synthesize accumulated text, emit audio, then emit audio EoS
+				emotion := style.take(doubaoTTSStyles)
 			if textBuilder.Len() > 0 {
-				if err := t.synthesize(ctx, textBuilder.String(), lastChunk, mimeType, output); err != nil {
+				if err := t.synthesize(ctx, textBuilder.String(), emotion, lastChunk, mimeType, output); err != nil {
 					output.CloseWithError(err)
 					return
 				}
@@ -220,7 +252,7 @@ func (t *DoubaoTTSSeedV2) transformLoop(input genx.Stream, output *bufferStream)
 	}
 }
 
-func (t *DoubaoTTSSeedV2) synthesize(ctx context.Context, text string, lastChunk *genx.MessageChunk, mimeType string, output *bufferStream) error {
+func (t *DoubaoTTSSeedV2) synthesize(ctx context.Context, text, emotion string, lastChunk *genx.MessageChunk, mimeType string, output *bufferStream) error {
 	req := &doubaospeech.TTSV2Request{
 		Text:        text,
 		Speaker:     t.speaker,
@@ -231,8 +263,11 @@ func (t *DoubaoTTSSeedV2) synthesize(ctx context.Context, text string, lastChunk
 		SpeedRatio:  t.speedRatio,
 		VolumeRatio: t.volumeRatio,
 		PitchRatio:  t.pitchRatio,
-		Emotion:     t.emotion,
+		Emotion:     cmp.Or(emotion, t.emotion),
 		Language:    t.language,
+		Lexicon:     t.lexicon,
+
+		EnableTimestamp: t.timestamps,
 	}
 
 	for chunk, err := range t.client.TTSV2.Stream(ctx, req) {
@@ -240,6 +275,11 @@ func (t *DoubaoTTSSeedV2) synthesize(ctx context.Context, text string, lastChunk
 			return err
 		}
 
+		var timings []SentenceTiming
+		if t.timestamps && chunk.Sentence != nil {
+			timings = append(timings, doubaoSentenceTiming(chunk.Sentence))
+		}
+
 		if chunk.Audio != nil && len(chunk.Audio) > 0 {
 			outChunk := &genx.MessageChunk{
 				Part: &genx.Blob{
@@ -251,15 +291,41 @@ func (t *DoubaoTTSSeedV2) synthesize(ctx context.Context, text string, lastChunk
 				outChunk.Role = lastChunk.Role
 				outChunk.Name = lastChunk.Name
 			}
+			if len(timings) > 0 {
+				SetTTSTiming(outChunk, timings...)
+			}
 
 			if err := output.Push(outChunk); err != nil {
 				return err
 			}
+		} else if len(timings) > 0 {
+			if err := output.Push(newTTSTimingChunk(lastChunk, "", timings)); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
+// doubaoSentenceTiming converts a sentence timing of Doubao, in seconds,
+// spanning the sentence over its words.
+func doubaoSentenceTiming(s *doubaospeech.TTSV2Sentence) SentenceTiming {
+	timing := SentenceTiming{Text: s.Text}
+	for i, w := range s.Words {
+		word := WordTiming{
+			Text:  w.Word,
+			Start: int64(w.StartTime * 1000),
+			End:   int64(w.EndTime * 1000),
+		}
+		if i == 0 {
+			timing.Start = word.Start
+		}
+		timing.End = max(timing.End, word.End)
+		timing.Words = append(timing.Words, word)
+	}
+	return timing
+}
+
 func (t *DoubaoTTSSeedV2) mimeType() string {
 	switch t.format {
 	case "mp3":
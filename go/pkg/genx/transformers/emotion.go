@@ -0,0 +1,364 @@
+package transformers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/haivivi/giztoy/go/pkg/audio/fbank"
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/onnx"
+)
+
+// Metadata keys written by the Emotion transformer into StreamCtrl.Metadata.
+const (
+	// MetaEmotion holds the top emotion label (e.g., "sad").
+	MetaEmotion = "emotion"
+	// MetaEmotionScore holds the top label's probability formatted as "%.3f".
+	MetaEmotionScore = "emotion.score"
+	// MetaEmotionScores holds all label probabilities as
+	// "label=score,label=score", sorted by descending score.
+	MetaEmotionScores = "emotion.scores"
+)
+
+// DefaultEmotionLabels is the label order of the default prosody model.
+var DefaultEmotionLabels = []string{"neutral", "happy", "sad", "angry", "fearful", "surprised"}
+
+// EmotionScore is a single emotion label with its probability.
+type EmotionScore struct {
+	Label string
+	Score float32
+}
+
+// EmotionModel classifies the emotion of a speech segment.
+//
+// The input audio must be PCM16 signed little-endian, 16kHz, mono.
+// Implementations must be safe for concurrent use.
+type EmotionModel interface {
+	// Classify returns emotion scores sorted by descending score.
+	Classify(audio []byte) ([]EmotionScore, error)
+
+	// Close releases any resources held by the model.
+	Close() error
+}
+
+// ONNXEmotionModel implements [EmotionModel] with an ONNX prosody classifier.
+//
+// # Model Contract
+//
+//   - Input "x": [1, T, 80] float32 (CMVN-normalized log mel filterbank)
+//   - Output "logits": [1, len(labels)] float32
+//
+// Probabilities are obtained by applying softmax to the logits.
+type ONNXEmotionModel struct {
+	mu      sync.RWMutex
+	session *onnx.Session
+	fbank   *fbank.Extractor
+	labels  []string
+	closed  bool
+
+	inputName  string
+	outputName string
+}
+
+// ONNXEmotionModelOption configures an ONNXEmotionModel.
+type ONNXEmotionModelOption func(*ONNXEmotionModel)
+
+// WithONNXEmotionLabels sets the label order matching the model output.
+func WithONNXEmotionLabels(labels []string) ONNXEmotionModelOption {
+	return func(m *ONNXEmotionModel) {
+		if len(labels) > 0 {
+			m.labels = labels
+		}
+	}
+}
+
+// WithONNXEmotionTensorNames sets the input and output tensor names.
+// Default: "x" and "logits".
+func WithONNXEmotionTensorNames(input, output string) ONNXEmotionModelOption {
+	return func(m *ONNXEmotionModel) {
+		m.inputName = input
+		m.outputName = output
+	}
+}
+
+// NewONNXEmotionModel creates an ONNXEmotionModel from a loaded session.
+// The model takes ownership of the session and closes it on Close.
+func NewONNXEmotionModel(session *onnx.Session, opts ...ONNXEmotionModelOption) *ONNXEmotionModel {
+	m := &ONNXEmotionModel{
+		session:    session,
+		fbank:      fbank.New(fbank.DefaultConfig()),
+		labels:     DefaultEmotionLabels,
+		inputName:  "x",
+		outputName: "logits",
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Classify implements [EmotionModel].
+func (m *ONNXEmotionModel) Classify(audio []byte) ([]EmotionScore, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return nil, fmt.Errorf("emotion: model is closed")
+	}
+
+	features := m.fbank.ExtractFromInt16(audio)
+	if len(features) == 0 {
+		return nil, fmt.Errorf("emotion: audio too short for fbank extraction")
+	}
+	fbank.CMVN(features)
+
+	shape := []int64{1, int64(len(features)), int64(len(features[0]))}
+	input, err := onnx.NewTensor(shape, fbank.Flatten(features))
+	if err != nil {
+		return nil, fmt.Errorf("emotion: %w", err)
+	}
+	defer input.Close()
+
+	outputs, err := m.session.Run([]string{m.inputName}, []*onnx.Tensor{input}, []string{m.outputName})
+	if err != nil {
+		return nil, fmt.Errorf("emotion: inference: %w", err)
+	}
+	defer outputs[0].Close()
+
+	logits, err := outputs[0].FloatData()
+	if err != nil {
+		return nil, fmt.Errorf("emotion: read output: %w", err)
+	}
+	if len(logits) != len(m.labels) {
+		return nil, fmt.Errorf("emotion: model returned %d scores for %d labels", len(logits), len(m.labels))
+	}
+	return rankEmotions(m.labels, softmax(logits)), nil
+}
+
+// Close implements [EmotionModel].
+func (m *ONNXEmotionModel) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	return m.session.Close()
+}
+
+// Emotion is a transformer that annotates user audio with detected emotion.
+//
+// Input type: audio/pcm (PCM16 signed little-endian, 16kHz, mono)
+// Output type: audio/pcm (pass-through, with emotion metadata set)
+//
+// Only chunks with Role == genx.RoleUser are analyzed. The transformer
+// accumulates user PCM audio and classifies it once per analysis window. Each
+// user audio chunk is annotated with the most recent result under the
+// MetaEmotion, MetaEmotionScore and MetaEmotionScores metadata keys, so
+// agents can adapt tone (e.g., switch to a comforting persona when the
+// speaker sounds upset).
+//
+// Results below the minimum score are not reported; the previous result is
+// kept instead. Annotated chunks are copies; the input chunks are not
+// modified. Non-audio and non-user chunks are passed through unchanged.
+//
+// EoS Handling:
+//   - On a user audio/pcm EoS, classify any remaining audio (if long enough),
+//     annotate the EoS marker, and pass it through
+//   - Emotion state is reset after each EoS so sub-streams don't leak labels
+//   - Other EoS markers are passed through unchanged
+type Emotion struct {
+	model EmotionModel
+
+	segmentDuration int     // analysis window in milliseconds (default 2000)
+	minDuration     int     // minimum audio for a tail analysis in ms (default 500)
+	minScore        float32 // minimum top score to report (default 0.4)
+	sampleRate      int     // PCM sample rate (default 16000)
+}
+
+var _ genx.Transformer = (*Emotion)(nil)
+
+// EmotionOption configures an Emotion transformer.
+type EmotionOption func(*Emotion)
+
+// WithEmotionSegmentDuration sets the analysis window in milliseconds.
+func WithEmotionSegmentDuration(ms int) EmotionOption {
+	return func(t *Emotion) {
+		if ms > 0 {
+			t.segmentDuration = ms
+		}
+	}
+}
+
+// WithEmotionMinDuration sets the minimum tail audio in milliseconds that is
+// still classified when an EoS arrives.
+func WithEmotionMinDuration(ms int) EmotionOption {
+	return func(t *Emotion) {
+		if ms > 0 {
+			t.minDuration = ms
+		}
+	}
+}
+
+// WithEmotionMinScore sets the minimum probability required to report a label.
+func WithEmotionMinScore(score float32) EmotionOption {
+	return func(t *Emotion) {
+		if score >= 0 {
+			t.minScore = score
+		}
+	}
+}
+
+// WithEmotionSampleRate sets the expected PCM sample rate.
+func WithEmotionSampleRate(rate int) EmotionOption {
+	return func(t *Emotion) {
+		if rate > 0 {
+			t.sampleRate = rate
+		}
+	}
+}
+
+// NewEmotion creates an Emotion transformer backed by the given model.
+func NewEmotion(model EmotionModel, opts ...EmotionOption) *Emotion {
+	t := &Emotion{
+		model:           model,
+		segmentDuration: 2000,
+		minDuration:     500,
+		minScore:        0.4,
+		sampleRate:      16000,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Transform implements [genx.Transformer]. It starts a background goroutine
+// that reads audio chunks, classifies emotion, and emits annotated chunks.
+// The goroutine exits when the input stream ends.
+func (t *Emotion) Transform(_ context.Context, _ string, input genx.Stream) (genx.Stream, error) {
+	output := newBufferStream(100)
+	go t.transformLoop(input, output)
+	return output, nil
+}
+
+func (t *Emotion) msToBytes(ms int) int {
+	return t.sampleRate * 2 * ms / 1000
+}
+
+func (t *Emotion) transformLoop(input genx.Stream, output *bufferStream) {
+	defer output.Close()
+
+	var (
+		pcmAccum []byte
+		last     []EmotionScore
+		segBytes = t.msToBytes(t.segmentDuration)
+		minBytes = t.msToBytes(t.minDuration)
+	)
+
+	for {
+		chunk, err := input.Next()
+		if err != nil {
+			if err != io.EOF {
+				output.CloseWithError(err)
+			}
+			return
+		}
+		if chunk == nil {
+			continue
+		}
+
+		blob, isPCM := chunk.Part.(*genx.Blob)
+		isPCM = isPCM && isPCMMIME(blob.MIMEType)
+		if !isPCM || chunk.Role != genx.RoleUser {
+			if err := output.Push(chunk); err != nil {
+				return
+			}
+			continue
+		}
+
+		if chunk.IsEndOfStream() {
+			if len(pcmAccum) >= minBytes {
+				last = t.classify(pcmAccum, last)
+			}
+			chunk = annotateEmotion(chunk, last)
+			pcmAccum = pcmAccum[:0]
+			last = nil
+			if err := output.Push(chunk); err != nil {
+				return
+			}
+			continue
+		}
+
+		pcmAccum = append(pcmAccum, blob.Data...)
+		for len(pcmAccum) >= segBytes {
+			last = t.classify(pcmAccum[:segBytes], last)
+			pcmAccum = pcmAccum[segBytes:]
+		}
+
+		chunk = annotateEmotion(chunk, last)
+		if err := output.Push(chunk); err != nil {
+			return
+		}
+	}
+}
+
+// classify runs the model and returns the new scores, or prev if the model
+// fails or is not confident enough.
+func (t *Emotion) classify(pcm []byte, prev []EmotionScore) []EmotionScore {
+	scores, err := t.model.Classify(pcm)
+	if err != nil || len(scores) == 0 || scores[0].Score < t.minScore {
+		return prev
+	}
+	return scores
+}
+
+// annotateEmotion returns chunk annotated with scores. The input may be
+// shared, so a copy is annotated and the input released.
+func annotateEmotion(chunk *genx.MessageChunk, scores []EmotionScore) *genx.MessageChunk {
+	if len(scores) == 0 {
+		return chunk
+	}
+	parts := make([]string, len(scores))
+	for i, s := range scores {
+		parts[i] = s.Label + "=" + strconv.FormatFloat(float64(s.Score), 'f', 3, 32)
+	}
+	annotated := chunk.ClonePooled()
+	chunk.Release()
+	annotated.SetMetadata(MetaEmotion, scores[0].Label)
+	annotated.SetMetadata(MetaEmotionScore, strconv.FormatFloat(float64(scores[0].Score), 'f', 3, 32))
+	annotated.SetMetadata(MetaEmotionScores, strings.Join(parts, ","))
+	return annotated
+}
+
+func rankEmotions(labels []string, probs []float32) []EmotionScore {
+	scores := make([]EmotionScore, len(labels))
+	for i, l := range labels {
+		scores[i] = EmotionScore{Label: l, Score: probs[i]}
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	return scores
+}
+
+func softmax(logits []float32) []float32 {
+	maxV := float32(math.Inf(-1))
+	for _, v := range logits {
+		maxV = max(maxV, v)
+	}
+	out := make([]float32, len(logits))
+	var sum float64
+	for i, v := range logits {
+		e := math.Exp(float64(v - maxV))
+		out[i] = float32(e)
+		sum += e
+	}
+	for i := range out {
+		out[i] = float32(float64(out[i]) / sum)
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+package transformers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// fakeEmotionModel classifies all audio as its scores.
+type fakeEmotionModel struct {
+	scores []EmotionScore
+}
+
+func (m *fakeEmotionModel) Classify([]byte) ([]EmotionScore, error) { return m.scores, nil }
+func (m *fakeEmotionModel) Close() error                            { return nil }
+
+func TestEmotion_AnnotatesCopies(t *testing.T) {
+	model := &fakeEmotionModel{scores: []EmotionScore{{Label: "sad", Score: 0.8}, {Label: "neutral", Score: 0.2}}}
+	e := NewEmotion(model, WithEmotionSegmentDuration(100))
+
+	// 100ms of 16kHz PCM16, shared with another consumer
+	shared := &genx.MessageChunk{Role: genx.RoleUser, Part: &genx.Blob{MIMEType: "audio/pcm", Data: make([]byte, 3200)}}
+	pooled := genx.AcquireChunk()
+	pooled.Role, pooled.Part = genx.RoleUser, genx.AcquireBlob("audio/pcm", 3200)
+	pooled.Retain() // the test's reference
+	eos := genx.NewEndOfStream("audio/pcm")
+	eos.Role = genx.RoleUser
+
+	out, err := e.Transform(context.Background(), "", chunkInput(shared, pooled, eos))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []*genx.MessageChunk
+	for {
+		chunk, err := out.Next()
+		if err != nil {
+			break
+		}
+		got = append(got, chunk)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(got))
+	}
+	for i, chunk := range got {
+		if chunk.Metadata(MetaEmotion) != "sad" || chunk.Metadata(MetaEmotionScore) != "0.800" ||
+			chunk.Metadata(MetaEmotionScores) != "sad=0.800,neutral=0.200" {
+			t.Errorf("chunk %d: %s", i, describeEmotion(chunk))
+		}
+	}
+	if got[0] == shared || shared.Ctrl != nil {
+		t.Errorf("input chunk annotated in place: %v", shared.Ctrl)
+	}
+	if pooled.Part == nil || pooled.Metadata(MetaEmotion) != "" {
+		t.Errorf("pooled input chunk modified: %+v", pooled)
+	}
+	if pooled.Release(); pooled.Part != nil {
+		t.Error("pooled input chunk not released by the transformer")
+	}
+	if eos.Metadata(MetaEmotion) != "" {
+		t.Errorf("input EoS annotated in place: %v", eos.Ctrl.Metadata)
+	}
+	if !got[2].IsEndOfStream() {
+		t.Error("EoS not passed on")
+	}
+}
+
+func TestEmotion_PassThrough(t *testing.T) {
+	model := &fakeEmotionModel{scores: []EmotionScore{{Label: "happy", Score: 0.9}}}
+	e := NewEmotion(model, WithEmotionSegmentDuration(100))
+
+	// Before the first window there is no result, and model audio is not
+	// analyzed: the chunks are passed on as they are
+	user := &genx.MessageChunk{Role: genx.RoleUser, Part: &genx.Blob{MIMEType: "audio/pcm", Data: make([]byte, 320)}}
+	modelAudioChunk := &genx.MessageChunk{Role: genx.RoleModel, Part: &genx.Blob{MIMEType: "audio/pcm", Data: make([]byte, 3200)}}
+	out, err := e.Transform(context.Background(), "", chunkInput(user, modelAudioChunk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []*genx.MessageChunk{user, modelAudioChunk} {
+		chunk, err := out.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chunk != want || chunk.Metadata(MetaEmotion) != "" {
+			t.Errorf("chunk = %s, want the input passed on", describeEmotion(chunk))
+		}
+	}
+}
+
+func describeEmotion(c *genx.MessageChunk) string {
+	return string(c.Role) + " emotion=" + c.Metadata(MetaEmotion) + " scores=" + c.Metadata(MetaEmotionScores)
+}
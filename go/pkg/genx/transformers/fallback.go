@@ -0,0 +1,290 @@
+package transformers
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// MetaFallback is written by the Fallback transformer into
+// StreamCtrl.Metadata of canned responses. Its value is the intent name, or
+// FallbackDefaultIntent for the default response.
+const MetaFallback = "fallback"
+
+// FallbackDefaultIntent is the MetaFallback value of the default response.
+const FallbackDefaultIntent = "default"
+
+// FallbackResponse is a locally cached response, typically pre-rendered
+// TTS audio.
+type FallbackResponse struct {
+	// Text is the text of the response. If set, it is emitted as a
+	// RoleModel text chunk before the audio (e.g., for captions).
+	Text string
+
+	// Audio is the cached audio of the response.
+	Audio *genx.Blob
+}
+
+// FallbackIntent maps user utterances to a cached response.
+type FallbackIntent struct {
+	// Name identifies the intent in MetaFallback (e.g., "time").
+	Name string
+
+	// Keywords match the user's text case-insensitively as substrings
+	// (e.g., "几点", "what time").
+	Keywords []string
+
+	// Response returns the response to the user's text. It is called when
+	// the intent matches, so dynamic intents such as the time can pick
+	// among cached clips. Returning nil falls through to the default.
+	Response func(text string) *FallbackResponse
+}
+
+func (i *FallbackIntent) match(text string) bool {
+	text = strings.ToLower(text)
+	for _, kw := range i.Keywords {
+		if kw != "" && strings.Contains(text, strings.ToLower(kw)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fallback keeps a pipeline minimally responsive when its transformer
+// fails, e.g. when the network is down. It wraps a primary transformer,
+// typically a realtime model or an ASR → LLM → TTS chain:
+//
+//   - While the primary works, chunks pass through it unchanged.
+//   - If the primary fails to start or its output fails, Fallback answers
+//     every user turn (RoleUser EoS) with a canned response for the rest
+//     of the stream. The turn that was waiting for an answer when the
+//     primary failed is answered too.
+//
+// The response is chosen from the intents by the user's text (RoleUser
+// text chunks, e.g. from a local ASR); turns without matching text, such
+// as audio-only input, get the default response.
+//
+// Canned responses are RoleModel chunks: optional text, the audio blob and
+// an audio EoS, all carrying MetaFallback.
+//
+// Input: any (forwarded to the primary)
+// Output: the primary's output, or text + audio/* canned responses
+type Fallback struct {
+	primary genx.Transformer
+	intents []FallbackIntent
+	def     *FallbackResponse
+	name    string
+}
+
+var _ genx.Transformer = (*Fallback)(nil)
+
+// FallbackOption configures a Fallback transformer.
+type FallbackOption func(*Fallback)
+
+// WithFallbackIntent adds an intent. Intents are tried in order.
+func WithFallbackIntent(intent FallbackIntent) FallbackOption {
+	return func(t *Fallback) {
+		t.intents = append(t.intents, intent)
+	}
+}
+
+// WithFallbackDefault sets the response for turns that match no intent,
+// e.g. "我现在连不上网". Without it such turns get no response.
+func WithFallbackDefault(resp *FallbackResponse) FallbackOption {
+	return func(t *Fallback) {
+		t.def = resp
+	}
+}
+
+// WithFallbackName sets MessageChunk.Name of canned responses
+// (default "fallback").
+func WithFallbackName(name string) FallbackOption {
+	return func(t *Fallback) {
+		t.name = name
+	}
+}
+
+// NewFallback creates a Fallback transformer around primary.
+func NewFallback(primary genx.Transformer, opts ...FallbackOption) *Fallback {
+	t := &Fallback{
+		primary: primary,
+		name:    "fallback",
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Transform implements genx.Transformer. The ctx and pattern are passed to
+// the primary. A primary initialization error is not returned: the stream
+// is served from the canned responses instead.
+func (t *Fallback) Transform(ctx context.Context, pattern string, input genx.Stream) (genx.Stream, error) {
+	output := newBufferStream(100)
+	primaryIn := newBufferStream(100)
+
+	s := &fallbackState{}
+	primaryOut, err := t.primary.Transform(ctx, pattern, primaryIn)
+	if err != nil {
+		slog.Warn("fallback: primary transformer unavailable", "pattern", pattern, "error", err)
+		s.failed = true
+		primaryIn.Close()
+		primaryOut = nil
+	}
+
+	go t.transformLoop(s, input, primaryIn, primaryOut, output)
+	return output, nil
+}
+
+// fallbackState is shared by the input loop and the primary output loop.
+type fallbackState struct {
+	mu       sync.Mutex
+	failed   bool
+	turn     strings.Builder // user text of the current turn
+	awaiting string          // text of the ended turn awaiting an answer
+	waiting  bool
+}
+
+func (t *Fallback) transformLoop(s *fallbackState, input genx.Stream, primaryIn *bufferStream, primaryOut genx.Stream, output *bufferStream) {
+	defer output.Close()
+
+	var wg sync.WaitGroup
+	if primaryOut != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.forwardLoop(s, primaryOut, output)
+		}()
+	}
+	defer wg.Wait()
+
+	for {
+		chunk, err := input.Next()
+		if err != nil {
+			if err != io.EOF {
+				primaryIn.CloseWithError(err)
+			} else {
+				primaryIn.Close()
+			}
+			return
+		}
+		if chunk == nil {
+			continue
+		}
+
+		s.mu.Lock()
+		failed := s.failed
+		if chunk.Role == genx.RoleUser {
+			if text, ok := chunk.Part.(genx.Text); ok {
+				s.turn.WriteString(string(text))
+			}
+		}
+		endOfTurn := chunk.Role == genx.RoleUser && chunk.IsEndOfStream()
+		var text string
+		if endOfTurn {
+			text = s.turn.String()
+			s.turn.Reset()
+			if !failed {
+				s.awaiting, s.waiting = text, true
+			}
+		}
+		s.mu.Unlock()
+
+		if failed {
+			if endOfTurn {
+				t.respond(text, output)
+			}
+			continue
+		}
+		if err := primaryIn.Push(chunk); err != nil {
+			t.fail(s, err, output)
+		}
+	}
+}
+
+// forwardLoop copies the primary's output and switches to fallback mode
+// when it fails.
+func (t *Fallback) forwardLoop(s *fallbackState, primaryOut genx.Stream, output *bufferStream) {
+	for {
+		chunk, err := primaryOut.Next()
+		if err != nil {
+			if err != io.EOF {
+				t.fail(s, err, output)
+			}
+			return
+		}
+		if chunk == nil {
+			continue
+		}
+		if chunk.Role == genx.RoleModel {
+			s.mu.Lock()
+			s.waiting = false
+			s.mu.Unlock()
+		}
+		if err := output.Push(chunk); err != nil {
+			primaryOut.CloseWithError(err)
+			return
+		}
+	}
+}
+
+// fail switches to fallback mode and answers the turn awaiting a response.
+func (t *Fallback) fail(s *fallbackState, err error, output *bufferStream) {
+	s.mu.Lock()
+	if s.failed {
+		s.mu.Unlock()
+		return
+	}
+	s.failed = true
+	text, waiting := s.awaiting, s.waiting
+	s.waiting = false
+	s.mu.Unlock()
+
+	slog.Warn("fallback: primary transformer failed", "error", err)
+	if waiting {
+		t.respond(text, output)
+	}
+}
+
+// respond emits the canned response for the user's text.
+func (t *Fallback) respond(text string, output *bufferStream) {
+	intent, resp := FallbackDefaultIntent, t.def
+	for i := range t.intents {
+		in := &t.intents[i]
+		if in.Response == nil || !in.match(text) {
+			continue
+		}
+		if r := in.Response(text); r != nil {
+			intent, resp = in.Name, r
+			break
+		}
+	}
+	if resp == nil {
+		return
+	}
+
+	newChunk := func(part genx.Part) *genx.MessageChunk {
+		c := &genx.MessageChunk{Role: genx.RoleModel, Name: t.name, Part: part}
+		c.SetMetadata(MetaFallback, intent)
+		return c
+	}
+	if resp.Text != "" {
+		if output.Push(newChunk(genx.Text(resp.Text))) != nil {
+			return
+		}
+	}
+	if resp.Audio == nil {
+		return
+	}
+	if output.Push(newChunk(resp.Audio)) != nil {
+		return
+	}
+	eos := genx.NewEndOfStream(resp.Audio.MIMEType)
+	eos.Role, eos.Name = genx.RoleModel, t.name
+	eos.SetMetadata(MetaFallback, intent)
+	output.Push(eos)
+}
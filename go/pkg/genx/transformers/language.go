@@ -0,0 +1,462 @@
+package transformers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// MetaLanguage is written by the LanguageRouter into StreamCtrl.Metadata of
+// the chunks its routes output. Its value is the language of the route
+// (e.g., "zh").
+const MetaLanguage = "language"
+
+// LanguageDetector detects the language of user input.
+//
+// Languages are identified by codes such as "zh" or "en"; the codes only
+// need to match the routes of the LanguageRouter. Implementations must be
+// safe for concurrent use.
+type LanguageDetector interface {
+	// DetectText returns the language of text and the confidence of the
+	// result in [0, 1]. It returns "" if the text tells nothing about its
+	// language, e.g. digits only.
+	DetectText(text string) (lang string, confidence float32)
+
+	// DetectAudio returns the language spoken in audio, PCM16 signed
+	// little-endian mono at the sample rate of the router. It returns ""
+	// if the detector cannot tell, e.g. a text-only detector.
+	DetectAudio(audio []byte) (lang string, confidence float32, err error)
+}
+
+// ScriptLanguageDetector is a LanguageDetector that tells languages apart
+// by their writing system: Han characters are Chinese, kana Japanese,
+// Hangul Korean, Cyrillic Russian, and Latin letters the Latin language.
+//
+// Han characters and kana are counted one by one, Latin and Cyrillic
+// letters by word, so a Chinese sentence with an English song title is
+// still Chinese. It does not detect audio; combine it with an ASR in front
+// of the router or a spoken language identification model.
+type ScriptLanguageDetector struct {
+	// Latin is the language of text in Latin script (default "en").
+	Latin string
+}
+
+var _ LanguageDetector = (*ScriptLanguageDetector)(nil)
+
+// DetectText implements [LanguageDetector].
+func (d *ScriptLanguageDetector) DetectText(text string) (string, float32) {
+	counts := make(map[string]int)
+	inWord := ""
+	for _, r := range text {
+		lang := d.scriptLanguage(r)
+		switch lang {
+		case "zh", "ja", "ko":
+			counts[lang]++
+		case "":
+			inWord = ""
+			continue
+		default:
+			if inWord != lang {
+				counts[lang]++
+			}
+		}
+		inWord = lang
+	}
+	// Han characters are Japanese in text with kana
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+
+	var (
+		best        string
+		most, total int
+	)
+	for lang, n := range counts {
+		total += n
+		// Ties go to the non-Latin script, the matrix language of mixed text
+		if n > most || (n == most && best == d.latin()) {
+			best, most = lang, n
+		}
+	}
+	if total == 0 {
+		return "", 0
+	}
+	return best, float32(most) / float32(total)
+}
+
+// DetectAudio implements [LanguageDetector]. The script detector cannot
+// tell the language of audio.
+func (d *ScriptLanguageDetector) DetectAudio([]byte) (string, float32, error) {
+	return "", 0, nil
+}
+
+func (d *ScriptLanguageDetector) latin() string {
+	if d.Latin != "" {
+		return d.Latin
+	}
+	return "en"
+}
+
+// scriptLanguage returns the language of the script of r, or "" if r is
+// not a letter.
+func (d *ScriptLanguageDetector) scriptLanguage(r rune) string {
+	switch {
+	case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+		return "ja"
+	case unicode.Is(unicode.Han, r):
+		return "zh"
+	case unicode.Is(unicode.Hangul, r):
+		return "ko"
+	case unicode.Is(unicode.Cyrillic, r):
+		return "ru"
+	case unicode.Is(unicode.Latin, r):
+		return d.latin()
+	}
+	return ""
+}
+
+// LanguageRouter is a transformer that detects the language of the user
+// and routes each sub-stream to a transformer for that language, e.g. a
+// Chinese and an English TTS voice, or ASR models per language, so that a
+// bilingual household is answered in the language it speaks.
+//
+// The decision is made per StreamID from RoleUser text and audio/pcm
+// chunks, and sticks: every later chunk with the same StreamID, of any
+// role, goes to the same route. While a sub-stream is undecided its chunks
+// are held back, until:
+//   - the detector is confident about the user text or the first
+//     analysis window of user audio, or
+//   - the user text or audio grows too long, the sub-stream ends (EoS), or
+//     a chunk of another role arrives. The sub-stream then goes to the
+//     language of the previous decision, or the default route at first.
+//
+// Languages without a route go to the default route. The outputs of the
+// routes are merged, each chunk annotated with MetaLanguage.
+//
+// Input: any (RoleUser text/plain and audio/pcm are analyzed)
+// Output: the outputs of the routes
+type LanguageRouter struct {
+	routes        map[string]genx.Transformer
+	langs         []string // languages of routes, in the order added
+	def           string
+	detector      LanguageDetector
+	minConfidence float32 // minimum confidence of a decision (default 0.6)
+	maxText       int     // runes of user text to decide on (default 50)
+	audioWindow   int     // audio analysis window in milliseconds (default 1500)
+	sampleRate    int     // PCM sample rate (default 16000)
+	maxStreams    int     // sticky decisions kept (default 1024)
+}
+
+var _ genx.Transformer = (*LanguageRouter)(nil)
+
+// LanguageRouterOption configures a LanguageRouter.
+type LanguageRouterOption func(*LanguageRouter)
+
+// WithLanguageRoute routes the sub-streams in language lang to t. The
+// first route is the default route unless WithLanguageDefault is given.
+func WithLanguageRoute(lang string, t genx.Transformer) LanguageRouterOption {
+	return func(r *LanguageRouter) {
+		if _, ok := r.routes[lang]; !ok {
+			r.langs = append(r.langs, lang)
+		}
+		r.routes[lang] = t
+	}
+}
+
+// WithLanguageDefault sets the language of the default route, used for
+// sub-streams whose language is unknown or has no route.
+func WithLanguageDefault(lang string) LanguageRouterOption {
+	return func(r *LanguageRouter) {
+		r.def = lang
+	}
+}
+
+// WithLanguageDetector sets the language detector
+// (default &ScriptLanguageDetector{}).
+func WithLanguageDetector(d LanguageDetector) LanguageRouterOption {
+	return func(r *LanguageRouter) {
+		if d != nil {
+			r.detector = d
+		}
+	}
+}
+
+// WithLanguageMinConfidence sets the minimum confidence of a detection to
+// decide the language of a sub-stream.
+func WithLanguageMinConfidence(c float32) LanguageRouterOption {
+	return func(r *LanguageRouter) {
+		if c >= 0 {
+			r.minConfidence = c
+		}
+	}
+}
+
+// WithLanguageAudioWindow sets the user audio in milliseconds analyzed to
+// decide the language of a sub-stream.
+func WithLanguageAudioWindow(ms int) LanguageRouterOption {
+	return func(r *LanguageRouter) {
+		if ms > 0 {
+			r.audioWindow = ms
+		}
+	}
+}
+
+// WithLanguageSampleRate sets the expected PCM sample rate.
+func WithLanguageSampleRate(rate int) LanguageRouterOption {
+	return func(r *LanguageRouter) {
+		if rate > 0 {
+			r.sampleRate = rate
+		}
+	}
+}
+
+// NewLanguageRouter creates a LanguageRouter. At least one route is
+// required.
+func NewLanguageRouter(opts ...LanguageRouterOption) *LanguageRouter {
+	r := &LanguageRouter{
+		routes:        make(map[string]genx.Transformer),
+		detector:      &ScriptLanguageDetector{},
+		minConfidence: 0.6,
+		maxText:       50,
+		audioWindow:   1500,
+		sampleRate:    16000,
+		maxStreams:    1024,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.def == "" && len(r.langs) > 0 {
+		r.def = r.langs[0]
+	}
+	return r
+}
+
+// Transform implements [genx.Transformer]. It starts the transformer of
+// every route with ctx and pattern, so that a failing route fails the
+// router at initialization.
+func (r *LanguageRouter) Transform(ctx context.Context, pattern string, input genx.Stream) (genx.Stream, error) {
+	if _, ok := r.routes[r.def]; !ok {
+		return nil, fmt.Errorf("transformers: language router: no route for default language %q", r.def)
+	}
+
+	ins := make(map[string]*bufferStream, len(r.langs))
+	outs := make(map[string]genx.Stream, len(r.langs))
+	for _, lang := range r.langs {
+		in := newBufferStream(100)
+		out, err := r.routes[lang].Transform(ctx, pattern, in)
+		if err != nil {
+			in.Close()
+			for _, opened := range ins {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("transformers: language router: route %s: %w", lang, err)
+		}
+		ins[lang], outs[lang] = in, out
+	}
+
+	output := newBufferStream(100)
+	s := &languageState{
+		LanguageRouter: r,
+		ins:            ins,
+		streams:        make(map[string]*languageStream),
+		last:           r.def,
+	}
+	go s.transformLoop(input, outs, output)
+	return output, nil
+}
+
+// languageStream is the routing state of a sub-stream.
+type languageStream struct {
+	lang    string // decided language, "" while detecting
+	pending []*genx.MessageChunk
+	text    strings.Builder
+	audio   []byte
+}
+
+// languageState is the state of one Transform call.
+type languageState struct {
+	*LanguageRouter
+	ins     map[string]*bufferStream
+	streams map[string]*languageStream
+	order   []string // StreamIDs by first appearance, to forget the oldest
+	last    string   // language of the last decision
+}
+
+func (s *languageState) transformLoop(input genx.Stream, outs map[string]genx.Stream, output *bufferStream) {
+	var wg sync.WaitGroup
+	for lang, out := range outs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			forwardLanguage(lang, out, output)
+		}()
+	}
+	defer func() {
+		wg.Wait()
+		output.Close()
+	}()
+
+	for {
+		chunk, err := input.Next()
+		if err != nil {
+			// Undecided sub-streams go to the fallback route
+			for _, id := range s.order {
+				if st := s.streams[id]; st.lang == "" {
+					s.decide(st, s.last)
+				}
+			}
+			for _, in := range s.ins {
+				if err != io.EOF {
+					in.CloseWithError(err)
+				} else {
+					in.Close()
+				}
+			}
+			return
+		}
+		if chunk == nil {
+			continue
+		}
+		if err := s.route(chunk); err != nil {
+			input.CloseWithError(err)
+			for _, in := range s.ins {
+				in.CloseWithError(err)
+			}
+			return
+		}
+	}
+}
+
+// route sends chunk to the route of its sub-stream, detecting the
+// language of the sub-stream if it is undecided.
+func (s *languageState) route(chunk *genx.MessageChunk) error {
+	var id string
+	if chunk.Ctrl != nil {
+		id = chunk.Ctrl.StreamID
+	}
+	st := s.stream(id)
+	if st.lang != "" {
+		return s.ins[st.lang].Push(chunk)
+	}
+	st.pending = append(st.pending, chunk)
+	if chunk.Role != genx.RoleUser {
+		return s.decide(st, s.last)
+	}
+
+	lang, confidence, err := s.detect(st, chunk)
+	if err != nil {
+		return err
+	}
+	switch {
+	case lang != "" && confidence >= s.minConfidence:
+		return s.decide(st, lang)
+	case chunk.IsEndOfStream(),
+		utf8.RuneCountInString(st.text.String()) >= s.maxText,
+		len(st.audio) >= s.msToBytes(s.audioWindow):
+		return s.decide(st, s.last)
+	}
+	return nil
+}
+
+// detect adds the user content of chunk to st and detects its language.
+func (s *languageState) detect(st *languageStream, chunk *genx.MessageChunk) (string, float32, error) {
+	switch part := chunk.Part.(type) {
+	case genx.Text:
+		if part == "" {
+			return "", 0, nil
+		}
+		st.text.WriteString(string(part))
+		lang, confidence := s.detector.DetectText(st.text.String())
+		return lang, confidence, nil
+	case *genx.Blob:
+		if !isPCMMIME(part.MIMEType) {
+			return "", 0, nil
+		}
+		st.audio = append(st.audio, part.Data...)
+		// Analyze a full window, or the shorter audio of an ended sub-stream
+		full := len(st.audio) >= s.msToBytes(s.audioWindow)
+		if !full && !(chunk.IsEndOfStream() && len(st.audio) > 0) {
+			return "", 0, nil
+		}
+		lang, confidence, err := s.detector.DetectAudio(st.audio)
+		if err != nil {
+			return "", 0, fmt.Errorf("transformers: language router: %w", err)
+		}
+		return lang, confidence, nil
+	}
+	return "", 0, nil
+}
+
+// decide routes st to lang and sends its pending chunks.
+func (s *languageState) decide(st *languageStream, lang string) error {
+	if _, ok := s.ins[lang]; !ok {
+		lang = s.def
+	}
+	st.lang = lang
+	st.text.Reset()
+	st.audio = nil
+	s.last = lang
+
+	pending := st.pending
+	st.pending = nil
+	in := s.ins[lang]
+	for _, chunk := range pending {
+		if err := in.Push(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stream returns the state of sub-stream id, forgetting the oldest
+// decisions beyond maxStreams.
+func (s *languageState) stream(id string) *languageStream {
+	if st, ok := s.streams[id]; ok {
+		return st
+	}
+	for len(s.order) >= s.maxStreams {
+		oldest := s.streams[s.order[0]]
+		if oldest.lang == "" {
+			s.decide(oldest, s.last)
+		}
+		delete(s.streams, s.order[0])
+		s.order = s.order[1:]
+	}
+	st := &languageStream{}
+	s.streams[id] = st
+	s.order = append(s.order, id)
+	return st
+}
+
+func (s *languageState) msToBytes(ms int) int {
+	return s.sampleRate * 2 * ms / 1000
+}
+
+// forwardLanguage copies the output of the route of lang to output,
+// annotating each chunk with the language.
+func forwardLanguage(lang string, out genx.Stream, output *bufferStream) {
+	for {
+		chunk, err := out.Next()
+		if err != nil {
+			if err != io.EOF {
+				output.CloseWithError(err)
+			}
+			return
+		}
+		if chunk == nil {
+			continue
+		}
+		chunk.SetMetadata(MetaLanguage, lang)
+		if err := output.Push(chunk); err != nil {
+			out.CloseWithError(err)
+			return
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package transformers
 
 import (
+	"cmp"
 	"context"
 	"io"
 	"strings"
@@ -19,6 +20,15 @@ import (
 // EoS Handling:
 //   - When receiving a text/plain EoS marker, finish synthesis, emit audio chunks, then emit audio/* EoS
 //   - Non-text chunks are passed through unchanged
+//
+// Style:
+//   - The MetaTTSStyle of a sub-stream (e.g., happy, whisper) overrides
+//     WithMinimaxTTSEmotion for its synthesis
+//
+// Subtitles:
+//   - With WithMinimaxTTSSubtitles, the timing of each sentence is
+//     attached under MetaTTSTiming, see SubtitleCollector; MiniMax does not
+//     report word timings
 type MinimaxTTS struct {
 	client     *minimax.Client
 	model      string
@@ -30,6 +40,7 @@ type MinimaxTTS struct {
 	format     string
 	sampleRate int
 	bitrate    int
+	subtitles  bool
 }
 
 var _ genx.Transformer = (*MinimaxTTS)(nil)
@@ -97,6 +108,14 @@ func WithMinimaxTTSBitrate(bitrate int) MinimaxTTSOption {
 	}
 }
 
+// WithMinimaxTTSSubtitles enables sentence timestamps from the MiniMax
+// subtitle API.
+func WithMinimaxTTSSubtitles(enable bool) MinimaxTTSOption {
+	return func(t *MinimaxTTS) {
+		t.subtitles = enable
+	}
+}
+
 // NewMinimaxTTS creates a new MinimaxTTS transformer.
 //
 // Parameters:
@@ -155,6 +174,7 @@ func (t *MinimaxTTS) transformLoop(input genx.Stream, output *bufferStream) {
 	mimeType := t.mimeType()
 	var textBuilder strings.Builder
 	var lastChunk *genx.MessageChunk
+	var style ttsStyle
 	var currentStreamID string
 
 	for {
@@ -166,7 +186,7 @@ func (t *MinimaxTTS) transformLoop(input genx.Stream, output *bufferStream) {
 			}
 			// EOF: synthesize any remaining text
 			if textBuilder.Len() > 0 {
-				if err := t.synthesize(ctx, textBuilder.String(), lastChunk, currentStreamID, mimeType, output); err != nil {
+				if err := t.synthesize(ctx, textBuilder.String(), style.take(minimaxTTSStyles), lastChunk, currentStreamID, mimeType, output); err != nil {
 					output.CloseWithError(err)
 					return
 				}
@@ -179,6 +199,9 @@ func (t *MinimaxTTS) transformLoop(input genx.Stream, output *bufferStream) {
 		}
 
 		lastChunk = chunk
+		if _, ok := chunk.Part.(genx.Text); ok {
+			style.observe(chunk)
+		}
 
 		// Track StreamID from input - inherit or generate new one
 		if chunk.Ctrl != nil && chunk.Ctrl.StreamID != "" {
@@ -192,8 +215,9 @@ func (t *MinimaxTTS) transformLoop(input genx.Stream, output *bufferStream) {
 		if chunk.IsEndOfStream() {
 			if _, ok := chunk.Part.(genx.Text); ok {
 				// Text EoS: synthesize accumulated text, emit audio, then emit audio EoS
+				emotion := style.take(minimaxTTSStyles)
 				if textBuilder.Len() > 0 {
-				if err := t.synthesize(ctx, textBuilder.String(), lastChunk, currentStreamID, mimeType, output); err != nil {
+				if err := t.synthesize(ctx, textBuilder.String(), emotion, lastChunk, currentStreamID, mimeType, output); err != nil {
 					output.CloseWithError(err)
 					return
 				}
@@ -242,7 +266,7 @@ func (t *MinimaxTTS) transformLoop(input genx.Stream, output *bufferStream) {
 	}
 }
 
-func (t *MinimaxTTS) synthesize(ctx context.Context, text string, lastChunk *genx.MessageChunk, streamID, mimeType string, output *bufferStream) error {
+func (t *MinimaxTTS) synthesize(ctx context.Context, text, emotion string, lastChunk *genx.MessageChunk, streamID, mimeType string, output *bufferStream) error {
 	// Emit BOS at the start of synthesis
 	bosChunk := &genx.MessageChunk{
 		Ctrl: &genx.StreamCtrl{StreamID: streamID, BeginOfStream: true},
@@ -263,13 +287,14 @@ func (t *MinimaxTTS) synthesize(ctx context.Context, text string, lastChunk *gen
 			Speed:   t.speed,
 			Vol:     t.vol,
 			Pitch:   t.pitch,
-			Emotion: t.emotion,
+			Emotion: cmp.Or(emotion, t.emotion),
 		},
 		AudioSetting: &minimax.AudioSetting{
 			Format:     minimax.AudioFormat(t.format),
 			SampleRate: t.sampleRate,
 			Bitrate:    t.bitrate,
 		},
+		SubtitleEnable: t.subtitles,
 	}
 
 	for chunk, err := range t.client.Speech.SynthesizeStream(ctx, req) {
@@ -277,6 +302,15 @@ func (t *MinimaxTTS) synthesize(ctx context.Context, text string, lastChunk *gen
 			return err
 		}
 
+		var timings []SentenceTiming
+		if t.subtitles && chunk.Subtitle != nil && chunk.Subtitle.Text != "" {
+			timings = append(timings, SentenceTiming{
+				Text:  chunk.Subtitle.Text,
+				Start: int64(chunk.Subtitle.StartTime),
+				End:   int64(chunk.Subtitle.EndTime),
+			})
+		}
+
 		if chunk.Audio != nil && len(chunk.Audio) > 0 {
 			outChunk := &genx.MessageChunk{
 				Part: &genx.Blob{
@@ -289,10 +323,17 @@ func (t *MinimaxTTS) synthesize(ctx context.Context, text string, lastChunk *gen
 				outChunk.Role = lastChunk.Role
 				outChunk.Name = lastChunk.Name
 			}
+			if len(timings) > 0 {
+				SetTTSTiming(outChunk, timings...)
+			}
 
 			if err := output.Push(outChunk); err != nil {
 				return err
 			}
+		} else if len(timings) > 0 {
+			if err := output.Push(newTTSTimingChunk(lastChunk, streamID, timings)); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
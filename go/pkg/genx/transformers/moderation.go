@@ -0,0 +1,361 @@
+package transformers
+
+import (
+	"context"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// Metadata keys written by the Moderation transformer into StreamCtrl.Metadata.
+const (
+	// MetaModeration is "masked" when blocklisted words were masked, or
+	// "replaced" when the Moderator flagged the text and it was replaced.
+	MetaModeration = "moderation"
+	// MetaModerationCategories holds the Moderator's categories as a
+	// comma-separated list (e.g., "violence,self-harm").
+	MetaModerationCategories = "moderation.categories"
+)
+
+// ModerationResult is the verdict of a Moderator.
+type ModerationResult struct {
+	// Flagged reports that the text must not be passed on.
+	Flagged bool
+	// Categories lists the reasons, if the Moderator provides them.
+	Categories []string
+}
+
+// Moderator checks text with an external moderation service (e.g., the
+// OpenAI moderation API). Implementations must be safe for concurrent use.
+type Moderator interface {
+	Moderate(ctx context.Context, text string) (ModerationResult, error)
+}
+
+// ModeratorFunc adapts a function to the Moderator interface.
+type ModeratorFunc func(ctx context.Context, text string) (ModerationResult, error)
+
+// Moderate calls f.
+func (f ModeratorFunc) Moderate(ctx context.Context, text string) (ModerationResult, error) {
+	return f(ctx, text)
+}
+
+// Moderation is a text safety filter for kid-facing devices. It screens
+// RoleModel text (and optionally RoleUser text, e.g., ASR results) before
+// it reaches TTS or the client.
+//
+// Text is buffered up to the end of each sentence (or EoS) so that words
+// split across chunks are caught, then:
+//
+//   - Blocklisted words are masked rune by rune (default '*'). ASCII words
+//     match case-insensitively on word boundaries; other words (e.g.,
+//     Chinese) match anywhere.
+//   - If a Moderator is set and flags the sentence, the whole sentence is
+//     replaced with the replacement text, or dropped if it is empty. A
+//     Moderator error or timeout is treated as flagged (fail closed).
+//
+// Text is buffered per sub-stream (role, name and StreamID). Emitted chunks
+// keep the StreamID and metadata of the input; the first one of a
+// sub-stream that began with a BOS marker carries BOS. Changed chunks also
+// carry MetaModeration. Non-text chunks pass through unchanged; pending text
+// of the same role and StreamID is flushed before them to keep the order,
+// while the text of other sub-streams stays buffered.
+//
+// Input: text (RoleModel; RoleUser with WithModerationUserText)
+// Output: text, one chunk per sentence
+type Moderation struct {
+	blocklist   *regexp.Regexp
+	mask        rune
+	replacement string
+	moderator   Moderator
+	timeout     time.Duration
+	userText    bool
+}
+
+var _ genx.Transformer = (*Moderation)(nil)
+
+// ModerationOption configures a Moderation transformer.
+type ModerationOption func(*Moderation)
+
+// WithModerationBlocklist sets the words to mask.
+func WithModerationBlocklist(words ...string) ModerationOption {
+	return func(t *Moderation) {
+		t.blocklist = compileBlocklist(words)
+	}
+}
+
+// WithModerationMask sets the rune that replaces each rune of a blocklisted
+// word (default '*').
+func WithModerationMask(mask rune) ModerationOption {
+	return func(t *Moderation) {
+		t.mask = mask
+	}
+}
+
+// WithModerator sets an external Moderator.
+func WithModerator(m Moderator) ModerationOption {
+	return func(t *Moderation) {
+		t.moderator = m
+	}
+}
+
+// WithModerationReplacement sets the text that replaces a flagged sentence
+// (default: drop the sentence).
+func WithModerationReplacement(text string) ModerationOption {
+	return func(t *Moderation) {
+		t.replacement = text
+	}
+}
+
+// WithModerationTimeout sets the timeout of a Moderator call (default 3s).
+func WithModerationTimeout(d time.Duration) ModerationOption {
+	return func(t *Moderation) {
+		if d > 0 {
+			t.timeout = d
+		}
+	}
+}
+
+// WithModerationUserText also screens RoleUser text.
+func WithModerationUserText() ModerationOption {
+	return func(t *Moderation) {
+		t.userText = true
+	}
+}
+
+// NewModeration creates a Moderation transformer.
+func NewModeration(opts ...ModerationOption) *Moderation {
+	t := &Moderation{
+		mask:    '*',
+		timeout: 3 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Transform implements genx.Transformer. Moderator calls use ctx; the
+// pattern is unused.
+func (t *Moderation) Transform(ctx context.Context, _ string, input genx.Stream) (genx.Stream, error) {
+	output := newBufferStream(100)
+	go t.transformLoop(ctx, input, output)
+	return output, nil
+}
+
+// pendingText is buffered text of one sub-stream.
+type pendingText struct {
+	tmpl *genx.MessageChunk
+	buf  strings.Builder
+	// bos reports that the sub-stream began with a BOS marker that has not
+	// been emitted yet.
+	bos bool
+}
+
+// pendingKey identifies a sub-stream of text: its role, name and StreamID.
+type pendingKey struct {
+	role     genx.Role
+	name     string
+	streamID string
+}
+
+func (t *Moderation) transformLoop(ctx context.Context, input genx.Stream, output *bufferStream) {
+	defer output.Close()
+
+	pending := make(map[pendingKey]*pendingText)
+	var order []pendingKey
+
+	flush := func(p *pendingText, text string) error {
+		chunk := t.screen(ctx, p.tmpl, text)
+		if chunk == nil {
+			return nil
+		}
+		if p.bos {
+			chunk.Ctrl.BeginOfStream = true
+			p.bos = false
+		}
+		return output.Push(chunk)
+	}
+	flushKey := func(key pendingKey) error {
+		p := pending[key]
+		text := p.buf.String()
+		p.buf.Reset()
+		if text == "" {
+			return nil
+		}
+		return flush(p, text)
+	}
+	// flushMatching flushes the text pending for the sub-streams of role and
+	// streamID. An empty role matches every role.
+	flushMatching := func(role genx.Role, streamID string) error {
+		for _, key := range order {
+			if key.streamID != streamID || role != "" && key.role != role {
+				continue
+			}
+			if err := flushKey(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for {
+		chunk, err := input.Next()
+		if err != nil {
+			for _, key := range order {
+				if flushKey(key) != nil {
+					return
+				}
+			}
+			if err != io.EOF {
+				output.CloseWithError(err)
+			}
+			return
+		}
+		if chunk == nil {
+			continue
+		}
+
+		text, ok := chunk.Part.(genx.Text)
+		if !ok || !t.screens(chunk.Role) {
+			if err := flushMatching(chunk.Role, chunkStreamID(chunk)); err != nil {
+				return
+			}
+			if err := output.Push(chunk); err != nil {
+				return
+			}
+			continue
+		}
+
+		key := pendingKey{chunk.Role, chunk.Name, chunkStreamID(chunk)}
+		p, ok := pending[key]
+		if !ok {
+			p = &pendingText{}
+			pending[key] = p
+			order = append(order, key)
+		}
+		p.tmpl = chunk
+		p.buf.WriteString(string(text))
+		if chunk.IsBeginOfStream() {
+			p.bos = true
+		}
+
+		if chunk.IsEndOfStream() {
+			if err := flushKey(key); err != nil {
+				return
+			}
+			eos := chunk.Clone()
+			eos.Part = genx.Text("")
+			eos.Ctrl.BeginOfStream = p.bos
+			delete(pending, key)
+			order = slices.DeleteFunc(order, func(k pendingKey) bool { return k == key })
+			if err := output.Push(eos); err != nil {
+				return
+			}
+			continue
+		}
+
+		buffered := p.buf.String()
+		if i := genx.LastSentenceEnd(buffered); i > 0 {
+			p.buf.Reset()
+			p.buf.WriteString(buffered[i:])
+			if err := flush(p, buffered[:i]); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (t *Moderation) screens(role genx.Role) bool {
+	return role == genx.RoleModel || (t.userText && role == genx.RoleUser)
+}
+
+// chunkStreamID returns the StreamID of chunk, or "" without Ctrl.
+func chunkStreamID(chunk *genx.MessageChunk) string {
+	if chunk.Ctrl == nil {
+		return ""
+	}
+	return chunk.Ctrl.StreamID
+}
+
+// screen returns the chunk to emit for text, or nil to drop it.
+func (t *Moderation) screen(ctx context.Context, tmpl *genx.MessageChunk, text string) *genx.MessageChunk {
+	out := &genx.MessageChunk{
+		Role: tmpl.Role,
+		Name: tmpl.Name,
+		Part: genx.Text(text),
+	}
+	ctrl := genx.StreamCtrl{}
+	if tmpl.Ctrl != nil {
+		ctrl = *tmpl.Ctrl
+		ctrl.BeginOfStream = false
+		ctrl.EndOfStream = false
+		ctrl.Metadata = maps.Clone(tmpl.Ctrl.Metadata)
+	}
+	out.Ctrl = &ctrl
+
+	if t.moderator != nil && strings.TrimSpace(text) != "" {
+		ctx, cancel := context.WithTimeout(ctx, t.timeout)
+		res, err := t.moderator.Moderate(ctx, text)
+		cancel()
+		if err != nil || res.Flagged {
+			if t.replacement == "" {
+				return nil
+			}
+			out.Part = genx.Text(t.replacement)
+			out.SetMetadata(MetaModeration, "replaced")
+			if len(res.Categories) > 0 {
+				out.SetMetadata(MetaModerationCategories, strings.Join(res.Categories, ","))
+			}
+			return out
+		}
+	}
+
+	if t.blocklist != nil {
+		masked := t.blocklist.ReplaceAllStringFunc(text, func(word string) string {
+			return strings.Repeat(string(t.mask), len([]rune(word)))
+		})
+		if masked != text {
+			out.Part = genx.Text(masked)
+			out.SetMetadata(MetaModeration, "masked")
+		}
+	}
+	return out
+}
+
+// compileBlocklist builds a case-insensitive pattern matching any word.
+func compileBlocklist(words []string) *regexp.Regexp {
+	var alts []string
+	for _, w := range words {
+		w = strings.TrimSpace(w)
+		if w == "" {
+			continue
+		}
+		alt := regexp.QuoteMeta(w)
+		if isASCIIWord(w) {
+			alt = `\b` + alt + `\b`
+		}
+		alts = append(alts, alt)
+	}
+	if len(alts) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`(?i)(?:` + strings.Join(alts, "|") + `)`)
+}
+
+func isASCIIWord(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+	isWordChar := func(b byte) bool {
+		return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+	}
+	return isWordChar(s[0]) && isWordChar(s[len(s)-1])
+}
@@ -0,0 +1,168 @@
+package transformers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// chunkInput returns a stream of chunks.
+func chunkInput(chunks ...*genx.MessageChunk) genx.Stream {
+	s := newBufferStream(len(chunks) + 1)
+	for _, chunk := range chunks {
+		s.Push(chunk)
+	}
+	s.Close()
+	return s
+}
+
+// runModeration runs the chunks through m and describes its output.
+func runModeration(t *testing.T, ctx context.Context, m *Moderation, chunks ...*genx.MessageChunk) []string {
+	t.Helper()
+	out, err := m.Transform(ctx, "", chunkInput(chunks...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for {
+		chunk, err := out.Next()
+		if err != nil {
+			return got
+		}
+		got = append(got, describeChunk(chunk))
+	}
+}
+
+func userText(text string) *genx.MessageChunk {
+	return &genx.MessageChunk{Role: genx.RoleUser, Part: genx.Text(text)}
+}
+
+func TestModeration_Blocklist(t *testing.T) {
+	m := NewModeration(WithModerationBlocklist("darn", "笨蛋"))
+	got := runModeration(t, context.Background(), m,
+		modelText("Well da"), modelText("rn it, 你这个笨蛋。"), modelText("Darned"), modelEoS("text/plain"))
+	want := []string{
+		"model text Well **** it, 你这个**。",
+		"model text Darned",
+		"model text EoS",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("output:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestModeration_FlushesOnlyTheRoleOfAChunk(t *testing.T) {
+	// User audio in the middle of a model sentence does not cut it: only
+	// pending text of the audio's role is flushed before it
+	m := NewModeration(WithModerationBlocklist("darn"), WithModerationUserText())
+	got := runModeration(t, context.Background(), m,
+		modelText("Oh da"),
+		userText("I said"),
+		userAudio("a1"),
+		modelText("rn."),
+		modelAudio("m1"),
+		modelText("Bye"),
+	)
+	want := []string{
+		"user text I said",
+		"user audio/pcm a1",
+		"model text Oh ****.",
+		"model audio/mp3 m1",
+		"model text Bye",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("output:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+type ctxKey struct{}
+
+func TestModeration_ModeratorContext(t *testing.T) {
+	var seen []any
+	moderator := ModeratorFunc(func(ctx context.Context, text string) (ModerationResult, error) {
+		seen = append(seen, ctx.Value(ctxKey{}))
+		if err := ctx.Err(); err != nil {
+			return ModerationResult{}, err
+		}
+		return ModerationResult{Flagged: strings.Contains(text, "bad"), Categories: []string{"harassment"}}, nil
+	})
+	m := NewModeration(WithModerator(moderator), WithModerationReplacement("[removed]"))
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "session-1")
+	got := runModeration(t, ctx, m, modelText("Fine. Bad? "), modelText("bad!"), modelEoS("text/plain"))
+	want := []string{"model text Fine. Bad?", "model text [removed]", "model text EoS"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("output:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if len(seen) != 2 {
+		t.Errorf("Moderator called %d times, want 2", len(seen))
+	}
+	for _, v := range seen {
+		if v != "session-1" {
+			t.Errorf("Moderator ctx value = %v, want the Transform ctx", v)
+		}
+	}
+
+	// A cancelled Transform ctx fails closed
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	got = runModeration(t, ctx, m, modelText("Fine."))
+	if want := "model text [removed]"; len(got) != 1 || got[0] != want {
+		t.Errorf("output with cancelled ctx = %q, want %q", got, want)
+	}
+}
+
+func TestModeration_SubStreams(t *testing.T) {
+	text := func(streamID, text string, bos, eos bool) *genx.MessageChunk {
+		return &genx.MessageChunk{Role: genx.RoleModel, Part: genx.Text(text), Ctrl: &genx.StreamCtrl{
+			StreamID: streamID, BeginOfStream: bos, EndOfStream: eos,
+			Metadata: map[string]string{MetaTTSStyle: "cheerful"},
+		}}
+	}
+	// Two sub-streams of the same role, interleaved mid-sentence
+	input := []*genx.MessageChunk{
+		text("s1", "", true, false),
+		text("s1", "Oh da", false, false),
+		text("s2", "Hi ", true, false),
+		text("s1", "rn. Bye", false, false),
+		text("s2", "there.", false, false),
+		text("s1", "", false, true),
+		text("s2", "", false, true),
+	}
+	out, err := NewModeration(WithModerationBlocklist("darn")).Transform(context.Background(), "", chunkInput(input...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for {
+		chunk, err := out.Next()
+		if err != nil {
+			break
+		}
+		desc := chunk.Ctrl.StreamID + " " + describeChunk(chunk)
+		if v := chunk.Metadata(MetaModeration); v != "" {
+			desc += " " + v
+		}
+		if chunk.Metadata(MetaTTSStyle) != "cheerful" {
+			t.Errorf("%s: metadata %v lost", desc, chunk.Ctrl.Metadata)
+		}
+		got = append(got, desc)
+	}
+	want := []string{
+		"s1 model text Oh ****. BoS masked",
+		"s2 model text Hi there. BoS",
+		"s1 model text  Bye",
+		"s1 model text EoS",
+		"s2 model text EoS",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("output:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	for _, chunk := range input {
+		if chunk.Metadata(MetaModeration) != "" {
+			t.Errorf("input chunk annotated in place: %v", chunk.Ctrl.Metadata)
+		}
+	}
+}
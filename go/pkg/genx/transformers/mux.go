@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sync"
 
 	"github.com/haivivi/giztoy/go/pkg/buffer"
 	"github.com/haivivi/giztoy/go/pkg/genx"
@@ -29,12 +30,16 @@ func Transform(ctx context.Context, pattern string, input genx.Stream) (genx.Str
 // based on pattern matching using a trie.
 type Mux struct {
 	mux *trie.Trie[genx.Transformer]
+
+	mu     sync.Mutex
+	routes map[string]*failover // failover routes, by pattern
 }
 
 // NewMux creates a new transformer multiplexer.
 func NewMux() *Mux {
 	return &Mux{
-		mux: trie.New[genx.Transformer](),
+		mux:    trie.New[genx.Transformer](),
+		routes: make(map[string]*failover),
 	}
 }
 
@@ -129,8 +134,13 @@ func (s *bufferStream) CloseWithError(err error) error {
 	return nil
 }
 
+// Push adds chunk to the stream. A chunk that cannot be added is released.
 func (s *bufferStream) Push(chunk *genx.MessageChunk) error {
-	return s.buf.Add(chunk)
+	if err := s.buf.Add(chunk); err != nil {
+		chunk.Release()
+		return err
+	}
+	return nil
 }
 
 // streamToReader converts a genx.Stream of Text chunks to an io.Reader.
@@ -54,15 +54,34 @@ func (m *ASR) Handle(pattern string, t genx.Transformer) error {
 // Create creates a new ASR session for the given model pattern.
 // Returns an ASRSession that can be used to send audio and receive text.
 func (m *ASR) Create(ctx context.Context, pattern string) (*ASRSession, error) {
-	ptr, ok := m.mux.Get(pattern)
-	if !ok {
-		return nil, fmt.Errorf("asr: transformer not found for %s", pattern)
+	t, err := m.get(pattern)
+	if err != nil {
+		return nil, err
 	}
-	t := *ptr
-	if t == nil {
+	return m.create(ctx, pattern, t)
+}
+
+// CreateContinuous creates an ASR session for continuous input longer than
+// a backend session may last, e.g. a meeting recording. Backend sessions
+// are rotated as configured by cfg, see ContinuousASR; Output emits the
+// final results as they are recognized, until Close.
+func (m *ASR) CreateContinuous(ctx context.Context, pattern string, cfg ContinuousASRConfig) (*ASRSession, error) {
+	t, err := m.get(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return m.create(ctx, pattern, NewContinuousASR(t, cfg))
+}
+
+func (m *ASR) get(pattern string) (genx.Transformer, error) {
+	ptr, ok := m.mux.Get(pattern)
+	if !ok || *ptr == nil {
 		return nil, fmt.Errorf("asr: transformer not found for %s", pattern)
 	}
+	return *ptr, nil
+}
 
+func (m *ASR) create(ctx context.Context, pattern string, t genx.Transformer) (*ASRSession, error) {
 	// Create input stream for audio
 	inputStream := newBufferStream(100)
 
@@ -0,0 +1,517 @@
+package transformers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/promtext"
+)
+
+const (
+	// DefaultBreakerFailures is the number of consecutive failures that
+	// open the circuit of a failover target.
+	DefaultBreakerFailures = 3
+
+	// DefaultBreakerCooldown is how long an open circuit skips its target.
+	DefaultBreakerCooldown = 30 * time.Second
+
+	// maxReplayChunks bounds the input a failover route keeps for replaying
+	// to the next target. Longer inputs fail over only before that.
+	maxReplayChunks = 1024
+)
+
+// FailoverTarget is a target of a failover route: the pattern of another
+// transformer of the same mux.
+type FailoverTarget struct {
+	Pattern string
+
+	// Weight spreads requests over the targets: each request tries them in
+	// a random order, a target coming first in proportion to its weight.
+	// Targets without weight come last, in order. If no target has a
+	// weight, they are tried in order.
+	Weight int
+}
+
+// FailoverOption configures a failover route.
+type FailoverOption func(*failover)
+
+// WithBreaker sets the consecutive failures that open the circuit of a
+// target and how long it stays open (defaults DefaultBreakerFailures and
+// DefaultBreakerCooldown). A failures of 0 disables circuit breaking.
+func WithBreaker(failures int, cooldown time.Duration) FailoverOption {
+	return func(f *failover) {
+		f.breakerFailures = failures
+		f.breakerCooldown = cooldown
+	}
+}
+
+// HandleFailover registers a failover route to the default mux.
+func HandleFailover(pattern string, targets []FailoverTarget, opts ...FailoverOption) error {
+	return DefaultMux.HandleFailover(pattern, targets, opts...)
+}
+
+// HandleFailover registers pattern as a route to the transformers of
+// targets, so that an outage of one backend falls back to the next:
+//
+//	mux.Handle("tts/minimax", minimaxTTS)
+//	mux.Handle("tts/doubao", doubaoTTS)
+//	mux.HandleFailover("tts/primary", []transformers.FailoverTarget{
+//	    {Pattern: "tts/minimax"},
+//	    {Pattern: "tts/doubao"},
+//	})
+//
+// A request tries the targets in order (see FailoverTarget.Weight), each
+// transformed with its own pattern. It moves on to the next target when
+// the target fails to start or its output fails before the first chunk;
+// the input read so far is replayed to the next target. Once output has
+// started, errors are returned as is.
+//
+// After consecutive failures the circuit of a target opens (see
+// WithBreaker): while open, the target is tried only after the others.
+// The counters of the route are in Stats and WritePrometheus.
+//
+// Targets are resolved per request, so they may be registered later.
+func (m *Mux) HandleFailover(pattern string, targets []FailoverTarget, opts ...FailoverOption) error {
+	if len(targets) == 0 {
+		return fmt.Errorf("transformers: failover %s has no targets", pattern)
+	}
+	f := &failover{
+		mux:             m,
+		pattern:         pattern,
+		targets:         slices.Clone(targets),
+		breakerFailures: DefaultBreakerFailures,
+		breakerCooldown: DefaultBreakerCooldown,
+		breakers:        make([]breaker, len(targets)),
+	}
+	for _, t := range targets {
+		if t.Pattern == pattern {
+			return fmt.Errorf("transformers: failover %s targets itself", pattern)
+		}
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if err := m.Handle(pattern, f); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.routes[pattern] = f
+	m.mu.Unlock()
+	return nil
+}
+
+// RouteStats are the counters of a failover route since it was registered.
+type RouteStats struct {
+	// Requests is the number of Transform calls, and Errors the number of
+	// those that failed on every target.
+	Requests int64
+	Errors   int64
+
+	// Fallbacks is the number of times a request moved on to the next
+	// target.
+	Fallbacks int64
+
+	// Targets are the counters per target pattern.
+	Targets map[string]TargetStats
+}
+
+// TargetStats are the counters of a failover target.
+type TargetStats struct {
+	// Attempts is the number of requests tried on the target, and Failures
+	// the number of those that failed.
+	Attempts int64
+	Failures int64
+
+	// CircuitOpen reports whether the target is skipped after consecutive
+	// failures.
+	CircuitOpen bool
+}
+
+// Stats returns the counters of the failover routes, by pattern.
+func (m *Mux) Stats() map[string]RouteStats {
+	m.mu.Lock()
+	routes := make(map[string]*failover, len(m.routes))
+	for p, f := range m.routes {
+		routes[p] = f
+	}
+	m.mu.Unlock()
+
+	out := make(map[string]RouteStats, len(routes))
+	now := time.Now()
+	for p, f := range routes {
+		out[p] = f.stats(now)
+	}
+	return out
+}
+
+// WritePrometheus writes the counters of the failover routes in the
+// Prometheus text exposition format, labeled by route and target.
+func (m *Mux) WritePrometheus(w io.Writer) error {
+	all := m.Stats()
+	routes := make([]string, 0, len(all))
+	for p := range all {
+		routes = append(routes, p)
+	}
+	slices.Sort(routes)
+
+	pw := promtext.NewWriter(w)
+	route := func(name, help string, value func(RouteStats) int64) {
+		pw.Header(name, help, "counter")
+		for _, p := range routes {
+			pw.Int(name, promtext.Label("route", p), value(all[p]))
+		}
+	}
+	target := func(name, help, typ string, value func(TargetStats) int64) {
+		pw.Header(name, help, typ)
+		for _, p := range routes {
+			targets := make([]string, 0, len(all[p].Targets))
+			for t := range all[p].Targets {
+				targets = append(targets, t)
+			}
+			slices.Sort(targets)
+			for _, t := range targets {
+				pw.Int(name, promtext.Label("route", p, "target", t), value(all[p].Targets[t]))
+			}
+		}
+	}
+	route("giztoy_transformer_requests_total", "Requests to a failover route.", func(s RouteStats) int64 { return s.Requests })
+	route("giztoy_transformer_errors_total", "Requests failed on every target.", func(s RouteStats) int64 { return s.Errors })
+	route("giztoy_transformer_fallbacks_total", "Requests moved on to the next target.", func(s RouteStats) int64 { return s.Fallbacks })
+	target("giztoy_transformer_attempts_total", "Requests tried on a target.", "counter", func(s TargetStats) int64 { return s.Attempts })
+	target("giztoy_transformer_failures_total", "Requests failed on a target.", "counter", func(s TargetStats) int64 { return s.Failures })
+	target("giztoy_transformer_circuit_open", "Whether the circuit of a target is open.", "gauge", func(s TargetStats) int64 {
+		if s.CircuitOpen {
+			return 1
+		}
+		return 0
+	})
+	return pw.Err()
+}
+
+// PrometheusHandler returns an HTTP handler serving WritePrometheus, to be
+// mounted at a metrics endpoint scraped by Prometheus.
+func (m *Mux) PrometheusHandler() http.Handler {
+	return promtext.Handler(m.WritePrometheus)
+}
+
+// failover is the transformer of a failover route.
+type failover struct {
+	mux             *Mux
+	pattern         string
+	targets         []FailoverTarget
+	breakerFailures int
+	breakerCooldown time.Duration
+
+	mu        sync.Mutex
+	breakers  []breaker // by target index
+	requests  int64
+	errCount  int64
+	fallbacks int64
+}
+
+var _ genx.Transformer = (*failover)(nil)
+
+// breaker is the circuit breaker and counters of a target.
+type breaker struct {
+	consecutive int
+	openUntil   time.Time
+	attempts    int64
+	failures    int64
+}
+
+func (f *failover) stats(now time.Time) RouteStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := RouteStats{
+		Requests:  f.requests,
+		Errors:    f.errCount,
+		Fallbacks: f.fallbacks,
+		Targets:   make(map[string]TargetStats, len(f.targets)),
+	}
+	for i, t := range f.targets {
+		b := f.breakers[i]
+		s.Targets[t.Pattern] = TargetStats{
+			Attempts:    b.attempts,
+			Failures:    b.failures,
+			CircuitOpen: now.Before(b.openUntil),
+		}
+	}
+	return s
+}
+
+// order returns the target indexes in the order a request tries them.
+func (f *failover) order() []int {
+	order := make([]int, len(f.targets))
+	keys := make([]float64, len(f.targets))
+	for i, t := range f.targets {
+		order[i] = i
+		// Weighted random order: sort by u^(1/w) descending
+		if t.Weight > 0 {
+			keys[i] = math.Pow(rand.Float64(), 1/float64(t.Weight))
+		} else {
+			keys[i] = -1
+		}
+	}
+	now := time.Now()
+	f.mu.Lock()
+	open := make([]bool, len(f.targets))
+	for i := range f.breakers {
+		open[i] = now.Before(f.breakers[i].openUntil)
+	}
+	f.mu.Unlock()
+
+	slices.SortStableFunc(order, func(a, b int) int {
+		if open[a] != open[b] {
+			if open[a] {
+				return 1
+			}
+			return -1
+		}
+		switch {
+		case keys[a] > keys[b]:
+			return -1
+		case keys[a] < keys[b]:
+			return 1
+		}
+		return 0
+	})
+	return order
+}
+
+// attempt counts a request tried on target i; fallback reports whether it
+// follows a failed target.
+func (f *failover) attempt(i int, fallback bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.breakers[i].attempts++
+	if fallback {
+		f.fallbacks++
+	}
+}
+
+func (f *failover) succeed(i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.breakers[i].consecutive = 0
+	f.breakers[i].openUntil = time.Time{}
+}
+
+func (f *failover) fail(i int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b := &f.breakers[i]
+	b.failures++
+	b.consecutive++
+	if f.breakerFailures > 0 && b.consecutive >= f.breakerFailures {
+		if !time.Now().Before(b.openUntil) {
+			slog.Warn("transformers: failover circuit open", "route", f.pattern, "target", f.targets[i].Pattern, "error", err)
+		}
+		b.openUntil = time.Now().Add(f.breakerCooldown)
+	}
+}
+
+// Transform implements genx.Transformer. It returns an error if no target
+// starts.
+func (f *failover) Transform(ctx context.Context, _ string, input genx.Stream) (genx.Stream, error) {
+	f.mu.Lock()
+	f.requests++
+	f.mu.Unlock()
+
+	r := &failoverRun{f: f, ctx: ctx, input: input, order: f.order()}
+	r.mu.Lock()
+	out, err := r.startNextLocked(nil)
+	r.mu.Unlock()
+	if err != nil {
+		f.mu.Lock()
+		f.errCount++
+		f.mu.Unlock()
+		return nil, err
+	}
+
+	output := newBufferStream(100)
+	go r.inputLoop()
+	go r.outputLoop(out, output)
+	return output, nil
+}
+
+// failoverRun is a request of a failover route. The input loop forwards
+// the input to the current target, logging it for replay until the
+// output commits to the target; the output loop forwards the target's
+// output and moves on to the next target when it fails.
+type failoverRun struct {
+	f     *failover
+	ctx   context.Context
+	input genx.Stream
+	order []int // target indexes left to try, current first
+
+	mu        sync.Mutex
+	in        *bufferStream        // input of the current target
+	log       []*genx.MessageChunk // copies of the input, for replay
+	committed bool                 // no more failover
+	inputDone bool
+	inputErr  error
+}
+
+// startNextLocked starts the next target that starts, replaying the
+// logged input to it. lastErr is the error of the previous target, if
+// any.
+func (r *failoverRun) startNextLocked(lastErr error) (genx.Stream, error) {
+	errs := []error{}
+	if lastErr != nil {
+		errs = append(errs, lastErr)
+	}
+	for len(r.order) > 0 {
+		if err := r.ctx.Err(); err != nil {
+			return nil, err
+		}
+		i := r.order[0]
+		pattern := r.f.targets[i].Pattern
+		r.f.attempt(i, len(errs) > 0)
+
+		t, err := r.f.mux.get(pattern)
+		var out genx.Stream
+		var in *bufferStream
+		if err == nil {
+			in = newBufferStream(len(r.log) + 100)
+			for _, c := range r.log {
+				in.Push(c.Clone())
+			}
+			if r.inputDone {
+				closeInput(in, r.inputErr)
+			}
+			out, err = t.Transform(r.ctx, pattern, in)
+		}
+		if err != nil {
+			if in != nil {
+				in.CloseWithError(err)
+			}
+			r.f.fail(i, err)
+			errs = append(errs, fmt.Errorf("%s: %w", pattern, err))
+			r.order = r.order[1:]
+			continue
+		}
+		r.in = in
+		return out, nil
+	}
+	return nil, fmt.Errorf("transformers: all targets of %s failed: %w", r.f.pattern, errors.Join(errs...))
+}
+
+func closeInput(in *bufferStream, err error) {
+	if err != nil {
+		in.CloseWithError(err)
+	} else {
+		in.Close()
+	}
+}
+
+func (r *failoverRun) inputLoop() {
+	for {
+		chunk, err := r.input.Next()
+		r.mu.Lock()
+		if err != nil {
+			r.inputDone = true
+			if err != io.EOF {
+				r.inputErr = err
+			}
+			if r.in != nil {
+				closeInput(r.in, r.inputErr)
+			}
+			r.mu.Unlock()
+			return
+		}
+		if chunk == nil {
+			r.mu.Unlock()
+			continue
+		}
+		if !r.committed {
+			if len(r.log) < maxReplayChunks {
+				r.log = append(r.log, chunk.Clone())
+			} else {
+				r.commitLocked()
+			}
+		}
+		in := r.in
+		r.mu.Unlock()
+
+		if in == nil {
+			// Every target failed
+			chunk.Release()
+			return
+		}
+		// A push to a failed target is replayed to the next one
+		in.Push(chunk)
+	}
+}
+
+func (r *failoverRun) commitLocked() {
+	r.committed = true
+	r.log = nil
+}
+
+func (r *failoverRun) outputLoop(out genx.Stream, output *bufferStream) {
+	defer output.Close()
+	started := false
+	for {
+		chunk, err := out.Next()
+		if err == nil && chunk == nil {
+			continue
+		}
+		if err == nil {
+			if !started {
+				started = true
+				r.mu.Lock()
+				r.commitLocked()
+				r.mu.Unlock()
+				r.f.succeed(r.order[0])
+			}
+			if err := output.Push(chunk); err != nil {
+				out.CloseWithError(err)
+				return
+			}
+			continue
+		}
+
+		if err == io.EOF {
+			if !started {
+				r.f.succeed(r.order[0])
+			}
+			return
+		}
+		r.f.fail(r.order[0], err)
+		r.mu.Lock()
+		if r.committed || r.ctx.Err() != nil {
+			r.mu.Unlock()
+			output.CloseWithError(err)
+			return
+		}
+		r.in.CloseWithError(err)
+		pattern := r.f.targets[r.order[0]].Pattern
+		slog.Warn("transformers: failover to next target", "route", r.f.pattern, "target", pattern, "error", err)
+		r.order = r.order[1:]
+		next, startErr := r.startNextLocked(fmt.Errorf("%s: %w", pattern, err))
+		if startErr != nil {
+			r.in = nil
+		}
+		r.mu.Unlock()
+		if startErr != nil {
+			r.f.mu.Lock()
+			r.f.errCount++
+			r.f.mu.Unlock()
+			// Unblock the input loop, which waits for the next chunk
+			r.input.CloseWithError(startErr)
+			output.CloseWithError(startErr)
+			return
+		}
+		out = next
+	}
+}
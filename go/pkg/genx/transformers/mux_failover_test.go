@@ -0,0 +1,382 @@
+package transformers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+var (
+	errOutage = errors.New("backend outage")
+	errDial   = errors.New("dial failed")
+)
+
+// fakeTarget is a failover target. It fails to start with startErr,
+// returns output if set, or runs run on its input and output.
+type fakeTarget struct {
+	startErr error
+	output   genx.Stream
+	run      func(input genx.Stream, output *bufferStream)
+	calls    atomic.Int32
+}
+
+func (f *fakeTarget) Transform(_ context.Context, _ string, input genx.Stream) (genx.Stream, error) {
+	f.calls.Add(1)
+	if f.startErr != nil {
+		return nil, f.startErr
+	}
+	if f.output != nil {
+		return f.output, nil
+	}
+	output := newBufferStream(100)
+	go f.run(input, output)
+	return output, nil
+}
+
+// echo emits the text of every input chunk with prefix.
+func echo(prefix string) func(genx.Stream, *bufferStream) {
+	return func(input genx.Stream, output *bufferStream) {
+		for _, text := range readTexts(input) {
+			output.Push(&genx.MessageChunk{Part: genx.Text(prefix + text)})
+		}
+		output.Close()
+	}
+}
+
+// failNow fails the output before reading any input.
+func failNow(input genx.Stream, output *bufferStream) {
+	output.CloseWithError(errOutage)
+}
+
+// readThenFail reads the whole input into got, then fails the output.
+func readThenFail(got *[]string) func(genx.Stream, *bufferStream) {
+	return func(input genx.Stream, output *bufferStream) {
+		*got = readTexts(input)
+		output.CloseWithError(errOutage)
+	}
+}
+
+func readTexts(s genx.Stream) []string {
+	var texts []string
+	for {
+		chunk, err := s.Next()
+		if err != nil {
+			return texts
+		}
+		if text, ok := chunk.Part.(genx.Text); ok {
+			texts = append(texts, string(text))
+		}
+	}
+}
+
+// readOutput returns the texts of s and the error ending it, nil for EOF.
+func readOutput(s genx.Stream) ([]string, error) {
+	var texts []string
+	for {
+		chunk, err := s.Next()
+		if err == io.EOF {
+			return texts, nil
+		}
+		if err != nil {
+			return texts, err
+		}
+		texts = append(texts, string(chunk.Part.(genx.Text)))
+	}
+}
+
+func textInput(texts ...string) genx.Stream {
+	s := newBufferStream(len(texts) + 1)
+	for _, text := range texts {
+		s.Push(&genx.MessageChunk{Part: genx.Text(text)})
+	}
+	s.Close()
+	return s
+}
+
+// scriptStream returns its texts, then fails with err once fail is
+// closed.
+type scriptStream struct {
+	texts []string
+	fail  chan struct{}
+	err   error
+}
+
+func (s *scriptStream) Next() (*genx.MessageChunk, error) {
+	if len(s.texts) == 0 {
+		<-s.fail
+		return nil, s.err
+	}
+	text := s.texts[0]
+	s.texts = s.texts[1:]
+	return &genx.MessageChunk{Part: genx.Text(text)}, nil
+}
+
+func (s *scriptStream) Close() error                   { return nil }
+func (s *scriptStream) CloseWithError(err error) error { return nil }
+
+// idleInput never returns a chunk, like an upstream waiting for the user.
+type idleInput struct {
+	closed chan error
+}
+
+func (s *idleInput) Next() (*genx.MessageChunk, error) {
+	err := <-s.closed
+	s.closed <- err
+	return nil, err
+}
+
+func (s *idleInput) Close() error { return s.CloseWithError(io.EOF) }
+
+func (s *idleInput) CloseWithError(err error) error {
+	select {
+	case s.closed <- err:
+	default:
+	}
+	return nil
+}
+
+func failoverMux(t *testing.T, targets map[string]*fakeTarget, route []FailoverTarget, opts ...FailoverOption) *Mux {
+	t.Helper()
+	m := NewMux()
+	for pattern, target := range targets {
+		if err := m.Handle(pattern, target); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.HandleFailover("tts/primary", route, opts...); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestFailover_ReplaysInputToNextTarget(t *testing.T) {
+	var gotA []string
+	a := &fakeTarget{run: readThenFail(&gotA)}
+	b := &fakeTarget{run: echo("b:")}
+	m := failoverMux(t, map[string]*fakeTarget{"tts/a": a, "tts/b": b},
+		[]FailoverTarget{{Pattern: "tts/a"}, {Pattern: "tts/b"}})
+
+	out, err := m.Transform(context.Background(), "tts/primary", textInput("x", "y"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := readOutput(out)
+	if err != nil || strings.Join(got, ",") != "b:x,b:y" {
+		t.Fatalf("output = %q, %v; want the input replayed to tts/b", got, err)
+	}
+	if strings.Join(gotA, ",") != "x,y" {
+		t.Errorf("tts/a input = %q", gotA)
+	}
+
+	st := m.Stats()["tts/primary"]
+	if st.Requests != 1 || st.Fallbacks != 1 || st.Errors != 0 {
+		t.Errorf("route stats = %+v", st)
+	}
+	if ta := st.Targets["tts/a"]; ta.Attempts != 1 || ta.Failures != 1 || ta.CircuitOpen {
+		t.Errorf("tts/a stats = %+v", ta)
+	}
+
+	var sb strings.Builder
+	if err := m.WritePrometheus(&sb); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`giztoy_transformer_fallbacks_total{route="tts/primary"} 1`,
+		`giztoy_transformer_failures_total{route="tts/primary",target="tts/a"} 1`,
+		`giztoy_transformer_attempts_total{route="tts/primary",target="tts/b"} 1`,
+	} {
+		if !strings.Contains(sb.String(), line+"\n") {
+			t.Errorf("metrics missing %q:\n%s", line, sb.String())
+		}
+	}
+}
+
+func TestFailover_StartError(t *testing.T) {
+	a := &fakeTarget{startErr: errDial}
+	b := &fakeTarget{run: echo("b:")}
+	m := failoverMux(t, map[string]*fakeTarget{"tts/a": a, "tts/b": b},
+		[]FailoverTarget{{Pattern: "tts/a"}, {Pattern: "tts/b"}})
+
+	out, err := m.Transform(context.Background(), "tts/primary", textInput("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readOutput(out); err != nil || strings.Join(got, ",") != "b:x" {
+		t.Errorf("output = %q, %v", got, err)
+	}
+	if st := m.Stats()["tts/primary"]; st.Fallbacks != 1 {
+		t.Errorf("fallbacks = %d, want 1", st.Fallbacks)
+	}
+}
+
+func TestFailover_NoFailoverAfterOutput(t *testing.T) {
+	fail := make(chan struct{})
+	a := &fakeTarget{output: &scriptStream{texts: []string{"a:first"}, fail: fail, err: errOutage}}
+	b := &fakeTarget{run: echo("b:")}
+	m := failoverMux(t, map[string]*fakeTarget{"tts/a": a, "tts/b": b},
+		[]FailoverTarget{{Pattern: "tts/a"}, {Pattern: "tts/b"}})
+
+	out, err := m.Transform(context.Background(), "tts/primary", textInput("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunk, err := out.Next(); err != nil || chunk.Part != genx.Text("a:first") {
+		t.Fatalf("first chunk = %+v, %v", chunk, err)
+	}
+	close(fail)
+	if got, err := readOutput(out); !errors.Is(err, errOutage) || len(got) != 0 {
+		t.Errorf("output = %q, %v; want the outage", got, err)
+	}
+	if n := b.calls.Load(); n != 0 {
+		t.Errorf("tts/b called %d times after output started", n)
+	}
+}
+
+func TestFailover_AllTargetsFailClosesInput(t *testing.T) {
+	a := &fakeTarget{run: failNow}
+	b := &fakeTarget{startErr: errDial}
+	m := failoverMux(t, map[string]*fakeTarget{"tts/a": a, "tts/b": b},
+		[]FailoverTarget{{Pattern: "tts/a"}, {Pattern: "tts/b"}})
+
+	input := &idleInput{closed: make(chan error, 1)}
+	out, err := m.Transform(context.Background(), "tts/primary", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readOutput(out); !errors.Is(err, errOutage) || !errors.Is(err, errDial) {
+		t.Errorf("output err = %v, want both target errors", err)
+	}
+
+	select {
+	case err := <-input.closed:
+		if err == nil || !strings.Contains(err.Error(), "all targets") {
+			t.Errorf("input closed with %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("input not closed after every target failed")
+	}
+	if st := m.Stats()["tts/primary"]; st.Errors != 1 {
+		t.Errorf("errors = %d, want 1", st.Errors)
+	}
+}
+
+func TestFailover_Breaker(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var down atomic.Bool
+		down.Store(true)
+		a := &fakeTarget{run: func(input genx.Stream, output *bufferStream) {
+			if down.Load() {
+				failNow(input, output)
+				return
+			}
+			echo("a:")(input, output)
+		}}
+		b := &fakeTarget{run: echo("b:")}
+		m := failoverMux(t, map[string]*fakeTarget{"tts/a": a, "tts/b": b},
+			[]FailoverTarget{{Pattern: "tts/a"}, {Pattern: "tts/b"}},
+			WithBreaker(2, time.Minute))
+
+		request := func() string {
+			t.Helper()
+			out, err := m.Transform(context.Background(), "tts/primary", textInput("x"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := readOutput(out)
+			if err != nil || len(got) != 1 {
+				t.Fatalf("output = %q, %v", got, err)
+			}
+			synctest.Wait()
+			return got[0]
+		}
+		circuitOpen := func() bool {
+			return m.Stats()["tts/primary"].Targets["tts/a"].CircuitOpen
+		}
+
+		// Two consecutive failures open the circuit of tts/a
+		for range 2 {
+			if got := request(); got != "b:x" {
+				t.Fatalf("output = %q, want b:x", got)
+			}
+		}
+		if !circuitOpen() {
+			t.Fatal("circuit not open after 2 failures")
+		}
+
+		// While open, tts/a is tried after tts/b, i.e. not at all
+		if got := request(); got != "b:x" || a.calls.Load() != 2 {
+			t.Fatalf("open circuit: output %q, tts/a calls %d", got, a.calls.Load())
+		}
+
+		// After the cooldown tts/a is tried first again; a single failure
+		// opens the circuit at once
+		time.Sleep(time.Minute)
+		if circuitOpen() {
+			t.Fatal("circuit still open after the cooldown")
+		}
+		request()
+		if a.calls.Load() != 3 || !circuitOpen() {
+			t.Fatalf("half-open failure: tts/a calls %d, open %v", a.calls.Load(), circuitOpen())
+		}
+
+		// A success after the next cooldown closes the circuit
+		time.Sleep(time.Minute)
+		down.Store(false)
+		if got := request(); got != "a:x" {
+			t.Fatalf("output = %q, want a:x", got)
+		}
+		down.Store(true)
+		request()
+		if circuitOpen() {
+			t.Error("circuit opened by one failure after it closed")
+		}
+	})
+}
+
+func TestFailover_Order(t *testing.T) {
+	newRoute := func(targets ...FailoverTarget) *failover {
+		return &failover{targets: targets, breakers: make([]breaker, len(targets))}
+	}
+
+	f := newRoute(FailoverTarget{Pattern: "a"}, FailoverTarget{Pattern: "b"}, FailoverTarget{Pattern: "c"})
+	for range 10 {
+		if got := f.order(); got[0] != 0 || got[1] != 1 || got[2] != 2 {
+			t.Fatalf("unweighted order = %v, want registration order", got)
+		}
+	}
+
+	f = newRoute(
+		FailoverTarget{Pattern: "a", Weight: 3},
+		FailoverTarget{Pattern: "b", Weight: 1},
+		FailoverTarget{Pattern: "c"},
+	)
+	const n = 4000
+	first := 0
+	for range n {
+		got := f.order()
+		if got[2] != 2 {
+			t.Fatalf("order = %v, want the unweighted target last", got)
+		}
+		if got[0] == 0 {
+			first++
+		}
+	}
+	// a comes first with probability 3/4
+	if first < n*70/100 || first > n*80/100 {
+		t.Errorf("weight 3 target first %d of %d times, want about 3/4", first, n)
+	}
+
+	f.breakers[0].openUntil = time.Now().Add(time.Minute)
+	for range 10 {
+		if got := f.order(); got[0] != 1 || got[1] != 2 || got[2] != 0 {
+			t.Fatalf("order = %v, want the open circuit last", got)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package transformers
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/promtext"
+)
+
+// poolDialTimeout limits the dials made by a pool in the background.
+const poolDialTimeout = 10 * time.Second
+
+// PoolConfig configures a pool of connections that a transformer opens
+// ahead of its Transform calls, so that a call does not wait 200-400ms for
+// a WebSocket handshake. A pooled connection serves one call and is
+// replaced in the background.
+type PoolConfig struct {
+	// Size is the number of idle connections kept open. Zero disables the
+	// pool.
+	Size int
+
+	// IdleTimeout is how long a connection stays idle before it is closed
+	// and replaced. Keep it below the idle timeout of the server. Default
+	// 10s.
+	IdleTimeout time.Duration
+
+	// HealthCheckInterval is how often idle connections are pinged; those
+	// that fail are replaced. Connections are also pinged when taken.
+	// Default 5s; negative disables the periodic check.
+	HealthCheckInterval time.Duration
+}
+
+func (c PoolConfig) withDefaults() PoolConfig {
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = 10 * time.Second
+	}
+	if c.HealthCheckInterval == 0 {
+		c.HealthCheckInterval = 5 * time.Second
+	}
+	return c
+}
+
+// PoolStats is a snapshot of the counters of a connection pool.
+type PoolStats struct {
+	// Hits is the number of Transform calls served by a pooled connection,
+	// Misses the number that dialed because the pool was empty.
+	Hits   int64
+	Misses int64
+
+	// Dials is the number of connections dialed in the background,
+	// DialErrors the number of those that failed.
+	Dials      int64
+	DialErrors int64
+
+	// Expired is the number of idle connections closed for IdleTimeout,
+	// Unhealthy the number closed for failing the health check.
+	Expired   int64
+	Unhealthy int64
+
+	// Idle is the number of idle connections.
+	Idle int
+}
+
+// ReuseRate returns the fraction of Transform calls served by a pooled
+// connection, or 0 before the first call.
+func (s PoolStats) ReuseRate() float64 {
+	if n := s.Hits + s.Misses; n > 0 {
+		return float64(s.Hits) / float64(n)
+	}
+	return 0
+}
+
+// WritePoolPrometheus writes the counters of connection pools in the
+// Prometheus text exposition format, labeled by the names of pools, e.g.
+// of the patterns their transformers are registered for:
+//
+//	transformers.WritePoolPrometheus(w, map[string]transformers.PoolStats{
+//	    "asr/doubao": asr.PoolStats(),
+//	})
+func WritePoolPrometheus(w io.Writer, pools map[string]PoolStats) error {
+	names := make([]string, 0, len(pools))
+	for name := range pools {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	pw := promtext.NewWriter(w)
+	metric := func(name, help, typ string, value func(PoolStats) int64) {
+		pw.Header(name, help, typ)
+		for _, n := range names {
+			pw.Int(name, promtext.Label("pool", n), value(pools[n]))
+		}
+	}
+	metric("giztoy_transformer_pool_hits_total", "Transform calls served by a pooled connection.", "counter", func(s PoolStats) int64 { return s.Hits })
+	metric("giztoy_transformer_pool_misses_total", "Transform calls that dialed because the pool was empty.", "counter", func(s PoolStats) int64 { return s.Misses })
+	metric("giztoy_transformer_pool_dials_total", "Connections dialed in the background.", "counter", func(s PoolStats) int64 { return s.Dials })
+	metric("giztoy_transformer_pool_dial_errors_total", "Background dials that failed.", "counter", func(s PoolStats) int64 { return s.DialErrors })
+	metric("giztoy_transformer_pool_expired_total", "Idle connections closed for the idle timeout.", "counter", func(s PoolStats) int64 { return s.Expired })
+	metric("giztoy_transformer_pool_unhealthy_total", "Idle connections closed for failing the health check.", "counter", func(s PoolStats) int64 { return s.Unhealthy })
+	metric("giztoy_transformer_pool_idle", "Idle connections.", "gauge", func(s PoolStats) int64 { return int64(s.Idle) })
+	return pw.Err()
+}
+
+// connPool keeps up to Size connections of type T open for get. It refills
+// in the background after every get and drops connections that expire or
+// fail their ping.
+type connPool[T any] struct {
+	cfg   PoolConfig
+	dial  func(context.Context) (T, error)
+	ping  func(T) error
+	close func(T)
+
+	mu      sync.Mutex
+	idle    []pooledConn[T] // oldest first
+	dialing int
+	closed  bool
+	stats   PoolStats
+	stop    chan struct{}
+}
+
+type pooledConn[T any] struct {
+	conn  T
+	since time.Time
+}
+
+// newConnPool creates a pool and starts filling it.
+func newConnPool[T any](cfg PoolConfig, dial func(context.Context) (T, error), ping func(T) error, close func(T)) *connPool[T] {
+	p := &connPool[T]{
+		cfg:   cfg.withDefaults(),
+		dial:  dial,
+		ping:  ping,
+		close: close,
+		stop:  make(chan struct{}),
+	}
+	p.mu.Lock()
+	p.refillLocked()
+	p.mu.Unlock()
+	if p.cfg.HealthCheckInterval > 0 {
+		go p.checkLoop()
+	}
+	return p
+}
+
+// get returns a pooled connection, or dials one with ctx if none is idle.
+// pooled reports which, so that a caller can retry with a fresh
+// connection if a pooled one fails right away.
+func (p *connPool[T]) get(ctx context.Context) (conn T, pooled bool, err error) {
+	for {
+		p.mu.Lock()
+		if len(p.idle) == 0 {
+			p.stats.Misses++
+			p.refillLocked()
+			p.mu.Unlock()
+			conn, err = p.dial(ctx)
+			return conn, false, err
+		}
+		// Take the newest: it is the least likely to have been dropped.
+		pc := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		expired := time.Since(pc.since) > p.cfg.IdleTimeout
+		if expired {
+			p.stats.Expired++
+		}
+		p.refillLocked()
+		p.mu.Unlock()
+
+		if expired {
+			p.close(pc.conn)
+			continue
+		}
+		if err := p.ping(pc.conn); err != nil {
+			p.mu.Lock()
+			p.stats.Unhealthy++
+			p.mu.Unlock()
+			p.close(pc.conn)
+			continue
+		}
+		p.mu.Lock()
+		p.stats.Hits++
+		p.mu.Unlock()
+		return pc.conn, true, nil
+	}
+}
+
+// refillLocked starts dials until idle and dialing connections make Size.
+func (p *connPool[T]) refillLocked() {
+	for !p.closed && len(p.idle)+p.dialing < p.cfg.Size {
+		p.dialing++
+		p.stats.Dials++
+		go p.dialIdle()
+	}
+}
+
+func (p *connPool[T]) dialIdle() {
+	ctx, cancel := context.WithTimeout(context.Background(), poolDialTimeout)
+	defer cancel()
+	conn, err := p.dial(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dialing--
+	if err != nil {
+		// The next get or check retries.
+		p.stats.DialErrors++
+		return
+	}
+	if p.closed {
+		go p.close(conn)
+		return
+	}
+	p.idle = append(p.idle, pooledConn[T]{conn: conn, since: time.Now()})
+}
+
+// checkLoop expires and pings idle connections and refills the pool.
+func (p *connPool[T]) checkLoop() {
+	ticker := time.NewTicker(p.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.check()
+		}
+	}
+}
+
+func (p *connPool[T]) check() {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	var keep, drop []pooledConn[T]
+	var expired, unhealthy int64
+	for _, pc := range idle {
+		switch {
+		case time.Since(pc.since) > p.cfg.IdleTimeout:
+			expired++
+			drop = append(drop, pc)
+		case p.ping(pc.conn) != nil:
+			unhealthy++
+			drop = append(drop, pc)
+		default:
+			keep = append(keep, pc)
+		}
+	}
+
+	p.mu.Lock()
+	p.stats.Expired += expired
+	p.stats.Unhealthy += unhealthy
+	if p.closed {
+		drop = append(drop, keep...)
+	} else {
+		// Connections dialed during the check are newer.
+		p.idle = append(keep, p.idle...)
+		p.refillLocked()
+	}
+	p.mu.Unlock()
+
+	for _, pc := range drop {
+		p.close(pc.conn)
+	}
+}
+
+// Stats returns the counters of the pool.
+func (p *connPool[T]) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.stats
+	s.Idle = len(p.idle)
+	return s
+}
+
+// Close closes the idle connections and stops refilling.
+func (p *connPool[T]) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	close(p.stop)
+	p.mu.Unlock()
+
+	for _, pc := range idle {
+		p.close(pc.conn)
+	}
+}
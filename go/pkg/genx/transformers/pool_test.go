@@ -0,0 +1,224 @@
+package transformers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// fakeConn is a connection of a fakeDialer.
+type fakeConn struct {
+	id int
+
+	mu        sync.Mutex
+	unhealthy bool
+	closed    bool
+}
+
+func (c *fakeConn) setUnhealthy() {
+	c.mu.Lock()
+	c.unhealthy = true
+	c.mu.Unlock()
+}
+
+func (c *fakeConn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// fakeDialer dials fakeConns, numbered from 1.
+type fakeDialer struct {
+	mu    sync.Mutex
+	conns []*fakeConn
+	fail  bool
+}
+
+func (d *fakeDialer) dial(context.Context) (*fakeConn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.fail {
+		return nil, errors.New("dial failed")
+	}
+	c := &fakeConn{id: len(d.conns) + 1}
+	d.conns = append(d.conns, c)
+	return c, nil
+}
+
+func (d *fakeDialer) setFail(fail bool) {
+	d.mu.Lock()
+	d.fail = fail
+	d.mu.Unlock()
+}
+
+func (d *fakeDialer) dialed() []*fakeConn {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*fakeConn(nil), d.conns...)
+}
+
+func pingFake(c *fakeConn) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.unhealthy {
+		return errors.New("ping failed")
+	}
+	return nil
+}
+
+func closeFake(c *fakeConn) {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+}
+
+func newFakePool(cfg PoolConfig) (*connPool[*fakeConn], *fakeDialer) {
+	d := &fakeDialer{}
+	return newConnPool(cfg, d.dial, pingFake, closeFake), d
+}
+
+func TestConnPool_HitMiss(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		p, d := newFakePool(PoolConfig{Size: 2, HealthCheckInterval: -1})
+		defer p.Close()
+		synctest.Wait()
+		if st := p.Stats(); st.Idle != 2 || st.Dials != 2 {
+			t.Fatalf("stats after fill = %+v, want 2 idle from 2 dials", st)
+		}
+
+		// With dials failing, the idle connections serve two calls and
+		// the third misses
+		d.setFail(true)
+		for i := range 2 {
+			if _, pooled, err := p.get(context.Background()); err != nil || !pooled {
+				t.Fatalf("get %d: pooled=%v err=%v, want a pooled connection", i, pooled, err)
+			}
+		}
+		synctest.Wait()
+		if _, _, err := p.get(context.Background()); err == nil {
+			t.Fatal("get from an empty pool with failing dials succeeded")
+		}
+		synctest.Wait()
+
+		d.setFail(false)
+		if _, pooled, err := p.get(context.Background()); err != nil || pooled {
+			t.Fatalf("get after failed refills: pooled=%v err=%v, want a dialed connection", pooled, err)
+		}
+
+		synctest.Wait()
+		st := p.Stats()
+		if st.Hits != 2 || st.Misses != 2 || st.ReuseRate() != 0.5 {
+			t.Errorf("stats = %+v, want 2 hits and 2 misses", st)
+		}
+		if st.DialErrors == 0 || st.Idle != 2 {
+			t.Errorf("stats = %+v, want failed dials and the pool refilled", st)
+		}
+	})
+}
+
+func TestConnPool_Expiry(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		// Expired on get
+		p, d := newFakePool(PoolConfig{Size: 1, IdleTimeout: 10 * time.Second, HealthCheckInterval: -1})
+		defer p.Close()
+		synctest.Wait()
+		time.Sleep(11 * time.Second)
+		conn, _, err := p.get(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := d.dialed()[0]
+		if conn == first || !first.isClosed() {
+			t.Errorf("got conn %d; the expired conn 1 must be closed, not handed out", conn.id)
+		}
+		if st := p.Stats(); st.Expired != 1 {
+			t.Errorf("stats = %+v, want 1 expired", st)
+		}
+
+		// Expired by the health check
+		p, d = newFakePool(PoolConfig{Size: 1, IdleTimeout: 10 * time.Second, HealthCheckInterval: 4 * time.Second})
+		defer p.Close()
+		synctest.Wait()
+		time.Sleep(13 * time.Second)
+		synctest.Wait()
+		conns := d.dialed()
+		if len(conns) != 2 || !conns[0].isClosed() || conns[1].isClosed() {
+			t.Fatalf("want conn 1 closed and replaced by conn 2, got %d conns", len(conns))
+		}
+		if st := p.Stats(); st.Expired != 1 || st.Idle != 1 {
+			t.Errorf("stats = %+v, want 1 expired, 1 idle", st)
+		}
+	})
+}
+
+func TestConnPool_Unhealthy(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		// Pinged on get
+		p, d := newFakePool(PoolConfig{Size: 1, HealthCheckInterval: -1})
+		defer p.Close()
+		synctest.Wait()
+		first := d.dialed()[0]
+		first.setUnhealthy()
+		conn, _, err := p.get(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if conn == first || !first.isClosed() {
+			t.Errorf("got conn %d; the unhealthy conn 1 must be closed, not handed out", conn.id)
+		}
+
+		// Pinged by the health check
+		p, d = newFakePool(PoolConfig{Size: 1, HealthCheckInterval: time.Second})
+		defer p.Close()
+		synctest.Wait()
+		d.dialed()[0].setUnhealthy()
+		time.Sleep(1500 * time.Millisecond)
+		synctest.Wait()
+		conns := d.dialed()
+		if len(conns) != 2 || !conns[0].isClosed() || conns[1].isClosed() {
+			t.Fatalf("want conn 1 closed and replaced by conn 2, got %d conns", len(conns))
+		}
+		if st := p.Stats(); st.Unhealthy != 1 || st.Idle != 1 {
+			t.Errorf("stats = %+v, want 1 unhealthy, 1 idle", st)
+		}
+	})
+}
+
+func TestConnPool_Close(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		// Closed while the initial dials are in flight
+		p, d := newFakePool(PoolConfig{Size: 2, HealthCheckInterval: time.Second})
+		p.Close()
+		synctest.Wait()
+		time.Sleep(5 * time.Second)
+		synctest.Wait()
+
+		conns := d.dialed()
+		for _, c := range conns {
+			if !c.isClosed() {
+				t.Errorf("conn %d dialed for a closed pool is open", c.id)
+			}
+		}
+		if st := p.Stats(); st.Dials != 2 || st.Idle != 0 {
+			t.Errorf("stats = %+v, want no dials after the 2 initial ones", st)
+		}
+
+		// Closed when full
+		p, d = newFakePool(PoolConfig{Size: 2, HealthCheckInterval: time.Second})
+		synctest.Wait()
+		p.Close()
+		time.Sleep(5 * time.Second)
+		synctest.Wait()
+		for _, c := range d.dialed() {
+			if !c.isClosed() {
+				t.Errorf("idle conn %d not closed", c.id)
+			}
+		}
+		if st := p.Stats(); st.Dials != 2 || st.Idle != 0 {
+			t.Errorf("stats = %+v, want no refill after Close", st)
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package transformers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// MetaTTSTiming is the metadata key under which TTS transformers with
+// timestamps enabled (WithDoubaoTTSSeedV2Timestamps,
+// WithMinimaxTTSSubtitles) attach the timing of the synthesized text: a
+// JSON array of SentenceTiming. It is set on the audio chunk that arrived
+// with the timing, or on a chunk without Part if none did.
+const MetaTTSTiming = "tts.timing"
+
+// SentenceTiming is the position of a sentence in the synthesized audio,
+// in milliseconds from the start of the audio of its sub-stream (since the
+// previous audio EoS marker).
+type SentenceTiming struct {
+	Text  string `json:"text"`
+	Start int64  `json:"start"` // milliseconds
+	End   int64  `json:"end"`   // milliseconds
+
+	// Words is the timing of each word (each character in Chinese), if
+	// the backend reports it.
+	Words []WordTiming `json:"words,omitempty"`
+}
+
+// WordTiming is the position of a word in the synthesized audio, as in
+// SentenceTiming.
+type WordTiming struct {
+	Text  string `json:"text"`
+	Start int64  `json:"start"` // milliseconds
+	End   int64  `json:"end"`   // milliseconds
+}
+
+// SetTTSTiming attaches timings to chunk under MetaTTSTiming.
+func SetTTSTiming(chunk *genx.MessageChunk, timings ...SentenceTiming) {
+	data, err := json.Marshal(timings)
+	if err != nil {
+		return
+	}
+	chunk.SetMetadata(MetaTTSTiming, string(data))
+}
+
+// TTSTiming returns the timings attached to chunk, or nil if it has none.
+func TTSTiming(chunk *genx.MessageChunk) []SentenceTiming {
+	data := chunk.Metadata(MetaTTSTiming)
+	if data == "" {
+		return nil
+	}
+	var timings []SentenceTiming
+	if err := json.Unmarshal([]byte(data), &timings); err != nil {
+		return nil
+	}
+	return timings
+}
+
+// newTTSTimingChunk creates a chunk without Part carrying timings, for
+// timings that arrive without audio.
+func newTTSTimingChunk(lastChunk *genx.MessageChunk, streamID string, timings []SentenceTiming) *genx.MessageChunk {
+	chunk := &genx.MessageChunk{Ctrl: &genx.StreamCtrl{StreamID: streamID}}
+	if lastChunk != nil {
+		chunk.Role = lastChunk.Role
+		chunk.Name = lastChunk.Name
+	}
+	SetTTSTiming(chunk, timings...)
+	return chunk
+}
+
+// SubtitleCollector assembles subtitles from the MetaTTSTiming of TTS
+// output, for captions or to store with recorded audio:
+//
+//	sc := transformers.NewSubtitleCollector()
+//	err := sc.Collect(ttsOutput) // or sc.Add(chunk) while playing each chunk
+//	sc.WriteVTT(w)
+//
+// Sub-streams are placed one after the other: the timings of a sub-stream
+// are offset by the end of the last sentence before its audio EoS marker,
+// so silence the backend adds after the last sentence is not accounted
+// for.
+type SubtitleCollector struct {
+	cues   []SentenceTiming
+	offset int64 // start of the current sub-stream
+	end    int64 // end of the last cue
+}
+
+// NewSubtitleCollector creates an empty SubtitleCollector.
+func NewSubtitleCollector() *SubtitleCollector {
+	return &SubtitleCollector{}
+}
+
+// Add collects the timings attached to chunk. An audio EoS marker starts a
+// new sub-stream.
+func (c *SubtitleCollector) Add(chunk *genx.MessageChunk) {
+	for _, t := range TTSTiming(chunk) {
+		t.Start += c.offset
+		t.End += c.offset
+		t.Words = append([]WordTiming(nil), t.Words...)
+		for i := range t.Words {
+			t.Words[i].Start += c.offset
+			t.Words[i].End += c.offset
+		}
+		c.cues = append(c.cues, t)
+		c.end = max(c.end, t.End)
+	}
+	if chunk.IsEndOfStream() {
+		if blob, ok := chunk.Part.(*genx.Blob); ok && isAudioMIME(blob.MIMEType) {
+			c.offset = c.end
+		}
+	}
+}
+
+// Collect adds the chunks of s until it ends. It returns nil at io.EOF.
+func (c *SubtitleCollector) Collect(s genx.Stream) error {
+	for {
+		chunk, err := s.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if chunk != nil {
+			c.Add(chunk)
+		}
+	}
+}
+
+// Cues returns the collected sentences, with times from the start of the
+// first sub-stream.
+func (c *SubtitleCollector) Cues() []SentenceTiming {
+	return c.cues
+}
+
+// WriteSRT writes the collected sentences as SubRip (SRT) subtitles.
+func (c *SubtitleCollector) WriteSRT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for i, cue := range c.cues {
+		fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n", i+1,
+			subtitleTime(cue.Start, ','), subtitleTime(cue.End, ','), cueText(cue.Text))
+	}
+	return bw.Flush()
+}
+
+// WriteVTT writes the collected sentences as WebVTT subtitles. Word
+// timings become cue timestamps, which players render as karaoke-style
+// highlighting.
+func (c *SubtitleCollector) WriteVTT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("WEBVTT\n\n")
+	for _, cue := range c.cues {
+		fmt.Fprintf(bw, "%s --> %s\n", subtitleTime(cue.Start, '.'), subtitleTime(cue.End, '.'))
+		if len(cue.Words) == 0 {
+			fmt.Fprintf(bw, "%s\n\n", vttEscape(cueText(cue.Text)))
+			continue
+		}
+		for i, word := range cue.Words {
+			// The first word starts with the cue.
+			if i > 0 && word.Start > cue.Start && word.Start < cue.End {
+				fmt.Fprintf(bw, "<%s>", subtitleTime(word.Start, '.'))
+			}
+			fmt.Fprintf(bw, "<c>%s</c>", vttEscape(cueText(word.Text)))
+		}
+		bw.WriteString("\n\n")
+	}
+	return bw.Flush()
+}
+
+// subtitleTime formats ms as hh:mm:ss followed by sep and milliseconds.
+func subtitleTime(ms int64, sep byte) string {
+	ms = max(ms, 0)
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// cueText keeps blank lines, which end a cue, out of text.
+func cueText(text string) string {
+	lines := strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == '\r' })
+	return strings.Join(lines, "\n")
+}
+
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func vttEscape(text string) string {
+	return vttEscaper.Replace(text)
+}
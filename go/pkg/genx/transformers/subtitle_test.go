@@ -0,0 +1,104 @@
+package transformers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// subtitleInput is TTS output of three sub-streams with timings, each
+// from the start of the audio of its sub-stream.
+func subtitleInput() []*genx.MessageChunk {
+	audio := func(timings ...SentenceTiming) *genx.MessageChunk {
+		chunk := &genx.MessageChunk{Role: genx.RoleModel, Part: &genx.Blob{MIMEType: "audio/mpeg", Data: []byte{0}}}
+		SetTTSTiming(chunk, timings...)
+		return chunk
+	}
+	return []*genx.MessageChunk{
+		audio(SentenceTiming{Text: "Hello <world> & co.", Start: 0, End: 1200, Words: []WordTiming{
+			{Text: "Hello", Start: 0, End: 400},
+			{Text: "<world>", Start: 400, End: 900},
+			{Text: "& co.", Start: 900, End: 1200},
+		}}),
+		modelEoS("text/plain"), // not a new sub-stream
+		modelEoS("audio/mpeg"),
+
+		newTTSTimingChunk(modelText(""), "", []SentenceTiming{
+			{Text: "Line one\n\nline two", Start: 100, End: 2000},
+		}),
+		audio(SentenceTiming{Text: "Bye", Start: 2000, End: 2500, Words: []WordTiming{
+			{Text: "Bye", Start: 2000, End: 2500},
+		}}),
+		modelEoS("audio/mpeg"),
+
+		audio(SentenceTiming{Text: "Late", Start: 3719304, End: 3720000, Words: []WordTiming{
+			{Text: "La", Start: 3719304, End: 3719600},
+			{Text: "te", Start: 3719304, End: 3720000}, // at the cue start
+		}}),
+		modelEoS("audio/mpeg"),
+	}
+}
+
+func newTestSubtitles(t *testing.T) *SubtitleCollector {
+	t.Helper()
+	sc := NewSubtitleCollector()
+	if err := sc.Collect(chunkInput(subtitleInput()...)); err != nil {
+		t.Fatal(err)
+	}
+	return sc
+}
+
+func TestSubtitleCollector_WriteSRT(t *testing.T) {
+	var b strings.Builder
+	if err := newTestSubtitles(t).WriteSRT(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `1
+00:00:00,000 --> 00:00:01,200
+Hello <world> & co.
+
+2
+00:00:01,300 --> 00:00:03,200
+Line one
+line two
+
+3
+00:00:03,200 --> 00:00:03,700
+Bye
+
+4
+01:02:03,004 --> 01:02:03,700
+Late
+
+`
+	if got := b.String(); got != want {
+		t.Errorf("SRT:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSubtitleCollector_WriteVTT(t *testing.T) {
+	var b strings.Builder
+	if err := newTestSubtitles(t).WriteVTT(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `WEBVTT
+
+00:00:00.000 --> 00:00:01.200
+<c>Hello</c><00:00:00.400><c>&lt;world&gt;</c><00:00:00.900><c>&amp; co.</c>
+
+00:00:01.300 --> 00:00:03.200
+Line one
+line two
+
+00:00:03.200 --> 00:00:03.700
+<c>Bye</c>
+
+01:02:03.004 --> 01:02:03.700
+<c>La</c><c>te</c>
+
+`
+	if got := b.String(); got != want {
+		t.Errorf("VTT:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,86 @@
+package transformers
+
+import "github.com/haivivi/giztoy/go/pkg/genx"
+
+// MetaTTSStyle is the metadata key under which the producer of the text
+// for TTS (e.g., an agent) sets the emotion or speaking style of a
+// sub-stream. TTS transformers translate it into the parameters of their
+// backend for the sub-stream, overriding the emotion they were configured
+// with; the last style set before the text EoS marker applies.
+//
+// Values are the TTSStyle* constants. Other values are passed to the
+// backend unchanged, for styles only one backend supports (e.g.,
+// "storytelling" for Doubao).
+//
+// MetaTTSStyle is distinct from MetaEmotion, which holds the emotion
+// detected in the user's speech.
+const MetaTTSStyle = "tts.style"
+
+// Styles understood by every TTS transformer in this package.
+const (
+	TTSStyleNeutral   = "neutral"
+	TTSStyleCalm      = "calm"
+	TTSStyleHappy     = "happy"
+	TTSStyleSad       = "sad"
+	TTSStyleAngry     = "angry"
+	TTSStyleFearful   = "fearful"
+	TTSStyleDisgusted = "disgusted"
+	TTSStyleSurprised = "surprised"
+	TTSStyleWhisper   = "whisper"
+)
+
+// doubaoTTSStyles maps styles to Doubao TTS 2.0 emotions.
+var doubaoTTSStyles = map[string]string{
+	TTSStyleNeutral:   "neutral",
+	TTSStyleCalm:      "neutral",
+	TTSStyleHappy:     "happy",
+	TTSStyleSad:       "sad",
+	TTSStyleAngry:     "angry",
+	TTSStyleFearful:   "fear",
+	TTSStyleDisgusted: "hate",
+	TTSStyleSurprised: "surprise",
+	TTSStyleWhisper:   "ASMR",
+}
+
+// minimaxTTSStyles maps styles to MiniMax emotions.
+var minimaxTTSStyles = map[string]string{
+	TTSStyleNeutral:   "neutral",
+	TTSStyleCalm:      "calm",
+	TTSStyleHappy:     "happy",
+	TTSStyleSad:       "sad",
+	TTSStyleAngry:     "angry",
+	TTSStyleFearful:   "fearful",
+	TTSStyleDisgusted: "disgusted",
+	TTSStyleSurprised: "surprised",
+	TTSStyleWhisper:   "whisper",
+}
+
+// SetTTSStyle sets the style of the sub-stream of chunk under
+// MetaTTSStyle.
+func SetTTSStyle(chunk *genx.MessageChunk, style string) {
+	chunk.SetMetadata(MetaTTSStyle, style)
+}
+
+// ttsStyle tracks the MetaTTSStyle of the sub-stream a TTS transformer is
+// collecting.
+type ttsStyle struct {
+	style string
+}
+
+// observe records the style of chunk, if it has one.
+func (s *ttsStyle) observe(chunk *genx.MessageChunk) {
+	if style := chunk.Metadata(MetaTTSStyle); style != "" {
+		s.style = style
+	}
+}
+
+// take returns the backend parameter for the style of the sub-stream, ""
+// if it has none, and resets the style for the next sub-stream.
+func (s *ttsStyle) take(styles map[string]string) string {
+	style := s.style
+	s.style = ""
+	if v, ok := styles[style]; ok {
+		return v
+	}
+	return style
+}
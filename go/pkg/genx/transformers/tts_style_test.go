@@ -0,0 +1,76 @@
+package transformers
+
+import (
+	"testing"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+func TestTTSStyle_Backends(t *testing.T) {
+	tests := []struct {
+		style   string
+		doubao  string
+		minimax string
+	}{
+		{"", "", ""},
+		{TTSStyleNeutral, "neutral", "neutral"},
+		{TTSStyleCalm, "neutral", "calm"},
+		{TTSStyleHappy, "happy", "happy"},
+		{TTSStyleSad, "sad", "sad"},
+		{TTSStyleAngry, "angry", "angry"},
+		{TTSStyleFearful, "fear", "fearful"},
+		{TTSStyleDisgusted, "hate", "disgusted"},
+		{TTSStyleSurprised, "surprise", "surprised"},
+		{TTSStyleWhisper, "ASMR", "whisper"},
+		{"storytelling", "storytelling", "storytelling"}, // unknown, passed on
+	}
+	for _, tt := range tests {
+		for _, backend := range []struct {
+			name   string
+			styles map[string]string
+			want   string
+		}{
+			{"doubao", doubaoTTSStyles, tt.doubao},
+			{"minimax", minimaxTTSStyles, tt.minimax},
+		} {
+			chunk := modelText("hi")
+			if tt.style != "" {
+				SetTTSStyle(chunk, tt.style)
+			}
+			var s ttsStyle
+			s.observe(chunk)
+			if got := s.take(backend.styles); got != backend.want {
+				t.Errorf("%s style %q = %q, want %q", backend.name, tt.style, got, backend.want)
+			}
+		}
+	}
+}
+
+func TestTTSStyle_SubStreams(t *testing.T) {
+	styled := func(text, style string) *genx.MessageChunk {
+		chunk := modelText(text)
+		SetTTSStyle(chunk, style)
+		return chunk
+	}
+	// Each sub-stream is observed up to its text EoS, then taken
+	subStreams := []struct {
+		chunks []*genx.MessageChunk
+		want   string
+	}{
+		{[]*genx.MessageChunk{styled("a", TTSStyleHappy), modelText("b")}, "happy"},
+		{[]*genx.MessageChunk{modelText("c")}, ""}, // reset by take
+		{[]*genx.MessageChunk{styled("d", TTSStyleSad), styled("e", TTSStyleWhisper), modelText("f")}, "ASMR"},
+		{[]*genx.MessageChunk{modelText("g"), styled("h", "storytelling")}, "storytelling"},
+		{nil, ""},
+	}
+	var s ttsStyle
+	for i, sub := range subStreams {
+		for _, chunk := range sub.chunks {
+			s.observe(chunk)
+		}
+		s.observe(modelEoS("text/plain"))
+		if got := s.take(doubaoTTSStyles); got != sub.want {
+			t.Errorf("sub-stream %d: style = %q, want %q", i, got, sub.want)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package transformers
+
+import (
+	"context"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// MetaTurn is the StreamCtrl.Metadata key written by TurnManager: "interrupted"
+// on the EoS marker that ends an interrupted model turn, "resumed" on the
+// first chunk of held model output that is let through.
+const MetaTurn = "turn"
+
+// Values of MetaTurn.
+const (
+	TurnInterrupted = "interrupted"
+	TurnResumed     = "resumed"
+)
+
+// TurnPolicy decides how TurnManager resolves overlapping user and model
+// speech.
+type TurnPolicy int
+
+const (
+	// TurnPolite holds model output while the user speaks. When the user
+	// stops, the held output resumes if the user spoke briefly (a
+	// backchannel such as "uh-huh"), and the model turn is interrupted
+	// otherwise.
+	TurnPolite TurnPolicy = iota
+
+	// TurnAssertive lets the model keep talking over the user until the
+	// user has spoken for the barge-in duration, then interrupts it.
+	TurnAssertive
+
+	// TurnHardBargeIn interrupts the model turn as soon as the user starts
+	// speaking.
+	TurnHardBargeIn
+)
+
+func (p TurnPolicy) String() string {
+	switch p {
+	case TurnPolite:
+		return "polite"
+	case TurnAssertive:
+		return "assertive"
+	case TurnHardBargeIn:
+		return "hard-barge-in"
+	default:
+		return "unknown"
+	}
+}
+
+// TurnManager arbitrates between user and model speech in a full-duplex
+// pipeline, where user audio keeps flowing while the model answers. It sits
+// on the merged stream of both, after VAD or ASR and before the player.
+//
+// User speech is delimited by the BOS and EoS markers of RoleUser chunks; a
+// RoleUser chunk without a preceding BOS also starts user speech. A model
+// turn runs from the first RoleModel chunk (or BOS) until each of its
+// sub-streams, one per MIME type (e.g. text and audio), has reached its EoS.
+//
+// While the user has the floor, model chunks are held. Interrupting a model
+// turn drops its held and remaining chunks and emits an EoS marker with
+// MetaTurn "interrupted" in their place, one per sub-stream of the turn, so
+// players stop at once. The
+// interrupt callback lets the pipeline cancel generation and regenerate
+// with the user's new input; a new model turn starts with a BOS marker.
+//
+// User chunks and chunks of other roles always pass through.
+//
+// Input: RoleUser and RoleModel chunks of any type
+// Output: the same chunks, with model output held or dropped
+type TurnManager struct {
+	policy      TurnPolicy
+	bargeIn     time.Duration
+	backchannel time.Duration
+	maxHold     int
+	onInterrupt func(streamID string)
+	now         func() time.Time
+}
+
+var _ genx.Transformer = (*TurnManager)(nil)
+
+// TurnManagerOption configures a TurnManager.
+type TurnManagerOption func(*TurnManager)
+
+// WithTurnPolicy sets the policy (default TurnPolite).
+func WithTurnPolicy(p TurnPolicy) TurnManagerOption {
+	return func(t *TurnManager) {
+		t.policy = p
+	}
+}
+
+// WithTurnBargeIn sets how long the user must speak to interrupt the model
+// under TurnAssertive (default 800ms).
+func WithTurnBargeIn(d time.Duration) TurnManagerOption {
+	return func(t *TurnManager) {
+		if d > 0 {
+			t.bargeIn = d
+		}
+	}
+}
+
+// WithTurnBackchannel sets the longest user speech after which held model
+// output resumes under TurnPolite (default 600ms).
+func WithTurnBackchannel(d time.Duration) TurnManagerOption {
+	return func(t *TurnManager) {
+		if d > 0 {
+			t.backchannel = d
+		}
+	}
+}
+
+// WithTurnMaxHold sets the most model chunks held while the user speaks
+// (default 1024). A model turn that exceeds it is interrupted.
+func WithTurnMaxHold(n int) TurnManagerOption {
+	return func(t *TurnManager) {
+		if n > 0 {
+			t.maxHold = n
+		}
+	}
+}
+
+// WithTurnOnInterrupt sets a function called with the StreamID of each
+// interrupted model turn. It is called from the transform goroutine and
+// must not block.
+func WithTurnOnInterrupt(fn func(streamID string)) TurnManagerOption {
+	return func(t *TurnManager) {
+		t.onInterrupt = fn
+	}
+}
+
+// NewTurnManager creates a TurnManager.
+func NewTurnManager(opts ...TurnManagerOption) *TurnManager {
+	t := &TurnManager{
+		policy:      TurnPolite,
+		bargeIn:     800 * time.Millisecond,
+		backchannel: 600 * time.Millisecond,
+		maxHold:     1024,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Transform implements genx.Transformer. The ctx and pattern are unused.
+func (t *TurnManager) Transform(_ context.Context, _ string, input genx.Stream) (genx.Stream, error) {
+	output := newBufferStream(100)
+	go t.transformLoop(input, output)
+	return output, nil
+}
+
+// turnState is the state of one Transform.
+type turnState struct {
+	*TurnManager
+	output *bufferStream
+
+	speaking  bool
+	userStart time.Time
+	bargedIn  bool
+
+	modelActive bool // a model turn has started and not ended
+	dropping    bool // the active model turn was interrupted
+	streamID    string
+	name        string
+	open        subStreams // sub-streams of the model turn not ended in the input
+	emitted     subStreams // sub-streams of the model turn not ended in the output
+	held        []*genx.MessageChunk
+}
+
+// subStreams are the MIME types of the open sub-streams of a model turn,
+// in the order they were opened.
+type subStreams []string
+
+// update opens or ends the sub-stream of chunk. An EoS marker without a
+// part ends every sub-stream.
+func (ss *subStreams) update(chunk *genx.MessageChunk) {
+	if chunk.Part == nil {
+		if chunk.IsEndOfStream() {
+			*ss = nil
+		}
+		return
+	}
+	mimeType := genx.PartMIMEType(chunk.Part)
+	i := slices.Index(*ss, mimeType)
+	switch {
+	case chunk.IsEndOfStream() && i >= 0:
+		*ss = slices.Delete(*ss, i, i+1)
+	case !chunk.IsEndOfStream() && i < 0:
+		*ss = append(*ss, mimeType)
+	}
+}
+
+func (t *TurnManager) transformLoop(input genx.Stream, output *bufferStream) {
+	defer output.Close()
+
+	s := &turnState{TurnManager: t, output: output}
+	for {
+		chunk, err := input.Next()
+		if err != nil {
+			// Nothing contests held output any more.
+			if s.release() != nil {
+				return
+			}
+			if err != io.EOF {
+				output.CloseWithError(err)
+			}
+			return
+		}
+		if chunk == nil {
+			continue
+		}
+
+		switch chunk.Role {
+		case genx.RoleUser:
+			err = s.user(chunk)
+		case genx.RoleModel:
+			err = s.model(chunk)
+		default:
+			err = output.Push(chunk)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// userHasFloor reports whether model output must wait.
+func (s *turnState) userHasFloor() bool {
+	return s.speaking && (s.policy != TurnAssertive || s.bargedIn)
+}
+
+func (s *turnState) user(chunk *genx.MessageChunk) error {
+	if chunk.IsEndOfStream() {
+		if err := s.output.Push(chunk); err != nil {
+			return err
+		}
+		if !s.speaking {
+			return nil
+		}
+		s.speaking = false
+		if s.policy == TurnPolite && s.now().Sub(s.userStart) >= s.backchannel {
+			return s.interrupt()
+		}
+		return s.release()
+	}
+
+	if !s.speaking {
+		s.speaking = true
+		s.userStart = s.now()
+		s.bargedIn = false
+		if s.policy == TurnHardBargeIn {
+			if err := s.interrupt(); err != nil {
+				return err
+			}
+		}
+	}
+	if s.policy == TurnAssertive && !s.bargedIn && s.now().Sub(s.userStart) >= s.bargeIn {
+		s.bargedIn = true
+		if err := s.interrupt(); err != nil {
+			return err
+		}
+	}
+	return s.output.Push(chunk)
+}
+
+func (s *turnState) model(chunk *genx.MessageChunk) error {
+	if chunk.IsBeginOfStream() {
+		// A new turn, even if the previous one was being dropped.
+		s.dropping = false
+		s.modelActive = true
+		s.streamID = chunk.Ctrl.StreamID
+		s.open, s.emitted = nil, nil
+	}
+	s.open.update(chunk)
+	if s.dropping {
+		// Drop until every sub-stream of the turn has ended
+		if chunk.IsEndOfStream() && len(s.open) == 0 {
+			s.dropping = false
+			s.modelActive = false
+		}
+		return nil
+	}
+
+	if chunk.Ctrl != nil && chunk.Ctrl.StreamID != "" {
+		s.streamID = chunk.Ctrl.StreamID
+	}
+	s.name = chunk.Name
+	s.modelActive = !chunk.IsEndOfStream() || len(s.open) > 0
+
+	if !s.userHasFloor() {
+		return s.push(chunk)
+	}
+	s.held = append(s.held, chunk)
+	if len(s.held) > s.maxHold {
+		return s.interrupt()
+	}
+	return nil
+}
+
+func appendNew(ss subStreams, mimeType string) subStreams {
+	if slices.Contains(ss, mimeType) {
+		return ss
+	}
+	return append(ss, mimeType)
+}
+
+// push passes a model chunk on.
+func (s *turnState) push(chunk *genx.MessageChunk) error {
+	s.emitted.update(chunk)
+	return s.output.Push(chunk)
+}
+
+// release passes held model output on.
+func (s *turnState) release() error {
+	for i, chunk := range s.held {
+		if i == 0 {
+			chunk = chunk.Clone()
+			chunk.SetMetadata(MetaTurn, TurnResumed)
+		}
+		if err := s.push(chunk); err != nil {
+			return err
+		}
+	}
+	s.held = nil
+	return nil
+}
+
+// interrupt ends the current model turn, if any, with an EoS marker for
+// each of its sub-streams not yet ended in the output.
+func (s *turnState) interrupt() error {
+	if !s.modelActive && len(s.held) == 0 {
+		return nil
+	}
+	// The sub-streams started in the output, then those only held or
+	// still open in the input
+	types := slices.Clone(s.emitted)
+	for _, chunk := range s.held {
+		if chunk.Part != nil {
+			types = appendNew(types, genx.PartMIMEType(chunk.Part))
+		}
+	}
+	for _, mimeType := range s.open {
+		types = appendNew(types, mimeType)
+	}
+	if len(types) == 0 {
+		types = subStreams{""}
+	}
+	s.held = nil
+	s.emitted = nil
+	s.dropping = s.modelActive
+	s.modelActive = false
+
+	for _, mimeType := range types {
+		var eos *genx.MessageChunk
+		if mimeType == "" || mimeType == "text/plain" {
+			eos = genx.NewTextEndOfStream()
+		} else {
+			eos = genx.NewEndOfStream(mimeType)
+		}
+		eos.Role = genx.RoleModel
+		eos.Name = s.name
+		eos.Ctrl.StreamID = s.streamID
+		eos.SetMetadata(MetaTurn, TurnInterrupted)
+		if err := s.output.Push(eos); err != nil {
+			return err
+		}
+	}
+	if s.onInterrupt != nil {
+		s.onInterrupt(s.streamID)
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+package transformers
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// timedChunk is an input chunk of a TurnManager, arriving at.
+type timedChunk struct {
+	at    time.Duration
+	chunk *genx.MessageChunk
+}
+
+// timedStream returns its chunks, setting the clock to their arrival.
+type timedStream struct {
+	chunks []timedChunk
+	clock  *time.Duration
+}
+
+func (s *timedStream) Next() (*genx.MessageChunk, error) {
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+	c := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	*s.clock = c.at
+	return c.chunk, nil
+}
+
+func (s *timedStream) Close() error                   { return nil }
+func (s *timedStream) CloseWithError(err error) error { return nil }
+
+// runTurns runs the chunks through a TurnManager. It returns its output,
+// one line per chunk, and the StreamIDs of the interrupted turns.
+func runTurns(t *testing.T, chunks []timedChunk, opts ...TurnManagerOption) (got, interrupted []string) {
+	t.Helper()
+	opts = append(opts, WithTurnOnInterrupt(func(streamID string) {
+		interrupted = append(interrupted, streamID)
+	}))
+	tm := NewTurnManager(opts...)
+	var clock time.Duration
+	base := time.Unix(0, 0)
+	tm.now = func() time.Time { return base.Add(clock) }
+
+	out, err := tm.Transform(context.Background(), "", &timedStream{chunks: chunks, clock: &clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		chunk, err := out.Next()
+		if err == io.EOF {
+			// The output ends after the last interrupt
+			return got, interrupted
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, describeChunk(chunk))
+	}
+}
+
+// describeChunk is e.g. "model text hi", "model audio/pcm EoS interrupted".
+func describeChunk(c *genx.MessageChunk) string {
+	var b strings.Builder
+	b.WriteString(string(c.Role))
+	switch p := c.Part.(type) {
+	case genx.Text:
+		b.WriteString(" text")
+		if p != "" {
+			b.WriteString(" " + string(p))
+		}
+	case *genx.Blob:
+		b.WriteString(" " + p.MIMEType)
+		if len(p.Data) > 0 {
+			b.WriteString(" " + string(p.Data))
+		}
+	}
+	if c.IsBeginOfStream() {
+		b.WriteString(" BoS")
+	}
+	if c.IsEndOfStream() {
+		b.WriteString(" EoS")
+	}
+	if v := c.Metadata(MetaTurn); v != "" {
+		b.WriteString(" " + v)
+	}
+	return b.String()
+}
+
+func at(ms int, chunk *genx.MessageChunk) timedChunk {
+	return timedChunk{at: time.Duration(ms) * time.Millisecond, chunk: chunk}
+}
+
+func userAudio(data string) *genx.MessageChunk {
+	return &genx.MessageChunk{Role: genx.RoleUser, Part: &genx.Blob{MIMEType: "audio/pcm", Data: []byte(data)}}
+}
+
+func userEoS() *genx.MessageChunk {
+	c := genx.NewEndOfStream("audio/pcm")
+	c.Role = genx.RoleUser
+	return c
+}
+
+func modelBoS(id string) *genx.MessageChunk {
+	c := genx.NewBeginOfStream(id)
+	c.Role = genx.RoleModel
+	return c
+}
+
+func modelText(text string) *genx.MessageChunk {
+	return &genx.MessageChunk{Role: genx.RoleModel, Part: genx.Text(text)}
+}
+
+func modelAudio(data string) *genx.MessageChunk {
+	return &genx.MessageChunk{Role: genx.RoleModel, Part: &genx.Blob{MIMEType: "audio/mp3", Data: []byte(data)}}
+}
+
+func modelEoS(mimeType string) *genx.MessageChunk {
+	c := genx.NewEndOfStream(mimeType)
+	if mimeType == "text/plain" {
+		c = genx.NewTextEndOfStream()
+	}
+	c.Role = genx.RoleModel
+	return c
+}
+
+func checkTurns(t *testing.T, got, interrupted, want []string, wantInterrupted ...string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("output:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if strings.Join(interrupted, ",") != strings.Join(wantInterrupted, ",") {
+		t.Errorf("interrupted = %q, want %q", interrupted, wantInterrupted)
+	}
+}
+
+func TestTurnManager_PoliteBackchannel(t *testing.T) {
+	got, interrupted := runTurns(t, []timedChunk{
+		at(0, modelBoS("m1")),
+		at(0, modelText("one")),
+		at(100, userAudio("uh")),
+		at(150, modelText("two")),
+		at(300, userEoS()),
+		at(350, modelText("three")),
+		at(400, modelEoS("text/plain")),
+	})
+	checkTurns(t, got, interrupted, []string{
+		"model BoS",
+		"model text one",
+		"user audio/pcm uh",
+		"user audio/pcm EoS",
+		"model text two resumed",
+		"model text three",
+		"model text EoS",
+	})
+}
+
+func TestTurnManager_PoliteInterrupt(t *testing.T) {
+	got, interrupted := runTurns(t, []timedChunk{
+		at(0, modelBoS("m1")),
+		at(0, modelText("one")),
+		at(100, userAudio("stop")),
+		at(150, modelText("two")),
+		at(1000, userEoS()),
+		at(1050, modelText("three")),
+		at(1100, modelEoS("text/plain")),
+		at(1200, modelBoS("m2")),
+		at(1200, modelText("answer")),
+	})
+	checkTurns(t, got, interrupted, []string{
+		"model BoS",
+		"model text one",
+		"user audio/pcm stop",
+		"user audio/pcm EoS",
+		"model text EoS interrupted",
+		"model BoS",
+		"model text answer",
+	}, "m1")
+}
+
+func TestTurnManager_Assertive(t *testing.T) {
+	got, interrupted := runTurns(t, []timedChunk{
+		at(0, modelBoS("m1")),
+		at(0, modelText("one")),
+		at(100, userAudio("a")),
+		at(200, modelText("two")),
+		at(900, userAudio("b")),
+		at(950, modelText("three")),
+		at(1000, modelEoS("text/plain")),
+	}, WithTurnPolicy(TurnAssertive), WithTurnBargeIn(800*time.Millisecond))
+	checkTurns(t, got, interrupted, []string{
+		"model BoS",
+		"model text one",
+		"user audio/pcm a",
+		"model text two",
+		"model text EoS interrupted",
+		"user audio/pcm b",
+	}, "m1")
+}
+
+func TestTurnManager_HardBargeIn(t *testing.T) {
+	got, interrupted := runTurns(t, []timedChunk{
+		at(0, modelBoS("m1")),
+		at(0, modelText("one")),
+		at(100, userAudio("a")),
+		at(150, modelText("two")),
+		at(200, modelEoS("text/plain")),
+	}, WithTurnPolicy(TurnHardBargeIn))
+	checkTurns(t, got, interrupted, []string{
+		"model BoS",
+		"model text one",
+		"model text EoS interrupted",
+		"user audio/pcm a",
+	}, "m1")
+}
+
+func TestTurnManager_InterruptTextAndAudio(t *testing.T) {
+	got, interrupted := runTurns(t, []timedChunk{
+		at(0, modelBoS("m1")),
+		at(0, modelText("hi")),
+		at(0, modelAudio("a1")),
+		at(50, modelEoS("text/plain")),
+		at(100, userAudio("a")),
+		at(150, modelAudio("a2")),
+		at(200, modelEoS("audio/mp3")),
+		at(250, userEoS()),
+		at(300, modelBoS("m2")),
+		at(300, modelAudio("b1")),
+	}, WithTurnPolicy(TurnHardBargeIn))
+	checkTurns(t, got, interrupted, []string{
+		"model BoS",
+		"model text hi",
+		"model audio/mp3 a1",
+		"model text EoS",
+		"model audio/mp3 EoS interrupted",
+		"user audio/pcm a",
+		"user audio/pcm EoS",
+		"model BoS",
+		"model audio/mp3 b1",
+	}, "m1")
+
+	// Both sub-streams open: an EoS for each, and the rest of the turn is
+	// dropped until both have ended
+	got, interrupted = runTurns(t, []timedChunk{
+		at(0, modelBoS("m1")),
+		at(0, modelText("hi")),
+		at(0, modelAudio("a1")),
+		at(100, userAudio("a")),
+		at(150, modelText("there")),
+		at(160, modelEoS("text/plain")),
+		at(170, modelAudio("a2")),
+		at(200, modelEoS("audio/mp3")),
+		at(250, userEoS()),
+		at(300, modelBoS("m2")),
+		at(300, modelText("ok")),
+	}, WithTurnPolicy(TurnHardBargeIn))
+	checkTurns(t, got, interrupted, []string{
+		"model BoS",
+		"model text hi",
+		"model audio/mp3 a1",
+		"model text EoS interrupted",
+		"model audio/mp3 EoS interrupted",
+		"user audio/pcm a",
+		"user audio/pcm EoS",
+		"model BoS",
+		"model text ok",
+	}, "m1")
+}
+
+func TestTurnManager_HeldTextAndAudio(t *testing.T) {
+	// Polite: the turn is held while the user speaks, including the text
+	// EoS, so the interrupt ends both sub-streams
+	got, interrupted := runTurns(t, []timedChunk{
+		at(0, modelBoS("m1")),
+		at(0, modelText("hi")),
+		at(100, userAudio("a")),
+		at(150, modelAudio("a1")),
+		at(160, modelEoS("text/plain")),
+		at(1000, userEoS()),
+		at(1100, modelEoS("audio/mp3")),
+	})
+	checkTurns(t, got, interrupted, []string{
+		"model BoS",
+		"model text hi",
+		"user audio/pcm a",
+		"user audio/pcm EoS",
+		"model text EoS interrupted",
+		"model audio/mp3 EoS interrupted",
+	}, "m1")
+}
@@ -0,0 +1,558 @@
+package transformers
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/onnx"
+)
+
+// MetaVAD is the StreamCtrl.Metadata key written by the VAD transformer on
+// the markers it emits: VADSpeechStart on the BOS marker that starts a
+// speech segment, VADSpeechEnd on the EoS marker that ends it.
+const MetaVAD = "vad"
+
+// Values of MetaVAD.
+const (
+	VADSpeechStart = "speech_start"
+	VADSpeechEnd   = "speech_end"
+)
+
+// vadSampleRate is the sample rate of the audio VAD models work on.
+const vadSampleRate = 16000
+
+// VADModel estimates the probability of speech in fixed-size frames of
+// 16kHz mono audio, with samples scaled to [-1, 1].
+//
+// Models with recurrent state keep it in a state slice of StateSize
+// elements, allocated zeroed by the caller for each stream, so one model
+// can serve many streams. Implementations must be safe for concurrent use.
+type VADModel interface {
+	// FrameSize returns the number of samples per frame.
+	FrameSize() int
+
+	// StateSize returns the number of state elements per stream.
+	StateSize() int
+
+	// SpeechProb returns the speech probability of frame, updating state.
+	SpeechProb(frame, state []float32) (float32, error)
+
+	// Close releases any resources held by the model.
+	Close() error
+}
+
+// ONNXSileroVAD implements [VADModel] with the Silero VAD model
+// ([onnx.ModelVADSilero]):
+//
+//	env, _ := onnx.NewEnv("vad")
+//	session, _ := onnx.LoadModel(env, onnx.ModelVADSilero)
+//	model := NewONNXSileroVAD(session)
+//
+// Frames are 512 samples (32ms). As in the reference implementation, each
+// frame is prefixed with the last 64 samples of the previous frame.
+type ONNXSileroVAD struct {
+	mu      sync.RWMutex
+	session *onnx.Session
+	closed  bool
+}
+
+const (
+	sileroFrameSize   = 512
+	sileroContextSize = 64
+	sileroLSTMSize    = 2 * 1 * 128
+)
+
+var _ VADModel = (*ONNXSileroVAD)(nil)
+
+// NewONNXSileroVAD creates an ONNXSileroVAD from a loaded session. The model
+// takes ownership of the session and closes it on Close.
+func NewONNXSileroVAD(session *onnx.Session) *ONNXSileroVAD {
+	return &ONNXSileroVAD{session: session}
+}
+
+// FrameSize implements [VADModel].
+func (m *ONNXSileroVAD) FrameSize() int { return sileroFrameSize }
+
+// StateSize implements [VADModel]. The state holds the LSTM state followed
+// by the context samples.
+func (m *ONNXSileroVAD) StateSize() int { return sileroLSTMSize + sileroContextSize }
+
+// SpeechProb implements [VADModel].
+func (m *ONNXSileroVAD) SpeechProb(frame, state []float32) (float32, error) {
+	if len(frame) != sileroFrameSize || len(state) != m.StateSize() {
+		return 0, fmt.Errorf("vad: silero needs %d samples and %d state, got %d and %d",
+			sileroFrameSize, m.StateSize(), len(frame), len(state))
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return 0, fmt.Errorf("vad: model is closed")
+	}
+
+	lstm, ctx := state[:sileroLSTMSize], state[sileroLSTMSize:]
+	samples := make([]float32, 0, sileroContextSize+sileroFrameSize)
+	samples = append(append(samples, ctx...), frame...)
+
+	input, err := onnx.NewTensor([]int64{1, int64(len(samples))}, samples)
+	if err != nil {
+		return 0, fmt.Errorf("vad: %w", err)
+	}
+	defer input.Close()
+	lstmIn, err := onnx.NewTensor([]int64{2, 1, 128}, lstm)
+	if err != nil {
+		return 0, fmt.Errorf("vad: %w", err)
+	}
+	defer lstmIn.Close()
+	sr, err := onnx.NewInt64Tensor(nil, []int64{vadSampleRate})
+	if err != nil {
+		return 0, fmt.Errorf("vad: %w", err)
+	}
+	defer sr.Close()
+
+	outputs, err := m.session.Run(
+		[]string{"input", "state", "sr"},
+		[]*onnx.Tensor{input, lstmIn, sr},
+		[]string{"output", "stateN"},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("vad: inference: %w", err)
+	}
+	defer outputs[0].Close()
+	defer outputs[1].Close()
+
+	prob, err := outputs[0].FloatData()
+	if err != nil {
+		return 0, fmt.Errorf("vad: read output: %w", err)
+	}
+	next, err := outputs[1].FloatData()
+	if err != nil {
+		return 0, fmt.Errorf("vad: read state: %w", err)
+	}
+	if len(prob) == 0 || len(next) != sileroLSTMSize {
+		return 0, fmt.Errorf("vad: model returned %d outputs and %d state", len(prob), len(next))
+	}
+	copy(lstm, next)
+	copy(ctx, frame[sileroFrameSize-sileroContextSize:])
+	return prob[0], nil
+}
+
+// Close implements [VADModel].
+func (m *ONNXSileroVAD) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	return m.session.Close()
+}
+
+// EnergyVAD is a [VADModel] that reports speech when the RMS level of a
+// 20ms frame reaches a threshold. It needs no model file, but unlike Silero
+// it cannot tell speech from other loud sounds; use it for quiet
+// environments or as a fallback.
+type EnergyVAD struct {
+	level float64
+}
+
+var _ VADModel = (*EnergyVAD)(nil)
+
+// NewEnergyVAD creates an EnergyVAD with the given RMS threshold as a
+// fraction of full scale. Zero or less uses 0.02 (about -34 dBFS).
+func NewEnergyVAD(level float64) *EnergyVAD {
+	if level <= 0 {
+		level = 0.02
+	}
+	return &EnergyVAD{level: level}
+}
+
+// FrameSize implements [VADModel].
+func (m *EnergyVAD) FrameSize() int { return vadSampleRate * 20 / 1000 }
+
+// StateSize implements [VADModel].
+func (m *EnergyVAD) StateSize() int { return 0 }
+
+// SpeechProb implements [VADModel]. It returns 1 or 0.
+func (m *EnergyVAD) SpeechProb(frame, _ []float32) (float32, error) {
+	var sum float64
+	for _, s := range frame {
+		sum += float64(s) * float64(s)
+	}
+	if len(frame) > 0 && math.Sqrt(sum/float64(len(frame))) >= m.level {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Close implements [VADModel].
+func (m *EnergyVAD) Close() error { return nil }
+
+// VAD is a transformer that detects speech in user audio locally, so that
+// devices without turn detection (e.g., push-to-talk toys streaming
+// continuously) get speech segments without relying on a provider's VAD.
+//
+// Input type: audio/pcm (PCM16 signed little-endian, 16kHz, mono; convert
+// other audio with AudioConvert first)
+// Output type: audio/pcm, delimited into speech segments
+//
+// Only chunks with Role == genx.RoleUser are analyzed. A segment starts
+// once speech lasted the minimum speech duration, and ends once silence
+// lasted the minimum silence duration. The VAD emits a BOS marker with
+// MetaVAD "speech_start" before the segment and an audio/pcm EoS marker
+// with MetaVAD "speech_end" after it, which TurnManager and the ASR
+// transformers treat as user turns.
+//
+// By default all audio passes through, with the markers inserted after
+// the chunk in which speech started or ended. With WithVADTrimSilence,
+// only the audio of segments is emitted, starting the speech pad before
+// the detected onset, and the rest is dropped.
+//
+// Non-audio and non-user chunks are passed through unchanged.
+//
+// EoS Handling:
+//   - A user audio/pcm EoS during a segment ends it: the EoS is annotated
+//     with MetaVAD "speech_end" and passed through
+//   - Other user audio/pcm EoS markers are passed through
+//   - Detection state is reset after each EoS
+type VAD struct {
+	model VADModel
+
+	threshold   float32
+	minSpeech   time.Duration
+	minSilence  time.Duration
+	speechPad   time.Duration
+	trimSilence bool
+}
+
+var _ genx.Transformer = (*VAD)(nil)
+
+// VADOption configures a VAD transformer.
+type VADOption func(*VAD)
+
+// WithVADThreshold sets the speech probability that starts speech (default
+// 0.5). Speech continues until the probability drops 0.15 below it.
+func WithVADThreshold(p float32) VADOption {
+	return func(t *VAD) {
+		if p > 0 && p < 1 {
+			t.threshold = p
+		}
+	}
+}
+
+// WithVADMinSpeech sets how long speech must last to start a segment
+// (default 250ms).
+func WithVADMinSpeech(d time.Duration) VADOption {
+	return func(t *VAD) {
+		if d > 0 {
+			t.minSpeech = d
+		}
+	}
+}
+
+// WithVADMinSilence sets how long silence must last to end a segment
+// (default 600ms).
+func WithVADMinSilence(d time.Duration) VADOption {
+	return func(t *VAD) {
+		if d > 0 {
+			t.minSilence = d
+		}
+	}
+}
+
+// WithVADSpeechPad sets how much audio before the detected onset is kept
+// when trimming silence (default 300ms).
+func WithVADSpeechPad(d time.Duration) VADOption {
+	return func(t *VAD) {
+		if d >= 0 {
+			t.speechPad = d
+		}
+	}
+}
+
+// WithVADTrimSilence drops the audio outside speech segments.
+func WithVADTrimSilence(trim bool) VADOption {
+	return func(t *VAD) {
+		t.trimSilence = trim
+	}
+}
+
+// NewVAD creates a VAD transformer backed by the given model.
+func NewVAD(model VADModel, opts ...VADOption) *VAD {
+	t := &VAD{
+		model:      model,
+		threshold:  0.5,
+		minSpeech:  250 * time.Millisecond,
+		minSilence: 600 * time.Millisecond,
+		speechPad:  300 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Transform implements [genx.Transformer]. The ctx and pattern are unused.
+func (t *VAD) Transform(_ context.Context, _ string, input genx.Stream) (genx.Stream, error) {
+	output := newBufferStream(100)
+	go t.transformLoop(input, output)
+	return output, nil
+}
+
+// vadState is the state of one Transform.
+type vadState struct {
+	*VAD
+	output *bufferStream
+
+	frameBytes int
+	frame      []byte    // PCM of the current frame
+	samples    []float32 // frame scaled for the model
+	state      []float32 // model state
+
+	speaking bool
+	voiced   time.Duration // speech before a segment starts
+	silence  time.Duration // silence within a segment
+
+	preroll    []byte // trimmed audio kept for the speech pad
+	prerollMax int
+}
+
+func (t *VAD) transformLoop(input genx.Stream, output *bufferStream) {
+	defer output.Close()
+
+	frameSize := t.model.FrameSize()
+	s := &vadState{
+		VAD:        t,
+		output:     output,
+		frameBytes: frameSize * 2,
+		samples:    make([]float32, frameSize),
+		state:      make([]float32, t.model.StateSize()),
+		prerollMax: vadSampleRate * 2 * int((t.speechPad+t.minSpeech)/time.Millisecond) / 1000,
+	}
+
+	for {
+		chunk, err := input.Next()
+		if err != nil {
+			if err != io.EOF {
+				output.CloseWithError(err)
+			}
+			return
+		}
+		if chunk == nil {
+			continue
+		}
+
+		blob, isPCM := chunk.Part.(*genx.Blob)
+		isPCM = isPCM && isPCMMIME(blob.MIMEType)
+		if !isPCM || chunk.Role != genx.RoleUser {
+			if err := output.Push(chunk); err != nil {
+				return
+			}
+			continue
+		}
+
+		if chunk.IsEndOfStream() {
+			if s.speaking {
+				chunk.SetMetadata(MetaVAD, VADSpeechEnd)
+			}
+			s.reset()
+			if err := output.Push(chunk); err != nil {
+				return
+			}
+			continue
+		}
+
+		if err := s.audio(chunk, blob); err != nil {
+			return
+		}
+	}
+}
+
+// vadEvent is a change of the speaking state at a frame.
+type vadEvent int
+
+const (
+	vadNone vadEvent = iota
+	vadStart
+	vadEnd
+)
+
+// audio detects speech in a user audio chunk and emits it with the markers.
+// A model error closes the output.
+func (s *vadState) audio(chunk *genx.MessageChunk, blob *genx.Blob) error {
+	var (
+		events []vadEvent
+		out    []byte // trimmed audio to emit
+	)
+	for data := blob.Data; len(data) > 0; {
+		n := min(len(data), s.frameBytes-len(s.frame))
+		piece := data[:n]
+		data = data[n:]
+		s.frame = append(s.frame, piece...)
+
+		if s.trimSilence {
+			if s.speaking {
+				out = append(out, piece...)
+			} else {
+				s.keep(piece)
+			}
+		}
+		if len(s.frame) < s.frameBytes {
+			continue
+		}
+
+		ev, err := s.detect()
+		if err != nil {
+			s.output.CloseWithError(err)
+			return err
+		}
+		if ev == vadNone {
+			continue
+		}
+		if !s.trimSilence {
+			events = append(events, ev)
+			continue
+		}
+		// Emit the trimmed audio at the frame boundary, so the
+		// markers delimit it exactly.
+		if ev == vadStart {
+			if err := s.output.Push(s.marker(chunk, ev)); err != nil {
+				return err
+			}
+			out = append(out, s.preroll...)
+			s.preroll = s.preroll[:0]
+			continue
+		}
+		if len(out) > 0 {
+			if err := s.output.Push(s.audioChunk(chunk, blob.MIMEType, out)); err != nil {
+				return err
+			}
+			out = nil
+		}
+		if err := s.output.Push(s.marker(chunk, ev)); err != nil {
+			return err
+		}
+	}
+
+	if s.trimSilence {
+		if len(out) > 0 {
+			if err := s.output.Push(s.audioChunk(chunk, blob.MIMEType, out)); err != nil {
+				return err
+			}
+		}
+		chunk.Release()
+		return nil
+	}
+	if err := s.output.Push(chunk); err != nil {
+		return err
+	}
+	for _, ev := range events {
+		if err := s.output.Push(s.marker(chunk, ev)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// detect runs the model on the current frame and updates the speaking
+// state.
+func (s *vadState) detect() (vadEvent, error) {
+	for i := range s.samples {
+		s.samples[i] = float32(int16(binary.LittleEndian.Uint16(s.frame[i*2:]))) / 32768
+	}
+	s.frame = s.frame[:0]
+
+	prob, err := s.model.SpeechProb(s.samples, s.state)
+	if err != nil {
+		return vadNone, err
+	}
+	frameDur := time.Duration(len(s.samples)) * time.Second / vadSampleRate
+	negThreshold := max(s.threshold-0.15, 0.01)
+
+	if !s.speaking {
+		switch {
+		case prob >= s.threshold:
+			s.voiced += frameDur
+		case prob < negThreshold:
+			s.voiced = 0
+		}
+		if s.voiced > 0 && s.voiced >= s.minSpeech {
+			s.speaking = true
+			s.voiced = 0
+			s.silence = 0
+			return vadStart, nil
+		}
+		return vadNone, nil
+	}
+
+	switch {
+	case prob >= s.threshold:
+		s.silence = 0
+	case prob < negThreshold:
+		s.silence += frameDur
+	}
+	if s.silence >= s.minSilence {
+		s.speaking = false
+		s.silence = 0
+		return vadEnd, nil
+	}
+	return vadNone, nil
+}
+
+// keep adds trimmed audio to the speech pad.
+func (s *vadState) keep(pcm []byte) {
+	s.preroll = append(s.preroll, pcm...)
+	if over := len(s.preroll) - s.prerollMax; over > 0 {
+		over += over % 2 // keep whole samples
+		s.preroll = append(s.preroll[:0], s.preroll[min(over, len(s.preroll)):]...)
+	}
+}
+
+// reset clears the detection state at the end of a stream.
+func (s *vadState) reset() {
+	s.frame = s.frame[:0]
+	clear(s.state)
+	s.speaking = false
+	s.voiced = 0
+	s.silence = 0
+	s.preroll = s.preroll[:0]
+}
+
+// marker returns the BOS or EoS marker of ev for the stream of chunk.
+func (s *vadState) marker(chunk *genx.MessageChunk, ev vadEvent) *genx.MessageChunk {
+	var streamID string
+	if chunk.Ctrl != nil {
+		streamID = chunk.Ctrl.StreamID
+	}
+	var m *genx.MessageChunk
+	if ev == vadStart {
+		m = genx.NewBeginOfStream(streamID)
+		m.SetMetadata(MetaVAD, VADSpeechStart)
+	} else {
+		m = genx.NewEndOfStream(chunk.Part.(*genx.Blob).MIMEType)
+		m.Ctrl.StreamID = streamID
+		m.SetMetadata(MetaVAD, VADSpeechEnd)
+	}
+	m.Role = chunk.Role
+	m.Name = chunk.Name
+	return m
+}
+
+// audioChunk returns a chunk of trimmed audio for the stream of chunk.
+func (s *vadState) audioChunk(chunk *genx.MessageChunk, mimeType string, pcm []byte) *genx.MessageChunk {
+	c := &genx.MessageChunk{
+		Role: chunk.Role,
+		Name: chunk.Name,
+		Part: &genx.Blob{MIMEType: mimeType, Data: pcm},
+	}
+	if chunk.Ctrl != nil && chunk.Ctrl.StreamID != "" {
+		c.Ctrl = &genx.StreamCtrl{StreamID: chunk.Ctrl.StreamID}
+	}
+	return c
+}
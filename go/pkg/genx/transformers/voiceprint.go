@@ -142,6 +142,7 @@ func (t *Voiceprint) transformLoop(ctx context.Context, input genx.Stream, outpu
 				eos := genx.NewEndOfStream(blob.MIMEType)
 				eos.Role = chunk.Role
 				eos.Name = chunk.Name
+				chunk.Release()
 				annotateLabel(eos, lastLabel)
 				if err := output.Push(eos); err != nil {
 					return
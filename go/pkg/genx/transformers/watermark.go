@@ -0,0 +1,140 @@
+package transformers
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/haivivi/giztoy/go/pkg/audio/watermark"
+	"github.com/haivivi/giztoy/go/pkg/genx"
+)
+
+// Metadata keys written by the Watermark transformer into StreamCtrl.Metadata.
+const (
+	// MetaWatermark marks generated audio. Its value is "synthetic".
+	MetaWatermark = "watermark"
+	// MetaWatermarkMethod is "spread-spectrum" when the audio samples carry
+	// a detectable mark, or "sidecar" when only this metadata marks them.
+	MetaWatermarkMethod = "watermark.method"
+)
+
+// Watermark method values for MetaWatermarkMethod.
+const (
+	WatermarkSpreadSpectrum = "spread-spectrum"
+	WatermarkSidecar        = "sidecar"
+)
+
+// Watermark is a pass-through transformer that marks model audio as
+// synthetic, for compliance requirements on generated speech.
+//
+// RoleModel PCM chunks get an inaudible spread-spectrum mark (see package
+// audio/watermark) that watermark.Detect identifies later. As that
+// package states, the mark survives gain changes and mixing but not
+// resampling or lossy codecs, so it is only detectable in the PCM as
+// generated or stored losslessly. Other RoleModel audio (e.g., mp3, ogg)
+// cannot be marked in the samples and only receives the metadata
+// sidecar. Every marked chunk carries MetaWatermark and
+// MetaWatermarkMethod, so downstream code that stores or forwards audio
+// can record provenance.
+//
+// Place Watermark directly after TTS or realtime transformers, before any
+// lossy encoding. Chunks are copied before marking; the input chunks are
+// not modified. Non-audio chunks and user audio pass through unchanged.
+// The embedder restarts at each EoS marker.
+//
+// Input: audio/* (RoleModel)
+// Output: same MIME type, annotated
+type Watermark struct {
+	cfg         watermark.Config
+	sidecarOnly bool
+}
+
+var _ genx.Transformer = (*Watermark)(nil)
+
+// WatermarkOption configures a Watermark transformer.
+type WatermarkOption func(*Watermark)
+
+// WithWatermarkKey sets the key of the spread-spectrum mark. Detectors must
+// use the same key.
+func WithWatermarkKey(key string) WatermarkOption {
+	return func(t *Watermark) {
+		t.cfg.Key = key
+	}
+}
+
+// WithWatermarkStrength sets the mark amplitude relative to the signal
+// level (default 0.03).
+func WithWatermarkStrength(strength float64) WatermarkOption {
+	return func(t *Watermark) {
+		if strength > 0 {
+			t.cfg.Strength = strength
+		}
+	}
+}
+
+// WithWatermarkSidecarOnly disables sample marking; chunks are only
+// annotated with metadata.
+func WithWatermarkSidecarOnly() WatermarkOption {
+	return func(t *Watermark) {
+		t.sidecarOnly = true
+	}
+}
+
+// NewWatermark creates a Watermark transformer.
+func NewWatermark(opts ...WatermarkOption) *Watermark {
+	t := &Watermark{}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Transform implements genx.Transformer. The ctx and pattern are unused.
+func (t *Watermark) Transform(_ context.Context, _ string, input genx.Stream) (genx.Stream, error) {
+	output := newBufferStream(100)
+	go t.transformLoop(input, output)
+	return output, nil
+}
+
+func (t *Watermark) transformLoop(input genx.Stream, output *bufferStream) {
+	defer output.Close()
+
+	embedder := watermark.NewEmbedder(t.cfg)
+	for {
+		chunk, err := input.Next()
+		if err != nil {
+			if err != io.EOF {
+				output.CloseWithError(err)
+			}
+			return
+		}
+		if chunk == nil {
+			continue
+		}
+
+		blob, ok := chunk.Part.(*genx.Blob)
+		if !ok || chunk.Role != genx.RoleModel || !strings.HasPrefix(blob.MIMEType, "audio/") {
+			if err := output.Push(chunk); err != nil {
+				return
+			}
+			continue
+		}
+
+		// The input may be shared, so mark a copy and release the input
+		marked := chunk.ClonePooled()
+		chunk.Release()
+		method := WatermarkSidecar
+		if !t.sidecarOnly && isPCMMIME(blob.MIMEType) {
+			embedder.Embed(marked.Part.(*genx.Blob).Data)
+			method = WatermarkSpreadSpectrum
+		}
+		marked.SetMetadata(MetaWatermark, "synthetic")
+		marked.SetMetadata(MetaWatermarkMethod, method)
+		if marked.IsEndOfStream() {
+			embedder.Reset()
+		}
+		if err := output.Push(marked); err != nil {
+			return
+		}
+	}
+}
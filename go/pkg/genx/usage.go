@@ -0,0 +1,87 @@
+package genx
+
+import "context"
+
+// Add returns the sum of u and v.
+func (u Usage) Add(v Usage) Usage {
+	return Usage{
+		PromptTokenCount:        u.PromptTokenCount + v.PromptTokenCount,
+		CachedContentTokenCount: u.CachedContentTokenCount + v.CachedContentTokenCount,
+		GeneratedTokenCount:     u.GeneratedTokenCount + v.GeneratedTokenCount,
+	}
+}
+
+// UsageStream is a Stream that reports the token usage of the generation
+// while it streams, e.g. so that an agent can cut a generation that runs
+// over its token budget. The usage of a finished stream is that of the
+// State ending it.
+type UsageStream interface {
+	Stream
+
+	// Usage returns the usage reported by the provider so far. Providers
+	// differ in when they report it: Gemini with every chunk, OpenAI and
+	// compatible servers with the last one only.
+	Usage() Usage
+}
+
+// StreamUsage returns the usage reported so far by s, or false if s is not
+// a UsageStream.
+func StreamUsage(s Stream) (Usage, bool) {
+	if us, ok := s.(UsageStream); ok {
+		return us.Usage(), true
+	}
+	return Usage{}, false
+}
+
+// UsageCollector receives the token usage of generator calls made with a
+// context from WithUsageCollector.
+type UsageCollector interface {
+	// CollectUsage is called once per completed call with the model the
+	// call was routed to. It may be called concurrently.
+	CollectUsage(ctx context.Context, model string, usage Usage)
+}
+
+// UsageCollectorFunc adapts a function to a UsageCollector.
+type UsageCollectorFunc func(ctx context.Context, model string, usage Usage)
+
+// CollectUsage implements UsageCollector.
+func (f UsageCollectorFunc) CollectUsage(ctx context.Context, model string, usage Usage) {
+	f(ctx, model, usage)
+}
+
+type usageCollectorsKey struct{}
+
+// WithUsageCollector returns a context that reports the usage of generator
+// calls to c, in addition to the collectors of ctx. Nesting collectors lets
+// one call be accounted for, e.g., both per persona and per device:
+//
+//	ctx = genx.WithUsageCollector(ctx, personaStats)
+//	ctx = genx.WithUsageCollector(ctx, deviceStats)
+//	stream, err := generators.GenerateStream(ctx, "qwen/turbo", mctx)
+//
+// Usage is reported by the code routing the call, such as generators.Mux,
+// through ReportUsage.
+func WithUsageCollector(ctx context.Context, c UsageCollector) context.Context {
+	parent := usageCollectors(ctx)
+	cs := make([]UsageCollector, len(parent), len(parent)+1)
+	copy(cs, parent)
+	return context.WithValue(ctx, usageCollectorsKey{}, append(cs, c))
+}
+
+// HasUsageCollector reports whether ctx has a usage collector.
+func HasUsageCollector(ctx context.Context) bool {
+	return len(usageCollectors(ctx)) > 0
+}
+
+// ReportUsage reports the usage of a call to model to the collectors of
+// ctx, outermost first.
+func ReportUsage(ctx context.Context, model string, usage Usage) {
+	for _, c := range usageCollectors(ctx) {
+		c.CollectUsage(ctx, model, usage)
+	}
+}
+
+func usageCollectors(ctx context.Context) []UsageCollector {
+	cs, _ := ctx.Value(usageCollectorsKey{}).([]UsageCollector)
+	return cs
+}
@@ -0,0 +1,50 @@
+package genx
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUsage_Add(t *testing.T) {
+	got := Usage{PromptTokenCount: 10, CachedContentTokenCount: 4, GeneratedTokenCount: 3}.
+		Add(Usage{PromptTokenCount: 5, GeneratedTokenCount: 2})
+	want := Usage{PromptTokenCount: 15, CachedContentTokenCount: 4, GeneratedTokenCount: 5}
+	if got != want {
+		t.Errorf("Add() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWithUsageCollector(t *testing.T) {
+	ctx := context.Background()
+	if HasUsageCollector(ctx) {
+		t.Fatal("HasUsageCollector() = true for a bare context")
+	}
+	ReportUsage(ctx, "m", Usage{PromptTokenCount: 1}) // no collectors: no-op
+
+	var order []string
+	collector := func(name string) UsageCollector {
+		return UsageCollectorFunc(func(_ context.Context, model string, u Usage) {
+			order = append(order, name+":"+model)
+		})
+	}
+	outer := WithUsageCollector(ctx, collector("persona"))
+	inner := WithUsageCollector(outer, collector("device"))
+	sibling := WithUsageCollector(outer, collector("other"))
+
+	if !HasUsageCollector(inner) {
+		t.Fatal("HasUsageCollector() = false")
+	}
+	ReportUsage(inner, "qwen/turbo", Usage{GeneratedTokenCount: 1})
+	ReportUsage(sibling, "qwen/plus", Usage{GeneratedTokenCount: 1})
+
+	want := []string{"persona:qwen/turbo", "device:qwen/turbo", "persona:qwen/plus", "other:qwen/plus"}
+	if len(order) != len(want) {
+		t.Fatalf("reports = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("reports = %v, want %v", order, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package graph
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/haivivi/giztoy/go/pkg/kv"
+)
+
+// Embeddings are stored next to the entities and searched by a brute-force
+// scan, which is fast enough for the few thousand entities of a persona
+// graph. Larger collections belong in a vecstore.Index.
+
+func (g *KVGraph) SetEmbedding(ctx context.Context, label string, vector []float32) error {
+	if err := g.validateSegments(label); err != nil {
+		return err
+	}
+	if _, err := g.store.Get(ctx, g.entityKey(label)); err != nil {
+		if errors.Is(err, kv.ErrNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if len(vector) == 0 {
+		return g.store.Delete(ctx, g.embeddingKey(label))
+	}
+	return g.store.Set(ctx, g.embeddingKey(label), encodeVector(vector))
+}
+
+func (g *KVGraph) GetEmbedding(ctx context.Context, label string) ([]float32, error) {
+	if err := g.validateSegments(label); err != nil {
+		return nil, err
+	}
+	data, err := g.store.Get(ctx, g.embeddingKey(label))
+	if err != nil {
+		if errors.Is(err, kv.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return decodeVector(data)
+}
+
+func (g *KVGraph) SimilarEntities(ctx context.Context, vector []float32, k int) ([]ScoredEntity, error) {
+	if k <= 0 || len(vector) == 0 {
+		return nil, nil
+	}
+	qnorm := norm(vector)
+	if qnorm == 0 {
+		return nil, nil
+	}
+
+	var results []ScoredEntity
+	for entry, err := range g.store.List(ctx, g.embeddingPrefix()) {
+		if err != nil {
+			return nil, err
+		}
+		v, err := decodeVector(entry.Value)
+		if err != nil || len(v) != len(vector) {
+			continue
+		}
+		vnorm := norm(v)
+		if vnorm == 0 {
+			continue
+		}
+		var dot float64
+		for i := range v {
+			dot += float64(v[i]) * float64(vector[i])
+		}
+		results = append(results, ScoredEntity{
+			Label: entry.Key[len(entry.Key)-1],
+			Score: float32(dot / (qnorm * vnorm)),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Label < results[j].Label
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return buf
+}
+
+func decodeVector(data []byte) ([]float32, error) {
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("graph: corrupt embedding of %d bytes", len(data))
+	}
+	v := make([]float32, len(data)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return v, nil
+}
+
+func norm(v []float32) float64 {
+	var sum float64
+	for _, f := range v {
+		sum += float64(f) * float64(f)
+	}
+	return math.Sqrt(sum)
+}
@@ -44,6 +44,15 @@ type Relation struct {
 	RelType string `json:"rel_type"`
 }
 
+// ScoredEntity is an entity label with its similarity to a query vector.
+type ScoredEntity struct {
+	// Label is the entity label.
+	Label string `json:"label"`
+
+	// Score is the cosine similarity in [-1, 1]; higher is more similar.
+	Score float32 `json:"score"`
+}
+
 // Graph is the interface for an entity-relation graph.
 type Graph interface {
 	// --- Entity operations ---
@@ -90,4 +99,20 @@ type Graph interface {
 	// labels, returning all discovered labels (including seeds). hops controls
 	// the maximum traversal depth (0 returns only the seeds).
 	Expand(ctx context.Context, labels []string, hops int) ([]string, error)
+
+	// --- Embeddings ---
+
+	// SetEmbedding attaches an embedding vector to an existing entity,
+	// replacing any previous one. An empty vector removes the embedding.
+	// Returns ErrNotFound if the entity does not exist.
+	SetEmbedding(ctx context.Context, label string, vector []float32) error
+
+	// GetEmbedding returns the embedding vector of an entity. Returns
+	// ErrNotFound if the entity has no embedding.
+	GetEmbedding(ctx context.Context, label string) ([]float32, error)
+
+	// SimilarEntities returns up to k entities whose embeddings are most
+	// similar to vector by cosine similarity, most similar first. Entities
+	// without an embedding or with a different dimension are skipped.
+	SimilarEntities(ctx context.Context, vector []float32, k int) ([]ScoredEntity, error)
 }
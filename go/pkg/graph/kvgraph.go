@@ -17,6 +17,7 @@ import (
 //	{prefix}:e:{label}                  → JSON-encoded Entity.Attrs
 //	{prefix}:r:{from}:{relType}:{to}   → empty (forward index)
 //	{prefix}:ri:{to}:{relType}:{from}  → empty (reverse index)
+//	{prefix}:v:{label}                  → embedding, little-endian float32s
 
 // KVGraph is a Graph implementation backed by a kv.Store.
 // All keys are scoped under a configurable prefix, allowing multiple
@@ -72,6 +73,21 @@ func (g *KVGraph) entityPrefix() kv.Key {
 	return k
 }
 
+func (g *KVGraph) embeddingKey(label string) kv.Key {
+	k := make(kv.Key, len(g.prefix)+2)
+	copy(k, g.prefix)
+	k[len(g.prefix)] = "v"
+	k[len(g.prefix)+1] = label
+	return k
+}
+
+func (g *KVGraph) embeddingPrefix() kv.Key {
+	k := make(kv.Key, len(g.prefix)+1)
+	copy(k, g.prefix)
+	k[len(g.prefix)] = "v"
+	return k
+}
+
 func (g *KVGraph) fwdKey(from, relType, to string) kv.Key {
 	k := make(kv.Key, len(g.prefix)+4)
 	copy(k, g.prefix)
@@ -151,9 +167,9 @@ func (g *KVGraph) DeleteEntity(ctx context.Context, label string) error {
 		return err
 	}
 
-	// Build a list of all keys to delete: entity + relation pairs.
-	keys := make([]kv.Key, 0, 1+len(rels)*2)
-	keys = append(keys, g.entityKey(label))
+	// Build a list of all keys to delete: entity + embedding + relation pairs.
+	keys := make([]kv.Key, 0, 2+len(rels)*2)
+	keys = append(keys, g.entityKey(label), g.embeddingKey(label))
 	for _, r := range rels {
 		keys = append(keys, g.fwdKey(r.From, r.RelType, r.To))
 		keys = append(keys, g.revKey(r.To, r.RelType, r.From))
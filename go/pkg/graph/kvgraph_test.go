@@ -612,6 +612,104 @@ func TestExpand_MultipleSeeds(t *testing.T) {
 	}
 }
 
+// --- Embedding tests ---
+
+func TestSimilarEntities(t *testing.T) {
+	g := newTestGraph(t)
+	ctx := context.Background()
+
+	vectors := map[string][]float32{
+		"cat":   {1, 0, 0},
+		"kitty": {0.9, 0.1, 0},
+		"dog":   {0.5, 0.5, 0},
+		"car":   {0, 0, 1},
+	}
+	for label, v := range vectors {
+		if err := g.SetEntity(ctx, graph.Entity{Label: label}); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.SetEmbedding(ctx, label, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Entity without an embedding is skipped.
+	if err := g.SetEntity(ctx, graph.Entity{Label: "plain"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := g.SimilarEntities(ctx, []float32{1, 0, 0}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var labels []string
+	for _, s := range got {
+		labels = append(labels, s.Label)
+	}
+	if want := []string{"cat", "kitty", "dog"}; !slices.Equal(labels, want) {
+		t.Fatalf("SimilarEntities = %v, want %v", labels, want)
+	}
+	if got[0].Score < 0.999 {
+		t.Errorf("top score = %v, want 1", got[0].Score)
+	}
+
+	// Dimension mismatch matches nothing.
+	got, err = g.SimilarEntities(ctx, []float32{1, 0}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("expected no matches for 2-dim query, got %v", got)
+	}
+}
+
+func TestSetEmbedding_NotFound(t *testing.T) {
+	g := newTestGraph(t)
+	ctx := context.Background()
+
+	err := g.SetEmbedding(ctx, "ghost", []float32{1, 2})
+	if !errors.Is(err, graph.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestEmbedding_RemovedWithEntity(t *testing.T) {
+	g := newTestGraph(t)
+	ctx := context.Background()
+
+	if err := g.SetEntity(ctx, graph.Entity{Label: "A"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetEmbedding(ctx, "A", []float32{0.25, -1.5}); err != nil {
+		t.Fatal(err)
+	}
+	v, err := g.GetEmbedding(ctx, "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(v, []float32{0.25, -1.5}) {
+		t.Fatalf("GetEmbedding = %v", v)
+	}
+
+	// Embeddings must not show up as entities.
+	var n int
+	for _, err := range g.ListEntities(ctx, "") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 1 {
+		t.Fatalf("ListEntities returned %d entities, want 1", n)
+	}
+
+	if err := g.DeleteEntity(ctx, "A"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.GetEmbedding(ctx, "A"); !errors.Is(err, graph.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
 // --- Benchmarks ---
 
 func setupBenchGraph(b *testing.B, nEntities, nRelations int) graph.Graph {
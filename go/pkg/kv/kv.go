@@ -3,7 +3,9 @@
 // and encoded internally using a configurable separator (default ':').
 //
 // The package includes a BadgerDB-backed implementation for production use and
-// an in-memory implementation for testing.
+// an in-memory implementation for testing. Instrument wraps any Store with
+// per-operation metrics and slow-operation logging, and NewTiered caches a
+// slow Store (e.g. Badger) in a fast one (e.g. Memory).
 package kv
 
 import (
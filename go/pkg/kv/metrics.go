@@ -0,0 +1,257 @@
+package kv
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Op names a Store operation for metrics.
+type Op string
+
+const (
+	OpGet         Op = "get"
+	OpSet         Op = "set"
+	OpDelete      Op = "delete"
+	OpList        Op = "list"
+	OpBatchSet    Op = "batch_set"
+	OpBatchDelete Op = "batch_delete"
+)
+
+// Metrics receives one observation per Store operation.
+//
+// n is the number of keys the operation touched: 1 for Get/Set/Delete, the
+// number of entries yielded for List, and the batch size for batch
+// operations. err is nil on success; Get returning ErrNotFound counts as a
+// success. Implementations must be safe for concurrent use.
+type Metrics interface {
+	ObserveOp(op Op, d time.Duration, n int, err error)
+}
+
+// InstrumentOptions configures Instrument.
+type InstrumentOptions struct {
+	// Name identifies the store in slow-operation logs (e.g. "memory").
+	Name string
+
+	// Metrics receives every operation. Nil disables metrics.
+	Metrics Metrics
+
+	// SlowThreshold logs operations that take at least this long. Zero
+	// disables slow-operation logging.
+	SlowThreshold time.Duration
+
+	// Logger receives slow-operation warnings. Default slog.Default().
+	Logger *slog.Logger
+}
+
+// Instrumented wraps a Store and reports the latency of every operation.
+// It works with any backend.
+type Instrumented struct {
+	store Store
+	opts  InstrumentOptions
+}
+
+var _ Store = (*Instrumented)(nil)
+
+// Instrument wraps store with metrics and slow-operation logging.
+//
+// List latency covers the time spent inside the backend iterator only, not
+// the time the caller spends between entries.
+func Instrument(store Store, opts InstrumentOptions) *Instrumented {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	return &Instrumented{store: store, opts: opts}
+}
+
+// Unwrap returns the underlying store.
+func (s *Instrumented) Unwrap() Store { return s.store }
+
+func (s *Instrumented) observe(op Op, key Key, d time.Duration, n int, err error) {
+	if errors.Is(err, ErrNotFound) {
+		err = nil
+	}
+	if s.opts.Metrics != nil {
+		s.opts.Metrics.ObserveOp(op, d, n, err)
+	}
+	if s.opts.SlowThreshold > 0 && d >= s.opts.SlowThreshold {
+		s.opts.Logger.Warn("kv: slow operation",
+			"store", s.opts.Name,
+			"op", string(op),
+			"key", key.String(),
+			"n", n,
+			"duration", d,
+			"error", err)
+	}
+}
+
+func (s *Instrumented) Get(ctx context.Context, key Key) ([]byte, error) {
+	start := time.Now()
+	v, err := s.store.Get(ctx, key)
+	s.observe(OpGet, key, time.Since(start), 1, err)
+	return v, err
+}
+
+func (s *Instrumented) Set(ctx context.Context, key Key, value []byte) error {
+	start := time.Now()
+	err := s.store.Set(ctx, key, value)
+	s.observe(OpSet, key, time.Since(start), 1, err)
+	return err
+}
+
+func (s *Instrumented) Delete(ctx context.Context, key Key) error {
+	start := time.Now()
+	err := s.store.Delete(ctx, key)
+	s.observe(OpDelete, key, time.Since(start), 1, err)
+	return err
+}
+
+func (s *Instrumented) List(ctx context.Context, prefix Key) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		var (
+			busy    time.Duration
+			n       int
+			err     error
+			stopped bool
+		)
+		resumed := time.Now()
+		for e, iterErr := range s.store.List(ctx, prefix) {
+			busy += time.Since(resumed)
+			if iterErr != nil {
+				err = iterErr
+			} else {
+				n++
+			}
+			if !yield(e, iterErr) {
+				stopped = true
+				break
+			}
+			resumed = time.Now()
+		}
+		if !stopped {
+			busy += time.Since(resumed)
+		}
+		s.observe(OpList, prefix, busy, n, err)
+	}
+}
+
+func (s *Instrumented) BatchSet(ctx context.Context, entries []Entry) error {
+	start := time.Now()
+	err := s.store.BatchSet(ctx, entries)
+	var key Key
+	if len(entries) > 0 {
+		key = entries[0].Key
+	}
+	s.observe(OpBatchSet, key, time.Since(start), len(entries), err)
+	return err
+}
+
+func (s *Instrumented) BatchDelete(ctx context.Context, keys []Key) error {
+	start := time.Now()
+	err := s.store.BatchDelete(ctx, keys)
+	var key Key
+	if len(keys) > 0 {
+		key = keys[0]
+	}
+	s.observe(OpBatchDelete, key, time.Since(start), len(keys), err)
+	return err
+}
+
+func (s *Instrumented) Close() error {
+	return s.store.Close()
+}
+
+// LatencyBuckets are the upper bounds of the OpStats latency histogram.
+// The last bucket of OpStats.Buckets counts operations slower than all of
+// them.
+var LatencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// OpStats holds the counters of one operation type.
+type OpStats struct {
+	Count  int64         `json:"count"`
+	Errors int64         `json:"errors"`
+	Keys   int64         `json:"keys"`
+	Total  time.Duration `json:"total"`
+	Max    time.Duration `json:"max"`
+
+	// Buckets counts operations per latency bucket; see LatencyBuckets.
+	Buckets []int64 `json:"buckets"`
+}
+
+// Mean returns the average latency.
+func (s OpStats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// Stats is an in-process Metrics implementation with per-operation
+// counters and latency histograms. It is safe for concurrent use.
+type Stats struct {
+	mu  sync.Mutex
+	ops map[Op]*OpStats
+}
+
+var _ Metrics = (*Stats)(nil)
+
+// NewStats creates an empty Stats.
+func NewStats() *Stats {
+	return &Stats{ops: make(map[Op]*OpStats)}
+}
+
+// ObserveOp implements Metrics.
+func (s *Stats) ObserveOp(op Op, d time.Duration, n int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.ops[op]
+	if !ok {
+		st = &OpStats{Buckets: make([]int64, len(LatencyBuckets)+1)}
+		s.ops[op] = st
+	}
+	st.Count++
+	st.Keys += int64(n)
+	if err != nil {
+		st.Errors++
+	}
+	st.Total += d
+	st.Max = max(st.Max, d)
+	i := 0
+	for i < len(LatencyBuckets) && d > LatencyBuckets[i] {
+		i++
+	}
+	st.Buckets[i]++
+}
+
+// Snapshot returns a copy of the current counters.
+func (s *Stats) Snapshot() map[Op]OpStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[Op]OpStats, len(s.ops))
+	for op, st := range s.ops {
+		cp := *st
+		cp.Buckets = append([]int64(nil), st.Buckets...)
+		out[op] = cp
+	}
+	return out
+}
+
+// Reset clears all counters.
+func (s *Stats) Reset() {
+	s.mu.Lock()
+	clear(s.ops)
+	s.mu.Unlock()
+}
@@ -0,0 +1,105 @@
+package kv_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/kv"
+)
+
+func TestInstrumentedStats(t *testing.T) {
+	ctx := context.Background()
+	stats := kv.NewStats()
+	s := kv.Instrument(newTestStore(t, nil), kv.InstrumentOptions{Metrics: stats})
+
+	if err := s.Set(ctx, kv.Key{"a", "1"}, []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.BatchSet(ctx, []kv.Entry{
+		{Key: kv.Key{"a", "2"}, Value: []byte("y")},
+		{Key: kv.Key{"a", "3"}, Value: []byte("z")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, kv.Key{"a", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, kv.Key{"missing"}); err != kv.ErrNotFound {
+		t.Fatalf("Get missing: err = %v", err)
+	}
+	for _, err := range s.List(ctx, kv.Key{"a"}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snap := stats.Snapshot()
+	if got := snap[kv.OpGet]; got.Count != 2 || got.Errors != 0 {
+		t.Errorf("get stats = %+v, want 2 ops, 0 errors (not found is not an error)", got)
+	}
+	if got := snap[kv.OpBatchSet]; got.Count != 1 || got.Keys != 2 {
+		t.Errorf("batch_set stats = %+v, want 1 op, 2 keys", got)
+	}
+	if got := snap[kv.OpList]; got.Count != 1 || got.Keys != 3 {
+		t.Errorf("list stats = %+v, want 1 op, 3 keys", got)
+	}
+	var sum int64
+	for _, n := range snap[kv.OpGet].Buckets {
+		sum += n
+	}
+	if sum != 2 || len(snap[kv.OpGet].Buckets) != len(kv.LatencyBuckets)+1 {
+		t.Errorf("get buckets = %v", snap[kv.OpGet].Buckets)
+	}
+
+	stats.Reset()
+	if len(stats.Snapshot()) != 0 {
+		t.Error("Reset did not clear stats")
+	}
+}
+
+func TestInstrumentedListEarlyStop(t *testing.T) {
+	ctx := context.Background()
+	stats := kv.NewStats()
+	s := kv.Instrument(newTestStore(t, nil), kv.InstrumentOptions{Metrics: stats})
+	for _, k := range []string{"1", "2", "3"} {
+		if err := s.Set(ctx, kv.Key{"a", k}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for range s.List(ctx, kv.Key{"a"}) {
+		// Time spent by the caller is not counted.
+		time.Sleep(20 * time.Millisecond)
+		break
+	}
+
+	got := stats.Snapshot()[kv.OpList]
+	if got.Count != 1 || got.Keys != 1 {
+		t.Errorf("list stats = %+v, want 1 op, 1 key", got)
+	}
+	if got.Max >= 20*time.Millisecond {
+		t.Errorf("list latency %v includes caller time", got.Max)
+	}
+}
+
+func TestInstrumentedSlowLog(t *testing.T) {
+	var buf bytes.Buffer
+	s := kv.Instrument(newTestStore(t, nil), kv.InstrumentOptions{
+		Name:          "test",
+		SlowThreshold: time.Nanosecond,
+		Logger:        slog.New(slog.NewTextHandler(&buf, nil)),
+	})
+	if err := s.Set(context.Background(), kv.Key{"slow", "key"}, []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"slow operation", "store=test", "op=set", "key=slow:key"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log %q missing %q", out, want)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package kv
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"hash/maphash"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tieredStripes is the number of locks serializing writes by key in
+// Tiered.
+const tieredStripes = 64
+
+// TieredOptions configures NewTiered.
+type TieredOptions struct {
+	// MaxEntries bounds the number of keys cached in the fast store; the
+	// least recently used keys are evicted. Zero means unbounded.
+	MaxEntries int
+
+	// TTL expires cached keys this long after they were cached, bounding
+	// how long writes to the slow store that bypass the Tiered store (e.g.
+	// by another process) stay invisible. Expiry is checked on Get, so
+	// set MaxEntries too to bound memory. Zero means no expiry.
+	TTL time.Duration
+}
+
+// TieredStats counts the Get calls of a Tiered store.
+type TieredStats struct {
+	// Hits is the number of Get calls served by the fast store, Misses the
+	// number that read the slow store.
+	Hits   int64
+	Misses int64
+
+	// Evictions is the number of keys evicted for MaxEntries or TTL.
+	Evictions int64
+
+	// Entries is the number of keys cached.
+	Entries int
+}
+
+// Tiered composes a fast store caching a slow one, e.g. a Memory over a
+// Badger store, so that hot keys are read without touching disk.
+//
+// Get reads the fast store first and caches values read from the slow
+// store. Writes go through to the slow store and then update (Set,
+// BatchSet) or invalidate (Delete, BatchDelete) the fast store; a failed
+// write invalidates the keys it touched. List reads the slow store only,
+// as the fast store holds a subset of the keys.
+//
+// The fast store must not be written by others. Writes to the slow store
+// that bypass the Tiered store are seen once the cached keys are evicted,
+// see TieredOptions.TTL.
+type Tiered struct {
+	fast Store
+	slow Store
+	opts TieredOptions
+
+	seed    maphash.Seed
+	stripes [tieredStripes]tieredStripe
+
+	mu      sync.Mutex
+	lru     *list.List // of *tieredEntry, most recently used first
+	entries map[string]*list.Element
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+// tieredStripe serializes writes to its keys. gen changes on every write,
+// so that a Get does not cache a value read before a write.
+type tieredStripe struct {
+	mu  sync.Mutex
+	gen uint64
+}
+
+type tieredEntry struct {
+	id      string
+	key     Key
+	expires time.Time
+}
+
+var _ Store = (*Tiered)(nil)
+
+// NewTiered creates a Tiered store caching slow in fast. Pass nil opts for
+// an unbounded cache without expiry.
+func NewTiered(fast, slow Store, opts *TieredOptions) *Tiered {
+	t := &Tiered{
+		fast:    fast,
+		slow:    slow,
+		seed:    maphash.MakeSeed(),
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+	if opts != nil {
+		t.opts = *opts
+	}
+	return t
+}
+
+// Fast returns the fast store.
+func (t *Tiered) Fast() Store { return t.fast }
+
+// Slow returns the slow store.
+func (t *Tiered) Slow() Store { return t.slow }
+
+// Stats returns the counters of the cache.
+func (t *Tiered) Stats() TieredStats {
+	t.mu.Lock()
+	n := len(t.entries)
+	t.mu.Unlock()
+	return TieredStats{
+		Hits:      t.hits.Load(),
+		Misses:    t.misses.Load(),
+		Evictions: t.evictions.Load(),
+		Entries:   n,
+	}
+}
+
+func (t *Tiered) Get(ctx context.Context, key Key) ([]byte, error) {
+	id := tieredID(key)
+	if t.touch(ctx, id) {
+		v, err := t.fast.Get(ctx, key)
+		if err == nil {
+			t.hits.Add(1)
+			return v, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+	}
+	t.misses.Add(1)
+
+	s := t.stripe(id)
+	s.mu.Lock()
+	gen := s.gen
+	s.mu.Unlock()
+
+	v, err := t.slow.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.gen == gen {
+		t.cache(ctx, id, key, v)
+	}
+	s.mu.Unlock()
+	return v, nil
+}
+
+func (t *Tiered) Set(ctx context.Context, key Key, value []byte) error {
+	id := tieredID(key)
+	s := t.stripe(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := t.slow.Set(ctx, key, value)
+	s.gen++
+	if err != nil {
+		t.invalidate(ctx, id, key)
+		return err
+	}
+	t.cache(ctx, id, key, value)
+	return nil
+}
+
+func (t *Tiered) Delete(ctx context.Context, key Key) error {
+	id := tieredID(key)
+	s := t.stripe(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := t.slow.Delete(ctx, key)
+	s.gen++
+	t.invalidate(ctx, id, key)
+	return err
+}
+
+func (t *Tiered) List(ctx context.Context, prefix Key) iter.Seq2[Entry, error] {
+	return t.slow.List(ctx, prefix)
+}
+
+func (t *Tiered) BatchSet(ctx context.Context, entries []Entry) error {
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = tieredID(e.Key)
+	}
+	unlock := t.lockStripes(ids)
+	defer unlock()
+
+	err := t.slow.BatchSet(ctx, entries)
+	for i, e := range entries {
+		if err != nil {
+			t.invalidate(ctx, ids[i], e.Key)
+		} else {
+			t.cache(ctx, ids[i], e.Key, e.Value)
+		}
+	}
+	return err
+}
+
+func (t *Tiered) BatchDelete(ctx context.Context, keys []Key) error {
+	ids := make([]string, len(keys))
+	for i, k := range keys {
+		ids[i] = tieredID(k)
+	}
+	unlock := t.lockStripes(ids)
+	defer unlock()
+
+	err := t.slow.BatchDelete(ctx, keys)
+	for i, k := range keys {
+		t.invalidate(ctx, ids[i], k)
+	}
+	return err
+}
+
+// Close closes both stores.
+func (t *Tiered) Close() error {
+	return errors.Join(t.fast.Close(), t.slow.Close())
+}
+
+// tieredID identifies key unambiguously, whatever the separator.
+func tieredID(key Key) string {
+	var b strings.Builder
+	for _, seg := range key {
+		b.WriteString(strconv.Itoa(len(seg)))
+		b.WriteByte(':')
+		b.WriteString(seg)
+	}
+	return b.String()
+}
+
+func (t *Tiered) stripe(id string) *tieredStripe {
+	return &t.stripes[t.stripeIndex(id)]
+}
+
+func (t *Tiered) stripeIndex(id string) int {
+	return int(maphash.String(t.seed, id) % tieredStripes)
+}
+
+// lockStripes locks the stripes of ids in index order, bumping their
+// generation, and returns the function unlocking them.
+func (t *Tiered) lockStripes(ids []string) func() {
+	idx := make([]int, len(ids))
+	for i, id := range ids {
+		idx[i] = t.stripeIndex(id)
+	}
+	slices.Sort(idx)
+	idx = slices.Compact(idx)
+	for _, i := range idx {
+		t.stripes[i].mu.Lock()
+		t.stripes[i].gen++
+	}
+	return func() {
+		for _, i := range idx {
+			t.stripes[i].mu.Unlock()
+		}
+	}
+}
+
+// touch reports whether id is cached and unexpired, marking it as recently
+// used. An expired key is evicted.
+func (t *Tiered) touch(ctx context.Context, id string) bool {
+	t.mu.Lock()
+	el, ok := t.entries[id]
+	if !ok {
+		t.mu.Unlock()
+		return false
+	}
+	e := el.Value.(*tieredEntry)
+	if e.expires.IsZero() || time.Now().Before(e.expires) {
+		t.lru.MoveToFront(el)
+		t.mu.Unlock()
+		return true
+	}
+	t.lru.Remove(el)
+	delete(t.entries, id)
+	t.mu.Unlock()
+
+	t.evictions.Add(1)
+	t.fast.Delete(ctx, e.key)
+	return false
+}
+
+// cache stores value in the fast store and evicts the least recently used
+// keys beyond MaxEntries. The stripe of id must be locked.
+func (t *Tiered) cache(ctx context.Context, id string, key Key, value []byte) {
+	if err := t.fast.Set(ctx, key, value); err != nil {
+		t.invalidate(ctx, id, key)
+		return
+	}
+
+	var expires time.Time
+	if t.opts.TTL > 0 {
+		expires = time.Now().Add(t.opts.TTL)
+	}
+	var evicted []Key
+	t.mu.Lock()
+	if el, ok := t.entries[id]; ok {
+		el.Value.(*tieredEntry).expires = expires
+		t.lru.MoveToFront(el)
+	} else {
+		t.entries[id] = t.lru.PushFront(&tieredEntry{id: id, key: slices.Clone(key), expires: expires})
+	}
+	for t.opts.MaxEntries > 0 && t.lru.Len() > t.opts.MaxEntries {
+		e := t.lru.Remove(t.lru.Back()).(*tieredEntry)
+		delete(t.entries, e.id)
+		evicted = append(evicted, e.key)
+	}
+	t.mu.Unlock()
+
+	if len(evicted) > 0 {
+		t.evictions.Add(int64(len(evicted)))
+		// The stripes of evicted keys are not locked: a racing cache of
+		// one may be deleted here, and its next Get misses.
+		t.fast.BatchDelete(ctx, evicted)
+	}
+}
+
+// invalidate removes key from the fast store. The stripe of id must be
+// locked.
+func (t *Tiered) invalidate(ctx context.Context, id string, key Key) {
+	t.mu.Lock()
+	if el, ok := t.entries[id]; ok {
+		t.lru.Remove(el)
+		delete(t.entries, id)
+	}
+	t.mu.Unlock()
+	t.fast.Delete(ctx, key)
+}
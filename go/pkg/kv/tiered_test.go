@@ -0,0 +1,147 @@
+package kv_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/kv"
+)
+
+// failingStore fails every write with errWrite.
+type failingStore struct{ kv.Store }
+
+var errWrite = errors.New("write failed")
+
+func (failingStore) Set(context.Context, kv.Key, []byte) error  { return errWrite }
+func (failingStore) BatchSet(context.Context, []kv.Entry) error { return errWrite }
+
+func TestTieredReadThrough(t *testing.T) {
+	ctx := context.Background()
+	fast, slow := kv.NewMemory(nil), kv.NewMemory(nil)
+	s := kv.NewTiered(fast, slow, nil)
+	defer s.Close()
+
+	key := kv.Key{"recall", "hot"}
+	slow.Set(ctx, key, []byte("v1"))
+
+	for range 3 {
+		got, err := s.Get(ctx, key)
+		if err != nil || string(got) != "v1" {
+			t.Fatalf("Get = %q, %v", got, err)
+		}
+	}
+	if st := s.Stats(); st.Hits != 2 || st.Misses != 1 || st.Entries != 1 {
+		t.Errorf("stats = %+v, want 2 hits, 1 miss, 1 entry", st)
+	}
+	if got, _ := fast.Get(ctx, key); string(got) != "v1" {
+		t.Errorf("fast = %q, want cached v1", got)
+	}
+
+	if _, err := s.Get(ctx, kv.Key{"missing"}); !errors.Is(err, kv.ErrNotFound) {
+		t.Errorf("Get missing: err = %v", err)
+	}
+}
+
+func TestTieredWriteThrough(t *testing.T) {
+	ctx := context.Background()
+	fast, slow := kv.NewMemory(nil), kv.NewMemory(nil)
+	s := kv.NewTiered(fast, slow, nil)
+	defer s.Close()
+
+	key := kv.Key{"config", "a"}
+	s.Set(ctx, key, []byte("v1"))
+	s.Get(ctx, key)
+	s.Set(ctx, key, []byte("v2"))
+	for _, store := range []kv.Store{s, fast, slow} {
+		if got, _ := store.Get(ctx, key); string(got) != "v2" {
+			t.Errorf("after Set: %T = %q, want v2", store, got)
+		}
+	}
+
+	s.BatchSet(ctx, []kv.Entry{{Key: key, Value: []byte("v3")}, {Key: kv.Key{"config", "b"}, Value: []byte("b")}})
+	if got, _ := s.Get(ctx, key); string(got) != "v3" {
+		t.Errorf("after BatchSet = %q, want v3", got)
+	}
+
+	s.Delete(ctx, key)
+	if _, err := fast.Get(ctx, key); !errors.Is(err, kv.ErrNotFound) {
+		t.Errorf("fast after Delete: err = %v, want invalidated", err)
+	}
+	if _, err := s.Get(ctx, key); !errors.Is(err, kv.ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v", err)
+	}
+
+	s.BatchDelete(ctx, []kv.Key{{"config", "b"}})
+	if _, err := s.Get(ctx, kv.Key{"config", "b"}); !errors.Is(err, kv.ErrNotFound) {
+		t.Errorf("Get after BatchDelete: err = %v", err)
+	}
+
+	var n int
+	for _, err := range s.List(ctx, kv.Key{"config"}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 0 {
+		t.Errorf("List = %d entries, want 0", n)
+	}
+}
+
+func TestTieredFailedWriteInvalidates(t *testing.T) {
+	ctx := context.Background()
+	fast, slow := kv.NewMemory(nil), kv.NewMemory(nil)
+	key := kv.Key{"k"}
+	slow.Set(ctx, key, []byte("old"))
+
+	s := kv.NewTiered(fast, failingStore{slow}, nil)
+	s.Get(ctx, key)
+	if err := s.Set(ctx, key, []byte("new")); !errors.Is(err, errWrite) {
+		t.Fatalf("Set: err = %v", err)
+	}
+	if _, err := fast.Get(ctx, key); !errors.Is(err, kv.ErrNotFound) {
+		t.Errorf("fast after failed Set: err = %v, want invalidated", err)
+	}
+	if got, _ := s.Get(ctx, key); string(got) != "old" {
+		t.Errorf("Get after failed Set = %q, want old", got)
+	}
+}
+
+func TestTieredEviction(t *testing.T) {
+	ctx := context.Background()
+	fast, slow := kv.NewMemory(nil), kv.NewMemory(nil)
+	s := kv.NewTiered(fast, slow, &kv.TieredOptions{MaxEntries: 2})
+
+	for _, k := range []string{"a", "b", "c"} {
+		s.Set(ctx, kv.Key{k}, []byte(k))
+	}
+	if _, err := fast.Get(ctx, kv.Key{"a"}); !errors.Is(err, kv.ErrNotFound) {
+		t.Errorf("least recently used key not evicted: err = %v", err)
+	}
+	if st := s.Stats(); st.Entries != 2 || st.Evictions != 1 {
+		t.Errorf("stats = %+v, want 2 entries, 1 eviction", st)
+	}
+	if got, _ := s.Get(ctx, kv.Key{"a"}); string(got) != "a" {
+		t.Errorf("evicted key = %q, want read from slow", got)
+	}
+}
+
+func TestTieredTTL(t *testing.T) {
+	ctx := context.Background()
+	fast, slow := kv.NewMemory(nil), kv.NewMemory(nil)
+	s := kv.NewTiered(fast, slow, &kv.TieredOptions{TTL: 20 * time.Millisecond})
+
+	key := kv.Key{"k"}
+	s.Set(ctx, key, []byte("v1"))
+	// A write bypassing the Tiered store.
+	slow.Set(ctx, key, []byte("v2"))
+	if got, _ := s.Get(ctx, key); string(got) != "v1" {
+		t.Errorf("before TTL = %q, want cached v1", got)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if got, _ := s.Get(ctx, key); string(got) != "v2" {
+		t.Errorf("after TTL = %q, want v2", got)
+	}
+}
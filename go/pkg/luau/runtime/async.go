@@ -0,0 +1,103 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/luau"
+)
+
+// AsyncFunc is a host function that does its work off the Luau thread, such
+// as an HTTP call or a tool invocation. args are the script's arguments
+// converted to Go values (see luaToGo); the returned values are converted
+// back for the script.
+//
+// ctx is derived from the runtime context and carries the function's
+// timeout, if any; fn should return when it is done.
+type AsyncFunc func(ctx context.Context, args []any) ([]any, error)
+
+// AsyncOption configures an async host function.
+type AsyncOption func(*asyncFuncConfig)
+
+type asyncFuncConfig struct {
+	timeout time.Duration
+}
+
+// WithAsyncTimeout limits each call of the function to d. A call that runs
+// out of time returns nil and the context error to the script.
+func WithAsyncTimeout(d time.Duration) AsyncOption {
+	return func(c *asyncFuncConfig) {
+		c.timeout = d
+	}
+}
+
+// RegisterAsyncFunc registers fn as the global function name.
+//
+// When a script running under Run calls the function, fn is started in a
+// goroutine and the calling coroutine yields, so the event loop keeps
+// serving other coroutines and promises until fn returns. The coroutine is
+// then resumed with fn's results, or with nil and the error message if fn
+// failed, the same convention as promise:await(). Scripts call it like any
+// other function:
+//
+//	local body, err = fetch("https://example.com")
+//
+// Where the caller cannot yield (RunSync, metamethods), the call blocks
+// until fn returns.
+func (rt *Runtime) RegisterAsyncFunc(name string, fn AsyncFunc, opts ...AsyncOption) error {
+	var cfg asyncFuncConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return rt.state.RegisterFunc(name, rt.asyncFunc(name, fn, cfg))
+}
+
+// asyncFunc wraps fn as a GoFunc that yields until fn returns.
+func (rt *Runtime) asyncFunc(name string, fn AsyncFunc, cfg asyncFuncConfig) luau.GoFunc {
+	return func(state *luau.State) int {
+		nargs := state.GetTop()
+		args := make([]any, nargs)
+		for i := range nargs {
+			args[i] = luaToGo(state, i+1)
+		}
+
+		promise := rt.promises.newPromise()
+		ctx, cancel := rt.asyncContext(cfg)
+		go func() {
+			defer cancel()
+			defer func() {
+				if r := recover(); r != nil {
+					promise.Reject(fmt.Errorf("%s: panic: %v", name, r))
+				}
+			}()
+			values, err := fn(ctx, args)
+			if err != nil {
+				promise.Reject(err)
+				return
+			}
+			promise.Resolve(values...)
+		}()
+
+		if rt.currentThread == nil || !state.IsYieldable() {
+			result := <-promise.ResultChan()
+			rt.promises.removePromise(promise.id)
+			return rt.pushPromiseResult(state, result)
+		}
+
+		// Resumed by processCompletedOp with the results on the stack.
+		rt.registerPendingPromise(promise)
+		return state.Yield(0)
+	}
+}
+
+func (rt *Runtime) asyncContext(cfg asyncFuncConfig) (context.Context, context.CancelFunc) {
+	ctx := rt.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if cfg.timeout > 0 {
+		return context.WithTimeout(ctx, cfg.timeout)
+	}
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,119 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/luau"
+)
+
+func newAsyncTestRuntime(t *testing.T) *Runtime {
+	t.Helper()
+	state, err := luau.New()
+	if err != nil {
+		t.Fatalf("luau.New failed: %v", err)
+	}
+	t.Cleanup(state.Close)
+	state.OpenLibs()
+
+	rt := New(state, nil)
+	if err := rt.RegisterAll(); err != nil {
+		t.Fatalf("RegisterAll failed: %v", err)
+	}
+	return rt
+}
+
+func TestRegisterAsyncFunc_Result(t *testing.T) {
+	rt := newAsyncTestRuntime(t)
+	err := rt.RegisterAsyncFunc("add", func(ctx context.Context, args []any) ([]any, error) {
+		time.Sleep(10 * time.Millisecond)
+		return []any{args[0].(float64) + args[1].(float64)}, nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterAsyncFunc failed: %v", err)
+	}
+
+	err = rt.Run(`
+		local sum, err = add(2, 3)
+		assert(err == nil, err)
+		assert(sum == 5, "sum = " .. tostring(sum))
+	`, "test")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+}
+
+func TestRegisterAsyncFunc_Error(t *testing.T) {
+	rt := newAsyncTestRuntime(t)
+	rt.RegisterAsyncFunc("fail", func(ctx context.Context, args []any) ([]any, error) {
+		return nil, errors.New("boom")
+	})
+
+	err := rt.Run(`
+		local v, err = fail()
+		assert(v == nil)
+		assert(err == "boom", "err = " .. tostring(err))
+	`, "test")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+}
+
+func TestRegisterAsyncFunc_Timeout(t *testing.T) {
+	rt := newAsyncTestRuntime(t)
+	rt.RegisterAsyncFunc("slow", func(ctx context.Context, args []any) ([]any, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+			return []any{"late"}, nil
+		}
+	}, WithAsyncTimeout(20*time.Millisecond))
+
+	start := time.Now()
+	err := rt.Run(`
+		local v, err = slow()
+		assert(v == nil)
+		assert(string.find(err, "deadline"), "err = " .. tostring(err))
+	`, "test")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("timeout not enforced: took %v", elapsed)
+	}
+}
+
+func TestRegisterAsyncFunc_Panic(t *testing.T) {
+	rt := newAsyncTestRuntime(t)
+	rt.RegisterAsyncFunc("bad", func(ctx context.Context, args []any) ([]any, error) {
+		panic("oops")
+	})
+
+	err := rt.Run(`
+		local _, err = bad()
+		assert(err ~= nil)
+		error(err)
+	`, "test")
+	if err == nil || !strings.Contains(err.Error(), "bad: panic: oops") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestRegisterAsyncFunc_RunSync(t *testing.T) {
+	rt := newAsyncTestRuntime(t)
+	rt.RegisterAsyncFunc("echo", func(ctx context.Context, args []any) ([]any, error) {
+		return args, nil
+	})
+
+	err := rt.RunSync(`
+		local v = echo("hi")
+		assert(v == "hi")
+	`, "test")
+	if err != nil {
+		t.Fatalf("RunSync failed: %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"sync"
+	"time"
 
 	"github.com/haivivi/giztoy/go/pkg/genx"
 	"github.com/haivivi/giztoy/go/pkg/luau"
@@ -174,7 +175,9 @@ func (s *genxTransformerBiStream) Send(ctx context.Context, chunk *MessageChunk)
 		case []byte:
 			genxChunk.Part = &genx.Blob{MIMEType: "audio/pcm", Data: p}
 		case map[string]any:
-			if data, ok := p["data"].([]byte); ok {
+			if part, ok := mediaPartFromMap(p); ok {
+				genxChunk.Part = part
+			} else if data, ok := p["data"].([]byte); ok {
 				mimeType, _ := p["mime_type"].(string)
 				if mimeType == "" {
 					mimeType = "audio/pcm"
@@ -197,6 +200,45 @@ func (s *genxTransformerBiStream) Send(ctx context.Context, chunk *MessageChunk)
 	return s.input.Push(genxChunk)
 }
 
+// mediaPartFromMap converts a part table of type "image" or "video_frame"
+// back to a genx.ImagePart or genx.VideoFramePart.
+func mediaPartFromMap(p map[string]any) (genx.Part, bool) {
+	mimeType, _ := p["mime_type"].(string)
+	data, _ := p["data"].([]byte)
+	switch p["type"] {
+	case "image":
+		url, _ := p["url"].(string)
+		return &genx.ImagePart{
+			MIMEType: mimeType,
+			Data:     data,
+			URL:      url,
+			Width:    intFromAny(p["width"]),
+			Height:   intFromAny(p["height"]),
+		}, true
+	case "video_frame":
+		return &genx.VideoFramePart{
+			MIMEType:  mimeType,
+			Data:      data,
+			Width:     intFromAny(p["width"]),
+			Height:    intFromAny(p["height"]),
+			Timestamp: time.Duration(intFromAny(p["timestamp_ms"])) * time.Millisecond,
+		}, true
+	}
+	return nil, false
+}
+
+func intFromAny(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
 func (s *genxTransformerBiStream) CloseSend() error {
 	return s.input.Close()
 }
@@ -227,6 +269,28 @@ func (s *genxTransformerBiStream) Recv(ctx context.Context) (*MessageChunk, erro
 					"data":      p.Data,
 				}
 			}
+		case *genx.ImagePart:
+			if p != nil {
+				rtChunk.Part = map[string]any{
+					"type":      "image",
+					"mime_type": p.MIMEType,
+					"data":      p.Data,
+					"url":       p.URL,
+					"width":     p.Width,
+					"height":    p.Height,
+				}
+			}
+		case *genx.VideoFramePart:
+			if p != nil {
+				rtChunk.Part = map[string]any{
+					"type":         "video_frame",
+					"mime_type":    p.MIMEType,
+					"data":         p.Data,
+					"width":        p.Width,
+					"height":       p.Height,
+					"timestamp_ms": p.Timestamp.Milliseconds(),
+				}
+			}
 		}
 	}
 	if chunk.Ctrl != nil {
@@ -91,10 +91,11 @@ func (m *Memory) CompactBucket(ctx context.Context, bucket recall.Bucket) error
 	}
 
 	// Run the compactor.
-	result, err := m.compressor.CompactSegments(ctx, summaries)
+	result, err := m.compressor.CompactSegments(m.TrackUsage(ctx, UsageCompressor), summaries)
 	if err != nil {
 		return fmt.Errorf("compact segments: %w", err)
 	}
+	m.stats.compactions.Add(1)
 
 	// Determine target bucket from time span.
 	firstTS := toCompact[0].Timestamp
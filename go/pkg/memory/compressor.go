@@ -45,6 +45,15 @@ type LLMCompressorConfig struct {
 	// label (e.g., "person:小明") → attribute map.
 	Profiles map[string]map[string]any
 
+	// SegmentorPrompt customizes the segmentor's instructions and adds
+	// few-shot examples, e.g. for the granularity of segment summaries.
+	// Optional. See also [LLMCompressor.WithPrompts].
+	SegmentorPrompt *segmentors.Prompt
+
+	// ProfilerPrompt customizes the profiler's instructions and adds
+	// few-shot examples. Optional.
+	ProfilerPrompt *segmentors.Prompt
+
 	// SegmentorMux overrides segmentors.DefaultMux. Optional.
 	SegmentorMux *segmentors.Mux
 
@@ -64,6 +73,24 @@ func NewLLMCompressor(cfg LLMCompressorConfig) (*LLMCompressor, error) {
 	}, nil
 }
 
+// WithPrompts returns a copy of c with the segmentor and profiler prompts
+// replaced, to tune the extraction style of one persona without another
+// configuration:
+//
+//	mem, err := host.Open("lele", memory.WithCompressor(c.WithPrompts(seg, prof)))
+//
+// A nil prompt keeps the prompt of c.
+func (c *LLMCompressor) WithPrompts(segmentor, profiler *segmentors.Prompt) *LLMCompressor {
+	cp := *c
+	if segmentor != nil {
+		cp.cfg.SegmentorPrompt = segmentor
+	}
+	if profiler != nil {
+		cp.cfg.ProfilerPrompt = profiler
+	}
+	return &cp
+}
+
 // CompressMessages compresses conversation messages into memory segments
 // and an updated summary by calling the segmentor LLM.
 func (c *LLMCompressor) CompressMessages(ctx context.Context, messages []Message) (*CompressResult, error) {
@@ -92,6 +119,7 @@ func (c *LLMCompressor) CompactSegments(ctx context.Context, summaries []string)
 	input := segmentors.Input{
 		Messages: summaries,
 		Schema:   c.cfg.Schema,
+		Prompt:   c.cfg.SegmentorPrompt,
 	}
 
 	var result *segmentors.Result
@@ -147,6 +175,7 @@ func (c *LLMCompressor) runSegmentor(ctx context.Context, messages []Message) (*
 	input := segmentors.Input{
 		Messages: messagesToStrings(messages),
 		Schema:   c.cfg.Schema,
+		Prompt:   c.cfg.SegmentorPrompt,
 	}
 
 	if c.segMux != nil {
@@ -162,6 +191,7 @@ func (c *LLMCompressor) runProfiler(ctx context.Context, messages []Message, seg
 		Extracted: segResult,
 		Schema:    c.cfg.Schema,
 		Profiles:  c.cfg.Profiles,
+		Prompt:    c.cfg.ProfilerPrompt,
 	}
 
 	if c.profMux != nil {
@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/recall"
+)
+
+// DedupPolicy controls near-duplicate detection in [Memory.StoreSegment].
+//
+// Conversations with children repeat themselves: the same request
+// ("今天想听恐龙故事") produces a nearly identical segment every day, and
+// those copies crowd everything else out of recall. With dedup enabled, a
+// new segment that is similar to a recent one in the same bucket is merged
+// into it instead of being stored: keywords and labels are unioned, the
+// repeat count ([recall.Segment.Repeats]) is incremented, and the timestamp
+// moves to now, so the memory ranks as recent.
+//
+// The zero value disables dedup.
+type DedupPolicy struct {
+	// KeywordThreshold is the minimum Jaccard similarity of the keyword sets
+	// (case-insensitive) for two segments to be duplicates, in (0,1].
+	// Zero disables the keyword signal.
+	KeywordThreshold float64
+
+	// VectorThreshold is the minimum cosine similarity of the summary
+	// embeddings for two segments to be duplicates, in (0,1]. It requires
+	// an embedder and vector index. Zero disables the vector signal.
+	VectorThreshold float64
+
+	// Window limits candidates to segments stored within Window before the
+	// new one. Default 7 days.
+	Window time.Duration
+}
+
+// DefaultDedupPolicy returns a dedup policy that merges segments sharing
+// most of their keywords, or with nearly identical summaries.
+func DefaultDedupPolicy() DedupPolicy {
+	return DedupPolicy{
+		KeywordThreshold: 0.8,
+		VectorThreshold:  0.95,
+		Window:           7 * 24 * time.Hour,
+	}
+}
+
+func (p DedupPolicy) enabled() bool {
+	return p.KeywordThreshold > 0 || p.VectorThreshold > 0
+}
+
+func (p DedupPolicy) window() time.Duration {
+	if p.Window <= 0 {
+		return 7 * 24 * time.Hour
+	}
+	return p.Window
+}
+
+// findDuplicate returns the most similar recent segment of bucket that seg
+// duplicates, or nil.
+func (m *Memory) findDuplicate(ctx context.Context, seg recall.Segment) (*recall.Segment, error) {
+	p := m.dedup
+	after := seg.Timestamp - int64(p.window())
+
+	candidate := func(c *recall.Segment) bool {
+		return c.Bucket == seg.Bucket && c.Timestamp >= after && labelsCompatible(c.Labels, seg.Labels)
+	}
+
+	var best *recall.Segment
+	bestScore := 0.0
+
+	if p.KeywordThreshold > 0 {
+		segments, err := m.index.BucketSegments(ctx, seg.Bucket)
+		if err != nil {
+			return nil, err
+		}
+		for i := range segments {
+			c := &segments[i]
+			if !candidate(c) {
+				continue
+			}
+			score := keywordJaccard(c.Keywords, seg.Keywords)
+			if c.Summary == seg.Summary {
+				score = 1
+			}
+			if score >= p.KeywordThreshold && score > bestScore {
+				best, bestScore = c, score
+			}
+		}
+	}
+
+	if p.VectorThreshold > 0 {
+		similar, err := m.index.SimilarSegments(ctx, seg.Summary, 5)
+		if err != nil {
+			return nil, err
+		}
+		for i := range similar {
+			c := &similar[i].Segment
+			score := similar[i].Score
+			if candidate(c) && score >= p.VectorThreshold && score > bestScore {
+				best, bestScore = c, score
+			}
+		}
+	}
+	return best, nil
+}
+
+// mergeDuplicate folds seg into its duplicate dup and stores the result
+// under dup's ID.
+func (m *Memory) mergeDuplicate(ctx context.Context, dup *recall.Segment, seg recall.Segment) error {
+	merged := *dup
+	merged.Keywords = unionFold(dup.Keywords, seg.Keywords)
+	merged.Labels = union(dup.Labels, seg.Labels)
+	merged.Timestamp = seg.Timestamp
+	merged.Repeats++
+	return m.index.StoreSegment(ctx, merged)
+}
+
+// labelsCompatible reports whether two segments may be about the same
+// subject: either has no labels, or they share one. This keeps the same
+// request from two children apart.
+func labelsCompatible(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for _, l := range a {
+		if slices.Contains(b, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// keywordJaccard returns the Jaccard similarity of two keyword sets,
+// compared case-insensitively. Two empty sets have similarity 0.
+func keywordJaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(a))
+	for _, k := range a {
+		set[strings.ToLower(k)] = false
+	}
+	inter, n := 0, len(set)
+	for _, k := range b {
+		k = strings.ToLower(k)
+		seen, ok := set[k]
+		switch {
+		case !ok:
+			set[k] = true
+			n++
+		case !seen:
+			set[k] = true
+			inter++
+		}
+	}
+	return float64(inter) / float64(n)
+}
+
+// union returns a followed by the elements of b not in a.
+func union(a, b []string) []string {
+	out := slices.Clone(a)
+	for _, s := range b {
+		if !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// unionFold is union with case-insensitive comparison.
+func unionFold(a, b []string) []string {
+	out := slices.Clone(a)
+	for _, s := range b {
+		if !slices.ContainsFunc(out, func(o string) bool { return strings.EqualFold(o, s) }) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
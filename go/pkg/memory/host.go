@@ -47,6 +47,10 @@ type HostConfig struct {
 	// Set both MaxChars and MaxMessages to 0 to disable auto-compression.
 	CompressPolicy CompressPolicy
 
+	// Dedup controls near-duplicate detection when segments are stored.
+	// The zero value disables it; see [DefaultDedupPolicy].
+	Dedup DedupPolicy
+
 	// Separator is the KV key separator byte. It must match the Store's
 	// configured separator. Labels (entity labels, segment labels) must not
 	// contain this character.
@@ -108,6 +112,7 @@ type OpenOption func(*openConfig)
 type openConfig struct {
 	compressor     Compressor
 	compressPolicy *CompressPolicy
+	dedup          *DedupPolicy
 	embedder       embed.Embedder
 }
 
@@ -121,6 +126,11 @@ func WithCompressPolicy(p CompressPolicy) OpenOption {
 	return func(o *openConfig) { o.compressPolicy = &p }
 }
 
+// WithDedupPolicy overrides the host-level [DedupPolicy] for this persona.
+func WithDedupPolicy(p DedupPolicy) OpenOption {
+	return func(o *openConfig) { o.dedup = &p }
+}
+
 // WithEmbedder overrides the host-level [embed.Embedder] for this persona.
 // The embedder's Dimension must match the host's configured embedder (if any)
 // to ensure vector compatibility; Open returns an error on mismatch.
@@ -137,7 +147,8 @@ func WithEmbedder(e embed.Embedder) OpenOption {
 // "cat_girl", "robot_boy"). It is used as the KV key prefix.
 //
 // Options override host-level defaults for this persona only. Use
-// [WithCompressor], [WithCompressPolicy], or [WithEmbedder] to customize.
+// [WithCompressor], [WithCompressPolicy], [WithDedupPolicy], or
+// [WithEmbedder] to customize.
 func (h *Host) Open(id string, opts ...OpenOption) (*Memory, error) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -182,6 +193,12 @@ func (h *Host) Open(id string, opts ...OpenOption) (*Memory, error) {
 		policy = DefaultCompressPolicy()
 	}
 
+	// Resolve dedup policy.
+	dedup := h.cfg.Dedup
+	if oc.dedup != nil {
+		dedup = *oc.dedup
+	}
+
 	idx := recall.NewIndex(recall.IndexConfig{
 		Store:     h.cfg.Store,
 		Embedder:  emb,
@@ -190,7 +207,7 @@ func (h *Host) Open(id string, opts ...OpenOption) (*Memory, error) {
 		Separator: h.cfg.Separator,
 	})
 
-	m := newMemory(id, h.cfg.Store, idx, compressor, policy)
+	m := newMemory(id, h.cfg.Store, idx, compressor, policy, dedup)
 	h.memories[id] = m
 	return m, nil
 }
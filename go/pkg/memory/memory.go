@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/haivivi/giztoy/go/pkg/graph"
 	"github.com/haivivi/giztoy/go/pkg/kv"
@@ -18,6 +19,7 @@ import (
 //   - Conversations: active dialogue sessions
 //   - Recall: combined graph expansion + segment search for LLM context
 //   - Compaction: automatic cascading compression (1h → 1d → 1w → ... → lt)
+//   - Rollup: daily/weekly summaries kept alongside their sources
 //
 // Each Memory is fully isolated — it owns a [recall.Index] scoped under
 // a unique KV prefix "mem:{id}".
@@ -27,15 +29,18 @@ type Memory struct {
 	index      *recall.Index
 	compressor Compressor     // default compressor from Host, may be nil
 	policy     CompressPolicy // auto-compression thresholds
+	dedup      DedupPolicy    // near-duplicate segment merging
+	stats      stats          // usage counters, see [Memory.Stats]
 }
 
-func newMemory(id string, store kv.Store, index *recall.Index, compressor Compressor, policy CompressPolicy) *Memory {
+func newMemory(id string, store kv.Store, index *recall.Index, compressor Compressor, policy CompressPolicy, dedup DedupPolicy) *Memory {
 	return &Memory{
 		id:         id,
 		store:      store,
 		index:      index,
 		compressor: compressor,
 		policy:     policy,
+		dedup:      dedup,
 	}
 }
 
@@ -68,9 +73,13 @@ func (m *Memory) OpenConversation(convID string, labels []string) *Conversation
 //
 // This is the primary method for building LLM context. The flow:
 //  1. Expand seed labels through the graph (BFS, up to Hops).
-//  2. Search segments across all buckets using expanded labels + query text.
+//  2. Search segments across all buckets using expanded labels + query text,
+//     within the time filters of the query.
 //  3. Fetch entity attributes for all expanded labels.
 func (m *Memory) Recall(ctx context.Context, q RecallQuery) (*RecallResult, error) {
+	start := time.Now()
+	defer func() { m.stats.observeRecall(time.Since(start)) }()
+
 	hops := q.Hops
 	if hops <= 0 {
 		hops = 2
@@ -80,16 +89,26 @@ func (m *Memory) Recall(ctx context.Context, q RecallQuery) (*RecallResult, erro
 		limit = 10
 	}
 
+	ranges, err := q.timeRanges()
+	if err != nil {
+		return nil, err
+	}
+
 	// Step 1+2: delegate to recall.Index.Search for graph expansion + segment search.
 	rResult, err := m.index.Search(ctx, recall.Query{
 		Labels: q.Labels,
 		Text:   q.Text,
 		Hops:   hops,
 		Limit:  limit,
+		Ranges: ranges,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("memory: recall search: %w", err)
 	}
+	if ranges != nil && len(ranges) == 0 {
+		// The time filters exclude all time
+		rResult.Segments = nil
+	}
 
 	// Step 3: fetch entity attributes for expanded labels.
 	var entities []EntityInfo
@@ -117,6 +136,8 @@ func (m *Memory) Recall(ctx context.Context, q RecallQuery) (*RecallResult, erro
 			Labels:    ss.Segment.Labels,
 			Timestamp: ss.Segment.Timestamp,
 			Score:     ss.Score,
+			Sources:   ss.Segment.Sources,
+			Repeats:   ss.Segment.Repeats,
 		}
 	}
 
@@ -129,6 +150,9 @@ func (m *Memory) Recall(ctx context.Context, q RecallQuery) (*RecallResult, erro
 // StoreSegment stores a new segment in this persona's recall index.
 // It generates an ID and timestamp, sets the bucket, and indexes the
 // segment for search.
+//
+// If a [DedupPolicy] is configured and the segment duplicates a recent one
+// in the same bucket, it is merged into that segment instead.
 func (m *Memory) StoreSegment(ctx context.Context, input SegmentInput, bucket recall.Bucket) error {
 	ts := nowNano()
 	seg := recall.Segment{
@@ -139,7 +163,28 @@ func (m *Memory) StoreSegment(ctx context.Context, input SegmentInput, bucket re
 		Timestamp: ts,
 		Bucket:    bucket,
 	}
-	return m.index.StoreSegment(ctx, seg)
+	if seg.Bucket == "" {
+		seg.Bucket = recall.Bucket1H
+	}
+
+	if m.dedup.enabled() {
+		dup, err := m.findDuplicate(ctx, seg)
+		if err != nil {
+			return fmt.Errorf("memory: find duplicate: %w", err)
+		}
+		if dup != nil {
+			if err := m.mergeDuplicate(ctx, dup, seg); err != nil {
+				return err
+			}
+			m.stats.segmentsMerged.Add(1)
+			return nil
+		}
+	}
+	if err := m.index.StoreSegment(ctx, seg); err != nil {
+		return err
+	}
+	m.stats.segmentsStored.Add(1)
+	return nil
 }
 
 // ApplyEntityUpdate applies entity and relation updates from a compression
@@ -190,6 +235,8 @@ func (m *Memory) ApplyEntityUpdate(ctx context.Context, update *EntityUpdate) er
 // If compressor is non-nil, it is used for this call. Otherwise, the
 // host's default compressor is used (see [HostConfig.Compressor]).
 // If both are nil, Compress returns an error.
+//
+// Runs, failures and the LLM tokens consumed are counted in [Memory.Stats].
 func (m *Memory) Compress(ctx context.Context, conv *Conversation, compressor Compressor) error {
 	if compressor == nil {
 		compressor = m.compressor
@@ -198,6 +245,15 @@ func (m *Memory) Compress(ctx context.Context, conv *Conversation, compressor Co
 		return fmt.Errorf("memory: compressor is nil (no per-call compressor and no default set in HostConfig)")
 	}
 
+	m.stats.compressions.Add(1)
+	if err := m.compress(m.TrackUsage(ctx, UsageCompressor), conv, compressor); err != nil {
+		m.stats.compressionErrors.Add(1)
+		return err
+	}
+	return nil
+}
+
+func (m *Memory) compress(ctx context.Context, conv *Conversation, compressor Compressor) error {
 	msgs, err := conv.All(ctx)
 	if err != nil {
 		return fmt.Errorf("memory: read messages: %w", err)
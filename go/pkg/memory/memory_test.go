@@ -2,10 +2,16 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/genx/segmentors"
 	"github.com/haivivi/giztoy/go/pkg/graph"
 	"github.com/haivivi/giztoy/go/pkg/kv"
 	"github.com/haivivi/giztoy/go/pkg/recall"
@@ -1051,6 +1057,116 @@ func TestRecallWithoutLabelsTextOnly(t *testing.T) {
 	}
 }
 
+func TestRecallPeriodRanges(t *testing.T) {
+	cst := time.FixedZone("CST", 8*3600)
+	at := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, cst) }
+	now := time.Date(2025, 6, 18, 15, 0, 0, 0, cst) // a Wednesday
+
+	tests := []struct {
+		period     RecallPeriod
+		start, end time.Time
+	}{
+		{RecallToday, at(2025, 6, 18), at(2025, 6, 19)},
+		{RecallYesterday, at(2025, 6, 17), at(2025, 6, 18)},
+		{RecallThisWeek, at(2025, 6, 16), at(2025, 6, 23)},
+		{RecallLastWeek, at(2025, 6, 9), at(2025, 6, 16)},
+		{RecallThisMonth, at(2025, 6, 1), at(2025, 7, 1)},
+		{RecallLastMonth, at(2025, 5, 1), at(2025, 6, 1)},
+		{RecallThisYear, at(2025, 1, 1), at(2026, 1, 1)},
+		{RecallLastYear, at(2024, 1, 1), at(2025, 1, 1)},
+	}
+	for _, tt := range tests {
+		ranges, err := tt.period.Ranges(now)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.period, err)
+		}
+		if len(ranges) != 1 || !ranges[0].Start.Equal(tt.start) || !ranges[0].End.Equal(tt.end) {
+			t.Errorf("%s = %v, want [%v, %v)", tt.period, ranges, tt.start, tt.end)
+		}
+	}
+
+	ranges, err := RecallOnThisDay.Ranges(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 100 || !ranges[0].Start.Equal(at(2024, 6, 18)) || !ranges[1].End.Equal(at(2023, 6, 19)) {
+		t.Errorf("on this day = %d ranges from %v", len(ranges), ranges[0])
+	}
+	ranges, _ = RecallOnThisDay.Ranges(time.Date(2024, 2, 29, 9, 0, 0, 0, cst))
+	if len(ranges) != 25 || !ranges[0].Start.Equal(at(2020, 2, 29)) {
+		t.Errorf("on February 29th = %d ranges from %v, want the 25 leap years", len(ranges), ranges[0])
+	}
+
+	if _, err := RecallPeriod("next_week").Ranges(now); err == nil {
+		t.Error("unknown period: want error")
+	}
+}
+
+func TestRecallTimeFilters(t *testing.T) {
+	h := newTestHostNoVec(t)
+	defer h.Close()
+	m := mustOpen(t, h, "time_filters")
+	ctx := context.Background()
+
+	cst := time.FixedZone("CST", 8*3600)
+	now := time.Date(2025, 6, 18, 15, 0, 0, 0, cst)
+	origNow := nowNano
+	defer func() { nowNano = origNow }()
+	for _, s := range []struct {
+		summary string
+		at      time.Time
+		bucket  recall.Bucket
+	}{
+		{"last monday", time.Date(2025, 6, 9, 20, 0, 0, 0, cst), recall.Bucket1H},
+		{"last week", time.Date(2025, 6, 15, 23, 0, 0, 0, cst), recall.Bucket1W},
+		{"yesterday", time.Date(2025, 6, 17, 10, 0, 0, 0, cst), recall.Bucket1H},
+		{"a year ago", time.Date(2024, 6, 18, 19, 0, 0, 0, cst), recall.Bucket1H},
+	} {
+		nowNano = func() int64 { return s.at.UnixNano() }
+		if err := m.StoreSegment(ctx, SegmentInput{Summary: s.summary}, s.bucket); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recalled := func(q RecallQuery) []string {
+		t.Helper()
+		q.Now = now
+		res, err := m.Recall(ctx, q)
+		if err != nil {
+			t.Fatalf("Recall(%+v): %v", q, err)
+		}
+		var summaries []string
+		for _, seg := range res.Segments {
+			summaries = append(summaries, seg.Summary)
+		}
+		return summaries
+	}
+
+	if got := recalled(RecallQuery{Period: RecallLastWeek}); !slices.Equal(got, []string{"last week", "last monday"}) {
+		t.Errorf("last week = %v", got)
+	}
+	if got := recalled(RecallQuery{Period: RecallYesterday}); !slices.Equal(got, []string{"yesterday"}) {
+		t.Errorf("yesterday = %v", got)
+	}
+	if got := recalled(RecallQuery{Period: RecallOnThisDay}); !slices.Equal(got, []string{"a year ago"}) {
+		t.Errorf("on this day = %v", got)
+	}
+	if got := recalled(RecallQuery{Since: time.Date(2025, 6, 16, 0, 0, 0, 0, cst)}); !slices.Equal(got, []string{"yesterday"}) {
+		t.Errorf("since monday = %v", got)
+	}
+	if got := recalled(RecallQuery{Until: time.Date(2025, 1, 1, 0, 0, 0, 0, cst)}); !slices.Equal(got, []string{"a year ago"}) {
+		t.Errorf("until 2025 = %v", got)
+	}
+	// Since narrows the period to nothing
+	if got := recalled(RecallQuery{Period: RecallLastWeek, Since: time.Date(2025, 6, 17, 0, 0, 0, 0, cst)}); len(got) != 0 {
+		t.Errorf("last week since yesterday = %v, want none", got)
+	}
+
+	if _, err := m.Recall(ctx, RecallQuery{Since: now, Until: now}); err == nil {
+		t.Error("empty since/until: want error")
+	}
+}
+
 func TestRecallWithLabelsGraphExpansion(t *testing.T) {
 	h := newTestHost(t)
 	defer h.Close()
@@ -2080,3 +2196,511 @@ func BenchmarkRecall(b *testing.B) {
 }
 
 // BenchmarkLongTermSetSummary was removed — LongTerm replaced by bucket compaction.
+
+// ---------------------------------------------------------------------------
+// Rollup
+// ---------------------------------------------------------------------------
+
+// storeAt stores a segment with an explicit timestamp in the given bucket.
+func storeAt(t *testing.T, m *Memory, id string, ts time.Time, bucket recall.Bucket) {
+	t.Helper()
+	if err := m.Index().StoreSegment(context.Background(), recall.Segment{
+		ID:        id,
+		Summary:   "event " + id,
+		Labels:    []string{"person:" + id},
+		Timestamp: ts.UnixNano(),
+		Bucket:    bucket,
+	}); err != nil {
+		t.Fatalf("StoreSegment %s: %v", id, err)
+	}
+}
+
+func TestRollupDailyAndWeekly(t *testing.T) {
+	h := newTestHostWithCompactor(t, CompressPolicy{})
+	defer h.Close()
+	m := mustOpen(t, h, "test")
+	ctx := context.Background()
+
+	// Monday 2026-03-02 .. Tuesday 2026-03-03, plus one segment on the
+	// current day (Monday 2026-03-09) which must not be rolled up yet.
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	storeAt(t, m, "a", monday.Add(9*time.Hour), recall.Bucket1H)
+	storeAt(t, m, "b", monday.Add(15*time.Hour), recall.Bucket1H)
+	storeAt(t, m, "c", monday.Add(33*time.Hour), recall.Bucket1H)
+	storeAt(t, m, "d", monday.Add(34*time.Hour), recall.Bucket1H)
+	storeAt(t, m, "e", monday.Add(7*24*time.Hour+time.Hour), recall.Bucket1H)
+
+	origNow := nowNano
+	nowNano = func() int64 { return monday.Add(7*24*time.Hour + 2*time.Hour).UnixNano() }
+	defer func() { nowNano = origNow }()
+
+	if err := m.Rollup(ctx, RollupConfig{}); err != nil {
+		t.Fatalf("Rollup: %v", err)
+	}
+
+	day1, err := m.Index().GetSegment(ctx, "test-daily-20260302")
+	if err != nil || day1 == nil {
+		t.Fatalf("daily rollup missing: %v", err)
+	}
+	if day1.Bucket != recall.Bucket1D {
+		t.Errorf("daily bucket = %s, want 1d", day1.Bucket)
+	}
+	if !slices.Equal(day1.Sources, []string{"a", "b"}) {
+		t.Errorf("daily sources = %v, want [a b]", day1.Sources)
+	}
+	if !slices.Contains(day1.Labels, "person:a") || !slices.Contains(day1.Labels, "person:b") {
+		t.Errorf("daily labels = %v, want source labels", day1.Labels)
+	}
+	if day2, _ := m.Index().GetSegment(ctx, "test-daily-20260303"); day2 == nil {
+		t.Error("second daily rollup missing")
+	}
+	if today, _ := m.Index().GetSegment(ctx, "test-daily-20260309"); today != nil {
+		t.Error("in-progress day should not be rolled up")
+	}
+
+	week, err := m.Index().GetSegment(ctx, "test-weekly-2026W10")
+	if err != nil || week == nil {
+		t.Fatalf("weekly rollup missing: %v", err)
+	}
+	if week.Bucket != recall.Bucket1W {
+		t.Errorf("weekly bucket = %s, want 1w", week.Bucket)
+	}
+	if !slices.Equal(week.Sources, []string{"test-daily-20260302", "test-daily-20260303"}) {
+		t.Errorf("weekly sources = %v", week.Sources)
+	}
+
+	// Sources are kept alongside the rollups.
+	count1h, _, _ := m.Index().BucketStats(ctx, recall.Bucket1H)
+	if count1h != 5 {
+		t.Errorf("1h count = %d, want 5 (sources kept)", count1h)
+	}
+
+	// A second pass is a no-op.
+	if err := m.Rollup(ctx, RollupConfig{}); err != nil {
+		t.Fatalf("second Rollup: %v", err)
+	}
+	count1d, _, _ := m.Index().BucketStats(ctx, recall.Bucket1D)
+	count1w, _, _ := m.Index().BucketStats(ctx, recall.Bucket1W)
+	if count1d != 2 || count1w != 1 {
+		t.Errorf("after second pass: 1d=%d 1w=%d, want 2 and 1", count1d, count1w)
+	}
+}
+
+func TestRollupPruneAndMinSegments(t *testing.T) {
+	h := newTestHostWithCompactor(t, CompressPolicy{})
+	defer h.Close()
+	m := mustOpen(t, h, "test")
+	ctx := context.Background()
+
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	storeAt(t, m, "a", day.Add(1*time.Hour), recall.Bucket1H)
+	storeAt(t, m, "b", day.Add(2*time.Hour), recall.Bucket1H)
+	storeAt(t, m, "lonely", day.Add(25*time.Hour), recall.Bucket1H)
+
+	origNow := nowNano
+	nowNano = func() int64 { return day.Add(5 * 24 * time.Hour).UnixNano() }
+	defer func() { nowNano = origNow }()
+
+	if err := m.Rollup(ctx, RollupConfig{PruneAfter: 24 * time.Hour}); err != nil {
+		t.Fatalf("Rollup: %v", err)
+	}
+
+	for _, id := range []string{"a", "b"} {
+		if seg, _ := m.Index().GetSegment(ctx, id); seg != nil {
+			t.Errorf("source %s should be pruned", id)
+		}
+	}
+	// A period below MinSegments is neither rolled up nor pruned.
+	if seg, _ := m.Index().GetSegment(ctx, "lonely"); seg == nil {
+		t.Error("lonely segment should be kept")
+	}
+	if seg, _ := m.Index().GetSegment(ctx, "test-daily-20260303"); seg != nil {
+		t.Error("period below MinSegments should not be rolled up")
+	}
+	if seg, _ := m.Index().GetSegment(ctx, "test-daily-20260302"); seg == nil {
+		t.Error("daily rollup missing")
+	}
+}
+
+func TestHostRollupNoCompressor(t *testing.T) {
+	h := newTestHostNoVec(t)
+	defer h.Close()
+	mustOpen(t, h, "test")
+	if err := h.Rollup(context.Background(), RollupConfig{}); err != nil {
+		t.Errorf("Rollup without compressor = %v, want nil", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Dedup
+// ---------------------------------------------------------------------------
+
+func TestStoreSegmentDedupKeywords(t *testing.T) {
+	h := newTestHostNoVec(t)
+	defer h.Close()
+	m := mustOpen(t, h, "test", WithDedupPolicy(DedupPolicy{KeywordThreshold: 0.6}))
+	ctx := context.Background()
+
+	store := func(summary string, keywords []string, labels ...string) {
+		t.Helper()
+		if err := m.StoreSegment(ctx, SegmentInput{
+			Summary: summary, Keywords: keywords, Labels: labels,
+		}, recall.Bucket1H); err != nil {
+			t.Fatalf("StoreSegment: %v", err)
+		}
+	}
+
+	store("小明今天想听恐龙故事", []string{"恐龙", "故事"}, "person:小明")
+	first, err := m.Index().RecentSegments(ctx, 1)
+	if err != nil {
+		t.Fatalf("RecentSegments: %v", err)
+	}
+	store("小明又想听恐龙的故事", []string{"恐龙", "故事", "霸王龙"}, "person:小明")
+	store("小明想听恐龙故事", []string{"恐龙", "故事"}, "person:小明")
+	store("小红也想听恐龙故事", []string{"恐龙", "故事"}, "person:小红") // different child
+	store("小明学做饭", []string{"做饭"}, "person:小明")
+
+	segs, err := m.Index().BucketSegments(ctx, recall.Bucket1H)
+	if err != nil {
+		t.Fatalf("BucketSegments: %v", err)
+	}
+	if len(segs) != 3 {
+		t.Fatalf("got %d segments, want 3: %+v", len(segs), segs)
+	}
+
+	var merged *recall.Segment
+	for i := range segs {
+		if slices.Contains(segs[i].Labels, "person:小明") && slices.Contains(segs[i].Keywords, "恐龙") {
+			merged = &segs[i]
+		}
+	}
+	if merged == nil {
+		t.Fatal("merged segment missing")
+	}
+	if merged.Repeats != 2 {
+		t.Errorf("Repeats = %d, want 2", merged.Repeats)
+	}
+	if merged.Summary != "小明今天想听恐龙故事" {
+		t.Errorf("Summary = %q, want the first summary", merged.Summary)
+	}
+	if !slices.Contains(merged.Keywords, "霸王龙") {
+		t.Errorf("Keywords = %v, want merged keywords", merged.Keywords)
+	}
+	if merged.ID != first[0].ID || merged.Timestamp <= first[0].Timestamp {
+		t.Errorf("merged segment %s@%d, want %s moved past %d", merged.ID, merged.Timestamp, first[0].ID, first[0].Timestamp)
+	}
+
+	// The merged segment is still found by its ID and recalled with its count.
+	result, err := m.Recall(ctx, RecallQuery{Labels: []string{"person:小明"}, Text: "恐龙"})
+	if err != nil {
+		t.Fatalf("Recall: %v", err)
+	}
+	if len(result.Segments) == 0 || result.Segments[0].Repeats != 2 {
+		t.Errorf("Recall = %+v, want merged segment first with Repeats 2", result.Segments)
+	}
+}
+
+func TestStoreSegmentDedupWindowAndBucket(t *testing.T) {
+	h := newTestHostNoVec(t)
+	defer h.Close()
+	m := mustOpen(t, h, "test", WithDedupPolicy(DedupPolicy{KeywordThreshold: 0.5, Window: time.Hour}))
+	ctx := context.Background()
+
+	origNow := nowNano
+	defer func() { nowNano = origNow }()
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	nowNano = func() int64 { return now.UnixNano() }
+
+	input := SegmentInput{Summary: "聊恐龙", Keywords: []string{"恐龙"}}
+	if err := m.StoreSegment(ctx, input, recall.Bucket1H); err != nil {
+		t.Fatalf("StoreSegment: %v", err)
+	}
+	// Same content in another bucket is not a duplicate.
+	now = now.Add(time.Minute)
+	if err := m.StoreSegment(ctx, input, recall.Bucket1D); err != nil {
+		t.Fatalf("StoreSegment: %v", err)
+	}
+	// Outside the window is not a duplicate.
+	now = now.Add(2 * time.Hour)
+	if err := m.StoreSegment(ctx, input, recall.Bucket1H); err != nil {
+		t.Fatalf("StoreSegment: %v", err)
+	}
+
+	for _, b := range []recall.Bucket{recall.Bucket1H, recall.Bucket1D} {
+		segs, err := m.Index().BucketSegments(ctx, b)
+		if err != nil {
+			t.Fatalf("BucketSegments: %v", err)
+		}
+		want := map[recall.Bucket]int{recall.Bucket1H: 2, recall.Bucket1D: 1}[b]
+		if len(segs) != want {
+			t.Errorf("bucket %s: got %d segments, want %d", b, len(segs), want)
+		}
+	}
+}
+
+func TestStoreSegmentDedupVector(t *testing.T) {
+	store := kv.NewMemory(&kv.Options{Separator: testSep})
+	h, err := NewHost(context.Background(), HostConfig{
+		Store:     store,
+		Vec:       vecstore.NewMemory(),
+		Embedder:  newMockEmbedder(),
+		Separator: testSep,
+		Dedup:     DedupPolicy{VectorThreshold: 0.99},
+	})
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	defer h.Close()
+	m := mustOpen(t, h, "test")
+	ctx := context.Background()
+
+	// Different wording, no keywords: only embeddings can tell.
+	for _, s := range []string{"和小明聊了恐龙，他最喜欢霸王龙", "和小明聊了恐龙", "学到了太空知识"} {
+		if err := m.StoreSegment(ctx, SegmentInput{Summary: s}, recall.Bucket1H); err != nil {
+			t.Fatalf("StoreSegment: %v", err)
+		}
+	}
+
+	segs, err := m.Index().BucketSegments(ctx, recall.Bucket1H)
+	if err != nil {
+		t.Fatalf("BucketSegments: %v", err)
+	}
+	if len(segs) != 2 {
+		t.Fatalf("got %d segments, want 2", len(segs))
+	}
+	for _, seg := range segs {
+		if seg.Summary == "和小明聊了恐龙，他最喜欢霸王龙" && seg.Repeats != 1 {
+			t.Errorf("Repeats = %d, want 1", seg.Repeats)
+		}
+	}
+}
+
+func TestStoreSegmentDedupDisabled(t *testing.T) {
+	h := newTestHostNoVec(t)
+	defer h.Close()
+	m := mustOpen(t, h, "test")
+	ctx := context.Background()
+
+	for range 3 {
+		if err := m.StoreSegment(ctx, SegmentInput{Summary: "same", Keywords: []string{"same"}}, recall.Bucket1H); err != nil {
+			t.Fatalf("StoreSegment: %v", err)
+		}
+	}
+	count, _, err := m.Index().BucketStats(ctx, recall.Bucket1H)
+	if err != nil {
+		t.Fatalf("BucketStats: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("count = %d, want 3 without a dedup policy", count)
+	}
+}
+
+func TestKeywordJaccard(t *testing.T) {
+	tests := []struct {
+		a, b []string
+		want float64
+	}{
+		{nil, []string{"a"}, 0},
+		{[]string{"a", "b"}, []string{"B", "a"}, 1},
+		{[]string{"a", "b"}, []string{"b", "c"}, 1.0 / 3},
+		{[]string{"a", "a"}, []string{"a", "b", "b"}, 0.5},
+	}
+	for _, tt := range tests {
+		if got := keywordJaccard(tt.a, tt.b); got != tt.want {
+			t.Errorf("keywordJaccard(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Stats
+// ---------------------------------------------------------------------------
+
+// usageCompressor reports usage for every call, like an LLM compressor.
+type usageCompressor struct {
+	mockCompressor
+	err error
+}
+
+func (uc *usageCompressor) CompressMessages(ctx context.Context, msgs []Message) (*CompressResult, error) {
+	genx.ReportUsage(ctx, "mock", genx.Usage{PromptTokenCount: 100, GeneratedTokenCount: 20})
+	if uc.err != nil {
+		return nil, uc.err
+	}
+	return uc.mockCompressor.CompressMessages(ctx, msgs)
+}
+
+func TestMemoryStats(t *testing.T) {
+	h := newTestHostNoVec(t)
+	defer h.Close()
+	m := mustOpen(t, h, "test", WithDedupPolicy(DedupPolicy{KeywordThreshold: 0.5, Window: time.Hour}))
+	ctx := context.Background()
+
+	for _, summary := range []string{"dinosaurs", "dinosaurs", "space"} {
+		if err := m.StoreSegment(ctx, SegmentInput{Summary: summary, Keywords: []string{summary}}, recall.Bucket1H); err != nil {
+			t.Fatalf("StoreSegment: %v", err)
+		}
+	}
+
+	conv := m.OpenConversation("s1", nil)
+	if err := conv.Append(ctx, Message{Role: RoleUser, Content: "hello"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := m.Compress(ctx, conv, &usageCompressor{}); err != nil {
+		t.Fatalf("Compress: %v", err)
+	}
+	if err := conv.Append(ctx, Message{Role: RoleUser, Content: "again"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := m.Compress(ctx, conv, &usageCompressor{err: errors.New("boom")}); err == nil {
+		t.Fatal("Compress: want error")
+	}
+
+	labelCtx := m.TrackUsage(ctx, UsageLabeler)
+	genx.ReportUsage(labelCtx, "mock", genx.Usage{PromptTokenCount: 7})
+	for range 2 {
+		if _, err := m.Recall(ctx, RecallQuery{Text: "dinosaurs"}); err != nil {
+			t.Fatalf("Recall: %v", err)
+		}
+	}
+
+	st := m.Stats()
+	if st.SegmentsStored != 3 || st.SegmentsMerged != 1 {
+		t.Errorf("stored, merged = %d, %d, want 3, 1", st.SegmentsStored, st.SegmentsMerged)
+	}
+	if st.Compressions != 2 || st.CompressionErrors != 1 {
+		t.Errorf("compressions, errors = %d, %d, want 2, 1", st.Compressions, st.CompressionErrors)
+	}
+	if got := st.Tokens[UsageCompressor]; got.PromptTokenCount != 200 || got.GeneratedTokenCount != 40 {
+		t.Errorf("compressor tokens = %+v", got)
+	}
+	if got := st.Tokens[UsageLabeler]; got.PromptTokenCount != 7 {
+		t.Errorf("labeler tokens = %+v", got)
+	}
+	if st.Recalls != 2 || len(st.RecallLatency.Counts) != len(st.RecallLatency.Bounds)+1 {
+		t.Errorf("recall latency = %+v", st.RecallLatency)
+	}
+
+	other := mustOpen(t, h, "other")
+	if got := other.Stats(); got.SegmentsStored != 0 || got.Recalls != 0 {
+		t.Errorf("other persona stats = %+v, want isolated", got)
+	}
+	if all := h.Stats(); len(all) != 2 || all["test"].SegmentsStored != 3 {
+		t.Errorf("host stats = %v", all)
+	}
+}
+
+func TestCompactStats(t *testing.T) {
+	h := newTestHostWithCompactor(t, CompressPolicy{MaxMessages: 2})
+	defer h.Close()
+	m := mustOpen(t, h, "test")
+	ctx := context.Background()
+
+	for i := range 4 {
+		if err := m.StoreSegment(ctx, SegmentInput{Summary: fmt.Sprintf("seg %d", i)}, recall.Bucket1H); err != nil {
+			t.Fatalf("StoreSegment: %v", err)
+		}
+	}
+	if err := m.CompactBucket(ctx, recall.Bucket1H); err != nil {
+		t.Fatalf("CompactBucket: %v", err)
+	}
+	if got := m.Stats().Compactions; got != 1 {
+		t.Errorf("Compactions = %d, want 1", got)
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	var h LatencyHistogram
+	for _, d := range []time.Duration{time.Millisecond, 5 * time.Millisecond, 30 * time.Millisecond, time.Minute} {
+		h.observe(d)
+	}
+	if h.Count != 4 || h.Sum != time.Minute+36*time.Millisecond {
+		t.Errorf("count, sum = %d, %v", h.Count, h.Sum)
+	}
+	// Bounds are inclusive: 5ms falls in the first bucket.
+	want := make([]int64, len(DefaultLatencyBuckets)+1)
+	want[0], want[3], want[len(want)-1] = 2, 1, 1
+	if !slices.Equal(h.Counts, want) {
+		t.Errorf("Counts = %v, want %v", h.Counts, want)
+	}
+}
+
+func TestHostPrometheus(t *testing.T) {
+	h := newTestHostNoVec(t)
+	defer h.Close()
+	m := mustOpen(t, h, `a"b`)
+	ctx := context.Background()
+
+	if err := m.StoreSegment(ctx, SegmentInput{Summary: "hello"}, recall.Bucket1H); err != nil {
+		t.Fatalf("StoreSegment: %v", err)
+	}
+	if _, err := m.Recall(ctx, RecallQuery{Text: "hello"}); err != nil {
+		t.Fatalf("Recall: %v", err)
+	}
+	genx.ReportUsage(m.TrackUsage(ctx, UsageLabeler), "mock", genx.Usage{PromptTokenCount: 3})
+
+	rec := httptest.NewRecorder()
+	h.PrometheusHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE giztoy_memory_segments_stored_total counter\n",
+		`giztoy_memory_segments_stored_total{persona="a\"b"} 1` + "\n",
+		`giztoy_memory_llm_tokens_total{persona="a\"b",source="labeler",kind="prompt"} 3` + "\n",
+		"# TYPE giztoy_memory_recall_duration_seconds histogram\n",
+		`giztoy_memory_recall_duration_seconds_bucket{persona="a\"b",le="+Inf"} 1` + "\n",
+		`giztoy_memory_recall_duration_seconds_count{persona="a\"b"} 1` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
+
+// promptSegmentor records the prompt of its input.
+type promptSegmentor struct {
+	prompt *segmentors.Prompt
+}
+
+func (s *promptSegmentor) Process(_ context.Context, input segmentors.Input) (*segmentors.Result, error) {
+	s.prompt = input.Prompt
+	return &segmentors.Result{Segment: segmentors.SegmentOutput{Summary: "ok"}}, nil
+}
+
+func (s *promptSegmentor) Model() string { return "mock" }
+
+func TestLLMCompressorWithPrompts(t *testing.T) {
+	seg := &promptSegmentor{}
+	mux := segmentors.NewMux()
+	if err := mux.Handle("seg/mock", seg); err != nil {
+		t.Fatal(err)
+	}
+	base := &segmentors.Prompt{Rules: []string{"base"}}
+	c, err := NewLLMCompressor(LLMCompressorConfig{
+		Segmentor:       "seg/mock",
+		SegmentorMux:    mux,
+		SegmentorPrompt: base,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	persona := &segmentors.Prompt{Rules: []string{"one segment per topic"}}
+	lele := c.WithPrompts(persona, nil)
+
+	ctx := context.Background()
+	msgs := []Message{{Role: RoleUser, Content: "hi"}}
+	if _, err := lele.CompressMessages(ctx, msgs); err != nil {
+		t.Fatal(err)
+	}
+	if seg.prompt != persona {
+		t.Errorf("persona compressor used prompt %+v", seg.prompt)
+	}
+	if _, err := c.CompactSegments(ctx, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if seg.prompt != base {
+		t.Errorf("base compressor used prompt %+v", seg.prompt)
+	}
+}
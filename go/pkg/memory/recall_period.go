@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/recall"
+)
+
+// RecallPeriod is a time period relative to the time of a recall, for
+// questions such as "上周我们聊了什么" (what did we talk about last week).
+type RecallPeriod string
+
+const (
+	RecallToday     RecallPeriod = "today"
+	RecallYesterday RecallPeriod = "yesterday"
+	RecallThisWeek  RecallPeriod = "this_week" // ISO weeks, Monday to Sunday
+	RecallLastWeek  RecallPeriod = "last_week"
+	RecallThisMonth RecallPeriod = "this_month"
+	RecallLastMonth RecallPeriod = "last_month"
+	RecallThisYear  RecallPeriod = "this_year"
+	RecallLastYear  RecallPeriod = "last_year"
+
+	// RecallOnThisDay is today's date in earlier years ("那年今日"), up to
+	// onThisDayYears back. On February 29th, only leap years match.
+	RecallOnThisDay RecallPeriod = "on_this_day"
+)
+
+// onThisDayYears is how many years back RecallOnThisDay looks.
+const onThisDayYears = 100
+
+// Ranges returns the time ranges of the period relative to now, with
+// calendar days in now's location.
+func (p RecallPeriod) Ranges(now time.Time) ([]recall.TimeRange, error) {
+	today := periodStart(RollupDaily, now)
+	week := periodStart(RollupWeekly, now)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	year := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+
+	span := func(start, end time.Time) []recall.TimeRange {
+		return []recall.TimeRange{{Start: start, End: end}}
+	}
+	switch p {
+	case RecallToday:
+		return span(today, today.AddDate(0, 0, 1)), nil
+	case RecallYesterday:
+		return span(today.AddDate(0, 0, -1), today), nil
+	case RecallThisWeek:
+		return span(week, week.AddDate(0, 0, 7)), nil
+	case RecallLastWeek:
+		return span(week.AddDate(0, 0, -7), week), nil
+	case RecallThisMonth:
+		return span(month, month.AddDate(0, 1, 0)), nil
+	case RecallLastMonth:
+		return span(month.AddDate(0, -1, 0), month), nil
+	case RecallThisYear:
+		return span(year, year.AddDate(1, 0, 0)), nil
+	case RecallLastYear:
+		return span(year.AddDate(-1, 0, 0), year), nil
+	case RecallOnThisDay:
+		var ranges []recall.TimeRange
+		for y := 1; y <= onThisDayYears; y++ {
+			day := time.Date(now.Year()-y, now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+			if day.Day() != now.Day() {
+				continue // February 29th in a common year
+			}
+			ranges = append(ranges, recall.TimeRange{Start: day, End: day.AddDate(0, 0, 1)})
+		}
+		return ranges, nil
+	default:
+		return nil, fmt.Errorf("memory: unknown recall period %q", p)
+	}
+}
+
+// timeRanges resolves the time filters of q: its Period, narrowed to
+// [Since, Until). It returns nil if q has no time filter, and an empty
+// non-nil slice if the filters exclude all time.
+func (q *RecallQuery) timeRanges() ([]recall.TimeRange, error) {
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Since.Before(q.Until) {
+		return nil, fmt.Errorf("memory: recall since %v is not before until %v", q.Since, q.Until)
+	}
+	bounds := recall.TimeRange{Start: q.Since, End: q.Until}
+	if q.Period == "" {
+		if bounds == (recall.TimeRange{}) {
+			return nil, nil
+		}
+		return []recall.TimeRange{bounds}, nil
+	}
+
+	now := q.Now
+	if now.IsZero() {
+		now = time.Unix(0, nowNano())
+	}
+	ranges, err := q.Period.Ranges(now)
+	if err != nil {
+		return nil, err
+	}
+	out := []recall.TimeRange{}
+	for _, r := range ranges {
+		if !bounds.Start.IsZero() && r.Start.Before(bounds.Start) {
+			r.Start = bounds.Start
+		}
+		if !bounds.End.IsZero() && r.End.After(bounds.End) {
+			r.End = bounds.End
+		}
+		if r.Start.Before(r.End) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,278 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/recall"
+)
+
+// RollupPeriod is the calendar period covered by a rollup segment.
+type RollupPeriod string
+
+const (
+	// RollupDaily summarizes a calendar day of [recall.Bucket1H] segments
+	// into a single [recall.Bucket1D] segment.
+	RollupDaily RollupPeriod = "daily"
+
+	// RollupWeekly summarizes an ISO week (Monday to Sunday) of
+	// [recall.Bucket1D] segments into a single [recall.Bucket1W] segment.
+	RollupWeekly RollupPeriod = "weekly"
+)
+
+// RollupConfig configures [Memory.Rollup].
+type RollupConfig struct {
+	// Location determines day and week boundaries. Default time.UTC.
+	Location *time.Location
+
+	// MinSegments is the minimum number of source segments a period must
+	// contain to be rolled up. Default 2.
+	MinSegments int
+
+	// PruneAfter deletes source segments once they have been rolled up and
+	// their period ended more than PruneAfter ago. Zero keeps sources
+	// forever (they are still subject to [Memory.Compact]). Periods with
+	// fewer than MinSegments are never pruned.
+	PruneAfter time.Duration
+
+	// OnError is called by [Host.RunRollups] when a rollup pass fails.
+	// Optional. The job keeps running after errors.
+	OnError func(err error)
+}
+
+func (c *RollupConfig) setDefaults() {
+	if c.Location == nil {
+		c.Location = time.UTC
+	}
+	if c.MinSegments <= 0 {
+		c.MinSegments = 2
+	}
+}
+
+// rollupSpec describes one level of the rollup hierarchy.
+type rollupSpec struct {
+	period RollupPeriod
+	source recall.Bucket
+	target recall.Bucket
+}
+
+// rollupLevels lists the hierarchy from finest to coarsest. Daily rollups
+// run first so that a weekly rollup in the same pass sees them.
+var rollupLevels = []rollupSpec{
+	{RollupDaily, recall.Bucket1H, recall.Bucket1D},
+	{RollupWeekly, recall.Bucket1D, recall.Bucket1W},
+}
+
+// Rollup builds hierarchical summaries: every completed calendar day of
+// hourly segments is summarized into a daily segment, and every completed
+// week of daily segments into a weekly segment.
+//
+// Unlike [Memory.Compact], rollup keeps the source segments alongside the
+// summary and records their IDs in the summary's [recall.Segment.Sources]
+// for provenance. Long-horizon questions match the coarse summaries while
+// recent detail remains searchable. Set [RollupConfig.PruneAfter] to drop
+// old sources and bound index growth.
+//
+// Rollup is idempotent: each period is summarized at most once, keyed by a
+// deterministic segment ID. Periods that have not ended yet are skipped.
+// It is intended to be called periodically, e.g. via [Host.RunRollups].
+//
+// If no compressor is configured, Rollup returns nil (no-op).
+func (m *Memory) Rollup(ctx context.Context, cfg RollupConfig) error {
+	if m.compressor == nil {
+		return nil
+	}
+	cfg.setDefaults()
+	now := time.Unix(0, nowNano()).In(cfg.Location)
+
+	for _, lvl := range rollupLevels {
+		if err := m.rollupLevel(ctx, lvl, cfg, now); err != nil {
+			return fmt.Errorf("memory: rollup %s: %w", lvl.period, err)
+		}
+	}
+	return nil
+}
+
+// Rollup runs [Memory.Rollup] on every persona opened on this host.
+// Errors from individual personas are joined; a failing persona does not
+// stop the others.
+func (h *Host) Rollup(ctx context.Context, cfg RollupConfig) error {
+	h.mu.Lock()
+	memories := make([]*Memory, 0, len(h.memories))
+	for _, m := range h.memories {
+		memories = append(memories, m)
+	}
+	h.mu.Unlock()
+
+	var errs []error
+	for _, m := range memories {
+		if err := m.Rollup(ctx, cfg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", m.id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunRollups runs [Host.Rollup] immediately and then every interval until
+// ctx is canceled. It always returns a non-nil error (ctx.Err()).
+func (h *Host) RunRollups(ctx context.Context, interval time.Duration, cfg RollupConfig) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := h.Rollup(ctx, cfg); err != nil && cfg.OnError != nil {
+			cfg.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// rollupGroup is the set of source segments within one period.
+type rollupGroup struct {
+	start, end time.Time
+	segments   []recall.Segment
+}
+
+func (m *Memory) rollupLevel(ctx context.Context, lvl rollupSpec, cfg RollupConfig, now time.Time) error {
+	segments, err := m.index.BucketSegments(ctx, lvl.source)
+	if err != nil {
+		return err
+	}
+
+	// Group by period. Segments are ordered by timestamp, so groups are too.
+	var groups []*rollupGroup
+	for _, seg := range segments {
+		start := periodStart(lvl.period, time.Unix(0, seg.Timestamp).In(cfg.Location))
+		if len(groups) == 0 || !groups[len(groups)-1].start.Equal(start) {
+			groups = append(groups, &rollupGroup{
+				start: start,
+				end:   periodEnd(lvl.period, start),
+			})
+		}
+		g := groups[len(groups)-1]
+		g.segments = append(g.segments, seg)
+	}
+
+	for _, g := range groups {
+		if g.end.After(now) {
+			continue // period still in progress
+		}
+		rolled, err := m.rollupGroup(ctx, lvl, cfg, g)
+		if err != nil {
+			return err
+		}
+		if rolled && cfg.PruneAfter > 0 && now.Sub(g.end) > cfg.PruneAfter {
+			for _, seg := range g.segments {
+				if err := m.index.DeleteSegment(ctx, seg.ID); err != nil {
+					return fmt.Errorf("prune source segment %s: %w", seg.ID, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// rollupGroup summarizes a single completed period unless it has already
+// been summarized or has too few segments. It reports whether a rollup for
+// the period exists afterwards.
+func (m *Memory) rollupGroup(ctx context.Context, lvl rollupSpec, cfg RollupConfig, g *rollupGroup) (bool, error) {
+	if len(g.segments) < cfg.MinSegments {
+		return false, nil
+	}
+	id := rollupID(m.id, lvl.period, g.start)
+	existing, err := m.index.GetSegment(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if existing != nil {
+		return true, nil
+	}
+
+	summaries := make([]string, len(g.segments))
+	sources := make([]string, len(g.segments))
+	var labels []string
+	for i, seg := range g.segments {
+		summaries[i] = seg.Summary
+		sources[i] = seg.ID
+		labels = appendUnique(labels, seg.Labels...)
+	}
+
+	result, err := m.compressor.CompactSegments(ctx, summaries)
+	if err != nil {
+		return false, fmt.Errorf("compact segments: %w", err)
+	}
+	if len(result.Segments) == 0 {
+		return false, errors.New("compressor returned no segments")
+	}
+
+	// A rollup is a single segment per period; merge if the compressor
+	// returned several.
+	var keywords []string
+	summary := result.Summary
+	for _, seg := range result.Segments {
+		keywords = appendUnique(keywords, seg.Keywords...)
+		labels = appendUnique(labels, seg.Labels...)
+	}
+	if summary == "" {
+		summary = result.Segments[0].Summary
+	}
+
+	// The timestamp is the last instant of the period rather than the
+	// latest source timestamp, so its storage key does not collide with a
+	// segment compacted from the same sources.
+	err = m.index.StoreSegment(ctx, recall.Segment{
+		ID:        id,
+		Summary:   summary,
+		Keywords:  keywords,
+		Labels:    labels,
+		Timestamp: g.end.UnixNano() - 1,
+		Bucket:    lvl.target,
+		Sources:   sources,
+	})
+	return err == nil, err
+}
+
+// periodStart returns the start of the period containing t, in t's location.
+func periodStart(p RollupPeriod, t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if p == RollupWeekly {
+		// ISO weeks start on Monday.
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	}
+	return day
+}
+
+// periodEnd returns the start of the period following start.
+func periodEnd(p RollupPeriod, start time.Time) time.Time {
+	if p == RollupWeekly {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// rollupID returns the deterministic segment ID of a rollup.
+// Format: "{mid}-daily-20060102" or "{mid}-weekly-2006W01".
+func rollupID(mid string, p RollupPeriod, start time.Time) string {
+	if p == RollupWeekly {
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%s-%s-%04dW%02d", mid, p, year, week)
+	}
+	return fmt.Sprintf("%s-%s-%s", mid, p, start.Format("20060102"))
+}
+
+// appendUnique appends items not already present in dst.
+func appendUnique(dst []string, items ...string) []string {
+	for _, it := range items {
+		if !slices.Contains(dst, it) {
+			dst = append(dst, it)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,242 @@
+package memory
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/genx"
+	"github.com/haivivi/giztoy/go/pkg/promtext"
+)
+
+// UsageSource identifies what consumed LLM tokens on behalf of a persona.
+type UsageSource string
+
+const (
+	// UsageCompressor counts the tokens of [Memory.Compress] and
+	// compaction, i.e. of the segmentor and profiler calls.
+	UsageCompressor UsageSource = "compressor"
+
+	// UsageLabeler counts the tokens of query-time label selection; see
+	// [Memory.TrackUsage].
+	UsageLabeler UsageSource = "labeler"
+)
+
+// DefaultLatencyBuckets are the upper bounds of [LatencyHistogram] buckets.
+var DefaultLatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// LatencyHistogram is a cumulative histogram of durations.
+type LatencyHistogram struct {
+	// Bounds are the upper bounds of the buckets, ascending.
+	Bounds []time.Duration
+
+	// Counts holds the number of observations per bucket, with one more
+	// entry than Bounds for observations above the last bound. Counts are
+	// not cumulative.
+	Counts []int64
+
+	// Count is the number of observations, Sum their total.
+	Count int64
+	Sum   time.Duration
+}
+
+func (h *LatencyHistogram) observe(d time.Duration) {
+	if h.Counts == nil {
+		h.Bounds = DefaultLatencyBuckets
+		h.Counts = make([]int64, len(h.Bounds)+1)
+	}
+	i, _ := slices.BinarySearch(h.Bounds, d)
+	h.Counts[i]++
+	h.Count++
+	h.Sum += d
+}
+
+func (h LatencyHistogram) clone() LatencyHistogram {
+	h.Counts = slices.Clone(h.Counts)
+	return h
+}
+
+// Stats are the usage counters of a persona's memory since it was opened
+// by the [Host]. They are kept in process and are not persisted.
+type Stats struct {
+	// SegmentsStored is the number of segments stored by
+	// [Memory.StoreSegment], excluding those merged into duplicates.
+	SegmentsStored int64
+
+	// SegmentsMerged is the number of segments merged into a near-duplicate
+	// (see [DedupPolicy]).
+	SegmentsMerged int64
+
+	// Compressions is the number of [Memory.Compress] runs, and
+	// CompressionErrors the number of those that failed.
+	Compressions      int64
+	CompressionErrors int64
+
+	// Compactions is the number of bucket compactions run.
+	Compactions int64
+
+	// Recalls is the number of [Memory.Recall] calls, and RecallLatency
+	// their latency.
+	Recalls       int64
+	RecallLatency LatencyHistogram
+
+	// Tokens are the LLM tokens consumed, by source.
+	Tokens map[UsageSource]genx.Usage
+}
+
+// stats holds the live counters of a Memory.
+type stats struct {
+	segmentsStored    atomic.Int64
+	segmentsMerged    atomic.Int64
+	compressions      atomic.Int64
+	compressionErrors atomic.Int64
+	compactions       atomic.Int64
+
+	mu            sync.Mutex
+	recallLatency LatencyHistogram
+	tokens        map[UsageSource]genx.Usage
+}
+
+func (s *stats) observeRecall(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recallLatency.observe(d)
+}
+
+func (s *stats) addTokens(source UsageSource, usage genx.Usage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tokens == nil {
+		s.tokens = make(map[UsageSource]genx.Usage)
+	}
+	s.tokens[source] = s.tokens[source].Add(usage)
+}
+
+func (s *stats) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := Stats{
+		SegmentsStored:    s.segmentsStored.Load(),
+		SegmentsMerged:    s.segmentsMerged.Load(),
+		Compressions:      s.compressions.Load(),
+		CompressionErrors: s.compressionErrors.Load(),
+		Compactions:       s.compactions.Load(),
+		RecallLatency:     s.recallLatency.clone(),
+		Tokens:            make(map[UsageSource]genx.Usage, len(s.tokens)),
+	}
+	st.Recalls = st.RecallLatency.Count
+	for source, usage := range s.tokens {
+		st.Tokens[source] = usage
+	}
+	return st
+}
+
+// Stats returns a snapshot of the persona's usage counters.
+func (m *Memory) Stats() Stats {
+	return m.stats.snapshot()
+}
+
+// TrackUsage returns a context that counts the tokens of generator calls
+// made with it as consumed by this persona for source. Memory tracks its
+// own compressor calls; use TrackUsage for calls made on the persona's
+// behalf elsewhere, e.g. a labeler selecting labels for [Memory.Recall]:
+//
+//	labels, err := labelers.Process(mem.TrackUsage(ctx, memory.UsageLabeler), pattern, input)
+func (m *Memory) TrackUsage(ctx context.Context, source UsageSource) context.Context {
+	return genx.WithUsageCollector(ctx, genx.UsageCollectorFunc(func(_ context.Context, _ string, usage genx.Usage) {
+		m.stats.addTokens(source, usage)
+	}))
+}
+
+// Stats returns a snapshot of the usage counters of each open persona,
+// by persona ID.
+func (h *Host) Stats() map[string]Stats {
+	h.mu.Lock()
+	memories := make(map[string]*Memory, len(h.memories))
+	for id, m := range h.memories {
+		memories[id] = m
+	}
+	h.mu.Unlock()
+
+	out := make(map[string]Stats, len(memories))
+	for id, m := range memories {
+		out[id] = m.Stats()
+	}
+	return out
+}
+
+// WritePrometheus writes the usage counters of all open personas in the
+// Prometheus text exposition format, labeled by persona.
+func (h *Host) WritePrometheus(w io.Writer) error {
+	all := h.Stats()
+	ids := make([]string, 0, len(all))
+	for id := range all {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	pw := promtext.NewWriter(w)
+	counter := func(name, help string, value func(Stats) int64) {
+		pw.Header(name, help, "counter")
+		for _, id := range ids {
+			pw.Int(name, promtext.Label("persona", id), value(all[id]))
+		}
+	}
+	counter("giztoy_memory_segments_stored_total", "Segments stored.", func(s Stats) int64 { return s.SegmentsStored })
+	counter("giztoy_memory_segments_merged_total", "Segments merged into a near-duplicate.", func(s Stats) int64 { return s.SegmentsMerged })
+	counter("giztoy_memory_compressions_total", "Conversation compressions run.", func(s Stats) int64 { return s.Compressions })
+	counter("giztoy_memory_compression_errors_total", "Conversation compressions failed.", func(s Stats) int64 { return s.CompressionErrors })
+	counter("giztoy_memory_compactions_total", "Bucket compactions run.", func(s Stats) int64 { return s.Compactions })
+
+	pw.Header("giztoy_memory_llm_tokens_total", "LLM tokens consumed.", "counter")
+	for _, id := range ids {
+		sources := make([]string, 0, len(all[id].Tokens))
+		for source := range all[id].Tokens {
+			sources = append(sources, string(source))
+		}
+		slices.Sort(sources)
+		for _, source := range sources {
+			usage := all[id].Tokens[UsageSource(source)]
+			const name = "giztoy_memory_llm_tokens_total"
+			pw.Int(name, promtext.Label("persona", id, "source", source, "kind", "prompt"), usage.PromptTokenCount)
+			pw.Int(name, promtext.Label("persona", id, "source", source, "kind", "cached"), usage.CachedContentTokenCount)
+			pw.Int(name, promtext.Label("persona", id, "source", source, "kind", "generated"), usage.GeneratedTokenCount)
+		}
+	}
+
+	const hist = "giztoy_memory_recall_duration_seconds"
+	pw.Header(hist, "Recall latency.", "histogram")
+	for _, id := range ids {
+		h := all[id].RecallLatency
+		var cum int64
+		for i, bound := range h.Bounds {
+			cum += h.Counts[i]
+			pw.Int(hist+"_bucket", promtext.Label("persona", id, "le", promtext.FormatFloat(bound.Seconds())), cum)
+		}
+		pw.Int(hist+"_bucket", promtext.Label("persona", id, "le", "+Inf"), h.Count)
+		pw.Float(hist+"_sum", promtext.Label("persona", id), h.Sum.Seconds())
+		pw.Int(hist+"_count", promtext.Label("persona", id), h.Count)
+	}
+	return pw.Err()
+}
+
+// PrometheusHandler returns an HTTP handler serving [Host.WritePrometheus],
+// to be mounted at a metrics endpoint scraped by Prometheus.
+func (h *Host) PrometheusHandler() http.Handler {
+	return promtext.Handler(h.WritePrometheus)
+}
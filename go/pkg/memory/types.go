@@ -14,7 +14,12 @@
 //   - Conversations: short-term message storage per device/session.
 //   - Bucket-based compaction: segments at finer granularity are automatically
 //     compacted into coarser buckets (1h → 1d → 1w → 1m → 3m → 6m → 1y → lt).
-//   - Recall: combined graph expansion + segment search for context building.
+//   - Hierarchical rollup: [Memory.Rollup] adds daily and weekly summary
+//     segments alongside their sources, linked by provenance IDs.
+//   - Deduplication: with a [DedupPolicy], near-duplicate segments are
+//     merged on store instead of flooding the index.
+//   - Recall: combined graph expansion + segment search for context building,
+//     optionally within a time range or a [RecallPeriod] ("last week").
 //
 // The package does not embed compression logic. An upper-layer [Compressor]
 // (provided by the agent runtime) drives message compression into segments
@@ -101,6 +106,23 @@ type RecallQuery struct {
 
 	// Limit is the maximum number of segments to return. Default 10.
 	Limit int
+
+	// Since and Until restrict recall to segments covering time in
+	// [Since, Until), resolved against segment timestamps and buckets (see
+	// [recall.Segment.Overlaps]): a weekly summary covers its whole week.
+	// Zero values leave that side unbounded.
+	Since time.Time
+	Until time.Time
+
+	// Period restricts recall to a period relative to Now, such as
+	// [RecallLastWeek] or [RecallOnThisDay], narrowed to Since and Until
+	// if they are set. With no Text or Labels, the segments of the period
+	// are returned newest first.
+	Period RecallPeriod
+
+	// Now is the time Period is relative to, and its location the time
+	// zone of calendar days. Default the current local time.
+	Now time.Time
 }
 
 // RecallResult holds the combined recall output.
@@ -128,6 +150,14 @@ type ScoredSegment struct {
 	Labels    []string `json:"labels,omitempty"`
 	Timestamp int64    `json:"ts"`
 	Score     float64  `json:"score"`
+
+	// Sources holds the IDs of the finer segments this segment summarizes
+	// (see [Memory.Rollup]). Empty for ordinary segments.
+	Sources []string `json:"sources,omitempty"`
+
+	// Repeats counts the near-duplicate segments merged into this one (see
+	// [DedupPolicy]). Zero for segments stored once.
+	Repeats int `json:"repeats,omitempty"`
 }
 
 // ---------------------------------------------------------------------------
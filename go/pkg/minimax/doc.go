@@ -37,6 +37,19 @@
 //	    }
 //	}
 //
+// # Long Text
+//
+// SynthesizeLong splits text longer than a single request allows at sentence
+// boundaries, synthesizes the chunks with the same voice settings and
+// returns the joined audio as one reader:
+//
+//	r, err := client.Speech.SynthesizeLong(ctx, req, &minimax.LongSpeechOptions{Parallel: 3})
+//	if err != nil {
+//	    return err
+//	}
+//	defer r.Close()
+//	io.Copy(w, r)
+//
 // # Async Tasks
 //
 // Long-running operations return Task objects that can be polled:
@@ -0,0 +1,245 @@
+package minimax
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultLongSpeechChunkRunes is the default maximum chunk length of
+// SynthesizeLong. It is well below the 10,000 character API limit so each
+// request stays fast.
+const DefaultLongSpeechChunkRunes = 1000
+
+// LongSpeechOptions configures SynthesizeLong.
+type LongSpeechOptions struct {
+	// MaxChunkRunes is the maximum length of one request's text
+	// (default DefaultLongSpeechChunkRunes, at most 10,000).
+	MaxChunkRunes int
+
+	// Parallel is the number of chunks synthesized concurrently. With 1
+	// (default) chunks are streamed one after another, so audio starts
+	// quickly; with more, whole chunks are synthesized ahead and written
+	// in order, which is faster overall for very long texts.
+	Parallel int
+}
+
+func (o *LongSpeechOptions) setDefaults() {
+	if o.MaxChunkRunes <= 0 {
+		o.MaxChunkRunes = DefaultLongSpeechChunkRunes
+	}
+	o.MaxChunkRunes = min(o.MaxChunkRunes, 10000)
+	if o.Parallel <= 0 {
+		o.Parallel = 1
+	}
+}
+
+// SynthesizeLong synthesizes text of any length and returns the joined
+// audio as a single stream.
+//
+// The text is split at sentence boundaries into chunks of at most
+// MaxChunkRunes; every chunk is synthesized with the same model, voice,
+// audio and pronunciation settings as req, so voice and prosody stay
+// consistent across chunks. Because chunk audio is concatenated, use mp3
+// or pcm (the default); wav and flac are rejected, and output_format must
+// be hex.
+//
+// The caller must close the returned reader; closing it early cancels the
+// remaining requests.
+//
+// Example:
+//
+//	r, err := client.Speech.SynthesizeLong(ctx, req, &minimax.LongSpeechOptions{Parallel: 3})
+//	if err != nil {
+//	    return err
+//	}
+//	defer r.Close()
+//	_, err = io.Copy(f, r)
+func (s *SpeechService) SynthesizeLong(ctx context.Context, req *SpeechRequest, opts *LongSpeechOptions) (io.ReadCloser, error) {
+	var o LongSpeechOptions
+	if opts != nil {
+		o = *opts
+	}
+	o.setDefaults()
+
+	if req.AudioSetting != nil {
+		switch req.AudioSetting.Format {
+		case AudioFormatWAV, AudioFormatFLAC:
+			return nil, fmt.Errorf("minimax: SynthesizeLong cannot join %s audio; use mp3 or pcm", req.AudioSetting.Format)
+		}
+	}
+	if req.OutputFormat == OutputFormatURL {
+		return nil, fmt.Errorf("minimax: SynthesizeLong requires hex output format")
+	}
+
+	chunks := SplitSpeechText(req.Text, o.MaxChunkRunes)
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+
+	go func() {
+		defer cancel()
+		var err error
+		if o.Parallel == 1 {
+			err = s.streamChunks(ctx, req, chunks, pw)
+		} else {
+			err = s.parallelChunks(ctx, req, chunks, o.Parallel, pw)
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return &longSpeechReader{PipeReader: pr, cancel: cancel}, nil
+}
+
+// longSpeechReader cancels synthesis when closed.
+type longSpeechReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (r *longSpeechReader) Close() error {
+	r.cancel()
+	return r.PipeReader.Close()
+}
+
+// chunkRequest returns a copy of req for one chunk of text.
+func chunkRequest(req *SpeechRequest, text string) *SpeechRequest {
+	cp := *req
+	cp.Text = text
+	return &cp
+}
+
+// streamChunks synthesizes chunks one after another, writing audio as it
+// streams in.
+func (s *SpeechService) streamChunks(ctx context.Context, req *SpeechRequest, chunks []string, w io.Writer) error {
+	for i, text := range chunks {
+		for chunk, err := range s.SynthesizeStream(ctx, chunkRequest(req, text)) {
+			if err != nil {
+				return fmt.Errorf("minimax: long speech chunk %d/%d: %w", i+1, len(chunks), err)
+			}
+			if len(chunk.Audio) == 0 {
+				continue
+			}
+			if _, err := w.Write(chunk.Audio); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parallelChunks synthesizes up to n chunks concurrently and writes their
+// audio in order.
+func (s *SpeechService) parallelChunks(ctx context.Context, req *SpeechRequest, chunks []string, n int, w io.Writer) error {
+	type result struct {
+		audio []byte
+		err   error
+	}
+	results := make([]chan result, len(chunks))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+
+	sem := make(chan struct{}, n)
+	go func() {
+		for i, text := range chunks {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func() {
+				resp, err := s.Synthesize(ctx, chunkRequest(req, text))
+				if err != nil {
+					results[i] <- result{err: err}
+					return
+				}
+				results[i] <- result{audio: resp.Audio}
+			}()
+		}
+	}()
+
+	for i := range chunks {
+		var res result
+		select {
+		case res = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		<-sem
+		if res.err != nil {
+			return fmt.Errorf("minimax: long speech chunk %d/%d: %w", i+1, len(chunks), res.err)
+		}
+		if _, err := w.Write(res.audio); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SplitSpeechText splits text into chunks of at most maxRunes runes for
+// speech synthesis. It breaks after sentence terminators (Chinese and
+// Western) where possible, then after clause punctuation or spaces, and
+// only cuts inside a word as a last resort. Whitespace-only chunks are
+// dropped.
+func SplitSpeechText(text string, maxRunes int) []string {
+	if maxRunes <= 0 {
+		maxRunes = DefaultLongSpeechChunkRunes
+	}
+	var chunks []string
+	add := func(s string) {
+		if strings.TrimSpace(s) != "" {
+			chunks = append(chunks, s)
+		}
+	}
+
+	for utf8.RuneCountInString(text) > maxRunes {
+		// Byte offset of the rune limit.
+		limit := 0
+		for range maxRunes {
+			_, size := utf8.DecodeRuneInString(text[limit:])
+			limit += size
+		}
+		cut := lastBreak(text[:limit], isSentenceEnd)
+		if cut == 0 {
+			cut = lastBreak(text[:limit], isClauseBreak)
+		}
+		if cut == 0 {
+			cut = limit
+		}
+		add(text[:cut])
+		text = text[cut:]
+	}
+	add(text)
+	return chunks
+}
+
+// lastBreak returns the byte offset after the last rune in s matching
+// isBreak, or 0.
+func lastBreak(s string, isBreak func(rune) bool) int {
+	for i := len(s); i > 0; {
+		r, size := utf8.DecodeLastRuneInString(s[:i])
+		if isBreak(r) {
+			return i
+		}
+		i -= size
+	}
+	return 0
+}
+
+func isSentenceEnd(r rune) bool {
+	switch r {
+	case '.', '!', '?', '\n', '。', '！', '？', '…':
+		return true
+	}
+	return false
+}
+
+func isClauseBreak(r rune) bool {
+	switch r {
+	case ',', ';', ':', ' ', '\t', '，', '；', '：', '、':
+		return true
+	}
+	return false
+}
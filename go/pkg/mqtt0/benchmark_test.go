@@ -419,6 +419,34 @@ func BenchmarkTrieMatching(b *testing.B) {
 	})
 }
 
+// BenchmarkTrieManyTopics measures matching with one subscription pair per
+// device, as in large fleets.
+func BenchmarkTrieManyTopics(b *testing.B) {
+	for _, devices := range []int{1_000, 100_000, 1_000_000} {
+		trie := NewTrie[int]()
+		for i := range devices {
+			trie.Insert(fmt.Sprintf("device/gear-%07d/state", i), i)
+			trie.Insert(fmt.Sprintf("device/gear-%07d/events/#", i), i)
+		}
+		trie.Insert("device/+/state", -1)
+
+		b.Run(fmt.Sprintf("devices=%d", devices), func(b *testing.B) {
+			topic := fmt.Sprintf("device/gear-%07d/state", devices/2)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				trie.Get(topic)
+			}
+		})
+		b.Run(fmt.Sprintf("devices=%d/single", devices), func(b *testing.B) {
+			topic := fmt.Sprintf("device/gear-%07d/events/click", devices/2)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				trie.Get(topic)
+			}
+		})
+	}
+}
+
 // =============================================================================
 // High Throughput Stress Test
 // =============================================================================
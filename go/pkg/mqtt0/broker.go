@@ -9,16 +9,20 @@ import (
 	"log/slog"
 	"net"
 	"net/netip"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/kv"
 )
 
 // Broker is a QoS 0 MQTT broker.
 type Broker struct {
 	// Authenticator provides authentication and ACL.
 	// If nil, all connections are allowed (AllowAll).
+	// A CertAuthenticator also sees the verified TLS client certificates.
 	Authenticator Authenticator
 
 	// Handler is called for each message received by the broker.
@@ -30,6 +34,16 @@ type Broker struct {
 	// OnDisconnect is called when a client disconnects.
 	OnDisconnect func(clientID string)
 
+	// OnSessionTakeover is called when a new connection takes over the
+	// session of a connected client with the same ClientID (only under
+	// DuplicateTakeover). It runs before the new client's OnConnect.
+	OnSessionTakeover func(clientID string)
+
+	// DuplicateClientIDPolicy controls what happens when a client connects
+	// with a ClientID that is already connected.
+	// Default: DuplicateTakeover.
+	DuplicateClientIDPolicy DuplicateClientIDPolicy
+
 	// MaxPacketSize is the maximum packet size.
 	// Default is MaxPacketSize (1MB).
 	MaxPacketSize int
@@ -50,6 +64,16 @@ type Broker struct {
 	// Default: 100. Range: 1+ (0 is treated as default).
 	MaxSubscriptionsPerClient int
 
+	// Store, if set, persists session state so that it survives broker
+	// restarts: retained messages, and the subscriptions of clients that
+	// connect without a clean session. If nil, session state is kept in
+	// memory only.
+	Store kv.Store
+
+	// StorePrefix is the key prefix of the state persisted in Store.
+	// Default: "mqtt0".
+	StorePrefix kv.Key
+
 	// internal state
 	mu                  sync.Mutex
 	running             atomic.Bool
@@ -57,6 +81,7 @@ type Broker struct {
 	clients             map[string]*clientHandle
 	clientSubscriptions map[string][]string // track subscriptions per client for cleanup
 	sharedTrie          *Trie[*sharedEntry] // shared subscriptions trie for O(topic_length) lookup
+	retained            map[string]*Message // retained messages by topic
 }
 
 // sharedGroup manages subscribers for a shared subscription.
@@ -108,6 +133,13 @@ func (g *sharedGroup) removeByHandle(h *clientHandle) {
 	}
 }
 
+// members returns a copy of the group's subscribers.
+func (g *sharedGroup) members() []*clientHandle {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return slices.Clone(g.subscribers)
+}
+
 func (g *sharedGroup) isEmpty() bool {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
@@ -127,8 +159,21 @@ func (g *sharedGroup) nextSubscriber() *clientHandle {
 
 // clientHandle represents a connected client.
 type clientHandle struct {
+	clientID   string
+	msgCh      chan *Message
+	persistent bool        // session outlives the connection (not clean)
+	takenOver  atomic.Bool // set before msgCh is closed by a session takeover
+}
+
+// originalIDAuth forwards ACL checks using the ClientID the client presented,
+// for clients that were assigned a suffixed ClientID.
+type originalIDAuth struct {
+	Authenticator
 	clientID string
-	msgCh    chan *Message
+}
+
+func (a originalIDAuth) ACL(_, topic string, write bool) bool {
+	return a.Authenticator.ACL(a.clientID, topic, write)
 }
 
 // Serve starts the broker and accepts connections from the listener.
@@ -176,6 +221,9 @@ func (b *Broker) init() {
 	if b.sharedTrie == nil {
 		b.sharedTrie = NewTrie[*sharedEntry]()
 	}
+	if b.retained == nil {
+		b.loadRetained()
+	}
 	if b.MaxPacketSize == 0 {
 		b.MaxPacketSize = MaxPacketSize
 	}
@@ -276,7 +324,8 @@ func (b *Broker) handleConnectionV4(conn net.Conn, reader *bufio.Reader) {
 		auth = AllowAll{}
 	}
 
-	if !auth.Authenticate(connect.ClientID, connect.Username, connect.Password) {
+	auth, ok = authenticate(auth, connect.ClientID, connect.Username, connect.Password, peerCertIdentity(conn))
+	if !ok {
 		slog.Debug("mqtt0: authentication failed", "clientID", connect.ClientID)
 		if err := WriteV4Packet(conn, &V4ConnAck{ReturnCode: ConnectNotAuthorized}); err != nil {
 			slog.Debug("mqtt0: write connack failed", "error", err)
@@ -284,60 +333,52 @@ func (b *Broker) handleConnectionV4(conn net.Conn, reader *bufio.Reader) {
 		return
 	}
 
-	// Send CONNACK
-	if err := WriteV4Packet(conn, &V4ConnAck{ReturnCode: ConnectAccepted}); err != nil {
-		slog.Debug("mqtt0: write connack failed", "error", err)
+	// Register client before CONNACK so the duplicate ClientID policy can
+	// still reject the connection
+	handle, clientID, ok := b.registerClient(connect.ClientID, !connect.CleanSession)
+	if !ok {
+		slog.Debug("mqtt0: duplicate clientID rejected", "clientID", connect.ClientID)
+		if err := WriteV4Packet(conn, &V4ConnAck{ReturnCode: ConnectIDRejected}); err != nil {
+			slog.Debug("mqtt0: write connack failed", "error", err)
+		}
 		return
 	}
-
-	// Register client
-	handle := &clientHandle{
-		clientID: connect.ClientID,
-		msgCh:    make(chan *Message, 100),
+	if clientID != connect.ClientID {
+		// ACL rules are written against the ClientID the device presents
+		auth = originalIDAuth{Authenticator: auth, clientID: connect.ClientID}
 	}
 
-	b.mu.Lock()
-	// Per MQTT spec: if a client connects with a clientID already in use,
-	// disconnect the old client first
-	var oldHandle *clientHandle
-	var oldTopics []string
-	if old, exists := b.clients[connect.ClientID]; exists {
-		oldHandle = old
-		oldTopics = b.clientSubscriptions[connect.ClientID]
-		delete(b.clientSubscriptions, connect.ClientID)
-	}
-	b.clients[connect.ClientID] = handle
-	b.mu.Unlock()
+	sessionPresent := b.openSession(handle, auth)
 
-	// Clean up old client's subscriptions BEFORE closing channel to prevent
-	// send-on-closed-channel panic in routeMessage
-	if oldHandle != nil {
-		b.removeClientSubscriptions(oldTopics, oldHandle)
-		close(oldHandle.msgCh) // Signal old client to disconnect
+	// Send CONNACK
+	if err := WriteV4Packet(conn, &V4ConnAck{SessionPresent: sessionPresent, ReturnCode: ConnectAccepted}); err != nil {
+		slog.Debug("mqtt0: write connack failed", "error", err)
+		b.cleanupClient(clientID, connect.Username, handle)
+		return
 	}
 
 	if b.OnConnect != nil {
-		b.OnConnect(connect.ClientID)
+		b.OnConnect(clientID)
 	}
 
 	// Publish $SYS connected event
 	if tcpAddr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
-		b.publishSysConnected(connect.ClientID, connect.Username, tcpAddr.AddrPort(), ProtocolV4, connect.KeepAlive)
+		b.publishSysConnected(clientID, connect.Username, tcpAddr.AddrPort(), ProtocolV4, connect.KeepAlive)
 	}
 
-	slog.Info("mqtt0: client connected", "clientID", connect.ClientID, "version", "v4")
+	slog.Info("mqtt0: client connected", "clientID", clientID, "version", "v4")
 
 	// Run client loop
-	b.clientLoopV4(conn, reader, connect.ClientID, connect.KeepAlive, handle, auth)
+	b.clientLoopV4(conn, reader, clientID, connect.KeepAlive, handle, auth)
 
 	// Cleanup - pass handle for pointer comparison to prevent race conditions
-	b.cleanupClient(connect.ClientID, connect.Username, handle)
+	b.cleanupClient(clientID, connect.Username, handle)
 
 	if b.OnDisconnect != nil {
-		b.OnDisconnect(connect.ClientID)
+		b.OnDisconnect(clientID)
 	}
 
-	slog.Info("mqtt0: client disconnected", "clientID", connect.ClientID)
+	slog.Info("mqtt0: client disconnected", "clientID", clientID)
 }
 
 func (b *Broker) handleConnectionV5(conn net.Conn, reader *bufio.Reader) {
@@ -360,7 +401,8 @@ func (b *Broker) handleConnectionV5(conn net.Conn, reader *bufio.Reader) {
 		auth = AllowAll{}
 	}
 
-	if !auth.Authenticate(connect.ClientID, connect.Username, connect.Password) {
+	auth, ok = authenticate(auth, connect.ClientID, connect.Username, connect.Password, peerCertIdentity(conn))
+	if !ok {
 		slog.Debug("mqtt0: authentication failed", "clientID", connect.ClientID)
 		if err := WriteV5Packet(conn, &V5ConnAck{ReasonCode: ReasonNotAuthorized}); err != nil {
 			slog.Debug("mqtt0: write connack failed", "error", err)
@@ -368,60 +410,114 @@ func (b *Broker) handleConnectionV5(conn net.Conn, reader *bufio.Reader) {
 		return
 	}
 
-	// Send CONNACK
-	if err := WriteV5Packet(conn, &V5ConnAck{ReasonCode: ReasonSuccess}); err != nil {
-		slog.Debug("mqtt0: write connack failed", "error", err)
+	// Register client before CONNACK so the duplicate ClientID policy can
+	// still reject the connection
+	// A v5 session outlives the connection only with a session expiry
+	// interval; the broker keeps it until a clean start, whatever the interval.
+	persistent := !connect.CleanStart && connect.Properties != nil &&
+		connect.Properties.SessionExpiry != nil && *connect.Properties.SessionExpiry > 0
+	handle, clientID, ok := b.registerClient(connect.ClientID, persistent)
+	if !ok {
+		slog.Debug("mqtt0: duplicate clientID rejected", "clientID", connect.ClientID)
+		if err := WriteV5Packet(conn, &V5ConnAck{ReasonCode: ReasonClientIDNotValid}); err != nil {
+			slog.Debug("mqtt0: write connack failed", "error", err)
+		}
 		return
 	}
-
-	// Register client
-	handle := &clientHandle{
-		clientID: connect.ClientID,
-		msgCh:    make(chan *Message, 100),
+	if clientID != connect.ClientID {
+		// ACL rules are written against the ClientID the device presents
+		auth = originalIDAuth{Authenticator: auth, clientID: connect.ClientID}
 	}
 
-	b.mu.Lock()
-	// Per MQTT spec: if a client connects with a clientID already in use,
-	// disconnect the old client first
-	var oldHandle *clientHandle
-	var oldTopics []string
-	if old, exists := b.clients[connect.ClientID]; exists {
-		oldHandle = old
-		oldTopics = b.clientSubscriptions[connect.ClientID]
-		delete(b.clientSubscriptions, connect.ClientID)
-	}
-	b.clients[connect.ClientID] = handle
-	b.mu.Unlock()
+	sessionPresent := b.openSession(handle, auth)
 
-	// Clean up old client's subscriptions BEFORE closing channel to prevent
-	// send-on-closed-channel panic in routeMessage
-	if oldHandle != nil {
-		b.removeClientSubscriptions(oldTopics, oldHandle)
-		close(oldHandle.msgCh) // Signal old client to disconnect
+	// Send CONNACK, reporting the assigned ClientID if it was suffixed
+	connack := &V5ConnAck{SessionPresent: sessionPresent, ReasonCode: ReasonSuccess}
+	if clientID != connect.ClientID {
+		connack.Properties = &V5Properties{AssignedClientID: clientID}
+	}
+	if err := WriteV5Packet(conn, connack); err != nil {
+		slog.Debug("mqtt0: write connack failed", "error", err)
+		b.cleanupClient(clientID, connect.Username, handle)
+		return
 	}
 
 	if b.OnConnect != nil {
-		b.OnConnect(connect.ClientID)
+		b.OnConnect(clientID)
 	}
 
 	// Publish $SYS connected event
 	if tcpAddr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
-		b.publishSysConnected(connect.ClientID, connect.Username, tcpAddr.AddrPort(), ProtocolV5, connect.KeepAlive)
+		b.publishSysConnected(clientID, connect.Username, tcpAddr.AddrPort(), ProtocolV5, connect.KeepAlive)
 	}
 
-	slog.Info("mqtt0: client connected", "clientID", connect.ClientID, "version", "v5")
+	slog.Info("mqtt0: client connected", "clientID", clientID, "version", "v5")
 
 	// Run client loop
-	b.clientLoopV5(conn, reader, connect.ClientID, connect.KeepAlive, handle, auth)
+	b.clientLoopV5(conn, reader, clientID, connect.KeepAlive, handle, auth)
 
 	// Cleanup - pass handle for pointer comparison to prevent race conditions
-	b.cleanupClient(connect.ClientID, connect.Username, handle)
+	b.cleanupClient(clientID, connect.Username, handle)
 
 	if b.OnDisconnect != nil {
-		b.OnDisconnect(connect.ClientID)
+		b.OnDisconnect(clientID)
 	}
 
-	slog.Info("mqtt0: client disconnected", "clientID", connect.ClientID)
+	slog.Info("mqtt0: client disconnected", "clientID", clientID)
+}
+
+// registerClient registers a new client according to DuplicateClientIDPolicy.
+// It returns the client handle and the effective ClientID, which differs from
+// clientID under DuplicateSuffix. ok is false if the connection must be
+// rejected. persistent marks a session that outlives the connection.
+func (b *Broker) registerClient(clientID string, persistent bool) (handle *clientHandle, effectiveID string, ok bool) {
+	effectiveID = clientID
+
+	b.mu.Lock()
+	var oldHandle *clientHandle
+	var oldTopics []string
+	if old, exists := b.clients[clientID]; exists {
+		switch b.DuplicateClientIDPolicy {
+		case DuplicateReject:
+			b.mu.Unlock()
+			return nil, "", false
+		case DuplicateSuffix:
+			for n := 2; ; n++ {
+				effectiveID = fmt.Sprintf("%s~%d", clientID, n)
+				if _, taken := b.clients[effectiveID]; !taken {
+					break
+				}
+			}
+		default:
+			// Per MQTT spec: if a client connects with a clientID already
+			// in use, disconnect the old client first
+			oldHandle = old
+			oldTopics = b.clientSubscriptions[clientID]
+			delete(b.clientSubscriptions, clientID)
+		}
+	}
+	handle = &clientHandle{
+		clientID:   effectiveID,
+		msgCh:      make(chan *Message, 100),
+		persistent: persistent,
+	}
+	b.clients[effectiveID] = handle
+	b.mu.Unlock()
+
+	// Clean up old client's subscriptions BEFORE closing channel to prevent
+	// send-on-closed-channel panic in routeMessage
+	if oldHandle != nil {
+		b.removeClientSubscriptions(oldTopics, oldHandle)
+		oldHandle.takenOver.Store(true)
+		close(oldHandle.msgCh) // Signal old client to disconnect
+
+		slog.Info("mqtt0: session taken over", "clientID", clientID)
+		if b.OnSessionTakeover != nil {
+			b.OnSessionTakeover(clientID)
+		}
+	}
+
+	return handle, effectiveID, true
 }
 
 func (b *Broker) clientLoopV4(conn net.Conn, reader *bufio.Reader, clientID string, keepAlive uint16, handle *clientHandle, auth Authenticator) {
@@ -488,6 +584,11 @@ func (b *Broker) clientLoopV4(conn net.Conn, reader *bufio.Reader, clientID stri
 			case *V4Subscribe:
 				codes := b.handleSubscribeV4(clientID, handle, p.Topics, auth)
 				WriteV4Packet(conn, &V4SubAck{PacketID: p.PacketID, ReturnCodes: codes})
+				for i, topic := range p.Topics {
+					if codes[i] == 0x00 {
+						b.sendRetained(handle, topic)
+					}
+				}
 			case *V4Unsubscribe:
 				b.handleUnsubscribe(clientID, p.Topics)
 				WriteV4Packet(conn, &V4UnsubAck{PacketID: p.PacketID})
@@ -557,6 +658,9 @@ func (b *Broker) clientLoopV5(conn net.Conn, reader *bufio.Reader, clientID stri
 			if !ok {
 				// Channel closed - another client connected with same ID
 				slog.Debug("mqtt0: disconnected (duplicate clientID)", "clientID", clientID)
+				if handle.takenOver.Load() {
+					WriteV5Packet(conn, &V5Disconnect{ReasonCode: ReasonSessionTakenOver})
+				}
 				return
 			}
 			// Send message to client
@@ -577,6 +681,11 @@ func (b *Broker) clientLoopV5(conn net.Conn, reader *bufio.Reader, clientID stri
 			case *V5Subscribe:
 				codes := b.handleSubscribeV5(clientID, handle, p.Topics, auth)
 				WriteV5Packet(conn, &V5SubAck{PacketID: p.PacketID, ReasonCodes: codes})
+				for i, filter := range p.Topics {
+					if codes[i] == ReasonGrantedQoS0 {
+						b.sendRetained(handle, filter.Topic)
+					}
+				}
 			case *V5Unsubscribe:
 				b.handleUnsubscribeV5(clientID, p.Topics)
 				WriteV5Packet(conn, &V5UnsubAck{PacketID: p.PacketID, ReasonCodes: make([]ReasonCode, len(p.Topics))})
@@ -784,6 +893,7 @@ func (b *Broker) handleSubscribeV4(clientID string, handle *clientHandle, topics
 		codes[i] = 0x00 // Success QoS 0
 	}
 
+	b.saveSession(clientID)
 	return codes
 }
 
@@ -869,6 +979,7 @@ func (b *Broker) handleSubscribeV5(clientID string, handle *clientHandle, filter
 		codes[i] = ReasonGrantedQoS0
 	}
 
+	b.saveSession(clientID)
 	return codes
 }
 
@@ -936,6 +1047,8 @@ func (b *Broker) handleUnsubscribe(clientID string, topics []string) {
 		b.clientSubscriptions[clientID] = newSubs
 	}
 	b.mu.Unlock()
+
+	b.saveSession(clientID)
 }
 
 func (b *Broker) handleUnsubscribeV5(clientID string, topics []string) {
@@ -943,9 +1056,29 @@ func (b *Broker) handleUnsubscribeV5(clientID string, topics []string) {
 }
 
 func (b *Broker) routeMessage(msg *Message) {
-	// Route to normal subscribers
+	if msg.Retain {
+		b.retain(msg)
+		// Established subscriptions receive it as a normal message
+		// (MQTT spec 3.3.1.3)
+		msg = &Message{Topic: msg.Topic, Payload: msg.Payload}
+	}
+
+	// Route to normal subscribers. A client whose subscriptions overlap
+	// (e.g. "a/+" and "a/#") receives the message once.
 	handles := b.subscriptions.Get(msg.Topic)
-	for _, handle := range handles {
+	var seen map[*clientHandle]struct{}
+	if len(handles) > 8 {
+		seen = make(map[*clientHandle]struct{}, len(handles))
+	}
+	for i, handle := range handles {
+		if seen != nil {
+			if _, ok := seen[handle]; ok {
+				continue
+			}
+			seen[handle] = struct{}{}
+		} else if slices.Contains(handles[:i], handle) {
+			continue
+		}
 		select {
 		case handle.msgCh <- msg:
 		default:
@@ -966,6 +1099,70 @@ func (b *Broker) routeMessage(msg *Message) {
 	}
 }
 
+// Subscription is a client's subscription, as reported by
+// Broker.Subscriptions.
+type Subscription struct {
+	// ClientID is the subscribed client.
+	ClientID string
+
+	// Topic is the topic filter, without the $share prefix.
+	Topic string
+
+	// Group is the shared subscription group, empty for normal
+	// subscriptions.
+	Group string
+}
+
+// Subscriptions returns the subscriptions whose topic filters are matched
+// by filter. Wildcards in filter match the levels of topic filters, so
+// "device/+/state" lists subscriptions to "device/gear-001/state" as well
+// as to "device/+/state", and "#" lists all subscriptions. Wildcards in
+// topic filters are matched literally.
+func (b *Broker) Subscriptions(filter string) []Subscription {
+	b.init()
+	var subs []Subscription
+	b.subscriptions.Walk(filter, func(pattern string, handles []*clientHandle) bool {
+		for _, h := range handles {
+			subs = append(subs, Subscription{ClientID: h.clientID, Topic: pattern})
+		}
+		return true
+	})
+	b.sharedTrie.Walk(filter, func(pattern string, entries []*sharedEntry) bool {
+		for _, e := range entries {
+			for _, h := range e.group.members() {
+				subs = append(subs, Subscription{ClientID: h.clientID, Topic: pattern, Group: e.groupName})
+			}
+		}
+		return true
+	})
+	return subs
+}
+
+// Subscribers returns the IDs of the clients subscribed to topic: the
+// clients a message published on topic is delivered to, and all members
+// of matching shared subscription groups (of which only one receives each
+// message).
+func (b *Broker) Subscribers(topic string) []string {
+	b.init()
+	var ids []string
+	seen := make(map[string]struct{})
+	add := func(id string) {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	for _, h := range b.subscriptions.Get(topic) {
+		add(h.clientID)
+	}
+	for _, e := range b.sharedTrie.Get(topic) {
+		for _, h := range e.group.members() {
+			add(h.clientID)
+		}
+	}
+	return ids
+}
+
 // removeClientSubscriptions removes a client's subscriptions from both
 // normal subscriptions trie and shared subscriptions trie.
 // Uses pointer comparison to ensure only the correct client instance is removed.
@@ -1031,9 +1228,18 @@ func (b *Broker) cleanupClient(clientID, username string, handle *clientHandle)
 
 // Publish sends a message from the broker to all matching subscribers.
 func (b *Broker) Publish(ctx context.Context, topic string, payload []byte) error {
+	return b.PublishRetain(ctx, topic, payload, false)
+}
+
+// PublishRetain sends a message from the broker with the retain flag. A
+// retained message is also sent to future subscribers of its topic; an empty
+// retained payload clears the topic's retained message.
+func (b *Broker) PublishRetain(ctx context.Context, topic string, payload []byte, retain bool) error {
+	b.init()
 	msg := &Message{
 		Topic:   topic,
 		Payload: payload,
+		Retain:  retain,
 	}
 	b.routeMessage(msg)
 	return nil
@@ -9,12 +9,16 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"fmt"
 	"math/big"
 	"net"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/haivivi/giztoy/go/pkg/kv"
 )
 
 func TestBrokerBasic(t *testing.T) {
@@ -190,6 +194,69 @@ func TestBrokerWildcardRouting(t *testing.T) {
 	broker.Close()
 }
 
+func TestBrokerOverlappingSubscriptions(t *testing.T) {
+	addr := getTestAddr()
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer ln.Close()
+
+	broker := &Broker{}
+	go broker.Serve(ln)
+	defer broker.Close()
+
+	ctx := context.Background()
+
+	exact, err := Connect(ctx, ClientConfig{Addr: "tcp://" + addr, ClientID: "exact-sub"})
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer exact.Close()
+	wild, err := Connect(ctx, ClientConfig{Addr: "tcp://" + addr, ClientID: "wild-sub"})
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer wild.Close()
+
+	if err := exact.Subscribe(ctx, "device/gear-001/state"); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	// Overlapping subscriptions of one client
+	if err := wild.Subscribe(ctx, "device/+/state", "device/#"); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	subs := broker.Subscribers("device/gear-001/state")
+	slices.Sort(subs)
+	if want := []string{"exact-sub", "wild-sub"}; !slices.Equal(subs, want) {
+		t.Errorf("Subscribers = %v, want %v", subs, want)
+	}
+
+	var filters []string
+	for _, s := range broker.Subscriptions("device/+/state") {
+		filters = append(filters, s.ClientID+" "+s.Topic)
+	}
+	slices.Sort(filters)
+	if want := []string{"exact-sub device/gear-001/state", "wild-sub device/+/state"}; !slices.Equal(filters, want) {
+		t.Errorf("Subscriptions = %v, want %v", filters, want)
+	}
+
+	if err := broker.Publish(ctx, "device/gear-001/state", []byte("on")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	for _, c := range []*Client{exact, wild} {
+		if msg, err := c.RecvTimeout(2 * time.Second); err != nil || msg == nil {
+			t.Fatalf("recv failed: %v", err)
+		}
+	}
+	// Both matching subscriptions deliver a single copy
+	if msg, err := wild.RecvTimeout(100 * time.Millisecond); err == nil && msg != nil {
+		t.Errorf("wild-sub received a duplicate: %s", msg.Topic)
+	}
+}
+
 func TestBrokerTLS(t *testing.T) {
 	// Generate test certificates
 	cert, key := generateTestCert(t)
@@ -1259,3 +1326,313 @@ func TestDuplicateClientID(t *testing.T) {
 
 	broker.Close()
 }
+
+// TestDuplicateClientIDTakeover tests that OnSessionTakeover fires when a
+// client reconnects with a ClientID that is still connected.
+func TestDuplicateClientIDTakeover(t *testing.T) {
+	addr := getTestAddr()
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer ln.Close()
+
+	takeovers := make(chan string, 1)
+	broker := &Broker{
+		OnSessionTakeover: func(clientID string) {
+			takeovers <- clientID
+		},
+	}
+	go broker.Serve(ln)
+	defer broker.Close()
+
+	ctx := context.Background()
+	cfg := ClientConfig{
+		Addr:            "tcp://" + addr,
+		ClientID:        "takeover-test",
+		ProtocolVersion: ProtocolV5,
+	}
+	client1, err := Connect(ctx, cfg)
+	if err != nil {
+		t.Fatalf("connect client1 failed: %v", err)
+	}
+	defer client1.Close()
+
+	client2, err := Connect(ctx, cfg)
+	if err != nil {
+		t.Fatalf("connect client2 failed: %v", err)
+	}
+	defer client2.Close()
+
+	select {
+	case id := <-takeovers:
+		if id != "takeover-test" {
+			t.Errorf("OnSessionTakeover clientID = %q, want %q", id, "takeover-test")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnSessionTakeover not called")
+	}
+
+	// The old client is disconnected by the broker.
+	if _, err := client1.RecvTimeout(500 * time.Millisecond); err == nil {
+		t.Error("client1 should be disconnected after takeover")
+	}
+}
+
+// TestDuplicateClientIDReject tests that DuplicateReject refuses the new
+// connection and keeps the existing client.
+func TestDuplicateClientIDReject(t *testing.T) {
+	addr := getTestAddr()
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer ln.Close()
+
+	broker := &Broker{DuplicateClientIDPolicy: DuplicateReject}
+	go broker.Serve(ln)
+	defer broker.Close()
+
+	ctx := context.Background()
+	for _, ver := range []ProtocolVersion{ProtocolV4, ProtocolV5} {
+		cfg := ClientConfig{
+			Addr:            "tcp://" + addr,
+			ClientID:        "reject-test-" + ver.String(),
+			ProtocolVersion: ver,
+		}
+		client1, err := Connect(ctx, cfg)
+		if err != nil {
+			t.Fatalf("%v: connect client1 failed: %v", ver, err)
+		}
+
+		if client2, err := Connect(ctx, cfg); err == nil {
+			client2.Close()
+			t.Errorf("%v: duplicate connect should be rejected", ver)
+		}
+
+		// The original client still works.
+		if err := client1.Subscribe(ctx, "test/reject"); err != nil {
+			t.Errorf("%v: client1 subscribe after rejected duplicate: %v", ver, err)
+		}
+		client1.Close()
+	}
+}
+
+// TestDuplicateClientIDSuffix tests that DuplicateSuffix keeps both clients
+// connected under distinct ClientIDs.
+func TestDuplicateClientIDSuffix(t *testing.T) {
+	addr := getTestAddr()
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer ln.Close()
+
+	connected := make(chan string, 2)
+	broker := &Broker{
+		DuplicateClientIDPolicy: DuplicateSuffix,
+		OnConnect: func(clientID string) {
+			connected <- clientID
+		},
+	}
+	go broker.Serve(ln)
+	defer broker.Close()
+
+	ctx := context.Background()
+	cfg := ClientConfig{
+		Addr:     "tcp://" + addr,
+		ClientID: "suffix-test",
+	}
+	client1, err := Connect(ctx, cfg)
+	if err != nil {
+		t.Fatalf("connect client1 failed: %v", err)
+	}
+	defer client1.Close()
+	client2, err := Connect(ctx, cfg)
+	if err != nil {
+		t.Fatalf("connect client2 failed: %v", err)
+	}
+	defer client2.Close()
+
+	ids := []string{<-connected, <-connected}
+	if ids[0] != "suffix-test" || ids[1] != "suffix-test~2" {
+		t.Errorf("connected IDs = %v, want [suffix-test suffix-test~2]", ids)
+	}
+
+	// Both clients keep receiving messages.
+	for _, c := range []*Client{client1, client2} {
+		if err := c.Subscribe(ctx, "test/suffix"); err != nil {
+			t.Fatalf("subscribe failed: %v", err)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := broker.Publish(ctx, "test/suffix", []byte("hi")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	for i, c := range []*Client{client1, client2} {
+		if _, err := c.RecvTimeout(500 * time.Millisecond); err != nil {
+			t.Errorf("client%d should receive message: %v", i+1, err)
+		}
+	}
+}
+
+func TestRetainedMessages(t *testing.T) {
+	addr := getTestAddr()
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer ln.Close()
+
+	broker := &Broker{}
+	go broker.Serve(ln)
+	defer broker.Close()
+
+	ctx := context.Background()
+	live, err := Connect(ctx, ClientConfig{Addr: "tcp://" + addr, ClientID: "retain-live"})
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer live.Close()
+	if err := live.Subscribe(ctx, "config/#"); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	pub, err := Connect(ctx, ClientConfig{Addr: "tcp://" + addr, ClientID: "retain-pub"})
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer pub.Close()
+	if err := pub.PublishRetain(ctx, "config/volume", []byte("7"), true); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	// Established subscriptions get a normal message
+	msg, err := live.RecvTimeout(2 * time.Second)
+	if err != nil || msg == nil {
+		t.Fatalf("live recv: msg=%v, err=%v", msg, err)
+	}
+	if msg.Retain {
+		t.Error("live subscriber got Retain=true, want false")
+	}
+
+	// New subscriptions get the retained message
+	late, err := Connect(ctx, ClientConfig{Addr: "tcp://" + addr, ClientID: "retain-late", ProtocolVersion: ProtocolV5})
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer late.Close()
+	if err := late.Subscribe(ctx, "config/+"); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	msg, err = late.RecvTimeout(2 * time.Second)
+	if err != nil || msg == nil {
+		t.Fatalf("late recv: msg=%v, err=%v", msg, err)
+	}
+	if msg.Topic != "config/volume" || string(msg.Payload) != "7" || !msg.Retain {
+		t.Errorf("retained = %+v", msg)
+	}
+
+	// An empty retained payload clears the topic
+	if err := pub.PublishRetain(ctx, "config/volume", nil, true); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := broker.Retained("#"); len(got) != 0 {
+		t.Errorf("Retained after clear = %v, want none", got)
+	}
+}
+
+func TestPersistentSession(t *testing.T) {
+	store := kv.NewMemory(nil)
+	ctx := context.Background()
+
+	start := func() (string, *Broker, func()) {
+		addr := getTestAddr()
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Fatalf("listen failed: %v", err)
+		}
+		broker := &Broker{Store: store}
+		go broker.Serve(ln)
+		return addr, broker, func() {
+			broker.Close()
+			ln.Close()
+		}
+	}
+
+	for _, version := range []ProtocolVersion{ProtocolV4, ProtocolV5} {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			clientID := fmt.Sprintf("gear:%d", version)
+			expiry := uint32(3600)
+			persistent := ClientConfig{
+				ClientID:        clientID,
+				CleanSession:    new(bool),
+				ProtocolVersion: version,
+				SessionExpiry:   &expiry,
+			}
+
+			// First broker: subscribe with a persistent session and retain presence
+			addr, broker, stop := start()
+			cfg := persistent
+			cfg.Addr = "tcp://" + addr
+			client, err := Connect(ctx, cfg)
+			if err != nil {
+				t.Fatalf("connect failed: %v", err)
+			}
+			if err := client.Subscribe(ctx, "device/"+clientID+"/cmd"); err != nil {
+				t.Fatalf("subscribe failed: %v", err)
+			}
+			if err := broker.PublishRetain(ctx, "presence/"+clientID, []byte("online"), true); err != nil {
+				t.Fatalf("publish failed: %v", err)
+			}
+			client.Close()
+			stop()
+
+			// Restarted broker: the device gets commands without resubscribing
+			addr, broker, stop = start()
+			defer stop()
+			if got := broker.Retained("presence/" + clientID); len(got) != 1 || string(got[0].Payload) != "online" {
+				t.Errorf("Retained after restart = %v", got)
+			}
+			cfg.Addr = "tcp://" + addr
+			client, err = Connect(ctx, cfg)
+			if err != nil {
+				t.Fatalf("reconnect failed: %v", err)
+			}
+			if got := broker.Subscribers("device/" + clientID + "/cmd"); !slices.Equal(got, []string{clientID}) {
+				t.Errorf("Subscribers after restart = %v", got)
+			}
+			if err := broker.Publish(ctx, "device/"+clientID+"/cmd", []byte("play")); err != nil {
+				t.Fatalf("publish failed: %v", err)
+			}
+			msg, err := client.RecvTimeout(2 * time.Second)
+			if err != nil || msg == nil || string(msg.Payload) != "play" {
+				t.Fatalf("recv after restart: msg=%v, err=%v", msg, err)
+			}
+			client.Close()
+
+			// A clean session discards the persisted subscriptions
+			clean := cfg
+			clean.CleanSession = nil
+			client, err = Connect(ctx, clean)
+			if err != nil {
+				t.Fatalf("clean connect failed: %v", err)
+			}
+			if got := broker.Subscribers("device/" + clientID + "/cmd"); len(got) != 0 {
+				t.Errorf("Subscribers after clean session = %v", got)
+			}
+			client.Close()
+			time.Sleep(100 * time.Millisecond)
+
+			client, err = Connect(ctx, cfg)
+			if err != nil {
+				t.Fatalf("reconnect failed: %v", err)
+			}
+			defer client.Close()
+			if got := broker.Subscribers("device/" + clientID + "/cmd"); len(got) != 0 {
+				t.Errorf("Subscribers after resuming a discarded session = %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package mqtt0
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"net"
+)
+
+// CertIdentity is the identity of a client that connected with a verified
+// TLS client certificate (mutual TLS).
+type CertIdentity struct {
+	// CommonName is the subject common name, typically the device ID.
+	CommonName string
+
+	// Organization and OrganizationalUnit are from the subject, e.g. the
+	// product line or fleet of the device.
+	Organization       []string
+	OrganizationalUnit []string
+
+	// DNSNames and URIs are the subject alternative names.
+	DNSNames []string
+	URIs     []string
+
+	// SerialNumber is the certificate serial number in decimal.
+	SerialNumber string
+
+	// Fingerprint is the hex SHA-256 of the DER certificate, for pinning a
+	// certificate rather than its subject.
+	Fingerprint string
+
+	// Certificate is the verified leaf certificate.
+	Certificate *x509.Certificate
+}
+
+// NewCertIdentity extracts the identity of a client certificate.
+func NewCertIdentity(cert *x509.Certificate) *CertIdentity {
+	sum := sha256.Sum256(cert.Raw)
+	id := &CertIdentity{
+		CommonName:         cert.Subject.CommonName,
+		Organization:       cert.Subject.Organization,
+		OrganizationalUnit: cert.Subject.OrganizationalUnit,
+		DNSNames:           cert.DNSNames,
+		SerialNumber:       cert.SerialNumber.String(),
+		Fingerprint:        hex.EncodeToString(sum[:]),
+		Certificate:        cert,
+	}
+	for _, u := range cert.URIs {
+		id.URIs = append(id.URIs, u.String())
+	}
+	return id
+}
+
+// CertAuthenticator is an Authenticator that also authenticates clients by
+// their TLS client certificate.
+//
+// For a client whose certificate the TLS listener verified (its tls.Config
+// sets ClientAuth to VerifyClientCertIfGiven or RequireAndVerifyClientCert,
+// and ClientCAs), the Broker calls AuthenticateCert and ACLCert instead of
+// Authenticate and ACL. Clients without a verified certificate go through
+// Authenticate and ACL as before.
+type CertAuthenticator interface {
+	Authenticator
+
+	// AuthenticateCert validates a client with a verified certificate.
+	// The username and password are those of the CONNECT packet, if any.
+	AuthenticateCert(clientID, username string, password []byte, cert *CertIdentity) bool
+
+	// ACLCert checks publish/subscribe permissions of a client with a
+	// verified certificate, like ACL.
+	ACLCert(clientID string, cert *CertIdentity, topic string, write bool) bool
+}
+
+// CertAuth is a CertAuthenticator for device fleets with a certificate per
+// device: a device may connect only as the ClientID its certificate names.
+type CertAuth struct {
+	// ClientID returns the ClientID the certificate may connect as.
+	// If nil, the certificate's common name.
+	ClientID func(cert *CertIdentity) string
+
+	// Allow checks publish/subscribe permissions of a device.
+	// If nil, all operations are allowed.
+	Allow func(cert *CertIdentity, topic string, write bool) bool
+
+	// Fallback authenticates clients without a verified certificate.
+	// If nil, they are rejected.
+	Fallback Authenticator
+}
+
+// Authenticate authenticates a client without a certificate via Fallback.
+func (a *CertAuth) Authenticate(clientID, username string, password []byte) bool {
+	return a.Fallback != nil && a.Fallback.Authenticate(clientID, username, password)
+}
+
+// ACL checks a client without a certificate via Fallback.
+func (a *CertAuth) ACL(clientID, topic string, write bool) bool {
+	return a.Fallback != nil && a.Fallback.ACL(clientID, topic, write)
+}
+
+// AuthenticateCert allows the client if it connects as the ClientID of its
+// certificate.
+func (a *CertAuth) AuthenticateCert(clientID, _ string, _ []byte, cert *CertIdentity) bool {
+	want := cert.CommonName
+	if a.ClientID != nil {
+		want = a.ClientID(cert)
+	}
+	return want != "" && clientID == want
+}
+
+// ACLCert checks the operation via Allow.
+func (a *CertAuth) ACLCert(_ string, cert *CertIdentity, topic string, write bool) bool {
+	return a.Allow == nil || a.Allow(cert, topic, write)
+}
+
+// certAuth forwards ACL checks of a client with a verified certificate to
+// ACLCert.
+type certAuth struct {
+	CertAuthenticator
+	cert *CertIdentity
+}
+
+func (a certAuth) ACL(clientID, topic string, write bool) bool {
+	return a.CertAuthenticator.ACLCert(clientID, a.cert, topic, write)
+}
+
+// tlsConn is implemented by connections with a TLS state: *tls.Conn, and
+// WebSocket connections accepted over TLS.
+type tlsConn interface {
+	ConnectionState() tls.ConnectionState
+}
+
+// peerCertIdentity returns the identity of the verified client certificate
+// of conn, or nil. The TLS handshake must be complete.
+func peerCertIdentity(conn net.Conn) *CertIdentity {
+	tc, ok := conn.(tlsConn)
+	if !ok {
+		return nil
+	}
+	state := tc.ConnectionState()
+	// PeerCertificates are also set for ClientAuth RequestClientCert,
+	// without verification
+	if len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	return NewCertIdentity(state.PeerCertificates[0])
+}
+
+// authenticate authenticates a CONNECT, by certificate if cert is not nil
+// and auth is a CertAuthenticator. It returns the Authenticator for the ACL
+// checks of the client.
+func authenticate(auth Authenticator, clientID, username string, password []byte, cert *CertIdentity) (Authenticator, bool) {
+	if ca, ok := auth.(CertAuthenticator); ok && cert != nil {
+		if !ca.AuthenticateCert(clientID, username, password, cert) {
+			return nil, false
+		}
+		return certAuth{CertAuthenticator: ca, cert: cert}, true
+	}
+	return auth, auth.Authenticate(clientID, username, password)
+}
@@ -0,0 +1,205 @@
+package mqtt0
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for mutual TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA failed: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse CA failed: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue issues a server certificate for 127.0.0.1 if server is set, or a
+// client certificate named cn.
+func (ca *testCA) issue(t *testing.T, cn string, serial int64, server bool) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"giztoy"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if server {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("create certificate failed: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// startMTLSBroker starts a broker on a TLS listener that verifies client
+// certificates issued by ca, if given.
+func startMTLSBroker(t *testing.T, ca *testCA, clientAuth tls.ClientAuthType, auth Authenticator) string {
+	t.Helper()
+	addr := getTestAddr()
+	ln, err := Listen("tls", addr, &tls.Config{
+		Certificates: []tls.Certificate{ca.issue(t, "broker", 2, true)},
+		ClientCAs:    ca.pool,
+		ClientAuth:   clientAuth,
+	})
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	broker := &Broker{Authenticator: auth}
+	go broker.Serve(ln)
+	t.Cleanup(func() {
+		ln.Close()
+		broker.Close()
+	})
+	return addr
+}
+
+func TestBrokerMTLS(t *testing.T) {
+	ca := newTestCA(t)
+	var seen atomic.Pointer[CertIdentity]
+	addr := startMTLSBroker(t, ca, tls.RequireAndVerifyClientCert, &CertAuth{
+		Allow: func(cert *CertIdentity, topic string, write bool) bool {
+			seen.Store(cert)
+			return strings.HasPrefix(topic, "device/"+cert.CommonName+"/")
+		},
+	})
+
+	ctx := context.Background()
+	for _, version := range []ProtocolVersion{ProtocolV4, ProtocolV5} {
+		t.Run(version.String(), func(t *testing.T) {
+			// ServerName is left to the dialer
+			client, err := Connect(ctx, ClientConfig{
+				Addr:            "tls://" + addr,
+				ClientID:        "device-001",
+				ProtocolVersion: version,
+				TLSConfig: &tls.Config{
+					Certificates: []tls.Certificate{ca.issue(t, "device-001", 10, false)},
+					RootCAs:      ca.pool,
+				},
+			})
+			if err != nil {
+				t.Fatalf("connect failed: %v", err)
+			}
+			defer client.Close()
+
+			if err := client.Subscribe(ctx, "device/device-001/cmd"); err != nil {
+				t.Fatalf("subscribe to own topic failed: %v", err)
+			}
+			if err := client.Subscribe(ctx, "device/device-002/cmd"); err != ErrACLDenied {
+				t.Errorf("subscribe to another device: expected ErrACLDenied, got %v", err)
+			}
+			if seen := seen.Load(); seen == nil || seen.CommonName != "device-001" || seen.SerialNumber != "10" ||
+				len(seen.Organization) != 1 || seen.Organization[0] != "giztoy" || len(seen.Fingerprint) != 64 {
+				t.Errorf("unexpected identity: %+v", seen)
+			}
+		})
+	}
+}
+
+func TestBrokerMTLSWrongClientID(t *testing.T) {
+	ca := newTestCA(t)
+	addr := startMTLSBroker(t, ca, tls.RequireAndVerifyClientCert, &CertAuth{})
+
+	_, err := Connect(context.Background(), ClientConfig{
+		Addr:     "tls://" + addr,
+		ClientID: "device-002",
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{ca.issue(t, "device-001", 10, false)},
+			RootCAs:      ca.pool,
+		},
+	})
+	if err == nil {
+		t.Fatal("expected device-001's certificate to be refused as device-002")
+	}
+}
+
+func TestBrokerMTLSFallback(t *testing.T) {
+	ca := newTestCA(t)
+	addr := startMTLSBroker(t, ca, tls.VerifyClientCertIfGiven, &CertAuth{
+		Fallback: &testAuthenticator{validUser: "user", validPass: []byte("pass")},
+	})
+
+	ctx := context.Background()
+	// Without a certificate, the password is checked
+	client, err := Connect(ctx, ClientConfig{
+		Addr:      "tls://" + addr,
+		ClientID:  "legacy",
+		Username:  "user",
+		Password:  []byte("pass"),
+		TLSConfig: &tls.Config{RootCAs: ca.pool},
+	})
+	if err != nil {
+		t.Fatalf("connect with password failed: %v", err)
+	}
+	client.Close()
+
+	_, err = Connect(ctx, ClientConfig{
+		Addr:      "tls://" + addr,
+		ClientID:  "legacy",
+		Username:  "user",
+		Password:  []byte("wrong"),
+		TLSConfig: &tls.Config{RootCAs: ca.pool},
+	})
+	if err == nil {
+		t.Fatal("expected wrong password to be refused")
+	}
+}
+
+func TestPeerCertIdentityUnverified(t *testing.T) {
+	// A plain connection has no identity
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	if id := peerCertIdentity(server); id != nil {
+		t.Errorf("expected no identity, got %+v", id)
+	}
+	if id := peerCertIdentity(&wsConn{}); id != nil {
+		t.Errorf("expected no identity for plain WebSocket, got %+v", id)
+	}
+}
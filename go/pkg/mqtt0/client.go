@@ -52,6 +52,8 @@ type ClientConfig struct {
 
 	// TLSConfig is the TLS configuration for secure connections.
 	// If nil, a default configuration is used for tls:// and wss:// connections.
+	// Set Certificates to authenticate with a client certificate (mutual
+	// TLS); ServerName defaults to the host of Addr.
 	TLSConfig *tls.Config
 
 	// MaxPacketSize is the maximum packet size.
@@ -77,12 +77,16 @@ func dialTCP(ctx context.Context, addr string) (net.Conn, error) {
 }
 
 func dialTLS(ctx context.Context, addr string, config *tls.Config) (net.Conn, error) {
-	if config == nil {
-		// Extract hostname for SNI
+	if config == nil || (config.ServerName == "" && !config.InsecureSkipVerify) {
+		// Extract hostname for SNI and verification, e.g. for a config that
+		// only sets the client certificate and the root CAs
 		host, _, _ := net.SplitHostPort(addr)
-		config = &tls.Config{
-			ServerName: host,
+		if config == nil {
+			config = &tls.Config{}
+		} else {
+			config = config.Clone()
 		}
+		config.ServerName = host
 	}
 
 	var d net.Dialer
@@ -116,9 +120,10 @@ func dialWebSocket(ctx context.Context, urlStr string, tlsConfig *tls.Config) (n
 
 // wsConn wraps a websocket connection to implement net.Conn.
 type wsConn struct {
-	ws      *websocket.Conn
-	reader  *wsReader
-	writeMu sync.Mutex // protects Write operations
+	ws       *websocket.Conn
+	reader   *wsReader
+	writeMu  sync.Mutex           // protects Write operations
+	tlsState *tls.ConnectionState // of the HTTP request, for connections accepted over TLS
 }
 
 type wsReader struct {
@@ -185,4 +190,13 @@ func (c *wsConn) SetWriteDeadline(t time.Time) error {
 	return c.ws.SetWriteDeadline(t)
 }
 
+// ConnectionState returns the TLS state of a connection accepted over TLS,
+// or the zero state.
+func (c *wsConn) ConnectionState() tls.ConnectionState {
+	if c.tlsState == nil {
+		return tls.ConnectionState{}
+	}
+	return *c.tlsState
+}
+
 var _ net.Conn = (*wsConn)(nil)
@@ -52,6 +52,78 @@
 //
 //	log.Fatal(broker.Serve(ln))
 //
+// # Mutual TLS
+//
+// Devices can authenticate with a certificate per device instead of an
+// embedded username and password. The listener verifies client
+// certificates against a CA:
+//
+//	ln, err := mqtt0.Listen("tls", ":8883", &tls.Config{
+//	    Certificates: []tls.Certificate{serverCert},
+//	    ClientCAs:    deviceCAs,
+//	    ClientAuth:   tls.RequireAndVerifyClientCert,
+//	})
+//
+// and a [CertAuthenticator] sees the verified [CertIdentity] when the
+// client connects and on every publish and subscribe. [CertAuth] lets a
+// device connect only as the ClientID its certificate names:
+//
+//	broker := &mqtt0.Broker{Authenticator: &mqtt0.CertAuth{
+//	    Allow: func(cert *mqtt0.CertIdentity, topic string, write bool) bool {
+//	        return strings.HasPrefix(topic, "device/"+cert.CommonName+"/")
+//	    },
+//	}}
+//
+// Devices set their certificate in ClientConfig.TLSConfig:
+//
+//	client, err := mqtt0.Connect(ctx, mqtt0.ClientConfig{
+//	    Addr:      "tls://broker:8883",
+//	    ClientID:  "device-001",
+//	    TLSConfig: &tls.Config{Certificates: []tls.Certificate{deviceCert}, RootCAs: serverCAs},
+//	})
+//
+// With ClientAuth VerifyClientCertIfGiven, clients without a certificate
+// fall back to Authenticate and ACL.
+//
+// # Duplicate Client IDs
+//
+// By default a new connection with an already-connected ClientID takes over
+// the session: the old connection is closed (MQTT 5.0 clients receive a
+// DISCONNECT with reason "Session Taken Over") and OnSessionTakeover is
+// called. Set Broker.DuplicateClientIDPolicy to DuplicateReject to refuse
+// the new connection instead, or DuplicateSuffix to accept it under a
+// suffixed ClientID such as "device~2".
+//
+// # Subscription Introspection
+//
+// Broker.Subscribers lists the clients subscribed to a topic, and
+// Broker.Subscriptions lists the subscriptions under a topic filter, e.g.
+// "device/+/state" for the state subscriptions of every device:
+//
+//	for _, sub := range broker.Subscriptions("device/+/state") {
+//	    log.Printf("%s -> %s", sub.ClientID, sub.Topic)
+//	}
+//
+// Subscriptions are kept in a [Trie], whose lookups do not slow down with
+// the number of topic filters.
+//
+// # Retained Messages and Persistent Sessions
+//
+// The broker keeps the last retained message of each topic and sends it to
+// new subscribers; Broker.PublishRetain retains from the server side. A
+// client connecting without a clean session (MQTT 5.0: with a session
+// expiry interval) keeps its subscriptions across reconnects.
+//
+// Set Broker.Store to persist both in a [kv.Store], so that a restarted
+// broker still has presence and config topics, and devices that resume
+// their session need not subscribe again:
+//
+//	broker := &mqtt0.Broker{Store: store}
+//
+// Only subscriptions are kept: QoS 0 messages published while a client is
+// offline are not queued. Sessions are kept until the client connects with
+// a clean session; the v5 expiry interval is not enforced.
+//
 // # Protocol Support
 //
 // | Protocol | Support |
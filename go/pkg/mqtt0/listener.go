@@ -127,7 +127,7 @@ func (l *wsListener) handleWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	conn := &wsConn{ws: ws}
+	conn := &wsConn{ws: ws, tlsState: r.TLS}
 
 	select {
 	case l.connCh <- conn:
@@ -0,0 +1,191 @@
+package mqtt0
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/haivivi/giztoy/go/pkg/kv"
+)
+
+// storedSession is the persisted state of a client session.
+type storedSession struct {
+	Topics []string `json:"topics"`
+}
+
+// storedRetained is a persisted retained message.
+type storedRetained struct {
+	Topic   string `json:"topic"`
+	Payload []byte `json:"payload"`
+}
+
+func (b *Broker) storePrefix() kv.Key {
+	if len(b.StorePrefix) > 0 {
+		return b.StorePrefix
+	}
+	return kv.Key{"mqtt0"}
+}
+
+// storeKey returns the key of a session or retained message. Client IDs and
+// topics may contain the kv separator, so they are encoded.
+func (b *Broker) storeKey(kind, name string) kv.Key {
+	prefix := b.storePrefix()
+	key := make(kv.Key, 0, len(prefix)+2)
+	key = append(key, prefix...)
+	return append(key, kind, base64.RawURLEncoding.EncodeToString([]byte(name)))
+}
+
+// loadRetained loads the persisted retained messages.
+// Must be called while holding b.mu lock.
+func (b *Broker) loadRetained() {
+	b.retained = make(map[string]*Message)
+	if b.Store == nil {
+		return
+	}
+	prefix := append(slices.Clone(b.storePrefix()), "retained")
+	for entry, err := range b.Store.List(context.Background(), prefix) {
+		if err != nil {
+			slog.Warn("mqtt0: load retained messages failed", "error", err)
+			return
+		}
+		var r storedRetained
+		if err := json.Unmarshal(entry.Value, &r); err != nil {
+			slog.Warn("mqtt0: invalid retained message", "key", entry.Key.String(), "error", err)
+			continue
+		}
+		b.retained[r.Topic] = &Message{Topic: r.Topic, Payload: r.Payload, Retain: true}
+	}
+}
+
+// retain updates the retained message of msg.Topic: a message with an empty
+// payload clears it, any other replaces it.
+func (b *Broker) retain(msg *Message) {
+	b.mu.Lock()
+	if len(msg.Payload) == 0 {
+		delete(b.retained, msg.Topic)
+	} else {
+		b.retained[msg.Topic] = &Message{Topic: msg.Topic, Payload: msg.Payload, Retain: true}
+	}
+	b.mu.Unlock()
+
+	if b.Store == nil {
+		return
+	}
+	ctx := context.Background()
+	key := b.storeKey("retained", msg.Topic)
+	var err error
+	if len(msg.Payload) == 0 {
+		err = b.Store.Delete(ctx, key)
+	} else {
+		var data []byte
+		data, err = json.Marshal(storedRetained{Topic: msg.Topic, Payload: msg.Payload})
+		if err == nil {
+			err = b.Store.Set(ctx, key, data)
+		}
+	}
+	if err != nil {
+		slog.Warn("mqtt0: persist retained message failed", "topic", msg.Topic, "error", err)
+	}
+}
+
+// sendRetained sends the retained messages matching a new subscription.
+// Shared subscriptions receive no retained messages.
+func (b *Broker) sendRetained(handle *clientHandle, filter string) {
+	if _, _, ok := ParseSharedTopic(filter); ok {
+		return
+	}
+	b.mu.Lock()
+	var msgs []*Message
+	for topic, msg := range b.retained {
+		if TopicMatches(filter, topic) {
+			msgs = append(msgs, msg)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, msg := range msgs {
+		select {
+		case handle.msgCh <- msg:
+		default:
+			slog.Debug("mqtt0: retained message dropped (channel full)", "clientID", handle.clientID, "topic", msg.Topic)
+		}
+	}
+}
+
+// Retained returns the retained messages whose topics match filter.
+func (b *Broker) Retained(filter string) []*Message {
+	b.init()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var msgs []*Message
+	for topic, msg := range b.retained {
+		if TopicMatches(filter, topic) {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+// openSession prepares the session of a newly connected client. A client
+// connecting with a clean session discards its persisted session; any other
+// gets its persisted subscriptions back. It reports whether a session was
+// resumed.
+func (b *Broker) openSession(handle *clientHandle, auth Authenticator) bool {
+	if b.Store == nil {
+		return false
+	}
+	ctx := context.Background()
+	key := b.storeKey("session", handle.clientID)
+	if !handle.persistent {
+		if err := b.Store.Delete(ctx, key); err != nil {
+			slog.Warn("mqtt0: delete session failed", "clientID", handle.clientID, "error", err)
+		}
+		return false
+	}
+
+	data, err := b.Store.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, kv.ErrNotFound) {
+			slog.Warn("mqtt0: load session failed", "clientID", handle.clientID, "error", err)
+		}
+		return false
+	}
+	var s storedSession
+	if err := json.Unmarshal(data, &s); err != nil {
+		slog.Warn("mqtt0: invalid session", "clientID", handle.clientID, "error", err)
+		return false
+	}
+	// Stored topics are plain topic filters, which the v4 path subscribes
+	// for either protocol. ACL is checked again, as it may have changed
+	// since the client subscribed.
+	b.handleSubscribeV4(handle.clientID, handle, s.Topics, auth)
+	slog.Debug("mqtt0: session resumed", "clientID", handle.clientID, "topics", len(s.Topics))
+	return true
+}
+
+// saveSession persists the subscriptions of a client with a persistent
+// session.
+func (b *Broker) saveSession(clientID string) {
+	if b.Store == nil {
+		return
+	}
+	b.mu.Lock()
+	handle := b.clients[clientID]
+	if handle == nil || !handle.persistent {
+		b.mu.Unlock()
+		return
+	}
+	s := storedSession{Topics: append([]string(nil), b.clientSubscriptions[clientID]...)}
+	b.mu.Unlock()
+
+	data, err := json.Marshal(s)
+	if err == nil {
+		err = b.Store.Set(context.Background(), b.storeKey("session", clientID), data)
+	}
+	if err != nil {
+		slog.Warn("mqtt0: persist session failed", "clientID", clientID, "error", err)
+	}
+}
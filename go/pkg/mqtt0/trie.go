@@ -8,7 +8,13 @@ import (
 // Trie is a thread-safe trie data structure for MQTT topic pattern matching.
 // It supports MQTT wildcards:
 //   - `+` matches exactly one topic level
-//   - `#` matches any number of remaining topic levels (must be last)
+//   - `#` matches the parent level and any number of remaining levels
+//     (must be last)
+//
+// Lookups walk one node per topic level and do not allocate unless values
+// of several patterns match, so matching cost is independent of the number
+// of patterns; fleets with a topic pair per device keep millions of them.
+// Nodes left without values are pruned on removal.
 type Trie[T any] struct {
 	mu   sync.RWMutex
 	root *trieNode[T]
@@ -28,278 +34,363 @@ func NewTrie[T any]() *Trie[T] {
 	}
 }
 
-// Insert adds a value at the given pattern.
+// Insert adds a value at the given pattern. A "$share/<group>/" or
+// "$queue/" prefix is stripped.
 func (t *Trie[T]) Insert(pattern string, value T) error {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	return t.root.insert(pattern, value)
+	pattern, ok := stripSharePrefix(pattern)
+	if !ok {
+		return ErrInvalidTopic
+	}
+	return t.Update(pattern, func(values *[]T) {
+		*values = append(*values, value)
+	})
 }
 
-// Get returns all values matching the given topic.
+// Get returns the values of all patterns matching the given topic.
+// The returned slice must not be modified.
 func (t *Trie[T]) Get(topic string) []T {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	return t.root.get(topic)
+	var acc matchAcc[T]
+	t.root.collect(topic, true, &acc)
+	return acc.result()
 }
 
-// Match returns the matched pattern and values for the given topic.
+// Match returns the most specific pattern matching the given topic and its
+// values. Exact levels take precedence over `+`, and `+` over `#`.
 func (t *Trie[T]) Match(topic string) (pattern string, values []T, ok bool) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	return t.root.match("", topic)
+	levels, values, ok := t.root.match(topic, true, 0)
+	if !ok {
+		return "", nil, false
+	}
+	return strings.Join(levels, "/"), values, true
+}
+
+// Values returns the values stored at exactly the given pattern, without
+// wildcard matching.
+func (t *Trie[T]) Values(pattern string) []T {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	n := t.root
+	for level, rest, more := nextLevel(pattern); ; level, rest, more = nextLevel(rest) {
+		n = n.child(level)
+		if n == nil {
+			return nil
+		}
+		if !more {
+			return n.values
+		}
+	}
+}
+
+// Walk calls fn for each pattern matched by filter, with the pattern's
+// values, until fn returns false. The filter's wildcards match pattern
+// levels: "device/+/state" visits "device/gear-001/state" and
+// "device/+/state", and "#" visits every pattern. Wildcards in patterns
+// are matched literally.
+func (t *Trie[T]) Walk(filter string, fn func(pattern string, values []T) bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	t.root.walk(nil, filter, fn)
 }
 
 // Remove removes values matching the predicate from the given pattern.
 // Returns true if any value was removed.
 func (t *Trie[T]) Remove(pattern string, predicate func(T) bool) bool {
+	removed := false
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	return t.root.remove(pattern, predicate)
+	t.root.update(pattern, false, func(values *[]T) {
+		kept := make([]T, 0, len(*values))
+		for _, v := range *values {
+			if !predicate(v) {
+				kept = append(kept, v)
+			}
+		}
+		removed = len(kept) < len(*values)
+		*values = kept
+	})
+	return removed
 }
 
 // Update allows modifying values at the given pattern using a callback.
 // The callback receives a pointer to the values slice and can modify it.
 // Returns an error if the pattern is invalid (e.g., # not at end).
 func (t *Trie[T]) Update(pattern string, f func(*[]T)) error {
+	if err := validatePattern(pattern); err != nil {
+		return err
+	}
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	return t.root.update(pattern, f)
+	t.root.update(pattern, true, f)
+	return nil
 }
 
-func (n *trieNode[T]) update(pattern string, f func(*[]T)) error {
-	if pattern == "" {
-		f(&n.values)
-		return nil
+// nextLevel splits the first level off a topic. more reports whether
+// another level follows; empty levels ("a//b", "a/") are levels too.
+func nextLevel(topic string) (level, rest string, more bool) {
+	if i := strings.IndexByte(topic, '/'); i >= 0 {
+		return topic[:i], topic[i+1:], true
 	}
+	return topic, "", false
+}
 
-	var first, rest string
-	idx := strings.Index(pattern, "/")
-	if idx == -1 {
-		first = pattern
-	} else {
-		first = pattern[:idx]
-		rest = pattern[idx+1:]
+func validatePattern(pattern string) error {
+	for level, rest, more := nextLevel(pattern); ; level, rest, more = nextLevel(rest) {
+		if level == "#" && more {
+			return ErrInvalidTopic
+		}
+		if !more {
+			return nil
+		}
 	}
+}
 
-	// Check existing children first
-	if n.children != nil {
-		if child, ok := n.children[first]; ok {
-			return child.update(rest, f)
-		}
+// stripSharePrefix removes a "$share/<group>/" or "$queue/" prefix. It
+// reports false for a shared pattern without a topic.
+func stripSharePrefix(pattern string) (string, bool) {
+	if rest, ok := strings.CutPrefix(pattern, "$share/"); ok {
+		_, topic, ok := strings.Cut(rest, "/")
+		return topic, ok
+	}
+	if rest, ok := strings.CutPrefix(pattern, "$queue/"); ok {
+		return rest, true
 	}
+	return pattern, true
+}
 
-	switch first {
+func (n *trieNode[T]) child(level string) *trieNode[T] {
+	switch level {
 	case "+":
-		if n.matchAny == nil {
-			n.matchAny = &trieNode[T]{}
-		}
-		return n.matchAny.update(rest, f)
+		return n.matchAny
 	case "#":
-		// # must be the last segment
-		if rest != "" {
-			return ErrInvalidTopic
-		}
-		if n.matchAll == nil {
-			n.matchAll = &trieNode[T]{}
-		}
-		f(&n.matchAll.values)
-		return nil
-	default:
-		if n.children == nil {
-			n.children = make(map[string]*trieNode[T])
-		}
-		if n.children[first] == nil {
-			n.children[first] = &trieNode[T]{}
-		}
-		return n.children[first].update(rest, f)
+		return n.matchAll
 	}
+	return n.children[level]
 }
 
-func (n *trieNode[T]) insert(pattern string, value T) error {
-	if pattern == "" {
-		n.values = append(n.values, value)
-		return nil
-	}
+func (n *trieNode[T]) empty() bool {
+	return len(n.values) == 0 && len(n.children) == 0 && n.matchAny == nil && n.matchAll == nil
+}
 
-	var first, rest string
-	idx := strings.Index(pattern, "/")
-	if idx == -1 {
-		first = pattern
-	} else {
-		first = pattern[:idx]
-		rest = pattern[idx+1:]
-
-		// Handle special prefixes
-		switch first {
-		case "$share":
-			// $share/<sharename>/<topic>
-			parts := strings.SplitN(rest, "/", 3)
-			if len(parts) < 2 {
-				return ErrInvalidTopic
-			}
-			first = parts[1]
-			if len(parts) == 3 {
-				rest = parts[2]
-			} else {
-				rest = ""
-			}
-		case "$queue":
-			// $queue/<topic>
-			parts := strings.SplitN(rest, "/", 2)
-			if len(parts) < 1 {
-				return ErrInvalidTopic
-			}
-			first = parts[0]
-			if len(parts) == 2 {
-				rest = parts[1]
-			} else {
-				rest = ""
+// update applies f to the values at pattern, creating nodes if create is
+// set, and prunes nodes left empty.
+func (n *trieNode[T]) update(pattern string, create bool, f func(*[]T)) {
+	level, rest, more := nextLevel(pattern)
+	c := n.child(level)
+	if c == nil {
+		if !create {
+			return
+		}
+		c = &trieNode[T]{}
+		switch level {
+		case "+":
+			n.matchAny = c
+		case "#":
+			n.matchAll = c
+		default:
+			if n.children == nil {
+				n.children = make(map[string]*trieNode[T])
 			}
+			n.children[level] = c
 		}
 	}
 
-	// Check existing children first
-	if n.children != nil {
-		if child, ok := n.children[first]; ok {
-			return child.insert(rest, value)
-		}
+	if more {
+		c.update(rest, create, f)
+	} else {
+		f(&c.values)
 	}
 
-	switch first {
-	case "+":
-		if n.matchAny == nil {
-			n.matchAny = &trieNode[T]{}
+	if c.empty() {
+		switch level {
+		case "+":
+			n.matchAny = nil
+		case "#":
+			n.matchAll = nil
+		default:
+			delete(n.children, level)
 		}
-		return n.matchAny.insert(rest, value)
-	case "#":
-		if rest != "" {
-			return ErrInvalidTopic
-		}
-		if n.matchAll == nil {
-			n.matchAll = &trieNode[T]{}
-		}
-		n.matchAll.values = append(n.matchAll.values, value)
-		return nil
+	}
+}
+
+// matchAcc accumulates matched values, allocating only when values of more
+// than one pattern match.
+type matchAcc[T any] struct {
+	first  []T
+	merged []T
+	n      int
+}
+
+func (a *matchAcc[T]) add(values []T) {
+	if len(values) == 0 {
+		return
+	}
+	switch a.n {
+	case 0:
+		a.first = values
+	case 1:
+		a.merged = make([]T, 0, len(a.first)+len(values))
+		a.merged = append(a.merged, a.first...)
+		a.merged = append(a.merged, values...)
 	default:
-		if n.children == nil {
-			n.children = make(map[string]*trieNode[T])
-		}
-		child := &trieNode[T]{}
-		n.children[first] = child
-		return child.insert(rest, value)
+		a.merged = append(a.merged, values...)
 	}
+	a.n++
 }
 
-func (n *trieNode[T]) get(topic string) []T {
-	_, values, _ := n.match("", topic)
-	return values
+func (a *matchAcc[T]) result() []T {
+	if a.n <= 1 {
+		return a.first
+	}
+	return a.merged
 }
 
-func (n *trieNode[T]) match(matched, topic string) (string, []T, bool) {
-	if topic == "" {
-		if len(n.values) > 0 {
-			return matched, n.values, true
-		}
-		return "", nil, false
+// collect adds the values of every pattern below n matching topic.
+func (n *trieNode[T]) collect(topic string, root bool, acc *matchAcc[T]) {
+	level, rest, more := nextLevel(topic)
+
+	// MQTT spec: $ topics only match explicit $ patterns, not wildcards at
+	// the root level.
+	wild := !(root && strings.HasPrefix(level, "$"))
+
+	if n.matchAll != nil && wild {
+		acc.add(n.matchAll.values)
+	}
+	if c := n.children[level]; c != nil {
+		c.leaf(rest, more, acc)
+	}
+	if n.matchAny != nil && wild {
+		n.matchAny.leaf(rest, more, acc)
 	}
+}
 
-	var first, rest string
-	idx := strings.Index(topic, "/")
-	if idx == -1 {
-		first = topic
-	} else {
-		first = topic[:idx]
-		rest = topic[idx+1:]
+// leaf continues collect at a node reached by one topic level.
+func (n *trieNode[T]) leaf(rest string, more bool, acc *matchAcc[T]) {
+	if more {
+		n.collect(rest, false, acc)
+		return
+	}
+	acc.add(n.values)
+	// "a/#" also matches "a".
+	if n.matchAll != nil {
+		acc.add(n.matchAll.values)
 	}
+}
 
-	// MQTT spec: $ topics should only match explicit $ patterns, not wildcards at root level
-	isDollarTopic := len(first) > 0 && first[0] == '$'
-	atRootLevel := matched == ""
+// match finds the most specific pattern matching topic. The levels of the
+// pattern are built on the way back up, so only a match allocates.
+func (n *trieNode[T]) match(topic string, root bool, depth int) ([]string, []T, bool) {
+	level, rest, more := nextLevel(topic)
+	wild := !(root && strings.HasPrefix(level, "$"))
 
-	// Try exact match first
-	if n.children != nil {
-		if child, ok := n.children[first]; ok {
-			newMatched := joinPath(matched, first)
-			if route, values, ok := child.match(newMatched, rest); ok {
-				return route, values, true
+	try := func(c *trieNode[T], name string) ([]string, []T, bool) {
+		if !more {
+			if len(c.values) > 0 {
+				return prepend(name, depth, nil), c.values, true
+			}
+			if c.matchAll != nil && len(c.matchAll.values) > 0 {
+				return prepend(name, depth, []string{"#"}), c.matchAll.values, true
 			}
+			return nil, nil, false
 		}
+		levels, values, ok := c.match(rest, false, depth+1)
+		if ok {
+			levels[depth] = name
+		}
+		return levels, values, ok
 	}
 
-	// Try single-level wildcard (+)
-	// Skip if this is a $ topic at root level (MQTT spec compliance)
-	if n.matchAny != nil && !(isDollarTopic && atRootLevel) {
-		newMatched := joinPath(matched, "+")
-		if route, values, ok := n.matchAny.match(newMatched, rest); ok {
-			return route, values, true
+	if c := n.children[level]; c != nil {
+		if levels, values, ok := try(c, level); ok {
+			return levels, values, true
 		}
 	}
-
-	// Try multi-level wildcard (#)
-	// Skip if this is a $ topic at root level (MQTT spec compliance)
-	if n.matchAll != nil && !(isDollarTopic && atRootLevel) {
-		newMatched := joinPath(matched, "#")
-		if len(n.matchAll.values) > 0 {
-			return newMatched, n.matchAll.values, true
+	if n.matchAny != nil && wild {
+		if levels, values, ok := try(n.matchAny, "+"); ok {
+			return levels, values, true
 		}
 	}
+	if n.matchAll != nil && wild && len(n.matchAll.values) > 0 {
+		return prepend("#", depth, nil), n.matchAll.values, true
+	}
+	return nil, nil, false
+}
 
-	return "", nil, false
+// prepend returns the levels of a matched pattern of depth+1+len(tail)
+// levels, with name at depth and tail after it; earlier levels are filled
+// in by the callers.
+func prepend(name string, depth int, tail []string) []string {
+	levels := make([]string, depth+1, depth+1+len(tail))
+	levels[depth] = name
+	return append(levels, tail...)
 }
 
-func (n *trieNode[T]) remove(pattern string, predicate func(T) bool) bool {
-	if pattern == "" {
-		before := len(n.values)
-		newValues := make([]T, 0, len(n.values))
-		for _, v := range n.values {
-			if !predicate(v) {
-				newValues = append(newValues, v)
+// walk visits the patterns below n matched by filter; path holds the
+// levels leading to n.
+func (n *trieNode[T]) walk(path []string, filter string, fn func(string, []T) bool) bool {
+	level, rest, more := nextLevel(filter)
+
+	visit := func(name string, c *trieNode[T]) bool {
+		p := append(path, name)
+		if !more {
+			if len(c.values) > 0 && !fn(strings.Join(p, "/"), c.values) {
+				return false
 			}
+			return true
 		}
-		n.values = newValues
-		return len(n.values) < before
+		return c.walk(p, rest, fn)
 	}
 
-	var first, rest string
-	idx := strings.Index(pattern, "/")
-	if idx == -1 {
-		first = pattern
-	} else {
-		first = pattern[:idx]
-		rest = pattern[idx+1:]
-	}
-
-	switch first {
-	case "+":
-		if n.matchAny != nil {
-			return n.matchAny.remove(rest, predicate)
-		}
+	switch level {
 	case "#":
-		if n.matchAll != nil {
-			before := len(n.matchAll.values)
-			newValues := make([]T, 0, len(n.matchAll.values))
-			for _, v := range n.matchAll.values {
-				if !predicate(v) {
-					newValues = append(newValues, v)
-				}
+		// "#" matches the parent level too.
+		if len(path) > 0 && len(n.values) > 0 && !fn(strings.Join(path, "/"), n.values) {
+			return false
+		}
+		return n.walkAll(path, fn)
+	case "+":
+		for name, c := range n.children {
+			if !visit(name, c) {
+				return false
 			}
-			n.matchAll.values = newValues
-			return len(n.matchAll.values) < before
 		}
+		if n.matchAny != nil && !visit("+", n.matchAny) {
+			return false
+		}
+		if n.matchAll != nil && !visit("#", n.matchAll) {
+			return false
+		}
+		return true
 	default:
-		if n.children != nil {
-			if child, ok := n.children[first]; ok {
-				return child.remove(rest, predicate)
-			}
+		if c := n.child(level); c != nil {
+			return visit(level, c)
 		}
+		return true
 	}
-
-	return false
 }
 
-func joinPath(base, segment string) string {
-	if base == "" {
-		return segment
+// walkAll visits every pattern below n.
+func (n *trieNode[T]) walkAll(path []string, fn func(string, []T) bool) bool {
+	visit := func(name string, c *trieNode[T]) bool {
+		p := append(path, name)
+		if len(c.values) > 0 && !fn(strings.Join(p, "/"), c.values) {
+			return false
+		}
+		return c.walkAll(p, fn)
+	}
+	for name, c := range n.children {
+		if !visit(name, c) {
+			return false
+		}
+	}
+	if n.matchAny != nil && !visit("+", n.matchAny) {
+		return false
+	}
+	if n.matchAll != nil && !visit("#", n.matchAll) {
+		return false
 	}
-	return base + "/" + segment
+	return true
 }
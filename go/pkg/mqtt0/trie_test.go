@@ -1,6 +1,8 @@
 package mqtt0
 
 import (
+	"fmt"
+	"slices"
 	"testing"
 )
 
@@ -163,6 +165,115 @@ func TestTrieMatch(t *testing.T) {
 	}
 }
 
+func TestTrieOverlappingPatterns(t *testing.T) {
+	trie := NewTrie[string]()
+
+	trie.Insert("device/gear-001/state", "exact")
+	trie.Insert("device/+/state", "plus")
+	trie.Insert("device/#", "hash")
+	trie.Insert("#", "all")
+
+	values := trie.Get("device/gear-001/state")
+	slices.Sort(values)
+	if want := []string{"all", "exact", "hash", "plus"}; !slices.Equal(values, want) {
+		t.Errorf("Get = %v, want %v", values, want)
+	}
+
+	// "#" also matches the parent level
+	values = trie.Get("device")
+	slices.Sort(values)
+	if want := []string{"all", "hash"}; !slices.Equal(values, want) {
+		t.Errorf("Get(device) = %v, want %v", values, want)
+	}
+
+	// Wildcards at the root don't match $ topics
+	if values := trie.Get("$SYS/broker"); len(values) != 0 {
+		t.Errorf("Get($SYS/broker) = %v, want none", values)
+	}
+}
+
+func TestTrieValues(t *testing.T) {
+	trie := NewTrie[string]()
+	trie.Insert("device/+/state", "plus")
+	trie.Insert("device/gear-001/state", "exact")
+
+	if values := trie.Values("device/+/state"); !slices.Equal(values, []string{"plus"}) {
+		t.Errorf("Values(device/+/state) = %v", values)
+	}
+	if values := trie.Values("device/gear-002/state"); len(values) != 0 {
+		t.Errorf("Values(device/gear-002/state) = %v, want none", values)
+	}
+}
+
+func TestTrieWalk(t *testing.T) {
+	trie := NewTrie[string]()
+	for _, p := range []string{
+		"device",
+		"device/gear-001/state",
+		"device/gear-002/state",
+		"device/+/state",
+		"device/gear-001/events/#",
+		"server/push",
+	} {
+		trie.Insert(p, p)
+	}
+
+	walk := func(filter string) []string {
+		var patterns []string
+		trie.Walk(filter, func(pattern string, values []string) bool {
+			if len(values) != 1 || values[0] != pattern {
+				t.Errorf("Walk(%q): values of %q = %v", filter, pattern, values)
+			}
+			patterns = append(patterns, pattern)
+			return true
+		})
+		slices.Sort(patterns)
+		return patterns
+	}
+
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"device/+/state", []string{"device/+/state", "device/gear-001/state", "device/gear-002/state"}},
+		{"device/gear-001/#", []string{"device/gear-001/events/#", "device/gear-001/state"}},
+		{"device/#", []string{"device", "device/+/state", "device/gear-001/events/#", "device/gear-001/state", "device/gear-002/state"}},
+		{"#", []string{"device", "device/+/state", "device/gear-001/events/#", "device/gear-001/state", "device/gear-002/state", "server/push"}},
+		{"server/push", []string{"server/push"}},
+		{"unknown/+", nil},
+	}
+	for _, tt := range tests {
+		if got := walk(tt.filter); !slices.Equal(got, tt.want) {
+			t.Errorf("Walk(%q) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	// Returning false stops the walk
+	n := 0
+	trie.Walk("#", func(string, []string) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("Walk visited %d patterns after stop, want 1", n)
+	}
+}
+
+func TestTrieRemovePrunes(t *testing.T) {
+	trie := NewTrie[int]()
+	for i := range 100 {
+		trie.Insert(fmt.Sprintf("device/gear-%03d/state", i), i)
+		trie.Insert(fmt.Sprintf("device/gear-%03d/events/#", i), i)
+	}
+	for i := range 100 {
+		trie.Remove(fmt.Sprintf("device/gear-%03d/state", i), func(int) bool { return true })
+		trie.Remove(fmt.Sprintf("device/gear-%03d/events/#", i), func(int) bool { return true })
+	}
+	if !trie.root.empty() {
+		t.Errorf("trie not pruned after removing all values: %d children", len(trie.root.children))
+	}
+}
+
 func TestTrieConcurrency(t *testing.T) {
 	trie := NewTrie[int]()
 
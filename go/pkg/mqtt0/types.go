@@ -40,6 +40,41 @@ type Message struct {
 	Retain bool
 }
 
+// DuplicateClientIDPolicy controls how the Broker handles a CONNECT whose
+// ClientID is already in use by a connected client.
+type DuplicateClientIDPolicy int
+
+const (
+	// DuplicateTakeover disconnects the existing client and lets the new one
+	// take over the ClientID, as required by the MQTT spec. MQTT 5.0 clients
+	// receive DISCONNECT with ReasonSessionTakenOver. This is the default.
+	DuplicateTakeover DuplicateClientIDPolicy = iota
+
+	// DuplicateReject refuses the new connection and keeps the existing one.
+	// The new client receives CONNACK with ConnectIDRejected (v4) or
+	// ReasonClientIDNotValid (v5).
+	DuplicateReject
+
+	// DuplicateSuffix keeps both connections by assigning the new client a
+	// suffixed ClientID ("id~2", "id~3", ...). MQTT 5.0 clients are told the
+	// assigned ID via the Assigned Client Identifier CONNACK property.
+	// Authentication and ACL checks still see the original ClientID.
+	DuplicateSuffix
+)
+
+func (p DuplicateClientIDPolicy) String() string {
+	switch p {
+	case DuplicateTakeover:
+		return "takeover"
+	case DuplicateReject:
+		return "reject"
+	case DuplicateSuffix:
+		return "suffix"
+	default:
+		return "unknown"
+	}
+}
+
 // Authenticator provides authentication and ACL for MQTT clients.
 type Authenticator interface {
 	// Authenticate validates client credentials.
@@ -135,6 +170,7 @@ const (
 	ReasonServerBusy                  ReasonCode = 0x89
 	ReasonBanned                      ReasonCode = 0x8A
 	ReasonBadAuthMethod               ReasonCode = 0x8C
+	ReasonSessionTakenOver            ReasonCode = 0x8E
 	ReasonTopicFilterInvalid          ReasonCode = 0x8F
 	ReasonTopicNameInvalid            ReasonCode = 0x90
 	ReasonPacketIDInUse               ReasonCode = 0x91
@@ -171,6 +207,10 @@ func (r ReasonCode) String() string {
 		return "Not Authorized"
 	case ReasonBadUserNameOrPassword:
 		return "Bad User Name or Password"
+	case ReasonClientIDNotValid:
+		return "Client Identifier Not Valid"
+	case ReasonSessionTakenOver:
+		return "Session Taken Over"
 	default:
 		return "Unknown"
 	}
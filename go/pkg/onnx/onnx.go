@@ -70,6 +70,13 @@ static OrtStatus* ort_create_tensor_float(const OrtApi* api, OrtMemoryInfo* info
         shape, shape_len, ONNX_TENSOR_ELEMENT_DATA_TYPE_FLOAT, out);
 }
 
+// Helper: create tensor with int64 data.
+static OrtStatus* ort_create_tensor_int64(const OrtApi* api, OrtMemoryInfo* info,
+    int64_t* data, size_t data_len, int64_t* shape, size_t shape_len, OrtValue** out) {
+    return api->CreateTensorWithDataAsOrtValue(info, data, data_len * sizeof(int64_t),
+        shape, shape_len, ONNX_TENSOR_ELEMENT_DATA_TYPE_INT64, out);
+}
+
 // Helper: create CPU memory info.
 static OrtStatus* ort_create_cpu_memory_info(const OrtApi* api, OrtMemoryInfo** out) {
     return api->CreateCpuMemoryInfo(OrtArenaAllocator, OrtMemTypeDefault, out);
@@ -326,6 +333,51 @@ func NewTensor(shape []int64, data []float32) (*Tensor, error) {
 	return t, nil
 }
 
+// NewInt64Tensor creates an int64 tensor with the given shape and data.
+// An empty shape creates a scalar. The data slice must remain valid for the
+// lifetime of the Tensor.
+func NewInt64Tensor(shape []int64, data []int64) (*Tensor, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("onnx: empty tensor data")
+	}
+
+	// Validate shape vs data length
+	total := int64(1)
+	for _, d := range shape {
+		total *= d
+	}
+	if int64(len(data)) < total {
+		return nil, fmt.Errorf("onnx: tensor data too short: got %d, need %d", len(data), total)
+	}
+
+	var memInfo *C.OrtMemoryInfo
+	if err := checkStatus(C.ort_create_cpu_memory_info(api(), &memInfo)); err != nil {
+		return nil, err
+	}
+	defer C.ort_release_memory_info(api(), memInfo)
+
+	var cShape *C.int64_t
+	if len(shape) > 0 {
+		cShape = (*C.int64_t)(unsafe.Pointer(&shape[0]))
+	}
+
+	var value *C.OrtValue
+	if err := checkStatus(C.ort_create_tensor_int64(
+		api(), memInfo,
+		(*C.int64_t)(unsafe.Pointer(&data[0])),
+		C.size_t(len(data)),
+		cShape,
+		C.size_t(len(shape)),
+		&value,
+	)); err != nil {
+		return nil, err
+	}
+
+	t := &Tensor{value: value, pinned: data, owned: true}
+	runtime.SetFinalizer(t, (*Tensor).Close)
+	return t, nil
+}
+
 // FloatData copies the tensor data into a new float32 slice.
 func (t *Tensor) FloatData() ([]float32, error) {
 	var ptr *C.float
@@ -157,10 +157,38 @@ func TestSileroVADONNX(t *testing.T) {
 	}
 	defer inputState.Close()
 
-	// Note: full inference requires int64 "sr" tensor which our API
-	// doesn't support yet (only float32). Test model load + session creation.
-	t.Logf("Silero VAD ONNX: session OK, audio=[1,512], state=[2,1,128], model=%d bytes",
-		len(vadSileroData))
+	inputSR, err := NewInt64Tensor(nil, []int64{16000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputSR.Close()
+
+	outputs, err := session.Run(
+		[]string{"input", "state", "sr"},
+		[]*Tensor{inputAudio, inputState, inputSR},
+		[]string{"output", "stateN"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outputs[0].Close()
+	defer outputs[1].Close()
+
+	prob, err := outputs[0].FloatData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prob) != 1 || prob[0] < 0 || prob[0] > 1 {
+		t.Fatalf("speech probability = %v, want one value in [0,1]", prob)
+	}
+	stateN, err := outputs[1].FloatData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stateN) != 2*1*128 {
+		t.Errorf("stateN length = %d, want %d", len(stateN), 2*1*128)
+	}
+	t.Logf("Silero VAD ONNX: prob=%.4f, model=%d bytes", prob[0], len(vadSileroData))
 }
 
 func TestNSNet2ONNX(t *testing.T) {
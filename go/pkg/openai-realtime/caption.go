@@ -0,0 +1,174 @@
+package openairealtime
+
+import "iter"
+
+// TranscriptWord is a timed token of an assistant audio transcript.
+// Some models attach these to response.audio_transcript.delta events;
+// offsets are relative to the start of the item's audio.
+type TranscriptWord struct {
+	Word    string `json:"word"`
+	StartMs int    `json:"start_ms"`
+	EndMs   int    `json:"end_ms"`
+}
+
+// Caption is a piece of assistant speech text aligned to audio playback.
+//
+// StartMs and EndMs are offsets within the audio of the conversation item
+// (ItemID, ContentIndex). A client compares them with its playback position
+// for that item to render karaoke-style captions.
+type Caption struct {
+	ResponseID   string
+	ItemID       string
+	ContentIndex int
+
+	// Text is the caption text. For a final caption it is the full transcript.
+	Text string
+
+	// StartMs is the playback offset at which Text starts.
+	StartMs int
+
+	// EndMs is the playback offset at which Text ends. Zero when unknown
+	// (estimated captions end where the next one starts).
+	EndMs int
+
+	// Estimated reports that timing was derived from the amount of audio
+	// received when the delta arrived, because the API sent no word timing.
+	Estimated bool
+
+	// Final marks the caption emitted on response.audio_transcript.done.
+	// It spans the whole item and can replace all earlier captions.
+	Final bool
+}
+
+// CaptionTracker turns transcript and audio events into a caption stream.
+//
+// Feed every server event to Process in order. When transcript deltas carry
+// word timing, one caption is produced per word. Otherwise each delta
+// becomes one estimated caption starting at the audio offset reached by the
+// item when the delta arrived; the server interleaves transcript and audio
+// deltas closely, so this tracks playback within a few hundred milliseconds.
+//
+// CaptionTracker is not safe for concurrent use.
+type CaptionTracker struct {
+	bytesPerMs int
+	items      map[captionKey]*captionState
+}
+
+type captionKey struct {
+	itemID       string
+	contentIndex int
+}
+
+type captionState struct {
+	audioBytes int
+}
+
+// NewCaptionTracker creates a CaptionTracker for the session's output audio
+// format (AudioFormatPCM16, AudioFormatG711ULaw or AudioFormatG711ALaw).
+// An empty format means AudioFormatPCM16.
+func NewCaptionTracker(outputAudioFormat string) *CaptionTracker {
+	bytesPerMs := 48 // pcm16: 24kHz * 2 bytes
+	switch outputAudioFormat {
+	case AudioFormatG711ULaw, AudioFormatG711ALaw:
+		bytesPerMs = 8 // 8kHz * 1 byte
+	}
+	return &CaptionTracker{
+		bytesPerMs: bytesPerMs,
+		items:      make(map[captionKey]*captionState),
+	}
+}
+
+// Process updates the tracker with event and returns the captions it
+// produced, if any.
+func (t *CaptionTracker) Process(event *ServerEvent) []Caption {
+	key := captionKey{event.ItemID, event.ContentIndex}
+
+	switch event.Type {
+	case EventTypeResponseAudioDelta:
+		t.state(key).audioBytes += len(event.Audio)
+
+	case EventTypeResponseAudioTranscriptDelta:
+		if len(event.Words) > 0 {
+			captions := make([]Caption, len(event.Words))
+			for i, w := range event.Words {
+				captions[i] = t.caption(event, w.Word)
+				captions[i].StartMs = w.StartMs
+				captions[i].EndMs = w.EndMs
+			}
+			return captions
+		}
+		if event.Delta == "" {
+			return nil
+		}
+		c := t.caption(event, event.Delta)
+		c.StartMs = t.state(key).audioBytes / t.bytesPerMs
+		c.Estimated = true
+		return []Caption{c}
+
+	case EventTypeResponseAudioTranscriptDone:
+		c := t.caption(event, event.Transcript)
+		c.EndMs = t.state(key).audioBytes / t.bytesPerMs
+		c.Final = true
+		delete(t.items, key)
+		return []Caption{c}
+
+	case EventTypeConversationItemTruncated:
+		// The item's audio was cut at AudioEndMs; later offsets never play.
+		if s, ok := t.items[key]; ok {
+			s.audioBytes = min(s.audioBytes, event.AudioEndMs*t.bytesPerMs)
+		}
+	}
+	return nil
+}
+
+// Reset discards all per-item state.
+func (t *CaptionTracker) Reset() {
+	clear(t.items)
+}
+
+func (t *CaptionTracker) state(key captionKey) *captionState {
+	s, ok := t.items[key]
+	if !ok {
+		s = &captionState{}
+		t.items[key] = s
+	}
+	return s
+}
+
+func (t *CaptionTracker) caption(event *ServerEvent, text string) Caption {
+	return Caption{
+		ResponseID:   event.ResponseID,
+		ItemID:       event.ItemID,
+		ContentIndex: event.ContentIndex,
+		Text:         text,
+	}
+}
+
+// Captions returns an iterator over the captions derived from events.
+// It consumes events; callers that also need the raw events should run a
+// CaptionTracker in their own event loop instead.
+//
+// Example:
+//
+//	for caption, err := range openairealtime.Captions(session.Events(), openairealtime.AudioFormatPCM16) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    ui.ShowCaption(caption.ItemID, caption.StartMs, caption.Text)
+//	}
+func Captions(events iter.Seq2[*ServerEvent, error], outputAudioFormat string) iter.Seq2[Caption, error] {
+	return func(yield func(Caption, error) bool) {
+		t := NewCaptionTracker(outputAudioFormat)
+		for event, err := range events {
+			if err != nil {
+				yield(Caption{}, err)
+				return
+			}
+			for _, c := range t.Process(event) {
+				if !yield(c, nil) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package openairealtime
+
+import (
+	"slices"
+	"sync"
+)
+
+// Item types.
+const (
+	ItemTypeMessage            = "message"
+	ItemTypeFunctionCall       = "function_call"
+	ItemTypeFunctionCallOutput = "function_call_output"
+
+	// MCP items, for tools of type ToolTypeMCP.
+	ItemTypeMCPListTools        = "mcp_list_tools"
+	ItemTypeMCPCall             = "mcp_call"
+	ItemTypeMCPApprovalRequest  = "mcp_approval_request"
+	ItemTypeMCPApprovalResponse = "mcp_approval_response"
+)
+
+// Item roles.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleSystem    = "system"
+)
+
+// PreviousItemRoot passed to CreateItem inserts the item at the start of
+// the conversation.
+const PreviousItemRoot = "root"
+
+// conversation mirrors the server-side conversation from server events, so
+// sessions can list items without a server round trip.
+type conversation struct {
+	mu    sync.Mutex
+	items []ConversationItem
+}
+
+// apply updates the conversation with a server event.
+func (c *conversation) apply(event *ServerEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch event.Type {
+	case EventTypeConversationItemCreated:
+		if event.Item == nil {
+			return
+		}
+		item := cloneItem(event.Item)
+		switch i := c.index(event.PreviousItemID); {
+		case event.PreviousItemID == "":
+			c.items = slices.Insert(c.items, 0, item)
+		case i < 0:
+			c.items = append(c.items, item)
+		default:
+			c.items = slices.Insert(c.items, i+1, item)
+		}
+
+	case EventTypeResponseOutputItemDone:
+		// The created event carries an empty item; take the final content.
+		if event.Item == nil {
+			return
+		}
+		if i := c.index(event.Item.ID); i >= 0 {
+			c.items[i] = cloneItem(event.Item)
+		}
+
+	case EventTypeConversationItemInputAudioTranscriptionCompleted:
+		if i := c.index(event.ItemID); i >= 0 && event.ContentIndex < len(c.items[i].Content) {
+			c.items[i].Content[event.ContentIndex].Transcript = event.Transcript
+		}
+
+	case EventTypeConversationItemTruncated:
+		// Truncation deletes the server-side transcript of the audio.
+		if i := c.index(event.ItemID); i >= 0 && event.ContentIndex < len(c.items[i].Content) {
+			c.items[i].Content[event.ContentIndex].Transcript = ""
+		}
+
+	case EventTypeConversationItemDeleted:
+		if i := c.index(event.ItemID); i >= 0 {
+			c.items = slices.Delete(c.items, i, i+1)
+		}
+	}
+}
+
+// list returns a copy of the items in conversation order.
+func (c *conversation) list() []ConversationItem {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	items := make([]ConversationItem, len(c.items))
+	for i := range c.items {
+		items[i] = cloneItem(&c.items[i])
+	}
+	return items
+}
+
+func (c *conversation) index(id string) int {
+	if id == "" {
+		return -1
+	}
+	return slices.IndexFunc(c.items, func(item ConversationItem) bool {
+		return item.ID == id
+	})
+}
+
+func cloneItem(item *ConversationItem) ConversationItem {
+	cp := *item
+	cp.Content = slices.Clone(item.Content)
+	return cp
+}
+
+// createItemEvent builds a conversation.item.create event.
+// mcpApprovalResponse returns the item answering an mcp_approval_request.
+func mcpApprovalResponse(approvalRequestID string, approve bool) *ConversationItem {
+	return &ConversationItem{
+		Type:              ItemTypeMCPApprovalResponse,
+		ApprovalRequestID: approvalRequestID,
+		Approve:           &approve,
+	}
+}
+
+func createItemEvent(item *ConversationItem, previousItemID string) map[string]interface{} {
+	event := map[string]interface{}{
+		"event_id": generateEventID(),
+		"type":     EventTypeConversationItemCreate,
+		"item":     item,
+	}
+	if previousItemID != "" {
+		event["previous_item_id"] = previousItemID
+	}
+	return event
+}
@@ -29,6 +29,40 @@
 //	}
 //	defer session.Close()
 //
+// Behind strict NATs, configure a TURN server and force relay candidates;
+// Stats reports RTT, packet loss and jitter of the connection:
+//
+//	session, err := client.ConnectWebRTC(ctx, &openairealtime.ConnectConfig{
+//	    ICEServers: []openairealtime.ICEServer{{
+//	        URLs:       []string{"turn:turn.example.com:3478"},
+//	        Username:   "user",
+//	        Credential: "secret",
+//	    }},
+//	    ForceRelay: true,
+//	})
+//	...
+//	stats := session.Stats()
+//	slog.Info("webrtc", "rtt", stats.RoundTripTime, "loss", stats.PacketLoss())
+//
+// # Session Pool
+//
+// For servers handling bursty device traffic, SessionPool keeps configured
+// sessions connected ahead of time and caps concurrent sessions, queuing
+// Acquire calls beyond the cap:
+//
+//	pool := openairealtime.NewSessionPool(client, &openairealtime.SessionPoolConfig{
+//	    Session:   &openairealtime.SessionConfig{Voice: openairealtime.VoiceAlloy},
+//	    Warm:      4,
+//	    MaxActive: 32,
+//	})
+//	defer pool.Close()
+//
+//	session, err := pool.Acquire(ctx)
+//	if err != nil {
+//	    return err
+//	}
+//	defer session.Close() // frees the slot
+//
 // # Session Configuration
 //
 // After connecting, configure the session:
@@ -41,6 +75,19 @@
 //	    },
 //	})
 //
+// For Chinese-first devices, fix the transcription language instead of
+// relying on auto-detection, and enable noise reduction for the microphone:
+//
+//	err = session.UpdateSession(&openairealtime.SessionConfig{
+//	    InputAudioTranscription: &openairealtime.TranscriptionConfig{
+//	        Model:    "whisper-1",
+//	        Language: "zh",
+//	    },
+//	    InputAudioNoiseReduction: &openairealtime.NoiseReduction{
+//	        Type: openairealtime.NoiseReductionNearField,
+//	    },
+//	})
+//
 // # Sending Audio
 //
 // Send audio data to the input buffer:
@@ -48,6 +95,23 @@
 //	// PCM 16-bit, 24kHz, mono
 //	err = session.AppendAudio(pcmData)
 //
+// With ConnectConfig.PaceAudio, AppendAudio takes audio of any size and the
+// session sends it in 20ms frames at real-time pace, so a file or a jittery
+// network source reaches the server VAD like a live microphone:
+//
+//	session, _ := client.ConnectWebSocket(ctx, &openairealtime.ConnectConfig{PaceAudio: true})
+//	err = session.AppendAudio(wholeRecording) // returns at once
+//
+// # Telephony (G.711)
+//
+// For 8kHz telephony audio, set ConnectConfig.AudioFormat to
+// AudioFormatG711ULaw or AudioFormatG711ALaw; the API then takes and
+// returns G.711 directly. EncodeAudio and DecodeAudio convert to and from
+// 8kHz PCM16:
+//
+//	ulaw, _ := openairealtime.EncodeAudio(openairealtime.AudioFormatG711ULaw, pcm8k)
+//	err = session.AppendAudio(ulaw)
+//
 // # Receiving Events
 //
 // Use the Events iterator to receive server events:
@@ -63,4 +127,163 @@
 //	        fmt.Print(event.Delta)
 //	    }
 //	}
+//
+// Typed returns a struct holding only the fields of the event's type, for a
+// type switch instead (As[T] extracts one type):
+//
+//	switch ev := event.Typed().(type) {
+//	case *openairealtime.ResponseAudioDeltaEvent:
+//	    playAudio(ev.Audio)
+//	case *openairealtime.InputSpeechStartedEvent:
+//	    stopPlayback()
+//	}
+//
+// # Playback
+//
+// Player paces response audio at wall-clock rate and fills underruns with
+// silence, so its output can go straight to a speaker. It drops buffered
+// audio when the user barges in; Interrupt does the same for client-side
+// cancellation and returns the position to truncate the item at:
+//
+//	player := openairealtime.NewPlayer(openairealtime.AudioFormatPCM16)
+//	go io.Copy(speaker, player)
+//	for event, err := range session.Events() {
+//	    ...
+//	    player.Process(event)
+//	}
+//	...
+//	session.CancelResponse()
+//	pos := player.Interrupt()
+//	session.TruncateItem(pos.ItemID, pos.ContentIndex, pos.Ms)
+//
+// # Conversation History
+//
+// Sessions track the server-side conversation from events. ListItems
+// returns it; CreateItem, DeleteItem and TruncateItem edit it, e.g. to trim
+// old turns or inject a system message mid-session:
+//
+//	for _, item := range session.ListItems()[:n] {
+//	    session.DeleteItem(item.ID)
+//	}
+//	session.CreateItem(&openairealtime.ConversationItem{
+//	    Type:    openairealtime.ItemTypeMessage,
+//	    Role:    openairealtime.RoleSystem,
+//	    Content: []openairealtime.ContentPart{{Type: "input_text", Text: "Be brief."}},
+//	}, "")
+//
+// # Per-Response Overrides
+//
+// Modalities, Voice, Temperature and MaxOutputTokens in
+// ResponseCreateOptions apply to one response only, so a session can mix
+// text-only background answers with spoken ones without UpdateSession:
+//
+//	maxTokens := 64
+//	err = session.CreateResponse(&openairealtime.ResponseCreateOptions{
+//	    Modalities:      []string{openairealtime.ModalityText},
+//	    MaxOutputTokens: &maxTokens,
+//	})
+//
+// # Out-of-Band Responses
+//
+// A response created with Conversation set to ConversationNone is not added
+// to the conversation, so one session can run side tasks such as
+// classification while the main audio conversation continues. Metadata
+// identifies the response's events:
+//
+//	err = session.CreateResponse(&openairealtime.ResponseCreateOptions{
+//	    Conversation: openairealtime.ConversationNone,
+//	    Modalities:   []string{"text"},
+//	    Instructions: "Classify the user's mood in one word.",
+//	    Metadata:     map[string]string{"task": "mood"},
+//	})
+//	...
+//	if event.Type == openairealtime.EventTypeResponseDone && event.ResponseMetadata["task"] == "mood" {
+//	    handleMood(event.Response.Output)
+//	}
+//
+// # Usage and Rate Limits
+//
+// Sessions add up the token usage of every response and keep the latest
+// rate limits, so a server can enforce per-device budgets:
+//
+//	if session.Usage().TotalTokens > budget {
+//	    session.Close()
+//	}
+//	limits := session.RateLimits()
+//	if limits.Tokens.Remaining < 1000 {
+//	    wait(time.Until(limits.ResetAt(limits.Tokens)))
+//	}
+//
+// # Graceful Shutdown
+//
+// Shutdown lets the responses in progress finish before closing, so a
+// server restart does not cut the assistant off mid-sentence. Input sent
+// meanwhile fails with ErrShuttingDown; responses still running when ctx
+// is done are cancelled. Keep consuming Events while it runs:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	usage, err := session.Shutdown(ctx)
+//	log.Printf("session used %d tokens", usage.TotalTokens)
+//
+// # Function Calling
+//
+// Register tools in SessionConfig.Tools. FunctionCallTracker assembles the
+// streamed arguments of each call; execute the function when the call is
+// done and return its result with SubmitToolOutput:
+//
+//	calls := openairealtime.NewFunctionCallTracker()
+//	for event, err := range session.Events() {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    if call := calls.Process(event); call != nil && call.Done {
+//	        result := runTool(call.Name, call.Arguments)
+//	        if err := session.SubmitToolOutput(call.CallID, result); err != nil {
+//	            return err
+//	        }
+//	    }
+//	}
+//
+// # MCP Tools
+//
+// Tools of type ToolTypeMCP connect the session to a remote MCP server; the
+// API lists the server's tools and calls them itself, so there is no output
+// to submit. Typed events report the lifecycle of the listing and of each
+// call, and calls that require approval wait for AddMCPApprovalResponse:
+//
+//	tool := openairealtime.MCPTool("docs", "https://mcp.example.com/sse")
+//	tool.RequireApproval = openairealtime.MCPApprovalNever
+//	session.UpdateSession(&openairealtime.SessionConfig{Tools: []openairealtime.Tool{tool}})
+//
+//	for event, err := range session.Events() {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    switch ev := event.Typed().(type) {
+//	    case *openairealtime.ResponseMCPCallEvent:
+//	        log.Printf("mcp call %s: %s", ev.ItemID, ev.Status)
+//	    case *openairealtime.ResponseOutputItemEvent:
+//	        if ev.Item.Type == openairealtime.ItemTypeMCPApprovalRequest {
+//	            session.AddMCPApprovalResponse(ev.Item.ID, allowed(ev.Item.Name))
+//	        }
+//	    }
+//	}
+//
+// # Captions
+//
+// CaptionTracker aligns assistant transcript deltas with the audio of the
+// same item, producing captions with playback offsets for karaoke-style
+// rendering. Word timing is used when the API provides it; otherwise offsets
+// are estimated from the audio received so far:
+//
+//	tracker := openairealtime.NewCaptionTracker(openairealtime.AudioFormatPCM16)
+//	for event, err := range session.Events() {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    for _, c := range tracker.Process(event) {
+//	        showCaption(c.ItemID, c.StartMs, c.Text)
+//	    }
+//	}
 package openairealtime
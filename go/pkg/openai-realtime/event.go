@@ -32,6 +32,7 @@ const (
 	// Conversation events
 	EventTypeConversationCreated                              = "conversation.created"
 	EventTypeConversationItemCreated                          = "conversation.item.created"
+	EventTypeConversationItemInputAudioTranscriptionDelta     = "conversation.item.input_audio_transcription.delta"
 	EventTypeConversationItemInputAudioTranscriptionCompleted = "conversation.item.input_audio_transcription.completed"
 	EventTypeConversationItemInputAudioTranscriptionFailed    = "conversation.item.input_audio_transcription.failed"
 	EventTypeConversationItemTruncated                        = "conversation.item.truncated"
@@ -67,6 +68,18 @@ const (
 	EventTypeResponseFunctionCallArgumentsDelta = "response.function_call_arguments.delta"
 	EventTypeResponseFunctionCallArgumentsDone  = "response.function_call_arguments.done"
 
+	// Response MCP call events
+	EventTypeResponseMCPCallArgumentsDelta = "response.mcp_call_arguments.delta"
+	EventTypeResponseMCPCallArgumentsDone  = "response.mcp_call_arguments.done"
+	EventTypeResponseMCPCallInProgress     = "response.mcp_call.in_progress"
+	EventTypeResponseMCPCallCompleted      = "response.mcp_call.completed"
+	EventTypeResponseMCPCallFailed         = "response.mcp_call.failed"
+
+	// MCP tool listing events
+	EventTypeMCPListToolsInProgress = "mcp_list_tools.in_progress"
+	EventTypeMCPListToolsCompleted  = "mcp_list_tools.completed"
+	EventTypeMCPListToolsFailed     = "mcp_list_tools.failed"
+
 	// Rate limits event
 	EventTypeRateLimitsUpdated = "rate_limits.updated"
 )
@@ -136,6 +149,13 @@ type ServerEvent struct {
 	// Delta contains incremental text/arguments (for *.delta events).
 	Delta string `json:"delta,omitzero"`
 
+	// Text is the complete text (for response.text.done).
+	Text string `json:"text,omitzero"`
+
+	// Words contains word timing for response.audio_transcript.delta, when
+	// the model provides it. See [CaptionTracker].
+	Words []TranscriptWord `json:"words,omitzero"`
+
 	// Audio contains decoded audio data (populated after parsing).
 	Audio []byte `json:"-"`
 
@@ -151,9 +171,15 @@ type ServerEvent struct {
 	// Name is the function name.
 	Name string `json:"name,omitzero"`
 
-	// Arguments is the function arguments (complete, for done event).
+	// Arguments is the function or MCP call arguments (complete, for done
+	// events).
 	Arguments string `json:"arguments,omitzero"`
 
+	// ResponseMetadata is the metadata the response was created with (see
+	// ResponseCreateOptions.Metadata). The session sets it on all events of
+	// that response, so out-of-band responses can be told apart.
+	ResponseMetadata map[string]string `json:"-"`
+
 	// === Rate limits event ===
 
 	// RateLimits contains rate limit information.
@@ -0,0 +1,116 @@
+package openairealtime
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// FunctionCallEvent is a typed view of function call argument streaming.
+//
+// The API announces a call with response.output_item.added (carrying the
+// name and call ID), streams the JSON arguments with
+// response.function_call_arguments.delta and ends with
+// response.function_call_arguments.done. FunctionCallTracker joins these
+// into one event type.
+type FunctionCallEvent struct {
+	ResponseID  string
+	ItemID      string
+	OutputIndex int
+
+	// CallID identifies the call; pass it to SubmitToolOutput.
+	CallID string
+
+	// Name is the function name.
+	Name string
+
+	// Delta is the arguments fragment of this event. Empty when Done.
+	Delta string
+
+	// Arguments is the JSON arguments received so far. Complete when Done.
+	Arguments string
+
+	// Done marks the end of the arguments; the call can be executed.
+	Done bool
+}
+
+// UnmarshalArguments decodes the arguments JSON into v.
+func (e *FunctionCallEvent) UnmarshalArguments(v any) error {
+	if err := json.Unmarshal([]byte(e.Arguments), v); err != nil {
+		return fmt.Errorf("function %s arguments: %w", e.Name, err)
+	}
+	return nil
+}
+
+// FunctionCallTracker turns server events into FunctionCallEvents.
+//
+// Feed every server event to Process in order. It returns an event for
+// each arguments delta and one with Done set when the call is complete.
+//
+// FunctionCallTracker is not safe for concurrent use.
+type FunctionCallTracker struct {
+	calls map[string]*FunctionCallEvent // by item ID
+}
+
+// NewFunctionCallTracker creates a FunctionCallTracker.
+func NewFunctionCallTracker() *FunctionCallTracker {
+	return &FunctionCallTracker{calls: make(map[string]*FunctionCallEvent)}
+}
+
+// Process updates the tracker with event and returns the function call
+// event it produced, or nil.
+func (t *FunctionCallTracker) Process(event *ServerEvent) *FunctionCallEvent {
+	switch event.Type {
+	case EventTypeResponseOutputItemAdded:
+		if event.Item == nil || event.Item.Type != ItemTypeFunctionCall {
+			return nil
+		}
+		t.calls[event.Item.ID] = &FunctionCallEvent{
+			ResponseID:  event.ResponseID,
+			ItemID:      event.Item.ID,
+			OutputIndex: event.OutputIndex,
+			CallID:      event.Item.CallID,
+			Name:        event.Item.Name,
+		}
+
+	case EventTypeResponseFunctionCallArgumentsDelta:
+		call := t.call(event)
+		call.Arguments += event.Delta
+		ev := *call
+		ev.Delta = event.Delta
+		return &ev
+
+	case EventTypeResponseFunctionCallArgumentsDone:
+		call := t.call(event)
+		delete(t.calls, event.ItemID)
+		if event.Arguments != "" {
+			call.Arguments = event.Arguments
+		}
+		if event.Name != "" {
+			call.Name = event.Name
+		}
+		call.Done = true
+		return call
+	}
+	return nil
+}
+
+// Reset discards all pending calls.
+func (t *FunctionCallTracker) Reset() {
+	clear(t.calls)
+}
+
+func (t *FunctionCallTracker) call(event *ServerEvent) *FunctionCallEvent {
+	call, ok := t.calls[event.ItemID]
+	if !ok {
+		call = &FunctionCallEvent{
+			ResponseID:  event.ResponseID,
+			ItemID:      event.ItemID,
+			OutputIndex: event.OutputIndex,
+		}
+		t.calls[event.ItemID] = call
+	}
+	if event.CallID != "" {
+		call.CallID = event.CallID
+	}
+	return call
+}
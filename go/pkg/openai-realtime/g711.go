@@ -0,0 +1,157 @@
+package openairealtime
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// G.711 transcoding for telephony integrations. The Realtime API accepts
+// and produces G.711 μ-law and A-law at 8kHz natively (AudioFormatG711ULaw,
+// AudioFormatG711ALaw); these helpers convert between that and 16-bit PCM
+// at 8kHz, so a SIP bridge can feed the session without external codecs
+// or resamplers.
+
+// AudioSampleRate returns the sample rate of an audio format: 24000 for
+// pcm16 (and ""), 8000 for the G.711 formats, 0 if unknown.
+func AudioSampleRate(format string) int {
+	switch format {
+	case "", AudioFormatPCM16:
+		return 24000
+	case AudioFormatG711ULaw, AudioFormatG711ALaw:
+		return 8000
+	}
+	return 0
+}
+
+// EncodeAudio converts 16-bit little-endian PCM to format. The PCM must be
+// at the format's sample rate (see AudioSampleRate). For pcm16 the input is
+// returned unchanged.
+func EncodeAudio(format string, pcm []byte) ([]byte, error) {
+	switch format {
+	case "", AudioFormatPCM16:
+		return pcm, nil
+	case AudioFormatG711ULaw:
+		return encodeG711(pcm, ulawEncode), nil
+	case AudioFormatG711ALaw:
+		return encodeG711(pcm, alawEncode), nil
+	}
+	return nil, fmt.Errorf("openai-realtime: unsupported audio format %q", format)
+}
+
+// DecodeAudio converts audio in format to 16-bit little-endian PCM at the
+// format's sample rate. For pcm16 the input is returned unchanged.
+//
+// Example, for a session with OutputAudioFormat g711_ulaw:
+//
+//	case openairealtime.EventTypeResponseAudioDelta:
+//	    pcm, _ := openairealtime.DecodeAudio(openairealtime.AudioFormatG711ULaw, event.Audio)
+//	    play8kHz(pcm)
+func DecodeAudio(format string, data []byte) ([]byte, error) {
+	switch format {
+	case "", AudioFormatPCM16:
+		return data, nil
+	case AudioFormatG711ULaw:
+		return decodeG711(data, &ulawTable), nil
+	case AudioFormatG711ALaw:
+		return decodeG711(data, &alawTable), nil
+	}
+	return nil, fmt.Errorf("openai-realtime: unsupported audio format %q", format)
+}
+
+func encodeG711(pcm []byte, encode func(int16) byte) []byte {
+	out := make([]byte, len(pcm)/2)
+	for i := range out {
+		out[i] = encode(int16(binary.LittleEndian.Uint16(pcm[i*2:])))
+	}
+	return out
+}
+
+func decodeG711(data []byte, table *[256]int16) []byte {
+	out := make([]byte, len(data)*2)
+	for i, b := range data {
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(table[b]))
+	}
+	return out
+}
+
+var ulawTable, alawTable [256]int16
+
+func init() {
+	for i := range 256 {
+		ulawTable[i] = ulawDecode(byte(i))
+		alawTable[i] = alawDecode(byte(i))
+	}
+}
+
+const (
+	ulawBias = 0x84
+	ulawClip = 32635
+)
+
+func ulawEncode(sample int16) byte {
+	s := int(sample)
+	var sign int
+	if s < 0 {
+		sign = 0x80
+		s = -s - 1 // one's complement, as in ITU-T G.191
+	}
+	s = min(s, ulawClip) + ulawBias
+
+	exponent := 7
+	for mask := 0x4000; s&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
+	}
+	mantissa := (s >> (exponent + 3)) & 0x0F
+	return ^byte(sign | exponent<<4 | mantissa)
+}
+
+func ulawDecode(u byte) int16 {
+	u = ^u
+	t := (int(u&0x0F)<<3 + ulawBias) << ((u & 0x70) >> 4)
+	if u&0x80 != 0 {
+		return int16(ulawBias - t)
+	}
+	return int16(t - ulawBias)
+}
+
+func alawEncode(sample int16) byte {
+	s := int(sample) >> 3 // 13-bit
+	mask := 0xD5
+	if s < 0 {
+		mask = 0x55
+		s = -s - 1
+	}
+
+	seg := 0
+	for end := 0x1F; seg < 8 && s > end; end = end<<1 | 1 {
+		seg++
+	}
+	if seg >= 8 {
+		return byte(0x7F ^ mask)
+	}
+	aval := seg << 4
+	if seg < 2 {
+		aval |= (s >> 1) & 0x0F
+	} else {
+		aval |= (s >> seg) & 0x0F
+	}
+	return byte(aval ^ mask)
+}
+
+func alawDecode(a byte) int16 {
+	a ^= 0x55
+	t := int(a&0x0F) << 4
+	switch seg := (a & 0x70) >> 4; seg {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= seg - 1
+	}
+	if a&0x80 != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
@@ -0,0 +1,151 @@
+package openairealtime
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// Reference values, as produced by the G.711 implementation of the ITU-T
+// Software Tool Library (G.191).
+func TestG711_Reference(t *testing.T) {
+	encode := []struct {
+		pcm        int16
+		ulaw, alaw byte
+	}{
+		{0, 0xFF, 0xD5},
+		{-1, 0x7F, 0x55},
+		{8, 0xFE, 0xD5},
+		{100, 0xF2, 0xD3},
+		{-100, 0x73, 0x53},
+		{1000, 0xCE, 0xFA},
+		{-1000, 0x4E, 0x7A},
+		{10000, 0x9C, 0xB6},
+		{-31612, 0x01, 0x2B},
+		{32767, 0x80, 0xAA},
+		{-32768, 0x00, 0x2A},
+	}
+	for _, tt := range encode {
+		if got := ulawEncode(tt.pcm); got != tt.ulaw {
+			t.Errorf("μ-law encode %d = %#02x, want %#02x", tt.pcm, got, tt.ulaw)
+		}
+		if got := alawEncode(tt.pcm); got != tt.alaw {
+			t.Errorf("A-law encode %d = %#02x, want %#02x", tt.pcm, got, tt.alaw)
+		}
+	}
+
+	ulaw := []struct {
+		code byte
+		pcm  int16
+	}{
+		{0x00, -32124},
+		{0x7F, 0},
+		{0x80, 32124},
+		{0xFF, 0},
+		{0xF2, 104},
+		{0x72, -104},
+		{0x9C, 9852},
+	}
+	for _, tt := range ulaw {
+		if got := ulawDecode(tt.code); got != tt.pcm {
+			t.Errorf("μ-law decode %#02x = %d, want %d", tt.code, got, tt.pcm)
+		}
+	}
+
+	alaw := []struct {
+		code byte
+		pcm  int16
+	}{
+		{0xD5, 8},
+		{0x55, -8},
+		{0xAA, 32256},
+		{0x2A, -32256},
+		{0xFA, 1008},
+		{0x7A, -1008},
+		{0xB6, 9984},
+	}
+	for _, tt := range alaw {
+		if got := alawDecode(tt.code); got != tt.pcm {
+			t.Errorf("A-law decode %#02x = %d, want %d", tt.code, got, tt.pcm)
+		}
+	}
+}
+
+// Every code decodes to a value that encodes back to it, except the
+// negative zero of μ-law, and every sample round-trips within the
+// quantization step of its segment.
+func TestG711_RoundTrip(t *testing.T) {
+	for _, codec := range []struct {
+		name   string
+		encode func(int16) byte
+		decode func(byte) int16
+	}{
+		{"μ-law", ulawEncode, ulawDecode},
+		{"A-law", alawEncode, alawDecode},
+	} {
+		for code := range 256 {
+			if codec.name == "μ-law" && code == 0x7F {
+				continue
+			}
+			if got := codec.encode(codec.decode(byte(code))); got != byte(code) {
+				t.Errorf("%s: code %#02x round-trips to %#02x", codec.name, code, got)
+			}
+		}
+		for x := -32768; x <= 32767; x++ {
+			got := int(codec.decode(codec.encode(int16(x))))
+			// Relative error below 1/16, or 16 for the smallest samples
+			if d := abs(got - x); d*16 > max(abs(x), 256) {
+				t.Fatalf("%s: %d round-trips to %d", codec.name, x, got)
+			}
+		}
+	}
+}
+
+func TestG711_EncodeDecodeAudio(t *testing.T) {
+	pcm := make([]byte, 8)
+	for i, v := range []int16{0, 1000, -1000, 32767} {
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(v))
+	}
+
+	for _, tt := range []struct {
+		format string
+		coded  []byte
+		pcm    []int16
+	}{
+		{AudioFormatG711ULaw, []byte{0xFF, 0xCE, 0x4E, 0x80}, []int16{0, 988, -988, 32124}},
+		{AudioFormatG711ALaw, []byte{0xD5, 0xFA, 0x7A, 0xAA}, []int16{8, 1008, -1008, 32256}},
+	} {
+		coded, err := EncodeAudio(tt.format, pcm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(coded, tt.coded) {
+			t.Errorf("EncodeAudio(%s) = % x, want % x", tt.format, coded, tt.coded)
+		}
+		decoded, err := DecodeAudio(tt.format, coded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := make([]byte, len(tt.pcm)*2)
+		for i, v := range tt.pcm {
+			binary.LittleEndian.PutUint16(want[i*2:], uint16(v))
+		}
+		if !bytes.Equal(decoded, want) {
+			t.Errorf("DecodeAudio(%s) = % x, want % x", tt.format, decoded, want)
+		}
+	}
+
+	if got, _ := EncodeAudio(AudioFormatPCM16, pcm); !bytes.Equal(got, pcm) {
+		t.Error("EncodeAudio(pcm16) changed the input")
+	}
+	if _, err := DecodeAudio("opus", pcm); err == nil {
+		t.Error("DecodeAudio(opus) succeeded")
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
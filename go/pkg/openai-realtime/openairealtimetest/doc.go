@@ -0,0 +1,44 @@
+// Package openairealtimetest provides an in-process mock of the OpenAI
+// Realtime API, so packages built on openairealtime can run end-to-end
+// tests without an API key or network access.
+//
+// The mock speaks the WebSocket protocol: it sends session.created on
+// connect, keeps the conversation, streams scripted responses as text or
+// audio deltas, honours response.cancel, and simulates server VAD on the
+// appended audio (an energy detector), including auto-responses and
+// barge-in.
+//
+// # Usage
+//
+//	srv := openairealtimetest.NewServer(
+//	    openairealtimetest.WithScript(
+//	        &openairealtimetest.Response{Text: "Hello!"},
+//	        &openairealtimetest.Response{FunctionCall: &openairealtimetest.FunctionCall{
+//	            Name:      "get_weather",
+//	            Arguments: `{"city":"Shanghai"}`,
+//	        }},
+//	    ),
+//	)
+//	defer srv.Close()
+//
+//	session, err := srv.Client().ConnectWebSocket(ctx, nil)
+//	...
+//	session.AddUserMessage("hi")
+//	session.CreateResponse(nil)
+//	for event, err := range session.Events() {
+//	    ...
+//	}
+//
+// Responder computes responses from the request instead of a fixed script.
+// ClientEvents returns the events the server received, for assertions.
+//
+// # VAD Simulation
+//
+// While turn detection is server_vad or semantic_vad (the default, as with
+// the real API), appended audio is cut into 20ms frames. A frame whose RMS
+// level exceeds the VAD level (see WithVADLevel) is speech. Speech starts
+// with input_audio_buffer.speech_started, interrupting a response in
+// progress; it stops after SilenceDurationMs of silence, after which the
+// buffer is committed and a response created. Use WithTranscriber to give
+// committed audio a transcript.
+package openairealtimetest
@@ -0,0 +1,281 @@
+package openairealtimetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	openairealtime "github.com/haivivi/giztoy/go/pkg/openai-realtime"
+)
+
+// DefaultVADLevel is the RMS level, as a fraction of full scale, above
+// which the VAD simulation treats a frame as speech.
+const DefaultVADLevel = 0.02
+
+// Response is a scripted model response.
+type Response struct {
+	// Text is the response text. It is streamed as text deltas, or as the
+	// audio transcript when the response has audio.
+	Text string
+
+	// Audio is the response audio as 16-bit PCM at the session's output
+	// sample rate. If nil and the response modalities include audio, a
+	// quiet tone is generated with a length proportional to Text.
+	Audio []byte
+
+	// FunctionCall, if set, makes the response a function call instead of
+	// a message.
+	FunctionCall *FunctionCall
+}
+
+// FunctionCall is a scripted function call.
+type FunctionCall struct {
+	Name      string
+	Arguments string
+}
+
+// Request describes the response being generated.
+type Request struct {
+	// SessionID is the mock session's ID.
+	SessionID string
+
+	// Input is the text of the last user message: its text, or the
+	// transcript of its audio (see WithTranscriber).
+	Input string
+
+	// Instructions are the effective instructions of the response.
+	Instructions string
+
+	// Modalities are the effective modalities of the response.
+	Modalities []string
+
+	// Metadata is the metadata of response.create.
+	Metadata map[string]string
+
+	// Items is the conversation (or the response's input items for
+	// out-of-band responses).
+	Items []openairealtime.ConversationItem
+}
+
+// Responder computes the response to a request.
+type Responder func(req *Request) *Response
+
+// Echo is the default Responder. It answers "You said: <input>".
+func Echo(req *Request) *Response {
+	return &Response{Text: "You said: " + req.Input}
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithResponder sets the Responder.
+func WithResponder(r Responder) Option {
+	return func(s *Server) {
+		s.responder = r
+	}
+}
+
+// WithScript answers with the given responses in order, shared by all
+// sessions. Once the script is exhausted, the Echo responder answers.
+func WithScript(responses ...*Response) Option {
+	return func(s *Server) {
+		var mu sync.Mutex
+		script := responses
+		s.responder = func(req *Request) *Response {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(script) == 0 {
+				return Echo(req)
+			}
+			resp := script[0]
+			script = script[1:]
+			return resp
+		}
+	}
+}
+
+// WithTranscriber sets the transcript of committed user audio (16-bit PCM
+// at the input sample rate). Without it, audio has an empty transcript.
+func WithTranscriber(f func(pcm []byte) string) Option {
+	return func(s *Server) {
+		s.transcriber = f
+	}
+}
+
+// WithDeltaInterval sets the delay between streamed deltas (default 0), so
+// tests can cancel or interrupt responses in progress.
+func WithDeltaInterval(d time.Duration) Option {
+	return func(s *Server) {
+		s.deltaInterval = d
+	}
+}
+
+// WithVADLevel sets the RMS level, as a fraction of full scale, above
+// which the VAD simulation treats a frame as speech (default
+// DefaultVADLevel).
+func WithVADLevel(level float64) Option {
+	return func(s *Server) {
+		s.vadLevel = level
+	}
+}
+
+// Server is a mock Realtime API server. Each WebSocket connection is an
+// independent session.
+type Server struct {
+	responder     Responder
+	transcriber   func(pcm []byte) string
+	deltaInterval time.Duration
+	vadLevel      float64
+
+	srv      *httptest.Server
+	upgrader websocket.Upgrader
+
+	mu       sync.Mutex
+	received []map[string]any
+	nextID   int
+	sessions int
+}
+
+// NewServer starts a mock server. Close it when done.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		responder: Echo,
+		vadLevel:  DefaultVADLevel,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// URL returns the WebSocket URL of the server, for
+// openairealtime.WithWebSocketURL.
+func (s *Server) URL() string {
+	return "ws" + strings.TrimPrefix(s.srv.URL, "http") + "/v1/realtime"
+}
+
+// Client returns a client connected to the server.
+func (s *Server) Client() *openairealtime.Client {
+	client, err := openairealtime.NewClient("test-key", openairealtime.WithWebSocketURL(s.URL()))
+	if err != nil {
+		panic(err) // unreachable: the API key is set
+	}
+	return client
+}
+
+// Close shuts the server down and closes all connections.
+func (s *Server) Close() {
+	s.srv.CloseClientConnections()
+	s.srv.Close()
+}
+
+// ClientEvents returns the client events received by all sessions, in
+// order. If types are given, only events of those types are returned.
+// input_audio_buffer.append events are recorded without their audio.
+func (s *Server) ClientEvents(types ...string) []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []map[string]any
+	for _, e := range s.received {
+		if len(types) == 0 || contains(types, e["type"]) {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Sessions returns the number of sessions opened so far.
+func (s *Server) Sessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions
+}
+
+func contains(types []string, t any) bool {
+	for _, typ := range types {
+		if typ == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		http.Error(w, `{"error":{"message":"missing API key"}}`, http.StatusUnauthorized)
+		return
+	}
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.sessions++
+	s.mu.Unlock()
+
+	model := r.URL.Query().Get("model")
+	if model == "" {
+		model = openairealtime.ModelGPT4oRealtimePreview
+	}
+	newSession(s, conn, model).run()
+}
+
+// record stores a received client event.
+func (s *Server) record(event map[string]any) {
+	if event["type"] == openairealtime.EventTypeInputAudioBufferAppend {
+		event = map[string]any{"type": event["type"], "event_id": event["event_id"]}
+	}
+	s.mu.Lock()
+	s.received = append(s.received, event)
+	s.mu.Unlock()
+}
+
+// newID returns a unique ID with the given prefix.
+func (s *Server) newID(prefix string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	return fmt.Sprintf("%s_mock%06d", prefix, s.nextID)
+}
+
+// tone generates a quiet 440Hz tone of the given duration as 16-bit PCM.
+func tone(sampleRate int, d time.Duration) []byte {
+	n := int(int64(sampleRate) * int64(d) / int64(time.Second))
+	pcm := make([]byte, 2*n)
+	for i := range n {
+		v := int16(1000 * math.Sin(2*math.Pi*440*float64(i)/float64(sampleRate)))
+		pcm[2*i] = byte(v)
+		pcm[2*i+1] = byte(uint16(v) >> 8)
+	}
+	return pcm
+}
+
+// rms returns the RMS level of 16-bit PCM as a fraction of full scale.
+func rms(pcm []byte) float64 {
+	n := len(pcm) / 2
+	if n == 0 {
+		return 0
+	}
+	var sum float64
+	for i := range n {
+		v := float64(int16(uint16(pcm[2*i])|uint16(pcm[2*i+1])<<8)) / 32768
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(n))
+}
+
+// decodeJSON converts a JSON-like value into v.
+func decodeJSON(from any, v any) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,270 @@
+package openairealtimetest_test
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	openairealtime "github.com/haivivi/giztoy/go/pkg/openai-realtime"
+	"github.com/haivivi/giztoy/go/pkg/openai-realtime/openairealtimetest"
+)
+
+// events reads the server events of a session.
+type events struct {
+	t  *testing.T
+	ch chan *openairealtime.ServerEvent
+}
+
+// connect opens a session to srv and starts reading its events.
+func connect(t *testing.T, srv *openairealtimetest.Server) (openairealtime.Session, *events) {
+	t.Helper()
+	session, err := srv.Client().ConnectWebSocket(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { session.Close() })
+
+	e := &events{t: t, ch: make(chan *openairealtime.ServerEvent, 1000)}
+	go func() {
+		defer close(e.ch)
+		for event, err := range session.Events() {
+			if err != nil {
+				return
+			}
+			e.ch <- event
+		}
+	}()
+	e.until(openairealtime.EventTypeSessionCreated)
+	return session, e
+}
+
+// until returns the events up to and including the next one of type typ.
+func (e *events) until(typ string) []*openairealtime.ServerEvent {
+	e.t.Helper()
+	var got []*openairealtime.ServerEvent
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-e.ch:
+			if !ok {
+				e.t.Fatalf("session closed waiting for %s", typ)
+			}
+			got = append(got, event)
+			if event.Type == openairealtime.EventTypeError {
+				e.t.Fatalf("error event: %s", event.Raw)
+			}
+			if event.Type == typ {
+				return got
+			}
+		case <-timeout:
+			e.t.Fatalf("timed out waiting for %s", typ)
+		}
+	}
+}
+
+// response waits for the next response.done and returns it with the text
+// or transcript of the response.
+func (e *events) response() (*openairealtime.ResponseResource, string) {
+	e.t.Helper()
+	var text strings.Builder
+	var done *openairealtime.ResponseResource
+	for _, event := range e.until(openairealtime.EventTypeResponseDone) {
+		switch event.Type {
+		case openairealtime.EventTypeResponseTextDelta, openairealtime.EventTypeResponseAudioTranscriptDelta:
+			text.WriteString(event.Delta)
+		case openairealtime.EventTypeResponseDone:
+			done = event.Response
+		}
+	}
+	return done, text.String()
+}
+
+func types(events []*openairealtime.ServerEvent) []string {
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+// speech returns loud 24kHz PCM, well above the VAD level.
+func speech(d time.Duration) []byte {
+	n := int(24000 * d / time.Second)
+	pcm := make([]byte, 2*n)
+	for i := range n {
+		v := int16(8000 * math.Sin(2*math.Pi*300*float64(i)/24000))
+		pcm[2*i] = byte(v)
+		pcm[2*i+1] = byte(uint16(v) >> 8)
+	}
+	return pcm
+}
+
+func silence(d time.Duration) []byte {
+	return make([]byte, 2*int(24000*d/time.Second))
+}
+
+func TestServer_Script(t *testing.T) {
+	srv := openairealtimetest.NewServer(openairealtimetest.WithScript(
+		&openairealtimetest.Response{Text: "Hello!"},
+		&openairealtimetest.Response{FunctionCall: &openairealtimetest.FunctionCall{
+			Name:      "get_weather",
+			Arguments: `{"city":"Shanghai"}`,
+		}},
+	))
+	defer srv.Close()
+	session, e := connect(t, srv)
+
+	if err := session.UpdateSession(&openairealtime.SessionConfig{Modalities: []string{openairealtime.ModalityText}}); err != nil {
+		t.Fatal(err)
+	}
+	e.until(openairealtime.EventTypeSessionUpdated)
+
+	session.AddUserMessage("hi")
+	session.CreateResponse(nil)
+	resp, text := e.response()
+	if resp.Status != "completed" || text != "Hello!" {
+		t.Errorf("first response: status %q, text %q; want completed, %q", resp.Status, text, "Hello!")
+	}
+
+	session.AddUserMessage("weather?")
+	session.CreateResponse(nil)
+	var call *openairealtime.ServerEvent
+	for _, event := range e.until(openairealtime.EventTypeResponseDone) {
+		if event.Type == openairealtime.EventTypeResponseFunctionCallArgumentsDone {
+			call = event
+		}
+	}
+	if call == nil || call.Name != "get_weather" || call.Arguments != `{"city":"Shanghai"}` || call.CallID == "" {
+		t.Errorf("function call = %+v", call)
+	}
+
+	// The script is exhausted: Echo answers
+	session.AddUserMessage("bye")
+	session.CreateResponse(nil)
+	if _, text := e.response(); text != "You said: bye" {
+		t.Errorf("echo response = %q", text)
+	}
+
+	if got := len(srv.ClientEvents(openairealtime.EventTypeResponseCreate)); got != 3 {
+		t.Errorf("server received %d response.create, want 3", got)
+	}
+	if got := srv.Sessions(); got != 1 {
+		t.Errorf("Sessions = %d, want 1", got)
+	}
+}
+
+func TestServer_AudioResponse(t *testing.T) {
+	srv := openairealtimetest.NewServer(openairealtimetest.WithScript(
+		&openairealtimetest.Response{Text: "Hi there"},
+	))
+	defer srv.Close()
+	session, e := connect(t, srv)
+
+	session.AddUserMessage("hi")
+	session.CreateResponse(nil)
+	var audio int
+	for _, event := range e.until(openairealtime.EventTypeResponseAudioDone) {
+		if event.Type == openairealtime.EventTypeResponseAudioDelta {
+			audio += len(event.Audio)
+		}
+	}
+	// A tone of 60ms per rune, at least 200ms
+	if want := 24000 * 2 * 8 * 60 / 1000; audio != want {
+		t.Errorf("audio = %d bytes, want %d", audio, want)
+	}
+	if _, text := e.response(); text != "" {
+		t.Errorf("transcript after audio.done = %q, want none", text)
+	}
+}
+
+func TestServer_ServerVAD(t *testing.T) {
+	srv := openairealtimetest.NewServer(openairealtimetest.WithTranscriber(func(pcm []byte) string {
+		if len(pcm) == 0 {
+			return ""
+		}
+		return "what time is it"
+	}))
+	defer srv.Close()
+	session, e := connect(t, srv)
+
+	session.AppendAudio(silence(200 * time.Millisecond))
+	session.AppendAudio(speech(400 * time.Millisecond))
+	started := e.until(openairealtime.EventTypeInputAudioBufferSpeechStarted)
+	// The speech starts at 200ms, less the 300ms prefix padding
+	if got := started[len(started)-1].AudioStartMs; got != 0 {
+		t.Errorf("audio_start_ms = %d, want 0", got)
+	}
+
+	// 500ms of silence ends the turn: the buffer is committed and a
+	// response created
+	session.AppendAudio(silence(600 * time.Millisecond))
+	got := e.until(openairealtime.EventTypeResponseCreated)
+	want := []string{
+		openairealtime.EventTypeInputAudioBufferSpeechStopped,
+		openairealtime.EventTypeInputAudioBufferCommitted,
+		openairealtime.EventTypeConversationItemCreated,
+		openairealtime.EventTypeResponseCreated,
+	}
+	if strings.Join(types(got), ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", types(got), want)
+	}
+	if got := got[0].AudioEndMs; got != 900 {
+		t.Errorf("audio_end_ms = %d, want 900", got)
+	}
+
+	resp, text := e.response()
+	if resp.Status != "completed" || text != "You said: what time is it" {
+		t.Errorf("response: status %q, transcript %q", resp.Status, text)
+	}
+
+	// Silence alone does not start a turn
+	session.AppendAudio(silence(time.Second))
+	session.CreateResponse(nil)
+	for _, event := range e.until(openairealtime.EventTypeResponseDone) {
+		if event.Type == openairealtime.EventTypeInputAudioBufferSpeechStarted {
+			t.Error("speech started on silence")
+		}
+	}
+}
+
+func TestServer_BargeIn(t *testing.T) {
+	srv := openairealtimetest.NewServer(
+		openairealtimetest.WithScript(&openairealtimetest.Response{Text: strings.Repeat("A long answer. ", 20)}),
+		openairealtimetest.WithDeltaInterval(20*time.Millisecond),
+	)
+	defer srv.Close()
+	session, e := connect(t, srv)
+
+	session.AddUserMessage("tell me a story")
+	session.CreateResponse(nil)
+	e.until(openairealtime.EventTypeResponseAudioDelta)
+
+	// The user speaks over the response, which is cancelled
+	session.AppendAudio(speech(100 * time.Millisecond))
+	e.until(openairealtime.EventTypeInputAudioBufferSpeechStarted)
+	resp, _ := e.response()
+	if resp.Status != "cancelled" || resp.StatusDetails == nil || resp.StatusDetails.Reason != "turn_detected" {
+		t.Errorf("interrupted response: status %q, details %+v", resp.Status, resp.StatusDetails)
+	}
+	if len(resp.Output) != 1 || resp.Output[0].Status != "incomplete" {
+		t.Errorf("interrupted response output = %+v, want an incomplete item", resp.Output)
+	}
+
+	// The end of the turn starts the next response
+	session.AppendAudio(silence(600 * time.Millisecond))
+	e.until(openairealtime.EventTypeInputAudioBufferCommitted)
+	if resp, _ := e.response(); resp.Status != "completed" {
+		t.Errorf("next response status = %q, want completed", resp.Status)
+	}
+
+	// A cancel by the client
+	session.AddUserMessage("again")
+	session.CreateResponse(nil)
+	e.until(openairealtime.EventTypeResponseAudioDelta)
+	session.CancelResponse()
+	if resp, _ := e.response(); resp.Status != "cancelled" || resp.StatusDetails.Reason != "client_cancelled" {
+		t.Errorf("cancelled response: status %q, details %+v", resp.Status, resp.StatusDetails)
+	}
+}
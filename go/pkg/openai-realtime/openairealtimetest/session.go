@@ -0,0 +1,765 @@
+package openairealtimetest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	openairealtime "github.com/haivivi/giztoy/go/pkg/openai-realtime"
+)
+
+// vadFrameMs is the frame length of the VAD simulation.
+const vadFrameMs = 20
+
+// session is the protocol state of one connection.
+type session struct {
+	srv  *Server
+	conn *websocket.Conn
+	id   string
+
+	writeMu sync.Mutex
+
+	mu        sync.Mutex
+	config    openairealtime.SessionResource
+	items     []openairealtime.ConversationItem
+	audio     []byte // input buffer, 16-bit PCM
+	audioMs   int    // audio appended since the session started
+	vad       vadState
+	active    *activeResponse
+	lastInput string
+}
+
+type vadState struct {
+	pending   []byte // incomplete frame
+	speaking  bool
+	itemID    string
+	silenceMs int
+}
+
+type activeResponse struct {
+	id     string
+	cancel chan struct{}
+	reason string
+	once   sync.Once
+}
+
+// stop cancels the response; reason ends up in status_details.
+func (r *activeResponse) stop(reason string) {
+	r.once.Do(func() {
+		r.reason = reason
+		close(r.cancel)
+	})
+}
+
+// responseOptions are the fields of response.create the mock uses.
+type responseOptions struct {
+	Modalities        []string                          `json:"modalities"`
+	Instructions      string                            `json:"instructions"`
+	OutputAudioFormat string                            `json:"output_audio_format"`
+	Conversation      string                            `json:"conversation"`
+	Input             []openairealtime.ConversationItem `json:"input"`
+	Metadata          map[string]string                 `json:"metadata"`
+}
+
+func newSession(srv *Server, conn *websocket.Conn, model string) *session {
+	createResponse, interrupt := true, true
+	return &session{
+		srv:  srv,
+		conn: conn,
+		id:   srv.newID("sess"),
+		config: openairealtime.SessionResource{
+			Object:            "realtime.session",
+			Model:             model,
+			Modalities:        []string{openairealtime.ModalityText, openairealtime.ModalityAudio},
+			Voice:             openairealtime.VoiceAlloy,
+			InputAudioFormat:  openairealtime.AudioFormatPCM16,
+			OutputAudioFormat: openairealtime.AudioFormatPCM16,
+			TurnDetection: &openairealtime.TurnDetection{
+				Type:              openairealtime.VADServerVAD,
+				Threshold:         0.5,
+				PrefixPaddingMs:   300,
+				SilenceDurationMs: 500,
+				CreateResponse:    &createResponse,
+				InterruptResponse: &interrupt,
+			},
+			ToolChoice:              openairealtime.ToolChoiceAuto,
+			Temperature:             0.8,
+			MaxResponseOutputTokens: "inf",
+		},
+	}
+}
+
+func (s *session) run() {
+	defer s.conn.Close()
+
+	s.config.ID = s.id
+	s.send(map[string]any{
+		"type":    openairealtime.EventTypeSessionCreated,
+		"session": s.config,
+	})
+
+	for {
+		_, message, err := s.conn.ReadMessage()
+		if err != nil {
+			s.mu.Lock()
+			if s.active != nil {
+				s.active.stop("client_cancelled")
+			}
+			s.mu.Unlock()
+			return
+		}
+		var event map[string]any
+		if err := json.Unmarshal(message, &event); err != nil {
+			s.sendError("invalid_request_error", "invalid_json", err.Error(), "")
+			continue
+		}
+		s.srv.record(event)
+		s.handle(event)
+	}
+}
+
+// send writes a server event, adding its event_id.
+func (s *session) send(event map[string]any) {
+	event["event_id"] = s.srv.newID("event")
+	data, err := json.Marshal(event)
+	if err != nil {
+		panic(err)
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (s *session) sendError(typ, code, message, eventID string) {
+	s.send(map[string]any{
+		"type": openairealtime.EventTypeError,
+		"error": map[string]any{
+			"type":     typ,
+			"code":     code,
+			"message":  message,
+			"event_id": eventID,
+		},
+	})
+}
+
+func (s *session) handle(event map[string]any) {
+	eventID, _ := event["event_id"].(string)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch event["type"] {
+	case openairealtime.EventTypeSessionUpdate:
+		s.updateSession(event["session"])
+		s.send(map[string]any{
+			"type":    openairealtime.EventTypeSessionUpdated,
+			"session": s.config,
+		})
+
+	case openairealtime.EventTypeInputAudioBufferAppend:
+		audio, _ := event["audio"].(string)
+		raw, err := base64.StdEncoding.DecodeString(audio)
+		if err != nil {
+			s.sendError("invalid_request_error", "invalid_value", "audio is not valid base64", eventID)
+			return
+		}
+		pcm, err := openairealtime.DecodeAudio(s.config.InputAudioFormat, raw)
+		if err != nil {
+			s.sendError("invalid_request_error", "invalid_value", err.Error(), eventID)
+			return
+		}
+		s.appendAudio(pcm)
+
+	case openairealtime.EventTypeInputAudioBufferCommit:
+		if len(s.audio) == 0 {
+			s.sendError("invalid_request_error", "input_audio_buffer_commit_empty", "buffer is empty", eventID)
+			return
+		}
+		s.commit(s.srv.newID("item"))
+
+	case openairealtime.EventTypeInputAudioBufferClear:
+		s.audio = nil
+		s.send(map[string]any{"type": openairealtime.EventTypeInputAudioBufferCleared})
+
+	case openairealtime.EventTypeConversationItemCreate:
+		var item openairealtime.ConversationItem
+		if err := decodeJSON(event["item"], &item); err != nil {
+			s.sendError("invalid_request_error", "invalid_value", err.Error(), eventID)
+			return
+		}
+		previous, _ := event["previous_item_id"].(string)
+		if !s.insertItem(&item, previous) {
+			s.sendError("invalid_request_error", "item_not_found", "previous item not found: "+previous, eventID)
+		}
+
+	case openairealtime.EventTypeConversationItemDelete:
+		itemID, _ := event["item_id"].(string)
+		i := s.itemIndex(itemID)
+		if i < 0 {
+			s.sendError("invalid_request_error", "item_not_found", "item not found: "+itemID, eventID)
+			return
+		}
+		s.items = slices.Delete(s.items, i, i+1)
+		s.send(map[string]any{
+			"type":    openairealtime.EventTypeConversationItemDeleted,
+			"item_id": itemID,
+		})
+
+	case openairealtime.EventTypeConversationItemTruncate:
+		itemID, _ := event["item_id"].(string)
+		if s.itemIndex(itemID) < 0 {
+			s.sendError("invalid_request_error", "item_not_found", "item not found: "+itemID, eventID)
+			return
+		}
+		s.send(map[string]any{
+			"type":          openairealtime.EventTypeConversationItemTruncated,
+			"item_id":       itemID,
+			"content_index": event["content_index"],
+			"audio_end_ms":  event["audio_end_ms"],
+		})
+
+	case openairealtime.EventTypeResponseCreate:
+		var opts responseOptions
+		if r, ok := event["response"]; ok && r != nil {
+			if err := decodeJSON(r, &opts); err != nil {
+				s.sendError("invalid_request_error", "invalid_value", err.Error(), eventID)
+				return
+			}
+		}
+		if s.active != nil {
+			s.sendError("invalid_request_error", "conversation_already_has_active_response",
+				"Conversation already has an active response", eventID)
+			return
+		}
+		s.startResponse(opts)
+
+	case openairealtime.EventTypeResponseCancel:
+		if s.active == nil {
+			s.sendError("invalid_request_error", "response_cancel_not_active", "No active response found", eventID)
+			return
+		}
+		s.active.stop("client_cancelled")
+
+	default:
+		s.sendError("invalid_request_error", "invalid_value", "unknown event type", eventID)
+	}
+}
+
+// updateSession merges a session.update into the config.
+func (s *session) updateSession(v any) {
+	raw, _ := v.(map[string]any)
+	var update openairealtime.SessionConfig
+	decodeJSON(raw, &update)
+
+	c := &s.config
+	if update.Modalities != nil {
+		c.Modalities = update.Modalities
+	}
+	if update.Instructions != "" {
+		c.Instructions = update.Instructions
+	}
+	if update.Voice != "" {
+		c.Voice = update.Voice
+	}
+	if update.InputAudioFormat != "" {
+		c.InputAudioFormat = update.InputAudioFormat
+	}
+	if update.OutputAudioFormat != "" {
+		c.OutputAudioFormat = update.OutputAudioFormat
+	}
+	if update.InputAudioTranscription != nil {
+		c.InputAudioTranscription = update.InputAudioTranscription
+	}
+	if update.InputAudioNoiseReduction != nil {
+		c.InputAudioNoiseReduction = update.InputAudioNoiseReduction
+	}
+	if td, ok := raw["turn_detection"]; ok {
+		if td == nil {
+			c.TurnDetection = nil
+		} else {
+			c.TurnDetection = update.TurnDetection
+		}
+	}
+	if update.Tools != nil {
+		c.Tools = update.Tools
+	}
+	if update.ToolChoice != nil {
+		c.ToolChoice = update.ToolChoice
+	}
+	if update.Temperature != nil {
+		c.Temperature = *update.Temperature
+	}
+	if update.MaxResponseOutputTokens != nil {
+		c.MaxResponseOutputTokens = *update.MaxResponseOutputTokens
+	}
+}
+
+func (s *session) vadEnabled() bool {
+	return s.config.TurnDetection != nil && s.config.TurnDetection.Type != ""
+}
+
+// appendAudio adds input audio and runs the VAD simulation on it.
+func (s *session) appendAudio(pcm []byte) {
+	s.audio = append(s.audio, pcm...)
+	if !s.vadEnabled() {
+		s.audioMs += len(pcm) * 1000 / (2 * openairealtime.AudioSampleRate(s.config.InputAudioFormat))
+		return
+	}
+
+	td := s.config.TurnDetection
+	prefixMs := td.PrefixPaddingMs
+	silenceMs := td.SilenceDurationMs
+	if silenceMs <= 0 {
+		silenceMs = 500
+	}
+	frameBytes := openairealtime.AudioSampleRate(s.config.InputAudioFormat) * 2 * vadFrameMs / 1000
+
+	s.vad.pending = append(s.vad.pending, pcm...)
+	for len(s.vad.pending) >= frameBytes {
+		frame := s.vad.pending[:frameBytes]
+		s.vad.pending = s.vad.pending[frameBytes:]
+		frameStart := s.audioMs
+		s.audioMs += vadFrameMs
+		speech := rms(frame) > s.srv.vadLevel
+
+		switch {
+		case !s.vad.speaking && speech:
+			s.vad.speaking = true
+			s.vad.silenceMs = 0
+			s.vad.itemID = s.srv.newID("item")
+			s.send(map[string]any{
+				"type":           openairealtime.EventTypeInputAudioBufferSpeechStarted,
+				"audio_start_ms": max(0, frameStart-prefixMs),
+				"item_id":        s.vad.itemID,
+			})
+			if s.active != nil && (td.InterruptResponse == nil || *td.InterruptResponse) {
+				s.active.stop("turn_detected")
+			}
+
+		case s.vad.speaking && speech:
+			s.vad.silenceMs = 0
+
+		case s.vad.speaking && !speech:
+			s.vad.silenceMs += vadFrameMs
+			if s.vad.silenceMs < silenceMs {
+				continue
+			}
+			s.vad.speaking = false
+			s.send(map[string]any{
+				"type":         openairealtime.EventTypeInputAudioBufferSpeechStopped,
+				"audio_end_ms": s.audioMs - s.vad.silenceMs + min(s.vad.silenceMs, prefixMs),
+				"item_id":      s.vad.itemID,
+			})
+			s.commit(s.vad.itemID)
+			if (td.CreateResponse == nil || *td.CreateResponse) && s.active == nil {
+				s.startResponse(responseOptions{})
+			}
+		}
+	}
+
+	if !s.vad.speaking {
+		// Outside speech only the prefix padding is kept.
+		keep := openairealtime.AudioSampleRate(s.config.InputAudioFormat) * 2 * prefixMs / 1000
+		if len(s.audio) > keep {
+			s.audio = s.audio[len(s.audio)-keep:]
+		}
+	}
+}
+
+// commit turns the input buffer into a user message.
+func (s *session) commit(itemID string) {
+	pcm := s.audio
+	s.audio = nil
+
+	var transcript string
+	if s.srv.transcriber != nil {
+		transcript = s.srv.transcriber(pcm)
+	}
+	s.lastInput = transcript
+
+	previous := ""
+	if len(s.items) > 0 {
+		previous = s.items[len(s.items)-1].ID
+	}
+	item := openairealtime.ConversationItem{
+		ID:      itemID,
+		Object:  "realtime.item",
+		Type:    openairealtime.ItemTypeMessage,
+		Status:  "completed",
+		Role:    openairealtime.RoleUser,
+		Content: []openairealtime.ContentPart{{Type: "input_audio"}},
+	}
+	s.items = append(s.items, item)
+	s.send(map[string]any{
+		"type":             openairealtime.EventTypeInputAudioBufferCommitted,
+		"previous_item_id": previous,
+		"item_id":          itemID,
+	})
+	s.send(map[string]any{
+		"type":             openairealtime.EventTypeConversationItemCreated,
+		"previous_item_id": previous,
+		"item":             item,
+	})
+
+	if s.config.InputAudioTranscription != nil {
+		s.items[len(s.items)-1].Content[0].Transcript = transcript
+		s.send(map[string]any{
+			"type":          openairealtime.EventTypeConversationItemInputAudioTranscriptionCompleted,
+			"item_id":       itemID,
+			"content_index": 0,
+			"transcript":    transcript,
+		})
+	}
+}
+
+// insertItem adds a client-created item after previous ("" appends,
+// "root" prepends). It reports false if previous is unknown.
+func (s *session) insertItem(item *openairealtime.ConversationItem, previous string) bool {
+	if item.ID == "" {
+		item.ID = s.srv.newID("item")
+	}
+	item.Object = "realtime.item"
+	item.Status = "completed"
+
+	var at int
+	switch previous {
+	case "":
+		at = len(s.items)
+	case openairealtime.PreviousItemRoot:
+		at = 0
+	default:
+		i := s.itemIndex(previous)
+		if i < 0 {
+			return false
+		}
+		at = i + 1
+	}
+	s.items = slices.Insert(s.items, at, *item)
+	if at > 0 {
+		previous = s.items[at-1].ID
+	} else {
+		previous = ""
+	}
+	if item.Role == openairealtime.RoleUser {
+		s.lastInput = itemText(item)
+	}
+	s.send(map[string]any{
+		"type":             openairealtime.EventTypeConversationItemCreated,
+		"previous_item_id": previous,
+		"item":             item,
+	})
+	return true
+}
+
+func (s *session) itemIndex(id string) int {
+	return slices.IndexFunc(s.items, func(item openairealtime.ConversationItem) bool {
+		return item.ID == id
+	})
+}
+
+// itemText returns the text or transcript of a message item.
+func itemText(item *openairealtime.ConversationItem) string {
+	var sb strings.Builder
+	for _, part := range item.Content {
+		if part.Text != "" {
+			sb.WriteString(part.Text)
+		} else {
+			sb.WriteString(part.Transcript)
+		}
+	}
+	return sb.String()
+}
+
+// startResponse starts generating a response. s.mu must be held.
+func (s *session) startResponse(opts responseOptions) {
+	r := &activeResponse{id: s.srv.newID("resp"), cancel: make(chan struct{})}
+	s.active = r
+
+	req := &Request{
+		SessionID:    s.id,
+		Input:        s.lastInput,
+		Instructions: s.config.Instructions,
+		Modalities:   s.config.Modalities,
+		Metadata:     opts.Metadata,
+		Items:        slices.Clone(s.items),
+	}
+	if opts.Instructions != "" {
+		req.Instructions = opts.Instructions
+	}
+	if opts.Modalities != nil {
+		req.Modalities = opts.Modalities
+	}
+	if opts.Input != nil {
+		req.Items = opts.Input
+		for i := range opts.Input {
+			if opts.Input[i].Role == openairealtime.RoleUser {
+				req.Input = itemText(&opts.Input[i])
+			}
+		}
+	}
+	format := s.config.OutputAudioFormat
+	if opts.OutputAudioFormat != "" {
+		format = opts.OutputAudioFormat
+	}
+	go s.respond(r, req, opts.Conversation == openairealtime.ConversationNone, format)
+}
+
+// respond streams the response to req.
+func (s *session) respond(r *activeResponse, req *Request, outOfBand bool, format string) {
+	resp := s.srv.responder(req)
+	if resp == nil {
+		resp = &Response{}
+	}
+
+	resource := map[string]any{
+		"id":     r.id,
+		"object": "realtime.response",
+		"status": "in_progress",
+		"output": []any{},
+	}
+	if !outOfBand {
+		resource["conversation_id"] = "conv_" + s.id
+	}
+	if req.Metadata != nil {
+		resource["metadata"] = req.Metadata
+	}
+	s.send(map[string]any{"type": openairealtime.EventTypeResponseCreated, "response": resource})
+
+	// wait paces deltas and reports whether the response was cancelled.
+	wait := func() bool {
+		if s.srv.deltaInterval <= 0 {
+			select {
+			case <-r.cancel:
+				return true
+			default:
+				return false
+			}
+		}
+		select {
+		case <-r.cancel:
+			return true
+		case <-time.After(s.srv.deltaInterval):
+			return false
+		}
+	}
+
+	ref := map[string]any{"response_id": r.id, "output_index": 0}
+	with := func(event map[string]any) map[string]any {
+		for k, v := range ref {
+			event[k] = v
+		}
+		return event
+	}
+
+	item := openairealtime.ConversationItem{
+		ID:     s.srv.newID("item"),
+		Object: "realtime.item",
+		Status: "in_progress",
+	}
+	ref["item_id"] = item.ID
+	cancelled := false
+	var textTokens, audioTokens int
+
+	if fc := resp.FunctionCall; fc != nil {
+		item.Type = openairealtime.ItemTypeFunctionCall
+		item.Name = fc.Name
+		item.CallID = s.srv.newID("call")
+		s.addOutputItem(item, outOfBand, with)
+
+		ref["call_id"] = item.CallID
+		for _, delta := range splitString(fc.Arguments, 16) {
+			if cancelled = wait(); cancelled {
+				break
+			}
+			item.Arguments += delta
+			s.send(with(map[string]any{"type": openairealtime.EventTypeResponseFunctionCallArgumentsDelta, "delta": delta}))
+		}
+		if !cancelled {
+			s.send(with(map[string]any{
+				"type":      openairealtime.EventTypeResponseFunctionCallArgumentsDone,
+				"name":      fc.Name,
+				"arguments": item.Arguments,
+			}))
+		}
+		textTokens = len(item.Arguments) / 4
+		delete(ref, "call_id")
+	} else {
+		item.Type = openairealtime.ItemTypeMessage
+		item.Role = openairealtime.RoleAssistant
+		s.addOutputItem(item, outOfBand, with)
+
+		ref["content_index"] = 0
+		withAudio := slices.Contains(req.Modalities, openairealtime.ModalityAudio)
+		partType := "text"
+		if withAudio {
+			partType = "audio"
+		}
+		s.send(with(map[string]any{
+			"type": openairealtime.EventTypeResponseContentPartAdded,
+			"part": map[string]any{"type": partType},
+		}))
+
+		textDeltas := splitString(resp.Text, 8)
+		var audioDeltas [][]byte
+		if withAudio {
+			audioDeltas = s.responseAudio(resp, format)
+		}
+		var text strings.Builder
+		var audioBytes int
+		for i := range max(len(textDeltas), len(audioDeltas)) {
+			if cancelled = wait(); cancelled {
+				break
+			}
+			if i < len(textDeltas) {
+				text.WriteString(textDeltas[i])
+				typ := openairealtime.EventTypeResponseTextDelta
+				if withAudio {
+					typ = openairealtime.EventTypeResponseAudioTranscriptDelta
+				}
+				s.send(with(map[string]any{"type": typ, "delta": textDeltas[i]}))
+			}
+			if i < len(audioDeltas) {
+				audioBytes += len(audioDeltas[i])
+				s.send(with(map[string]any{
+					"type":  openairealtime.EventTypeResponseAudioDelta,
+					"delta": base64.StdEncoding.EncodeToString(audioDeltas[i]),
+				}))
+			}
+		}
+
+		part := openairealtime.ContentPart{Type: partType}
+		switch {
+		case withAudio:
+			part.Transcript = text.String()
+			if !cancelled {
+				s.send(with(map[string]any{"type": openairealtime.EventTypeResponseAudioDone}))
+				s.send(with(map[string]any{"type": openairealtime.EventTypeResponseAudioTranscriptDone, "transcript": part.Transcript}))
+			}
+		default:
+			part.Text = text.String()
+			if !cancelled {
+				s.send(with(map[string]any{"type": openairealtime.EventTypeResponseTextDone, "text": part.Text}))
+			}
+		}
+		s.send(with(map[string]any{"type": openairealtime.EventTypeResponseContentPartDone, "part": part}))
+		delete(ref, "content_index")
+		item.Content = []openairealtime.ContentPart{part}
+		textTokens = len([]rune(text.String()))
+		if rate := openairealtime.AudioSampleRate(format); rate > 0 && withAudio {
+			// About one token per 50ms of audio.
+			audioTokens = audioBytes / bytesPerSample(format) * 20 / rate
+		}
+	}
+
+	status := "completed"
+	if cancelled {
+		status = "cancelled"
+		item.Status = "incomplete"
+	} else {
+		item.Status = "completed"
+	}
+	delete(ref, "item_id")
+	s.send(with(map[string]any{"type": openairealtime.EventTypeResponseOutputItemDone, "item": item}))
+
+	s.mu.Lock()
+	if !outOfBand {
+		if i := s.itemIndex(item.ID); i >= 0 {
+			s.items[i] = item
+		}
+	}
+	if s.active == r {
+		s.active = nil
+	}
+	s.mu.Unlock()
+
+	inputTokens := len([]rune(req.Instructions+req.Input))/4 + 4*len(req.Items)
+	resource["status"] = status
+	resource["output"] = []openairealtime.ConversationItem{item}
+	resource["usage"] = openairealtime.Usage{
+		TotalTokens:        inputTokens + textTokens + audioTokens,
+		InputTokens:        inputTokens,
+		OutputTokens:       textTokens + audioTokens,
+		InputTokenDetails:  &openairealtime.TokenDetails{TextTokens: inputTokens},
+		OutputTokenDetails: &openairealtime.TokenDetails{TextTokens: textTokens, AudioTokens: audioTokens},
+	}
+	if cancelled {
+		resource["status_details"] = map[string]any{"type": "cancelled", "reason": r.reason}
+	}
+	s.send(map[string]any{"type": openairealtime.EventTypeResponseDone, "response": resource})
+	s.send(map[string]any{
+		"type": openairealtime.EventTypeRateLimitsUpdated,
+		"rate_limits": []openairealtime.RateLimit{
+			{Name: openairealtime.RateLimitRequests, Limit: 5000, Remaining: 4999, ResetSeconds: 0.012},
+			{Name: openairealtime.RateLimitTokens, Limit: 40000, Remaining: 40000 - inputTokens - textTokens - audioTokens, ResetSeconds: 1},
+		},
+	})
+}
+
+// addOutputItem announces a new response output item.
+func (s *session) addOutputItem(item openairealtime.ConversationItem, outOfBand bool, with func(map[string]any) map[string]any) {
+	s.send(with(map[string]any{"type": openairealtime.EventTypeResponseOutputItemAdded, "item": item}))
+	if outOfBand {
+		return
+	}
+	s.mu.Lock()
+	previous := ""
+	if len(s.items) > 0 {
+		previous = s.items[len(s.items)-1].ID
+	}
+	s.items = append(s.items, item)
+	s.send(map[string]any{
+		"type":             openairealtime.EventTypeConversationItemCreated,
+		"previous_item_id": previous,
+		"item":             item,
+	})
+	s.mu.Unlock()
+}
+
+// responseAudio returns the response audio in format, split into 100ms
+// deltas.
+func (s *session) responseAudio(resp *Response, format string) [][]byte {
+	rate := openairealtime.AudioSampleRate(format)
+	if rate == 0 {
+		return nil
+	}
+	pcm := resp.Audio
+	if pcm == nil {
+		d := time.Duration(len([]rune(resp.Text))) * 60 * time.Millisecond
+		pcm = tone(rate, max(d, 200*time.Millisecond))
+	}
+	encoded, err := openairealtime.EncodeAudio(format, pcm)
+	if err != nil {
+		return nil
+	}
+	chunk := rate / 10 * bytesPerSample(format)
+	var deltas [][]byte
+	for len(encoded) > 0 {
+		n := min(chunk, len(encoded))
+		deltas = append(deltas, encoded[:n])
+		encoded = encoded[n:]
+	}
+	return deltas
+}
+
+func bytesPerSample(format string) int {
+	if format == openairealtime.AudioFormatG711ULaw || format == openairealtime.AudioFormatG711ALaw {
+		return 1
+	}
+	return 2
+}
+
+// splitString splits s into pieces of up to n runes.
+func splitString(s string, n int) []string {
+	var pieces []string
+	runes := []rune(s)
+	for len(runes) > 0 {
+		k := min(n, len(runes))
+		pieces = append(pieces, string(runes[:k]))
+		runes = runes[k:]
+	}
+	return pieces
+}
@@ -0,0 +1,281 @@
+package openairealtime
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// pacerFrame is the duration of the audio sent per
+	// input_audio_buffer.append event by a paced session.
+	pacerFrame = 20 * time.Millisecond
+
+	// pacerLead is how far ahead of real time a paced session may send,
+	// absorbing the jitter of the caller.
+	pacerLead = 100 * time.Millisecond
+)
+
+// audioPacer queues the input audio of a session with
+// ConnectConfig.PaceAudio and sends it in frames at real-time pace.
+type audioPacer struct {
+	send func(audio []byte) error
+
+	sendMu sync.Mutex // held while taking and sending audio, for order
+
+	mu         sync.Mutex
+	format     string
+	frameBytes int
+	sampleSize int
+	pending    []byte
+	err        error // first send error, returned by later calls
+
+	wake    chan struct{}
+	closeCh chan struct{}
+	once    sync.Once
+}
+
+// newAudioPacer starts a pacer sending audio of format with send.
+func newAudioPacer(format string, send func(audio []byte) error) *audioPacer {
+	p := &audioPacer{
+		send:    send,
+		wake:    make(chan struct{}, 1),
+		closeCh: make(chan struct{}),
+	}
+	p.setFormat(format)
+	go p.run()
+	return p
+}
+
+// setFormat sets the input audio format. Unknown formats are rejected by
+// append.
+func (p *audioPacer) setFormat(format string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.format = format
+	p.sampleSize = 2
+	if format == AudioFormatG711ULaw || format == AudioFormatG711ALaw {
+		p.sampleSize = 1
+	}
+	p.frameBytes = AudioSampleRate(format) * p.sampleSize * int(pacerFrame/time.Millisecond) / 1000
+}
+
+// append validates audio and queues it. It does not block.
+func (p *audioPacer) append(audio []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	if p.frameBytes == 0 {
+		return fmt.Errorf("openai-realtime: unsupported audio format %q", p.format)
+	}
+	audio, err := p.stripWAVLocked(audio)
+	if err != nil {
+		return err
+	}
+	p.pending = append(p.pending, audio...)
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// appendBase64 decodes audio and queues it.
+func (p *audioPacer) appendBase64(audioBase64 string) error {
+	audio, err := base64.StdEncoding.DecodeString(audioBase64)
+	if err != nil {
+		return fmt.Errorf("openai-realtime: invalid base64 audio: %w", err)
+	}
+	return p.append(audio)
+}
+
+// flush sends the queued audio at once, e.g. before a commit. A trailing
+// partial sample is dropped.
+func (p *audioPacer) flush() error {
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+
+	p.mu.Lock()
+	audio := p.pending
+	audio = audio[:len(audio)-len(audio)%p.sampleSize]
+	p.pending = nil
+	err := p.err
+	p.mu.Unlock()
+
+	if err != nil || len(audio) == 0 {
+		return err
+	}
+	if err := p.send(audio); err != nil {
+		p.fail(err)
+		return err
+	}
+	return nil
+}
+
+// clear drops the queued audio.
+func (p *audioPacer) clear() {
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+	p.mu.Lock()
+	p.pending = nil
+	p.mu.Unlock()
+}
+
+// close stops the pacer.
+func (p *audioPacer) close() {
+	p.once.Do(func() { close(p.closeCh) })
+}
+
+func (p *audioPacer) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// run sends whole frames as they are queued, at most pacerLead ahead of
+// real time. When the caller falls behind, the pace restarts from now
+// rather than catching up in a burst. A trailing partial frame is sent
+// once no audio has been queued for a frame duration, so that with server
+// VAD and no commit the end of the speech still reaches the server.
+func (p *audioPacer) run() {
+	var due time.Time // real time of the end of the audio sent
+	for {
+		p.mu.Lock()
+		ok := p.frameBytes > 0 && p.err == nil
+		full := ok && len(p.pending) >= p.frameBytes
+		partial := ok && !full && len(p.pending) >= p.sampleSize
+		p.mu.Unlock()
+		if !full {
+			if !p.waitIdle(partial) {
+				return
+			}
+			continue
+		}
+
+		now := time.Now()
+		if due.Before(now) {
+			due = now
+		}
+		if wait := due.Sub(now) - pacerLead; wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-p.closeCh:
+				timer.Stop()
+				return
+			}
+		}
+		due = due.Add(p.sendNext(false))
+	}
+}
+
+// waitIdle waits for audio to be queued or the pacer to be closed. With
+// partial, it sends the partial frame queued if no audio is queued for
+// pacerFrame. It returns false once the pacer is closed.
+func (p *audioPacer) waitIdle(partial bool) bool {
+	var idle <-chan time.Time
+	if partial {
+		timer := time.NewTimer(pacerFrame)
+		defer timer.Stop()
+		idle = timer.C
+	}
+	select {
+	case <-p.wake:
+	case <-p.closeCh:
+		return false
+	case <-idle:
+		p.sendNext(true)
+	}
+	return true
+}
+
+// sendNext sends the next whole frame queued or, with partial, the whole
+// samples queued if less than a frame. It returns the duration of the
+// audio sent.
+func (p *audioPacer) sendNext(partial bool) time.Duration {
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+
+	p.mu.Lock()
+	var n int
+	switch {
+	case len(p.pending) >= p.frameBytes:
+		n = p.frameBytes
+	case partial:
+		n = len(p.pending) - len(p.pending)%p.sampleSize
+	}
+	frame := p.pending[:n:n]
+	p.pending = p.pending[n:]
+	frameBytes := p.frameBytes
+	p.mu.Unlock()
+
+	if n == 0 {
+		return 0
+	}
+	if err := p.send(frame); err != nil {
+		p.fail(err)
+	}
+	return pacerFrame * time.Duration(n) / time.Duration(frameBytes)
+}
+
+// stripWAVLocked returns the samples of audio if it is a WAV file in the
+// input format, and an error if it is a WAV file in another format. Other
+// audio is returned unchanged.
+func (p *audioPacer) stripWAVLocked(audio []byte) ([]byte, error) {
+	if len(audio) < 12 || !bytes.Equal(audio[:4], []byte("RIFF")) || !bytes.Equal(audio[8:12], []byte("WAVE")) {
+		return audio, nil
+	}
+
+	var wavFormat, channels, bits uint16
+	var rate uint32
+	for rest := audio[12:]; len(rest) >= 8; {
+		id, size := string(rest[:4]), int(binary.LittleEndian.Uint32(rest[4:8]))
+		rest = rest[8:]
+		switch {
+		case id == "fmt " && size >= 16 && len(rest) >= 16:
+			wavFormat = binary.LittleEndian.Uint16(rest[0:2])
+			channels = binary.LittleEndian.Uint16(rest[2:4])
+			rate = binary.LittleEndian.Uint32(rest[4:8])
+			bits = binary.LittleEndian.Uint16(rest[14:16])
+		case id == "data":
+			if err := p.checkWAVLocked(wavFormat, channels, bits, rate); err != nil {
+				return nil, err
+			}
+			return rest[:min(size, len(rest))], nil
+		}
+		if size > len(rest) {
+			break
+		}
+		rest = rest[size+size%2:]
+	}
+	return nil, fmt.Errorf("openai-realtime: WAV audio without data chunk")
+}
+
+// checkWAVLocked checks the format of a WAV file against the input format.
+func (p *audioPacer) checkWAVLocked(wavFormat, channels, bits uint16, rate uint32) error {
+	// WAVE format tags: 1 PCM, 6 A-law, 7 μ-law
+	want := map[string]uint16{AudioFormatG711ALaw: 6, AudioFormatG711ULaw: 7}[p.format]
+	if want == 0 {
+		want = 1
+	}
+	if wavFormat != want || channels != 1 || int(bits) != p.sampleSize*8 || int(rate) != AudioSampleRate(p.format) {
+		return fmt.Errorf("openai-realtime: WAV audio is format %d, %d channels, %d-bit at %d Hz; the session input is %s, mono at %d Hz",
+			wavFormat, channels, bits, rate, p.inputFormatLocked(), AudioSampleRate(p.format))
+	}
+	return nil
+}
+
+func (p *audioPacer) inputFormatLocked() string {
+	if p.format == "" {
+		return AudioFormatPCM16
+	}
+	return p.format
+}
@@ -0,0 +1,366 @@
+package openairealtime
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// sentFrame is audio sent by a pacer, at the time it was sent.
+type sentFrame struct {
+	at    time.Duration
+	audio []byte
+}
+
+// fakeSend records the audio sent by a pacer.
+type fakeSend struct {
+	start time.Time
+	err   error
+
+	mu     sync.Mutex
+	frames []sentFrame
+}
+
+func (f *fakeSend) send(audio []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.frames = append(f.frames, sentFrame{at: time.Since(f.start), audio: bytes.Clone(audio)})
+	return f.err
+}
+
+func (f *fakeSend) sent() []sentFrame {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]sentFrame(nil), f.frames...)
+}
+
+func (f *fakeSend) sizes() []int {
+	var sizes []int
+	for _, fr := range f.sent() {
+		sizes = append(sizes, len(fr.audio))
+	}
+	return sizes
+}
+
+func (f *fakeSend) audio() []byte {
+	var audio []byte
+	for _, fr := range f.sent() {
+		audio = append(audio, fr.audio...)
+	}
+	return audio
+}
+
+// newTestPacer starts a pacer sending to a fakeSend. It must be called in
+// a synctest bubble.
+func newTestPacer(t *testing.T, format string) (*audioPacer, *fakeSend) {
+	f := &fakeSend{start: time.Now()}
+	p := newAudioPacer(format, f.send)
+	t.Cleanup(p.close)
+	return p, f
+}
+
+// ramp returns n bytes counting up, to check the order of the audio sent.
+func ramp(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+// wavFile returns audio in a WAV file with the given format.
+func wavFile(tag, channels, bits uint16, rate uint32, audio []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(4+8+16+8+len(audio)))
+	b.WriteString("WAVEfmt ")
+	blockAlign := channels * bits / 8
+	for _, v := range []any{uint32(16), tag, channels, rate, rate * uint32(blockAlign), blockAlign, bits} {
+		binary.Write(&b, binary.LittleEndian, v)
+	}
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(len(audio)))
+	b.Write(audio)
+	return b.Bytes()
+}
+
+func TestAudioPacer_Rechunk(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		p, f := newTestPacer(t, AudioFormatPCM16)
+		audio := ramp(2500)
+		if err := p.append(audio[:1000]); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.append(audio[1000:]); err != nil {
+			t.Fatal(err)
+		}
+		synctest.Wait()
+		// 20ms of 24kHz pcm16 is 960 bytes
+		if got := f.sizes(); !slices.Equal(got, []int{960, 960}) {
+			t.Fatalf("frames = %v, want [960 960] before the input pauses", got)
+		}
+
+		// The trailing partial frame is sent once no audio is appended for
+		// a frame duration, without a commit
+		time.Sleep(pacerFrame)
+		synctest.Wait()
+		if got := f.sizes(); !slices.Equal(got, []int{960, 960, 580}) {
+			t.Errorf("frames = %v, want [960 960 580]", got)
+		}
+		if !bytes.Equal(f.audio(), audio) {
+			t.Error("audio sent differs from the audio appended")
+		}
+	})
+}
+
+func TestAudioPacer_PartialWaitsForMore(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		p, f := newTestPacer(t, AudioFormatG711ULaw)
+		// 20ms of 8kHz G.711 is 160 bytes
+		p.append(ramp(100))
+		time.Sleep(pacerFrame / 2)
+		p.append(ramp(100))
+		synctest.Wait()
+		if got := f.sizes(); !slices.Equal(got, []int{160}) {
+			t.Fatalf("frames = %v, want [160]", got)
+		}
+		time.Sleep(pacerFrame)
+		synctest.Wait()
+		if got := f.sizes(); !slices.Equal(got, []int{160, 40}) {
+			t.Errorf("frames = %v, want [160 40]", got)
+		}
+	})
+}
+
+func TestAudioPacer_Lead(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		p, f := newTestPacer(t, AudioFormatPCM16)
+		p.append(make([]byte, 48000)) // 1s
+		synctest.Wait()
+		// Up to pacerLead ahead of real time: 5 frames, and the 6th once
+		// the first is 100ms ahead
+		lead := int(pacerLead/pacerFrame) + 1
+		if got := len(f.sent()); got != lead {
+			t.Fatalf("frames at start = %d, want %d", got, lead)
+		}
+
+		time.Sleep(200 * time.Millisecond)
+		synctest.Wait()
+		if got := len(f.sent()); got != lead+10 {
+			t.Errorf("frames after 200ms = %d, want %d", got, lead+10)
+		}
+		for _, fr := range f.sent()[lead:] {
+			if fr.at%pacerFrame != 0 {
+				t.Errorf("frame sent at %v, want on the 20ms pace", fr.at)
+			}
+		}
+
+		time.Sleep(time.Second)
+		synctest.Wait()
+		if got := len(f.sent()); got != 50 {
+			t.Errorf("frames = %d, want 50", got)
+		}
+	})
+}
+
+func TestAudioPacer_PaceReset(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		p, f := newTestPacer(t, AudioFormatPCM16)
+		p.append(make([]byte, 960*10))
+		time.Sleep(5 * time.Second)
+		synctest.Wait()
+		if got := len(f.sent()); got != 10 {
+			t.Fatalf("frames = %d, want 10", got)
+		}
+
+		// After a pause the pace restarts from now: no burst to catch up
+		// with the time the caller fell behind
+		start := time.Since(f.start)
+		p.append(make([]byte, 960*20))
+		synctest.Wait()
+		lead := int(pacerLead/pacerFrame) + 1
+		if got := len(f.sent()) - 10; got != lead {
+			t.Errorf("frames sent at once after the pause = %d, want %d", got, lead)
+		}
+		time.Sleep(time.Second)
+		synctest.Wait()
+		frames := f.sent()
+		if len(frames) != 30 {
+			t.Fatalf("frames = %d, want 30", len(frames))
+		}
+		if last := frames[29].at - start; last != time.Duration(20-lead)*pacerFrame {
+			t.Errorf("last frame sent %v after the pause, want %v", last, time.Duration(20-lead)*pacerFrame)
+		}
+	})
+}
+
+func TestAudioPacer_Flush(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		p, f := newTestPacer(t, AudioFormatPCM16)
+		audio := ramp(960*20 + 101)
+		p.append(audio)
+		synctest.Wait()
+		sent := len(f.sent())
+
+		if err := p.flush(); err != nil {
+			t.Fatal(err)
+		}
+		frames := f.sent()
+		if len(frames) != sent+1 {
+			t.Fatalf("flush sent %d events, want 1", len(frames)-sent)
+		}
+		// The trailing partial sample is dropped
+		if got, want := f.audio(), audio[:len(audio)-1]; !bytes.Equal(got, want) {
+			t.Errorf("audio sent = %d bytes, want the first %d bytes appended", len(got), len(want))
+		}
+
+		// Nothing is left to send by the pacer
+		time.Sleep(time.Second)
+		synctest.Wait()
+		if got := len(f.sent()); got != sent+1 {
+			t.Errorf("%d frames sent after flush", got-sent-1)
+		}
+		if err := p.flush(); err != nil || len(f.sent()) != sent+1 {
+			t.Errorf("flush of nothing: err = %v, %d sends", err, len(f.sent())-sent-1)
+		}
+	})
+}
+
+func TestAudioPacer_FlushOrder(t *testing.T) {
+	// A flush while the pacer is sending a frame waits for it, so the audio
+	// is sent in order. The flush blocks on a mutex, which synctest does
+	// not see as durably blocked, so this test runs in real time.
+	sending, release := make(chan struct{}), make(chan struct{})
+	var mu sync.Mutex
+	var sent []byte
+	send := func(audio []byte) error {
+		mu.Lock()
+		first := len(sent) == 0
+		mu.Unlock()
+		if first {
+			close(sending)
+			<-release
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, audio...)
+		return nil
+	}
+	p := newAudioPacer(AudioFormatPCM16, send)
+	defer p.close()
+	audio := ramp(960 * 3)
+	p.append(audio)
+	<-sending
+
+	done := make(chan error)
+	go func() { done <- p.flush() }()
+	select {
+	case <-done:
+		t.Fatal("flush did not wait for the frame being sent")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !bytes.Equal(sent, audio) {
+		t.Error("audio sent out of order")
+	}
+}
+
+func TestAudioPacer_Clear(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		p, f := newTestPacer(t, AudioFormatPCM16)
+		p.append(make([]byte, 960*20))
+		synctest.Wait()
+		sent := len(f.sent())
+		p.clear()
+
+		time.Sleep(time.Second)
+		synctest.Wait()
+		if got := len(f.sent()); got != sent {
+			t.Errorf("%d frames sent after clear", got-sent)
+		}
+
+		// Audio appended after clear is sent
+		p.append(make([]byte, 960))
+		synctest.Wait()
+		if got := len(f.sent()); got != sent+1 {
+			t.Errorf("%d frames sent after clear and append, want 1", got-sent)
+		}
+	})
+}
+
+func TestAudioPacer_WAV(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		wav     []byte
+		wantErr string
+	}{
+		{"pcm16", AudioFormatPCM16, wavFile(1, 1, 16, 24000, ramp(960)), ""},
+		{"default format", "", wavFile(1, 1, 16, 24000, ramp(960)), ""},
+		{"g711 ulaw", AudioFormatG711ULaw, wavFile(7, 1, 8, 8000, ramp(160)), ""},
+		{"g711 alaw", AudioFormatG711ALaw, wavFile(6, 1, 8, 8000, ramp(160)), ""},
+		{"sample rate", AudioFormatPCM16, wavFile(1, 1, 16, 16000, ramp(960)), "16000 Hz; the session input is pcm16, mono at 24000 Hz"},
+		{"stereo", AudioFormatPCM16, wavFile(1, 2, 16, 24000, ramp(960)), "2 channels"},
+		{"ulaw for alaw", AudioFormatG711ALaw, wavFile(7, 1, 8, 8000, ramp(160)), "format 7"},
+		{"pcm for ulaw", AudioFormatG711ULaw, wavFile(1, 1, 16, 8000, ramp(160)), "format 1"},
+		{"no data", AudioFormatPCM16, wavFile(1, 1, 16, 24000, nil)[:36], "without data chunk"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				p, f := newTestPacer(t, tt.format)
+				err := p.append(tt.wav)
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Errorf("append = %v, want error with %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				synctest.Wait()
+				if got, want := f.audio(), tt.wav[44:]; !bytes.Equal(got, want) {
+					t.Errorf("sent %d bytes, want the %d bytes of the data chunk", len(got), len(want))
+				}
+			})
+		})
+	}
+}
+
+func TestAudioPacer_Errors(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		p, f := newTestPacer(t, "opus")
+		if err := p.append(make([]byte, 960)); err == nil || !strings.Contains(err.Error(), `unsupported audio format "opus"`) {
+			t.Errorf("append in an unknown format = %v", err)
+		}
+
+		// A send error is returned by the calls that follow
+		p.setFormat(AudioFormatPCM16)
+		f.err = errors.New("connection closed")
+		if err := p.append(make([]byte, 960)); err != nil {
+			t.Fatal(err)
+		}
+		synctest.Wait()
+		if err := p.append(make([]byte, 960)); !errors.Is(err, f.err) {
+			t.Errorf("append after send error = %v", err)
+		}
+		if err := p.flush(); !errors.Is(err, f.err) {
+			t.Errorf("flush after send error = %v", err)
+		}
+		if err := p.appendBase64("!"); err == nil || !strings.Contains(err.Error(), "invalid base64") {
+			t.Errorf("appendBase64 of invalid base64 = %v", err)
+		}
+	})
+}
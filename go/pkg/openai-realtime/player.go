@@ -0,0 +1,247 @@
+package openairealtime
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// playerFrame is the amount of audio a Player waits for before a Read
+// returns, and so the granularity of its pacing.
+const playerFrame = 20 * time.Millisecond
+
+// playerMaxLag bounds how far a Player catches up after its reader stalls;
+// older audio time is skipped instead of being emitted as a burst.
+const playerMaxLag = 200 * time.Millisecond
+
+// PlaybackPosition identifies the audio a Player is playing: the item, its
+// content part and the offset within that part's audio. It is what
+// Session.TruncateItem needs after an interruption.
+type PlaybackPosition struct {
+	ItemID       string
+	ContentIndex int
+	Ms           int
+}
+
+// Player paces response audio for playback. Feed it every server event
+// with Process; Read then returns 16-bit little-endian PCM at the session's
+// output sample rate (see AudioSampleRate), at wall-clock rate. When no
+// audio is buffered, Read fills with silence, so the reader can be wired
+// directly to a sound card or an RTP sender. G.711 output is decoded.
+//
+// Buffered audio is dropped when:
+//   - the user starts speaking (input_audio_buffer.speech_started),
+//   - a response is done with status "cancelled",
+//   - an item is truncated,
+//   - Interrupt is called, e.g. after sending response.cancel.
+//
+// Deltas of an interrupted response that arrive afterwards are ignored.
+//
+// Process and Interrupt may be called concurrently with Read.
+type Player struct {
+	format     string
+	bytesPerMs int
+
+	mu        sync.Mutex
+	segments  []playerSegment
+	cancelled map[string]bool // response IDs
+	pos       PlaybackPosition
+	posBytes  int
+	playing   string // response ID of the audio played last
+	start     time.Time
+	emitted   int64
+	closed    bool
+	done      chan struct{}
+}
+
+type playerSegment struct {
+	responseID   string
+	itemID       string
+	contentIndex int
+	data         []byte
+}
+
+var _ io.ReadCloser = (*Player)(nil)
+
+// NewPlayer creates a Player for the session's output audio format
+// (AudioFormatPCM16, AudioFormatG711ULaw or AudioFormatG711ALaw). An empty
+// format means AudioFormatPCM16.
+func NewPlayer(outputAudioFormat string) *Player {
+	rate := AudioSampleRate(outputAudioFormat)
+	if rate == 0 {
+		rate = 24000
+	}
+	return &Player{
+		format:     outputAudioFormat,
+		bytesPerMs: rate * 2 / 1000,
+		cancelled:  make(map[string]bool),
+		done:       make(chan struct{}),
+	}
+}
+
+// Process updates the player with a server event.
+func (p *Player) Process(event *ServerEvent) {
+	switch event.Type {
+	case EventTypeResponseAudioDelta:
+		pcm, err := DecodeAudio(p.format, event.Audio)
+		if err != nil || len(pcm) == 0 {
+			return
+		}
+		p.mu.Lock()
+		if !p.cancelled[event.ResponseID] {
+			p.segments = append(p.segments, playerSegment{
+				responseID:   event.ResponseID,
+				itemID:       event.ItemID,
+				contentIndex: event.ContentIndex,
+				data:         pcm,
+			})
+		}
+		p.mu.Unlock()
+
+	case EventTypeInputAudioBufferSpeechStarted:
+		p.Interrupt()
+
+	case EventTypeResponseDone:
+		if event.Response == nil {
+			return
+		}
+		p.mu.Lock()
+		if event.Response.Status == "cancelled" {
+			p.drop(func(s *playerSegment) bool { return s.responseID == event.Response.ID })
+		}
+		delete(p.cancelled, event.Response.ID)
+		p.mu.Unlock()
+
+	case EventTypeConversationItemTruncated:
+		p.mu.Lock()
+		p.drop(func(s *playerSegment) bool { return s.itemID == event.ItemID })
+		p.mu.Unlock()
+	}
+}
+
+// Interrupt drops the buffered audio and ignores further deltas of the
+// responses it belonged to. It returns the position playback stopped at;
+// pass it to Session.TruncateItem so the conversation matches what the
+// user heard.
+func (p *Player) Interrupt() PlaybackPosition {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.playing != "" {
+		p.cancelled[p.playing] = true
+	}
+	for _, s := range p.segments {
+		if s.responseID != "" {
+			p.cancelled[s.responseID] = true
+		}
+	}
+	p.segments = nil
+	return p.pos
+}
+
+// Position returns the position of the audio played last.
+func (p *Player) Position() PlaybackPosition {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pos
+}
+
+// Buffered returns the duration of audio waiting to be played.
+func (p *Player) Buffered() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, s := range p.segments {
+		n += len(s.data)
+	}
+	return time.Duration(n/p.bytesPerMs) * time.Millisecond
+}
+
+// Read blocks until audio is due and reads it into b. The pacing clock
+// starts with the first Read. Read returns io.EOF after Close.
+func (p *Player) Read(b []byte) (int, error) {
+	b = b[:len(b)&^1] // whole samples
+	if len(b) == 0 {
+		return 0, nil
+	}
+	frame := int64(playerFrame.Milliseconds()) * int64(p.bytesPerMs)
+	want := min(int64(len(b)), frame)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.start.IsZero() {
+		p.start = time.Now()
+	}
+	for {
+		if p.closed {
+			return 0, io.EOF
+		}
+		due := p.due()
+		if lag := int64(playerMaxLag.Milliseconds()) * int64(p.bytesPerMs); due-p.emitted > lag {
+			// The reader stalled; skip ahead instead of bursting.
+			p.emitted = due - lag
+		}
+		if avail := due - p.emitted; avail >= want {
+			n := int(min(avail, int64(len(b))))
+			n &^= 1
+			p.fill(b[:n])
+			p.emitted += int64(n)
+			return n, nil
+		}
+		wait := time.Duration(want-(due-p.emitted)) * time.Millisecond / time.Duration(p.bytesPerMs)
+		p.mu.Unlock()
+		select {
+		case <-time.After(max(wait, time.Millisecond)):
+		case <-p.done:
+		}
+		p.mu.Lock()
+	}
+}
+
+// due returns the number of bytes that should have been played by now.
+func (p *Player) due() int64 {
+	return int64(time.Since(p.start)) * int64(p.bytesPerMs) / int64(time.Millisecond)
+}
+
+// fill copies buffered audio into b and pads it with silence.
+func (p *Player) fill(b []byte) {
+	n := 0
+	for n < len(b) && len(p.segments) > 0 {
+		s := &p.segments[0]
+		if s.itemID != p.pos.ItemID || s.contentIndex != p.pos.ContentIndex {
+			p.pos = PlaybackPosition{ItemID: s.itemID, ContentIndex: s.contentIndex}
+			p.posBytes = 0
+		}
+		p.playing = s.responseID
+		c := copy(b[n:], s.data)
+		s.data = s.data[c:]
+		n += c
+		p.posBytes += c
+		p.pos.Ms = p.posBytes / p.bytesPerMs
+		if len(s.data) == 0 {
+			p.segments = p.segments[1:]
+		}
+	}
+	clear(b[n:])
+}
+
+// drop removes the buffered segments matching f.
+func (p *Player) drop(f func(*playerSegment) bool) {
+	kept := p.segments[:0]
+	for i := range p.segments {
+		if !f(&p.segments[i]) {
+			kept = append(kept, p.segments[i])
+		}
+	}
+	p.segments = kept
+}
+
+// Close stops the player; pending and future Reads return io.EOF.
+func (p *Player) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.closed = true
+		close(p.done)
+	}
+	return nil
+}
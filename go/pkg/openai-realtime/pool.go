@@ -0,0 +1,343 @@
+package openairealtime
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned by SessionPool.Acquire after Close.
+var ErrPoolClosed = errors.New("openai-realtime: session pool closed")
+
+// ErrPoolBusy is returned by SessionPool.Acquire when MaxActive sessions
+// are in use and MaxWaiting callers are already queued.
+var ErrPoolBusy = errors.New("openai-realtime: session pool busy")
+
+// SessionPoolConfig configures a SessionPool.
+type SessionPoolConfig struct {
+	// Connect is the connection config of every session.
+	Connect *ConnectConfig
+
+	// Session, if set, is sent with UpdateSession when a session is
+	// dialed, so sessions are handed out configured.
+	Session *SessionConfig
+
+	// Warm is the number of connected idle sessions to keep ready.
+	// Default: 2
+	Warm int
+
+	// MaxActive is the maximum number of sessions handed out at once.
+	// Acquire waits for a session to be returned beyond it.
+	// Default: 16
+	MaxActive int
+
+	// MaxWaiting is the maximum number of Acquire calls waiting for a
+	// session; further calls fail with ErrPoolBusy. Zero means no limit.
+	MaxWaiting int
+
+	// MaxIdle is how long a warm session is kept before it is replaced.
+	// Realtime sessions have a limited lifetime, so a session must not sit
+	// in the pool for too long.
+	// Default: 10m
+	MaxIdle time.Duration
+}
+
+func (c *SessionPoolConfig) setDefaults() {
+	if c.Warm <= 0 {
+		c.Warm = 2
+	}
+	if c.MaxActive <= 0 {
+		c.MaxActive = 16
+	}
+	if c.MaxIdle <= 0 {
+		c.MaxIdle = 10 * time.Minute
+	}
+}
+
+// SessionPoolStats is a snapshot of a SessionPool.
+type SessionPoolStats struct {
+	// Active is the number of sessions handed out.
+	Active int
+	// Warm is the number of idle sessions ready to be handed out.
+	Warm int
+	// Waiting is the number of Acquire calls waiting for a session.
+	Waiting int
+}
+
+// SessionPool keeps WebSocket sessions connected and configured ahead of
+// time, so a conversation does not pay for the connection and session
+// setup, and limits the number of concurrent sessions.
+//
+// A session belongs to one conversation: closing a session returned by
+// Acquire closes its connection and frees its slot, and the pool dials a
+// replacement in the background. Sessions are never reused.
+//
+// SessionPool is safe for concurrent use.
+type SessionPool struct {
+	client *Client
+	config SessionPoolConfig
+
+	slots  chan struct{}
+	refill chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	warm    []warmSession
+	waiting int
+	closed  bool
+}
+
+type warmSession struct {
+	session Session
+	dialed  time.Time
+}
+
+// NewSessionPool creates a SessionPool and starts dialing the warm
+// sessions in the background.
+func NewSessionPool(client *Client, config *SessionPoolConfig) *SessionPool {
+	cfg := SessionPoolConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	cfg.setDefaults()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &SessionPool{
+		client: client,
+		config: cfg,
+		slots:  make(chan struct{}, cfg.MaxActive),
+		refill: make(chan struct{}, 1),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	p.wg.Add(1)
+	go p.refillLoop()
+	return p
+}
+
+// Acquire returns a session for one conversation, waiting while MaxActive
+// sessions are in use. Close the session to return its slot.
+//
+// A warm session is handed out if one is ready; otherwise a session is
+// dialed with ctx.
+func (p *SessionPool) Acquire(ctx context.Context) (Session, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrPoolClosed
+	}
+	select {
+	case p.slots <- struct{}{}:
+		p.mu.Unlock()
+	default:
+		if p.config.MaxWaiting > 0 && p.waiting >= p.config.MaxWaiting {
+			p.mu.Unlock()
+			return nil, ErrPoolBusy
+		}
+		p.waiting++
+		p.mu.Unlock()
+
+		var err error
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-p.ctx.Done():
+			err = ErrPoolClosed
+		}
+		p.mu.Lock()
+		p.waiting--
+		p.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// A slot freed by a closing session may have won against Close
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		<-p.slots
+		return nil, ErrPoolClosed
+	}
+
+	session := p.takeWarm()
+	p.signalRefill()
+	if session == nil {
+		var err error
+		session, err = p.dial(ctx)
+		if err != nil {
+			<-p.slots
+			return nil, err
+		}
+	}
+	return &pooledSession{Session: session, pool: p}, nil
+}
+
+// Stats returns the current pool state.
+func (p *SessionPool) Stats() SessionPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return SessionPoolStats{
+		Active:  len(p.slots),
+		Warm:    len(p.warm),
+		Waiting: p.waiting,
+	}
+}
+
+// Close closes the warm sessions and fails waiting and future Acquire
+// calls. Sessions already handed out stay open until they are closed.
+func (p *SessionPool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	warm := p.warm
+	p.warm = nil
+	p.mu.Unlock()
+
+	p.cancel()
+	p.wg.Wait()
+	for _, w := range warm {
+		w.session.Close()
+	}
+	return nil
+}
+
+func (p *SessionPool) takeWarm() Session {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.warm) > 0 {
+		w := p.warm[0]
+		p.warm = p.warm[1:]
+		if time.Since(w.dialed) < p.config.MaxIdle {
+			return w.session
+		}
+		go w.session.Close()
+	}
+	return nil
+}
+
+func (p *SessionPool) signalRefill() {
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+}
+
+// dial connects and configures a session.
+func (p *SessionPool) dial(ctx context.Context) (Session, error) {
+	var connect ConnectConfig
+	if p.config.Connect != nil {
+		connect = *p.config.Connect
+	}
+	session, err := p.client.ConnectWebSocket(ctx, &connect)
+	if err != nil {
+		return nil, err
+	}
+	if p.config.Session != nil {
+		if err := session.UpdateSession(p.config.Session); err != nil {
+			session.Close()
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+// refillLoop keeps Warm sessions ready and replaces expired ones.
+func (p *SessionPool) refillLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(max(p.config.MaxIdle/4, time.Second))
+	defer ticker.Stop()
+
+	backoff := time.Second
+	for {
+		p.expire()
+		for p.needsWarm() {
+			session, err := p.dial(p.ctx)
+			if err != nil {
+				if p.ctx.Err() != nil {
+					return
+				}
+				slog.Warn("openai-realtime: pool failed to dial session", "error", err, "retry", backoff)
+				select {
+				case <-time.After(backoff):
+				case <-p.ctx.Done():
+					return
+				}
+				backoff = min(backoff*2, 30*time.Second)
+				continue
+			}
+			backoff = time.Second
+
+			p.mu.Lock()
+			if p.closed {
+				p.mu.Unlock()
+				session.Close()
+				return
+			}
+			p.warm = append(p.warm, warmSession{session: session, dialed: time.Now()})
+			p.mu.Unlock()
+		}
+
+		select {
+		case <-p.refill:
+		case <-ticker.C:
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *SessionPool) needsWarm() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return !p.closed && len(p.warm) < p.config.Warm
+}
+
+// expire closes warm sessions older than MaxIdle.
+func (p *SessionPool) expire() {
+	p.mu.Lock()
+	var expired []Session
+	kept := p.warm[:0]
+	for _, w := range p.warm {
+		if time.Since(w.dialed) >= p.config.MaxIdle {
+			expired = append(expired, w.session)
+		} else {
+			kept = append(kept, w)
+		}
+	}
+	p.warm = kept
+	p.mu.Unlock()
+
+	for _, s := range expired {
+		s.Close()
+	}
+}
+
+// pooledSession returns its slot to the pool when closed.
+type pooledSession struct {
+	Session
+	pool *SessionPool
+	once sync.Once
+}
+
+func (s *pooledSession) Close() error {
+	err := s.Session.Close()
+	s.once.Do(func() { <-s.pool.slots })
+	return err
+}
+
+func (s *pooledSession) Shutdown(ctx context.Context) (SessionUsage, error) {
+	usage, err := s.Session.Shutdown(ctx)
+	s.once.Do(func() { <-s.pool.slots })
+	return usage, err
+}
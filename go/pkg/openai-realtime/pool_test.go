@@ -0,0 +1,163 @@
+package openairealtime_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	openairealtime "github.com/haivivi/giztoy/go/pkg/openai-realtime"
+	"github.com/haivivi/giztoy/go/pkg/openai-realtime/openairealtimetest"
+)
+
+// waitFor polls cond until it holds or fails the test after 5s.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func newTestPool(t *testing.T, config *openairealtime.SessionPoolConfig) (*openairealtime.SessionPool, *openairealtimetest.Server) {
+	t.Helper()
+	srv := openairealtimetest.NewServer()
+	t.Cleanup(srv.Close)
+	pool := openairealtime.NewSessionPool(srv.Client(), config)
+	t.Cleanup(func() { pool.Close() })
+	return pool, srv
+}
+
+func TestSessionPool_Warm(t *testing.T) {
+	pool, srv := newTestPool(t, &openairealtime.SessionPoolConfig{
+		Warm:    2,
+		Session: &openairealtime.SessionConfig{Instructions: "be brief"},
+	})
+	waitFor(t, "2 warm sessions", func() bool { return pool.Stats().Warm == 2 })
+	if n := srv.Sessions(); n != 2 {
+		t.Fatalf("server sessions = %d, want 2", n)
+	}
+
+	session, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Sessions(); n != 2 {
+		t.Errorf("server sessions after Acquire = %d, want the warm session handed out", n)
+	}
+	if got := pool.Stats().Active; got != 1 {
+		t.Errorf("active = %d, want 1", got)
+	}
+
+	// A warm session was handed out and is replaced in the background,
+	// configured
+	waitFor(t, "the pool to refill", func() bool { return pool.Stats().Warm == 2 })
+	if n := srv.Sessions(); n != 3 {
+		t.Errorf("server sessions = %d, want 3 (2 warm, 1 refilled)", n)
+	}
+	if n := len(srv.ClientEvents(openairealtime.EventTypeSessionUpdate)); n != 3 {
+		t.Errorf("session.update sent %d times, want once per session", n)
+	}
+
+	session.Close()
+	if got := pool.Stats().Active; got != 0 {
+		t.Errorf("active after Close = %d, want 0", got)
+	}
+}
+
+func TestSessionPool_Busy(t *testing.T) {
+	pool, _ := newTestPool(t, &openairealtime.SessionPoolConfig{Warm: 1, MaxActive: 1, MaxWaiting: 1})
+
+	first, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		session openairealtime.Session
+		err     error
+	}
+	waiter := make(chan result, 1)
+	go func() {
+		s, err := pool.Acquire(context.Background())
+		waiter <- result{s, err}
+	}()
+	waitFor(t, "a waiting Acquire", func() bool { return pool.Stats().Waiting == 1 })
+
+	if _, err := pool.Acquire(context.Background()); !errors.Is(err, openairealtime.ErrPoolBusy) {
+		t.Errorf("Acquire beyond MaxWaiting = %v, want ErrPoolBusy", err)
+	}
+
+	first.Close()
+	r := <-waiter
+	if r.err != nil {
+		t.Fatalf("waiting Acquire = %v", r.err)
+	}
+	defer r.session.Close()
+	if st := pool.Stats(); st.Active != 1 || st.Waiting != 0 {
+		t.Errorf("stats = %+v, want 1 active, none waiting", st)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pool.Acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Acquire with a canceled context = %v, want context.Canceled", err)
+	}
+}
+
+func TestSessionPool_MaxIdle(t *testing.T) {
+	pool, srv := newTestPool(t, &openairealtime.SessionPoolConfig{Warm: 1, MaxIdle: 200 * time.Millisecond})
+	waitFor(t, "a warm session", func() bool { return pool.Stats().Warm == 1 })
+	time.Sleep(300 * time.Millisecond)
+
+	session, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	// The expired session is dropped: Acquire dials one and the pool
+	// another to refill
+	waitFor(t, "the pool to refill", func() bool { return pool.Stats().Warm == 1 })
+	if n := srv.Sessions(); n != 3 {
+		t.Errorf("server sessions = %d, want 3 (expired, acquired, refilled)", n)
+	}
+}
+
+func TestSessionPool_Close(t *testing.T) {
+	pool, srv := newTestPool(t, &openairealtime.SessionPoolConfig{Warm: 1, MaxActive: 1})
+	first, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "a warm session", func() bool { return pool.Stats().Warm == 1 })
+
+	waiter := make(chan error, 1)
+	go func() {
+		s, err := pool.Acquire(context.Background())
+		if s != nil {
+			s.Close()
+		}
+		waiter <- err
+	}()
+	waitFor(t, "a waiting Acquire", func() bool { return pool.Stats().Waiting == 1 })
+	sessions := srv.Sessions()
+
+	pool.Close()
+	first.Close()
+	if err := <-waiter; !errors.Is(err, openairealtime.ErrPoolClosed) {
+		t.Errorf("waiting Acquire = %v, want ErrPoolClosed", err)
+	}
+	if _, err := pool.Acquire(context.Background()); !errors.Is(err, openairealtime.ErrPoolClosed) {
+		t.Errorf("Acquire after Close = %v, want ErrPoolClosed", err)
+	}
+	if st := pool.Stats(); st.Warm != 0 || st.Active != 0 {
+		t.Errorf("stats after Close = %+v, want no sessions", st)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := srv.Sessions(); n != sessions {
+		t.Errorf("%d sessions dialed after Close", n-sessions)
+	}
+}
@@ -0,0 +1,120 @@
+package openairealtime
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Conversation values for ResponseCreateOptions.Conversation.
+const (
+	// ConversationAuto adds the response to the default conversation.
+	ConversationAuto = "auto"
+	// ConversationNone creates an out-of-band response: it is not added to
+	// the conversation, so side tasks (classification, moderation, ...) can
+	// run while the main conversation continues. Use Metadata to tell its
+	// events apart.
+	ConversationNone = "none"
+)
+
+// responseCreateEvent builds the response.create event for opts. Fields of
+// opts override the session configuration for this response only.
+func responseCreateEvent(opts *ResponseCreateOptions) (map[string]interface{}, error) {
+	event := map[string]interface{}{
+		"event_id": generateEventID(),
+		"type":     EventTypeResponseCreate,
+	}
+	if opts == nil {
+		return event, nil
+	}
+
+	for _, m := range opts.Modalities {
+		if m != ModalityText && m != ModalityAudio {
+			return nil, fmt.Errorf("openai-realtime: unknown modality %q", m)
+		}
+	}
+	if slices.Contains(opts.Modalities, ModalityAudio) && !slices.Contains(opts.Modalities, ModalityText) {
+		return nil, fmt.Errorf("openai-realtime: audio responses must include the text modality")
+	}
+	if opts.UnlimitedOutputTokens && opts.MaxOutputTokens != nil {
+		return nil, fmt.Errorf("openai-realtime: MaxOutputTokens and UnlimitedOutputTokens are exclusive")
+	}
+
+	response := map[string]interface{}{}
+	if len(opts.Modalities) > 0 {
+		response["modalities"] = opts.Modalities
+	}
+	if opts.Instructions != "" {
+		response["instructions"] = opts.Instructions
+	}
+	if opts.Voice != "" {
+		response["voice"] = opts.Voice
+	}
+	if opts.OutputAudioFormat != "" {
+		response["output_audio_format"] = opts.OutputAudioFormat
+	}
+	if len(opts.Tools) > 0 {
+		response["tools"] = opts.Tools
+	}
+	if opts.ToolChoice != nil {
+		response["tool_choice"] = opts.ToolChoice
+	}
+	if opts.Temperature != nil {
+		response["temperature"] = *opts.Temperature
+	}
+	if opts.MaxOutputTokens != nil {
+		response["max_output_tokens"] = *opts.MaxOutputTokens
+	}
+	if opts.UnlimitedOutputTokens {
+		response["max_output_tokens"] = "inf"
+	}
+	if opts.Conversation != "" {
+		response["conversation"] = opts.Conversation
+	}
+	if len(opts.Input) > 0 {
+		response["input"] = opts.Input
+	}
+	if len(opts.Metadata) > 0 {
+		response["metadata"] = opts.Metadata
+	}
+	if len(response) > 0 {
+		event["response"] = response
+	}
+	return event, nil
+}
+
+// responseMetadata remembers the metadata of responses in progress, so it
+// can be attached to all of their events.
+type responseMetadata struct {
+	mu   sync.Mutex
+	byID map[string]map[string]string
+}
+
+// apply records metadata from response.created and sets
+// ServerEvent.ResponseMetadata on events of responses that have metadata.
+func (r *responseMetadata) apply(event *ServerEvent) {
+	id := event.ResponseID
+	if event.Response != nil {
+		id = event.Response.ID
+	}
+	if id == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch event.Type {
+	case EventTypeResponseCreated:
+		if event.Response != nil && len(event.Response.Metadata) > 0 {
+			if r.byID == nil {
+				r.byID = make(map[string]map[string]string)
+			}
+			r.byID[id] = event.Response.Metadata
+		}
+	case EventTypeResponseDone:
+		defer delete(r.byID, id)
+	}
+	if md, ok := r.byID[id]; ok {
+		event.ResponseMetadata = md
+	}
+}
@@ -1,6 +1,9 @@
 package openairealtime
 
-import "iter"
+import (
+	"context"
+	"iter"
+)
 
 // Session is the common interface for OpenAI Realtime sessions.
 // Both WebSocket and WebRTC implementations satisfy this interface.
@@ -14,6 +17,14 @@ type Session interface {
 	// Close closes the session connection.
 	Close() error
 
+	// Shutdown gracefully closes the session: it rejects new input with
+	// ErrShuttingDown, waits for the responses in progress to finish,
+	// closes the connection and returns the final usage. When ctx is done
+	// first, the responses are cancelled and ctx's error is returned.
+	// Keep consuming Events until it returns; the remaining events of the
+	// responses are delivered there.
+	Shutdown(ctx context.Context) (SessionUsage, error)
+
 	// SessionID returns the session ID assigned by the server.
 	// Returns empty string if session.created has not been received yet.
 	SessionID() string
@@ -27,6 +38,7 @@ type Session interface {
 	//   - Channels: Mono (1 channel)
 	//   - Encoding: Little-endian PCM
 	// The audio is automatically base64 encoded before sending.
+	// For G.711 input formats, pass the encoded bytes (see EncodeAudio).
 	AppendAudio(audio []byte) error
 
 	// AppendAudioBase64 appends base64-encoded audio data to the input buffer.
@@ -52,9 +64,23 @@ type Session interface {
 	// AddAssistantMessage adds an assistant text message to the conversation.
 	AddAssistantMessage(text string) error
 
+	// CreateItem adds an item (message, function_call or
+	// function_call_output) to the conversation. The item is inserted after
+	// previousItemID; pass "" to append it, or PreviousItemRoot to insert it
+	// at the start. Use it to inject system messages or restore history.
+	CreateItem(item *ConversationItem, previousItemID string) error
+
 	// AddFunctionCallOutput adds a function call output to the conversation.
 	AddFunctionCallOutput(callID string, output string) error
 
+	// SubmitToolOutput adds a function call output and requests a new
+	// response, so the model continues with the tool result.
+	SubmitToolOutput(callID string, output string) error
+
+	// AddMCPApprovalResponse approves or denies the MCP tool call of an
+	// mcp_approval_request item. The API runs an approved call itself.
+	AddMCPApprovalResponse(approvalRequestID string, approve bool) error
+
 	// TruncateItem truncates a conversation item (assistant audio).
 	// contentIndex is the index of the content part to truncate.
 	// audioEndMs is the audio end time in milliseconds.
@@ -63,6 +89,12 @@ type Session interface {
 	// DeleteItem deletes a conversation item.
 	DeleteItem(itemID string) error
 
+	// ListItems returns the conversation items in order. The list is kept
+	// from conversation.item.* and response.output_item.done events as
+	// they are received; items of responses still in progress may lack
+	// content.
+	ListItems() []ConversationItem
+
 	// === Response Control ===
 
 	// CreateResponse requests the model to generate a response.
@@ -81,6 +113,15 @@ type Session interface {
 	// After an error is yielded, iteration stops.
 	Events() iter.Seq2[*ServerEvent, error]
 
+	// === Usage ===
+
+	// Usage returns the token usage accumulated over the session's
+	// responses, e.g. to enforce per-device budgets.
+	Usage() SessionUsage
+
+	// RateLimits returns the latest rate limits reported by the server.
+	RateLimits() RateLimits
+
 	// === Raw Operations ===
 
 	// SendRaw sends a raw JSON event to the server.
@@ -0,0 +1,124 @@
+package openairealtime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrShuttingDown is returned by the methods of a session that sends
+// events after Shutdown was called.
+var ErrShuttingDown = errors.New("openai-realtime: session shutting down")
+
+const (
+	// cancelWait is how long Shutdown waits for the response.done of
+	// responses it cancelled, so their usage is counted.
+	cancelWait = time.Second
+
+	// closeWait is how long Shutdown waits for the server to answer the
+	// close frame.
+	closeWait = time.Second
+)
+
+// responseDrain tracks the responses in progress so that Shutdown can
+// wait for them to finish.
+type responseDrain struct {
+	mu       sync.Mutex
+	active   map[string]struct{}
+	shutdown bool
+	ended    bool          // no more events will be received
+	idle     chan struct{} // closed when shut down and no response is active
+}
+
+// apply tracks response.created and response.done.
+func (d *responseDrain) apply(event *ServerEvent) {
+	if event.Response == nil || event.Response.ID == "" {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch event.Type {
+	case EventTypeResponseCreated:
+		if d.active == nil {
+			d.active = make(map[string]struct{})
+		}
+		d.active[event.Response.ID] = struct{}{}
+	case EventTypeResponseDone:
+		delete(d.active, event.Response.ID)
+		d.signalLocked()
+	}
+}
+
+// end marks that the session receives no more events, so active
+// responses will never finish.
+func (d *responseDrain) end() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ended = true
+	d.signalLocked()
+}
+
+// begin starts the shutdown. It returns a channel that is closed when no
+// response is in progress.
+func (d *responseDrain) begin() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.shutdown {
+		d.shutdown = true
+		d.idle = make(chan struct{})
+		d.signalLocked()
+	}
+	return d.idle
+}
+
+// shuttingDown reports whether Shutdown was called.
+func (d *responseDrain) shuttingDown() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.shutdown
+}
+
+// admit returns ErrShuttingDown for events sent after Shutdown was
+// called, except response.cancel.
+func (d *responseDrain) admit(event map[string]interface{}) error {
+	if event["type"] == EventTypeResponseCancel {
+		return nil
+	}
+	if d.shuttingDown() {
+		return ErrShuttingDown
+	}
+	return nil
+}
+
+func (d *responseDrain) signalLocked() {
+	if !d.shutdown || (len(d.active) > 0 && !d.ended) {
+		return
+	}
+	select {
+	case <-d.idle:
+	default:
+		close(d.idle)
+	}
+}
+
+// drain waits until d is idle. When ctx is done first, it cancels the
+// response in progress with cancel, waits up to cancelWait for it to
+// finish and returns the context error.
+func (d *responseDrain) drain(ctx context.Context, cancel func() error) error {
+	idle := d.begin()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+	}
+
+	cancel()
+	timer := time.NewTimer(cancelWait)
+	defer timer.Stop()
+	select {
+	case <-idle:
+	case <-timer.C:
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,150 @@
+package openairealtime
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func responseEvent(typ, id string) *ServerEvent {
+	return &ServerEvent{Type: typ, Response: &ResponseResource{ID: id}}
+}
+
+// drainResult is the outcome of responseDrain.drain.
+type drainResult struct {
+	err   error
+	after time.Duration
+}
+
+// startDrain runs d.drain in a goroutine. It must be called in a synctest
+// bubble.
+func startDrain(ctx context.Context, d *responseDrain, cancel func() error) <-chan drainResult {
+	done := make(chan drainResult, 1)
+	start := time.Now()
+	go func() {
+		err := d.drain(ctx, cancel)
+		done <- drainResult{err: err, after: time.Since(start)}
+	}()
+	return done
+}
+
+func noCancel(t *testing.T) func() error {
+	return func() error {
+		t.Error("response cancelled")
+		return nil
+	}
+}
+
+func TestResponseDrain_IdleAtStart(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var d responseDrain
+		d.apply(responseEvent(EventTypeResponseCreated, "resp_1"))
+		d.apply(responseEvent(EventTypeResponseDone, "resp_1"))
+
+		if err := d.drain(context.Background(), noCancel(t)); err != nil {
+			t.Errorf("drain = %v", err)
+		}
+		if !d.shuttingDown() {
+			t.Error("not shutting down after drain")
+		}
+		if err := d.admit(map[string]interface{}{"type": EventTypeResponseCreate}); !errors.Is(err, ErrShuttingDown) {
+			t.Errorf("admit response.create = %v, want ErrShuttingDown", err)
+		}
+		if err := d.admit(map[string]interface{}{"type": EventTypeResponseCancel}); err != nil {
+			t.Errorf("admit response.cancel = %v", err)
+		}
+	})
+}
+
+func TestResponseDrain_WaitsForActiveResponses(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var d responseDrain
+		d.apply(responseEvent(EventTypeResponseCreated, "resp_1"))
+		d.apply(responseEvent(EventTypeResponseCreated, "resp_2"))
+		done := startDrain(context.Background(), &d, noCancel(t))
+
+		time.Sleep(time.Minute)
+		d.apply(responseEvent(EventTypeResponseDone, "resp_1"))
+		synctest.Wait()
+		select {
+		case res := <-done:
+			t.Fatalf("drain returned %v with resp_2 in progress", res.err)
+		default:
+		}
+
+		// Events without a response ID are ignored
+		d.apply(&ServerEvent{Type: EventTypeResponseDone})
+		d.apply(responseEvent(EventTypeResponseDone, "resp_2"))
+		res := <-done
+		if res.err != nil || res.after != time.Minute {
+			t.Errorf("drain = %v after %v, want nil after 1m", res.err, res.after)
+		}
+	})
+}
+
+func TestResponseDrain_TimeoutCancels(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var d responseDrain
+		d.apply(responseEvent(EventTypeResponseCreated, "resp_1"))
+		ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelCtx()
+
+		// The server answers the cancel with response.done
+		var cancels int
+		done := startDrain(ctx, &d, func() error {
+			cancels++
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				d.apply(responseEvent(EventTypeResponseDone, "resp_1"))
+			}()
+			return nil
+		})
+		res := <-done
+		if !errors.Is(res.err, context.DeadlineExceeded) || res.after != 5*time.Second+100*time.Millisecond {
+			t.Errorf("drain = %v after %v, want deadline exceeded after 5.1s", res.err, res.after)
+		}
+		if cancels != 1 {
+			t.Errorf("cancel called %d times, want 1", cancels)
+		}
+	})
+}
+
+func TestResponseDrain_TimeoutWithoutAnswer(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var d responseDrain
+		d.apply(responseEvent(EventTypeResponseCreated, "resp_1"))
+		ctx, cancelCtx := context.WithCancel(context.Background())
+
+		cancelled := make(chan struct{})
+		done := startDrain(ctx, &d, func() error {
+			close(cancelled)
+			return errors.New("connection closed")
+		})
+		time.Sleep(time.Second)
+		cancelCtx()
+		<-cancelled
+
+		// The response never finishes: drain gives up after cancelWait
+		res := <-done
+		if !errors.Is(res.err, context.Canceled) || res.after != time.Second+cancelWait {
+			t.Errorf("drain = %v after %v, want canceled after %v", res.err, res.after, time.Second+cancelWait)
+		}
+	})
+}
+
+func TestResponseDrain_SessionEnded(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var d responseDrain
+		d.apply(responseEvent(EventTypeResponseCreated, "resp_1"))
+		done := startDrain(context.Background(), &d, noCancel(t))
+
+		// The connection is lost: the response will never finish
+		time.Sleep(time.Second)
+		d.end()
+		if res := <-done; res.err != nil || res.after != time.Second {
+			t.Errorf("drain = %v after %v, want nil after 1s", res.err, res.after)
+		}
+	})
+}
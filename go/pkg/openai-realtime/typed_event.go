@@ -0,0 +1,346 @@
+package openairealtime
+
+// Typed server events. ServerEvent carries the fields of every event type;
+// the types below hold only the fields the API sends for one type, so a
+// type switch replaces switching on Type and guessing which fields are set:
+//
+//	switch ev := event.Typed().(type) {
+//	case *openairealtime.ResponseAudioDeltaEvent:
+//	    play(ev.Audio)
+//	case *openairealtime.InputSpeechStartedEvent:
+//	    stopPlayback()
+//	}
+//
+// or, for a single type:
+//
+//	if ev, ok := openairealtime.As[*openairealtime.ResponseTextDeltaEvent](event); ok {
+//	    fmt.Print(ev.Delta)
+//	}
+//
+// The ServerEvent fields remain available.
+
+// ContentRef identifies the content part a response event belongs to.
+type ContentRef struct {
+	ResponseID   string
+	ItemID       string
+	OutputIndex  int
+	ContentIndex int
+}
+
+// ErrorEvent is an "error" event.
+type ErrorEvent struct {
+	Error *EventError
+}
+
+// SessionCreatedEvent is a "session.created" event.
+type SessionCreatedEvent struct {
+	Session *SessionResource
+}
+
+// SessionUpdatedEvent is a "session.updated" event.
+type SessionUpdatedEvent struct {
+	Session *SessionResource
+}
+
+// ConversationCreatedEvent is a "conversation.created" event.
+type ConversationCreatedEvent struct {
+	Conversation *ConversationResource
+}
+
+// ConversationItemCreatedEvent is a "conversation.item.created" event.
+type ConversationItemCreatedEvent struct {
+	PreviousItemID string
+	Item           *ConversationItem
+}
+
+// InputTranscriptionDeltaEvent is a
+// "conversation.item.input_audio_transcription.delta" event, streamed by
+// the gpt-4o transcribe models.
+type InputTranscriptionDeltaEvent struct {
+	ItemID       string
+	ContentIndex int
+	Delta        string
+}
+
+// InputTranscriptionCompletedEvent is a
+// "conversation.item.input_audio_transcription.completed" event.
+type InputTranscriptionCompletedEvent struct {
+	ItemID       string
+	ContentIndex int
+	Transcript   string
+}
+
+// InputTranscriptionFailedEvent is a
+// "conversation.item.input_audio_transcription.failed" event.
+type InputTranscriptionFailedEvent struct {
+	ItemID       string
+	ContentIndex int
+	Error        *EventError
+}
+
+// ConversationItemTruncatedEvent is a "conversation.item.truncated" event.
+type ConversationItemTruncatedEvent struct {
+	ItemID       string
+	ContentIndex int
+	AudioEndMs   int
+}
+
+// ConversationItemDeletedEvent is a "conversation.item.deleted" event.
+type ConversationItemDeletedEvent struct {
+	ItemID string
+}
+
+// InputAudioCommittedEvent is an "input_audio_buffer.committed" event.
+type InputAudioCommittedEvent struct {
+	PreviousItemID string
+	ItemID         string
+}
+
+// InputAudioClearedEvent is an "input_audio_buffer.cleared" event.
+type InputAudioClearedEvent struct{}
+
+// InputSpeechStartedEvent is an "input_audio_buffer.speech_started" event.
+type InputSpeechStartedEvent struct {
+	ItemID       string
+	AudioStartMs int
+}
+
+// InputSpeechStoppedEvent is an "input_audio_buffer.speech_stopped" event.
+type InputSpeechStoppedEvent struct {
+	ItemID     string
+	AudioEndMs int
+}
+
+// ResponseCreatedEvent is a "response.created" event.
+type ResponseCreatedEvent struct {
+	Response *ResponseResource
+}
+
+// ResponseDoneEvent is a "response.done" event.
+type ResponseDoneEvent struct {
+	Response *ResponseResource
+}
+
+// ResponseOutputItemEvent is a "response.output_item.added" or
+// "response.output_item.done" event.
+type ResponseOutputItemEvent struct {
+	ResponseID  string
+	OutputIndex int
+	Item        *ConversationItem
+	Done        bool
+}
+
+// ResponseContentPartEvent is a "response.content_part.added" or
+// "response.content_part.done" event.
+type ResponseContentPartEvent struct {
+	ContentRef
+	Part *ContentPart
+	Done bool
+}
+
+// ResponseTextDeltaEvent is a "response.text.delta" event.
+type ResponseTextDeltaEvent struct {
+	ContentRef
+	Delta string
+}
+
+// ResponseTextDoneEvent is a "response.text.done" event.
+type ResponseTextDoneEvent struct {
+	ContentRef
+	Text string
+}
+
+// ResponseAudioDeltaEvent is a "response.audio.delta" event.
+type ResponseAudioDeltaEvent struct {
+	ContentRef
+	// Audio is the decoded audio in the session's output format.
+	Audio []byte
+}
+
+// ResponseAudioDoneEvent is a "response.audio.done" event.
+type ResponseAudioDoneEvent struct {
+	ContentRef
+}
+
+// ResponseTranscriptDeltaEvent is a "response.audio_transcript.delta" event.
+type ResponseTranscriptDeltaEvent struct {
+	ContentRef
+	Delta string
+	Words []TranscriptWord
+}
+
+// ResponseTranscriptDoneEvent is a "response.audio_transcript.done" event.
+type ResponseTranscriptDoneEvent struct {
+	ContentRef
+	Transcript string
+}
+
+// ResponseFunctionCallArgumentsEvent is a
+// "response.function_call_arguments.delta" or
+// "response.function_call_arguments.done" event. See also
+// FunctionCallTracker.
+type ResponseFunctionCallArgumentsEvent struct {
+	ResponseID  string
+	ItemID      string
+	OutputIndex int
+	CallID      string
+	// Delta is set for delta events.
+	Delta string
+	// Name and Arguments are set for the done event.
+	Name      string
+	Arguments string
+	Done      bool
+}
+
+// MCP call and tool listing statuses.
+const (
+	MCPStatusInProgress = "in_progress"
+	MCPStatusCompleted  = "completed"
+	MCPStatusFailed     = "failed"
+)
+
+// MCPListToolsEvent is an "mcp_list_tools.in_progress",
+// "mcp_list_tools.completed" or "mcp_list_tools.failed" event. The tools
+// are in the mcp_list_tools item with ID ItemID.
+type MCPListToolsEvent struct {
+	ItemID string
+	// Status is MCPStatusInProgress, MCPStatusCompleted or MCPStatusFailed.
+	Status string
+}
+
+// ResponseMCPCallArgumentsEvent is a "response.mcp_call_arguments.delta"
+// or "response.mcp_call_arguments.done" event. The API executes the call
+// itself; the arguments are for display and logging.
+type ResponseMCPCallArgumentsEvent struct {
+	ResponseID  string
+	ItemID      string
+	OutputIndex int
+	// Delta is set for delta events.
+	Delta string
+	// Arguments is set for the done event.
+	Arguments string
+	Done      bool
+}
+
+// ResponseMCPCallEvent is a "response.mcp_call.in_progress",
+// "response.mcp_call.completed" or "response.mcp_call.failed" event. The
+// output or error of the call is in the mcp_call item with ID ItemID, see
+// ResponseOutputItemEvent.
+type ResponseMCPCallEvent struct {
+	ItemID      string
+	OutputIndex int
+	// Status is MCPStatusInProgress, MCPStatusCompleted or MCPStatusFailed.
+	Status string
+}
+
+// RateLimitsUpdatedEvent is a "rate_limits.updated" event.
+type RateLimitsUpdatedEvent struct {
+	RateLimits []RateLimit
+}
+
+// Typed returns the typed event for e's Type, as a pointer to one of the
+// event types above. For event types without one, it returns e itself.
+func (e *ServerEvent) Typed() any {
+	ref := ContentRef{
+		ResponseID:   e.ResponseID,
+		ItemID:       e.ItemID,
+		OutputIndex:  e.OutputIndex,
+		ContentIndex: e.ContentIndex,
+	}
+	switch e.Type {
+	case EventTypeError:
+		return &ErrorEvent{Error: e.TranscriptionError}
+	case EventTypeSessionCreated:
+		return &SessionCreatedEvent{Session: e.Session}
+	case EventTypeSessionUpdated:
+		return &SessionUpdatedEvent{Session: e.Session}
+	case EventTypeConversationCreated:
+		return &ConversationCreatedEvent{Conversation: e.Conversation}
+	case EventTypeConversationItemCreated:
+		return &ConversationItemCreatedEvent{PreviousItemID: e.PreviousItemID, Item: e.Item}
+	case EventTypeConversationItemInputAudioTranscriptionDelta:
+		return &InputTranscriptionDeltaEvent{ItemID: e.ItemID, ContentIndex: e.ContentIndex, Delta: e.Delta}
+	case EventTypeConversationItemInputAudioTranscriptionCompleted:
+		return &InputTranscriptionCompletedEvent{ItemID: e.ItemID, ContentIndex: e.ContentIndex, Transcript: e.Transcript}
+	case EventTypeConversationItemInputAudioTranscriptionFailed:
+		return &InputTranscriptionFailedEvent{ItemID: e.ItemID, ContentIndex: e.ContentIndex, Error: e.TranscriptionError}
+	case EventTypeConversationItemTruncated:
+		return &ConversationItemTruncatedEvent{ItemID: e.ItemID, ContentIndex: e.ContentIndex, AudioEndMs: e.AudioEndMs}
+	case EventTypeConversationItemDeleted:
+		return &ConversationItemDeletedEvent{ItemID: e.ItemID}
+	case EventTypeInputAudioBufferCommitted:
+		return &InputAudioCommittedEvent{PreviousItemID: e.PreviousItemID, ItemID: e.ItemID}
+	case EventTypeInputAudioBufferCleared:
+		return &InputAudioClearedEvent{}
+	case EventTypeInputAudioBufferSpeechStarted:
+		return &InputSpeechStartedEvent{ItemID: e.ItemID, AudioStartMs: e.AudioStartMs}
+	case EventTypeInputAudioBufferSpeechStopped:
+		return &InputSpeechStoppedEvent{ItemID: e.ItemID, AudioEndMs: e.AudioEndMs}
+	case EventTypeResponseCreated:
+		return &ResponseCreatedEvent{Response: e.Response}
+	case EventTypeResponseDone:
+		return &ResponseDoneEvent{Response: e.Response}
+	case EventTypeResponseOutputItemAdded, EventTypeResponseOutputItemDone:
+		return &ResponseOutputItemEvent{
+			ResponseID:  e.ResponseID,
+			OutputIndex: e.OutputIndex,
+			Item:        e.Item,
+			Done:        e.Type == EventTypeResponseOutputItemDone,
+		}
+	case EventTypeResponseContentPartAdded, EventTypeResponseContentPartDone:
+		return &ResponseContentPartEvent{ContentRef: ref, Part: e.Part, Done: e.Type == EventTypeResponseContentPartDone}
+	case EventTypeResponseTextDelta:
+		return &ResponseTextDeltaEvent{ContentRef: ref, Delta: e.Delta}
+	case EventTypeResponseTextDone:
+		return &ResponseTextDoneEvent{ContentRef: ref, Text: e.Text}
+	case EventTypeResponseAudioDelta:
+		return &ResponseAudioDeltaEvent{ContentRef: ref, Audio: e.Audio}
+	case EventTypeResponseAudioDone:
+		return &ResponseAudioDoneEvent{ContentRef: ref}
+	case EventTypeResponseAudioTranscriptDelta:
+		return &ResponseTranscriptDeltaEvent{ContentRef: ref, Delta: e.Delta, Words: e.Words}
+	case EventTypeResponseAudioTranscriptDone:
+		return &ResponseTranscriptDoneEvent{ContentRef: ref, Transcript: e.Transcript}
+	case EventTypeResponseFunctionCallArgumentsDelta, EventTypeResponseFunctionCallArgumentsDone:
+		return &ResponseFunctionCallArgumentsEvent{
+			ResponseID:  e.ResponseID,
+			ItemID:      e.ItemID,
+			OutputIndex: e.OutputIndex,
+			CallID:      e.CallID,
+			Delta:       e.Delta,
+			Name:        e.Name,
+			Arguments:   e.Arguments,
+			Done:        e.Type == EventTypeResponseFunctionCallArgumentsDone,
+		}
+	case EventTypeResponseMCPCallArgumentsDelta, EventTypeResponseMCPCallArgumentsDone:
+		return &ResponseMCPCallArgumentsEvent{
+			ResponseID:  e.ResponseID,
+			ItemID:      e.ItemID,
+			OutputIndex: e.OutputIndex,
+			Delta:       e.Delta,
+			Arguments:   e.Arguments,
+			Done:        e.Type == EventTypeResponseMCPCallArgumentsDone,
+		}
+	case EventTypeResponseMCPCallInProgress:
+		return &ResponseMCPCallEvent{ItemID: e.ItemID, OutputIndex: e.OutputIndex, Status: MCPStatusInProgress}
+	case EventTypeResponseMCPCallCompleted:
+		return &ResponseMCPCallEvent{ItemID: e.ItemID, OutputIndex: e.OutputIndex, Status: MCPStatusCompleted}
+	case EventTypeResponseMCPCallFailed:
+		return &ResponseMCPCallEvent{ItemID: e.ItemID, OutputIndex: e.OutputIndex, Status: MCPStatusFailed}
+	case EventTypeMCPListToolsInProgress:
+		return &MCPListToolsEvent{ItemID: e.ItemID, Status: MCPStatusInProgress}
+	case EventTypeMCPListToolsCompleted:
+		return &MCPListToolsEvent{ItemID: e.ItemID, Status: MCPStatusCompleted}
+	case EventTypeMCPListToolsFailed:
+		return &MCPListToolsEvent{ItemID: e.ItemID, Status: MCPStatusFailed}
+	case EventTypeRateLimitsUpdated:
+		return &RateLimitsUpdatedEvent{RateLimits: e.RateLimits}
+	}
+	return e
+}
+
+// As returns the typed event of event if it is a T.
+func As[T any](event *ServerEvent) (T, bool) {
+	t, ok := event.Typed().(T)
+	return t, ok
+}
@@ -57,6 +57,20 @@ const (
 	ToolChoiceRequired = "required"
 )
 
+// Tool types.
+const (
+	// ToolTypeFunction is a function executed by the client.
+	ToolTypeFunction = "function"
+	// ToolTypeMCP is a remote MCP server whose tools the API calls itself.
+	ToolTypeMCP = "mcp"
+)
+
+// MCP approval policies for Tool.RequireApproval.
+const (
+	MCPApprovalAlways = "always"
+	MCPApprovalNever  = "never"
+)
+
 // ConnectConfig contains configuration for establishing a realtime connection.
 type ConnectConfig struct {
 	// Model is the model ID to use.
@@ -67,6 +81,63 @@ type ConnectConfig struct {
 	// Used when creating the ephemeral token.
 	// Default: alloy
 	Voice string `json:"voice,omitzero"`
+
+	// AudioFormat is the default input and output audio format of the
+	// session (AudioFormatPCM16, AudioFormatG711ULaw, AudioFormatG711ALaw).
+	// UpdateSession uses it where SessionConfig leaves InputAudioFormat or
+	// OutputAudioFormat empty. Use the G.711 formats for telephony at 8kHz;
+	// see EncodeAudio and DecodeAudio.
+	// Default: pcm16
+	AudioFormat string `json:"audio_format,omitzero"`
+
+	// PaceAudio makes AppendAudio take audio of any size without blocking,
+	// and send it in 20ms frames at real-time pace (up to 100ms ahead), as
+	// the server VAD expects of a live microphone. Callers then need no
+	// chunking or sleeps of their own. AppendAudio checks the audio against
+	// the input format: a WAV file in the input format has its header
+	// stripped, and one in another format is rejected. A trailing partial
+	// frame is sent once no audio has been appended for 20ms, so the end
+	// of the speech reaches the server VAD without a commit. CommitInput
+	// sends the queued audio at once before committing; ClearInput drops
+	// it.
+	// Default: false (each AppendAudio is sent as one event immediately)
+	PaceAudio bool `json:"-"`
+
+	// ICEServers are the STUN/TURN servers of the peer connection
+	// (WebRTC only).
+	// Default: stun:stun.l.google.com:19302
+	ICEServers []ICEServer `json:"-"`
+
+	// ForceRelay restricts the peer connection to TURN relay candidates
+	// (WebRTC only), for networks behind strict NATs or firewalls.
+	// ICEServers must include a TURN server.
+	ForceRelay bool `json:"-"`
+}
+
+// ICEServer is a STUN or TURN server for WebRTC connections.
+type ICEServer struct {
+	// URLs are the server URLs, e.g. "stun:stun.example.com:3478" or
+	// "turn:turn.example.com:3478?transport=tcp".
+	URLs []string
+
+	// Username and Credential authenticate with a TURN server.
+	Username   string
+	Credential string
+}
+
+// withAudioFormat returns config with empty audio formats set to format.
+func (c *SessionConfig) withAudioFormat(format string) *SessionConfig {
+	if c == nil || format == "" || (c.InputAudioFormat != "" && c.OutputAudioFormat != "") {
+		return c
+	}
+	cp := *c
+	if cp.InputAudioFormat == "" {
+		cp.InputAudioFormat = format
+	}
+	if cp.OutputAudioFormat == "" {
+		cp.OutputAudioFormat = format
+	}
+	return &cp
 }
 
 // SessionConfig contains configuration for updating session parameters.
@@ -93,6 +164,10 @@ type SessionConfig struct {
 	// Set to enable transcription of user audio.
 	InputAudioTranscription *TranscriptionConfig `json:"input_audio_transcription,omitzero"`
 
+	// InputAudioNoiseReduction configures noise reduction of input audio
+	// before VAD and the model.
+	InputAudioNoiseReduction *NoiseReduction `json:"input_audio_noise_reduction,omitzero"`
+
 	// TurnDetection configures voice activity detection.
 	// Set TurnDetectionDisabled=true to explicitly disable VAD (manual mode).
 	// Use nil to keep current setting.
@@ -102,7 +177,7 @@ type SessionConfig struct {
 	// This disables server-side VAD and enables manual mode.
 	TurnDetectionDisabled bool `json:"-"`
 
-	// Tools defines the available functions for the model.
+	// Tools defines the available functions and MCP servers for the model.
 	Tools []Tool `json:"tools,omitzero"`
 
 	// ToolChoice specifies how the model should use tools.
@@ -151,6 +226,9 @@ func (s SessionConfig) MarshalJSON() ([]byte, error) {
 		if s.InputAudioTranscription != nil {
 			m["input_audio_transcription"] = s.InputAudioTranscription
 		}
+		if s.InputAudioNoiseReduction != nil {
+			m["input_audio_noise_reduction"] = s.InputAudioNoiseReduction
+		}
 		m["turn_detection"] = nil // Explicit null
 		if len(s.Tools) > 0 {
 			m["tools"] = s.Tools
@@ -178,6 +256,30 @@ type TranscriptionConfig struct {
 	// Model is the transcription model to use.
 	// Default: whisper-1
 	Model string `json:"model,omitzero"`
+
+	// Language is the ISO-639-1 language of the input audio (e.g., "zh").
+	// Setting it improves accuracy and latency over auto-detection.
+	Language string `json:"language,omitzero"`
+
+	// Prompt guides the transcription: keywords or vocabulary for
+	// whisper-1, free text for the gpt-4o transcribe models.
+	Prompt string `json:"prompt,omitzero"`
+}
+
+// Noise reduction types.
+const (
+	// NoiseReductionNearField is for close-talking microphones such as
+	// headphones or handheld toys.
+	NoiseReductionNearField = "near_field"
+	// NoiseReductionFarField is for far-field microphones such as
+	// speakers and room devices.
+	NoiseReductionFarField = "far_field"
+)
+
+// NoiseReduction configures input audio noise reduction.
+type NoiseReduction struct {
+	// Type is NoiseReductionNearField or NoiseReductionFarField.
+	Type string `json:"type"`
 }
 
 // TurnDetection configures voice activity detection.
@@ -213,24 +315,73 @@ type TurnDetection struct {
 	Eagerness string `json:"eagerness,omitzero"`
 }
 
-// Tool defines a function tool available to the model.
+// Tool defines a tool available to the model: a function the client
+// executes (ToolTypeFunction), or a remote MCP server whose tools the API
+// lists and calls itself (ToolTypeMCP). See MCPTool.
 type Tool struct {
-	// Type is always "function".
+	// Type is ToolTypeFunction or ToolTypeMCP.
 	Type string `json:"type"`
 
 	// Name is the function name.
-	Name string `json:"name"`
+	Name string `json:"name,omitzero"`
 
 	// Description describes what the function does.
 	Description string `json:"description,omitzero"`
 
 	// Parameters is the JSON Schema for the function parameters.
 	Parameters map[string]interface{} `json:"parameters,omitzero"`
+
+	// === MCP tools ===
+
+	// ServerLabel names the MCP server in events and items.
+	ServerLabel string `json:"server_label,omitzero"`
+
+	// ServerURL is the URL of the MCP server.
+	ServerURL string `json:"server_url,omitzero"`
+
+	// ServerDescription tells the model what the server is for.
+	ServerDescription string `json:"server_description,omitzero"`
+
+	// ConnectorID selects an OpenAI-hosted connector instead of ServerURL.
+	ConnectorID string `json:"connector_id,omitzero"`
+
+	// Authorization is an OAuth access token for the server.
+	Authorization string `json:"authorization,omitzero"`
+
+	// Headers are sent with requests to the server.
+	Headers map[string]string `json:"headers,omitzero"`
+
+	// AllowedTools limits the server tools the model may call.
+	// Default: all tools of the server.
+	AllowedTools []string `json:"allowed_tools,omitzero"`
+
+	// RequireApproval is MCPApprovalAlways, MCPApprovalNever or an object
+	// selecting tools, e.g. {"never": {"tool_names": ["search"]}}. Calls
+	// that need approval produce an mcp_approval_request item; answer it
+	// with AddMCPApprovalResponse.
+	// Default: always
+	RequireApproval interface{} `json:"require_approval,omitzero"`
+}
+
+// MCPTool returns a tool for the MCP server at url, labeled label.
+func MCPTool(label, url string) Tool {
+	return Tool{Type: ToolTypeMCP, ServerLabel: label, ServerURL: url}
+}
+
+// MCPToolInfo describes a tool of an MCP server, as listed in an
+// mcp_list_tools item.
+type MCPToolInfo struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitzero"`
+	InputSchema map[string]interface{} `json:"input_schema,omitzero"`
+	Annotations map[string]interface{} `json:"annotations,omitzero"`
 }
 
 // ResponseCreateOptions contains options for creating a response.
 type ResponseCreateOptions struct {
-	// Modalities specifies the output modalities for this response.
+	// Modalities specifies the output modalities for this response:
+	// [ModalityText] for a text-only answer, or [ModalityText, ModalityAudio]
+	// for a spoken one. Default: the session's modalities.
 	Modalities []string `json:"modalities,omitzero"`
 
 	// Instructions override for this response.
@@ -256,14 +407,24 @@ type ResponseCreateOptions struct {
 	// MaxOutputTokens limits the output length for this response.
 	MaxOutputTokens *int `json:"max_output_tokens,omitzero"`
 
+	// UnlimitedOutputTokens lifts the session's output limit for this
+	// response (sends max_output_tokens "inf"). It cannot be combined with
+	// MaxOutputTokens.
+	UnlimitedOutputTokens bool `json:"-"`
+
 	// Conversation specifies conversation handling.
-	// "auto" (default) uses existing conversation.
-	// "none" creates response without conversation context.
+	// ConversationAuto (default) uses the existing conversation.
+	// ConversationNone creates an out-of-band response that is not added
+	// to the conversation.
 	Conversation string `json:"conversation,omitzero"`
 
 	// Input provides input items directly instead of using the buffer.
 	// Use this for text-only input or to inject conversation history.
 	Input []ConversationItem `json:"input,omitzero"`
+
+	// Metadata is attached to the response (up to 16 key-value pairs) and
+	// returned in its events as ServerEvent.ResponseMetadata.
+	Metadata map[string]string `json:"metadata,omitzero"`
 }
 
 // SessionResource represents the session state returned by the server.
@@ -278,6 +439,7 @@ type SessionResource struct {
 	InputAudioFormat          string               `json:"input_audio_format,omitzero"`
 	OutputAudioFormat         string               `json:"output_audio_format,omitzero"`
 	InputAudioTranscription   *TranscriptionConfig `json:"input_audio_transcription,omitzero"`
+	InputAudioNoiseReduction  *NoiseReduction      `json:"input_audio_noise_reduction,omitzero"`
 	TurnDetection             *TurnDetection       `json:"turn_detection,omitzero"`
 	Tools                     []Tool               `json:"tools,omitzero"`
 	ToolChoice                interface{}          `json:"tool_choice,omitzero"`
@@ -295,14 +457,21 @@ type ConversationResource struct {
 type ConversationItem struct {
 	ID       string        `json:"id,omitzero"`
 	Object   string        `json:"object,omitzero"`
-	Type     string        `json:"type,omitzero"` // "message", "function_call", "function_call_output"
+	Type     string        `json:"type,omitzero"` // see ItemType constants
 	Status   string        `json:"status,omitzero"`
 	Role     string        `json:"role,omitzero"` // "user", "assistant", "system"
 	Content  []ContentPart `json:"content,omitzero"`
 	CallID   string        `json:"call_id,omitzero"`   // for function_call_output
-	Name     string        `json:"name,omitzero"`      // for function_call
-	Arguments string       `json:"arguments,omitzero"` // for function_call
-	Output   string        `json:"output,omitzero"`    // for function_call_output
+	Name     string        `json:"name,omitzero"`      // for function_call, mcp_call, mcp_approval_request
+	Arguments string       `json:"arguments,omitzero"` // for function_call, mcp_call, mcp_approval_request
+	Output   string        `json:"output,omitzero"`    // for function_call_output, mcp_call
+
+	ServerLabel       string        `json:"server_label,omitzero"`        // for mcp_* items
+	Tools             []MCPToolInfo `json:"tools,omitzero"`               // for mcp_list_tools
+	Error             *Error        `json:"error,omitzero"`               // for mcp_call
+	ApprovalRequestID string        `json:"approval_request_id,omitzero"` // for mcp_approval_response
+	Approve           *bool         `json:"approve,omitzero"`             // for mcp_approval_response
+	Reason            string        `json:"reason,omitzero"`              // for mcp_approval_response
 }
 
 // ContentPart represents a part of message content.
@@ -322,6 +491,8 @@ type ResponseResource struct {
 	StatusDetails      *StatusDetails     `json:"status_details,omitzero"`
 	Output             []ConversationItem `json:"output,omitzero"`
 	Usage              *Usage             `json:"usage,omitzero"`
+	ConversationID     string             `json:"conversation_id,omitzero"` // empty for out-of-band responses
+	Metadata           map[string]string  `json:"metadata,omitzero"`
 }
 
 // StatusDetails contains details about the response status.
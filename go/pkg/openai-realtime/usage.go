@@ -0,0 +1,103 @@
+package openairealtime
+
+import (
+	"sync"
+	"time"
+)
+
+// Rate limit names in rate_limits.updated events.
+const (
+	RateLimitRequests = "requests"
+	RateLimitTokens   = "tokens"
+)
+
+// RateLimits is the latest rate limit state of a session, from the
+// rate_limits.updated events the server sends after each response.
+type RateLimits struct {
+	// Requests and Tokens are the request and token limits. Zero until
+	// the server reports them.
+	Requests RateLimit
+	Tokens   RateLimit
+
+	// UpdatedAt is when the limits were received; ResetSeconds count
+	// from here. Zero if no update was received yet.
+	UpdatedAt time.Time
+}
+
+// ResetAt returns when the limit resets.
+func (r *RateLimits) ResetAt(limit RateLimit) time.Time {
+	return r.UpdatedAt.Add(time.Duration(limit.ResetSeconds * float64(time.Second)))
+}
+
+// SessionUsage is the token usage accumulated over all responses of a
+// session, from the usage reported in response.done events.
+type SessionUsage struct {
+	// Responses is the number of responses with usage.
+	Responses int
+
+	TotalTokens  int
+	InputTokens  int
+	OutputTokens int
+
+	// CachedTokens is the part of InputTokens served from the prompt cache.
+	CachedTokens int
+
+	InputTextTokens   int
+	InputAudioTokens  int
+	OutputTextTokens  int
+	OutputAudioTokens int
+}
+
+// add accumulates the usage of one response.
+func (u *SessionUsage) add(usage *Usage) {
+	u.Responses++
+	u.TotalTokens += usage.TotalTokens
+	u.InputTokens += usage.InputTokens
+	u.OutputTokens += usage.OutputTokens
+	if d := usage.InputTokenDetails; d != nil {
+		u.CachedTokens += d.CachedTokens
+		u.InputTextTokens += d.TextTokens
+		u.InputAudioTokens += d.AudioTokens
+	}
+	if d := usage.OutputTokenDetails; d != nil {
+		u.OutputTextTokens += d.TextTokens
+		u.OutputAudioTokens += d.AudioTokens
+	}
+}
+
+// usageTracker accumulates usage and rate limits from server events.
+type usageTracker struct {
+	mu         sync.Mutex
+	usage      SessionUsage
+	rateLimits RateLimits
+}
+
+func (t *usageTracker) apply(event *ServerEvent) {
+	switch event.Type {
+	case EventTypeResponseDone:
+		if event.Response == nil || event.Response.Usage == nil {
+			return
+		}
+		t.mu.Lock()
+		t.usage.add(event.Response.Usage)
+		t.mu.Unlock()
+	case EventTypeRateLimitsUpdated:
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.rateLimits.UpdatedAt = time.Now()
+		for _, l := range event.RateLimits {
+			switch l.Name {
+			case RateLimitRequests:
+				t.rateLimits.Requests = l
+			case RateLimitTokens:
+				t.rateLimits.Tokens = l
+			}
+		}
+	}
+}
+
+func (t *usageTracker) snapshot() (SessionUsage, RateLimits) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usage, t.rateLimits
+}
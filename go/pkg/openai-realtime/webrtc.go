@@ -10,6 +10,7 @@ import (
 	"iter"
 	"log/slog"
 	"net/http"
+	"strings"
 	"sync"
 
 	"github.com/pion/webrtc/v3"
@@ -23,6 +24,11 @@ type WebRTCSession struct {
 	config      *ConnectConfig
 	client      *Client
 	sessionID   string
+	conv        conversation
+	responses   responseMetadata
+	usage       usageTracker
+	drain       responseDrain
+	pacer       *audioPacer // nil unless ConnectConfig.PaceAudio
 	closeCh     chan struct{}
 	eventsCh    chan eventOrError
 	closeOnce   sync.Once
@@ -81,6 +87,11 @@ func (c *Client) connectWebRTC(ctx context.Context, config *ConnectConfig) (*Web
 		config.Model = ModelGPT4oRealtimePreview
 	}
 
+	pcConfig, err := peerConnectionConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
 	// Step 1: Get ephemeral token from OpenAI API
 	token, err := c.getEphemeralToken(ctx, config.Model, config.Voice)
 	if err != nil {
@@ -88,11 +99,7 @@ func (c *Client) connectWebRTC(ctx context.Context, config *ConnectConfig) (*Web
 	}
 
 	// Step 2: Create WebRTC peer connection
-	peerConnection, err := webrtc.NewPeerConnection(webrtc.Configuration{
-		ICEServers: []webrtc.ICEServer{
-			{URLs: []string{"stun:stun.l.google.com:19302"}},
-		},
-	})
+	peerConnection, err := webrtc.NewPeerConnection(pcConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create peer connection: %w", err)
 	}
@@ -104,6 +111,9 @@ func (c *Client) connectWebRTC(ctx context.Context, config *ConnectConfig) (*Web
 		closeCh:  make(chan struct{}),
 		eventsCh: make(chan eventOrError, 100),
 	}
+	if config.PaceAudio {
+		session.pacer = newAudioPacer(config.AudioFormat, session.sendAudio)
+	}
 
 	// Step 3: Add audio transceiver for receiving audio
 	_, err = peerConnection.AddTransceiverFromKind(webrtc.RTPCodecTypeAudio, webrtc.RTPTransceiverInit{
@@ -144,6 +154,10 @@ func (c *Client) connectWebRTC(ctx context.Context, config *ConnectConfig) (*Web
 			session.sessionID = event.Session.ID
 			session.mu.Unlock()
 		}
+		session.conv.apply(event)
+		session.responses.apply(event)
+		session.usage.apply(event)
+		session.drain.apply(event)
 
 		// Check for error event
 		if event.Type == EventTypeError && event.TranscriptionError != nil {
@@ -164,6 +178,7 @@ func (c *Client) connectWebRTC(ctx context.Context, config *ConnectConfig) (*Web
 
 	dataChannel.OnClose(func() {
 		slog.Debug("data channel closed")
+		session.drain.end()
 		session.eventsOnce.Do(func() {
 			close(session.eventsCh)
 		})
@@ -222,6 +237,38 @@ func (c *Client) connectWebRTC(ctx context.Context, config *ConnectConfig) (*Web
 	return session, nil
 }
 
+// peerConnectionConfig builds the peer connection configuration from the
+// ICE settings of config.
+func peerConnectionConfig(config *ConnectConfig) (webrtc.Configuration, error) {
+	servers := config.ICEServers
+	if len(servers) == 0 {
+		servers = []ICEServer{{URLs: []string{"stun:stun.l.google.com:19302"}}}
+	}
+
+	var pcConfig webrtc.Configuration
+	var hasTURN bool
+	for _, server := range servers {
+		ice := webrtc.ICEServer{URLs: server.URLs}
+		if server.Username != "" || server.Credential != "" {
+			ice.Username = server.Username
+			ice.Credential = server.Credential
+		}
+		for _, u := range server.URLs {
+			if strings.HasPrefix(u, "turn:") || strings.HasPrefix(u, "turns:") {
+				hasTURN = true
+			}
+		}
+		pcConfig.ICEServers = append(pcConfig.ICEServers, ice)
+	}
+	if config.ForceRelay {
+		if !hasTURN {
+			return webrtc.Configuration{}, fmt.Errorf("ForceRelay requires a TURN server in ICEServers")
+		}
+		pcConfig.ICETransportPolicy = webrtc.ICETransportPolicyRelay
+	}
+	return pcConfig, nil
+}
+
 // getEphemeralToken gets an ephemeral token for WebRTC session.
 func (c *Client) getEphemeralToken(ctx context.Context, model, voice string) (string, error) {
 	if voice == "" {
@@ -302,23 +349,36 @@ func (c *Client) sendOffer(ctx context.Context, token, model, sdp string) (strin
 
 // UpdateSession updates the session configuration.
 func (s *WebRTCSession) UpdateSession(config *SessionConfig) error {
+	config = config.withAudioFormat(s.config.AudioFormat)
 	event := map[string]interface{}{
 		"event_id": generateEventID(),
 		"type":     EventTypeSessionUpdate,
 		"session":  config,
 	}
-	return s.sendEvent(event)
+	if err := s.sendEvent(event); err != nil {
+		return err
+	}
+	if s.pacer != nil && config != nil && config.InputAudioFormat != "" {
+		s.pacer.setFormat(config.InputAudioFormat)
+	}
+	return nil
 }
 
 // AppendAudio appends PCM audio data to the input audio buffer.
 // Note: For WebRTC, prefer using AddAudioTrack for real-time audio streaming.
+// With ConnectConfig.PaceAudio, the audio is queued and sent by the pacer.
 func (s *WebRTCSession) AppendAudio(audio []byte) error {
-	encoded := base64.StdEncoding.EncodeToString(audio)
-	return s.AppendAudioBase64(encoded)
+	if s.pacer != nil {
+		return s.pacer.append(audio)
+	}
+	return s.sendAudio(audio)
 }
 
 // AppendAudioBase64 appends base64-encoded audio data to the input buffer.
 func (s *WebRTCSession) AppendAudioBase64(audioBase64 string) error {
+	if s.pacer != nil {
+		return s.pacer.appendBase64(audioBase64)
+	}
 	return s.sendEvent(map[string]interface{}{
 		"event_id": generateEventID(),
 		"type":     EventTypeInputAudioBufferAppend,
@@ -326,8 +386,22 @@ func (s *WebRTCSession) AppendAudioBase64(audioBase64 string) error {
 	})
 }
 
+// sendAudio sends audio to the input audio buffer.
+func (s *WebRTCSession) sendAudio(audio []byte) error {
+	return s.sendEvent(map[string]interface{}{
+		"event_id": generateEventID(),
+		"type":     EventTypeInputAudioBufferAppend,
+		"audio":    base64.StdEncoding.EncodeToString(audio),
+	})
+}
+
 // CommitInput commits the audio buffer.
 func (s *WebRTCSession) CommitInput() error {
+	if s.pacer != nil {
+		if err := s.pacer.flush(); err != nil {
+			return err
+		}
+	}
 	return s.sendEvent(map[string]interface{}{
 		"event_id": generateEventID(),
 		"type":     EventTypeInputAudioBufferCommit,
@@ -336,6 +410,9 @@ func (s *WebRTCSession) CommitInput() error {
 
 // ClearInput clears the input audio buffer.
 func (s *WebRTCSession) ClearInput() error {
+	if s.pacer != nil {
+		s.pacer.clear()
+	}
 	return s.sendEvent(map[string]interface{}{
 		"event_id": generateEventID(),
 		"type":     EventTypeInputAudioBufferClear,
@@ -411,6 +488,24 @@ func (s *WebRTCSession) AddFunctionCallOutput(callID string, output string) erro
 	})
 }
 
+// SubmitToolOutput adds a function call output and requests a response.
+func (s *WebRTCSession) SubmitToolOutput(callID string, output string) error {
+	if err := s.AddFunctionCallOutput(callID, output); err != nil {
+		return err
+	}
+	return s.CreateResponse(nil)
+}
+
+// AddMCPApprovalResponse answers an MCP approval request.
+func (s *WebRTCSession) AddMCPApprovalResponse(approvalRequestID string, approve bool) error {
+	return s.sendEvent(createItemEvent(mcpApprovalResponse(approvalRequestID, approve), ""))
+}
+
+// CreateItem adds an item to the conversation after previousItemID.
+func (s *WebRTCSession) CreateItem(item *ConversationItem, previousItemID string) error {
+	return s.sendEvent(createItemEvent(item, previousItemID))
+}
+
 // TruncateItem truncates a conversation item.
 func (s *WebRTCSession) TruncateItem(itemID string, contentIndex int, audioEndMs int) error {
 	return s.sendEvent(map[string]interface{}{
@@ -433,48 +528,10 @@ func (s *WebRTCSession) DeleteItem(itemID string) error {
 
 // CreateResponse requests the model to generate a response.
 func (s *WebRTCSession) CreateResponse(opts *ResponseCreateOptions) error {
-	event := map[string]interface{}{
-		"event_id": generateEventID(),
-		"type":     EventTypeResponseCreate,
-	}
-
-	if opts != nil {
-		response := map[string]interface{}{}
-		if len(opts.Modalities) > 0 {
-			response["modalities"] = opts.Modalities
-		}
-		if opts.Instructions != "" {
-			response["instructions"] = opts.Instructions
-		}
-		if opts.Voice != "" {
-			response["voice"] = opts.Voice
-		}
-		if opts.OutputAudioFormat != "" {
-			response["output_audio_format"] = opts.OutputAudioFormat
-		}
-		if len(opts.Tools) > 0 {
-			response["tools"] = opts.Tools
-		}
-		if opts.ToolChoice != nil {
-			response["tool_choice"] = opts.ToolChoice
-		}
-		if opts.Temperature != nil {
-			response["temperature"] = *opts.Temperature
-		}
-		if opts.MaxOutputTokens != nil {
-			response["max_output_tokens"] = opts.MaxOutputTokens
-		}
-		if opts.Conversation != "" {
-			response["conversation"] = opts.Conversation
-		}
-		if len(opts.Input) > 0 {
-			response["input"] = opts.Input
-		}
-		if len(response) > 0 {
-			event["response"] = response
-		}
+	event, err := responseCreateEvent(opts)
+	if err != nil {
+		return err
 	}
-
 	return s.sendEvent(event)
 }
 
@@ -486,6 +543,23 @@ func (s *WebRTCSession) CancelResponse() error {
 	})
 }
 
+// ListItems returns the conversation items known to the session.
+func (s *WebRTCSession) ListItems() []ConversationItem {
+	return s.conv.list()
+}
+
+// Usage returns the token usage accumulated over the session's responses.
+func (s *WebRTCSession) Usage() SessionUsage {
+	usage, _ := s.usage.snapshot()
+	return usage
+}
+
+// RateLimits returns the latest rate limits reported by the server.
+func (s *WebRTCSession) RateLimits() RateLimits {
+	_, limits := s.usage.snapshot()
+	return limits
+}
+
 // Events returns an iterator over server events.
 func (s *WebRTCSession) Events() iter.Seq2[*ServerEvent, error] {
 	return func(yield func(*ServerEvent, error) bool) {
@@ -518,6 +592,9 @@ func (s *WebRTCSession) Close() error {
 	var err error
 	s.closeOnce.Do(func() {
 		close(s.closeCh)
+		if s.pacer != nil {
+			s.pacer.close()
+		}
 		if s.dc != nil {
 			s.dc.Close()
 		}
@@ -528,6 +605,16 @@ func (s *WebRTCSession) Close() error {
 	return err
 }
 
+// Shutdown waits for the responses in progress, then closes the data
+// channel and the peer connection.
+func (s *WebRTCSession) Shutdown(ctx context.Context) (SessionUsage, error) {
+	err := s.drain.drain(ctx, s.CancelResponse)
+	if cerr := s.Close(); err == nil {
+		err = cerr
+	}
+	return s.Usage(), err
+}
+
 // SessionID returns the session ID.
 func (s *WebRTCSession) SessionID() string {
 	s.mu.Lock()
@@ -579,6 +666,9 @@ func (s *WebRTCSession) PeerConnection() *webrtc.PeerConnection {
 
 // sendEvent sends a JSON event through the data channel.
 func (s *WebRTCSession) sendEvent(event map[string]interface{}) error {
+	if err := s.drain.admit(event); err != nil {
+		return err
+	}
 	if s.dc == nil || s.dc.ReadyState() != webrtc.DataChannelStateOpen {
 		return fmt.Errorf("data channel not ready")
 	}
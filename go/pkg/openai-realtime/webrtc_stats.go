@@ -0,0 +1,83 @@
+package openairealtime
+
+import (
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// WebRTCStats is a snapshot of the connection quality of a WebRTCSession.
+type WebRTCStats struct {
+	// ICEState is the ICE connection state, e.g. "connected" or "failed".
+	ICEState string
+
+	// RoundTripTime is the latest STUN round-trip time of the selected
+	// candidate pair. Zero until measured.
+	RoundTripTime time.Duration
+
+	// BytesSent and BytesReceived count the bytes on the selected
+	// candidate pair.
+	BytesSent     uint64
+	BytesReceived uint64
+
+	// AvailableOutgoingBitrate is the estimated available outgoing
+	// bitrate in bits per second, if known.
+	AvailableOutgoingBitrate float64
+
+	// PacketsReceived and PacketsLost count the RTP packets of the
+	// incoming audio.
+	PacketsReceived uint32
+	PacketsLost     int32
+
+	// Jitter is the interarrival jitter of the incoming audio.
+	Jitter time.Duration
+
+	// DataChannelMessagesSent and DataChannelMessagesReceived count the
+	// events on the data channel.
+	DataChannelMessagesSent     uint32
+	DataChannelMessagesReceived uint32
+}
+
+// PacketLoss returns the fraction of incoming audio packets lost, between
+// 0 and 1.
+func (s *WebRTCStats) PacketLoss() float64 {
+	total := int64(s.PacketsReceived) + int64(s.PacketsLost)
+	if total <= 0 || s.PacketsLost <= 0 {
+		return 0
+	}
+	return float64(s.PacketsLost) / float64(total)
+}
+
+// Stats returns the current connection statistics from the peer
+// connection. Use it to diagnose poor audio quality, e.g. high RTT or
+// packet loss behind a TURN relay. For the full report, call GetStats on
+// PeerConnection.
+func (s *WebRTCSession) Stats() WebRTCStats {
+	stats := WebRTCStats{
+		ICEState: s.pc.ICEConnectionState().String(),
+	}
+	for _, report := range s.pc.GetStats() {
+		switch st := report.(type) {
+		case webrtc.ICECandidatePairStats:
+			if !st.Nominated || st.State != webrtc.StatsICECandidatePairStateSucceeded {
+				continue
+			}
+			stats.RoundTripTime = secondsToDuration(st.CurrentRoundTripTime)
+			stats.BytesSent = st.BytesSent
+			stats.BytesReceived = st.BytesReceived
+			stats.AvailableOutgoingBitrate = st.AvailableOutgoingBitrate
+		case webrtc.InboundRTPStreamStats:
+			stats.PacketsReceived += st.PacketsReceived
+			stats.PacketsLost += st.PacketsLost
+			stats.Jitter = max(stats.Jitter, secondsToDuration(st.Jitter))
+		case webrtc.DataChannelStats:
+			stats.DataChannelMessagesSent += st.MessagesSent
+			stats.DataChannelMessagesReceived += st.MessagesReceived
+		}
+	}
+	return stats
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
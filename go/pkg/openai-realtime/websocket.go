@@ -9,6 +9,7 @@ import (
 	"log/slog"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
@@ -20,6 +21,12 @@ type WebSocketSession struct {
 	config    *ConnectConfig
 	client    *Client
 	sessionID string
+	conv      conversation
+	responses responseMetadata
+	usage     usageTracker
+	drain     responseDrain
+	pacer     *audioPacer // nil unless ConnectConfig.PaceAudio
+	readDone  chan struct{}
 	closeCh   chan struct{}
 	eventsCh  chan eventOrError
 	closeOnce sync.Once
@@ -74,10 +81,15 @@ func (c *Client) connectWebSocket(ctx context.Context, config *ConnectConfig) (*
 		conn:     conn,
 		config:   config,
 		client:   c,
+		readDone: make(chan struct{}),
 		closeCh:  make(chan struct{}),
 		eventsCh: make(chan eventOrError, 100),
 	}
 
+	if config.PaceAudio {
+		session.pacer = newAudioPacer(config.AudioFormat, session.sendAudio)
+	}
+
 	// Start background reader
 	go session.readLoop()
 
@@ -91,22 +103,35 @@ func generateEventID() string {
 
 // UpdateSession updates the session configuration.
 func (s *WebSocketSession) UpdateSession(config *SessionConfig) error {
+	config = config.withAudioFormat(s.config.AudioFormat)
 	event := map[string]interface{}{
 		"event_id": generateEventID(),
 		"type":     EventTypeSessionUpdate,
 		"session":  config,
 	}
-	return s.sendEvent(event)
+	if err := s.sendEvent(event); err != nil {
+		return err
+	}
+	if s.pacer != nil && config != nil && config.InputAudioFormat != "" {
+		s.pacer.setFormat(config.InputAudioFormat)
+	}
+	return nil
 }
 
 // AppendAudio appends PCM audio data to the input audio buffer.
+// With ConnectConfig.PaceAudio, the audio is queued and sent by the pacer.
 func (s *WebSocketSession) AppendAudio(audio []byte) error {
-	encoded := base64.StdEncoding.EncodeToString(audio)
-	return s.AppendAudioBase64(encoded)
+	if s.pacer != nil {
+		return s.pacer.append(audio)
+	}
+	return s.sendAudio(audio)
 }
 
 // AppendAudioBase64 appends base64-encoded audio data to the input buffer.
 func (s *WebSocketSession) AppendAudioBase64(audioBase64 string) error {
+	if s.pacer != nil {
+		return s.pacer.appendBase64(audioBase64)
+	}
 	return s.sendEvent(map[string]interface{}{
 		"event_id": generateEventID(),
 		"type":     EventTypeInputAudioBufferAppend,
@@ -114,8 +139,22 @@ func (s *WebSocketSession) AppendAudioBase64(audioBase64 string) error {
 	})
 }
 
+// sendAudio sends audio to the input audio buffer.
+func (s *WebSocketSession) sendAudio(audio []byte) error {
+	return s.sendEvent(map[string]interface{}{
+		"event_id": generateEventID(),
+		"type":     EventTypeInputAudioBufferAppend,
+		"audio":    base64.StdEncoding.EncodeToString(audio),
+	})
+}
+
 // CommitInput commits the audio buffer.
 func (s *WebSocketSession) CommitInput() error {
+	if s.pacer != nil {
+		if err := s.pacer.flush(); err != nil {
+			return err
+		}
+	}
 	return s.sendEvent(map[string]interface{}{
 		"event_id": generateEventID(),
 		"type":     EventTypeInputAudioBufferCommit,
@@ -124,6 +163,9 @@ func (s *WebSocketSession) CommitInput() error {
 
 // ClearInput clears the input audio buffer.
 func (s *WebSocketSession) ClearInput() error {
+	if s.pacer != nil {
+		s.pacer.clear()
+	}
 	return s.sendEvent(map[string]interface{}{
 		"event_id": generateEventID(),
 		"type":     EventTypeInputAudioBufferClear,
@@ -199,6 +241,24 @@ func (s *WebSocketSession) AddFunctionCallOutput(callID string, output string) e
 	})
 }
 
+// SubmitToolOutput adds a function call output and requests a response.
+func (s *WebSocketSession) SubmitToolOutput(callID string, output string) error {
+	if err := s.AddFunctionCallOutput(callID, output); err != nil {
+		return err
+	}
+	return s.CreateResponse(nil)
+}
+
+// AddMCPApprovalResponse answers an MCP approval request.
+func (s *WebSocketSession) AddMCPApprovalResponse(approvalRequestID string, approve bool) error {
+	return s.sendEvent(createItemEvent(mcpApprovalResponse(approvalRequestID, approve), ""))
+}
+
+// CreateItem adds an item to the conversation after previousItemID.
+func (s *WebSocketSession) CreateItem(item *ConversationItem, previousItemID string) error {
+	return s.sendEvent(createItemEvent(item, previousItemID))
+}
+
 // TruncateItem truncates a conversation item.
 func (s *WebSocketSession) TruncateItem(itemID string, contentIndex int, audioEndMs int) error {
 	return s.sendEvent(map[string]interface{}{
@@ -221,48 +281,10 @@ func (s *WebSocketSession) DeleteItem(itemID string) error {
 
 // CreateResponse requests the model to generate a response.
 func (s *WebSocketSession) CreateResponse(opts *ResponseCreateOptions) error {
-	event := map[string]interface{}{
-		"event_id": generateEventID(),
-		"type":     EventTypeResponseCreate,
-	}
-
-	if opts != nil {
-		response := map[string]interface{}{}
-		if len(opts.Modalities) > 0 {
-			response["modalities"] = opts.Modalities
-		}
-		if opts.Instructions != "" {
-			response["instructions"] = opts.Instructions
-		}
-		if opts.Voice != "" {
-			response["voice"] = opts.Voice
-		}
-		if opts.OutputAudioFormat != "" {
-			response["output_audio_format"] = opts.OutputAudioFormat
-		}
-		if len(opts.Tools) > 0 {
-			response["tools"] = opts.Tools
-		}
-		if opts.ToolChoice != nil {
-			response["tool_choice"] = opts.ToolChoice
-		}
-		if opts.Temperature != nil {
-			response["temperature"] = *opts.Temperature
-		}
-		if opts.MaxOutputTokens != nil {
-			response["max_output_tokens"] = opts.MaxOutputTokens
-		}
-		if opts.Conversation != "" {
-			response["conversation"] = opts.Conversation
-		}
-		if len(opts.Input) > 0 {
-			response["input"] = opts.Input
-		}
-		if len(response) > 0 {
-			event["response"] = response
-		}
+	event, err := responseCreateEvent(opts)
+	if err != nil {
+		return err
 	}
-
 	return s.sendEvent(event)
 }
 
@@ -274,6 +296,23 @@ func (s *WebSocketSession) CancelResponse() error {
 	})
 }
 
+// ListItems returns the conversation items known to the session.
+func (s *WebSocketSession) ListItems() []ConversationItem {
+	return s.conv.list()
+}
+
+// Usage returns the token usage accumulated over the session's responses.
+func (s *WebSocketSession) Usage() SessionUsage {
+	usage, _ := s.usage.snapshot()
+	return usage
+}
+
+// RateLimits returns the latest rate limits reported by the server.
+func (s *WebSocketSession) RateLimits() RateLimits {
+	_, limits := s.usage.snapshot()
+	return limits
+}
+
 // Events returns an iterator over server events.
 func (s *WebSocketSession) Events() iter.Seq2[*ServerEvent, error] {
 	return func(yield func(*ServerEvent, error) bool) {
@@ -306,11 +345,40 @@ func (s *WebSocketSession) Close() error {
 	var err error
 	s.closeOnce.Do(func() {
 		close(s.closeCh)
+		if s.pacer != nil {
+			s.pacer.close()
+		}
 		err = s.conn.Close()
 	})
 	return err
 }
 
+// Shutdown waits for the responses in progress, then closes the
+// connection with a normal closure frame.
+func (s *WebSocketSession) Shutdown(ctx context.Context) (SessionUsage, error) {
+	err := s.drain.drain(ctx, s.CancelResponse)
+
+	s.mu.Lock()
+	werr := s.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(closeWait))
+	s.mu.Unlock()
+	if werr == nil {
+		// Wait for the server to echo the close frame
+		timer := time.NewTimer(closeWait)
+		select {
+		case <-s.readDone:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+
+	if cerr := s.Close(); err == nil {
+		err = cerr
+	}
+	return s.Usage(), err
+}
+
 // SessionID returns the session ID.
 func (s *WebSocketSession) SessionID() string {
 	s.mu.Lock()
@@ -320,6 +388,10 @@ func (s *WebSocketSession) SessionID() string {
 
 // sendEvent sends a JSON event to the server.
 func (s *WebSocketSession) sendEvent(event map[string]interface{}) error {
+	if err := s.drain.admit(event); err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -339,6 +411,8 @@ func (s *WebSocketSession) sendEvent(event map[string]interface{}) error {
 // readLoop reads events from the WebSocket connection.
 func (s *WebSocketSession) readLoop() {
 	defer close(s.eventsCh)
+	defer close(s.readDone)
+	defer s.drain.end()
 
 	for {
 		select {
@@ -349,6 +423,9 @@ func (s *WebSocketSession) readLoop() {
 
 		_, message, err := s.conn.ReadMessage()
 		if err != nil {
+			if s.drain.shuttingDown() && websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return
+			}
 			select {
 			case <-s.closeCh:
 				return
@@ -381,6 +458,10 @@ func (s *WebSocketSession) readLoop() {
 			s.sessionID = event.Session.ID
 			s.mu.Unlock()
 		}
+		s.conv.apply(event)
+		s.responses.apply(event)
+		s.usage.apply(event)
+		s.drain.apply(event)
 
 		// Check for error event - send error and stop reading
 		if event.Type == EventTypeError && event.TranscriptionError != nil {
@@ -0,0 +1,95 @@
+// Package promtext writes metrics in the Prometheus text exposition format,
+// for packages that keep their own counters and export them without a
+// Prometheus client library:
+//
+//	w := promtext.NewWriter(out)
+//	w.Header("giztoy_requests_total", "Requests served.", "counter")
+//	w.Int("giztoy_requests_total", promtext.Label("route", route), n)
+//	return w.Err()
+package promtext
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the content type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Writer writes Prometheus text lines, keeping the first error.
+type Writer struct {
+	w   io.Writer
+	err error
+}
+
+// NewWriter returns a Writer writing to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Header writes the HELP and TYPE lines of a metric; typ is "counter",
+// "gauge" or "histogram".
+func (w *Writer) Header(name, help, typ string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// Int writes a sample of the metric with labels, as returned by Label.
+func (w *Writer) Int(name, labels string, value int64) {
+	w.sample(name, labels, strconv.FormatInt(value, 10))
+}
+
+// Float writes a sample of the metric with labels, as returned by Label.
+func (w *Writer) Float(name, labels string, value float64) {
+	w.sample(name, labels, FormatFloat(value))
+}
+
+// Err returns the first error writing, if any.
+func (w *Writer) Err() error {
+	return w.err
+}
+
+func (w *Writer) sample(name, labels, value string) {
+	w.printf("%s{%s} %s\n", name, labels, value)
+}
+
+func (w *Writer) printf(format string, args ...any) {
+	if w.err == nil {
+		_, w.err = fmt.Fprintf(w.w, format, args...)
+	}
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Label returns the label pairs of names and values, e.g. Label("route",
+// "tts/a", "target", "tts/b") is `route="tts/a",target="tts/b"`. Values are
+// escaped.
+func Label(pairs ...string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		escaper.WriteString(&b, pairs[i+1])
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+// FormatFloat formats a sample value or histogram bound.
+func FormatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Handler returns an HTTP handler serving the metrics written by write, to
+// be mounted at a metrics endpoint scraped by Prometheus.
+func Handler(write func(io.Writer) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		write(w)
+	})
+}
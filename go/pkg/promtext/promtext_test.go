@@ -0,0 +1,67 @@
+package promtext
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	var b strings.Builder
+	w := NewWriter(&b)
+	w.Header("giztoy_requests_total", "Requests served.", "counter")
+	w.Int("giztoy_requests_total", Label("route", `tts/"a"`, "target", "b\\c\n"), 42)
+	w.Header("giztoy_latency_seconds", "Latency.", "histogram")
+	w.Float("giztoy_latency_seconds_bucket", Label("le", FormatFloat(0.25)), 3)
+	if err := w.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP giztoy_requests_total Requests served.
+# TYPE giztoy_requests_total counter
+giztoy_requests_total{route="tts/\"a\"",target="b\\c\n"} 42
+# HELP giztoy_latency_seconds Latency.
+# TYPE giztoy_latency_seconds histogram
+giztoy_latency_seconds_bucket{le="0.25"} 3
+`
+	if got := b.String(); got != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+type failingWriter struct{ n int }
+
+var errWrite = errors.New("write failed")
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	f.n++
+	return 0, errWrite
+}
+
+func TestWriterKeepsFirstError(t *testing.T) {
+	fw := &failingWriter{}
+	w := NewWriter(fw)
+	w.Header("a", "A.", "gauge")
+	w.Int("a", "", 1)
+	if !errors.Is(w.Err(), errWrite) || fw.n != 1 {
+		t.Errorf("err = %v after %d writes, want the first error only", w.Err(), fw.n)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := Handler(func(w io.Writer) error {
+		pw := NewWriter(w)
+		pw.Int("a", Label("k", "v"), 1)
+		return pw.Err()
+	})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if body := rec.Body.String(); body != "a{k=\"v\"} 1\n" {
+		t.Errorf("body = %q", body)
+	}
+}
@@ -3,6 +3,7 @@ package recall
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -277,6 +278,41 @@ func TestSearchSegmentsVector(t *testing.T) {
 	}
 }
 
+func TestSimilarSegments(t *testing.T) {
+	idx, _ := newTestIndex(t)
+	ctx := context.Background()
+
+	segments := []Segment{
+		{ID: "dino", Summary: "dinosaurs", Keywords: []string{"space"}, Timestamp: 1},
+		{ID: "space", Summary: "space", Keywords: []string{"dinosaur"}, Timestamp: 2},
+	}
+	for _, s := range segments {
+		if err := idx.StoreSegment(ctx, s); err != nil {
+			t.Fatalf("StoreSegment %s: %v", s.ID, err)
+		}
+	}
+
+	// Keywords are ignored: only the embeddings count.
+	results, err := idx.SimilarSegments(ctx, "dinosaur fossils", 2)
+	if err != nil {
+		t.Fatalf("SimilarSegments: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[0].Segment.ID != "dino" || results[0].Score < 0.99 {
+		t.Errorf("top result = %q (%.3f), want 'dino' above 0.99", results[0].Segment.ID, results[0].Score)
+	}
+	if results[1].Score >= results[0].Score {
+		t.Errorf("scores not descending: %.3f, %.3f", results[0].Score, results[1].Score)
+	}
+
+	// Without vector search there is nothing to compare.
+	if results, err := newTestIndexNoVec(t).SimilarSegments(ctx, "dinosaurs", 2); err != nil || results != nil {
+		t.Errorf("SimilarSegments without vec = %v, %v; want nil, nil", results, err)
+	}
+}
+
 func TestSearchSegmentsKeywordOnly(t *testing.T) {
 	idx := newTestIndexNoVec(t)
 	ctx := context.Background()
@@ -392,6 +428,68 @@ func TestSearchSegmentsTimeFilter(t *testing.T) {
 	}
 }
 
+func TestSegmentOverlaps(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 0, 0, 0, 0, time.UTC) }
+	june3 := TimeRange{Start: day(3), End: day(4)}
+	tests := []struct {
+		name string
+		seg  Segment
+		r    TimeRange
+		want bool
+	}{
+		{"hour inside", Segment{Timestamp: day(3).Add(10 * time.Hour).UnixNano()}, june3, true},
+		{"hour after", Segment{Timestamp: day(4).Add(2 * time.Hour).UnixNano()}, june3, false},
+		{"hour spilling over midnight", Segment{Timestamp: day(4).Add(30 * time.Minute).UnixNano()}, june3, true},
+		{"hour before", Segment{Timestamp: day(2).Add(23 * time.Hour).UnixNano()}, june3, false},
+		{"week ending later", Segment{Bucket: Bucket1W, Timestamp: day(8).UnixNano()}, june3, true},
+		{"week ending before", Segment{Bucket: Bucket1W, Timestamp: day(2).UnixNano()}, june3, false},
+		{"week starting after", Segment{Bucket: Bucket1W, Timestamp: day(12).UnixNano()}, june3, false},
+		{"lifetime at its timestamp", Segment{Bucket: BucketLT, Timestamp: day(5).UnixNano()}, june3, false},
+		{"unbounded end", Segment{Timestamp: day(20).UnixNano()}, TimeRange{Start: day(3)}, true},
+		{"unbounded start", Segment{Timestamp: day(1).UnixNano()}, TimeRange{End: day(3)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.seg.Overlaps(tt.r); got != tt.want {
+				t.Errorf("Overlaps = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchSegmentsRanges(t *testing.T) {
+	idx := newTestIndexNoVec(t)
+	ctx := context.Background()
+
+	day := func(year, d int) time.Time { return time.Date(year, 6, d, 12, 0, 0, 0, time.UTC) }
+	for _, seg := range []Segment{
+		{ID: "2023", Summary: "a", Timestamp: day(2023, 3).UnixNano()},
+		{ID: "2024", Summary: "b", Timestamp: day(2024, 3).UnixNano(), Bucket: Bucket1D},
+		{ID: "2024-week", Summary: "c", Timestamp: day(2024, 20).UnixNano(), Bucket: Bucket1W},
+		{ID: "2025", Summary: "d", Timestamp: day(2025, 3).UnixNano()},
+	} {
+		if err := idx.StoreSegment(ctx, seg); err != nil {
+			t.Fatalf("StoreSegment: %v", err)
+		}
+	}
+
+	dayRange := func(year int) TimeRange {
+		start := time.Date(year, 6, 3, 0, 0, 0, 0, time.UTC)
+		return TimeRange{Start: start, End: start.AddDate(0, 0, 1)}
+	}
+	results, err := idx.Search(ctx, Query{Ranges: []TimeRange{dayRange(2023), dayRange(2024)}})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	var ids []string
+	for _, r := range results.Segments {
+		ids = append(ids, r.Segment.ID)
+	}
+	if strings.Join(ids, ",") != "2024,2023" {
+		t.Errorf("segments = %v, want 2024,2023 newest first", ids)
+	}
+}
+
 func TestSearchWithGraphExpansion(t *testing.T) {
 	idx, _ := newTestIndex(t)
 	ctx := context.Background()
@@ -20,6 +20,10 @@ type Query struct {
 	// Limit is the maximum number of segments to return. Default is 10
 	// if zero.
 	Limit int
+
+	// Ranges filters segments to those overlapping at least one of the
+	// ranges (see [Segment.Overlaps]). Empty means no range filter.
+	Ranges []TimeRange
 }
 
 // Result holds the output of [Index.Search].
@@ -66,6 +70,7 @@ func (idx *Index) Search(ctx context.Context, q Query) (*Result, error) {
 		Text:   q.Text,
 		Labels: expanded,
 		Limit:  limit,
+		Ranges: q.Ranges,
 	}
 
 	// Step 3: Search segments.
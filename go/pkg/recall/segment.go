@@ -300,8 +300,51 @@ func (idx *Index) SearchSegments(ctx context.Context, q SearchQuery) ([]ScoredSe
 	return scored, nil
 }
 
+// SimilarSegments returns up to limit segments whose summary embeddings are
+// closest to text, scored by cosine similarity in [0,1] and sorted by score
+// descending. Unlike [Index.SearchSegments], no keyword or label signal is
+// mixed in, so scores are comparable against a fixed threshold.
+//
+// Returns nil if no embedder or vector index is configured.
+func (idx *Index) SimilarSegments(ctx context.Context, text string, limit int) ([]ScoredSegment, error) {
+	if idx.embedder == nil || idx.vec == nil || text == "" || idx.vec.Len() == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	vec, err := idx.embedder.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := idx.vec.Search(vec, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	scored := make([]ScoredSegment, 0, len(matches))
+	for _, m := range matches {
+		seg, err := idx.GetSegment(ctx, m.ID)
+		if err != nil {
+			return nil, err
+		}
+		if seg == nil {
+			continue // vector of a segment stored by another index
+		}
+		sim := 1.0 - float64(m.Distance)/2.0
+		if sim < 0 {
+			sim = 0
+		}
+		scored = append(scored, ScoredSegment{Segment: *seg, Score: sim})
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Score > scored[j].Score
+	})
+	return scored, nil
+}
+
 // loadSegments scans all segments from KV across all buckets, applying
-// time and label filters.
+// time, range and label filters.
 func (idx *Index) loadSegments(ctx context.Context, q SearchQuery) ([]Segment, error) {
 	prefix := segmentPrefix(idx.prefix)
 	afterNs := int64(0)
@@ -331,6 +374,11 @@ func (idx *Index) loadSegments(ctx context.Context, q SearchQuery) ([]Segment, e
 			continue
 		}
 
+		// Range filter: segment must overlap one of the ranges.
+		if len(q.Ranges) > 0 && !overlapsAny(seg, q.Ranges) {
+			continue
+		}
+
 		// Label filter: segment must share at least one label.
 		if filterLabels && !hasOverlap(seg.Labels, labelSet) {
 			continue
@@ -351,6 +399,16 @@ func (idx *Index) lookupSid(ctx context.Context, id string) (Bucket, int64, erro
 	return parseSidValue(data)
 }
 
+// overlapsAny reports whether seg overlaps any of ranges.
+func overlapsAny(seg Segment, ranges []TimeRange) bool {
+	for _, r := range ranges {
+		if seg.Overlaps(r) {
+			return true
+		}
+	}
+	return false
+}
+
 // keywordScore computes the fraction of query terms found in the segment's
 // keywords. Both are compared in lowercase for case-insensitive matching.
 func keywordScore(queryTerms []string, segKeywords []string) float64 {
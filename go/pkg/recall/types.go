@@ -112,6 +112,38 @@ type Segment struct {
 	// belongs to. Set when the segment is stored. Defaults to Bucket1H
 	// for segments produced by realtime conversation compression.
 	Bucket Bucket `json:"bucket,omitempty" msgpack:"bucket,omitempty"`
+
+	// Sources lists the IDs of the segments this segment summarizes, for
+	// provenance. Empty for segments produced directly from conversation.
+	// Sources may have been deleted since.
+	Sources []string `json:"sources,omitempty" msgpack:"sources,omitempty"`
+
+	// Repeats counts the near-duplicate segments merged into this one
+	// instead of being stored. Zero for segments stored once.
+	Repeats int `json:"repeats,omitempty" msgpack:"repeats,omitempty"`
+}
+
+// TimeRange is the half-open time interval [Start, End). A zero Start or
+// End leaves that side unbounded.
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Overlaps reports whether the segment may cover time in r. A segment
+// covers the span of its bucket (see [BucketDuration]) up to its
+// Timestamp, so a weekly summary overlaps any day of its week. Segments
+// of the lifetime bucket are taken at their Timestamp.
+func (s Segment) Overlaps(r TimeRange) bool {
+	bucket := s.Bucket
+	if bucket == "" {
+		bucket = Bucket1H
+	}
+	if !r.Start.IsZero() && s.Timestamp < r.Start.UnixNano() {
+		return false
+	}
+	first := s.Timestamp - int64(BucketDuration(bucket))
+	return r.End.IsZero() || first < r.End.UnixNano()
 }
 
 // SearchQuery specifies parameters for [Index.SearchSegments].
@@ -135,6 +167,10 @@ type SearchQuery struct {
 	// Before filters segments to those created before this time.
 	// Zero value means no upper bound.
 	Before time.Time
+
+	// Ranges filters segments to those overlapping at least one of the
+	// ranges (see [Segment.Overlaps]). Empty means no range filter.
+	Ranges []TimeRange
 }
 
 // ScoredSegment pairs a segment with its relevance score.
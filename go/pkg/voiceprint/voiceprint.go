@@ -24,7 +24,8 @@
 //
 // The Transformer wraps the full pipeline as a [genx.Transformer],
 // consuming audio/pcm chunks and injecting SpeakerChunk metadata
-// into the stream. Downstream consumers (e.g., memory) use the
+// into the stream. Downstream consumers (e.g., memory, or the cortex
+// SpeakerHook switching the assistant's voice per family member) use the
 // voice label strings ("voice:A3F8") without depending on this package.
 package voiceprint
 